@@ -0,0 +1,702 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/joho/godotenv"
+)
+
+// DefaultConfigFile is read when CONFIG_FILE is not set. Variables already
+// present in the environment take precedence over the file.
+const DefaultConfigFile = "local.env"
+
+// Config holds every setting of the application
+type Config struct {
+	Database DatabaseConfig
+	Sentry   SentryConfig
+	JWT      JWTConfig
+	Storage  StorageConfig
+	Geocoder GeocoderConfig
+	GeoIP    GeoIPConfig
+	Travel   TravelTimeConfig
+	CORS     CORSConfig
+	Security SecurityConfig
+	Push     PushConfig
+	Mail     MailConfig
+
+	ContentFilter ContentFilterConfig
+	Translation   TranslationConfig
+	ReviewSummary ReviewSummaryConfig
+	Tracing       TracingConfig
+	Anomalies     AnomalyConfig
+	Digests       NotificationDigestConfig
+
+	// MaxBodyBytes caps the size of request bodies
+	MaxBodyBytes int
+	// CompressMinBytes is the smallest response body that is compressed
+	CompressMinBytes int
+	// CheckinDedupWindow is how long checking in again at a venue returns
+	// the user's previous check-in there, zero disables it
+	CheckinDedupWindow time.Duration
+	// OwnerAlertInterval is the least time between two emails about new
+	// reviews and milestones to a venue owner
+	OwnerAlertInterval time.Duration
+	// WishlistWeight is the share of a recommendation's score a venue of the
+	// user's "Want to Try" collection gains when it is nearby and fits
+	WishlistWeight float64
+	// MaxReviewPhotos is how many photos a review can have
+	MaxReviewPhotos int
+
+	// SiteBaseURL is the public web URL used in feeds and links
+	SiteBaseURL string
+	// VoteReceiptSecret signs vote receipts, apart from the JWT secret
+	VoteReceiptSecret string
+	// CampaignAuditKey is the PEM encoded Ed25519 private key campaign
+	// audit certificates are signed with
+	CampaignAuditKey string
+	// LegacyVotingSunset makes the legacy votings read-only, votes going to
+	// campaigns instead
+	LegacyVotingSunset bool
+	// TrustedProxies are the addresses and CIDR ranges of the proxies whose
+	// X-Forwarded-For header gives the client IP. Without any, the client IP
+	// is the address of the connection.
+	TrustedProxies []string
+}
+
+// DatabaseConfig for the Postgres connection
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+
+	// QueryTimeout bounds the database work of a single API request
+	QueryTimeout time.Duration
+
+	// Connection pool limits
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// PoolWaitWarning is the number of waits for a free connection within a
+	// pool check above which a warning is logged
+	PoolWaitWarning int
+
+	// SlowQueryLog records the search and analytics statements running
+	// longer than SlowQueryThreshold, with their plan when SlowQueryExplain
+	// is set
+	SlowQueryLog       bool
+	SlowQueryThreshold time.Duration
+	SlowQueryExplain   bool
+}
+
+// SentryConfig for error reporting, an empty DSN disables Sentry
+type SentryConfig struct {
+	DSN         string
+	Environment string
+}
+
+// JWTConfig for admin authentication
+type JWTConfig struct {
+	Secret    string // Signs issued tokens
+	PublicKey string // PEM encoded Ed25519 key tokens are verified with
+}
+
+// Storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageGCS   = "gcs"
+)
+
+// StorageConfig for file storage. The s3 backend works with S3 and MinIO,
+// gcs uses Cloud Storage's S3 compatible API with HMAC keys and local keeps
+// files on disk.
+type StorageConfig struct {
+	Backend   string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Region    string
+	Secure    bool   // Connect to the endpoint over HTTPS
+	LocalDir  string // Where the local backend keeps files
+	// SigningSecret signs the local backend's URLs to private files, apart
+	// from the JWT secret
+	SigningSecret string
+}
+
+// GeocoderConfig for external geocoding providers. Calls to them go through
+// a circuit breaker opening after BreakerFailures consecutive failures.
+type GeocoderConfig struct {
+	MapboxToken string
+	GoogleToken string
+
+	Timeout         time.Duration
+	BreakerFailures int
+	BreakerCooldown time.Duration // How long the breaker stays open
+}
+
+// GeoIPConfig for the MaxMind GeoLite web service locating clients by IP
+// when they send no coordinates, an empty license key disables it
+type GeoIPConfig struct {
+	URL        string
+	AccountID  string
+	LicenseKey string
+}
+
+// Travel time backends
+const (
+	TravelTimeMapbox = "mapbox" // Mapbox Matrix API, with the Mapbox token
+	TravelTimeOSRM   = "osrm"   // A self-hosted OSRM server
+)
+
+// TravelTimeConfig for the distance matrix service timing the trips to
+// venues, an empty backend disables travel times
+type TravelTimeConfig struct {
+	Backend string
+	URL     string // API base URL, defaults to Mapbox's with the mapbox backend
+	// CacheTTL is how long the travel times are served from memory
+	CacheTTL time.Duration
+}
+
+// CORSConfig for browsers calling the API from other origins. Without
+// allowed origins cross-origin requests get no CORS headers, "*" allows every
+// origin.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // How long browsers cache preflight answers
+}
+
+// SecurityConfig for the security headers sent with every response
+type SecurityConfig struct {
+	// HSTSMaxAge is how long browsers only use HTTPS, zero leaves out
+	// Strict-Transport-Security
+	HSTSMaxAge   time.Duration
+	FrameOptions string // DENY or SAMEORIGIN
+}
+
+// PushConfig for push notification providers, empty keys disable a provider
+type PushConfig struct {
+	FCMServerKey   string
+	APNsKey        string
+	APNsKeyID      string
+	APNsTeamID     string
+	APNsTopic      string
+	APNsProduction bool
+}
+
+// MailConfig for the SMTP server sending account emails, an empty host logs
+// the emails instead
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+}
+
+// ContentFilterConfig for filtering user written text. The word lists extend
+// the built-in list and an empty moderation URL disables the external check.
+type ContentFilterConfig struct {
+	BlockedWords     []string // Reject the text
+	FlaggedWords     []string // Accept the text and flag it for moderation
+	ModerationURL    string
+	ModerationAPIKey string
+}
+
+// TranslationConfig for the review translation API, an empty URL disables
+// translation
+type TranslationConfig struct {
+	URL    string
+	APIKey string
+}
+
+// ReviewSummaryConfig for summarizing venue reviews with an OpenAI
+// compatible chat completions API. Reviews are summarized by picking their
+// most representative sentences when the URL is empty.
+type ReviewSummaryConfig struct {
+	URL    string
+	APIKey string
+	Model  string
+}
+
+// TracingConfig for exporting OpenTelemetry traces over OTLP/HTTP, an empty
+// endpoint disables tracing
+type TracingConfig struct {
+	Endpoint    string // Collector URL, e.g. http://localhost:4318
+	ServiceName string
+	SampleRatio float64 // Share of traces recorded, 0-1
+}
+
+// NotificationDigestConfig holds how long the notifications of frequent
+// events are collected before being sent as one digest. Zero sends each
+// event on its own.
+type NotificationDigestConfig struct {
+	HelpfulWindow  time.Duration // Helpful votes on a review
+	FollowerWindow time.Duration // New followers
+}
+
+// AnomalyConfig for the alerts on daily platform metrics
+type AnomalyConfig struct {
+	// Threshold is the z-score from which a day's metric raises an alert
+	Threshold float64
+	// NotifyAdmins notifies the snapp users linked to admin accounts
+	NotifyAdmins bool
+}
+
+// ValidationError lists every missing or invalid setting
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var (
+	current  *Config
+	loadOnce sync.Once
+)
+
+// Get returns the application configuration, loading it on first use. It
+// exits the process when the configuration is invalid.
+func Get() *Config {
+	loadOnce.Do(func() {
+		cfg, err := Load()
+		if err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		current = cfg
+	})
+	return current
+}
+
+// Set replaces the configuration Get returns, for unit tests that run
+// without the environment
+func Set(cfg *Config) {
+	loadOnce.Do(func() {})
+	current = cfg
+}
+
+// Load reads the configuration file and the environment and validates every
+// setting, reporting all problems at once
+func Load() (*Config, error) {
+	l := &loader{}
+
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile != "" {
+		if err := godotenv.Load(configFile); err != nil {
+			l.problem("CONFIG_FILE %q could not be read: %v", configFile, err)
+		}
+	} else {
+		// The default file is optional, the environment may hold everything
+		godotenv.Load(DefaultConfigFile)
+	}
+
+	cfg := &Config{
+		Database: DatabaseConfig{
+			Host:     l.required("DB_HOST"),
+			Port:     l.integer("DB_PORT", 5432, 1, 65535),
+			User:     l.required("DB_USER"),
+			Password: l.optional("DB_PASS", "postgres"),
+			Name:     l.required("DB_NAME"),
+
+			QueryTimeout: l.duration("DB_QUERY_TIMEOUT", 10*time.Second, 100*time.Millisecond, 5*time.Minute),
+
+			MaxOpenConns:    l.integer("DB_MAX_OPEN_CONNS", 25, 1, 1000),
+			MaxIdleConns:    l.integer("DB_MAX_IDLE_CONNS", 10, 0, 1000),
+			ConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute, time.Second, 24*time.Hour),
+			PoolWaitWarning: l.integer("DB_POOL_WAIT_WARNING", 50, 1, 1000000),
+
+			SlowQueryLog:       l.boolean("DB_SLOW_QUERY_LOG", false),
+			SlowQueryThreshold: l.duration("DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond, time.Millisecond, time.Minute),
+			SlowQueryExplain:   l.boolean("DB_SLOW_QUERY_EXPLAIN", false),
+		},
+		Sentry: SentryConfig{
+			DSN:         l.urlValue("SENTRY_DSN", "http", "https"),
+			Environment: l.optional("SENTRY_ENVIRONMENT", "production"),
+		},
+		JWT: JWTConfig{
+			Secret:    l.required("JWT_SECRET"),
+			PublicKey: l.required("JWT_KEY"),
+		},
+		Storage: StorageConfig{
+			Backend:       strings.ToLower(l.optional("STORAGE_BACKEND", StorageS3)),
+			Endpoint:      l.optional("MINIO_STORAGE_ENDPOINT", ""),
+			AccessKey:     l.optional("MINIO_STORAGE_ACCESS", ""),
+			SecretKey:     l.optional("MINIO_STORAGE_SECRET", ""),
+			Region:        l.optional("STORAGE_REGION", ""),
+			Secure:        l.boolean("STORAGE_SECURE", false),
+			LocalDir:      l.optional("STORAGE_LOCAL_DIR", "storage"),
+			SigningSecret: l.optional("STORAGE_SIGNING_SECRET", ""),
+		},
+		Geocoder: GeocoderConfig{
+			MapboxToken: l.optional("MAPBOX_TOKEN", ""),
+			GoogleToken: l.optional("GOOGLE_MAPS_API_KEY", ""),
+
+			Timeout:         l.duration("GEOCODER_TIMEOUT", 2*time.Second, 100*time.Millisecond, 30*time.Second),
+			BreakerFailures: l.integer("GEOCODER_BREAKER_FAILURES", 5, 1, 100),
+			BreakerCooldown: l.duration("GEOCODER_BREAKER_COOLDOWN", 30*time.Second, time.Second, time.Hour),
+		},
+		GeoIP: GeoIPConfig{
+			URL:        l.urlValue("GEOIP_URL", "http", "https"),
+			AccountID:  l.optional("GEOIP_ACCOUNT_ID", ""),
+			LicenseKey: l.optional("GEOIP_LICENSE_KEY", ""),
+		},
+		Travel: TravelTimeConfig{
+			Backend:  strings.ToLower(l.optional("TRAVEL_TIME_BACKEND", "")),
+			URL:      l.urlValue("TRAVEL_TIME_URL", "http", "https"),
+			CacheTTL: l.duration("TRAVEL_TIME_CACHE_TTL", time.Hour, time.Minute, 7*24*time.Hour),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   l.list("CORS_ALLOWED_ORIGINS"),
+			AllowedMethods:   l.listOr("CORS_ALLOWED_METHODS", "GET", "POST", "PUT", "PATCH", "DELETE"),
+			AllowedHeaders:   l.listOr("CORS_ALLOWED_HEADERS", "Authorization", "Content-Type", "If-None-Match", "If-Modified-Since", "X-Tenant", "X-Voting-Session", "X-Impersonation-Token"),
+			AllowCredentials: l.boolean("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           l.duration("CORS_MAX_AGE", 10*time.Minute, 0, 24*time.Hour),
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:   l.duration("HSTS_MAX_AGE", 180*24*time.Hour, 0, 2*365*24*time.Hour),
+			FrameOptions: strings.ToUpper(l.optional("FRAME_OPTIONS", "DENY")),
+		},
+		Push: PushConfig{
+			FCMServerKey:   l.optional("FCM_SERVER_KEY", ""),
+			APNsKey:        l.optional("APNS_KEY", ""),
+			APNsKeyID:      l.optional("APNS_KEY_ID", ""),
+			APNsTeamID:     l.optional("APNS_TEAM_ID", ""),
+			APNsTopic:      l.optional("APNS_TOPIC", ""),
+			APNsProduction: l.boolean("APNS_PRODUCTION", false),
+		},
+		Mail: MailConfig{
+			SMTPHost:     l.optional("SMTP_HOST", ""),
+			SMTPPort:     l.integer("SMTP_PORT", 587, 1, 65535),
+			SMTPUser:     l.optional("SMTP_USER", ""),
+			SMTPPassword: l.optional("SMTP_PASS", ""),
+			From:         l.optional("MAIL_FROM", ""),
+		},
+		ContentFilter: ContentFilterConfig{
+			BlockedWords:     l.list("CONTENT_FILTER_BLOCKED_WORDS"),
+			FlaggedWords:     l.list("CONTENT_FILTER_FLAGGED_WORDS"),
+			ModerationURL:    l.urlValue("CONTENT_MODERATION_URL", "http", "https"),
+			ModerationAPIKey: l.optional("CONTENT_MODERATION_API_KEY", ""),
+		},
+		Translation: TranslationConfig{
+			URL:    l.urlValue("TRANSLATION_API_URL", "http", "https"),
+			APIKey: l.optional("TRANSLATION_API_KEY", ""),
+		},
+		ReviewSummary: ReviewSummaryConfig{
+			URL:    l.urlValue("REVIEW_SUMMARY_API_URL", "http", "https"),
+			APIKey: l.optional("REVIEW_SUMMARY_API_KEY", ""),
+			Model:  l.optional("REVIEW_SUMMARY_MODEL", ""),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    l.urlValue("OTEL_EXPORTER_OTLP_ENDPOINT", "http", "https"),
+			ServiceName: l.optional("OTEL_SERVICE_NAME", "voting-app"),
+			SampleRatio: l.float("OTEL_TRACES_SAMPLE_RATIO", 1, 0, 1),
+		},
+		Anomalies: AnomalyConfig{
+			Threshold:    l.float("ANOMALY_ZSCORE_THRESHOLD", 3, 1, 10),
+			NotifyAdmins: l.boolean("ANOMALY_NOTIFY_ADMINS", false),
+		},
+		Digests: NotificationDigestConfig{
+			HelpfulWindow:  l.duration("NOTIFICATION_DIGEST_HELPFUL_WINDOW", 24*time.Hour, 0, 7*24*time.Hour),
+			FollowerWindow: l.duration("NOTIFICATION_DIGEST_FOLLOWER_WINDOW", time.Hour, 0, 7*24*time.Hour),
+		},
+		MaxBodyBytes:       l.integer("MAX_BODY_BYTES", 1<<20, 1024, 100<<20),
+		CompressMinBytes:   l.integer("COMPRESS_MIN_BYTES", 1024, 0, 1<<20),
+		CheckinDedupWindow: l.duration("CHECKIN_DEDUP_WINDOW", 2*time.Hour, 0, 24*time.Hour),
+		OwnerAlertInterval: l.duration("OWNER_ALERT_INTERVAL", 6*time.Hour, 0, 7*24*time.Hour),
+		WishlistWeight:     l.float("RECOMMENDATION_WISHLIST_WEIGHT", 0.3, 0, 1),
+		MaxReviewPhotos:    l.integer("MAX_REVIEW_PHOTOS", 10, 1, 50),
+		SiteBaseURL:        strings.TrimRight(l.urlValue("SITE_BASE_URL", "http", "https"), "/"),
+		VoteReceiptSecret:  l.required("VOTE_RECEIPT_SECRET"),
+		CampaignAuditKey:   l.required("CAMPAIGN_AUDIT_KEY"),
+		LegacyVotingSunset: l.boolean("LEGACY_VOTING_SUNSET", false),
+		TrustedProxies:     l.list("TRUSTED_PROXIES"),
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			if cfg.CORS.AllowCredentials {
+				l.problem("CORS_ALLOWED_ORIGINS must list the origins when CORS_ALLOW_CREDENTIALS is set")
+			}
+			continue
+		}
+		if parsed, err := url.Parse(origin); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+			parsed.Host == "" || strings.TrimPrefix(origin, parsed.Scheme+"://") != parsed.Host {
+			l.problem("CORS_ALLOWED_ORIGINS must hold origins like https://example.com, got %q", origin)
+		}
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			l.problem("TRUSTED_PROXIES must hold IP addresses or CIDR ranges, got %q", proxy)
+		}
+	}
+	if cfg.Security.FrameOptions != "DENY" && cfg.Security.FrameOptions != "SAMEORIGIN" {
+		l.problem("FRAME_OPTIONS must be DENY or SAMEORIGIN, got %q", cfg.Security.FrameOptions)
+	}
+
+	if cfg.SiteBaseURL == "" {
+		cfg.SiteBaseURL = "http://localhost:3000"
+	}
+	// Tokens are verified when they are used, a bad key would only show as
+	// every authenticated request failing
+	if cfg.JWT.PublicKey != "" {
+		if _, err := jwt.ParseEdPublicKeyFromPEM([]byte(cfg.JWT.PublicKey)); err != nil {
+			l.problem("JWT_KEY must be a PEM encoded Ed25519 public key: %v", err)
+		}
+	}
+	// Secrets are kept apart, so one leaking doesn't give away the others
+	if cfg.VoteReceiptSecret != "" && cfg.VoteReceiptSecret == cfg.JWT.Secret {
+		l.problem("VOTE_RECEIPT_SECRET must differ from JWT_SECRET")
+	}
+	if cfg.CampaignAuditKey != "" {
+		if _, err := ParseEd25519PrivateKey(cfg.CampaignAuditKey); err != nil {
+			l.problem("CAMPAIGN_AUDIT_KEY must be a PEM encoded Ed25519 private key: %v", err)
+		}
+	}
+	switch cfg.Storage.Backend {
+	case StorageLocal:
+		// Private files are served with links signed by the backend
+		if cfg.Storage.SigningSecret == "" {
+			l.problem("STORAGE_SIGNING_SECRET is required with the %s storage backend", StorageLocal)
+		} else if cfg.Storage.SigningSecret == cfg.JWT.Secret {
+			l.problem("STORAGE_SIGNING_SECRET must differ from JWT_SECRET")
+		}
+	case StorageGCS:
+		if cfg.Storage.Endpoint == "" {
+			cfg.Storage.Endpoint = "storage.googleapis.com"
+		}
+		cfg.Storage.Secure = true
+		fallthrough
+	case StorageS3:
+		// Object storage needs its endpoint and keys
+		for key, value := range map[string]string{
+			"MINIO_STORAGE_ENDPOINT": cfg.Storage.Endpoint,
+			"MINIO_STORAGE_ACCESS":   cfg.Storage.AccessKey,
+			"MINIO_STORAGE_SECRET":   cfg.Storage.SecretKey,
+		} {
+			if value == "" {
+				l.problem("%s is required", key)
+			}
+		}
+	default:
+		l.problem("STORAGE_BACKEND must be one of %s, %s or %s", StorageLocal, StorageS3, StorageGCS)
+	}
+	if cfg.ReviewSummary.URL != "" && cfg.ReviewSummary.Model == "" {
+		l.problem("REVIEW_SUMMARY_MODEL is required with REVIEW_SUMMARY_API_URL")
+	}
+	if cfg.GeoIP.URL == "" {
+		cfg.GeoIP.URL = "https://geolite.info/geoip/v2.1/city"
+	}
+	switch cfg.Travel.Backend {
+	case "":
+	case TravelTimeMapbox:
+		if cfg.Geocoder.MapboxToken == "" {
+			l.problem("MAPBOX_TOKEN is required with the %s travel time backend", TravelTimeMapbox)
+		}
+		if cfg.Travel.URL == "" {
+			cfg.Travel.URL = "https://api.mapbox.com/directions-matrix/v1/mapbox"
+		}
+	case TravelTimeOSRM:
+		if cfg.Travel.URL == "" {
+			l.problem("TRAVEL_TIME_URL is required with the %s travel time backend", TravelTimeOSRM)
+		}
+	default:
+		l.problem("TRAVEL_TIME_BACKEND must be %s or %s", TravelTimeMapbox, TravelTimeOSRM)
+	}
+
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		l.problem("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS (%d)", cfg.Database.MaxOpenConns)
+	}
+
+	// APNs needs the whole key set once enabled
+	if cfg.Push.APNsKey != "" {
+		for key, value := range map[string]string{
+			"APNS_KEY_ID":  cfg.Push.APNsKeyID,
+			"APNS_TEAM_ID": cfg.Push.APNsTeamID,
+			"APNS_TOPIC":   cfg.Push.APNsTopic,
+		} {
+			if value == "" {
+				l.problem("%s is required when APNS_KEY is set", key)
+			}
+		}
+	}
+
+	if cfg.GeoIP.LicenseKey != "" && cfg.GeoIP.AccountID == "" {
+		l.problem("GEOIP_ACCOUNT_ID is required when GEOIP_LICENSE_KEY is set")
+	}
+
+	if cfg.Mail.SMTPHost != "" && cfg.Mail.From == "" {
+		l.problem("MAIL_FROM is required when SMTP_HOST is set")
+	}
+
+	if len(l.problems) > 0 {
+		return nil, &ValidationError{Problems: l.sortedProblems()}
+	}
+	return cfg, nil
+}
+
+// loader reads environment variables, collecting problems instead of failing
+// on the first one
+type loader struct {
+	problems []string
+}
+
+func (l *loader) problem(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) required(key string) string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		l.problem("%s is required", key)
+	}
+	return value
+}
+
+func (l *loader) optional(key, defaultValue string) string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) integer(key string, defaultValue, min, max int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		l.problem("%s must be an integer between %d and %d, got %q", key, min, max, value)
+		return defaultValue
+	}
+	return number
+}
+
+func (l *loader) duration(key string, defaultValue, min, max time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < min || parsed > max {
+		l.problem("%s must be a duration between %s and %s, got %q", key, min, max, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) float(key string, defaultValue, min, max float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < min || number > max {
+		l.problem("%s must be a number between %g and %g, got %q", key, min, max, value)
+		return defaultValue
+	}
+	return number
+}
+
+func (l *loader) boolean(key string, defaultValue bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.problem("%s must be true or false, got %q", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+// list reads an optional comma separated list, dropping empty entries
+func (l *loader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// listOr reads an optional comma separated list, defaulting to defaultValues
+// when it's empty
+func (l *loader) listOr(key string, defaultValues ...string) []string {
+	if values := l.list(key); len(values) > 0 {
+		return values
+	}
+	return defaultValues
+}
+
+// urlValue reads an optional URL, checking its scheme when set
+func (l *loader) urlValue(key string, schemes ...string) string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		l.problem("%s must be a valid URL", key)
+		return ""
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return value
+		}
+	}
+	l.problem("%s must use one of the schemes: %s", key, strings.Join(schemes, ", "))
+	return ""
+}
+
+func (l *loader) sortedProblems() []string {
+	// Map iteration makes the APNs checks unordered, keep the report stable
+	problems := append([]string(nil), l.problems...)
+	sort.Strings(problems)
+	return problems
+}
+
+// ParseEd25519PrivateKey parses a PEM encoded PKCS #8 Ed25519 private key
+func ParseEd25519PrivateKey(data string) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return edKey, nil
+}
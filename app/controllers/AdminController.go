@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct{}
+
+// GetOverview returns the moderation queue sizes and moderator throughput (admin only)
+// @Summary      Get moderation overview
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.ModerationOverview
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/overview [get]
+func (AdminController) GetOverview(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view the moderation overview",
+		})
+		return
+	}
+
+	overview, err := models.GetModerationOverview(ctx.Request.Context(), time.Now().UTC())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get moderation overview",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, overview)
+}
+
+// GetSlowQueries lists the recent search and analytics statements that ran
+// longer than the slow query threshold (admin only)
+// @Summary      Get slow queries
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        hours   query     int     false  "How far back (default 24, max 720)"
+// @Param        source  query     string  false  "Only the statements of this function"
+// @Param        limit   query     int     false  "Number of results (default 50, max 200)"
+// @Success      200  {object}  serializers.SlowQueriesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/slow-queries [get]
+func (AdminController) GetSlowQueries(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view slow queries",
+		})
+		return
+	}
+
+	var query serializers.SlowQueriesQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	since := time.Now().UTC().Add(-time.Duration(query.Hours) * time.Hour)
+	slowQueries, err := models.GetSlowQueries(ctx.Request.Context(), since, query.Source, query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get slow queries",
+		})
+		return
+	}
+
+	settings := models.GetSlowQuerySettings()
+	ctx.JSON(http.StatusOK, serializers.SlowQueriesResponse{
+		Enabled:     settings.Enabled,
+		ThresholdMs: settings.Threshold.Milliseconds(),
+		Explain:     settings.Explain,
+		SlowQueries: slowQueries,
+	})
+}
+
+// ExportLegacyData downloads the mentors, participants or votings of the
+// legacy voting as CSV (admin only)
+// @Summary      Export legacy voting data
+// @Tags         admin
+// @Produce      text/csv
+// @Security     BearerAuth
+// @Param        dataset        path      string  true   "mentors, participants or voting"
+// @Success      200  {string}  string  "CSV file"
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/legacy/{dataset}/export [get]
+func (AdminController) ExportLegacyData(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can export legacy data",
+		})
+		return
+	}
+
+	dataset := ctx.Param("dataset")
+	var buf bytes.Buffer
+	legacyDataService := &services.LegacyDataService{}
+	err := legacyDataService.Export(ctx.Request.Context(), dataset, &buf)
+	if err == services.ErrUnknownDataset {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to export legacy data",
+		})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, dataset))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ImportLegacyData saves an edited CSV export of the legacy voting data.
+// Rows are validated first and nothing is saved when any is invalid. With
+// dry_run the changes are reported without saving them (admin only).
+// @Summary      Import legacy voting data
+// @Tags         admin
+// @Accept       text/csv
+// @Produce      json
+// @Security     BearerAuth
+// @Param        dataset        path      string  true   "mentors, participants or voting"
+// @Param        dry_run        query     boolean false  "Validate and count the changes without saving"
+// @Param        file           formData  file    false  "CSV file, unless sent as the body"
+// @Success      200  {object}  services.LegacyImportResult
+// @Failure      400  {object}  services.LegacyImportResult
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      413  {object}  serializers.Base
+// @Router       /admin/legacy/{dataset}/import [post]
+func (AdminController) ImportLegacyData(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can import legacy data",
+		})
+		return
+	}
+
+	dryRun := false
+	if dryRunStr := ctx.Query("dry_run"); dryRunStr != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "dry_run must be true or false",
+			})
+			return
+		}
+	}
+
+	data, ok := readImportFile(ctx, services.MaxLegacyImportBytes)
+	if !ok {
+		return
+	}
+
+	legacyDataService := &services.LegacyDataService{}
+	result, err := legacyDataService.Import(ctx.Request.Context(), ctx.Param("dataset"), bytes.NewReader(data), dryRun)
+	if err == services.ErrUnknownDataset {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to import legacy data",
+		})
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, result)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ImportExternalRatings imports venue ratings from other platforms from a
+// CSV file with the columns venue_id, source, rating, review_count and url.
+// Rows are validated first and nothing is saved when any is invalid. With
+// dry_run the ratings are checked without saving them (admin only).
+// @Summary      Import external ratings
+// @Tags         admin
+// @Accept       text/csv
+// @Produce      json
+// @Security     BearerAuth
+// @Param        dry_run        query     boolean false  "Validate the ratings without saving"
+// @Param        file           formData  file    false  "CSV file, unless sent as the body"
+// @Success      200  {object}  services.ExternalRatingImportResult
+// @Failure      400  {object}  services.ExternalRatingImportResult
+// @Failure      403  {object}  serializers.Base
+// @Failure      413  {object}  serializers.Base
+// @Router       /admin/external-ratings/import [post]
+func (AdminController) ImportExternalRatings(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can import external ratings",
+		})
+		return
+	}
+
+	dryRun := false
+	if dryRunStr := ctx.Query("dry_run"); dryRunStr != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "dry_run must be true or false",
+			})
+			return
+		}
+	}
+
+	data, ok := readImportFile(ctx, services.MaxExternalRatingImportBytes)
+	if !ok {
+		return
+	}
+
+	externalRatingService := &services.ExternalRatingService{}
+	result, err := externalRatingService.ImportCSV(ctx.Request.Context(), bytes.NewReader(data), ctx.GetInt64("user_id"), dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to import external ratings",
+		})
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, result)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// readImportFile reads an imported file of at most maxBytes, sent as the
+// body or as the file field of a form, writing the error response when it
+// can't be read
+func readImportFile(ctx *gin.Context, maxBytes int64) ([]byte, bool) {
+	// Leave room for the form encoding around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes+64<<10)
+
+	var body io.Reader = ctx.Request.Body
+	if mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "The file field is required",
+			})
+			return nil, false
+		}
+		opened, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Failed to read file",
+			})
+			return nil, false
+		}
+		defer opened.Close()
+		body = opened
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil || int64(len(data)) > maxBytes {
+		ctx.JSON(http.StatusRequestEntityTooLarge, serializers.Base{
+			Code:    serializers.PayloadTooLarge,
+			Message: fmt.Sprintf("Files must be at most %d MB", maxBytes>>20),
+		})
+		return nil, false
+	}
+	return data, true
+}
+
+// BulkModerateReviews approves, rejects or flags many reviews at once (admin
+// only). The batch is applied in one transaction and every changed review is
+// audited. Reviews that don't exist or already are in the state are
+// reported per review without failing the batch.
+// @Summary      Bulk moderate reviews
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      serializers.BulkModerationRequest  true  "Reviews and action"
+// @Success      200  {object}  serializers.BulkModerationResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/reviews/bulk [post]
+func (AdminController) BulkModerateReviews(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can moderate reviews",
+		})
+		return
+	}
+
+	var request serializers.BulkModerationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid moderation data",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	results, err := models.BulkModerateReviews(ctx.Request.Context(), ctx.GetInt64("user_id"),
+		request.ReviewIDs, request.Action, time.Now().UTC())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to moderate reviews",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.NewBulkModerationResponse(request.Action, results))
+}
+
+// GetReviewDuplicates returns the group of near-identical reviews the review
+// belongs to, its original and the reviews copying it (admin only)
+// @Summary      Get review duplicates
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Review ID"
+// @Success      200  {object}  serializers.ReviewDuplicatesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/reviews/{id}/duplicates [get]
+func (AdminController) GetReviewDuplicates(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view review duplicates",
+		})
+		return
+	}
+
+	reviewID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || reviewID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid review ID",
+		})
+		return
+	}
+
+	review := &models.VenueReview{ID: reviewID}
+	if err := review.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.ReviewNotFound,
+			Message: "Review not found",
+		})
+		return
+	}
+
+	originalID := review.ID
+	if review.DuplicateOf != nil {
+		originalID = *review.DuplicateOf
+	}
+	duplicates, err := models.GetReviewDuplicates(ctx.Request.Context(), originalID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get review duplicates",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.ReviewDuplicatesResponse{
+		OriginalID: originalID,
+		Duplicates: duplicates,
+	})
+}
+
+// Impersonate opens a read-only session seeing the app as the snapp user
+// does, for support (admin only). Requests sent with the returned token in
+// the X-Impersonation-Token header act as the user and are audited.
+// @Summary      Impersonate a snapp user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        snapp_id  path      string                          true  "User Snapp ID"
+// @Param        request   body      serializers.ImpersonateRequest  true  "Reason"
+// @Success      201  {object}  serializers.ImpersonationResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/impersonate/{snapp_id} [post]
+func (AdminController) Impersonate(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can impersonate users",
+		})
+		return
+	}
+
+	var request serializers.ImpersonateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "A reason of 3 to 500 characters is required",
+		})
+		return
+	}
+
+	snappUser := &models.SnappUser{SnappId: ctx.Param("snapp_id")}
+	exists, err := snappUser.GetUser(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get user",
+		})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.SnappIdDoesNotExists,
+			Message: "snapp_id does not exists",
+		})
+		return
+	}
+
+	impersonationService := &services.ImpersonationService{}
+	session, token, err := impersonationService.Start(ctx.Request.Context(), ctx.GetInt64("user_id"), snappUser, request.Reason)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to start impersonation",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.ImpersonationResponse{
+		Session: *session,
+		Token:   token,
+		Header:  services.ImpersonationHeader,
+	})
+}
+
+// GetImpersonationAudit returns the requests made in an impersonation
+// session, including the refused ones (admin only)
+// @Summary      Get impersonation audit
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Impersonation session ID"
+// @Success      200  {object}  serializers.ImpersonationAuditResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/impersonations/{id}/audit [get]
+func (AdminController) GetImpersonationAudit(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view impersonation audits",
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid impersonation session ID",
+		})
+		return
+	}
+
+	entries, err := models.GetImpersonationAudit(ctx.Request.Context(), sessionID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get impersonation audit",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.ImpersonationAuditResponse{
+		SessionID: sessionID,
+		Requests:  entries,
+	})
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsController struct{}
+
+// venueCompetitorsLimit is how many competitors the admin scope lists
+const venueCompetitorsLimit = 10
+
+// GetVenueAnalytics returns the analytics of a venue to its owner or an admin.
+// Owners get the owner scope, which ranks only their own venue; admins also
+// get the venues it competes with. Owners on the free tier get the basic
+// analytics level.
+// @Summary      Get venue analytics
+// @Tags         analytics
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path      int     true   "Venue ID"
+// @Param        time_range  query     string  false  "today, yesterday, week, month, quarter or year"  default(week)
+// @Success      200  {object}  services.VenueAnalytics
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /analytics/venues/{id} [get]
+func (AnalyticsController) GetVenueAnalytics(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var query serializers.VenueAnalyticsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	analyticsService := &services.AnalyticsService{}
+	analytics, err := analyticsService.GetVenueAnalytics(ctx.Request.Context(), venue.ID, query.TimeRange)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get venue analytics",
+		})
+		return
+	}
+
+	planService := &services.OwnerPlanService{}
+	analytics.Plan, err = planService.GetVenuePlan(ctx.Request.Context(), venue)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get owner plan",
+		})
+		return
+	}
+
+	analytics.Level = services.AnalyticsFull
+	if analytics.Plan.Limits.Analytics == services.AnalyticsBasic && !ctx.GetBool("is_superuser") {
+		analytics.LimitToBasic()
+	}
+
+	analytics.Scope = services.AnalyticsScopeOwner
+	if ctx.GetBool("is_superuser") {
+		analytics.Scope = services.AnalyticsScopeAdmin
+		analytics.Competitors, err = analyticsService.GetVenueCompetitors(ctx.Request.Context(), venue.ID, venueCompetitorsLimit)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get venue competitors",
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, analytics)
+}
+
+// GetWatchlistComparison compares the weekly views, ratings and review
+// volume of the venue with the competitors on its watchlist
+// @Summary      Compare venue with watchlist
+// @Tags         analytics
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int  true   "Venue ID"
+// @Param        weeks  query     int  false  "Weeks compared, the current one included"  default(8)
+// @Success      200  {object}  services.WatchlistComparison
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /analytics/venues/{id}/watchlist [get]
+func (AnalyticsController) GetWatchlistComparison(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var query serializers.WatchlistComparisonQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	analyticsService := &services.AnalyticsService{}
+	comparison, err := analyticsService.GetWatchlistComparison(ctx.Request.Context(), venue, query.Weeks)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to compare the venue with its watchlist",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comparison)
+}
+
+// GetMetricAlerts lists the days on which daily reviews, votes or signups
+// deviated sharply from the weeks before
+// @Summary      List metric alerts
+// @Tags         analytics
+// @Produce      json
+// @Security     BearerAuth
+// @Param        days    query     int     false  "Days to look back"  default(30)
+// @Param        metric  query     string  false  "reviews, votes or signups"
+// @Success      200  {object}  serializers.MetricAlertsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /analytics/alerts [get]
+func (AnalyticsController) GetMetricAlerts(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view metric alerts",
+		})
+		return
+	}
+
+	var query serializers.MetricAlertsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	analyticsService := &services.AnalyticsService{}
+	alerts, err := analyticsService.GetMetricAlerts(ctx.Request.Context(), query.Days, query.Metric)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get metric alerts",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.MetricAlertsResponse{Alerts: alerts})
+}
+
+// TrackEvents stores a batch of events reported by a client app. Events of
+// unknown types or outside the accepted time window are left out.
+// @Summary      Report client events
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        request  body      serializers.ClientEventsRequest  true  "Batch of events"
+// @Success      202  {object}  serializers.ClientEventsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /analytics/events [post]
+func (AnalyticsController) TrackEvents(ctx *gin.Context) {
+	var request serializers.ClientEventsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid events",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	events := request.ToEvents(time.Now())
+	if len(events) > 0 {
+		if err := models.InsertClientEvents(ctx.Request.Context(), events); err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to store events",
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, serializers.ClientEventsResponse{
+		Accepted: len(events),
+		Rejected: len(request.Events) - len(events),
+	})
+}
+
+// TrackVenueEvents counts a batch of venue interactions reported by a client
+// app towards the venues' daily analytics. Events are deduplicated by their
+// client and event IDs, so clients can safely resend a batch.
+// @Summary      Report venue interactions
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        request  body      serializers.VenueEventsRequest  true  "Batch of venue events"
+// @Success      202  {object}  serializers.VenueEventsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /analytics/track [post]
+func (AnalyticsController) TrackVenueEvents(ctx *gin.Context) {
+	var request serializers.VenueEventsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid events",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	var result models.VenueEventsResult
+	events := request.ToEvents(time.Now())
+	if len(events) > 0 {
+		var err error
+		result, err = models.RecordVenueEvents(ctx.Request.Context(), events)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to record events",
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, serializers.VenueEventsResponse{
+		Accepted:   result.Recorded,
+		Duplicates: result.Duplicates,
+		Rejected:   len(request.Events) - result.Recorded - result.Duplicates,
+	})
+}
+
+// GetEngagement returns session duration, pages per session, bounce rate and
+// returning client rate, computed from the sessionized client events
+// @Summary      Get engagement metrics
+// @Tags         analytics
+// @Produce      json
+// @Security     BearerAuth
+// @Param        time_range  query     string  false  "today, yesterday, week, month, quarter or year"  default(week)
+// @Success      200  {object}  services.UserEngagementMetrics
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /analytics/engagement [get]
+func (AnalyticsController) GetEngagement(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view engagement metrics",
+		})
+		return
+	}
+
+	var query serializers.EngagementQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	analyticsService := &services.AnalyticsService{}
+	metrics, err := analyticsService.GetEngagementMetrics(ctx.Request.Context(), query.TimeRange)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get engagement metrics",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, metrics)
+}
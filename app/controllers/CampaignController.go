@@ -0,0 +1,2282 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CampaignController struct{}
+
+// SubmitCampaignVote votes for a venue in a campaign and returns a signed receipt.
+// Campaigns requiring one-time codes hold the vote instead, answering 202,
+// until it is confirmed through verify-otp.
+// @Summary      Submit campaign vote
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        vote           body      serializers.SubmitCampaignVoteRequest  true  "Vote data"
+// @Success      201  {object}  serializers.SubmitCampaignVoteResponse
+// @Success      202  {object}  serializers.HeldCampaignVoteResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/vote [post]
+func (CampaignController) SubmitCampaignVote(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	submitCampaignVote(ctx, campaign, campaignVoter{userID: ctx.GetInt64("snappUser_id")}, nil)
+}
+
+// SubmitSessionCampaignVote votes for a venue in a campaign through an
+// anonymous kiosk voting session, sent in the X-Voting-Session header
+// @Summary      Submit campaign vote with a voting session
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        id                 path      int     true   "Campaign ID"
+// @Param        X-Voting-Session   header    string  true   "Voting session token"
+// @Param        vote               body      serializers.SubmitCampaignVoteRequest  true  "Vote data"
+// @Success      201  {object}  serializers.SubmitCampaignVoteResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      401  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /campaigns/{id}/vote [post]
+func (CampaignController) SubmitSessionCampaignVote(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	sessionService := &services.VotingSessionService{}
+	session, err := sessionService.Get(ctx.Request.Context(), ctx.GetHeader("X-Voting-Session"))
+	if err == services.ErrInvalidVotingSession || (err == nil && session.CampaignID != campaign.ID) {
+		ctx.JSON(http.StatusUnauthorized, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: "Voting session is invalid or expired",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to submit vote",
+		})
+		return
+	}
+
+	submitCampaignVote(ctx, campaign, campaignVoter{session: session}, nil)
+}
+
+// VerifyCampaignVote casts the vote held for the user in a campaign
+// requiring one-time codes, once given the code emailed to them. The vote is
+// checked again against the campaign rules when cast.
+// @Summary      Confirm held campaign vote
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        code           body      serializers.VerifyCampaignVoteRequest  true  "One-time code"
+// @Success      201  {object}  serializers.SubmitCampaignVoteResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/verify-otp [post]
+func (CampaignController) VerifyCampaignVote(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.VerifyCampaignVoteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.BindingError(err))
+		return
+	}
+
+	voter := campaignVoter{userID: ctx.GetInt64("snappUser_id"), verified: true}
+	verificationService := &services.VoteVerificationService{}
+	held, err := verificationService.Confirm(ctx.Request.Context(), campaign.ID, voter.userID, request.Code)
+	if err == models.ErrInvalidVoteCode {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: "Verification code is invalid or expired",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to verify vote",
+		})
+		return
+	}
+
+	var vote serializers.SubmitCampaignVoteRequest
+	if err := json.Unmarshal(held, &vote); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to verify vote",
+		})
+		return
+	}
+	submitCampaignVote(ctx, campaign, voter, &vote)
+}
+
+// submitCampaignVote checks the vote against the campaign rules and the
+// voter's limits, then records it, or holds it until the voter confirms it
+// in campaigns requiring one-time codes. The request is read from the body
+// unless given, as held votes are when confirmed.
+func submitCampaignVote(ctx *gin.Context, campaign *models.VotingCampaign, voter campaignVoter, request *serializers.SubmitCampaignVoteRequest) {
+	if !campaign.IsOpen(time.Now().UTC()) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.CampaignClosed,
+			Message: "Campaign is not open for voting",
+		})
+		return
+	}
+
+	if request == nil {
+		request = &serializers.SubmitCampaignVoteRequest{}
+		if err := ctx.ShouldBindJSON(request); err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Invalid vote data",
+			})
+			return
+		}
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	// Voting sessions have no credit balance
+	if voter.session != nil && campaign.IsQuadratic() {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Quadratic campaigns can't be voted in with a voting session",
+		})
+		return
+	}
+	// nor a contact to send codes to
+	if voter.session != nil && campaign.RequireOTP {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "This campaign can't be voted in with a voting session",
+		})
+		return
+	}
+
+	venue, category, ok := loadVoteVenue(ctx, campaign, voter, request.VenueID, request.CategoryID)
+	if !ok {
+		return
+	}
+
+	if !campaign.IsQuadratic() && request.Votes > 1 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "This campaign allows a single vote per venue",
+		})
+		return
+	}
+
+	// Quadratic campaigns are limited by the credit budget instead. The vote
+	// limit applies per category in campaigns with categories.
+	var votesCast int
+	var err error
+	if !campaign.IsQuadratic() {
+		votesCast, err = voter.countVotes(ctx.Request.Context(), campaign.ID, category)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to submit vote",
+			})
+			return
+		}
+		if votesCast >= campaign.MaxVotesPerUser {
+			message := "You have used all your votes in this campaign"
+			if category != nil {
+				message = "You have used all your votes in this category"
+			}
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.AlreadyVoted,
+				Message: message,
+			})
+			return
+		}
+	}
+
+	if category != nil && !campaign.AllowMultipleCategories {
+		votedCategoryIDs, err := voter.votedCategoryIDs(ctx.Request.Context(), campaign.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to submit vote",
+			})
+			return
+		}
+		for _, votedCategoryID := range votedCategoryIDs {
+			if votedCategoryID != category.ID {
+				ctx.JSON(http.StatusBadRequest, serializers.Base{
+					Code:    serializers.AlreadyVoted,
+					Message: "This campaign allows voting in a single category",
+				})
+				return
+			}
+		}
+	}
+
+	if voter.session != nil {
+		err := new(services.VotingSessionService).CheckDeviceLimit(ctx.Request.Context(), voter.session)
+		if err == services.ErrKioskDeviceLimit {
+			ctx.JSON(http.StatusTooManyRequests, serializers.Base{
+				Code:    serializers.TooManyRequests,
+				Message: "This kiosk has reached its hourly vote limit",
+			})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to submit vote",
+			})
+			return
+		}
+	}
+
+	// Screen the reason, flagged ones wait for a moderator before they can
+	// be quoted
+	var check services.ContentCheck
+	if request.Reason != "" {
+		contentFilter := &services.ContentFilterService{}
+		check = contentFilter.Check(ctx.Request.Context(), request.Reason)
+		if check.Verdict == services.ContentRejected {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.ContentRejected,
+				Message: "Reason contains language that is not allowed",
+			})
+			return
+		}
+	}
+
+	if campaign.RequireOTP && !voter.verified {
+		holdCampaignVote(ctx, campaign, voter, request)
+		return
+	}
+
+	vote := request.ToCampaignVote(campaign.ID, voter.userID)
+	if check.Verdict == services.ContentFlagged {
+		vote.ReasonStatus = models.ReasonPending
+		vote.IsFlagged = true
+	}
+	if voter.session != nil {
+		vote.VotingSessionID = &voter.session.ID
+	}
+	if campaign.IsQuadratic() {
+		vote.CreditsSpent = models.QuadraticCost(vote.Votes)
+		vote.CreditBudget = campaign.CreditBudget
+	} else {
+		// Counted again while the vote is stored, concurrent votes could
+		// both pass the count above
+		vote.MaxVotes = campaign.MaxVotesPerUser
+	}
+	vote.SingleCategory = category != nil && !campaign.AllowMultipleCategories
+
+	err = vote.Create(ctx.Request.Context())
+	if err == models.ErrCampaignVoteExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyVoted,
+			Message: "You already voted for this venue",
+		})
+		return
+	}
+	if err == models.ErrVoteLimitReached {
+		message := "You have used all your votes in this campaign"
+		if category != nil {
+			message = "You have used all your votes in this category"
+		}
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyVoted,
+			Message: message,
+		})
+		return
+	}
+	if err == models.ErrOtherCategoryVoted {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyVoted,
+			Message: "This campaign allows voting in a single category",
+		})
+		return
+	}
+	if err == models.ErrInsufficientCredits {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InsufficientCredits,
+			Message: fmt.Sprintf("%d votes cost %d credits, more than you have left", vote.Votes, vote.CreditsSpent),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to submit vote",
+		})
+		return
+	}
+	vote.VenueName = venue.Name
+
+	receiptService := &services.VoteReceiptService{}
+	response := serializers.SubmitCampaignVoteResponse{
+		Vote:           *vote,
+		Receipt:        *receiptService.CampaignReceipt(vote),
+		RemainingVotes: campaign.MaxVotesPerUser - votesCast - 1,
+	}
+
+	if campaign.IsQuadratic() {
+		balance, err := models.GetCampaignCreditBalance(ctx.Request.Context(), campaign, voter.userID)
+		if err == nil {
+			response.RemainingCredits = &balance.Remaining
+			response.RemainingVotes = int(math.Sqrt(float64(balance.Remaining)))
+		}
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// holdCampaignVote keeps the vote until the voter confirms it with the code
+// emailed to them
+func holdCampaignVote(ctx *gin.Context, campaign *models.VotingCampaign, voter campaignVoter, request *serializers.SubmitCampaignVoteRequest) {
+	vote, err := json.Marshal(request)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to submit vote",
+		})
+		return
+	}
+
+	verificationService := &services.VoteVerificationService{}
+	held, err := verificationService.Hold(ctx.Request.Context(), campaign, voter.userID, vote)
+	if err == services.ErrNoVerifiedContact {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.EmailNotVerified,
+			Message: "Link an account with a verified email to vote in this campaign",
+		})
+		return
+	}
+	if err == models.ErrVoteCodeResendTooSoon {
+		ctx.Header("Retry-After", strconv.Itoa(int(models.VoteCodeResendInterval.Seconds())))
+		ctx.JSON(http.StatusTooManyRequests, serializers.Base{
+			Code:    serializers.TooManyRequests,
+			Message: "A verification code was just sent, wait a minute before asking for another",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to send the verification code",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, serializers.HeldCampaignVoteResponse{
+		Status:    serializers.VotePendingVerification,
+		SentTo:    held.SentTo,
+		ExpiresAt: held.ExpiresAt,
+	})
+}
+
+// CreateVotingSession opens an anonymous voting session for a kiosk at an
+// event. Campaigns must allow kiosk sessions and the kiosk must hold a
+// credential an administrator issued for the campaign. The session votes in
+// the campaign until it expires, with the vote limit of a user, and every
+// kiosk has an hourly limit.
+// @Summary      Create kiosk voting session
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        session        body      serializers.VotingSessionRequest  true  "Campaign and kiosk credential"
+// @Success      201  {object}  serializers.VotingSessionResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      401  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      429  {object}  serializers.Base
+// @Router       /vote/sessions [post]
+func (CampaignController) CreateVotingSession(ctx *gin.Context) {
+	var request serializers.VotingSessionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid voting session data",
+		})
+		return
+	}
+
+	campaign := &models.VotingCampaign{ID: request.CampaignID}
+	if err := campaign.GetByID(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Campaign not found",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get campaign",
+			})
+		}
+		return
+	}
+
+	if !campaign.AllowKioskSessions {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "This campaign doesn't take votes from kiosks",
+		})
+		return
+	}
+	if !campaign.IsOpen(time.Now().UTC()) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.CampaignClosed,
+			Message: "Campaign is not open for voting",
+		})
+		return
+	}
+	if campaign.IsQuadratic() || campaign.RequireReview || campaign.RequireOTP {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "This campaign can't be voted in anonymously",
+		})
+		return
+	}
+
+	sessionService := &services.VotingSessionService{}
+	session, token, err := sessionService.Create(ctx.Request.Context(), campaign.ID, request.KioskToken)
+	switch {
+	case err == services.ErrInvalidKiosk:
+		ctx.JSON(http.StatusUnauthorized, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: "Kiosk credential is invalid or revoked",
+		})
+	case err == services.ErrKioskDeviceLimit:
+		ctx.JSON(http.StatusTooManyRequests, serializers.Base{
+			Code:    serializers.TooManyRequests,
+			Message: "This kiosk has opened too many voting sessions, try again later",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create voting session",
+		})
+	default:
+		ctx.JSON(http.StatusCreated, serializers.VotingSessionResponse{
+			Token:    token,
+			Session:  *session,
+			MaxVotes: campaign.MaxVotesPerUser,
+		})
+	}
+}
+
+// CreateKioskDevice issues a credential letting an event kiosk open
+// anonymous voting sessions in the campaign (admin only). The credential is
+// only returned here.
+// @Summary      Issue kiosk credential
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        kiosk          body      serializers.KioskDeviceRequest  true  "Kiosk name"
+// @Success      201  {object}  serializers.KioskDeviceResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/kiosks [post]
+func (CampaignController) CreateKioskDevice(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can issue kiosk credentials",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.KioskDeviceRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.BindingError(err))
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "name is required",
+		})
+		return
+	}
+	if !campaign.AllowKioskSessions {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "This campaign doesn't take votes from kiosks",
+		})
+		return
+	}
+
+	sessionService := &services.VotingSessionService{}
+	kiosk, token, err := sessionService.IssueKiosk(ctx.Request.Context(), campaign.ID, name, ctx.GetInt64("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to issue kiosk credential",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.KioskDeviceResponse{Kiosk: *kiosk, Token: token})
+}
+
+// GetKioskDevices lists the kiosks issued a credential for the campaign
+// (admin only)
+// @Summary      Get campaign kiosks
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  serializers.KioskDevicesResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/kiosks [get]
+func (CampaignController) GetKioskDevices(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage kiosks",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	kiosks, err := models.GetCampaignKioskDevices(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get kiosks",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.KioskDevicesResponse{CampaignID: campaign.ID, Kiosks: kiosks})
+}
+
+// RevokeKioskDevice revokes a kiosk's credential, its sessions can't vote
+// anymore (admin only)
+// @Summary      Revoke kiosk credential
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        kiosk_id       path      int     true   "Kiosk ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/kiosks/{kiosk_id} [delete]
+func (CampaignController) RevokeKioskDevice(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage kiosks",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	kioskID, err := strconv.ParseInt(ctx.Param("kiosk_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid kiosk ID",
+		})
+		return
+	}
+
+	revoked, err := models.RevokeKioskDevice(ctx.Request.Context(), campaign.ID, kioskID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to revoke kiosk",
+		})
+		return
+	}
+	if !revoked {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Kiosk not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Kiosk revoked",
+	})
+}
+
+// GetCreditBalance returns the user's credit balance in a quadratic campaign
+// @Summary      Get campaign credit balance
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  models.CampaignCreditBalance
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/credits [get]
+func (CampaignController) GetCreditBalance(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	if !campaign.IsQuadratic() {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "This campaign does not use voting credits",
+		})
+		return
+	}
+
+	balance, err := models.GetCampaignCreditBalance(ctx.Request.Context(), campaign, ctx.GetInt64("snappUser_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get credit balance",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+// GetVoteReceipts returns the signed receipts of the user's votes in a campaign
+// @Summary      Get campaign vote receipts
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.CampaignReceiptsResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/receipts [get]
+func (CampaignController) GetVoteReceipts(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	receiptService := &services.VoteReceiptService{}
+	receipts, err := receiptService.UserCampaignReceipts(ctx.Request.Context(), campaign.ID, ctx.GetInt64("snappUser_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get vote receipts",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignReceiptsResponse{
+		CampaignID: campaign.ID,
+		Receipts:   receipts,
+	})
+}
+
+// GetUserVotes returns the user's votes in a campaign with the venues voted
+// for, the votes left and whether they can still be changed
+// @Summary      Get user's campaign votes
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.CampaignVoteHistoryResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/votes [get]
+func (CampaignController) GetUserVotes(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetInt64("snappUser_id")
+	votes, err := models.GetUserCampaignVoteHistory(ctx.Request.Context(), campaign.ID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get votes",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	response := serializers.CampaignVoteHistoryResponse{
+		CampaignID:      campaign.ID,
+		Votes:           votes,
+		VotesUsed:       len(votes),
+		MaxVotesPerUser: campaign.MaxVotesPerUser,
+		RemainingVotes:  campaign.MaxVotesPerUser - len(votes),
+		CanChangeVotes:  campaign.IsOpen(now) && campaign.VotesChangeable(),
+		ChangeDeadline:  campaign.EndDate,
+	}
+	if response.RemainingVotes < 0 {
+		response.RemainingVotes = 0
+	}
+	for i := range votes {
+		if campaign.CanChangeVote(votes[i].CreatedAt, now) {
+			deadline := campaign.VoteChangeDeadline(votes[i].CreatedAt)
+			votes[i].ChangeableUntil = &deadline
+		}
+	}
+
+	if campaign.IsQuadratic() {
+		balance, err := models.GetCampaignCreditBalance(ctx.Request.Context(), campaign, userID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get votes",
+			})
+			return
+		}
+		response.RemainingCredits = &balance.Remaining
+		response.RemainingVotes = int(math.Sqrt(float64(balance.Remaining)))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ChangeCampaignVote moves one of the user's votes to another venue of its
+// category, while the campaign lets votes be changed. The vote keeps its
+// cast time, and the change is kept in its history.
+// @Summary      Change campaign vote
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        vote_id        path      int     true   "Vote ID"
+// @Param        vote           body      serializers.ChangeCampaignVoteRequest  true  "New venue"
+// @Success      200  {object}  serializers.ChangeCampaignVoteResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/{snapp_id}/votes/{vote_id} [put]
+func (CampaignController) ChangeCampaignVote(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	// A change would move a vote confirmed with a one-time code without one
+	if campaign.RequireOTP {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.VoteFinal,
+			Message: "Votes of campaigns confirmed with a code can't be changed",
+		})
+		return
+	}
+
+	voteID, err := strconv.ParseInt(ctx.Param("vote_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid vote ID",
+		})
+		return
+	}
+
+	voter := campaignVoter{userID: ctx.GetInt64("snappUser_id")}
+	vote := &models.CampaignVote{ID: voteID, CampaignID: campaign.ID, UserID: voter.userID}
+	if err := vote.GetUserVote(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Vote not found",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to change vote",
+			})
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	if !campaign.IsOpen(now) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.CampaignClosed,
+			Message: "Campaign is not open for voting",
+		})
+		return
+	}
+	if !campaign.CanChangeVote(vote.CreatedAt, now) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.VoteFinal,
+			Message: "This vote can no longer be changed",
+		})
+		return
+	}
+
+	var request serializers.ChangeCampaignVoteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid vote data",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+	if request.VenueID == vote.VenueID {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The vote already goes to this venue",
+		})
+		return
+	}
+
+	venue, _, ok := loadVoteVenue(ctx, campaign, voter, request.VenueID, vote.CategoryID)
+	if !ok {
+		return
+	}
+
+	vote.Reason, vote.ReasonStatus, vote.IsFlagged = request.Reason, "", false
+	if request.Reason != "" {
+		contentFilter := &services.ContentFilterService{}
+		check := contentFilter.Check(ctx.Request.Context(), request.Reason)
+		if check.Verdict == services.ContentRejected {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.ContentRejected,
+				Message: "Reason contains language that is not allowed",
+			})
+			return
+		}
+		if check.Verdict == services.ContentFlagged {
+			vote.ReasonStatus = models.ReasonPending
+			vote.IsFlagged = true
+		}
+	}
+
+	_, err = vote.ChangeVenue(ctx.Request.Context(), venue.ID)
+	if err == models.ErrCampaignVoteExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyVoted,
+			Message: "You already voted for this venue",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to change vote",
+		})
+		return
+	}
+	vote.VenueName = venue.Name
+	deadline := campaign.VoteChangeDeadline(vote.CreatedAt)
+	vote.ChangeableUntil = &deadline
+
+	changes, err := models.GetVoteChanges(ctx.Request.Context(), vote.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to change vote",
+		})
+		return
+	}
+
+	receiptService := &services.VoteReceiptService{}
+	ctx.JSON(http.StatusOK, serializers.ChangeCampaignVoteResponse{
+		Vote:    *vote,
+		Receipt: *receiptService.CampaignReceipt(vote),
+		Changes: changes,
+	})
+}
+
+// VerifyReceipt checks the signature of a vote receipt
+// @Summary      Verify vote receipt
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        receipt        body      serializers.VerifyReceiptRequest  true  "Vote receipt"
+// @Success      200  {object}  serializers.VerifyReceiptResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /receipts/verify [post]
+func (CampaignController) VerifyReceipt(ctx *gin.Context) {
+	var request serializers.VerifyReceiptRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid receipt data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	receiptService := &services.VoteReceiptService{}
+	if !receiptService.Verify(&request.VoteReceipt) {
+		ctx.JSON(http.StatusOK, serializers.VerifyReceiptResponse{
+			Valid:   false,
+			Message: "Receipt signature is invalid",
+		})
+		return
+	}
+
+	recorded, err := receiptService.IsRecorded(ctx.Request.Context(), &request.VoteReceipt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to verify receipt",
+		})
+		return
+	}
+
+	message := "Receipt is valid and the vote is recorded"
+	if !recorded {
+		message = "Receipt is valid but the vote is no longer recorded"
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VerifyReceiptResponse{
+		Valid:    true,
+		Recorded: recorded,
+		Message:  message,
+	})
+}
+
+// CreateCampaignCategory adds a category to a campaign (admin only)
+// @Summary      Create campaign category
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        category       body      serializers.CampaignCategoryRequest  true  "Category data"
+// @Success      201  {object}  models.CampaignCategory
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/categories [post]
+func (CampaignController) CreateCampaignCategory(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign categories",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.CampaignCategoryRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid category data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	category := request.ToCategory(campaign.ID)
+	err := category.Create(ctx.Request.Context())
+	if err == models.ErrCampaignCategoryExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Campaign already has a category with this name",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create category",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+// CreateCampaignPromotion schedules a campaign to be featured for a date range (admin only)
+// @Summary      Schedule campaign promotion
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        promotion      body      serializers.CampaignPromotionRequest  true  "Promotion data"
+// @Success      201  {object}  models.CampaignPromotion
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/promotions [post]
+func (CampaignController) CreateCampaignPromotion(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign promotions",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.CampaignPromotionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid promotion data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate(time.Now())
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	promotion := request.ToPromotion(campaign.ID, ctx.GetInt64("user_id"))
+	err := promotion.Create(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Campaign not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to schedule promotion",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, promotion)
+}
+
+// GetCampaignPromotions returns the promotion calendar of the coming days (admin only)
+// @Summary      Get campaign promotion calendar
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        days           query     int     false  "Days covered from now (default 30, max 366)"
+// @Success      200  {object}  serializers.CampaignPromotionsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/campaign-promotions [get]
+func (CampaignController) GetCampaignPromotions(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign promotions",
+		})
+		return
+	}
+
+	var query serializers.CampaignPromotionsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	from := time.Now()
+	to := from.AddDate(0, 0, query.Days)
+	promotions, err := models.GetCampaignPromotions(ctx.Request.Context(), from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign promotions",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignPromotionsResponse{
+		From:       from,
+		To:         to,
+		Promotions: promotions,
+	})
+}
+
+// DeleteCampaignPromotion removes a promotion from the calendar (admin only)
+// @Summary      Delete campaign promotion
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        promotion_id   path      int     true   "Promotion ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaign-promotions/{promotion_id} [delete]
+func (CampaignController) DeleteCampaignPromotion(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign promotions",
+		})
+		return
+	}
+
+	promotionID, err := strconv.ParseInt(ctx.Param("promotion_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid promotion ID",
+		})
+		return
+	}
+
+	promotion := &models.CampaignPromotion{ID: promotionID}
+	err = promotion.Delete(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Promotion not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete promotion",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Promotion deleted",
+	})
+}
+
+// GetFeaturedCampaigns returns the campaigns featured right now, highest priority first
+// @Summary      Get featured campaigns
+// @Tags         campaigns
+// @Produce      json
+// @Success      200  {array}   models.FeaturedCampaign
+// @Router       /campaigns/featured [get]
+func (CampaignController) GetFeaturedCampaigns(ctx *gin.Context) {
+	featured, err := models.GetFeaturedCampaigns(ctx.Request.Context(), time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get featured campaigns",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, featured)
+}
+
+// GetCampaignResults returns the standings and winner of every category of a campaign.
+// Campaigns hiding their results until they end only show the participation
+// totals to non-administrators while voting runs. Administrators are
+// recognized by the optional JWT.
+// @Summary      Get campaign results
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  models.CampaignResults
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaign-results/{id} [get]
+func (CampaignController) GetCampaignResults(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	fields, ok := bindFields(ctx, serializers.CampaignResultsFields)
+	if !ok {
+		return
+	}
+
+	results, err := models.GetCampaignResults(ctx.Request.Context(), campaign)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign results",
+		})
+		return
+	}
+	results.IsFinal = campaign.ResultsFinalizedAt != nil
+	if campaign.ResultsHidden(time.Now()) && !ctx.GetBool("is_superuser") {
+		results.HideStandings(campaign.EndDate)
+	}
+
+	body, err := fields.Filter(results)
+	respondPartial(ctx, body, err)
+}
+
+// GetCampaignSnapshots returns the stored results snapshots of a campaign.
+// Snapshots of campaigns hiding their results are only shown to
+// administrators until the campaign ends.
+// @Summary      Get campaign results snapshots
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        limit          query     int     false  "Number of snapshots, at most 100" default(24)
+// @Success      200  {object}  serializers.CampaignSnapshotsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaign-results/{id}/snapshots [get]
+func (CampaignController) GetCampaignSnapshots(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	if campaign.ResultsHidden(time.Now()) && !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "The results of this campaign are hidden until voting ends",
+		})
+		return
+	}
+
+	var query serializers.CampaignSnapshotsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	snapshots, err := models.GetCampaignSnapshots(ctx.Request.Context(), campaign.ID, query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign snapshots",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignSnapshotsResponse{
+		CampaignID: campaign.ID,
+		Snapshots:  snapshots,
+	})
+}
+
+// GetCampaignAudit downloads the signed certificate of a campaign's final
+// results: the per venue tallies, the excluded votes and the hash of the
+// counted votes. The Ed25519 signature covers the exact certificate bytes and
+// is verified with the key of GET /campaigns/audit-key.
+// @Summary      Download campaign audit file
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  models.CampaignAuditFile
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/audit [get]
+func (CampaignController) GetCampaignAudit(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	auditService := &services.CampaignAuditService{}
+	file, err := auditService.GetAudit(ctx.Request.Context(), campaign)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Campaign results are not certified yet",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign audit",
+		})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign-%d-audit.json"`, campaign.ID))
+	ctx.JSON(http.StatusOK, file)
+}
+
+// GetCampaignAuditKey returns the public key campaign audit certificates are
+// verified with
+// @Summary      Get campaign audit key
+// @Tags         campaigns
+// @Produce      json
+// @Success      200  {object}  serializers.CampaignAuditKeyResponse
+// @Router       /campaigns/audit-key [get]
+func (CampaignController) GetCampaignAuditKey(ctx *gin.Context) {
+	auditService := &services.CampaignAuditService{}
+	ctx.JSON(http.StatusOK, serializers.CampaignAuditKeyResponse{
+		Algorithm: services.AuditSignatureAlgorithm,
+		PublicKey: auditService.PublicKey(),
+	})
+}
+
+// CreateEmbedToken issues a signed, expiring token letting the pages of the
+// given origins embed the campaign's results widget (admin only)
+// @Summary      Issue campaign embed token
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        embed          body      serializers.CampaignEmbedTokenRequest  true  "Allowed origins and expiry"
+// @Success      201  {object}  serializers.CampaignEmbedTokenResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/embed-tokens [post]
+func (CampaignController) CreateEmbedToken(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can issue embed tokens",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.CampaignEmbedTokenRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.BindingError(err))
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	embedService := &services.CampaignEmbedService{}
+	expiresAt := time.Now().UTC().Add(request.TTL()).Truncate(time.Second)
+	token, err := embedService.Issue(campaign.ID, request.Origins, expiresAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to issue embed token",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.CampaignEmbedTokenResponse{
+		CampaignID: campaign.ID,
+		Token:      token,
+		Origins:    request.Origins,
+		ExpiresAt:  expiresAt,
+		WidgetPath: fmt.Sprintf("/v1/embed/campaigns/%d?format=html&token=%s", campaign.ID, url.QueryEscape(token)),
+	})
+}
+
+// GetCampaignWidget serves the public results of a campaign to the pages an
+// embed token was issued for, as JSON or as an HTML page for iframes. The
+// embedding page is told by the Origin header, or the Referer of iframes;
+// requests naming no allowed origin are refused.
+// @Summary      Get embedded campaign widget
+// @Tags         campaigns
+// @Produce      json,html
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        token          query     string  true   "Embed token"
+// @Param        format         query     string  false  "json or html" default(json)
+// @Success      200  {object}  services.CampaignWidget
+// @Failure      400  {object}  serializers.Base
+// @Failure      401  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /embed/campaigns/{id} [get]
+func (CampaignController) GetCampaignWidget(ctx *gin.Context) {
+	var query serializers.CampaignWidgetQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	embedService := &services.CampaignEmbedService{}
+	now := time.Now()
+	embed, err := embedService.Parse(query.Token, now)
+	if err != nil || strconv.FormatInt(embed.CampaignID, 10) != ctx.Param("id") {
+		ctx.JSON(http.StatusUnauthorized, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: "Embed token is invalid or expired",
+		})
+		return
+	}
+	origin := services.RequestOrigin(ctx.Request)
+	if !embed.Allows(origin) {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "This page may not embed the widget",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	widget, err := embedService.Widget(ctx.Request.Context(), campaign, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign results",
+		})
+		return
+	}
+
+	// Only the token's origins may frame or read the widget
+	header := ctx.Writer.Header()
+	header.Del("X-Frame-Options")
+	header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors "+strings.Join(embed.Origins, " "))
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Referer")
+
+	if query.Format != "html" {
+		ctx.JSON(http.StatusOK, widget)
+		return
+	}
+	page, err := embedService.RenderWidget(widget)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to render widget",
+		})
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// ExcludeCampaignVote leaves a vote out of the campaign tallies, like votes
+// found to be fraudulent, until the results are finalized (admin only).
+// Excluded votes are counted per reason in the campaign audit file.
+// @Summary      Exclude campaign vote
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        vote_id        path      int     true   "Vote ID"
+// @Param        exclusion      body      serializers.ExcludeCampaignVoteRequest  true  "Reason"
+// @Success      200  {object}  models.ExcludedCampaignVote
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      409  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/votes/{vote_id}/exclusion [put]
+func (CampaignController) ExcludeCampaignVote(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can exclude votes",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	voteID, err := strconv.ParseInt(ctx.Param("vote_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid vote ID",
+		})
+		return
+	}
+
+	var request serializers.ExcludeCampaignVoteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Reason must be one of: fraud, duplicate_voter, automated",
+		})
+		return
+	}
+
+	exclusion := &models.ExcludedCampaignVote{
+		VoteID:     voteID,
+		CampaignID: campaign.ID,
+		Reason:     request.Reason,
+		ExcludedBy: ctx.GetInt64("user_id"),
+	}
+	err = exclusion.Exclude(ctx.Request.Context())
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Vote not found",
+		})
+	case err == models.ErrCampaignFinalized:
+		ctx.JSON(http.StatusConflict, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Votes can't be excluded once the results are finalized",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to exclude vote",
+		})
+	default:
+		ctx.JSON(http.StatusOK, exclusion)
+	}
+}
+
+// loadVoteVenue loads the venue a vote goes to and the category it is
+// scoped to, checking the venue is nominated and eligible for both and,
+// when the campaign requires it, reviewed by the voter
+func loadVoteVenue(ctx *gin.Context, campaign *models.VotingCampaign, voter campaignVoter, venueID int64, categoryID *int64) (*models.Venue, *models.CampaignCategory, bool) {
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return nil, nil, false
+	}
+
+	if (campaign.CityID != nil && *campaign.CityID != venue.CityID) ||
+		(campaign.CategoryID != nil && *campaign.CategoryID != venue.CategoryID) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Venue is not eligible for this campaign",
+		})
+		return nil, nil, false
+	}
+	nominated, err := models.IsCampaignNominee(ctx.Request.Context(), campaign.ID, venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to submit vote",
+		})
+		return nil, nil, false
+	}
+	if !nominated {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Venue is not nominated in this campaign",
+		})
+		return nil, nil, false
+	}
+
+	category, ok := loadVoteCategory(ctx, campaign, categoryID)
+	if !ok {
+		return nil, nil, false
+	}
+	if category != nil && category.VenueCategoryID != nil && *category.VenueCategoryID != venue.CategoryID {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Venue is not eligible for this category",
+		})
+		return nil, nil, false
+	}
+
+	if campaign.RequireReview {
+		// Anonymous voters have no reviews
+		reviewed := false
+		if voter.session == nil {
+			reviewed, err = models.HasUserReviewedVenue(ctx.Request.Context(), venue.ID, voter.userID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, serializers.Base{
+					Code:    serializers.InternalError,
+					Message: "Failed to submit vote",
+				})
+				return nil, nil, false
+			}
+		}
+		if !reviewed {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.ReviewRequired,
+				Message: "Review the venue before voting for it in this campaign",
+			})
+			return nil, nil, false
+		}
+	}
+
+	return venue, category, true
+}
+
+// loadVoteCategory loads the category a vote is scoped to. Campaigns with
+// categories require one, campaigns without categories reject it.
+func loadVoteCategory(ctx *gin.Context, campaign *models.VotingCampaign, categoryID *int64) (*models.CampaignCategory, bool) {
+	categories, err := models.GetCampaignCategories(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to submit vote",
+		})
+		return nil, false
+	}
+
+	if len(categories) == 0 {
+		if categoryID != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "This campaign has no categories",
+			})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	if categoryID == nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Category ID is required for this campaign",
+		})
+		return nil, false
+	}
+
+	for i := range categories {
+		if categories[i].ID == *categoryID {
+			return &categories[i], true
+		}
+	}
+
+	ctx.JSON(http.StatusNotFound, serializers.Base{
+		Code:    serializers.NotFound,
+		Message: "Category not found in this campaign",
+	})
+	return nil, false
+}
+
+// AutoGenerateCampaign proposes a "best of" campaign of a city and venue
+// category, like "Best Cafes in San Francisco Q3 2026", nominating its best
+// rated and fastest growing venues. The campaign is created inactive for the
+// nominees to be reviewed before it is published (admin only).
+// @Summary      Auto-generate campaign
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        proposal       body      serializers.AutoGenerateCampaignRequest  true  "Campaign proposal"
+// @Success      201  {object}  serializers.CampaignNomineesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/auto-generate [post]
+func (CampaignController) AutoGenerateCampaign(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can generate campaigns",
+		})
+		return
+	}
+
+	var request serializers.AutoGenerateCampaignRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid campaign proposal",
+		})
+		return
+	}
+
+	generator := &services.CampaignGeneratorService{}
+	campaign, nominees, err := generator.Generate(ctx.Request.Context(), request.ToProposal(), time.Now())
+	switch err {
+	case nil:
+	case services.ErrInvalidQuarter, services.ErrNotEnoughCandidates:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: err.Error(),
+		})
+		return
+	case sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "City or category not found",
+		})
+		return
+	default:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to generate campaign",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.CampaignNomineesResponse{Campaign: campaign, Nominees: nominees})
+}
+
+// GetCampaign returns a campaign with its categories and nominees.
+// Unpublished campaigns are only shown to administrators.
+// @Summary      Get campaign
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  serializers.CampaignDetailResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id} [get]
+func (CampaignController) GetCampaign(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	if !campaign.IsActive && !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Campaign not found",
+		})
+		return
+	}
+
+	categories, err := models.GetCampaignCategories(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign",
+		})
+		return
+	}
+	nominees, err := models.GetCampaignNominees(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get campaign",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignDetailResponse{
+		Campaign:   campaign,
+		Categories: categories,
+		Nominees:   nominees,
+		IsOpen:     campaign.IsOpen(time.Now()),
+	})
+}
+
+// GetCampaignNominees lists the venues nominated in a campaign. Nominees of
+// unpublished campaigns are only shown to administrators.
+// @Summary      Get campaign nominees
+// @Tags         campaigns
+// @Produce      json
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  serializers.CampaignNomineesResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /campaigns/{id}/nominees [get]
+// @Router       /admin/campaigns/{id}/nominees [get]
+func (CampaignController) GetCampaignNominees(ctx *gin.Context) {
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	if !campaign.IsActive && !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Campaign not found",
+		})
+		return
+	}
+
+	nominees, err := models.GetCampaignNominees(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get nominees",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignNomineesResponse{Campaign: campaign, Nominees: nominees})
+}
+
+// AddCampaignNominee nominates a venue of the campaign's city and category
+// by hand (admin only)
+// @Summary      Add campaign nominee
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        nominee        body      serializers.CampaignNomineeRequest  true  "Nominated venue"
+// @Success      201  {object}  models.CampaignNominee
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/nominees [post]
+func (CampaignController) AddCampaignNominee(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign nominees",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.CampaignNomineeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid nominee data",
+		})
+		return
+	}
+
+	venue := &models.Venue{ID: request.VenueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+	if (campaign.CityID != nil && *campaign.CityID != venue.CityID) ||
+		(campaign.CategoryID != nil && *campaign.CategoryID != venue.CategoryID) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Venue is not eligible for this campaign",
+		})
+		return
+	}
+
+	nominee := &models.CampaignNominee{
+		CampaignID: campaign.ID,
+		VenueID:    venue.ID,
+		VenueName:  venue.Name,
+		VenueSlug:  venue.Slug,
+	}
+	err := nominee.Create(ctx.Request.Context())
+	if err == models.ErrCampaignNomineeExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Venue is already nominated",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to nominate venue",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, nominee)
+}
+
+// AddEligibleCampaignNominees nominates the venues of the campaign's city
+// and category with enough approved reviews and a high enough rating
+// (admin only)
+// @Summary      Nominate eligible venues
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        criteria       body      serializers.EligibleNomineesRequest  false  "Eligibility criteria"
+// @Success      201  {object}  serializers.EligibleNomineesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/nominees/eligible [post]
+func (CampaignController) AddEligibleCampaignNominees(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign nominees",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	// Every criterion is optional, so is the body
+	var request serializers.EligibleNomineesRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid eligibility criteria",
+		})
+		return
+	}
+
+	nominated, err := models.NominateEligibleVenues(ctx.Request.Context(), campaign.ID,
+		campaign.CityID, campaign.CategoryID, request.ToCriteria())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to nominate venues",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.EligibleNomineesResponse{
+		CampaignID: campaign.ID,
+		Nominated:  nominated,
+		Count:      len(nominated),
+	})
+}
+
+// RemoveCampaignNominee withdraws a venue's nomination (admin only)
+// @Summary      Remove campaign nominee
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        venue_id       path      int     true   "Venue ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/nominees/{venue_id} [delete]
+func (CampaignController) RemoveCampaignNominee(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage campaign nominees",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	venueID, err := strconv.ParseInt(ctx.Param("venue_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	nominee := &models.CampaignNominee{CampaignID: campaign.ID, VenueID: venueID}
+	if err := nominee.Delete(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Venue is not nominated",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to remove nominee",
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Nominee removed",
+	})
+}
+
+// PublishCampaign opens an unpublished campaign with nominees to voters
+// (admin only)
+// @Summary      Publish campaign
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Success      200  {object}  serializers.CampaignNomineesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      409  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/publish [post]
+func (CampaignController) PublishCampaign(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can publish campaigns",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	if campaign.IsActive {
+		ctx.JSON(http.StatusConflict, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The campaign is already published",
+		})
+		return
+	}
+	if !time.Now().Before(campaign.EndDate) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The campaign has ended",
+		})
+		return
+	}
+
+	nominees, err := models.GetCampaignNominees(ctx.Request.Context(), campaign.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get nominees",
+		})
+		return
+	}
+	if len(nominees) < services.MinCampaignNominees {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: fmt.Sprintf("The campaign needs at least %d nominees", services.MinCampaignNominees),
+		})
+		return
+	}
+
+	if err := campaign.Publish(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to publish campaign",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignNomineesResponse{Campaign: campaign, Nominees: nominees})
+}
+
+// GetCampaignVoteReasons lists the reasons voters gave in a campaign for
+// moderation, newest first (admin only)
+// @Summary      Get campaign vote reasons
+// @Tags         campaigns
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        status         query     string  false  "pending, approved or rejected"
+// @Param        limit          query     int     false  "Number of reasons, at most 100" default(50)
+// @Param        offset         query     int     false  "Offset"
+// @Success      200  {object}  serializers.CampaignVoteReasonsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/reasons [get]
+func (CampaignController) GetCampaignVoteReasons(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can moderate vote reasons",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+	var query serializers.CampaignVoteReasonsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	reasons, err := models.GetCampaignVoteReasons(ctx.Request.Context(), campaign.ID, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get vote reasons",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CampaignVoteReasonsResponse{CampaignID: campaign.ID, Reasons: reasons})
+}
+
+// ModerateVoteReason approves or rejects the reason given with a campaign
+// vote, and marks approved reasons as highlights quoted in the campaign
+// results and the winner announcement (admin only)
+// @Summary      Moderate campaign vote reason
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Campaign ID"
+// @Param        vote_id        path      int     true   "Vote ID"
+// @Param        moderation     body      serializers.ModerateVoteReasonRequest  true  "Status and highlight"
+// @Success      200  {object}  models.CampaignVoteReason
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/campaigns/{id}/reasons/{vote_id} [put]
+func (CampaignController) ModerateVoteReason(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can moderate vote reasons",
+		})
+		return
+	}
+
+	campaign, ok := loadCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	voteID, err := strconv.ParseInt(ctx.Param("vote_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid vote ID",
+		})
+		return
+	}
+
+	var request serializers.ModerateVoteReasonRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Status must be one of: approved, rejected",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	reason := &models.CampaignVoteReason{VoteID: voteID, CampaignID: campaign.ID}
+	err = reason.Moderate(ctx.Request.Context(), ctx.GetInt64("user_id"), request.Status, request.Highlight)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Vote has no reason to moderate",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to moderate vote reason",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reason)
+}
+
+// loadCampaign loads the campaign of the id param
+func loadCampaign(ctx *gin.Context) (*models.VotingCampaign, bool) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid campaign ID",
+		})
+		return nil, false
+	}
+
+	campaign := &models.VotingCampaign{ID: campaignID}
+	if err := campaign.GetByID(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Campaign not found",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get campaign",
+			})
+		}
+		return nil, false
+	}
+
+	return campaign, true
+}
+
+// campaignVoter is who casts a campaign vote: a Snapp user, or an anonymous
+// voting session at an event kiosk
+type campaignVoter struct {
+	userID  int64
+	session *models.VotingSession
+	// verified is set when the voter confirmed the vote with a one-time code
+	verified bool
+}
+
+// countVotes returns how many votes the voter cast in the campaign, or in
+// the category when one is given
+func (v campaignVoter) countVotes(ctx context.Context, campaignID int64, category *models.CampaignCategory) (int, error) {
+	if v.session != nil {
+		var categoryID *int64
+		if category != nil {
+			categoryID = &category.ID
+		}
+		return models.CountSessionCampaignVotes(ctx, v.session.ID, categoryID)
+	}
+	if category != nil {
+		return models.CountUserCategoryVotes(ctx, campaignID, category.ID, v.userID)
+	}
+	return models.CountUserCampaignVotes(ctx, campaignID, v.userID)
+}
+
+// votedCategoryIDs returns the campaign categories the voter voted in
+func (v campaignVoter) votedCategoryIDs(ctx context.Context, campaignID int64) ([]int64, error) {
+	if v.session != nil {
+		return models.GetSessionVotedCategoryIDs(ctx, v.session.ID)
+	}
+	return models.GetUserVotedCategoryIDs(ctx, campaignID, v.userID)
+}
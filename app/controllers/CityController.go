@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CityController onboards cities and their districts. The active ones are
+// listed by UtilityController.
+type CityController struct{}
+
+// CreateCity onboards a city
+// @Summary      Create city
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        city  body      serializers.CityRequest  true  "City"
+// @Success      201   {object}  serializers.CityResponse
+// @Failure      400   {object}  serializers.Base
+// @Failure      403   {object}  serializers.Base
+// @Router       /admin/cities [post]
+func (CityController) CreateCity(ctx *gin.Context) {
+	if !authorizeCityAdmin(ctx) {
+		return
+	}
+
+	request, ok := bindCityRequest(ctx)
+	if !ok {
+		return
+	}
+
+	city := request.ToCity(0)
+	cityService := &services.CityService{}
+	err := cityService.CreateCity(ctx.Request.Context(), city)
+	if err == models.ErrCityExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.CityAlreadyExists,
+			Message: "This city already exists",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create city",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.NewCityResponse(city))
+}
+
+// UpdateCity replaces the names, centre and timezone of a city, and lists
+// or unlists it. Changes apply to all instances within the city cache TTL.
+// @Summary      Update city
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                      true  "City ID"
+// @Param        city  body      serializers.CityRequest  true  "City"
+// @Success      200   {object}  serializers.CityResponse
+// @Failure      400   {object}  serializers.Base
+// @Failure      403   {object}  serializers.Base
+// @Failure      404   {object}  serializers.Base
+// @Router       /admin/cities/{id} [put]
+func (CityController) UpdateCity(ctx *gin.Context) {
+	if !authorizeCityAdmin(ctx) {
+		return
+	}
+
+	cityID, ok := parseCityAdminID(ctx, "Invalid city ID")
+	if !ok {
+		return
+	}
+
+	request, ok := bindCityRequest(ctx)
+	if !ok {
+		return
+	}
+
+	city := request.ToCity(cityID)
+	cityService := &services.CityService{}
+	err := cityService.UpdateCity(ctx.Request.Context(), city)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "City not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update city",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.NewCityResponse(city))
+}
+
+// CreateDistrict adds a district to a city, listed or not
+// @Summary      Create district
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                          true  "City ID"
+// @Param        district  body      serializers.DistrictRequest  true  "District"
+// @Success      201       {object}  models.District
+// @Failure      400       {object}  serializers.Base
+// @Failure      403       {object}  serializers.Base
+// @Failure      404       {object}  serializers.Base
+// @Router       /admin/cities/{id}/districts [post]
+func (CityController) CreateDistrict(ctx *gin.Context) {
+	if !authorizeCityAdmin(ctx) {
+		return
+	}
+
+	cityID, ok := parseCityAdminID(ctx, "Invalid city ID")
+	if !ok {
+		return
+	}
+
+	request, ok := bindDistrictRequest(ctx)
+	if !ok {
+		return
+	}
+
+	district := request.ToDistrict(cityID, 0)
+	cityService := &services.CityService{}
+	err := cityService.CreateDistrict(ctx.Request.Context(), district)
+	if err == services.ErrUnknownCity {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "City not found",
+		})
+		return
+	}
+	if !respondDistrictError(ctx, err, "Failed to create district") {
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, district)
+}
+
+// UpdateDistrict replaces the name and centre of a district
+// @Summary      Update district
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                          true  "District ID"
+// @Param        district  body      serializers.DistrictRequest  true  "District"
+// @Success      200       {object}  models.District
+// @Failure      400       {object}  serializers.Base
+// @Failure      403       {object}  serializers.Base
+// @Failure      404       {object}  serializers.Base
+// @Router       /admin/districts/{id} [put]
+func (CityController) UpdateDistrict(ctx *gin.Context) {
+	if !authorizeCityAdmin(ctx) {
+		return
+	}
+
+	districtID, ok := parseCityAdminID(ctx, "Invalid district ID")
+	if !ok {
+		return
+	}
+
+	request, ok := bindDistrictRequest(ctx)
+	if !ok {
+		return
+	}
+
+	district := request.ToDistrict(0, districtID)
+	cityService := &services.CityService{}
+	err := cityService.UpdateDistrict(ctx.Request.Context(), district)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "District not found",
+		})
+		return
+	}
+	if !respondDistrictError(ctx, err, "Failed to update district") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, district)
+}
+
+// DeleteDistrict removes a district
+// @Summary      Delete district
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "District ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/districts/{id} [delete]
+func (CityController) DeleteDistrict(ctx *gin.Context) {
+	if !authorizeCityAdmin(ctx) {
+		return
+	}
+
+	districtID, ok := parseCityAdminID(ctx, "Invalid district ID")
+	if !ok {
+		return
+	}
+
+	cityService := &services.CityService{}
+	err := cityService.DeleteDistrict(ctx.Request.Context(), districtID)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "District not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete district",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "District deleted",
+	})
+}
+
+// authorizeCityAdmin writes a 403 unless the caller is an administrator
+func authorizeCityAdmin(ctx *gin.Context) bool {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage cities",
+		})
+		return false
+	}
+	return true
+}
+
+// parseCityAdminID parses the id path parameter, writing a 400 with the
+// message when it is invalid
+func parseCityAdminID(ctx *gin.Context, message string) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// bindCityRequest binds and validates the city request, writing a 400 when
+// it is invalid
+func bindCityRequest(ctx *gin.Context) (serializers.CityRequest, bool) {
+	var request serializers.CityRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid city data",
+		})
+		return request, false
+	}
+
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return request, false
+	}
+	return request, true
+}
+
+// bindDistrictRequest binds and validates the district request, writing a
+// 400 when it is invalid
+func bindDistrictRequest(ctx *gin.Context) (serializers.DistrictRequest, bool) {
+	var request serializers.DistrictRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid district data",
+		})
+		return request, false
+	}
+
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return request, false
+	}
+	return request, true
+}
+
+// respondDistrictError writes the response of the errors creating and
+// updating districts share, telling whether there was none
+func respondDistrictError(ctx *gin.Context, err error, message string) bool {
+	switch err {
+	case nil:
+		return true
+	case services.ErrDistrictTooFar:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "District centre must be within 100 km of the city centre",
+		})
+	case models.ErrDistrictExists:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "City already has a district with this name",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: message,
+		})
+	}
+	return false
+}
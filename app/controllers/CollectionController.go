@@ -0,0 +1,484 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CollectionController manages users' venue collections and the
+// collaborators who edit them with their owners
+type CollectionController struct{}
+
+// GetCollections lists the collections the user owns or collaborates on,
+// with the user's pending invites to collaborate
+// @Summary      List collections
+// @Tags         collections
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.CollectionsResponse
+// @Router       /collections/{snapp_id} [get]
+func (CollectionController) GetCollections(ctx *gin.Context) {
+	userID := ctx.GetInt64("snappUser_id")
+	collections, err := models.GetUserCollections(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get collections",
+		})
+		return
+	}
+	invites, err := models.GetCollaborationInvites(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get collections",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.CollectionsResponse{
+		Collections: collections,
+		Invites:     invites,
+	})
+}
+
+// CreateCollection creates a collection owned by the user
+// @Summary      Create collection
+// @Tags         collections
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection     body      serializers.CreateCollectionRequest  true  "Collection"
+// @Success      201  {object}  models.VenueCollection
+// @Failure      400  {object}  serializers.Base
+// @Router       /collections/{snapp_id} [post]
+func (CollectionController) CreateCollection(ctx *gin.Context) {
+	var request serializers.CreateCollectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid collection data",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	collection := request.ToVenueCollection(ctx.GetInt64("snappUser_id"))
+	if err := collection.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create collection",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, collection)
+}
+
+// GetCollection returns a collection with its venues. Private collections
+// are only shown to their owner and collaborators, who also see the
+// collaborators.
+// @Summary      Get collection
+// @Tags         collections
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Success      200  {object}  serializers.CollectionDetailResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id} [get]
+func (CollectionController) GetCollection(ctx *gin.Context) {
+	collection, ok := loadCollection(ctx)
+	if !ok {
+		return
+	}
+
+	items, err := collection.GetItems(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get collection",
+		})
+		return
+	}
+	response := serializers.CollectionDetailResponse{VenueCollection: *collection, Items: items}
+
+	if collection.CanEdit() {
+		response.Collaborators, err = collection.GetCollaborators(ctx.Request.Context())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get collection",
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// AddVenueToCollection adds a venue to a collection, or updates its note
+// when it is already in it. The owner and collaborators can add venues.
+// @Summary      Add venue to collection
+// @Tags         collections
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Param        item           body      serializers.AddVenueToCollectionRequest  true  "Venue and note"
+// @Success      200  {object}  models.CollectionItem
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id}/venues [post]
+func (CollectionController) AddVenueToCollection(ctx *gin.Context) {
+	collection, ok := loadEditableCollection(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.AddVenueToCollectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid collection venue",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	existing, err := models.GetExistingVenueIDs(ctx.Request.Context(), []int64{request.VenueID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to add venue to collection",
+		})
+		return
+	}
+	if !existing[request.VenueID] {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+
+	item := &models.CollectionItem{
+		CollectionID: collection.ID,
+		VenueID:      request.VenueID,
+		Note:         request.Note,
+		AddedBy:      ctx.GetInt64("snappUser_id"),
+	}
+	if err := item.Save(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to add venue to collection",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+// RemoveVenueFromCollection removes a venue from a collection. The owner and
+// collaborators can remove venues.
+// @Summary      Remove venue from collection
+// @Tags         collections
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Param        venue_id       path      int     true   "Venue ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id}/venues/{venue_id} [delete]
+func (CollectionController) RemoveVenueFromCollection(ctx *gin.Context) {
+	collection, ok := loadEditableCollection(ctx)
+	if !ok {
+		return
+	}
+
+	venueID, err := strconv.ParseInt(ctx.Param("venue_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	err = models.RemoveCollectionItem(ctx.Request.Context(), collection.ID, venueID)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Venue is not in the collection",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to remove venue from collection",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Venue removed from collection",
+	})
+}
+
+// InviteCollaborator invites a user to add and remove the collection's
+// venues. Only the owner can invite.
+// @Summary      Invite collaborator
+// @Tags         collections
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Param        invite         body      serializers.InviteCollaboratorRequest  true  "User to invite"
+// @Success      201  {object}  models.CollectionCollaborator
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id}/collaborators [post]
+func (CollectionController) InviteCollaborator(ctx *gin.Context) {
+	collection, ok := loadCollection(ctx)
+	if !ok {
+		return
+	}
+	if collection.Role != models.CollectionRoleOwner {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only the owner can invite collaborators",
+		})
+		return
+	}
+
+	var request serializers.InviteCollaboratorRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid invite",
+		})
+		return
+	}
+	if request.UserID == collection.UserID {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "You cannot invite yourself",
+		})
+		return
+	}
+
+	invitee := &models.SnappUser{Id: request.UserID}
+	exists, err := invitee.GetByID(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to invite collaborator",
+		})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	collaborator := &models.CollectionCollaborator{
+		CollectionID:   collection.ID,
+		CollectionName: collection.Name,
+		UserID:         request.UserID,
+		UserName:       invitee.SnappId,
+		InvitedBy:      ctx.GetInt64("snappUser_id"),
+	}
+	err = collaborator.Invite(ctx.Request.Context())
+	if err == models.ErrCollaboratorExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyInvited,
+			Message: "The user is already invited to this collection",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to invite collaborator",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, collaborator)
+}
+
+// AcceptCollaboration accepts the user's invite to collaborate on a
+// collection
+// @Summary      Accept collaboration invite
+// @Tags         collections
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Success      200  {object}  models.CollectionCollaborator
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id}/collaborators/accept [post]
+func (CollectionController) AcceptCollaboration(ctx *gin.Context) {
+	collectionID, ok := collectionIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	collaborator, err := models.AcceptCollaboration(ctx.Request.Context(), collectionID, ctx.GetInt64("snappUser_id"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Invite not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to accept invite",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, collaborator)
+}
+
+// RemoveCollaborator removes a collaborator or cancels an invite. The owner
+// can remove anyone, and users can leave or decline themselves.
+// @Summary      Remove collaborator
+// @Tags         collections
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        collection_id  path      int     true   "Collection ID"
+// @Param        user_id        path      int     true   "Collaborator user ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /collections/{snapp_id}/{collection_id}/collaborators/{user_id} [delete]
+func (CollectionController) RemoveCollaborator(ctx *gin.Context) {
+	collectionID, ok := collectionIDParam(ctx)
+	if !ok {
+		return
+	}
+	collaboratorID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	// Invitees can decline without seeing the collection
+	userID := ctx.GetInt64("snappUser_id")
+	if collaboratorID != userID {
+		collection, ok := loadCollection(ctx)
+		if !ok {
+			return
+		}
+		if collection.Role != models.CollectionRoleOwner {
+			ctx.JSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.Forbidden,
+				Message: "Only the owner can remove collaborators",
+			})
+			return
+		}
+	}
+
+	err = models.RemoveCollaborator(ctx.Request.Context(), collectionID, collaboratorID)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Collaborator not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to remove collaborator",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Collaborator removed",
+	})
+}
+
+// collectionIDParam parses the collection_id param, writing the 400
+// response when it is invalid
+func collectionIDParam(ctx *gin.Context) (int64, bool) {
+	collectionID, err := strconv.ParseInt(ctx.Param("collection_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid collection ID",
+		})
+		return 0, false
+	}
+	return collectionID, true
+}
+
+// loadCollection loads the collection of the collection_id param with the
+// current user's role. Private collections are not found by users without
+// a role in them.
+func loadCollection(ctx *gin.Context) (*models.VenueCollection, bool) {
+	collectionID, ok := collectionIDParam(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	collection, err := models.GetCollection(ctx.Request.Context(), collectionID, ctx.GetInt64("snappUser_id"))
+	if err == sql.ErrNoRows || (err == nil && !collection.IsPublic && collection.Role == "") {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Collection not found",
+		})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get collection",
+		})
+		return nil, false
+	}
+
+	return collection, true
+}
+
+// loadEditableCollection loads the collection of the collection_id param,
+// checking the current user is its owner or a collaborator
+func loadEditableCollection(ctx *gin.Context) (*models.VenueCollection, bool) {
+	collection, ok := loadCollection(ctx)
+	if !ok {
+		return nil, false
+	}
+	if !collection.CanEdit() {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only the owner and collaborators can change this collection",
+		})
+		return nil, false
+	}
+	return collection, true
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsentController lets users give and withdraw their consent to marketing
+// and personalization
+type ConsentController struct{}
+
+// consentPurposeParam reads the purpose path parameter, answering 400 when
+// users don't consent to it
+func consentPurposeParam(ctx *gin.Context) (string, bool) {
+	purpose := ctx.Param("purpose")
+	if !models.IsConsentPurpose(purpose) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Purpose must be one of: push_marketing, email_digest, personalized_recommendations",
+		})
+		return "", false
+	}
+	return purpose, true
+}
+
+// GetConsents lists the user's consent to each purpose
+// @Summary      Get consents
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.ConsentsResponse
+// @Router       /users/{snapp_id}/consents [get]
+func (ConsentController) GetConsents(ctx *gin.Context) {
+	consents, err := models.GetUserConsents(ctx.Request.Context(), ctx.GetInt64("snappUser_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get consents",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.ConsentsResponse{Consents: consents})
+}
+
+// RequestConsent asks for the user's consent to a purpose. The consent stays
+// pending until confirmed with the code sent to the user as a notification.
+// @Summary      Request consent
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string                      true   "User Snapp ID"
+// @Param        purpose        path      string                      true   "push_marketing, email_digest or personalized_recommendations"
+// @Param        request        body      serializers.ConsentRequest  false  "Source"
+// @Success      202  {object}  models.UserConsent
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/consents/{purpose} [post]
+func (ConsentController) RequestConsent(ctx *gin.Context) {
+	purpose, ok := consentPurposeParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ConsentRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid consent data",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	consentService := &services.ConsentService{}
+	consent, err := consentService.RequestConsent(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), purpose, request.Source)
+	switch {
+	case err == models.ErrConsentGranted:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Consent is already granted",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to request consent",
+		})
+	default:
+		ctx.JSON(http.StatusAccepted, consent)
+	}
+}
+
+// ConfirmConsent grants the user's pending consent to a purpose with the
+// code sent to them
+// @Summary      Confirm consent
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string                             true   "User Snapp ID"
+// @Param        purpose        path      string                             true   "push_marketing, email_digest or personalized_recommendations"
+// @Param        request        body      serializers.ConfirmConsentRequest  true   "Code"
+// @Success      200  {object}  models.UserConsent
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/consents/{purpose}/confirm [post]
+func (ConsentController) ConfirmConsent(ctx *gin.Context) {
+	purpose, ok := consentPurposeParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ConfirmConsentRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "code is required",
+		})
+		return
+	}
+
+	consentService := &services.ConsentService{}
+	consent, err := consentService.ConfirmConsent(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), purpose, request.Code)
+	switch {
+	case err == models.ErrInvalidConsentCode:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: err.Error(),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to confirm consent",
+		})
+	default:
+		ctx.JSON(http.StatusOK, consent)
+	}
+}
+
+// RevokeConsent withdraws the user's consent to a purpose at once, a pending
+// consent can't be confirmed anymore
+// @Summary      Revoke consent
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        purpose        path      string  true   "push_marketing, email_digest or personalized_recommendations"
+// @Param        source         query     string  false  "app, web, onboarding or settings (default app)"
+// @Success      200  {object}  models.UserConsent
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/consents/{purpose} [delete]
+func (ConsentController) RevokeConsent(ctx *gin.Context) {
+	purpose, ok := consentPurposeParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ConsentRequest
+	if !bindQuery(ctx, &request) {
+		return
+	}
+
+	consent := &models.UserConsent{UserID: ctx.GetInt64("snappUser_id"), Purpose: purpose, Source: request.Source}
+	if err := consent.Revoke(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to revoke consent",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, consent)
+}
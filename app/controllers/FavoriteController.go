@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FavoriteController lets users heart venues with a single action, apart
+// from their collections
+type FavoriteController struct{}
+
+// FavoriteVenue adds the venue to the user's favorites. Favoriting it again
+// changes nothing.
+// @Summary      Favorite venue
+// @Tags         venues
+// @Produce      json
+// @Param        id        path      int     true  "Venue ID"
+// @Param        snapp_id  path      string  true  "User Snapp ID"
+// @Success      200  {object}  models.FavoriteStatus
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/{snapp_id}/favorite [post]
+func (FavoriteController) FavoriteVenue(ctx *gin.Context) {
+	setFavorite(ctx, true)
+}
+
+// UnfavoriteVenue takes the venue out of the user's favorites. Venues that
+// aren't favorites are left as they are.
+// @Summary      Unfavorite venue
+// @Tags         venues
+// @Produce      json
+// @Param        id        path      int     true  "Venue ID"
+// @Param        snapp_id  path      string  true  "User Snapp ID"
+// @Success      200  {object}  models.FavoriteStatus
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/{snapp_id}/favorite [delete]
+func (FavoriteController) UnfavoriteVenue(ctx *gin.Context) {
+	setFavorite(ctx, false)
+}
+
+func setFavorite(ctx *gin.Context, favorite bool) {
+	venueID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	status, err := models.SetFavorite(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), venueID, favorite)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update favorites",
+		})
+	default:
+		ctx.JSON(http.StatusOK, status)
+	}
+}
+
+// GetFavorites lists the venues the user favorited, the latest first
+// @Summary      Get favorites
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id  path      string  true   "User Snapp ID"
+// @Param        page      query     int     false  "Page number (default 1)"
+// @Param        limit     query     int     false  "Results per page (default 20, max 100)"
+// @Success      200  {object}  serializers.FavoritesResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/favorites [get]
+func (FavoriteController) GetFavorites(ctx *gin.Context) {
+	var query serializers.FavoritesQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	favorites, total, err := models.GetUserFavorites(ctx.Request.Context(), ctx.GetInt64("snappUser_id"),
+		query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get favorites",
+		})
+		return
+	}
+
+	totalPages := (total + query.Limit - 1) / query.Limit
+	ctx.JSON(http.StatusOK, serializers.FavoritesResponse{
+		Favorites: favorites,
+		Pagination: serializers.PaginationInfo{
+			Page:       query.Page,
+			Limit:      query.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    query.Page < totalPages,
+			HasPrev:    query.Page > 1,
+		},
+	})
+}
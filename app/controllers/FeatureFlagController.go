@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlagController manages the feature flags gating gradual rollouts
+type FeatureFlagController struct{}
+
+// GetFlags lists the feature flags
+// @Summary      List feature flags
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  serializers.FeatureFlagsResponse
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/flags [get]
+func (FeatureFlagController) GetFlags(ctx *gin.Context) {
+	if !authorizeFlagAdmin(ctx) {
+		return
+	}
+
+	flagService := &services.FlagService{}
+	flags, err := flagService.GetFlags(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get feature flags",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.FeatureFlagsResponse{Flags: flags})
+}
+
+// CreateFlag creates a feature flag
+// @Summary      Create feature flag
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        flag  body      serializers.FeatureFlagRequest  true  "Flag"
+// @Success      201   {object}  models.FeatureFlag
+// @Failure      400   {object}  serializers.Base
+// @Failure      403   {object}  serializers.Base
+// @Router       /admin/flags [post]
+func (FeatureFlagController) CreateFlag(ctx *gin.Context) {
+	if !authorizeFlagAdmin(ctx) {
+		return
+	}
+
+	request, ok := bindFlagRequest(ctx)
+	if !ok {
+		return
+	}
+	if base, isValid := request.ValidKey(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	flag := request.ToFeatureFlag(request.Key)
+	flagService := &services.FlagService{}
+	err := flagService.CreateFlag(ctx.Request.Context(), flag)
+	if err == models.ErrFeatureFlagExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.FlagAlreadyExists,
+			Message: "A feature flag with this key already exists",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create feature flag",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, flag)
+}
+
+// UpdateFlag replaces the targeting of a feature flag. Changes apply to all
+// instances within the flag cache TTL.
+// @Summary      Update feature flag
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        key   path      string  true  "Flag key"
+// @Param        flag  body      serializers.FeatureFlagRequest  true  "Flag"
+// @Success      200   {object}  models.FeatureFlag
+// @Failure      400   {object}  serializers.Base
+// @Failure      403   {object}  serializers.Base
+// @Failure      404   {object}  serializers.Base
+// @Router       /admin/flags/{key} [put]
+func (FeatureFlagController) UpdateFlag(ctx *gin.Context) {
+	if !authorizeFlagAdmin(ctx) {
+		return
+	}
+
+	request, ok := bindFlagRequest(ctx)
+	if !ok {
+		return
+	}
+
+	flag := request.ToFeatureFlag(ctx.Param("key"))
+	flagService := &services.FlagService{}
+	err := flagService.UpdateFlag(ctx.Request.Context(), flag)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Feature flag not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update feature flag",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, flag)
+}
+
+// DeleteFlag deletes a feature flag, turning the feature off for everyone
+// @Summary      Delete feature flag
+// @Tags         admin
+// @Produce      json
+// @Param        key  path      string  true  "Flag key"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/flags/{key} [delete]
+func (FeatureFlagController) DeleteFlag(ctx *gin.Context) {
+	if !authorizeFlagAdmin(ctx) {
+		return
+	}
+
+	flagService := &services.FlagService{}
+	err := flagService.DeleteFlag(ctx.Request.Context(), ctx.Param("key"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Feature flag not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete feature flag",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Feature flag deleted",
+	})
+}
+
+// authorizeFlagAdmin writes a 403 unless the caller is an administrator
+func authorizeFlagAdmin(ctx *gin.Context) bool {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage feature flags",
+		})
+		return false
+	}
+	return true
+}
+
+// bindFlagRequest binds and validates the flag request, writing a 400 when
+// it is invalid
+func bindFlagRequest(ctx *gin.Context) (serializers.FeatureFlagRequest, bool) {
+	var request serializers.FeatureFlagRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid feature flag data",
+		})
+		return request, false
+	}
+
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return request, false
+	}
+	return request, true
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedMaxAge is how long clients and CDNs may cache feeds, in seconds. It
+// matches the regeneration interval of the feed job.
+const feedMaxAge = 30 * 60
+
+type FeedController struct{}
+
+// Sitemap serves the venue sitemap
+// @Summary      Venue sitemap
+// @Tags         feeds
+// @Produce      xml
+// @Param        city           query     int     false  "City ID"
+// @Param        category       query     int     false  "Category ID"
+// @Success      200  {string}  string  "sitemap.xml"
+// @Success      304
+// @Failure      400  {object}  serializers.Base
+// @Router       /feeds/sitemap.xml [get]
+func (FeedController) Sitemap(ctx *gin.Context) {
+	serveFeed(ctx, services.FeedSitemap)
+}
+
+// VenuesFeed serves the machine-readable venue feed
+// @Summary      Venue feed
+// @Tags         feeds
+// @Produce      json
+// @Param        city           query     int     false  "City ID"
+// @Param        category       query     int     false  "Category ID"
+// @Success      200  {object}  services.VenuesFeed
+// @Success      304
+// @Failure      400  {object}  serializers.Base
+// @Router       /feeds/venues.json [get]
+func (FeedController) VenuesFeed(ctx *gin.Context) {
+	serveFeed(ctx, services.FeedVenues)
+}
+
+func serveFeed(ctx *gin.Context, kind string) {
+	var cityID, categoryID *int64
+
+	if cityStr := ctx.Query("city"); cityStr != "" {
+		id, err := strconv.ParseInt(cityStr, 10, 64)
+		if err != nil || id <= 0 {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Invalid city ID",
+			})
+			return
+		}
+		cityID = &id
+	}
+
+	if categoryStr := ctx.Query("category"); categoryStr != "" {
+		id, err := strconv.ParseInt(categoryStr, 10, 64)
+		if err != nil || id <= 0 {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Invalid category ID",
+			})
+			return
+		}
+		categoryID = &id
+	}
+
+	feedService := &services.FeedService{}
+	document, err := feedService.GetFeed(ctx.Request.Context(), kind, cityID, categoryID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to generate feed",
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", feedMaxAge))
+	ctx.Header("ETag", document.ETag)
+	ctx.Header("Last-Modified", document.GeneratedAt.Format(http.TimeFormat))
+
+	if match := ctx.GetHeader("If-None-Match"); match != "" {
+		if match == document.ETag {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since")); err == nil {
+		if !document.GeneratedAt.Truncate(time.Second).After(since) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	ctx.Data(http.StatusOK, document.ContentType, document.Body)
+}
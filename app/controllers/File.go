@@ -1,45 +1,61 @@
 package controllers
 
 import (
-	"bytes"
-	"context"
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
-	"io"
 	"net/http"
+	"strings"
+	"voting-app/app/serializers"
 	"voting-app/app/services"
 )
 
 type FileController struct {
 }
 
+// Serve serves a stored file. Photos are public, files in private buckets
+// need a signed URL from the local storage backend, cloud backends sign
+// their own URLs.
+// @Summary      Serve file
+// @Tags         files
+// @Produce      octet-stream
+// @Param        file_name  path      string  true   "File key"
+// @Param        bucket     query     string  false  "Private bucket of a signed URL"
+// @Param        expires    query     int     false  "Expiry of a signed URL (unix time)"
+// @Param        signature  query     string  false  "Signature of a signed URL"
+// @Success      200
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /files/{file_name} [get]
 func (FileController) Serve(ctx *gin.Context) {
-	object, err := services.MinioClient.GetObject(context.Background(),
-		"reportage-snapp",
-		ctx.Param("file_name"), minio.GetObjectOptions{})
-	if err != nil {
-		print(err.Error())
-		ctx.JSON(404, gin.H{
-			"message": "error",
-		})
-		return
+	key := strings.TrimPrefix(ctx.Param("file_name"), "/")
+	bucket := services.PhotoBucket
+	if signedBucket := ctx.Query("bucket"); signedBucket != "" {
+		local, isLocal := services.FileStorage.(*services.LocalStorage)
+		if !isLocal || !local.Verify(signedBucket, key, ctx.Query("expires"), ctx.Query("signature")) {
+			ctx.JSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.Forbidden,
+				Message: "Invalid or expired link",
+			})
+			return
+		}
+		bucket = signedBucket
 	}
-	_, err = object.Stat()
-	if err != nil {
-		ctx.JSON(404, gin.H{
-			"message": "error",
+
+	file, info, err := services.FileStorage.Get(ctx.Request.Context(), bucket, key)
+	if err == services.ErrObjectNotFound {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "File not found",
 		})
 		return
 	}
-	buf := bytes.NewBuffer(nil)
-	_, err = io.Copy(buf, object)
 	if err != nil {
-		print(err.Error())
-		ctx.JSON(500, gin.H{
-			"message": "error",
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to read the file",
 		})
 		return
-	} // Error handling elided for brevity.
+	}
+	defer file.Close()
 
-	ctx.Data(200, http.DetectContentType(buf.Bytes()), buf.Bytes())
+	ctx.DataFromReader(http.StatusOK, info.Size, info.ContentType, file, nil)
 }
@@ -0,0 +1,613 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuController struct{}
+
+// GetVenueMenus returns the active menus of a venue
+// @Summary      Get venue menus
+// @Tags         menus
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        dietary        query     string  false  "Only items with this dietary tag (vegan, halal, gluten-free, ...)"
+// @Success      200  {object}  serializers.VenueMenusResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/menus [get]
+func (MenuController) GetVenueMenus(ctx *gin.Context) {
+	venueID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	dietary := strings.ToLower(strings.TrimSpace(ctx.Query("dietary")))
+	if dietary != "" && !models.IsValidDietaryTag(dietary) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Dietary tag must be one of: " + strings.Join(models.DietaryTags, ", "),
+		})
+		return
+	}
+
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+
+	menus, err := models.GetVenueMenus(ctx.Request.Context(), venueID, true, dietary)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get menus",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VenueMenusResponse{
+		VenueID: venueID,
+		Menus:   menus,
+	})
+}
+
+// GetOwnerMenus returns all menus of an owned venue, including inactive ones
+// @Summary      Get owned venue menus
+// @Tags         menus
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Success      200  {object}  serializers.VenueMenusResponse
+// @Failure      403  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus [get]
+func (MenuController) GetOwnerMenus(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menus, err := models.GetVenueMenus(ctx.Request.Context(), venue.ID, false, "")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get menus",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VenueMenusResponse{
+		VenueID: venue.ID,
+		Menus:   menus,
+	})
+}
+
+// CreateMenu creates a menu for an owned venue
+// @Summary      Create venue menu
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu           body      serializers.MenuRequest  true  "Menu data"
+// @Success      201  {object}  models.VenueMenu
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus [post]
+func (MenuController) CreateMenu(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid menu data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	menu := request.ToMenu(venue.ID)
+	if err := menu.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create menu",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, menu)
+}
+
+// UpdateMenu updates a menu of an owned venue
+// @Summary      Update venue menu
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        menu           body      serializers.MenuRequest  true  "Menu data"
+// @Success      200  {object}  models.VenueMenu
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id} [put]
+func (MenuController) UpdateMenu(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid menu data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	updated := request.ToMenu(venue.ID)
+	updated.ID = menu.ID
+	updated.CreatedAt = menu.CreatedAt
+	if err := updated.Update(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update menu",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteMenu deletes a menu of an owned venue with all its sections and items
+// @Summary      Delete venue menu
+// @Tags         menus
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id} [delete]
+func (MenuController) DeleteMenu(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	if err := menu.Delete(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete menu",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Menu deleted",
+	})
+}
+
+// CreateSection adds a section to a menu of an owned venue
+// @Summary      Create menu section
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section        body      serializers.MenuSectionRequest  true  "Section data"
+// @Success      201  {object}  models.MenuSection
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections [post]
+func (MenuController) CreateSection(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuSectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid section data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	section := request.ToSection(menu.ID)
+	if err := section.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create section",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, section)
+}
+
+// UpdateSection updates a menu section of an owned venue
+// @Summary      Update menu section
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section_id     path      int     true   "Section ID"
+// @Param        section        body      serializers.MenuSectionRequest  true  "Section data"
+// @Success      200  {object}  models.MenuSection
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections/{section_id} [put]
+func (MenuController) UpdateSection(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	section, ok := loadMenuSection(ctx, menu.ID)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuSectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid section data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	updated := request.ToSection(menu.ID)
+	updated.ID = section.ID
+	updated.CreatedAt = section.CreatedAt
+	if err := updated.Update(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update section",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteSection deletes a menu section of an owned venue with all its items
+// @Summary      Delete menu section
+// @Tags         menus
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section_id     path      int     true   "Section ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections/{section_id} [delete]
+func (MenuController) DeleteSection(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	section, ok := loadMenuSection(ctx, menu.ID)
+	if !ok {
+		return
+	}
+
+	if err := section.Delete(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete section",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Section deleted",
+	})
+}
+
+// CreateItem adds an item to a menu section of an owned venue
+// @Summary      Create menu item
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section_id     path      int     true   "Section ID"
+// @Param        item           body      serializers.MenuItemRequest  true  "Item data"
+// @Success      201  {object}  models.MenuItem
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections/{section_id}/items [post]
+func (MenuController) CreateItem(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	section, ok := loadMenuSection(ctx, menu.ID)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid item data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	item := request.ToItem(section.ID, venue.ID)
+	if err := item.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create item",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item)
+}
+
+// UpdateItem updates a menu item of an owned venue
+// @Summary      Update menu item
+// @Tags         menus
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section_id     path      int     true   "Section ID"
+// @Param        item_id        path      int     true   "Item ID"
+// @Param        item           body      serializers.MenuItemRequest  true  "Item data"
+// @Success      200  {object}  models.MenuItem
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections/{section_id}/items/{item_id} [put]
+func (MenuController) UpdateItem(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	section, ok := loadMenuSection(ctx, menu.ID)
+	if !ok {
+		return
+	}
+
+	item, ok := loadMenuItem(ctx, section.ID)
+	if !ok {
+		return
+	}
+
+	var request serializers.MenuItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid item data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	updated := request.ToItem(section.ID, venue.ID)
+	updated.ID = item.ID
+	updated.CreatedAt = item.CreatedAt
+	if err := updated.Update(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update item",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteItem deletes a menu item of an owned venue
+// @Summary      Delete menu item
+// @Tags         menus
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        menu_id        path      int     true   "Menu ID"
+// @Param        section_id     path      int     true   "Section ID"
+// @Param        item_id        path      int     true   "Item ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/menus/{menu_id}/sections/{section_id}/items/{item_id} [delete]
+func (MenuController) DeleteItem(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	menu, ok := loadVenueMenu(ctx, venue.ID)
+	if !ok {
+		return
+	}
+
+	section, ok := loadMenuSection(ctx, menu.ID)
+	if !ok {
+		return
+	}
+
+	item, ok := loadMenuItem(ctx, section.ID)
+	if !ok {
+		return
+	}
+
+	if err := item.Delete(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete item",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Item deleted",
+	})
+}
+
+// Helper functions
+
+// loadVenueMenu loads the menu from the :menu_id path parameter, making sure
+// it belongs to the venue
+func loadVenueMenu(ctx *gin.Context, venueID int64) (*models.VenueMenu, bool) {
+	menuID, err := strconv.ParseInt(ctx.Param("menu_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid menu ID",
+		})
+		return nil, false
+	}
+
+	menu := &models.VenueMenu{ID: menuID}
+	if err := menu.GetByID(ctx.Request.Context()); err != nil || menu.VenueID != venueID {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Menu not found",
+		})
+		return nil, false
+	}
+
+	return menu, true
+}
+
+// loadMenuSection loads the section from the :section_id path parameter,
+// making sure it belongs to the menu
+func loadMenuSection(ctx *gin.Context, menuID int64) (*models.MenuSection, bool) {
+	sectionID, err := strconv.ParseInt(ctx.Param("section_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid section ID",
+		})
+		return nil, false
+	}
+
+	section := &models.MenuSection{ID: sectionID}
+	if err := section.GetByID(ctx.Request.Context()); err != nil || section.MenuID != menuID {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Section not found",
+		})
+		return nil, false
+	}
+
+	return section, true
+}
+
+// loadMenuItem loads the item from the :item_id path parameter, making sure
+// it belongs to the section
+func loadMenuItem(ctx *gin.Context, sectionID int64) (*models.MenuItem, bool) {
+	itemID, err := strconv.ParseInt(ctx.Param("item_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid item ID",
+		})
+		return nil, false
+	}
+
+	item := &models.MenuItem{ID: itemID}
+	if err := item.GetByID(ctx.Request.Context()); err != nil || item.SectionID != sectionID {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Item not found",
+		})
+		return nil, false
+	}
+
+	return item, true
+}
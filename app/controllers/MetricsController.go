@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsController struct {
+	DatabasePool *services.DatabasePoolService
+}
+
+// Metrics serves operational metrics for Prometheus
+// @Summary      Operational metrics
+// @Tags         metrics
+// @Produce      plain
+// @Success      200  {string}  string  "Prometheus text format"
+// @Router       /metrics [get]
+func (mc MetricsController) Metrics(ctx *gin.Context) {
+	ctx.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	mc.DatabasePool.WritePoolMetrics(ctx.Writer)
+	services.WriteBreakerMetrics(ctx.Writer)
+}
+
+// readinessPingTimeout bounds the database check of the readiness probe
+const readinessPingTimeout = 2 * time.Second
+
+// Ready tells load balancers whether the instance can serve requests. It
+// can't without the database; open circuit breakers leave it degraded but
+// ready, as their dependencies have fallbacks.
+// @Summary      Readiness probe
+// @Tags         metrics
+// @Produce      json
+// @Success      200  {object}  serializers.ReadinessResponse
+// @Failure      503  {object}  serializers.ReadinessResponse
+// @Router       /readyz [get]
+func (mc MetricsController) Ready(ctx *gin.Context) {
+	response := serializers.ReadinessResponse{
+		Status:   serializers.ReadinessReady,
+		Database: "ok",
+		Breakers: []services.BreakerStatus{},
+	}
+	for _, breaker := range services.Breakers() {
+		status := breaker.Status()
+		if status.State == services.BreakerOpen {
+			response.Status = serializers.ReadinessDegraded
+		}
+		response.Breakers = append(response.Breakers, status)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessPingTimeout)
+	defer cancel()
+	if err := mc.DatabasePool.Ping(pingCtx); err != nil {
+		response.Status = serializers.ReadinessUnavailable
+		response.Database = "unreachable"
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NeighborhoodController struct{}
+
+// CreateNeighborhood adds a neighborhood to a city and places the city's
+// venues within it (admin only)
+// @Summary      Create neighborhood
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        neighborhood   body      serializers.NeighborhoodRequest  true  "Neighborhood data"
+// @Success      201  {object}  serializers.NeighborhoodResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/neighborhoods [post]
+func (NeighborhoodController) CreateNeighborhood(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage neighborhoods",
+		})
+		return
+	}
+
+	var request serializers.NeighborhoodRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid neighborhood data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	neighborhood := request.ToNeighborhood()
+	neighborhoodService := &services.NeighborhoodService{}
+	placed, err := neighborhoodService.CreateNeighborhood(ctx.Request.Context(), neighborhood)
+	switch err {
+	case nil:
+	case services.ErrUnknownCity:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "City not found",
+		})
+		return
+	case models.ErrInvalidBoundary:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Boundary must be a polygon enclosing an area without crossing itself",
+		})
+		return
+	case models.ErrNeighborhoodExists:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "City already has a neighborhood with this name",
+		})
+		return
+	default:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create neighborhood",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.NeighborhoodResponse{
+		Neighborhood: *neighborhood,
+		VenuesPlaced: placed,
+	})
+}
+
+// DiscoverByNeighborhood lists the venues within a neighborhood
+// @Summary      Discover venues by neighborhood
+// @Tags         venues
+// @Produce      json
+// @Param        id             path      int     true   "Neighborhood ID"
+// @Param        category       query     int     false  "Category ID"
+// @Param        sort_by        query     string  false  "Sort by: rating, popularity, newest"
+// @Param        page           query     int     false  "Page number (default 1)"
+// @Param        limit          query     int     false  "Results per page (default 20, max 100)"
+// @Success      200  {object}  serializers.NeighborhoodVenuesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /discover/by-neighborhood/{id} [get]
+func (NeighborhoodController) DiscoverByNeighborhood(ctx *gin.Context) {
+	neighborhoodID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid neighborhood ID",
+		})
+		return
+	}
+
+	neighborhood := &models.Neighborhood{ID: neighborhoodID}
+	err = neighborhood.GetByID(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Neighborhood not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get neighborhood",
+		})
+		return
+	}
+
+	params := models.VenueSearchParams{
+		CityID:         &neighborhood.CityID,
+		NeighborhoodID: &neighborhood.ID,
+		SortBy:         ctx.DefaultQuery("sort_by", "rating"),
+		Page:           1,
+		Limit:          20,
+	}
+
+	if categoryStr := ctx.Query("category"); categoryStr != "" {
+		if categoryID, err := strconv.ParseInt(categoryStr, 10, 64); err == nil {
+			params.CategoryID = &categoryID
+		}
+	}
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			params.Page = page
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
+			params.Limit = limit
+		}
+	}
+
+	venue := &models.Venue{}
+	venues, totalCount, err := venue.Search(ctx.Request.Context(), params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get neighborhood venues",
+		})
+		return
+	}
+	if venues == nil {
+		venues = []models.Venue{}
+	}
+
+	totalPages := (totalCount + params.Limit - 1) / params.Limit
+	ctx.JSON(http.StatusOK, serializers.NeighborhoodVenuesResponse{
+		Neighborhood: *neighborhood,
+		Venues:       venues,
+		Pagination: serializers.PaginationInfo{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      totalCount,
+			TotalPages: totalPages,
+			HasNext:    params.Page < totalPages,
+			HasPrev:    params.Page > 1,
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct{}
+
+// RegisterDevice registers an APNs/FCM token for push notifications
+// @Summary      Register push notification device
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        device         body      serializers.RegisterDeviceRequest  true  "Device data"
+// @Success      201  {object}  models.UserDevice
+// @Failure      400  {object}  serializers.Base
+// @Router       /notifications/{snapp_id}/devices [post]
+func (NotificationController) RegisterDevice(ctx *gin.Context) {
+	var request serializers.RegisterDeviceRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid device data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	device := request.ToDevice(ctx.GetInt64("snappUser_id"))
+	err := device.Register(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to register device",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, device)
+}
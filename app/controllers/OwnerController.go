@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OwnerController serves venue owners their plan and the venue galleries it
+// limits
+type OwnerController struct{}
+
+// GetPlan returns the tier of the authenticated owner and what it allows
+// @Summary      Get owner plan
+// @Tags         owners
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  services.OwnerPlan
+// @Router       /owner/plan [get]
+func (OwnerController) GetPlan(ctx *gin.Context) {
+	planService := &services.OwnerPlanService{}
+	plan, err := planService.GetPlan(ctx.Request.Context(), ctx.GetInt64("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get owner plan",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plan)
+}
+
+// SetOwnerSubscription sets the tier of a venue owner
+// @Summary      Set owner subscription
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id       path      int                                      true  "Owner user ID"
+// @Param        subscription  body      serializers.SetOwnerSubscriptionRequest  true  "Tier"
+// @Success      200  {object}  models.OwnerSubscription
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/owners/{user_id}/subscription [put]
+func (OwnerController) SetOwnerSubscription(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can set owner subscriptions",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	var request serializers.SetOwnerSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Tier must be free or premium",
+		})
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "A subscription must expire in the future",
+		})
+		return
+	}
+
+	subscription := &models.OwnerSubscription{UserID: userID, Tier: request.Tier, ExpiresAt: request.ExpiresAt}
+	err = subscription.Save(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "User not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to set owner subscription",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+// GetVenuePhotos returns the gallery of a venue
+// @Summary      Get venue photos
+// @Tags         venues
+// @Produce      json
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.VenuePhotosResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/photos [get]
+func (OwnerController) GetVenuePhotos(ctx *gin.Context) {
+	venueID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+
+	photos, err := models.GetVenuePhotos(ctx.Request.Context(), venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get venue photos",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VenuePhotosResponse{
+		VenueID: venue.ID,
+		Photos:  photos,
+	})
+}
+
+// GetOwnerVenuePhotos returns the gallery of an owned venue with the plan
+// limiting it
+// @Summary      Get owned venue photos
+// @Tags         owners
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.VenuePhotosResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/photos [get]
+func (OwnerController) GetOwnerVenuePhotos(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	planService := &services.OwnerPlanService{}
+	plan, err := planService.GetVenuePlan(ctx.Request.Context(), venue)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get owner plan",
+		})
+		return
+	}
+
+	photos, err := models.GetVenuePhotos(ctx.Request.Context(), venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get venue photos",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VenuePhotosResponse{
+		VenueID: venue.ID,
+		Photos:  photos,
+		Plan:    plan,
+	})
+}
+
+// AddVenuePhoto adds a photo to the gallery of an owned venue. Venues of free
+// owners have up to services.FreeVenuePhotos photos.
+// @Summary      Add venue photo
+// @Tags         owners
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int                               true  "Venue ID"
+// @Param        photo  body      serializers.AddVenuePhotoRequest  true  "Photo"
+// @Success      201  {object}  serializers.VenuePhotoResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/photos [post]
+func (OwnerController) AddVenuePhoto(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.AddVenuePhotoRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "A photo needs a valid URL",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	photo := &models.VenuePhoto{URL: request.URL, Caption: request.Caption, AddedBy: ctx.GetInt64("user_id")}
+	planService := &services.OwnerPlanService{}
+	plan, err := planService.AddVenuePhoto(ctx.Request.Context(), venue, photo)
+	if err == models.ErrVenuePhotoQuota {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.TierLimitReached,
+			Message: fmt.Sprintf("The %s tier allows %d photos per venue", plan.Tier, plan.Limits.MaxVenuePhotos),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to add venue photo",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.VenuePhotoResponse{
+		Photo: photo,
+		Plan:  plan,
+	})
+}
+
+// DeleteVenuePhoto removes a photo from the gallery of an owned venue
+// @Summary      Delete venue photo
+// @Tags         owners
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      int  true  "Venue ID"
+// @Param        photo_id  path      int  true  "Photo ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/photos/{photo_id} [delete]
+func (OwnerController) DeleteVenuePhoto(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	photoID, err := strconv.ParseInt(ctx.Param("photo_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid photo ID",
+		})
+		return
+	}
+
+	err = models.DeleteVenuePhoto(ctx.Request.Context(), venue.ID, photoID)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Photo not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete venue photo",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Photo deleted",
+	})
+}
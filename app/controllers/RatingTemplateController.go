@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RatingTemplateController manages the detailed rating dimensions of each
+// venue category
+type RatingTemplateController struct{}
+
+// GetRatingTemplates lists the rating templates of the categories that have
+// one
+// @Summary      List rating templates
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  serializers.RatingTemplatesResponse
+// @Failure      403  {object}  serializers.Base
+// @Router       /admin/rating-templates [get]
+func (RatingTemplateController) GetRatingTemplates(ctx *gin.Context) {
+	if !authorizeRatingTemplateAdmin(ctx) {
+		return
+	}
+
+	templates, err := models.GetRatingTemplates(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get rating templates",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.RatingTemplatesResponse{Templates: templates})
+}
+
+// SaveRatingTemplate sets the dimensions reviews of the category's venues
+// rate. Existing reviews keep their ratings.
+// @Summary      Set rating template
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        category_id  path      int                                true  "Category ID"
+// @Param        template     body      serializers.RatingTemplateRequest  true  "Dimensions"
+// @Success      200  {object}  models.RatingTemplate
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/rating-templates/{category_id} [put]
+func (RatingTemplateController) SaveRatingTemplate(ctx *gin.Context) {
+	if !authorizeRatingTemplateAdmin(ctx) {
+		return
+	}
+
+	categoryID, ok := parseTemplateCategoryID(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.RatingTemplateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid rating template data",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	template := request.ToRatingTemplate(categoryID)
+	err := template.Save(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Category not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save rating template",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, template)
+}
+
+// DeleteRatingTemplate removes the category's template, letting reviews rate
+// any dimension again
+// @Summary      Delete rating template
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        category_id  path      int  true  "Category ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/rating-templates/{category_id} [delete]
+func (RatingTemplateController) DeleteRatingTemplate(ctx *gin.Context) {
+	if !authorizeRatingTemplateAdmin(ctx) {
+		return
+	}
+
+	categoryID, ok := parseTemplateCategoryID(ctx)
+	if !ok {
+		return
+	}
+
+	deleted, err := models.DeleteRatingTemplate(ctx.Request.Context(), categoryID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete rating template",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Rating template not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Rating template deleted",
+	})
+}
+
+// authorizeRatingTemplateAdmin writes a 403 unless the caller is an
+// administrator
+func authorizeRatingTemplateAdmin(ctx *gin.Context) bool {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage rating templates",
+		})
+		return false
+	}
+	return true
+}
+
+// parseTemplateCategoryID reads the :category_id path parameter, writing a
+// 400 when it is invalid
+func parseTemplateCategoryID(ctx *gin.Context) (int64, bool) {
+	categoryID, err := strconv.ParseInt(ctx.Param("category_id"), 10, 64)
+	if err != nil || categoryID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid category ID",
+		})
+		return 0, false
+	}
+	return categoryID, true
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationController struct{}
+
+// GetRecommendations returns the venues recommended to the user. Users in
+// the recommendation_ranking experiment are ranked by their variant, named
+// in the response and the X-Experiment-Variant header.
+// @Summary      Get personalized recommendations
+// @Tags         discover
+// @Produce      json
+// @Param        snapp_id     path      string   true   "User Snapp ID"
+// @Param        lat          query     number   false  "Latitude"
+// @Param        lng          query     number   false  "Longitude"
+// @Param        radius       query     number   false  "Radius in km"  default(10)
+// @Param        limit        query     int      false  "Limit"  default(20)
+// @Param        time_of_day  query     string   false  "morning, afternoon, evening or night"
+// @Param        occasion     query     string   false  "casual, date, business or celebration"
+// @Param        group_size   query     int      false  "Group size"
+// @Param        travel_mode  query     string   false  "walking or driving"
+// @Success      200  {object}  serializers.RecommendationsResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /discover/{snapp_id}/recommendations [get]
+func (RecommendationController) GetRecommendations(ctx *gin.Context) {
+	var query serializers.RecommendationsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	userID := ctx.GetInt64("snappUser_id")
+	rc := query.ToContext(userID)
+	experimentService := &services.ExperimentService{}
+	variant, inExperiment := experimentService.Assign(ctx.Request.Context(), services.ExperimentRecommendationRanking, userID)
+	rc.Variant = variant
+
+	engine := &services.RecommendationEngine{}
+	recommendations, err := engine.GetPersonalizedRecommendations(ctx.Request.Context(), rc)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get recommendations",
+		})
+		return
+	}
+
+	response := serializers.RecommendationsResponse{Recommendations: recommendations}
+	if inExperiment {
+		venueIDs := make([]int64, len(recommendations))
+		for i, recommendation := range recommendations {
+			venueIDs[i] = recommendation.Venue.ID
+		}
+		// Serving the recommendations matters more than counting them, and
+		// the ones seen while impersonating the user aren't counted
+		if _, impersonated := models.ImpersonationFromContext(ctx.Request.Context()); !impersonated {
+			experimentService.RecordImpressions(ctx.Request.Context(), services.ExperimentRecommendationRanking, variant, userID, venueIDs)
+		}
+
+		response.Experiment = services.ExperimentRecommendationRanking
+		response.Variant = variant
+		ctx.Header("X-Experiment-Variant", services.ExperimentRecommendationRanking+"="+variant)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetExperimentReport returns the impressions, clicks and conversions of
+// each variant of an experiment, clicks being recommended venues opened
+// and conversions calls and directions asked from them
+// @Summary      Get experiment report
+// @Tags         admin
+// @Produce      json
+// @Param        key   path      string  true   "Experiment key"
+// @Param        days  query     int     false  "Days to report"  default(30)
+// @Success      200  {object}  services.ExperimentReport
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /admin/experiments/{key}/report [get]
+func (RecommendationController) GetExperimentReport(ctx *gin.Context) {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can view experiment reports",
+		})
+		return
+	}
+
+	experiment, exists := services.GetExperiment(ctx.Param("key"))
+	if !exists {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Experiment not found",
+		})
+		return
+	}
+
+	var query serializers.ExperimentReportQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	experimentService := &services.ExperimentService{}
+	since := time.Now().UTC().AddDate(0, 0, -query.Days)
+	report, err := experimentService.Report(ctx.Request.Context(), experiment, since)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get experiment report",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// SubmitFeedback marks a recommended venue as not interesting or already
+// visited, so it isn't recommended to the user again
+// @Summary      Give feedback on a recommendation
+// @Tags         discover
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id  path      string  true  "User Snapp ID"
+// @Param        feedback  body      serializers.RecommendationFeedbackRequest  true  "Venue and feedback"
+// @Success      201  {object}  models.RecommendationFeedback
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /discover/{snapp_id}/feedback [post]
+func (RecommendationController) SubmitFeedback(ctx *gin.Context) {
+	var request serializers.RecommendationFeedbackRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid feedback data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	venue := &models.Venue{ID: request.VenueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+
+	engine := &services.RecommendationEngine{}
+	feedback, err := engine.RecordFeedback(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), *venue, request.Feedback)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save feedback",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, feedback)
+}
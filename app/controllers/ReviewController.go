@@ -1,12 +1,20 @@
 package controllers
 
 import (
+	"database/sql"
+	"fmt"
+	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"strconv"
 	"time"
 	"voting-app/app/models"
 	"voting-app/app/serializers"
+	"voting-app/app/services"
+	"voting-app/app/store"
 )
 
 type ReviewController struct{}
@@ -39,14 +47,40 @@ func (ReviewController) CreateReview(ctx *gin.Context) {
 		return
 	}
 
+	// Detailed ratings must follow the template of the venue's category
+	ratingTemplate, err := models.GetVenueRatingTemplate(ctx.Request.Context(), request.VenueID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get the venue's rating template",
+		})
+		return
+	}
+	if base, isValid := request.ValidateDetailedRatings(ratingTemplate); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	// Screen the text, flagged reviews are published for moderators to check
+	contentFilter := &services.ContentFilterService{}
+	check := contentFilter.Check(ctx.Request.Context(), request.Title, request.ReviewText)
+	if check.Verdict == services.ContentRejected {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.ContentRejected,
+			Message: "Review contains language that is not allowed",
+		})
+		return
+	}
+
 	// Get user ID from context (set by middleware)
 	userID := ctx.GetInt64("snappUser_id")
 
 	// Create review
 	review := request.ToReview()
 	review.UserID = userID
+	review.IsFlagged = check.Verdict == services.ContentFlagged
 
-	err := review.Create()
+	err = review.Create(ctx.Request.Context())
 	if err != nil {
 		if err.Error() == "user has already reviewed this venue" {
 			ctx.JSON(http.StatusBadRequest, serializers.Base{
@@ -56,6 +90,14 @@ func (ReviewController) CreateReview(ctx *gin.Context) {
 			return
 		}
 
+		if err == models.ErrInvalidReviewInvite {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Review invite is invalid, already used or expired",
+			})
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
 			Message: "Failed to create review",
@@ -63,8 +105,23 @@ func (ReviewController) CreateReview(ctx *gin.Context) {
 		return
 	}
 
+	// Copies of the user's other reviews or of other recent reviews are
+	// flagged for moderation, failures don't fail the review
+	duplicateService := &services.ReviewDuplicateService{}
+	duplicateService.Check(ctx.Request.Context(), review)
+
+	// Notify the venue's webhook subscribers, failures don't fail the review
+	webhookService := &services.WebhookService{}
+	webhookService.Publish(ctx.Request.Context(), models.WebhookEventReviewCreated, &review.VenueID, map[string]interface{}{
+		"reviewId":      review.ID,
+		"venueId":       review.VenueID,
+		"overallRating": review.OverallRating,
+		"title":         review.Title,
+		"isVerified":    review.IsVerified,
+	})
+
 	// Get the created review with full details
-	err = review.GetByID()
+	err = review.GetByID(ctx.Request.Context())
 	if err != nil {
 		// Review was created but we couldn't fetch details, still return success
 		ctx.JSON(http.StatusCreated, review)
@@ -74,6 +131,325 @@ func (ReviewController) CreateReview(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, review)
 }
 
+// SaveReviewDraft creates or replaces the user's draft review of a venue
+// @Summary      Save review draft
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        venue_id       path      int     true   "Venue ID"
+// @Param        draft          body      serializers.ReviewDraftRequest  true  "Draft data"
+// @Success      200  {object}  models.ReviewDraft
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /reviews/{snapp_id}/drafts/{venue_id} [put]
+func (ReviewController) SaveReviewDraft(ctx *gin.Context) {
+	venueID, ok := loadDraftVenue(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ReviewDraftRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid draft data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	userID := ctx.GetInt64("snappUser_id")
+	reviewed, err := store.Default.HasUserReviewedVenue(ctx.Request.Context(), venueID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save draft",
+		})
+		return
+	}
+	if reviewed {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyReviewed,
+			Message: "You have already reviewed this venue",
+		})
+		return
+	}
+
+	draft := request.ToDraft(userID, venueID)
+	if err := draft.Save(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save draft",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// GetReviewDraft returns the user's draft review of a venue to resume it
+// @Summary      Get review draft
+// @Tags         reviews
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        venue_id       path      int     true   "Venue ID"
+// @Success      200  {object}  models.ReviewDraft
+// @Failure      404  {object}  serializers.Base
+// @Router       /reviews/{snapp_id}/drafts/{venue_id} [get]
+func (ReviewController) GetReviewDraft(ctx *gin.Context) {
+	venueID, err := strconv.ParseInt(ctx.Param("venue_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
+
+	draft := &models.ReviewDraft{UserID: ctx.GetInt64("snappUser_id"), VenueID: venueID}
+	err = draft.Get(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "No draft saved for this venue",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get draft",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+// UploadReviewPhoto stores a photo for a review. EXIF data like the GPS
+// position is removed and the returned URL goes into the review's photos.
+// The photo is sent as the raw body or as the "photo" field of a form.
+// @Summary      Upload review photo
+// @Tags         reviews
+// @Accept       image/jpeg,image/png,image/gif,multipart/form-data
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        photo          formData  file    false  "Photo, unless sent as the body"
+// @Success      201  {object}  models.Photo
+// @Failure      400  {object}  serializers.Base
+// @Failure      413  {object}  serializers.Base
+// @Router       /reviews/{snapp_id}/photos [post]
+func (ReviewController) UploadReviewPhoto(ctx *gin.Context) {
+	// Leave room for the form encoding around the photo
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxPhotoBytes+64<<10)
+
+	var body io.Reader = ctx.Request.Body
+	if mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+		file, err := ctx.FormFile("photo")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "The photo field is required",
+			})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Failed to read photo",
+			})
+			return
+		}
+		defer opened.Close()
+		body = opened
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, services.MaxPhotoBytes+1))
+	if err != nil || len(data) > services.MaxPhotoBytes {
+		ctx.JSON(http.StatusRequestEntityTooLarge, serializers.Base{
+			Code:    serializers.PayloadTooLarge,
+			Message: fmt.Sprintf("Photos must be at most %d MB", services.MaxPhotoBytes>>20),
+		})
+		return
+	}
+
+	photoService := &services.PhotoService{}
+	photo, err := photoService.UploadReviewPhoto(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), data)
+	if err != nil {
+		if err == services.ErrUnsupportedPhoto || err == services.ErrPhotoTooLarge {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to store photo",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, photo)
+}
+
+// ReorderReviewPhotos puts the photos of the user's review in a new order
+// @Summary      Reorder review photos
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        review_id      path      int     true   "Review ID"
+// @Param        order          body      serializers.ReorderReviewPhotosRequest  true  "Photo URLs in order"
+// @Success      200  {object}  models.VenueReview
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /reviews/{snapp_id}/{review_id}/photos/order [put]
+func (ReviewController) ReorderReviewPhotos(ctx *gin.Context) {
+	review, ok := loadOwnReview(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ReorderReviewPhotosRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The photo URLs are required",
+		})
+		return
+	}
+
+	if err := review.Photos.Reorder(request.URLs); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "List every photo of the review once",
+		})
+		return
+	}
+
+	updateReviewPhotos(ctx, review, false)
+}
+
+// CaptionReviewPhoto captions a photo of the user's review. Captions are
+// screened like review text.
+// @Summary      Caption review photo
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        review_id      path      int     true   "Review ID"
+// @Param        caption        body      serializers.CaptionReviewPhotoRequest  true  "Photo URL and caption"
+// @Success      200  {object}  models.VenueReview
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /reviews/{snapp_id}/{review_id}/photos/caption [put]
+func (ReviewController) CaptionReviewPhoto(ctx *gin.Context) {
+	review, ok := loadOwnReview(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.CaptionReviewPhotoRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The photo URL is required",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	if err := review.Photos.SetCaption(request.URL, request.Caption); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "The review has no photo with this URL",
+		})
+		return
+	}
+
+	// Flagged captions are kept for moderators to check
+	contentFilter := &services.ContentFilterService{}
+	check := contentFilter.Check(ctx.Request.Context(), request.Caption)
+	if check.Verdict == services.ContentRejected {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.ContentRejected,
+			Message: "Caption contains language that is not allowed",
+		})
+		return
+	}
+
+	updateReviewPhotos(ctx, review, check.Verdict == services.ContentFlagged)
+}
+
+// loadOwnReview gets the review of the review_id path parameter, writing a
+// 404 unless it exists and a 403 unless the user wrote it
+func loadOwnReview(ctx *gin.Context) (*models.VenueReview, bool) {
+	reviewID, err := strconv.ParseInt(ctx.Param("review_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid review ID",
+		})
+		return nil, false
+	}
+
+	review := &models.VenueReview{ID: reviewID}
+	if err := review.GetByID(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Review not found",
+			})
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get review",
+		})
+		return nil, false
+	}
+
+	if review.UserID != ctx.GetInt64("snappUser_id") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "You can only change your own reviews",
+		})
+		return nil, false
+	}
+	return review, true
+}
+
+// updateReviewPhotos stores the changed photos of the review and writes it
+func updateReviewPhotos(ctx *gin.Context, review *models.VenueReview, flag bool) {
+	if err := review.UpdatePhotos(ctx.Request.Context(), flag); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update review photos",
+		})
+		return
+	}
+
+	// The photo details follow the new order
+	reviews := []models.VenueReview{*review}
+	models.AttachPhotoDetails(ctx.Request.Context(), reviews)
+	review.PhotoDetails = reviews[0].PhotoDetails
+
+	ctx.JSON(http.StatusOK, review)
+}
+
 // GetVenueReviews gets all reviews for a venue
 // @Summary      Get venue reviews
 // @Tags         reviews
@@ -83,14 +459,16 @@ func (ReviewController) CreateReview(ctx *gin.Context) {
 // @Param        max_rating     query     number  false  "Maximum rating filter"
 // @Param        visit_type     query     string  false  "Visit type filter (dinner, lunch, drinks, etc.)"
 // @Param        has_photos     query     boolean false  "Filter reviews with photos"
-// @Param        sort_by        query     string  false  "Sort by: newest, oldest, rating_high, rating_low, helpful"
+// @Param        sort_by        query     string  false  "Sort by: newest, oldest, rating_high, rating_low, helpful (share of helpful votes, decaying with age)"
+// @Param        viewer         query     string  false  "Snapp ID of the viewer, hides reviews of users they blocked or muted"
+// @Param        translate_to   query     string  false  "Two letter language code to translate the reviews into"
 // @Param        page           query     int     false  "Page number (default 1)"
 // @Param        limit          query     int     false  "Results per page (default 20)"
 // @Success      200  {object}  serializers.ReviewSearchResponse
 // @Failure      400  {object}  serializers.Base
 // @Router       /venues/{venue_id}/reviews [get]
 func (ReviewController) GetVenueReviews(ctx *gin.Context) {
-	venueIDStr := ctx.Param("venue_id")
+	venueIDStr := venueIDParam(ctx)
 	venueID, err := strconv.ParseInt(venueIDStr, 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, serializers.Base{
@@ -100,6 +478,15 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 		return
 	}
 
+	var translation serializers.ReviewTranslationQuery
+	if !bindQuery(ctx, &translation) {
+		return
+	}
+	fields, ok := bindFields(ctx, serializers.ReviewFields)
+	if !ok {
+		return
+	}
+
 	// Parse filters
 	filters := models.ReviewFilters{
 		VenueID: &venueID,
@@ -131,6 +518,14 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 		}
 	}
 
+	// Unknown viewers see every review
+	if viewer := ctx.Query("viewer"); viewer != "" {
+		viewerUser := &models.SnappUser{SnappId: viewer}
+		if exists, err := viewerUser.GetUser(ctx.Request.Context()); err == nil && exists {
+			filters.ViewerID = &viewerUser.Id
+		}
+	}
+
 	// Parse pagination
 	if pageStr := ctx.Query("page"); pageStr != "" {
 		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
@@ -146,7 +541,7 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 
 	// Get reviews
 	review := &models.VenueReview{}
-	reviews, totalCount, err := review.Search(filters)
+	reviews, totalCount, err := review.Search(ctx.Request.Context(), filters)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -154,6 +549,7 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 		})
 		return
 	}
+	translateReviews(ctx, reviews, translation)
 
 	// Calculate pagination
 	totalPages := (totalCount + filters.Limit - 1) / filters.Limit
@@ -173,7 +569,8 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 		Filters: filters,
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	body, err := fields.FilterUnder(response, "reviews")
+	respondPartial(ctx, body, err)
 }
 
 // GetReviewSummary gets review statistics for a venue
@@ -185,7 +582,7 @@ func (ReviewController) GetVenueReviews(ctx *gin.Context) {
 // @Failure      400  {object}  serializers.Base
 // @Router       /venues/{venue_id}/reviews/summary [get]
 func (ReviewController) GetReviewSummary(ctx *gin.Context) {
-	venueIDStr := ctx.Param("venue_id")
+	venueIDStr := venueIDParam(ctx)
 	venueID, err := strconv.ParseInt(venueIDStr, 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, serializers.Base{
@@ -195,7 +592,7 @@ func (ReviewController) GetReviewSummary(ctx *gin.Context) {
 		return
 	}
 
-	summary, err := models.GetVenueReviewSummary(venueID)
+	summary, err := store.Default.GetVenueReviewSummary(ctx.Request.Context(), venueID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -213,6 +610,7 @@ func (ReviewController) GetReviewSummary(ctx *gin.Context) {
 // @Produce      json
 // @Param        snapp_id       path      string  true   "User Snapp ID"
 // @Param        sort_by        query     string  false  "Sort by: newest, oldest, rating_high, rating_low"
+// @Param        translate_to   query     string  false  "Two letter language code to translate the reviews into"
 // @Param        page           query     int     false  "Page number (default 1)"
 // @Param        limit          query     int     false  "Results per page (default 20)"
 // @Success      200  {object}  serializers.ReviewSearchResponse
@@ -221,6 +619,11 @@ func (ReviewController) GetReviewSummary(ctx *gin.Context) {
 func (ReviewController) GetUserReviews(ctx *gin.Context) {
 	userID := ctx.GetInt64("snappUser_id")
 
+	var translation serializers.ReviewTranslationQuery
+	if !bindQuery(ctx, &translation) {
+		return
+	}
+
 	// Parse filters
 	filters := models.ReviewFilters{
 		UserID: &userID,
@@ -244,7 +647,7 @@ func (ReviewController) GetUserReviews(ctx *gin.Context) {
 
 	// Get reviews
 	review := &models.VenueReview{}
-	reviews, totalCount, err := review.Search(filters)
+	reviews, totalCount, err := review.Search(ctx.Request.Context(), filters)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -252,6 +655,7 @@ func (ReviewController) GetUserReviews(ctx *gin.Context) {
 		})
 		return
 	}
+	translateReviews(ctx, reviews, translation)
 
 	// Calculate pagination
 	totalPages := (totalCount + filters.Limit - 1) / filters.Limit
@@ -309,7 +713,7 @@ func (ReviewController) VoteReviewHelpful(ctx *gin.Context) {
 	userID := ctx.GetInt64("snappUser_id")
 
 	review := &models.VenueReview{ID: reviewID}
-	err = review.VoteHelpful(userID, request.IsHelpful)
+	err = review.VoteHelpful(ctx.Request.Context(), userID, request.IsHelpful)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -318,6 +722,12 @@ func (ReviewController) VoteReviewHelpful(ctx *gin.Context) {
 		return
 	}
 
+	// The vote counts even when the author can't be notified
+	if request.IsHelpful {
+		notificationService := &services.NotificationService{}
+		notificationService.NotifyReviewHelpful(ctx.Request.Context(), reviewID, userID)
+	}
+
 	ctx.JSON(http.StatusOK, serializers.Base{
 		Code:    serializers.Success,
 		Message: "Vote recorded successfully",
@@ -362,7 +772,7 @@ func (ReviewController) UpdateReview(ctx *gin.Context) {
 
 	// Check if review exists and belongs to user
 	review := &models.VenueReview{ID: reviewID}
-	err = review.GetByID()
+	err = review.GetByID(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, serializers.Base{
 			Code:    serializers.NotFound,
@@ -418,7 +828,7 @@ func (ReviewController) DeleteReview(ctx *gin.Context) {
 
 	// Check if review exists and belongs to user
 	review := &models.VenueReview{ID: reviewID}
-	err = review.GetByID()
+	err = review.GetByID(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, serializers.Base{
 			Code:    serializers.NotFound,
@@ -451,9 +861,16 @@ func (ReviewController) DeleteReview(ctx *gin.Context) {
 // @Param        city           query     int     false  "Filter by city"
 // @Param        time_period    query     string  false  "Time period: today, week, month (default week)"
 // @Param        limit          query     int     false  "Number of results (default 20)"
+// @Param        translate_to   query     string  false  "Two letter language code to translate the reviews into"
 // @Success      200  {object}  []models.VenueReview
+// @Failure      400  {object}  serializers.Base
 // @Router       /reviews/trending [get]
 func (ReviewController) GetTrendingReviews(ctx *gin.Context) {
+	var translation serializers.ReviewTranslationQuery
+	if !bindQuery(ctx, &translation) {
+		return
+	}
+
 	limit := 20
 	if limitStr := ctx.Query("limit"); limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
@@ -491,7 +908,7 @@ func (ReviewController) GetTrendingReviews(ctx *gin.Context) {
 	}
 
 	review := &models.VenueReview{}
-	reviews, _, err := review.Search(filters)
+	reviews, _, err := review.Search(ctx.Request.Context(), filters)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -499,6 +916,320 @@ func (ReviewController) GetTrendingReviews(ctx *gin.Context) {
 		})
 		return
 	}
+	translateReviews(ctx, reviews, translation)
 
 	ctx.JSON(http.StatusOK, reviews)
 }
+
+// GetFeaturedReviews returns the home feed's featured reviews: editorial
+// picks and the most helpful reviews of the week. The selection is the same
+// for every user and may be cached for a few minutes.
+// @Summary      Get featured reviews
+// @Tags         reviews
+// @Produce      json
+// @Param        city           query     int     false  "Filter by city"
+// @Success      200  {object}  serializers.FeaturedReviewsResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /reviews/featured [get]
+func (ReviewController) GetFeaturedReviews(ctx *gin.Context) {
+	var query serializers.FeaturedReviewsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	featuredReviewService := &services.FeaturedReviewService{}
+	featured, err := featuredReviewService.GetFeaturedReviews(ctx.Request.Context(), query.City)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get featured reviews",
+		})
+		return
+	}
+
+	// Shared caches may keep the selection until it is recomputed
+	maxAge := services.FeaturedReviewsTTL - time.Since(featured.GeneratedAt)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	ctx.Writer.Header().Add("Vary", "X-Tenant")
+
+	ctx.JSON(http.StatusOK, serializers.FeaturedReviewsResponse{
+		Reviews:     featured.Reviews,
+		CityID:      query.City,
+		GeneratedAt: featured.GeneratedAt,
+	})
+}
+
+// CreateReviewInvites generates single-use review invites for an owned venue.
+// Reviews written with an invite are marked as verified visits.
+// @Summary      Create review invites
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        invites        body      serializers.ReviewInviteRequest  false  "Invite options"
+// @Success      201  {object}  serializers.ReviewInvitesResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /owner/venues/{id}/review-invites [post]
+func (ReviewController) CreateReviewInvites(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ReviewInviteRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Invalid invite data",
+			})
+			return
+		}
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	expiresAt := time.Now().UTC().AddDate(0, 0, request.ExpiresInDays)
+	invites, err := models.CreateReviewInvites(ctx.Request.Context(), venue.ID, ctx.GetInt64("user_id"), request.Count, expiresAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create review invites",
+		})
+		return
+	}
+
+	for i := range invites {
+		invites[i].InviteURL = services.VenuePageURL(venue.Slug) + "/review?invite=" + invites[i].Token
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.ReviewInvitesResponse{
+		VenueID: venue.ID,
+		Invites: invites,
+	})
+}
+
+// loadDraftVenue validates the venue_id path parameter of the draft routes.
+// The error response is written when the venue doesn't exist.
+func loadDraftVenue(ctx *gin.Context) (int64, bool) {
+	venueID, err := strconv.ParseInt(ctx.Param("venue_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return 0, false
+	}
+
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return 0, false
+	}
+
+	return venueID, true
+}
+
+// translateReviews translates the listed reviews when a language was asked
+// for with translate_to
+func translateReviews(ctx *gin.Context, reviews []models.VenueReview, query serializers.ReviewTranslationQuery) {
+	if query.TranslateTo == "" {
+		return
+	}
+	translationService := &services.TranslationService{}
+	translationService.TranslateReviews(ctx.Request.Context(), reviews, query.TranslateTo)
+}
+
+// ExportVenueReviews downloads every approved review of the venue as CSV or
+// as a JSON array, streamed as the reviews are read (owner or admin). Venues with too many reviews to stream right away get
+// the export generated in the background: the response is 202 with the
+// URL to poll until it can be downloaded.
+// @Summary      Export venue reviews
+// @Tags         reviews
+// @Produce      text/csv
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Venue ID"
+// @Param        format         query     string  false  "Export format, csv or json"
+// @Success      200  {string}  string  "CSV or JSON file"
+// @Success      202  {object}  serializers.ReviewExportResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/reviews/export [get]
+func (ReviewController) ExportVenueReviews(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var query serializers.ReviewExportQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	exportService := &services.ReviewExportService{}
+	inBackground, err := exportService.ExportsInBackground(ctx.Request.Context(), venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to export reviews",
+		})
+		return
+	}
+
+	if inBackground {
+		export, err := exportService.Queue(ctx.Request.Context(), venue.ID, ctx.GetInt64("user_id"), query.Format)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to queue the review export",
+			})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, reviewExportResponse(ctx, export))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-reviews.%s"`, venue.Slug, query.Format))
+	ctx.Header("Content-Type", services.ReviewExportContentType(query.Format))
+	if _, err := exportService.Write(ctx.Request.Context(), venue.ID, query.Format, ctx.Writer); err != nil && !ctx.Writer.Written() {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to export reviews",
+		})
+	}
+}
+
+// GetReviewExport returns the status of a review export generated in the
+// background (owner or admin)
+// @Summary      Get review export
+// @Tags         reviews
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Venue ID"
+// @Param        export_id      path      int     true   "Export ID"
+// @Success      200  {object}  serializers.ReviewExportResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/reviews/exports/{export_id} [get]
+func (ReviewController) GetReviewExport(ctx *gin.Context) {
+	export, ok := loadReviewExport(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reviewExportResponse(ctx, export))
+}
+
+// DownloadReviewExport downloads the file of a completed review export
+// (owner or admin)
+// @Summary      Download review export
+// @Tags         reviews
+// @Produce      text/csv
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int     true   "Venue ID"
+// @Param        export_id      path      int     true   "Export ID"
+// @Success      200  {string}  string  "CSV or JSON file"
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Failure      409  {object}  serializers.Base
+// @Router       /venues/{id}/reviews/exports/{export_id}/download [get]
+func (ReviewController) DownloadReviewExport(ctx *gin.Context) {
+	export, ok := loadReviewExport(ctx)
+	if !ok {
+		return
+	}
+	if export.Status != models.ReviewExportCompleted {
+		ctx.JSON(http.StatusConflict, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The export is " + export.Status,
+		})
+		return
+	}
+
+	file, err := new(services.ReviewExportService).Open(ctx.Request.Context(), export)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to open the export",
+		})
+		return
+	}
+	defer file.Close()
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="reviews-%d.%s"`, export.ID, export.Format))
+	ctx.DataFromReader(http.StatusOK, -1, services.ReviewExportContentType(export.Format), file, nil)
+}
+
+// loadReviewExport loads the export of the export_id path parameter once the
+// caller is authorized for its venue. The error response is written when it
+// can't be loaded.
+func loadReviewExport(ctx *gin.Context) (*models.ReviewExport, bool) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	exportID, err := strconv.ParseInt(ctx.Param("export_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid export ID",
+		})
+		return nil, false
+	}
+
+	export := &models.ReviewExport{ID: exportID, VenueID: venue.ID}
+	if err := export.Get(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Export not found",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get the export",
+			})
+		}
+		return nil, false
+	}
+	return export, true
+}
+
+func reviewExportResponse(ctx *gin.Context, export *models.ReviewExport) serializers.ReviewExportResponse {
+	statusURL := fmt.Sprintf("/v1/venues/%d/reviews/exports/%d", export.VenueID, export.ID)
+	response := serializers.ReviewExportResponse{Export: export, StatusURL: statusURL}
+	if export.Status == models.ReviewExportCompleted {
+		response.DownloadURL = statusURL + "/download"
+		// The authenticated download still works when signing fails
+		if signedURL, err := new(services.ReviewExportService).SignedURL(ctx.Request.Context(), export); err == nil {
+			response.SignedURL = signedURL
+		} else {
+			sentry.CaptureException(err)
+		}
+	}
+	return response
+}
+
+// venueIDParam is the venue of the route, named venue_id under /v1 and id
+// under /v2 where the venue routes share their wildcard
+func venueIDParam(ctx *gin.Context) string {
+	if venueID := ctx.Param("venue_id"); venueID != "" {
+		return venueID
+	}
+	return ctx.Param("id")
+}
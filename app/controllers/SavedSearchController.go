@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SavedSearchController struct{}
+
+// CreateSavedSearch saves a venue search with new match alerts
+// @Summary      Save a venue search
+// @Tags         saved-searches
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        search         body      serializers.SavedSearchRequest  true  "Search query and filters"
+// @Success      201  {object}  models.SavedSearch
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/saved-searches [post]
+func (SavedSearchController) CreateSavedSearch(ctx *gin.Context) {
+	var request serializers.SavedSearchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid saved search data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	userID := ctx.GetInt64("snappUser_id")
+
+	count, err := models.CountUserSavedSearches(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save search",
+		})
+		return
+	}
+	if count >= serializers.MaxSavedSearchesPerUser {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "You can save at most 20 searches",
+		})
+		return
+	}
+
+	search := request.ToSavedSearch(userID)
+	err = search.Create(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save search",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, search)
+}
+
+// GetSavedSearches lists the user's saved searches with unread match counts
+// @Summary      List saved searches
+// @Tags         saved-searches
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.SavedSearchListResponse
+// @Router       /users/{snapp_id}/saved-searches [get]
+func (SavedSearchController) GetSavedSearches(ctx *gin.Context) {
+	searches, err := models.GetUserSavedSearches(ctx.Request.Context(), ctx.GetInt64("snappUser_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get saved searches",
+		})
+		return
+	}
+
+	response := serializers.SavedSearchListResponse{SavedSearches: searches}
+	for _, search := range searches {
+		response.TotalNewMatches += search.NewMatchesCount
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetSavedSearchMatches lists the venues that matched a saved search and
+// marks them as read
+// @Summary      Get saved search matches
+// @Tags         saved-searches
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        search_id      path      int     true   "Saved search ID"
+// @Success      200  {object}  serializers.SavedSearchMatchesResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /users/{snapp_id}/saved-searches/{search_id}/matches [get]
+func (SavedSearchController) GetSavedSearchMatches(ctx *gin.Context) {
+	search, ok := loadSavedSearch(ctx)
+	if !ok {
+		return
+	}
+
+	matches, err := search.GetMatches(ctx.Request.Context(), 50)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get saved search matches",
+		})
+		return
+	}
+
+	// Matches are returned with their previous read state
+	err = search.MarkMatchesRead(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get saved search matches",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.SavedSearchMatchesResponse{
+		SavedSearch: *search,
+		Matches:     matches,
+	})
+}
+
+// DeleteSavedSearch removes a saved search
+// @Summary      Delete saved search
+// @Tags         saved-searches
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        search_id      path      int     true   "Saved search ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /users/{snapp_id}/saved-searches/{search_id} [delete]
+func (SavedSearchController) DeleteSavedSearch(ctx *gin.Context) {
+	search, ok := loadSavedSearch(ctx)
+	if !ok {
+		return
+	}
+
+	err := search.Delete(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete saved search",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Saved search deleted",
+	})
+}
+
+// loadSavedSearch loads the saved search of the search_id param, making sure
+// it belongs to the current user
+func loadSavedSearch(ctx *gin.Context) (*models.SavedSearch, bool) {
+	searchID, err := strconv.ParseInt(ctx.Param("search_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid saved search ID",
+		})
+		return nil, false
+	}
+
+	search := &models.SavedSearch{ID: searchID, UserID: ctx.GetInt64("snappUser_id")}
+	if err := search.GetByID(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Saved search not found",
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get saved search",
+			})
+		}
+		return nil, false
+	}
+
+	return search, true
+}
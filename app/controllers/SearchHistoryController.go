@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHistoryController lets users see and clear the searches recorded
+// about them, and stop them being recorded
+type SearchHistoryController struct{}
+
+// GetSearchHistory lists the user's recorded venue searches, newest first
+// @Summary      Get search history
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        page           query     int     false  "Page number (default 1)"
+// @Param        limit          query     int     false  "Results per page (default 20, max 100)"
+// @Success      200  {object}  serializers.SearchHistoryResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/search-history [get]
+func (SearchHistoryController) GetSearchHistory(ctx *gin.Context) {
+	var query serializers.SearchHistoryQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	userID := ctx.GetInt64("snappUser_id")
+	settings, err := models.GetPrivacySettings(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get search history",
+		})
+		return
+	}
+
+	history, total, err := models.GetSearchHistory(ctx.Request.Context(), userID,
+		query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get search history",
+		})
+		return
+	}
+
+	totalPages := (total + query.Limit - 1) / query.Limit
+	ctx.JSON(http.StatusOK, serializers.SearchHistoryResponse{
+		History:              history,
+		SearchHistoryEnabled: settings.SearchHistoryEnabled,
+		Pagination: serializers.PaginationInfo{
+			Page:       query.Page,
+			Limit:      query.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    query.Page < totalPages,
+			HasPrev:    query.Page > 1,
+		},
+	})
+}
+
+// ClearSearchHistory deletes the user's recorded searches
+// @Summary      Clear search history
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.Base
+// @Router       /users/{snapp_id}/search-history [delete]
+func (SearchHistoryController) ClearSearchHistory(ctx *gin.Context) {
+	if _, err := models.ClearSearchHistory(ctx.Request.Context(), ctx.GetInt64("snappUser_id")); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to clear search history",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Search history cleared",
+	})
+}
+
+// GetPrivacySettings returns the user's privacy settings
+// @Summary      Get privacy settings
+// @Tags         users
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  models.PrivacySettings
+// @Router       /users/{snapp_id}/privacy [get]
+func (SearchHistoryController) GetPrivacySettings(ctx *gin.Context) {
+	settings, err := models.GetPrivacySettings(ctx.Request.Context(), ctx.GetInt64("snappUser_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get privacy settings",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// UpdatePrivacySettings changes the user's privacy settings. Turning the
+// search history off stops recording searches, the recorded ones are kept
+// until cleared.
+// @Summary      Update privacy settings
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        settings       body      serializers.PrivacySettingsRequest  true  "Privacy settings"
+// @Success      200  {object}  models.PrivacySettings
+// @Failure      400  {object}  serializers.Base
+// @Router       /users/{snapp_id}/privacy [put]
+func (SearchHistoryController) UpdatePrivacySettings(ctx *gin.Context) {
+	var request serializers.PrivacySettingsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid privacy settings",
+		})
+		return
+	}
+
+	settings := &models.PrivacySettings{
+		UserID:               ctx.GetInt64("snappUser_id"),
+		SearchHistoryEnabled: *request.SearchHistoryEnabled,
+	}
+	if err := settings.Save(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update privacy settings",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SocialController struct{}
+
+// BlockUser blocks a user, hiding their activity and stopping them from
+// following or replying to the blocker
+// @Summary      Block user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "User ID to block"
+// @Success      201  {object}  models.UserRelation
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/block/{target_user_id} [post]
+func (SocialController) BlockUser(ctx *gin.Context) {
+	createUserRelation(ctx, models.RelationBlock)
+}
+
+// UnblockUser removes a block
+// @Summary      Unblock user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "Blocked user ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/block/{target_user_id} [delete]
+func (SocialController) UnblockUser(ctx *gin.Context) {
+	deleteUserRelation(ctx, models.RelationBlock)
+}
+
+// MuteUser hides a user's activity without blocking them
+// @Summary      Mute user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "User ID to mute"
+// @Success      201  {object}  models.UserRelation
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/mute/{target_user_id} [post]
+func (SocialController) MuteUser(ctx *gin.Context) {
+	createUserRelation(ctx, models.RelationMute)
+}
+
+// UnmuteUser removes a mute
+// @Summary      Unmute user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "Muted user ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/mute/{target_user_id} [delete]
+func (SocialController) UnmuteUser(ctx *gin.Context) {
+	deleteUserRelation(ctx, models.RelationMute)
+}
+
+// GetUserRelations lists the users the user blocked and muted
+// @Summary      Get blocked and muted users
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Success      200  {object}  serializers.UserRelationsResponse
+// @Router       /social/{snapp_id}/blocked [get]
+func (SocialController) GetUserRelations(ctx *gin.Context) {
+	userID := ctx.GetInt64("snappUser_id")
+
+	blocked, err := models.GetUserRelations(ctx.Request.Context(), userID, models.RelationBlock)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get blocked users",
+		})
+		return
+	}
+
+	muted, err := models.GetUserRelations(ctx.Request.Context(), userID, models.RelationMute)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get muted users",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.UserRelationsResponse{
+		Blocked: blocked,
+		Muted:   muted,
+	})
+}
+
+// FollowUser follows a user's reviews and check-ins
+// @Summary      Follow user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "User ID to follow"
+// @Success      201  {object}  models.UserFollow
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/follow/{target_user_id} [post]
+func (SocialController) FollowUser(ctx *gin.Context) {
+	targetUserID, ok := loadTargetUser(ctx)
+	if !ok {
+		return
+	}
+
+	follow := &models.UserFollow{
+		FollowerID:  ctx.GetInt64("snappUser_id"),
+		FollowingID: targetUserID,
+	}
+	err := follow.Create(ctx.Request.Context())
+	if err == models.ErrUserBlocked {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "You cannot follow this user",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to follow user",
+		})
+		return
+	}
+
+	// The follow stands even when the user can't be notified
+	notificationService := &services.NotificationService{}
+	notificationService.NotifyNewFollower(ctx.Request.Context(), follow.FollowingID, follow.FollowerID)
+
+	ctx.JSON(http.StatusCreated, follow)
+}
+
+// UnfollowUser stops following a user
+// @Summary      Unfollow user
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id        path      string  true   "User Snapp ID"
+// @Param        target_user_id  path      int     true   "Followed user ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /social/{snapp_id}/follow/{target_user_id} [delete]
+func (SocialController) UnfollowUser(ctx *gin.Context) {
+	targetUserID, err := strconv.ParseInt(ctx.Param("target_user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	follow := &models.UserFollow{
+		FollowerID:  ctx.GetInt64("snappUser_id"),
+		FollowingID: targetUserID,
+	}
+	deleted, err := follow.Delete(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to unfollow user",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "You do not follow this user",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "User unfollowed",
+	})
+}
+
+// GetSocialFeed returns the reviews and check-ins of followed users, without
+// blocked or muted users
+// @Summary      Get social feed
+// @Tags         social
+// @Produce      json
+// @Param        snapp_id       path      string  true   "User Snapp ID"
+// @Param        page           query     int     false  "Page number (default 1)"
+// @Param        limit          query     int     false  "Results per page (default 20)"
+// @Success      200  {object}  serializers.SocialFeedResponse
+// @Router       /social/{snapp_id}/feed [get]
+func (SocialController) GetSocialFeed(ctx *gin.Context) {
+	page, limit := 1, 20
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	// Fetch one extra activity to know whether there is a next page
+	activities, err := models.GetSocialFeed(ctx.Request.Context(), ctx.GetInt64("snappUser_id"), limit+1, (page-1)*limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get social feed",
+		})
+		return
+	}
+
+	hasNext := len(activities) > limit
+	if hasNext {
+		activities = activities[:limit]
+	}
+
+	ctx.JSON(http.StatusOK, serializers.SocialFeedResponse{
+		Activities: activities,
+		Page:       page,
+		Limit:      limit,
+		HasNext:    hasNext,
+	})
+}
+
+// createUserRelation blocks or mutes the target user
+func createUserRelation(ctx *gin.Context, relationType string) {
+	targetUserID, ok := loadTargetUser(ctx)
+	if !ok {
+		return
+	}
+
+	relation := &models.UserRelation{
+		UserID:       ctx.GetInt64("snappUser_id"),
+		TargetUserID: targetUserID,
+		Type:         relationType,
+	}
+	if err := relation.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to " + relationType + " user",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, relation)
+}
+
+// deleteUserRelation unblocks or unmutes the target user
+func deleteUserRelation(ctx *gin.Context, relationType string) {
+	targetUserID, err := strconv.ParseInt(ctx.Param("target_user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	relation := &models.UserRelation{
+		UserID:       ctx.GetInt64("snappUser_id"),
+		TargetUserID: targetUserID,
+		Type:         relationType,
+	}
+	deleted, err := relation.Delete(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to un" + relationType + " user",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "User is not " + relationType + "d",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "User un" + relationType + "d",
+	})
+}
+
+// loadTargetUser validates the target_user_id param, which must be an
+// existing user other than the acting one
+func loadTargetUser(ctx *gin.Context) (int64, bool) {
+	targetUserID, err := strconv.ParseInt(ctx.Param("target_user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid user ID",
+		})
+		return 0, false
+	}
+
+	if targetUserID == ctx.GetInt64("snappUser_id") {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "You cannot do this to yourself",
+		})
+		return 0, false
+	}
+
+	target := &models.SnappUser{Id: targetUserID}
+	exists, err := target.GetByID(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get user",
+		})
+		return 0, false
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "User not found",
+		})
+		return 0, false
+	}
+
+	return targetUserID, true
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncController serves the changes mobile clients apply to their offline
+// copies of venues, reviews and campaigns
+type SyncController struct{}
+
+// Sync returns the venues, reviews and campaigns that changed since the
+// client last synced, oldest first, and the ones to drop. Clients page
+// through the changes with the returned cursor and send the last one on
+// their next sync. With wait set and no changes yet, the request waits for
+// them for up to that many seconds.
+// @Summary      Sync changes
+// @Tags         sync
+// @Produce      json
+// @Param        since   query     string  false  "RFC 3339 time of the cached data, on the first sync"
+// @Param        cursor  query     string  false  "Cursor of the previous sync or page"
+// @Param        city    query     int     false  "City ID"
+// @Param        limit   query     int     false  "Changes per page (default 100, max 500)"
+// @Param        wait    query     int     false  "Seconds to wait for changes (max 30)"
+// @Success      200  {object}  serializers.SyncResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      410  {object}  serializers.Base
+// @Router       /sync [get]
+func (SyncController) Sync(ctx *gin.Context) {
+	var query serializers.SyncQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	position, err := query.Position()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid sync cursor",
+		})
+		return
+	}
+
+	syncService := &services.SyncService{}
+	page, err := syncService.Changes(ctx.Request.Context(), query.CityID, position, query.Limit, time.Duration(query.Wait)*time.Second)
+	if err == services.ErrSyncExpired {
+		ctx.JSON(http.StatusGone, serializers.Base{
+			Code:    serializers.SyncExpired,
+			Message: "Last synced too long ago, fetch everything again",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get changes",
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, serializers.NewSyncResponse(page))
+}
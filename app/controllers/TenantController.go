@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantController serves the configuration of the city brands hosted on
+// the platform
+type TenantController struct{}
+
+// GetTenant returns the branding and configuration of the request's tenant,
+// resolved from the X-Tenant header or the host
+// @Summary      Get tenant configuration
+// @Tags         tenants
+// @Produce      json
+// @Param        X-Tenant  header    string  false  "Tenant slug, defaults to the host's tenant"
+// @Success      200       {object}  serializers.TenantConfigResponse
+// @Failure      404       {object}  serializers.Base
+// @Router       /tenant [get]
+func (TenantController) GetTenant(ctx *gin.Context) {
+	tenant, exists := ctx.Get("tenant")
+	if !exists {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Unknown tenant",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.NewTenantConfigResponse(tenant.(*models.Tenant)))
+}
+
+// CreateTenant creates a tenant
+// @Summary      Create tenant
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        tenant  body      serializers.TenantRequest  true  "Tenant"
+// @Success      201     {object}  models.Tenant
+// @Failure      400     {object}  serializers.Base
+// @Failure      403     {object}  serializers.Base
+// @Router       /admin/tenants [post]
+func (TenantController) CreateTenant(ctx *gin.Context) {
+	if !authorizeTenantAdmin(ctx) {
+		return
+	}
+
+	request, ok := bindTenantRequest(ctx)
+	if !ok {
+		return
+	}
+	if base, isValid := request.ValidSlug(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	tenant := request.ToTenant(0)
+	tenantService := &services.TenantService{}
+	err := tenantService.CreateTenant(ctx.Request.Context(), tenant)
+	if err == models.ErrTenantExists {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.TenantAlreadyExists,
+			Message: "A tenant with this slug already exists",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create tenant",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, tenant)
+}
+
+// UpdateTenant replaces the hostnames and configuration of a tenant. Changes
+// apply to all instances within the tenant cache TTL.
+// @Summary      Update tenant
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int  true  "Tenant ID"
+// @Param        tenant  body      serializers.TenantRequest  true  "Tenant"
+// @Success      200     {object}  models.Tenant
+// @Failure      400     {object}  serializers.Base
+// @Failure      403     {object}  serializers.Base
+// @Failure      404     {object}  serializers.Base
+// @Router       /admin/tenants/{id} [put]
+func (TenantController) UpdateTenant(ctx *gin.Context) {
+	if !authorizeTenantAdmin(ctx) {
+		return
+	}
+
+	tenantID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid tenant ID",
+		})
+		return
+	}
+
+	request, ok := bindTenantRequest(ctx)
+	if !ok {
+		return
+	}
+
+	tenant := request.ToTenant(tenantID)
+	// Requests no other tenant claims fall back to the default tenant
+	if tenant.ID == models.DefaultTenantID && !tenant.IsActive {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "The default tenant can't be deactivated",
+		})
+		return
+	}
+
+	tenantService := &services.TenantService{}
+	err = tenantService.UpdateTenant(ctx.Request.Context(), tenant)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Tenant not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update tenant",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tenant)
+}
+
+// authorizeTenantAdmin writes a 403 unless the caller is an administrator
+func authorizeTenantAdmin(ctx *gin.Context) bool {
+	if !ctx.GetBool("is_superuser") {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only administrators can manage tenants",
+		})
+		return false
+	}
+	return true
+}
+
+// bindTenantRequest binds and validates the tenant request, writing a 400
+// when it is invalid
+func bindTenantRequest(ctx *gin.Context) (serializers.TenantRequest, bool) {
+	var request serializers.TenantRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid tenant data",
+		})
+		return request, false
+	}
+
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return request, false
+	}
+	return request, true
+}
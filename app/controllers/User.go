@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"database/sql"
 	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
 	"voting-app/app/models"
 	"voting-app/app/serializers"
+	"voting-app/app/services"
 )
 
 type User struct {
@@ -25,12 +29,17 @@ func (User) Register(ctx *gin.Context) {
 		ctx.JSON(500, serializers.Base{Message: serializers.InternalError})
 		return
 	}
-	err = user.Create()
+	err = user.Create(ctx.Request.Context())
 	if err != nil {
 		sentry.CaptureException(err)
 		ctx.JSON(500, serializers.Base{Message: serializers.InternalError})
 		return
 	}
+	// The account exists either way, a lost email can be sent again
+	userTokenService := &services.UserTokenService{}
+	if err := userTokenService.SendEmailVerification(ctx.Request.Context(), &user); err != nil {
+		sentry.CaptureException(err)
+	}
 	ctx.JSON(200, serializers.Base{Message: serializers.Success})
 }
 
@@ -43,7 +52,7 @@ func (User) Login(ctx *gin.Context) {
 	user := models.User{
 		Email: request.Email,
 	}
-	err := user.Get()
+	err := user.Get(ctx.Request.Context())
 	if err != nil {
 		sentry.CaptureException(err)
 		ctx.JSON(404, serializers.Base{Message: serializers.NotFound})
@@ -54,6 +63,13 @@ func (User) Login(ctx *gin.Context) {
 		ctx.JSON(403, serializers.Base{Message: serializers.WrongPassword})
 		return
 	}
+	if user.EmailVerifiedAt == nil {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.EmailNotVerified,
+			Message: "Verify your email before logging in",
+		})
+		return
+	}
 	auth, err := user.Auth()
 	if err != nil {
 		sentry.CaptureException(err)
@@ -80,7 +96,7 @@ func (User) Reset(ctx *gin.Context) {
 		ctx.JSON(500, serializers.Base{Message: serializers.InternalError})
 		return
 	}
-	err = user.UpdatePassword()
+	err = user.UpdatePassword(ctx.Request.Context())
 	if err != nil {
 		sentry.CaptureException(err)
 		ctx.JSON(500, serializers.Base{Message: serializers.InternalError})
@@ -88,3 +104,317 @@ func (User) Reset(ctx *gin.Context) {
 	}
 	ctx.JSON(200, serializers.Base{Message: serializers.Success})
 }
+
+// VerifyEmail verifies the user's email with the token of the link sent to it
+// @Summary      Verify email
+// @Tags         auth
+// @Produce      json
+// @Param        token  path      string  true  "Verification token"
+// @Success      200    {object}  serializers.Base
+// @Failure      400    {object}  serializers.Base
+// @Failure      429    {object}  serializers.Base
+// @Router       /auth/verify-email/{token} [post]
+func (User) VerifyEmail(ctx *gin.Context) {
+	userTokenService := &services.UserTokenService{}
+	err := userTokenService.VerifyEmail(ctx.Request.Context(), ctx.Param("token"))
+	switch {
+	case err == models.ErrInvalidUserToken:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: err.Error(),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to verify the email",
+		})
+	default:
+		ctx.JSON(http.StatusOK, serializers.Base{
+			Code:    serializers.Success,
+			Message: "Email verified",
+		})
+	}
+}
+
+// ResendEmailVerification sends a new verification link to an unverified
+// account. The response doesn't tell whether the account exists.
+// @Summary      Resend email verification
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        email  body      serializers.EmailRequest  true  "Account email"
+// @Success      202    {object}  serializers.Base
+// @Failure      400    {object}  serializers.Base
+// @Failure      429    {object}  serializers.Base
+// @Router       /auth/verify-email/resend [post]
+func (User) ResendEmailVerification(ctx *gin.Context) {
+	var request serializers.EmailRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "email is required",
+		})
+		return
+	}
+
+	userTokenService := &services.UserTokenService{}
+	if err := userTokenService.ResendEmailVerification(ctx.Request.Context(), request.Email); err != nil {
+		sentry.CaptureException(err)
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to send the verification email",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, serializers.Base{
+		Code:    serializers.Success,
+		Message: "A verification link is sent if the account needs one",
+	})
+}
+
+// ForgotPassword emails a password reset link to the account. The response
+// doesn't tell whether the account exists.
+// @Summary      Forgot password
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        email  body      serializers.EmailRequest  true  "Account email"
+// @Success      202    {object}  serializers.Base
+// @Failure      400    {object}  serializers.Base
+// @Failure      429    {object}  serializers.Base
+// @Router       /auth/forgot-pass [post]
+func (User) ForgotPassword(ctx *gin.Context) {
+	var request serializers.EmailRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "email is required",
+		})
+		return
+	}
+
+	userTokenService := &services.UserTokenService{}
+	if err := userTokenService.SendPasswordReset(ctx.Request.Context(), request.Email); err != nil {
+		sentry.CaptureException(err)
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to send the password reset email",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, serializers.Base{
+		Code:    serializers.Success,
+		Message: "A password reset link is sent if the account exists",
+	})
+}
+
+// ResetForgottenPassword sets a new password with the token of the reset
+// link
+// @Summary      Reset forgotten password
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token     path      string                     true  "Password reset token"
+// @Param        password  body      serializers.ResetPassword  true  "New password"
+// @Success      200       {object}  serializers.Base
+// @Failure      400       {object}  serializers.Base
+// @Failure      429       {object}  serializers.Base
+// @Router       /auth/reset-pass/{token} [post]
+func (User) ResetForgottenPassword(ctx *gin.Context) {
+	var request serializers.ResetPassword
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "password is required",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	userTokenService := &services.UserTokenService{}
+	err := userTokenService.ResetPassword(ctx.Request.Context(), ctx.Param("token"), request.Password)
+	switch {
+	case err == models.ErrInvalidUserToken:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidToken,
+			Message: err.Error(),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to reset the password",
+		})
+	default:
+		ctx.JSON(http.StatusOK, serializers.Base{
+			Code:    serializers.Success,
+			Message: "Password reset",
+		})
+	}
+}
+
+// LinkSnapp starts linking the user to a snapp user by sending them a
+// verification code
+// @Summary      Link snapp user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        link  body      serializers.LinkSnappRequest  true  "Snapp user"
+// @Success      202   {object}  serializers.LinkSnappResponse
+// @Failure      400   {object}  serializers.Base
+// @Failure      404   {object}  serializers.Base
+// @Failure      429   {object}  serializers.Base
+// @Router       /auth/link-snapp [post]
+func (User) LinkSnapp(ctx *gin.Context) {
+	var request serializers.LinkSnappRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "snappId is required",
+		})
+		return
+	}
+
+	accountLinkService := &services.AccountLinkService{}
+	expiresAt, err := accountLinkService.RequestLink(ctx.Request.Context(), ctx.GetInt64("user_id"), request.SnappId)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.SnappIdDoesNotExists,
+			Message: "snapp_id does not exists",
+		})
+	case err == models.ErrAccountAlreadyLinked:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyLinked,
+			Message: err.Error(),
+		})
+	case err == models.ErrLinkCodeResendTooSoon:
+		ctx.Header("Retry-After", strconv.Itoa(int(models.AccountLinkResendInterval.Seconds())))
+		ctx.JSON(http.StatusTooManyRequests, serializers.Base{
+			Code:    serializers.TooManyRequests,
+			Message: "A verification code was just sent, wait a minute before asking for another",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to start linking the account",
+		})
+	default:
+		ctx.JSON(http.StatusAccepted, serializers.LinkSnappResponse{ExpiresAt: expiresAt})
+	}
+}
+
+// VerifySnappLink links the user to the snapp user with the code sent to
+// the snapp user
+// @Summary      Verify snapp user link
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        link  body      serializers.LinkSnappRequest  true  "Snapp user and code"
+// @Success      200   {object}  models.Identity
+// @Failure      400   {object}  serializers.Base
+// @Failure      429   {object}  serializers.Base
+// @Router       /auth/link-snapp/verify [post]
+func (User) VerifySnappLink(ctx *gin.Context) {
+	var request serializers.LinkSnappRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "snappId and code are required",
+		})
+		return
+	}
+
+	accountLinkService := &services.AccountLinkService{}
+	identity, err := accountLinkService.ConfirmLink(ctx.Request.Context(), ctx.GetInt64("user_id"), request.SnappId, request.Code)
+	switch {
+	case err == models.ErrInvalidLinkCode:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: err.Error(),
+		})
+	case err == models.ErrAccountAlreadyLinked:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyLinked,
+			Message: err.Error(),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to link the account",
+		})
+	default:
+		ctx.JSON(http.StatusOK, identity)
+	}
+}
+
+// UnlinkSnapp removes the link of the user to their snapp user
+// @Summary      Unlink snapp user
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /auth/link-snapp [delete]
+func (User) UnlinkSnapp(ctx *gin.Context) {
+	deleted, err := models.DeleteAccountLink(ctx.Request.Context(), ctx.GetInt64("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to unlink the account",
+		})
+		return
+	}
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Account is not linked",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Account unlinked",
+	})
+}
+
+// GetIdentity returns the user's identity with the linked snapp user and
+// their legacy votes
+// @Summary      Get identity
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  serializers.IdentityResponse
+// @Router       /auth/identity [get]
+func (User) GetIdentity(ctx *gin.Context) {
+	identity, err := models.GetIdentityByUser(ctx.Request.Context(), ctx.GetInt64("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get identity",
+		})
+		return
+	}
+
+	response := serializers.IdentityResponse{
+		Identity: identity,
+		Votes:    make([]models.VotingHistoryEntry, 0),
+	}
+	if identity.IsLinked() {
+		response.Votes, err = models.GetUserVotingHistory(ctx.Request.Context(), identity.SnappUserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to get voting history",
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
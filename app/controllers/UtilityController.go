@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UtilityController struct{}
+
+// referenceDataMaxAge is how long clients cache the reference data, which
+// only changes with deployments
+const referenceDataMaxAge = time.Hour
+
+// cityListMaxAge is how long clients cache the cities and districts, which
+// change when cities are onboarded
+const cityListMaxAge = 5 * time.Minute
+
+// MeetingPoint finds venues in the middle of a group of people
+// @Summary      Find a meeting point for a group
+// @Tags         utils
+// @Accept       json
+// @Produce      json
+// @Param        request        body      serializers.MeetingPointRequest  true  "Participant locations and preferences"
+// @Success      200  {object}  serializers.MeetingPointResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /utils/meeting-point [post]
+func (UtilityController) MeetingPoint(ctx *gin.Context) {
+	var request serializers.MeetingPointRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid meeting point data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	geoService := &services.GeolocationService{}
+	locations := request.Locations()
+
+	centroid, venues, err := geoService.FindOptimalMeetingPoint(ctx.Request.Context(), locations, request.PreferenceFilters())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to find a meeting point",
+		})
+		return
+	}
+
+	ranked := geoService.RankMeetingVenues(locations, venues)
+	if len(ranked) > request.Preferences.Limit {
+		ranked = ranked[:request.Preferences.Limit]
+	}
+
+	participants := make([]serializers.ParticipantDistance, len(request.Participants))
+	for i, participant := range request.Participants {
+		distance := geoService.CalculateDistance(participant.Latitude, participant.Longitude, centroid.Latitude, centroid.Longitude)
+		participants[i] = serializers.ParticipantDistance{
+			Name:       participant.Name,
+			Latitude:   participant.Latitude,
+			Longitude:  participant.Longitude,
+			DistanceKm: math.Round(distance.Kilometers*100) / 100,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, serializers.MeetingPointResponse{
+		Centroid:     centroid,
+		Participants: participants,
+		Venues:       ranked,
+	})
+}
+
+// Distances annotates a list of venues with their distance from a location
+// and whether they are open now
+// @Summary      Distances to venues
+// @Tags         utils
+// @Accept       json
+// @Produce      json
+// @Param        request        body      serializers.DistancesRequest  true  "Location and venue IDs"
+// @Success      200  {object}  serializers.DistancesResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /utils/distances [post]
+func (UtilityController) Distances(ctx *gin.Context) {
+	var request serializers.DistancesRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid distances data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate()
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	geoService := &services.GeolocationService{}
+	distances, err := geoService.GetVenueDistances(ctx.Request.Context(), request.Latitude, request.Longitude, request.VenueIDs, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to calculate distances",
+		})
+		return
+	}
+
+	found := make(map[int64]bool, len(distances))
+	for _, distance := range distances {
+		found[distance.VenueID] = true
+	}
+	notFound := make([]int64, 0)
+	for _, venueID := range request.VenueIDs {
+		if !found[venueID] {
+			notFound = append(notFound, venueID)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, serializers.DistancesResponse{
+		Distances: distances,
+		NotFound:  notFound,
+	})
+}
+
+// GetSearchSuggestions returns popular and trending search queries
+// @Summary      Popular and trending searches
+// @Tags         utils
+// @Produce      json
+// @Param        city           query     int     false  "City ID"
+// @Param        limit          query     int     false  "Queries per list (default 10, max 50)"
+// @Success      200  {object}  services.SearchSuggestions
+// @Failure      400  {object}  serializers.Base
+// @Router       /utils/suggestions [get]
+func (UtilityController) GetSearchSuggestions(ctx *gin.Context) {
+	cityID, ok := parseSuggestionCity(ctx)
+	if !ok {
+		return
+	}
+
+	suggestionService := &services.SearchSuggestionService{}
+	result, err := suggestionService.GetSuggestions(ctx.Request.Context(), cityID, parseSuggestionLimit(ctx, 10, 50))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get search suggestions",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetAutocomplete completes a search prefix with past queries and venue names
+// @Summary      Search autocomplete
+// @Tags         utils
+// @Produce      json
+// @Param        q              query     string  true   "Search prefix"
+// @Param        city           query     int     false  "City ID"
+// @Param        limit          query     int     false  "Maximum suggestions (default 8, max 20)"
+// @Success      200  {object}  serializers.AutocompleteResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /utils/autocomplete [get]
+func (UtilityController) GetAutocomplete(ctx *gin.Context) {
+	prefix := strings.TrimSpace(ctx.Query("q"))
+	if prefix == "" || len(prefix) > 100 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Query must be between 1 and 100 characters",
+		})
+		return
+	}
+
+	cityID, ok := parseSuggestionCity(ctx)
+	if !ok {
+		return
+	}
+
+	suggestionService := &services.SearchSuggestionService{}
+	suggestions, err := suggestionService.Autocomplete(ctx.Request.Context(), prefix, cityID, parseSuggestionLimit(ctx, 8, 20))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get autocomplete suggestions",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.AutocompleteResponse{
+		Query:       prefix,
+		Suggestions: suggestions,
+	})
+}
+
+func parseSuggestionCity(ctx *gin.Context) (*int64, bool) {
+	cityStr := ctx.Query("city")
+	if cityStr == "" {
+		return nil, true
+	}
+
+	cityID, err := strconv.ParseInt(cityStr, 10, 64)
+	if err != nil || cityID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid city ID",
+		})
+		return nil, false
+	}
+	return &cityID, true
+}
+
+func parseSuggestionLimit(ctx *gin.Context, defaultLimit, maxLimit int) int {
+	limit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// GetReferenceData returns the visit types, price ranges, amenity keys and
+// campaign types the API accepts, with their labels
+// @Summary      Get reference data
+// @Tags         utils
+// @Produce      json
+// @Success      200  {object}  serializers.ReferenceData
+// @Router       /utils/reference [get]
+func (UtilityController) GetReferenceData(ctx *gin.Context) {
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(referenceDataMaxAge.Seconds())))
+	ctx.JSON(http.StatusOK, serializers.NewReferenceData())
+}
+
+// GetCities lists the active cities with their centre and timezone
+// @Summary      List cities
+// @Tags         utils
+// @Produce      json
+// @Success      200  {object}  serializers.CitiesResponse
+// @Failure      500  {object}  serializers.Base
+// @Router       /utils/cities [get]
+func (UtilityController) GetCities(ctx *gin.Context) {
+	cityService := &services.CityService{}
+	cities, err := cityService.GetCities(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get cities",
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cityListMaxAge.Seconds())))
+	ctx.JSON(http.StatusOK, serializers.CitiesResponse{Cities: cities})
+}
+
+// GetDistricts lists the districts of an active city with their centre
+// @Summary      List city districts
+// @Tags         utils
+// @Produce      json
+// @Param        city_id  path      int  true  "City ID"
+// @Success      200      {object}  serializers.DistrictsResponse
+// @Failure      400      {object}  serializers.Base
+// @Failure      404      {object}  serializers.Base
+// @Router       /utils/cities/{city_id}/districts [get]
+func (UtilityController) GetDistricts(ctx *gin.Context) {
+	cityID, err := strconv.ParseInt(ctx.Param("city_id"), 10, 64)
+	if err != nil || cityID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid city ID",
+		})
+		return
+	}
+
+	cityService := &services.CityService{}
+	city, districts, err := cityService.GetDistricts(ctx.Request.Context(), cityID)
+	if err == services.ErrUnknownCity {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "City not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get districts",
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cityListMaxAge.Seconds())))
+	ctx.JSON(http.StatusOK, serializers.DistrictsResponse{City: *city, Districts: districts})
+}
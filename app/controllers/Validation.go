@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestValidator is a request with checks spanning several fields
+type requestValidator interface {
+	Validate() (serializers.Base, bool)
+}
+
+// bindQuery binds the query parameters to the request and validates them.
+// The 400 response is written when they're invalid.
+func bindQuery(ctx *gin.Context, request interface{}) bool {
+	if err := ctx.ShouldBindQuery(request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.BindingError(err))
+		return false
+	}
+
+	if validator, ok := request.(requestValidator); ok {
+		if base, isValid := validator.Validate(); !isValid {
+			ctx.JSON(http.StatusBadRequest, base)
+			return false
+		}
+	}
+
+	return true
+}
+
+// bindFields parses the fields query parameter against the fields the
+// endpoint allows. A nil selection asks for the full response. The 400
+// response is written when it names unknown fields.
+func bindFields(ctx *gin.Context, allowed ...[]string) (serializers.FieldSelection, bool) {
+	fields, base, ok := serializers.ParseFields(ctx.Query("fields"), allowed...)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return nil, false
+	}
+	return fields, true
+}
+
+// respondPartial writes the response filtered down to the requested fields
+func respondPartial(ctx *gin.Context, response interface{}, err error) {
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to select the requested fields",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -1,18 +1,27 @@
 package controllers
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"voting-app/app/config"
 	"voting-app/app/models"
 	"voting-app/app/serializers"
+	"voting-app/app/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type VenueController struct{}
 
+// venueDetailSimilarLimit is how many similar venues the venue details show
+const venueDetailSimilarLimit = 6
+
 // Search performs advanced venue search
 // @Summary      Search venues with filters
 // @Tags         venues
@@ -22,9 +31,10 @@ type VenueController struct{}
 // @Param        category       query     int     false  "Category ID"
 // @Param        subcategory    query     int     false  "Subcategory ID"
 // @Param        city           query     int     false  "City ID"
+// @Param        neighborhood   query     int     false  "Neighborhood ID"
 // @Param        lat            query     number  false  "Latitude for location search"
 // @Param        lng            query     number  false  "Longitude for location search"
-// @Param        radius         query     number  false  "Search radius in km (default 10)"
+// @Param        radius         query     number  false  "Search radius in km (default 10, max 100)"
 // @Param        price_range    query     string  false  "Price ranges (comma separated: $,$$,$$$,$$$$)"
 // @Param        min_rating     query     number  false  "Minimum rating (1-5)"
 // @Param        amenities      query     string  false  "Required amenities (comma separated)"
@@ -33,106 +43,24 @@ type VenueController struct{}
 // @Param        sort_by        query     string  false  "Sort by: rating, distance, popularity, newest"
 // @Param        page           query     int     false  "Page number (default 1)"
 // @Param        limit          query     int     false  "Results per page (default 20, max 100)"
+// @Param        estimate_total query     boolean false  "Estimate the total of broad searches, see pagination.exactTotal"
 // @Success      200  {object}  serializers.VenueSearchResponse
 // @Failure      400  {object}  serializers.Base
 // @Router       /venues/search [get]
 func (VenueController) Search(ctx *gin.Context) {
-	// Parse search parameters
-	params := models.VenueSearchParams{
-		Query:  ctx.Query("q"),
-		SortBy: ctx.DefaultQuery("sort_by", "rating"),
-		Page:   1,
-		Limit:  20,
-	}
-
-	// Parse numeric parameters
-	if categoryStr := ctx.Query("category"); categoryStr != "" {
-		if categoryID, err := strconv.ParseInt(categoryStr, 10, 64); err == nil {
-			params.CategoryID = &categoryID
-		}
-	}
-
-	if subcategoryStr := ctx.Query("subcategory"); subcategoryStr != "" {
-		if subcategoryID, err := strconv.ParseInt(subcategoryStr, 10, 64); err == nil {
-			params.SubcategoryID = &subcategoryID
-		}
-	}
-
-	if cityStr := ctx.Query("city"); cityStr != "" {
-		if cityID, err := strconv.ParseInt(cityStr, 10, 64); err == nil {
-			params.CityID = &cityID
-		}
-	}
-
-	// Parse location parameters
-	if latStr := ctx.Query("lat"); latStr != "" {
-		if lat, err := strconv.ParseFloat(latStr, 64); err == nil {
-			params.Latitude = &lat
-		}
-	}
-
-	if lngStr := ctx.Query("lng"); lngStr != "" {
-		if lng, err := strconv.ParseFloat(lngStr, 64); err == nil {
-			params.Longitude = &lng
-		}
-	}
-
-	if radiusStr := ctx.Query("radius"); radiusStr != "" {
-		if radius, err := strconv.ParseFloat(radiusStr, 64); err == nil {
-			params.Radius = &radius
-		}
-	} else if params.Latitude != nil && params.Longitude != nil {
-		// Default radius of 10km for location searches
-		defaultRadius := 10.0
-		params.Radius = &defaultRadius
-	}
-
-	// Parse price range
-	if priceRangeStr := ctx.Query("price_range"); priceRangeStr != "" {
-		params.PriceRange = strings.Split(priceRangeStr, ",")
-	}
-
-	// Parse minimum rating
-	if minRatingStr := ctx.Query("min_rating"); minRatingStr != "" {
-		if minRating, err := strconv.ParseFloat(minRatingStr, 64); err == nil {
-			params.MinRating = &minRating
-		}
-	}
-
-	// Parse amenities
-	if amenitiesStr := ctx.Query("amenities"); amenitiesStr != "" {
-		params.Amenities = strings.Split(amenitiesStr, ",")
-	}
-
-	// Parse boolean flags
-	if isOpenStr := ctx.Query("is_open"); isOpenStr != "" {
-		if isOpen, err := strconv.ParseBool(isOpenStr); err == nil {
-			params.IsOpen = &isOpen
-		}
-	}
-
-	if isFeaturedStr := ctx.Query("is_featured"); isFeaturedStr != "" {
-		if isFeatured, err := strconv.ParseBool(isFeaturedStr); err == nil {
-			params.IsFeatured = &isFeatured
-		}
-	}
-
-	// Parse pagination
-	if pageStr := ctx.Query("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			params.Page = page
-		}
+	var query serializers.VenueSearchQuery
+	if !bindQuery(ctx, &query) {
+		return
 	}
-
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
-			params.Limit = limit
-		}
+	fields, ok := bindFields(ctx, serializers.VenueFields)
+	if !ok {
+		return
 	}
+	params := query.ToSearchParams()
 
 	// Perform search
 	venue := &models.Venue{}
-	venues, totalCount, err := venue.Search(params)
+	venues, total, err := venue.SearchWithTotal(ctx.Request.Context(), params)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -142,8 +70,13 @@ func (VenueController) Search(ctx *gin.Context) {
 	}
 
 	// Calculate pagination info
+	totalCount := total.Count
 	totalPages := (totalCount + params.Limit - 1) / params.Limit
 	hasNext := params.Page < totalPages
+	if !total.Exact {
+		// An estimate only knows there's more when the page is full
+		hasNext = len(venues) == params.Limit
+	}
 	hasPrev := params.Page > 1
 
 	response := serializers.VenueSearchResponse{
@@ -155,11 +88,35 @@ func (VenueController) Search(ctx *gin.Context) {
 			TotalPages: totalPages,
 			HasNext:    hasNext,
 			HasPrev:    hasPrev,
+			ExactTotal: &total.Exact,
 		},
 		SearchParams: params,
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	// Count the results per neighborhood so clients can narrow the search
+	if neighborhoodFacets, err := models.GetNeighborhoodFacets(ctx.Request.Context(), params); err == nil {
+		response.Filters.Neighborhoods = neighborhoodFacets
+	}
+
+	// Record text searches for trending queries and autocomplete. Searches
+	// made while impersonating aren't the user's and aren't recorded.
+	_, impersonated := models.ImpersonationFromContext(ctx.Request.Context())
+	if params.Page == 1 && strings.TrimSpace(params.Query) != "" && !impersonated {
+		analyticsService := &services.AnalyticsService{}
+		// Tracked in the background, detached from the request context
+		go analyticsService.TrackSearch(context.Background(), ctx.GetInt64("snappUser_id"), params.Query, searchAnalyticsFilters(params), venues, nil, nil)
+	}
+
+	// Surface matching dishes when the query looks like a dish name
+	if params.Page == 1 && looksLikeDishQuery(params.Query) {
+		menuMatches, err := models.SearchMenuItems(ctx.Request.Context(), params.Query, nil, params.CityID, 10)
+		if err == nil {
+			response.MenuMatches = menuMatches
+		}
+	}
+
+	body, err := fields.FilterUnder(response, "venues")
+	respondPartial(ctx, body, err)
 }
 
 // GetByID retrieves a venue by ID with all details
@@ -183,8 +140,56 @@ func (VenueController) GetByID(ctx *gin.Context) {
 		return
 	}
 
+	respondVenueDetail(ctx, venueID, nil)
+}
+
+// GetBySlug retrieves a venue by its slug with the same details as GetByID.
+// Slugs the venue used before renames still resolve, with a redirect to the
+// current slug.
+// @Summary      Get venue details by slug
+// @Tags         venues
+// @Produce      json
+// @Param        slug           path      string  true   "Venue slug"
+// @Param        user_lat       query     number  false  "User latitude for distance calculation"
+// @Param        user_lng       query     number  false  "User longitude for distance calculation"
+// @Success      200  {object}  serializers.VenueDetailResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/by-slug/{slug} [get]
+func (VenueController) GetBySlug(ctx *gin.Context) {
+	// Slugs are lower case, shared links may not be
+	slug := strings.ToLower(ctx.Param("slug"))
+	venueID, currentSlug, moved, err := models.ResolveVenueSlug(ctx.Request.Context(), slug)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get venue",
+		})
+		return
+	}
+
+	var redirect *serializers.SlugRedirect
+	if moved {
+		redirect = &serializers.SlugRedirect{Status: http.StatusMovedPermanently, From: slug, To: currentSlug}
+	}
+	respondVenueDetail(ctx, venueID, redirect)
+}
+
+// respondVenueDetail writes the details of the venue
+func respondVenueDetail(ctx *gin.Context, venueID int64, redirect *serializers.SlugRedirect) {
+	fields, ok := bindFields(ctx, serializers.VenueFields, serializers.VenueDetailSections)
+	if !ok {
+		return
+	}
+
 	venue := &models.Venue{ID: venueID}
-	err = venue.GetByID()
+	err := venue.GetByID(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, serializers.Base{
 			Code:    serializers.NotFound,
@@ -207,85 +212,237 @@ func (VenueController) GetByID(ctx *gin.Context) {
 	}
 
 	// Get review summary
-	reviewSummary, err := models.GetVenueReviewSummary(venueID)
+	reviewSummary, err := models.GetVenueReviewSummary(ctx.Request.Context(), venueID)
 	if err != nil {
 		reviewSummary = &models.ReviewSummary{VenueID: venueID}
 	}
 
+	// Venues without a rating template are shown without one
+	ratingTemplate, _ := models.GetCategoryRatingTemplate(ctx.Request.Context(), venue.CategoryID)
+
+	// Similar venues come from the nightly precomputation and are left out
+	// when they can't be loaded
+	recommendationEngine := &services.RecommendationEngine{}
+	similarVenues, _ := recommendationEngine.GetSimilarVenues(ctx.Request.Context(), venueID, venueDetailSimilarLimit)
+
+	// Venues are shown without their busyness when it can't be loaded
+	if busyness, err := models.GetVenueBusyness(ctx.Request.Context(), []int64{venueID}); err == nil {
+		venue.Busyness = busyness[venueID]
+	}
+
+	// Venues are shown without their deals when they can't be loaded
+	if deals, err := models.GetActiveDeals(ctx.Request.Context(), []int64{venueID}); err == nil {
+		venue.Deals = deals[venueID]
+	}
+
+	// Venues are shown without whether they are open when their hours can't
+	// be read
+	openNowService := &services.OpenNowService{}
+	openNowService.AttachOpenStatus(ctx.Request.Context(), venue, time.Now())
+
+	// Venues are summarized once they have enough approved reviews
+	if summary, err := models.GetGeneratedReviewSummary(ctx.Request.Context(), venueID); err == nil {
+		venue.ReviewSummary = &summary.Summary
+	}
+
+	// Venues are shown without their external ratings when they can't be
+	// loaded
+	externalRatings, _ := models.GetExternalRatings(ctx.Request.Context(), []int64{venueID})
+
 	// Get recent events (commented out for now)
 	// events := getVenueEvents(venueID, 5)
 
 	response := serializers.VenueDetailResponse{
-		Venue:         *venue,
-		ReviewSummary: reviewSummary,
+		Venue:           *venue,
+		ReviewSummary:   reviewSummary,
+		SimilarVenues:   similarVenues,
+		RatingTemplate:  ratingTemplate,
+		ExternalRatings: externalRatings[venueID],
+		Redirect:        redirect,
 		// Events:        events,
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	body, err := response.Select(fields)
+	respondPartial(ctx, body, err)
 }
 
-// GetNearby finds venues near a location
+// GetNearby finds venues near a location. Without coordinates the client is
+// located by IP, and the X-Location-Source header tells which was used. With
+// a travel mode the venues are ordered by trip time, and the X-Travel-Mode
+// header is set unless trips couldn't be timed and straight-line distance
+// was used instead.
 // @Summary      Get nearby venues
 // @Tags         venues
 // @Produce      json
-// @Param        lat            query     number  true   "Latitude"
-// @Param        lng            query     number  true   "Longitude"
-// @Param        radius         query     number  false  "Search radius in km (default 5)"
-// @Param        category       query     int     false  "Filter by category ID"
-// @Param        limit          query     int     false  "Number of results (default 20)"
+// @Param        lat            query     number  false  "Latitude, located by IP when missing"
+// @Param        lng            query     number  false  "Longitude, located by IP when missing"
+// @Param        radius         query     number  false  "Search radius in km (default 5, max 100)"
+// @Param        limit          query     int     false  "Number of results (default 20, max 100)"
+// @Param        travel_mode    query     string  false  "walking or driving, orders by trip time"
 // @Success      200  {object}  []models.Venue
 // @Failure      400  {object}  serializers.Base
 // @Router       /venues/nearby [get]
 func (VenueController) GetNearby(ctx *gin.Context) {
-	latStr := ctx.Query("lat")
-	lngStr := ctx.Query("lng")
+	var query serializers.NearbyVenuesQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+	fields, ok := bindFields(ctx, serializers.VenueFields)
+	if !ok {
+		return
+	}
 
-	if latStr == "" || lngStr == "" {
-		ctx.JSON(http.StatusBadRequest, serializers.Base{
-			Code:    serializers.InvalidInput,
-			Message: "Latitude and longitude are required",
-		})
+	location, ok := resolveLocation(ctx, query.LocationQuery)
+	if !ok {
 		return
 	}
 
-	lat, err := strconv.ParseFloat(latStr, 64)
+	// The closest venues in a straight line aren't always the quickest to
+	// get to, so more candidates are timed than returned
+	candidates := query.Limit
+	if query.TravelMode != "" {
+		candidates = query.Limit * travelTimeCandidates
+		if candidates > maxTravelTimeCandidates {
+			candidates = maxTravelTimeCandidates
+		}
+	}
+
+	venue := &models.Venue{}
+	venues, err := venue.GetNearby(ctx.Request.Context(), location.Latitude, location.Longitude, query.Radius, candidates)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, serializers.Base{
-			Code:    serializers.InvalidInput,
-			Message: "Invalid latitude",
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to find nearby venues",
 		})
 		return
 	}
 
-	lng, err := strconv.ParseFloat(lngStr, 64)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, serializers.Base{
-			Code:    serializers.InvalidInput,
-			Message: "Invalid longitude",
+	if query.TravelMode != "" {
+		travelService := &services.TravelTimeService{}
+		origin := services.LatLng{Latitude: location.Latitude, Longitude: location.Longitude}
+		byTravelTime, err := travelService.SortByTravelTime(ctx.Request.Context(), query.TravelMode, origin, venues, query.Limit)
+		if err == nil {
+			venues = byTravelTime
+			ctx.Header(TravelModeHeader, query.TravelMode)
+		} else if len(venues) > query.Limit {
+			venues = venues[:query.Limit]
+		}
+	}
+
+	busynessService := &services.BusynessService{}
+	if err := busynessService.AttachBusyness(ctx.Request.Context(), venues); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to find nearby venues",
 		})
 		return
 	}
 
-	radius := 5.0 // Default 5km
-	if radiusStr := ctx.Query("radius"); radiusStr != "" {
-		if r, err := strconv.ParseFloat(radiusStr, 64); err == nil {
-			radius = r
-		}
+	dealService := &services.DealService{}
+	if err := dealService.AttachActiveDeals(ctx.Request.Context(), venues); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to find nearby venues",
+		})
+		return
 	}
 
-	limit := 20
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	body, err := fields.Filter(venues)
+	respondPartial(ctx, body, err)
+}
+
+// GetMapVenues lists the venues within a bounding box as map pins, best
+// rated first. The list is streamed as the venues are read, so large areas
+// can be shown at once.
+// @Summary      Get map venues
+// @Tags         venues
+// @Produce      json
+// @Param        sw_lat         query     number  true   "South-west corner latitude"
+// @Param        sw_lng         query     number  true   "South-west corner longitude"
+// @Param        ne_lat         query     number  true   "North-east corner latitude"
+// @Param        ne_lng         query     number  true   "North-east corner longitude"
+// @Param        category_id    query     int     false  "Category ID"
+// @Param        min_rating     query     number  false  "Minimum average rating"
+// @Param        limit          query     int     false  "Number of venues (default 2000, max 10000)"
+// @Success      200  {object}  serializers.MapVenuesResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /venues/map [get]
+func (VenueController) GetMapVenues(ctx *gin.Context) {
+	var query serializers.MapVenuesQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+	if base, ok := query.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	bounds := services.LocationBounds{
+		SouthWest: services.LatLng{Latitude: *query.SouthWestLat, Longitude: *query.SouthWestLng},
+		NorthEast: services.LatLng{Latitude: *query.NorthEastLat, Longitude: *query.NorthEastLng},
+	}
+	filters := make(map[string]interface{})
+	if query.CategoryID != 0 {
+		filters["category_id"] = query.CategoryID
+	}
+	if query.MinRating != 0 {
+		filters["min_rating"] = query.MinRating
+	}
+
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	venues := services.NewJSONArrayWriter(ctx.Writer, `{"venues":`)
+	geolocationService := &services.GeolocationService{}
+	err := geolocationService.EachVenueInBounds(ctx.Request.Context(), bounds, filters, query.Limit, func(venue *models.Venue) error {
+		return venues.Write(serializers.NewMapVenue(venue))
+	})
+	if err == nil {
+		if err = venues.Close(); err == nil {
+			_, err = fmt.Fprintf(ctx.Writer, `,"count":%d}`, venues.Count())
+		}
+	}
+	if err != nil {
+		if !venues.Started() {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to find map venues",
+			})
 		}
+		// Once streaming, the response is cut short, which clients see as
+		// invalid JSON
+		ctx.Abort()
 	}
+}
 
-	venue := &models.Venue{}
-	venues, err := venue.GetNearby(lat, lng, radius, limit)
+// DiscoverOpenNow finds open venues near a location, sorted by closing time.
+// Venues open for at least another hour come first.
+// @Summary      Discover venues open now
+// @Tags         discover
+// @Produce      json
+// @Param        lat            query     number  false  "Latitude, located by IP when missing"
+// @Param        lng            query     number  false  "Longitude, located by IP when missing"
+// @Param        radius         query     number  false  "Search radius in km (default 5, max 100)"
+// @Param        limit          query     int     false  "Number of results (default 20, max 100)"
+// @Success      200  {object}  []services.OpenVenue
+// @Failure      400  {object}  serializers.Base
+// @Router       /discover/open-now [get]
+func (VenueController) DiscoverOpenNow(ctx *gin.Context) {
+	var query serializers.NearbyQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	location, ok := resolveLocation(ctx, query.LocationQuery)
+	if !ok {
+		return
+	}
+
+	openNowService := &services.OpenNowService{}
+	venues, err := openNowService.GetOpenNow(ctx.Request.Context(), location.Latitude, location.Longitude,
+		query.Radius, query.Limit, time.Now())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
-			Message: "Failed to find nearby venues",
+			Message: "Failed to find open venues",
 		})
 		return
 	}
@@ -293,6 +450,122 @@ func (VenueController) GetNearby(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, venues)
 }
 
+// DiscoverTrending finds the venues near a location with the most reviews,
+// check-ins and campaign votes in the last week, or those of a city. Without
+// coordinates the client is located by IP. The trending lists are cached for
+// TrendingCacheTTL and recomputed in the background.
+// @Summary      Discover trending venues
+// @Tags         discover
+// @Produce      json
+// @Param        lat            query     number  false  "Latitude, located by IP when missing"
+// @Param        lng            query     number  false  "Longitude, located by IP when missing"
+// @Param        radius         query     number  false  "Search radius in km (default 5, max 100)"
+// @Param        limit          query     int     false  "Number of results (default 20, max 100)"
+// @Param        city           query     int     false  "City ID, the venues aren't located without lat and lng"
+// @Param        category       query     int     false  "Category ID"
+// @Success      200  {object}  serializers.TrendingVenuesResponse
+// @Failure      400  {object}  serializers.Base
+// @Router       /discover/trending [get]
+func (VenueController) DiscoverTrending(ctx *gin.Context) {
+	var query serializers.TrendingQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	var location *services.ResolvedLocation
+	if query.CityID == 0 || query.Latitude != nil {
+		var ok bool
+		location, ok = resolveLocation(ctx, query.LocationQuery)
+		if !ok {
+			return
+		}
+	}
+
+	trendingService := &services.TrendingService{}
+	trending, err := trendingService.GetTrending(ctx.Request.Context(), query.CityID, query.CategoryID,
+		location, query.Radius, query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to find trending venues",
+		})
+		return
+	}
+
+	response := serializers.TrendingVenuesResponse{
+		Venues:     trending.Venues,
+		ComputedAt: trending.ComputedAt,
+	}
+	if location != nil {
+		response.LocationSource = location.Source
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// DiscoverCity selects the city to show the client first: the city it is in,
+// located from its coordinates or else its IP, or the tenant's default city
+// @Summary      Discover the client's city
+// @Tags         discover
+// @Produce      json
+// @Param        lat            query     number  false  "Latitude, located by IP when missing"
+// @Param        lng            query     number  false  "Longitude, located by IP when missing"
+// @Success      200  {object}  serializers.DefaultCityResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /discover/city [get]
+func (VenueController) DiscoverCity(ctx *gin.Context) {
+	var query serializers.LocationQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	geoIPService := &services.IPGeolocationService{}
+	location, err := geoIPService.ResolveLocation(ctx.Request.Context(), query.Latitude, query.Longitude, ctx.ClientIP())
+	if err == nil {
+		cityService := &services.CityAssignmentService{}
+		assignment, err := cityService.AssignCity(ctx.Request.Context(), 0, location.Latitude, location.Longitude)
+		if err == nil {
+			ctx.Header(LocationSourceHeader, location.Source)
+			ctx.JSON(http.StatusOK, serializers.DefaultCityResponse{
+				City:           assignment.City,
+				LocationSource: location.Source,
+			})
+			return
+		}
+		if err != services.ErrNoNearbyCity {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to locate the city",
+			})
+			return
+		}
+	}
+
+	// Clients that can't be placed in a city get the tenant's default one
+	if tenant, exists := ctx.Get("tenant"); exists && tenant.(*models.Tenant).DefaultCityID != nil {
+		defaultCityID := *tenant.(*models.Tenant).DefaultCityID
+		cities, err := models.GetActiveCities(ctx.Request.Context())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to locate the city",
+			})
+			return
+		}
+		for _, city := range cities {
+			if city.ID == defaultCityID {
+				ctx.JSON(http.StatusOK, serializers.DefaultCityResponse{City: city})
+				return
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusNotFound, serializers.Base{
+		Code:    serializers.NotFound,
+		Message: "No city found for the location",
+	})
+}
+
 // GetFeatured returns featured venues
 // @Summary      Get featured venues
 // @Tags         venues
@@ -311,7 +584,7 @@ func (VenueController) GetFeatured(ctx *gin.Context) {
 	}
 
 	venue := &models.Venue{}
-	venues, err := venue.GetFeatured(limit)
+	venues, err := venue.GetFeatured(ctx.Request.Context(), limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -330,7 +603,7 @@ func (VenueController) GetFeatured(ctx *gin.Context) {
 // @Success      200  {object}  []models.VenueCategory
 // @Router       /venues/categories [get]
 func (VenueController) GetCategories(ctx *gin.Context) {
-	categories, err := models.GetVenueCategories()
+	categories, err := models.GetVenueCategories(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -342,7 +615,9 @@ func (VenueController) GetCategories(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, categories)
 }
 
-// CreateVenue creates a new venue (admin or owner only)
+// CreateVenue creates a new venue (admin or owner only). The venue is placed
+// in the city its coordinates are in, correcting the submitted city if needed,
+// and in the city's neighborhood containing them.
 // @Summary      Create new venue
 // @Tags         venues
 // @Accept       json
@@ -369,8 +644,53 @@ func (VenueController) CreateVenue(ctx *gin.Context) {
 		return
 	}
 
+	cityService := &services.CityAssignmentService{}
+	assignment, err := cityService.AssignCity(ctx.Request.Context(), request.CityID, request.Latitude, request.Longitude)
+	if err != nil {
+		switch err {
+		case services.ErrUnknownCity:
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "City not found",
+			})
+		case services.ErrNoNearbyCity:
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidLocation,
+				Message: "No city found near the venue, please choose one",
+			})
+		case services.ErrCityTooFar:
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidLocation,
+				Message: fmt.Sprintf("The venue is more than %.0f km from the chosen city", services.MaxClaimedCityDistanceKm),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to check the venue's city",
+			})
+		}
+		return
+	}
+
+	neighborhoodService := &services.NeighborhoodService{}
+	neighborhood, err := neighborhoodService.FindNeighborhood(ctx.Request.Context(), assignment.City.ID, request.Latitude, request.Longitude)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to check the venue's neighborhood",
+		})
+		return
+	}
+
 	// Create venue
 	venue := request.ToVenue()
+	venue.CityID = assignment.City.ID
+	venue.City = &assignment.City
+	if neighborhood != nil {
+		venue.NeighborhoodID = &neighborhood.ID
+		neighborhood.Boundary = nil
+		venue.Neighborhood = neighborhood
+	}
 	// Set owner from authenticated user
 	if userID := ctx.GetInt64("user_id"); userID > 0 {
 		venue.OwnerID = &userID
@@ -378,7 +698,7 @@ func (VenueController) CreateVenue(ctx *gin.Context) {
 		venue.ClaimedAt = &now
 	}
 
-	err := venue.Create()
+	err = venue.Create(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, serializers.Base{
 			Code:    serializers.InternalError,
@@ -387,25 +707,1185 @@ func (VenueController) CreateVenue(ctx *gin.Context) {
 		return
 	}
 
+	if assignment.Corrected {
+		correction := &models.VenueCityCorrection{
+			VenueID:        venue.ID,
+			ClaimedCityID:  request.CityID,
+			AssignedCityID: assignment.City.ID,
+			DistanceKm:     assignment.DistanceKm,
+		}
+		// The venue is created either way, a lost record only loses the audit trail
+		correction.Create(ctx.Request.Context())
+	}
+
 	ctx.JSON(http.StatusCreated, venue)
 }
 
-// Helper functions
+// GetVenueCheckins lists the public check-ins of a venue, newest first
+// @Summary      Get venue check-ins
+// @Tags         venues
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        viewer         query     string  false  "Snapp ID of the viewer, hides check-ins of users they blocked or muted"
+// @Param        page           query     int     false  "Page number (default 1)"
+// @Param        limit          query     int     false  "Results per page (default 20, max 100)"
+// @Success      200  {object}  serializers.VenueCheckinsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id}/checkins [get]
+func (VenueController) GetVenueCheckins(ctx *gin.Context) {
+	venueID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return
+	}
 
-func calculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
-	// Simple Haversine formula implementation
-	// For production, use a proper geospatial library
-	const R = 6371 // Earth radius in kilometers
+	var query serializers.VenueCheckinsQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
 
-	dLat := (lat2 - lat1) * (3.14159 / 180)
-	_ = (lng2 - lng1) * (3.14159 / 180) // dLng not used in simplified calc
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return
+	}
 
-	a := 0.5 - (0.5 * (1 + (dLat * dLat / 4)))
-	return R * 2 * (1.5708 - a) // Simplified calculation
+	// Unknown viewers see every public check-in
+	var viewerID *int64
+	if query.Viewer != "" {
+		viewerUser := &models.SnappUser{SnappId: query.Viewer}
+		if exists, err := viewerUser.GetUser(ctx.Request.Context()); err == nil && exists {
+			viewerID = &viewerUser.Id
+		}
+	}
+
+	checkins, total, err := models.GetPublicVenueCheckins(ctx.Request.Context(), venueID, viewerID,
+		query.Limit, (query.Page-1)*query.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get check-ins",
+		})
+		return
+	}
+
+	totalPages := (total + query.Limit - 1) / query.Limit
+	ctx.JSON(http.StatusOK, serializers.VenueCheckinsResponse{
+		Checkins: checkins,
+		Pagination: serializers.PaginationInfo{
+			Page:       query.Page,
+			Limit:      query.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    query.Page < totalPages,
+			HasPrev:    query.Page > 1,
+		},
+	})
 }
 
-func getVenueEvents(venueID int64, limit int) []interface{} {
-	// This would be implemented in a VenueEvent model
-	// For now, return empty slice
-	return []interface{}{}
+// CreateCheckin checks the user in at a venue. A wait time or crowd level
+// sent with the check-in counts towards the venue's live busyness for an
+// hour. Checking in again within CHECKIN_DEDUP_WINDOW returns the previous
+// check-in marked deduplicated.
+// @Summary      Check in at a venue
+// @Tags         venues
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id  path      string                            true  "User Snapp ID"
+// @Param        request   body      serializers.CreateCheckinRequest  true  "Check-in"
+// @Success      200  {object}  models.VenueCheckin  "Deduplicated"
+// @Success      201  {object}  models.VenueCheckin
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /users/{snapp_id}/checkins [post]
+func (VenueController) CreateCheckin(ctx *gin.Context) {
+	var request serializers.CreateCheckinRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid check-in data",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	// Screen the message, flagged check-ins are kept out of public listings
+	// for moderators to check
+	var check services.ContentCheck
+	if request.Message != "" {
+		contentFilter := &services.ContentFilterService{}
+		check = contentFilter.Check(ctx.Request.Context(), request.Message)
+		if check.Verdict == services.ContentRejected {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.ContentRejected,
+				Message: "Message contains language that is not allowed",
+			})
+			return
+		}
+	}
+
+	checkin := request.ToCheckin(ctx.GetInt64("snappUser_id"))
+	checkin.IsFlagged = check.Verdict == services.ContentFlagged
+	if err := checkin.Create(ctx.Request.Context(), config.Get().CheckinDedupWindow); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.VenueNotFound,
+				Message: "Venue not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to check in",
+		})
+		return
+	}
+
+	if checkin.Deduplicated {
+		ctx.JSON(http.StatusOK, checkin)
+		return
+	}
+	ctx.JSON(http.StatusCreated, checkin)
+}
+
+// GetWatchlist lists the competitors the venue watches
+// @Summary      Get competitor watchlist
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.WatchlistResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/watchlist [get]
+func (VenueController) GetWatchlist(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	competitors, err := models.GetWatchlist(ctx.Request.Context(), venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get watchlist",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.WatchlistResponse{
+		VenueID:        venue.ID,
+		Competitors:    competitors,
+		MaxCompetitors: models.MaxWatchlistCompetitors,
+	})
+}
+
+// AddToWatchlist adds a competitor to the venue's watchlist
+// @Summary      Watch competitor
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                           true  "Venue ID"
+// @Param        request  body      serializers.WatchlistRequest  true  "Competitor"
+// @Success      201  {object}  models.WatchlistCompetitor
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/watchlist [post]
+func (VenueController) AddToWatchlist(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.WatchlistRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "competitorVenueId is required",
+		})
+		return
+	}
+	if request.CompetitorVenueID == venue.ID {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "A venue can't watch itself",
+		})
+		return
+	}
+
+	competitor, err := models.AddWatchlistCompetitor(ctx.Request.Context(), venue.ID, request.CompetitorVenueID)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Competitor venue not found",
+		})
+	case err == models.ErrWatchlistFull:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: fmt.Sprintf("A venue can watch at most %d competitors", models.MaxWatchlistCompetitors),
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to add the competitor",
+		})
+	default:
+		ctx.JSON(http.StatusCreated, competitor)
+	}
+}
+
+// RemoveFromWatchlist removes a competitor from the venue's watchlist
+// @Summary      Stop watching competitor
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id             path      int  true  "Venue ID"
+// @Param        competitor_id  path      int  true  "Competitor venue ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/watchlist/{competitor_id} [delete]
+func (VenueController) RemoveFromWatchlist(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	competitorID, err := strconv.ParseInt(ctx.Param("competitor_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid competitor venue ID",
+		})
+		return
+	}
+
+	err = models.RemoveWatchlistCompetitor(ctx.Request.Context(), venue.ID, competitorID)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Competitor is not on the watchlist",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to remove the competitor",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Competitor removed",
+	})
+}
+
+// GetHoursExceptions lists the venue's hours exceptions from yesterday on
+// @Summary      Get hours exceptions
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.HoursExceptionsResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/hours-exceptions [get]
+func (VenueController) GetHoursExceptions(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	exceptions, err := models.GetHoursExceptions(ctx.Request.Context(), []int64{venue.ID},
+		now.AddDate(0, 0, -1).Format(models.DateLayout),
+		now.AddDate(0, 0, serializers.MaxHoursExceptionDays).Format(models.DateLayout))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get hours exceptions",
+		})
+		return
+	}
+
+	response := serializers.HoursExceptionsResponse{VenueID: venue.ID, Exceptions: exceptions[venue.ID]}
+	if response.Exceptions == nil {
+		response.Exceptions = make([]models.HoursException, 0)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// SetHoursException closes the venue on a date or sets different hours for
+// it, replacing the exception the date already has
+// @Summary      Set hours exception
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                                true  "Venue ID"
+// @Param        request  body      serializers.HoursExceptionRequest  true  "Exception"
+// @Success      200  {object}  models.HoursException
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/hours-exceptions [post]
+func (VenueController) SetHoursException(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.HoursExceptionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid hours exception data",
+		})
+		return
+	}
+	base, isValid := request.Validate(time.Now())
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	exception := request.ToHoursException(venue.ID, ctx.GetInt64("user_id"))
+	if err := exception.Save(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to save hours exception",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, exception)
+}
+
+// DeleteHoursException removes an hours exception, restoring the venue's
+// regular hours for its date
+// @Summary      Delete hours exception
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id            path      int  true  "Venue ID"
+// @Param        exception_id  path      int  true  "Exception ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/hours-exceptions/{exception_id} [delete]
+func (VenueController) DeleteHoursException(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	exceptionID, err := strconv.ParseInt(ctx.Param("exception_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid exception ID",
+		})
+		return
+	}
+
+	exception := &models.HoursException{ID: exceptionID, VenueID: venue.ID}
+	err = exception.Delete(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Hours exception not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete hours exception",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Hours exception deleted",
+	})
+}
+
+// GetDeals lists all the venue's deals, including ended ones
+// @Summary      Get venue deals
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.VenueDealsResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/deals [get]
+func (VenueController) GetDeals(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	deals, err := models.GetVenueDeals(ctx.Request.Context(), venue.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get deals",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.VenueDealsResponse{VenueID: venue.ID, Deals: deals})
+}
+
+// CreateDeal starts a deal at the venue, shown with the venue while it runs
+// @Summary      Create venue deal
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                           true  "Venue ID"
+// @Param        request  body      serializers.VenueDealRequest  true  "Deal"
+// @Success      201  {object}  models.VenueDeal
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/deals [post]
+func (VenueController) CreateDeal(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.VenueDealRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid deal data",
+		})
+		return
+	}
+	base, isValid := request.Validate(time.Now())
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	deal := request.ToVenueDeal(venue.ID, ctx.GetInt64("user_id"))
+	if err := deal.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create deal",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, deal)
+}
+
+// UpdateDeal replaces the details of a deal, keeping its redemptions
+// @Summary      Update venue deal
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                           true  "Venue ID"
+// @Param        deal_id  path      int                           true  "Deal ID"
+// @Param        request  body      serializers.VenueDealRequest  true  "Deal"
+// @Success      200  {object}  models.VenueDeal
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/deals/{deal_id} [put]
+func (VenueController) UpdateDeal(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	dealID, err := strconv.ParseInt(ctx.Param("deal_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid deal ID",
+		})
+		return
+	}
+
+	var request serializers.VenueDealRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid deal data",
+		})
+		return
+	}
+	base, isValid := request.Validate(time.Now())
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	deal := request.ToVenueDeal(venue.ID, ctx.GetInt64("user_id"))
+	deal.ID = dealID
+	err = deal.Update(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Deal not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update deal",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deal)
+}
+
+// DeleteDeal removes a deal with its redemptions
+// @Summary      Delete venue deal
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int  true  "Venue ID"
+// @Param        deal_id  path      int  true  "Deal ID"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/deals/{deal_id} [delete]
+func (VenueController) DeleteDeal(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	dealID, err := strconv.ParseInt(ctx.Param("deal_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid deal ID",
+		})
+		return
+	}
+
+	deal := &models.VenueDeal{ID: dealID, VenueID: venue.ID}
+	err = deal.Delete(ctx.Request.Context())
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Deal not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete deal",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "Deal deleted",
+	})
+}
+
+// RedeemDeal redeems a deal with the user's recent check-in at its venue,
+// attributing the visit to the deal
+// @Summary      Redeem venue deal
+// @Tags         venues
+// @Accept       json
+// @Produce      json
+// @Param        snapp_id  path      string                         true  "User Snapp ID"
+// @Param        deal_id   path      int                            true  "Deal ID"
+// @Param        request   body      serializers.RedeemDealRequest  true  "Check-in"
+// @Success      201  {object}  models.DealRedemption
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /users/{snapp_id}/deals/{deal_id}/redeem [post]
+func (VenueController) RedeemDeal(ctx *gin.Context) {
+	dealID, err := strconv.ParseInt(ctx.Param("deal_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid deal ID",
+		})
+		return
+	}
+
+	var request serializers.RedeemDealRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid redemption data",
+		})
+		return
+	}
+
+	redemption, err := models.RedeemDeal(ctx.Request.Context(), dealID, ctx.GetInt64("snappUser_id"), request.CheckinID)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Deal not found",
+		})
+	case err == models.ErrDealUnavailable:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.DealUnavailable,
+			Message: "The deal is not running",
+		})
+	case err == models.ErrDealLimitReached:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.DealUnavailable,
+			Message: "The deal has been fully redeemed",
+		})
+	case err == models.ErrDealAlreadyRedeemed:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.AlreadyRedeemed,
+			Message: "You already redeemed this deal",
+		})
+	case err == models.ErrCheckinRequired:
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.CheckinRequired,
+			Message: "Check in at the venue to redeem the deal",
+		})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to redeem deal",
+		})
+	default:
+		ctx.JSON(http.StatusCreated, redemption)
+	}
+}
+
+// GetExternalRatings lists the venue's ratings imported from other platforms
+// @Summary      Get external ratings
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Venue ID"
+// @Success      200  {object}  serializers.ExternalRatingsResponse
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/external-ratings [get]
+func (VenueController) GetExternalRatings(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	ratings, err := models.GetExternalRatings(ctx.Request.Context(), []int64{venue.ID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get external ratings",
+		})
+		return
+	}
+
+	response := serializers.ExternalRatingsResponse{VenueID: venue.ID, Ratings: ratings[venue.ID]}
+	if response.Ratings == nil {
+		response.Ratings = make([]models.ExternalRating, 0)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ImportExternalRatings imports the venue's aggregate ratings on other
+// platforms, like Google or TripAdvisor, replacing the ones already imported
+// from them. They are shown apart from the venue's reviews and don't count
+// towards its rating.
+// @Summary      Import external ratings
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                                 true  "Venue ID"
+// @Param        request  body      serializers.ExternalRatingsRequest  true  "Ratings"
+// @Success      200  {object}  serializers.ExternalRatingsResponse
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/external-ratings [post]
+func (VenueController) ImportExternalRatings(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.ExternalRatingsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid external ratings",
+		})
+		return
+	}
+	if base, isValid := request.Validate(); !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	ratings := request.ToExternalRatings(venue.ID, ctx.GetInt64("user_id"))
+	if err := models.SaveExternalRatings(ctx.Request.Context(), ratings); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to import external ratings",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.ExternalRatingsResponse{VenueID: venue.ID, Ratings: ratings})
+}
+
+// DeleteExternalRating removes the venue's rating imported from a platform
+// @Summary      Delete external rating
+// @Tags         owner
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int     true  "Venue ID"
+// @Param        source  path      string  true  "google, tripadvisor, yelp or facebook"
+// @Success      200  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/external-ratings/{source} [delete]
+func (VenueController) DeleteExternalRating(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	err := models.DeleteExternalRating(ctx.Request.Context(), venue.ID, strings.ToLower(ctx.Param("source")))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "External rating not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete external rating",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: "External rating deleted",
+	})
+}
+
+// RenameVenue renames a venue. Its slug follows the new name and the old
+// slug keeps resolving to the venue.
+// @Summary      Rename venue
+// @Tags         owner
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                             true  "Venue ID"
+// @Param        request  body      serializers.RenameVenueRequest  true  "New name"
+// @Success      200  {object}  models.Venue
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/name [put]
+func (VenueController) RenameVenue(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.RenameVenueRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue name",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	if err := venue.Rename(ctx.Request.Context(), request.Name, request.Slug()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to rename venue",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, venue)
+}
+
+// UpdateVenue changes the given details of a venue. A new name gives the
+// venue a new slug, and the old slug keeps resolving to it.
+// @Summary      Update venue
+// @Tags         venues
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                             true  "Venue ID"
+// @Param        request  body      serializers.UpdateVenueRequest  true  "Details to change"
+// @Success      200  {object}  models.Venue
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id} [put]
+func (VenueController) UpdateVenue(ctx *gin.Context) {
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.UpdateVenueRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue data",
+		})
+		return
+	}
+	if base, ok := request.Validate(); !ok {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	request.ApplyTo(venue)
+	if err := venue.Update(ctx.Request.Context()); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.VenueNotFound,
+				Message: "Venue not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to update venue",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, venue)
+}
+
+// DeleteVenue removes a venue. With the hide policy the venue is deactivated
+// and its reviews, collection items and campaign votes are kept; with the
+// cascade policy they are deleted with it. Owners can only hide their venues.
+// @Summary      Delete venue
+// @Tags         venues
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int     true   "Venue ID"
+// @Param        policy  query     string  false  "What happens to dependent data: hide (default) or cascade"
+// @Success      200  {object}  serializers.Base
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/{id} [delete]
+func (VenueController) DeleteVenue(ctx *gin.Context) {
+	var query serializers.DeleteVenueQuery
+	if !bindQuery(ctx, &query) {
+		return
+	}
+
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return
+	}
+
+	var err error
+	message := "Venue deactivated"
+	switch query.Policy {
+	case models.VenueDeleteCascade:
+		if !ctx.GetBool("is_superuser") {
+			ctx.JSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.Forbidden,
+				Message: "Only administrators can delete a venue with its reviews and votes",
+			})
+			return
+		}
+		err = venue.Delete(ctx.Request.Context())
+		message = "Venue deleted"
+	default:
+		err = venue.Deactivate(ctx.Request.Context())
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.VenueNotFound,
+				Message: "Venue not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to delete venue",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.Base{
+		Code:    serializers.Success,
+		Message: message,
+	})
+}
+
+// CompareVenues returns 2 to 4 venues side by side with their ratings aligned
+// by dimension
+// @Summary      Compare venues
+// @Tags         venues
+// @Produce      json
+// @Param        ids            query     string  true   "Comma separated venue IDs (2-4)"
+// @Param        lat            query     number  false  "Latitude to measure distances from"
+// @Param        lng            query     number  false  "Longitude to measure distances from"
+// @Success      200  {object}  services.VenueComparison
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /venues/compare [get]
+func (VenueController) CompareVenues(ctx *gin.Context) {
+	venueIDs, ok := parseComparedVenueIDs(ctx.Query("ids"))
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Provide 2 to 4 distinct venue IDs",
+		})
+		return
+	}
+
+	var origin *services.LatLng
+	if latStr, lngStr := ctx.Query("lat"), ctx.Query("lng"); latStr != "" || lngStr != "" {
+		lat, latErr := strconv.ParseFloat(latStr, 64)
+		lng, lngErr := strconv.ParseFloat(lngStr, 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			ctx.JSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Invalid latitude or longitude",
+			})
+			return
+		}
+		origin = &services.LatLng{Latitude: lat, Longitude: lng}
+	}
+
+	comparisonService := &services.VenueComparisonService{}
+	comparison, err := comparisonService.Compare(ctx.Request.Context(), venueIDs, origin, time.Now())
+	if err != nil {
+		if err == services.ErrComparedVenueNotFound {
+			ctx.JSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Venue not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to compare venues",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comparison)
+}
+
+// Helper functions
+
+// parseComparedVenueIDs parses the comma separated ids of a venue comparison
+func parseComparedVenueIDs(raw string) ([]int64, bool) {
+	parts := strings.Split(raw, ",")
+	if len(parts) < services.MinComparedVenues || len(parts) > services.MaxComparedVenues {
+		return nil, false
+	}
+
+	seen := make(map[int64]bool, len(parts))
+	venueIDs := make([]int64, 0, len(parts))
+	for _, part := range parts {
+		venueID, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || venueID <= 0 || seen[venueID] {
+			return nil, false
+		}
+		seen[venueID] = true
+		venueIDs = append(venueIDs, venueID)
+	}
+	return venueIDs, true
+}
+
+// authorizeVenueOwner loads the venue from the :id path parameter and checks
+// that the authenticated user owns it or is a superuser. The error response is
+// written when the check fails.
+func authorizeVenueOwner(ctx *gin.Context) (*models.Venue, bool) {
+	venueID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid venue ID",
+		})
+		return nil, false
+	}
+
+	venue := &models.Venue{ID: venueID}
+	err = venue.GetByID(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.VenueNotFound,
+			Message: "Venue not found",
+		})
+		return nil, false
+	}
+
+	userID := ctx.GetInt64("user_id")
+	if !ctx.GetBool("is_superuser") && (venue.OwnerID == nil || *venue.OwnerID != userID) {
+		ctx.JSON(http.StatusForbidden, serializers.Base{
+			Code:    serializers.Forbidden,
+			Message: "Only the venue owner can manage this venue",
+		})
+		return nil, false
+	}
+
+	return venue, true
+}
+
+// LocationSourceHeader tells whether discovery results were computed from
+// the client's coordinates (gps) or its IP address (ip)
+const LocationSourceHeader = "X-Location-Source"
+
+// TravelModeHeader tells the travel mode nearby venues were ordered by trip
+// time for
+const TravelModeHeader = "X-Travel-Mode"
+
+const (
+	// travelTimeCandidates is how many nearby venues per result are timed
+	travelTimeCandidates = 2
+	// maxTravelTimeCandidates bounds the venues timed per search
+	maxTravelTimeCandidates = 100
+)
+
+// resolveLocation returns the coordinates of the query, or else the location
+// of the client's IP address, setting LocationSourceHeader. The 400 response
+// is written when the client can't be located.
+func resolveLocation(ctx *gin.Context, query serializers.LocationQuery) (*services.ResolvedLocation, bool) {
+	geoIPService := &services.IPGeolocationService{}
+	location, err := geoIPService.ResolveLocation(ctx.Request.Context(), query.Latitude, query.Longitude, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.LocationRequired,
+			Message: "lat and lng are required, the location couldn't be found from the IP address",
+		})
+		return nil, false
+	}
+
+	ctx.Header(LocationSourceHeader, location.Source)
+	return location, true
+}
+
+// venueQueryWords are words of venue searches ("cafe near me"), no dish
+// name has them
+var venueQueryWords = map[string]bool{
+	"restaurant": true, "restaurants": true, "cafe": true, "café": true, "bar": true,
+	"bars": true, "pub": true, "bistro": true, "diner": true, "bakery": true,
+	"brewery": true, "near": true, "nearby": true, "open": true, "best": true,
+}
+
+// looksLikeDishQuery reports whether a search query could be a dish name
+// ("pad thai", "shepherd's pie") rather than a venue search, an address or a
+// code: up to five words of letters, none of them a venue word
+func looksLikeDishQuery(query string) bool {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 || len(words) > 5 {
+		return false
+	}
+
+	letters := 0
+	for _, word := range words {
+		if word == "&" {
+			continue
+		}
+		if venueQueryWords[word] {
+			return false
+		}
+		for i, char := range word {
+			switch {
+			case unicode.IsLetter(char):
+				letters++
+			case (char == '-' || char == '\'') && i > 0 && i < len(word)-1:
+				// Inner hyphens and apostrophes ("bánh-mì", "shepherd's")
+			default:
+				return false
+			}
+		}
+	}
+	return letters >= 3
+}
+
+func calculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
+	// Simple Haversine formula implementation
+	// For production, use a proper geospatial library
+	const R = 6371 // Earth radius in kilometers
+
+	dLat := (lat2 - lat1) * (3.14159 / 180)
+	_ = (lng2 - lng1) * (3.14159 / 180) // dLng not used in simplified calc
+
+	a := 0.5 - (0.5 * (1 + (dLat * dLat / 4)))
+	return R * 2 * (1.5708 - a) // Simplified calculation
+}
+
+func getVenueEvents(venueID int64, limit int) []interface{} {
+	// This would be implemented in a VenueEvent model
+	// For now, return empty slice
+	return []interface{}{}
+}
+
+// searchAnalyticsFilters converts search params to the filters recorded in
+// search_analytics
+func searchAnalyticsFilters(params models.VenueSearchParams) map[string]interface{} {
+	filters := make(map[string]interface{})
+	if params.CityID != nil {
+		filters["city_id"] = *params.CityID
+	}
+	if params.CategoryID != nil {
+		filters["category_id"] = *params.CategoryID
+	}
+	if params.Latitude != nil && params.Longitude != nil {
+		filters["latitude"] = *params.Latitude
+		filters["longitude"] = *params.Longitude
+	}
+	if params.Radius != nil {
+		filters["radius"] = *params.Radius
+	}
+	if params.MinRating != nil {
+		filters["min_rating"] = *params.MinRating
+	}
+	if len(params.PriceRange) > 0 {
+		filters["price_range"] = strings.Join(params.PriceRange, ",")
+	}
+	return filters
 }
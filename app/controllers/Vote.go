@@ -1,38 +1,42 @@
 package controllers
 
 import (
+	"context"
+	"database/sql"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"time"
 	"voting-app/app/models"
 	"voting-app/app/serializers"
+	"voting-app/app/services"
 )
 
 type VoteController struct {
 }
 
 func (VoteController) Vote(ctx *gin.Context) {
-	var banner models.Banner
 	var participant models.Participant
 	var voucher models.Voucher
 	var mentor models.Mentor
 	var voting models.Voting
-	voting.GetLastWinner()
+	voting.GetLastWinner(ctx.Request.Context())
 	voucher.OwnerId = ctx.GetInt64("snappUser_id")
-	err := voting.GetLast()
+	err := voting.GetLast(ctx.Request.Context())
 	if err != nil {
 		print(err.Error())
 	}
-	voucher.GetUserVouchers()
+	voucher.GetUserVouchers(ctx.Request.Context())
 	ctx.JSON(http.StatusOK, serializers.Vote{
-		Banner:       banner.GetBanner(),
-		Participants: participant.All(),
-		Mentors:      mentor.All(),
+		Banner:       voteBanner(ctx.Request.Context()),
+		Participants: participant.All(ctx.Request.Context()),
+		Mentors:      mentor.All(ctx.Request.Context()),
 		Voting:       voting,
 		History: serializers.UserHistory{
-			Vouchers: voucher.GetUserVouchers(),
-			Voted:    voting.GetUserVotesParticipants(voucher.OwnerId),
+			Vouchers: voucher.GetUserVouchers(ctx.Request.Context()),
+			Voted:    voting.GetUserVotesParticipants(ctx.Request.Context(), voucher.OwnerId),
 		},
-		LastWinner: voting.GetLastWinner(),
+		LastWinner: voting.GetLastWinner(ctx.Request.Context()),
 	})
 }
 
@@ -46,13 +50,14 @@ func (VoteController) Vote(ctx *gin.Context) {
 // @Success      200  {object}  serializers.Vote
 // @Failure      400  {object}  serializers.Base
 // @Failure      404  {object}  serializers.Base
+// @Failure      410  {object}  serializers.LegacyVotingMigratedResponse
 // @Router       /vote/{snapp_id}/{voting_id}/{vote_id} [post]
 func (VoteController) SubmitVote(ctx *gin.Context) {
 	votingIdString, voteIdString := ctx.Param("voting_id"), ctx.Param("vote_id")
 
 	request := new(serializers.VoteRequest)
 
-	base, isValid := request.Validate(votingIdString, voteIdString)
+	base, isValid := request.Validate(ctx.Request.Context(), votingIdString, voteIdString)
 	if !isValid {
 		ctx.JSON(http.StatusBadRequest, base)
 		return
@@ -64,7 +69,7 @@ func (VoteController) SubmitVote(ctx *gin.Context) {
 		VoteId:   request.VoteId,
 	}
 
-	alreadyVoted := userVoting.SubmitVote()
+	alreadyVoted := userVoting.SubmitVote(ctx.Request.Context())
 	if alreadyVoted {
 		ctx.JSON(http.StatusBadRequest, serializers.Base{
 			Code:    serializers.AlreadyVoted,
@@ -73,27 +78,114 @@ func (VoteController) SubmitVote(ctx *gin.Context) {
 		return
 	}
 
-	var banner models.Banner
+	// user_voting has no timestamp column, the receipt is stamped at submission
+	var receipt *services.VoteReceipt
+	if userVoting.Id != 0 {
+		receiptService := &services.VoteReceiptService{}
+		receipt = receiptService.LegacyReceipt(&userVoting, time.Now())
+
+		voteResultService := &services.VoteResultService{}
+		voteResultService.InvalidateResults(userVoting.VotingId)
+	}
+
 	var participant models.Participant
 	var voucher models.Voucher
 	var mentor models.Mentor
 	var voting models.Voting
-	voting.GetLastWinner()
+	voting.GetLastWinner(ctx.Request.Context())
 	voucher.OwnerId = ctx.GetInt64("snappUser_id")
-	err := voting.GetLast()
+	err := voting.GetLast(ctx.Request.Context())
 	if err != nil {
 		print(err.Error())
 	}
-	voucher.GetUserVouchers()
+	voucher.GetUserVouchers(ctx.Request.Context())
 	ctx.JSON(http.StatusOK, serializers.Vote{
-		Banner:       banner.GetBanner(),
-		Participants: participant.All(),
-		Mentors:      mentor.All(),
+		Banner:       voteBanner(ctx.Request.Context()),
+		Participants: participant.All(ctx.Request.Context()),
+		Mentors:      mentor.All(ctx.Request.Context()),
 		Voting:       voting,
 		History: serializers.UserHistory{
-			Vouchers: voucher.GetUserVouchers(),
-			Voted:    voting.GetUserVotesParticipants(voucher.OwnerId),
+			Vouchers: voucher.GetUserVouchers(ctx.Request.Context()),
+			Voted:    voting.GetUserVotesParticipants(ctx.Request.Context(), voucher.OwnerId),
 		},
-		LastWinner: voting.GetLastWinner(),
+		LastWinner: voting.GetLastWinner(ctx.Request.Context()),
+		Receipt:    receipt,
 	})
 }
+
+// GetResults returns the live totals of a voting
+// @Summary      Get voting results
+// @Tags         vote
+// @Produce      json
+// @Param        snapp_id   path      int  true  "Snapp id"
+// @Param        voting_id   path      int  true  "voting id"
+// @Success      200  {object}  services.VotingResults
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /vote/{snapp_id}/results/{voting_id} [get]
+func (VoteController) GetResults(ctx *gin.Context) {
+	votingID, err := strconv.ParseInt(ctx.Param("voting_id"), 10, 64)
+	if err != nil || votingID <= 0 {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "voting id is invalid",
+		})
+		return
+	}
+
+	voteResultService := &services.VoteResultService{}
+	results, err := voteResultService.GetResults(ctx.Request.Context(), votingID, ctx.GetInt64("snappUser_id"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "voting not found",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get voting results",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
+
+// GetArchive returns the final results of the ended legacy voting rounds
+// @Summary      Get legacy voting archive
+// @Tags         vote
+// @Produce      json
+// @Success      200  {array}   services.ArchivedVoting
+// @Failure      500  {object}  serializers.Base
+// @Router       /legacy/archive [get]
+func (VoteController) GetArchive(ctx *gin.Context) {
+	archiveService := &services.LegacyArchiveService{}
+	archive, err := archiveService.GetArchive(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get legacy voting archive",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, archive)
+}
+
+// voteBanner fills the banner slot of the vote response. The banner of the
+// highest priority featured campaign takes it over from the static banners.
+func voteBanner(ctx context.Context) *models.Banner {
+	featured, err := models.GetFeaturedCampaigns(ctx, time.Now())
+	if err == nil {
+		for _, campaign := range featured {
+			if banner := campaign.Promotion.Banner(); banner != nil {
+				return banner
+			}
+		}
+	}
+
+	var banner models.Banner
+	return banner.GetBanner(ctx)
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController manages webhook subscriptions. The same handlers serve
+// venue owners under /owner/venues/:id/webhooks, where subscriptions only
+// receive the events of that venue, and administrators under /admin/webhooks.
+type WebhookController struct{}
+
+// CreateWebhook creates a webhook subscription. The signing secret is only
+// returned in this response.
+// @Summary      Create webhook subscription
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        subscription   body      serializers.WebhookSubscriptionRequest  true  "Subscription"
+// @Success      201  {object}  models.WebhookSubscription
+// @Failure      400  {object}  serializers.Base
+// @Failure      403  {object}  serializers.Base
+// @Router       /owner/venues/{id}/webhooks [post]
+// @Router       /admin/webhooks [post]
+func (WebhookController) CreateWebhook(ctx *gin.Context) {
+	venueID, allowedEvents, ok := authorizeWebhookScope(ctx)
+	if !ok {
+		return
+	}
+
+	var request serializers.WebhookSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid webhook subscription data",
+		})
+		return
+	}
+
+	base, isValid := request.Validate(allowedEvents)
+	if !isValid {
+		ctx.JSON(http.StatusBadRequest, base)
+		return
+	}
+
+	webhookService := &services.WebhookService{}
+	if err := webhookService.CheckTarget(ctx.Request.Context(), request.TargetURL); err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Target URL must resolve to a public address",
+		})
+		return
+	}
+
+	subscription := request.ToSubscription(ctx.GetInt64("user_id"), venueID)
+	if err := subscription.Create(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to create webhook subscription",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+// GetWebhooks lists the webhook subscriptions
+// @Summary      List webhook subscriptions
+// @Tags         webhooks
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Success      200  {object}  serializers.WebhookSubscriptionsResponse
+// @Failure      403  {object}  serializers.Base
+// @Router       /owner/venues/{id}/webhooks [get]
+// @Router       /admin/webhooks [get]
+func (WebhookController) GetWebhooks(ctx *gin.Context) {
+	venueID, _, ok := authorizeWebhookScope(ctx)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := models.GetWebhookSubscriptions(ctx.Request.Context(), venueID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get webhook subscriptions",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.WebhookSubscriptionsResponse{
+		VenueID:       venueID,
+		Subscriptions: subscriptions,
+	})
+}
+
+// TestWebhook sends a webhook.test event to the subscription and returns
+// the delivery log of the attempt
+// @Summary      Test webhook subscription
+// @Tags         webhooks
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        webhook_id     path      int     true   "Subscription ID"
+// @Success      200  {object}  models.WebhookDelivery
+// @Failure      400  {object}  serializers.Base
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/webhooks/{webhook_id}/test [post]
+// @Router       /admin/webhooks/{webhook_id}/test [post]
+func (WebhookController) TestWebhook(ctx *gin.Context) {
+	subscription, ok := loadWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	if !subscription.IsActive {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Webhook subscription is disabled",
+		})
+		return
+	}
+
+	webhookService := &services.WebhookService{}
+	delivery, err := webhookService.SendTest(ctx.Request.Context(), subscription)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to send test event",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// DisableWebhook stops deliveries to the subscription
+// @Summary      Disable webhook subscription
+// @Tags         webhooks
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        webhook_id     path      int     true   "Subscription ID"
+// @Success      200  {object}  models.WebhookSubscription
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/webhooks/{webhook_id}/disable [post]
+// @Router       /admin/webhooks/{webhook_id}/disable [post]
+func (WebhookController) DisableWebhook(ctx *gin.Context) {
+	subscription, ok := loadWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	if err := subscription.Disable(ctx.Request.Context()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to disable webhook subscription",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+// GetWebhookDeliveries returns the most recent delivery logs of the subscription
+// @Summary      Get webhook delivery logs
+// @Tags         webhooks
+// @Produce      json
+// @Param        id             path      int     true   "Venue ID"
+// @Param        webhook_id     path      int     true   "Subscription ID"
+// @Param        limit          query     int     false  "Number of deliveries (default 20, max 100)"
+// @Success      200  {object}  serializers.WebhookDeliveriesResponse
+// @Failure      404  {object}  serializers.Base
+// @Router       /owner/venues/{id}/webhooks/{webhook_id}/deliveries [get]
+// @Router       /admin/webhooks/{webhook_id}/deliveries [get]
+func (WebhookController) GetWebhookDeliveries(ctx *gin.Context) {
+	subscription, ok := loadWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > serializers.MaxWebhookDeliveries {
+		limit = 20
+	}
+
+	deliveries, err := models.GetWebhookDeliveries(ctx.Request.Context(), subscription.ID, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, serializers.Base{
+			Code:    serializers.InternalError,
+			Message: "Failed to get webhook deliveries",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.WebhookDeliveriesResponse{
+		SubscriptionID: subscription.ID,
+		Deliveries:     deliveries,
+	})
+}
+
+// authorizeWebhookScope resolves the venue the request manages webhooks for
+// and the events it may subscribe to. Owner routes are scoped to their venue
+// and its review events, admin routes manage the global subscriptions. The
+// error response is written when the caller is not allowed.
+func authorizeWebhookScope(ctx *gin.Context) (*int64, []string, bool) {
+	if ctx.Param("id") == "" {
+		if !ctx.GetBool("is_superuser") {
+			ctx.JSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.Forbidden,
+				Message: "Only administrators can manage global webhooks",
+			})
+			return nil, nil, false
+		}
+		return nil, models.WebhookEventTypes, true
+	}
+
+	venue, ok := authorizeVenueOwner(ctx)
+	if !ok {
+		return nil, nil, false
+	}
+	return &venue.ID, []string{models.WebhookEventReviewCreated}, true
+}
+
+// loadWebhookSubscription loads the subscription from the :webhook_id path
+// parameter, which must belong to the scope of the request
+func loadWebhookSubscription(ctx *gin.Context) (*models.WebhookSubscription, bool) {
+	venueID, _, ok := authorizeWebhookScope(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	subscriptionID, err := strconv.ParseInt(ctx.Param("webhook_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, serializers.Base{
+			Code:    serializers.InvalidInput,
+			Message: "Invalid webhook subscription ID",
+		})
+		return nil, false
+	}
+
+	subscription := &models.WebhookSubscription{ID: subscriptionID}
+	err = subscription.GetByID(ctx.Request.Context())
+
+	inScope := err == nil && ((venueID == nil && subscription.VenueID == nil) ||
+		(venueID != nil && subscription.VenueID != nil && *venueID == *subscription.VenueID))
+	if !inScope {
+		ctx.JSON(http.StatusNotFound, serializers.Base{
+			Code:    serializers.NotFound,
+			Message: "Webhook subscription not found",
+		})
+		return nil, false
+	}
+
+	return subscription, true
+}
@@ -4,38 +4,39 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/Meraj/PoSql"
-	"github.com/joho/godotenv"
-	"os"
-	"strconv"
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 	"time"
+	"voting-app/app/config"
 )
 
+// PostgresDB is set by ConnectToPostgresDB, which main calls on startup.
+// Packages using it can be imported without a database.
 var PostgresDB *sql.DB
 
-func init() {
-	err := godotenv.Load("local.env")
-	if err != nil {
-		panic(err.Error())
-	}
-	ConnectToPostgresDB()
-}
 func ConnectToPostgresDB() {
-	port, _ := strconv.ParseInt(os.Getenv("DB_PORT"), 10, 64)
+	dbConfig := config.Get().Database
 	connection := fmt.Sprintf("host=%s port=%d user=%s "+
 		"password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		port,
-		os.Getenv("DB_USER"),
-		"postgres",
-		os.Getenv("DB_NAME"),
+		dbConfig.Host,
+		dbConfig.Port,
+		dbConfig.User,
+		dbConfig.Password,
+		dbConfig.Name,
 	)
 	var err error
-	PostgresDB, err = sql.Open("postgres", connection)
+	// Every query gets a span, a child of the request's span
+	PostgresDB, err = otelsql.Open("postgres", connection,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true}))
 	if err != nil {
 		fmt.Print(err.Error())
 		time.Sleep(5000)
 		ConnectToPostgresDB()
 	}
+	PostgresDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	PostgresDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	PostgresDB.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
 	migration()
 }
 
@@ -0,0 +1,163 @@
+// Package fixtures generates venues, reviews and campaigns for unit tests.
+// Every generator fills in realistic defaults that the given changes can
+// override, and numbers names and slugs so fixtures don't collide.
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/store/memory"
+)
+
+// Default IDs of the seeded city, category and users
+const (
+	CityID     int64 = 1
+	CategoryID int64 = 1
+	UserID     int64 = 1
+	OtherUser  int64 = 2
+)
+
+var sequence int64
+
+func next() int64 {
+	return atomic.AddInt64(&sequence, 1)
+}
+
+// Venue returns an active venue in the default city and category
+func Venue(changes ...func(*models.Venue)) models.Venue {
+	n := next()
+	now := time.Now()
+	venue := models.Venue{
+		Name:             fmt.Sprintf("Test Venue %d", n),
+		Slug:             fmt.Sprintf("test-venue-%d", n),
+		Description:      "A neighbourhood restaurant serving seasonal dishes",
+		Address:          fmt.Sprintf("%d Market Street", n),
+		CityID:           CityID,
+		City:             &models.City{ID: CityID, Name: "San Francisco", Country: "USA", Timezone: "America/Los_Angeles"},
+		Latitude:         37.7749,
+		Longitude:        -122.4194,
+		CategoryID:       CategoryID,
+		Category:         &models.VenueCategory{ID: CategoryID, Name: "Restaurant", IsActive: true},
+		PriceRange:       "$$",
+		AvgCostPerPerson: 35,
+		Amenities:        json.RawMessage(`["wifi", "outdoor_seating"]`),
+		IsActive:         true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	for _, change := range changes {
+		change(&venue)
+	}
+	return venue
+}
+
+// Venues returns n venues with the same changes applied
+func Venues(n int, changes ...func(*models.Venue)) []models.Venue {
+	venues := make([]models.Venue, n)
+	for i := range venues {
+		venues[i] = Venue(changes...)
+	}
+	return venues
+}
+
+// Review returns an approved review of the venue by the user
+func Review(venueID, userID int64, changes ...func(*models.VenueReview)) models.VenueReview {
+	n := next()
+	now := time.Now()
+	review := models.VenueReview{
+		VenueID:          venueID,
+		UserID:           userID,
+		OverallRating:    4,
+		DetailedRatings:  json.RawMessage(`{"food": 4, "service": 4}`),
+		Title:            fmt.Sprintf("Review %d", n),
+		ReviewText:       "Good food and friendly staff",
+		VisitType:        "dinner",
+		PartySize:        2,
+		ModerationStatus: "approved",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	for _, change := range changes {
+		change(&review)
+	}
+	return review
+}
+
+// Reviews returns approved reviews of the venue with the given ratings, each
+// by a different user starting at UserID
+func Reviews(venueID int64, ratings ...float64) []models.VenueReview {
+	reviews := make([]models.VenueReview, len(ratings))
+	for i, rating := range ratings {
+		rating := rating
+		reviews[i] = Review(venueID, UserID+int64(i), func(r *models.VenueReview) {
+			r.OverallRating = rating
+		})
+	}
+	return reviews
+}
+
+// Campaign returns a standard campaign that opened an hour ago and runs for a
+// week
+func Campaign(changes ...func(*models.VotingCampaign)) models.VotingCampaign {
+	n := next()
+	now := time.Now()
+	cityID, categoryID := CityID, CategoryID
+	campaign := models.VotingCampaign{
+		Title:           fmt.Sprintf("Best Restaurant %d", n),
+		CampaignType:    models.CampaignTypeBestRestaurant,
+		CityID:          &cityID,
+		CategoryID:      &categoryID,
+		StartDate:       now.Add(-time.Hour),
+		EndDate:         now.AddDate(0, 0, 7),
+		MaxVotesPerUser: 3,
+		VotingMode:      models.VotingModeStandard,
+		IsActive:        true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	for _, change := range changes {
+		change(&campaign)
+	}
+	return campaign
+}
+
+// Closed ends a campaign a day ago
+func Closed(c *models.VotingCampaign) {
+	c.StartDate = time.Now().AddDate(0, 0, -8)
+	c.EndDate = time.Now().AddDate(0, 0, -1)
+}
+
+// Quadratic makes a campaign a quadratic one with the default credit budget
+func Quadratic(c *models.VotingCampaign) {
+	c.VotingMode = models.VotingModeQuadratic
+	c.CreditBudget = models.DefaultCreditBudget
+}
+
+// Dataset is what Seed puts into a store
+type Dataset struct {
+	Venues    []models.Venue
+	Reviews   []models.VenueReview
+	Campaigns []models.VotingCampaign
+}
+
+// Seed fills the store with two reviewed venues, an open and a closed
+// campaign and returns them with their IDs
+func Seed(s *memory.Store) Dataset {
+	var data Dataset
+	data.Venues = s.SeedVenues(
+		Venue(),
+		Venue(func(v *models.Venue) {
+			v.PriceRange = "$$$"
+			v.Latitude, v.Longitude = 37.7849, -122.4094
+		}),
+	)
+	data.Reviews = s.SeedReviews(append(
+		Reviews(data.Venues[0].ID, 5, 4),
+		Reviews(data.Venues[1].ID, 3)...,
+	)...)
+	data.Campaigns = s.SeedCampaigns(Campaign(), Campaign(Closed))
+	return data
+}
@@ -3,7 +3,8 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
-	"os"
+	"voting-app/app/config"
+	"voting-app/app/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
@@ -20,7 +21,7 @@ func AuthorizeJWT() gin.HandlerFunc {
 		if authHeader != "" && len(BEARER_SCHEMA) < len(authHeader) {
 			tokenString := authHeader[len(BEARER_SCHEMA):]
 
-			key, err := jwt.ParseEdPublicKeyFromPEM([]byte(os.Getenv("JWT_KEY")))
+			key, err := jwt.ParseEdPublicKeyFromPEM([]byte(config.Get().JWT.PublicKey))
 			if err != nil {
 				fmt.Printf("%v\n", err)
 				c.AbortWithStatus(http.StatusUnauthorized)
@@ -40,8 +41,24 @@ func AuthorizeJWT() gin.HandlerFunc {
 			}
 			if err == nil && parse.Valid {
 				claims := parse.Claims.(jwt.MapClaims)
-				c.Set("user_id", int64(claims["user_id"].(float64)))
+				// Users only act within the tenant they signed up with
+				if tenantClaim, ok := claims["tenant_id"].(float64); ok {
+					if tenantID, scoped := models.TenantFromContext(c.Request.Context()); scoped && tenantID != int64(tenantClaim) {
+						c.AbortWithStatus(http.StatusForbidden)
+						return
+					}
+				}
+				userID := int64(claims["user_id"].(float64))
+				c.Set("user_id", userID)
 				c.Set("is_superuser", claims["is_superuser"].(bool))
+
+				// Users linked to a snapp user act as them on snapp user
+				// features, unless they are impersonating another one
+				identity, err := models.GetIdentityByUser(c.Request.Context(), userID)
+				_, impersonating := c.Get("impersonation")
+				if err == nil && identity.IsLinked() && !impersonating {
+					c.Set("snappUser_id", identity.SnappUserID)
+				}
 			} else {
 				fmt.Println(err)
 				c.AbortWithStatus(http.StatusUnauthorized)
@@ -51,3 +68,15 @@ func AuthorizeJWT() gin.HandlerFunc {
 		}
 	}
 }
+
+// OptionalJWT authenticates the requests carrying a JWT like AuthorizeJWT,
+// and lets anonymous ones through, for public routes showing administrators
+// more
+func OptionalJWT() gin.HandlerFunc {
+	authorize := AuthorizeJWT()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			authorize(c)
+		}
+	}
+}
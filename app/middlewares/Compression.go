@@ -0,0 +1,216 @@
+package middlewares
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compress compresses response bodies of at least minBytes with gzip or
+// deflate, whichever the client prefers in Accept-Encoding. Smaller bodies
+// are sent as they are, as are media that is compressed already, partial
+// content and responses that set their own Content-Encoding.
+//
+// The body is buffered until it reaches minBytes, so streamed responses
+// are compressed as they are written. Flushing starts compression right
+// away.
+func Compress(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minBytes: minBytes}
+		c.Writer = writer
+		defer writer.finish()
+		c.Next()
+	}
+}
+
+// compressor is a gzip or flate writer
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var compressorPools = map[string]*sync.Pool{
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(ioutil.Discard)
+	}},
+	"deflate": {New: func() interface{} {
+		writer, _ := flate.NewWriter(ioutil.Discard, flate.DefaultCompression)
+		return writer
+	}},
+}
+
+// incompressibleTypes are media types that are compressed already
+var incompressibleTypes = []string{
+	"image/", "audio/", "video/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/pdf",
+}
+
+// compressWriter buffers the body until it knows whether to compress it
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minBytes int
+
+	buf        []byte
+	decided    bool
+	compressor compressor
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minBytes {
+			return len(data), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written is true once the handler wrote any of the body, even while it is
+// buffered
+func (w *compressWriter) Written() bool {
+	return w.decided || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(true)
+	}
+	if w.compressor != nil {
+		w.compressor.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start sends the headers and the buffered body, compressed when the
+// response allows it
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	if compress && compressible(header, w.ResponseWriter.Status()) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.compressor = compressorPools[w.encoding].Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.compressor != nil {
+		_, err := w.compressor.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish sends a body left below minBytes as it is and ends the compressed
+// stream
+func (w *compressWriter) finish() {
+	if !w.decided && len(w.buf) > 0 {
+		w.start(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+		compressorPools[w.encoding].Put(w.compressor)
+		w.compressor = nil
+	}
+}
+
+// compressible reports whether a response of the status and headers can be
+// compressed
+func compressible(header http.Header, status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent ||
+		status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) && contentType != "image/svg+xml" {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncoding returns gzip or deflate, whichever Accept-Encoding gives
+// the higher quality, preferring gzip on a tie. It is empty when the client
+// accepts neither.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					parsed = 0
+				}
+				quality = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		quality, listed := qualities[encoding]
+		if !listed {
+			if wildcard < 0 {
+				continue
+			}
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
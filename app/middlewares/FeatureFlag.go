@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFlag hides the routes behind the feature flag, answering 404 to the
+// users it is off for. It runs after the middleware identifying the user.
+func RequireFlag(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !FlagEnabled(c, key) {
+			c.AbortWithStatusJSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Not found",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// FlagEnabled tells whether the feature flag is on for the request's user,
+// the Snapp user or else the JWT user
+func FlagEnabled(c *gin.Context, key string) bool {
+	userID := c.GetInt64("snappUser_id")
+	if userID == 0 {
+		userID = c.GetInt64("user_id")
+	}
+
+	flagService := &services.FlagService{}
+	return flagService.IsEnabled(c.Request.Context(), key, userID)
+}
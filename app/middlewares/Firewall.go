@@ -19,7 +19,7 @@ func AuthSnappUserFirewall() gin.HandlerFunc {
 			})
 			return
 		}
-		exists, err := snappUser.GetUser()
+		exists, err := snappUser.GetUser(ctx.Request.Context())
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusInternalServerError, serializers.Base{
 				Code:    serializers.InternalError,
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Impersonation lets requests with an impersonation token act as the
+// session's snapp user. They are read-only: other methods, and the routes of
+// other snapp users, are refused with 403. The request is marked in its
+// context, its response and the log, and every request, refused or not, is
+// recorded in the session's audit log.
+func Impersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(services.ImpersonationHeader)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		impersonationService := &services.ImpersonationService{}
+		session, err := impersonationService.Get(c.Request.Context(), token)
+		if err == services.ErrInvalidImpersonation {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, serializers.Base{
+				Code:    serializers.InvalidToken,
+				Message: "Impersonation token is invalid or expired",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to check impersonation token",
+			})
+			return
+		}
+
+		c.Set("impersonation", session)
+		c.Set("impersonator_id", session.AdminID)
+		c.Set("snappUser_id", session.SnappUserID)
+		c.Request = c.Request.WithContext(models.WithImpersonation(c.Request.Context(), session))
+		c.Header("X-Impersonation", fmt.Sprintf("%d", session.ID))
+
+		method := c.Request.Method
+		snappID := c.Param("snapp_id")
+		blocked := true
+		switch {
+		case method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions:
+			c.AbortWithStatusJSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.ImpersonationReadOnly,
+				Message: "Impersonation is read-only",
+			})
+		case snappID != "" && snappID != session.SnappID:
+			c.AbortWithStatusJSON(http.StatusForbidden, serializers.Base{
+				Code:    serializers.Forbidden,
+				Message: "Impersonation is limited to " + session.SnappID,
+			})
+		default:
+			blocked = false
+			c.Next()
+		}
+
+		entry := &models.ImpersonationAuditEntry{
+			SessionID:   session.ID,
+			AdminID:     session.AdminID,
+			SnappUserID: session.SnappUserID,
+			Method:      method,
+			Path:        c.Request.URL.RequestURI(),
+			Route:       c.FullPath(),
+			Status:      c.Writer.Status(),
+			Blocked:     blocked,
+		}
+		log.Printf("[impersonation %d] admin %d as %s: %s %s -> %d (blocked: %t)",
+			session.ID, session.AdminID, session.SnappID, entry.Method, entry.Path, entry.Status, blocked)
+		// Recorded even when the request's context timed out
+		entry.Create(context.Background())
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LegacyVotingReadOnly refuses the votes of the legacy voting routes once the
+// legacy votings are sunset, answering 410 with the campaigns to vote in
+// instead. Reading the votings and their results keeps working.
+func LegacyVotingReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || !services.IsLegacyVotingSunset() {
+			c.Next()
+			return
+		}
+
+		campaigns := make([]serializers.MigratedCampaign, 0)
+		// The featured campaigns are a hint, the answer doesn't depend on them
+		featured, err := models.GetFeaturedCampaigns(c.Request.Context(), time.Now())
+		if err == nil {
+			for _, campaign := range featured {
+				campaigns = append(campaigns, serializers.MigratedCampaign{
+					ID:    campaign.ID,
+					Title: campaign.Title,
+					URL:   fmt.Sprintf("/v1/campaigns/%d", campaign.ID),
+				})
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusGone, serializers.LegacyVotingMigratedResponse{
+			Base: serializers.Base{
+				Code:    serializers.LegacyVotingMigrated,
+				Message: "Legacy voting has moved to campaigns",
+			},
+			CampaignsURL: "/v1/campaigns/featured",
+			Campaigns:    campaigns,
+			ArchiveURL:   "/v1/legacy/archive",
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit allows each client IP limit requests per window on every route
+// it guards, answering 429 past that. Counts are kept in memory, so every
+// instance of the server limits on its own.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := newFixedWindowLimiter(limit, window)
+
+	return func(c *gin.Context) {
+		if limiter.reject(c, c.ClientIP()+" "+c.FullPath()) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitEmail allows limit requests per window for each email address
+// named in the JSON body, whichever IP they come from, so one account can't
+// be flooded with emails through many addresses. Requests without an email
+// are left to the handler.
+func RateLimitEmail(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := newFixedWindowLimiter(limit, window)
+
+	return func(c *gin.Context) {
+		// The body is put back for the handler to bind
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		var request struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(body, &request) == nil {
+			email := strings.ToLower(strings.TrimSpace(request.Email))
+			if email != "" && limiter.reject(c, email+" "+c.FullPath()) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+type fixedWindowLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+func newFixedWindowLimiter(limit int, window time.Duration) *fixedWindowLimiter {
+	return &fixedWindowLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// reject answers 429 when the request is over the key's limit
+func (l *fixedWindowLimiter) reject(c *gin.Context, key string) bool {
+	retryAfter, allowed := l.allow(key, time.Now())
+	if allowed {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.5)))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, serializers.Base{
+		Code:    serializers.TooManyRequests,
+		Message: "Too many requests, try again later",
+	})
+	return true
+}
+
+// allow counts the request against the key's window, returning how long
+// until the window resets when the request is over the limit
+func (l *fixedWindowLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop the windows of clients gone quiet so the map doesn't grow
+	// without bound
+	if now.Sub(l.lastSweep) > l.window {
+		for windowKey, window := range l.windows {
+			if now.Sub(window.start) >= l.window {
+				delete(l.windows, windowKey)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	window, exists := l.windows[key]
+	if !exists || now.Sub(window.start) >= l.window {
+		window = &rateWindow{start: now}
+		l.windows[key] = window
+	}
+	if window.count >= l.limit {
+		return window.start.Add(l.window).Sub(now), false
+	}
+	window.count++
+	return 0, true
+}
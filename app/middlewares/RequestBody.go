@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestBody rejects request bodies larger than maxBytes with 413 and
+// bodies that are not JSON with 415. Requests without a body pass through.
+// The body is buffered, so chunked uploads are measured as well.
+//
+// Requests to the upload routes, given as route paths like
+// "/v1/reviews/:snapp_id/photos", are left to their handlers, which limit
+// the size themselves.
+func RequestBody(maxBytes int64, uploadRoutes ...string) gin.HandlerFunc {
+	uploads := make(map[string]bool, len(uploadRoutes))
+	for _, route := range uploadRoutes {
+		uploads[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody || uploads[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			abortPayloadTooLarge(c, maxBytes)
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxBytes+1))
+		c.Request.Body.Close()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, serializers.Base{
+				Code:    serializers.InvalidInput,
+				Message: "Failed to read request body",
+			})
+			return
+		}
+		if int64(len(body)) > maxBytes {
+			abortPayloadTooLarge(c, maxBytes)
+			return
+		}
+
+		if len(body) > 0 && !isJSONContentType(c.GetHeader("Content-Type")) {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, serializers.Base{
+				Code:    serializers.UnsupportedMediaType,
+				Message: "Request body must be application/json",
+			})
+			return
+		}
+
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Next()
+	}
+}
+
+func abortPayloadTooLarge(c *gin.Context, maxBytes int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, serializers.Base{
+		Code:    serializers.PayloadTooLarge,
+		Message: fmt.Sprintf("Request body must be at most %d bytes", maxBytes),
+	})
+}
+
+// isJSONContentType accepts application/json and structured +json types
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
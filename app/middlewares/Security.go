@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"voting-app/app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers browsers let cross-origin
+// scripts read
+const corsExposedHeaders = "API-Version, Retry-After, ETag, Last-Modified, X-Experiment-Variant"
+
+// CORS lets browsers call the API from the configured origins. Preflight
+// requests are answered here; requests from other origins are served
+// without CORS headers, so browsers keep their responses from scripts.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		if len(origins) == 0 {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" || (!allowAll && !origins[strings.ToLower(origin)]) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+		c.Next()
+	}
+}
+
+// SecurityHeaders sets the standard security headers on every response.
+// The API only serves data, so responses may not be framed or sniffed.
+func SecurityHeaders(cfg config.SecurityConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+	frameAncestors := "'none'"
+	if cfg.FrameOptions == "SAMEORIGIN" {
+		frameAncestors = "'self'"
+	}
+	csp := "default-src 'none'; frame-ancestors " + frameAncestors
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", cfg.FrameOptions)
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("Content-Security-Policy", csp)
+		c.Next()
+	}
+}
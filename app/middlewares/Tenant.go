@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader selects the tenant by its slug, overriding the host
+const TenantHeader = "X-Tenant"
+
+// Tenant resolves the tenant the request is for and scopes the request
+// context to it, so the models' queries only see the tenant's venues,
+// campaigns and users. Unknown X-Tenant slugs are answered with 404.
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantService := &services.TenantService{}
+		tenant, err := tenantService.Resolve(c.Request.Context(), c.Request.Host, c.GetHeader(TenantHeader))
+		if err == services.ErrUnknownTenant {
+			c.AbortWithStatusJSON(http.StatusNotFound, serializers.Base{
+				Code:    serializers.NotFound,
+				Message: "Unknown tenant",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, serializers.Base{
+				Code:    serializers.InternalError,
+				Message: "Failed to resolve tenant",
+			})
+			return
+		}
+
+		c.Set("tenant_id", tenant.ID)
+		c.Set("tenant", tenant)
+		c.Request = c.Request.WithContext(models.WithTenant(c.Request.Context(), tenant.ID))
+		c.Next()
+	}
+}
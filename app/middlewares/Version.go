@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"voting-app/app/serializers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionHeader tells clients which API version answered
+const APIVersionHeader = "API-Version"
+
+// APIVersion marks the requests of a version's route group, exposing the
+// version to handlers as "api_version"
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Header(APIVersionHeader, version)
+		c.Next()
+	}
+}
+
+// Envelope wraps the JSON responses of the v1 handlers in the v2 envelope
+// {data, meta, errors}. Other responses, like file downloads, are sent as
+// they are.
+func Envelope(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &envelopeWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		writer.finish(version)
+	}
+}
+
+// envelopeWriter buffers JSON bodies until the handler is done
+type envelopeWriter struct {
+	gin.ResponseWriter
+	status int
+
+	decided   bool // Whether the body is JSON is known once it's written
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (w *envelopeWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = envelopedStatus(w.status) && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *envelopeWriter) WriteHeaderNow() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *envelopeWriter) Status() int {
+	if w.decided && !w.buffering {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *envelopeWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *envelopeWriter) Written() bool {
+	return w.decided || w.ResponseWriter.Written()
+}
+
+// Flush only reaches the client once the body isn't buffered
+func (w *envelopeWriter) Flush() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish writes the enveloped body, or the status of responses that had
+// none
+func (w *envelopeWriter) finish(version string) {
+	if !w.decided {
+		if !envelopedStatus(w.status) {
+			w.ResponseWriter.WriteHeader(w.status)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		// Handlers that wrote no body still answer with an envelope
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.buffering = true
+	}
+	if !w.buffering {
+		return
+	}
+
+	body, err := json.Marshal(serializers.NewEnvelope(version, w.status, w.body.Bytes()))
+	if err != nil {
+		// Not valid JSON after all, sent as it was
+		body = w.body.Bytes()
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// envelopedStatus reports whether responses with the status are enveloped.
+// Redirects and responses that can't have a body are sent as they are.
+func envelopedStatus(status int) bool {
+	switch {
+	case status < http.StatusOK:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	case status >= http.StatusMultipleChoices && status < http.StatusBadRequest:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// MaxAccountLinkAttempts caps the codes tried against the link requests
+	// of a user for a snapp user within AccountLinkAttemptWindow
+	MaxAccountLinkAttempts = 5
+	// AccountLinkAttemptWindow is how long wrong codes count against new
+	// requests for the same snapp user
+	AccountLinkAttemptWindow = time.Hour
+	// AccountLinkResendInterval is the least time between two codes sent to
+	// a snapp user, whoever asks for them
+	AccountLinkResendInterval = time.Minute
+)
+
+var (
+	// ErrAccountAlreadyLinked is returned when the user or the snapp user
+	// is already linked to another account
+	ErrAccountAlreadyLinked = errors.New("account is already linked")
+	// ErrInvalidLinkCode is returned when the verification code is wrong,
+	// expired or was tried too often
+	ErrInvalidLinkCode = errors.New("verification code is invalid or expired")
+	// ErrLinkCodeResendTooSoon is returned when the snapp user was sent a
+	// code less than AccountLinkResendInterval ago
+	ErrLinkCodeResendTooSoon = errors.New("verification code was sent too recently")
+)
+
+// Identity is a user of either identity system with the account it is
+// linked to. JWT users have a UserID, snapp users a SnappUserID, linked
+// accounts both.
+type Identity struct {
+	UserID      int64      `json:"userId,omitempty"`
+	SnappUserID int64      `json:"snappUserId,omitempty"`
+	SnappId     string     `json:"snappId,omitempty"`
+	LinkedAt    *time.Time `json:"linkedAt,omitempty"`
+}
+
+// IsLinked tells whether the identity joins a JWT user and a snapp user
+func (i *Identity) IsLinked() bool {
+	return i.UserID != 0 && i.SnappUserID != 0
+}
+
+// AccountLinkRequest is a pending link of a JWT user to a snapp user,
+// confirmed with the code sent to the snapp user
+type AccountLinkRequest struct {
+	UserID      int64
+	SnappUserID int64
+	CodeHash    string
+	ExpiresAt   time.Time
+}
+
+func (r *AccountLinkRequest) TableName() string {
+	return "account_link_requests"
+}
+
+// Create stores the request, replacing an earlier one of the user. The wrong
+// codes of a request for the same snapp user within AccountLinkAttemptWindow
+// still count, so asking for new codes doesn't grant new attempts. Returns
+// ErrLinkCodeResendTooSoon when any user asked for a code for the snapp user
+// less than AccountLinkResendInterval ago.
+func (r *AccountLinkRequest) Create(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO account_link_requests (user_id, snapp_user_id, code_hash, expires_at)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM account_link_requests
+			WHERE snapp_user_id = $2 AND created_at > CURRENT_TIMESTAMP - $5 * INTERVAL '1 second'
+		)
+		ON CONFLICT (user_id) DO UPDATE
+		SET snapp_user_id = EXCLUDED.snapp_user_id, code_hash = EXCLUDED.code_hash,
+			expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP,
+			attempts = CASE
+				WHEN account_link_requests.snapp_user_id = EXCLUDED.snapp_user_id
+					AND account_link_requests.created_at > CURRENT_TIMESTAMP - $6 * INTERVAL '1 second'
+				THEN account_link_requests.attempts
+				ELSE 0
+			END`,
+		r.UserID, r.SnappUserID, r.CodeHash, r.ExpiresAt,
+		AccountLinkResendInterval.Seconds(), AccountLinkAttemptWindow.Seconds())
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrLinkCodeResendTooSoon
+	}
+	return nil
+}
+
+// ConfirmAccountLink links the user to the snapp user when the code matches
+// their pending request. Every wrong code counts as an attempt.
+func ConfirmAccountLink(ctx context.Context, userID, snappUserID int64, codeHash string, now time.Time) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var storedHash string
+	var attempts int
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT code_hash, attempts, expires_at
+		FROM account_link_requests
+		WHERE user_id = $1 AND snapp_user_id = $2
+		FOR UPDATE`,
+		userID, snappUserID).Scan(&storedHash, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidLinkCode
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if attempts >= MaxAccountLinkAttempts || !now.Before(expiresAt) {
+		return ErrInvalidLinkCode
+	}
+
+	if storedHash != codeHash {
+		_, err = tx.ExecContext(ctx, "UPDATE account_link_requests SET attempts = attempts + 1 WHERE user_id = $1", userID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		return ErrInvalidLinkCode
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO account_links (user_id, snapp_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		userID, snappUserID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if linked, _ := result.RowsAffected(); linked == 0 {
+		return ErrAccountAlreadyLinked
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM account_link_requests WHERE user_id = $1", userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteAccountLink unlinks the user, reporting whether they were linked
+func DeleteAccountLink(ctx context.Context, userID int64) (bool, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM account_links WHERE user_id = $1", userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// GetIdentityByUser returns the identity of the JWT user, with the snapp
+// user it is linked to if any
+func GetIdentityByUser(ctx context.Context, userID int64) (Identity, error) {
+	identity := Identity{UserID: userID}
+	var linkedAt time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT s.id, s.snapp_id, l.linked_at
+		FROM account_links l
+		INNER JOIN snapp_users s ON s.id = l.snapp_user_id
+		WHERE l.user_id = $1`,
+		userID).Scan(&identity.SnappUserID, &identity.SnappId, &linkedAt)
+	if err == sql.ErrNoRows {
+		return identity, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return identity, err
+	}
+	identity.LinkedAt = &linkedAt
+	return identity, nil
+}
+
+// GetIdentityBySnappUser returns the identity of the snapp user, with the
+// JWT user it is linked to if any
+func GetIdentityBySnappUser(ctx context.Context, snappUser *SnappUser) (Identity, error) {
+	identity := Identity{SnappUserID: snappUser.Id, SnappId: snappUser.SnappId}
+	var linkedAt time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT user_id, linked_at FROM account_links WHERE snapp_user_id = $1",
+		snappUser.Id).Scan(&identity.UserID, &linkedAt)
+	if err == sql.ErrNoRows {
+		return identity, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return identity, err
+	}
+	identity.LinkedAt = &linkedAt
+	return identity, nil
+}
+
+// GetAdminSnappUserIDs returns the snapp users linked to superusers, the
+// only way admins can receive notifications
+func GetAdminSnappUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT l.snapp_user_id
+		FROM account_links l
+		INNER JOIN users u ON u.id = l.user_id
+		WHERE u.is_superuser = true`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/getsentry/sentry-go"
@@ -11,14 +12,16 @@ type Banner struct {
 	Id    int64  `json:"id,omitempty"`
 	Image string `json:"image"`
 	Link  string `json:"link,omitempty"`
+	// CampaignID is set when the banner promotes a featured campaign
+	CampaignID int64 `json:"campaignId,omitempty"`
 }
 
 func (b *Banner) TableName() string {
 	return "banners"
 }
-func (b *Banner) GetBanner() *Banner {
+func (b *Banner) GetBanner(ctx context.Context) *Banner {
 	query := fmt.Sprintf("SELECT image,link FROM %s WHERE is_active = true order by id desc LIMIT 1", b.TableName())
-	row := databases.PostgresDB.QueryRow(query)
+	row := databases.PostgresDB.QueryRowContext(ctx, query)
 	if row.Err() != nil {
 		if row.Err() != sql.ErrNoRows {
 			sentry.CaptureException(row.Err())
@@ -0,0 +1,318 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Reasons a campaign vote is excluded from the tallies
+const (
+	ExclusionFraud          = "fraud"           // Bought or coerced votes
+	ExclusionDuplicateVoter = "duplicate_voter" // Several accounts or kiosk sessions of one person
+	ExclusionAutomated      = "automated"       // Cast by a script
+)
+
+// VoteExclusionReasons are all the reasons a vote is excluded for
+var VoteExclusionReasons = []string{ExclusionFraud, ExclusionDuplicateVoter, ExclusionAutomated}
+
+// ErrCampaignFinalized is returned when changing the votes of a campaign
+// whose results are certified
+var ErrCampaignFinalized = errors.New("campaign results are finalized")
+
+// IsVoteExclusionReason tells whether votes are excluded for the reason
+func IsVoteExclusionReason(reason string) bool {
+	for _, r := range VoteExclusionReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludedCampaignVote is a vote left out of the campaign tallies
+type ExcludedCampaignVote struct {
+	VoteID     int64     `json:"voteId"`
+	CampaignID int64     `json:"campaignId"`
+	Reason     string    `json:"reason"`
+	ExcludedBy int64     `json:"excludedBy"`
+	ExcludedAt time.Time `json:"excludedAt"`
+}
+
+// Exclude leaves the vote out of the campaign tallies and its vote counter.
+// Excluding a vote again only changes the reason. sql.ErrNoRows when the
+// campaign has no such vote, ErrCampaignFinalized once its results are
+// certified.
+func (e *ExcludedCampaignVote) Exclude(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the campaign keeps the exclusion from racing its finalization
+	var finalizedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT results_finalized_at FROM voting_campaigns WHERE id = $1 FOR UPDATE",
+		e.CampaignID).Scan(&finalizedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	if finalizedAt.Valid {
+		return ErrCampaignFinalized
+	}
+
+	var voteCount int
+	var wasExcluded bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT vote_count, excluded_at IS NOT NULL
+		FROM campaign_votes
+		WHERE id = $1 AND campaign_id = $2
+		FOR UPDATE`,
+		e.VoteID, e.CampaignID).Scan(&voteCount, &wasExcluded)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE campaign_votes
+		SET excluded_reason = $2, excluded_by = $3, excluded_at = COALESCE(excluded_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+		RETURNING excluded_at`,
+		e.VoteID, e.Reason, e.ExcludedBy).Scan(&e.ExcludedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if !wasExcluded {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE voting_campaigns
+			SET total_votes = GREATEST(total_votes - $2, 0), updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`, e.CampaignID, voteCount)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// CampaignAuditExclusions counts the votes left out of the tallies per reason
+type CampaignAuditExclusions struct {
+	Votes    int            `json:"votes"` // Effective votes
+	Ballots  int            `json:"ballots"`
+	ByReason map[string]int `json:"byReason"` // Effective votes per reason
+}
+
+// CampaignAuditVoteSet identifies the counted votes. The hash is the
+// SHA-256 of one line per vote, ordered by ID:
+// "<id>|<category id or 0>|<venue id>|<votes>|<created at RFC 3339>\n".
+type CampaignAuditVoteSet struct {
+	Algorithm string `json:"algorithm"`
+	Ballots   int    `json:"ballots"`
+	Hash      string `json:"hash"`
+}
+
+// CampaignAuditCategory holds the certified tallies of a campaign category
+type CampaignAuditCategory struct {
+	CategoryID    *int64          `json:"categoryId,omitempty"`
+	Name          string          `json:"name"`
+	TotalVotes    int             `json:"totalVotes"`
+	WinnerVenueID *int64          `json:"winnerVenueId,omitempty"`
+	Tallies       []VenueStanding `json:"tallies"`
+}
+
+// CampaignAudit is the certificate of the final results of a campaign
+type CampaignAudit struct {
+	Version     int                     `json:"version"`
+	CampaignID  int64                   `json:"campaignId"`
+	Title       string                  `json:"title"`
+	VotingMode  string                  `json:"votingMode"`
+	StartDate   time.Time               `json:"startDate"`
+	EndDate     time.Time               `json:"endDate"`
+	TotalVotes  int                     `json:"totalVotes"`
+	Voters      int                     `json:"voters"`
+	Categories  []CampaignAuditCategory `json:"categories"`
+	Excluded    CampaignAuditExclusions `json:"excluded"`
+	VoteSet     CampaignAuditVoteSet    `json:"voteSet"`
+	CertifiedAt time.Time               `json:"certifiedAt"`
+}
+
+// CampaignAuditFile is the signed certificate as it is stored and served.
+// The signature covers the exact bytes of Certificate.
+type CampaignAuditFile struct {
+	Certificate        json.RawMessage `json:"certificate"`
+	SignatureAlgorithm string          `json:"signatureAlgorithm"`
+	Signature          string          `json:"signature"`
+}
+
+// NewCampaignAudit certifies the final results of the campaign, counting
+// its excluded votes and hashing the counted ones
+func NewCampaignAudit(ctx context.Context, campaign *VotingCampaign, results *CampaignResults, now time.Time) (*CampaignAudit, error) {
+	audit := &CampaignAudit{
+		Version:     1,
+		CampaignID:  campaign.ID,
+		Title:       campaign.Title,
+		VotingMode:  campaign.VotingMode,
+		StartDate:   campaign.StartDate.UTC(),
+		EndDate:     campaign.EndDate.UTC(),
+		TotalVotes:  results.TotalVotes,
+		Voters:      results.Voters,
+		Categories:  make([]CampaignAuditCategory, len(results.Categories)),
+		CertifiedAt: now.UTC(),
+	}
+	for i, category := range results.Categories {
+		audit.Categories[i] = CampaignAuditCategory{
+			CategoryID:    category.CategoryID,
+			Name:          category.Name,
+			TotalVotes:    category.TotalVotes,
+			WinnerVenueID: category.WinnerVenueID,
+			Tallies:       category.Standings,
+		}
+	}
+
+	excluded, err := getCampaignExclusions(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	audit.Excluded = *excluded
+
+	voteSet, err := hashCampaignVoteSet(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	audit.VoteSet = *voteSet
+
+	return audit, nil
+}
+
+// getCampaignExclusions counts the excluded votes of the campaign
+func getCampaignExclusions(ctx context.Context, campaignID int64) (*CampaignAuditExclusions, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT excluded_reason, COUNT(*), COALESCE(SUM(vote_count), 0)
+		FROM campaign_votes
+		WHERE campaign_id = $1 AND excluded_at IS NOT NULL
+		GROUP BY excluded_reason`,
+		campaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	excluded := &CampaignAuditExclusions{ByReason: make(map[string]int, len(VoteExclusionReasons))}
+	for _, reason := range VoteExclusionReasons {
+		excluded.ByReason[reason] = 0
+	}
+	for rows.Next() {
+		var reason string
+		var ballots, votes int
+		if err := rows.Scan(&reason, &ballots, &votes); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		excluded.ByReason[reason] = votes
+		excluded.Votes += votes
+		excluded.Ballots += ballots
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return excluded, nil
+}
+
+// hashCampaignVoteSet hashes the counted votes of the campaign as described
+// by CampaignAuditVoteSet
+func hashCampaignVoteSet(ctx context.Context, campaignID int64) (*CampaignAuditVoteSet, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, COALESCE(campaign_category_id, 0), venue_id, vote_count, created_at
+		FROM campaign_votes
+		WHERE campaign_id = $1 AND excluded_at IS NULL
+		ORDER BY id`,
+		campaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hash := sha256.New()
+	voteSet := &CampaignAuditVoteSet{Algorithm: "sha256"}
+	for rows.Next() {
+		var id, categoryID, venueID int64
+		var votes int
+		var createdAt time.Time
+		if err := rows.Scan(&id, &categoryID, &venueID, &votes, &createdAt); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		fmt.Fprintf(hash, "%d|%d|%d|%d|%s\n", id, categoryID, venueID, votes, createdAt.UTC().Format(time.RFC3339Nano))
+		voteSet.Ballots++
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	voteSet.Hash = hex.EncodeToString(hash.Sum(nil))
+	return voteSet, nil
+}
+
+// StoreCampaignAudit stores the signed certificate of the campaign. A
+// campaign is certified once, the first stored file is returned.
+func StoreCampaignAudit(ctx context.Context, campaignID int64, file *CampaignAuditFile) (*CampaignAuditFile, error) {
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO campaign_audits (campaign_id, certificate, signature_algorithm, signature)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (campaign_id) DO NOTHING`,
+		campaignID, string(file.Certificate), file.SignatureAlgorithm, file.Signature)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return GetCampaignAudit(ctx, campaignID)
+}
+
+// GetCampaignAudit returns the signed certificate of the campaign,
+// sql.ErrNoRows when it isn't certified
+func GetCampaignAudit(ctx context.Context, campaignID int64) (*CampaignAuditFile, error) {
+	// The certificate is kept as text, JSONB would reorder its keys and
+	// break the signature
+	var certificate string
+	file := &CampaignAuditFile{}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT certificate, signature_algorithm, signature
+		FROM campaign_audits
+		WHERE campaign_id = $1`,
+		campaignID).Scan(&certificate, &file.SignatureAlgorithm, &file.Signature)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	file.Certificate = json.RawMessage(certificate)
+	return file, nil
+}
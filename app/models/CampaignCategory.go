@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrCampaignCategoryExists is returned when the campaign already has a
+// category with the same slug
+var ErrCampaignCategoryExists = errors.New("campaign already has this category")
+
+// CampaignCategory is an award category of a campaign, e.g. "Best Brunch"
+// under "Best of the City 2025". Votes of a campaign with categories are
+// scoped to one of them and every category has its own winner.
+type CampaignCategory struct {
+	ID          int64  `json:"id"`
+	CampaignID  int64  `json:"campaignId"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description,omitempty"`
+
+	// VenueCategoryID restricts the eligible venues when set
+	VenueCategoryID *int64 `json:"venueCategoryId,omitempty"`
+	SortOrder       int    `json:"sortOrder"`
+
+	WinnerVenueID *int64    `json:"winnerVenueId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func (c *CampaignCategory) TableName() string {
+	return "campaign_categories"
+}
+
+// Create creates a campaign category
+func (c *CampaignCategory) Create(ctx context.Context) error {
+	query := `
+		INSERT INTO campaign_categories (campaign_id, name, slug, description, venue_category_id, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (campaign_id, slug) DO NOTHING
+		RETURNING id, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, c.CampaignID, c.Name, c.Slug, c.Description, c.VenueCategoryID, c.SortOrder,
+	).Scan(&c.ID, &c.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrCampaignCategoryExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a category of the campaign
+func (c *CampaignCategory) GetByID(ctx context.Context) error {
+	query := `
+		SELECT id, campaign_id, name, slug, description, venue_category_id, sort_order,
+			   winner_venue_id, created_at
+		FROM campaign_categories
+		WHERE id = $1 AND campaign_id = $2`
+
+	var description sql.NullString
+	var venueCategoryID, winnerVenueID sql.NullInt64
+
+	err := databases.PostgresDB.QueryRowContext(ctx, query, c.ID, c.CampaignID).Scan(
+		&c.ID, &c.CampaignID, &c.Name, &c.Slug, &description, &venueCategoryID, &c.SortOrder,
+		&winnerVenueID, &c.CreatedAt,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	c.Description = description.String
+	if venueCategoryID.Valid {
+		c.VenueCategoryID = &venueCategoryID.Int64
+	}
+	if winnerVenueID.Valid {
+		c.WinnerVenueID = &winnerVenueID.Int64
+	}
+	return nil
+}
+
+// GetCampaignCategories returns the categories of a campaign in display order
+func GetCampaignCategories(ctx context.Context, campaignID int64) ([]CampaignCategory, error) {
+	query := `
+		SELECT id, campaign_id, name, slug, description, venue_category_id, sort_order,
+			   winner_venue_id, created_at
+		FROM campaign_categories
+		WHERE campaign_id = $1
+		ORDER BY sort_order, id`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]CampaignCategory, 0)
+	for rows.Next() {
+		var category CampaignCategory
+		var description sql.NullString
+		var venueCategoryID, winnerVenueID sql.NullInt64
+
+		err := rows.Scan(
+			&category.ID, &category.CampaignID, &category.Name, &category.Slug, &description,
+			&venueCategoryID, &category.SortOrder, &winnerVenueID, &category.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		category.Description = description.String
+		if venueCategoryID.Valid {
+			category.VenueCategoryID = &venueCategoryID.Int64
+		}
+		if winnerVenueID.Valid {
+			category.WinnerVenueID = &winnerVenueID.Int64
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
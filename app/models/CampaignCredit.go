@@ -0,0 +1,97 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Campaign voting modes
+const (
+	VotingModeStandard  = "standard"  // One vote per venue
+	VotingModeQuadratic = "quadratic" // Votes cost credits, n votes cost n²
+)
+
+// DefaultCreditBudget is the credit budget of quadratic campaigns without one
+const DefaultCreditBudget = 100
+
+// ErrInsufficientCredits is returned when a vote costs more credits than the
+// user has left in the campaign
+var ErrInsufficientCredits = errors.New("not enough credits left in the campaign")
+
+// CampaignCreditBalance is a user's credit budget in a quadratic campaign
+type CampaignCreditBalance struct {
+	CampaignID int64      `json:"campaignId"`
+	UserID     int64      `json:"userId"`
+	Budget     int        `json:"budget"`
+	Spent      int        `json:"spent"`
+	Remaining  int        `json:"remaining"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+// QuadraticCost returns the credits needed to cast votes for a single venue
+func QuadraticCost(votes int) int {
+	return votes * votes
+}
+
+// GetCampaignCreditBalance returns the user's credit balance in the campaign
+func GetCampaignCreditBalance(ctx context.Context, campaign *VotingCampaign, userID int64) (*CampaignCreditBalance, error) {
+	balance := &CampaignCreditBalance{
+		CampaignID: campaign.ID,
+		UserID:     userID,
+		Budget:     campaign.CreditBudget,
+	}
+
+	var updatedAt time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT credits_spent, updated_at FROM campaign_credit_balances
+		WHERE campaign_id = $1 AND user_id = $2`,
+		campaign.ID, userID,
+	).Scan(&balance.Spent, &updatedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	if err == nil {
+		balance.UpdatedAt = &updatedAt
+	}
+
+	balance.Remaining = balance.Budget - balance.Spent
+	if balance.Remaining < 0 {
+		balance.Remaining = 0
+	}
+	return balance, nil
+}
+
+// spendCampaignCredits debits the user's balance within the vote transaction.
+// The budget check is part of the upsert so concurrent votes cannot overspend.
+func spendCampaignCredits(ctx context.Context, tx *sql.Tx, campaignID, userID int64, credits, budget int) error {
+	if credits > budget {
+		return ErrInsufficientCredits
+	}
+
+	var spent int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO campaign_credit_balances (campaign_id, user_id, credits_spent)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (campaign_id, user_id) DO UPDATE
+		SET credits_spent = campaign_credit_balances.credits_spent + EXCLUDED.credits_spent,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_credit_balances.credits_spent + EXCLUDED.credits_spent <= $4
+		RETURNING credits_spent`,
+		campaignID, userID, credits, budget,
+	).Scan(&spent)
+
+	if err == sql.ErrNoRows {
+		return ErrInsufficientCredits
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
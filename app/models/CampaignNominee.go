@@ -0,0 +1,320 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrCampaignNomineeExists is returned when the venue is already nominated
+var ErrCampaignNomineeExists = errors.New("venue is already nominated")
+
+// CampaignNominee is a venue voters can choose from in a campaign. Campaigns
+// with nominees only take votes for them.
+type CampaignNominee struct {
+	CampaignID int64     `json:"campaignId"`
+	VenueID    int64     `json:"venueId"`
+	VenueName  string    `json:"venueName"`
+	VenueSlug  string    `json:"venueSlug"`
+	Score      *float64  `json:"score,omitempty"` // Candidate score, unset for venues nominated by hand
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (n *CampaignNominee) TableName() string {
+	return "campaign_nominees"
+}
+
+// NomineeCandidate is a venue that can be nominated, with the review and
+// profile view counts of the scoring window and the one before it
+type NomineeCandidate struct {
+	VenueID       int64
+	VenueName     string
+	VenueSlug     string
+	AverageRating float64
+	TotalReviews  int
+	RecentReviews int
+	PriorReviews  int
+	RecentViews   int
+	PriorViews    int
+}
+
+// GetNomineeCandidates returns the active venues of the city and category
+// with at least minReviews approved reviews. Recent counts are from since
+// on, prior ones from priorSince until since.
+func GetNomineeCandidates(ctx context.Context, cityID, categoryID int64, since, priorSince time.Time, minReviews int) ([]NomineeCandidate, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id, v.name, v.slug, r.average_rating, r.total,
+			   r.recent, r.prior, COALESCE(a.recent_views, 0), COALESCE(a.prior_views, 0)
+		FROM venues v
+		INNER JOIN (
+			SELECT venue_id, AVG(overall_rating) AS average_rating, COUNT(*) AS total,
+				   COUNT(*) FILTER (WHERE created_at >= $3) AS recent,
+				   COUNT(*) FILTER (WHERE created_at >= $4 AND created_at < $3) AS prior
+			FROM venue_reviews
+			WHERE moderation_status = 'approved'
+			GROUP BY venue_id
+		) r ON r.venue_id = v.id
+		LEFT JOIN (
+			SELECT venue_id,
+				   SUM(profile_views) FILTER (WHERE date >= $3::date) AS recent_views,
+				   SUM(profile_views) FILTER (WHERE date < $3::date) AS prior_views
+			FROM venue_analytics
+			WHERE date >= $4::date
+			GROUP BY venue_id
+		) a ON a.venue_id = v.id
+		WHERE v.is_active = true AND v.city_id = $1 AND v.category_id = $2 AND r.total >= $5
+		  AND ($6::bigint IS NULL OR v.tenant_id = $6)
+		ORDER BY v.id`,
+		cityID, categoryID, since, priorSince, minReviews, TenantFilter(ctx),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]NomineeCandidate, 0)
+	for rows.Next() {
+		var c NomineeCandidate
+		err := rows.Scan(&c.VenueID, &c.VenueName, &c.VenueSlug, &c.AverageRating, &c.TotalReviews,
+			&c.RecentReviews, &c.PriorReviews, &c.RecentViews, &c.PriorViews)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// CreateWithNominees creates the campaign together with its nominees
+func (c *VotingCampaign) CreateWithNominees(ctx context.Context, nominees []CampaignNominee) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if c.VotingMode == "" {
+		c.VotingMode = VotingModeStandard
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO voting_campaigns (
+			title, description, campaign_type, city_id, category_id, start_date, end_date,
+			max_votes_per_user, allow_multiple_categories, require_review, voting_mode,
+			is_active, is_featured, hide_results_until_end, require_otp, allow_kiosk_sessions, vote_change_minutes, tenant_id
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id, created_at, updated_at`,
+		c.Title, c.Description, c.CampaignType, c.CityID, c.CategoryID, c.StartDate, c.EndDate,
+		c.MaxVotesPerUser, c.AllowMultipleCategories, c.RequireReview, c.VotingMode,
+		c.IsActive, c.IsFeatured, c.HideResultsUntilEnd, c.RequireOTP, c.AllowKioskSessions, c.VoteChangeMinutes, ownerTenant(ctx),
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	for i := range nominees {
+		nominee := &nominees[i]
+		nominee.CampaignID = c.ID
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO campaign_nominees (campaign_id, venue_id, score)
+			VALUES ($1, $2, $3)
+			RETURNING created_at`,
+			c.ID, nominee.VenueID, nominee.Score,
+		).Scan(&nominee.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// Create nominates the venue by hand, returning ErrCampaignNomineeExists
+// when it already is
+func (n *CampaignNominee) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO campaign_nominees (campaign_id, venue_id)
+		VALUES ($1, $2)
+		ON CONFLICT (campaign_id, venue_id) DO NOTHING
+		RETURNING created_at`,
+		n.CampaignID, n.VenueID,
+	).Scan(&n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCampaignNomineeExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// NomineeCriteria selects the venues of a campaign's city and category that
+// are nominated automatically
+type NomineeCriteria struct {
+	MinReviews int     // Fewest approved reviews
+	MinRating  float64 // Lowest average rating of the approved reviews
+	Limit      int     // Most venues nominated, best rated first
+}
+
+// NominateEligibleVenues nominates the active venues of the city and
+// category matching the criteria, skipping those already nominated. A nil
+// city or category matches every one. Returns the new nominees.
+func NominateEligibleVenues(ctx context.Context, campaignID int64, cityID, categoryID *int64, criteria NomineeCriteria) ([]CampaignNominee, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		WITH eligible AS (
+			SELECT v.id
+			FROM venues v
+			INNER JOIN (
+				SELECT venue_id, AVG(overall_rating) AS average_rating, COUNT(*) AS total
+				FROM venue_reviews
+				WHERE moderation_status = 'approved'
+				GROUP BY venue_id
+			) r ON r.venue_id = v.id
+			WHERE v.is_active = true AND ($7::bigint IS NULL OR v.tenant_id = $7)
+			  AND ($2::bigint IS NULL OR v.city_id = $2)
+			  AND ($3::bigint IS NULL OR v.category_id = $3)
+			  AND r.total >= $4 AND r.average_rating >= $5
+			  AND NOT EXISTS (SELECT 1 FROM campaign_nominees n WHERE n.campaign_id = $1 AND n.venue_id = v.id)
+			ORDER BY r.average_rating DESC, r.total DESC, v.id
+			LIMIT $6
+		), inserted AS (
+			INSERT INTO campaign_nominees (campaign_id, venue_id)
+			SELECT $1, id FROM eligible
+			ON CONFLICT (campaign_id, venue_id) DO NOTHING
+			RETURNING campaign_id, venue_id, created_at
+		)
+		SELECT i.campaign_id, i.venue_id, v.name, v.slug, i.created_at
+		FROM inserted i
+		INNER JOIN venues v ON v.id = i.venue_id
+		ORDER BY i.venue_id`,
+		campaignID, cityID, categoryID, criteria.MinReviews, criteria.MinRating, criteria.Limit, TenantFilter(ctx),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	nominees := make([]CampaignNominee, 0)
+	for rows.Next() {
+		var nominee CampaignNominee
+		err := rows.Scan(&nominee.CampaignID, &nominee.VenueID, &nominee.VenueName, &nominee.VenueSlug, &nominee.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		nominees = append(nominees, nominee)
+	}
+	return nominees, rows.Err()
+}
+
+// Delete withdraws the nomination, returning sql.ErrNoRows when the venue
+// wasn't nominated
+func (n *CampaignNominee) Delete(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM campaign_nominees WHERE campaign_id = $1 AND venue_id = $2",
+		n.CampaignID, n.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCampaignNominees returns the nominees of the campaign, best scored
+// first and those nominated by hand last
+func GetCampaignNominees(ctx context.Context, campaignID int64) ([]CampaignNominee, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT n.campaign_id, n.venue_id, v.name, v.slug, n.score, n.created_at
+		FROM campaign_nominees n
+		INNER JOIN venues v ON v.id = n.venue_id
+		WHERE n.campaign_id = $1
+		ORDER BY n.score DESC NULLS LAST, n.created_at, n.venue_id`,
+		campaignID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	nominees := make([]CampaignNominee, 0)
+	for rows.Next() {
+		var nominee CampaignNominee
+		var score sql.NullFloat64
+		err := rows.Scan(&nominee.CampaignID, &nominee.VenueID, &nominee.VenueName, &nominee.VenueSlug,
+			&score, &nominee.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if score.Valid {
+			nominee.Score = &score.Float64
+		}
+		nominees = append(nominees, nominee)
+	}
+	return nominees, nil
+}
+
+// IsCampaignNominee reports whether the venue can be voted for in the
+// campaign: it is nominated, or the campaign has no nominees
+func IsCampaignNominee(ctx context.Context, campaignID, venueID int64) (bool, error) {
+	var eligible bool
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT NOT EXISTS (SELECT 1 FROM campaign_nominees WHERE campaign_id = $1)
+			OR EXISTS (SELECT 1 FROM campaign_nominees WHERE campaign_id = $1 AND venue_id = $2)`,
+		campaignID, venueID,
+	).Scan(&eligible)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return eligible, err
+}
+
+// Publish opens the campaign to voters
+func (c *VotingCampaign) Publish(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE voting_campaigns SET is_active = true, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`,
+		c.ID,
+	).Scan(&c.UpdatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	c.IsActive = true
+	return nil
+}
+
+// GetCityAndCategoryNames returns the names of the city and the venue
+// category, sql.ErrNoRows when either doesn't exist
+func GetCityAndCategoryNames(ctx context.Context, cityID, categoryID int64) (string, string, error) {
+	var cityName, categoryName string
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT c.name, vc.name FROM cities c, venue_categories vc
+		WHERE c.id = $1 AND vc.id = $2`,
+		cityID, categoryID,
+	).Scan(&cityName, &categoryName)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return cityName, categoryName, err
+}
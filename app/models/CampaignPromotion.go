@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// CampaignPromotion features a campaign for a date range. Operators schedule
+// promotions ahead of time, making up the featured content calendar.
+type CampaignPromotion struct {
+	ID          int64     `json:"id"`
+	CampaignID  int64     `json:"campaignId"`
+	StartsAt    time.Time `json:"startsAt"`
+	EndsAt      time.Time `json:"endsAt"`
+	Priority    int       `json:"priority"`              // Higher priorities are featured first
+	BannerImage string    `json:"bannerImage,omitempty"` // Fills the legacy vote banner while running
+	BannerLink  string    `json:"bannerLink,omitempty"`
+	CreatedBy   int64     `json:"createdBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (p *CampaignPromotion) TableName() string {
+	return "campaign_promotions"
+}
+
+// FeaturedCampaign is a campaign with the promotion featuring it
+type FeaturedCampaign struct {
+	VotingCampaign
+	Promotion CampaignPromotion `json:"promotion"`
+}
+
+const campaignPromotionColumns = `
+	p.id, p.campaign_id, p.starts_at, p.ends_at, p.priority,
+	COALESCE(p.banner_image, ''), COALESCE(p.banner_link, ''), COALESCE(p.created_by, 0), p.created_at`
+
+func (p *CampaignPromotion) fields() []interface{} {
+	return []interface{}{
+		&p.ID, &p.CampaignID, &p.StartsAt, &p.EndsAt, &p.Priority,
+		&p.BannerImage, &p.BannerLink, &p.CreatedBy, &p.CreatedAt,
+	}
+}
+
+// Create schedules the promotion, returning sql.ErrNoRows when the campaign
+// doesn't exist
+func (p *CampaignPromotion) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO campaign_promotions
+			(campaign_id, starts_at, ends_at, priority, banner_image, banner_link, created_by)
+		SELECT id, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, 0)
+		FROM voting_campaigns WHERE id = $1
+		RETURNING id, created_at`,
+		p.CampaignID, p.StartsAt, p.EndsAt, p.Priority, p.BannerImage, p.BannerLink, p.CreatedBy,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the promotion from the calendar, returning sql.ErrNoRows
+// when it doesn't exist
+func (p *CampaignPromotion) Delete(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM campaign_promotions WHERE id = $1", p.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Banner returns the legacy vote banner of the promotion, nil when it has
+// no banner image
+func (p *CampaignPromotion) Banner() *Banner {
+	if p.BannerImage == "" {
+		return nil
+	}
+	return &Banner{Image: p.BannerImage, Link: p.BannerLink, CampaignID: p.CampaignID}
+}
+
+// GetCampaignPromotions returns the promotions running at any time between
+// from and to, in calendar order
+func GetCampaignPromotions(ctx context.Context, from, to time.Time) ([]CampaignPromotion, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+campaignPromotionColumns+`
+		FROM campaign_promotions p
+		WHERE p.starts_at < $2 AND p.ends_at > $1
+		ORDER BY p.starts_at, p.priority DESC, p.id`,
+		from, to)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []CampaignPromotion{}
+	for rows.Next() {
+		var promotion CampaignPromotion
+		if err := rows.Scan(promotion.fields()...); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, rows.Err()
+}
+
+// GetFeaturedCampaigns returns the active campaigns promoted at the given
+// time, highest priority first. A campaign with overlapping promotions is
+// listed once, with its highest priority promotion.
+func GetFeaturedCampaigns(ctx context.Context, at time.Time) ([]FeaturedCampaign, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+votingCampaignColumns+`, `+campaignPromotionColumns+`
+		FROM campaign_promotions p
+		INNER JOIN voting_campaigns c ON c.id = p.campaign_id
+		WHERE c.is_active = true AND ($2::bigint IS NULL OR c.tenant_id = $2) AND p.id = (
+			SELECT running.id FROM campaign_promotions running
+			WHERE running.campaign_id = c.id AND running.starts_at <= $1 AND running.ends_at > $1
+			ORDER BY running.priority DESC, running.starts_at DESC, running.id DESC
+			LIMIT 1
+		)
+		ORDER BY p.priority DESC, p.starts_at DESC, c.id`,
+		at, TenantFilter(ctx))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	featured := []FeaturedCampaign{}
+	for rows.Next() {
+		var campaign FeaturedCampaign
+		if err := campaign.scan(rows, campaign.Promotion.fields()...); err != nil {
+			return nil, err
+		}
+		featured = append(featured, campaign)
+	}
+	return featured, rows.Err()
+}
@@ -0,0 +1,329 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// VenueStanding is the tally of a venue in a campaign category
+type VenueStanding struct {
+	Rank              int     `json:"rank"` // Tied venues share a rank
+	VenueID           int64   `json:"venueId"`
+	VenueName         string  `json:"venueName"`
+	Votes             int     `json:"votes"`
+	AverageConfidence float64 `json:"averageConfidence,omitempty"`
+}
+
+// CategoryResult holds the standings of one campaign category. Campaigns
+// without categories have a single result with a nil CategoryID.
+type CategoryResult struct {
+	CategoryID    *int64          `json:"categoryId,omitempty"`
+	Name          string          `json:"name"`
+	Slug          string          `json:"slug,omitempty"`
+	TotalVotes    int             `json:"totalVotes"`
+	WinnerVenueID *int64          `json:"winnerVenueId,omitempty"`
+	Standings     []VenueStanding `json:"standings"`
+	// Highlights quote the approved vote reasons moderators picked
+	Highlights []ReasonHighlight `json:"highlights,omitempty"`
+}
+
+// CampaignResults are the per category standings of a campaign
+type CampaignResults struct {
+	CampaignID  int64            `json:"campaignId"`
+	Title       string           `json:"title"`
+	VotingMode  string           `json:"votingMode"`
+	TotalVotes  int              `json:"totalVotes"`
+	Voters      int              `json:"voters"`
+	IsFinal     bool             `json:"isFinal"`
+	Categories  []CategoryResult `json:"categories"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+
+	// Set when the standings are embargoed until the campaign ends
+	ResultsHidden      bool       `json:"resultsHidden,omitempty"`
+	ResultsAvailableAt *time.Time `json:"resultsAvailableAt,omitempty"`
+}
+
+// CampaignResultSnapshot is a stored copy of the results at a point in time
+type CampaignResultSnapshot struct {
+	ID         int64           `json:"id"`
+	CampaignID int64           `json:"campaignId"`
+	IsFinal    bool            `json:"isFinal"`
+	TotalVotes int             `json:"totalVotes"`
+	Results    json.RawMessage `json:"results"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func (s *CampaignResultSnapshot) TableName() string {
+	return "campaign_result_snapshots"
+}
+
+// GetCampaignResults tallies the votes of a campaign per category, summing
+// the effective votes of quadratic campaigns. Excluded votes aren't
+// counted. The winner of a category has the most votes, ties are broken by
+// the average confidence of the voters and then by the lowest venue ID.
+func GetCampaignResults(ctx context.Context, campaign *VotingCampaign) (*CampaignResults, error) {
+	categories, err := GetCampaignCategories(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT cv.campaign_category_id, cv.venue_id, COALESCE(v.name, ''),
+			   SUM(cv.vote_count) AS votes, COALESCE(AVG(cv.confidence_score), 0) AS average_confidence
+		FROM campaign_votes cv
+		LEFT JOIN venues v ON v.id = cv.venue_id
+		WHERE cv.campaign_id = $1 AND cv.excluded_at IS NULL
+		GROUP BY cv.campaign_category_id, cv.venue_id, v.name
+		ORDER BY votes DESC, average_confidence DESC, cv.venue_id`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, campaign.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Standings keyed by category, 0 for votes outside any category
+	standings := make(map[int64][]VenueStanding)
+	for rows.Next() {
+		var categoryID sql.NullInt64
+		var standing VenueStanding
+		err := rows.Scan(&categoryID, &standing.VenueID, &standing.VenueName, &standing.Votes, &standing.AverageConfidence)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		standings[categoryID.Int64] = append(standings[categoryID.Int64], standing)
+	}
+
+	results := &CampaignResults{
+		CampaignID:  campaign.ID,
+		Title:       campaign.Title,
+		VotingMode:  campaign.VotingMode,
+		Categories:  make([]CategoryResult, 0, len(categories)+1),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	if len(categories) == 0 || len(standings[0]) > 0 {
+		results.Categories = append(results.Categories, newCategoryResult(nil, campaign.Title, "", standings[0]))
+	}
+	for _, category := range categories {
+		categoryID := category.ID
+		results.Categories = append(results.Categories,
+			newCategoryResult(&categoryID, category.Name, category.Slug, standings[category.ID]))
+	}
+
+	highlights, err := getReasonHighlights(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i, category := range results.Categories {
+		if category.CategoryID != nil {
+			results.Categories[i].Highlights = highlights[*category.CategoryID]
+		} else {
+			results.Categories[i].Highlights = highlights[0]
+		}
+		results.TotalVotes += category.TotalVotes
+	}
+
+	// Session votes without a user count once per session
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT COALESCE('u' || cv.user_id, 's' || cv.voting_session_id))
+		FROM campaign_votes cv
+		WHERE cv.campaign_id = $1 AND cv.excluded_at IS NULL`,
+		campaign.ID,
+	).Scan(&results.Voters)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// HideStandings strips the standings, winners and highlights, leaving the
+// participation totals, for results embargoed until availableAt
+func (r *CampaignResults) HideStandings(availableAt time.Time) {
+	for i := range r.Categories {
+		r.Categories[i].WinnerVenueID = nil
+		r.Categories[i].Standings = []VenueStanding{}
+		r.Categories[i].Highlights = nil
+	}
+	r.ResultsHidden = true
+	r.ResultsAvailableAt = &availableAt
+}
+
+func newCategoryResult(categoryID *int64, name, slug string, standings []VenueStanding) CategoryResult {
+	result := CategoryResult{
+		CategoryID: categoryID,
+		Name:       name,
+		Slug:       slug,
+		Standings:  make([]VenueStanding, len(standings)),
+	}
+
+	for i, standing := range standings {
+		standing.Rank = i + 1
+		if i > 0 && standing.Votes == standings[i-1].Votes {
+			standing.Rank = result.Standings[i-1].Rank
+		}
+		result.Standings[i] = standing
+		result.TotalVotes += standing.Votes
+	}
+
+	if len(standings) > 0 {
+		result.WinnerVenueID = &standings[0].VenueID
+	}
+	return result
+}
+
+// Create stores a snapshot of the results
+func (s *CampaignResultSnapshot) Create(ctx context.Context, results *CampaignResults) error {
+	return createResultSnapshot(ctx, databases.PostgresDB, s, results)
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func createResultSnapshot(ctx context.Context, db queryRower, s *CampaignResultSnapshot, results *CampaignResults) error {
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	s.CampaignID = results.CampaignID
+	s.IsFinal = results.IsFinal
+	s.TotalVotes = results.TotalVotes
+	s.Results = resultsJSON
+
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO campaign_result_snapshots (campaign_id, is_final, total_votes, results)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		s.CampaignID, s.IsFinal, s.TotalVotes, s.Results,
+	).Scan(&s.ID, &s.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// FinalizeCampaignResults stores the winners of a closed campaign and its
+// final snapshot. Finalized campaigns are no longer snapshotted.
+func FinalizeCampaignResults(ctx context.Context, results *CampaignResults) (*CampaignResultSnapshot, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, category := range results.Categories {
+		if category.CategoryID == nil {
+			_, err = tx.ExecContext(ctx,
+				"UPDATE voting_campaigns SET winner_venue_id = $1 WHERE id = $2",
+				category.WinnerVenueID, results.CampaignID,
+			)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				"UPDATE campaign_categories SET winner_venue_id = $1 WHERE id = $2",
+				category.WinnerVenueID, *category.CategoryID,
+			)
+		}
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE voting_campaigns
+		SET results_finalized_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, results.CampaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	results.IsFinal = true
+	snapshot := &CampaignResultSnapshot{}
+	if err := createResultSnapshot(ctx, tx, snapshot, results); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetCampaignSnapshots returns the newest result snapshots of a campaign
+func GetCampaignSnapshots(ctx context.Context, campaignID int64, limit int) ([]CampaignResultSnapshot, error) {
+	query := `
+		SELECT id, campaign_id, is_final, total_votes, results, created_at
+		FROM campaign_result_snapshots
+		WHERE campaign_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, campaignID, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]CampaignResultSnapshot, 0)
+	for rows.Next() {
+		var snapshot CampaignResultSnapshot
+		var results []byte
+		err := rows.Scan(&snapshot.ID, &snapshot.CampaignID, &snapshot.IsFinal, &snapshot.TotalVotes, &results, &snapshot.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		snapshot.Results = results
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// GetCampaignsPendingResults returns the started campaigns whose results are
+// not finalized yet
+func GetCampaignsPendingResults(ctx context.Context, now time.Time) ([]VotingCampaign, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id FROM voting_campaigns
+		WHERE is_active = true AND start_date <= $1 AND results_finalized_at IS NULL
+		ORDER BY id`, now)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	var campaignIDs []int64
+	for rows.Next() {
+		var campaignID int64
+		if err := rows.Scan(&campaignID); err == nil {
+			campaignIDs = append(campaignIDs, campaignID)
+		}
+	}
+	rows.Close()
+
+	campaigns := make([]VotingCampaign, 0, len(campaignIDs))
+	for _, campaignID := range campaignIDs {
+		campaign := VotingCampaign{ID: campaignID}
+		if err := campaign.GetByID(ctx); err != nil {
+			continue
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, nil
+}
@@ -0,0 +1,384 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrCampaignVoteExists is returned when the user already voted for the venue
+// in the campaign category
+var ErrCampaignVoteExists = errors.New("user already voted for this venue in the campaign")
+
+// Errors of votes over the campaign's limits
+var (
+	ErrVoteLimitReached   = errors.New("voter has used all their votes")
+	ErrOtherCategoryVoted = errors.New("voter already voted in another category")
+)
+
+// CampaignVote is a user's vote for a venue in a voting campaign
+type CampaignVote struct {
+	ID              int64     `json:"id"`
+	CampaignID      int64     `json:"campaignId"`
+	CategoryID      *int64    `json:"categoryId,omitempty"` // Campaign category the vote is scoped to
+	VenueID         int64     `json:"venueId"`
+	UserID          int64     `json:"userId,omitempty"` // 0 for votes of anonymous voting sessions
+	VotingSessionID *int64    `json:"-"`                // Kiosk voting session that cast the vote
+	Reason          string    `json:"reason,omitempty"`
+	ReasonStatus    string    `json:"reasonStatus,omitempty"` // Moderation status of the reason
+	IsFlagged       bool      `json:"-"`                      // Reason flagged by the content filter
+	ConfidenceScore *float64  `json:"confidenceScore,omitempty"`
+	VenueName       string    `json:"venueName,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+
+	// Venue and CategoryName are joined in the user's vote history
+	Venue        *VotedVenue `json:"venue,omitempty"`
+	CategoryName string      `json:"categoryName,omitempty"`
+	// ChangeableUntil is set in the user's vote history while the vote can
+	// be changed
+	ChangeableUntil *time.Time `json:"changeableUntil,omitempty"`
+
+	// Votes is the effective number of votes, above 1 only in quadratic
+	// campaigns where they cost CreditsSpent credits of the user's budget
+	Votes        int `json:"votes"`
+	CreditsSpent int `json:"creditsSpent,omitempty"`
+	CreditBudget int `json:"-"`
+
+	// MaxVotes limits the votes of the voter in the campaign, or in the
+	// category of the vote, when above 0. SingleCategory refuses the vote
+	// when the voter voted in another category.
+	MaxVotes       int  `json:"-"`
+	SingleCategory bool `json:"-"`
+}
+
+// VotedVenue is the venue details shown with a vote
+type VotedVenue struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	Slug          string  `json:"slug"`
+	Address       string  `json:"address,omitempty"`
+	CoverImage    string  `json:"coverImage,omitempty"`
+	PriceRange    string  `json:"priceRange,omitempty"`
+	CityName      string  `json:"city,omitempty"`
+	CategoryName  string  `json:"category,omitempty"`
+	AverageRating float64 `json:"averageRating"`
+	TotalRatings  int     `json:"totalRatings"`
+}
+
+func (v *CampaignVote) TableName() string {
+	return "campaign_votes"
+}
+
+// Create stores the vote, debits its credits when it has a cost and bumps the
+// campaign vote counter. Votes over MaxVotes return ErrVoteLimitReached and
+// votes in a second category of a SingleCategory vote ErrOtherCategoryVoted.
+func (v *CampaignVote) Create(ctx context.Context) error {
+	if v.Votes == 0 {
+		v.Votes = 1
+	}
+
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var reason, reasonStatus sql.NullString
+	if v.Reason != "" {
+		if v.ReasonStatus == "" {
+			v.ReasonStatus = ReasonApproved
+		}
+		reason = sql.NullString{String: v.Reason, Valid: true}
+		reasonStatus = sql.NullString{String: v.ReasonStatus, Valid: true}
+	}
+
+	if v.MaxVotes > 0 || v.SingleCategory {
+		if err := v.checkLimits(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO campaign_votes
+			(campaign_id, campaign_category_id, venue_id, user_id, voting_session_id,
+			 reason, reason_status, is_flagged, flagged_at, confidence_score, vote_count, credits_spent)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, CASE WHEN $8 THEN CURRENT_TIMESTAMP END, $9, $10, $11)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at`,
+		v.CampaignID, v.CategoryID, v.VenueID, v.UserID, v.VotingSessionID,
+		reason, reasonStatus, v.IsFlagged, v.ConfidenceScore, v.Votes, v.CreditsSpent,
+	).Scan(&v.ID, &v.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrCampaignVoteExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if v.CreditsSpent > 0 {
+		err = spendCampaignCredits(ctx, tx, v.CampaignID, v.UserID, v.CreditsSpent, v.CreditBudget)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE voting_campaigns
+		SET total_votes = total_votes + $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, v.CampaignID, v.Votes)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// checkLimits counts the voter's votes in the campaign under a lock of the
+// voter, held until the transaction ends, so concurrent votes can't both
+// pass the count
+func (v *CampaignVote) checkLimits(ctx context.Context, tx *sql.Tx) error {
+	voter := fmt.Sprintf("user:%d", v.UserID)
+	if v.VotingSessionID != nil {
+		voter = fmt.Sprintf("session:%d", *v.VotingSessionID)
+	}
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('campaign_vote:' || $1 || ':' || $2))",
+		v.CampaignID, voter)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	var cast int
+	var otherCategory bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE $4::bigint IS NULL OR campaign_category_id = $4),
+			   COALESCE(bool_or(campaign_category_id <> $4), false)
+		FROM campaign_votes
+		WHERE campaign_id = $1
+		  AND CASE WHEN $3::bigint IS NULL THEN user_id = $2 ELSE voting_session_id = $3 END`,
+		v.CampaignID, v.UserID, v.VotingSessionID, v.CategoryID,
+	).Scan(&cast, &otherCategory)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if v.SingleCategory && otherCategory {
+		return ErrOtherCategoryVoted
+	}
+	if v.MaxVotes > 0 && cast >= v.MaxVotes {
+		return ErrVoteLimitReached
+	}
+	return nil
+}
+
+// Exists reports whether the vote is recorded for the campaign, category and venue
+func (v *CampaignVote) Exists(ctx context.Context) (bool, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM campaign_votes
+		WHERE id = $1 AND campaign_id = $2 AND venue_id = $3
+		  AND campaign_category_id IS NOT DISTINCT FROM $4
+		  AND ($5::int = 0 OR vote_count = $5::int)`,
+		v.ID, v.CampaignID, v.VenueID, v.CategoryID, v.Votes,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountUserCampaignVotes returns how many votes the user cast in the campaign
+func CountUserCampaignVotes(ctx context.Context, campaignID, userID int64) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM campaign_votes WHERE campaign_id = $1 AND user_id = $2",
+		campaignID, userID,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// GetCampaignVoterIDs returns the users who voted in the campaign
+func GetCampaignVoterIDs(ctx context.Context, campaignID int64) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		"SELECT DISTINCT user_id FROM campaign_votes WHERE campaign_id = $1 AND user_id IS NOT NULL",
+		campaignID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]int64, 0)
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// CountUserCategoryVotes returns how many votes the user cast in a category
+// of the campaign
+func CountUserCategoryVotes(ctx context.Context, campaignID, categoryID, userID int64) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM campaign_votes WHERE campaign_id = $1 AND campaign_category_id = $2 AND user_id = $3",
+		campaignID, categoryID, userID,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// GetUserVotedCategoryIDs returns the campaign categories the user voted in
+func GetUserVotedCategoryIDs(ctx context.Context, campaignID, userID int64) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT DISTINCT campaign_category_id FROM campaign_votes
+		WHERE campaign_id = $1 AND user_id = $2 AND campaign_category_id IS NOT NULL`,
+		campaignID, userID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categoryIDs := make([]int64, 0)
+	for rows.Next() {
+		var categoryID int64
+		if err := rows.Scan(&categoryID); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		categoryIDs = append(categoryIDs, categoryID)
+	}
+	return categoryIDs, nil
+}
+
+// GetUserCampaignVotes returns the votes the user cast in the campaign
+func GetUserCampaignVotes(ctx context.Context, campaignID, userID int64) ([]CampaignVote, error) {
+	query := `
+		SELECT cv.id, cv.campaign_id, cv.campaign_category_id, cv.venue_id, cv.user_id, cv.reason,
+			   cv.confidence_score, cv.vote_count, cv.credits_spent, v.name, cv.created_at
+		FROM campaign_votes cv
+		LEFT JOIN venues v ON v.id = cv.venue_id
+		WHERE cv.campaign_id = $1 AND cv.user_id = $2
+		ORDER BY cv.created_at, cv.id`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, campaignID, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]CampaignVote, 0)
+	for rows.Next() {
+		var vote CampaignVote
+		var reason, venueName sql.NullString
+		var confidenceScore sql.NullFloat64
+		var categoryID sql.NullInt64
+
+		err := rows.Scan(
+			&vote.ID, &vote.CampaignID, &categoryID, &vote.VenueID, &vote.UserID, &reason,
+			&confidenceScore, &vote.Votes, &vote.CreditsSpent, &venueName, &vote.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		vote.Reason = reason.String
+		vote.VenueName = venueName.String
+		if confidenceScore.Valid {
+			vote.ConfidenceScore = &confidenceScore.Float64
+		}
+		if categoryID.Valid {
+			vote.CategoryID = &categoryID.Int64
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
+
+// GetUserCampaignVoteHistory returns the votes the user cast in the campaign
+// with the details of the venues and categories voted for
+func GetUserCampaignVoteHistory(ctx context.Context, campaignID, userID int64) ([]CampaignVote, error) {
+	query := `
+		SELECT cv.id, cv.campaign_id, cv.campaign_category_id, COALESCE(cc.name, ''), cv.venue_id, cv.user_id,
+			   cv.reason, cv.confidence_score, cv.vote_count, cv.credits_spent, cv.created_at,
+			   v.name, v.slug, COALESCE(v.address, ''), COALESCE(v.cover_image, ''), COALESCE(v.price_range, ''),
+			   COALESCE(c.name, ''), COALESCE(cat.name, ''), COALESCE(v.average_rating, 0), COALESCE(v.total_ratings, 0)
+		FROM campaign_votes cv
+		JOIN venues v ON v.id = cv.venue_id
+		LEFT JOIN cities c ON c.id = v.city_id
+		LEFT JOIN venue_categories cat ON cat.id = v.category_id
+		LEFT JOIN campaign_categories cc ON cc.id = cv.campaign_category_id
+		WHERE cv.campaign_id = $1 AND cv.user_id = $2
+		ORDER BY cv.created_at DESC, cv.id DESC`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, campaignID, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]CampaignVote, 0)
+	for rows.Next() {
+		var vote CampaignVote
+		var venue VotedVenue
+		var reason sql.NullString
+		var confidenceScore sql.NullFloat64
+		var categoryID sql.NullInt64
+
+		err := rows.Scan(
+			&vote.ID, &vote.CampaignID, &categoryID, &vote.CategoryName, &vote.VenueID, &vote.UserID,
+			&reason, &confidenceScore, &vote.Votes, &vote.CreditsSpent, &vote.CreatedAt,
+			&venue.Name, &venue.Slug, &venue.Address, &venue.CoverImage, &venue.PriceRange,
+			&venue.CityName, &venue.CategoryName, &venue.AverageRating, &venue.TotalRatings,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		venue.ID = vote.VenueID
+		vote.Venue = &venue
+		vote.VenueName = venue.Name
+		vote.Reason = reason.String
+		if confidenceScore.Valid {
+			vote.ConfidenceScore = &confidenceScore.Float64
+		}
+		if categoryID.Valid {
+			vote.CategoryID = &categoryID.Int64
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
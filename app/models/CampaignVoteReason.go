@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Moderation statuses of the reason given with a campaign vote
+const (
+	ReasonPending  = "pending"  // Flagged by the content filter, waiting for a moderator
+	ReasonApproved = "approved" // Can be quoted as a highlight
+	ReasonRejected = "rejected" // Hidden by a moderator
+)
+
+// maxReasonHighlights bounds the highlights shown per category
+const maxReasonHighlights = 5
+
+// CampaignVoteReason is the reason a voter gave for a campaign vote, as
+// moderators see it
+type CampaignVoteReason struct {
+	VoteID      int64      `json:"voteId"`
+	CampaignID  int64      `json:"campaignId"`
+	CategoryID  *int64     `json:"categoryId,omitempty"`
+	VenueID     int64      `json:"venueId"`
+	VenueName   string     `json:"venueName"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status"`
+	IsFlagged   bool       `json:"isFlagged"` // Flagged by the content filter
+	IsHighlight bool       `json:"isHighlight"`
+	ModeratedBy *int64     `json:"moderatedBy,omitempty"`
+	ModeratedAt *time.Time `json:"moderatedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// ReasonHighlight is an approved vote reason quoted in the campaign results
+type ReasonHighlight struct {
+	VoteID    int64  `json:"voteId"`
+	VenueID   int64  `json:"venueId"`
+	VenueName string `json:"venueName"`
+	Reason    string `json:"reason"`
+}
+
+const campaignVoteReasonColumns = `
+	cv.id, cv.campaign_id, cv.campaign_category_id, cv.venue_id, COALESCE(v.name, ''),
+	cv.reason, cv.reason_status, COALESCE(cv.is_flagged, false), cv.is_highlight,
+	cv.moderated_by, cv.moderated_at, cv.created_at`
+
+func scanCampaignVoteReason(row interface{ Scan(...interface{}) error }, r *CampaignVoteReason) error {
+	var categoryID, moderatedBy sql.NullInt64
+	var moderatedAt sql.NullTime
+	err := row.Scan(&r.VoteID, &r.CampaignID, &categoryID, &r.VenueID, &r.VenueName,
+		&r.Reason, &r.Status, &r.IsFlagged, &r.IsHighlight,
+		&moderatedBy, &moderatedAt, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if categoryID.Valid {
+		r.CategoryID = &categoryID.Int64
+	}
+	if moderatedBy.Valid {
+		r.ModeratedBy = &moderatedBy.Int64
+	}
+	if moderatedAt.Valid {
+		r.ModeratedAt = &moderatedAt.Time
+	}
+	return nil
+}
+
+// GetCampaignVoteReasons returns the reasons given with a campaign's votes,
+// newest first, filtered by status when set
+func GetCampaignVoteReasons(ctx context.Context, campaignID int64, status string, limit, offset int) ([]CampaignVoteReason, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+campaignVoteReasonColumns+`
+		FROM campaign_votes cv
+		LEFT JOIN venues v ON v.id = cv.venue_id
+		WHERE cv.campaign_id = $1 AND cv.reason IS NOT NULL
+		  AND ($2 = '' OR cv.reason_status = $2)
+		ORDER BY cv.created_at DESC, cv.id DESC
+		LIMIT $3 OFFSET $4`,
+		campaignID, status, limit, offset)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := make([]CampaignVoteReason, 0)
+	for rows.Next() {
+		var reason CampaignVoteReason
+		if err := scanCampaignVoteReason(rows, &reason); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons, nil
+}
+
+// Moderate sets the status of the reason and whether it is quoted as a
+// highlight, recording the moderator. Only approved reasons can be
+// highlights. sql.ErrNoRows is returned when the vote has no reason.
+func (r *CampaignVoteReason) Moderate(ctx context.Context, moderatorID int64, status string, highlight bool) error {
+	row := databases.PostgresDB.QueryRowContext(ctx, `
+		WITH moderated AS (
+			UPDATE campaign_votes
+			SET reason_status = $3, is_highlight = $4 AND $3 = 'approved',
+				moderated_by = NULLIF($5, 0), moderated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND campaign_id = $2 AND reason IS NOT NULL
+			RETURNING *
+		)
+		SELECT `+campaignVoteReasonColumns+`
+		FROM moderated cv
+		LEFT JOIN venues v ON v.id = cv.venue_id`,
+		r.VoteID, r.CampaignID, status, highlight, moderatorID)
+
+	err := scanCampaignVoteReason(row, r)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// getReasonHighlights returns the approved highlights of a campaign keyed by
+// category, 0 for votes outside any category, newest first
+func getReasonHighlights(ctx context.Context, campaignID int64) (map[int64][]ReasonHighlight, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT cv.campaign_category_id, cv.id, cv.venue_id, COALESCE(v.name, ''), cv.reason
+		FROM campaign_votes cv
+		LEFT JOIN venues v ON v.id = cv.venue_id
+		WHERE cv.campaign_id = $1 AND cv.is_highlight = true AND cv.excluded_at IS NULL
+		  AND cv.reason_status = 'approved' AND cv.reason IS NOT NULL
+		ORDER BY cv.moderated_at DESC, cv.id DESC`,
+		campaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	highlights := make(map[int64][]ReasonHighlight)
+	for rows.Next() {
+		var categoryID sql.NullInt64
+		var highlight ReasonHighlight
+		err := rows.Scan(&categoryID, &highlight.VoteID, &highlight.VenueID, &highlight.VenueName, &highlight.Reason)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if len(highlights[categoryID.Int64]) < maxReasonHighlights {
+			highlights[categoryID.Int64] = append(highlights[categoryID.Int64], highlight)
+		}
+	}
+	return highlights, nil
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrCityExists is returned when the country already has a city of the name
+// in the state
+var ErrCityExists = errors.New("city already exists")
+
+// ErrDistrictExists is returned when the city already has a district of the
+// slug
+var ErrDistrictExists = errors.New("city already has this district")
+
+func (c *City) TableName() string {
+	return "cities"
+}
+
+// Create stores the city, ErrCityExists when the country already has a city
+// of the name in the state
+func (c *City) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO cities (name, state, country, latitude, longitude, timezone, is_active)
+		SELECT $1, NULLIF($2, ''), $3, $4, $5, $6, $7
+		WHERE NOT EXISTS (
+			SELECT 1 FROM cities
+			WHERE LOWER(name) = LOWER($1) AND LOWER(COALESCE(state, '')) = LOWER($2) AND LOWER(country) = LOWER($3)
+		)
+		RETURNING id`,
+		c.Name, c.State, c.Country, c.Latitude, c.Longitude, c.Timezone, c.IsActive,
+	).Scan(&c.ID)
+	if err == sql.ErrNoRows {
+		return ErrCityExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	c.HasCoordinates = true
+	return nil
+}
+
+// Update replaces the city's names, centroid, timezone and whether it is
+// listed, sql.ErrNoRows when it doesn't exist
+func (c *City) Update(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		UPDATE cities
+		SET name = $2, state = NULLIF($3, ''), country = $4, latitude = $5, longitude = $6, timezone = $7, is_active = $8
+		WHERE id = $1`,
+		c.ID, c.Name, c.State, c.Country, c.Latitude, c.Longitude, c.Timezone, c.IsActive)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return sql.ErrNoRows
+	}
+	c.HasCoordinates = true
+	return nil
+}
+
+// GetByID retrieves the city, active or not
+func (c *City) GetByID(ctx context.Context) error {
+	var latitude, longitude sql.NullFloat64
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT name, COALESCE(state, ''), country, latitude, longitude, COALESCE(timezone, ''), COALESCE(is_active, false)
+		FROM cities
+		WHERE id = $1`,
+		c.ID,
+	).Scan(&c.Name, &c.State, &c.Country, &latitude, &longitude, &c.Timezone, &c.IsActive)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	c.Latitude, c.Longitude = latitude.Float64, longitude.Float64
+	c.HasCoordinates = latitude.Valid && longitude.Valid
+	return nil
+}
+
+// District is an area of a city venues and users pick from lists, placed by
+// its centroid. Neighborhoods are the areas with a boundary venues are
+// placed in.
+type District struct {
+	ID        int64     `json:"id"`
+	CityID    int64     `json:"cityId"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (d *District) TableName() string {
+	return "districts"
+}
+
+// Create stores the district, ErrDistrictExists when its slug is taken in
+// the city
+func (d *District) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO districts (city_id, name, slug, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (city_id, slug) DO NOTHING
+		RETURNING id, created_at`,
+		d.CityID, d.Name, d.Slug, d.Latitude, d.Longitude,
+	).Scan(&d.ID, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrDistrictExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Update replaces the district's name, slug and centroid. It returns
+// sql.ErrNoRows when it doesn't exist, ErrDistrictExists when the new slug
+// is taken in the city.
+func (d *District) Update(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE districts SET name = $2, slug = $3, latitude = $4, longitude = $5
+		WHERE id = $1 AND NOT EXISTS (
+			SELECT 1 FROM districts other
+			WHERE other.city_id = districts.city_id AND other.slug = $3 AND other.id <> $1
+		)
+		RETURNING city_id, created_at`,
+		d.ID, d.Name, d.Slug, d.Latitude, d.Longitude,
+	).Scan(&d.CityID, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := databases.PostgresDB.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM districts WHERE id = $1)", d.ID).Scan(&exists); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		if exists {
+			return ErrDistrictExists
+		}
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves the district
+func (d *District) GetByID(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT city_id, name, slug, latitude, longitude, created_at
+		FROM districts
+		WHERE id = $1`,
+		d.ID,
+	).Scan(&d.CityID, &d.Name, &d.Slug, &d.Latitude, &d.Longitude, &d.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// DeleteDistrict removes the district, sql.ErrNoRows when it doesn't exist
+func DeleteDistrict(ctx context.Context, districtID int64) error {
+	result, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM districts WHERE id = $1", districtID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCityDistricts returns the districts of a city by name
+func GetCityDistricts(ctx context.Context, cityID int64) ([]District, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, city_id, name, slug, latitude, longitude, created_at
+		FROM districts
+		WHERE city_id = $1
+		ORDER BY name, id`,
+		cityID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	districts := make([]District, 0)
+	for rows.Next() {
+		var district District
+		err := rows.Scan(&district.ID, &district.CityID, &district.Name, &district.Slug,
+			&district.Latitude, &district.Longitude, &district.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		districts = append(districts, district)
+	}
+	return districts, rows.Err()
+}
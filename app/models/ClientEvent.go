@@ -0,0 +1,186 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Client event types sent by the web and mobile apps
+const (
+	ClientEventPageView   = "page_view"
+	ClientEventScreenView = "screen_view"
+	ClientEventClick      = "click"
+	ClientEventSearch     = "search"
+	ClientEventShare      = "share"
+)
+
+// ClientEventTypes lists the accepted client event types
+var ClientEventTypes = []string{
+	ClientEventPageView, ClientEventScreenView, ClientEventClick, ClientEventSearch, ClientEventShare,
+}
+
+// IsClientPageEvent reports whether the event type counts as a page of a
+// session
+func IsClientPageEvent(eventType string) bool {
+	return eventType == ClientEventPageView || eventType == ClientEventScreenView
+}
+
+// ClientEvent is an event reported by an app install or browser, identified
+// by its client ID. Events are grouped into sessions by the sessionization
+// job.
+type ClientEvent struct {
+	ID         int64     `json:"id"`
+	ClientID   string    `json:"clientId"`
+	Type       string    `json:"type"`
+	Path       string    `json:"path,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+	SessionID  *int64    `json:"sessionId,omitempty"`
+}
+
+func (e *ClientEvent) TableName() string {
+	return "client_events"
+}
+
+// InsertClientEvents stores a batch of client events
+func InsertClientEvents(ctx context.Context, events []ClientEvent) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO client_events (client_id, event_type, path, occurred_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		if _, err := stmt.ExecContext(ctx, event.ClientID, event.Type, event.Path, event.OccurredAt); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetUnsessionizedEvents returns up to limit events not yet in a session,
+// ordered by client and time
+func GetUnsessionizedEvents(ctx context.Context, tx *sql.Tx, limit int) ([]ClientEvent, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, client_id, event_type, COALESCE(path, ''), occurred_at
+		FROM client_events
+		WHERE session_id IS NULL
+		ORDER BY client_id, occurred_at, id
+		LIMIT $1`, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []ClientEvent{}
+	for rows.Next() {
+		var event ClientEvent
+		if err := rows.Scan(&event.ID, &event.ClientID, &event.Type, &event.Path, &event.OccurredAt); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// DeleteSessionizedEvents removes events already in a session that occurred
+// before the cutoff. Their sessions are kept.
+func DeleteSessionizedEvents(ctx context.Context, before time.Time) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM client_events WHERE session_id IS NOT NULL AND occurred_at < $1", before)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// ClientSession is a run of a client's events with no gap longer than the
+// session timeout between them
+type ClientSession struct {
+	ID         int64     `json:"id"`
+	ClientID   string    `json:"clientId"`
+	StartedAt  time.Time `json:"startedAt"`
+	EndedAt    time.Time `json:"endedAt"`
+	PageViews  int       `json:"pageViews"`
+	EventCount int       `json:"eventCount"`
+}
+
+func (s *ClientSession) TableName() string {
+	return "client_sessions"
+}
+
+// GetLatestClientSession returns the client's most recently ended session,
+// or nil when the client has none
+func GetLatestClientSession(ctx context.Context, tx *sql.Tx, clientID string) (*ClientSession, error) {
+	session := &ClientSession{ClientID: clientID}
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, started_at, ended_at, page_views, event_count
+		FROM client_sessions
+		WHERE client_id = $1
+		ORDER BY ended_at DESC
+		LIMIT 1`, clientID,
+	).Scan(&session.ID, &session.StartedAt, &session.EndedAt, &session.PageViews, &session.EventCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return session, nil
+}
+
+// Save creates the session or updates its bounds and counts, and moves the
+// events into it
+func (s *ClientSession) Save(ctx context.Context, tx *sql.Tx, eventIDs []int64) error {
+	var err error
+	if s.ID == 0 {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO client_sessions (client_id, started_at, ended_at, page_views, event_count)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+			s.ClientID, s.StartedAt, s.EndedAt, s.PageViews, s.EventCount,
+		).Scan(&s.ID)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE client_sessions
+			SET started_at = $2, ended_at = $3, page_views = $4, event_count = $5,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`,
+			s.ID, s.StartedAt, s.EndedAt, s.PageViews, s.EventCount)
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE client_events SET session_id = $1 WHERE id = ANY($2)",
+		s.ID, pq.Array(eventIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
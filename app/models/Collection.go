@@ -0,0 +1,407 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Roles of users in a collection
+const (
+	CollectionRoleOwner        = "owner"
+	CollectionRoleCollaborator = "collaborator"
+)
+
+// Collaborator invite statuses
+const (
+	CollaboratorPending  = "pending"
+	CollaboratorAccepted = "accepted"
+)
+
+// WishlistCollectionName is the name of the collections users keep the
+// venues they want to try in, matched in any case
+const WishlistCollectionName = "Want to Try"
+
+// ErrCollaboratorExists is returned when the user is already invited to the
+// collection
+var ErrCollaboratorExists = errors.New("user is already invited to the collection")
+
+// VenueCollection is a user's list of venues. Collaborators who accepted
+// the owner's invite can add and remove its venues too.
+type VenueCollection struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"` // The owner
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	IsPublic    bool      `json:"isPublic"`
+	CoverImage  string    `json:"coverImage,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// Role is the requesting user's role, empty for other users
+	Role      string `json:"role,omitempty"`
+	ItemCount int    `json:"itemCount"`
+}
+
+func (c *VenueCollection) TableName() string {
+	return "venue_collections"
+}
+
+// CollectionItem is a venue in a collection
+type CollectionItem struct {
+	ID           int64     `json:"id"`
+	CollectionID int64     `json:"collectionId"`
+	VenueID      int64     `json:"venueId"`
+	VenueName    string    `json:"venueName,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	AddedBy      int64     `json:"addedBy,omitempty"`
+	AddedAt      time.Time `json:"addedAt"`
+}
+
+func (i *CollectionItem) TableName() string {
+	return "venue_collection_items"
+}
+
+// CollectionCollaborator is a user invited to edit a collection
+type CollectionCollaborator struct {
+	CollectionID   int64      `json:"collectionId"`
+	CollectionName string     `json:"collectionName,omitempty"`
+	UserID         int64      `json:"userId"`
+	UserName       string     `json:"userName,omitempty"`
+	InvitedBy      int64      `json:"invitedBy"`
+	Status         string     `json:"status"` // pending, accepted
+	InvitedAt      time.Time  `json:"invitedAt"`
+	AcceptedAt     *time.Time `json:"acceptedAt,omitempty"`
+}
+
+func (c *CollectionCollaborator) TableName() string {
+	return "collection_collaborators"
+}
+
+// Create stores a new collection
+func (c *VenueCollection) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_collections (user_id, name, description, is_public, cover_image)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''))
+		RETURNING id, created_at, updated_at`,
+		c.UserID, c.Name, c.Description, c.IsPublic, c.CoverImage,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	c.Role = CollectionRoleOwner
+	return nil
+}
+
+const collectionColumns = `
+	c.id, c.user_id, c.name, COALESCE(c.description, ''), COALESCE(c.is_public, true),
+	COALESCE(c.cover_image, ''), c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM venue_collection_items i WHERE i.collection_id = c.id)`
+
+// GetCollection returns the collection with the user's role in it,
+// sql.ErrNoRows when it doesn't exist
+func GetCollection(ctx context.Context, collectionID, userID int64) (*VenueCollection, error) {
+	collection := &VenueCollection{}
+	var role string
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT `+collectionColumns+`,
+			   CASE WHEN c.user_id = $2 THEN 'owner'
+					WHEN EXISTS (SELECT 1 FROM collection_collaborators cc
+								 WHERE cc.collection_id = c.id AND cc.user_id = $2 AND cc.status = 'accepted')
+					THEN 'collaborator'
+					ELSE '' END
+		FROM venue_collections c
+		WHERE c.id = $1`,
+		collectionID, userID,
+	).Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.Description, &collection.IsPublic,
+		&collection.CoverImage, &collection.CreatedAt, &collection.UpdatedAt, &collection.ItemCount, &role)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	collection.Role = role
+	return collection, nil
+}
+
+// CanEdit tells whether the requesting user can change the collection's
+// venues
+func (c *VenueCollection) CanEdit() bool {
+	return c.Role == CollectionRoleOwner || c.Role == CollectionRoleCollaborator
+}
+
+// GetUserCollections returns the collections the user owns or collaborates
+// on, most recently updated first
+func GetUserCollections(ctx context.Context, userID int64) ([]VenueCollection, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+collectionColumns+`,
+			   CASE WHEN c.user_id = $1 THEN 'owner' ELSE 'collaborator' END
+		FROM venue_collections c
+		WHERE c.user_id = $1
+		   OR c.id IN (SELECT collection_id FROM collection_collaborators
+					   WHERE user_id = $1 AND status = 'accepted')
+		ORDER BY c.updated_at DESC, c.id DESC`,
+		userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := make([]VenueCollection, 0)
+	for rows.Next() {
+		var collection VenueCollection
+		err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.Description,
+			&collection.IsPublic, &collection.CoverImage, &collection.CreatedAt, &collection.UpdatedAt,
+			&collection.ItemCount, &collection.Role)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+// GetItems returns the collection's venues, most recently added first
+func (c *VenueCollection) GetItems(ctx context.Context) ([]CollectionItem, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT i.id, i.collection_id, i.venue_id, v.name, COALESCE(i.note, ''),
+			   COALESCE(i.added_by, 0), i.added_at
+		FROM venue_collection_items i
+		JOIN venues v ON v.id = i.venue_id
+		WHERE i.collection_id = $1
+		ORDER BY i.added_at DESC, i.id DESC`,
+		c.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]CollectionItem, 0)
+	for rows.Next() {
+		var item CollectionItem
+		err := rows.Scan(&item.ID, &item.CollectionID, &item.VenueID, &item.VenueName,
+			&item.Note, &item.AddedBy, &item.AddedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Save adds the venue to the collection, or updates its note when it is
+// already in it. The venue keeps who first added it and when.
+func (i *CollectionItem) Save(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var addedBy sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO venue_collection_items (collection_id, venue_id, note, added_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (collection_id, venue_id) DO UPDATE SET note = EXCLUDED.note
+		RETURNING id, added_by, added_at`,
+		i.CollectionID, i.VenueID, i.Note, i.AddedBy,
+	).Scan(&i.ID, &addedBy, &i.AddedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	i.AddedBy = addedBy.Int64
+
+	if err := touchCollection(ctx, tx, i.CollectionID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// RemoveCollectionItem removes the venue from the collection, returning
+// sql.ErrNoRows when it isn't in it
+func RemoveCollectionItem(ctx context.Context, collectionID, venueID int64) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM venue_collection_items WHERE collection_id = $1 AND venue_id = $2",
+		collectionID, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := touchCollection(ctx, tx, collectionID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// touchCollection marks the collection as updated
+func touchCollection(ctx context.Context, tx *sql.Tx, collectionID int64) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE venue_collections SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", collectionID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Invite stores a pending invite of the user to collaborate on the
+// collection, ErrCollaboratorExists when the user is already invited
+func (c *CollectionCollaborator) Invite(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO collection_collaborators (collection_id, user_id, invited_by, status)
+		VALUES ($1, $2, $3, 'pending')
+		ON CONFLICT (collection_id, user_id) DO NOTHING
+		RETURNING status, invited_at`,
+		c.CollectionID, c.UserID, c.InvitedBy,
+	).Scan(&c.Status, &c.InvitedAt)
+	if err == sql.ErrNoRows {
+		return ErrCollaboratorExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// AcceptCollaboration accepts the user's pending invite to the collection,
+// returning sql.ErrNoRows when there is none
+func AcceptCollaboration(ctx context.Context, collectionID, userID int64) (*CollectionCollaborator, error) {
+	collaborator := &CollectionCollaborator{CollectionID: collectionID, UserID: userID}
+	var acceptedAt time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE collection_collaborators
+		SET status = 'accepted', accepted_at = CURRENT_TIMESTAMP
+		WHERE collection_id = $1 AND user_id = $2 AND status = 'pending'
+		RETURNING invited_by, status, invited_at, accepted_at`,
+		collectionID, userID,
+	).Scan(&collaborator.InvitedBy, &collaborator.Status, &collaborator.InvitedAt, &acceptedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	collaborator.AcceptedAt = &acceptedAt
+	return collaborator, nil
+}
+
+// RemoveCollaborator removes the user from the collection's collaborators,
+// declining a pending invite, returning sql.ErrNoRows when the user isn't
+// one
+func RemoveCollaborator(ctx context.Context, collectionID, userID int64) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM collection_collaborators WHERE collection_id = $1 AND user_id = $2",
+		collectionID, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCollaborators returns the collection's collaborators and pending
+// invites, oldest invite first
+func (c *VenueCollection) GetCollaborators(ctx context.Context) ([]CollectionCollaborator, error) {
+	return queryCollaborators(ctx, "cc.collection_id = $1", c.ID)
+}
+
+// GetCollaborationInvites returns the user's pending invites to
+// collaborate on collections
+func GetCollaborationInvites(ctx context.Context, userID int64) ([]CollectionCollaborator, error) {
+	return queryCollaborators(ctx, "cc.user_id = $1 AND cc.status = 'pending'", userID)
+}
+
+func queryCollaborators(ctx context.Context, condition string, arg int64) ([]CollectionCollaborator, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT cc.collection_id, c.name, cc.user_id, COALESCE(u.snapp_id, ''),
+			   cc.invited_by, cc.status, cc.invited_at, cc.accepted_at
+		FROM collection_collaborators cc
+		JOIN venue_collections c ON c.id = cc.collection_id
+		LEFT JOIN snapp_users u ON u.id = cc.user_id
+		WHERE `+condition+`
+		ORDER BY cc.invited_at, cc.user_id`,
+		arg)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := make([]CollectionCollaborator, 0)
+	for rows.Next() {
+		var collaborator CollectionCollaborator
+		var acceptedAt sql.NullTime
+		err := rows.Scan(&collaborator.CollectionID, &collaborator.CollectionName, &collaborator.UserID,
+			&collaborator.UserName, &collaborator.InvitedBy, &collaborator.Status,
+			&collaborator.InvitedAt, &acceptedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if acceptedAt.Valid {
+			collaborator.AcceptedAt = &acceptedAt.Time
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	return collaborators, rows.Err()
+}
+
+// GetWishlistVenueIDs returns the venues of the user's "Want to Try"
+// collections
+func GetWishlistVenueIDs(ctx context.Context, userID int64) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT DISTINCT i.venue_id
+		FROM venue_collection_items i
+		INNER JOIN venue_collections c ON c.id = i.collection_id
+		WHERE c.user_id = $1 AND LOWER(c.name) = LOWER($2)`,
+		userID, WishlistCollectionName)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// ExperimentEventImpression is a venue recommended to a user in an
+// experiment. The other experiment events are the venue events the apps
+// tag with the variant they were served.
+const ExperimentEventImpression = "impression"
+
+// VariantMetrics counts how a variant of an experiment performed
+type VariantMetrics struct {
+	Variant     string `json:"variant"`
+	Users       int    `json:"users"`       // Users recommended venues
+	Impressions int    `json:"impressions"` // Venues recommended
+	Clicks      int    `json:"clicks"`      // Recommended venues opened, tagged venue views
+	Conversions int    `json:"conversions"` // Calls and directions asked from recommended venues
+	// CTR is clicks per impression and ConversionRate conversions per click
+	CTR            float64 `json:"ctr"`
+	ConversionRate float64 `json:"conversionRate"`
+}
+
+// RecordExperimentImpressions stores the venues recommended to the user with
+// the variant of the experiment they were served
+func RecordExperimentImpressions(ctx context.Context, experiment, variant string, userID int64, venueIDs []int64) error {
+	if len(venueIDs) == 0 {
+		return nil
+	}
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO experiment_events (experiment, variant, user_id, venue_id, event_type)
+		SELECT $1, $2, $3, venue_id, $4
+		FROM UNNEST($5::BIGINT[]) AS venue_id`,
+		experiment, variant, userID, ExperimentEventImpression, pq.Array(venueIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetExperimentMetrics counts the events of each variant of the experiment
+// since the time. Variants without events are left out.
+func GetExperimentMetrics(ctx context.Context, experiment string, since time.Time) ([]VariantMetrics, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT variant,
+			COUNT(DISTINCT user_id) FILTER (WHERE event_type = $3),
+			COUNT(*) FILTER (WHERE event_type = $3),
+			COUNT(*) FILTER (WHERE event_type = $4),
+			COUNT(*) FILTER (WHERE event_type IN ($5, $6))
+		FROM experiment_events
+		WHERE experiment = $1 AND occurred_at >= $2
+		GROUP BY variant
+		ORDER BY variant`,
+		experiment, since, ExperimentEventImpression, VenueEventView, VenueEventPhoneClick, VenueEventDirections)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	metrics := []VariantMetrics{}
+	for rows.Next() {
+		var variant VariantMetrics
+		if err := rows.Scan(&variant.Variant, &variant.Users, &variant.Impressions, &variant.Clicks, &variant.Conversions); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		metrics = append(metrics, variant)
+	}
+	return metrics, rows.Err()
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// ExternalRatingSources are the platforms ratings can be imported from, all
+// rating from 1 to 5
+var ExternalRatingSources = []string{"google", "tripadvisor", "yelp", "facebook"}
+
+// ExternalRating is a venue's aggregate rating on another platform. They are
+// shown next to the native reviews and never count towards the venue's
+// average rating, rating totals or rankings.
+type ExternalRating struct {
+	ID          int64     `json:"id"`
+	VenueID     int64     `json:"venueId"`
+	Source      string    `json:"source"`
+	Rating      float64   `json:"rating"`
+	ReviewCount int       `json:"reviewCount"`
+	URL         string    `json:"url,omitempty"` // The venue's page on the platform
+	ImportedBy  int64     `json:"importedBy,omitempty"`
+	ImportedAt  time.Time `json:"importedAt"`
+}
+
+func (e *ExternalRating) TableName() string {
+	return "external_ratings"
+}
+
+// IsExternalRatingSource tells whether ratings can be imported from the
+// platform
+func IsExternalRatingSource(source string) bool {
+	for _, known := range ExternalRatingSources {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveExternalRatings stores the ratings, replacing the ones their venues
+// already have from the same platforms
+func SaveExternalRatings(ctx context.Context, ratings []ExternalRating) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range ratings {
+		rating := &ratings[i]
+		var importedBy sql.NullInt64
+		if rating.ImportedBy != 0 {
+			importedBy = sql.NullInt64{Int64: rating.ImportedBy, Valid: true}
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO external_ratings (venue_id, source, rating, review_count, url, imported_by, imported_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, CURRENT_TIMESTAMP)
+			ON CONFLICT (venue_id, source) DO UPDATE SET
+				rating = EXCLUDED.rating, review_count = EXCLUDED.review_count, url = EXCLUDED.url,
+				imported_by = EXCLUDED.imported_by, imported_at = EXCLUDED.imported_at
+			RETURNING id, imported_at`,
+			rating.VenueID, rating.Source, rating.Rating, rating.ReviewCount, rating.URL, importedBy,
+		).Scan(&rating.ID, &rating.ImportedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetExternalRatings returns the imported ratings of the venues, by venue
+// and in source order
+func GetExternalRatings(ctx context.Context, venueIDs []int64) (map[int64][]ExternalRating, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, venue_id, source, rating, review_count, COALESCE(url, ''),
+			   COALESCE(imported_by, 0), imported_at
+		FROM external_ratings
+		WHERE venue_id = ANY($1)
+		ORDER BY venue_id, source`,
+		pq.Array(venueIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[int64][]ExternalRating)
+	for rows.Next() {
+		var rating ExternalRating
+		err := rows.Scan(&rating.ID, &rating.VenueID, &rating.Source, &rating.Rating,
+			&rating.ReviewCount, &rating.URL, &rating.ImportedBy, &rating.ImportedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		ratings[rating.VenueID] = append(ratings[rating.VenueID], rating)
+	}
+	return ratings, rows.Err()
+}
+
+// DeleteExternalRating removes the venue's rating from the platform,
+// returning sql.ErrNoRows when it has none
+func DeleteExternalRating(ctx context.Context, venueID int64, source string) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM external_ratings WHERE venue_id = $1 AND source = $2", venueID, source)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetExistingVenueIDs returns which of the venues exist
+func GetExistingVenueIDs(ctx context.Context, venueIDs []int64) (map[int64]bool, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		"SELECT id FROM venues WHERE id = ANY($1) AND ($2::bigint IS NULL OR tenant_id = $2)",
+		pq.Array(venueIDs), TenantFilter(ctx))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[int64]bool, len(venueIDs))
+	for rows.Next() {
+		var venueID int64
+		if err := rows.Scan(&venueID); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		existing[venueID] = true
+	}
+	return existing, rows.Err()
+}
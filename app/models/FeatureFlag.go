@@ -0,0 +1,117 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// ErrFeatureFlagExists is returned when a flag with the key already exists
+var ErrFeatureFlagExists = errors.New("feature flag already exists")
+
+// FeatureFlag gates a feature being rolled out. An enabled flag is on for
+// the listed users and for RolloutPercentage percent of all users, picked
+// by a stable hash of the flag key and user ID.
+type FeatureFlag struct {
+	ID                int64     `json:"id"`
+	Key               string    `json:"key"`
+	Description       string    `json:"description,omitempty"`
+	Enabled           bool      `json:"enabled"`
+	RolloutPercentage int       `json:"rolloutPercentage"` // 0-100
+	UserIDs           []int64   `json:"userIds"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+func (f *FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// Create stores the flag, ErrFeatureFlagExists when its key is taken
+func (f *FeatureFlag) Create(ctx context.Context) error {
+	if f.UserIDs == nil {
+		f.UserIDs = []int64{}
+	}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO feature_flags (key, description, enabled, rollout_percentage, user_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		f.Key, f.Description, f.Enabled, f.RolloutPercentage, pq.Array(f.UserIDs),
+	).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrFeatureFlagExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Update replaces the targeting of the flag with the key, sql.ErrNoRows when
+// there is none
+func (f *FeatureFlag) Update(ctx context.Context) error {
+	if f.UserIDs == nil {
+		f.UserIDs = []int64{}
+	}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE feature_flags
+		SET description = $2, enabled = $3, rollout_percentage = $4, user_ids = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE key = $1
+		RETURNING id, created_at, updated_at`,
+		f.Key, f.Description, f.Enabled, f.RolloutPercentage, pq.Array(f.UserIDs),
+	).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// DeleteFeatureFlag removes the flag with the key, reporting whether it
+// existed
+func DeleteFeatureFlag(ctx context.Context, key string) (bool, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM feature_flags WHERE key = $1", key)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// GetFeatureFlags returns all flags by key
+func GetFeatureFlags(ctx context.Context) ([]FeatureFlag, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, key, COALESCE(description, ''), enabled, rollout_percentage, user_ids, created_at, updated_at
+		FROM feature_flags
+		ORDER BY key`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := make([]FeatureFlag, 0)
+	for rows.Next() {
+		var flag FeatureFlag
+		var userIDs pq.Int64Array
+		err := rows.Scan(&flag.ID, &flag.Key, &flag.Description, &flag.Enabled, &flag.RolloutPercentage,
+			&userIDs, &flag.CreatedAt, &flag.UpdatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		flag.UserIDs = []int64(userIDs)
+		if flag.UserIDs == nil {
+			flag.UserIDs = []int64{}
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Why a review is featured
+const (
+	FeaturedEditorial  = "editorial"   // Flagged is_featured by the editors
+	FeaturedTopHelpful = "top_helpful" // Among the most helpful of the week
+)
+
+// FeaturedReview is a review picked for the home feed
+type FeaturedReview struct {
+	VenueReview
+	Reason string `json:"reason"` // editorial or top_helpful
+}
+
+// GetFeaturedReviews returns the approved reviews for the home feed: the
+// editorial picks, newest first, then the reviews written since the time
+// with the most helpful votes. The city filters by the venue's city.
+func GetFeaturedReviews(ctx context.Context, cityID *int64, since time.Time, limit int) ([]FeaturedReview, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT r.id, r.venue_id, r.user_id, r.overall_rating, COALESCE(r.title, ''),
+			   COALESCE(r.review_text, ''), r.photos, r.is_verified, r.is_featured,
+			   COALESCE(r.helpful_votes, 0), r.created_at, r.updated_at,
+			   v.name, u.snapp_id,
+			   CASE WHEN r.is_featured THEN $1 ELSE $2 END
+		FROM venue_reviews r
+		JOIN venues v ON v.id = r.venue_id AND v.is_active = true
+		LEFT JOIN snapp_users u ON u.id = r.user_id
+		WHERE r.moderation_status = 'approved' AND COALESCE(r.is_flagged, false) = false
+		  AND (r.is_featured = true OR (r.created_at >= $3 AND r.helpful_votes > 0))
+		  AND ($4::bigint IS NULL OR v.city_id = $4)
+		  AND ($5::bigint IS NULL OR v.tenant_id = $5)
+		ORDER BY r.is_featured DESC,
+				 CASE WHEN r.is_featured THEN 0 ELSE r.helpful_votes END DESC,
+				 r.created_at DESC, r.id DESC
+		LIMIT $6`,
+		FeaturedEditorial, FeaturedTopHelpful, since, cityID, TenantFilter(ctx), limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []FeaturedReview{}
+	for rows.Next() {
+		var r FeaturedReview
+		var userSnappID sql.NullString
+		err := rows.Scan(&r.ID, &r.VenueID, &r.UserID, &r.OverallRating, &r.Title,
+			&r.ReviewText, &r.Photos, &r.IsVerified, &r.IsFeatured,
+			&r.HelpfulVotes, &r.CreatedAt, &r.UpdatedAt,
+			&r.VenueName, &userSnappID, &r.Reason)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		r.UserName = userSnappID.String
+		r.ModerationStatus = "approved"
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+	// GeohashPrecision is the length of the geohash venues store, about
+	// 4cm by 2cm
+	GeohashPrecision = 12
+	// maxGeohashCellPrecision bounds the cells of small radiuses, cells of 8
+	// characters are already only 38m wide
+	maxGeohashCellPrecision = 8
+	// geohashCoverMargin widens the circle covered by the cells, making up
+	// for the spheroid ST_DWithin measures on
+	geohashCoverMargin   = 1.02
+	kmPerLatitudeDegree  = 110.574 // The least length of a degree of latitude
+	kmPerLongitudeDegree = 111.320 // At the equator
+)
+
+// GeohashEncode returns the geohash of the point with the given number of
+// characters
+func GeohashEncode(lat, lng float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+	var hash strings.Builder
+	bit, ch, even := 0, 0, true
+	for hash.Len() < precision {
+		// Bits alternate between longitude and latitude, longitude first
+		if even {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				minLng = mid
+			} else {
+				ch <<= 1
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// geohashCellSize returns the height and width in degrees of the cells of
+// the given number of characters
+func geohashCellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lngBits))
+}
+
+// GeohashCoverCells returns the geohash prefixes of the cells covering the
+// circle: the cell of the center and its neighbors, of the smallest size
+// still wider and taller than the circle. It returns nil when the circle is
+// too large or reaches too close to a pole to be covered this way.
+func GeohashCoverCells(lat, lng, radiusKm float64) []string {
+	latDegrees := radiusKm / kmPerLatitudeDegree * geohashCoverMargin
+	farthestLat := math.Abs(lat) + latDegrees
+	if radiusKm <= 0 || farthestLat >= 89 {
+		return nil
+	}
+	// Degrees of longitude shrink away from the equator, the side of the
+	// circle nearest to the pole is the widest in degrees
+	lngDegrees := radiusKm / (kmPerLongitudeDegree * math.Cos(farthestLat*math.Pi/180)) * geohashCoverMargin
+
+	precision := 0
+	for p := 1; p <= maxGeohashCellPrecision; p++ {
+		height, width := geohashCellSize(p)
+		if height < latDegrees || width < lngDegrees {
+			break
+		}
+		precision = p
+	}
+	if precision == 0 {
+		return nil
+	}
+
+	// The neighbors are found from the center of the center's cell
+	height, width := geohashCellSize(precision)
+	centerLat := (math.Floor((lat+90)/height)+0.5)*height - 90
+	centerLng := (math.Floor((lng+180)/width)+0.5)*width - 180
+
+	seen := make(map[string]bool)
+	cells := make([]string, 0, 9)
+	for dy := -1; dy <= 1; dy++ {
+		cellLat := centerLat + float64(dy)*height
+		if cellLat <= -90 || cellLat >= 90 {
+			continue
+		}
+		for dx := -1; dx <= 1; dx++ {
+			// Wrapped around the antimeridian
+			cellLng := math.Mod(centerLng+float64(dx)*width+540, 360) - 180
+			cell := GeohashEncode(cellLat, cellLng, precision)
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells
+}
+
+// geohashCondition returns the condition keeping the rows whose geohash
+// column starts with one of the cells covering the circle, "" when the
+// circle can't be covered. Geohashes only use the characters of
+// geohashAlphabet, the cells are written into the query as is so that the
+// planner sees the prefixes.
+func geohashCondition(column string, lat, lng, radiusKm float64) string {
+	cells := GeohashCoverCells(lat, lng, radiusKm)
+	if len(cells) == 0 {
+		return ""
+	}
+	conditions := make([]string, len(cells))
+	for i, cell := range cells {
+		conditions[i] = column + " LIKE '" + cell + "%'"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ImpersonationSession lets a support admin see the app as a snapp user
+// until it expires. Only the hash of its token is stored.
+type ImpersonationSession struct {
+	ID          int64     `json:"id"`
+	AdminID     int64     `json:"adminId"`
+	SnappUserID int64     `json:"snappUserId"`
+	SnappID     string    `json:"snappId"`
+	Reason      string    `json:"reason"`
+	TokenHash   string    `json:"-"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (s *ImpersonationSession) TableName() string {
+	return "impersonation_sessions"
+}
+
+// Create stores the session
+func (s *ImpersonationSession) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO impersonation_sessions (admin_id, snapp_user_id, reason, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		s.AdminID, s.SnappUserID, s.Reason, s.TokenHash, s.ExpiresAt,
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetImpersonationSession returns the unexpired session of the token hash,
+// sql.ErrNoRows when there is none
+func GetImpersonationSession(ctx context.Context, tokenHash string, now time.Time) (*ImpersonationSession, error) {
+	session := &ImpersonationSession{TokenHash: tokenHash}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT i.id, i.admin_id, i.snapp_user_id, s.snapp_id, i.reason, i.expires_at, i.created_at
+		FROM impersonation_sessions i
+		INNER JOIN snapp_users s ON s.id = i.snapp_user_id
+		WHERE i.token_hash = $1 AND i.expires_at > $2`,
+		tokenHash, now,
+	).Scan(&session.ID, &session.AdminID, &session.SnappUserID, &session.SnappID, &session.Reason,
+		&session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+type impersonationKey struct{}
+
+// WithImpersonation returns a copy of the context marked as impersonating
+// the session's snapp user
+func WithImpersonation(ctx context.Context, session *ImpersonationSession) context.Context {
+	return context.WithValue(ctx, impersonationKey{}, session)
+}
+
+// ImpersonationFromContext returns the impersonation session of the
+// context's request, if any
+func ImpersonationFromContext(ctx context.Context) (*ImpersonationSession, bool) {
+	session, ok := ctx.Value(impersonationKey{}).(*ImpersonationSession)
+	return session, ok
+}
+
+// ImpersonationAuditEntry records a request made while impersonating,
+// whether it went through or was blocked
+type ImpersonationAuditEntry struct {
+	ID          int64     `json:"id"`
+	SessionID   int64     `json:"sessionId"`
+	AdminID     int64     `json:"adminId"`
+	SnappUserID int64     `json:"snappUserId"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`            // With the query string
+	Route       string    `json:"route,omitempty"` // The matched route pattern
+	Status      int       `json:"status"`
+	Blocked     bool      `json:"blocked"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (e *ImpersonationAuditEntry) TableName() string {
+	return "impersonation_audit_log"
+}
+
+// Create stores the entry
+func (e *ImpersonationAuditEntry) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO impersonation_audit_log
+			(session_id, admin_id, snapp_user_id, method, path, route, status, blocked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		e.SessionID, e.AdminID, e.SnappUserID, e.Method, e.Path, e.Route, e.Status, e.Blocked,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetImpersonationAudit returns the requests made in the session, oldest
+// first
+func GetImpersonationAudit(ctx context.Context, sessionID int64) ([]ImpersonationAuditEntry, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, session_id, admin_id, snapp_user_id, method, path, COALESCE(route, ''), status, blocked, created_at
+		FROM impersonation_audit_log
+		WHERE session_id = $1
+		ORDER BY created_at, id`,
+		sessionID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]ImpersonationAuditEntry, 0)
+	for rows.Next() {
+		var entry ImpersonationAuditEntry
+		err := rows.Scan(&entry.ID, &entry.SessionID, &entry.AdminID, &entry.SnappUserID, &entry.Method,
+			&entry.Path, &entry.Route, &entry.Status, &entry.Blocked, &entry.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// KioskDevice is an event kiosk an administrator issued a credential to,
+// letting it open anonymous voting sessions in one campaign. Only the hash
+// of the credential is stored.
+type KioskDevice struct {
+	ID         int64      `json:"id"`
+	CampaignID int64      `json:"campaignId"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	CreatedBy  int64      `json:"createdBy,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (k *KioskDevice) TableName() string {
+	return "kiosk_devices"
+}
+
+// Create stores the kiosk
+func (k *KioskDevice) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO kiosk_devices (campaign_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+		RETURNING id, created_at`,
+		k.CampaignID, k.Name, k.TokenHash, k.CreatedBy,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetKioskDevice returns the unrevoked kiosk of the campaign holding the
+// credential hash, sql.ErrNoRows when there is none
+func GetKioskDevice(ctx context.Context, campaignID int64, tokenHash string) (*KioskDevice, error) {
+	var createdBy sql.NullInt64
+	kiosk := &KioskDevice{CampaignID: campaignID, TokenHash: tokenHash}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT id, name, created_by, created_at FROM kiosk_devices
+		WHERE campaign_id = $1 AND token_hash = $2 AND revoked_at IS NULL`,
+		campaignID, tokenHash,
+	).Scan(&kiosk.ID, &kiosk.Name, &createdBy, &kiosk.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	kiosk.CreatedBy = createdBy.Int64
+	return kiosk, nil
+}
+
+// GetCampaignKioskDevices returns the kiosks of the campaign, newest first
+func GetCampaignKioskDevices(ctx context.Context, campaignID int64) ([]KioskDevice, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, name, created_by, revoked_at, created_at FROM kiosk_devices
+		WHERE campaign_id = $1
+		ORDER BY created_at DESC, id DESC`,
+		campaignID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	kiosks := make([]KioskDevice, 0)
+	for rows.Next() {
+		var createdBy sql.NullInt64
+		var revokedAt sql.NullTime
+		kiosk := KioskDevice{CampaignID: campaignID}
+		if err := rows.Scan(&kiosk.ID, &kiosk.Name, &createdBy, &revokedAt, &kiosk.CreatedAt); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		kiosk.CreatedBy = createdBy.Int64
+		if revokedAt.Valid {
+			kiosk.RevokedAt = &revokedAt.Time
+		}
+		kiosks = append(kiosks, kiosk)
+	}
+	return kiosks, rows.Err()
+}
+
+// RevokeKioskDevice revokes the credential of the campaign's kiosk, its
+// open sessions can't vote anymore. Returns false when the campaign has no
+// such kiosk.
+func RevokeKioskDevice(ctx context.Context, campaignID, kioskID int64) (bool, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		UPDATE kiosk_devices SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND campaign_id = $2`,
+		kioskID, campaignID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
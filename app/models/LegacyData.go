@@ -0,0 +1,320 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// LegacyImport holds rows of the legacy voting tables to save. Rows with an
+// ID replace the stored row or are created with that ID, rows without one
+// are created.
+type LegacyImport struct {
+	Mentors      []Mentor
+	Participants []Participant
+	Votings      []Voting
+}
+
+// GetLegacyMentors returns all mentors by ID
+func GetLegacyMentors(ctx context.Context) ([]Mentor, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, "SELECT id, name, COALESCE(photo, '') FROM mentors ORDER BY id")
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentors := make([]Mentor, 0)
+	for rows.Next() {
+		var mentor Mentor
+		if err := rows.Scan(&mentor.Id, &mentor.Name, &mentor.Photo); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		mentors = append(mentors, mentor)
+	}
+	return mentors, nil
+}
+
+// GetLegacyParticipants returns all participants, active or not, by ID
+func GetLegacyParticipants(ctx context.Context) ([]Participant, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, name, COALESCE(code, ''), COALESCE(photo, ''), COALESCE(is_active, true), COALESCE(mentor_id, 0)
+		FROM participants
+		ORDER BY id`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := make([]Participant, 0)
+	for rows.Next() {
+		var participant Participant
+		err := rows.Scan(&participant.Id, &participant.Name, &participant.Code, &participant.Photo,
+			&participant.IsActive, &participant.MentorId)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		participants = append(participants, participant)
+	}
+	return participants, nil
+}
+
+// GetLegacyVotings returns all votings by ID
+func GetLegacyVotings(ctx context.Context) ([]Voting, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, name, COALESCE(description, ''), COALESCE(winner_id, 0), started_at, ended_at
+		FROM voting
+		ORDER BY id`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	votings := make([]Voting, 0)
+	for rows.Next() {
+		var voting Voting
+		err := rows.Scan(&voting.Id, &voting.Name, &voting.Description, &voting.WinnerId,
+			&voting.StartedAt, &voting.EndedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		votings = append(votings, voting)
+	}
+	return votings, nil
+}
+
+// GetEndedLegacyVotings returns the votings that ended before the given
+// time, the most recent first
+func GetEndedLegacyVotings(ctx context.Context, before time.Time) ([]Voting, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, name, COALESCE(description, ''), COALESCE(winner_id, 0), started_at, ended_at
+		FROM voting
+		WHERE ended_at <= $1
+		ORDER BY ended_at DESC, id DESC`,
+		before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	votings := make([]Voting, 0)
+	for rows.Next() {
+		var voting Voting
+		err := rows.Scan(&voting.Id, &voting.Name, &voting.Description, &voting.WinnerId,
+			&voting.StartedAt, &voting.EndedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		votings = append(votings, voting)
+	}
+	return votings, rows.Err()
+}
+
+// GetLegacyVoteCounts returns the number of votes of each participant by
+// voting ID and participant ID
+func GetLegacyVoteCounts(ctx context.Context, votingIDs []int64) (map[int64]map[int64]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT voting_id, vote_id, COUNT(id)
+		FROM user_voting
+		WHERE voting_id = ANY($1)
+		GROUP BY voting_id, vote_id`,
+		pq.Array(votingIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]map[int64]int64)
+	for rows.Next() {
+		var votingID, participantID, votes int64
+		if err := rows.Scan(&votingID, &participantID, &votes); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if counts[votingID] == nil {
+			counts[votingID] = make(map[int64]int64)
+		}
+		counts[votingID][participantID] = votes
+	}
+	return counts, rows.Err()
+}
+
+// GetExistingLegacyIDs returns which of the IDs exist in the legacy table
+func GetExistingLegacyIDs(ctx context.Context, table string, ids []int64) (map[int64]bool, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		fmt.Sprintf("SELECT id FROM %s WHERE id = ANY($1)", pq.QuoteIdentifier(table)),
+		pq.Array(ids),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[int64]bool, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// Apply saves the rows in one transaction and counts the created and
+// updated ones. A dry run rolls the transaction back, so the counts and any
+// database errors are reported without changing anything.
+func (li *LegacyImport) Apply(ctx context.Context, dryRun bool) (created int, updated int, err error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	count := func(inserted bool) {
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	mentorRows := make([]legacyRow, len(li.Mentors))
+	for i := range li.Mentors {
+		mentor := &li.Mentors[i]
+		mentorRows[i] = legacyRow{id: &mentor.Id, values: []interface{}{mentor.Name, nullIfEmpty(mentor.Photo)}}
+	}
+	participantRows := make([]legacyRow, len(li.Participants))
+	for i := range li.Participants {
+		participant := &li.Participants[i]
+		participantRows[i] = legacyRow{id: &participant.Id, values: []interface{}{
+			participant.Name, nullIfEmpty(participant.Code), nullIfEmpty(participant.Photo),
+			participant.IsActive, nullIfZero(participant.MentorId),
+		}}
+	}
+	votingRows := make([]legacyRow, len(li.Votings))
+	for i := range li.Votings {
+		voting := &li.Votings[i]
+		votingRows[i] = legacyRow{id: &voting.Id, values: []interface{}{
+			voting.Name, nullIfEmpty(voting.Description), nullIfZero(voting.WinnerId),
+			voting.StartedAt, voting.EndedAt,
+		}}
+	}
+
+	tables := []struct {
+		name    string
+		columns []string
+		rows    []legacyRow
+	}{
+		{"mentors", []string{"name", "photo"}, mentorRows},
+		{"participants", []string{"name", "code", "photo", "is_active", "mentor_id"}, participantRows},
+		{"voting", []string{"name", "description", "winner_id", "started_at", "ended_at"}, votingRows},
+	}
+	for _, table := range tables {
+		if len(table.rows) == 0 {
+			continue
+		}
+		if err := saveLegacyRows(ctx, tx, table.name, table.columns, table.rows, count); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if dryRun {
+		return created, updated, nil
+	}
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+// legacyRow is a row to save, its ID is set once created
+type legacyRow struct {
+	id     *int64
+	values []interface{}
+}
+
+// saveLegacyRows saves the rows with IDs first, moves the table's sequence
+// past them, then creates the rows without IDs
+func saveLegacyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows []legacyRow, count func(inserted bool)) error {
+	placeholders := make([]string, len(columns)+1)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	upsert := fmt.Sprintf(`
+		INSERT INTO %s (id, %s) VALUES (%s)
+		ON CONFLICT (id) DO UPDATE SET %s
+		RETURNING (xmax = 0)`,
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id",
+		table, strings.Join(columns, ", "), strings.Join(placeholders[:len(columns)], ", "))
+
+	for _, row := range rows {
+		if *row.id == 0 {
+			continue
+		}
+		var inserted bool
+		args := append([]interface{}{*row.id}, row.values...)
+		if err := tx.QueryRowContext(ctx, upsert, args...).Scan(&inserted); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		count(inserted)
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", table))
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	for _, row := range rows {
+		if *row.id != 0 {
+			continue
+		}
+		if err := tx.QueryRowContext(ctx, insert, row.values...).Scan(row.id); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		count(true)
+	}
+	return nil
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func nullIfZero(value int64) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	databases "voting-app/app"
@@ -17,9 +18,9 @@ type Mentor struct {
 func (c *Mentor) TableName() string {
 	return "mentors"
 }
-func (c *Mentor) All() []Mentor {
+func (c *Mentor) All(ctx context.Context) []Mentor {
 	query := fmt.Sprintf("SELECT id,name,photo FROM %s", c.TableName())
-	rows, err := databases.PostgresDB.Query(query)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil
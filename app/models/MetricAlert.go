@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Daily platform metrics watched for anomalies
+const (
+	MetricReviews = "reviews"
+	MetricVotes   = "votes"
+	MetricSignups = "signups"
+)
+
+// Metrics lists the metrics watched for anomalies
+var Metrics = []string{MetricReviews, MetricVotes, MetricSignups}
+
+// Directions of a metric alert
+const (
+	MetricSpike = "spike"
+	MetricDrop  = "drop"
+)
+
+// metricSources select the daily totals of each metric between the days $1
+// and $2 as (day, total)
+var metricSources = map[string]string{
+	MetricReviews: `SELECT period_start, new_reviews FROM platform_stats_rollups
+		WHERE period = 'day' AND period_start BETWEEN $1 AND $2`,
+	MetricSignups: `SELECT period_start, new_users FROM platform_stats_rollups
+		WHERE period = 'day' AND period_start BETWEEN $1 AND $2`,
+	MetricVotes: `SELECT date_trunc('day', created_at), SUM(vote_count) FROM campaign_votes
+		WHERE created_at >= $1 AND created_at < $2::date + 1
+		GROUP BY 1`,
+}
+
+// MetricAlert records a day on which a platform metric deviated sharply
+// from the days before it
+type MetricAlert struct {
+	ID        int64     `json:"id"`
+	Metric    string    `json:"metric"`
+	Day       time.Time `json:"day"`
+	Value     float64   `json:"value"`
+	Mean      float64   `json:"mean"`   // Of the baseline days
+	StdDev    float64   `json:"stdDev"` // Of the baseline days
+	ZScore    float64   `json:"zScore"`
+	Direction string    `json:"direction"` // spike or drop
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (a *MetricAlert) TableName() string {
+	return "metric_alerts"
+}
+
+// Create stores the alert unless the metric already has one for the day,
+// reporting whether it was stored
+func (a *MetricAlert) Create(ctx context.Context) (bool, error) {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO metric_alerts (metric, day, value, mean, std_dev, z_score, direction)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (metric, day) DO NOTHING
+		RETURNING id, created_at`,
+		a.Metric, a.Day, a.Value, a.Mean, a.StdDev, a.ZScore, a.Direction,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetMetricAlerts returns the alerts of the days since the given one, latest
+// first, of one metric or all when metric is empty
+func GetMetricAlerts(ctx context.Context, since time.Time, metric string) ([]MetricAlert, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, metric, day, value, mean, std_dev, z_score, direction, created_at
+		FROM metric_alerts
+		WHERE day >= $1 AND ($2 = '' OR metric = $2)
+		ORDER BY day DESC, metric`,
+		since, metric)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]MetricAlert, 0)
+	for rows.Next() {
+		var alert MetricAlert
+		err := rows.Scan(&alert.ID, &alert.Metric, &alert.Day, &alert.Value, &alert.Mean,
+			&alert.StdDev, &alert.ZScore, &alert.Direction, &alert.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// GetDailyMetric returns the metric's total of every day from the first to
+// the last day, days without activity as zero
+func GetDailyMetric(ctx context.Context, metric string, from, to time.Time) ([]float64, error) {
+	source, exists := metricSources[metric]
+	if !exists {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(m.total, 0)
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d(day)
+		LEFT JOIN (%s) AS m(day, total) ON m.day = d.day
+		ORDER BY d.day`, source),
+		from, to)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make([]float64, 0)
+	for rows.Next() {
+		var total float64
+		if err := rows.Scan(&total); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, nil
+}
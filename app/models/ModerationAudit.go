@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Kinds of content moderators act on
+const (
+	AuditTargetReview = "review"
+)
+
+// ModerationAuditEntry records a moderator's action on a piece of content
+// with the state it changed from and to
+type ModerationAuditEntry struct {
+	ID          int64     `json:"id"`
+	ModeratorID int64     `json:"moderatorId"`
+	Action      string    `json:"action"`
+	TargetType  string    `json:"targetType"`
+	TargetID    int64     `json:"targetId"`
+	FromState   string    `json:"fromState"`
+	ToState     string    `json:"toState"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (e *ModerationAuditEntry) TableName() string {
+	return "moderation_audit_log"
+}
+
+// createAuditEntries records the entries with the changes they audit
+func createAuditEntries(ctx context.Context, tx *sql.Tx, entries []ModerationAuditEntry) error {
+	statement, err := tx.PrepareContext(ctx, `
+		INSERT INTO moderation_audit_log (moderator_id, action, target_type, target_id, from_state, to_state)
+		VALUES ($1, $2, $3, $4, $5, $6)`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer statement.Close()
+
+	for _, entry := range entries {
+		_, err := statement.ExecContext(ctx, entry.ModeratorID, entry.Action,
+			entry.TargetType, entry.TargetID, entry.FromState, entry.ToState)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAuditEntries returns the moderation history of a piece of content,
+// oldest first
+func GetAuditEntries(ctx context.Context, targetType string, targetID int64) ([]ModerationAuditEntry, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, moderator_id, action, target_type, target_id, from_state, to_state, created_at
+		FROM moderation_audit_log
+		WHERE target_type = $1 AND target_id = $2
+		ORDER BY created_at, id`,
+		targetType, targetID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]ModerationAuditEntry, 0)
+	for rows.Next() {
+		var entry ModerationAuditEntry
+		err := rows.Scan(&entry.ID, &entry.ModeratorID, &entry.Action, &entry.TargetType,
+			&entry.TargetID, &entry.FromState, &entry.ToState, &entry.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ModerationThroughputDays is the window moderator throughput is counted over
+const ModerationThroughputDays = 7
+
+// ModerationQueue is the size of a moderation queue
+type ModerationQueue struct {
+	// pending_reviews, reported_reviews, pending_venue_claims, flagged_campaign_votes
+	Name             string     `json:"name"`
+	Count            int        `json:"count"`
+	OldestItemAt     *time.Time `json:"oldestItemAt,omitempty"`
+	OldestAgeSeconds int64      `json:"oldestAgeSeconds"`
+}
+
+// ModeratorThroughput counts the items a moderator resolved per queue
+type ModeratorThroughput struct {
+	ModeratorID   int64  `json:"moderatorId"`
+	Email         string `json:"email,omitempty"`
+	Reviews       int    `json:"reviews"`
+	VenueClaims   int    `json:"venueClaims"`
+	CampaignVotes int    `json:"campaignVotes"`
+	Total         int    `json:"total"`
+}
+
+// ModerationOverview sizes the moderation work of the platform
+type ModerationOverview struct {
+	Queues      []ModerationQueue     `json:"queues"`
+	Throughput  []ModeratorThroughput `json:"throughput"`
+	WindowDays  int                   `json:"windowDays"`
+	GeneratedAt time.Time             `json:"generatedAt"`
+}
+
+// GetModerationOverview returns the size and oldest item of every moderation
+// queue and what each moderator resolved in the last week
+func GetModerationOverview(ctx context.Context, now time.Time) (*ModerationOverview, error) {
+	overview := &ModerationOverview{
+		Queues:      make([]ModerationQueue, 0, 4),
+		Throughput:  make([]ModeratorThroughput, 0),
+		WindowDays:  ModerationThroughputDays,
+		GeneratedAt: now,
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT 'pending_reviews', COUNT(*), MIN(created_at)
+		FROM venue_reviews WHERE moderation_status = 'pending'
+		UNION ALL
+		SELECT 'reported_reviews', COUNT(*), MIN(COALESCE(flagged_at, created_at))
+		FROM venue_reviews WHERE is_flagged = true AND moderation_status <> 'rejected'
+		UNION ALL
+		SELECT 'pending_venue_claims', COUNT(*), MIN(created_at)
+		FROM venue_claims WHERE status = 'pending'
+		UNION ALL
+		SELECT 'flagged_campaign_votes', COUNT(*), MIN(COALESCE(flagged_at, created_at))
+		FROM campaign_votes WHERE is_flagged = true AND moderated_at IS NULL`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	for rows.Next() {
+		var queue ModerationQueue
+		var oldest sql.NullTime
+		if err := rows.Scan(&queue.Name, &queue.Count, &oldest); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if oldest.Valid {
+			queue.OldestItemAt = &oldest.Time
+			queue.OldestAgeSeconds = int64(now.Sub(oldest.Time).Seconds())
+		}
+		overview.Queues = append(overview.Queues, queue)
+	}
+	rows.Close()
+
+	rows, err = databases.PostgresDB.QueryContext(ctx, `
+		SELECT m.moderator_id, COALESCE(u.email, ''),
+			   SUM(m.reviews), SUM(m.claims), SUM(m.votes), COUNT(*) AS total
+		FROM (
+			SELECT moderated_by AS moderator_id, 1 AS reviews, 0 AS claims, 0 AS votes
+			FROM venue_reviews WHERE moderated_by IS NOT NULL AND moderated_at >= $1
+			UNION ALL
+			SELECT reviewed_by, 0, 1, 0
+			FROM venue_claims WHERE reviewed_by IS NOT NULL AND reviewed_at >= $1
+			UNION ALL
+			SELECT moderated_by, 0, 0, 1
+			FROM campaign_votes WHERE moderated_by IS NOT NULL AND moderated_at >= $1
+		) m
+		LEFT JOIN users u ON u.id = m.moderator_id
+		GROUP BY m.moderator_id, u.email
+		ORDER BY total DESC, m.moderator_id`,
+		now.AddDate(0, 0, -ModerationThroughputDays),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var throughput ModeratorThroughput
+		err := rows.Scan(
+			&throughput.ModeratorID, &throughput.Email,
+			&throughput.Reviews, &throughput.VenueClaims, &throughput.CampaignVotes, &throughput.Total,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		overview.Throughput = append(overview.Throughput, throughput)
+	}
+
+	return overview, nil
+}
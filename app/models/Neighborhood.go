@@ -0,0 +1,224 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrNeighborhoodExists is returned when the city already has a neighborhood
+// with the same slug
+var ErrNeighborhoodExists = errors.New("city already has this neighborhood")
+
+// ErrInvalidBoundary is returned when a neighborhood's polygon isn't a valid
+// one enclosing an area, like one crossing itself
+var ErrInvalidBoundary = errors.New("neighborhood boundary is not a valid polygon")
+
+// Polygon holds the coordinates of a GeoJSON polygon: the outer ring first,
+// then any holes. Points are [longitude, latitude].
+type Polygon [][][2]float64
+
+// geoJSON returns the polygon as a GeoJSON geometry, closing rings that
+// don't end where they start
+func (p Polygon) geoJSON() ([]byte, error) {
+	rings := make([][][2]float64, len(p))
+	for i, ring := range p {
+		rings[i] = ring
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			rings[i] = append(append([][2]float64{}, ring...), ring[0])
+		}
+	}
+	return json.Marshal(map[string]interface{}{"type": "Polygon", "coordinates": rings})
+}
+
+// scanBoundary reads the polygon back from ST_AsGeoJSON
+func scanBoundary(data []byte, p *Polygon) error {
+	var geometry struct {
+		Coordinates Polygon `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return err
+	}
+	*p = geometry.Coordinates
+	return nil
+}
+
+// Neighborhood is a named area of a city, like "Mission District", outlined
+// by a polygon
+type Neighborhood struct {
+	ID        int64     `json:"id"`
+	CityID    int64     `json:"cityId"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Boundary  Polygon   `json:"boundary,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NeighborhoodFacet counts the search results in a neighborhood
+type NeighborhoodFacet struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	VenueCount int    `json:"venueCount"`
+}
+
+func (n *Neighborhood) TableName() string {
+	return "neighborhoods"
+}
+
+// Create stores the neighborhood, ErrNeighborhoodExists when its slug is
+// taken in the city and ErrInvalidBoundary when its polygon isn't valid
+func (n *Neighborhood) Create(ctx context.Context) error {
+	boundary, err := n.Boundary.geoJSON()
+	if err != nil {
+		return err
+	}
+
+	var valid bool
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT ST_IsValid(g) AND ST_Area(g) > 0
+		FROM ST_GeomFromGeoJSON($1) AS g`,
+		string(boundary),
+	).Scan(&valid)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if !valid {
+		return ErrInvalidBoundary
+	}
+
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO neighborhoods (city_id, name, slug, boundary)
+		VALUES ($1, $2, $3, ST_SetSRID(ST_GeomFromGeoJSON($4), 4326))
+		ON CONFLICT (city_id, slug) DO NOTHING
+		RETURNING id, created_at`,
+		n.CityID, n.Name, n.Slug, string(boundary),
+	).Scan(&n.ID, &n.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrNeighborhoodExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves the neighborhood with its boundary
+func (n *Neighborhood) GetByID(ctx context.Context) error {
+	var boundary []byte
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT id, city_id, name, slug, ST_AsGeoJSON(boundary), created_at
+		FROM neighborhoods
+		WHERE id = $1`,
+		n.ID,
+	).Scan(&n.ID, &n.CityID, &n.Name, &n.Slug, &boundary, &n.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	return scanBoundary(boundary, &n.Boundary)
+}
+
+// FindContainingNeighborhood returns the neighborhood of the city the point
+// lies in, sql.ErrNoRows when it is in none. Where neighborhoods overlap the
+// smallest one wins, as it describes the location best.
+func FindContainingNeighborhood(ctx context.Context, cityID int64, latitude, longitude float64) (*Neighborhood, error) {
+	neighborhood := &Neighborhood{}
+	var boundary []byte
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT id, city_id, name, slug, ST_AsGeoJSON(boundary), created_at
+		FROM neighborhoods
+		WHERE city_id = $1 AND ST_Contains(boundary, ST_SetSRID(ST_Point($3, $2), 4326))
+		ORDER BY ST_Area(boundary), id
+		LIMIT 1`,
+		cityID, latitude, longitude,
+	).Scan(&neighborhood.ID, &neighborhood.CityID, &neighborhood.Name, &neighborhood.Slug, &boundary, &neighborhood.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	if err := scanBoundary(boundary, &neighborhood.Boundary); err != nil {
+		return nil, err
+	}
+	return neighborhood, nil
+}
+
+// PlaceVenuesInNeighborhood moves the venues within the neighborhood into it
+// when it is the smallest neighborhood containing them, including venues
+// placed in a larger neighborhood before. Only the venues of the context's
+// tenant are moved. It returns how many venues moved.
+func PlaceVenuesInNeighborhood(ctx context.Context, neighborhoodID int64) (int, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		WITH placement AS (
+			SELECT v.id, (
+				SELECT n.id
+				FROM neighborhoods n
+				WHERE n.city_id = v.city_id
+					AND ST_Contains(n.boundary, ST_SetSRID(ST_Point(v.longitude, v.latitude), 4326))
+				ORDER BY ST_Area(n.boundary), n.id
+				LIMIT 1
+			) AS neighborhood_id
+			FROM venues v
+			JOIN neighborhoods placed ON placed.id = $1 AND placed.city_id = v.city_id
+			WHERE ST_Contains(placed.boundary, ST_SetSRID(ST_Point(v.longitude, v.latitude), 4326))
+			  AND ($2::bigint IS NULL OR v.tenant_id = $2)
+		)
+		UPDATE venues v SET neighborhood_id = p.neighborhood_id, updated_at = CURRENT_TIMESTAMP
+		FROM placement p
+		WHERE v.id = p.id AND v.neighborhood_id IS DISTINCT FROM p.neighborhood_id`,
+		neighborhoodID, TenantFilter(ctx),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	placed, err := result.RowsAffected()
+	return int(placed), err
+}
+
+// GetNeighborhoodFacets counts the venues matching the search in each
+// neighborhood. The neighborhood filter itself is left out, so the counts
+// show where else the search has results.
+func GetNeighborhoodFacets(ctx context.Context, params VenueSearchParams) ([]NeighborhoodFacet, error) {
+	params.NeighborhoodID = nil
+	whereClause, args := params.whereClause(ctx)
+
+	query := fmt.Sprintf(`
+		SELECT n.id, n.name, n.slug, COUNT(*) AS venue_count
+		FROM venues v
+		JOIN neighborhoods n ON v.neighborhood_id = n.id
+		%s
+		GROUP BY n.id, n.name, n.slug
+		ORDER BY venue_count DESC, n.name`, whereClause)
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := make([]NeighborhoodFacet, 0)
+	for rows.Next() {
+		var facet NeighborhoodFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Slug, &facet.VenueCount); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Notification event types
+const (
+	NotificationCampaignStart   = "campaign_start"
+	NotificationCampaignResults = "campaign_results"
+	NotificationReviewReply     = "review_reply"
+	NotificationBadge           = "badge"
+	NotificationSavedSearch     = "saved_search"
+	NotificationAccountLink     = "account_link"
+	NotificationMetricAlert     = "metric_alert"
+	NotificationConsent         = "consent"
+	NotificationOwnerReview     = "owner_review"
+	NotificationOwnerMilestone  = "owner_milestone"
+	NotificationReviewHelpful   = "review_helpful"
+	NotificationNewFollower     = "new_follower"
+)
+
+// Notification represents an in-app notification delivered to a user
+type Notification struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"userId"`
+	EventType string          `json:"eventType"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	IsRead    bool            `json:"isRead"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func (n *Notification) TableName() string {
+	return "notifications"
+}
+
+// Create stores a new notification
+func (n *Notification) Create(ctx context.Context) error {
+	query := `
+		INSERT INTO notifications (user_id, event_type, title, body, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, n.UserID, n.EventType, n.Title, n.Body, n.Data,
+	).Scan(&n.ID, &n.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetUserNotifications returns the latest notifications of a user
+func GetUserNotifications(ctx context.Context, userID int64, limit int) ([]Notification, error) {
+	query := `
+		SELECT id, user_id, event_type, title, body, data, is_read, created_at
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var notification Notification
+		var body sql.NullString
+
+		err := rows.Scan(
+			&notification.ID, &notification.UserID, &notification.EventType,
+			&notification.Title, &body, &notification.Data,
+			&notification.IsRead, &notification.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		notification.Body = body.String
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// DigestEvent is an event waiting to be notified in a digest, like a helpful
+// vote on a review. Each actor counts once per subject.
+type DigestEvent struct {
+	UserID    int64  // The user notified
+	EventType string // The notification event type
+	SubjectID int64  // What the event is about, like the review, 0 for none
+	ActorID   int64  // Who caused the event
+}
+
+func (e *DigestEvent) TableName() string {
+	return "notification_digest_events"
+}
+
+// Create stores the event unless the actor's is already waiting
+func (e *DigestEvent) Create(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO notification_digest_events (user_id, event_type, subject_id, actor_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, event_type, subject_id, actor_id) DO NOTHING`,
+		e.UserID, e.EventType, e.SubjectID, e.ActorID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Digest groups the waiting events of a user on a subject
+type Digest struct {
+	UserID    int64
+	EventType string
+	SubjectID int64
+	Actors    int
+}
+
+// GetDueDigests returns the digests of the event type whose first event
+// waited since before the time
+func GetDueDigests(ctx context.Context, eventType string, before time.Time) ([]Digest, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT user_id, subject_id, COUNT(*)
+		FROM notification_digest_events
+		WHERE event_type = $1
+		GROUP BY user_id, subject_id
+		HAVING MIN(created_at) <= $2
+		ORDER BY MIN(created_at)`,
+		eventType, before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	digests := []Digest{}
+	for rows.Next() {
+		digest := Digest{EventType: eventType}
+		if err := rows.Scan(&digest.UserID, &digest.SubjectID, &digest.Actors); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// TakeDigestEvents removes the waiting events of the digest and returns how
+// many actors they had, counting the events that arrived since it was found
+func TakeDigestEvents(ctx context.Context, digest Digest) (int, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		DELETE FROM notification_digest_events
+		WHERE user_id = $1 AND event_type = $2 AND subject_id = $3`,
+		digest.UserID, digest.EventType, digest.SubjectID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	actors, err := result.RowsAffected()
+	return int(actors), err
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// DayHours are the hours of a day, as "HH:MM" local to the venue's city.
+// Hours closing before they open run past midnight.
+type DayHours struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// OpeningHours are a venue's hours by lowercase weekday, like
+// {"monday": {"open": "09:00", "close": "22:00"}}. Days left out are closed.
+type OpeningHours map[string]DayHours
+
+// ParseOpeningHours decodes the opening hours stored with a venue
+func ParseOpeningHours(raw json.RawMessage) (OpeningHours, error) {
+	var hours OpeningHours
+	if err := json.Unmarshal(raw, &hours); err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// ClosesAt returns when the venue closes if it is open at the time, which is
+// in the venue's time zone. Hours of the day before that run past midnight
+// count too.
+func (h OpeningHours) ClosesAt(at time.Time) (time.Time, bool) {
+	return Schedule{Hours: h}.ClosesAt(at)
+}
+
+// nextOpenDays is how far ahead NextOpenTime looks
+const nextOpenDays = 14
+
+// Schedule is a venue's regular opening hours together with the dated
+// exceptions that replace them
+type Schedule struct {
+	Hours      OpeningHours
+	Exceptions map[string]HoursException // By date
+}
+
+// NewSchedule combines the regular hours with the exceptions
+func NewSchedule(hours OpeningHours, exceptions []HoursException) Schedule {
+	schedule := Schedule{Hours: hours, Exceptions: make(map[string]HoursException, len(exceptions))}
+	for _, exception := range exceptions {
+		schedule.Exceptions[exception.Date] = exception
+	}
+	return schedule
+}
+
+// ClosesAt returns when the venue closes if it is open at the time, which is
+// in the venue's time zone. Hours of the day before that run past midnight
+// count too.
+func (s Schedule) ClosesAt(at time.Time) (time.Time, bool) {
+	for _, day := range []time.Time{at.AddDate(0, 0, -1), at} {
+		open, close, ok := s.span(day)
+		if ok && !at.Before(open) && at.Before(close) {
+			return close, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextOpenTime returns when the venue opens next after the time, looking up
+// to two weeks ahead
+func (s Schedule) NextOpenTime(at time.Time) (time.Time, bool) {
+	for i := 0; i <= nextOpenDays; i++ {
+		open, _, ok := s.span(at.AddDate(0, 0, i))
+		if ok && open.After(at) {
+			return open, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// span returns when the venue opens and closes on the day, from the day's
+// exception when it has one
+func (s Schedule) span(day time.Time) (time.Time, time.Time, bool) {
+	if exception, exists := s.Exceptions[day.Format(DateLayout)]; exists {
+		if exception.IsClosed {
+			return time.Time{}, time.Time{}, false
+		}
+		return DayHours{Open: exception.Open, Close: exception.Close}.span(day)
+	}
+
+	hours, exists := s.Hours[strings.ToLower(day.Weekday().String())]
+	if !exists {
+		return time.Time{}, time.Time{}, false
+	}
+	return hours.span(day)
+}
+
+// span returns when the hours open and close on the day
+func (h DayHours) span(day time.Time) (time.Time, time.Time, bool) {
+	openHour, openMinute, ok := parseClock(h.Open)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	closeHour, closeMinute, ok := parseClock(h.Close)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	year, month, date := day.Date()
+	open := time.Date(year, month, date, openHour, openMinute, 0, 0, day.Location())
+	close := time.Date(year, month, date, closeHour, closeMinute, 0, 0, day.Location())
+	if !close.After(open) {
+		close = close.AddDate(0, 0, 1)
+	}
+	return open, close, true
+}
+
+// ValidClock reports whether the clock is a time of day like "09:30"
+func ValidClock(clock string) bool {
+	_, _, ok := parseClock(clock)
+	return ok
+}
+
+// parseClock parses "HH:MM", up to "24:00"
+func parseClock(clock string) (int, int, bool) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil {
+		return 0, 0, false
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// OpenAtExpression returns a SQL expression telling whether the venue of
+// the table alias is open at the local time, a timestamp expression. The
+// venue's hours exception of the day replaces its regular opening hours of
+// the form {"monday": {"open": "09:00", "close": "22:00"}}. It is NULL when
+// the venue has neither. Hours closing before they open run past midnight.
+func OpenAtExpression(venue, localTime string) string {
+	day := fmt.Sprintf("lower(to_char(%s, 'FMDay'))", localTime)
+	at := fmt.Sprintf("to_char(%s, 'HH24:MI')", localTime)
+	openTime := fmt.Sprintf("(%s.opening_hours -> %s ->> 'open')", venue, day)
+	closeTime := fmt.Sprintf("(%s.opening_hours -> %s ->> 'close')", venue, day)
+
+	return fmt.Sprintf(`COALESCE(
+				(SELECT NOT e.is_closed AND CASE
+					WHEN e.close_time > e.open_time THEN %[3]s >= e.open_time AND %[3]s < e.close_time
+					ELSE %[3]s >= e.open_time OR %[3]s < e.close_time
+				 END
+				 FROM venue_hours_exceptions e
+				 WHERE e.venue_id = %[4]s.id AND e.date = (%[5]s)::date),
+				CASE
+					WHEN %[1]s IS NULL OR %[2]s IS NULL THEN NULL
+					WHEN %[2]s > %[1]s THEN %[3]s >= %[1]s AND %[3]s < %[2]s
+					ELSE %[3]s >= %[1]s OR %[3]s < %[2]s
+				END)`, openTime, closeTime, at, venue, localTime)
+}
+
+// VenueLocalNow returns a SQL expression of the current time local to the
+// city of the venue of the table alias, UTC for cities without a time zone
+func VenueLocalNow(venue string) string {
+	return fmt.Sprintf(`(CURRENT_TIMESTAMP AT TIME ZONE COALESCE(
+				(SELECT NULLIF(timezone, '') FROM cities WHERE id = %s.city_id), 'UTC'))`, venue)
+}
+
+// GetNearbyVenuesWithHours returns the active venues within the radius in km
+// that have opening hours, with their distance
+func GetNearbyVenuesWithHours(ctx context.Context, lat, lng, radius float64) ([]Venue, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id, v.name, v.slug, COALESCE(v.short_description, ''),
+			   v.address, v.city_id, v.latitude, v.longitude, v.category_id,
+			   COALESCE(v.price_range, ''), v.average_rating, v.total_ratings,
+			   COALESCE(v.cover_image, ''), v.is_featured, v.opening_hours,
+			   ST_Distance(
+				   ST_Point(v.longitude, v.latitude)::geography,
+				   ST_Point($1, $2)::geography
+			   ) / 1000 AS distance
+		FROM venues v
+		WHERE v.is_active = true AND v.opening_hours IS NOT NULL
+		  AND ($4::bigint IS NULL OR v.tenant_id = $4)
+		  AND ST_DWithin(
+			  ST_Point(v.longitude, v.latitude)::geography,
+			  ST_Point($1, $2)::geography,
+			  $3 * 1000)`,
+		lng, lat, radius, TenantFilter(ctx),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var venues []Venue
+	for rows.Next() {
+		var venue Venue
+		var cityID sql.NullInt64
+		var distance float64
+		err := rows.Scan(&venue.ID, &venue.Name, &venue.Slug, &venue.ShortDesc,
+			&venue.Address, &cityID, &venue.Latitude, &venue.Longitude, &venue.CategoryID,
+			&venue.PriceRange, &venue.AverageRating, &venue.TotalRatings,
+			&venue.CoverImage, &venue.IsFeatured, &venue.OpeningHours, &distance)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		venue.CityID = cityID.Int64
+		venue.Distance = &distance
+		venues = append(venues, venue)
+	}
+
+	return venues, nil
+}
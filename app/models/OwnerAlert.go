@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Milestones of claimed venues
+const (
+	VenueMilestoneReviews = "reviews" // Reached a number of approved reviews
+	VenueMilestoneRating  = "rating"  // Reached an average rating
+)
+
+// OwnerAlertVenue is a claimed venue and the owner its alerts go to
+type OwnerAlertVenue struct {
+	OwnerID    int64
+	OwnerEmail string
+	VenueID    int64
+	VenueName  string
+	VenueSlug  string
+}
+
+// OwnerReviewAlert is an approved review the venue's owner wasn't told about
+type OwnerReviewAlert struct {
+	OwnerAlertVenue
+	ReviewID   int64
+	Rating     float64
+	Title      string
+	ApprovedAt time.Time
+}
+
+// OwnerMilestoneAlert is a milestone the venue's owner wasn't told about
+type OwnerMilestoneAlert struct {
+	OwnerAlertVenue
+	Milestone string
+	ReachedAt time.Time
+}
+
+// RecordVenueMilestones records the claimed venues that reached the given
+// number of approved reviews, or the given average rating with at least
+// ratingMinReviews reviews, from their rating cache. Every milestone is
+// recorded once per venue.
+func RecordVenueMilestones(ctx context.Context, reviews int, rating float64, ratingMinReviews int) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO venue_milestones (venue_id, milestone)
+		SELECT id, $1::text FROM venues
+		WHERE owner_id IS NOT NULL AND total_ratings >= $2
+		UNION ALL
+		SELECT id, $3::text FROM venues
+		WHERE owner_id IS NOT NULL AND total_ratings >= $4 AND average_rating >= $5
+		ON CONFLICT (venue_id, milestone) DO NOTHING`,
+		VenueMilestoneReviews, reviews, VenueMilestoneRating, ratingMinReviews, rating)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetPendingOwnerReviews returns the reviews approved since their venue was
+// claimed and since its owner was last told about reviews, leaving out
+// duplicates and owners alerted after sentBefore. They come by owner, oldest
+// first.
+func GetPendingOwnerReviews(ctx context.Context, sentBefore time.Time) ([]OwnerReviewAlert, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.owner_id, u.email, v.id, v.name, v.slug,
+			   r.id, r.overall_rating, COALESCE(r.title, ''), COALESCE(r.moderated_at, r.created_at) AS approved_at
+		FROM venue_reviews r
+		INNER JOIN venues v ON v.id = r.venue_id
+		INNER JOIN users u ON u.id = v.owner_id
+		LEFT JOIN owner_alert_state s ON s.user_id = v.owner_id
+		WHERE r.moderation_status = 'approved' AND r.duplicate_of IS NULL
+		  AND COALESCE(r.moderated_at, r.created_at) > GREATEST(s.reviews_until, v.claimed_at)
+		  AND (s.last_sent_at IS NULL OR s.last_sent_at <= $1)
+		ORDER BY v.owner_id, approved_at, r.id`,
+		sentBefore)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]OwnerReviewAlert, 0)
+	for rows.Next() {
+		var alert OwnerReviewAlert
+		err := rows.Scan(&alert.OwnerID, &alert.OwnerEmail, &alert.VenueID, &alert.VenueName, &alert.VenueSlug,
+			&alert.ReviewID, &alert.Rating, &alert.Title, &alert.ApprovedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// GetPendingOwnerMilestones returns the milestones of claimed venues their
+// owners weren't told about, leaving out owners alerted after sentBefore
+func GetPendingOwnerMilestones(ctx context.Context, sentBefore time.Time) ([]OwnerMilestoneAlert, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.owner_id, u.email, v.id, v.name, v.slug, m.milestone, m.reached_at
+		FROM venue_milestones m
+		INNER JOIN venues v ON v.id = m.venue_id
+		INNER JOIN users u ON u.id = v.owner_id
+		LEFT JOIN owner_alert_state s ON s.user_id = v.owner_id
+		WHERE m.notified_at IS NULL
+		  AND (s.last_sent_at IS NULL OR s.last_sent_at <= $1)
+		ORDER BY v.owner_id, m.reached_at, m.venue_id`,
+		sentBefore)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]OwnerMilestoneAlert, 0)
+	for rows.Next() {
+		var alert OwnerMilestoneAlert
+		err := rows.Scan(&alert.OwnerID, &alert.OwnerEmail, &alert.VenueID, &alert.VenueName, &alert.VenueSlug,
+			&alert.Milestone, &alert.ReachedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// MarkOwnerAlertSent records that the owner was told about the reviews
+// approved until reviewsUntil, when set, and about the milestones
+func MarkOwnerAlertSent(ctx context.Context, ownerID int64, sentAt time.Time, reviewsUntil *time.Time, milestones []OwnerMilestoneAlert) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO owner_alert_state (user_id, reviews_until, last_sent_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			reviews_until = COALESCE(EXCLUDED.reviews_until, owner_alert_state.reviews_until),
+			last_sent_at = EXCLUDED.last_sent_at`,
+		ownerID, reviewsUntil, sentAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	for _, milestone := range milestones {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE venue_milestones SET notified_at = $3
+			WHERE venue_id = $1 AND milestone = $2`,
+			milestone.VenueID, milestone.Milestone, sentAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
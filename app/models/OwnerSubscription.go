@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Venue owner tiers
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+)
+
+// OwnerSubscription is the tier a venue owner pays for. Owners without one,
+// or whose subscription expired, are on the free tier.
+type OwnerSubscription struct {
+	UserID    int64      `json:"userId"`
+	Tier      string     `json:"tier"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Never when unset
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+func (s *OwnerSubscription) TableName() string {
+	return "owner_subscriptions"
+}
+
+// GetOwnerSubscription returns the owner's current subscription, the free
+// tier when they have none running at now
+func GetOwnerSubscription(ctx context.Context, userID int64, now time.Time) (*OwnerSubscription, error) {
+	subscription := &OwnerSubscription{UserID: userID}
+	var expiresAt, updatedAt sql.NullTime
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT tier, expires_at, updated_at
+		FROM owner_subscriptions
+		WHERE user_id = $1`,
+		userID).Scan(&subscription.Tier, &expiresAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		subscription.Tier = TierFree
+		return subscription, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	if updatedAt.Valid {
+		subscription.UpdatedAt = &updatedAt.Time
+	}
+	if expiresAt.Valid {
+		if !expiresAt.Time.After(now) {
+			subscription.Tier = TierFree
+			return subscription, nil
+		}
+		subscription.ExpiresAt = &expiresAt.Time
+	}
+	return subscription, nil
+}
+
+// Save sets the owner's tier until ExpiresAt, sql.ErrNoRows when there is
+// no such user
+func (s *OwnerSubscription) Save(ctx context.Context) error {
+	var updatedAt time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO owner_subscriptions (user_id, tier, expires_at, updated_at)
+		SELECT id, $2, $3, CURRENT_TIMESTAMP FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET
+			tier = EXCLUDED.tier, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		s.UserID, s.Tier, s.ExpiresAt,
+	).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return err
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	s.UpdatedAt = &updatedAt
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"github.com/getsentry/sentry-go"
 	databases "voting-app/app"
@@ -19,10 +20,10 @@ type Participant struct {
 func (c *Participant) TableName() string {
 	return "participants"
 }
-func (c *Participant) IsValid() bool {
+func (c *Participant) IsValid(ctx context.Context) bool {
 	var total int64
 	query := fmt.Sprintf("SELECT COUNT(id) FROM %s WHERE id = $1 and is_active = true", c.TableName())
-	err := databases.PostgresDB.QueryRow(query, c.Id).Scan(&total)
+	err := databases.PostgresDB.QueryRowContext(ctx, query, c.Id).Scan(&total)
 	if err != nil || total == 0 {
 		if err != nil {
 			print(err.Error())
@@ -32,9 +33,9 @@ func (c *Participant) IsValid() bool {
 	return true
 }
 
-func (c *Participant) All() []Participant {
+func (c *Participant) All(ctx context.Context) []Participant {
 	query := fmt.Sprintf("SELECT id,name,photo,code,is_active,mentor_id FROM %s", c.TableName())
-	rows, err := databases.PostgresDB.Query(query)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil
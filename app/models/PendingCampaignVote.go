@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// MaxVoteCodeAttempts caps the codes tried against one pending vote
+const MaxVoteCodeAttempts = 5
+
+// VoteCodeResendInterval is the least time between two codes sent for the
+// votes of a voter in a campaign
+const VoteCodeResendInterval = time.Minute
+
+// ErrInvalidVoteCode is returned when the code of a pending vote is wrong,
+// expired or was tried too often
+var ErrInvalidVoteCode = errors.New("vote verification code is invalid or expired")
+
+// ErrVoteCodeResendTooSoon is returned when a vote is held again before
+// VoteCodeResendInterval passed since the last code
+var ErrVoteCodeResendTooSoon = errors.New("vote verification code was sent too recently")
+
+// PendingCampaignVote is a vote of a campaign requiring verification, held
+// until the voter confirms it with the code emailed to them. Voters have one
+// pending vote per campaign, holding another replaces it.
+type PendingCampaignVote struct {
+	CampaignID int64
+	UserID     int64
+	Vote       json.RawMessage // The vote request, cast again once confirmed
+	CodeHash   string
+	ExpiresAt  time.Time
+}
+
+func (v *PendingCampaignVote) TableName() string {
+	return "pending_campaign_votes"
+}
+
+// Create stores the pending vote, replacing an earlier one of the voter.
+// Replacing one held less than VoteCodeResendInterval ago returns
+// ErrVoteCodeResendTooSoon, so voters can't have codes emailed over and over
+// and start their attempts afresh with each.
+func (v *PendingCampaignVote) Create(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO pending_campaign_votes (campaign_id, user_id, vote, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (campaign_id, user_id) DO UPDATE
+		SET vote = EXCLUDED.vote, code_hash = EXCLUDED.code_hash,
+			expires_at = EXCLUDED.expires_at, attempts = 0, created_at = CURRENT_TIMESTAMP
+		WHERE pending_campaign_votes.created_at <= CURRENT_TIMESTAMP - $6 * INTERVAL '1 second'`,
+		v.CampaignID, v.UserID, []byte(v.Vote), v.CodeHash, v.ExpiresAt, VoteCodeResendInterval.Seconds())
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrVoteCodeResendTooSoon
+	}
+	return nil
+}
+
+// ConfirmPendingCampaignVote returns the held vote request when the code
+// matches, removing it. Every wrong code counts as an attempt.
+func ConfirmPendingCampaignVote(ctx context.Context, campaignID, userID int64, codeHash string, now time.Time) (json.RawMessage, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var vote []byte
+	var storedHash string
+	var attempts int
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT vote, code_hash, attempts, expires_at
+		FROM pending_campaign_votes
+		WHERE campaign_id = $1 AND user_id = $2
+		FOR UPDATE`,
+		campaignID, userID).Scan(&vote, &storedHash, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidVoteCode
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	if attempts >= MaxVoteCodeAttempts || !now.Before(expiresAt) {
+		return nil, ErrInvalidVoteCode
+	}
+
+	if storedHash != codeHash {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE pending_campaign_votes SET attempts = attempts + 1
+			WHERE campaign_id = $1 AND user_id = $2`,
+			campaignID, userID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		return nil, ErrInvalidVoteCode
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM pending_campaign_votes WHERE campaign_id = $1 AND user_id = $2", campaignID, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return vote, nil
+}
+
+// GetVerifiedEmailBySnappUser returns the verified email of the account
+// linked to the snapp user, sql.ErrNoRows when there is none
+func GetVerifiedEmailBySnappUser(ctx context.Context, snappUserID int64) (string, error) {
+	var email string
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT u.email
+		FROM account_links l
+		INNER JOIN users u ON u.id = l.user_id
+		WHERE l.snapp_user_id = $1 AND u.email_verified_at IS NOT NULL`,
+		snappUserID).Scan(&email)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return email, err
+}
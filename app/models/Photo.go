@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Photo is an uploaded photo with the metadata clients need to lay it out
+// before it loads
+type Photo struct {
+	ID            int64     `json:"-"`
+	UserID        int64     `json:"-"`
+	URL           string    `json:"url"`
+	ObjectKey     string    `json:"-"`
+	ContentType   string    `json:"contentType,omitempty"`
+	Width         int       `json:"width,omitempty"`
+	Height        int       `json:"height,omitempty"`
+	DominantColor string    `json:"dominantColor,omitempty"` // #rrggbb
+	SizeBytes     int64     `json:"size,omitempty"`
+	CreatedAt     time.Time `json:"-"`
+}
+
+func (p *Photo) TableName() string {
+	return "photos"
+}
+
+// Create stores the photo metadata
+func (p *Photo) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO photos (user_id, url, object_key, content_type, width, height, dominant_color, size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		p.UserID, p.URL, p.ObjectKey, p.ContentType, p.Width, p.Height, p.DominantColor, p.SizeBytes,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetPhotosByURLs returns the metadata of the uploaded photos among the URLs
+func GetPhotosByURLs(ctx context.Context, urls []string) (map[string]Photo, error) {
+	photos := make(map[string]Photo, len(urls))
+	if len(urls) == 0 {
+		return photos, nil
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, user_id, url, object_key, content_type, width, height, dominant_color, size_bytes, created_at
+		FROM photos
+		WHERE url = ANY($1)`,
+		pq.Array(urls),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var photo Photo
+		err := rows.Scan(&photo.ID, &photo.UserID, &photo.URL, &photo.ObjectKey, &photo.ContentType,
+			&photo.Width, &photo.Height, &photo.DominantColor, &photo.SizeBytes, &photo.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		photos[photo.URL] = photo
+	}
+
+	return photos, nil
+}
+
+// AttachPhotoDetails sets the PhotoDetails of the reviews, one entry per
+// photo URL in order. Photos that weren't uploaded here, like external
+// links, only have their URL.
+func AttachPhotoDetails(ctx context.Context, reviews []VenueReview) {
+	urlsByReview := make([][]string, len(reviews))
+	var urls []string
+	for i, review := range reviews {
+		urlsByReview[i] = review.Photos.URLs()
+		urls = append(urls, urlsByReview[i]...)
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	photos, err := GetPhotosByURLs(ctx, urls)
+	if err != nil {
+		return
+	}
+
+	for i, reviewURLs := range urlsByReview {
+		if len(reviewURLs) == 0 {
+			continue
+		}
+		reviews[i].PhotoDetails = make([]Photo, len(reviewURLs))
+		for j, url := range reviewURLs {
+			photo, exists := photos[url]
+			if !exists {
+				photo = Photo{URL: url}
+			}
+			reviews[i].PhotoDetails[j] = photo
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// RatingDimension is an aspect reviewers rate a venue on, like "food" for
+// restaurants or "music" for bars
+type RatingDimension struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// RatingTemplate lists the detailed rating dimensions of the venues of a
+// category. Reviews of venues in categories without a template can rate
+// any dimension.
+type RatingTemplate struct {
+	CategoryID int64             `json:"categoryId"`
+	Dimensions []RatingDimension `json:"dimensions"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+func (t *RatingTemplate) TableName() string {
+	return "rating_templates"
+}
+
+// HasDimension reports whether the template has the dimension
+func (t *RatingTemplate) HasDimension(key string) bool {
+	for _, dimension := range t.Dimensions {
+		if dimension.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Save creates or replaces the template of the category, sql.ErrNoRows when
+// the category doesn't exist
+func (t *RatingTemplate) Save(ctx context.Context) error {
+	dimensions, err := json.Marshal(t.Dimensions)
+	if err != nil {
+		return err
+	}
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO rating_templates (category_id, dimensions)
+		SELECT id, $2 FROM venue_categories WHERE id = $1
+		ON CONFLICT (category_id) DO UPDATE SET
+			dimensions = EXCLUDED.dimensions,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`,
+		t.CategoryID, dimensions,
+	).Scan(&t.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// DeleteRatingTemplate removes the template of the category, reporting
+// whether it had one
+func DeleteRatingTemplate(ctx context.Context, categoryID int64) (bool, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM rating_templates WHERE category_id = $1", categoryID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// GetRatingTemplates returns the templates of every category that has one
+func GetRatingTemplates(ctx context.Context) ([]RatingTemplate, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		"SELECT category_id, dimensions, updated_at FROM rating_templates ORDER BY category_id")
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]RatingTemplate, 0)
+	for rows.Next() {
+		template, err := scanRatingTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	return templates, nil
+}
+
+// GetCategoryRatingTemplate returns the template of the category, nil when
+// it has none
+func GetCategoryRatingTemplate(ctx context.Context, categoryID int64) (*RatingTemplate, error) {
+	template, err := scanRatingTemplate(databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT category_id, dimensions, updated_at FROM rating_templates WHERE category_id = $1",
+		categoryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return template, err
+}
+
+// GetVenueRatingTemplate returns the template of the venue's category, nil
+// when it has none or the venue doesn't exist
+func GetVenueRatingTemplate(ctx context.Context, venueID int64) (*RatingTemplate, error) {
+	template, err := scanRatingTemplate(databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT t.category_id, t.dimensions, t.updated_at
+		FROM venues v
+		INNER JOIN rating_templates t ON t.category_id = v.category_id
+		WHERE v.id = $1`,
+		venueID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return template, err
+}
+
+type ratingTemplateScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRatingTemplate(row ratingTemplateScanner) (*RatingTemplate, error) {
+	var template RatingTemplate
+	var dimensions []byte
+	err := row.Scan(&template.CategoryID, &dimensions, &template.UpdatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(dimensions, &template.Dimensions); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return &template, nil
+}
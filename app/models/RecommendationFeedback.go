@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Recommendation feedback kinds
+const (
+	RecommendationNotInterested = "not_interested"
+	RecommendationBeenThere     = "been_there"
+)
+
+// RecommendationFeedback is a user's answer to a venue recommendation. The
+// venue isn't recommended to the user again either way.
+type RecommendationFeedback struct {
+	UserID   int64  `json:"userId"`
+	VenueID  int64  `json:"venueId"`
+	Feedback string `json:"feedback"` // not_interested, been_there
+	// Signals are the scoring signals the venue was recommended for, used to
+	// tune their weights
+	Signals   []string  `json:"signals"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SignalFeedback sums up the feedback on recommendations made for a signal
+type SignalFeedback struct {
+	Signal        string
+	NotInterested int
+	BeenThere     int
+}
+
+func (f *RecommendationFeedback) TableName() string {
+	return "recommendation_feedback"
+}
+
+// Save stores the feedback, replacing the user's earlier feedback on the venue
+func (f *RecommendationFeedback) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO recommendation_feedback (user_id, venue_id, feedback, signals)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, venue_id) DO UPDATE
+		SET feedback = EXCLUDED.feedback, signals = EXCLUDED.signals, created_at = CURRENT_TIMESTAMP
+		RETURNING created_at`,
+		f.UserID, f.VenueID, f.Feedback, pq.Array(f.Signals),
+	).Scan(&f.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetSignalFeedback counts the feedback given since the time per signal
+func GetSignalFeedback(ctx context.Context, since time.Time) ([]SignalFeedback, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT signal,
+			COUNT(*) FILTER (WHERE feedback = $2),
+			COUNT(*) FILTER (WHERE feedback = $3)
+		FROM recommendation_feedback, UNNEST(signals) AS signal
+		WHERE created_at >= $1
+		GROUP BY signal`,
+		since, RecommendationNotInterested, RecommendationBeenThere,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feedback []SignalFeedback
+	for rows.Next() {
+		var signal SignalFeedback
+		if err := rows.Scan(&signal.Signal, &signal.NotInterested, &signal.BeenThere); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		feedback = append(feedback, signal)
+	}
+
+	return feedback, nil
+}
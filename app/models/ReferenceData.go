@@ -0,0 +1,82 @@
+package models
+
+// ReferenceOption is a value of an enumeration with the label clients show
+type ReferenceOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// ReferenceEnum is the set of values a field accepts, in display order. The
+// enums below are the single source of truth of the validators and of
+// GET /v1/utils/reference.
+type ReferenceEnum []ReferenceOption
+
+// Values returns the values of the enum, in order
+func (e ReferenceEnum) Values() []string {
+	values := make([]string, len(e))
+	for i, option := range e {
+		values[i] = option.Value
+	}
+	return values
+}
+
+// Contains reports whether the value belongs to the enum
+func (e ReferenceEnum) Contains(value string) bool {
+	for _, option := range e {
+		if option.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Campaign types of voting campaigns
+const (
+	CampaignTypeBestRestaurant = "best_restaurant"
+	CampaignTypeTopBars        = "top_bars"
+	CampaignTypeHiddenGems     = "hidden_gems"
+	CampaignTypeBestOf         = "best_of" // Generated from a city's best rated venues of a category
+)
+
+// VisitTypes are the kinds of visit a review describes
+var VisitTypes = ReferenceEnum{
+	{Value: "breakfast", Label: "Breakfast"},
+	{Value: "lunch", Label: "Lunch"},
+	{Value: "dinner", Label: "Dinner"},
+	{Value: "drinks", Label: "Drinks"},
+	{Value: "coffee", Label: "Coffee"},
+	{Value: "event", Label: "Event"},
+	{Value: "takeout", Label: "Takeout"},
+}
+
+// PriceRanges are the venue price ranges, cheapest first
+var PriceRanges = ReferenceEnum{
+	{Value: "$", Label: "Inexpensive"},
+	{Value: "$$", Label: "Moderate"},
+	{Value: "$$$", Label: "Expensive"},
+	{Value: "$$$$", Label: "Very expensive"},
+}
+
+// Amenities are the amenity keys venues list
+var Amenities = ReferenceEnum{
+	{Value: "wifi", Label: "Wi-Fi"},
+	{Value: "outdoor_seating", Label: "Outdoor seating"},
+	{Value: "parking", Label: "Parking"},
+	{Value: "wheelchair_accessible", Label: "Wheelchair accessible"},
+	{Value: "reservations", Label: "Takes reservations"},
+	{Value: "delivery", Label: "Delivery"},
+	{Value: "takeout", Label: "Takeout"},
+	{Value: "vegetarian_options", Label: "Vegetarian options"},
+	{Value: "pet_friendly", Label: "Pet friendly"},
+	{Value: "kid_friendly", Label: "Kid friendly"},
+	{Value: "live_music", Label: "Live music"},
+	{Value: "air_conditioning", Label: "Air conditioning"},
+}
+
+// CampaignTypes are the kinds of voting campaigns
+var CampaignTypes = ReferenceEnum{
+	{Value: CampaignTypeBestRestaurant, Label: "Best restaurant"},
+	{Value: CampaignTypeTopBars, Label: "Top bars"},
+	{Value: CampaignTypeHiddenGems, Label: "Hidden gems"},
+	{Value: CampaignTypeBestOf, Label: "Best of the city"},
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ReviewDraft is an unfinished review autosaved by the app. A user has at
+// most one draft per venue, it is removed once the review is published.
+type ReviewDraft struct {
+	UserID          int64           `json:"userId"`
+	VenueID         int64           `json:"venueId"`
+	OverallRating   *float64        `json:"overallRating,omitempty"`
+	DetailedRatings json.RawMessage `json:"detailedRatings,omitempty"`
+	Title           string          `json:"title,omitempty"`
+	ReviewText      string          `json:"reviewText,omitempty"`
+	VisitDate       *time.Time      `json:"visitDate,omitempty"`
+	VisitType       string          `json:"visitType,omitempty"`
+	PartySize       int             `json:"partySize,omitempty"`
+	Photos          json.RawMessage `json:"photos,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+func (d *ReviewDraft) TableName() string {
+	return "review_drafts"
+}
+
+// Save creates the draft or replaces the saved one
+func (d *ReviewDraft) Save(ctx context.Context) error {
+	query := `
+		INSERT INTO review_drafts (
+			user_id, venue_id, overall_rating, detailed_ratings, title,
+			review_text, visit_date, visit_type, party_size, photos
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, venue_id) DO UPDATE SET
+			overall_rating = EXCLUDED.overall_rating,
+			detailed_ratings = EXCLUDED.detailed_ratings,
+			title = EXCLUDED.title,
+			review_text = EXCLUDED.review_text,
+			visit_date = EXCLUDED.visit_date,
+			visit_type = EXCLUDED.visit_type,
+			party_size = EXCLUDED.party_size,
+			photos = EXCLUDED.photos,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query,
+		d.UserID, d.VenueID, d.OverallRating, d.DetailedRatings, d.Title,
+		d.ReviewText, d.VisitDate, d.VisitType, d.PartySize, d.Photos,
+	).Scan(&d.CreatedAt, &d.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Get retrieves the user's draft of the venue
+func (d *ReviewDraft) Get(ctx context.Context) error {
+	query := `
+		SELECT overall_rating, detailed_ratings, COALESCE(title, ''), COALESCE(review_text, ''),
+			   visit_date, COALESCE(visit_type, ''), COALESCE(party_size, 0), photos,
+			   created_at, updated_at
+		FROM review_drafts
+		WHERE user_id = $1 AND venue_id = $2`
+
+	var overallRating sql.NullFloat64
+	var visitDate sql.NullTime
+	var detailedRatings, photos []byte
+
+	err := databases.PostgresDB.QueryRowContext(ctx, query, d.UserID, d.VenueID).Scan(
+		&overallRating, &detailedRatings, &d.Title, &d.ReviewText,
+		&visitDate, &d.VisitType, &d.PartySize, &photos,
+		&d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	if overallRating.Valid {
+		d.OverallRating = &overallRating.Float64
+	}
+	if visitDate.Valid {
+		d.VisitDate = &visitDate.Time
+	}
+	d.DetailedRatings = detailedRatings
+	d.Photos = photos
+	return nil
+}
+
+// Delete discards the draft
+func (d *ReviewDraft) Delete(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM review_drafts WHERE user_id = $1 AND venue_id = $2",
+		d.UserID, d.VenueID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
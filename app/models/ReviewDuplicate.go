@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// ReviewSignature is the MinHash signature of a review's text, used to find
+// reviews pasted across venues
+type ReviewSignature struct {
+	ReviewID    int64
+	UserID      int64
+	Signature   []int64
+	DuplicateOf int64 // Original of the review when it is a duplicate itself
+	CreatedAt   time.Time
+}
+
+func (s *ReviewSignature) TableName() string {
+	return "review_signatures"
+}
+
+// Save stores the signature of the review, replacing the previous one
+func (s *ReviewSignature) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO review_signatures (review_id, user_id, signature)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (review_id) DO UPDATE SET signature = EXCLUDED.signature
+		RETURNING created_at`,
+		s.ReviewID, s.UserID, pq.Array(s.Signature),
+	).Scan(&s.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetDuplicateCandidates returns the signatures sharing a hash with the given
+// one, among the user's other reviews and everyone's reviews since the given
+// time, oldest first
+func GetDuplicateCandidates(ctx context.Context, reviewID, userID int64, signature []int64, since time.Time, limit int) ([]ReviewSignature, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT s.review_id, s.user_id, s.signature, COALESCE(r.duplicate_of, 0), s.created_at
+		FROM review_signatures s
+		INNER JOIN venue_reviews r ON r.id = s.review_id
+		WHERE s.review_id <> $1 AND s.signature && $3
+		  AND (s.user_id = $2 OR s.created_at >= $4)
+		ORDER BY s.created_at, s.review_id
+		LIMIT $5`,
+		reviewID, userID, pq.Array(signature), since, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]ReviewSignature, 0)
+	for rows.Next() {
+		var candidate ReviewSignature
+		err := rows.Scan(&candidate.ReviewID, &candidate.UserID, pq.Array(&candidate.Signature),
+			&candidate.DuplicateOf, &candidate.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// MarkReviewDuplicate merges the review into the group of its original and
+// flags it for moderation. Duplicates are left out of the venues' summaries,
+// even once approved.
+func MarkReviewDuplicate(ctx context.Context, reviewID, originalID int64, similarity float64) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		UPDATE venue_reviews
+		SET duplicate_of = $2, duplicate_similarity = $3,
+			is_flagged = true, flagged_at = COALESCE(flagged_at, CURRENT_TIMESTAMP)
+		WHERE id = $1`,
+		reviewID, originalID, similarity)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// ReviewDuplicate is a review merged into the group of an original review
+type ReviewDuplicate struct {
+	ReviewID         int64     `json:"reviewId"`
+	VenueID          int64     `json:"venueId"`
+	UserID           int64     `json:"userId"`
+	Title            string    `json:"title,omitempty"`
+	ReviewText       string    `json:"reviewText,omitempty"`
+	Similarity       float64   `json:"similarity"` // Estimated share of shingles in common with the original
+	ModerationStatus string    `json:"moderationStatus"`
+	IsFlagged        bool      `json:"isFlagged"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// GetReviewDuplicates returns the reviews merged into the original's group,
+// oldest first
+func GetReviewDuplicates(ctx context.Context, originalID int64) ([]ReviewDuplicate, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, venue_id, user_id, COALESCE(title, ''), COALESCE(review_text, ''),
+			   COALESCE(duplicate_similarity, 0), COALESCE(moderation_status, 'pending'),
+			   COALESCE(is_flagged, false), created_at
+		FROM venue_reviews
+		WHERE duplicate_of = $1
+		ORDER BY created_at, id`,
+		originalID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := make([]ReviewDuplicate, 0)
+	for rows.Next() {
+		var duplicate ReviewDuplicate
+		err := rows.Scan(&duplicate.ReviewID, &duplicate.VenueID, &duplicate.UserID, &duplicate.Title,
+			&duplicate.ReviewText, &duplicate.Similarity, &duplicate.ModerationStatus,
+			&duplicate.IsFlagged, &duplicate.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	return duplicates, rows.Err()
+}
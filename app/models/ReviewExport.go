@@ -0,0 +1,238 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Review export statuses
+const (
+	ReviewExportPending    = "pending"
+	ReviewExportProcessing = "processing"
+	ReviewExportCompleted  = "completed"
+	ReviewExportFailed     = "failed"
+)
+
+// ReviewExport is a file of a venue's approved reviews generated in the
+// background for venues with too many reviews to stream right away
+type ReviewExport struct {
+	ID          int64      `json:"id"`
+	VenueID     int64      `json:"venueId"`
+	RequestedBy int64      `json:"requestedBy"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"` // pending, processing, completed, failed
+	ObjectKey   string     `json:"-"`
+	ReviewCount *int       `json:"reviewCount,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// ExportedReview is one approved review as written to an export
+type ExportedReview struct {
+	CreatedAt       time.Time              `json:"date"`
+	OverallRating   float64                `json:"rating"`
+	DetailedRatings map[string]interface{} `json:"ratings,omitempty"`
+	Title           string                 `json:"title"`
+	ReviewText      string                 `json:"text"`
+	HelpfulVotes    int                    `json:"helpfulVotes"`
+}
+
+const reviewExportColumns = `id, venue_id, requested_by, format, status, COALESCE(object_key, ''),
+	review_count, COALESCE(error, ''), created_at, completed_at`
+
+// Create queues the export. When the venue already has an export waiting or
+// in progress in the same format, that one is loaded instead.
+func (e *ReviewExport) Create(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serializes the exports of the venue so only one is queued at a time
+	_, err = tx.ExecContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", e.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	err = e.scan(tx.QueryRowContext(ctx, `
+		SELECT `+reviewExportColumns+`
+		FROM review_exports
+		WHERE venue_id = $1 AND format = $2 AND status IN ('pending', 'processing')
+		ORDER BY created_at
+		LIMIT 1`, e.VenueID, e.Format))
+	if err == sql.ErrNoRows {
+		err = e.scan(tx.QueryRowContext(ctx, `
+			INSERT INTO review_exports (venue_id, requested_by, format, status)
+			VALUES ($1, $2, $3, 'pending')
+			RETURNING `+reviewExportColumns,
+			e.VenueID, e.RequestedBy, e.Format))
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// Get loads the export by ID and venue, returning sql.ErrNoRows when the
+// venue has no such export
+func (e *ReviewExport) Get(ctx context.Context) error {
+	err := e.scan(databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT `+reviewExportColumns+`
+		FROM review_exports
+		WHERE id = $1 AND venue_id = $2`, e.ID, e.VenueID))
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Complete records the stored file of the export
+func (e *ReviewExport) Complete(ctx context.Context, objectKey string, reviewCount int) error {
+	err := e.scan(databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE review_exports
+		SET status = 'completed', object_key = $2, review_count = $3, error = NULL, completed_at = LOCALTIMESTAMP
+		WHERE id = $1
+		RETURNING `+reviewExportColumns, e.ID, objectKey, reviewCount))
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Fail records why the export couldn't be generated
+func (e *ReviewExport) Fail(ctx context.Context, reason string) error {
+	err := e.scan(databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE review_exports
+		SET status = 'failed', error = $2, completed_at = LOCALTIMESTAMP
+		WHERE id = $1
+		RETURNING `+reviewExportColumns, e.ID, reason))
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// ClaimReviewExports marks up to limit waiting exports as processing and
+// returns them. Exports left processing longer than lease, by a worker that
+// stopped, are claimed again. Times use the database clock.
+func ClaimReviewExports(ctx context.Context, lease time.Duration, limit int) ([]ReviewExport, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		UPDATE review_exports
+		SET status = 'processing', started_at = LOCALTIMESTAMP
+		WHERE id IN (
+			SELECT id FROM review_exports
+			WHERE status = 'pending'
+			   OR (status = 'processing' AND started_at < LOCALTIMESTAMP - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+reviewExportColumns,
+		lease.Seconds(), limit,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := make([]ReviewExport, 0)
+	for rows.Next() {
+		var export ReviewExport
+		if err := export.scan(rows); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, nil
+}
+
+type reviewExportScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (e *ReviewExport) scan(row reviewExportScanner) error {
+	var reviewCount sql.NullInt64
+	var completedAt sql.NullTime
+	err := row.Scan(
+		&e.ID, &e.VenueID, &e.RequestedBy, &e.Format, &e.Status, &e.ObjectKey,
+		&reviewCount, &e.Error, &e.CreatedAt, &completedAt,
+	)
+	if err != nil {
+		return err
+	}
+	e.ReviewCount = nil
+	if reviewCount.Valid {
+		count := int(reviewCount.Int64)
+		e.ReviewCount = &count
+	}
+	e.CompletedAt = nil
+	if completedAt.Valid {
+		e.CompletedAt = &completedAt.Time
+	}
+	return nil
+}
+
+// CountApprovedVenueReviews counts the approved reviews of the venue
+func CountApprovedVenueReviews(ctx context.Context, venueID int64) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1 AND moderation_status = 'approved'",
+		venueID).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// EachExportedReview calls fn with every approved review of the venue,
+// oldest first, reading them from the database as it goes
+func EachExportedReview(ctx context.Context, venueID int64, fn func(*ExportedReview) error) error {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT created_at, overall_rating, detailed_ratings, COALESCE(title, ''),
+			   COALESCE(review_text, ''), COALESCE(helpful_votes, 0)
+		FROM venue_reviews
+		WHERE venue_id = $1 AND moderation_status = 'approved'
+		ORDER BY created_at, id`, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review ExportedReview
+		var detailedRatings []byte
+		err := rows.Scan(&review.CreatedAt, &review.OverallRating, &detailedRatings,
+			&review.Title, &review.ReviewText, &review.HelpfulVotes)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		// Ratings that aren't a JSON object are left out
+		if len(detailedRatings) > 0 {
+			json.Unmarshal(detailedRatings, &review.DetailedRatings)
+		}
+		if err := fn(&review); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// helpfulnessZ is the z-score of the 95% confidence the helpful share
+	// of a review's votes is estimated with
+	helpfulnessZ = 1.96
+	// HelpfulnessHalfLife is the age at which a review's helpfulness counts
+	// half as much, so old reviews make way for recent ones
+	HelpfulnessHalfLife = 180 * 24 * time.Hour
+)
+
+// HelpfulnessScore ranks a review by helpfulness. The helpful share of its
+// votes is the lower bound of its Wilson score interval, so a few votes
+// can't outrank many, and it halves every HelpfulnessHalfLife of age.
+// Reviews without votes score 0.
+func HelpfulnessScore(helpful, unhelpful int, createdAt, now time.Time) float64 {
+	votes := float64(helpful + unhelpful)
+	if votes == 0 {
+		return 0
+	}
+	z2 := helpfulnessZ * helpfulnessZ
+	wilson := ((float64(helpful)+z2/2)/votes -
+		helpfulnessZ*math.Sqrt(float64(helpful)*float64(unhelpful)/votes+z2/4)/votes) / (1 + z2/votes)
+
+	age := now.Sub(createdAt)
+	if age < 0 {
+		age = 0
+	}
+	return wilson * math.Pow(0.5, age.Hours()/HelpfulnessHalfLife.Hours())
+}
+
+// HelpfulnessExpression is the SQL computing HelpfulnessScore of the
+// reviews aliased as table
+func HelpfulnessExpression(table string) string {
+	helpful := fmt.Sprintf("COALESCE(%s.helpful_votes, 0)::float8", table)
+	unhelpful := fmt.Sprintf("COALESCE(%s.unhelpful_votes, 0)::float8", table)
+	votes := fmt.Sprintf("NULLIF(%s + %s, 0)", helpful, unhelpful)
+	z2 := helpfulnessZ * helpfulnessZ
+	return fmt.Sprintf(`COALESCE(
+		((%[1]s + %[4]g) / %[3]s - %[6]g * SQRT(%[1]s * %[2]s / %[3]s + %[5]g) / %[3]s) / (1 + %[7]g / %[3]s)
+		* POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - %[8]s.created_at)), 0) / %[9]g),
+		0)`,
+		helpful, unhelpful, votes, z2/2, z2/4, helpfulnessZ, z2, table, HelpfulnessHalfLife.Seconds())
+}
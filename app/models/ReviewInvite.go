@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrInvalidReviewInvite is returned when a review invite token is unknown,
+// belongs to another venue, was already used or has expired
+var ErrInvalidReviewInvite = errors.New("review invite is invalid, used or expired")
+
+// ReviewInvite is a single-use token handed out by a venue (e.g. as a QR code
+// on the receipt) that marks the review written with it as a verified visit
+type ReviewInvite struct {
+	ID        int64      `json:"id"`
+	VenueID   int64      `json:"venueId"`
+	Token     string     `json:"token"`
+	CreatedBy int64      `json:"createdBy"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	ReviewID  *int64     `json:"reviewId,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	InviteURL string     `json:"inviteUrl,omitempty"`
+}
+
+func (i *ReviewInvite) TableName() string {
+	return "review_invites"
+}
+
+// CreateReviewInvites generates count invites for a venue
+func CreateReviewInvites(ctx context.Context, venueID, createdBy int64, count int, expiresAt time.Time) ([]ReviewInvite, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO review_invites (venue_id, token, created_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	invites := make([]ReviewInvite, count)
+	for i := range invites {
+		token, err := newReviewInviteToken()
+		if err != nil {
+			return nil, err
+		}
+
+		invite := ReviewInvite{
+			VenueID:   venueID,
+			Token:     token,
+			CreatedBy: createdBy,
+			ExpiresAt: expiresAt,
+		}
+		err = tx.QueryRowContext(ctx, query, venueID, token, createdBy, expiresAt).Scan(&invite.ID, &invite.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		invites[i] = invite
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return invites, nil
+}
+
+// claimReviewInvite marks an unused invite of the venue as used within the
+// review transaction, so a token can only verify one review
+func claimReviewInvite(ctx context.Context, tx *sql.Tx, venueID int64, token string) (int64, error) {
+	var inviteID int64
+	err := tx.QueryRowContext(ctx, `
+		UPDATE review_invites SET used_at = CURRENT_TIMESTAMP
+		WHERE token = $1 AND venue_id = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id`,
+		token, venueID,
+	).Scan(&inviteID)
+
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidReviewInvite
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return inviteID, err
+}
+
+func newReviewInviteToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		sentry.CaptureException(err)
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
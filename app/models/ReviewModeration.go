@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Moderation actions on reviews
+const (
+	ModerationApprove = "approve"
+	ModerationReject  = "reject"
+	ModerationFlag    = "flag"
+)
+
+// Results of moderating one review of a batch
+const (
+	BulkResultUpdated   = "updated"
+	BulkResultUnchanged = "unchanged" // The review already was in the state
+	BulkResultNotFound  = "not_found"
+)
+
+// BulkModerationResult is what moderating one review of a batch did
+type BulkModerationResult struct {
+	ReviewID         int64  `json:"reviewId"`
+	Result           string `json:"result"`
+	ModerationStatus string `json:"moderationStatus,omitempty"`
+	IsFlagged        bool   `json:"isFlagged"`
+}
+
+// reviewState describes a review's moderation state in the audit log
+func reviewState(status string, isFlagged bool) string {
+	if isFlagged {
+		return status + "/flagged"
+	}
+	return status
+}
+
+// BulkModerateReviews applies the action to the reviews in one transaction,
+// auditing every review it changes. Approving clears the review's flag,
+// flagging keeps its status and puts it in the reported queue. The results
+// follow the order of reviewIDs, repeated IDs are moderated once.
+func BulkModerateReviews(ctx context.Context, moderatorID int64, reviewIDs []int64, action string, now time.Time) ([]BulkModerationResult, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the reviews keeps concurrent moderators from auditing stale
+	// states
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, venue_id, COALESCE(moderation_status, 'pending'), COALESCE(is_flagged, false)
+		FROM venue_reviews
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE`,
+		pq.Array(reviewIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	type reviewModeration struct {
+		venueID   int64
+		status    string
+		isFlagged bool
+	}
+	current := make(map[int64]reviewModeration, len(reviewIDs))
+	for rows.Next() {
+		var id int64
+		var review reviewModeration
+		if err := rows.Scan(&id, &review.venueID, &review.status, &review.isFlagged); err != nil {
+			rows.Close()
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		current[id] = review
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	results := make([]BulkModerationResult, 0, len(reviewIDs))
+	seen := make(map[int64]bool, len(reviewIDs))
+	var changed []int64
+	var entries []ModerationAuditEntry
+	venueIDs := make(map[int64]bool)
+	for _, id := range reviewIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		review, exists := current[id]
+		if !exists {
+			results = append(results, BulkModerationResult{ReviewID: id, Result: BulkResultNotFound})
+			continue
+		}
+
+		status, isFlagged := review.status, review.isFlagged
+		switch action {
+		case ModerationApprove:
+			status, isFlagged = "approved", false
+		case ModerationReject:
+			status = "rejected"
+		case ModerationFlag:
+			isFlagged = true
+		}
+
+		result := BulkModerationResult{ReviewID: id, Result: BulkResultUnchanged, ModerationStatus: status, IsFlagged: isFlagged}
+		if status != review.status || isFlagged != review.isFlagged {
+			result.Result = BulkResultUpdated
+			changed = append(changed, id)
+			entries = append(entries, ModerationAuditEntry{
+				ModeratorID: moderatorID,
+				Action:      action,
+				TargetType:  AuditTargetReview,
+				TargetID:    id,
+				FromState:   reviewState(review.status, review.isFlagged),
+				ToState:     reviewState(status, isFlagged),
+			})
+			if status != review.status {
+				venueIDs[review.venueID] = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	if len(changed) > 0 {
+		query := `UPDATE venue_reviews
+			SET is_flagged = true, flagged_at = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ANY($1)`
+		args := []interface{}{pq.Array(changed), now}
+		switch action {
+		case ModerationApprove:
+			query = `UPDATE venue_reviews
+				SET moderation_status = 'approved', is_flagged = false,
+					moderated_by = $3, moderated_at = $2, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ANY($1)`
+			args = append(args, moderatorID)
+		case ModerationReject:
+			query = `UPDATE venue_reviews
+				SET moderation_status = 'rejected', moderated_by = $3, moderated_at = $2, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ANY($1)`
+			args = append(args, moderatorID)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+
+		if err := createAuditEntries(ctx, tx, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	// Approved and rejected reviews change their venues' ratings
+	for venueID := range venueIDs {
+		venue := &Venue{ID: venueID}
+		go venue.UpdateRatingCache(context.Background())
+	}
+
+	return results, nil
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Review photo errors
+var (
+	ErrUnknownReviewPhoto = errors.New("review has no photo with this URL")
+	ErrReviewPhotoOrder   = errors.New("photo order must list every photo of the review once")
+)
+
+// ReviewPhoto is a photo of a review. Photos are shown by Order, starting
+// at 0.
+type ReviewPhoto struct {
+	URL        string    `json:"url"`
+	Caption    string    `json:"caption,omitempty"`
+	Order      int       `json:"order"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// UnmarshalJSON also reads the plain URLs photos were stored as before
+// they had captions
+func (p *ReviewPhoto) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*p = ReviewPhoto{URL: url}
+		return nil
+	}
+
+	type reviewPhoto ReviewPhoto
+	return json.Unmarshal(data, (*reviewPhoto)(p))
+}
+
+// ReviewPhotos are the photos of a review in order, stored as a JSON array
+type ReviewPhotos []ReviewPhoto
+
+// NewReviewPhotos returns the photos with the URLs, in order
+func NewReviewPhotos(urls []string, uploadedAt time.Time) ReviewPhotos {
+	if len(urls) == 0 {
+		return nil
+	}
+	photos := make(ReviewPhotos, len(urls))
+	for i, url := range urls {
+		photos[i] = ReviewPhoto{URL: url, Order: i, UploadedAt: uploadedAt}
+	}
+	return photos
+}
+
+// Scan reads the photos from their JSON array, sorting them by order
+func (p *ReviewPhotos) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("cannot scan %T into review photos", src)
+	}
+
+	var photos ReviewPhotos
+	if err := json.Unmarshal(data, &photos); err != nil {
+		return err
+	}
+	sort.SliceStable(photos, func(i, j int) bool {
+		return photos[i].Order < photos[j].Order
+	})
+	photos.renumber()
+	*p = photos
+	return nil
+}
+
+// Value stores the photos as a JSON array, NULL when there are none
+func (p ReviewPhotos) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// URLs returns the URLs of the photos in order
+func (p ReviewPhotos) URLs() []string {
+	urls := make([]string, len(p))
+	for i, photo := range p {
+		urls[i] = photo.URL
+	}
+	return urls
+}
+
+// Reorder puts the photos in the order of the URLs, which must list every
+// photo once. It returns ErrReviewPhotoOrder otherwise.
+func (p ReviewPhotos) Reorder(urls []string) error {
+	if len(urls) != len(p) {
+		return ErrReviewPhotoOrder
+	}
+	positions := make(map[string]int, len(urls))
+	for i, url := range urls {
+		if _, listed := positions[url]; listed {
+			return ErrReviewPhotoOrder
+		}
+		positions[url] = i
+	}
+	for _, photo := range p {
+		if _, listed := positions[photo.URL]; !listed {
+			return ErrReviewPhotoOrder
+		}
+	}
+
+	sort.Slice(p, func(i, j int) bool {
+		return positions[p[i].URL] < positions[p[j].URL]
+	})
+	p.renumber()
+	return nil
+}
+
+// SetCaption captions the photo with the URL, an empty caption removes it.
+// It returns ErrUnknownReviewPhoto when there is no such photo.
+func (p ReviewPhotos) SetCaption(url, caption string) error {
+	for i := range p {
+		if p[i].URL == url {
+			p[i].Caption = caption
+			return nil
+		}
+	}
+	return ErrUnknownReviewPhoto
+}
+
+// renumber sets the order of the photos to their position
+func (p ReviewPhotos) renumber() {
+	for i := range p {
+		p[i].Order = i
+	}
+}
+
+// UpdatePhotos stores the photos of the review, flagging it for moderation
+// when flag is set, like for a caption the content filter flagged
+func (r *VenueReview) UpdatePhotos(ctx context.Context, flag bool) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE venue_reviews
+		SET photos = $2, is_flagged = is_flagged OR $3,
+			flagged_at = CASE WHEN $3 AND NOT is_flagged THEN CURRENT_TIMESTAMP ELSE flagged_at END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING is_flagged, updated_at`,
+		r.ID, r.Photos, flag,
+	).Scan(&r.IsFlagged, &r.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
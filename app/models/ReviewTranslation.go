@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// ReviewTranslation is a review's title and text in another language,
+// cached per review and language
+type ReviewTranslation struct {
+	ReviewID       int64     `json:"-"`
+	Language       string    `json:"language"`
+	SourceLanguage string    `json:"sourceLanguage,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	ReviewText     string    `json:"reviewText,omitempty"`
+	Provider       string    `json:"provider"` // Backend that translated the review
+	TranslatedAt   time.Time `json:"translatedAt"`
+}
+
+func (t *ReviewTranslation) TableName() string {
+	return "review_translations"
+}
+
+// Save stores the translation, replacing an earlier one of the language
+func (t *ReviewTranslation) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO review_translations (review_id, language, source_language, title, review_text, provider)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (review_id, language) DO UPDATE
+		SET source_language = EXCLUDED.source_language, title = EXCLUDED.title,
+			review_text = EXCLUDED.review_text, provider = EXCLUDED.provider,
+			translated_at = CURRENT_TIMESTAMP
+		RETURNING translated_at`,
+		t.ReviewID, t.Language, t.SourceLanguage, t.Title, t.ReviewText, t.Provider,
+	).Scan(&t.TranslatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetReviewTranslations returns the cached translations of the reviews into
+// the language by review ID
+func GetReviewTranslations(ctx context.Context, reviewIDs []int64, language string) (map[int64]ReviewTranslation, error) {
+	translations := make(map[int64]ReviewTranslation)
+	if len(reviewIDs) == 0 {
+		return translations, nil
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT review_id, language, source_language, COALESCE(title, ''), COALESCE(review_text, ''),
+			   provider, translated_at
+		FROM review_translations
+		WHERE review_id = ANY($1) AND language = $2`,
+		pq.Array(reviewIDs), language)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t ReviewTranslation
+		err := rows.Scan(&t.ReviewID, &t.Language, &t.SourceLanguage, &t.Title, &t.ReviewText,
+			&t.Provider, &t.TranslatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		translations[t.ReviewID] = t
+	}
+	return translations, nil
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// SavedSearch is a venue search a user wants to be alerted about
+type SavedSearch struct {
+	ID              int64             `json:"id"`
+	UserID          int64             `json:"userId"`
+	Name            string            `json:"name"`
+	Params          VenueSearchParams `json:"params"`
+	AlertsEnabled   bool              `json:"alertsEnabled"`
+	NewMatchesCount int               `json:"newMatchesCount"`
+	LastEvaluatedAt time.Time         `json:"lastEvaluatedAt"`
+	LastNotifiedAt  *time.Time        `json:"lastNotifiedAt,omitempty"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
+
+// SavedSearchMatch is a venue that appeared after a search was saved
+type SavedSearchMatch struct {
+	SavedSearchID int64     `json:"savedSearchId"`
+	VenueID       int64     `json:"venueId"`
+	VenueName     string    `json:"venueName"`
+	VenueSlug     string    `json:"venueSlug"`
+	IsRead        bool      `json:"isRead"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func (s *SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+const savedSearchColumns = `
+	s.id, s.user_id, s.name, s.params, s.alerts_enabled,
+	s.last_evaluated_at, s.last_notified_at, s.created_at, s.updated_at,
+	(SELECT COUNT(*) FROM saved_search_matches m
+	 WHERE m.saved_search_id = s.id AND m.is_read = false)`
+
+// Create stores a new saved search. Only venues added afterwards are matches.
+func (s *SavedSearch) Create(ctx context.Context) error {
+	params, err := json.Marshal(s.Params)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saved_searches (user_id, name, params, alerts_enabled)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, last_evaluated_at, created_at, updated_at`
+
+	err = databases.PostgresDB.QueryRowContext(ctx,
+		query, s.UserID, s.Name, params, s.AlertsEnabled,
+	).Scan(&s.ID, &s.LastEvaluatedAt, &s.CreatedAt, &s.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a saved search of the user
+func (s *SavedSearch) GetByID(ctx context.Context) error {
+	query := `SELECT` + savedSearchColumns + `
+		FROM saved_searches s
+		WHERE s.id = $1 AND s.user_id = $2`
+
+	err := scanSavedSearch(databases.PostgresDB.QueryRowContext(ctx, query, s.ID, s.UserID), s)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the saved search and its matches
+func (s *SavedSearch) Delete(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM saved_searches WHERE id = $1 AND user_id = $2",
+		s.ID, s.UserID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// AddMatches records venues as new matches, returning how many were not
+// matched before
+func (s *SavedSearch) AddMatches(ctx context.Context, venueIDs []int64) (int, error) {
+	added := 0
+	for _, venueID := range venueIDs {
+		result, err := databases.PostgresDB.ExecContext(ctx, `
+			INSERT INTO saved_search_matches (saved_search_id, venue_id)
+			VALUES ($1, $2)
+			ON CONFLICT (saved_search_id, venue_id) DO NOTHING`,
+			s.ID, venueID,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			return added, err
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// MarkEvaluated moves the evaluation watermark of the saved search
+func (s *SavedSearch) MarkEvaluated(ctx context.Context, evaluatedAt time.Time, notified bool) error {
+	query := "UPDATE saved_searches SET last_evaluated_at = $2 WHERE id = $1"
+	if notified {
+		query = "UPDATE saved_searches SET last_evaluated_at = $2, last_notified_at = $2 WHERE id = $1"
+	}
+
+	_, err := databases.PostgresDB.ExecContext(ctx, query, s.ID, evaluatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	s.LastEvaluatedAt = evaluatedAt
+	if notified {
+		s.LastNotifiedAt = &evaluatedAt
+	}
+	return nil
+}
+
+// SavedSearchEvaluationTime returns the database clock. Venue creation times
+// are compared against it, so it is used for the evaluation watermark.
+func SavedSearchEvaluationTime(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT LOCALTIMESTAMP").Scan(&now)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return now, err
+}
+
+// GetMatches returns the matches of the saved search, newest first
+func (s *SavedSearch) GetMatches(ctx context.Context, limit int) ([]SavedSearchMatch, error) {
+	query := `
+		SELECT m.saved_search_id, m.venue_id, v.name, v.slug, m.is_read, m.created_at
+		FROM saved_search_matches m
+		JOIN venues v ON v.id = m.venue_id
+		WHERE m.saved_search_id = $1
+		ORDER BY m.created_at DESC, m.venue_id DESC
+		LIMIT $2`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, s.ID, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]SavedSearchMatch, 0)
+	for rows.Next() {
+		var match SavedSearchMatch
+		err := rows.Scan(
+			&match.SavedSearchID, &match.VenueID, &match.VenueName,
+			&match.VenueSlug, &match.IsRead, &match.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// MarkMatchesRead resets the new matches count of the saved search
+func (s *SavedSearch) MarkMatchesRead(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"UPDATE saved_search_matches SET is_read = true WHERE saved_search_id = $1 AND is_read = false",
+		s.ID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	s.NewMatchesCount = 0
+	return nil
+}
+
+// GetUserSavedSearches returns the saved searches of a user
+func GetUserSavedSearches(ctx context.Context, userID int64) ([]SavedSearch, error) {
+	query := `SELECT` + savedSearchColumns + `
+		FROM saved_searches s
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC`
+
+	return querySavedSearches(ctx, query, userID)
+}
+
+// GetAlertingSavedSearches returns every saved search with alerts enabled
+func GetAlertingSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	query := `SELECT` + savedSearchColumns + `
+		FROM saved_searches s
+		WHERE s.alerts_enabled = true
+		ORDER BY s.id`
+
+	return querySavedSearches(ctx, query)
+}
+
+// CountUserSavedSearches returns how many searches the user has saved
+func CountUserSavedSearches(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM saved_searches WHERE user_id = $1", userID,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+func querySavedSearches(ctx context.Context, query string, args ...interface{}) ([]SavedSearch, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := make([]SavedSearch, 0)
+	for rows.Next() {
+		var search SavedSearch
+		if err := scanSavedSearch(rows, &search); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, nil
+}
+
+func scanSavedSearch(row interface{ Scan(...interface{}) error }, s *SavedSearch) error {
+	var params []byte
+	var lastNotifiedAt sql.NullTime
+
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.Name, &params, &s.AlertsEnabled,
+		&s.LastEvaluatedAt, &lastNotifiedAt, &s.CreatedAt, &s.UpdatedAt,
+		&s.NewMatchesCount,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lastNotifiedAt.Valid {
+		s.LastNotifiedAt = &lastNotifiedAt.Time
+	}
+	return json.Unmarshal(params, &s.Params)
+}
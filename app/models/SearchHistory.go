@@ -0,0 +1,129 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// SearchHistoryEntry is a venue search the user made, as recorded in
+// search_analytics
+type SearchHistoryEntry struct {
+	ID             int64           `json:"id"`
+	Query          string          `json:"query"`
+	SearchType     string          `json:"searchType"` // text, filter
+	Filters        json.RawMessage `json:"filters,omitempty"`
+	ResultsCount   int             `json:"resultsCount"`
+	ClickedVenueID *int64          `json:"clickedVenueId,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// PrivacySettings are a user's choices about the data recorded about them.
+// Users without settings have the defaults.
+type PrivacySettings struct {
+	UserID int64 `json:"userId"`
+	// SearchHistoryEnabled records the user's searches, off stops
+	// recording them
+	SearchHistoryEnabled bool      `json:"searchHistoryEnabled"`
+	UpdatedAt            time.Time `json:"updatedAt,omitempty"`
+}
+
+func (s *PrivacySettings) TableName() string {
+	return "user_privacy_settings"
+}
+
+// GetSearchHistory returns a page of the user's searches, newest first, with
+// their total
+func GetSearchHistory(ctx context.Context, userID int64, limit, offset int) ([]SearchHistoryEntry, int, error) {
+	var total int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM search_analytics WHERE user_id = $1", userID).Scan(&total)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, COALESCE(search_query, ''), COALESCE(search_type, ''), filters_used,
+			   COALESCE(results_count, 0), clicked_venue_id, created_at
+		FROM search_analytics
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`,
+		userID, limit, offset)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	history := make([]SearchHistoryEntry, 0)
+	for rows.Next() {
+		var entry SearchHistoryEntry
+		var filters []byte
+		var clickedVenueID sql.NullInt64
+		err := rows.Scan(&entry.ID, &entry.Query, &entry.SearchType, &filters,
+			&entry.ResultsCount, &clickedVenueID, &entry.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, 0, err
+		}
+		if len(filters) > 0 && string(filters) != "{}" && string(filters) != "null" {
+			entry.Filters = filters
+		}
+		if clickedVenueID.Valid {
+			entry.ClickedVenueID = &clickedVenueID.Int64
+		}
+		history = append(history, entry)
+	}
+	return history, total, rows.Err()
+}
+
+// ClearSearchHistory deletes the user's recorded searches, returning how
+// many there were
+func ClearSearchHistory(ctx context.Context, userID int64) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM search_analytics WHERE user_id = $1", userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetPrivacySettings returns the user's privacy settings, the defaults when
+// they changed none
+func GetPrivacySettings(ctx context.Context, userID int64) (*PrivacySettings, error) {
+	settings := &PrivacySettings{UserID: userID, SearchHistoryEnabled: true}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT search_history_enabled, updated_at
+		FROM user_privacy_settings
+		WHERE user_id = $1`,
+		userID,
+	).Scan(&settings.SearchHistoryEnabled, &settings.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Save stores the user's privacy settings
+func (s *PrivacySettings) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO user_privacy_settings (user_id, search_history_enabled, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE SET
+			search_history_enabled = EXCLUDED.search_history_enabled, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		s.UserID, s.SearchHistoryEnabled,
+	).Scan(&s.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
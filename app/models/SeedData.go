@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// The Ensure functions back the development seed: each loads the row
+// matching the natural key given, creating it first when it is missing,
+// and sets its ID.
+
+// EnsureCity ensures a city of the name and country exists
+func EnsureCity(ctx context.Context, city *City) error {
+	return ensureRow(ctx, &city.ID, `
+		WITH existing AS (
+			SELECT id FROM cities WHERE name = $1 AND country = $2 ORDER BY id LIMIT 1
+		), inserted AS (
+			INSERT INTO cities (name, state, country, latitude, longitude, timezone)
+			SELECT $1, NULLIF($3, ''), $2, $4, $5, NULLIF($6, '')
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			RETURNING id
+		)
+		SELECT id FROM existing UNION ALL SELECT id FROM inserted`,
+		city.Name, city.Country, city.State, city.Latitude, city.Longitude, city.Timezone)
+}
+
+// EnsureVenueCategory ensures a venue category of the name exists
+func EnsureVenueCategory(ctx context.Context, category *VenueCategory) error {
+	return ensureRow(ctx, &category.ID, `
+		WITH existing AS (
+			SELECT id FROM venue_categories WHERE name = $1
+		), inserted AS (
+			INSERT INTO venue_categories (name, description, icon)
+			SELECT $1, NULLIF($2, ''), NULLIF($3, '')
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			RETURNING id
+		)
+		SELECT id FROM existing UNION ALL SELECT id FROM inserted`,
+		category.Name, category.Description, category.Icon)
+}
+
+// EnsureSnappUser ensures a Snapp user of the Snapp ID exists
+func EnsureSnappUser(ctx context.Context, user *SnappUser) error {
+	return ensureRow(ctx, &user.Id, `
+		WITH existing AS (
+			SELECT id FROM snapp_users WHERE snapp_id = $1
+		), inserted AS (
+			INSERT INTO snapp_users (snapp_id)
+			SELECT $1
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			RETURNING id
+		)
+		SELECT id FROM existing UNION ALL SELECT id FROM inserted`,
+		user.SnappId)
+}
+
+// EnsureVotingCampaign ensures a campaign of the title exists
+func EnsureVotingCampaign(ctx context.Context, campaign *VotingCampaign) error {
+	if campaign.VotingMode == "" {
+		campaign.VotingMode = VotingModeStandard
+	}
+	return ensureRow(ctx, &campaign.ID, `
+		WITH existing AS (
+			SELECT id FROM voting_campaigns WHERE title = $1 ORDER BY id LIMIT 1
+		), inserted AS (
+			INSERT INTO voting_campaigns (
+				title, description, campaign_type, city_id, category_id, start_date, end_date,
+				max_votes_per_user, allow_multiple_categories, require_review, voting_mode,
+				is_active, is_featured
+			)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			RETURNING id
+		)
+		SELECT id FROM existing UNION ALL SELECT id FROM inserted`,
+		campaign.Title, campaign.Description, campaign.CampaignType, campaign.CityID, campaign.CategoryID,
+		campaign.StartDate, campaign.EndDate, campaign.MaxVotesPerUser, campaign.AllowMultipleCategories,
+		campaign.RequireReview, campaign.VotingMode, campaign.IsActive, campaign.IsFeatured)
+}
+
+// GetVenueIDBySlug returns the ID of the venue with the current slug, active
+// or not, returning sql.ErrNoRows when there is none
+func GetVenueIDBySlug(ctx context.Context, slug string) (int64, error) {
+	var venueID int64
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT id FROM venues WHERE slug = $1", slug).Scan(&venueID)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return venueID, err
+}
+
+func ensureRow(ctx context.Context, id *int64, query string, args ...interface{}) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, query, args...).Scan(id)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
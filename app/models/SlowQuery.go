@@ -0,0 +1,245 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/config"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// slowQueryExplainTimeout bounds explaining a slow statement, which runs
+// after the request that ran it
+const slowQueryExplainTimeout = 5 * time.Second
+
+// SlowQuerySettings tell which statements are logged as slow
+type SlowQuerySettings struct {
+	Enabled   bool
+	Threshold time.Duration
+	Explain   bool // Record the statements' plans, without running them
+}
+
+var slowQueryLog = struct {
+	sync.RWMutex
+	once     sync.Once
+	settings SlowQuerySettings
+}{}
+
+// loadSlowQuerySettings reads the settings of the configuration on first
+// use, so the package can be imported without one
+func loadSlowQuerySettings() {
+	slowQueryLog.once.Do(func() {
+		database := config.Get().Database
+		slowQueryLog.Lock()
+		slowQueryLog.settings = SlowQuerySettings{
+			Enabled:   database.SlowQueryLog,
+			Threshold: database.SlowQueryThreshold,
+			Explain:   database.SlowQueryExplain,
+		}
+		slowQueryLog.Unlock()
+	})
+}
+
+// GetSlowQuerySettings returns the current slow query settings
+func GetSlowQuerySettings() SlowQuerySettings {
+	loadSlowQuerySettings()
+	slowQueryLog.RLock()
+	defer slowQueryLog.RUnlock()
+	return slowQueryLog.settings
+}
+
+// SetSlowQuerySettings replaces the slow query settings of the configuration
+func SetSlowQuerySettings(settings SlowQuerySettings) {
+	loadSlowQuerySettings()
+	slowQueryLog.Lock()
+	slowQueryLog.settings = settings
+	slowQueryLog.Unlock()
+}
+
+// SlowQuery is a search or analytics statement that ran longer than the
+// slow query threshold, kept for postmortems
+type SlowQuery struct {
+	ID         int64           `json:"id"`
+	Source     string          `json:"source"` // Function that ran the statement
+	Statement  string          `json:"statement"`
+	Params     []string        `json:"params"`
+	DurationMs float64         `json:"durationMs"`
+	Error      string          `json:"error,omitempty"` // Why the statement failed, e.g. it timed out
+	Plan       json.RawMessage `json:"plan,omitempty"`  // EXPLAIN output, when enabled
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func (q *SlowQuery) TableName() string {
+	return "slow_queries"
+}
+
+// LoggedQuery runs the query like databases.PostgresDB.QueryContext, logging
+// it when it is slow
+func LoggedQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
+	logIfSlow(query, args, time.Since(start), err)
+	return rows, err
+}
+
+// LoggedQueryRow runs the query like databases.PostgresDB.QueryRowContext,
+// logging it when it is slow
+func LoggedQueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := databases.PostgresDB.QueryRowContext(ctx, query, args...)
+	logIfSlow(query, args, time.Since(start), row.Err())
+	return row
+}
+
+// logIfSlow records the statement in the background when it ran longer than
+// the threshold. Statements failing after the threshold, like the ones
+// cancelled by the request timeout, are recorded too.
+func logIfSlow(query string, args []interface{}, duration time.Duration, err error) {
+	settings := GetSlowQuerySettings()
+	if !settings.Enabled || duration < settings.Threshold {
+		return
+	}
+
+	slowQuery := &SlowQuery{
+		Source:     slowQuerySource(),
+		Statement:  strings.TrimSpace(query),
+		Params:     formatQueryParams(args),
+		DurationMs: float64(duration.Microseconds()) / 1000,
+	}
+	if err != nil {
+		slowQuery.Error = err.Error()
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), slowQueryExplainTimeout)
+		defer cancel()
+
+		if settings.Explain {
+			slowQuery.Plan = explainQuery(ctx, query, args)
+		}
+		slowQuery.Create(ctx)
+	}()
+}
+
+// slowQuerySource returns the function that called LoggedQuery or
+// LoggedQueryRow
+func slowQuerySource() string {
+	pc, _, _, ok := runtime.Caller(3)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	// Package paths are left out, e.g. services.(*AnalyticsService).getRatingMetrics
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	return name
+}
+
+// formatQueryParams formats the statement's parameters as the database
+// receives them
+func formatQueryParams(args []interface{}) []string {
+	params := make([]string, len(args))
+	for i, arg := range args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if value, err := valuer.Value(); err == nil {
+				arg = value
+			}
+		}
+		switch value := arg.(type) {
+		case nil:
+			params[i] = "NULL"
+		case []byte:
+			params[i] = string(value)
+		case time.Time:
+			params[i] = value.Format(time.RFC3339Nano)
+		default:
+			params[i] = fmt.Sprint(value)
+		}
+	}
+	return params
+}
+
+// explainQuery returns the plan of the statement without running it, nil
+// when it can't be explained
+func explainQuery(ctx context.Context, query string, args []interface{}) json.RawMessage {
+	var plan []byte
+	err := databases.PostgresDB.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan)
+	if err != nil {
+		return nil
+	}
+	return plan
+}
+
+// Create records the slow query
+func (q *SlowQuery) Create(ctx context.Context) error {
+	var plan interface{}
+	if len(q.Plan) > 0 {
+		plan = string(q.Plan)
+	}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO slow_queries (source, statement, params, duration_ms, error, plan)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at`,
+		q.Source, q.Statement, pq.Array(q.Params), q.DurationMs, q.Error, plan,
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetSlowQueries returns the slow queries recorded since the given time,
+// the most recent first, optionally only the ones of a source
+func GetSlowQueries(ctx context.Context, since time.Time, source string, limit int) ([]SlowQuery, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, source, statement, params, duration_ms, COALESCE(error, ''), plan, created_at
+		FROM slow_queries
+		WHERE created_at >= $1 AND ($2 = '' OR source = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`,
+		since, source, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	slowQueries := make([]SlowQuery, 0)
+	for rows.Next() {
+		var slowQuery SlowQuery
+		var plan []byte
+		err := rows.Scan(&slowQuery.ID, &slowQuery.Source, &slowQuery.Statement, pq.Array(&slowQuery.Params),
+			&slowQuery.DurationMs, &slowQuery.Error, &plan, &slowQuery.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if plan != nil {
+			slowQuery.Plan = plan
+		}
+		slowQueries = append(slowQueries, slowQuery)
+	}
+	return slowQueries, rows.Err()
+}
+
+// DeleteSlowQueries removes the slow queries recorded before the given time
+func DeleteSlowQueries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM slow_queries WHERE created_at < $1", before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"github.com/getsentry/sentry-go"
 	databases "voting-app/app"
@@ -11,9 +12,9 @@ type SnappUser struct {
 	SnappId string `json:"snapp_id"`
 }
 
-func (u *SnappUser) GetUser() (bool, error) {
+func (u *SnappUser) GetUser(ctx context.Context) (bool, error) {
 	var total int64
-	err := databases.PostgresDB.QueryRow("SELECT id FROM snapp_users WHERE snapp_id = $1", u.SnappId).Scan(&u.Id)
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT id FROM snapp_users WHERE snapp_id = $1", u.SnappId).Scan(&u.Id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -26,3 +27,16 @@ func (u *SnappUser) GetUser() (bool, error) {
 	}
 	return true, err
 }
+
+// GetByID loads the user by id, returning false when it does not exist
+func (u *SnappUser) GetByID(ctx context.Context) (bool, error) {
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT snapp_id FROM snapp_users WHERE id = $1", u.Id).Scan(&u.SnappId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return true, nil
+}
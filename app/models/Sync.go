@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Types of the entities mobile clients sync
+const (
+	SyncVenue    = "venue"
+	SyncReview   = "review"
+	SyncCampaign = "campaign"
+)
+
+// SyncPosition is a position in the changes, the changes after it being
+// ordered by time, type and ID
+type SyncPosition struct {
+	ChangedAt time.Time
+	Type      string
+	ID        int64
+}
+
+// SyncChange is a change of an entity. Deleted entities were removed or are
+// no longer shown: deactivated venues and campaigns, and reviews that
+// aren't approved.
+type SyncChange struct {
+	SyncPosition
+	Deleted bool
+}
+
+// SyncTombstone tells clients to drop a deleted entity
+type SyncTombstone struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// syncChangesQuery lists the changes of the venues, reviews and campaigns
+// of the city ($1, any when NULL) after the position ($2-$4), and the
+// tombstones of removed ones. Reviews of deleted venues are left to clients
+// to drop with their venue.
+//
+// Changes are stamped with the start of their transaction, so one still in
+// flight can commit a change older than those already listed. Only changes
+// from before the oldest open transaction are listed, for a cursor never to
+// pass a change that isn't visible yet, holding them back for at most $7
+// seconds.
+const syncChangesQuery = `
+	WITH settled AS (
+		SELECT GREATEST(COALESCE(MIN(xact_start)::timestamp, LOCALTIMESTAMP),
+			LOCALTIMESTAMP - $7 * INTERVAL '1 second') AS before
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND backend_type = 'client backend'
+		  AND pid <> pg_backend_pid() AND xact_start IS NOT NULL
+	)
+	SELECT entity_type, id, changed_at, deleted FROM (
+		SELECT 'venue' AS entity_type, v.id, v.updated_at AS changed_at, NOT v.is_active AS deleted
+		FROM venues v
+		WHERE v.updated_at >= $2 AND ($1::bigint IS NULL OR v.city_id = $1)
+		  AND ($5::bigint IS NULL OR v.tenant_id = $5)
+		UNION ALL
+		SELECT 'review', r.id, r.updated_at, r.moderation_status <> 'approved'
+		FROM venue_reviews r
+		JOIN venues v ON r.venue_id = v.id
+		WHERE r.updated_at >= $2 AND ($1::bigint IS NULL OR v.city_id = $1)
+		  AND ($5::bigint IS NULL OR v.tenant_id = $5)
+		UNION ALL
+		SELECT 'campaign', c.id, c.updated_at, NOT c.is_active
+		FROM voting_campaigns c
+		WHERE c.updated_at >= $2 AND ($1::bigint IS NULL OR c.city_id = $1)
+		  AND ($5::bigint IS NULL OR c.tenant_id = $5)
+		UNION ALL
+		SELECT t.entity_type, t.entity_id, t.deleted_at, true
+		FROM sync_tombstones t
+		WHERE t.deleted_at >= $2 AND ($1::bigint IS NULL OR t.city_id = $1)
+		  AND ($5::bigint IS NULL OR t.tenant_id = $5)
+	) changes
+	WHERE (changed_at, entity_type, id) > ($2::timestamp, $3::text, $4::bigint)
+	  AND changed_at < (SELECT before FROM settled)
+	ORDER BY changed_at, entity_type, id
+	LIMIT $6`
+
+// SyncMaxHoldBack bounds how long changes wait for older transactions to
+// end, so a transaction left open doesn't stop sync. A change it commits
+// later than that is missed by clients that synced meanwhile.
+var SyncMaxHoldBack = time.Minute
+
+// GetSyncChanges returns at most limit changes after the position, oldest
+// first
+func GetSyncChanges(ctx context.Context, cityID *int64, after SyncPosition, limit int) ([]SyncChange, error) {
+	var city sql.NullInt64
+	if cityID != nil {
+		city = sql.NullInt64{Int64: *cityID, Valid: true}
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, syncChangesQuery,
+		city, after.ChangedAt, after.Type, after.ID, TenantFilter(ctx), limit, SyncMaxHoldBack.Seconds())
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]SyncChange, 0)
+	for rows.Next() {
+		var change SyncChange
+		if err := rows.Scan(&change.Type, &change.ID, &change.ChangedAt, &change.Deleted); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// ExpireSyncTombstones removes the tombstones of entities deleted before the
+// time, returning how many were removed
+func ExpireSyncTombstones(ctx context.Context, before time.Time) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM sync_tombstones WHERE deleted_at < $1", before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
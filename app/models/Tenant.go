@@ -0,0 +1,162 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// DefaultTenantID is the brand of requests no other tenant claims, and the
+// owner of rows created outside requests
+const DefaultTenantID int64 = 1
+
+// ErrTenantExists is returned when a tenant with the slug already exists
+var ErrTenantExists = errors.New("tenant already exists")
+
+// Tenant is a city brand the platform is hosted for. Venues, campaigns and
+// users belong to one tenant and requests only see their tenant's.
+type Tenant struct {
+	ID            int64          `json:"id"`
+	Slug          string         `json:"slug"` // Selects the tenant in the X-Tenant header
+	Name          string         `json:"name"`
+	Hostnames     []string       `json:"hostnames"`
+	Branding      TenantBranding `json:"branding"`
+	DefaultCityID *int64         `json:"defaultCityId,omitempty"`
+	IsActive      bool           `json:"isActive"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// TenantBranding is how the brand's clients look
+type TenantBranding struct {
+	AppName      string `json:"appName,omitempty"`
+	LogoURL      string `json:"logoUrl,omitempty"`
+	FaviconURL   string `json:"faviconUrl,omitempty"`
+	PrimaryColor string `json:"primaryColor,omitempty"`
+	SupportEmail string `json:"supportEmail,omitempty"`
+}
+
+func (t *Tenant) TableName() string {
+	return "tenants"
+}
+
+// Create stores the tenant, ErrTenantExists when its slug is taken
+func (t *Tenant) Create(ctx context.Context) error {
+	if t.Hostnames == nil {
+		t.Hostnames = []string{}
+	}
+	branding, err := json.Marshal(t.Branding)
+	if err != nil {
+		return err
+	}
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO tenants (slug, name, hostnames, branding, default_city_id, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING id, created_at`,
+		t.Slug, t.Name, pq.Array(t.Hostnames), branding, t.DefaultCityID, t.IsActive,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrTenantExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Update replaces the tenant's name, hostnames and configuration,
+// sql.ErrNoRows when it doesn't exist
+func (t *Tenant) Update(ctx context.Context) error {
+	if t.Hostnames == nil {
+		t.Hostnames = []string{}
+	}
+	branding, err := json.Marshal(t.Branding)
+	if err != nil {
+		return err
+	}
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE tenants SET name = $2, hostnames = $3, branding = $4, default_city_id = $5, is_active = $6
+		WHERE id = $1
+		RETURNING slug, created_at`,
+		t.ID, t.Name, pq.Array(t.Hostnames), branding, t.DefaultCityID, t.IsActive,
+	).Scan(&t.Slug, &t.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetActiveTenants returns every active tenant
+func GetActiveTenants(ctx context.Context) ([]Tenant, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, slug, name, hostnames, branding, default_city_id, is_active, created_at
+		FROM tenants
+		WHERE is_active = true
+		ORDER BY id`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := make([]Tenant, 0)
+	for rows.Next() {
+		var tenant Tenant
+		var branding []byte
+		var defaultCityID sql.NullInt64
+		err := rows.Scan(&tenant.ID, &tenant.Slug, &tenant.Name, pq.Array(&tenant.Hostnames),
+			&branding, &defaultCityID, &tenant.IsActive, &tenant.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if len(branding) > 0 {
+			if err := json.Unmarshal(branding, &tenant.Branding); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+		if defaultCityID.Valid {
+			tenant.DefaultCityID = &defaultCityID.Int64
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of the context scoping the models' queries to
+// the tenant
+func WithTenant(ctx context.Context, tenantID int64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant the context is scoped to. Contexts
+// outside requests, like those of background jobs, aren't scoped and see
+// every tenant.
+func TenantFromContext(ctx context.Context) (int64, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(int64)
+	return tenantID, ok
+}
+
+// TenantFilter is the tenant of the context as a query argument, NULL when
+// the context isn't scoped. Queries compare it as
+// ($n::bigint IS NULL OR tenant_id = $n).
+func TenantFilter(ctx context.Context) sql.NullInt64 {
+	tenantID, ok := TenantFromContext(ctx)
+	return sql.NullInt64{Int64: tenantID, Valid: ok}
+}
+
+// ownerTenant is the tenant rows created with the context belong to
+func ownerTenant(ctx context.Context) int64 {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		return tenantID
+	}
+	return DefaultTenantID
+}
@@ -1,12 +1,12 @@
 package models
 
 import (
+	"context"
 	"github.com/golang-jwt/jwt"
-	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
-	"os"
 	"time"
 	databases "voting-app/app"
+	"voting-app/app/config"
 )
 
 type User struct {
@@ -15,6 +15,10 @@ type User struct {
 	Password    string    `json:"password"`
 	IsSuperUser bool      `json:"isSuperUser"`
 	CreatedAt   time.Time `json:"createdAt"`
+	TenantID    int64     `json:"tenantId"`
+	// EmailVerifiedAt is set once the user opened the verification link
+	// sent to their email
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
 }
 
 func (u *User) SetPassword(password string) error {
@@ -22,37 +26,38 @@ func (u *User) SetPassword(password string) error {
 	u.Password = string(bytes)
 	return err
 }
-func (u *User) Get() error {
-	return databases.PostgresDB.QueryRow("SELECT id,password,is_superuser FROM users WHERE email = $1", u.Email).Scan(&u.Id, &u.Password, &u.IsSuperUser)
+func (u *User) Get(ctx context.Context) error {
+	return databases.PostgresDB.QueryRowContext(ctx, "SELECT id,password,is_superuser,email_verified_at,tenant_id FROM users WHERE email = $1 AND ($2::bigint IS NULL OR tenant_id = $2)", u.Email, TenantFilter(ctx)).Scan(&u.Id, &u.Password, &u.IsSuperUser, &u.EmailVerifiedAt, &u.TenantID)
 }
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
-func (u *User) Create() (err error) {
+func (u *User) Create(ctx context.Context) (err error) {
 	u.CreatedAt = time.Now().UTC()
-	_, err = databases.PostgresDB.Query("INSERT INTO users (email,password,is_superuser,created_at) VALUES ($1,$2,$3,$4)", u.Email, u.Password, true, u.CreatedAt)
+	u.TenantID = ownerTenant(ctx)
+	err = databases.PostgresDB.QueryRowContext(ctx, "INSERT INTO users (email,password,is_superuser,created_at,tenant_id) VALUES ($1,$2,$3,$4,$5) RETURNING id", u.Email, u.Password, true, u.CreatedAt, u.TenantID).Scan(&u.Id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (u *User) UpdatePassword() error {
-	_, err := databases.PostgresDB.Exec("UPDATE users SET password = $1 WHERE id = $2", u.Password, u.Id)
+func (u *User) UpdatePassword(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, "UPDATE users SET password = $1 WHERE id = $2", u.Password, u.Id)
 	return err
 }
 func (u *User) Auth() (access string, err error) {
-	godotenv.Load("local.env")
 	atClaims := jwt.MapClaims{}
 	now := time.Now().UTC()
 
 	atClaims["user_id"] = u.Id
 	atClaims["is_superuser"] = u.IsSuperUser
+	atClaims["tenant_id"] = u.TenantID
 	atClaims["exp"] = now.Add(time.Hour * 12).Unix()
 	atClaims["iat"] = now.Unix() // The time at which the token was issued.
 	atClaims["nbf"] = now.Unix()
 
-	access, err = jwt.NewWithClaims(jwt.SigningMethodHS512, atClaims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	access, err = jwt.NewWithClaims(jwt.SigningMethodHS512, atClaims).SignedString([]byte(config.Get().JWT.Secret))
 	if err != nil {
 		return access, err
 	}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Purposes users consent to
+const (
+	ConsentPushMarketing               = "push_marketing"
+	ConsentEmailDigest                 = "email_digest"
+	ConsentPersonalizedRecommendations = "personalized_recommendations"
+)
+
+// ConsentPurposes are all the purposes users consent to
+var ConsentPurposes = []string{ConsentPushMarketing, ConsentEmailDigest, ConsentPersonalizedRecommendations}
+
+// Consent statuses. Only granted consents allow their purpose.
+const (
+	ConsentNone    = "none" // Never asked for
+	ConsentPending = "pending"
+	ConsentGranted = "granted"
+	ConsentRevoked = "revoked"
+)
+
+// MaxConsentConfirmAttempts caps the codes tried against one consent request
+const MaxConsentConfirmAttempts = 5
+
+var (
+	// ErrConsentGranted is returned when asking for a consent the user
+	// already granted
+	ErrConsentGranted = errors.New("consent is already granted")
+	// ErrInvalidConsentCode is returned when the confirmation code is wrong,
+	// expired or was tried too often
+	ErrInvalidConsentCode = errors.New("confirmation code is invalid or expired")
+)
+
+// UserConsent is a user's consent to one purpose. Consents are double opt-in:
+// asking for one leaves it pending until the user confirms it with the code
+// sent to them.
+type UserConsent struct {
+	UserID  int64  `json:"userId"`
+	Purpose string `json:"purpose"`
+	Status  string `json:"status"`
+	// Source is where the user last changed the consent, like the app's
+	// settings or onboarding
+	Source      string     `json:"source,omitempty"`
+	RequestedAt *time.Time `json:"requestedAt,omitempty"`
+	GrantedAt   *time.Time `json:"grantedAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+
+	// ExpiresAt is when the code confirming a pending consent expires
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (c *UserConsent) TableName() string {
+	return "user_consents"
+}
+
+// IsConsentPurpose tells whether users consent to the purpose
+func IsConsentPurpose(purpose string) bool {
+	for _, p := range ConsentPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+const userConsentColumns = `user_id, purpose, status, COALESCE(source, ''),
+	requested_at, granted_at, revoked_at, CASE WHEN status = 'pending' THEN code_expires_at END`
+
+func scanUserConsent(row interface{ Scan(...interface{}) error }, c *UserConsent) error {
+	return row.Scan(&c.UserID, &c.Purpose, &c.Status, &c.Source,
+		&c.RequestedAt, &c.GrantedAt, &c.RevokedAt, &c.ExpiresAt)
+}
+
+// Request asks for the consent, leaving it pending until confirmed with the
+// code of the hash. Asking again replaces the code. ErrConsentGranted when
+// the consent is already granted.
+func (c *UserConsent) Request(ctx context.Context, codeHash string, expiresAt time.Time) error {
+	err := scanUserConsent(databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO user_consents (user_id, purpose, status, source, code_hash, code_expires_at, requested_at, updated_at)
+		VALUES ($1, $2, 'pending', $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, purpose) DO UPDATE SET
+			status = 'pending', source = EXCLUDED.source, code_hash = EXCLUDED.code_hash,
+			code_expires_at = EXCLUDED.code_expires_at, attempts = 0,
+			requested_at = EXCLUDED.requested_at, updated_at = EXCLUDED.updated_at
+		WHERE user_consents.status <> 'granted'
+		RETURNING `+userConsentColumns,
+		c.UserID, c.Purpose, c.Source, codeHash, expiresAt,
+	), c)
+	if err == sql.ErrNoRows {
+		return ErrConsentGranted
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Confirm grants the pending consent when the code of the hash is the one
+// sent for it. Every wrong code counts as an attempt.
+func (c *UserConsent) Confirm(ctx context.Context, codeHash string, now time.Time) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var storedHash string
+	var attempts int
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT code_hash, attempts, code_expires_at
+		FROM user_consents
+		WHERE user_id = $1 AND purpose = $2 AND status = 'pending'
+		FOR UPDATE`,
+		c.UserID, c.Purpose).Scan(&storedHash, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidConsentCode
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if attempts >= MaxConsentConfirmAttempts || !expiresAt.After(now) {
+		return ErrInvalidConsentCode
+	}
+
+	if storedHash != codeHash {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE user_consents SET attempts = attempts + 1 WHERE user_id = $1 AND purpose = $2",
+			c.UserID, c.Purpose)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		return ErrInvalidConsentCode
+	}
+
+	err = scanUserConsent(tx.QueryRowContext(ctx, `
+		UPDATE user_consents
+		SET status = 'granted', granted_at = $3, revoked_at = NULL, code_hash = NULL, updated_at = $3
+		WHERE user_id = $1 AND purpose = $2
+		RETURNING `+userConsentColumns,
+		c.UserID, c.Purpose, now), c)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// Revoke withdraws the consent, or records that the user declined it when
+// they never gave it. Pending consents can't be confirmed anymore.
+func (c *UserConsent) Revoke(ctx context.Context) error {
+	err := scanUserConsent(databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO user_consents (user_id, purpose, status, source, revoked_at, updated_at)
+		VALUES ($1, $2, 'revoked', $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, purpose) DO UPDATE SET
+			status = 'revoked', source = EXCLUDED.source, code_hash = NULL,
+			revoked_at = EXCLUDED.revoked_at, updated_at = EXCLUDED.updated_at
+		RETURNING `+userConsentColumns,
+		c.UserID, c.Purpose, c.Source,
+	), c)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetUserConsents returns the user's consent to each purpose, in the order
+// of ConsentPurposes. Purposes the user was never asked for are "none".
+func GetUserConsents(ctx context.Context, userID int64) ([]UserConsent, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		"SELECT "+userConsentColumns+" FROM user_consents WHERE user_id = $1",
+		userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]UserConsent)
+	for rows.Next() {
+		var consent UserConsent
+		if err := scanUserConsent(rows, &consent); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		stored[consent.Purpose] = consent
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	consents := make([]UserConsent, len(ConsentPurposes))
+	for i, purpose := range ConsentPurposes {
+		consent, exists := stored[purpose]
+		if !exists {
+			consent = UserConsent{UserID: userID, Purpose: purpose, Status: ConsentNone}
+		}
+		consents[i] = consent
+	}
+	return consents, nil
+}
+
+// HasConsent tells whether the user granted the consent to the purpose
+func HasConsent(ctx context.Context, userID int64, purpose string) (bool, error) {
+	var granted bool
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM user_consents WHERE user_id = $1 AND purpose = $2 AND status = 'granted'
+		)`,
+		userID, purpose).Scan(&granted)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return granted, nil
+}
+
+// GetConsentedUserIDs returns the users who granted the consent to the
+// purpose
+func GetConsentedUserIDs(ctx context.Context, purpose string) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		"SELECT user_id FROM user_consents WHERE purpose = $1 AND status = 'granted' ORDER BY user_id",
+		purpose)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]int64, 0)
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// FilterConsentedUsers returns the users among userIDs who granted the
+// consent to the purpose, in no particular order
+func FilterConsentedUsers(ctx context.Context, userIDs []int64, purpose string) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT user_id FROM user_consents
+		WHERE user_id = ANY($1) AND purpose = $2 AND status = 'granted'`,
+		pq.Array(userIDs), purpose)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	consented := make([]int64, 0, len(userIDs))
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		consented = append(consented, userID)
+	}
+	return consented, rows.Err()
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Push notification platforms
+const (
+	PlatformAPNs = "apns"
+	PlatformFCM  = "fcm"
+)
+
+// UserDevice represents a device registered for push notifications
+type UserDevice struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"userId"`
+	Platform   string    `json:"platform"` // apns, fcm
+	Token      string    `json:"token"`
+	AppVersion string    `json:"appVersion,omitempty"`
+	IsActive   bool      `json:"isActive"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (d *UserDevice) TableName() string {
+	return "user_devices"
+}
+
+// Register stores the device token, moving it to the current user if it was
+// previously registered by someone else (e.g. after a logout/login)
+func (d *UserDevice) Register(ctx context.Context) error {
+	query := `
+		INSERT INTO user_devices (user_id, platform, token, app_version)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform,
+			app_version = EXCLUDED.app_version,
+			is_active = true,
+			last_seen_at = CURRENT_TIMESTAMP
+		RETURNING id, is_active, last_seen_at, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, d.UserID, d.Platform, d.Token, d.AppVersion,
+	).Scan(&d.ID, &d.IsActive, &d.LastSeenAt, &d.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Deactivate disables a token the push provider reported as invalid
+func (d *UserDevice) Deactivate(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"UPDATE user_devices SET is_active = false WHERE token = $1",
+		d.Token,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetUserDevices returns the active devices of a user
+func GetUserDevices(ctx context.Context, userID int64) ([]UserDevice, error) {
+	query := `
+		SELECT id, user_id, platform, token, app_version, is_active, last_seen_at, created_at
+		FROM user_devices
+		WHERE user_id = $1 AND is_active = true`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]UserDevice, 0)
+	for rows.Next() {
+		var device UserDevice
+		var appVersion sql.NullString
+
+		err := rows.Scan(
+			&device.ID, &device.UserID, &device.Platform, &device.Token,
+			&appVersion, &device.IsActive, &device.LastSeenAt, &device.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		device.AppVersion = appVersion.String
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,296 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Relation types between users
+const (
+	RelationBlock = "block"
+	RelationMute  = "mute"
+)
+
+// ErrUserBlocked is returned when the target user blocked the acting user
+var ErrUserBlocked = errors.New("user is blocked")
+
+// UserRelation is a block or mute of a user by another user. Blocked users
+// cannot interact with the blocker, muted users can but are hidden from them.
+type UserRelation struct {
+	UserID       int64     `json:"userId"`
+	TargetUserID int64     `json:"targetUserId"`
+	TargetName   string    `json:"targetName,omitempty"`
+	Type         string    `json:"type"` // block, mute
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// relationTable returns the table a relation type is stored in
+func relationTable(relationType string) string {
+	if relationType == RelationMute {
+		return "user_mutes"
+	}
+	return "user_blocks"
+}
+
+// HiddenUsersCondition returns a SQL condition excluding the users a viewer
+// blocked or muted. column is the user id column to filter and placeholder
+// the parameter holding the viewer id, e.g. "$3".
+func HiddenUsersCondition(column, placeholder string) string {
+	return fmt.Sprintf(`%s NOT IN (
+		SELECT target_user_id FROM user_blocks WHERE user_id = %s
+		UNION SELECT target_user_id FROM user_mutes WHERE user_id = %s)`,
+		column, placeholder, placeholder)
+}
+
+// Create stores the relation. Blocking also removes follows in both
+// directions so neither user keeps seeing the other's activity.
+func (r *UserRelation) Create(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (user_id, target_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, target_user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING created_at`, relationTable(r.Type))
+
+	if err := tx.QueryRowContext(ctx, query, r.UserID, r.TargetUserID).Scan(&r.CreatedAt); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if r.Type == RelationBlock {
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM user_follows
+			WHERE (follower_id = $1 AND following_id = $2)
+			   OR (follower_id = $2 AND following_id = $1)`,
+			r.UserID, r.TargetUserID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// Delete removes the relation, returning false when it did not exist
+func (r *UserRelation) Delete(ctx context.Context) (bool, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1 AND target_user_id = $2", relationTable(r.Type))
+
+	result, err := databases.PostgresDB.ExecContext(ctx, query, r.UserID, r.TargetUserID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// GetUserRelations returns the users a user blocked or muted
+func GetUserRelations(ctx context.Context, userID int64, relationType string) ([]UserRelation, error) {
+	query := fmt.Sprintf(`
+		SELECT r.user_id, r.target_user_id, COALESCE(u.snapp_id, ''), r.created_at
+		FROM %s r
+		LEFT JOIN snapp_users u ON u.id = r.target_user_id
+		WHERE r.user_id = $1
+		ORDER BY r.created_at DESC`, relationTable(relationType))
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := make([]UserRelation, 0)
+	for rows.Next() {
+		relation := UserRelation{Type: relationType}
+		if err := rows.Scan(&relation.UserID, &relation.TargetUserID, &relation.TargetName, &relation.CreatedAt); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, nil
+}
+
+// IsBlockedBy reports whether userID was blocked by blockerID
+func IsBlockedBy(ctx context.Context, userID, blockerID int64) (bool, error) {
+	var blocked bool
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM user_blocks WHERE user_id = $1 AND target_user_id = $2)",
+		blockerID, userID,
+	).Scan(&blocked)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return blocked, err
+}
+
+// UserFollow is a user following another user's activity
+type UserFollow struct {
+	FollowerID  int64     `json:"followerId"`
+	FollowingID int64     `json:"followingId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Create follows the user unless the follower was blocked by them
+func (f *UserFollow) Create(ctx context.Context) error {
+	blocked, err := IsBlockedBy(ctx, f.FollowerID, f.FollowingID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+
+	query := `
+		INSERT INTO user_follows (follower_id, following_id)
+		VALUES ($1, $2)
+		ON CONFLICT (follower_id, following_id) DO UPDATE SET follower_id = EXCLUDED.follower_id
+		RETURNING created_at`
+
+	err = databases.PostgresDB.QueryRowContext(ctx, query, f.FollowerID, f.FollowingID).Scan(&f.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete unfollows the user, returning false when they were not followed
+func (f *UserFollow) Delete(ctx context.Context) (bool, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM user_follows WHERE follower_id = $1 AND following_id = $2",
+		f.FollowerID, f.FollowingID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// Social feed activity types
+const (
+	ActivityReview        = "review"
+	ActivityCheckin       = "checkin"
+	ActivityCollectionAdd = "collection_add"
+)
+
+// FeedActivity is a review or check-in of a followed user, or a venue added
+// to a collection
+type FeedActivity struct {
+	Type      string    `json:"type"` // review, checkin, collection_add
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	UserName  string    `json:"userName,omitempty"`
+	VenueID   int64     `json:"venueId"`
+	VenueName string    `json:"venueName"`
+	Rating    *float64  `json:"rating,omitempty"`
+	Text      string    `json:"text,omitempty"` // The review, check-in message or collection note
+	CreatedAt time.Time `json:"createdAt"`
+
+	// The collection the venue was added to
+	CollectionID   *int64 `json:"collectionId,omitempty"`
+	CollectionName string `json:"collectionName,omitempty"`
+	// Summary describes collection activities, e.g. "alex added Blue Bottle
+	// to Coffee crawl"
+	Summary string `json:"summary,omitempty"`
+}
+
+// GetSocialFeed returns the newest reviews and public check-ins of the users
+// a user follows, and the venues they added to public collections. Venues
+// others added to the collections the user owns or collaborates on are
+// included too. Anyone the user blocked or muted is left out.
+func GetSocialFeed(ctx context.Context, userID int64, limit, offset int) ([]FeedActivity, error) {
+	query := `
+		SELECT type, id, user_id, user_name, venue_id, venue_name, rating, text, created_at,
+			   collection_id, collection_name
+		FROM (
+			SELECT 'review' AS type, r.id, r.user_id, COALESCE(u.snapp_id, '') AS user_name,
+				   r.venue_id, v.name AS venue_name, r.overall_rating AS rating,
+				   COALESCE(r.review_text, '') AS text, r.created_at,
+				   NULL::bigint AS collection_id, NULL::text AS collection_name
+			FROM venue_reviews r
+			JOIN venues v ON v.id = r.venue_id
+			LEFT JOIN snapp_users u ON u.id = r.user_id
+			WHERE r.moderation_status = 'approved'
+			  AND r.user_id IN (SELECT following_id FROM user_follows WHERE follower_id = $1)
+			  AND ` + HiddenUsersCondition("r.user_id", "$1") + `
+			UNION ALL
+			SELECT 'checkin', c.id, c.user_id, COALESCE(u.snapp_id, ''),
+				   c.venue_id, v.name, c.rating,
+				   COALESCE(c.message, ''), c.created_at,
+				   NULL, NULL
+			FROM venue_checkins c
+			JOIN venues v ON v.id = c.venue_id
+			LEFT JOIN snapp_users u ON u.id = c.user_id
+			WHERE c.is_public = true AND COALESCE(c.is_flagged, false) = false
+			  AND c.user_id IN (SELECT following_id FROM user_follows WHERE follower_id = $1)
+			  AND ` + HiddenUsersCondition("c.user_id", "$1") + `
+			UNION ALL
+			SELECT 'collection_add', i.id, i.added_by, COALESCE(u.snapp_id, ''),
+				   i.venue_id, v.name, NULL,
+				   COALESCE(i.note, ''), i.added_at,
+				   vc.id, vc.name
+			FROM venue_collection_items i
+			JOIN venue_collections vc ON vc.id = i.collection_id
+			JOIN venues v ON v.id = i.venue_id
+			LEFT JOIN snapp_users u ON u.id = i.added_by
+			WHERE i.added_by <> $1
+			  AND ((vc.is_public = true AND i.added_by IN (SELECT following_id FROM user_follows WHERE follower_id = $1))
+				OR vc.user_id = $1
+				OR vc.id IN (SELECT collection_id FROM collection_collaborators WHERE user_id = $1 AND status = 'accepted'))
+			  AND ` + HiddenUsersCondition("i.added_by", "$1") + `
+		) activity
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := make([]FeedActivity, 0)
+	for rows.Next() {
+		var activity FeedActivity
+		var collectionName sql.NullString
+		err := rows.Scan(
+			&activity.Type, &activity.ID, &activity.UserID, &activity.UserName,
+			&activity.VenueID, &activity.VenueName, &activity.Rating, &activity.Text, &activity.CreatedAt,
+			&activity.CollectionID, &collectionName,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if activity.Type == ActivityCollectionAdd {
+			activity.CollectionName = collectionName.String
+			activity.Summary = fmt.Sprintf("%s added %s to %s", activity.UserName, activity.VenueName, activity.CollectionName)
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, nil
+}
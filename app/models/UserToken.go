@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Purposes of the single use tokens emailed to users
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+)
+
+var (
+	// ErrInvalidUserToken is returned when a token is unknown, used or
+	// expired
+	ErrInvalidUserToken = errors.New("token is invalid or expired")
+	// ErrUserTokenThrottled is returned when the user was sent a token of
+	// the same purpose too recently
+	ErrUserTokenThrottled = errors.New("token was sent recently")
+)
+
+// UserToken is a single use token emailed to a user, for verifying their
+// email or resetting their password. Only the hash of the token is stored.
+type UserToken struct {
+	UserID    int64
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+func (t *UserToken) TableName() string {
+	return "user_tokens"
+}
+
+// Create stores the token, replacing the user's unused tokens of the same
+// purpose. ErrUserTokenThrottled when the last one was created less than
+// minInterval ago.
+func (t *UserToken) Create(ctx context.Context, minInterval time.Duration) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serializes the token requests of the user
+	_, err = tx.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", t.UserID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	var recent bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $3)
+		)`,
+		t.UserID, t.Purpose, minInterval.Seconds()).Scan(&recent)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if recent {
+		return ErrUserTokenThrottled
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		t.UserID, t.Purpose)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// consumeUserToken marks the token used, returning the user it was sent to
+func consumeUserToken(ctx context.Context, tx *sql.Tx, purpose, tokenHash string, now time.Time) (int64, error) {
+	var userID int64
+	err := tx.QueryRowContext(ctx, `
+		UPDATE user_tokens SET used_at = $3
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING user_id`,
+		purpose, tokenHash, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidUserToken
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return userID, nil
+}
+
+// VerifyUserEmail marks the email of the user the verification token was
+// sent to as verified
+func VerifyUserEmail(ctx context.Context, tokenHash string, now time.Time) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(ctx, tx, UserTokenVerifyEmail, tokenHash, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, $2) WHERE id = $1",
+		userID, now)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// ResetUserPassword sets the password of the user the reset token was sent
+// to. Receiving the token proves the user owns the email, so it is verified
+// as well, and their other reset tokens stop working.
+func ResetUserPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(ctx, tx, UserTokenResetPassword, tokenHash, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET password = $2, email_verified_at = COALESCE(email_verified_at, $3)
+		WHERE id = $1`,
+		userID, passwordHash, now)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userID, UserTokenResetPassword)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredUserTokens removes tokens that can no longer be used,
+// returning how many were removed
+func DeleteExpiredUserTokens(ctx context.Context, now time.Time) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM user_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"github.com/getsentry/sentry-go"
 	databases "voting-app/app"
@@ -13,11 +14,11 @@ type UserVoting struct {
 	VoteId   int64 `json:"vote"`
 }
 
-func (u *UserVoting) SubmitVote() bool {
+func (u *UserVoting) SubmitVote(ctx context.Context) bool {
 	//var total
-	//databases.PostgresDB.QueryRow("SELECT COUNT(*) FROM user_voting WHERE voting_id = $1 AND owner = $2", u.VotingId, u.Owner).Scan(&u.Vote)
+	//databases.PostgresDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_voting WHERE voting_id = $1 AND owner = $2", u.VotingId, u.Owner).Scan(&u.Vote)
 	var totalCount int64
-	err := databases.PostgresDB.QueryRow("SELECT COUNT(id) FROM user_voting WHERE voting_id = $1 AND owner_id= $2", u.VotingId, u.OwnerId).Scan(&totalCount)
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT COUNT(id) FROM user_voting WHERE voting_id = $1 AND owner_id= $2", u.VotingId, u.OwnerId).Scan(&totalCount)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			return true
@@ -27,7 +28,7 @@ func (u *UserVoting) SubmitVote() bool {
 	if totalCount > 0 {
 		return true
 	}
-	_, err = databases.PostgresDB.Query("INSERT INTO user_voting (owner_id, voting_id,vote_id) VALUES ($1,$2,$3)", u.OwnerId, u.VotingId, u.VoteId)
+	err = databases.PostgresDB.QueryRowContext(ctx, "INSERT INTO user_voting (owner_id, voting_id,vote_id) VALUES ($1,$2,$3) RETURNING id", u.OwnerId, u.VotingId, u.VoteId).Scan(&u.Id)
 	if err != nil {
 		sentry.CaptureException(err)
 		return false
@@ -36,9 +37,9 @@ func (u *UserVoting) SubmitVote() bool {
 	return false
 }
 
-func (u *UserVoting) GetUserVoteCounts() int64 {
+func (u *UserVoting) GetUserVoteCounts(ctx context.Context) int64 {
 	var totalCount int64
-	err := databases.PostgresDB.QueryRow("SELECT COUNT(id) FROM user_voting WHERE voting_id = $1 AND vote_id= $2", u.VotingId, u.VoteId).Scan(&totalCount)
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT COUNT(id) FROM user_voting WHERE voting_id = $1 AND vote_id= $2", u.VotingId, u.VoteId).Scan(&totalCount)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			return 0
@@ -47,3 +48,85 @@ func (u *UserVoting) GetUserVoteCounts() int64 {
 	}
 	return totalCount
 }
+
+// Exists reports whether the vote is recorded for the voting and participant
+func (u *UserVoting) Exists(ctx context.Context) bool {
+	var totalCount int64
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT COUNT(id) FROM user_voting WHERE id = $1 AND voting_id = $2 AND vote_id = $3", u.Id, u.VotingId, u.VoteId).Scan(&totalCount)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false
+	}
+	return totalCount > 0
+}
+
+// GetVotingVoteCounts returns the number of votes of each participant of the
+// voting by participant ID
+func GetVotingVoteCounts(ctx context.Context, votingID int64) (map[int64]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, "SELECT vote_id, COUNT(id) FROM user_voting WHERE voting_id = $1 GROUP BY vote_id", votingID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int64)
+	for rows.Next() {
+		var participantID, votes int64
+		if err := rows.Scan(&participantID, &votes); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		counts[participantID] = votes
+	}
+	return counts, nil
+}
+
+// GetUserVote loads the participant the owner voted for in the voting,
+// reporting whether they voted
+func (u *UserVoting) GetUserVote(ctx context.Context) (bool, error) {
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT id, vote_id FROM user_voting WHERE voting_id = $1 AND owner_id = $2 ORDER BY id LIMIT 1", u.VotingId, u.OwnerId).Scan(&u.Id, &u.VoteId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// VotingHistoryEntry is a vote a snapp user cast in a legacy voting
+type VotingHistoryEntry struct {
+	VotingID        int64  `json:"votingId"`
+	VotingName      string `json:"votingName"`
+	ParticipantID   int64  `json:"participantId"`
+	ParticipantName string `json:"participantName"`
+}
+
+// GetUserVotingHistory returns the owner's legacy votes, latest voting first
+func GetUserVotingHistory(ctx context.Context, ownerID int64) ([]VotingHistoryEntry, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT voting.id, voting.name, participants.id, participants.name
+		FROM user_voting
+		INNER JOIN voting ON voting.id = user_voting.voting_id
+		INNER JOIN participants ON participants.id = user_voting.vote_id
+		WHERE user_voting.owner_id = $1
+		ORDER BY voting.ended_at DESC, user_voting.id DESC`, ownerID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]VotingHistoryEntry, 0)
+	for rows.Next() {
+		var entry VotingHistoryEntry
+		if err := rows.Scan(&entry.VotingID, &entry.VotingName, &entry.ParticipantID, &entry.ParticipantName); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
@@ -1,10 +1,13 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+	"sync"
 	"time"
 	databases "voting-app/app"
 )
@@ -25,6 +28,11 @@ type Venue struct {
 	Longitude  float64 `json:"longitude"`
 	PostalCode string  `json:"postalCode,omitempty"`
 
+	// Neighborhood is set when the venue lies within one of the city's
+	// neighborhoods, without its boundary
+	NeighborhoodID *int64        `json:"neighborhoodId,omitempty"`
+	Neighborhood   *Neighborhood `json:"neighborhood,omitempty"`
+
 	// Category
 	CategoryID    int64             `json:"categoryId"`
 	Category      *VenueCategory    `json:"category,omitempty"`
@@ -52,6 +60,9 @@ type Venue struct {
 	TotalRatings  int     `json:"totalRatings"`
 	TotalReviews  int     `json:"totalReviews"`
 
+	// TotalFavorites is how many users favorited the venue
+	TotalFavorites int `json:"totalFavorites"`
+
 	// Features
 	Amenities json.RawMessage `json:"amenities,omitempty"`
 
@@ -66,10 +77,19 @@ type Venue struct {
 
 	// Computed fields
 	Distance      *float64 `json:"distance,omitempty"`      // Distance from user in km
+	TravelMinutes *float64 `json:"travelMinutes,omitempty"` // Trip from user, with a travel mode
 	IsOpen        *bool    `json:"isOpen,omitempty"`        // Currently open
 	NextOpenTime  *string  `json:"nextOpenTime,omitempty"`  // When it opens next
 	ReviewSummary *string  `json:"reviewSummary,omitempty"` // AI-generated summary
 
+	// Busyness is the live crowd level and wait time, unset without recent
+	// reports
+	Busyness *VenueBusyness `json:"busyness,omitempty"`
+
+	// Deals are the venue's running deals, set in its details and nearby
+	// results
+	Deals []VenueDeal `json:"deals,omitempty"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -98,56 +118,120 @@ type City struct {
 	Latitude  float64 `json:"latitude,omitempty"`
 	Longitude float64 `json:"longitude,omitempty"`
 	Timezone  string  `json:"timezone,omitempty"`
+
+	// HasCoordinates is false when the city has no latitude and longitude
+	HasCoordinates bool `json:"-"`
+	// IsActive is false for cities being onboarded, which aren't listed
+	IsActive bool `json:"-"`
 }
 
 // VenueSearchParams for advanced venue discovery
 type VenueSearchParams struct {
-	Query         string   `json:"query,omitempty"`
-	CategoryID    *int64   `json:"categoryId,omitempty"`
-	SubcategoryID *int64   `json:"subcategoryId,omitempty"`
-	CityID        *int64   `json:"cityId,omitempty"`
-	Latitude      *float64 `json:"latitude,omitempty"`
-	Longitude     *float64 `json:"longitude,omitempty"`
-	Radius        *float64 `json:"radius,omitempty"` // in km
-	PriceRange    []string `json:"priceRange,omitempty"`
-	MinRating     *float64 `json:"minRating,omitempty"`
-	Amenities     []string `json:"amenities,omitempty"`
-	IsOpen        *bool    `json:"isOpen,omitempty"`
-	IsFeatured    *bool    `json:"isFeatured,omitempty"`
-	SortBy        string   `json:"sortBy,omitempty"` // rating, distance, popularity, newest
-	Page          int      `json:"page"`
-	Limit         int      `json:"limit"`
+	Query          string   `json:"query,omitempty"`
+	CategoryID     *int64   `json:"categoryId,omitempty"`
+	SubcategoryID  *int64   `json:"subcategoryId,omitempty"`
+	CityID         *int64   `json:"cityId,omitempty"`
+	NeighborhoodID *int64   `json:"neighborhoodId,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+	Radius         *float64 `json:"radius,omitempty"` // in km
+	PriceRange     []string `json:"priceRange,omitempty"`
+	MinRating      *float64 `json:"minRating,omitempty"`
+	Amenities      []string `json:"amenities,omitempty"`
+	IsOpen         *bool    `json:"isOpen,omitempty"`
+	IsFeatured     *bool    `json:"isFeatured,omitempty"`
+	SortBy         string   `json:"sortBy,omitempty"` // rating, distance, popularity, newest, trending
+	Page           int      `json:"page"`
+	Limit          int      `json:"limit"`
+
+	// EstimateTotal returns the query planner's estimate as the total of
+	// broad searches instead of counting every match
+	EstimateTotal bool `json:"estimateTotal,omitempty"`
+
+	// CreatedAfter restricts the search to venues added after the given time
+	CreatedAfter *time.Time `json:"-"`
+}
+
+// EstimatedTotalThreshold is the planner's row estimate from which searches
+// asking for an estimated total skip the exact count
+const EstimatedTotalThreshold = 1000
+
+// SearchTotal is the number of venues matching a search
+type SearchTotal struct {
+	Count int
+	Exact bool // false when Count is the query planner's estimate
 }
 
 func (v *Venue) TableName() string {
 	return "venues"
 }
 
-// GetByID retrieves a venue by ID with all related data
-func (v *Venue) GetByID() error {
-	query := `
+// venueDetailQuery selects the venues with all related data scanned by
+// Venue.scanDetail, followed by the WHERE clause
+const venueDetailQuery = `
 		SELECT v.id, v.name, v.slug, v.description, v.short_description,
 			   v.address, v.city_id, v.latitude, v.longitude, v.postal_code,
 			   v.category_id, v.subcategory_id, v.phone, v.email, v.website,
 			   v.opening_hours, v.price_range, v.average_cost_per_person,
 			   v.cover_image, v.logo, v.average_rating, v.total_ratings, v.total_reviews,
-			   v.amenities, v.is_active, v.is_verified, v.is_featured,
+			   v.total_favorites, v.amenities, v.is_active, v.is_verified, v.is_featured,
 			   v.owner_id, v.claimed_at, v.created_at, v.updated_at,
 			   c.name as city_name, c.state, c.country,
 			   cat.name as category_name, cat.icon as category_icon,
-			   sub.name as subcategory_name
+			   sub.name as subcategory_name,
+			   v.neighborhood_id, n.name as neighborhood_name, n.slug as neighborhood_slug
 		FROM venues v
 		LEFT JOIN cities c ON v.city_id = c.id
 		LEFT JOIN venue_categories cat ON v.category_id = cat.id
 		LEFT JOIN venue_subcategories sub ON v.subcategory_id = sub.id
-		WHERE v.id = $1 AND v.is_active = true`
+		LEFT JOIN neighborhoods n ON v.neighborhood_id = n.id`
 
-	row := databases.PostgresDB.QueryRow(query, v.ID)
+// GetByID retrieves a venue by ID with all related data
+func (v *Venue) GetByID(ctx context.Context) error {
+	query := venueDetailQuery + `
+		WHERE v.id = $1 AND v.is_active = true AND ($2::bigint IS NULL OR v.tenant_id = $2)`
+	err := v.scanDetail(databases.PostgresDB.QueryRowContext(ctx, query, v.ID, TenantFilter(ctx)))
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetVenuesByIDs returns the active venues among the IDs with all related
+// data, in no particular order
+func GetVenuesByIDs(ctx context.Context, ids []int64) ([]Venue, error) {
+	venues := make([]Venue, 0, len(ids))
+	if len(ids) == 0 {
+		return venues, nil
+	}
+
+	query := venueDetailQuery + `
+		WHERE v.id = ANY($1) AND v.is_active = true AND ($2::bigint IS NULL OR v.tenant_id = $2)`
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, pq.Array(ids), TenantFilter(ctx))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
 
-	var subcategoryID sql.NullInt64
+	for rows.Next() {
+		var venue Venue
+		if err := venue.scanDetail(rows); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		venues = append(venues, venue)
+	}
+	return venues, rows.Err()
+}
+
+// scanDetail reads a row of venueDetailQuery
+func (v *Venue) scanDetail(row interface{ Scan(...interface{}) error }) error {
+	var subcategoryID, neighborhoodID sql.NullInt64
 	var ownerID sql.NullInt64
 	var claimedAt sql.NullTime
 	var cityName, state, country, categoryName, categoryIcon, subcategoryName sql.NullString
+	var neighborhoodName, neighborhoodSlug sql.NullString
 
 	err := row.Scan(
 		&v.ID, &v.Name, &v.Slug, &v.Description, &v.ShortDesc,
@@ -155,17 +239,14 @@ func (v *Venue) GetByID() error {
 		&v.CategoryID, &subcategoryID, &v.Phone, &v.Email, &v.Website,
 		&v.OpeningHours, &v.PriceRange, &v.AvgCostPerPerson,
 		&v.CoverImage, &v.Logo, &v.AverageRating, &v.TotalRatings, &v.TotalReviews,
-		&v.Amenities, &v.IsActive, &v.IsVerified, &v.IsFeatured,
+		&v.TotalFavorites, &v.Amenities, &v.IsActive, &v.IsVerified, &v.IsFeatured,
 		&ownerID, &claimedAt, &v.CreatedAt, &v.UpdatedAt,
 		&cityName, &state, &country,
 		&categoryName, &categoryIcon,
 		&subcategoryName,
+		&neighborhoodID, &neighborhoodName, &neighborhoodSlug,
 	)
-
 	if err != nil {
-		if err != sql.ErrNoRows {
-			sentry.CaptureException(err)
-		}
 		return err
 	}
 
@@ -206,18 +287,36 @@ func (v *Venue) GetByID() error {
 		}
 	}
 
+	if neighborhoodID.Valid {
+		v.NeighborhoodID = &neighborhoodID.Int64
+		v.Neighborhood = &Neighborhood{
+			ID:     neighborhoodID.Int64,
+			CityID: v.CityID,
+			Name:   neighborhoodName.String,
+			Slug:   neighborhoodSlug.String,
+		}
+	}
+
 	return nil
 }
 
 // Search performs advanced venue search with filters and location
-func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
+func (v *Venue) Search(ctx context.Context, params VenueSearchParams) ([]Venue, int, error) {
+	venues, total, err := v.SearchWithTotal(ctx, params)
+	return venues, total.Count, err
+}
+
+// SearchWithTotal performs the search, telling whether the total is exact.
+// Searches with EstimateTotal set are only counted exactly when the planner
+// expects fewer than EstimatedTotalThreshold matches.
+func (v *Venue) SearchWithTotal(ctx context.Context, params VenueSearchParams) ([]Venue, SearchTotal, error) {
 	// Build dynamic query based on search parameters
 	baseQuery := `
-		SELECT v.id, v.name, v.slug, v.short_description,
+		SELECT v.id, v.name, v.slug, COALESCE(v.short_description, ''),
 			   v.address, v.latitude, v.longitude,
-			   v.category_id, v.phone, v.website,
-			   v.price_range, v.average_rating, v.total_ratings,
-			   v.cover_image, v.is_featured,
+			   v.category_id, COALESCE(v.phone, ''), COALESCE(v.website, ''),
+			   COALESCE(v.price_range, ''), v.average_rating, v.total_ratings, v.total_favorites,
+			   COALESCE(v.cover_image, ''), v.is_featured, v.neighborhood_id,
 			   c.name as city_name,
 			   cat.name as category_name, cat.icon as category_icon`
 
@@ -235,54 +334,7 @@ func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
 		LEFT JOIN cities c ON v.city_id = c.id
 		LEFT JOIN venue_categories cat ON v.category_id = cat.id`
 
-	whereClause := "WHERE v.is_active = true"
-	var args []interface{}
-	argCount := 0
-
-	// Add search filters
-	if params.Query != "" {
-		argCount++
-		whereClause += fmt.Sprintf(" AND (v.name ILIKE $%d OR v.description ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+params.Query+"%")
-	}
-
-	if params.CategoryID != nil {
-		argCount++
-		whereClause += fmt.Sprintf(" AND v.category_id = $%d", argCount)
-		args = append(args, *params.CategoryID)
-	}
-
-	if params.CityID != nil {
-		argCount++
-		whereClause += fmt.Sprintf(" AND v.city_id = $%d", argCount)
-		args = append(args, *params.CityID)
-	}
-
-	if params.MinRating != nil {
-		argCount++
-		whereClause += fmt.Sprintf(" AND v.average_rating >= $%d", argCount)
-		args = append(args, *params.MinRating)
-	}
-
-	if len(params.PriceRange) > 0 {
-		argCount++
-		whereClause += fmt.Sprintf(" AND v.price_range = ANY($%d)", argCount)
-		args = append(args, params.PriceRange)
-	}
-
-	// Location radius filter
-	if params.Latitude != nil && params.Longitude != nil && params.Radius != nil {
-		argCount += 3
-		whereClause += fmt.Sprintf(` AND ST_DWithin(
-			ST_Point(v.longitude, v.latitude)::geography,
-			ST_Point($%d, $%d)::geography,
-			$%d * 1000)`, argCount-2, argCount-1, argCount)
-		args = append(args, *params.Longitude, *params.Latitude, *params.Radius)
-	}
-
-	if params.IsFeatured != nil && *params.IsFeatured {
-		whereClause += " AND v.is_featured = true"
-	}
+	whereClause, args := params.whereClause(ctx)
 
 	// Sorting
 	var orderBy string
@@ -297,6 +349,8 @@ func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
 		}
 	case "newest":
 		orderBy = "ORDER BY v.created_at DESC"
+	case "trending":
+		orderBy = "ORDER BY " + trendingScoreExpression + " DESC, v.average_rating DESC, v.id"
 	default:
 		orderBy = "ORDER BY v.is_featured DESC, v.average_rating DESC, v.total_ratings DESC"
 	}
@@ -313,12 +367,12 @@ func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
 	limitClause := fmt.Sprintf(" LIMIT %d OFFSET %d", params.Limit, offset)
 
 	// Execute query
-	fullQuery := baseQuery + distanceSelect + fromClause + whereClause + orderBy + limitClause
+	fullQuery := baseQuery + distanceSelect + fromClause + " " + whereClause + " " + orderBy + limitClause
 
-	rows, err := databases.PostgresDB.Query(fullQuery, args...)
+	rows, err := LoggedQuery(ctx, fullQuery, args...)
 	if err != nil {
 		sentry.CaptureException(err)
-		return nil, 0, err
+		return nil, SearchTotal{}, err
 	}
 	defer rows.Close()
 
@@ -326,14 +380,15 @@ func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
 	for rows.Next() {
 		var venue Venue
 		var cityName, categoryName, categoryIcon sql.NullString
+		var neighborhoodID sql.NullInt64
 		var distance sql.NullFloat64
 
 		scanArgs := []interface{}{
 			&venue.ID, &venue.Name, &venue.Slug, &venue.ShortDesc,
 			&venue.Address, &venue.Latitude, &venue.Longitude,
 			&venue.CategoryID, &venue.Phone, &venue.Website,
-			&venue.PriceRange, &venue.AverageRating, &venue.TotalRatings,
-			&venue.CoverImage, &venue.IsFeatured,
+			&venue.PriceRange, &venue.AverageRating, &venue.TotalRatings, &venue.TotalFavorites,
+			&venue.CoverImage, &venue.IsFeatured, &neighborhoodID,
 			&cityName, &categoryName, &categoryIcon,
 		}
 
@@ -361,24 +416,141 @@ func (v *Venue) Search(params VenueSearchParams) ([]Venue, int, error) {
 		if distance.Valid {
 			venue.Distance = &distance.Float64
 		}
+		if neighborhoodID.Valid {
+			venue.NeighborhoodID = &neighborhoodID.Int64
+		}
 
 		venues = append(venues, venue)
 	}
 
 	// Get total count for pagination
-	countQuery := "SELECT COUNT(*) FROM venues v" + fromClause + whereClause
-	var totalCount int
-	err = databases.PostgresDB.QueryRow(countQuery, args...).Scan(&totalCount)
+	total, err := countSearchResults(ctx, fromClause+" "+whereClause, args, params.EstimateTotal)
+	if err != nil {
+		return venues, total, err
+	}
+
+	// An estimate can't be below the venues paged through so far
+	if seen := offset + len(venues); !total.Exact && total.Count < seen {
+		total.Count = seen
+	}
+	return venues, total, nil
+}
+
+// countSearchResults counts the venues of the search's FROM and WHERE
+// clauses. With estimate set, the planner's row estimate is returned when it
+// reaches EstimatedTotalThreshold, narrower searches are counted exactly.
+func countSearchResults(ctx context.Context, fromWhere string, args []interface{}, estimate bool) (SearchTotal, error) {
+	if estimate {
+		var plan []byte
+		err := databases.PostgresDB.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) SELECT 1"+fromWhere, args...).Scan(&plan)
+		if err != nil {
+			// Counted exactly instead
+			sentry.CaptureException(err)
+		} else {
+			var explained []struct {
+				Plan struct {
+					Rows float64 `json:"Plan Rows"`
+				} `json:"Plan"`
+			}
+			if err := json.Unmarshal(plan, &explained); err == nil && len(explained) > 0 &&
+				explained[0].Plan.Rows >= EstimatedTotalThreshold {
+				return SearchTotal{Count: int(explained[0].Plan.Rows)}, nil
+			}
+		}
+	}
+
+	var count int
+	err := LoggedQueryRow(ctx, "SELECT COUNT(*)"+fromWhere, args...).Scan(&count)
 	if err != nil {
 		sentry.CaptureException(err)
-		return venues, 0, err
+		return SearchTotal{}, err
+	}
+	return SearchTotal{Count: count, Exact: true}, nil
+}
+
+// whereClause builds the WHERE clause of the search filters and its
+// arguments, for queries over venues aliased v of the context's tenant
+func (params VenueSearchParams) whereClause(ctx context.Context) (string, []interface{}) {
+	whereClause := "WHERE v.is_active = true"
+	var args []interface{}
+	argCount := 0
+
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.tenant_id = $%d", argCount)
+		args = append(args, tenantID)
+	}
+
+	if params.Query != "" {
+		argCount++
+		whereClause += fmt.Sprintf(" AND (v.name ILIKE $%d OR v.description ILIKE $%d)", argCount, argCount)
+		args = append(args, "%"+params.Query+"%")
+	}
+
+	if params.CategoryID != nil {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.category_id = $%d", argCount)
+		args = append(args, *params.CategoryID)
+	}
+
+	if params.CityID != nil {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.city_id = $%d", argCount)
+		args = append(args, *params.CityID)
+	}
+
+	if params.NeighborhoodID != nil {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.neighborhood_id = $%d", argCount)
+		args = append(args, *params.NeighborhoodID)
+	}
+
+	if params.MinRating != nil {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.average_rating >= $%d", argCount)
+		args = append(args, *params.MinRating)
+	}
+
+	if len(params.PriceRange) > 0 {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.price_range = ANY($%d)", argCount)
+		args = append(args, pq.Array(params.PriceRange))
 	}
 
-	return venues, totalCount, nil
+	// Location radius filter. The geohash cells covering the circle narrow
+	// the candidates through an index before the exact distance check.
+	if params.Latitude != nil && params.Longitude != nil && params.Radius != nil {
+		if cells := geohashCondition("v.geohash", *params.Latitude, *params.Longitude, *params.Radius); cells != "" {
+			whereClause += " AND " + cells
+		}
+		argCount += 3
+		whereClause += fmt.Sprintf(` AND ST_DWithin(
+			ST_Point(v.longitude, v.latitude)::geography,
+			ST_Point($%d, $%d)::geography,
+			$%d * 1000)`, argCount-2, argCount-1, argCount)
+		args = append(args, *params.Longitude, *params.Latitude, *params.Radius)
+	}
+
+	if params.IsFeatured != nil && *params.IsFeatured {
+		whereClause += " AND v.is_featured = true"
+	}
+
+	// Venues without hours are left out, as are those an exception closes
+	if params.IsOpen != nil && *params.IsOpen {
+		whereClause += " AND " + OpenAtExpression("v", VenueLocalNow("v")) + " IS TRUE"
+	}
+
+	if params.CreatedAfter != nil {
+		argCount++
+		whereClause += fmt.Sprintf(" AND v.created_at > $%d", argCount)
+		args = append(args, *params.CreatedAfter)
+	}
+
+	return whereClause, args
 }
 
 // GetNearby finds venues near a location
-func (v *Venue) GetNearby(lat, lng, radius float64, limit int) ([]Venue, error) {
+func (v *Venue) GetNearby(ctx context.Context, lat, lng, radius float64, limit int) ([]Venue, error) {
 	params := VenueSearchParams{
 		Latitude:  &lat,
 		Longitude: &lng,
@@ -388,12 +560,104 @@ func (v *Venue) GetNearby(lat, lng, radius float64, limit int) ([]Venue, error)
 		Page:      1,
 	}
 
-	venues, _, err := v.Search(params)
+	venues, _, err := v.Search(ctx, params)
 	return venues, err
 }
 
+// TrendingWindow is how far back the activity venues trend by is counted
+const TrendingWindow = 7 * 24 * time.Hour
+
+// trendingScoreExpression counts the approved reviews and check-ins of the
+// venues aliased as v within TrendingWindow
+var trendingScoreExpression = fmt.Sprintf(`(
+	(SELECT COUNT(*) FROM venue_reviews tr
+	 WHERE tr.venue_id = v.id AND tr.moderation_status = 'approved'
+	   AND tr.created_at > CURRENT_TIMESTAMP - INTERVAL '%[1]d seconds') +
+	(SELECT COUNT(*) FROM venue_checkins tc
+	 WHERE tc.venue_id = v.id AND tc.created_at > CURRENT_TIMESTAMP - INTERVAL '%[1]d seconds'))`,
+	int64(TrendingWindow.Seconds()))
+
+// TrendingVenue is a venue with its activity within TrendingWindow
+type TrendingVenue struct {
+	Venue
+	TenantID int64
+	Score    int // Approved reviews, check-ins and counted campaign votes
+}
+
+// GetTrendingVenues returns the active venues of every tenant with reviews,
+// check-ins or campaign votes since the given time, the most active first
+func GetTrendingVenues(ctx context.Context, since time.Time) ([]TrendingVenue, error) {
+	query := `
+		WITH activity AS (
+			SELECT venue_id FROM venue_reviews
+			WHERE moderation_status = 'approved' AND created_at > $1
+			UNION ALL
+			SELECT venue_id FROM venue_checkins WHERE created_at > $1
+			UNION ALL
+			SELECT venue_id FROM campaign_votes WHERE created_at > $1 AND excluded_at IS NULL
+		), scores AS (
+			SELECT venue_id, COUNT(*) AS score FROM activity GROUP BY venue_id
+		)
+		SELECT v.id, v.name, v.slug, COALESCE(v.short_description, ''),
+			   v.address, v.latitude, v.longitude, v.city_id, v.category_id,
+			   COALESCE(v.price_range, ''), v.average_rating, v.total_ratings, v.total_favorites,
+			   COALESCE(v.cover_image, ''), v.is_featured, v.neighborhood_id,
+			   c.name, cat.name, cat.icon, v.tenant_id, s.score
+		FROM scores s
+		INNER JOIN venues v ON v.id = s.venue_id
+		LEFT JOIN cities c ON v.city_id = c.id
+		LEFT JOIN venue_categories cat ON v.category_id = cat.id
+		WHERE v.is_active = true
+		ORDER BY s.score DESC, v.average_rating DESC, v.id`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, since)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	venues := make([]TrendingVenue, 0)
+	for rows.Next() {
+		var venue TrendingVenue
+		var cityName, categoryName, categoryIcon sql.NullString
+		var neighborhoodID sql.NullInt64
+		err := rows.Scan(
+			&venue.ID, &venue.Name, &venue.Slug, &venue.ShortDesc,
+			&venue.Address, &venue.Latitude, &venue.Longitude, &venue.CityID, &venue.CategoryID,
+			&venue.PriceRange, &venue.AverageRating, &venue.TotalRatings, &venue.TotalFavorites,
+			&venue.CoverImage, &venue.IsFeatured, &neighborhoodID,
+			&cityName, &categoryName, &categoryIcon, &venue.TenantID, &venue.Score,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+
+		if cityName.Valid {
+			venue.City = &City{ID: venue.CityID, Name: cityName.String}
+		}
+		if categoryName.Valid {
+			venue.Category = &VenueCategory{
+				ID:   venue.CategoryID,
+				Name: categoryName.String,
+				Icon: categoryIcon.String,
+			}
+		}
+		if neighborhoodID.Valid {
+			venue.NeighborhoodID = &neighborhoodID.Int64
+		}
+		venues = append(venues, venue)
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return venues, nil
+}
+
 // GetFeatured returns featured venues
-func (v *Venue) GetFeatured(limit int) ([]Venue, error) {
+func (v *Venue) GetFeatured(ctx context.Context, limit int) ([]Venue, error) {
 	featured := true
 	params := VenueSearchParams{
 		IsFeatured: &featured,
@@ -402,12 +666,12 @@ func (v *Venue) GetFeatured(limit int) ([]Venue, error) {
 		Page:       1,
 	}
 
-	venues, _, err := v.Search(params)
+	venues, _, err := v.Search(ctx, params)
 	return venues, err
 }
 
 // UpdateRatingCache updates the cached rating statistics
-func (v *Venue) UpdateRatingCache() error {
+func (v *Venue) UpdateRatingCache(ctx context.Context) error {
 	query := `
 		UPDATE venues 
 		SET average_rating = (
@@ -428,44 +692,214 @@ func (v *Venue) UpdateRatingCache() error {
 		updated_at = CURRENT_TIMESTAMP
 		WHERE id = $1`
 
-	_, err := databases.PostgresDB.Exec(query, v.ID)
+	_, err := databases.PostgresDB.ExecContext(ctx, query, v.ID)
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 	return err
 }
 
-// Create creates a new venue
-func (v *Venue) Create() error {
+// Create creates a new venue. Its slug is made unique with a numeric suffix
+// when another venue uses or used it.
+func (v *Venue) Create(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	slug, err := allocateVenueSlug(ctx, tx, venueSlugBase(v.Slug), 0)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO venues (
 			name, slug, description, short_description, address, city_id,
 			latitude, longitude, postal_code, category_id, subcategory_id,
 			phone, email, website, opening_hours, price_range, average_cost_per_person,
-			cover_image, logo, amenities, owner_id
+			cover_image, logo, amenities, owner_id, neighborhood_id, tenant_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23
 		) RETURNING id, created_at, updated_at`
 
-	err := databases.PostgresDB.QueryRow(
+	err = tx.QueryRowContext(ctx,
 		query,
-		v.Name, v.Slug, v.Description, v.ShortDesc, v.Address, v.CityID,
+		v.Name, slug, v.Description, v.ShortDesc, v.Address, v.CityID,
 		v.Latitude, v.Longitude, v.PostalCode, v.CategoryID, v.SubcategoryID,
 		v.Phone, v.Email, v.Website, v.OpeningHours, v.PriceRange, v.AvgCostPerPerson,
-		v.CoverImage, v.Logo, v.Amenities, v.OwnerID,
+		v.CoverImage, v.Logo, v.Amenities, v.OwnerID, v.NeighborhoodID, ownerTenant(ctx),
 	).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	v.Slug = slug
+	return nil
+}
 
+// Update saves the venue's details. When Slug no longer maps to the current
+// slug, as after a name change, the venue gets a new slug from it and keeps
+// the old one in its slug history.
+func (v *Venue) Update(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
 	if err != nil {
 		sentry.CaptureException(err)
+		return err
 	}
-	return err
+	defer tx.Rollback()
+
+	var currentSlug string
+	err = tx.QueryRowContext(ctx,
+		"SELECT slug FROM venues WHERE id = $1 AND is_active = true FOR UPDATE",
+		v.ID).Scan(&currentSlug)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	slug, err := v.followSlug(ctx, tx, currentSlug, v.Slug)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE venues SET
+			name = $2, slug = $3, description = $4, short_description = $5,
+			address = $6, phone = $7, email = $8, website = $9,
+			opening_hours = $10, price_range = $11, average_cost_per_person = $12,
+			cover_image = $13, logo = $14, amenities = $15,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx,
+		query,
+		v.ID, v.Name, slug, v.Description, v.ShortDesc,
+		v.Address, v.Phone, v.Email, v.Website,
+		v.OpeningHours, v.PriceRange, v.AvgCostPerPerson,
+		v.CoverImage, v.Logo, v.Amenities,
+	).Scan(&v.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	v.Slug = slug
+	return nil
+}
+
+// Venue delete policies, deciding what happens to the venue's reviews,
+// collection items and campaign votes
+const (
+	// VenueDeleteHide deactivates the venue. Its dependent data is kept, so
+	// the venue can be restored with it.
+	VenueDeleteHide = "hide"
+	// VenueDeleteCascade removes the venue together with its dependent data
+	VenueDeleteCascade = "cascade"
+)
+
+// Deactivate hides the venue from listings, lookups and search while keeping
+// its reviews, collection items and campaign votes
+func (v *Venue) Deactivate(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE venues SET is_active = false, is_featured = false, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND is_active = true
+		RETURNING updated_at`,
+		v.ID).Scan(&v.UpdatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	v.IsActive = false
+	v.IsFeatured = false
+	return nil
+}
+
+// venueDependentDeletes remove the rows that reference a venue without
+// cascading, in dependency order
+var venueDependentDeletes = []string{
+	"DELETE FROM review_votes WHERE review_id IN (SELECT id FROM venue_reviews WHERE venue_id = $1)",
+	"DELETE FROM venue_reviews WHERE venue_id = $1",
+	"DELETE FROM venue_collection_items WHERE venue_id = $1",
+	"DELETE FROM campaign_votes WHERE venue_id = $1",
+	"DELETE FROM venue_checkins WHERE venue_id = $1",
+	"DELETE FROM venue_events WHERE venue_id = $1",
+	"DELETE FROM venue_analytics WHERE venue_id = $1",
+	"UPDATE search_analytics SET clicked_venue_id = NULL WHERE clicked_venue_id = $1",
+	"UPDATE campaign_categories SET winner_venue_id = NULL WHERE winner_venue_id = $1",
+	"UPDATE voting_campaigns SET winner_venue_id = NULL WHERE winner_venue_id = $1",
+}
+
+// Delete removes the venue with its reviews, collection items, campaign votes
+// and the rest of its data in one transaction, leaving a tombstone for
+// syncing clients. Past campaign results and search clicks keep their rows
+// without the venue.
+func (v *Venue) Delete(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", v.ID).Scan(&id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	for _, statement := range venueDependentDeletes {
+		if _, err := tx.ExecContext(ctx, statement, v.ID); err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	// Syncing clients are told to drop the venue
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sync_tombstones (entity_type, entity_id, city_id, tenant_id)
+		SELECT $2, id, city_id, tenant_id FROM venues WHERE id = $1`,
+		v.ID, SyncVenue)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM venues WHERE id = $1", v.ID); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
 }
 
 // GetCategories returns all venue categories
-func GetVenueCategories() ([]VenueCategory, error) {
+func GetVenueCategories(ctx context.Context) ([]VenueCategory, error) {
 	query := "SELECT id, name, description, icon, is_active FROM venue_categories WHERE is_active = true ORDER BY name"
 
-	rows, err := databases.PostgresDB.Query(query)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil, err
@@ -490,3 +924,178 @@ func GetVenueCategories() ([]VenueCategory, error) {
 
 	return categories, nil
 }
+
+// VenueFeedEntry is the subset of venue data published in sitemaps and feeds
+type VenueFeedEntry struct {
+	ID            int64     `json:"id"`
+	TenantID      int64     `json:"-"`
+	Name          string    `json:"name"`
+	Slug          string    `json:"slug"`
+	CityID        int64     `json:"cityId"`
+	CityName      string    `json:"city,omitempty"`
+	CategoryID    int64     `json:"categoryId"`
+	CategoryName  string    `json:"category,omitempty"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	AverageRating float64   `json:"averageRating"`
+	TotalRatings  int       `json:"totalRatings"`
+	UpdatedAt     time.Time `json:"lastmod"`
+}
+
+// GetVenueFeedEntries returns active venues of the context's tenant for
+// sitemap and feed generation, optionally restricted to a city and/or
+// category
+func GetVenueFeedEntries(ctx context.Context, cityID, categoryID *int64, limit int) ([]VenueFeedEntry, error) {
+	query := `
+		SELECT v.id, v.tenant_id, v.name, v.slug, v.city_id, c.name, v.category_id, cat.name,
+			   v.latitude, v.longitude, v.average_rating, v.total_ratings, v.updated_at
+		FROM venues v
+		LEFT JOIN cities c ON v.city_id = c.id
+		LEFT JOIN venue_categories cat ON v.category_id = cat.id
+		WHERE v.is_active = true AND ($1::bigint IS NULL OR v.tenant_id = $1)`
+
+	args := []interface{}{TenantFilter(ctx)}
+	argCount := 1
+
+	if cityID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND v.city_id = $%d", argCount)
+		args = append(args, *cityID)
+	}
+
+	if categoryID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND v.category_id = $%d", argCount)
+		args = append(args, *categoryID)
+	}
+
+	argCount++
+	query += fmt.Sprintf(" ORDER BY v.updated_at DESC, v.id LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]VenueFeedEntry, 0)
+	for rows.Next() {
+		var entry VenueFeedEntry
+		var venueCityID, venueCategoryID sql.NullInt64
+		var cityName, categoryName sql.NullString
+
+		err := rows.Scan(
+			&entry.ID, &entry.TenantID, &entry.Name, &entry.Slug, &venueCityID, &cityName,
+			&venueCategoryID, &categoryName, &entry.Latitude, &entry.Longitude,
+			&entry.AverageRating, &entry.TotalRatings, &entry.UpdatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		entry.CityID = venueCityID.Int64
+		entry.CityName = cityName.String
+		entry.CategoryID = venueCategoryID.Int64
+		entry.CategoryName = categoryName.String
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// cityLocations caches loaded time zones by name
+var cityLocations sync.Map
+
+// CityLocation returns the time zone of a city, UTC when the name is empty
+// or unknown
+func CityLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	if location, cached := cityLocations.Load(timezone); cached {
+		return location.(*time.Location)
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil || location == time.Local {
+		location = time.UTC
+	}
+	cityLocations.Store(timezone, location)
+	return location
+}
+
+// GetVenueLocations returns the time zone of each venue's city, used to
+// bucket venue activity by local days and hours
+func GetVenueLocations(ctx context.Context, venueIDs []int64) (map[int64]*time.Location, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id, COALESCE(c.timezone, '')
+		FROM venues v
+		LEFT JOIN cities c ON v.city_id = c.id
+		WHERE v.id = ANY($1)`,
+		pq.Array(venueIDs),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := make(map[int64]*time.Location, len(venueIDs))
+	for rows.Next() {
+		var venueID int64
+		var timezone string
+		if err := rows.Scan(&venueID, &timezone); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		locations[venueID] = CityLocation(timezone)
+	}
+
+	return locations, nil
+}
+
+// GetVenueLocation returns the time zone of the venue's city
+func GetVenueLocation(ctx context.Context, venueID int64) (*time.Location, error) {
+	locations, err := GetVenueLocations(ctx, []int64{venueID})
+	if err != nil {
+		return nil, err
+	}
+	if location, exists := locations[venueID]; exists {
+		return location, nil
+	}
+	return time.UTC, nil
+}
+
+// GetActiveCities returns every active city
+func GetActiveCities(ctx context.Context) ([]City, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, name, COALESCE(state, ''), country, latitude, longitude, COALESCE(timezone, '')
+		FROM cities
+		WHERE is_active = true
+		ORDER BY id`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cities := make([]City, 0)
+	for rows.Next() {
+		var city City
+		var latitude, longitude sql.NullFloat64
+		err := rows.Scan(&city.ID, &city.Name, &city.State, &city.Country, &latitude, &longitude, &city.Timezone)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		city.Latitude, city.Longitude = latitude.Float64, longitude.Float64
+		city.HasCoordinates = latitude.Valid && longitude.Valid
+		city.IsActive = true
+		cities = append(cities, city)
+	}
+
+	return cities, nil
+}
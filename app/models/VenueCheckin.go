@@ -0,0 +1,199 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// VenueCheckin is a user's check-in at a venue as shown on the venue's page
+type VenueCheckin struct {
+	ID        int64     `json:"id"`
+	VenueID   int64     `json:"venueId"`
+	UserID    int64     `json:"userId"`
+	UserName  string    `json:"userName,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Rating    *float64  `json:"rating,omitempty"`
+	Photos    []Photo   `json:"photos"`
+	IsPublic  bool      `json:"isPublic"`
+	IsFlagged bool      `json:"isFlagged"` // Flagged by the content filter, kept out of public listings
+	CreatedAt time.Time `json:"createdAt"`
+
+	// WaitReport is the wait time and crowd level reported with the check-in
+	WaitReport *VenueWaitReport `json:"waitReport,omitempty"`
+	// Deduplicated is set when checking in again was merged into the user's
+	// recent check-in at the venue instead of creating one
+	Deduplicated bool `json:"deduplicated,omitempty"`
+
+	photoURLs []string
+}
+
+func (c *VenueCheckin) TableName() string {
+	return "venue_checkins"
+}
+
+// Create stores the check-in at an active venue, with its wait report when
+// set. sql.ErrNoRows is returned when the venue does not exist.
+//
+// Users often check in twice by accident: when they already checked in at
+// the venue within dedupWindow no check-in is stored and c becomes the
+// existing one, marked Deduplicated. A wait report sent along still counts
+// and is linked to the existing check-in. A zero window disables this.
+func (c *VenueCheckin) Create(ctx context.Context, dedupWindow time.Duration) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if dedupWindow > 0 {
+		// Serializes the user's check-ins at the venue so that a double tap
+		// can't create two
+		_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('checkin:' || $1 || ':' || $2))",
+			c.UserID, c.VenueID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+
+		var photos []byte
+		err = tx.QueryRowContext(ctx, `
+			SELECT c.id, COALESCE(c.message, ''), c.rating, c.is_public, COALESCE(c.is_flagged, false),
+				   COALESCE(c.photos, '[]'), c.created_at
+			FROM venue_checkins c
+			JOIN venues v ON v.id = c.venue_id AND v.is_active = true
+			WHERE c.user_id = $1 AND c.venue_id = $2
+			  AND c.created_at > CURRENT_TIMESTAMP - make_interval(secs => $3)
+			ORDER BY c.created_at DESC, c.id DESC
+			LIMIT 1`,
+			c.UserID, c.VenueID, dedupWindow.Seconds(),
+		).Scan(&c.ID, &c.Message, &c.Rating, &c.IsPublic, &c.IsFlagged, &photos, &c.CreatedAt)
+		if err == nil {
+			c.Deduplicated = true
+			json.Unmarshal(photos, &c.photoURLs)
+		} else if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	if !c.Deduplicated {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO venue_checkins (venue_id, user_id, message, rating, is_public, is_flagged)
+			SELECT id, $2, NULLIF($3, ''), $4, $5, $6 FROM venues WHERE id = $1 AND is_active = true
+			RETURNING id, created_at`,
+			c.VenueID, c.UserID, c.Message, c.Rating, c.IsPublic, c.IsFlagged,
+		).Scan(&c.ID, &c.CreatedAt)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				sentry.CaptureException(err)
+			}
+			return err
+		}
+	}
+
+	if c.WaitReport != nil {
+		c.WaitReport.VenueID = c.VenueID
+		c.WaitReport.UserID = c.UserID
+		c.WaitReport.CheckinID = &c.ID
+		if err := c.WaitReport.create(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	checkins := []VenueCheckin{*c}
+	if err := attachCheckinPhotos(ctx, checkins); err != nil {
+		return err
+	}
+	c.Photos = checkins[0].Photos
+	return nil
+}
+
+// GetPublicVenueCheckins returns the newest public check-ins of a venue and
+// how many there are. Flagged check-ins are left out, and so are those of
+// users the viewer blocked or muted when viewerID is set. Only photos that went through the photo
+// upload pipeline and were uploaded by the check-in's author are kept.
+func GetPublicVenueCheckins(ctx context.Context, venueID int64, viewerID *int64, limit, offset int) ([]VenueCheckin, int, error) {
+	// Without a viewer nobody is hidden
+	where := "WHERE c.venue_id = $1 AND c.is_public = true AND COALESCE(c.is_flagged, false) = false AND " +
+		HiddenUsersCondition("c.user_id", "$2")
+
+	var total int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM venue_checkins c "+where, venueID, viewerID).Scan(&total)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT c.id, c.venue_id, c.user_id, COALESCE(u.snapp_id, ''), COALESCE(c.message, ''),
+			   c.rating, COALESCE(c.photos, '[]'), c.created_at
+		FROM venue_checkins c
+		LEFT JOIN snapp_users u ON u.id = c.user_id
+		`+where+`
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $3 OFFSET $4`,
+		venueID, viewerID, limit, offset)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	checkins := make([]VenueCheckin, 0)
+	for rows.Next() {
+		var checkin VenueCheckin
+		var photos []byte
+		err := rows.Scan(&checkin.ID, &checkin.VenueID, &checkin.UserID, &checkin.UserName,
+			&checkin.Message, &checkin.Rating, &photos, &checkin.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, 0, err
+		}
+		checkin.IsPublic = true
+		// Malformed photo lists show no photos
+		json.Unmarshal(photos, &checkin.photoURLs)
+		checkins = append(checkins, checkin)
+	}
+
+	if err := attachCheckinPhotos(ctx, checkins); err != nil {
+		return nil, 0, err
+	}
+
+	return checkins, total, nil
+}
+
+// attachCheckinPhotos sets the photos of the check-ins. Only photos that went
+// through the photo upload pipeline and were uploaded by the check-in's
+// author are kept.
+func attachCheckinPhotos(ctx context.Context, checkins []VenueCheckin) error {
+	var urls []string
+	for _, checkin := range checkins {
+		urls = append(urls, checkin.photoURLs...)
+	}
+
+	uploaded, err := GetPhotosByURLs(ctx, urls)
+	if err != nil {
+		return err
+	}
+	for i := range checkins {
+		checkins[i].Photos = make([]Photo, 0, len(checkins[i].photoURLs))
+		for _, url := range checkins[i].photoURLs {
+			if photo, exists := uploaded[url]; exists && photo.UserID == checkins[i].UserID {
+				checkins[i].Photos = append(checkins[i].Photos, photo)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// VenueCityCorrection records that a venue was assigned another city than
+// the one submitted, because its coordinates are nearer to that city
+type VenueCityCorrection struct {
+	ID             int64     `json:"id"`
+	VenueID        int64     `json:"venueId"`
+	ClaimedCityID  int64     `json:"claimedCityId"`
+	AssignedCityID int64     `json:"assignedCityId"`
+	DistanceKm     float64   `json:"distanceKm"` // From the venue to the assigned city
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func (c *VenueCityCorrection) TableName() string {
+	return "venue_city_corrections"
+}
+
+// Create stores the correction
+func (c *VenueCityCorrection) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_city_corrections (venue_id, claimed_city_id, assigned_city_id, distance_km)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		c.VenueID, c.ClaimedCityID, c.AssignedCityID, c.DistanceKm,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
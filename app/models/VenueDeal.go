@@ -0,0 +1,317 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// dealCheckinWindow is how long after checking in a deal can be redeemed
+// with the check-in
+const dealCheckinWindow = "2 hours"
+
+var (
+	// ErrDealUnavailable is returned when redeeming a deal outside its
+	// validity window
+	ErrDealUnavailable = errors.New("deal is not running")
+
+	// ErrDealLimitReached is returned when the deal was redeemed as many
+	// times as its limit allows
+	ErrDealLimitReached = errors.New("deal has no redemptions left")
+
+	// ErrDealAlreadyRedeemed is returned when the user already redeemed the
+	// deal
+	ErrDealAlreadyRedeemed = errors.New("user already redeemed the deal")
+
+	// ErrCheckinRequired is returned when the check-in isn't the user's
+	// recent check-in at the deal's venue
+	ErrCheckinRequired = errors.New("deal requires a recent check-in at its venue")
+)
+
+// VenueDeal is a promotion a venue's owner runs for a while, redeemed by
+// users when they check in at the venue
+type VenueDeal struct {
+	ID              int64     `json:"id"`
+	VenueID         int64     `json:"venueId"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description,omitempty"`
+	StartsAt        time.Time `json:"startsAt"`
+	EndsAt          time.Time `json:"endsAt"`
+	RedemptionLimit *int      `json:"redemptionLimit,omitempty"` // Unlimited when unset
+	Redemptions     int       `json:"redemptions"`
+	CreatedBy       int64     `json:"createdBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+func (d *VenueDeal) TableName() string {
+	return "venue_deals"
+}
+
+// DealRedemption is a user redeeming a deal with their check-in, which
+// attributes the visit to the deal
+type DealRedemption struct {
+	ID         int64     `json:"id"`
+	DealID     int64     `json:"dealId"`
+	VenueID    int64     `json:"venueId"`
+	UserID     int64     `json:"userId"`
+	CheckinID  int64     `json:"checkinId"`
+	RedeemedAt time.Time `json:"redeemedAt"`
+}
+
+func (r *DealRedemption) TableName() string {
+	return "deal_redemptions"
+}
+
+// DealAttribution is how much traffic a deal brought its venue in a period
+type DealAttribution struct {
+	DealID      int64  `json:"dealId"`
+	Title       string `json:"title"`
+	Redemptions int    `json:"redemptions"`
+}
+
+const venueDealColumns = `id, venue_id, title, COALESCE(description, ''), starts_at, ends_at,
+	redemption_limit, redemption_count, COALESCE(created_by, 0), created_at, updated_at`
+
+func scanVenueDeal(row interface{ Scan(...interface{}) error }, d *VenueDeal) error {
+	var limit sql.NullInt64
+	err := row.Scan(&d.ID, &d.VenueID, &d.Title, &d.Description, &d.StartsAt, &d.EndsAt,
+		&limit, &d.Redemptions, &d.CreatedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	d.RedemptionLimit = nil
+	if limit.Valid {
+		value := int(limit.Int64)
+		d.RedemptionLimit = &value
+	}
+	return nil
+}
+
+// Create stores the deal
+func (d *VenueDeal) Create(ctx context.Context) error {
+	err := scanVenueDeal(databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_deals (venue_id, title, description, starts_at, ends_at, redemption_limit, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)
+		RETURNING `+venueDealColumns,
+		d.VenueID, d.Title, d.Description, d.StartsAt, d.EndsAt, d.RedemptionLimit, d.CreatedBy,
+	), d)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Update replaces the deal's details, keeping its redemptions. A limit below
+// the redemptions so far stops further redemptions. sql.ErrNoRows is
+// returned when the venue has no deal with its ID.
+func (d *VenueDeal) Update(ctx context.Context) error {
+	err := scanVenueDeal(databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE venue_deals
+		SET title = $3, description = NULLIF($4, ''), starts_at = $5, ends_at = $6,
+			redemption_limit = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND venue_id = $2
+		RETURNING `+venueDealColumns,
+		d.ID, d.VenueID, d.Title, d.Description, d.StartsAt, d.EndsAt, d.RedemptionLimit,
+	), d)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the deal with its redemptions, returning sql.ErrNoRows when
+// the venue has no deal with its ID. Deals that were redeemed are better
+// ended early, which keeps them in the venue's analytics.
+func (d *VenueDeal) Delete(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_deals WHERE id = $1 AND venue_id = $2",
+		d.ID, d.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetVenueDeals returns all the deals of a venue, the latest to start first
+func GetVenueDeals(ctx context.Context, venueID int64) ([]VenueDeal, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+venueDealColumns+`
+		FROM venue_deals
+		WHERE venue_id = $1
+		ORDER BY starts_at DESC, id DESC`,
+		venueID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deals := make([]VenueDeal, 0)
+	for rows.Next() {
+		var deal VenueDeal
+		if err := scanVenueDeal(rows, &deal); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		deals = append(deals, deal)
+	}
+	return deals, rows.Err()
+}
+
+// GetActiveDeals returns the deals of the venues that are running and have
+// redemptions left, by venue and ending soonest first
+func GetActiveDeals(ctx context.Context, venueIDs []int64) (map[int64][]VenueDeal, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT `+venueDealColumns+`
+		FROM venue_deals
+		WHERE venue_id = ANY($1)
+		  AND starts_at <= CURRENT_TIMESTAMP AND ends_at > CURRENT_TIMESTAMP
+		  AND (redemption_limit IS NULL OR redemption_count < redemption_limit)
+		ORDER BY venue_id, ends_at, id`,
+		pq.Array(venueIDs),
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deals := make(map[int64][]VenueDeal)
+	for rows.Next() {
+		var deal VenueDeal
+		if err := scanVenueDeal(rows, &deal); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		deals[deal.VenueID] = append(deals[deal.VenueID], deal)
+	}
+	return deals, rows.Err()
+}
+
+// RedeemDeal redeems the deal for the user with their check-in, which must be
+// at the deal's venue within the last two hours. Each user redeems a deal
+// once. sql.ErrNoRows is returned when the deal does not exist.
+func RedeemDeal(ctx context.Context, dealID, userID, checkinID int64) (*DealRedemption, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the deal keeps concurrent redemptions within its limit
+	var venueID int64
+	var isRunning, hasRedemptionsLeft bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT venue_id,
+			   starts_at <= CURRENT_TIMESTAMP AND ends_at > CURRENT_TIMESTAMP,
+			   redemption_limit IS NULL OR redemption_count < redemption_limit
+		FROM venue_deals
+		WHERE id = $1
+		FOR UPDATE`,
+		dealID,
+	).Scan(&venueID, &isRunning, &hasRedemptionsLeft)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	if !isRunning {
+		return nil, ErrDealUnavailable
+	}
+
+	var checkedIn bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM venue_checkins
+			WHERE id = $1 AND user_id = $2 AND venue_id = $3
+			  AND created_at >= CURRENT_TIMESTAMP - INTERVAL '`+dealCheckinWindow+`'
+		)`,
+		checkinID, userID, venueID,
+	).Scan(&checkedIn)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	if !checkedIn {
+		return nil, ErrCheckinRequired
+	}
+
+	redemption := &DealRedemption{DealID: dealID, VenueID: venueID, UserID: userID, CheckinID: checkinID}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO deal_redemptions (deal_id, venue_id, user_id, checkin_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (deal_id, user_id) DO NOTHING
+		RETURNING id, redeemed_at`,
+		dealID, venueID, userID, checkinID,
+	).Scan(&redemption.ID, &redemption.RedeemedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDealAlreadyRedeemed
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	// Checked after the user's own redemption so they learn they already
+	// have it rather than that the deal ran out
+	if !hasRedemptionsLeft {
+		return nil, ErrDealLimitReached
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE venue_deals SET redemption_count = redemption_count + 1 WHERE id = $1",
+		dealID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// GetDealAttribution returns the redemptions of the venue's deals from one
+// time to another, most redeemed first. Deals without redemptions in the
+// period are left out.
+func GetDealAttribution(ctx context.Context, venueID int64, from, to time.Time) ([]DealAttribution, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT d.id, d.title, COUNT(*)
+		FROM deal_redemptions r
+		JOIN venue_deals d ON d.id = r.deal_id
+		WHERE r.venue_id = $1 AND r.redeemed_at BETWEEN $2 AND $3
+		GROUP BY d.id, d.title
+		ORDER BY COUNT(*) DESC, d.id`,
+		venueID, from, to,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	attribution := make([]DealAttribution, 0)
+	for rows.Next() {
+		var deal DealAttribution
+		if err := rows.Scan(&deal.DealID, &deal.Title, &deal.Redemptions); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		attribution = append(attribution, deal)
+	}
+	return attribution, rows.Err()
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Venue interactions reported by the apps
+const (
+	VenueEventView       = "venue_view"
+	VenueEventPhotoView  = "photo_view"
+	VenueEventPhoneClick = "phone_click"
+	VenueEventDirections = "directions"
+	VenueEventShare      = "share"
+)
+
+// venueEventColumns are the venue_analytics counters of the interactions
+var venueEventColumns = map[string]string{
+	VenueEventView:       "profile_views",
+	VenueEventPhotoView:  "photo_views",
+	VenueEventPhoneClick: "phone_clicks",
+	VenueEventDirections: "direction_requests",
+	VenueEventShare:      "shares",
+}
+
+// VenueEventTypes lists the accepted venue interactions
+var VenueEventTypes = []string{
+	VenueEventView, VenueEventPhotoView, VenueEventPhoneClick, VenueEventDirections, VenueEventShare,
+}
+
+// IsVenueEventType reports whether the venue interaction is known
+func IsVenueEventType(eventType string) bool {
+	_, exists := venueEventColumns[eventType]
+	return exists
+}
+
+// VenueEvent is an interaction with a venue reported by a client app. The
+// event ID is chosen by the client so that resent events count once.
+type VenueEvent struct {
+	ClientID   string
+	EventID    string
+	VenueID    int64
+	Type       string
+	OccurredAt time.Time
+	// Experiment and Variant tag events of venues the user was recommended
+	// in an experiment, empty otherwise
+	Experiment string
+	Variant    string
+}
+
+// VenueEventsResult tells what became of a batch of venue events
+type VenueEventsResult struct {
+	Recorded      int
+	Duplicates    int // Received before from the same client
+	UnknownVenues int
+}
+
+// RecordVenueEvents counts the events towards their venues' daily analytics,
+// on the venue's local day the event occurred, and tagged events towards
+// their experiment. Events the client sent before and events of venues that
+// don't exist are left out.
+func RecordVenueEvents(ctx context.Context, events []VenueEvent) (VenueEventsResult, error) {
+	var result VenueEventsResult
+
+	venueIDs := make([]int64, 0, len(events))
+	for _, event := range events {
+		venueIDs = append(venueIDs, event.VenueID)
+	}
+	locations, err := GetVenueLocations(ctx, venueIDs)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return result, err
+	}
+	defer tx.Rollback()
+
+	receipt, err := tx.PrepareContext(ctx, `
+		INSERT INTO venue_event_receipts (client_id, event_id)
+		VALUES ($1, $2)
+		ON CONFLICT (client_id, event_id) DO NOTHING`)
+	if err != nil {
+		sentry.CaptureException(err)
+		return result, err
+	}
+	defer receipt.Close()
+
+	for _, event := range events {
+		location, exists := locations[event.VenueID]
+		if !exists {
+			result.UnknownVenues++
+			continue
+		}
+
+		inserted, err := receipt.ExecContext(ctx, event.ClientID, event.EventID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return VenueEventsResult{}, err
+		}
+		if received, _ := inserted.RowsAffected(); received == 0 {
+			result.Duplicates++
+			continue
+		}
+
+		column := venueEventColumns[event.Type]
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO venue_analytics (venue_id, date, %[1]s)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (venue_id, date) DO UPDATE SET
+				%[1]s = COALESCE(venue_analytics.%[1]s, 0) + 1`, column),
+			event.VenueID, event.OccurredAt.In(location).Format("2006-01-02"))
+		if err != nil {
+			sentry.CaptureException(err)
+			return VenueEventsResult{}, err
+		}
+
+		if event.Experiment != "" {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO experiment_events (experiment, variant, venue_id, event_type, occurred_at)
+				VALUES ($1, $2, $3, $4, $5)`,
+				event.Experiment, event.Variant, event.VenueID, event.Type, event.OccurredAt)
+			if err != nil {
+				sentry.CaptureException(err)
+				return VenueEventsResult{}, err
+			}
+		}
+		result.Recorded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return VenueEventsResult{}, err
+	}
+	return result, nil
+}
+
+// DeleteVenueEventReceipts removes the receipts of events received before
+// the time, once their events can't be sent anymore. Returns how many were
+// removed.
+func DeleteVenueEventReceipts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_event_receipts WHERE received_at < $1", before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
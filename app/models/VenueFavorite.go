@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// FavoriteStatus is whether a user favorited a venue and how many users did
+type FavoriteStatus struct {
+	VenueID        int64 `json:"venueId"`
+	IsFavorite     bool  `json:"isFavorite"`
+	TotalFavorites int   `json:"totalFavorites"`
+}
+
+// FavoriteVenue is a venue in a user's favorites
+type FavoriteVenue struct {
+	VenueID        int64     `json:"venueId"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	CategoryID     int64     `json:"categoryId,omitempty"`
+	CityID         int64     `json:"cityId,omitempty"`
+	PriceRange     string    `json:"priceRange,omitempty"`
+	CoverImage     string    `json:"coverImage,omitempty"`
+	AverageRating  float64   `json:"averageRating"`
+	TotalReviews   int       `json:"totalReviews"`
+	TotalFavorites int       `json:"totalFavorites"`
+	FavoritedAt    time.Time `json:"favoritedAt"`
+}
+
+// SetFavorite favorites the active venue for the user or takes it out of
+// their favorites. Setting the state the venue already is in changes
+// nothing, so clients can toggle without knowing it. sql.ErrNoRows when the
+// venue does not exist.
+func SetFavorite(ctx context.Context, userID, venueID int64, favorite bool) (*FavoriteStatus, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the venue keeps its cached count in step with the favorites
+	var id int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT id FROM venues WHERE id = $1 AND is_active = true FOR UPDATE", venueID).Scan(&id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+
+	query := `INSERT INTO venue_favorites (user_id, venue_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, venue_id) DO NOTHING`
+	if !favorite {
+		query = "DELETE FROM venue_favorites WHERE user_id = $1 AND venue_id = $2"
+	}
+	if _, err := tx.ExecContext(ctx, query, userID, venueID); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	status := &FavoriteStatus{VenueID: venueID, IsFavorite: favorite}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE venues
+		SET total_favorites = (SELECT COUNT(*) FROM venue_favorites WHERE venue_id = $1)
+		WHERE id = $1
+		RETURNING total_favorites`,
+		venueID).Scan(&status.TotalFavorites)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetUserFavorites returns the user's favorite active venues, the latest
+// favorited first, and how many there are
+func GetUserFavorites(ctx context.Context, userID int64, limit, offset int) ([]FavoriteVenue, int, error) {
+	var total int
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM venue_favorites f
+		INNER JOIN venues v ON v.id = f.venue_id
+		WHERE f.user_id = $1 AND v.is_active = true`,
+		userID).Scan(&total)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id, v.name, v.slug, COALESCE(v.category_id, 0), COALESCE(v.city_id, 0),
+			   COALESCE(v.price_range, ''), COALESCE(v.cover_image, ''),
+			   COALESCE(v.average_rating, 0), COALESCE(v.total_reviews, 0), v.total_favorites, f.created_at
+		FROM venue_favorites f
+		INNER JOIN venues v ON v.id = f.venue_id
+		WHERE f.user_id = $1 AND v.is_active = true
+		ORDER BY f.created_at DESC, v.id
+		LIMIT $2 OFFSET $3`,
+		userID, limit, offset)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	favorites := make([]FavoriteVenue, 0)
+	for rows.Next() {
+		var favorite FavoriteVenue
+		err := rows.Scan(&favorite.VenueID, &favorite.Name, &favorite.Slug, &favorite.CategoryID,
+			&favorite.CityID, &favorite.PriceRange, &favorite.CoverImage, &favorite.AverageRating,
+			&favorite.TotalReviews, &favorite.TotalFavorites, &favorite.FavoritedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, 0, err
+		}
+		favorites = append(favorites, favorite)
+	}
+	return favorites, total, rows.Err()
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// DateLayout is the layout of the dates of hours exceptions
+const DateLayout = "2006-01-02"
+
+// HoursException replaces a venue's regular hours on a date, closing it for
+// a holiday or opening it for different hours for a special event
+type HoursException struct {
+	ID        int64     `json:"id"`
+	VenueID   int64     `json:"venueId"`
+	Date      string    `json:"date"` // "2006-01-02", local to the venue's city
+	IsClosed  bool      `json:"isClosed"`
+	Open      string    `json:"open,omitempty"`  // HH:MM, unset when closed
+	Close     string    `json:"close,omitempty"` // Before Open to run past midnight
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy int64     `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (e *HoursException) TableName() string {
+	return "venue_hours_exceptions"
+}
+
+// Save sets the exception of the venue's date, replacing the one the date
+// already has
+func (e *HoursException) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_hours_exceptions (venue_id, date, is_closed, open_time, close_time, reason, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7)
+		ON CONFLICT (venue_id, date) DO UPDATE SET
+			is_closed = EXCLUDED.is_closed, open_time = EXCLUDED.open_time, close_time = EXCLUDED.close_time,
+			reason = EXCLUDED.reason, created_by = EXCLUDED.created_by, created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at`,
+		e.VenueID, e.Date, e.IsClosed, e.Open, e.Close, e.Reason, e.CreatedBy,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the exception, returning sql.ErrNoRows when the venue has
+// no exception with its ID
+func (e *HoursException) Delete(ctx context.Context) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_hours_exceptions WHERE id = $1 AND venue_id = $2",
+		e.ID, e.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetHoursExceptions returns the exceptions of the venues from one date to
+// another, both included, by venue and in date order
+func GetHoursExceptions(ctx context.Context, venueIDs []int64, from, to string) (map[int64][]HoursException, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, venue_id, to_char(date, 'YYYY-MM-DD'), is_closed,
+			   COALESCE(open_time, ''), COALESCE(close_time, ''), COALESCE(reason, ''),
+			   COALESCE(created_by, 0), created_at
+		FROM venue_hours_exceptions
+		WHERE venue_id = ANY($1) AND date BETWEEN $2 AND $3
+		ORDER BY venue_id, date`,
+		pq.Array(venueIDs), from, to,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := make(map[int64][]HoursException)
+	for rows.Next() {
+		var e HoursException
+		err := rows.Scan(&e.ID, &e.VenueID, &e.Date, &e.IsClosed,
+			&e.Open, &e.Close, &e.Reason, &e.CreatedBy, &e.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		exceptions[e.VenueID] = append(exceptions[e.VenueID], e)
+	}
+	return exceptions, rows.Err()
+}
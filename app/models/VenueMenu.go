@@ -0,0 +1,527 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Dietary tags supported on menu items
+const (
+	DietaryVegan      = "vegan"
+	DietaryVegetarian = "vegetarian"
+	DietaryHalal      = "halal"
+	DietaryKosher     = "kosher"
+	DietaryGlutenFree = "gluten-free"
+	DietaryDairyFree  = "dairy-free"
+	DietaryNutFree    = "nut-free"
+)
+
+// DietaryTags lists every valid dietary tag
+var DietaryTags = []string{
+	DietaryVegan, DietaryVegetarian, DietaryHalal, DietaryKosher,
+	DietaryGlutenFree, DietaryDairyFree, DietaryNutFree,
+}
+
+// VenueMenu represents a menu published by a venue (e.g. "Lunch", "Drinks")
+type VenueMenu struct {
+	ID          int64         `json:"id"`
+	VenueID     int64         `json:"venueId"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Currency    string        `json:"currency"`
+	IsActive    bool          `json:"isActive"`
+	SortOrder   int           `json:"sortOrder"`
+	Sections    []MenuSection `json:"sections"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+// MenuSection groups menu items (e.g. "Starters", "Mains")
+type MenuSection struct {
+	ID          int64      `json:"id"`
+	MenuID      int64      `json:"menuId"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	SortOrder   int        `json:"sortOrder"`
+	Items       []MenuItem `json:"items"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// MenuItem represents a single dish or drink
+type MenuItem struct {
+	ID          int64     `json:"id"`
+	SectionID   int64     `json:"sectionId"`
+	VenueID     int64     `json:"venueId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Price       *float64  `json:"price,omitempty"`
+	DietaryTags []string  `json:"dietaryTags"`
+	IsAvailable bool      `json:"isAvailable"`
+	SortOrder   int       `json:"sortOrder"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// MenuItemMatch is a menu item surfaced by venue search
+type MenuItemMatch struct {
+	MenuItem
+	VenueName string `json:"venueName"`
+	VenueSlug string `json:"venueSlug"`
+}
+
+func (m *VenueMenu) TableName() string {
+	return "venue_menus"
+}
+
+func (s *MenuSection) TableName() string {
+	return "menu_sections"
+}
+
+func (i *MenuItem) TableName() string {
+	return "menu_items"
+}
+
+// Create creates a new menu
+func (m *VenueMenu) Create(ctx context.Context) error {
+	query := `
+		INSERT INTO venue_menus (venue_id, name, description, currency, is_active, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, m.VenueID, m.Name, m.Description, m.Currency, m.IsActive, m.SortOrder,
+	).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a menu without its sections
+func (m *VenueMenu) GetByID(ctx context.Context) error {
+	query := `
+		SELECT id, venue_id, name, description, currency, is_active, sort_order, created_at, updated_at
+		FROM venue_menus
+		WHERE id = $1`
+
+	var description sql.NullString
+	err := databases.PostgresDB.QueryRowContext(ctx, query, m.ID).Scan(
+		&m.ID, &m.VenueID, &m.Name, &description, &m.Currency,
+		&m.IsActive, &m.SortOrder, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	m.Description = description.String
+	return nil
+}
+
+// Update updates the menu details
+func (m *VenueMenu) Update(ctx context.Context) error {
+	query := `
+		UPDATE venue_menus
+		SET name = $2, description = $3, currency = $4, is_active = $5, sort_order = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, m.ID, m.Name, m.Description, m.Currency, m.IsActive, m.SortOrder,
+	).Scan(&m.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the menu along with its sections and items
+func (m *VenueMenu) Delete(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM venue_menus WHERE id = $1", m.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Create creates a new menu section
+func (s *MenuSection) Create(ctx context.Context) error {
+	query := `
+		INSERT INTO menu_sections (menu_id, name, description, sort_order)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, s.MenuID, s.Name, s.Description, s.SortOrder,
+	).Scan(&s.ID, &s.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a menu section without its items
+func (s *MenuSection) GetByID(ctx context.Context) error {
+	query := `
+		SELECT id, menu_id, name, description, sort_order, created_at
+		FROM menu_sections
+		WHERE id = $1`
+
+	var description sql.NullString
+	err := databases.PostgresDB.QueryRowContext(ctx, query, s.ID).Scan(
+		&s.ID, &s.MenuID, &s.Name, &description, &s.SortOrder, &s.CreatedAt,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	s.Description = description.String
+	return nil
+}
+
+// Update updates the menu section details
+func (s *MenuSection) Update(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"UPDATE menu_sections SET name = $2, description = $3, sort_order = $4 WHERE id = $1",
+		s.ID, s.Name, s.Description, s.SortOrder,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the menu section along with its items
+func (s *MenuSection) Delete(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM menu_sections WHERE id = $1", s.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Create creates a new menu item
+func (i *MenuItem) Create(ctx context.Context) error {
+	tagsJSON := encodeDietaryTags(i.DietaryTags)
+
+	query := `
+		INSERT INTO menu_items (section_id, venue_id, name, description, price, dietary_tags, is_available, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, i.SectionID, i.VenueID, i.Name, i.Description, i.Price,
+		tagsJSON, i.IsAvailable, i.SortOrder,
+	).Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a menu item
+func (i *MenuItem) GetByID(ctx context.Context) error {
+	query := `
+		SELECT id, section_id, venue_id, name, description, price, dietary_tags,
+			   is_available, sort_order, created_at, updated_at
+		FROM menu_items
+		WHERE id = $1`
+
+	err := scanMenuItem(databases.PostgresDB.QueryRowContext(ctx, query, i.ID), i)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Update updates the menu item details
+func (i *MenuItem) Update(ctx context.Context) error {
+	tagsJSON := encodeDietaryTags(i.DietaryTags)
+
+	query := `
+		UPDATE menu_items
+		SET name = $2, description = $3, price = $4, dietary_tags = $5,
+			is_available = $6, sort_order = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, i.ID, i.Name, i.Description, i.Price, tagsJSON, i.IsAvailable, i.SortOrder,
+	).Scan(&i.UpdatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Delete removes the menu item
+func (i *MenuItem) Delete(ctx context.Context) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, "DELETE FROM menu_items WHERE id = $1", i.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetVenueMenus returns the venue's menus with sections and items. When
+// dietaryTag is set only items carrying that tag are included.
+func GetVenueMenus(ctx context.Context, venueID int64, activeOnly bool, dietaryTag string) ([]VenueMenu, error) {
+	menuQuery := `
+		SELECT id, venue_id, name, description, currency, is_active, sort_order, created_at, updated_at
+		FROM venue_menus
+		WHERE venue_id = $1`
+	if activeOnly {
+		menuQuery += " AND is_active = true"
+	}
+	menuQuery += " ORDER BY sort_order, id"
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, menuQuery, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	menus := make([]VenueMenu, 0)
+	menuIndex := make(map[int64]int)
+	for rows.Next() {
+		var menu VenueMenu
+		var description sql.NullString
+
+		err := rows.Scan(
+			&menu.ID, &menu.VenueID, &menu.Name, &description, &menu.Currency,
+			&menu.IsActive, &menu.SortOrder, &menu.CreatedAt, &menu.UpdatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		menu.Description = description.String
+		menu.Sections = make([]MenuSection, 0)
+		menuIndex[menu.ID] = len(menus)
+		menus = append(menus, menu)
+	}
+
+	if len(menus) == 0 {
+		return menus, nil
+	}
+
+	// Load sections of all menus at once
+	sectionRows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT s.id, s.menu_id, s.name, s.description, s.sort_order, s.created_at
+		FROM menu_sections s
+		JOIN venue_menus m ON s.menu_id = m.id
+		WHERE m.venue_id = $1
+		ORDER BY s.sort_order, s.id`, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer sectionRows.Close()
+
+	type sectionRef struct{ menu, section int }
+	sectionIndex := make(map[int64]sectionRef)
+	for sectionRows.Next() {
+		var section MenuSection
+		var description sql.NullString
+
+		err := sectionRows.Scan(
+			&section.ID, &section.MenuID, &section.Name, &description,
+			&section.SortOrder, &section.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		idx, exists := menuIndex[section.MenuID]
+		if !exists {
+			continue
+		}
+
+		section.Description = description.String
+		section.Items = make([]MenuItem, 0)
+		sectionIndex[section.ID] = sectionRef{menu: idx, section: len(menus[idx].Sections)}
+		menus[idx].Sections = append(menus[idx].Sections, section)
+	}
+
+	// Load items of all sections at once
+	itemQuery := `
+		SELECT id, section_id, venue_id, name, description, price, dietary_tags,
+			   is_available, sort_order, created_at, updated_at
+		FROM menu_items
+		WHERE venue_id = $1`
+	args := []interface{}{venueID}
+	if dietaryTag != "" {
+		tagJSON, _ := json.Marshal([]string{dietaryTag})
+		itemQuery += " AND dietary_tags @> $2::jsonb"
+		args = append(args, string(tagJSON))
+	}
+	itemQuery += " ORDER BY sort_order, id"
+
+	itemRows, err := databases.PostgresDB.QueryContext(ctx, itemQuery, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item MenuItem
+		if err := scanMenuItem(itemRows, &item); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		ref, exists := sectionIndex[item.SectionID]
+		if !exists {
+			continue
+		}
+		menus[ref.menu].Sections[ref.section].Items = append(menus[ref.menu].Sections[ref.section].Items, item)
+	}
+
+	return menus, nil
+}
+
+// SearchMenuItems finds available items of active venues whose name matches
+// the query, e.g. to surface dishes in venue search
+func SearchMenuItems(ctx context.Context, query string, dietaryTags []string, cityID *int64, limit int) ([]MenuItemMatch, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	sqlQuery := `
+		SELECT mi.id, mi.section_id, mi.venue_id, mi.name, mi.description, mi.price, mi.dietary_tags,
+			   mi.is_available, mi.sort_order, mi.created_at, mi.updated_at,
+			   v.name, v.slug
+		FROM menu_items mi
+		JOIN menu_sections s ON mi.section_id = s.id
+		JOIN venue_menus m ON s.menu_id = m.id AND m.is_active = true
+		JOIN venues v ON mi.venue_id = v.id AND v.is_active = true
+		WHERE mi.is_available = true AND mi.name ILIKE $1`
+
+	args := []interface{}{"%" + query + "%"}
+	argCount := 1
+
+	if len(dietaryTags) > 0 {
+		argCount++
+		tagsJSON, _ := json.Marshal(dietaryTags)
+		sqlQuery += fmt.Sprintf(" AND mi.dietary_tags @> $%d::jsonb", argCount)
+		args = append(args, string(tagsJSON))
+	}
+
+	if cityID != nil {
+		argCount++
+		sqlQuery += fmt.Sprintf(" AND v.city_id = $%d", argCount)
+		args = append(args, *cityID)
+	}
+
+	argCount++
+	sqlQuery += fmt.Sprintf(" ORDER BY v.average_rating DESC, mi.name LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]MenuItemMatch, 0)
+	for rows.Next() {
+		var match MenuItemMatch
+		var description sql.NullString
+		var price sql.NullFloat64
+		var tagsJSON []byte
+
+		err := rows.Scan(
+			&match.ID, &match.SectionID, &match.VenueID, &match.Name, &description,
+			&price, &tagsJSON, &match.IsAvailable, &match.SortOrder,
+			&match.CreatedAt, &match.UpdatedAt,
+			&match.VenueName, &match.VenueSlug,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		match.Description = description.String
+		if price.Valid {
+			match.Price = &price.Float64
+		}
+		match.DietaryTags = decodeDietaryTags(tagsJSON)
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// IsValidDietaryTag reports whether tag is a supported dietary tag
+func IsValidDietaryTag(tag string) bool {
+	for _, valid := range DietaryTags {
+		if tag == valid {
+			return true
+		}
+	}
+	return false
+}
+
+type menuItemScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMenuItem(row menuItemScanner, item *MenuItem) error {
+	var description sql.NullString
+	var price sql.NullFloat64
+	var tagsJSON []byte
+
+	err := row.Scan(
+		&item.ID, &item.SectionID, &item.VenueID, &item.Name, &description,
+		&price, &tagsJSON, &item.IsAvailable, &item.SortOrder,
+		&item.CreatedAt, &item.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	item.Description = description.String
+	if price.Valid {
+		item.Price = &price.Float64
+	}
+	item.DietaryTags = decodeDietaryTags(tagsJSON)
+	return nil
+}
+
+func encodeDietaryTags(tags []string) []byte {
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, _ := json.Marshal(tags)
+	return tagsJSON
+}
+
+func decodeDietaryTags(tagsJSON []byte) []string {
+	tags := make([]string, 0)
+	if len(tagsJSON) > 0 {
+		json.Unmarshal(tagsJSON, &tags)
+	}
+	return tags
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrVenuePhotoQuota is returned when the venue has as many photos as its
+// owner's tier allows
+var ErrVenuePhotoQuota = errors.New("venue has as many photos as its owner's tier allows")
+
+// VenuePhoto is a photo of a venue's gallery, added by its owner
+type VenuePhoto struct {
+	ID        int64     `json:"id"`
+	VenueID   int64     `json:"venueId"`
+	URL       string    `json:"url"`
+	Caption   string    `json:"caption,omitempty"`
+	AddedBy   int64     `json:"addedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (p *VenuePhoto) TableName() string {
+	return "venue_photos"
+}
+
+// Create adds the photo to the venue's gallery unless the gallery already
+// has maxPhotos photos, 0 for no limit. The quota is soft: photos added
+// before an owner's tier went down stay.
+func (p *VenuePhoto) Create(ctx context.Context, maxPhotos int) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the venue keeps concurrent uploads from both taking the last
+	// photo of the quota
+	_, err = tx.ExecContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", p.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if maxPhotos > 0 {
+		var count int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM venue_photos WHERE venue_id = $1", p.VenueID).Scan(&count)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+		if count >= maxPhotos {
+			return ErrVenuePhotoQuota
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO venue_photos (venue_id, url, caption, added_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id, created_at`,
+		p.VenueID, p.URL, p.Caption, p.AddedBy,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	return nil
+}
+
+// GetVenuePhotos returns the venue's gallery, oldest first
+func GetVenuePhotos(ctx context.Context, venueID int64) ([]VenuePhoto, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, venue_id, url, COALESCE(caption, ''), added_by, created_at
+		FROM venue_photos
+		WHERE venue_id = $1
+		ORDER BY created_at, id`,
+		venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := make([]VenuePhoto, 0)
+	for rows.Next() {
+		var photo VenuePhoto
+		err := rows.Scan(&photo.ID, &photo.VenueID, &photo.URL, &photo.Caption, &photo.AddedBy, &photo.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
+
+// DeleteVenuePhoto removes the photo from the venue's gallery,
+// sql.ErrNoRows when the venue has no such photo
+func DeleteVenuePhoto(ctx context.Context, venueID, photoID int64) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_photos WHERE id = $1 AND venue_id = $2", photoID, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	databases "voting-app/app"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
 )
 
 // VenueReview represents a detailed review of a venue
@@ -24,24 +26,32 @@ type VenueReview struct {
 	Title      string `json:"title,omitempty"`
 	ReviewText string `json:"reviewText,omitempty"`
 
+	// Translation of the title and text, set when listing with translate_to
+	Translation *ReviewTranslation `json:"translation,omitempty"`
+
 	// Visit Information
 	VisitDate *time.Time `json:"visitDate,omitempty"`
 	VisitType string     `json:"visitType,omitempty"` // dinner, lunch, drinks, event
 	PartySize int        `json:"partySize,omitempty"`
 
 	// Media
-	Photos json.RawMessage `json:"photos,omitempty"` // Array of photo URLs
+	Photos       ReviewPhotos `json:"photos,omitempty"`       // In order, with their captions
+	PhotoDetails []Photo      `json:"photoDetails,omitempty"` // Dimensions and colors of Photos, in order
 
 	// Moderation
 	IsVerified       bool   `json:"isVerified"`
 	IsFeatured       bool   `json:"isFeatured"`
 	IsFlagged        bool   `json:"isFlagged"`
-	ModerationStatus string `json:"moderationStatus"` // pending, approved, rejected
+	ModerationStatus string `json:"moderationStatus"`      // pending, approved, rejected
+	DuplicateOf      *int64 `json:"duplicateOf,omitempty"` // Original review this one copies
 
 	// Engagement
 	HelpfulVotes   int `json:"helpfulVotes"`
 	UnhelpfulVotes int `json:"unhelpfulVotes"`
 
+	// InviteToken of a venue review invite, verifies the visit when valid
+	InviteToken string `json:"-"`
+
 	// User Information (joined)
 	User     *SnappUser `json:"user,omitempty"`
 	UserName string     `json:"userName,omitempty"`
@@ -54,11 +64,17 @@ type VenueReview struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// VerifiedReviewWeight is how much more a review of a verified visit counts
+// in weighted ratings and recommendations than an unverified one
+const VerifiedReviewWeight = 2.0
+
 // ReviewSummary for analytics and display
 type ReviewSummary struct {
 	VenueID         int64              `json:"venueId"`
 	AverageRating   float64            `json:"averageRating"`
+	WeightedRating  float64            `json:"weightedRating"` // Average with verified reviews weighted higher
 	TotalReviews    int                `json:"totalReviews"`
+	VerifiedReviews int                `json:"verifiedReviews"`
 	RatingBreakdown map[string]int     `json:"ratingBreakdown"` // {"5": 10, "4": 5, "3": 2, "2": 1, "1": 0}
 	DetailedAverage map[string]float64 `json:"detailedAverage"` // {"food": 4.2, "service": 4.1, ...}
 	RecentReviews   []VenueReview      `json:"recentReviews"`
@@ -79,6 +95,9 @@ type ReviewFilters struct {
 	SortBy     string     `json:"sortBy,omitempty"` // newest, oldest, rating_high, rating_low, helpful
 	Page       int        `json:"page"`
 	Limit      int        `json:"limit"`
+
+	// ViewerID hides reviews of users the viewer blocked or muted
+	ViewerID *int64 `json:"-"`
 }
 
 func (r *VenueReview) TableName() string {
@@ -86,10 +105,10 @@ func (r *VenueReview) TableName() string {
 }
 
 // Create creates a new review
-func (r *VenueReview) Create() error {
+func (r *VenueReview) Create(ctx context.Context) error {
 	// Check if user has already reviewed this venue
 	var existingCount int
-	err := databases.PostgresDB.QueryRow(
+	err := databases.PostgresDB.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1 AND user_id = $2",
 		r.VenueID, r.UserID,
 	).Scan(&existingCount)
@@ -108,20 +127,38 @@ func (r *VenueReview) Create() error {
 		return fmt.Errorf("rating must be between 1.0 and 5.0")
 	}
 
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// A valid invite verifies the visit and is used up by this review
+	var inviteID int64
+	if r.InviteToken != "" {
+		inviteID, err = claimReviewInvite(ctx, tx, r.VenueID, r.InviteToken)
+		if err != nil {
+			return err
+		}
+		r.IsVerified = true
+	}
+
 	// Insert new review
 	query := `
 		INSERT INTO venue_reviews (
 			venue_id, user_id, overall_rating, detailed_ratings,
 			title, review_text, visit_date, visit_type, party_size,
-			photos, moderation_status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			photos, moderation_status, is_verified, is_flagged, flagged_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+			CASE WHEN $13 THEN CURRENT_TIMESTAMP END)
 		RETURNING id, created_at, updated_at`
 
-	err = databases.PostgresDB.QueryRow(
+	err = tx.QueryRowContext(ctx,
 		query,
 		r.VenueID, r.UserID, r.OverallRating, r.DetailedRatings,
 		r.Title, r.ReviewText, r.VisitDate, r.VisitType, r.PartySize,
-		r.Photos, "pending",
+		r.Photos, "pending", r.IsVerified, r.IsFlagged,
 	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
 
 	if err != nil {
@@ -129,50 +166,137 @@ func (r *VenueReview) Create() error {
 		return err
 	}
 
+	if inviteID != 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE review_invites SET review_id = $1 WHERE id = $2", r.ID, inviteID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	// The draft of the review is no longer needed
+	_, err = tx.ExecContext(ctx, "DELETE FROM review_drafts WHERE user_id = $1 AND venue_id = $2", r.UserID, r.VenueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
 	// Update venue rating cache
 	venue := &Venue{ID: r.VenueID}
-	go venue.UpdateRatingCache() // Update in background
+	go venue.UpdateRatingCache(context.Background()) // Update in background, outliving the request
 
 	return nil
 }
 
-// GetByID retrieves a review by ID with user and venue info
-func (r *VenueReview) GetByID() error {
-	query := `
+// HasUserReviewedVenue reports whether the user wrote a review of the venue
+func HasUserReviewedVenue(ctx context.Context, venueID, userID int64) (bool, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1 AND user_id = $2",
+		venueID, userID,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// reviewDetailQuery selects the reviews with user and venue info scanned
+// by VenueReview.scanDetail, followed by the WHERE clause
+const reviewDetailQuery = `
 		SELECT r.id, r.venue_id, r.user_id, r.overall_rating, r.detailed_ratings,
 			   r.title, r.review_text, r.visit_date, r.visit_type, r.party_size,
 			   r.photos, r.is_verified, r.is_featured, r.is_flagged, r.moderation_status,
-			   r.helpful_votes, r.unhelpful_votes, r.created_at, r.updated_at,
+			   r.duplicate_of, r.helpful_votes, r.unhelpful_votes, r.created_at, r.updated_at,
 			   v.name as venue_name,
 			   u.snapp_id as user_snapp_id
 		FROM venue_reviews r
 		LEFT JOIN venues v ON r.venue_id = v.id
-		LEFT JOIN snapp_users u ON r.user_id = u.id
+		LEFT JOIN snapp_users u ON r.user_id = u.id`
+
+// GetByID retrieves a review by ID with user and venue info
+func (r *VenueReview) GetByID(ctx context.Context) error {
+	query := reviewDetailQuery + `
 		WHERE r.id = $1`
+	err := r.scanDetail(databases.PostgresDB.QueryRowContext(ctx, query, r.ID))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	reviews := []VenueReview{*r}
+	AttachPhotoDetails(ctx, reviews)
+	r.PhotoDetails = reviews[0].PhotoDetails
 
-	row := databases.PostgresDB.QueryRow(query, r.ID)
+	return nil
+}
 
+// GetReviewsByIDs returns the approved reviews among the IDs with user and
+// venue info, in no particular order
+func GetReviewsByIDs(ctx context.Context, ids []int64) ([]VenueReview, error) {
+	reviews := make([]VenueReview, 0, len(ids))
+	if len(ids) == 0 {
+		return reviews, nil
+	}
+
+	query := reviewDetailQuery + `
+		WHERE r.id = ANY($1) AND r.moderation_status = 'approved'`
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review VenueReview
+		if err := review.scanDetail(rows); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	AttachPhotoDetails(ctx, reviews)
+	return reviews, nil
+}
+
+// scanDetail reads a row of reviewDetailQuery
+func (r *VenueReview) scanDetail(row interface{ Scan(...interface{}) error }) error {
 	var visitDate sql.NullTime
 	var userSnapID sql.NullString
+	var duplicateOf sql.NullInt64
 
 	err := row.Scan(
 		&r.ID, &r.VenueID, &r.UserID, &r.OverallRating, &r.DetailedRatings,
 		&r.Title, &r.ReviewText, &visitDate, &r.VisitType, &r.PartySize,
 		&r.Photos, &r.IsVerified, &r.IsFeatured, &r.IsFlagged, &r.ModerationStatus,
-		&r.HelpfulVotes, &r.UnhelpfulVotes, &r.CreatedAt, &r.UpdatedAt,
+		&duplicateOf, &r.HelpfulVotes, &r.UnhelpfulVotes, &r.CreatedAt, &r.UpdatedAt,
 		&r.VenueName, &userSnapID,
 	)
-
 	if err != nil {
-		if err != sql.ErrNoRows {
-			sentry.CaptureException(err)
-		}
 		return err
 	}
 
 	if visitDate.Valid {
 		r.VisitDate = &visitDate.Time
 	}
+	r.DuplicateOf = nil
+	if duplicateOf.Valid {
+		r.DuplicateOf = &duplicateOf.Int64
+	}
 
 	// Set user name (you might want to get from user profile instead)
 	if userSnapID.Valid {
@@ -183,7 +307,7 @@ func (r *VenueReview) GetByID() error {
 }
 
 // Search finds reviews based on filters
-func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error) {
+func (r *VenueReview) Search(ctx context.Context, filters ReviewFilters) ([]VenueReview, int, error) {
 	baseQuery := `
 		SELECT r.id, r.venue_id, r.user_id, r.overall_rating, r.detailed_ratings,
 			   r.title, r.review_text, r.visit_date, r.visit_type, r.party_size,
@@ -254,6 +378,12 @@ func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error)
 		args = append(args, *filters.DateTo)
 	}
 
+	if filters.ViewerID != nil {
+		argCount++
+		whereClause += " AND " + HiddenUsersCondition("r.user_id", fmt.Sprintf("$%d", argCount))
+		args = append(args, *filters.ViewerID)
+	}
+
 	// Sorting
 	var orderBy string
 	switch filters.SortBy {
@@ -264,7 +394,7 @@ func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error)
 	case "rating_low":
 		orderBy = "ORDER BY r.overall_rating ASC, r.created_at DESC"
 	case "helpful":
-		orderBy = "ORDER BY r.helpful_votes DESC, r.created_at DESC"
+		orderBy = "ORDER BY " + HelpfulnessExpression("r") + " DESC, r.helpful_votes DESC, r.created_at DESC"
 	default: // newest
 		orderBy = "ORDER BY r.created_at DESC"
 	}
@@ -283,7 +413,7 @@ func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error)
 	// Execute query
 	fullQuery := baseQuery + " " + whereClause + " " + orderBy + limitClause
 
-	rows, err := databases.PostgresDB.Query(fullQuery, args...)
+	rows, err := databases.PostgresDB.QueryContext(ctx, fullQuery, args...)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil, 0, err
@@ -319,10 +449,12 @@ func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error)
 		reviews = append(reviews, review)
 	}
 
+	AttachPhotoDetails(ctx, reviews)
+
 	// Get total count
 	countQuery := "SELECT COUNT(*) FROM venue_reviews r " + whereClause
 	var totalCount int
-	err = databases.PostgresDB.QueryRow(countQuery, args...).Scan(&totalCount)
+	err = databases.PostgresDB.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
 	if err != nil {
 		sentry.CaptureException(err)
 		return reviews, 0, err
@@ -332,7 +464,7 @@ func (r *VenueReview) Search(filters ReviewFilters) ([]VenueReview, int, error)
 }
 
 // GetVenueReviewSummary returns comprehensive review statistics for a venue
-func GetVenueReviewSummary(venueID int64) (*ReviewSummary, error) {
+func GetVenueReviewSummary(ctx context.Context, venueID int64) (*ReviewSummary, error) {
 	summary := &ReviewSummary{
 		VenueID:         venueID,
 		RatingBreakdown: make(map[string]int),
@@ -343,18 +475,21 @@ func GetVenueReviewSummary(venueID int64) (*ReviewSummary, error) {
 	basicQuery := `
 		SELECT 
 			COALESCE(AVG(overall_rating), 0) as avg_rating,
+			COALESCE(SUM(overall_rating * CASE WHEN is_verified THEN $2::numeric ELSE 1.0 END) /
+				NULLIF(SUM(CASE WHEN is_verified THEN $2::numeric ELSE 1.0 END), 0), 0) as weighted_rating,
 			COUNT(*) as total_reviews,
+			COUNT(CASE WHEN is_verified THEN 1 END) as verified_reviews,
 			COUNT(CASE WHEN overall_rating >= 4.5 THEN 1 END) as rating_5,
 			COUNT(CASE WHEN overall_rating >= 3.5 AND overall_rating < 4.5 THEN 1 END) as rating_4,
 			COUNT(CASE WHEN overall_rating >= 2.5 AND overall_rating < 3.5 THEN 1 END) as rating_3,
 			COUNT(CASE WHEN overall_rating >= 1.5 AND overall_rating < 2.5 THEN 1 END) as rating_2,
 			COUNT(CASE WHEN overall_rating < 1.5 THEN 1 END) as rating_1
 		FROM venue_reviews 
-		WHERE venue_id = $1 AND moderation_status = 'approved'`
+		WHERE venue_id = $1 AND moderation_status = 'approved' AND duplicate_of IS NULL`
 
 	var rating5, rating4, rating3, rating2, rating1 int
-	err := databases.PostgresDB.QueryRow(basicQuery, venueID).Scan(
-		&summary.AverageRating, &summary.TotalReviews,
+	err := databases.PostgresDB.QueryRowContext(ctx, basicQuery, venueID, VerifiedReviewWeight).Scan(
+		&summary.AverageRating, &summary.WeightedRating, &summary.TotalReviews, &summary.VerifiedReviews,
 		&rating5, &rating4, &rating3, &rating2, &rating1,
 	)
 
@@ -378,7 +513,7 @@ func GetVenueReviewSummary(venueID int64) (*ReviewSummary, error) {
 	}
 
 	review := &VenueReview{}
-	recentReviews, _, err := review.Search(recentFilters)
+	recentReviews, _, err := review.Search(ctx, recentFilters)
 	if err == nil {
 		summary.RecentReviews = recentReviews
 	}
@@ -391,7 +526,7 @@ func GetVenueReviewSummary(venueID int64) (*ReviewSummary, error) {
 		Page:    1,
 	}
 
-	topReviews, _, err := review.Search(topFilters)
+	topReviews, _, err := review.Search(ctx, topFilters)
 	if err == nil {
 		summary.TopReviews = topReviews
 	}
@@ -400,10 +535,10 @@ func GetVenueReviewSummary(venueID int64) (*ReviewSummary, error) {
 }
 
 // VoteHelpful marks a review as helpful/unhelpful
-func (r *VenueReview) VoteHelpful(userID int64, isHelpful bool) error {
+func (r *VenueReview) VoteHelpful(ctx context.Context, userID int64, isHelpful bool) error {
 	// Check if user has already voted
 	var existingVote bool
-	err := databases.PostgresDB.QueryRow(
+	err := databases.PostgresDB.QueryRowContext(ctx,
 		"SELECT is_helpful FROM review_votes WHERE review_id = $1 AND user_id = $2",
 		r.ID, userID,
 	).Scan(&existingVote)
@@ -411,14 +546,14 @@ func (r *VenueReview) VoteHelpful(userID int64, isHelpful bool) error {
 	if err == nil {
 		// Update existing vote
 		if existingVote != isHelpful {
-			_, err = databases.PostgresDB.Exec(
+			_, err = databases.PostgresDB.ExecContext(ctx,
 				"UPDATE review_votes SET is_helpful = $1 WHERE review_id = $2 AND user_id = $3",
 				isHelpful, r.ID, userID,
 			)
 		}
 	} else if err == sql.ErrNoRows {
 		// Create new vote
-		_, err = databases.PostgresDB.Exec(
+		_, err = databases.PostgresDB.ExecContext(ctx,
 			"INSERT INTO review_votes (review_id, user_id, is_helpful) VALUES ($1, $2, $3)",
 			r.ID, userID, isHelpful,
 		)
@@ -433,7 +568,7 @@ func (r *VenueReview) VoteHelpful(userID int64, isHelpful bool) error {
 	}
 
 	// Update review vote counts
-	_, err = databases.PostgresDB.Exec(`
+	_, err = databases.PostgresDB.ExecContext(ctx, `
 		UPDATE venue_reviews SET 
 			helpful_votes = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND is_helpful = true),
 			unhelpful_votes = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND is_helpful = false)
@@ -448,11 +583,14 @@ func (r *VenueReview) VoteHelpful(userID int64, isHelpful bool) error {
 	return err
 }
 
-// ApproveReview approves a review for display
-func (r *VenueReview) ApproveReview() error {
-	_, err := databases.PostgresDB.Exec(
-		"UPDATE venue_reviews SET moderation_status = 'approved', updated_at = CURRENT_TIMESTAMP WHERE id = $1",
-		r.ID,
+// ApproveReview approves a review for display, recording the moderator
+func (r *VenueReview) ApproveReview(ctx context.Context, moderatorID int64) error {
+	_, err := databases.PostgresDB.ExecContext(ctx, `
+		UPDATE venue_reviews
+		SET moderation_status = 'approved', is_flagged = false,
+			moderated_by = $2, moderated_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		r.ID, moderatorID,
 	)
 
 	if err != nil {
@@ -462,7 +600,44 @@ func (r *VenueReview) ApproveReview() error {
 
 	// Update venue rating cache
 	venue := &Venue{ID: r.VenueID}
-	go venue.UpdateRatingCache()
+	go venue.UpdateRatingCache(context.Background())
 
 	return nil
 }
+
+// GetDetailedRatingAverages returns the average of every rating dimension
+// ("food", "service", ...) of the approved reviews of each venue
+func GetDetailedRatingAverages(ctx context.Context, venueIDs []int64) (map[int64]map[string]float64, error) {
+	query := `
+		SELECT r.venue_id, d.key, AVG(d.value::numeric)
+		FROM venue_reviews r,
+			 jsonb_each_text(CASE WHEN jsonb_typeof(r.detailed_ratings) = 'object'
+								  THEN r.detailed_ratings ELSE '{}'::jsonb END) d
+		WHERE r.venue_id = ANY($1) AND r.moderation_status = 'approved'
+		  AND d.value ~ '^[0-9]+(\.[0-9]+)?$'
+		GROUP BY r.venue_id, d.key`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, pq.Array(venueIDs))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	averages := make(map[int64]map[string]float64, len(venueIDs))
+	for rows.Next() {
+		var venueID int64
+		var dimension string
+		var average float64
+		if err := rows.Scan(&venueID, &dimension, &average); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if averages[venueID] == nil {
+			averages[venueID] = make(map[string]float64)
+		}
+		averages[venueID][dimension] = average
+	}
+
+	return averages, nil
+}
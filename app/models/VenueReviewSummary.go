@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// GeneratedReviewSummary is the short text summary of a venue's approved
+// reviews shown on the venue, regenerated once they change
+type GeneratedReviewSummary struct {
+	VenueID     int64     `json:"venueId"`
+	Summary     string    `json:"summary"`
+	Provider    string    `json:"provider"`    // Summarizer that wrote it
+	ReviewCount int       `json:"reviewCount"` // Approved reviews summarized
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// ReviewsChangedAt is when the summarized reviews last changed, the
+	// summary is stale once it differs
+	ReviewsChangedAt *time.Time `json:"-"`
+}
+
+// SummaryReviewStats describes a venue's approved reviews
+type SummaryReviewStats struct {
+	ReviewCount   int
+	AverageRating float64
+	ChangedAt     *time.Time // Latest creation or edit
+}
+
+func (s *GeneratedReviewSummary) TableName() string {
+	return "venue_review_summaries"
+}
+
+// Save stores the summary, replacing the venue's earlier one
+func (s *GeneratedReviewSummary) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_review_summaries (venue_id, summary, provider, review_count, reviews_changed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (venue_id) DO UPDATE
+		SET summary = EXCLUDED.summary, provider = EXCLUDED.provider,
+			review_count = EXCLUDED.review_count, reviews_changed_at = EXCLUDED.reviews_changed_at,
+			generated_at = CURRENT_TIMESTAMP
+		RETURNING generated_at`,
+		s.VenueID, s.Summary, s.Provider, s.ReviewCount, s.ReviewsChangedAt,
+	).Scan(&s.GeneratedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetGeneratedReviewSummary returns the venue's summary, sql.ErrNoRows when
+// it has none
+func GetGeneratedReviewSummary(ctx context.Context, venueID int64) (*GeneratedReviewSummary, error) {
+	s := &GeneratedReviewSummary{}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT venue_id, summary, provider, review_count, generated_at, reviews_changed_at
+		FROM venue_review_summaries
+		WHERE venue_id = $1`, venueID,
+	).Scan(&s.VenueID, &s.Summary, &s.Provider, &s.ReviewCount, &s.GeneratedAt, &s.ReviewsChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DeleteGeneratedReviewSummary removes the summary of a venue left with too
+// few approved reviews
+func DeleteGeneratedReviewSummary(ctx context.Context, venueID int64) error {
+	_, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_review_summaries WHERE venue_id = $1", venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetStaleReviewSummaryVenues returns the active venues after afterID whose
+// summary is missing or out of date: ones with at least minReviews approved
+// reviews and no summary, and summarized ones whose approved reviews were
+// added, edited or removed since
+func GetStaleReviewSummaryVenues(ctx context.Context, afterID int64, minReviews, limit int) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id
+		FROM venues v
+		LEFT JOIN venue_review_summaries s ON s.venue_id = v.id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS review_count, MAX(GREATEST(r.created_at, r.updated_at)) AS changed_at
+			FROM venue_reviews r
+			WHERE r.venue_id = v.id AND r.moderation_status = 'approved' AND r.duplicate_of IS NULL
+		) r
+		WHERE v.id > $1 AND v.is_active = true
+		  AND ((s.venue_id IS NULL AND r.review_count >= $2)
+		   OR (s.venue_id IS NOT NULL AND (r.review_count <> s.review_count
+				OR r.changed_at IS DISTINCT FROM s.reviews_changed_at)))
+		ORDER BY v.id
+		LIMIT $3`, afterID, minReviews, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var venueIDs []int64
+	for rows.Next() {
+		var venueID int64
+		if err := rows.Scan(&venueID); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		venueIDs = append(venueIDs, venueID)
+	}
+	return venueIDs, rows.Err()
+}
+
+// GetSummaryReviewStats counts the venue's approved reviews, duplicates left
+// out
+func GetSummaryReviewStats(ctx context.Context, venueID int64) (*SummaryReviewStats, error) {
+	stats := &SummaryReviewStats{}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(AVG(overall_rating), 0), MAX(GREATEST(created_at, updated_at))
+		FROM venue_reviews
+		WHERE venue_id = $1 AND moderation_status = 'approved' AND duplicate_of IS NULL`, venueID,
+	).Scan(&stats.ReviewCount, &stats.AverageRating, &stats.ChangedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetSummarizedReviews returns the venue's approved reviews to summarize,
+// the most helpful and then most recent first. Duplicates of other reviews
+// are left out.
+func GetSummarizedReviews(ctx context.Context, venueID int64, limit int) ([]VenueReview, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, venue_id, overall_rating, COALESCE(title, ''), COALESCE(review_text, ''),
+			   COALESCE(helpful_votes, 0), created_at
+		FROM venue_reviews
+		WHERE venue_id = $1 AND moderation_status = 'approved' AND duplicate_of IS NULL
+		ORDER BY COALESCE(helpful_votes, 0) DESC, created_at DESC, id DESC
+		LIMIT $2`, venueID, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []VenueReview
+	for rows.Next() {
+		var r VenueReview
+		err := rows.Scan(&r.ID, &r.VenueID, &r.OverallRating, &r.Title, &r.ReviewText,
+			&r.HelpfulVotes, &r.CreatedAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
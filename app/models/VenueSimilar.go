@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// SimilarVenueList is the precomputed list of venues similar to a venue,
+// most similar first
+type SimilarVenueList struct {
+	VenueID    int64
+	SimilarIDs []int64
+	ComputedAt time.Time
+	Stale      bool // Older than the max age it was loaded with
+}
+
+func (l *SimilarVenueList) TableName() string {
+	return "venue_similar"
+}
+
+// Save creates or replaces the venue's list
+func (l *SimilarVenueList) Save(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO venue_similar (venue_id, similar_venue_ids, computed_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (venue_id) DO UPDATE SET
+			similar_venue_ids = EXCLUDED.similar_venue_ids,
+			computed_at = EXCLUDED.computed_at
+		RETURNING computed_at`,
+		l.VenueID, pq.Array(l.SimilarIDs),
+	).Scan(&l.ComputedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetSimilarVenueList returns the venue's precomputed list, nil when there
+// is none. Lists computed more than maxAge ago are marked stale.
+func GetSimilarVenueList(ctx context.Context, venueID int64, maxAge time.Duration) (*SimilarVenueList, error) {
+	list := &SimilarVenueList{VenueID: venueID}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT similar_venue_ids, computed_at,
+			   computed_at < CURRENT_TIMESTAMP - $2 * INTERVAL '1 second'
+		FROM venue_similar
+		WHERE venue_id = $1`,
+		venueID, maxAge.Seconds(),
+	).Scan(pq.Array(&list.SimilarIDs), &list.ComputedAt, &list.Stale)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetVenuesDueSimilarRefresh returns active venues whose list is missing or
+// older than maxAge, oldest first
+func GetVenuesDueSimilarRefresh(ctx context.Context, maxAge time.Duration, limit int) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id
+		FROM venues v
+		LEFT JOIN venue_similar s ON s.venue_id = v.id
+		WHERE v.is_active = true
+		  AND (s.venue_id IS NULL OR s.computed_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second')
+		ORDER BY s.computed_at NULLS FIRST, v.id
+		LIMIT $2`,
+		maxAge.Seconds(), limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
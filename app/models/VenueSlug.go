@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// maxVenueSlugBase leaves room for a numeric suffix in the 255 character
+// slug column
+const maxVenueSlugBase = 240
+
+// venueSlugBase shortens the slug generated from a venue name to leave room
+// for a suffix, falling back to "venue" for names without usable characters
+func venueSlugBase(slug string) string {
+	if len(slug) > maxVenueSlugBase {
+		slug = strings.TrimRight(slug[:maxVenueSlugBase], "-")
+	}
+	if slug == "" {
+		return "venue"
+	}
+	return slug
+}
+
+// allocateVenueSlug returns the base slug, or the base with the first free
+// numeric suffix ("joes-pizza-2"), that no other venue uses or used before.
+// Allocations of the same base are serialized until the transaction ends.
+func allocateVenueSlug(ctx context.Context, tx *sql.Tx, base string, venueID int64) (string, error) {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('venue_slug:' || $1))", base)
+	if err != nil {
+		sentry.CaptureException(err)
+		return "", err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT slug FROM venues
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2
+		UNION
+		SELECT slug FROM venue_slug_history
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND venue_id <> $2`,
+		base, venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			sentry.CaptureException(err)
+			return "", err
+		}
+		taken[slug] = true
+	}
+
+	slug := base
+	for suffix := 2; taken[slug]; suffix++ {
+		slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+	return slug, nil
+}
+
+// Rename changes the venue's name and, when the name no longer maps to the
+// current slug, gives it a new slug. The old slug is kept in the venue's slug
+// history so links to it still resolve.
+func (v *Venue) Rename(ctx context.Context, name, baseSlug string) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var currentSlug string
+	err = tx.QueryRowContext(ctx, "SELECT slug FROM venues WHERE id = $1 FOR UPDATE", v.ID).Scan(&currentSlug)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	slug, err := v.followSlug(ctx, tx, currentSlug, baseSlug)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE venues SET name = $2, slug = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`,
+		v.ID, name, slug).Scan(&v.UpdatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	v.Name = name
+	v.Slug = slug
+	return nil
+}
+
+// followSlug returns the slug the venue should use for the base slug, moving
+// the current slug to the venue's slug history when it changes. The caller
+// holds the venue's row lock.
+func (v *Venue) followSlug(ctx context.Context, tx *sql.Tx, currentSlug, baseSlug string) (string, error) {
+	baseSlug = venueSlugBase(baseSlug)
+	if slugHasBase(currentSlug, baseSlug) {
+		return currentSlug, nil
+	}
+
+	slug, err := allocateVenueSlug(ctx, tx, baseSlug, v.ID)
+	if err != nil || slug == currentSlug {
+		return slug, err
+	}
+
+	// A slug the venue used before is taken back from its history
+	_, err = tx.ExecContext(ctx, "DELETE FROM venue_slug_history WHERE slug = $1", slug)
+	if err != nil {
+		sentry.CaptureException(err)
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO venue_slug_history (slug, venue_id) VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING`,
+		currentSlug, v.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return "", err
+	}
+	return slug, nil
+}
+
+// slugHasBase reports whether the slug is the base slug or the base with a
+// numeric suffix
+func slugHasBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix := strings.TrimPrefix(slug, base+"-")
+	if suffix == slug || suffix == "" {
+		return false
+	}
+	for _, char := range suffix {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveVenueSlug returns the ID of the active venue using the slug, or
+// that used it before. The current slug is returned with moved set when the
+// slug is a historical one.
+func ResolveVenueSlug(ctx context.Context, slug string) (venueID int64, currentSlug string, moved bool, err error) {
+	// A slug is either current or historical, never both
+	err = databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT id, slug FROM venues
+		WHERE slug = $1 AND is_active = true AND ($2::bigint IS NULL OR tenant_id = $2)
+		UNION ALL
+		SELECT v.id, v.slug
+		FROM venue_slug_history h
+		INNER JOIN venues v ON v.id = h.venue_id
+		WHERE h.slug = $1 AND v.is_active = true AND ($2::bigint IS NULL OR v.tenant_id = $2)
+		LIMIT 1`,
+		slug, TenantFilter(ctx)).Scan(&venueID, &currentSlug)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return venueID, currentSlug, currentSlug != slug, err
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Crowd levels reported at check-in, from the quietest to the busiest
+const (
+	CrowdQuiet    = "quiet"
+	CrowdModerate = "moderate"
+	CrowdBusy     = "busy"
+	CrowdPacked   = "packed"
+)
+
+// CrowdLevels lists the crowd levels, from the quietest to the busiest
+var CrowdLevels = []string{CrowdQuiet, CrowdModerate, CrowdBusy, CrowdPacked}
+
+const (
+	// busynessWindow is how far back reports count towards a venue's live
+	// busyness
+	busynessWindow = "1 hour"
+	// waitReportRetention is how long reports are kept before they are aged
+	// out
+	waitReportRetention = "24 hours"
+)
+
+// VenueWaitReport is the wait time and crowd level a user reported at a
+// venue when checking in. Either may be missing.
+type VenueWaitReport struct {
+	ID          int64     `json:"id"`
+	VenueID     int64     `json:"venueId"`
+	UserID      int64     `json:"userId"`
+	CheckinID   *int64    `json:"checkinId,omitempty"`
+	WaitMinutes *int      `json:"waitMinutes,omitempty"`
+	CrowdLevel  string    `json:"crowdLevel,omitempty"`
+	ReportedAt  time.Time `json:"reportedAt"`
+}
+
+func (r *VenueWaitReport) TableName() string {
+	return "venue_wait_reports"
+}
+
+// create stores the report within the check-in's transaction
+func (r *VenueWaitReport) create(ctx context.Context, tx *sql.Tx) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO venue_wait_reports (venue_id, user_id, checkin_id, wait_minutes, crowd_level)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, reported_at`,
+		r.VenueID, r.UserID, r.CheckinID, r.WaitMinutes, r.CrowdLevel,
+	).Scan(&r.ID, &r.ReportedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// VenueBusyness is a venue's live crowd level and wait time, aggregated
+// from the reports of the last hour. Only each user's latest report counts.
+type VenueBusyness struct {
+	Level        string    `json:"level,omitempty"`       // Average reported crowd level, unset without crowd reports
+	WaitMinutes  *int      `json:"waitMinutes,omitempty"` // Median reported wait
+	Reports      int       `json:"reports"`
+	LastReportAt time.Time `json:"lastReportAt"`
+}
+
+// GetVenueBusyness returns the live busyness of the venues with recent
+// reports, by venue ID
+func GetVenueBusyness(ctx context.Context, venueIDs []int64) (map[int64]*VenueBusyness, error) {
+	busyness := make(map[int64]*VenueBusyness)
+	if len(venueIDs) == 0 {
+		return busyness, nil
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		WITH latest AS (
+			SELECT DISTINCT ON (venue_id, user_id) venue_id, wait_minutes, crowd_level, reported_at
+			FROM venue_wait_reports
+			WHERE venue_id = ANY($1) AND reported_at >= CURRENT_TIMESTAMP - INTERVAL '`+busynessWindow+`'
+			ORDER BY venue_id, user_id, reported_at DESC
+		)
+		SELECT venue_id, COUNT(*),
+			   AVG(array_position($2::text[], crowd_level::text)),
+			   percentile_cont(0.5) WITHIN GROUP (ORDER BY wait_minutes),
+			   MAX(reported_at)
+		FROM latest
+		GROUP BY venue_id`,
+		pq.Array(venueIDs), pq.Array(CrowdLevels))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var venueID int64
+		var level, wait sql.NullFloat64
+		venueBusyness := &VenueBusyness{}
+		err := rows.Scan(&venueID, &venueBusyness.Reports, &level, &wait, &venueBusyness.LastReportAt)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if level.Valid {
+			// Levels are 1-based positions in CrowdLevels
+			venueBusyness.Level = CrowdLevels[int(level.Float64+0.5)-1]
+		}
+		if wait.Valid {
+			minutes := int(wait.Float64 + 0.5)
+			venueBusyness.WaitMinutes = &minutes
+		}
+		busyness[venueID] = venueBusyness
+	}
+	return busyness, rows.Err()
+}
+
+// DeleteStaleWaitReports ages out reports older than the retention,
+// returning how many were removed
+func DeleteStaleWaitReports(ctx context.Context) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_wait_reports WHERE reported_at < CURRENT_TIMESTAMP - INTERVAL '"+waitReportRetention+"'")
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
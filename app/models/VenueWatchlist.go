@@ -0,0 +1,156 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// MaxWatchlistCompetitors caps the competitors a venue can watch. Hidden
+// venues don't count until they are active again.
+const MaxWatchlistCompetitors = 5
+
+// ErrWatchlistFull is returned when the venue already watches
+// MaxWatchlistCompetitors competitors
+var ErrWatchlistFull = errors.New("watchlist is full")
+
+// WatchlistCompetitor is a competitor venue an owner follows
+type WatchlistCompetitor struct {
+	VenueID       int64     `json:"venueId"`
+	Name          string    `json:"name"`
+	Slug          string    `json:"slug"`
+	CategoryID    int64     `json:"categoryId,omitempty"`
+	CityID        int64     `json:"cityId,omitempty"`
+	AverageRating float64   `json:"averageRating"`
+	TotalReviews  int       `json:"totalReviews"`
+	AddedAt       time.Time `json:"addedAt"`
+}
+
+// AddWatchlistCompetitor adds the competitor to the venue's watchlist.
+// Adding a watched competitor again changes nothing. sql.ErrNoRows when the
+// competitor is not an active venue, ErrWatchlistFull when there is no room.
+func AddWatchlistCompetitor(ctx context.Context, venueID, competitorID int64) (*WatchlistCompetitor, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serializes the additions to the venue's watchlist so the cap holds
+	_, err = tx.ExecContext(ctx, "SELECT id FROM venues WHERE id = $1 FOR UPDATE", venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	var watched bool
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(BOOL_OR(w.competitor_venue_id = $2), false), COUNT(*) FILTER (WHERE v.is_active)
+		FROM venue_watchlist w
+		INNER JOIN venues v ON v.id = w.competitor_venue_id
+		WHERE w.venue_id = $1`,
+		venueID, competitorID).Scan(&watched, &count)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	if !watched {
+		if count >= MaxWatchlistCompetitors {
+			return nil, ErrWatchlistFull
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO venue_watchlist (venue_id, competitor_venue_id)
+			SELECT $1, id FROM venues WHERE id = $2 AND is_active = true`,
+			venueID, competitorID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		if added, _ := result.RowsAffected(); added == 0 {
+			return nil, sql.ErrNoRows
+		}
+	}
+
+	competitor, err := scanWatchlistCompetitor(tx.QueryRowContext(ctx,
+		watchlistCompetitorsQuery+" AND w.competitor_venue_id = $2", venueID, competitorID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return competitor, nil
+}
+
+// RemoveWatchlistCompetitor removes the competitor from the venue's
+// watchlist, returning sql.ErrNoRows when it wasn't on it
+func RemoveWatchlistCompetitor(ctx context.Context, venueID, competitorID int64) error {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM venue_watchlist WHERE venue_id = $1 AND competitor_venue_id = $2",
+		venueID, competitorID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return err
+	} else if removed == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetWatchlist returns the active competitors the venue watches, in the
+// order they were added
+func GetWatchlist(ctx context.Context, venueID int64) ([]WatchlistCompetitor, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		watchlistCompetitorsQuery+" ORDER BY w.created_at, w.competitor_venue_id", venueID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	competitors := []WatchlistCompetitor{}
+	for rows.Next() {
+		competitor, err := scanWatchlistCompetitor(rows)
+		if err != nil {
+			return nil, err
+		}
+		competitors = append(competitors, *competitor)
+	}
+	return competitors, rows.Err()
+}
+
+const watchlistCompetitorsQuery = `
+	SELECT v.id, v.name, v.slug, COALESCE(v.category_id, 0), COALESCE(v.city_id, 0),
+		   COALESCE(v.average_rating, 0), COALESCE(v.total_reviews, 0), w.created_at
+	FROM venue_watchlist w
+	INNER JOIN venues v ON v.id = w.competitor_venue_id
+	WHERE w.venue_id = $1 AND v.is_active = true`
+
+type watchlistCompetitorScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWatchlistCompetitor(row watchlistCompetitorScanner) (*WatchlistCompetitor, error) {
+	var competitor WatchlistCompetitor
+	err := row.Scan(&competitor.VenueID, &competitor.Name, &competitor.Slug, &competitor.CategoryID,
+		&competitor.CityID, &competitor.AverageRating, &competitor.TotalReviews, &competitor.AddedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	return &competitor, nil
+}
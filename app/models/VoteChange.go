@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// VoteChange is a change of a campaign vote from one venue to another. The
+// vote itself always holds the latest venue, so tallies only count that.
+type VoteChange struct {
+	ID              int64     `json:"id"`
+	VoteID          int64     `json:"voteId"`
+	PreviousVenueID int64     `json:"previousVenueId"`
+	VenueID         int64     `json:"venueId"`
+	ChangedAt       time.Time `json:"changedAt"`
+}
+
+func (c *VoteChange) TableName() string {
+	return "vote_changes"
+}
+
+// GetUserVote retrieves the vote the user cast in the campaign,
+// sql.ErrNoRows when the user has no such vote
+func (v *CampaignVote) GetUserVote(ctx context.Context) error {
+	var categoryID sql.NullInt64
+	var reason, reasonStatus sql.NullString
+	var confidenceScore sql.NullFloat64
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT campaign_category_id, venue_id, reason, reason_status, COALESCE(is_flagged, false),
+			   confidence_score, vote_count, credits_spent, created_at
+		FROM campaign_votes
+		WHERE id = $1 AND campaign_id = $2 AND user_id = $3`,
+		v.ID, v.CampaignID, v.UserID,
+	).Scan(&categoryID, &v.VenueID, &reason, &reasonStatus, &v.IsFlagged,
+		&confidenceScore, &v.Votes, &v.CreditsSpent, &v.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	if categoryID.Valid {
+		v.CategoryID = &categoryID.Int64
+	}
+	v.Reason, v.ReasonStatus = reason.String, reasonStatus.String
+	if confidenceScore.Valid {
+		v.ConfidenceScore = &confidenceScore.Float64
+	}
+	return nil
+}
+
+// ChangeVenue moves the vote to the venue with the vote's reason, which
+// replaces the one given for the previous venue, and records the change.
+// The vote keeps its cast time, votes and credits. ErrCampaignVoteExists is
+// returned when the user already voted for the venue in the category.
+func (v *CampaignVote) ChangeVenue(ctx context.Context, venueID int64) (*VoteChange, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var reason, reasonStatus sql.NullString
+	if v.Reason != "" {
+		if v.ReasonStatus == "" {
+			v.ReasonStatus = ReasonApproved
+		}
+		reason = sql.NullString{String: v.Reason, Valid: true}
+		reasonStatus = sql.NullString{String: v.ReasonStatus, Valid: true}
+	}
+
+	change := &VoteChange{VoteID: v.ID, VenueID: venueID}
+	err = tx.QueryRowContext(ctx, `
+		WITH previous AS (
+			SELECT venue_id FROM campaign_votes WHERE id = $1 FOR UPDATE
+		)
+		UPDATE campaign_votes cv
+		SET venue_id = $2, reason = $3, reason_status = $4, is_flagged = $5,
+			flagged_at = CASE WHEN $5 THEN CURRENT_TIMESTAMP END, is_highlight = false,
+			moderated_by = NULL, moderated_at = NULL
+		FROM previous
+		WHERE cv.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM campaign_votes other
+			WHERE other.campaign_id = cv.campaign_id AND other.user_id = cv.user_id
+			  AND other.campaign_category_id IS NOT DISTINCT FROM cv.campaign_category_id
+			  AND other.venue_id = $2
+		)
+		RETURNING previous.venue_id`,
+		v.ID, venueID, reason, reasonStatus, v.IsFlagged,
+	).Scan(&change.PreviousVenueID)
+	if err == sql.ErrNoRows {
+		return nil, ErrCampaignVoteExists
+	}
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO vote_changes (vote_id, campaign_id, user_id, previous_venue_id, venue_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, changed_at`,
+		v.ID, v.CampaignID, v.UserID, change.PreviousVenueID, venueID,
+	).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	v.VenueID = venueID
+	return change, nil
+}
+
+// GetVoteChanges returns the changes of a vote, oldest first
+func GetVoteChanges(ctx context.Context, voteID int64) ([]VoteChange, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, vote_id, previous_venue_id, venue_id, changed_at
+		FROM vote_changes
+		WHERE vote_id = $1
+		ORDER BY changed_at, id`,
+		voteID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]VoteChange, 0)
+	for rows.Next() {
+		var change VoteChange
+		if err := rows.Scan(&change.ID, &change.VoteID, &change.PreviousVenueID, &change.VenueID, &change.ChangedAt); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -29,9 +30,10 @@ type UserVotes struct {
 // WinnersVotesCountsMap map[ContestantId]VotesCount
 var WinnersVotesCountsMap = make(map[int64]int64)
 
-func init() {
+// LoadWinnersVotesCounts fills WinnersVotesCountsMap from the database
+func LoadWinnersVotesCounts() {
 	currentTime := time.Now().UTC()
-	rows, err := databases.PostgresDB.Query("select COUNT(user_voting.id),voting.id from user_voting inner join voting on voting.winner_id = user_voting.vote_id where ended_at > $1 group by voting.id", currentTime)
+	rows, err := databases.PostgresDB.QueryContext(context.Background(), "select COUNT(user_voting.id),voting.id from user_voting inner join voting on voting.winner_id = user_voting.vote_id where ended_at > $1 group by voting.id", currentTime)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			sentry.CaptureException(err)
@@ -52,10 +54,10 @@ func init() {
 func (v *Voting) TableName() string {
 	return "voting"
 }
-func (v *Voting) IsValid() bool {
+func (v *Voting) IsValid(ctx context.Context) bool {
 	currentTime := time.Now().UTC()
 	var total int64
-	err := databases.PostgresDB.QueryRow("SELECT COUNT(id) FROM voting WHERE id = $1 AND ended_at > $2 AND $3 > started_at", v.Id, currentTime, currentTime).Scan(&total)
+	err := databases.PostgresDB.QueryRowContext(ctx, "SELECT COUNT(id) FROM voting WHERE id = $1 AND ended_at > $2 AND $3 > started_at", v.Id, currentTime, currentTime).Scan(&total)
 	if err != nil || total == 0 {
 		if err != nil && err != sql.ErrNoRows {
 			sentry.CaptureException(err)
@@ -65,9 +67,9 @@ func (v *Voting) IsValid() bool {
 	return true
 }
 
-func (v *Voting) GetLastWinner() *UserVotes {
+func (v *Voting) GetLastWinner(ctx context.Context) *UserVotes {
 	var userVote UserVotes
-	row := databases.PostgresDB.QueryRow("SELECT participants.id,voting.name,voting_votes_cache.votes FROM voting INNER JOIN participants ON voting.winner_id = participants.id inner join voting_votes_cache on voting_votes_cache.participant_id = participants.id  WHERE voting.winner_id is not null ORDER BY ended_at desc LIMIT 1")
+	row := databases.PostgresDB.QueryRowContext(ctx, "SELECT participants.id,voting.name,voting_votes_cache.votes FROM voting INNER JOIN participants ON voting.winner_id = participants.id inner join voting_votes_cache on voting_votes_cache.participant_id = participants.id  WHERE voting.winner_id is not null ORDER BY ended_at desc LIMIT 1")
 	if row.Err() != nil {
 		if row.Err() != sql.ErrNoRows {
 			sentry.CaptureException(row.Err())
@@ -87,10 +89,10 @@ func (v *Voting) GetLastWinner() *UserVotes {
 	return &userVote
 }
 
-func (v *Voting) GetLast() error {
+func (v *Voting) GetLast(ctx context.Context) error {
 	query := fmt.Sprintf("SELECT id,name,description,winner_id,started_at,ended_at FROM %s WHERE started_at < $1 order by ended_at desc LIMIT 1", v.TableName())
 	currentTime := time.Now().UTC()
-	row := databases.PostgresDB.QueryRow(query, currentTime)
+	row := databases.PostgresDB.QueryRowContext(ctx, query, currentTime)
 	if row.Err() != nil {
 		if row.Err() != sql.ErrNoRows {
 			sentry.CaptureException(row.Err())
@@ -112,8 +114,26 @@ func (v *Voting) GetLast() error {
 	return nil
 }
 
-func (v *Voting) GetUserVotesParticipants(SnappUserId int64) []UserVotes {
-	rows, err := databases.PostgresDB.Query("SELECT user_voting.vote_id, voting_votes_cache.votes,voting.name,voting_votes_cache.is_winner FROM user_voting INNER JOIN voting_votes_cache ON user_voting.vote_id = voting_votes_cache.participant_id inner join voting on voting.id = user_voting.voting_id WHERE user_voting.owner_id = $1 WHERE voting_votes_cache.voting_id = user_voting.voting_id", SnappUserId)
+// Get loads the voting by ID, sql.ErrNoRows when it doesn't exist
+func (v *Voting) Get(ctx context.Context) error {
+	query := fmt.Sprintf("SELECT id,name,description,winner_id,started_at,ended_at FROM %s WHERE id = $1", v.TableName())
+	var winnerId sql.NullInt64
+	err := databases.PostgresDB.QueryRowContext(ctx, query, v.Id).
+		Scan(&v.Id, &v.Name, &v.Description, &winnerId, &v.StartedAt, &v.EndedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+	if winnerId.Valid {
+		v.WinnerId = winnerId.Int64
+	}
+	return nil
+}
+
+func (v *Voting) GetUserVotesParticipants(ctx context.Context, SnappUserId int64) []UserVotes {
+	rows, err := databases.PostgresDB.QueryContext(ctx, "SELECT user_voting.vote_id, voting_votes_cache.votes,voting.name,voting_votes_cache.is_winner FROM user_voting INNER JOIN voting_votes_cache ON user_voting.vote_id = voting_votes_cache.participant_id inner join voting on voting.id = user_voting.voting_id WHERE user_voting.owner_id = $1 WHERE voting_votes_cache.voting_id = user_voting.voting_id", SnappUserId)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			sentry.CaptureException(err)
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// VotingCampaign is a venue voting campaign, e.g. "Best Restaurant 2024"
+type VotingCampaign struct {
+	ID           int64  `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description,omitempty"`
+	CampaignType string `json:"campaignType,omitempty"` // One of CampaignTypes
+
+	// Geographic Scope
+	CityID     *int64 `json:"cityId,omitempty"`
+	CategoryID *int64 `json:"categoryId,omitempty"`
+
+	// Campaign Duration
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+
+	// Voting Rules
+	MaxVotesPerUser         int  `json:"maxVotesPerUser"`
+	AllowMultipleCategories bool `json:"allowMultipleCategories"`
+	RequireReview           bool `json:"requireReview"`
+
+	// VotingMode is standard or quadratic. Quadratic campaigns give every
+	// user CreditBudget credits to spend across venues.
+	VotingMode   string `json:"votingMode"`
+	CreditBudget int    `json:"creditBudget,omitempty"`
+
+	// Status
+	IsActive   bool `json:"isActive"`
+	IsFeatured bool `json:"isFeatured"`
+
+	// HideResultsUntilEnd embargoes the standings while voting runs, only
+	// administrators see them before EndDate
+	HideResultsUntilEnd bool `json:"hideResultsUntilEnd"`
+
+	// RequireOTP holds votes until the voter confirms them with a code sent
+	// to their verified email
+	RequireOTP bool `json:"requireOtp"`
+
+	// AllowKioskSessions lets kiosks holding a credential issued by an
+	// administrator open anonymous voting sessions
+	AllowKioskSessions bool `json:"allowKioskSessions"`
+
+	// VoteChangeMinutes is how long after casting a vote voters may change
+	// it, 0 when votes are final. Without it votes can be changed while the
+	// campaign runs.
+	VoteChangeMinutes *int `json:"voteChangeMinutes,omitempty"`
+
+	// Results
+	WinnerVenueID      *int64     `json:"winnerVenueId,omitempty"`
+	TotalVotes         int        `json:"totalVotes"`
+	ResultsFinalizedAt *time.Time `json:"resultsFinalizedAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (c *VotingCampaign) TableName() string {
+	return "voting_campaigns"
+}
+
+// votingCampaignColumns are the columns scanned by VotingCampaign.scan
+const votingCampaignColumns = `
+	c.id, c.title, c.description, c.campaign_type, c.city_id, c.category_id,
+	c.start_date, c.end_date, c.max_votes_per_user, c.allow_multiple_categories,
+	c.require_review, c.voting_mode, c.credit_budget, c.is_active, c.is_featured,
+	c.winner_venue_id, c.total_votes, c.results_finalized_at, c.hide_results_until_end,
+	c.require_otp, c.allow_kiosk_sessions, c.vote_change_minutes, c.created_at, c.updated_at`
+
+// GetByID retrieves a campaign by ID
+func (c *VotingCampaign) GetByID(ctx context.Context) error {
+	query := `SELECT ` + votingCampaignColumns + ` FROM voting_campaigns c
+		WHERE c.id = $1 AND ($2::bigint IS NULL OR c.tenant_id = $2)`
+	return c.scan(databases.PostgresDB.QueryRowContext(ctx, query, c.ID, TenantFilter(ctx)))
+}
+
+// GetCampaignsByIDs returns the active campaigns among the IDs, in no
+// particular order
+func GetCampaignsByIDs(ctx context.Context, ids []int64) ([]VotingCampaign, error) {
+	campaigns := make([]VotingCampaign, 0, len(ids))
+	if len(ids) == 0 {
+		return campaigns, nil
+	}
+
+	query := `SELECT ` + votingCampaignColumns + ` FROM voting_campaigns c
+		WHERE c.id = ANY($1) AND c.is_active = true AND ($2::bigint IS NULL OR c.tenant_id = $2)`
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, pq.Array(ids), TenantFilter(ctx))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaign VotingCampaign
+		if err := campaign.scan(rows); err != nil {
+			continue
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+// ClaimStartedCampaigns marks the running campaigns whose opening wasn't
+// announced yet as announced and returns them, each once
+func ClaimStartedCampaigns(ctx context.Context, now time.Time) ([]VotingCampaign, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		UPDATE voting_campaigns c SET start_notified_at = $1
+		WHERE c.is_active = true AND c.start_date <= $1 AND c.end_date > $1
+		  AND c.start_notified_at IS NULL
+		RETURNING `+votingCampaignColumns, now)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := make([]VotingCampaign, 0)
+	for rows.Next() {
+		var campaign VotingCampaign
+		if err := campaign.scan(rows); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+type votingCampaignScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scan reads the votingCampaignColumns of a row, followed by any extra
+// columns of the query
+func (c *VotingCampaign) scan(row votingCampaignScanner, extra ...interface{}) error {
+	var description, campaignType sql.NullString
+	var cityID, categoryID, winnerVenueID sql.NullInt64
+	var maxVotesPerUser, creditBudget, voteChangeMinutes sql.NullInt64
+	var resultsFinalizedAt sql.NullTime
+
+	dest := []interface{}{
+		&c.ID, &c.Title, &description, &campaignType, &cityID, &categoryID,
+		&c.StartDate, &c.EndDate, &maxVotesPerUser, &c.AllowMultipleCategories,
+		&c.RequireReview, &c.VotingMode, &creditBudget, &c.IsActive, &c.IsFeatured,
+		&winnerVenueID, &c.TotalVotes, &resultsFinalizedAt, &c.HideResultsUntilEnd,
+		&c.RequireOTP, &c.AllowKioskSessions, &voteChangeMinutes, &c.CreatedAt, &c.UpdatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return err
+	}
+
+	c.Description = description.String
+	c.CampaignType = campaignType.String
+	c.MaxVotesPerUser = 1
+	if maxVotesPerUser.Valid {
+		c.MaxVotesPerUser = int(maxVotesPerUser.Int64)
+	}
+	if c.IsQuadratic() {
+		c.CreditBudget = DefaultCreditBudget
+		if creditBudget.Valid {
+			c.CreditBudget = int(creditBudget.Int64)
+		}
+	}
+	if cityID.Valid {
+		c.CityID = &cityID.Int64
+	}
+	if categoryID.Valid {
+		c.CategoryID = &categoryID.Int64
+	}
+	if winnerVenueID.Valid {
+		c.WinnerVenueID = &winnerVenueID.Int64
+	}
+	if resultsFinalizedAt.Valid {
+		c.ResultsFinalizedAt = &resultsFinalizedAt.Time
+	}
+	if voteChangeMinutes.Valid {
+		minutes := int(voteChangeMinutes.Int64)
+		c.VoteChangeMinutes = &minutes
+	}
+
+	return nil
+}
+
+// IsOpen reports whether the campaign currently accepts votes
+func (c *VotingCampaign) IsOpen(now time.Time) bool {
+	return c.IsActive && !now.Before(c.StartDate) && now.Before(c.EndDate)
+}
+
+// ResultsHidden reports whether the standings are embargoed at the time,
+// until the campaign ends
+func (c *VotingCampaign) ResultsHidden(now time.Time) bool {
+	return c.HideResultsUntilEnd && now.Before(c.EndDate)
+}
+
+// VotesChangeable reports whether the campaign lets voters change their
+// votes at all. Votes of campaigns requiring one-time codes are final, a
+// change would skip the code confirming the vote.
+func (c *VotingCampaign) VotesChangeable() bool {
+	if c.RequireOTP {
+		return false
+	}
+	return c.VoteChangeMinutes == nil || *c.VoteChangeMinutes > 0
+}
+
+// VoteChangeDeadline returns until when a vote cast at the time can be
+// changed: the end of the campaign, or of the change window when it ends
+// first
+func (c *VotingCampaign) VoteChangeDeadline(castAt time.Time) time.Time {
+	if c.VoteChangeMinutes != nil {
+		windowEnd := castAt.Add(time.Duration(*c.VoteChangeMinutes) * time.Minute)
+		if windowEnd.Before(c.EndDate) {
+			return windowEnd
+		}
+	}
+	return c.EndDate
+}
+
+// CanChangeVote reports whether a vote cast at the time can still be changed
+func (c *VotingCampaign) CanChangeVote(castAt, now time.Time) bool {
+	return c.IsOpen(now) && c.VotesChangeable() && now.Before(c.VoteChangeDeadline(castAt))
+}
+
+// IsQuadratic reports whether votes are paid for with credits
+func (c *VotingCampaign) IsQuadratic() bool {
+	return c.VotingMode == VotingModeQuadratic
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// VotingSession lets an anonymous voter at an event kiosk vote in one
+// campaign until it expires. Sessions are opened by a kiosk holding a
+// credential for the campaign, only the hash of the token is stored.
+type VotingSession struct {
+	ID         int64     `json:"id"`
+	CampaignID int64     `json:"campaignId"`
+	TokenHash  string    `json:"-"`
+	KioskID    int64     `json:"kioskId"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (s *VotingSession) TableName() string {
+	return "voting_sessions"
+}
+
+// Create stores the session
+func (s *VotingSession) Create(ctx context.Context) error {
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		INSERT INTO voting_sessions (campaign_id, token_hash, kiosk_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		s.CampaignID, s.TokenHash, s.KioskID, s.ExpiresAt,
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetVotingSession returns the unexpired session of the token hash,
+// sql.ErrNoRows when there is none
+func GetVotingSession(ctx context.Context, tokenHash string, now time.Time) (*VotingSession, error) {
+	session := &VotingSession{TokenHash: tokenHash}
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT s.id, s.campaign_id, s.kiosk_id, s.expires_at, s.created_at
+		FROM voting_sessions s
+		INNER JOIN kiosk_devices k ON k.id = s.kiosk_id
+		WHERE s.token_hash = $1 AND s.expires_at > $2 AND k.revoked_at IS NULL`,
+		tokenHash, now,
+	).Scan(&session.ID, &session.CampaignID, &session.KioskID, &session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			sentry.CaptureException(err)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// CountKioskVotingSessions returns how many sessions the kiosk opened
+// within the window. Times use the database clock.
+func CountKioskVotingSessions(ctx context.Context, kioskID int64, window time.Duration) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM voting_sessions
+		WHERE kiosk_id = $1 AND created_at >= LOCALTIMESTAMP - make_interval(secs => $2)`,
+		kioskID, window.Seconds(),
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// CountSessionCampaignVotes returns how many votes the session cast, in the
+// category when one is given
+func CountSessionCampaignVotes(ctx context.Context, sessionID int64, categoryID *int64) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM campaign_votes
+		WHERE voting_session_id = $1 AND ($2::bigint IS NULL OR campaign_category_id = $2)`,
+		sessionID, categoryID,
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// GetSessionVotedCategoryIDs returns the campaign categories the session
+// voted in
+func GetSessionVotedCategoryIDs(ctx context.Context, sessionID int64) ([]int64, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT DISTINCT campaign_category_id FROM campaign_votes
+		WHERE voting_session_id = $1 AND campaign_category_id IS NOT NULL`,
+		sessionID,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categoryIDs := make([]int64, 0)
+	for rows.Next() {
+		var categoryID int64
+		if err := rows.Scan(&categoryID); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		categoryIDs = append(categoryIDs, categoryID)
+	}
+	return categoryIDs, nil
+}
+
+// CountKioskCampaignVotes returns how many votes the sessions of the kiosk
+// cast in the campaign within the window. Times use the database clock.
+func CountKioskCampaignVotes(ctx context.Context, campaignID, kioskID int64, window time.Duration) (int, error) {
+	var count int
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM campaign_votes cv
+		INNER JOIN voting_sessions s ON s.id = cv.voting_session_id
+		WHERE cv.campaign_id = $1 AND s.kiosk_id = $2
+		  AND cv.created_at >= LOCALTIMESTAMP - make_interval(secs => $3)`,
+		campaignID, kioskID, window.Seconds(),
+	).Scan(&count)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return count, err
+}
+
+// DeleteExpiredVotingSessions removes the sessions that expired before the
+// given time. Their votes stay, no longer tied to a session.
+func DeleteExpiredVotingSessions(ctx context.Context, before time.Time) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx,
+		"DELETE FROM voting_sessions WHERE expires_at < $1", before)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
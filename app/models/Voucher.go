@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"github.com/getsentry/sentry-go"
 	databases "voting-app/app"
 )
@@ -14,8 +15,8 @@ type Voucher struct {
 	IsNew       bool   `json:"isNew"`
 }
 
-func (v Voucher) GetUserVouchers() []Voucher {
-	query, err := databases.PostgresDB.Query("SELECT name,description,icon,is_new FROM vouchers WHERE owner_id = $1", v.OwnerId)
+func (v Voucher) GetUserVouchers(ctx context.Context) []Voucher {
+	query, err := databases.PostgresDB.QueryContext(ctx, "SELECT name,description,icon,is_new FROM vouchers WHERE owner_id = $1", v.OwnerId)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil
@@ -0,0 +1,364 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Webhook event types
+const (
+	WebhookEventReviewCreated   = "review.created"
+	WebhookEventCampaignResults = "campaign.results_finalized"
+	WebhookEventTest            = "webhook.test"
+)
+
+// WebhookEventTypes are the events partners can subscribe to
+var WebhookEventTypes = []string{WebhookEventReviewCreated, WebhookEventCampaignResults}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription is a partner endpoint notified of events. Subscriptions
+// with a venue only receive the events of that venue.
+type WebhookSubscription struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"userId"`
+	VenueID    *int64     `json:"venueId,omitempty"`
+	TargetURL  string     `json:"targetUrl"`
+	EventTypes []string   `json:"eventTypes"`
+	Secret     string     `json:"secret,omitempty"` // Only returned on creation
+	IsActive   bool       `json:"isActive"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// WebhookDelivery is the delivery log of one event to one subscription
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscriptionId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, delivered, failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+func (w *WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+const webhookSubscriptionColumns = `
+	id, user_id, venue_id, target_url, event_types, is_active, disabled_at, created_at`
+
+// Create stores the subscription with a newly generated signing secret
+func (w *WebhookSubscription) Create(ctx context.Context) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	w.Secret = "whsec_" + hex.EncodeToString(secret)
+	w.IsActive = true
+
+	query := `
+		INSERT INTO webhook_subscriptions (user_id, venue_id, target_url, event_types, secret)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, w.UserID, w.VenueID, w.TargetURL, pq.Array(w.EventTypes), w.Secret,
+	).Scan(&w.ID, &w.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetByID retrieves a subscription without its secret
+func (w *WebhookSubscription) GetByID(ctx context.Context) error {
+	query := `SELECT` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	err := scanWebhookSubscription(databases.PostgresDB.QueryRowContext(ctx, query, w.ID), w)
+	if err != nil && err != sql.ErrNoRows {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// Disable stops deliveries to the subscription. Pending deliveries are
+// marked as failed.
+func (w *WebhookSubscription) Disable(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET is_active = false, disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+		RETURNING disabled_at`, w.ID).Scan(&w.DisabledAt)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'failed', next_attempt_at = NULL, last_error = 'subscription disabled'
+		WHERE subscription_id = $1 AND status = 'pending'`, w.ID)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	w.IsActive = false
+	return nil
+}
+
+// GetSecret loads the signing secret of the subscription
+func (w *WebhookSubscription) GetSecret(ctx context.Context) (string, error) {
+	var secret string
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		"SELECT secret FROM webhook_subscriptions WHERE id = $1", w.ID,
+	).Scan(&secret)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return secret, err
+}
+
+// GetWebhookSubscriptions lists the subscriptions of a venue, or the ones
+// without a venue when venueID is nil
+func GetWebhookSubscriptions(ctx context.Context, venueID *int64) ([]WebhookSubscription, error) {
+	query := `SELECT` + webhookSubscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE venue_id IS NULL
+		ORDER BY created_at DESC`
+	args := []interface{}{}
+	if venueID != nil {
+		query = `SELECT` + webhookSubscriptionColumns + `
+			FROM webhook_subscriptions
+			WHERE venue_id = $1
+			ORDER BY created_at DESC`
+		args = append(args, *venueID)
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		var subscription WebhookSubscription
+		if err := scanWebhookSubscription(rows, &subscription); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// CreateWebhookDeliveries queues the event for every active subscription to
+// it. Subscriptions with a venue only get events of that venue.
+func CreateWebhookDeliveries(ctx context.Context, eventType string, venueID *int64, payload []byte) (int64, error) {
+	result, err := databases.PostgresDB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+		SELECT id, $1::varchar, $3::jsonb
+		FROM webhook_subscriptions
+		WHERE is_active = true AND $1 = ANY(event_types)
+		  AND (venue_id IS NULL OR venue_id = $2)`,
+		eventType, venueID, payload,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Create stores a delivery to a single subscription. It is not picked up by
+// the delivery job, the caller attempts it directly.
+func (d *WebhookDelivery) Create(ctx context.Context) error {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload, next_attempt_at)
+		VALUES ($1, $2, $3, NULL)
+		RETURNING id, status, next_attempt_at, created_at`
+
+	err := databases.PostgresDB.QueryRowContext(ctx,
+		query, d.SubscriptionID, d.EventType, []byte(d.Payload),
+	).Scan(&d.ID, &d.Status, &d.NextAttemptAt, &d.CreatedAt)
+
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries that are
+// due and pushes their next attempt back by lease, so concurrent workers do
+// not send the same delivery twice. Times use the database clock.
+func ClaimDueWebhookDeliveries(ctx context.Context, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = LOCALTIMESTAMP + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= LOCALTIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, subscription_id, event_type, payload, status, attempts, created_at`,
+		lease.Seconds(), limit,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID, &delivery.SubscriptionID, &delivery.EventType, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &delivery.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt logs the outcome of a delivery attempt. A failed attempt is
+// retried after retryIn, a zero retryIn marks the delivery as failed.
+func (d *WebhookDelivery) RecordAttempt(ctx context.Context, statusCode *int, attemptErr string, succeeded bool, retryIn time.Duration) error {
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	d.LastError = attemptErr
+
+	switch {
+	case succeeded:
+		d.Status = WebhookDeliveryDelivered
+	case retryIn > 0:
+		d.Status = WebhookDeliveryPending
+	default:
+		d.Status = WebhookDeliveryFailed
+	}
+
+	err := databases.PostgresDB.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = NULLIF($5, ''),
+			next_attempt_at = CASE WHEN $2 = 'pending' THEN LOCALTIMESTAMP + make_interval(secs => $6) END,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN LOCALTIMESTAMP END
+		WHERE id = $1
+		RETURNING next_attempt_at, delivered_at`,
+		d.ID, d.Status, d.Attempts, d.LastStatusCode, d.LastError, retryIn.Seconds(),
+	).Scan(&d.NextAttemptAt, &d.DeliveredAt)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+	return err
+}
+
+// GetWebhookDeliveries returns the most recent delivery logs of a subscription
+func GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT id, subscription_id, event_type, payload, status, attempts,
+			   next_attempt_at, last_status_code, COALESCE(last_error, ''), delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var nextAttemptAt, deliveredAt sql.NullTime
+		var statusCode sql.NullInt64
+		err := rows.Scan(
+			&delivery.ID, &delivery.SubscriptionID, &delivery.EventType, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &nextAttemptAt, &statusCode,
+			&delivery.LastError, &deliveredAt, &delivery.CreatedAt,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if nextAttemptAt.Valid {
+			delivery.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			delivery.LastStatusCode = &code
+		}
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhookSubscription(row interface{ Scan(...interface{}) error }, w *WebhookSubscription) error {
+	var venueID sql.NullInt64
+	var disabledAt sql.NullTime
+
+	err := row.Scan(
+		&w.ID, &w.UserID, &venueID, &w.TargetURL, pq.Array(&w.EventTypes),
+		&w.IsActive, &disabledAt, &w.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if venueID.Valid {
+		w.VenueID = &venueID.Int64
+	}
+	if disabledAt.Valid {
+		w.DisabledAt = &disabledAt.Time
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package serializers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// VenueAnalyticsQuery holds the query parameters of the venue analytics
+type VenueAnalyticsQuery struct {
+	TimeRange string `form:"time_range,default=week" binding:"oneof=today yesterday week month quarter year"`
+}
+
+// WatchlistComparisonQuery holds the query parameters of the watchlist
+// comparison
+type WatchlistComparisonQuery struct {
+	Weeks int `form:"weeks,default=8" binding:"min=1,max=26"`
+}
+
+// MetricAlertsQuery holds the query parameters of the metric alerts
+type MetricAlertsQuery struct {
+	Days   int    `form:"days,default=30" binding:"min=1,max=365"`
+	Metric string `form:"metric" binding:"omitempty,oneof=reviews votes signups"`
+}
+
+// MetricAlertsResponse for the metric alerts API
+type MetricAlertsResponse struct {
+	Alerts []models.MetricAlert `json:"alerts"`
+}
+
+const (
+	// MaxClientEventsPerBatch is how many events a batch may carry, client
+	// and venue events alike
+	MaxClientEventsPerBatch = 100
+	// maxClientEventAge is how old an event may be when it is received
+	maxClientEventAge = 24 * time.Hour
+	// maxClientClockSkew is how far in the future an event may be, to allow
+	// for client clocks running ahead
+	maxClientClockSkew = 5 * time.Minute
+)
+
+// ClientEventRequest is an event in a batch of client events
+type ClientEventRequest struct {
+	Type       string    `json:"type" binding:"required"`
+	Path       string    `json:"path,omitempty"`
+	OccurredAt time.Time `json:"occurredAt" binding:"required"`
+}
+
+// ClientEventsRequest for reporting a batch of client events. The client ID
+// identifies the app install or browser across sessions.
+type ClientEventsRequest struct {
+	ClientID string               `json:"clientId" binding:"required"`
+	Events   []ClientEventRequest `json:"events" binding:"required"`
+}
+
+// Validate validates the batch. Events are checked one by one in ToEvents.
+func (r *ClientEventsRequest) Validate() (Base, bool) {
+	r.ClientID = strings.TrimSpace(r.ClientID)
+	if r.ClientID == "" || len(r.ClientID) > 64 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Client ID must be 1-64 characters",
+		}, false
+	}
+
+	if len(r.Events) == 0 || len(r.Events) > MaxClientEventsPerBatch {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("A batch must have 1-%d events", MaxClientEventsPerBatch),
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToEvents returns the events to store, leaving out events of unknown types,
+// with overlong paths or occurring outside the accepted time window
+func (r *ClientEventsRequest) ToEvents(now time.Time) []models.ClientEvent {
+	events := []models.ClientEvent{}
+	for _, event := range r.Events {
+		if !isClientEventType(event.Type) || len(event.Path) > 255 {
+			continue
+		}
+		if event.OccurredAt.Before(now.Add(-maxClientEventAge)) || event.OccurredAt.After(now.Add(maxClientClockSkew)) {
+			continue
+		}
+		events = append(events, models.ClientEvent{
+			ClientID:   r.ClientID,
+			Type:       event.Type,
+			Path:       event.Path,
+			OccurredAt: event.OccurredAt,
+		})
+	}
+	return events
+}
+
+// isClientEventType reports whether the client event type is known
+func isClientEventType(eventType string) bool {
+	for _, known := range models.ClientEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientEventsResponse for the client events API
+type ClientEventsResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"` // Unknown types or outside the time window
+}
+
+// VenueEventRequest is a venue interaction in a batch. The ID is chosen by
+// the client, like a UUID, and stays the same when the event is resent.
+// Events of recommended venues carry the experiment and variant the
+// recommendations were served with.
+type VenueEventRequest struct {
+	ID         string    `json:"id" binding:"required"`
+	Type       string    `json:"type" binding:"required"` // venue_view, photo_view, phone_click, directions or share
+	VenueID    int64     `json:"venueId" binding:"required"`
+	OccurredAt time.Time `json:"occurredAt" binding:"required"`
+	Experiment string    `json:"experiment,omitempty"`
+	Variant    string    `json:"variant,omitempty"`
+}
+
+// VenueEventsRequest for reporting a batch of venue interactions
+type VenueEventsRequest struct {
+	ClientID string              `json:"clientId" binding:"required"`
+	Events   []VenueEventRequest `json:"events" binding:"required"`
+}
+
+// Validate validates the batch. Events are checked one by one in ToEvents.
+func (r *VenueEventsRequest) Validate() (Base, bool) {
+	r.ClientID = strings.TrimSpace(r.ClientID)
+	if r.ClientID == "" || len(r.ClientID) > 64 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Client ID must be 1-64 characters",
+		}, false
+	}
+
+	if len(r.Events) == 0 || len(r.Events) > MaxClientEventsPerBatch {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("A batch must have 1-%d events", MaxClientEventsPerBatch),
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToEvents returns the events to record, leaving out events of unknown
+// types, with invalid IDs or occurring outside the accepted time window.
+// Events tagged with unknown variants are recorded untagged.
+func (r *VenueEventsRequest) ToEvents(now time.Time) []models.VenueEvent {
+	events := []models.VenueEvent{}
+	for _, event := range r.Events {
+		event.ID = strings.TrimSpace(event.ID)
+		if event.ID == "" || len(event.ID) > 64 {
+			continue
+		}
+		if !models.IsVenueEventType(event.Type) || event.VenueID <= 0 {
+			continue
+		}
+		if event.OccurredAt.Before(now.Add(-maxClientEventAge)) || event.OccurredAt.After(now.Add(maxClientClockSkew)) {
+			continue
+		}
+		venueEvent := models.VenueEvent{
+			ClientID:   r.ClientID,
+			EventID:    event.ID,
+			VenueID:    event.VenueID,
+			Type:       event.Type,
+			OccurredAt: event.OccurredAt,
+		}
+		if services.IsExperimentVariant(event.Experiment, event.Variant) {
+			venueEvent.Experiment = event.Experiment
+			venueEvent.Variant = event.Variant
+		}
+		events = append(events, venueEvent)
+	}
+	return events
+}
+
+// VenueEventsResponse for the venue events API
+type VenueEventsResponse struct {
+	Accepted   int `json:"accepted"`
+	Duplicates int `json:"duplicates"` // Sent before or repeated in the batch, counted once
+	Rejected   int `json:"rejected"`   // Invalid, outside the time window or of unknown venues
+}
+
+// EngagementQuery holds the query parameters of the engagement metrics
+type EngagementQuery struct {
+	TimeRange string `form:"time_range,default=week" binding:"oneof=today yesterday week month quarter year"`
+}
@@ -0,0 +1,542 @@
+package serializers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// MaxVotesPerVenue caps the votes a quadratic campaign vote can cast
+const MaxVotesPerVenue = 100
+
+// SubmitCampaignVoteResponse for the campaign vote API
+type SubmitCampaignVoteResponse struct {
+	Vote           models.CampaignVote  `json:"vote"`
+	Receipt        services.VoteReceipt `json:"receipt"`
+	RemainingVotes int                  `json:"remainingVotes"`
+	// RemainingCredits is set for quadratic campaigns, RemainingVotes is
+	// then the most votes still affordable for a single venue
+	RemainingCredits *int `json:"remainingCredits,omitempty"`
+}
+
+// ChangeCampaignVoteRequest moves a campaign vote to another venue of its
+// category. The reason replaces the one given for the previous venue.
+type ChangeCampaignVoteRequest struct {
+	VenueID int64  `json:"venueId" binding:"required"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ChangeCampaignVoteResponse is the changed vote with its new receipt, the
+// receipt of the previous venue no longer verifies as recorded
+type ChangeCampaignVoteResponse struct {
+	Vote    models.CampaignVote  `json:"vote"`
+	Receipt services.VoteReceipt `json:"receipt"`
+	Changes []models.VoteChange  `json:"changes"` // Every change of the vote, oldest first
+}
+
+// VotePendingVerification is the status of votes held until confirmed with
+// a one-time code
+const VotePendingVerification = "pending_verification"
+
+// HeldCampaignVoteResponse tells the voter their vote waits for the code
+// emailed to them
+type HeldCampaignVoteResponse struct {
+	Status    string    `json:"status"`
+	SentTo    string    `json:"sentTo"` // The masked email
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// VerifyCampaignVoteRequest confirms a held vote with its one-time code
+type VerifyCampaignVoteRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// CampaignReceiptsResponse lists the user's vote receipts of a campaign
+type CampaignReceiptsResponse struct {
+	CampaignID int64                  `json:"campaignId"`
+	Receipts   []services.VoteReceipt `json:"receipts"`
+}
+
+// CampaignVoteHistoryResponse lists the user's votes in a campaign and what
+// they have left to spend
+type CampaignVoteHistoryResponse struct {
+	CampaignID      int64                 `json:"campaignId"`
+	Votes           []models.CampaignVote `json:"votes"`
+	VotesUsed       int                   `json:"votesUsed"`
+	MaxVotesPerUser int                   `json:"maxVotesPerUser"`
+	RemainingVotes  int                   `json:"remainingVotes"`
+	// RemainingCredits is set for quadratic campaigns, RemainingVotes is
+	// then the most votes still affordable for a single venue
+	RemainingCredits *int `json:"remainingCredits,omitempty"`
+	// CanChangeVotes is true while the campaign is open and lets votes be
+	// changed, at the latest until ChangeDeadline. Votes of campaigns with
+	// a change window say until when they can be changed.
+	CanChangeVotes bool      `json:"canChangeVotes"`
+	ChangeDeadline time.Time `json:"changeDeadline"`
+}
+
+// VotingSessionRequest for opening an anonymous kiosk voting session
+type VotingSessionRequest struct {
+	CampaignID int64  `json:"campaignId" binding:"required,min=1"`
+	KioskToken string `json:"kioskToken" binding:"required,max=128"` // Credential issued to the kiosk for the campaign
+}
+
+// KioskDeviceRequest for issuing a kiosk credential
+type KioskDeviceRequest struct {
+	Name string `json:"name" binding:"required,max=100"` // Like "Hall A entrance"
+}
+
+// KioskDevicesResponse lists the kiosks of a campaign
+type KioskDevicesResponse struct {
+	CampaignID int64                `json:"campaignId"`
+	Kiosks     []models.KioskDevice `json:"kiosks"`
+}
+
+// KioskDeviceResponse is an issued kiosk with its credential, only shown
+// once
+type KioskDeviceResponse struct {
+	Kiosk models.KioskDevice `json:"kiosk"`
+	Token string             `json:"token"` // Sent as kioskToken when opening voting sessions
+}
+
+// VotingSessionResponse for the voting session API
+type VotingSessionResponse struct {
+	Token    string               `json:"token"` // Sent in the X-Voting-Session header when voting
+	Session  models.VotingSession `json:"session"`
+	MaxVotes int                  `json:"maxVotes"`
+}
+
+// CampaignCategoryRequest for adding a category to a campaign
+type CampaignCategoryRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description,omitempty"`
+	VenueCategoryID *int64 `json:"venueCategoryId,omitempty"` // Restricts the eligible venues
+	SortOrder       int    `json:"sortOrder"`
+}
+
+// CampaignPromotionRequest schedules a campaign to be featured
+type CampaignPromotionRequest struct {
+	StartsAt    time.Time `json:"startsAt" binding:"required"`
+	EndsAt      time.Time `json:"endsAt" binding:"required"`
+	Priority    int       `json:"priority"`
+	BannerImage string    `json:"bannerImage,omitempty"` // Fills the legacy vote banner while running
+	BannerLink  string    `json:"bannerLink,omitempty"`
+}
+
+// CampaignPromotionsQuery holds the query parameters of the promotion
+// calendar, which covers the coming days
+type CampaignPromotionsQuery struct {
+	Days int `form:"days,default=30" binding:"min=1,max=366"`
+}
+
+// CampaignPromotionsResponse is the promotion calendar
+type CampaignPromotionsResponse struct {
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Promotions []models.CampaignPromotion `json:"promotions"`
+}
+
+// CampaignVoteReasonsQuery pages through the reasons given with a campaign's
+// votes, for moderation
+type CampaignVoteReasonsQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=100"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+// CampaignVoteReasonsResponse lists vote reasons for moderation
+type CampaignVoteReasonsResponse struct {
+	CampaignID int64                       `json:"campaignId"`
+	Reasons    []models.CampaignVoteReason `json:"reasons"`
+}
+
+// ModerateVoteReasonRequest approves or rejects a vote reason, quoting
+// approved ones as highlights of the campaign results when Highlight is set
+type ModerateVoteReasonRequest struct {
+	Status    string `json:"status" binding:"required,oneof=approved rejected"`
+	Highlight bool   `json:"highlight"`
+}
+
+// Validate validates the ModerateVoteReasonRequest
+func (r *ModerateVoteReasonRequest) Validate() (Base, bool) {
+	if r.Highlight && r.Status != models.ReasonApproved {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Only approved reasons can be highlights",
+		}, false
+	}
+	return Base{}, true
+}
+
+// ExcludeCampaignVoteRequest leaves a vote out of the campaign tallies
+type ExcludeCampaignVoteRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=fraud duplicate_voter automated"`
+}
+
+// AutoGenerateCampaignRequest proposes a "best of" campaign of a city and
+// venue category, nominating its top venues
+type AutoGenerateCampaignRequest struct {
+	CityID          int64  `json:"cityId" binding:"required,min=1"`
+	CategoryID      int64  `json:"categoryId" binding:"required,min=1"`
+	Quarter         string `json:"quarter,omitempty"`                           // Like "2026-Q3", defaults to the next quarter
+	Title           string `json:"title,omitempty" binding:"omitempty,max=255"` // Defaults to "Best Cafes in San Francisco Q3 2026"
+	Nominees        int    `json:"nominees,omitempty" binding:"omitempty,min=2,max=50"`
+	MaxVotesPerUser int    `json:"maxVotesPerUser,omitempty" binding:"omitempty,min=1,max=10"`
+	// HideResultsUntilEnd only shows the standings to administrators until
+	// the campaign ends
+	HideResultsUntilEnd bool `json:"hideResultsUntilEnd,omitempty"`
+	// RequireOTP holds every vote until the voter confirms it with a code
+	// emailed to the verified address of their linked account
+	RequireOTP bool `json:"requireOtp,omitempty"`
+	// AllowKioskSessions lets event kiosks an administrator issued a
+	// credential to open anonymous voting sessions
+	AllowKioskSessions bool `json:"allowKioskSessions,omitempty"`
+	// VoteChangeMinutes lets voters change a vote for this long after
+	// casting it, 0 makes votes final. Votes can be changed until the
+	// campaign ends without it.
+	VoteChangeMinutes *int `json:"voteChangeMinutes,omitempty" binding:"omitempty,min=0,max=525600"`
+}
+
+// ToProposal converts the request to a campaign proposal
+func (r *AutoGenerateCampaignRequest) ToProposal() services.CampaignProposal {
+	return services.CampaignProposal{
+		CityID:          r.CityID,
+		CategoryID:      r.CategoryID,
+		Quarter:         strings.TrimSpace(r.Quarter),
+		Title:           strings.TrimSpace(r.Title),
+		Nominees:        r.Nominees,
+		MaxVotesPerUser: r.MaxVotesPerUser,
+
+		HideResultsUntilEnd: r.HideResultsUntilEnd,
+		RequireOTP:          r.RequireOTP,
+		AllowKioskSessions:  r.AllowKioskSessions,
+		VoteChangeMinutes:   r.VoteChangeMinutes,
+	}
+}
+
+// CampaignNomineesResponse is a campaign with its nominees
+type CampaignNomineesResponse struct {
+	Campaign *models.VotingCampaign   `json:"campaign"`
+	Nominees []models.CampaignNominee `json:"nominees"`
+}
+
+// CampaignNomineeRequest nominates a venue by hand
+type CampaignNomineeRequest struct {
+	VenueID int64 `json:"venueId" binding:"required,min=1"`
+}
+
+// EligibleNomineesRequest nominates the venues of a campaign's city and
+// category matching the criteria
+type EligibleNomineesRequest struct {
+	MinReviews int     `json:"minReviews,omitempty" binding:"min=0,max=1000"`
+	MinRating  float64 `json:"minRating,omitempty" binding:"min=0,max=5"`
+	Limit      int     `json:"limit,omitempty" binding:"omitempty,min=1,max=50"` // Defaults to 10
+}
+
+// ToCriteria converts the request to nominee criteria
+func (r *EligibleNomineesRequest) ToCriteria() models.NomineeCriteria {
+	criteria := models.NomineeCriteria{
+		MinReviews: r.MinReviews,
+		MinRating:  r.MinRating,
+		Limit:      r.Limit,
+	}
+	if criteria.MinReviews == 0 {
+		criteria.MinReviews = services.NomineeMinReviews
+	}
+	if criteria.Limit == 0 {
+		criteria.Limit = services.DefaultCampaignNominees
+	}
+	return criteria
+}
+
+// EligibleNomineesResponse lists the venues nominated by criteria
+type EligibleNomineesResponse struct {
+	CampaignID int64                    `json:"campaignId"`
+	Nominated  []models.CampaignNominee `json:"nominated"`
+	Count      int                      `json:"count"`
+}
+
+// CampaignDetailResponse is a campaign with its categories and nominees
+type CampaignDetailResponse struct {
+	Campaign   *models.VotingCampaign    `json:"campaign"`
+	Categories []models.CampaignCategory `json:"categories"`
+	Nominees   []models.CampaignNominee  `json:"nominees"`
+	IsOpen     bool                      `json:"isOpen"`
+}
+
+// CampaignAuditKeyResponse is the public key campaign audit certificates are
+// verified with
+type CampaignAuditKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"` // PEM encoded
+}
+
+// CampaignSnapshotsResponse lists the stored results snapshots of a campaign
+type CampaignSnapshotsResponse struct {
+	CampaignID int64                           `json:"campaignId"`
+	Snapshots  []models.CampaignResultSnapshot `json:"snapshots"`
+}
+
+// CampaignSnapshotsQuery holds the query parameters of the results snapshots
+type CampaignSnapshotsQuery struct {
+	Limit int `form:"limit,default=24" binding:"min=1,max=100"`
+}
+
+// VerifyReceiptRequest is a vote receipt submitted for verification
+type VerifyReceiptRequest struct {
+	services.VoteReceipt
+}
+
+// VerifyReceiptResponse for the receipt verification API
+type VerifyReceiptResponse struct {
+	Valid    bool   `json:"valid"`
+	Recorded bool   `json:"recorded"` // The vote is still counted
+	Message  string `json:"message"`
+}
+
+// Validate validates the SubmitCampaignVoteRequest
+func (r *SubmitCampaignVoteRequest) Validate() (Base, bool) {
+	if r.VenueID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Venue ID is required",
+		}, false
+	}
+
+	r.Reason = SanitizeText(r.Reason)
+	if TextLength(r.Reason) > 1000 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Reason must be at most 1000 characters",
+		}, false
+	}
+
+	if r.ConfidenceScore != 0 && (r.ConfidenceScore < 1 || r.ConfidenceScore > 5) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Confidence score must be between 1 and 5",
+		}, false
+	}
+
+	if r.CategoryID != nil && *r.CategoryID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid category ID",
+		}, false
+	}
+
+	if r.Votes == 0 {
+		r.Votes = 1
+	}
+	if r.Votes < 1 || r.Votes > MaxVotesPerVenue {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("Votes must be between 1 and %d", MaxVotesPerVenue),
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// Validate validates the ChangeCampaignVoteRequest
+func (r *ChangeCampaignVoteRequest) Validate() (Base, bool) {
+	if r.VenueID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Venue ID is required",
+		}, false
+	}
+
+	r.Reason = SanitizeText(r.Reason)
+	if TextLength(r.Reason) > 1000 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Reason must be at most 1000 characters",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToCampaignVote converts SubmitCampaignVoteRequest to CampaignVote model
+func (r *SubmitCampaignVoteRequest) ToCampaignVote(campaignID, userID int64) *models.CampaignVote {
+	vote := &models.CampaignVote{
+		CampaignID: campaignID,
+		CategoryID: r.CategoryID,
+		VenueID:    r.VenueID,
+		UserID:     userID,
+		Reason:     r.Reason,
+		Votes:      r.Votes,
+	}
+	if r.ConfidenceScore != 0 {
+		vote.ConfidenceScore = &r.ConfidenceScore
+	}
+	return vote
+}
+
+// Validate validates the VerifyReceiptRequest
+func (r *VerifyReceiptRequest) Validate() (Base, bool) {
+	if r.Kind != services.ReceiptCampaign && r.Kind != services.ReceiptLegacy {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Receipt kind must be one of: campaign, legacy",
+		}, false
+	}
+
+	if r.VoteID <= 0 || r.Signature == "" || r.Timestamp.IsZero() {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Receipt vote ID, timestamp and signature are required",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// Validate validates the CampaignCategoryRequest
+func (r *CampaignCategoryRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must be between 1 and 255 characters",
+		}, false
+	}
+
+	if generateSlug(r.Name) == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must contain letters or digits",
+		}, false
+	}
+
+	if r.VenueCategoryID != nil && *r.VenueCategoryID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid venue category ID",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// Validate validates the CampaignPromotionRequest
+func (r *CampaignPromotionRequest) Validate(now time.Time) (Base, bool) {
+	if !r.EndsAt.After(r.StartsAt) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Promotion must end after it starts",
+		}, false
+	}
+
+	if !r.EndsAt.After(now) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Promotion must end in the future",
+		}, false
+	}
+
+	if r.Priority < 0 || r.Priority > 100 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Priority must be between 0 and 100",
+		}, false
+	}
+
+	r.BannerImage = strings.TrimSpace(r.BannerImage)
+	r.BannerLink = strings.TrimSpace(r.BannerLink)
+	if r.BannerLink != "" && r.BannerImage == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Banner link requires a banner image",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToPromotion converts CampaignPromotionRequest to CampaignPromotion model
+func (r *CampaignPromotionRequest) ToPromotion(campaignID, createdBy int64) *models.CampaignPromotion {
+	return &models.CampaignPromotion{
+		CampaignID:  campaignID,
+		StartsAt:    r.StartsAt,
+		EndsAt:      r.EndsAt,
+		Priority:    r.Priority,
+		BannerImage: r.BannerImage,
+		BannerLink:  r.BannerLink,
+		CreatedBy:   createdBy,
+	}
+}
+
+// ToCategory converts CampaignCategoryRequest to CampaignCategory model
+func (r *CampaignCategoryRequest) ToCategory(campaignID int64) *models.CampaignCategory {
+	return &models.CampaignCategory{
+		CampaignID:      campaignID,
+		Name:            r.Name,
+		Slug:            generateSlug(r.Name),
+		Description:     strings.TrimSpace(r.Description),
+		VenueCategoryID: r.VenueCategoryID,
+		SortOrder:       r.SortOrder,
+	}
+}
+
+// CampaignEmbedTokenRequest issues a token for embedding the campaign's
+// results widget on the pages of the origins, e.g. https://news.example.com
+type CampaignEmbedTokenRequest struct {
+	Origins       []string `json:"origins" binding:"required,min=1,dive,required,max=255"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// Validate validates the CampaignEmbedTokenRequest, normalizing its origins
+func (r *CampaignEmbedTokenRequest) Validate() (Base, bool) {
+	if len(r.Origins) > services.MaxEmbedOrigins {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("At most %d origins can embed a widget", services.MaxEmbedOrigins),
+		}, false
+	}
+
+	seen := make(map[string]bool, len(r.Origins))
+	origins := make([]string, 0, len(r.Origins))
+	for _, raw := range r.Origins {
+		origin, ok := services.NormalizeOrigin(raw)
+		if !ok {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Invalid origin: " + raw,
+			}, false
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	r.Origins = origins
+	return Base{}, true
+}
+
+// TTL returns how long the token works
+func (r *CampaignEmbedTokenRequest) TTL() time.Duration {
+	if r.ExpiresInDays == 0 {
+		return services.DefaultEmbedTokenTTL
+	}
+	return time.Duration(r.ExpiresInDays) * 24 * time.Hour
+}
+
+// CampaignEmbedTokenResponse is an issued embed token and the widget URL to
+// put in the iframe
+type CampaignEmbedTokenResponse struct {
+	CampaignID int64     `json:"campaignId"`
+	Token      string    `json:"token"`
+	Origins    []string  `json:"origins"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	WidgetPath string    `json:"widgetPath"` // Relative to the API host
+}
+
+// CampaignWidgetQuery holds the query parameters of the widget endpoint
+type CampaignWidgetQuery struct {
+	Token  string `form:"token" binding:"required"`
+	Format string `form:"format" binding:"omitempty,oneof=json html"`
+}
@@ -0,0 +1,137 @@
+package serializers
+
+import (
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// CityRequest for onboarding or updating a city
+type CityRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	State     string   `json:"state"`
+	Country   string   `json:"country" binding:"required"`
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"` // Centroid
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	Timezone  string   `json:"timezone" binding:"required"` // IANA name like Asia/Tehran
+	IsActive  *bool    `json:"isActive"`                    // Defaults to true, false lists the city once its districts are in
+}
+
+// DistrictRequest for adding or updating a district of a city
+type DistrictRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"` // Centroid
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// CityResponse for a city as administrators see it
+type CityResponse struct {
+	models.City
+	IsActive bool `json:"isActive"`
+}
+
+// CitiesResponse for listing the active cities
+type CitiesResponse struct {
+	Cities []models.City `json:"cities"`
+}
+
+// DistrictsResponse for listing the districts of a city
+type DistrictsResponse struct {
+	City      models.City       `json:"city"`
+	Districts []models.District `json:"districts"`
+}
+
+// Validate validates the CityRequest
+func (r *CityRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.State = strings.TrimSpace(r.State)
+	r.Country = strings.TrimSpace(r.Country)
+	if r.Name == "" || len(r.Name) > 100 || len(r.State) > 100 || r.Country == "" || len(r.Country) > 100 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name and country must be 1-100 characters, state at most 100",
+		}, false
+	}
+
+	if r.Latitude == nil || r.Longitude == nil {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Latitude and longitude of the city centre are required",
+		}, false
+	}
+
+	r.Timezone = strings.TrimSpace(r.Timezone)
+	if !validTimezone(r.Timezone) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Timezone must be an IANA timezone like Europe/Berlin",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToCity converts CityRequest to City model
+func (r *CityRequest) ToCity(id int64) *models.City {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+	return &models.City{
+		ID:        id,
+		Name:      r.Name,
+		State:     r.State,
+		Country:   r.Country,
+		Latitude:  *r.Latitude,
+		Longitude: *r.Longitude,
+		Timezone:  r.Timezone,
+		IsActive:  isActive,
+	}
+}
+
+// Validate validates the DistrictRequest
+func (r *DistrictRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 || generateSlug(r.Name) == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must be between 1 and 100 characters with letters or digits",
+		}, false
+	}
+
+	if r.Latitude == nil || r.Longitude == nil {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Latitude and longitude of the district centre are required",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToDistrict converts DistrictRequest to District model
+func (r *DistrictRequest) ToDistrict(cityID, id int64) *models.District {
+	return &models.District{
+		ID:        id,
+		CityID:    cityID,
+		Name:      r.Name,
+		Slug:      generateSlug(r.Name),
+		Latitude:  *r.Latitude,
+		Longitude: *r.Longitude,
+	}
+}
+
+// NewCityResponse returns the city with whether it is listed
+func NewCityResponse(city *models.City) CityResponse {
+	return CityResponse{City: *city, IsActive: city.IsActive}
+}
+
+// validTimezone tells whether the name is a timezone of the IANA database.
+// Local is rejected as it depends on the server.
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" || len(name) > 50 {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
@@ -0,0 +1,81 @@
+package serializers
+
+import "voting-app/app/models"
+
+// MaxCollectionNoteLength caps the note on a venue in a collection
+const MaxCollectionNoteLength = 500
+
+// InviteCollaboratorRequest invites a user to edit a collection
+type InviteCollaboratorRequest struct {
+	UserID int64 `json:"userId" binding:"required,gt=0"`
+}
+
+// CollectionsResponse lists the collections a user owns or collaborates on,
+// with the user's pending invites
+type CollectionsResponse struct {
+	Collections []models.VenueCollection        `json:"collections"`
+	Invites     []models.CollectionCollaborator `json:"invites"`
+}
+
+// CollectionDetailResponse for a collection with its venues and
+// collaborators
+type CollectionDetailResponse struct {
+	models.VenueCollection
+	Items         []models.CollectionItem         `json:"items"`
+	Collaborators []models.CollectionCollaborator `json:"collaborators,omitempty"` // Only shown to the owner and collaborators
+}
+
+// Validate validates the CreateCollectionRequest
+func (r *CreateCollectionRequest) Validate() (Base, bool) {
+	r.Name = SanitizeLine(r.Name)
+	if r.Name == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name is required",
+		}, false
+	}
+	if TextLength(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must be at most 255 characters",
+		}, false
+	}
+	r.Description = SanitizeText(r.Description)
+	if TextLength(r.Description) > 1000 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Description must be at most 1000 characters",
+		}, false
+	}
+	if len(r.CoverImage) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Cover image must be at most 255 characters",
+		}, false
+	}
+	return Base{}, true
+}
+
+// ToVenueCollection converts CreateCollectionRequest to a VenueCollection
+// owned by the user
+func (r *CreateCollectionRequest) ToVenueCollection(userID int64) *models.VenueCollection {
+	return &models.VenueCollection{
+		UserID:      userID,
+		Name:        r.Name,
+		Description: r.Description,
+		IsPublic:    r.IsPublic,
+		CoverImage:  r.CoverImage,
+	}
+}
+
+// Validate validates the AddVenueToCollectionRequest
+func (r *AddVenueToCollectionRequest) Validate() (Base, bool) {
+	r.Note = SanitizeText(r.Note)
+	if TextLength(r.Note) > MaxCollectionNoteLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Note must be at most 500 characters",
+		}, false
+	}
+	return Base{}, true
+}
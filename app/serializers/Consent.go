@@ -0,0 +1,42 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+)
+
+// ConsentSources are where users give and withdraw their consents
+var ConsentSources = []string{"app", "web", "onboarding", "settings"}
+
+// ConsentRequest asks for or withdraws a consent
+type ConsentRequest struct {
+	Source string `json:"source" form:"source"` // app, web, onboarding or settings, defaults to app
+}
+
+// ConfirmConsentRequest confirms a pending consent with the code sent to the
+// user
+type ConfirmConsentRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConsentsResponse lists the user's consent to each purpose
+type ConsentsResponse struct {
+	Consents []models.UserConsent `json:"consents"`
+}
+
+// Validate validates the ConsentRequest
+func (r *ConsentRequest) Validate() (Base, bool) {
+	r.Source = strings.TrimSpace(r.Source)
+	if r.Source == "" {
+		r.Source = "app"
+	}
+	for _, source := range ConsentSources {
+		if r.Source == source {
+			return Base{}, true
+		}
+	}
+	return Base{
+		Code:    InvalidInput,
+		Message: "Source must be one of: " + strings.Join(ConsentSources, ", "),
+	}, false
+}
@@ -0,0 +1,87 @@
+package serializers
+
+import (
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	MaxDealTitleLength       = 100
+	MaxDealDescriptionLength = 1000
+	// MaxDealDays is how long a deal can run
+	MaxDealDays = 366
+)
+
+// VenueDealRequest creates a deal or replaces its details
+type VenueDealRequest struct {
+	Title           string    `json:"title" binding:"required"`
+	Description     string    `json:"description,omitempty"`
+	StartsAt        time.Time `json:"startsAt" binding:"required"`
+	EndsAt          time.Time `json:"endsAt" binding:"required"`
+	RedemptionLimit *int      `json:"redemptionLimit,omitempty"` // Unlimited when unset
+}
+
+// VenueDealsResponse lists a venue's deals
+type VenueDealsResponse struct {
+	VenueID int64              `json:"venueId"`
+	Deals   []models.VenueDeal `json:"deals"`
+}
+
+// RedeemDealRequest redeems a deal with a check-in at its venue
+type RedeemDealRequest struct {
+	CheckinID int64 `json:"checkinId" binding:"required"`
+}
+
+// Validate validates the VenueDealRequest. Deals can't have ended already.
+func (r *VenueDealRequest) Validate(now time.Time) (Base, bool) {
+	r.Title = SanitizeLine(r.Title)
+	if r.Title == "" || TextLength(r.Title) > MaxDealTitleLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Title must be between 1 and 100 characters",
+		}, false
+	}
+
+	r.Description = SanitizeText(r.Description)
+	if TextLength(r.Description) > MaxDealDescriptionLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Description must be at most 1000 characters",
+		}, false
+	}
+
+	if !r.EndsAt.After(r.StartsAt) || !r.EndsAt.After(now) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A deal must end after it starts and in the future",
+		}, false
+	}
+	if r.EndsAt.Sub(r.StartsAt) > MaxDealDays*24*time.Hour {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A deal can run for at most a year",
+		}, false
+	}
+
+	if r.RedemptionLimit != nil && *r.RedemptionLimit < 1 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Redemption limit must be at least 1",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToVenueDeal converts VenueDealRequest to VenueDeal model
+func (r *VenueDealRequest) ToVenueDeal(venueID, createdBy int64) *models.VenueDeal {
+	return &models.VenueDeal{
+		VenueID:         venueID,
+		Title:           r.Title,
+		Description:     r.Description,
+		StartsAt:        r.StartsAt,
+		EndsAt:          r.EndsAt,
+		RedemptionLimit: r.RedemptionLimit,
+		CreatedBy:       createdBy,
+	}
+}
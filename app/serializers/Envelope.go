@@ -0,0 +1,71 @@
+package serializers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Envelope is the shape of every v2 response. Data is null for errors,
+// which are listed in Errors instead.
+type Envelope struct {
+	Data   interface{}     `json:"data"`
+	Meta   EnvelopeMeta    `json:"meta"`
+	Errors []EnvelopeError `json:"errors,omitempty"`
+}
+
+// EnvelopeMeta describes the response apart from its data
+type EnvelopeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	// Pagination is moved out of the data of paginated responses
+	Pagination json.RawMessage `json:"pagination,omitempty"`
+}
+
+// EnvelopeError is an error of a v2 response
+type EnvelopeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// statusCodes are the error codes of error responses without a Base body
+var statusCodes = map[int]string{
+	http.StatusUnauthorized:    Unauthorized,
+	http.StatusForbidden:       Forbidden,
+	http.StatusNotFound:        NotFound,
+	http.StatusTooManyRequests: TooManyRequests,
+}
+
+// NewEnvelope wraps the JSON body of a v1 response with the given status.
+// Error responses are expected to be a Base, others are kept as the data
+// with their pagination moved to the meta.
+func NewEnvelope(version string, status int, body []byte) Envelope {
+	envelope := Envelope{Meta: EnvelopeMeta{APIVersion: version}}
+
+	if status >= http.StatusBadRequest {
+		var base Base
+		if err := json.Unmarshal(body, &base); err != nil || base.Code == "" {
+			base = Base{Code: statusCodes[status], Message: http.StatusText(status)}
+			if base.Code == "" && status < http.StatusInternalServerError {
+				base.Code = InvalidInput
+			} else if base.Code == "" {
+				base.Code = InternalError
+			}
+		}
+		envelope.Errors = []EnvelopeError{{Code: base.Code, Message: base.Message}}
+		return envelope
+	}
+
+	if len(body) == 0 {
+		return envelope
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err == nil {
+		if pagination, ok := object["pagination"]; ok {
+			envelope.Meta.Pagination = pagination
+			delete(object, "pagination")
+			envelope.Data = object
+			return envelope
+		}
+	}
+	envelope.Data = json.RawMessage(body)
+	return envelope
+}
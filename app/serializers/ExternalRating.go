@@ -0,0 +1,66 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// ExternalRatingsRequest imports a venue's ratings from other platforms
+type ExternalRatingsRequest struct {
+	Ratings []ExternalRatingRequest `json:"ratings" binding:"required,min=1,max=10"`
+}
+
+// ExternalRatingRequest is a venue's aggregate rating on a platform
+type ExternalRatingRequest struct {
+	Source      string  `json:"source" binding:"required"` // google, tripadvisor, yelp or facebook
+	Rating      float64 `json:"rating" binding:"required"` // From 1 to 5
+	ReviewCount int     `json:"reviewCount"`
+	URL         string  `json:"url,omitempty"`
+}
+
+// ExternalRatingsResponse lists a venue's imported ratings
+type ExternalRatingsResponse struct {
+	VenueID int64                   `json:"venueId"`
+	Ratings []models.ExternalRating `json:"ratings"`
+}
+
+// Validate validates the ExternalRatingsRequest
+func (r *ExternalRatingsRequest) Validate() (Base, bool) {
+	seen := make(map[string]bool)
+	for i := range r.Ratings {
+		rating := &r.Ratings[i]
+		rating.Source = strings.ToLower(strings.TrimSpace(rating.Source))
+		rating.URL = strings.TrimSpace(rating.URL)
+		if message := services.ValidateExternalRating(rating.Source, rating.Rating, rating.ReviewCount, rating.URL); message != "" {
+			return Base{
+				Code:    InvalidInput,
+				Message: strings.ToUpper(message[:1]) + message[1:],
+			}, false
+		}
+		if seen[rating.Source] {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Each source can only be imported once",
+			}, false
+		}
+		seen[rating.Source] = true
+	}
+	return Base{}, true
+}
+
+// ToExternalRatings converts ExternalRatingsRequest to ExternalRating models
+func (r *ExternalRatingsRequest) ToExternalRatings(venueID, importedBy int64) []models.ExternalRating {
+	ratings := make([]models.ExternalRating, len(r.Ratings))
+	for i, rating := range r.Ratings {
+		ratings[i] = models.ExternalRating{
+			VenueID:     venueID,
+			Source:      rating.Source,
+			Rating:      rating.Rating,
+			ReviewCount: rating.ReviewCount,
+			URL:         rating.URL,
+			ImportedBy:  importedBy,
+		}
+	}
+	return ratings
+}
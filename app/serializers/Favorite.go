@@ -0,0 +1,15 @@
+package serializers
+
+import "voting-app/app/models"
+
+// FavoritesQuery holds the query parameters of a user's favorites
+type FavoritesQuery struct {
+	Page  int `form:"page,default=1" binding:"min=1"`
+	Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// FavoritesResponse for the venues a user favorited
+type FavoritesResponse struct {
+	Favorites  []models.FavoriteVenue `json:"favorites"`
+	Pagination PaginationInfo         `json:"pagination"`
+}
@@ -0,0 +1,93 @@
+package serializers
+
+import (
+	"regexp"
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxFlagUserIDs caps the users a feature flag can target by ID
+const MaxFlagUserIDs = 1000
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,100}$`)
+
+// FeatureFlagRequest for creating or updating a feature flag. The key is
+// only read on create, updates take it from the path.
+type FeatureFlagRequest struct {
+	Key               string  `json:"key"`
+	Description       string  `json:"description"`
+	Enabled           bool    `json:"enabled"`
+	RolloutPercentage int     `json:"rolloutPercentage"`
+	UserIDs           []int64 `json:"userIds"`
+}
+
+// FeatureFlagsResponse for the feature flags API
+type FeatureFlagsResponse struct {
+	Flags []models.FeatureFlag `json:"flags"`
+}
+
+// ValidKey tells whether the key is a valid feature flag key, made of lower
+// case letters, digits and underscores
+func (r *FeatureFlagRequest) ValidKey() (Base, bool) {
+	if !flagKeyPattern.MatchString(r.Key) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Key must be 1-100 lower case letters, digits or underscores",
+		}, false
+	}
+	return Base{}, true
+}
+
+// Validate validates the FeatureFlagRequest targeting
+func (r *FeatureFlagRequest) Validate() (Base, bool) {
+	r.Description = strings.TrimSpace(r.Description)
+	if len(r.Description) > 500 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Description must be at most 500 characters",
+		}, false
+	}
+
+	if r.RolloutPercentage < 0 || r.RolloutPercentage > 100 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Rollout percentage must be between 0 and 100",
+		}, false
+	}
+
+	if len(r.UserIDs) > MaxFlagUserIDs {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A flag can target at most 1000 users",
+		}, false
+	}
+
+	seen := make(map[int64]bool)
+	userIDs := make([]int64, 0, len(r.UserIDs))
+	for _, userID := range r.UserIDs {
+		if userID <= 0 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "User IDs must be positive",
+			}, false
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	r.UserIDs = userIDs
+
+	return Base{}, true
+}
+
+// ToFeatureFlag converts FeatureFlagRequest to FeatureFlag model
+func (r *FeatureFlagRequest) ToFeatureFlag(key string) *models.FeatureFlag {
+	return &models.FeatureFlag{
+		Key:               key,
+		Description:       r.Description,
+		Enabled:           r.Enabled,
+		RolloutPercentage: r.RolloutPercentage,
+		UserIDs:           r.UserIDs,
+	}
+}
@@ -0,0 +1,164 @@
+package serializers
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// VenueFields are the venue fields clients can ask for with the fields
+// query parameter
+var VenueFields = []string{
+	"id", "name", "slug", "description", "shortDescription",
+	"address", "cityId", "city", "latitude", "longitude", "postalCode",
+	"neighborhoodId", "neighborhood", "categoryId", "category", "subcategoryId", "subcategory",
+	"phone", "email", "website", "openingHours", "priceRange", "averageCostPerPerson",
+	"coverImage", "logo", "averageRating", "totalRatings", "totalReviews", "totalFavorites", "amenities",
+	"isVerified", "isFeatured", "ownerId", "distance", "travelMinutes", "isOpen", "nextOpenTime",
+	"reviewSummary", "busyness", "deals", "createdAt", "updatedAt",
+}
+
+// VenueDetailSections are the parts of the venue details besides the venue.
+// With a field selection they are only included when asked for.
+var VenueDetailSections = []string{"reviewSummary", "similarVenues", "checkinCount", "ratingTemplate", "externalRatings"}
+
+// ReviewFields are the review fields clients can ask for
+var ReviewFields = []string{
+	"id", "venueId", "userId", "overallRating", "detailedRatings", "title", "reviewText",
+	"translation", "visitDate", "visitType", "partySize", "photos", "photoDetails",
+	"isVerified", "isFeatured", "helpfulVotes", "unhelpfulVotes",
+	"user", "userName", "venueName", "createdAt", "updatedAt",
+}
+
+// CampaignResultsFields are the campaign results fields clients can ask for
+var CampaignResultsFields = []string{
+	"campaignId", "title", "votingMode", "totalVotes", "voters", "isFinal", "categories", "generatedAt",
+	"resultsHidden", "resultsAvailableAt",
+}
+
+// FieldSelection is the set of fields a client asked for. A nil selection
+// asks for the full response.
+type FieldSelection map[string]bool
+
+// ParseFields parses the comma separated fields query parameter, accepting
+// only the fields the endpoint allows
+func ParseFields(fields string, allowed ...[]string) (FieldSelection, Base, bool) {
+	if strings.TrimSpace(fields) == "" {
+		return nil, Base{}, true
+	}
+
+	known := make(map[string]bool)
+	for _, list := range allowed {
+		for _, field := range list {
+			known[field] = true
+		}
+	}
+
+	selection := make(FieldSelection)
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !known[field] {
+			return nil, Base{
+				Code:    InvalidInput,
+				Message: "Unknown field: " + field,
+			}, false
+		}
+		selection[field] = true
+	}
+	return selection, Base{}, true
+}
+
+// Filter keeps the selected fields of an object, or of each object in a list
+func (s FieldSelection) Filter(value interface{}) (interface{}, error) {
+	if s == nil {
+		return value, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterJSON(data)
+}
+
+// FilterUnder keeps the selected fields of the object or list under the key
+// of the response, leaving the rest of the response whole
+func (s FieldSelection) FilterUnder(response interface{}, key string) (interface{}, error) {
+	if s == nil {
+		return response, nil
+	}
+
+	object, err := jsonObject(response)
+	if err != nil {
+		return nil, err
+	}
+	if data, exists := object[key]; exists {
+		filtered, err := s.filterJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		if object[key], err = json.Marshal(filtered); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
+
+// Select returns the venue details with the selected venue fields, and only
+// the sections asked for. The redirect of a moved slug is always kept.
+func (r VenueDetailResponse) Select(fields FieldSelection) (interface{}, error) {
+	if fields == nil {
+		return r, nil
+	}
+
+	filtered, err := fields.FilterUnder(r, "venue")
+	if err != nil {
+		return nil, err
+	}
+	object := filtered.(map[string]json.RawMessage)
+	for _, section := range VenueDetailSections {
+		if !fields[section] {
+			delete(object, section)
+		}
+	}
+	return object, nil
+}
+
+// filterJSON keeps the selected fields of a JSON object or of each object
+// in a JSON list. Other values are returned as they are.
+func (s FieldSelection) filterJSON(data json.RawMessage) (interface{}, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err == nil && list != nil {
+		filtered := make([]interface{}, len(list))
+		for i, item := range list {
+			if filtered[i], err = s.filterJSON(item); err != nil {
+				return nil, err
+			}
+		}
+		return filtered, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return data, nil
+	}
+	for field := range object {
+		if !s[field] {
+			delete(object, field)
+		}
+	}
+	return object, nil
+}
+
+// jsonObject returns the fields of a value encoding to a JSON object
+func jsonObject(value interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	err = json.Unmarshal(data, &object)
+	return object, err
+}
@@ -0,0 +1,85 @@
+package serializers
+
+import (
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// MaxHoursExceptionDays is how far ahead hours exceptions can be set
+const MaxHoursExceptionDays = 366
+
+// HoursExceptionRequest closes a venue on a date or sets different hours
+// for it
+type HoursExceptionRequest struct {
+	Date     string `json:"date" binding:"required"` // "2006-01-02", local to the venue's city
+	IsClosed bool   `json:"isClosed"`
+	Open     string `json:"open,omitempty"`  // HH:MM, required unless closed
+	Close    string `json:"close,omitempty"` // Before Open to run past midnight
+	Reason   string `json:"reason,omitempty"`
+}
+
+// HoursExceptionsResponse lists a venue's upcoming hours exceptions
+type HoursExceptionsResponse struct {
+	VenueID    int64                   `json:"venueId"`
+	Exceptions []models.HoursException `json:"exceptions"`
+}
+
+// Validate validates the HoursExceptionRequest. The date can be yesterday at
+// the earliest, which is still today in some time zones.
+func (r *HoursExceptionRequest) Validate(now time.Time) (Base, bool) {
+	date, err := time.Parse(models.DateLayout, strings.TrimSpace(r.Date))
+	if err != nil {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Date must look like 2026-12-25",
+		}, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if date.Before(today.AddDate(0, 0, -1)) || date.After(today.AddDate(0, 0, MaxHoursExceptionDays)) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Date must be within the coming year",
+		}, false
+	}
+	r.Date = date.Format(models.DateLayout)
+
+	r.Open = strings.TrimSpace(r.Open)
+	r.Close = strings.TrimSpace(r.Close)
+	if r.IsClosed {
+		if r.Open != "" || r.Close != "" {
+			return Base{
+				Code:    InvalidInput,
+				Message: "A closed day has no hours",
+			}, false
+		}
+	} else if !models.ValidClock(r.Open) || !models.ValidClock(r.Close) || r.Open == r.Close {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Open and close must be different times like 09:00",
+		}, false
+	}
+
+	r.Reason = strings.TrimSpace(r.Reason)
+	if len(r.Reason) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Reason must be at most 255 characters",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToHoursException converts HoursExceptionRequest to HoursException model
+func (r *HoursExceptionRequest) ToHoursException(venueID, createdBy int64) *models.HoursException {
+	return &models.HoursException{
+		VenueID:   venueID,
+		Date:      r.Date,
+		IsClosed:  r.IsClosed,
+		Open:      r.Open,
+		Close:     r.Close,
+		Reason:    r.Reason,
+		CreatedBy: createdBy,
+	}
+}
@@ -0,0 +1,161 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+)
+
+// VenueMenusResponse for the public venue menu API
+type VenueMenusResponse struct {
+	VenueID int64              `json:"venueId"`
+	Menus   []models.VenueMenu `json:"menus"`
+}
+
+// MenuRequest for creating and updating venue menus
+type MenuRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	IsActive    *bool  `json:"isActive,omitempty"`
+	SortOrder   int    `json:"sortOrder"`
+}
+
+// MenuSectionRequest for creating and updating menu sections
+type MenuSectionRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description,omitempty"`
+	SortOrder   int    `json:"sortOrder"`
+}
+
+// MenuItemRequest for creating and updating menu items
+type MenuItemRequest struct {
+	Name        string   `json:"name" binding:"required,min=1,max=255"`
+	Description string   `json:"description,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	DietaryTags []string `json:"dietaryTags,omitempty"`
+	IsAvailable *bool    `json:"isAvailable,omitempty"`
+	SortOrder   int      `json:"sortOrder"`
+}
+
+// Validate validates the MenuRequest
+func (r *MenuRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Menu name is required and must be at most 255 characters",
+		}, false
+	}
+
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency == "" {
+		r.Currency = "USD"
+	}
+	if len(r.Currency) != 3 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Currency must be a 3 letter ISO code",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToMenu converts MenuRequest to VenueMenu model
+func (r *MenuRequest) ToMenu(venueID int64) *models.VenueMenu {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+
+	return &models.VenueMenu{
+		VenueID:     venueID,
+		Name:        r.Name,
+		Description: r.Description,
+		Currency:    r.Currency,
+		IsActive:    isActive,
+		SortOrder:   r.SortOrder,
+		Sections:    []models.MenuSection{},
+	}
+}
+
+// Validate validates the MenuSectionRequest
+func (r *MenuSectionRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Section name is required and must be at most 255 characters",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToSection converts MenuSectionRequest to MenuSection model
+func (r *MenuSectionRequest) ToSection(menuID int64) *models.MenuSection {
+	return &models.MenuSection{
+		MenuID:      menuID,
+		Name:        r.Name,
+		Description: r.Description,
+		SortOrder:   r.SortOrder,
+		Items:       []models.MenuItem{},
+	}
+}
+
+// Validate validates the MenuItemRequest
+func (r *MenuItemRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Item name is required and must be at most 255 characters",
+		}, false
+	}
+
+	if r.Price != nil && *r.Price < 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Price can not be negative",
+		}, false
+	}
+
+	// Normalize and de-duplicate dietary tags
+	tags := make([]string, 0, len(r.DietaryTags))
+	seen := make(map[string]bool)
+	for _, tag := range r.DietaryTags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !models.IsValidDietaryTag(tag) {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Dietary tags must be any of: " + strings.Join(models.DietaryTags, ", "),
+			}, false
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	r.DietaryTags = tags
+
+	return Base{}, true
+}
+
+// ToItem converts MenuItemRequest to MenuItem model
+func (r *MenuItemRequest) ToItem(sectionID, venueID int64) *models.MenuItem {
+	isAvailable := true
+	if r.IsAvailable != nil {
+		isAvailable = *r.IsAvailable
+	}
+
+	return &models.MenuItem{
+		SectionID:   sectionID,
+		VenueID:     venueID,
+		Name:        r.Name,
+		Description: r.Description,
+		Price:       r.Price,
+		DietaryTags: r.DietaryTags,
+		IsAvailable: isAvailable,
+		SortOrder:   r.SortOrder,
+	}
+}
@@ -0,0 +1,113 @@
+package serializers
+
+import (
+	"fmt"
+	"voting-app/app/models"
+)
+
+// MaxBulkModerationReviews caps the reviews moderated in one request
+const MaxBulkModerationReviews = 500
+
+// BulkModerationRequest applies one moderation action to many reviews
+type BulkModerationRequest struct {
+	ReviewIDs []int64 `json:"reviewIds"`
+	Action    string  `json:"action"` // approve, reject or flag
+}
+
+// BulkModerationResponse tells what happened to each review of the batch
+type BulkModerationResponse struct {
+	Action    string                        `json:"action"`
+	Updated   int                           `json:"updated"`
+	Unchanged int                           `json:"unchanged"`
+	NotFound  int                           `json:"notFound"`
+	Results   []models.BulkModerationResult `json:"results"`
+}
+
+// Validate validates the BulkModerationRequest
+func (r *BulkModerationRequest) Validate() (Base, bool) {
+	switch r.Action {
+	case models.ModerationApprove, models.ModerationReject, models.ModerationFlag:
+	default:
+		return Base{
+			Code:    InvalidInput,
+			Message: "Action must be one of: approve, reject, flag",
+		}, false
+	}
+
+	if len(r.ReviewIDs) == 0 || len(r.ReviewIDs) > MaxBulkModerationReviews {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("Between 1 and %d review IDs are required", MaxBulkModerationReviews),
+		}, false
+	}
+	for _, id := range r.ReviewIDs {
+		if id <= 0 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Review IDs must be positive",
+			}, false
+		}
+	}
+
+	return Base{}, true
+}
+
+// NewBulkModerationResponse counts the results of the batch
+func NewBulkModerationResponse(action string, results []models.BulkModerationResult) BulkModerationResponse {
+	response := BulkModerationResponse{Action: action, Results: results}
+	for _, result := range results {
+		switch result.Result {
+		case models.BulkResultUpdated:
+			response.Updated++
+		case models.BulkResultUnchanged:
+			response.Unchanged++
+		case models.BulkResultNotFound:
+			response.NotFound++
+		}
+	}
+	return response
+}
+
+// SlowQueriesQuery filters the slow query log
+type SlowQueriesQuery struct {
+	Hours  int    `form:"hours,default=24" binding:"min=1,max=720"` // How far back
+	Source string `form:"source" binding:"max=255"`                 // Function that ran the statements
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=200"`
+}
+
+// SlowQueriesResponse lists the recent slow queries with the settings they
+// were logged with
+type SlowQueriesResponse struct {
+	Enabled     bool               `json:"enabled"`
+	ThresholdMs int64              `json:"thresholdMs"`
+	Explain     bool               `json:"explain"`
+	SlowQueries []models.SlowQuery `json:"slowQueries"`
+}
+
+// ReviewDuplicatesResponse is an original review with the reviews found to
+// copy it
+type ReviewDuplicatesResponse struct {
+	OriginalID int64                    `json:"originalId"`
+	Duplicates []models.ReviewDuplicate `json:"duplicates"`
+}
+
+// ImpersonateRequest gives the reason an admin impersonates a snapp user,
+// kept with the session
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// ImpersonationResponse is a new impersonation session with its token, sent
+// in the header it names on the requests made as the snapp user
+type ImpersonationResponse struct {
+	Session models.ImpersonationSession `json:"session"`
+	Token   string                      `json:"token"`
+	Header  string                      `json:"header"`
+}
+
+// ImpersonationAuditResponse lists the requests made in an impersonation
+// session
+type ImpersonationAuditResponse struct {
+	SessionID int64                            `json:"sessionId"`
+	Requests  []models.ImpersonationAuditEntry `json:"requests"`
+}
@@ -0,0 +1,91 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxNeighborhoodPoints caps the points of a neighborhood boundary
+const MaxNeighborhoodPoints = 1000
+
+// NeighborhoodRequest for adding a neighborhood to a city
+type NeighborhoodRequest struct {
+	CityID   int64          `json:"cityId" binding:"required"`
+	Name     string         `json:"name" binding:"required"`
+	Boundary models.Polygon `json:"boundary" binding:"required"` // GeoJSON polygon coordinates, [longitude, latitude] points
+}
+
+// NeighborhoodResponse for a created neighborhood
+type NeighborhoodResponse struct {
+	Neighborhood models.Neighborhood `json:"neighborhood"`
+	VenuesPlaced int                 `json:"venuesPlaced"` // Existing venues placed in it
+}
+
+// NeighborhoodVenuesResponse for discovering the venues of a neighborhood
+type NeighborhoodVenuesResponse struct {
+	Neighborhood models.Neighborhood `json:"neighborhood"`
+	Venues       []models.Venue      `json:"venues"`
+	Pagination   PaginationInfo      `json:"pagination"`
+}
+
+// Validate validates the NeighborhoodRequest
+func (r *NeighborhoodRequest) Validate() (Base, bool) {
+	if r.CityID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Valid city ID is required",
+		}, false
+	}
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 || generateSlug(r.Name) == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must be between 1 and 255 characters with letters or digits",
+		}, false
+	}
+
+	if len(r.Boundary) == 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Boundary is required",
+		}, false
+	}
+
+	points := 0
+	for _, ring := range r.Boundary {
+		if len(ring) < 3 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Boundary rings need at least 3 points",
+			}, false
+		}
+		for _, point := range ring {
+			if point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+				return Base{
+					Code:    InvalidInput,
+					Message: "Boundary points must be [longitude, latitude] pairs",
+				}, false
+			}
+		}
+		points += len(ring)
+	}
+	if points > MaxNeighborhoodPoints {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Boundary has too many points",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToNeighborhood converts NeighborhoodRequest to Neighborhood model
+func (r *NeighborhoodRequest) ToNeighborhood() *models.Neighborhood {
+	return &models.Neighborhood{
+		CityID:   r.CityID,
+		Name:     r.Name,
+		Slug:     generateSlug(r.Name),
+		Boundary: r.Boundary,
+	}
+}
@@ -0,0 +1,44 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+)
+
+// RegisterDeviceRequest for registering a push notification token
+type RegisterDeviceRequest struct {
+	Platform   string `json:"platform" binding:"required"` // apns, fcm
+	Token      string `json:"token" binding:"required"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+// Validate validates the RegisterDeviceRequest
+func (r *RegisterDeviceRequest) Validate() (Base, bool) {
+	r.Platform = strings.ToLower(strings.TrimSpace(r.Platform))
+	if r.Platform != models.PlatformAPNs && r.Platform != models.PlatformFCM {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Platform must be one of: apns, fcm",
+		}, false
+	}
+
+	r.Token = strings.TrimSpace(r.Token)
+	if r.Token == "" || len(r.Token) > 512 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Device token is required and must be at most 512 characters",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToDevice converts RegisterDeviceRequest to UserDevice model
+func (r *RegisterDeviceRequest) ToDevice(userID int64) *models.UserDevice {
+	return &models.UserDevice{
+		UserID:     userID,
+		Platform:   r.Platform,
+		Token:      r.Token,
+		AppVersion: r.AppVersion,
+	}
+}
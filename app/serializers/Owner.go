@@ -0,0 +1,47 @@
+package serializers
+
+import (
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// MaxVenuePhotoCaptionLength is how long a gallery photo's caption can be
+const MaxVenuePhotoCaptionLength = 200
+
+// SetOwnerSubscriptionRequest sets a venue owner's tier
+type SetOwnerSubscriptionRequest struct {
+	Tier      string     `json:"tier" binding:"required,oneof=free premium"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Never when unset
+}
+
+// AddVenuePhotoRequest adds a photo to a venue's gallery
+type AddVenuePhotoRequest struct {
+	URL     string `json:"url" binding:"required,url,max=2048"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// Validate validates the AddVenuePhotoRequest
+func (r *AddVenuePhotoRequest) Validate() (Base, bool) {
+	r.Caption = SanitizeLine(r.Caption)
+	if TextLength(r.Caption) > MaxVenuePhotoCaptionLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Caption must be at most 200 characters",
+		}, false
+	}
+	return Base{}, true
+}
+
+// VenuePhotosResponse lists a venue's gallery with the plan of its owner
+type VenuePhotosResponse struct {
+	VenueID int64               `json:"venueId"`
+	Photos  []models.VenuePhoto `json:"photos"`
+	Plan    *services.OwnerPlan `json:"plan,omitempty"` // Owner-facing responses only
+}
+
+// VenuePhotoResponse is a photo added to a venue's gallery
+type VenuePhotoResponse struct {
+	Photo *models.VenuePhoto  `json:"photo"`
+	Plan  *services.OwnerPlan `json:"plan"`
+}
@@ -0,0 +1,72 @@
+package serializers
+
+import (
+	"regexp"
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxRatingDimensions caps the dimensions of a rating template
+const MaxRatingDimensions = 10
+
+var ratingDimensionKeyPattern = regexp.MustCompile(`^[a-z_]{1,30}$`)
+
+// RatingTemplateRequest for setting the rating template of a category
+type RatingTemplateRequest struct {
+	Dimensions []models.RatingDimension `json:"dimensions" binding:"required"`
+}
+
+// RatingTemplatesResponse for the rating templates API
+type RatingTemplatesResponse struct {
+	Templates []models.RatingTemplate `json:"templates"`
+}
+
+// Validate validates the RatingTemplateRequest, labels default to the key
+func (r *RatingTemplateRequest) Validate() (Base, bool) {
+	if len(r.Dimensions) == 0 || len(r.Dimensions) > MaxRatingDimensions {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A rating template must have 1-10 dimensions",
+		}, false
+	}
+
+	seen := make(map[string]bool, len(r.Dimensions))
+	for i := range r.Dimensions {
+		dimension := &r.Dimensions[i]
+		if !ratingDimensionKeyPattern.MatchString(dimension.Key) {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Dimension keys must be 1-30 lower case letters or underscores",
+			}, false
+		}
+		if seen[dimension.Key] {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Dimension keys must be unique",
+			}, false
+		}
+		seen[dimension.Key] = true
+
+		dimension.Label = strings.TrimSpace(dimension.Label)
+		if dimension.Label == "" {
+			dimension.Label = dimension.Key
+		}
+		if len(dimension.Label) > 50 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Dimension labels must be at most 50 characters",
+			}, false
+		}
+	}
+
+	return Base{}, true
+}
+
+// ToRatingTemplate converts RatingTemplateRequest to the category's
+// RatingTemplate model
+func (r *RatingTemplateRequest) ToRatingTemplate(categoryID int64) *models.RatingTemplate {
+	return &models.RatingTemplate{
+		CategoryID: categoryID,
+		Dimensions: r.Dimensions,
+	}
+}
@@ -0,0 +1,71 @@
+package serializers
+
+import (
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// RecommendationFeedbackRequest for answering a venue recommendation
+type RecommendationFeedbackRequest struct {
+	VenueID  int64  `json:"venueId" binding:"required"`
+	Feedback string `json:"feedback" binding:"required"` // not_interested, been_there
+}
+
+// Validate validates the RecommendationFeedbackRequest
+func (r *RecommendationFeedbackRequest) Validate() (Base, bool) {
+	if r.VenueID <= 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Valid venue ID is required",
+		}, false
+	}
+
+	if r.Feedback != models.RecommendationNotInterested && r.Feedback != models.RecommendationBeenThere {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Feedback must be not_interested or been_there",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// RecommendationsQuery holds the context of the recommendations asked for
+type RecommendationsQuery struct {
+	LocationQuery
+	Radius     float64 `form:"radius,default=10" binding:"gt=0,max=100"` // in km
+	Limit      int     `form:"limit,default=20" binding:"min=1,max=50"`
+	TimeOfDay  string  `form:"time_of_day" binding:"omitempty,oneof=morning afternoon evening night"`
+	Occasion   string  `form:"occasion" binding:"omitempty,oneof=casual date business celebration"`
+	GroupSize  int     `form:"group_size" binding:"omitempty,min=1,max=50"`
+	TravelMode string  `form:"travel_mode" binding:"omitempty,oneof=walking driving"`
+}
+
+// ToContext returns the recommendation context of the user
+func (q *RecommendationsQuery) ToContext(userID int64) services.RecommendationContext {
+	return services.RecommendationContext{
+		UserID:      userID,
+		UserLat:     q.Latitude,
+		UserLng:     q.Longitude,
+		TimeOfDay:   q.TimeOfDay,
+		Occasion:    q.Occasion,
+		GroupSize:   q.GroupSize,
+		MaxDistance: q.Radius,
+		Limit:       q.Limit,
+		TravelMode:  q.TravelMode,
+	}
+}
+
+// RecommendationsResponse for the recommendations API. Users in an
+// experiment get its key and the variant they were served, to tag the
+// events of the recommended venues with.
+type RecommendationsResponse struct {
+	Recommendations []services.RecommendationScore `json:"recommendations"`
+	Experiment      string                         `json:"experiment,omitempty"`
+	Variant         string                         `json:"variant,omitempty"`
+}
+
+// ExperimentReportQuery holds the period of an experiment report
+type ExperimentReportQuery struct {
+	Days int `form:"days,default=30" binding:"min=1,max=365"`
+}
@@ -0,0 +1,47 @@
+package serializers
+
+import "voting-app/app/models"
+
+// Review invite limits
+const (
+	MaxReviewInvitesPerRequest = 100
+	DefaultReviewInviteDays    = 30
+	MaxReviewInviteDays        = 365
+)
+
+// ReviewInviteRequest for generating review invites
+type ReviewInviteRequest struct {
+	Count         int `json:"count,omitempty"`         // Defaults to 1
+	ExpiresInDays int `json:"expiresInDays,omitempty"` // Defaults to 30
+}
+
+// ReviewInvitesResponse for the review invites API
+type ReviewInvitesResponse struct {
+	VenueID int64                 `json:"venueId"`
+	Invites []models.ReviewInvite `json:"invites"`
+}
+
+// Validate validates the ReviewInviteRequest
+func (r *ReviewInviteRequest) Validate() (Base, bool) {
+	if r.Count == 0 {
+		r.Count = 1
+	}
+	if r.Count < 1 || r.Count > MaxReviewInvitesPerRequest {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Count must be between 1 and 100",
+		}, false
+	}
+
+	if r.ExpiresInDays == 0 {
+		r.ExpiresInDays = DefaultReviewInviteDays
+	}
+	if r.ExpiresInDays < 1 || r.ExpiresInDays > MaxReviewInviteDays {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invites must expire within 1 to 365 days",
+		}, false
+	}
+
+	return Base{}, true
+}
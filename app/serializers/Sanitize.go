@@ -0,0 +1,142 @@
+package serializers
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSanitizePasses bounds how often markup is stripped again after decoding
+// entities, which can spell out new tags like &lt;script&gt;
+const maxSanitizePasses = 3
+
+// SanitizeLine cleans single line user text like names and titles: markup
+// is stripped, unicode normalized and all whitespace collapsed into single
+// spaces
+func SanitizeLine(text string) string {
+	return strings.Join(strings.Fields(sanitize(text)), " ")
+}
+
+// SanitizeText cleans multi-line user text like reviews and messages:
+// markup is stripped and unicode normalized, keeping line breaks but at
+// most one empty line between paragraphs
+func SanitizeText(text string) string {
+	lines := strings.Split(sanitize(text), "\n")
+	var kept []string
+	empty := 0
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			empty++
+			if empty > 1 || len(kept) == 0 {
+				continue
+			}
+		} else {
+			empty = 0
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// TextLength is the length of sanitized text in characters, which length
+// limits are given in
+func TextLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// sanitize strips markup, decodes entities and normalizes the text to NFC
+// without control, zero width or direction override characters
+func sanitize(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	for i := 0; i < maxSanitizePasses; i++ {
+		stripped := html.UnescapeString(stripTags(text))
+		if stripped == text {
+			break
+		}
+		text = stripped
+	}
+	// Whatever still looks like a tag after the last pass is dropped
+	text = stripTags(text)
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r), isInvisibleFormat(r):
+			return -1
+		}
+		return r
+	}, text)
+	return norm.NFC.String(text)
+}
+
+// isInvisibleFormat reports zero width and bidirectional formatting
+// characters, used to hide or reorder text
+func isInvisibleFormat(r rune) bool {
+	return (r >= 0x200B && r <= 0x200F) || (r >= 0x202A && r <= 0x202E) ||
+		(r >= 0x2066 && r <= 0x2069) || r == 0xFEFF || r == 0x00AD
+}
+
+// stripTags removes HTML tags, comments and the contents of script and
+// style elements. A '<' only starts a tag when followed by a letter, '/' or
+// '!', so text like "a < b" is kept.
+func stripTags(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] != '<' || i+1 == len(text) || !startsTag(text[i+1]) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(text[i:], "<!--") {
+			end := strings.Index(text[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := strings.IndexByte(text[i:], '>')
+		if end < 0 {
+			// An unclosed tag swallows the rest of the text
+			break
+		}
+		name := tagName(text[i+1 : i+end])
+		i += end + 1
+
+		// Scripts and styles are dropped with their contents
+		if name == "script" || name == "style" {
+			closing := strings.Index(strings.ToLower(text[i:]), "</"+name)
+			if closing < 0 {
+				break
+			}
+			i += closing
+		}
+	}
+	return out.String()
+}
+
+func startsTag(c byte) bool {
+	return c == '/' || c == '!' || c == '?' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// tagName returns the lowercase name of an opening tag, empty for closing
+// tags and declarations
+func tagName(tag string) string {
+	end := strings.IndexFunc(tag, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if end < 0 {
+		end = len(tag)
+	}
+	return strings.ToLower(tag[:end])
+}
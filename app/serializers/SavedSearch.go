@@ -0,0 +1,128 @@
+package serializers
+
+import (
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxSavedSearchesPerUser caps the number of searches a user can save
+const MaxSavedSearchesPerUser = 20
+
+// SavedSearchRequest for saving a venue search
+type SavedSearchRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Query         string   `json:"query,omitempty"`
+	CategoryID    *int64   `json:"categoryId,omitempty"`
+	SubcategoryID *int64   `json:"subcategoryId,omitempty"`
+	CityID        *int64   `json:"cityId,omitempty"`
+	Latitude      *float64 `json:"latitude,omitempty"`
+	Longitude     *float64 `json:"longitude,omitempty"`
+	Radius        *float64 `json:"radius,omitempty"` // in km, defaults to 10 with a location
+	PriceRange    []string `json:"priceRange,omitempty"`
+	MinRating     *float64 `json:"minRating,omitempty"`
+	Amenities     []string `json:"amenities,omitempty"`
+	AlertsEnabled *bool    `json:"alertsEnabled,omitempty"`
+}
+
+// SavedSearchListResponse for the saved searches API
+type SavedSearchListResponse struct {
+	SavedSearches   []models.SavedSearch `json:"savedSearches"`
+	TotalNewMatches int                  `json:"totalNewMatches"`
+}
+
+// SavedSearchMatchesResponse for the saved search matches API
+type SavedSearchMatchesResponse struct {
+	SavedSearch models.SavedSearch        `json:"savedSearch"`
+	Matches     []models.SavedSearchMatch `json:"matches"`
+}
+
+// Validate validates the SavedSearchRequest
+func (r *SavedSearchRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name is required and must be at most 100 characters",
+		}, false
+	}
+
+	r.Query = strings.TrimSpace(r.Query)
+	if len(r.Query) > 200 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Query must be at most 200 characters",
+		}, false
+	}
+
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		return Base{
+			Code:    InvalidLocation,
+			Message: "Latitude and longitude must be provided together",
+		}, false
+	}
+
+	if r.Latitude != nil {
+		if *r.Latitude < -90 || *r.Latitude > 90 || *r.Longitude < -180 || *r.Longitude > 180 {
+			return Base{
+				Code:    InvalidLocation,
+				Message: "Coordinates are out of range",
+			}, false
+		}
+
+		if r.Radius == nil {
+			defaultRadius := 10.0
+			r.Radius = &defaultRadius
+		}
+		if *r.Radius <= 0 || *r.Radius > 100 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Radius must be between 0 and 100 km",
+			}, false
+		}
+	} else {
+		r.Radius = nil
+	}
+
+	if r.MinRating != nil && (*r.MinRating < 1 || *r.MinRating > 5) {
+		return Base{
+			Code:    InvalidRating,
+			Message: "Minimum rating must be between 1.0 and 5.0",
+		}, false
+	}
+
+	if r.Query == "" && r.CategoryID == nil && r.SubcategoryID == nil && r.CityID == nil &&
+		r.Latitude == nil && len(r.PriceRange) == 0 && r.MinRating == nil && len(r.Amenities) == 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A saved search needs a query or at least one filter",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToSavedSearch converts SavedSearchRequest to SavedSearch model
+func (r *SavedSearchRequest) ToSavedSearch(userID int64) *models.SavedSearch {
+	alertsEnabled := true
+	if r.AlertsEnabled != nil {
+		alertsEnabled = *r.AlertsEnabled
+	}
+
+	return &models.SavedSearch{
+		UserID:        userID,
+		Name:          r.Name,
+		AlertsEnabled: alertsEnabled,
+		Params: models.VenueSearchParams{
+			Query:         r.Query,
+			CategoryID:    r.CategoryID,
+			SubcategoryID: r.SubcategoryID,
+			CityID:        r.CityID,
+			Latitude:      r.Latitude,
+			Longitude:     r.Longitude,
+			Radius:        r.Radius,
+			PriceRange:    r.PriceRange,
+			MinRating:     r.MinRating,
+			Amenities:     r.Amenities,
+		},
+	}
+}
@@ -0,0 +1,21 @@
+package serializers
+
+import "voting-app/app/models"
+
+// SearchHistoryQuery holds the query parameters of a user's search history
+type SearchHistoryQuery struct {
+	Page  int `form:"page,default=1" binding:"min=1"`
+	Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// SearchHistoryResponse for the search history API
+type SearchHistoryResponse struct {
+	History              []models.SearchHistoryEntry `json:"history"`
+	SearchHistoryEnabled bool                        `json:"searchHistoryEnabled"`
+	Pagination           PaginationInfo              `json:"pagination"`
+}
+
+// PrivacySettingsRequest for changing a user's privacy settings
+type PrivacySettingsRequest struct {
+	SearchHistoryEnabled *bool `json:"searchHistoryEnabled" binding:"required"`
+}
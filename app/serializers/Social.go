@@ -0,0 +1,17 @@
+package serializers
+
+import "voting-app/app/models"
+
+// UserRelationsResponse lists the users a user blocked and muted
+type UserRelationsResponse struct {
+	Blocked []models.UserRelation `json:"blocked"`
+	Muted   []models.UserRelation `json:"muted"`
+}
+
+// SocialFeedResponse for the social feed API
+type SocialFeedResponse struct {
+	Activities []models.FeedActivity `json:"activities"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+	HasNext    bool                  `json:"hasNext"`
+}
@@ -0,0 +1,55 @@
+package serializers
+
+import (
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// SyncQuery holds the query parameters of a delta sync. Clients send the
+// time of their cached data as since on their first sync and the returned
+// cursor after.
+type SyncQuery struct {
+	Since  *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor string     `form:"cursor" binding:"max=200"`
+	CityID *int64     `form:"city" binding:"omitempty,gt=0"`
+	Limit  int        `form:"limit,default=100" binding:"min=1,max=500"`
+	Wait   int        `form:"wait" binding:"min=0,max=30"` // Seconds to wait for changes
+}
+
+// Position returns the position the query syncs from, the cursor's when
+// sent, else since, else the beginning
+func (q *SyncQuery) Position() (models.SyncPosition, error) {
+	if q.Cursor != "" {
+		return services.ParseSyncCursor(q.Cursor)
+	}
+	if q.Since != nil {
+		return models.SyncPosition{ChangedAt: q.Since.UTC()}, nil
+	}
+	return models.SyncPosition{}, nil
+}
+
+// SyncResponse holds the venues, reviews and campaigns that changed, and
+// the ones to drop
+type SyncResponse struct {
+	Venues    []models.Venue          `json:"venues"`
+	Reviews   []models.VenueReview    `json:"reviews"`
+	Campaigns []models.VotingCampaign `json:"campaigns"`
+	Deleted   []models.SyncTombstone  `json:"deleted"`
+	// Cursor continues with the next page when HasMore is set, else it is
+	// sent on the next sync
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"hasMore"`
+}
+
+// NewSyncResponse creates the response of a page of changes
+func NewSyncResponse(page *services.SyncPage) SyncResponse {
+	return SyncResponse{
+		Venues:    page.Venues,
+		Reviews:   page.Reviews,
+		Campaigns: page.Campaigns,
+		Deleted:   page.Deleted,
+		Cursor:    page.Cursor,
+		HasMore:   page.HasMore,
+	}
+}
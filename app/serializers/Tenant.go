@@ -0,0 +1,124 @@
+package serializers
+
+import (
+	"regexp"
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxTenantHostnames caps the hostnames a tenant can claim
+const MaxTenantHostnames = 20
+
+var (
+	tenantSlugPattern  = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+	tenantColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// TenantRequest for creating or updating a tenant. The slug is only read on
+// create.
+type TenantRequest struct {
+	Slug          string                `json:"slug"`
+	Name          string                `json:"name" binding:"required"`
+	Hostnames     []string              `json:"hostnames"`
+	Branding      models.TenantBranding `json:"branding"`
+	DefaultCityID *int64                `json:"defaultCityId"`
+	IsActive      *bool                 `json:"isActive"` // Defaults to true
+}
+
+// TenantConfigResponse is the public configuration of the request's tenant
+// clients brand themselves with
+type TenantConfigResponse struct {
+	Slug          string                `json:"slug"`
+	Name          string                `json:"name"`
+	Branding      models.TenantBranding `json:"branding"`
+	DefaultCityID *int64                `json:"defaultCityId,omitempty"`
+}
+
+// ValidSlug tells whether the slug is made of lower case letters, digits and
+// dashes
+func (r *TenantRequest) ValidSlug() (Base, bool) {
+	r.Slug = strings.TrimSpace(r.Slug)
+	if !tenantSlugPattern.MatchString(r.Slug) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Slug must be 1-50 lower case letters, digits or dashes",
+		}, false
+	}
+	return Base{}, true
+}
+
+// Validate validates the TenantRequest
+func (r *TenantRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Name must be 1-100 characters",
+		}, false
+	}
+
+	if len(r.Hostnames) > MaxTenantHostnames {
+		return Base{
+			Code:    InvalidInput,
+			Message: "A tenant can claim at most 20 hostnames",
+		}, false
+	}
+	seen := make(map[string]bool)
+	hostnames := make([]string, 0, len(r.Hostnames))
+	for _, hostname := range r.Hostnames {
+		hostname = strings.ToLower(strings.TrimSpace(hostname))
+		if hostname == "" || len(hostname) > 253 || strings.ContainsAny(hostname, " /:") {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Hostnames must be bare host names like vote.example.com",
+			}, false
+		}
+		if !seen[hostname] {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	r.Hostnames = hostnames
+
+	if r.Branding.PrimaryColor != "" && !tenantColorPattern.MatchString(r.Branding.PrimaryColor) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Primary color must look like #1a2b3c",
+		}, false
+	}
+	if r.Branding.SupportEmail != "" && !strings.Contains(r.Branding.SupportEmail, "@") {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Support email must be an email address",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToTenant converts TenantRequest to Tenant model
+func (r *TenantRequest) ToTenant(id int64) *models.Tenant {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+	return &models.Tenant{
+		ID:            id,
+		Slug:          r.Slug,
+		Name:          r.Name,
+		Hostnames:     r.Hostnames,
+		Branding:      r.Branding,
+		DefaultCityID: r.DefaultCityID,
+		IsActive:      isActive,
+	}
+}
+
+// NewTenantConfigResponse returns the public configuration of the tenant
+func NewTenantConfigResponse(tenant *models.Tenant) TenantConfigResponse {
+	return TenantConfigResponse{
+		Slug:          tenant.Slug,
+		Name:          tenant.Name,
+		Branding:      tenant.Branding,
+		DefaultCityID: tenant.DefaultCityID,
+	}
+}
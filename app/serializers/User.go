@@ -1,5 +1,10 @@
 package serializers
 
+import (
+	"time"
+	"voting-app/app/models"
+)
+
 type UserRequest struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -7,6 +12,43 @@ type UserRequest struct {
 type ResetPassword struct {
 	Password string `json:"password" binding:"required"`
 }
+
+// EmailRequest names the account a verification or password reset email is
+// sent to
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
 type UserJWT struct {
 	Access string `json:"access"`
 }
+
+// LinkSnappRequest for linking the user to a snapp user. The code is only
+// sent when verifying the link.
+type LinkSnappRequest struct {
+	SnappId string `json:"snappId" binding:"required"`
+	Code    string `json:"code"`
+}
+
+// LinkSnappResponse for a started link, the code is sent to the snapp user
+type LinkSnappResponse struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// IdentityResponse for the identity API, with the legacy votes of the
+// linked snapp user
+type IdentityResponse struct {
+	models.Identity
+	Votes []models.VotingHistoryEntry `json:"votes"`
+}
+
+// Validate validates the new password
+func (r *ResetPassword) Validate() (Base, bool) {
+	// bcrypt only uses the first 72 bytes
+	if len(r.Password) < 8 || len(r.Password) > 72 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Password must be between 8 and 72 characters",
+		}, false
+	}
+	return Base{}, true
+}
@@ -0,0 +1,202 @@
+package serializers
+
+import (
+	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// MaxMeetingParticipants caps the number of locations of a meeting point request
+const MaxMeetingParticipants = 10
+
+// MaxDistanceVenues caps the number of venues of a distances request
+const MaxDistanceVenues = 200
+
+// MeetingParticipant is a participant location of a meeting point request
+type MeetingParticipant struct {
+	Name      string  `json:"name,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// MeetingPreferences narrows down the candidate venues
+type MeetingPreferences struct {
+	CategoryID *int64   `json:"categoryId,omitempty"`
+	MinRating  *float64 `json:"minRating,omitempty"`
+	PriceRange string   `json:"priceRange,omitempty"`
+	Limit      int      `json:"limit,omitempty"` // Candidate venues to return (default 10, max 50)
+}
+
+// MeetingPointRequest for finding a venue in the middle of a group
+type MeetingPointRequest struct {
+	Participants []MeetingParticipant `json:"participants" binding:"required"`
+	Preferences  MeetingPreferences   `json:"preferences"`
+}
+
+// ParticipantDistance is the distance of a participant to the centroid
+type ParticipantDistance struct {
+	Name       string  `json:"name,omitempty"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// MeetingPointResponse for the meeting point API
+type MeetingPointResponse struct {
+	Centroid     *services.LocationResult `json:"centroid"`
+	Participants []ParticipantDistance    `json:"participants"`
+	Venues       []services.MeetingVenue  `json:"venues"`
+}
+
+// AutocompleteResponse for the autocomplete API
+type AutocompleteResponse struct {
+	Query       string                            `json:"query"`
+	Suggestions []services.AutocompleteSuggestion `json:"suggestions"`
+}
+
+// DistancesRequest for annotating a list of venues with distances
+type DistancesRequest struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+	VenueIDs  []int64 `json:"venueIds" binding:"required"`
+}
+
+// DistancesResponse for the distances API
+type DistancesResponse struct {
+	Distances []services.VenueDistance `json:"distances"`
+	NotFound  []int64                  `json:"notFound"` // Unknown or inactive venue IDs
+}
+
+// Validate validates the MeetingPointRequest
+func (r *MeetingPointRequest) Validate() (Base, bool) {
+	if len(r.Participants) < 2 || len(r.Participants) > MaxMeetingParticipants {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Between 2 and 10 participant locations are required",
+		}, false
+	}
+
+	for _, participant := range r.Participants {
+		if participant.Latitude < -90 || participant.Latitude > 90 ||
+			participant.Longitude < -180 || participant.Longitude > 180 {
+			return Base{
+				Code:    InvalidLocation,
+				Message: "Participant coordinates are out of range",
+			}, false
+		}
+	}
+
+	if r.Preferences.MinRating != nil && (*r.Preferences.MinRating < 1 || *r.Preferences.MinRating > 5) {
+		return Base{
+			Code:    InvalidRating,
+			Message: "Minimum rating must be between 1.0 and 5.0",
+		}, false
+	}
+
+	if r.Preferences.PriceRange != "" && !models.PriceRanges.Contains(r.Preferences.PriceRange) {
+		return priceRangeError(), false
+	}
+
+	if r.Preferences.Limit <= 0 {
+		r.Preferences.Limit = 10
+	}
+	if r.Preferences.Limit > 50 {
+		r.Preferences.Limit = 50
+	}
+
+	return Base{}, true
+}
+
+// Locations returns the participant coordinates in request order
+func (r *MeetingPointRequest) Locations() []services.LatLng {
+	locations := make([]services.LatLng, len(r.Participants))
+	for i, participant := range r.Participants {
+		locations[i] = services.LatLng{
+			Latitude:  participant.Latitude,
+			Longitude: participant.Longitude,
+		}
+	}
+	return locations
+}
+
+// PreferenceFilters converts the preferences to GeolocationService filters
+func (r *MeetingPointRequest) PreferenceFilters() map[string]interface{} {
+	filters := make(map[string]interface{})
+	if r.Preferences.CategoryID != nil {
+		filters["category_id"] = *r.Preferences.CategoryID
+	}
+	if r.Preferences.MinRating != nil {
+		filters["min_rating"] = *r.Preferences.MinRating
+	}
+	if r.Preferences.PriceRange != "" {
+		filters["price_range"] = r.Preferences.PriceRange
+	}
+	return filters
+}
+
+// Validate validates the DistancesRequest, dropping duplicate venue IDs
+func (r *DistancesRequest) Validate() (Base, bool) {
+	if r.Latitude < -90 || r.Latitude > 90 || r.Longitude < -180 || r.Longitude > 180 {
+		return Base{
+			Code:    InvalidLocation,
+			Message: "Coordinates are out of range",
+		}, false
+	}
+
+	seen := make(map[int64]bool, len(r.VenueIDs))
+	venueIDs := make([]int64, 0, len(r.VenueIDs))
+	for _, venueID := range r.VenueIDs {
+		if venueID <= 0 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Venue IDs must be positive",
+			}, false
+		}
+		if !seen[venueID] {
+			seen[venueID] = true
+			venueIDs = append(venueIDs, venueID)
+		}
+	}
+	r.VenueIDs = venueIDs
+
+	if len(r.VenueIDs) == 0 || len(r.VenueIDs) > MaxDistanceVenues {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Between 1 and 200 venue IDs are required",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ReferenceData lists the values of every enumeration the API accepts, with
+// their labels
+type ReferenceData struct {
+	VisitTypes    models.ReferenceEnum `json:"visitTypes"`
+	PriceRanges   models.ReferenceEnum `json:"priceRanges"`
+	Amenities     models.ReferenceEnum `json:"amenities"`
+	CampaignTypes models.ReferenceEnum `json:"campaignTypes"`
+}
+
+// NewReferenceData collects the reference data of the models
+func NewReferenceData() ReferenceData {
+	return ReferenceData{
+		VisitTypes:    models.VisitTypes,
+		PriceRanges:   models.PriceRanges,
+		Amenities:     models.Amenities,
+		CampaignTypes: models.CampaignTypes,
+	}
+}
+
+// Readiness statuses
+const (
+	ReadinessReady       = "ready"
+	ReadinessDegraded    = "degraded" // An external dependency is skipped, with fallbacks
+	ReadinessUnavailable = "unavailable"
+)
+
+// ReadinessResponse for the readiness probe
+type ReadinessResponse struct {
+	Status   string                   `json:"status"`
+	Database string                   `json:"database"` // ok or unreachable
+	Breakers []services.BreakerStatus `json:"breakers"`
+}
@@ -0,0 +1,112 @@
+package serializers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"voting-app/app/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// init sets up the validator behind gin's binding tags, shared by all
+// request structs
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Name fields in errors after their query or JSON parameter
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"form", "json"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	for tag, list := range enumListTags {
+		validate.RegisterValidation(tag, isEnumList(list.enum))
+	}
+	validate.RegisterValidation("campaign_type", isEnum(models.CampaignTypes))
+}
+
+// enumListTags are the binding tags validating comma separated reference
+// data, with the enum they check and its name in error messages
+var enumListTags = map[string]struct {
+	enum models.ReferenceEnum
+	name string
+}{
+	"price_ranges": {models.PriceRanges, "price ranges"},
+	"amenities":    {models.Amenities, "amenities"},
+}
+
+// isEnum validates a value of the enum
+func isEnum(enum models.ReferenceEnum) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return enum.Contains(fl.Field().String())
+	}
+}
+
+// isEnumList validates a comma separated list of values of the enum
+func isEnumList(enum models.ReferenceEnum) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		_, unknown := unknownEnumValue(enum, strings.Split(fl.Field().String(), ","))
+		return !unknown
+	}
+}
+
+// unknownEnumValue returns the first of the values outside the enum
+func unknownEnumValue(enum models.ReferenceEnum, values []string) (string, bool) {
+	for _, value := range values {
+		if !enum.Contains(value) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// BindingError describes why binding a request failed
+func BindingError(err error) Base {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) || len(validationErrors) == 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid request parameters",
+		}
+	}
+
+	fieldError := validationErrors[0]
+	field := fieldError.Field()
+
+	var message string
+	switch fieldError.Tag() {
+	case "required":
+		message = fmt.Sprintf("%s is required", field)
+	case "min", "gte":
+		message = fmt.Sprintf("%s must be at least %s", field, fieldError.Param())
+	case "max", "lte":
+		message = fmt.Sprintf("%s must be at most %s", field, fieldError.Param())
+	case "gt":
+		message = fmt.Sprintf("%s must be greater than %s", field, fieldError.Param())
+	case "oneof":
+		message = fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	case "price_ranges", "amenities":
+		list := enumListTags[fieldError.Tag()]
+		message = fmt.Sprintf("%s must be comma separated %s: %s", field, list.name, strings.Join(list.enum.Values(), ", "))
+	case "campaign_type":
+		message = fmt.Sprintf("%s must be one of: %s", field, strings.Join(models.CampaignTypes.Values(), ", "))
+	default:
+		message = fmt.Sprintf("Invalid %s", field)
+	}
+
+	return Base{
+		Code:    InvalidInput,
+		Message: message,
+	}
+}
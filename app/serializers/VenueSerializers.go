@@ -2,9 +2,20 @@ package serializers
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 	"voting-app/app/models"
+	"voting-app/app/services"
+)
+
+// Review limits, text lengths are in characters
+const (
+	MaxReviewTitleLength     = 255
+	MaxReviewTextLength      = 20000
+	MaxReviewDraftTextLength = 20000
+	MaxPhotoCaptionLength    = 300
+	MaxCheckinMessageLength  = 1000
 )
 
 // VenueSearchResponse for venue search API
@@ -14,6 +25,7 @@ type VenueSearchResponse struct {
 	SearchParams models.VenueSearchParams `json:"searchParams"`
 	Suggestions  []string                 `json:"suggestions,omitempty"` // Search suggestions
 	Filters      VenueFilterOptions       `json:"filters"`               // Available filter options
+	MenuMatches  []models.MenuItemMatch   `json:"menuMatches,omitempty"` // Dishes matching the query
 }
 
 // VenueDetailResponse for detailed venue information
@@ -21,17 +33,344 @@ type VenueDetailResponse struct {
 	Venue         models.Venue          `json:"venue"`
 	ReviewSummary *models.ReviewSummary `json:"reviewSummary"`
 	// Events        []models.VenueEvent   `json:"events"`
-	SimilarVenues []models.Venue `json:"similarVenues,omitempty"`
-	CheckinCount  int            `json:"checkinCount,omitempty"`
+	SimilarVenues  *services.SimilarVenues `json:"similarVenues,omitempty"`
+	CheckinCount   int                     `json:"checkinCount,omitempty"`
+	RatingTemplate *models.RatingTemplate  `json:"ratingTemplate,omitempty"` // Unset when reviews can rate any dimension
+	// ExternalRatings are the venue's ratings on other platforms, apart from
+	// its own reviews and rating
+	ExternalRatings []models.ExternalRating `json:"externalRatings,omitempty"`
+	Redirect        *SlugRedirect           `json:"redirect,omitempty"`
+}
+
+// SlugRedirect tells clients that looked a venue up by a slug it no longer
+// uses to link to its current slug instead
+type SlugRedirect struct {
+	Status int    `json:"status"` // Always 301, the old slug moved permanently
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// WatchlistRequest adds a competitor to a venue's watchlist
+type WatchlistRequest struct {
+	CompetitorVenueID int64 `json:"competitorVenueId" binding:"required,min=1"`
+}
+
+// WatchlistResponse lists the competitors a venue watches
+type WatchlistResponse struct {
+	VenueID        int64                        `json:"venueId"`
+	Competitors    []models.WatchlistCompetitor `json:"competitors"`
+	MaxCompetitors int                          `json:"maxCompetitors"`
+}
+
+// RenameVenueRequest for renaming a venue
+type RenameVenueRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// Validate validates the new venue name
+func (r *RenameVenueRequest) Validate() (Base, bool) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 255 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Venue name must be 1-255 characters",
+		}, false
+	}
+	return Base{}, true
+}
+
+// Slug returns the slug generated from the new name, before it is made
+// unique
+func (r *RenameVenueRequest) Slug() string {
+	return generateSlug(r.Name)
 }
 
 // VenueFilterOptions provides available filter options for search
 type VenueFilterOptions struct {
-	Categories    []models.VenueCategory    `json:"categories"`
-	Subcategories []models.VenueSubcategory `json:"subcategories"`
-	PriceRanges   []string                  `json:"priceRanges"`
-	Amenities     []string                  `json:"amenities"`
-	Cities        []models.City             `json:"cities"`
+	Categories    []models.VenueCategory     `json:"categories"`
+	Subcategories []models.VenueSubcategory  `json:"subcategories"`
+	PriceRanges   models.ReferenceEnum       `json:"priceRanges"`
+	Amenities     models.ReferenceEnum       `json:"amenities"`
+	Cities        []models.City              `json:"cities"`
+	Neighborhoods []models.NeighborhoodFacet `json:"neighborhoods,omitempty"` // Result counts per neighborhood
+}
+
+// VenueSearchQuery holds the query parameters of the venue search
+type VenueSearchQuery struct {
+	Query          string   `form:"q" binding:"max=200"`
+	CategoryID     *int64   `form:"category" binding:"omitempty,gt=0"`
+	SubcategoryID  *int64   `form:"subcategory" binding:"omitempty,gt=0"`
+	CityID         *int64   `form:"city" binding:"omitempty,gt=0"`
+	NeighborhoodID *int64   `form:"neighborhood" binding:"omitempty,gt=0"`
+	Latitude       *float64 `form:"lat" binding:"omitempty,min=-90,max=90"`
+	Longitude      *float64 `form:"lng" binding:"omitempty,min=-180,max=180"`
+	Radius         *float64 `form:"radius" binding:"omitempty,gt=0,max=100"` // in km, defaults to 10 with a location
+	PriceRange     string   `form:"price_range" binding:"omitempty,price_ranges"`
+	MinRating      *float64 `form:"min_rating" binding:"omitempty,min=1,max=5"`
+	Amenities      string   `form:"amenities" binding:"omitempty,amenities"`
+	IsOpen         *bool    `form:"is_open"`
+	IsFeatured     *bool    `form:"is_featured"`
+	SortBy         string   `form:"sort_by,default=rating" binding:"oneof=rating distance popularity newest"`
+	Page           int      `form:"page,default=1" binding:"min=1"`
+	Limit          int      `form:"limit,default=20" binding:"min=1,max=100"`
+	EstimateTotal  bool     `form:"estimate_total"` // Estimates the total of broad searches
+}
+
+// LocationQuery holds the client's coordinates. Without them the location
+// of the client's IP address is used.
+type LocationQuery struct {
+	Latitude  *float64 `form:"lat" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `form:"lng" binding:"omitempty,min=-180,max=180"`
+}
+
+// Validate validates the LocationQuery
+func (q *LocationQuery) Validate() (Base, bool) {
+	if (q.Latitude == nil) != (q.Longitude == nil) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "lat and lng must be sent together",
+		}, false
+	}
+	return Base{}, true
+}
+
+// NearbyQuery holds the query parameters of the nearby venues search
+type NearbyQuery struct {
+	LocationQuery
+	Radius float64 `form:"radius,default=5" binding:"gt=0,max=100"` // in km
+	Limit  int     `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// NearbyVenuesQuery holds the nearby venues search, ordered by trip time
+// with a travel mode
+type NearbyVenuesQuery struct {
+	NearbyQuery
+	TravelMode string `form:"travel_mode" binding:"omitempty,oneof=walking driving"`
+}
+
+// TrendingQuery holds the location and filters of the trending venues.
+// Venues of a city aren't filtered by location unless coordinates are sent.
+type TrendingQuery struct {
+	NearbyQuery
+	CityID     int64 `form:"city" binding:"min=0"`
+	CategoryID int64 `form:"category" binding:"min=0"`
+}
+
+// TrendingVenuesResponse for the venues trending near the client or in a
+// city. ComputedAt is when the cached trending lists were computed.
+type TrendingVenuesResponse struct {
+	Venues         []models.Venue `json:"venues"`
+	LocationSource string         `json:"locationSource,omitempty"` // gps or ip
+	ComputedAt     time.Time      `json:"computedAt"`
+}
+
+// DefaultCityResponse for the city selected for the client. LocationSource
+// is empty when the client couldn't be located and the tenant's default city
+// was selected.
+type DefaultCityResponse struct {
+	City           models.City `json:"city"`
+	LocationSource string      `json:"locationSource,omitempty"` // gps or ip
+}
+
+// MapVenuesQuery holds the bounding box and filters of the map venues
+type MapVenuesQuery struct {
+	SouthWestLat *float64 `form:"sw_lat" binding:"required,min=-90,max=90"`
+	SouthWestLng *float64 `form:"sw_lng" binding:"required,min=-180,max=180"`
+	NorthEastLat *float64 `form:"ne_lat" binding:"required,min=-90,max=90"`
+	NorthEastLng *float64 `form:"ne_lng" binding:"required,min=-180,max=180"`
+	CategoryID   int64    `form:"category_id" binding:"omitempty,min=1"`
+	MinRating    float64  `form:"min_rating" binding:"omitempty,min=0,max=5"`
+	Limit        int      `form:"limit,default=2000" binding:"min=1,max=10000"`
+}
+
+// Validate checks the south-west corner of the box is below and left of
+// the north-east one
+func (q *MapVenuesQuery) Validate() (Base, bool) {
+	if *q.SouthWestLat > *q.NorthEastLat || *q.SouthWestLng > *q.NorthEastLng {
+		return Base{
+			Code:    InvalidInput,
+			Message: "The south-west corner must be below and left of the north-east corner",
+		}, false
+	}
+	return Base{}, true
+}
+
+// MapVenue is a venue pin on the map
+type MapVenue struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	Slug          string  `json:"slug"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	CategoryID    int64   `json:"categoryId"`
+	AverageRating float64 `json:"averageRating"`
+	TotalRatings  int     `json:"totalRatings"`
+	CoverImage    string  `json:"coverImage,omitempty"`
+}
+
+// MapVenuesResponse for the map venues. It is streamed, Count follows the
+// venues.
+type MapVenuesResponse struct {
+	Venues []MapVenue `json:"venues"`
+	Count  int        `json:"count"`
+}
+
+// NewMapVenue returns the map pin of the venue
+func NewMapVenue(venue *models.Venue) MapVenue {
+	return MapVenue{
+		ID:            venue.ID,
+		Name:          venue.Name,
+		Slug:          venue.Slug,
+		Latitude:      venue.Latitude,
+		Longitude:     venue.Longitude,
+		CategoryID:    venue.CategoryID,
+		AverageRating: venue.AverageRating,
+		TotalRatings:  venue.TotalRatings,
+		CoverImage:    venue.CoverImage,
+	}
+}
+
+// VenueCheckinsQuery holds the query parameters of a venue's check-ins
+type VenueCheckinsQuery struct {
+	Viewer string `form:"viewer"` // Snapp ID, hides check-ins of users they blocked or muted
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// VenueCheckinsResponse for the check-ins of a venue
+type VenueCheckinsResponse struct {
+	Checkins   []models.VenueCheckin `json:"checkins"`
+	Pagination PaginationInfo        `json:"pagination"`
+}
+
+// MaxReportedWaitMinutes is the longest wait time a check-in can report
+const MaxReportedWaitMinutes = 240
+
+// CreateCheckinRequest for checking in at a venue, optionally reporting the
+// current wait time and crowd level
+type CreateCheckinRequest struct {
+	VenueID     int64    `json:"venueId" binding:"required"`
+	Message     string   `json:"message,omitempty"`
+	Rating      *float64 `json:"rating,omitempty"`
+	IsPublic    *bool    `json:"isPublic,omitempty"` // Defaults to true
+	WaitMinutes *int     `json:"waitMinutes,omitempty"`
+	CrowdLevel  string   `json:"crowdLevel,omitempty"` // quiet, moderate, busy or packed
+}
+
+// Validate validates the check-in and its wait report
+func (r *CreateCheckinRequest) Validate() (Base, bool) {
+	r.Message = SanitizeText(r.Message)
+	if TextLength(r.Message) > MaxCheckinMessageLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Check-in message must be at most 1000 characters",
+		}, false
+	}
+
+	if r.Rating != nil && (*r.Rating < 1 || *r.Rating > 5) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Rating must be between 1 and 5",
+		}, false
+	}
+
+	if r.WaitMinutes != nil && (*r.WaitMinutes < 0 || *r.WaitMinutes > MaxReportedWaitMinutes) {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("Wait time must be between 0 and %d minutes", MaxReportedWaitMinutes),
+		}, false
+	}
+
+	if r.CrowdLevel != "" {
+		isValid := false
+		for _, level := range models.CrowdLevels {
+			if r.CrowdLevel == level {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Crowd level must be one of: quiet, moderate, busy, packed",
+			}, false
+		}
+	}
+
+	return Base{}, true
+}
+
+// ToCheckin converts the request to the user's check-in
+func (r *CreateCheckinRequest) ToCheckin(userID int64) *models.VenueCheckin {
+	checkin := &models.VenueCheckin{
+		VenueID:  r.VenueID,
+		UserID:   userID,
+		Message:  r.Message,
+		Rating:   r.Rating,
+		IsPublic: r.IsPublic == nil || *r.IsPublic,
+	}
+	if r.WaitMinutes != nil || r.CrowdLevel != "" {
+		checkin.WaitReport = &models.VenueWaitReport{
+			WaitMinutes: r.WaitMinutes,
+			CrowdLevel:  r.CrowdLevel,
+		}
+	}
+	return checkin
+}
+
+// Validate validates the VenueSearchQuery
+func (q *VenueSearchQuery) Validate() (Base, bool) {
+	if (q.Latitude == nil) != (q.Longitude == nil) {
+		return Base{
+			Code:    InvalidLocation,
+			Message: "Latitude and longitude must be provided together",
+		}, false
+	}
+
+	if q.Radius != nil && q.Latitude == nil {
+		return Base{
+			Code:    InvalidLocation,
+			Message: "Radius requires a location",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ToSearchParams converts VenueSearchQuery to the venue search parameters
+func (q *VenueSearchQuery) ToSearchParams() models.VenueSearchParams {
+	params := models.VenueSearchParams{
+		Query:          q.Query,
+		CategoryID:     q.CategoryID,
+		SubcategoryID:  q.SubcategoryID,
+		CityID:         q.CityID,
+		NeighborhoodID: q.NeighborhoodID,
+		Latitude:       q.Latitude,
+		Longitude:      q.Longitude,
+		Radius:         q.Radius,
+		MinRating:      q.MinRating,
+		IsOpen:         q.IsOpen,
+		IsFeatured:     q.IsFeatured,
+		SortBy:         q.SortBy,
+		Page:           q.Page,
+		Limit:          q.Limit,
+		EstimateTotal:  q.EstimateTotal,
+	}
+
+	if q.PriceRange != "" {
+		params.PriceRange = strings.Split(q.PriceRange, ",")
+	}
+	if q.Amenities != "" {
+		params.Amenities = strings.Split(q.Amenities, ",")
+	}
+
+	// Default radius of 10km for location searches
+	if params.Latitude != nil && params.Radius == nil {
+		defaultRadius := 10.0
+		params.Radius = &defaultRadius
+	}
+
+	return params
 }
 
 // CreateVenueRequest for creating new venues
@@ -40,7 +379,7 @@ type CreateVenueRequest struct {
 	Description      string          `json:"description,omitempty"`
 	ShortDescription string          `json:"shortDescription,omitempty"`
 	Address          string          `json:"address" binding:"required"`
-	CityID           int64           `json:"cityId" binding:"required"`
+	CityID           int64           `json:"cityId,omitempty"` // The nearest city is assigned when left out
 	Latitude         float64         `json:"latitude" binding:"required,min=-90,max=90"`
 	Longitude        float64         `json:"longitude" binding:"required,min=-180,max=180"`
 	PostalCode       string          `json:"postalCode,omitempty"`
@@ -74,6 +413,96 @@ type UpdateVenueRequest struct {
 	Amenities        []string        `json:"amenities,omitempty"`
 }
 
+// Validate validates the fields being changed
+func (r *UpdateVenueRequest) Validate() (Base, bool) {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		if name == "" || len(name) > 255 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Venue name must be 1-255 characters",
+			}, false
+		}
+		r.Name = &name
+	}
+
+	if r.Address != nil && strings.TrimSpace(*r.Address) == "" {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Venue address cannot be empty",
+		}, false
+	}
+
+	if r.PriceRange != nil && *r.PriceRange != "" && !models.PriceRanges.Contains(*r.PriceRange) {
+		return priceRangeError(), false
+	}
+
+	if base, isValid := validateAmenities(r.Amenities); !isValid {
+		return base, false
+	}
+
+	if r.AvgCostPerPerson != nil && *r.AvgCostPerPerson < 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Average cost per person cannot be negative",
+		}, false
+	}
+
+	return Base{}, true
+}
+
+// ApplyTo copies the fields being changed onto the venue. A new name also
+// sets the venue's slug to the one generated from it, before it is made
+// unique.
+func (r *UpdateVenueRequest) ApplyTo(venue *models.Venue) {
+	if r.Name != nil && *r.Name != venue.Name {
+		venue.Name = *r.Name
+		venue.Slug = generateSlug(*r.Name)
+	}
+	if r.Description != nil {
+		venue.Description = *r.Description
+	}
+	if r.ShortDescription != nil {
+		venue.ShortDesc = *r.ShortDescription
+	}
+	if r.Address != nil {
+		venue.Address = strings.TrimSpace(*r.Address)
+	}
+	if r.Phone != nil {
+		venue.Phone = *r.Phone
+	}
+	if r.Email != nil {
+		venue.Email = *r.Email
+	}
+	if r.Website != nil {
+		venue.Website = *r.Website
+	}
+	if len(r.OpeningHours) > 0 {
+		venue.OpeningHours = r.OpeningHours
+	}
+	if r.PriceRange != nil {
+		venue.PriceRange = *r.PriceRange
+	}
+	if r.AvgCostPerPerson != nil {
+		venue.AvgCostPerPerson = *r.AvgCostPerPerson
+	}
+	if r.CoverImage != nil {
+		venue.CoverImage = *r.CoverImage
+	}
+	if r.Logo != nil {
+		venue.Logo = *r.Logo
+	}
+	if r.Amenities != nil {
+		amenitiesJSON, _ := json.Marshal(r.Amenities)
+		venue.Amenities = amenitiesJSON
+	}
+}
+
+// DeleteVenueQuery holds the query parameters of a venue deletion
+type DeleteVenueQuery struct {
+	Policy string `form:"policy,default=hide" binding:"oneof=hide cascade"`
+}
+
 // Validate validates the CreateVenueRequest
 func (r *CreateVenueRequest) Validate() (Base, bool) {
 	if strings.TrimSpace(r.Name) == "" {
@@ -90,10 +519,10 @@ func (r *CreateVenueRequest) Validate() (Base, bool) {
 		}, false
 	}
 
-	if r.CityID <= 0 {
+	if r.CityID < 0 {
 		return Base{
 			Code:    InvalidInput,
-			Message: "Valid city ID is required",
+			Message: "Invalid city ID",
 		}, false
 	}
 
@@ -119,21 +548,12 @@ func (r *CreateVenueRequest) Validate() (Base, bool) {
 	}
 
 	// Validate price range if provided
-	if r.PriceRange != "" {
-		validPriceRanges := []string{"$", "$$", "$$$", "$$$$"}
-		isValid := false
-		for _, valid := range validPriceRanges {
-			if r.PriceRange == valid {
-				isValid = true
-				break
-			}
-		}
-		if !isValid {
-			return Base{
-				Code:    InvalidInput,
-				Message: "Price range must be one of: $, $$, $$$, $$$$",
-			}, false
-		}
+	if r.PriceRange != "" && !models.PriceRanges.Contains(r.PriceRange) {
+		return priceRangeError(), false
+	}
+
+	if base, isValid := validateAmenities(r.Amenities); !isValid {
+		return base, false
 	}
 
 	return Base{}, true
@@ -183,6 +603,9 @@ type PaginationInfo struct {
 	TotalPages int  `json:"totalPages"`
 	HasNext    bool `json:"hasNext"`
 	HasPrev    bool `json:"hasPrev"`
+	// ExactTotal is set by searches that can estimate their total, false
+	// when Total and TotalPages are estimates
+	ExactTotal *bool `json:"exactTotal,omitempty"`
 }
 
 // ReviewSearchResponse for review search results
@@ -192,6 +615,56 @@ type ReviewSearchResponse struct {
 	Filters    models.ReviewFilters `json:"filters"`
 }
 
+// FeaturedReviewsQuery for the home feed's featured reviews
+type FeaturedReviewsQuery struct {
+	City *int64 `form:"city" binding:"omitempty,gt=0"`
+}
+
+// FeaturedReviewsResponse for the home feed's featured reviews
+type FeaturedReviewsResponse struct {
+	Reviews     []models.FeaturedReview `json:"reviews"`
+	CityID      *int64                  `json:"cityId,omitempty"`
+	GeneratedAt time.Time               `json:"generatedAt"`
+}
+
+// ReviewTranslationQuery for translating review listings
+type ReviewTranslationQuery struct {
+	TranslateTo string `form:"translate_to"`
+}
+
+// Validate validates the ReviewTranslationQuery language, a two letter
+// ISO 639-1 code
+func (q *ReviewTranslationQuery) Validate() (Base, bool) {
+	q.TranslateTo = strings.ToLower(strings.TrimSpace(q.TranslateTo))
+	if q.TranslateTo == "" {
+		return Base{}, true
+	}
+
+	if len(q.TranslateTo) != 2 || q.TranslateTo[0] < 'a' || q.TranslateTo[0] > 'z' ||
+		q.TranslateTo[1] < 'a' || q.TranslateTo[1] > 'z' {
+		return Base{
+			Code:    InvalidInput,
+			Message: "translate_to must be a two letter language code",
+		}, false
+	}
+	return Base{}, true
+}
+
+// ReviewExportQuery for exporting a venue's reviews
+type ReviewExportQuery struct {
+	Format string `form:"format,default=csv" binding:"oneof=csv json"`
+}
+
+// ReviewExportResponse for exports generated in the background
+type ReviewExportResponse struct {
+	Export      *models.ReviewExport `json:"export"`
+	StatusURL   string               `json:"statusUrl"`
+	DownloadURL string               `json:"downloadUrl,omitempty"` // Once the export is completed
+	// SignedURL downloads the completed export without authentication for
+	// a short while
+	SignedURL string `json:"signedUrl,omitempty"`
+}
+
 // CreateReviewRequest for creating new reviews
 type CreateReviewRequest struct {
 	VenueID         int64           `json:"venueId" binding:"required"`
@@ -203,6 +676,7 @@ type CreateReviewRequest struct {
 	VisitType       string          `json:"visitType,omitempty"`
 	PartySize       int             `json:"partySize,omitempty"`
 	Photos          []string        `json:"photos,omitempty"`
+	InviteToken     string          `json:"inviteToken,omitempty"` // From a venue review invite, marks the visit verified
 }
 
 // UpdateReviewRequest for updating reviews
@@ -217,6 +691,19 @@ type UpdateReviewRequest struct {
 	Photos          []string        `json:"photos,omitempty"`
 }
 
+// ReviewDraftRequest for autosaving an unfinished review. Every field is
+// optional, the draft is replaced as a whole on each save.
+type ReviewDraftRequest struct {
+	OverallRating   *float64        `json:"overallRating,omitempty"`
+	DetailedRatings json.RawMessage `json:"detailedRatings,omitempty"`
+	Title           string          `json:"title,omitempty"`
+	ReviewText      string          `json:"reviewText,omitempty"`
+	VisitDate       *time.Time      `json:"visitDate,omitempty"`
+	VisitType       string          `json:"visitType,omitempty"`
+	PartySize       int             `json:"partySize,omitempty"`
+	Photos          []string        `json:"photos,omitempty"`
+}
+
 // ReviewVoteRequest for voting on review helpfulness
 type ReviewVoteRequest struct {
 	IsHelpful bool `json:"isHelpful" binding:"required"`
@@ -239,21 +726,11 @@ func (r *CreateReviewRequest) Validate() (Base, bool) {
 	}
 
 	// Validate visit type if provided
-	if r.VisitType != "" {
-		validVisitTypes := []string{"breakfast", "lunch", "dinner", "drinks", "coffee", "event", "takeout"}
-		isValid := false
-		for _, valid := range validVisitTypes {
-			if r.VisitType == valid {
-				isValid = true
-				break
-			}
-		}
-		if !isValid {
-			return Base{
-				Code:    InvalidInput,
-				Message: "Invalid visit type",
-			}, false
-		}
+	if r.VisitType != "" && !models.VisitTypes.Contains(r.VisitType) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid visit type",
+		}, false
 	}
 
 	if r.PartySize < 0 || r.PartySize > 50 {
@@ -263,6 +740,68 @@ func (r *CreateReviewRequest) Validate() (Base, bool) {
 		}, false
 	}
 
+	if base, isValid := validateReviewPhotoCount(r.Photos); !isValid {
+		return base, false
+	}
+
+	r.Title = SanitizeLine(r.Title)
+	r.ReviewText = SanitizeText(r.ReviewText)
+	return validateReviewText(r.Title, r.ReviewText)
+}
+
+// validateReviewText checks the lengths of a sanitized review title and
+// text
+func validateReviewText(title, text string) (Base, bool) {
+	if TextLength(title) > MaxReviewTitleLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Title must be at most 255 characters",
+		}, false
+	}
+	if TextLength(text) > MaxReviewTextLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Review text must be at most 20000 characters",
+		}, false
+	}
+	return Base{}, true
+}
+
+// ValidateDetailedRatings checks that the detailed ratings are 1-5 ratings
+// of the dimensions of the venue's rating template, of any dimension when
+// template is nil
+func (r *CreateReviewRequest) ValidateDetailedRatings(template *models.RatingTemplate) (Base, bool) {
+	if len(r.DetailedRatings) == 0 || string(r.DetailedRatings) == "null" {
+		return Base{}, true
+	}
+
+	var ratings map[string]float64
+	if err := json.Unmarshal(r.DetailedRatings, &ratings); err != nil {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Detailed ratings must map dimensions to ratings",
+		}, false
+	}
+
+	for key, rating := range ratings {
+		if template != nil && !template.HasDimension(key) {
+			keys := make([]string, len(template.Dimensions))
+			for i, dimension := range template.Dimensions {
+				keys[i] = dimension.Key
+			}
+			return Base{
+				Code:    InvalidInput,
+				Message: fmt.Sprintf("Unknown rating dimension %q, this venue is rated on %s", key, strings.Join(keys, ", ")),
+			}, false
+		}
+		if rating < 1.0 || rating > 5.0 {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Detailed ratings must be between 1.0 and 5.0",
+			}, false
+		}
+	}
+
 	return Base{}, true
 }
 
@@ -286,9 +825,101 @@ func (r *UpdateReviewRequest) Validate() (Base, bool) {
 		}
 	}
 
+	if r.VisitType != nil && *r.VisitType != "" && !models.VisitTypes.Contains(*r.VisitType) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid visit type",
+		}, false
+	}
+
+	var title, text string
+	if r.Title != nil {
+		title = SanitizeLine(*r.Title)
+		r.Title = &title
+	}
+	if r.ReviewText != nil {
+		text = SanitizeText(*r.ReviewText)
+		r.ReviewText = &text
+	}
+	return validateReviewText(title, text)
+}
+
+// Validate validates the ReviewDraftRequest
+func (r *ReviewDraftRequest) Validate() (Base, bool) {
+	if r.OverallRating != nil && (*r.OverallRating < 1.0 || *r.OverallRating > 5.0) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Rating must be between 1.0 and 5.0",
+		}, false
+	}
+
+	r.Title = SanitizeLine(r.Title)
+	if TextLength(r.Title) > MaxReviewTitleLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Title must be at most 255 characters",
+		}, false
+	}
+
+	r.ReviewText = SanitizeText(r.ReviewText)
+	if TextLength(r.ReviewText) > MaxReviewDraftTextLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Review text must be at most 20000 characters",
+		}, false
+	}
+
+	if r.VisitType != "" && !models.VisitTypes.Contains(r.VisitType) {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Invalid visit type",
+		}, false
+	}
+
+	if r.PartySize < 0 || r.PartySize > 50 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Party size must be between 0 and 50",
+		}, false
+	}
+
+	return validateReviewPhotoCount(r.Photos)
+}
+
+// validateReviewPhotoCount checks that a review has at most
+// services.MaxReviewPhotos photos
+func validateReviewPhotoCount(photos []string) (Base, bool) {
+	if len(photos) > services.MaxReviewPhotos {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("A review can have at most %d photos", services.MaxReviewPhotos),
+		}, false
+	}
 	return Base{}, true
 }
 
+// ToDraft converts ReviewDraftRequest to ReviewDraft model
+func (r *ReviewDraftRequest) ToDraft(userID, venueID int64) *models.ReviewDraft {
+	draft := &models.ReviewDraft{
+		UserID:          userID,
+		VenueID:         venueID,
+		OverallRating:   r.OverallRating,
+		DetailedRatings: r.DetailedRatings,
+		Title:           r.Title,
+		ReviewText:      r.ReviewText,
+		VisitDate:       r.VisitDate,
+		VisitType:       r.VisitType,
+		PartySize:       r.PartySize,
+	}
+
+	if len(r.Photos) > 0 {
+		photosJSON, _ := json.Marshal(r.Photos)
+		draft.Photos = photosJSON
+	}
+
+	return draft
+}
+
 // ToReview converts CreateReviewRequest to VenueReview model
 func (r *CreateReviewRequest) ToReview() *models.VenueReview {
 	review := &models.VenueReview{
@@ -301,17 +932,40 @@ func (r *CreateReviewRequest) ToReview() *models.VenueReview {
 		VisitType:        r.VisitType,
 		PartySize:        r.PartySize,
 		ModerationStatus: "pending",
+		InviteToken:      strings.TrimSpace(r.InviteToken),
 	}
 
-	// Convert photos slice to JSON
-	if len(r.Photos) > 0 {
-		photosJSON, _ := json.Marshal(r.Photos)
-		review.Photos = photosJSON
-	}
+	// The photos keep the order they were sent in
+	review.Photos = models.NewReviewPhotos(r.Photos, time.Now())
 
 	return review
 }
 
+// ReorderReviewPhotosRequest lists the URLs of a review's photos in their
+// new order
+type ReorderReviewPhotosRequest struct {
+	URLs []string `json:"urls" binding:"required"`
+}
+
+// CaptionReviewPhotoRequest captions the photo of a review with the URL, an
+// empty caption removes it
+type CaptionReviewPhotoRequest struct {
+	URL     string `json:"url" binding:"required"`
+	Caption string `json:"caption"`
+}
+
+// Validate validates the CaptionReviewPhotoRequest
+func (r *CaptionReviewPhotoRequest) Validate() (Base, bool) {
+	r.Caption = SanitizeLine(r.Caption)
+	if TextLength(r.Caption) > MaxPhotoCaptionLength {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("Captions must be at most %d characters", MaxPhotoCaptionLength),
+		}, false
+	}
+	return Base{}, true
+}
+
 // VenueCollectionResponse for venue collections/lists
 type VenueCollectionResponse struct {
 	// Collections []models.VenueCollection `json:"collections"`
@@ -342,7 +996,7 @@ type VotingCampaignResponse struct {
 type CreateCampaignRequest struct {
 	Title                   string    `json:"title" binding:"required,min=1,max=255"`
 	Description             string    `json:"description,omitempty"`
-	CampaignType            string    `json:"campaignType" binding:"required"`
+	CampaignType            string    `json:"campaignType" binding:"required,campaign_type"`
 	CityID                  *int64    `json:"cityId,omitempty"`
 	CategoryID              *int64    `json:"categoryId,omitempty"`
 	StartDate               time.Time `json:"startDate" binding:"required"`
@@ -355,6 +1009,8 @@ type CreateCampaignRequest struct {
 // SubmitCampaignVoteRequest for voting in campaigns
 type SubmitCampaignVoteRequest struct {
 	VenueID         int64   `json:"venueId" binding:"required"`
+	CategoryID      *int64  `json:"categoryId,omitempty"` // Required by campaigns with categories
+	Votes           int     `json:"votes,omitempty"`      // Quadratic campaigns only, defaults to 1
 	Reason          string  `json:"reason,omitempty"`
 	ConfidenceScore float64 `json:"confidenceScore,omitempty"`
 }
@@ -375,3 +1031,22 @@ func generateSlug(name string) string {
 	}
 	return result.String()
 }
+
+// priceRangeError tells the price ranges a venue can have
+func priceRangeError() Base {
+	return Base{
+		Code:    InvalidInput,
+		Message: "Price range must be one of: " + strings.Join(models.PriceRanges.Values(), ", "),
+	}
+}
+
+// validateAmenities checks that the venue lists known amenity keys
+func validateAmenities(amenities []string) (Base, bool) {
+	if amenity, unknown := unknownEnumValue(models.Amenities, amenities); unknown {
+		return Base{
+			Code:    InvalidInput,
+			Message: fmt.Sprintf("Unknown amenity %q, amenities are: %s", amenity, strings.Join(models.Amenities.Values(), ", ")),
+		}, false
+	}
+	return Base{}, true
+}
@@ -1,8 +1,10 @@
 package serializers
 
 import (
+	"context"
 	"strconv"
 	"voting-app/app/models"
+	"voting-app/app/services"
 )
 
 type UserHistory struct {
@@ -10,12 +12,29 @@ type UserHistory struct {
 	Voted    []models.UserVotes `json:"voted"`
 }
 type Vote struct {
-	Banner       *models.Banner       `json:"banner,omitempty"`
-	Participants []models.Participant `json:"participants"`
-	Mentors      []models.Mentor      `json:"mentors"`
-	Voting       models.Voting        `json:"voting"`
-	History      UserHistory          `json:"history"`
-	LastWinner   *models.UserVotes    `json:"lastWinner"`
+	Banner       *models.Banner        `json:"banner,omitempty"`
+	Participants []models.Participant  `json:"participants"`
+	Mentors      []models.Mentor       `json:"mentors"`
+	Voting       models.Voting         `json:"voting"`
+	History      UserHistory           `json:"history"`
+	LastWinner   *models.UserVotes     `json:"lastWinner"`
+	Receipt      *services.VoteReceipt `json:"receipt,omitempty"`
+}
+
+// LegacyVotingMigratedResponse answers legacy votes once the legacy votings
+// are sunset, pointing voters to the campaigns that replaced them
+type LegacyVotingMigratedResponse struct {
+	Base
+	CampaignsURL string             `json:"campaignsUrl"`
+	Campaigns    []MigratedCampaign `json:"campaigns"` // Featured right now
+	ArchiveURL   string             `json:"archiveUrl"`
+}
+
+// MigratedCampaign is a campaign legacy voters can vote in instead
+type MigratedCampaign struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
 }
 
 type VoteRequest struct {
@@ -26,7 +45,7 @@ type VoteRequest struct {
 	contestant models.Participant
 }
 
-func (v *VoteRequest) Validate(votingIdString, voteIdString string) (Base, bool) {
+func (v *VoteRequest) Validate(ctx context.Context, votingIdString, voteIdString string) (Base, bool) {
 	var err error
 	v.VotingId, err = strconv.ParseInt(votingIdString, 10, 64)
 	if err != nil {
@@ -44,14 +63,14 @@ func (v *VoteRequest) Validate(votingIdString, voteIdString string) (Base, bool)
 	}
 	v.voting.Id = v.VotingId
 
-	if !v.voting.IsValid() {
+	if !v.voting.IsValid(ctx) {
 		return Base{
 			Code:    InvalidInput,
 			Message: "voting id is invalid",
 		}, false
 	}
 	v.contestant.Id = v.VoteId
-	if !v.contestant.IsValid() {
+	if !v.contestant.IsValid(ctx) {
 
 		return Base{
 			Code:    InvalidInput,
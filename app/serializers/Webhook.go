@@ -0,0 +1,83 @@
+package serializers
+
+import (
+	"net/url"
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxWebhookDeliveries caps the delivery logs returned per request
+const MaxWebhookDeliveries = 100
+
+// WebhookSubscriptionRequest for creating a webhook subscription
+type WebhookSubscriptionRequest struct {
+	TargetURL  string   `json:"targetUrl" binding:"required"`
+	EventTypes []string `json:"eventTypes" binding:"required"`
+}
+
+// WebhookSubscriptionsResponse for the webhook subscriptions API
+type WebhookSubscriptionsResponse struct {
+	VenueID       *int64                       `json:"venueId,omitempty"`
+	Subscriptions []models.WebhookSubscription `json:"subscriptions"`
+}
+
+// WebhookDeliveriesResponse for the webhook delivery logs API
+type WebhookDeliveriesResponse struct {
+	SubscriptionID int64                    `json:"subscriptionId"`
+	Deliveries     []models.WebhookDelivery `json:"deliveries"`
+}
+
+// Validate validates the WebhookSubscriptionRequest against the event types
+// the caller may subscribe to
+func (r *WebhookSubscriptionRequest) Validate(allowedEvents []string) (Base, bool) {
+	r.TargetURL = strings.TrimSpace(r.TargetURL)
+	target, err := url.Parse(r.TargetURL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" || len(r.TargetURL) > 2048 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "Target URL must be an absolute http or https URL",
+		}, false
+	}
+
+	if len(r.EventTypes) == 0 {
+		return Base{
+			Code:    InvalidInput,
+			Message: "At least one event type is required",
+		}, false
+	}
+
+	seen := make(map[string]bool)
+	eventTypes := make([]string, 0, len(r.EventTypes))
+	for _, eventType := range r.EventTypes {
+		isAllowed := false
+		for _, allowed := range allowedEvents {
+			if eventType == allowed {
+				isAllowed = true
+				break
+			}
+		}
+		if !isAllowed {
+			return Base{
+				Code:    InvalidInput,
+				Message: "Event types must be one of: " + strings.Join(allowedEvents, ", "),
+			}, false
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	r.EventTypes = eventTypes
+
+	return Base{}, true
+}
+
+// ToSubscription converts WebhookSubscriptionRequest to WebhookSubscription model
+func (r *WebhookSubscriptionRequest) ToSubscription(userID int64, venueID *int64) *models.WebhookSubscription {
+	return &models.WebhookSubscription{
+		UserID:     userID,
+		VenueID:    venueID,
+		TargetURL:  r.TargetURL,
+		EventTypes: r.EventTypes,
+	}
+}
@@ -1,19 +1,41 @@
 package serializers
 
 const (
-	InvalidInput         = "INVALID_INPUT"
-	InternalError        = "INTERNAL_ERROR"
-	SnappIdDoesNotExists = "SNAPP_ID_DOES_NOT_EXISTS"
-	AlreadyVoted         = "ALREADY_VOTED"
-	AlreadyReviewed      = "ALREADY_REVIEWED"
-	NotFound             = "NOT_FOUND"
-	Success              = "SUCCESS"
-	WrongPassword        = "WRONG_PASSWORD"
-	Forbidden            = "FORBIDDEN"
-	Unauthorized         = "UNAUTHORIZED"
-	LocationRequired     = "LOCATION_REQUIRED"
-	VenueNotFound        = "VENUE_NOT_FOUND"
-	ReviewNotFound       = "REVIEW_NOT_FOUND"
-	InvalidRating        = "INVALID_RATING"
-	InvalidLocation      = "INVALID_LOCATION"
+	InvalidInput          = "INVALID_INPUT"
+	InternalError         = "INTERNAL_ERROR"
+	SnappIdDoesNotExists  = "SNAPP_ID_DOES_NOT_EXISTS"
+	AlreadyVoted          = "ALREADY_VOTED"
+	AlreadyReviewed       = "ALREADY_REVIEWED"
+	NotFound              = "NOT_FOUND"
+	Success               = "SUCCESS"
+	WrongPassword         = "WRONG_PASSWORD"
+	Forbidden             = "FORBIDDEN"
+	Unauthorized          = "UNAUTHORIZED"
+	LocationRequired      = "LOCATION_REQUIRED"
+	VenueNotFound         = "VENUE_NOT_FOUND"
+	ReviewNotFound        = "REVIEW_NOT_FOUND"
+	InvalidRating         = "INVALID_RATING"
+	InvalidLocation       = "INVALID_LOCATION"
+	CampaignClosed        = "CAMPAIGN_CLOSED"
+	ReviewRequired        = "REVIEW_REQUIRED"
+	InsufficientCredits   = "INSUFFICIENT_CREDITS"
+	PayloadTooLarge       = "PAYLOAD_TOO_LARGE"
+	UnsupportedMediaType  = "UNSUPPORTED_MEDIA_TYPE"
+	ContentRejected       = "CONTENT_REJECTED"
+	FlagAlreadyExists     = "FLAG_ALREADY_EXISTS"
+	AlreadyLinked         = "ALREADY_LINKED"
+	EmailNotVerified      = "EMAIL_NOT_VERIFIED"
+	InvalidToken          = "INVALID_TOKEN"
+	TooManyRequests       = "TOO_MANY_REQUESTS"
+	TenantAlreadyExists   = "TENANT_ALREADY_EXISTS"
+	AlreadyInvited        = "ALREADY_INVITED"
+	DealUnavailable       = "DEAL_UNAVAILABLE"
+	AlreadyRedeemed       = "ALREADY_REDEEMED"
+	CheckinRequired       = "CHECKIN_REQUIRED"
+	TierLimitReached      = "TIER_LIMIT_REACHED"
+	LegacyVotingMigrated  = "LEGACY_VOTING_MIGRATED"
+	ImpersonationReadOnly = "IMPERSONATION_READ_ONLY"
+	CityAlreadyExists     = "CITY_ALREADY_EXISTS"
+	VoteFinal             = "VOTE_FINAL"
+	SyncExpired           = "SYNC_EXPIRED"
 )
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+	"voting-app/app/models"
+)
+
+// AccountLinkCodeTTL is how long a link verification code can be used
+const AccountLinkCodeTTL = 15 * time.Minute
+
+// AccountLinkService links JWT users to snapp users. The snapp user proves
+// the link by handing back a code sent to them as a notification.
+type AccountLinkService struct{}
+
+// RequestLink sends a verification code to the snapp user, returning when it
+// expires. sql.ErrNoRows when the snapp user doesn't exist,
+// models.ErrAccountAlreadyLinked when either account is linked and
+// models.ErrLinkCodeResendTooSoon when the snapp user was just sent a code.
+func (as *AccountLinkService) RequestLink(ctx context.Context, userID int64, snappID string) (time.Time, error) {
+	snappUser := &models.SnappUser{SnappId: snappID}
+	exists, err := snappUser.GetUser(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !exists {
+		return time.Time{}, sql.ErrNoRows
+	}
+
+	if err := as.checkUnlinked(ctx, userID, snappUser); err != nil {
+		return time.Time{}, err
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+	request := &models.AccountLinkRequest{
+		UserID:      userID,
+		SnappUserID: snappUser.Id,
+		CodeHash:    hashVerificationCode(code),
+		ExpiresAt:   time.Now().UTC().Add(AccountLinkCodeTTL),
+	}
+	if err := request.Create(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	notificationService := &NotificationService{}
+	_, err = notificationService.Notify(ctx, snappUser.Id, models.NotificationAccountLink,
+		"Link your account",
+		fmt.Sprintf("Your verification code is %s. It expires in %d minutes, ignore it if you didn't ask to link your account.",
+			code, int(AccountLinkCodeTTL.Minutes())),
+		nil,
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return request.ExpiresAt, nil
+}
+
+// ConfirmLink links the user to the snapp user when the code is the one sent
+// to them, models.ErrInvalidLinkCode otherwise
+func (as *AccountLinkService) ConfirmLink(ctx context.Context, userID int64, snappID, code string) (models.Identity, error) {
+	snappUser := &models.SnappUser{SnappId: snappID}
+	exists, err := snappUser.GetUser(ctx)
+	if err != nil {
+		return models.Identity{}, err
+	}
+	if !exists {
+		return models.Identity{}, models.ErrInvalidLinkCode
+	}
+
+	err = models.ConfirmAccountLink(ctx, userID, snappUser.Id, hashVerificationCode(code), time.Now().UTC())
+	if err != nil {
+		return models.Identity{}, err
+	}
+	return models.GetIdentityByUser(ctx, userID)
+}
+
+// checkUnlinked returns models.ErrAccountAlreadyLinked when the user or the
+// snapp user is linked already
+func (as *AccountLinkService) checkUnlinked(ctx context.Context, userID int64, snappUser *models.SnappUser) error {
+	identity, err := models.GetIdentityByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if identity.IsLinked() {
+		return models.ErrAccountAlreadyLinked
+	}
+
+	identity, err = models.GetIdentityBySnappUser(ctx, snappUser)
+	if err != nil {
+		return err
+	}
+	if identity.IsLinked() {
+		return models.ErrAccountAlreadyLinked
+	}
+	return nil
+}
+
+// newVerificationCode generates a random six digit code, sent to users as a
+// notification to prove they asked for something
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashVerificationCode hashes the code, only hashes are stored
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,8 +9,17 @@ import (
 	"time"
 	databases "voting-app/app"
 	"voting-app/app/models"
+	"voting-app/app/tracing"
 
 	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Venue analytics scopes. Owners get their venue's own ranks, admins also
+// the top ranked venues it competes with.
+const (
+	AnalyticsScopeOwner = "owner"
+	AnalyticsScopeAdmin = "admin"
 )
 
 // AnalyticsService provides comprehensive analytics and insights
@@ -17,9 +27,13 @@ type AnalyticsService struct{}
 
 // VenueAnalytics represents comprehensive venue performance metrics
 type VenueAnalytics struct {
-	VenueID   int64  `json:"venueId"`
-	VenueName string `json:"venueName"`
-	TimeRange string `json:"timeRange"`
+	VenueID   int64      `json:"venueId"`
+	VenueName string     `json:"venueName"`
+	TimeRange string     `json:"timeRange"`
+	Timezone  string     `json:"timezone"`        // Days and hours are local to the venue's city
+	Scope     string     `json:"scope,omitempty"` // AnalyticsScopeOwner or AnalyticsScopeAdmin when served over the API
+	Level     string     `json:"level,omitempty"` // AnalyticsBasic or AnalyticsFull when served over the API
+	Plan      *OwnerPlan `json:"plan,omitempty"`  // The plan of the venue's owner
 
 	// Engagement Metrics
 	ProfileViews      int `json:"profileViews"`
@@ -33,6 +47,10 @@ type VenueAnalytics struct {
 	ReviewsCount  int `json:"reviewsCount"`
 	SharesCount   int `json:"sharesCount"`
 
+	// Deal Attribution
+	DealRedemptions int                      `json:"dealRedemptions"` // Check-ins that redeemed a deal
+	Deals           []models.DealAttribution `json:"deals"`
+
 	// Rating Trends
 	AverageRating      float64        `json:"averageRating"`
 	RatingTrend        []DailyRating  `json:"ratingTrend"`
@@ -49,8 +67,9 @@ type VenueAnalytics struct {
 	AveragePosition   float64 `json:"averagePosition"`
 
 	// Competitive Analysis
-	CategoryRank int `json:"categoryRank"`
-	LocalRank    int `json:"localRank"`
+	CategoryRank int         `json:"categoryRank"`
+	LocalRank    int         `json:"localRank"`
+	Competitors  []VenueRank `json:"competitors,omitempty"` // Admin scope only
 
 	// User Demographics
 	Demographics UserDemographics `json:"demographics"`
@@ -59,6 +78,29 @@ type VenueAnalytics struct {
 	GrowthMetrics GrowthData `json:"growthMetrics"`
 }
 
+// LimitToBasic drops the metrics only the full analytics level has: the
+// rating trend and distribution, popular times, search performance,
+// demographics and growth
+func (va *VenueAnalytics) LimitToBasic() {
+	va.Level = AnalyticsBasic
+	va.RatingTrend = nil
+	va.RatingDistribution = nil
+	va.PopularHours = nil
+	va.PopularDays = nil
+	va.SearchImpressions, va.SearchClicks = 0, 0
+	va.ClickThroughRate, va.AveragePosition = 0, 0
+	va.Demographics = UserDemographics{}
+	va.GrowthMetrics = GrowthData{}
+}
+
+// VenueRank is a venue's place among the venues of its category in its city
+type VenueRank struct {
+	VenueID       int64   `json:"venueId"`
+	VenueName     string  `json:"venueName"`
+	AverageRating float64 `json:"averageRating"`
+	Rank          int     `json:"rank"`
+}
+
 type DailyRating struct {
 	Date   string  `json:"date"`
 	Rating float64 `json:"rating"`
@@ -87,11 +129,12 @@ type GrowthData struct {
 type PlatformAnalytics struct {
 	TimeRange string `json:"timeRange"`
 
-	// Overall Metrics
-	TotalVenues   int `json:"totalVenues"`
-	TotalUsers    int `json:"totalUsers"`
-	TotalReviews  int `json:"totalReviews"`
-	TotalCheckins int `json:"totalCheckins"`
+	// Overall Metrics, read from the platform stats rollups
+	StatsUpdatedAt *time.Time `json:"statsUpdatedAt"` // When the rollups were last refreshed
+	TotalVenues    int        `json:"totalVenues"`
+	TotalUsers     int        `json:"totalUsers"`
+	TotalReviews   int        `json:"totalReviews"`
+	TotalCheckins  int        `json:"totalCheckins"`
 
 	// Activity Metrics
 	DailyActiveUsers   int `json:"dailyActiveUsers"`
@@ -144,17 +187,32 @@ type SearchTrendMetric struct {
 	Growth float64     `json:"growth"`
 }
 
+// UserEngagementMetrics are computed from the sessions of client events
 type UserEngagementMetrics struct {
-	AverageSessionDuration time.Duration `json:"averageSessionDuration"`
-	BounceRate             float64       `json:"bounceRate"`
-	PagesPerSession        float64       `json:"pagesPerSession"`
-	ReturnUserRate         float64       `json:"returnUserRate"`
+	TimeRange              string  `json:"timeRange,omitempty"`
+	Sessions               int     `json:"sessions"`
+	AverageSessionDuration float64 `json:"averageSessionDuration"` // In seconds
+	BounceRate             float64 `json:"bounceRate"`             // Share of sessions with a single event
+	PagesPerSession        float64 `json:"pagesPerSession"`
+	ReturnUserRate         float64 `json:"returnUserRate"` // Share of clients with a session before the range
 }
 
 // GetVenueAnalytics returns comprehensive analytics for a specific venue
-func (as *AnalyticsService) GetVenueAnalytics(venueID int64, timeRange string) (*VenueAnalytics, error) {
+func (as *AnalyticsService) GetVenueAnalytics(ctx context.Context, venueID int64, timeRange string) (_ *VenueAnalytics, err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsService.GetVenueAnalytics",
+		attribute.Int64("venue.id", venueID), attribute.String("analytics.time_range", timeRange))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	location, err := models.GetVenueLocation(ctx, venueID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse time range
-	startDate, endDate, err := as.parseTimeRange(timeRange)
+	startDate, endDate, err := as.parseTimeRange(timeRange, location)
 	if err != nil {
 		return nil, err
 	}
@@ -162,57 +220,82 @@ func (as *AnalyticsService) GetVenueAnalytics(venueID int64, timeRange string) (
 	analytics := &VenueAnalytics{
 		VenueID:            venueID,
 		TimeRange:          timeRange,
+		Timezone:           location.String(),
 		RatingDistribution: make(map[string]int),
 		PopularHours:       make(map[int]int),
 		PopularDays:        make(map[string]int),
+		Deals:              make([]models.DealAttribution, 0),
 	}
 
 	// Get venue name
 	var venueName string
-	err = databases.PostgresDB.QueryRow("SELECT name FROM venues WHERE id = $1", venueID).Scan(&venueName)
+	err = models.LoggedQueryRow(ctx, "SELECT name FROM venues WHERE id = $1 AND ($2::bigint IS NULL OR tenant_id = $2)",
+		venueID, models.TenantFilter(ctx)).Scan(&venueName)
 	if err != nil {
 		return nil, err
 	}
 	analytics.VenueName = venueName
 
 	// Get engagement metrics
-	err = as.getVenueEngagementMetrics(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.engagement", func(ctx context.Context) error {
+		return as.getVenueEngagementMetrics(ctx, venueID, startDate, endDate, analytics)
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+
+	// Get deal attribution
+	err = tracing.Phase(ctx, "analytics.deals", func(ctx context.Context) error {
+		return as.getVenueDealAttribution(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get rating analytics
-	err = as.getVenueRatingAnalytics(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.ratings", func(ctx context.Context) error {
+		return as.getVenueRatingAnalytics(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get popular times
-	err = as.getVenuePopularTimes(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.popular_times", func(ctx context.Context) error {
+		return as.getVenuePopularTimes(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get search performance
-	err = as.getVenueSearchPerformance(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.search_performance", func(ctx context.Context) error {
+		return as.getVenueSearchPerformance(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get competitive ranking
-	err = as.getVenueRanking(venueID, analytics)
+	err = tracing.Phase(ctx, "analytics.ranking", func(ctx context.Context) error {
+		return as.getVenueRanking(ctx, venueID, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get user demographics
-	err = as.getVenueDemographics(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.demographics", func(ctx context.Context) error {
+		return as.getVenueDemographics(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Calculate growth metrics
-	err = as.calculateVenueGrowth(venueID, startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.growth", func(ctx context.Context) error {
+		return as.calculateVenueGrowth(ctx, venueID, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
@@ -221,8 +304,15 @@ func (as *AnalyticsService) GetVenueAnalytics(venueID int64, timeRange string) (
 }
 
 // GetPlatformAnalytics returns overall platform performance metrics
-func (as *AnalyticsService) GetPlatformAnalytics(timeRange string) (*PlatformAnalytics, error) {
-	startDate, endDate, err := as.parseTimeRange(timeRange)
+func (as *AnalyticsService) GetPlatformAnalytics(ctx context.Context, timeRange string) (_ *PlatformAnalytics, err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsService.GetPlatformAnalytics",
+		attribute.String("analytics.time_range", timeRange))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	startDate, endDate, err := as.parseTimeRange(timeRange, time.Local)
 	if err != nil {
 		return nil, err
 	}
@@ -232,31 +322,49 @@ func (as *AnalyticsService) GetPlatformAnalytics(timeRange string) (*PlatformAna
 	}
 
 	// Get overall counts
-	err = as.getPlatformOverviewMetrics(analytics)
+	err = tracing.Phase(ctx, "analytics.overview", func(ctx context.Context) error {
+		return as.getPlatformOverviewMetrics(ctx, analytics)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Get activity metrics
-	err = as.getPlatformActivityMetrics(startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.activity", func(ctx context.Context) error {
+		return as.getPlatformActivityMetrics(ctx, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get content metrics
-	err = as.getPlatformContentMetrics(startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.content", func(ctx context.Context) error {
+		return as.getPlatformContentMetrics(ctx, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get top categories and cities
-	err = as.getPlatformTopMetrics(startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.top", func(ctx context.Context) error {
+		return as.getPlatformTopMetrics(ctx, startDate, endDate, analytics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Get search analytics
-	err = as.getPlatformSearchAnalytics(startDate, endDate, analytics)
+	err = tracing.Phase(ctx, "analytics.search", func(ctx context.Context) error {
+		return as.getPlatformSearchAnalytics(ctx, startDate, endDate, analytics)
+	})
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+
+	// Get engagement metrics
+	err = tracing.Phase(ctx, "analytics.engagement", func(ctx context.Context) error {
+		return as.getEngagementMetrics(ctx, startDate, endDate, &analytics.EngagementMetrics)
+	})
 	if err != nil {
 		sentry.CaptureException(err)
 	}
@@ -264,12 +372,17 @@ func (as *AnalyticsService) GetPlatformAnalytics(timeRange string) (*PlatformAna
 	return analytics, nil
 }
 
-// TrackVenueView records a venue profile view
-func (as *AnalyticsService) TrackVenueView(venueID, userID int64, viewType string) error {
+// TrackVenueView records a venue profile view on the venue's local day
+func (as *AnalyticsService) TrackVenueView(ctx context.Context, venueID, userID int64, viewType string) error {
+	location, err := models.GetVenueLocation(ctx, venueID)
+	if err != nil {
+		return err
+	}
+
 	// Insert or update daily analytics
 	query := `
 		INSERT INTO venue_analytics (venue_id, date, profile_views, photo_views, phone_clicks, website_clicks, direction_requests)
-		VALUES ($1, CURRENT_DATE, 
+		VALUES ($1, (CURRENT_TIMESTAMP AT TIME ZONE $3)::date, 
 			CASE WHEN $2 = 'profile' THEN 1 ELSE 0 END,
 			CASE WHEN $2 = 'photo' THEN 1 ELSE 0 END,
 			CASE WHEN $2 = 'phone' THEN 1 ELSE 0 END,
@@ -283,7 +396,7 @@ func (as *AnalyticsService) TrackVenueView(venueID, userID int64, viewType strin
 			website_clicks = venue_analytics.website_clicks + CASE WHEN $2 = 'website' THEN 1 ELSE 0 END,
 			direction_requests = venue_analytics.direction_requests + CASE WHEN $2 = 'directions' THEN 1 ELSE 0 END`
 
-	_, err := databases.PostgresDB.Exec(query, venueID, viewType)
+	_, err = databases.PostgresDB.ExecContext(ctx, query, venueID, viewType, location.String())
 	if err != nil {
 		sentry.CaptureException(err)
 	}
@@ -291,8 +404,30 @@ func (as *AnalyticsService) TrackVenueView(venueID, userID int64, viewType strin
 	return err
 }
 
-// TrackSearch records search analytics
-func (as *AnalyticsService) TrackSearch(userID int64, query string, filters map[string]interface{}, results []models.Venue, clickedVenueID *int64, clickPosition *int) error {
+// VenueEventReceiptTTL is how long venue events are remembered to drop
+// resent ones. Events are only accepted for a day after they occurred.
+const VenueEventReceiptTTL = 48 * time.Hour
+
+// ExpireVenueEventReceipts forgets the venue events that are too old to be
+// sent again
+func (as *AnalyticsService) ExpireVenueEventReceipts(ctx context.Context) error {
+	_, err := models.DeleteVenueEventReceipts(ctx, time.Now().UTC().Add(-VenueEventReceiptTTL))
+	return err
+}
+
+// TrackSearch records search analytics. Searches of users who turned their
+// search history off aren't recorded.
+func (as *AnalyticsService) TrackSearch(ctx context.Context, userID int64, query string, filters map[string]interface{}, results []models.Venue, clickedVenueID *int64, clickPosition *int) error {
+	if userID != 0 {
+		settings, err := models.GetPrivacySettings(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !settings.SearchHistoryEnabled {
+			return nil
+		}
+	}
+
 	// Get user location if available
 	var userLat, userLng *float64
 	if lat, exists := filters["latitude"]; exists {
@@ -331,9 +466,15 @@ func (as *AnalyticsService) TrackSearch(userID int64, query string, filters map[
 		searchType = "filter"
 	}
 
-	_, err := databases.PostgresDB.Exec(
+	// Anonymous searches are recorded without a user
+	var searchUserID sql.NullInt64
+	if userID != 0 {
+		searchUserID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	_, err := databases.PostgresDB.ExecContext(ctx,
 		insertQuery,
-		userID, query, searchType, filtersJSON,
+		searchUserID, query, searchType, filtersJSON,
 		userLat, userLng, searchRadius,
 		len(results), clickedVenueID, clickPosition,
 	)
@@ -347,8 +488,11 @@ func (as *AnalyticsService) TrackSearch(userID int64, query string, filters map[
 
 // Helper methods for analytics calculation
 
-func (as *AnalyticsService) parseTimeRange(timeRange string) (time.Time, time.Time, error) {
-	now := time.Now()
+// parseTimeRange returns the bounds of the range, with days starting at
+// midnight in the given location. The bounds are in the server's zone, which
+// timestamps are stored in.
+func (as *AnalyticsService) parseTimeRange(timeRange string, location *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(location)
 	var startDate time.Time
 
 	switch timeRange {
@@ -370,10 +514,15 @@ func (as *AnalyticsService) parseTimeRange(timeRange string) (time.Time, time.Ti
 		startDate = now.AddDate(0, 0, -7) // Default to last week
 	}
 
-	return startDate, now, nil
+	return startDate.In(time.Local), now.In(time.Local), nil
 }
 
-func (as *AnalyticsService) getVenueEngagementMetrics(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+// localDate formats the day of t in the time zone
+func localDate(t time.Time, timezone string) string {
+	return t.In(models.CityLocation(timezone)).Format("2006-01-02")
+}
+
+func (as *AnalyticsService) getVenueEngagementMetrics(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	query := `
 		SELECT 
 			COALESCE(SUM(profile_views), 0) as profile_views,
@@ -387,7 +536,9 @@ func (as *AnalyticsService) getVenueEngagementMetrics(venueID int64, startDate,
 		FROM venue_analytics
 		WHERE venue_id = $1 AND date BETWEEN $2 AND $3`
 
-	err := databases.PostgresDB.QueryRow(query, venueID, startDate, endDate).Scan(
+	err := models.LoggedQueryRow(ctx, query, venueID,
+		localDate(startDate, analytics.Timezone), localDate(endDate, analytics.Timezone),
+	).Scan(
 		&analytics.ProfileViews,
 		&analytics.PhotoViews,
 		&analytics.PhoneClicks,
@@ -401,9 +552,23 @@ func (as *AnalyticsService) getVenueEngagementMetrics(venueID int64, startDate,
 	return err
 }
 
-func (as *AnalyticsService) getVenueRatingAnalytics(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+// getVenueDealAttribution counts the check-ins that redeemed each of the
+// venue's deals
+func (as *AnalyticsService) getVenueDealAttribution(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+	deals, err := models.GetDealAttribution(ctx, venueID, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	analytics.Deals = deals
+	for _, deal := range deals {
+		analytics.DealRedemptions += deal.Redemptions
+	}
+	return nil
+}
+
+func (as *AnalyticsService) getVenueRatingAnalytics(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	// Get current average rating
-	err := databases.PostgresDB.QueryRow(
+	err := models.LoggedQueryRow(ctx,
 		"SELECT average_rating FROM venues WHERE id = $1", venueID,
 	).Scan(&analytics.AverageRating)
 	if err != nil {
@@ -425,7 +590,7 @@ func (as *AnalyticsService) getVenueRatingAnalytics(venueID int64, startDate, en
 		WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3
 		GROUP BY rating_bucket`
 
-	rows, err := databases.PostgresDB.Query(distQuery, venueID, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, distQuery, venueID, startDate, endDate)
 	if err != nil {
 		return err
 	}
@@ -442,15 +607,15 @@ func (as *AnalyticsService) getVenueRatingAnalytics(venueID int64, startDate, en
 	// Get daily rating trend
 	trendQuery := `
 		SELECT 
-			DATE(created_at) as date,
+			DATE(created_at::timestamptz AT TIME ZONE $4) as date,
 			AVG(overall_rating) as avg_rating,
 			COUNT(*) as review_count
 		FROM venue_reviews
 		WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3
-		GROUP BY DATE(created_at)
+		GROUP BY date
 		ORDER BY date`
 
-	rows, err = databases.PostgresDB.Query(trendQuery, venueID, startDate, endDate)
+	rows, err = models.LoggedQuery(ctx, trendQuery, venueID, startDate, endDate, analytics.Timezone)
 	if err != nil {
 		return err
 	}
@@ -468,18 +633,19 @@ func (as *AnalyticsService) getVenueRatingAnalytics(venueID int64, startDate, en
 	return nil
 }
 
-func (as *AnalyticsService) getVenuePopularTimes(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+// getVenuePopularTimes buckets check-ins by the venue's local hours and days
+func (as *AnalyticsService) getVenuePopularTimes(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	// Get popular hours from check-ins
 	hourQuery := `
 		SELECT 
-			EXTRACT(hour FROM created_at) as hour,
+			EXTRACT(hour FROM created_at::timestamptz AT TIME ZONE $4) as hour,
 			COUNT(*) as count
 		FROM venue_checkins
 		WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3
 		GROUP BY hour
 		ORDER BY hour`
 
-	rows, err := databases.PostgresDB.Query(hourQuery, venueID, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, hourQuery, venueID, startDate, endDate, analytics.Timezone)
 	if err != nil {
 		return err
 	}
@@ -494,15 +660,19 @@ func (as *AnalyticsService) getVenuePopularTimes(venueID int64, startDate, endDa
 
 	// Get popular days
 	dayQuery := `
-		SELECT 
-			TO_CHAR(created_at, 'Day') as day_name,
-			COUNT(*) as count
-		FROM venue_checkins
-		WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3
-		GROUP BY day_name, EXTRACT(dow FROM created_at)
-		ORDER BY EXTRACT(dow FROM created_at)`
-
-	rows, err = databases.PostgresDB.Query(dayQuery, venueID, startDate, endDate)
+		SELECT day_name, COUNT(*) as count
+		FROM (
+			SELECT TO_CHAR(local_time, 'Day') as day_name, EXTRACT(dow FROM local_time) as dow
+			FROM (
+				SELECT created_at::timestamptz AT TIME ZONE $4 as local_time
+				FROM venue_checkins
+				WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3
+			) local_checkins
+		) checkin_days
+		GROUP BY day_name, dow
+		ORDER BY dow`
+
+	rows, err = models.LoggedQuery(ctx, dayQuery, venueID, startDate, endDate, analytics.Timezone)
 	if err != nil {
 		return err
 	}
@@ -519,7 +689,7 @@ func (as *AnalyticsService) getVenuePopularTimes(venueID int64, startDate, endDa
 	return nil
 }
 
-func (as *AnalyticsService) getVenueSearchPerformance(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+func (as *AnalyticsService) getVenueSearchPerformance(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	// Get search impressions and clicks
 	query := `
 		SELECT 
@@ -530,7 +700,7 @@ func (as *AnalyticsService) getVenueSearchPerformance(venueID int64, startDate,
 		WHERE sa.created_at BETWEEN $2 AND $3
 		  AND EXISTS (
 			  SELECT 1 FROM venues v 
-			  WHERE v.id = $1 
+			  WHERE v.id = $1 AND ($4::bigint IS NULL OR v.tenant_id = $4)
 			  AND (
 				  LOWER(v.name) LIKE LOWER('%' || sa.search_query || '%')
 				  OR v.category_id::text = ANY(string_to_array(sa.filters_used->>'category_id', ','))
@@ -538,7 +708,7 @@ func (as *AnalyticsService) getVenueSearchPerformance(venueID int64, startDate,
 		  )`
 
 	var avgPosition sql.NullFloat64
-	err := databases.PostgresDB.QueryRow(query, venueID, startDate, endDate).Scan(
+	err := models.LoggedQueryRow(ctx, query, venueID, startDate, endDate, models.TenantFilter(ctx)).Scan(
 		&analytics.SearchImpressions,
 		&analytics.SearchClicks,
 		&avgPosition,
@@ -559,21 +729,22 @@ func (as *AnalyticsService) getVenueSearchPerformance(venueID int64, startDate,
 	return nil
 }
 
-func (as *AnalyticsService) getVenueRanking(venueID int64, analytics *VenueAnalytics) error {
+func (as *AnalyticsService) getVenueRanking(ctx context.Context, venueID int64, analytics *VenueAnalytics) error {
 	// Get category ranking
 	var categoryID int64
-	err := databases.PostgresDB.QueryRow("SELECT category_id FROM venues WHERE id = $1", venueID).Scan(&categoryID)
+	err := models.LoggedQueryRow(ctx, "SELECT category_id FROM venues WHERE id = $1", venueID).Scan(&categoryID)
 	if err != nil {
 		return err
 	}
 
-	// Category rank
+	// Category rank, among the venues of the request's tenant
 	categoryRankQuery := `
 		SELECT COUNT(*) + 1 as rank
 		FROM venues
-		WHERE category_id = $1 AND average_rating > (SELECT average_rating FROM venues WHERE id = $2)`
+		WHERE category_id = $1 AND average_rating > (SELECT average_rating FROM venues WHERE id = $2)
+		  AND ($3::bigint IS NULL OR tenant_id = $3)`
 
-	err = databases.PostgresDB.QueryRow(categoryRankQuery, categoryID, venueID).Scan(&analytics.CategoryRank)
+	err = models.LoggedQueryRow(ctx, categoryRankQuery, categoryID, venueID, models.TenantFilter(ctx)).Scan(&analytics.CategoryRank)
 	if err != nil {
 		analytics.CategoryRank = 0
 	}
@@ -583,9 +754,10 @@ func (as *AnalyticsService) getVenueRanking(venueID int64, analytics *VenueAnaly
 		SELECT COUNT(*) + 1 as rank
 		FROM venues v1
 		JOIN venues v2 ON v1.city_id = v2.city_id
-		WHERE v2.id = $1 AND v1.average_rating > v2.average_rating`
+		WHERE v2.id = $1 AND v1.average_rating > v2.average_rating
+		  AND ($2::bigint IS NULL OR v1.tenant_id = $2)`
 
-	err = databases.PostgresDB.QueryRow(localRankQuery, venueID).Scan(&analytics.LocalRank)
+	err = models.LoggedQueryRow(ctx, localRankQuery, venueID, models.TenantFilter(ctx)).Scan(&analytics.LocalRank)
 	if err != nil {
 		analytics.LocalRank = 0
 	}
@@ -593,7 +765,7 @@ func (as *AnalyticsService) getVenueRanking(venueID int64, analytics *VenueAnaly
 	return nil
 }
 
-func (as *AnalyticsService) getVenueDemographics(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+func (as *AnalyticsService) getVenueDemographics(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	analytics.Demographics = UserDemographics{
 		AgeGroups: make(map[string]int),
 	}
@@ -613,7 +785,7 @@ func (as *AnalyticsService) getVenueDemographics(venueID int64, startDate, endDa
 		ORDER BY count DESC
 		LIMIT 5`
 
-	rows, err := databases.PostgresDB.Query(cityQuery, venueID, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, cityQuery, venueID, startDate, endDate)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -637,7 +809,7 @@ func (as *AnalyticsService) getVenueDemographics(venueID int64, startDate, endDa
 		) user_visits`
 
 	var totalUsers, returnUsers int
-	err = databases.PostgresDB.QueryRow(returnQuery, venueID, startDate, endDate).Scan(&totalUsers, &returnUsers)
+	err = models.LoggedQueryRow(ctx, returnQuery, venueID, startDate, endDate).Scan(&totalUsers, &returnUsers)
 	if err == nil && totalUsers > 0 {
 		analytics.Demographics.ReturnVisitors = float64(returnUsers) / float64(totalUsers)
 	}
@@ -645,7 +817,7 @@ func (as *AnalyticsService) getVenueDemographics(venueID int64, startDate, endDa
 	return nil
 }
 
-func (as *AnalyticsService) calculateVenueGrowth(venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
+func (as *AnalyticsService) calculateVenueGrowth(ctx context.Context, venueID int64, startDate, endDate time.Time, analytics *VenueAnalytics) error {
 	// Calculate growth compared to previous period
 	duration := endDate.Sub(startDate)
 	prevStartDate := startDate.Add(-duration)
@@ -654,12 +826,12 @@ func (as *AnalyticsService) calculateVenueGrowth(venueID int64, startDate, endDa
 	// Reviews growth
 	var currentReviews, prevReviews int
 
-	databases.PostgresDB.QueryRow(
+	models.LoggedQueryRow(ctx,
 		"SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3",
 		venueID, startDate, endDate,
 	).Scan(&currentReviews)
 
-	databases.PostgresDB.QueryRow(
+	models.LoggedQueryRow(ctx,
 		"SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1 AND created_at BETWEEN $2 AND $3",
 		venueID, prevStartDate, prevEndDate,
 	).Scan(&prevReviews)
@@ -671,14 +843,14 @@ func (as *AnalyticsService) calculateVenueGrowth(venueID int64, startDate, endDa
 	// Profile views growth
 	var currentViews, prevViews int
 
-	databases.PostgresDB.QueryRow(
+	models.LoggedQueryRow(ctx,
 		"SELECT COALESCE(SUM(profile_views), 0) FROM venue_analytics WHERE venue_id = $1 AND date BETWEEN $2 AND $3",
-		venueID, startDate, endDate,
+		venueID, localDate(startDate, analytics.Timezone), localDate(endDate, analytics.Timezone),
 	).Scan(&currentViews)
 
-	databases.PostgresDB.QueryRow(
+	models.LoggedQueryRow(ctx,
 		"SELECT COALESCE(SUM(profile_views), 0) FROM venue_analytics WHERE venue_id = $1 AND date BETWEEN $2 AND $3",
-		venueID, prevStartDate, prevEndDate,
+		venueID, localDate(prevStartDate, analytics.Timezone), localDate(prevEndDate, analytics.Timezone),
 	).Scan(&prevViews)
 
 	if prevViews > 0 {
@@ -690,30 +862,57 @@ func (as *AnalyticsService) calculateVenueGrowth(venueID int64, startDate, endDa
 	return nil
 }
 
-// Platform-wide analytics helper methods would follow similar patterns...
-func (as *AnalyticsService) getPlatformOverviewMetrics(analytics *PlatformAnalytics) error {
-	query := `
-		SELECT 
-			(SELECT COUNT(*) FROM venues WHERE is_active = true),
-			(SELECT COUNT(*) FROM snapp_users),
-			(SELECT COUNT(*) FROM venue_reviews),
-			(SELECT COUNT(*) FROM venue_checkins),
-			(SELECT AVG(average_rating) FROM venues WHERE total_ratings > 0)`
-
-	err := databases.PostgresDB.QueryRow(query).Scan(
-		&analytics.TotalVenues,
-		&analytics.TotalUsers,
-		&analytics.TotalReviews,
-		&analytics.TotalCheckins,
-		&analytics.AverageRating,
+// GetVenueCompetitors ranks the venues sharing the venue's category and city
+// by rating, returning the top ones. Ties share a rank.
+func (as *AnalyticsService) GetVenueCompetitors(ctx context.Context, venueID int64, limit int) ([]VenueRank, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	rows, err := models.LoggedQuery(ctx, `
+		SELECT v.id, v.name, COALESCE(v.average_rating, 0),
+			RANK() OVER (ORDER BY COALESCE(v.average_rating, 0) DESC) AS rank
+		FROM venues v
+		JOIN venues target ON target.id = $1
+		WHERE v.category_id = target.category_id AND v.city_id = target.city_id AND v.is_active = true
+		  AND ($3::bigint IS NULL OR (v.tenant_id = $3 AND target.tenant_id = $3))
+		ORDER BY rank, v.id
+		LIMIT $2`,
+		venueID, limit, models.TenantFilter(ctx),
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var competitors []VenueRank
+	for rows.Next() {
+		var competitor VenueRank
+		if err := rows.Scan(&competitor.VenueID, &competitor.VenueName, &competitor.AverageRating, &competitor.Rank); err != nil {
+			return nil, err
+		}
+		competitors = append(competitors, competitor)
+	}
+	return competitors, rows.Err()
+}
+
+// Platform-wide analytics helper methods would follow similar patterns...
+func (as *AnalyticsService) getPlatformOverviewMetrics(ctx context.Context, analytics *PlatformAnalytics) error {
+	err := as.readPlatformRollups(ctx, analytics)
+	if err == nil && analytics.StatsUpdatedAt == nil {
+		// The rollup job has never run (fresh install), build the counters inline once
+		err = as.RollupPlatformStats(ctx)
+		if err == nil {
+			err = as.readPlatformRollups(ctx, analytics)
+		}
+	}
 
 	return err
 }
 
-func (as *AnalyticsService) getPlatformActivityMetrics(startDate, endDate time.Time, analytics *PlatformAnalytics) error {
+func (as *AnalyticsService) getPlatformActivityMetrics(ctx context.Context, startDate, endDate time.Time, analytics *PlatformAnalytics) error {
 	// Daily active users
-	err := databases.PostgresDB.QueryRow(
+	err := models.LoggedQueryRow(ctx,
 		`SELECT COUNT(DISTINCT user_id) FROM venue_checkins WHERE created_at >= CURRENT_DATE`,
 	).Scan(&analytics.DailyActiveUsers)
 
@@ -722,7 +921,7 @@ func (as *AnalyticsService) getPlatformActivityMetrics(startDate, endDate time.T
 	}
 
 	// Weekly active users
-	err = databases.PostgresDB.QueryRow(
+	err = models.LoggedQueryRow(ctx,
 		`SELECT COUNT(DISTINCT user_id) FROM venue_checkins WHERE created_at >= CURRENT_DATE - INTERVAL '7 days'`,
 	).Scan(&analytics.WeeklyActiveUsers)
 
@@ -731,7 +930,7 @@ func (as *AnalyticsService) getPlatformActivityMetrics(startDate, endDate time.T
 	}
 
 	// Monthly active users
-	err = databases.PostgresDB.QueryRow(
+	err = models.LoggedQueryRow(ctx,
 		`SELECT COUNT(DISTINCT user_id) FROM venue_checkins WHERE created_at >= CURRENT_DATE - INTERVAL '30 days'`,
 	).Scan(&analytics.MonthlyActiveUsers)
 
@@ -742,7 +941,7 @@ func (as *AnalyticsService) getPlatformActivityMetrics(startDate, endDate time.T
 	return nil
 }
 
-func (as *AnalyticsService) getPlatformContentMetrics(startDate, endDate time.Time, analytics *PlatformAnalytics) error {
+func (as *AnalyticsService) getPlatformContentMetrics(ctx context.Context, startDate, endDate time.Time, analytics *PlatformAnalytics) error {
 	// Reviews per day
 	reviewQuery := `
 		SELECT DATE(created_at), COUNT(*) 
@@ -751,7 +950,7 @@ func (as *AnalyticsService) getPlatformContentMetrics(startDate, endDate time.Ti
 		GROUP BY DATE(created_at) 
 		ORDER BY DATE(created_at)`
 
-	rows, err := databases.PostgresDB.Query(reviewQuery, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, reviewQuery, startDate, endDate)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -771,7 +970,7 @@ func (as *AnalyticsService) getPlatformContentMetrics(startDate, endDate time.Ti
 	return nil
 }
 
-func (as *AnalyticsService) getPlatformTopMetrics(startDate, endDate time.Time, analytics *PlatformAnalytics) error {
+func (as *AnalyticsService) getPlatformTopMetrics(ctx context.Context, startDate, endDate time.Time, analytics *PlatformAnalytics) error {
 	// Top categories
 	categoryQuery := `
 		SELECT vc.name, COUNT(v.id) as venue_count, COUNT(vr.id) as review_count, AVG(v.average_rating) as avg_rating
@@ -782,7 +981,7 @@ func (as *AnalyticsService) getPlatformTopMetrics(startDate, endDate time.Time,
 		ORDER BY venue_count DESC, review_count DESC
 		LIMIT 10`
 
-	rows, err := databases.PostgresDB.Query(categoryQuery, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, categoryQuery, startDate, endDate)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -800,7 +999,7 @@ func (as *AnalyticsService) getPlatformTopMetrics(startDate, endDate time.Time,
 	return nil
 }
 
-func (as *AnalyticsService) getPlatformSearchAnalytics(startDate, endDate time.Time, analytics *PlatformAnalytics) error {
+func (as *AnalyticsService) getPlatformSearchAnalytics(ctx context.Context, startDate, endDate time.Time, analytics *PlatformAnalytics) error {
 	// Top search queries
 	queryQuery := `
 		SELECT search_query, COUNT(*) as search_count, 
@@ -812,7 +1011,7 @@ func (as *AnalyticsService) getPlatformSearchAnalytics(startDate, endDate time.T
 		ORDER BY search_count DESC
 		LIMIT 20`
 
-	rows, err := databases.PostgresDB.Query(queryQuery, startDate, endDate)
+	rows, err := models.LoggedQuery(ctx, queryQuery, startDate, endDate)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -831,12 +1030,12 @@ func (as *AnalyticsService) getPlatformSearchAnalytics(startDate, endDate time.T
 }
 
 // GetTopPerformingVenues returns the best performing venues
-func (as *AnalyticsService) GetTopPerformingVenues(timeRange string, category *int64, city *int64, limit int) ([]VenueAnalytics, error) {
+func (as *AnalyticsService) GetTopPerformingVenues(ctx context.Context, timeRange string, category *int64, city *int64, limit int) ([]VenueAnalytics, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
 
-	startDate, endDate, err := as.parseTimeRange(timeRange)
+	startDate, endDate, err := as.parseTimeRange(timeRange, time.Local)
 	if err != nil {
 		return nil, err
 	}
@@ -854,10 +1053,11 @@ func (as *AnalyticsService) GetTopPerformingVenues(timeRange string, category *i
 			WHERE date BETWEEN $1 AND $2
 			GROUP BY venue_id
 		) va ON v.id = va.venue_id
-		WHERE v.is_active = true AND v.total_ratings >= 5`
+		WHERE v.is_active = true AND v.total_ratings >= 5
+		  AND ($3::bigint IS NULL OR v.tenant_id = $3)`
 
-	args := []interface{}{startDate, endDate}
-	argCount := 2
+	args := []interface{}{startDate, endDate, models.TenantFilter(ctx)}
+	argCount := 3
 
 	if category != nil {
 		argCount++
@@ -878,7 +1078,7 @@ func (as *AnalyticsService) GetTopPerformingVenues(timeRange string, category *i
 		LIMIT $` + fmt.Sprintf("%d", argCount+1)
 	args = append(args, limit)
 
-	rows, err := databases.PostgresDB.Query(query, args...)
+	rows, err := models.LoggedQuery(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"voting-app/app/models"
+)
+
+// BusynessService serves the live crowd levels and wait times reported at
+// check-in
+type BusynessService struct{}
+
+// AttachBusyness sets the live busyness of the venues that have recent
+// reports
+func (bs *BusynessService) AttachBusyness(ctx context.Context, venues []models.Venue) error {
+	venueIDs := make([]int64, len(venues))
+	for i, venue := range venues {
+		venueIDs[i] = venue.ID
+	}
+
+	busyness, err := models.GetVenueBusyness(ctx, venueIDs)
+	if err != nil {
+		return err
+	}
+	for i := range venues {
+		venues[i].Busyness = busyness[venues[i].ID]
+	}
+	return nil
+}
+
+// ExpireWaitReports ages out the wait reports past their retention
+func (bs *BusynessService) ExpireWaitReports(ctx context.Context) error {
+	_, err := models.DeleteStaleWaitReports(ctx)
+	return err
+}
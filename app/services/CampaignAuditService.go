@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+// AuditSignatureAlgorithm is how campaign certificates are signed
+const AuditSignatureAlgorithm = "Ed25519"
+
+// campaignAuditKey signs campaign certificates. Its public key is published,
+// so anyone can verify a certificate without being able to sign one.
+var campaignAuditKey ed25519.PrivateKey
+
+func init() {
+	// Required and validated with the configuration
+	campaignAuditKey, _ = config.ParseEd25519PrivateKey(config.Get().CampaignAuditKey)
+}
+
+// CampaignAuditService certifies the final results of campaigns. The
+// certificates are signed with the campaign audit key.
+type CampaignAuditService struct{}
+
+// Certify signs and stores the certificate of the campaign's final results.
+// A campaign is certified once, certifying again returns the stored file.
+func (as *CampaignAuditService) Certify(ctx context.Context, campaign *models.VotingCampaign, results *models.CampaignResults, now time.Time) (*models.CampaignAuditFile, error) {
+	audit, err := models.NewCampaignAudit(ctx, campaign, results, now)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := json.Marshal(audit)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.StoreCampaignAudit(ctx, campaign.ID, &models.CampaignAuditFile{
+		Certificate:        certificate,
+		SignatureAlgorithm: AuditSignatureAlgorithm,
+		Signature:          as.sign(certificate),
+	})
+}
+
+// GetAudit returns the certificate of the campaign, certifying finalized
+// campaigns whose certification failed or that were finalized before
+// certificates existed. sql.ErrNoRows while the results aren't final.
+func (as *CampaignAuditService) GetAudit(ctx context.Context, campaign *models.VotingCampaign) (*models.CampaignAuditFile, error) {
+	if campaign.ResultsFinalizedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	file, err := models.GetCampaignAudit(ctx, campaign.ID)
+	if err != sql.ErrNoRows {
+		return file, err
+	}
+
+	results, err := models.GetCampaignResults(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	results.IsFinal = true
+	return as.Certify(ctx, campaign, results, time.Now())
+}
+
+// Verify checks the signature of a certificate
+func (as *CampaignAuditService) Verify(file *models.CampaignAuditFile) bool {
+	if file.SignatureAlgorithm != AuditSignatureAlgorithm {
+		return false
+	}
+	signature, err := hex.DecodeString(file.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(campaignAuditKey.Public().(ed25519.PublicKey), file.Certificate, signature)
+}
+
+// PublicKey returns the PEM encoded public key certificates are verified
+// with
+func (as *CampaignAuditService) PublicKey() string {
+	der, _ := x509.MarshalPKIXPublicKey(campaignAuditKey.Public())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// sign returns the hex Ed25519 signature of the certificate bytes
+func (as *CampaignAuditService) sign(certificate []byte) string {
+	return hex.EncodeToString(ed25519.Sign(campaignAuditKey, certificate))
+}
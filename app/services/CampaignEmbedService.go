@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	// DefaultEmbedTokenTTL is how long embed tokens work when no expiry is
+	// asked for
+	DefaultEmbedTokenTTL = 30 * 24 * time.Hour
+	// MaxEmbedOrigins bounds the origins an embed token allows
+	MaxEmbedOrigins = 10
+	// embedWidgetVenues is the number of venues a widget shows per category
+	embedWidgetVenues = 10
+)
+
+// ErrInvalidEmbedToken is returned for embed tokens that are malformed,
+// wrongly signed or expired
+var ErrInvalidEmbedToken = errors.New("embed token is invalid or expired")
+
+// EmbedToken lets the pages of its origins embed the results widget of a
+// campaign until it expires. Tokens aren't stored: the fields are encoded in
+// the token and signed with the vote receipt key.
+type EmbedToken struct {
+	CampaignID int64    `json:"c"`
+	Origins    []string `json:"o"`
+	ExpiresAt  int64    `json:"e"` // Unix seconds
+}
+
+// Allows reports whether pages of the origin may embed the widget
+func (t *EmbedToken) Allows(origin string) bool {
+	for _, allowed := range t.Origins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// CampaignWidget is the public part of a campaign's results shown by
+// embedded widgets
+type CampaignWidget struct {
+	CampaignID         int64            `json:"campaignId"`
+	Title              string           `json:"title"`
+	TotalVotes         int              `json:"totalVotes"`
+	IsFinal            bool             `json:"isFinal"`
+	ResultsHidden      bool             `json:"resultsHidden,omitempty"`
+	ResultsAvailableAt *time.Time       `json:"resultsAvailableAt,omitempty"`
+	Categories         []WidgetCategory `json:"categories"`
+	GeneratedAt        time.Time        `json:"generatedAt"`
+}
+
+// WidgetCategory holds the top venues of a campaign category
+type WidgetCategory struct {
+	Name      string           `json:"name"`
+	Standings []WidgetStanding `json:"standings"`
+}
+
+// WidgetStanding is the rank of a venue in a widget
+type WidgetStanding struct {
+	Rank      int    `json:"rank"`
+	VenueName string `json:"venueName"`
+	Votes     int    `json:"votes"`
+}
+
+// CampaignEmbedService issues the tokens of embedded campaign widgets and
+// builds the widgets
+type CampaignEmbedService struct{}
+
+// Issue returns a token letting the origins embed the campaign's widget
+// until expiresAt. The origins must be normalized by NormalizeOrigin.
+func (es *CampaignEmbedService) Issue(campaignID int64, origins []string, expiresAt time.Time) (string, error) {
+	payload, err := json.Marshal(EmbedToken{
+		CampaignID: campaignID,
+		Origins:    origins,
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + es.sign(encoded), nil
+}
+
+// Parse checks the token's signature and expiry and returns its fields,
+// ErrInvalidEmbedToken when it can't be used
+func (es *CampaignEmbedService) Parse(token string, now time.Time) (*EmbedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(es.sign(parts[0]))) {
+		return nil, ErrInvalidEmbedToken
+	}
+	encoded := parts[0]
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidEmbedToken
+	}
+	var embed EmbedToken
+	if err := json.Unmarshal(payload, &embed); err != nil {
+		return nil, ErrInvalidEmbedToken
+	}
+	if !now.Before(time.Unix(embed.ExpiresAt, 0)) {
+		return nil, ErrInvalidEmbedToken
+	}
+	return &embed, nil
+}
+
+// Widget returns the public results of the campaign. Embargoed standings
+// stay hidden, whoever embeds them.
+func (es *CampaignEmbedService) Widget(ctx context.Context, campaign *models.VotingCampaign, now time.Time) (*CampaignWidget, error) {
+	results, err := models.GetCampaignResults(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ResultsHidden(now) {
+		results.HideStandings(campaign.EndDate)
+	}
+
+	widget := &CampaignWidget{
+		CampaignID:         campaign.ID,
+		Title:              campaign.Title,
+		TotalVotes:         results.TotalVotes,
+		IsFinal:            campaign.ResultsFinalizedAt != nil,
+		ResultsHidden:      results.ResultsHidden,
+		ResultsAvailableAt: results.ResultsAvailableAt,
+		Categories:         make([]WidgetCategory, len(results.Categories)),
+		GeneratedAt:        results.GeneratedAt,
+	}
+	for i, category := range results.Categories {
+		standings := category.Standings
+		if len(standings) > embedWidgetVenues {
+			standings = standings[:embedWidgetVenues]
+		}
+		widget.Categories[i] = WidgetCategory{
+			Name:      category.Name,
+			Standings: make([]WidgetStanding, len(standings)),
+		}
+		for j, standing := range standings {
+			widget.Categories[i].Standings[j] = WidgetStanding{
+				Rank:      standing.Rank,
+				VenueName: standing.VenueName,
+				Votes:     standing.Votes,
+			}
+		}
+	}
+	return widget, nil
+}
+
+// RenderWidget returns the widget as a standalone HTML page for iframes
+func (es *CampaignEmbedService) RenderWidget(widget *CampaignWidget) ([]byte, error) {
+	var page bytes.Buffer
+	if err := widgetTemplate.Execute(&page, widget); err != nil {
+		return nil, err
+	}
+	return page.Bytes(), nil
+}
+
+// sign returns the base64 HMAC-SHA256 of the encoded token fields
+func (es *CampaignEmbedService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, voteReceiptSecret)
+	mac.Write([]byte("embed|" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NormalizeOrigin returns the origin, scheme and host, of an http or https
+// URL in lower case, false when it isn't one
+func NormalizeOrigin(raw string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" || parsed.User != nil {
+		return "", false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", false
+	}
+	return scheme + "://" + strings.ToLower(parsed.Host), true
+}
+
+// RequestOrigin returns the origin of the page making the request: its
+// Origin header, or the origin of its Referer for iframes, which browsers
+// load without an Origin. It is "" when the request names neither.
+func RequestOrigin(req *http.Request) string {
+	for _, header := range []string{"Origin", "Referer"} {
+		if origin, ok := NormalizeOrigin(req.Header.Get(header)); ok {
+			return origin
+		}
+	}
+	return ""
+}
+
+// widgetTemplate is the HTML of embedded widgets, styled inline so that
+// they load nothing else
+var widgetTemplate = template.Must(template.New("widget").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:0;padding:12px;color:#222}
+h1{font-size:18px;margin:0 0 8px}
+h2{font-size:15px;margin:12px 0 4px}
+ol{margin:0;padding-left:24px}
+li{padding:2px 0}
+.votes{float:right}
+.note{color:#666;font-size:13px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .ResultsHidden}}<p class="note">Results are announced {{with .ResultsAvailableAt}}on {{.Format "January 2, 2006"}}{{else}}when voting ends{{end}}.</p>
+{{else}}{{range .Categories}}<h2>{{.Name}}</h2>
+<ol>
+{{range .Standings}}<li value="{{.Rank}}">{{.VenueName}}<span class="votes">{{.Votes}}</span></li>
+{{end}}</ol>
+{{end}}{{end}}<p class="note">{{.TotalVotes}} votes{{if .IsFinal}}, final results{{end}}</p>
+</body>
+</html>
+`))
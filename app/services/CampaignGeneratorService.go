@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	// DefaultCampaignNominees is how many venues a generated campaign
+	// nominates unless asked otherwise
+	DefaultCampaignNominees = 10
+	// MinCampaignNominees is the fewest nominees a campaign is published with
+	MinCampaignNominees = 2
+	// NomineeMinReviews is the fewest approved reviews a venue needs to be
+	// nominated
+	NomineeMinReviews = 3
+
+	// nomineeScoringWindow is the period growth is measured over, compared
+	// to the one before it
+	nomineeScoringWindow = 90 * 24 * time.Hour
+
+	// Weights of the candidate score parts
+	nomineeRatingWeight = 0.5
+	nomineeVolumeWeight = 0.3
+	nomineeGrowthWeight = 0.2
+
+	// nomineePriorRating and nomineePriorReviews pull the ratings of venues
+	// with few reviews towards an average rating
+	nomineePriorRating  = 3.5
+	nomineePriorReviews = 5
+)
+
+// Campaign generation errors
+var (
+	ErrNotEnoughCandidates = errors.New("not enough venues with reviews to nominate")
+	ErrInvalidQuarter      = errors.New("quarter must look like 2026-Q3")
+)
+
+// CampaignProposal asks for a "best of" campaign of a city and venue
+// category
+type CampaignProposal struct {
+	CityID          int64
+	CategoryID      int64
+	Quarter         string // Like "2026-Q3", defaults to the next quarter
+	Title           string // Defaults to "Best Cafes in San Francisco Q3 2026"
+	Nominees        int
+	MaxVotesPerUser int
+	// HideResultsUntilEnd embargoes the standings while voting runs
+	HideResultsUntilEnd bool
+	// RequireOTP holds votes until confirmed with an emailed code
+	RequireOTP bool
+	// AllowKioskSessions lets credentialed kiosks open anonymous sessions
+	AllowKioskSessions bool
+	// VoteChangeMinutes is how long votes can be changed after they are
+	// cast, 0 for final votes, until the campaign ends when nil
+	VoteChangeMinutes *int
+}
+
+// CampaignGeneratorService proposes "best of" campaigns from analytics
+type CampaignGeneratorService struct{}
+
+// Generate creates an unpublished campaign running for the proposal's
+// quarter, nominating the best scored venues of the city and category.
+// Venues are scored by their rating, review volume, and growth in reviews
+// and profile views over the last 90 days. The campaign stays inactive until
+// an administrator reviewed the nominees and published it. Returns
+// sql.ErrNoRows when the city or category doesn't exist.
+func (cs *CampaignGeneratorService) Generate(ctx context.Context, proposal CampaignProposal, now time.Time) (*models.VotingCampaign, []models.CampaignNominee, error) {
+	start, quarter, err := quarterStart(proposal.Quarter, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cityName, categoryName, err := models.GetCityAndCategoryNames(ctx, proposal.CityID, proposal.CategoryID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	since := now.Add(-nomineeScoringWindow)
+	candidates, err := models.GetNomineeCandidates(ctx, proposal.CityID, proposal.CategoryID,
+		since, since.Add(-nomineeScoringWindow), NomineeMinReviews)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) < MinCampaignNominees {
+		return nil, nil, ErrNotEnoughCandidates
+	}
+
+	count := proposal.Nominees
+	if count == 0 {
+		count = DefaultCampaignNominees
+	}
+	nominees := nominateCandidates(candidates, count)
+
+	title := proposal.Title
+	if title == "" {
+		title = fmt.Sprintf("Best %s in %s %s", pluralize(categoryName), cityName, quarter)
+	}
+	maxVotes := proposal.MaxVotesPerUser
+	if maxVotes == 0 {
+		maxVotes = 1
+	}
+	campaign := &models.VotingCampaign{
+		Title:           title,
+		Description:     fmt.Sprintf("The %d best rated and fastest growing %s in %s, nominated from the last 90 days of reviews and visits.", len(nominees), strings.ToLower(pluralize(categoryName)), cityName),
+		CampaignType:    models.CampaignTypeBestOf,
+		CityID:          &proposal.CityID,
+		CategoryID:      &proposal.CategoryID,
+		StartDate:       start,
+		EndDate:         start.AddDate(0, 3, 0),
+		MaxVotesPerUser: maxVotes,
+		IsActive:        false,
+
+		HideResultsUntilEnd: proposal.HideResultsUntilEnd,
+		RequireOTP:          proposal.RequireOTP,
+		AllowKioskSessions:  proposal.AllowKioskSessions,
+		VoteChangeMinutes:   proposal.VoteChangeMinutes,
+	}
+	if err := campaign.CreateWithNominees(ctx, nominees); err != nil {
+		return nil, nil, err
+	}
+	return campaign, nominees, nil
+}
+
+// nominateCandidates scores the candidates and returns the best count of
+// them as nominees
+func nominateCandidates(candidates []models.NomineeCandidate, count int) []models.CampaignNominee {
+	maxReviews := 0
+	for _, candidate := range candidates {
+		if candidate.TotalReviews > maxReviews {
+			maxReviews = candidate.TotalReviews
+		}
+	}
+
+	nominees := make([]models.CampaignNominee, len(candidates))
+	for i, candidate := range candidates {
+		rating := (candidate.AverageRating*float64(candidate.TotalReviews) + nomineePriorRating*nomineePriorReviews) /
+			float64(candidate.TotalReviews+nomineePriorReviews) / 5
+		volume := math.Log1p(float64(candidate.TotalReviews)) / math.Log1p(float64(maxReviews))
+		growth := (growthRatio(candidate.RecentReviews, candidate.PriorReviews) +
+			growthRatio(candidate.RecentViews, candidate.PriorViews) + 2) / 4
+
+		score := math.Round((nomineeRatingWeight*rating+nomineeVolumeWeight*volume+nomineeGrowthWeight*growth)*1000) / 1000
+		nominees[i] = models.CampaignNominee{
+			VenueID:   candidate.VenueID,
+			VenueName: candidate.VenueName,
+			VenueSlug: candidate.VenueSlug,
+			Score:     &score,
+		}
+	}
+
+	sort.SliceStable(nominees, func(i, j int) bool {
+		return *nominees[i].Score > *nominees[j].Score
+	})
+	if len(nominees) > count {
+		nominees = nominees[:count]
+	}
+	return nominees
+}
+
+// growthRatio compares a count to the one of the period before, from -1
+// for a drop to nothing to 1 for growth from nothing
+func growthRatio(recent, prior int) float64 {
+	if recent+prior == 0 {
+		return 0
+	}
+	return float64(recent-prior) / float64(recent+prior)
+}
+
+// quarterStart returns the start of the quarter, like "2026-Q3", and its
+// label, "Q3 2026". An empty quarter is the one after now's.
+func quarterStart(quarter string, now time.Time) (time.Time, string, error) {
+	var year, number int
+	if quarter == "" {
+		now = now.UTC()
+		year, number = now.Year(), (int(now.Month())-1)/3+2
+		if number > 4 {
+			year, number = year+1, 1
+		}
+	} else {
+		parts := strings.SplitN(strings.ToUpper(quarter), "-Q", 2)
+		if len(parts) != 2 {
+			return time.Time{}, "", ErrInvalidQuarter
+		}
+		var err error
+		if year, err = strconv.Atoi(parts[0]); err != nil || year < 2000 || year > 9999 {
+			return time.Time{}, "", ErrInvalidQuarter
+		}
+		if number, err = strconv.Atoi(parts[1]); err != nil || number < 1 || number > 4 {
+			return time.Time{}, "", ErrInvalidQuarter
+		}
+	}
+	start := time.Date(year, time.Month((number-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	return start, fmt.Sprintf("Q%d %d", number, year), nil
+}
+
+// pluralize makes a venue category name plural, "Cafe" to "Cafes"
+func pluralize(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case len(lower) < 2:
+		return name
+	case strings.HasSuffix(lower, "y") && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(lower, "s") || strings.HasSuffix(lower, "x") ||
+		strings.HasSuffix(lower, "ch") || strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// CampaignResultService snapshots the results of running campaigns and
+// finalizes the winners of closed ones
+type CampaignResultService struct{}
+
+// SnapshotCampaignResults stores a results snapshot of every running
+// campaign. Campaigns that have ended get their final snapshot and their
+// per category winners recorded. It is run periodically by the job runner.
+func (rs *CampaignResultService) SnapshotCampaignResults(ctx context.Context) error {
+	now := time.Now().UTC()
+	campaigns, err := models.GetCampaignsPendingResults(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range campaigns {
+		if err := rs.Snapshot(ctx, &campaigns[i], now); err != nil {
+			// Keep going, the campaign is retried on the next run
+			sentry.CaptureException(err)
+		}
+	}
+
+	return nil
+}
+
+// Snapshot stores the current results of the campaign, finalizing them once
+// voting has ended
+func (rs *CampaignResultService) Snapshot(ctx context.Context, campaign *models.VotingCampaign, now time.Time) error {
+	results, err := models.GetCampaignResults(ctx, campaign)
+	if err != nil {
+		return err
+	}
+
+	if !now.Before(campaign.EndDate) {
+		_, err = models.FinalizeCampaignResults(ctx, results)
+		if err != nil {
+			return err
+		}
+
+		// A failed certification is retried when the audit file is first
+		// downloaded
+		auditService := &CampaignAuditService{}
+		if _, err := auditService.Certify(ctx, campaign, results, now); err != nil {
+			sentry.CaptureException(err)
+		}
+
+		webhookService := &WebhookService{}
+		if err := webhookService.Publish(ctx, models.WebhookEventCampaignResults, nil, results); err != nil {
+			sentry.CaptureException(err)
+		}
+
+		voterIDs, err := models.GetCampaignVoterIDs(ctx, campaign.ID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil
+		}
+		notificationService := &NotificationService{}
+		notificationService.NotifyCampaignResults(ctx, voterIDs, campaign.ID, campaign.Title)
+		return nil
+	}
+
+	snapshot := &models.CampaignResultSnapshot{}
+	return snapshot.Create(ctx, results)
+}
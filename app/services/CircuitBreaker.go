@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// breakerStateValues are the states as exported in the metrics
+var breakerStateValues = map[string]int{
+	BreakerClosed:   0,
+	BreakerOpen:     1,
+	BreakerHalfOpen: 2,
+}
+
+// ErrCircuitOpen is returned without calling the dependency while its
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling a failing dependency so its outage doesn't
+// hold up requests. It opens after FailureThreshold consecutive failures and
+// rejects calls for Cooldown, then lets one trial call through: the breaker
+// closes when it succeeds and opens again when it fails.
+type CircuitBreaker struct {
+	Name             string
+	FailureThreshold int
+	Cooldown         time.Duration
+	Timeout          time.Duration // Bounds each call, 0 for no bound
+
+	mu       sync.Mutex
+	state    string
+	failures int // Consecutive
+	openedAt time.Time
+	trial    bool // A half open trial call is running
+
+	failuresTotal   int64
+	rejectionsTotal int64
+}
+
+// BreakerStatus is the state of a circuit breaker
+type BreakerStatus struct {
+	Name            string     `json:"name"`
+	State           string     `json:"state"`
+	Failures        int        `json:"consecutiveFailures"`
+	OpenedAt        *time.Time `json:"openedAt,omitempty"`
+	FailuresTotal   int64      `json:"-"`
+	RejectionsTotal int64      `json:"-"`
+}
+
+// NewCircuitBreaker returns a closed breaker
+func NewCircuitBreaker(name string, failureThreshold int, cooldown, timeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Name:             name,
+		FailureThreshold: failureThreshold,
+		Cooldown:         cooldown,
+		Timeout:          timeout,
+		state:            BreakerClosed,
+	}
+}
+
+// Call runs fn unless the breaker is open, ErrCircuitOpen otherwise. Calls
+// failing or outlasting the timeout count as failures, unless the caller
+// gave up on them first.
+func (cb *CircuitBreaker) Call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !cb.allow(time.Now()) {
+		return ErrCircuitOpen
+	}
+
+	callCtx := ctx
+	if cb.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, cb.Timeout)
+		defer cancel()
+	}
+	err := fn(callCtx)
+	if err != nil && callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		err = fmt.Errorf("%s timed out after %s: %w", cb.Name, cb.Timeout, err)
+	}
+
+	cb.record(err, ctx.Err() != nil, time.Now())
+	return err
+}
+
+// allow tells whether a call may go through, moving an open breaker whose
+// cooldown passed to half open
+func (cb *CircuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.currentState() {
+	case BreakerOpen:
+		if now.Sub(cb.openedAt) < cb.Cooldown {
+			cb.rejectionsTotal++
+			return false
+		}
+		cb.state = BreakerHalfOpen
+	case BreakerHalfOpen:
+		if cb.trial {
+			cb.rejectionsTotal++
+			return false
+		}
+	}
+	if cb.state == BreakerHalfOpen {
+		cb.trial = true
+	}
+	return true
+}
+
+// record counts the outcome of a call. Calls the caller abandoned tell
+// nothing about the dependency and only end a trial.
+func (cb *CircuitBreaker) record(err error, abandoned bool, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	halfOpen := cb.currentState() == BreakerHalfOpen
+	cb.trial = false
+	switch {
+	case err == nil:
+		cb.state, cb.failures = BreakerClosed, 0
+	case abandoned:
+	default:
+		cb.failures++
+		cb.failuresTotal++
+		if halfOpen || cb.failures >= cb.FailureThreshold {
+			cb.state, cb.openedAt = BreakerOpen, now
+		}
+	}
+}
+
+func (cb *CircuitBreaker) currentState() string {
+	if cb.state == "" {
+		return BreakerClosed
+	}
+	return cb.state
+}
+
+// Status returns the state of the breaker. An open breaker whose cooldown
+// passed stays open until the next call tries the dependency.
+func (cb *CircuitBreaker) Status() BreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	status := BreakerStatus{
+		Name:            cb.Name,
+		State:           cb.currentState(),
+		Failures:        cb.failures,
+		FailuresTotal:   cb.failuresTotal,
+		RejectionsTotal: cb.rejectionsTotal,
+	}
+	if status.State != BreakerClosed {
+		openedAt := cb.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Reset closes the breaker, used when the dependency changes
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.state, cb.failures, cb.trial = BreakerClosed, 0, false
+}
+
+// Breakers returns the breakers guarding the external dependencies
+func Breakers() []*CircuitBreaker {
+	return []*CircuitBreaker{GeocoderBreaker}
+}
+
+// WriteBreakerMetrics writes the state and counters of the breakers in the
+// Prometheus text format
+func WriteBreakerMetrics(w io.Writer) error {
+	metrics := []struct {
+		name, kind, help string
+		value            func(status BreakerStatus) interface{}
+	}{
+		{"circuit_breaker_state", "gauge", "State of the circuit breaker, 0 closed, 1 open, 2 half open",
+			func(status BreakerStatus) interface{} { return breakerStateValues[status.State] }},
+		{"circuit_breaker_consecutive_failures", "gauge", "Failed calls since the last success",
+			func(status BreakerStatus) interface{} { return status.Failures }},
+		{"circuit_breaker_failures_total", "counter", "Failed calls",
+			func(status BreakerStatus) interface{} { return status.FailuresTotal }},
+		{"circuit_breaker_rejections_total", "counter", "Calls rejected while the breaker was open",
+			func(status BreakerStatus) interface{} { return status.RejectionsTotal }},
+	}
+
+	statuses := make([]BreakerStatus, 0, len(Breakers()))
+	for _, breaker := range Breakers() {
+		statuses = append(statuses, breaker.Status())
+	}
+	for _, metric := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if _, err := fmt.Fprintf(w, "%s{breaker=%q} %v\n", metric.name, status.Name, metric.value(status)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
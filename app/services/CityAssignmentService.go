@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"voting-app/app/models"
+)
+
+// City assignment thresholds
+const (
+	// NearestCityRadiusKm is how far from a city's center venues still belong
+	// to it
+	NearestCityRadiusKm = 50.0
+	// MaxClaimedCityDistanceKm is how far from the submitted city a venue may
+	// be when no city is within NearestCityRadiusKm
+	MaxClaimedCityDistanceKm = 100.0
+)
+
+// City assignment errors
+var (
+	ErrUnknownCity  = errors.New("city not found")
+	ErrNoNearbyCity = errors.New("no city near the coordinates")
+	ErrCityTooFar   = errors.New("coordinates are too far from the city")
+)
+
+// CityAssignment is the city a venue is placed in
+type CityAssignment struct {
+	City       models.City
+	DistanceKm float64 // From the venue to the city center, 0 when the city has no coordinates
+	Corrected  bool    // The city differs from the submitted one
+}
+
+// CityAssignmentService places venues in the city their coordinates are in
+type CityAssignmentService struct{}
+
+// AssignCity checks the submitted city against the coordinates. The
+// submitted city is kept when the venue is within NearestCityRadiusKm of it,
+// otherwise the nearest city within that radius is assigned instead. Without
+// a nearby city the submitted one is kept up to MaxClaimedCityDistanceKm away.
+// A claimedCityID of 0 assigns the nearest city.
+func (cs *CityAssignmentService) AssignCity(ctx context.Context, claimedCityID int64, latitude, longitude float64) (*CityAssignment, error) {
+	cities, err := models.GetActiveCities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	geoService := &GeolocationService{}
+	var claimed, nearest *CityAssignment
+	for _, city := range cities {
+		assignment := &CityAssignment{City: city}
+		if city.HasCoordinates {
+			assignment.DistanceKm = geoService.CalculateDistance(latitude, longitude, city.Latitude, city.Longitude).Kilometers
+			if nearest == nil || assignment.DistanceKm < nearest.DistanceKm {
+				nearest = assignment
+			}
+		}
+		if city.ID == claimedCityID {
+			claimed = assignment
+		}
+	}
+
+	if claimedCityID != 0 && claimed == nil {
+		return nil, ErrUnknownCity
+	}
+
+	if claimed != nil && (!claimed.City.HasCoordinates || claimed.DistanceKm <= NearestCityRadiusKm) {
+		return claimed, nil
+	}
+
+	if nearest != nil && nearest.DistanceKm <= NearestCityRadiusKm {
+		nearest.Corrected = claimed != nil
+		return nearest, nil
+	}
+
+	if claimed == nil {
+		return nil, ErrNoNearbyCity
+	}
+	if claimed.DistanceKm > MaxClaimedCityDistanceKm {
+		return nil, ErrCityTooFar
+	}
+	return claimed, nil
+}
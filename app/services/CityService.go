@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// cityCacheTTL is how long the listed cities and districts are served from
+// memory before reloading. Changes made through CityService apply at once
+// on this instance.
+const cityCacheTTL = 5 * time.Minute
+
+// MaxDistrictDistanceKm is how far the centroid of a district may be from
+// the centroid of its city
+const MaxDistrictDistanceKm = 100.0
+
+// ErrDistrictTooFar is returned when a district's centroid is more than
+// MaxDistrictDistanceKm away from its city's
+var ErrDistrictTooFar = errors.New("district is too far from its city")
+
+// CityService onboards cities and their districts and lists them
+type CityService struct{}
+
+// cityCache holds the active cities and the districts of those listed since
+// the cities were loaded
+var cityCache struct {
+	sync.RWMutex
+	cities    []models.City
+	districts map[int64][]models.District
+	loadedAt  time.Time
+}
+
+// GetCities returns the active cities
+func (s *CityService) GetCities(ctx context.Context) ([]models.City, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	cityCache.RLock()
+	defer cityCache.RUnlock()
+	return cityCache.cities, nil
+}
+
+// GetDistricts returns an active city with its districts, ErrUnknownCity
+// when the city isn't active
+func (s *CityService) GetDistricts(ctx context.Context, cityID int64) (*models.City, []models.District, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	cityCache.RLock()
+	var city *models.City
+	for i := range cityCache.cities {
+		if cityCache.cities[i].ID == cityID {
+			found := cityCache.cities[i]
+			city = &found
+			break
+		}
+	}
+	districts, cached := cityCache.districts[cityID]
+	cityCache.RUnlock()
+	if city == nil {
+		return nil, nil, ErrUnknownCity
+	}
+	if cached {
+		return city, districts, nil
+	}
+
+	districts, err := models.GetCityDistricts(ctx, cityID)
+	if err != nil {
+		return nil, nil, err
+	}
+	cityCache.Lock()
+	if cityCache.districts != nil {
+		cityCache.districts[cityID] = districts
+	}
+	cityCache.Unlock()
+	return city, districts, nil
+}
+
+// CreateCity onboards a city, models.ErrCityExists when the country
+// already has it
+func (s *CityService) CreateCity(ctx context.Context, city *models.City) error {
+	if err := city.Create(ctx); err != nil {
+		return err
+	}
+	invalidateCities()
+	return nil
+}
+
+// UpdateCity replaces a city's names, centroid and timezone, and lists or
+// unlists it. It returns sql.ErrNoRows when the city doesn't exist.
+func (s *CityService) UpdateCity(ctx context.Context, city *models.City) error {
+	if err := city.Update(ctx); err != nil {
+		return err
+	}
+	invalidateCities()
+	return nil
+}
+
+// CreateDistrict adds a district to a city, listed or not. It returns
+// ErrUnknownCity when the city doesn't exist, ErrDistrictTooFar when the
+// district's centroid is far from the city's and models.ErrDistrictExists
+// when the city has it already.
+func (s *CityService) CreateDistrict(ctx context.Context, district *models.District) error {
+	if err := s.checkDistrictCentroid(ctx, district); err != nil {
+		return err
+	}
+	if err := district.Create(ctx); err != nil {
+		return err
+	}
+	invalidateCities()
+	return nil
+}
+
+// UpdateDistrict replaces a district's name and centroid, sql.ErrNoRows
+// when it doesn't exist
+func (s *CityService) UpdateDistrict(ctx context.Context, district *models.District) error {
+	existing := &models.District{ID: district.ID}
+	if err := existing.GetByID(ctx); err != nil {
+		return err
+	}
+	district.CityID = existing.CityID
+	if err := s.checkDistrictCentroid(ctx, district); err != nil {
+		return err
+	}
+	if err := district.Update(ctx); err != nil {
+		return err
+	}
+	invalidateCities()
+	return nil
+}
+
+// DeleteDistrict removes a district, sql.ErrNoRows when it doesn't exist
+func (s *CityService) DeleteDistrict(ctx context.Context, districtID int64) error {
+	if err := models.DeleteDistrict(ctx, districtID); err != nil {
+		return err
+	}
+	invalidateCities()
+	return nil
+}
+
+// checkDistrictCentroid checks that the district's city exists and that its
+// centroid is within MaxDistrictDistanceKm of the city's
+func (s *CityService) checkDistrictCentroid(ctx context.Context, district *models.District) error {
+	city := &models.City{ID: district.CityID}
+	if err := city.GetByID(ctx); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUnknownCity
+		}
+		return err
+	}
+	if !city.HasCoordinates {
+		return nil
+	}
+
+	geoService := &GeolocationService{}
+	distance := geoService.CalculateDistance(district.Latitude, district.Longitude, city.Latitude, city.Longitude)
+	if distance.Kilometers > MaxDistrictDistanceKm {
+		return ErrDistrictTooFar
+	}
+	return nil
+}
+
+// load reloads the cities when the cache is stale, forgetting the districts.
+// When reloading fails the stale cities are kept, and an error is only
+// returned without any.
+func (s *CityService) load(ctx context.Context) error {
+	cityCache.RLock()
+	loaded := cityCache.cities != nil
+	fresh := loaded && time.Since(cityCache.loadedAt) < cityCacheTTL
+	cityCache.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	cities, err := models.GetActiveCities(ctx)
+	if err != nil {
+		sentry.CaptureException(err)
+		if loaded {
+			return nil
+		}
+		return err
+	}
+
+	cityCache.Lock()
+	cityCache.cities = cities
+	cityCache.districts = make(map[int64][]models.District)
+	cityCache.loadedAt = time.Now()
+	cityCache.Unlock()
+	return nil
+}
+
+// invalidateCities makes the next listing reload the cities
+func invalidateCities() {
+	cityCache.Lock()
+	cityCache.cities = nil
+	cityCache.districts = nil
+	cityCache.Unlock()
+}
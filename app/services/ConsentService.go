@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// ConsentCodeTTL is how long a consent confirmation code can be used
+const ConsentCodeTTL = 24 * time.Hour
+
+// ConsentService records the consents users give to marketing and
+// personalization. Granting is double opt-in: the user confirms with a code
+// sent to them as a notification.
+type ConsentService struct{}
+
+// RequestConsent leaves the user's consent to the purpose pending and sends
+// them the code confirming it. models.ErrConsentGranted when the user
+// already granted it.
+func (cs *ConsentService) RequestConsent(ctx context.Context, userID int64, purpose, source string) (*models.UserConsent, error) {
+	code, err := newVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	consent := &models.UserConsent{UserID: userID, Purpose: purpose, Source: source}
+	err = consent.Request(ctx, hashVerificationCode(code), time.Now().UTC().Add(ConsentCodeTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	// The confirmation itself is no marketing and is sent without consent
+	notificationService := &NotificationService{}
+	_, err = notificationService.Notify(ctx, userID, models.NotificationConsent,
+		"Confirm your choice",
+		fmt.Sprintf("Your confirmation code for %s is %s. It expires in %d hours, ignore it if you didn't ask for this.",
+			consentDescription(purpose), code, int(ConsentCodeTTL.Hours())),
+		map[string]string{"purpose": purpose},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return consent, nil
+}
+
+// ConfirmConsent grants the user's pending consent to the purpose when the
+// code is the one sent to them, models.ErrInvalidConsentCode otherwise
+func (cs *ConsentService) ConfirmConsent(ctx context.Context, userID int64, purpose, code string) (*models.UserConsent, error) {
+	consent := &models.UserConsent{UserID: userID, Purpose: purpose}
+	err := consent.Confirm(ctx, hashVerificationCode(strings.TrimSpace(code)), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// consentDescription describes the purpose in the confirmation message
+func consentDescription(purpose string) string {
+	switch purpose {
+	case models.ConsentPushMarketing:
+		return "promotional notifications"
+	case models.ConsentEmailDigest:
+		return "email digests"
+	case models.ConsentPersonalizedRecommendations:
+		return "personalized recommendations"
+	}
+	return purpose
+}
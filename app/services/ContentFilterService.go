@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"voting-app/app/config"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Content filter verdicts, from least to most severe
+const (
+	ContentAllowed  = "allowed"
+	ContentFlagged  = "flagged"  // Published and queued for moderation
+	ContentRejected = "rejected" // Not accepted
+)
+
+// Reasons reported by the wordlist filter
+const (
+	ContentReasonProfanity   = "profanity"
+	ContentReasonFlaggedWord = "flagged_word"
+	ContentReasonLinks       = "too_many_links"
+	ContentReasonRepetition  = "repetitive_text"
+)
+
+// Spam heuristics of the wordlist filter
+const (
+	maxContentLinks          = 2
+	maxRepeatedCharacters    = 10
+	minWordsForRepetition    = 12
+	minDistinctWordsFraction = 0.3
+)
+
+var contentSeverity = map[string]int{
+	ContentAllowed:  0,
+	ContentFlagged:  1,
+	ContentRejected: 2,
+}
+
+// defaultBlockedWords are rejected unless configured otherwise
+var defaultBlockedWords = []string{
+	"fuck", "fucking", "motherfucker", "shit", "bullshit", "cunt",
+	"bitch", "asshole", "bastard", "dickhead", "wanker",
+}
+
+// ContentCheck is the outcome of filtering a piece of user written text
+type ContentCheck struct {
+	Verdict string   `json:"verdict"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// merge keeps the most severe verdict and every reason behind it
+func (c *ContentCheck) merge(other ContentCheck) {
+	if other.Verdict == ContentAllowed {
+		return
+	}
+	if contentSeverity[other.Verdict] > contentSeverity[c.Verdict] {
+		c.Verdict = other.Verdict
+	}
+next:
+	for _, reason := range other.Reasons {
+		for _, existing := range c.Reasons {
+			if existing == reason {
+				continue next
+			}
+		}
+		c.Reasons = append(c.Reasons, reason)
+	}
+}
+
+// ContentFilterBackend checks text through a single filter
+type ContentFilterBackend interface {
+	Name() string
+	Check(ctx context.Context, text string) (ContentCheck, error)
+}
+
+// ContentFilterBackends holds the configured backends, run in order
+var ContentFilterBackends []ContentFilterBackend
+
+func init() {
+	filter := config.Get().ContentFilter
+
+	ContentFilterBackends = append(ContentFilterBackends,
+		NewWordlistFilter(append(defaultBlockedWords, filter.BlockedWords...), filter.FlaggedWords))
+
+	if filter.ModerationURL != "" {
+		ContentFilterBackends = append(ContentFilterBackends, &ModerationAPIFilter{
+			URL:    filter.ModerationURL,
+			APIKey: filter.ModerationAPIKey,
+		})
+	}
+}
+
+// ContentFilterService screens reviews and other user written text for
+// profanity and spam
+type ContentFilterService struct{}
+
+// Check runs every backend over the texts and returns the most severe
+// verdict. A failing backend is skipped, so an outage of the moderation API
+// doesn't stop users from posting.
+func (cf *ContentFilterService) Check(ctx context.Context, texts ...string) ContentCheck {
+	result := ContentCheck{Verdict: ContentAllowed}
+
+	text := strings.TrimSpace(strings.Join(texts, "\n"))
+	if text == "" {
+		return result
+	}
+
+	for _, backend := range ContentFilterBackends {
+		check, err := backend.Check(ctx, text)
+		if err != nil {
+			sentry.CaptureException(fmt.Errorf("content filter %s: %w", backend.Name(), err))
+			continue
+		}
+		result.merge(check)
+	}
+
+	return result
+}
+
+// WordlistFilter rejects blocked words, flags listed words and flags text
+// that looks like spam
+type WordlistFilter struct {
+	blocked map[string]bool
+	flagged map[string]bool
+}
+
+// NewWordlistFilter creates a filter of the given words, matched case
+// insensitively as whole words
+func NewWordlistFilter(blocked, flagged []string) *WordlistFilter {
+	f := &WordlistFilter{
+		blocked: make(map[string]bool, len(blocked)),
+		flagged: make(map[string]bool, len(flagged)),
+	}
+	for _, word := range blocked {
+		f.blocked[strings.ToLower(word)] = true
+	}
+	for _, word := range flagged {
+		f.flagged[strings.ToLower(word)] = true
+	}
+	return f
+}
+
+func (f *WordlistFilter) Name() string {
+	return "wordlist"
+}
+
+// Check never fails
+func (f *WordlistFilter) Check(ctx context.Context, text string) (ContentCheck, error) {
+	result := ContentCheck{Verdict: ContentAllowed}
+
+	words := contentWords(text)
+	for _, word := range words {
+		switch {
+		case f.blocked[word]:
+			result.merge(ContentCheck{Verdict: ContentRejected, Reasons: []string{ContentReasonProfanity}})
+		case f.flagged[word]:
+			result.merge(ContentCheck{Verdict: ContentFlagged, Reasons: []string{ContentReasonFlaggedWord}})
+		}
+	}
+
+	lower := strings.ToLower(text)
+	links := strings.Count(lower, "http://") + strings.Count(lower, "https://") + strings.Count(lower, "www.")
+	if links > maxContentLinks {
+		result.merge(ContentCheck{Verdict: ContentFlagged, Reasons: []string{ContentReasonLinks}})
+	}
+
+	if isRepetitive(text, words) {
+		result.merge(ContentCheck{Verdict: ContentFlagged, Reasons: []string{ContentReasonRepetition}})
+	}
+
+	return result, nil
+}
+
+// leetReplacer undoes common character substitutions like "sh1t"
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// contentWords splits text into lower case words with substitutions undone
+func contentWords(text string) []string {
+	normalized := leetReplacer.Replace(strings.ToLower(text))
+	return strings.FieldsFunc(normalized, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// isRepetitive reports long runs of one character or text made of a few
+// words repeated over and over
+func isRepetitive(text string, words []string) bool {
+	run := 0
+	var previous rune
+	for _, r := range text {
+		if r == previous && !unicode.IsSpace(r) {
+			run++
+			if run >= maxRepeatedCharacters {
+				return true
+			}
+		} else {
+			run = 1
+		}
+		previous = r
+	}
+
+	if len(words) < minWordsForRepetition {
+		return false
+	}
+	distinct := make(map[string]bool, len(words))
+	for _, word := range words {
+		distinct[word] = true
+	}
+	return float64(len(distinct)) < float64(len(words))*minDistinctWordsFraction
+}
+
+var moderationHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// ModerationAPIFilter asks an external moderation API. The API receives
+// {"text": "..."} and answers with {"verdict": "allowed|flagged|rejected",
+// "reasons": [...]}.
+type ModerationAPIFilter struct {
+	URL    string
+	APIKey string
+}
+
+func (m *ModerationAPIFilter) Name() string {
+	return "moderation_api"
+}
+
+// Check posts the text to the moderation API
+func (m *ModerationAPIFilter) Check(ctx context.Context, text string) (ContentCheck, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return ContentCheck{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(body))
+	if err != nil {
+		return ContentCheck{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+
+	resp, err := moderationHTTPClient.Do(req)
+	if err != nil {
+		return ContentCheck{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ContentCheck{}, fmt.Errorf("moderation api: unexpected status %d", resp.StatusCode)
+	}
+
+	var result ContentCheck
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return ContentCheck{}, err
+	}
+	if _, known := contentSeverity[result.Verdict]; !known {
+		return ContentCheck{}, fmt.Errorf("moderation api: unknown verdict %q", result.Verdict)
+	}
+
+	return result, nil
+}
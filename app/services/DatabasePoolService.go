@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+// SlowQueryRetention is how long slow queries are kept
+const SlowQueryRetention = 14 * 24 * time.Hour
+
+// DatabasePoolService reports on the Postgres connection pool
+type DatabasePoolService struct {
+	mu            sync.Mutex
+	lastWaitCount int64
+	checked       bool
+}
+
+// CheckPool logs a warning when more requests had to wait for a free
+// connection since the previous check than DB_POOL_WAIT_WARNING allows.
+// Registered as a job, it never fails.
+func (ps *DatabasePoolService) CheckPool(ctx context.Context) error {
+	ps.check(databases.PostgresDB.Stats(), config.Get().Database.PoolWaitWarning)
+	return nil
+}
+
+// check compares the stats with the previous ones and reports whether a
+// warning was logged. The first check only records the baseline.
+func (ps *DatabasePoolService) check(stats sql.DBStats, threshold int) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	waits := stats.WaitCount - ps.lastWaitCount
+	first := !ps.checked
+	ps.lastWaitCount, ps.checked = stats.WaitCount, true
+
+	if first || waits <= int64(threshold) {
+		return false
+	}
+
+	log.Printf("Warning: %d waits for a database connection since the last check (%d/%d connections in use, %s waited in total). Consider raising DB_MAX_OPEN_CONNS.",
+		waits, stats.InUse, stats.MaxOpenConnections, stats.WaitDuration)
+	return true
+}
+
+// Ping checks that the database answers
+func (ps *DatabasePoolService) Ping(ctx context.Context) error {
+	return databases.PostgresDB.PingContext(ctx)
+}
+
+// ExpireSlowQueries removes the slow queries older than SlowQueryRetention
+func (ps *DatabasePoolService) ExpireSlowQueries(ctx context.Context) error {
+	_, err := models.DeleteSlowQueries(ctx, time.Now().UTC().Add(-SlowQueryRetention))
+	return err
+}
+
+// WritePoolMetrics writes the pool stats in the Prometheus text format
+func (ps *DatabasePoolService) WritePoolMetrics(w io.Writer) error {
+	return writePoolMetrics(w, databases.PostgresDB.Stats())
+}
+
+func writePoolMetrics(w io.Writer, stats sql.DBStats) error {
+	metrics := []struct {
+		name, kind, help string
+		value            interface{}
+	}{
+		{"db_pool_max_open_connections", "gauge", "Maximum number of open connections", stats.MaxOpenConnections},
+		{"db_pool_open_connections", "gauge", "Established connections, in use and idle", stats.OpenConnections},
+		{"db_pool_in_use_connections", "gauge", "Connections currently in use", stats.InUse},
+		{"db_pool_idle_connections", "gauge", "Idle connections", stats.Idle},
+		{"db_pool_wait_count_total", "counter", "Waits for a free connection", stats.WaitCount},
+		{"db_pool_wait_duration_seconds_total", "counter", "Time spent waiting for a free connection", stats.WaitDuration.Seconds()},
+		{"db_pool_max_idle_closed_total", "counter", "Connections closed because of the idle limit", stats.MaxIdleClosed},
+		{"db_pool_max_idle_time_closed_total", "counter", "Connections closed because of the idle time limit", stats.MaxIdleTimeClosed},
+		{"db_pool_max_lifetime_closed_total", "counter", "Connections closed because of their maximum lifetime", stats.MaxLifetimeClosed},
+	}
+
+	for _, metric := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n",
+			metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
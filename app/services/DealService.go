@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"voting-app/app/models"
+)
+
+// DealService serves the deals venue owners run
+type DealService struct{}
+
+// AttachActiveDeals sets the running deals of the venues that have any
+func (ds *DealService) AttachActiveDeals(ctx context.Context, venues []models.Venue) error {
+	venueIDs := make([]int64, len(venues))
+	for i, venue := range venues {
+		venueIDs[i] = venue.ID
+	}
+
+	deals, err := models.GetActiveDeals(ctx, venueIDs)
+	if err != nil {
+		return err
+	}
+	for i := range venues {
+		venues[i].Deals = deals[venues[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// sessionTimeout is the longest gap between two events of a session
+	sessionTimeout = 30 * time.Minute
+	// sessionizationBatchSize is how many events each sessionization
+	// transaction handles
+	sessionizationBatchSize = 5000
+	// clientEventRetention is how long events are kept once in a session,
+	// sessions are kept forever as they back the engagement metrics
+	clientEventRetention = 90 * 24 * time.Hour
+)
+
+// SessionizeClientEvents groups the client events received since the
+// previous run into sessions. An event joins the client's latest session when
+// it is within the session timeout of it, otherwise it starts a new session.
+// Runs are serialized with an advisory lock.
+func (as *AnalyticsService) SessionizeClientEvents(ctx context.Context) error {
+	for {
+		count, err := as.sessionizeBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if count < sessionizationBatchSize {
+			break
+		}
+	}
+
+	return models.DeleteSessionizedEvents(ctx, time.Now().Add(-clientEventRetention))
+}
+
+// sessionizeBatch puts a batch of events into sessions, returning how many
+// events it handled
+func (as *AnalyticsService) sessionizeBatch(ctx context.Context) (int, error) {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('client_sessionization'))")
+	if err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+
+	events, err := models.GetUnsessionizedEvents(ctx, tx, sessionizationBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Events are ordered by client, so each client's events in the batch
+	// are consecutive
+	for start := 0; start < len(events); {
+		end := start
+		for end < len(events) && events[end].ClientID == events[start].ClientID {
+			end++
+		}
+		if err := as.sessionizeClient(ctx, tx, events[start:end]); err != nil {
+			return 0, err
+		}
+		start = end
+	}
+
+	if err := tx.Commit(); err != nil {
+		sentry.CaptureException(err)
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// sessionizeClient puts the events of one client, oldest first, into sessions
+func (as *AnalyticsService) sessionizeClient(ctx context.Context, tx *sql.Tx, events []models.ClientEvent) error {
+	session, err := models.GetLatestClientSession(ctx, tx, events[0].ClientID)
+	if err != nil {
+		return err
+	}
+
+	var eventIDs []int64
+	for _, event := range events {
+		if session == nil || !sessionIncludes(session, event.OccurredAt) {
+			if len(eventIDs) > 0 {
+				if err := session.Save(ctx, tx, eventIDs); err != nil {
+					return err
+				}
+			}
+			session = &models.ClientSession{
+				ClientID:  event.ClientID,
+				StartedAt: event.OccurredAt,
+				EndedAt:   event.OccurredAt,
+			}
+			eventIDs = nil
+		}
+
+		if event.OccurredAt.Before(session.StartedAt) {
+			session.StartedAt = event.OccurredAt
+		}
+		if event.OccurredAt.After(session.EndedAt) {
+			session.EndedAt = event.OccurredAt
+		}
+		session.EventCount++
+		if models.IsClientPageEvent(event.Type) {
+			session.PageViews++
+		}
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	return session.Save(ctx, tx, eventIDs)
+}
+
+// sessionIncludes reports whether an event at the time belongs to the
+// session, being no further than the session timeout from it
+func sessionIncludes(session *models.ClientSession, at time.Time) bool {
+	return !at.Before(session.StartedAt.Add(-sessionTimeout)) && !at.After(session.EndedAt.Add(sessionTimeout))
+}
+
+// GetEngagementMetrics computes session metrics over the sessions started
+// within the time range
+func (as *AnalyticsService) GetEngagementMetrics(ctx context.Context, timeRange string) (*UserEngagementMetrics, error) {
+	startDate, endDate, err := as.parseTimeRange(timeRange, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &UserEngagementMetrics{TimeRange: timeRange}
+	if err := as.getEngagementMetrics(ctx, startDate, endDate, metrics); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// getEngagementMetrics fills the session metrics. A bounce is a session with
+// a single event; a returning client had a session before the range.
+func (as *AnalyticsService) getEngagementMetrics(ctx context.Context, startDate, endDate time.Time, metrics *UserEngagementMetrics) error {
+	query := `
+		WITH sessions AS (
+			SELECT client_id, started_at, ended_at, page_views, event_count
+			FROM client_sessions
+			WHERE started_at >= $1 AND started_at <= $2
+		),
+		clients AS (
+			SELECT DISTINCT client_id FROM sessions
+		)
+		SELECT
+			(SELECT COUNT(*) FROM sessions),
+			(SELECT COALESCE(AVG(EXTRACT(EPOCH FROM ended_at - started_at)), 0) FROM sessions),
+			(SELECT COALESCE(AVG(page_views), 0) FROM sessions),
+			(SELECT COALESCE(AVG(CASE WHEN event_count <= 1 THEN 1.0 ELSE 0.0 END), 0) FROM sessions),
+			(SELECT COUNT(*) FROM clients),
+			(SELECT COUNT(*) FROM clients c WHERE EXISTS (
+				SELECT 1 FROM client_sessions s
+				WHERE s.client_id = c.client_id AND s.started_at < $1
+			))`
+
+	var clients, returningClients int
+	err := databases.PostgresDB.QueryRowContext(ctx, query, startDate, endDate).Scan(
+		&metrics.Sessions,
+		&metrics.AverageSessionDuration,
+		&metrics.PagesPerSession,
+		&metrics.BounceRate,
+		&clients,
+		&returningClients,
+	)
+	if err != nil {
+		return err
+	}
+
+	if clients > 0 {
+		metrics.ReturnUserRate = float64(returningClients) / float64(clients)
+	}
+	return nil
+}
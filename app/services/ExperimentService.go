@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"time"
+	"voting-app/app/models"
+)
+
+// Experiments and their variants
+const (
+	ExperimentRecommendationRanking = "recommendation_ranking"
+
+	VariantControl = "control"
+	// VariantNeutralWeights ranks recommendations without the weights tuned
+	// by feedback
+	VariantNeutralWeights = "neutral_weights"
+)
+
+// Experiment compares variants of a feature, the first being the control
+type Experiment struct {
+	Key      string   `json:"key"`
+	Variants []string `json:"variants"`
+}
+
+// experiments are the experiments that can run, by key
+var experiments = map[string]Experiment{
+	ExperimentRecommendationRanking: {
+		Key:      ExperimentRecommendationRanking,
+		Variants: []string{VariantControl, VariantNeutralWeights},
+	},
+}
+
+// ExperimentReport holds the metrics of an experiment's variants
+type ExperimentReport struct {
+	Experiment string                  `json:"experiment"`
+	Since      time.Time               `json:"since"`
+	Variants   []models.VariantMetrics `json:"variants"`
+}
+
+// ExperimentService places users in the variants of experiments and reports
+// how the variants perform. An experiment runs for the users its feature
+// flag, of the same key, is on for; the others get the control and aren't
+// counted.
+type ExperimentService struct{}
+
+// GetExperiment returns the experiment with the key
+func GetExperiment(key string) (Experiment, bool) {
+	experiment, exists := experiments[key]
+	return experiment, exists
+}
+
+// IsExperimentVariant reports whether the variant is one of the experiment's
+func IsExperimentVariant(key, variant string) bool {
+	experiment, exists := experiments[key]
+	if !exists {
+		return false
+	}
+	for _, known := range experiment.Variants {
+		if known == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign returns the variant of the experiment the user is served, and
+// whether they are in the experiment. Users keep their variant for as long
+// as the experiment runs.
+func (es *ExperimentService) Assign(ctx context.Context, key string, userID int64) (string, bool) {
+	experiment, exists := experiments[key]
+	if !exists {
+		return "", false
+	}
+	flagService := &FlagService{}
+	if !flagService.IsEnabled(ctx, key, userID) {
+		return experiment.Variants[0], false
+	}
+	// Hashed apart from the flag's rollout, so enrolling more users doesn't
+	// fill one variant first
+	bucket := RolloutBucket("experiment:"+key, userID)
+	return experiment.Variants[bucket*len(experiment.Variants)/100], true
+}
+
+// RecordImpressions counts the venues recommended to the user towards the
+// variant they were served
+func (es *ExperimentService) RecordImpressions(ctx context.Context, key, variant string, userID int64, venueIDs []int64) error {
+	return models.RecordExperimentImpressions(ctx, key, variant, userID, venueIDs)
+}
+
+// Report returns the metrics of every variant of the experiment since the
+// time, variants without events included
+func (es *ExperimentService) Report(ctx context.Context, experiment Experiment, since time.Time) (*ExperimentReport, error) {
+	metrics, err := models.GetExperimentMetrics(ctx, experiment.Key, since)
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[string]models.VariantMetrics, len(metrics))
+	for _, variant := range metrics {
+		byVariant[variant.Variant] = variant
+	}
+
+	report := &ExperimentReport{
+		Experiment: experiment.Key,
+		Since:      since,
+		Variants:   make([]models.VariantMetrics, len(experiment.Variants)),
+	}
+	for i, key := range experiment.Variants {
+		variant := byVariant[key]
+		variant.Variant = key
+		if variant.Impressions > 0 {
+			variant.CTR = float64(variant.Clicks) / float64(variant.Impressions)
+		}
+		if variant.Clicks > 0 {
+			variant.ConversionRate = float64(variant.Conversions) / float64(variant.Clicks)
+		}
+		report.Variants[i] = variant
+	}
+	return report, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"voting-app/app/models"
+)
+
+// MaxExternalRatingImportBytes caps the size of an imported ratings file
+const MaxExternalRatingImportBytes = 5 << 20
+
+// externalRatingColumns are the CSV columns of imported ratings
+var externalRatingColumns = []string{"venue_id", "source", "rating", "review_count", "url"}
+
+// ExternalRatingService imports venue ratings from other platforms
+type ExternalRatingService struct{}
+
+// ExternalRatingImportResult reports what an import saved, or would save in
+// a dry run. Nothing is saved when a row has errors.
+type ExternalRatingImportResult struct {
+	DryRun   bool             `json:"dryRun"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Errors   []LegacyRowError `json:"errors,omitempty"`
+}
+
+// ImportCSV validates a CSV file of ratings and saves them, replacing the
+// ratings their venues already have from the same platforms. The header
+// names the columns venue_id, source, rating, review_count and url, in any
+// order. A dry run reports the changes without saving them.
+func (s *ExternalRatingService) ImportCSV(ctx context.Context, r io.Reader, importedBy int64, dryRun bool) (*ExternalRatingImportResult, error) {
+	result := &ExternalRatingImportResult{DryRun: dryRun}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: "the file is empty"})
+		return result, nil
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: err.Error()})
+		return result, nil
+	}
+	index, headerErr := legacyColumnIndex(header, externalRatingColumns)
+	if headerErr != "" {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: headerErr})
+		return result, nil
+	}
+
+	var ratings []models.ExternalRating
+	var ratingLines []int
+	lines := make(map[string]int)
+	venueIDs := make([]int64, 0)
+	seenVenues := make(map[int64]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, LegacyRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		result.Rows++
+
+		rating, message := parseExternalRating(record, index)
+		if message == "" {
+			key := fmt.Sprintf("%d:%s", rating.VenueID, rating.Source)
+			if first, duplicate := lines[key]; duplicate {
+				message = fmt.Sprintf("the venue's %s rating is already on line %d", rating.Source, first)
+			} else {
+				lines[key] = line
+			}
+		}
+		if message != "" {
+			result.Errors = append(result.Errors, LegacyRowError{Line: line, Message: message})
+			continue
+		}
+		if !seenVenues[rating.VenueID] {
+			seenVenues[rating.VenueID] = true
+			venueIDs = append(venueIDs, rating.VenueID)
+		}
+		rating.ImportedBy = importedBy
+		ratings = append(ratings, rating)
+		ratingLines = append(ratingLines, line)
+	}
+
+	existing, err := models.GetExistingVenueIDs(ctx, venueIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, rating := range ratings {
+		if !existing[rating.VenueID] {
+			result.Errors = append(result.Errors, LegacyRowError{
+				Line:    ratingLines[i],
+				Message: fmt.Sprintf("venue %d doesn't exist", rating.VenueID),
+			})
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	result.Imported = len(ratings)
+	if dryRun || len(ratings) == 0 {
+		return result, nil
+	}
+	if err := models.SaveExternalRatings(ctx, ratings); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseExternalRating parses a row of an imported file, returning why it is
+// invalid
+func parseExternalRating(record []string, index map[string]int) (models.ExternalRating, string) {
+	var rating models.ExternalRating
+	field := func(column string) string {
+		return strings.TrimSpace(record[index[column]])
+	}
+
+	venueID, err := strconv.ParseInt(field("venue_id"), 10, 64)
+	if err != nil || venueID <= 0 {
+		return rating, "venue_id must be a venue ID"
+	}
+	rating.VenueID = venueID
+
+	rating.Source = strings.ToLower(field("source"))
+	if !models.IsExternalRatingSource(rating.Source) {
+		return rating, externalSourceMessage()
+	}
+
+	if rating.Rating, err = strconv.ParseFloat(field("rating"), 64); err != nil {
+		return rating, "rating must be a number"
+	}
+	if rating.ReviewCount, err = strconv.Atoi(field("review_count")); err != nil {
+		return rating, "review_count must be a whole number"
+	}
+	rating.URL = field("url")
+
+	return rating, ValidateExternalRating(rating.Source, rating.Rating, rating.ReviewCount, rating.URL)
+}
+
+// ValidateExternalRating returns why an imported rating is invalid, empty
+// when it is valid
+func ValidateExternalRating(source string, rating float64, reviewCount int, url string) string {
+	if !models.IsExternalRatingSource(source) {
+		return externalSourceMessage()
+	}
+	if rating < 1 || rating > 5 {
+		return "rating must be between 1 and 5"
+	}
+	if reviewCount < 0 {
+		return "review_count can't be negative"
+	}
+	if url != "" && (len(url) > 500 || !(strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://"))) {
+		return "url must be an http(s) link of at most 500 characters"
+	}
+	return ""
+}
+
+func externalSourceMessage() string {
+	return "source must be one of " + strings.Join(models.ExternalRatingSources, ", ")
+}
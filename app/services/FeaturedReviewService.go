@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	// FeaturedReviewsTTL is how long the featured reviews are served from
+	// memory, and may be cached by clients and CDNs
+	FeaturedReviewsTTL = 5 * time.Minute
+
+	// FeaturedReviewsWindow is how far back the most helpful reviews are
+	// picked from
+	FeaturedReviewsWindow = 7 * 24 * time.Hour
+
+	featuredReviewsLimit = 10
+	// featuredReviewsMaxEntries bounds the cached tenant/city combinations,
+	// the cache is emptied when full
+	featuredReviewsMaxEntries = 1000
+)
+
+// FeaturedReviews is the home feed's selection of reviews
+type FeaturedReviews struct {
+	Reviews     []models.FeaturedReview
+	GeneratedAt time.Time
+}
+
+// FeaturedReviewService selects the reviews of the home feed
+type FeaturedReviewService struct{}
+
+var featuredReviewCache struct {
+	sync.Mutex
+	entries map[string]*FeaturedReviews
+}
+
+// GetFeaturedReviews returns the featured reviews of the request's tenant,
+// of the city when set. Selections are computed once per TTL.
+func (fs *FeaturedReviewService) GetFeaturedReviews(ctx context.Context, cityID *int64) (*FeaturedReviews, error) {
+	var city int64
+	if cityID != nil {
+		city = *cityID
+	}
+	key := fmt.Sprintf("%d:%d", models.TenantFilter(ctx).Int64, city)
+	now := time.Now()
+
+	featuredReviewCache.Lock()
+	cached, exists := featuredReviewCache.entries[key]
+	featuredReviewCache.Unlock()
+	if exists && now.Sub(cached.GeneratedAt) < FeaturedReviewsTTL {
+		return cached, nil
+	}
+
+	reviews, err := models.GetFeaturedReviews(ctx, cityID, now.Add(-FeaturedReviewsWindow), featuredReviewsLimit)
+	if err != nil {
+		return nil, err
+	}
+	selection := &FeaturedReviews{Reviews: reviews, GeneratedAt: now.UTC()}
+
+	featuredReviewCache.Lock()
+	if featuredReviewCache.entries == nil || len(featuredReviewCache.entries) >= featuredReviewsMaxEntries {
+		featuredReviewCache.entries = make(map[string]*FeaturedReviews)
+	}
+	featuredReviewCache.entries[key] = selection
+	featuredReviewCache.Unlock()
+
+	return selection, nil
+}
+
+// ClearFeaturedReviewCache forgets the cached selections
+func ClearFeaturedReviewCache() {
+	featuredReviewCache.Lock()
+	featuredReviewCache.entries = nil
+	featuredReviewCache.Unlock()
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sync"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+// Feed kinds
+const (
+	FeedSitemap = "sitemap"
+	FeedVenues  = "venues"
+)
+
+const (
+	// sitemapMaxURLs is the sitemap protocol limit of URLs per file
+	sitemapMaxURLs = 50000
+	// feedCacheMaxEntries bounds the number of city/category combinations kept
+	feedCacheMaxEntries = 500
+)
+
+// SiteBaseURL is the public web URL venue pages are linked to from feeds
+var SiteBaseURL string
+
+func init() {
+	SiteBaseURL = config.Get().SiteBaseURL
+}
+
+// FeedService renders sitemaps and machine-readable venue feeds. Rendered
+// documents are cached and refreshed by the feed regeneration job.
+type FeedService struct{}
+
+// FeedDocument is a rendered feed ready to be served
+type FeedDocument struct {
+	Kind string
+	// Tenant is the tenant whose venues the feed lists, every tenant's when
+	// not valid
+	Tenant      sql.NullInt64
+	CityID      *int64
+	CategoryID  *int64
+	Body        []byte
+	ContentType string
+	ETag        string
+	GeneratedAt time.Time
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+}
+
+// VenuesFeed is the body of the venues.json feed
+type VenuesFeed struct {
+	CityID     *int64            `json:"cityId,omitempty"`
+	CategoryID *int64            `json:"categoryId,omitempty"`
+	Count      int               `json:"count"`
+	Venues     []VenuesFeedEntry `json:"venues"`
+}
+
+// VenuesFeedEntry is a venue of the venues.json feed
+type VenuesFeedEntry struct {
+	models.VenueFeedEntry
+	URL string `json:"url"`
+}
+
+var feedCache = struct {
+	sync.RWMutex
+	documents map[string]*FeedDocument
+}{documents: make(map[string]*FeedDocument)}
+
+// GetFeed returns the cached feed of the context's tenant, rendering it on
+// first request
+func (fs *FeedService) GetFeed(ctx context.Context, kind string, cityID, categoryID *int64) (*FeedDocument, error) {
+	key := feedCacheKey(kind, models.TenantFilter(ctx), cityID, categoryID)
+
+	feedCache.RLock()
+	document, exists := feedCache.documents[key]
+	feedCache.RUnlock()
+	if exists {
+		return document, nil
+	}
+
+	document, err := fs.render(ctx, kind, cityID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	feedCache.Lock()
+	if len(feedCache.documents) < feedCacheMaxEntries {
+		feedCache.documents[key] = document
+	}
+	feedCache.Unlock()
+
+	return document, nil
+}
+
+// RegenerateFeeds re-renders the unfiltered feeds and every cached
+// tenant/city/category variant. It is run periodically by the job runner.
+func (fs *FeedService) RegenerateFeeds(ctx context.Context) error {
+	feedCache.RLock()
+	stale := make([]*FeedDocument, 0, len(feedCache.documents)+2)
+	for _, document := range feedCache.documents {
+		stale = append(stale, document)
+	}
+	feedCache.RUnlock()
+
+	if len(stale) == 0 {
+		stale = append(stale, &FeedDocument{Kind: FeedSitemap}, &FeedDocument{Kind: FeedVenues})
+	}
+
+	for _, old := range stale {
+		renderCtx := ctx
+		if old.Tenant.Valid {
+			renderCtx = models.WithTenant(ctx, old.Tenant.Int64)
+		}
+		document, err := fs.render(renderCtx, old.Kind, old.CityID, old.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		// Keep Last-Modified stable while the content doesn't change
+		if document.ETag == old.ETag {
+			document.GeneratedAt = old.GeneratedAt
+		}
+
+		feedCache.Lock()
+		feedCache.documents[feedCacheKey(old.Kind, old.Tenant, old.CityID, old.CategoryID)] = document
+		feedCache.Unlock()
+	}
+
+	return nil
+}
+
+func (fs *FeedService) render(ctx context.Context, kind string, cityID, categoryID *int64) (*FeedDocument, error) {
+	entries, err := models.GetVenueFeedEntries(ctx, cityID, categoryID, sitemapMaxURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &FeedDocument{
+		Kind:        kind,
+		Tenant:      models.TenantFilter(ctx),
+		CityID:      cityID,
+		CategoryID:  categoryID,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	switch kind {
+	case FeedSitemap:
+		urlSet := sitemapURLSet{
+			Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+			URLs:  make([]sitemapURL, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			urlSet.URLs = append(urlSet.URLs, sitemapURL{
+				Loc:        VenuePageURL(entry.Slug),
+				LastMod:    entry.UpdatedAt.UTC().Format(time.RFC3339),
+				ChangeFreq: "weekly",
+			})
+		}
+
+		body, err := xml.Marshal(urlSet)
+		if err != nil {
+			return nil, err
+		}
+		document.Body = append([]byte(xml.Header), body...)
+		document.ContentType = "application/xml; charset=utf-8"
+
+	case FeedVenues:
+		feed := VenuesFeed{
+			CityID:     cityID,
+			CategoryID: categoryID,
+			Count:      len(entries),
+			Venues:     make([]VenuesFeedEntry, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			feed.Venues = append(feed.Venues, VenuesFeedEntry{
+				VenueFeedEntry: entry,
+				URL:            VenuePageURL(entry.Slug),
+			})
+		}
+
+		body, err := json.Marshal(feed)
+		if err != nil {
+			return nil, err
+		}
+		document.Body = body
+		document.ContentType = "application/json; charset=utf-8"
+
+	default:
+		return nil, fmt.Errorf("unknown feed kind %q", kind)
+	}
+
+	hash := sha1.Sum(document.Body)
+	document.ETag = `"` + hex.EncodeToString(hash[:]) + `"`
+
+	return document, nil
+}
+
+// VenuePageURL returns the public web page of a venue
+func VenuePageURL(slug string) string {
+	return SiteBaseURL + "/venues/" + slug
+}
+
+func feedCacheKey(kind string, tenant sql.NullInt64, cityID, categoryID *int64) string {
+	var city, category int64
+	if cityID != nil {
+		city = *cityID
+	}
+	if categoryID != nil {
+		category = *categoryID
+	}
+	return fmt.Sprintf("%s:%d:%d:%d", kind, tenant.Int64, city, category)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// flagCacheTTL is how long flags are served from memory before reloading.
+// Changes made through FlagService apply at once on this instance.
+const flagCacheTTL = 30 * time.Second
+
+// FlagService manages feature flags and tells which are on for a user
+type FlagService struct{}
+
+// flagCache holds the flags by key
+var flagCache struct {
+	sync.RWMutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+}
+
+// IsEnabled tells whether the flag is on for the user. Unknown flags are
+// off, and users with ID 0 only get flags rolled out to everyone.
+func (s *FlagService) IsEnabled(ctx context.Context, key string, userID int64) bool {
+	flag, exists := s.getFlag(ctx, key)
+	if !exists || !flag.Enabled {
+		return false
+	}
+
+	if userID != 0 {
+		for _, targeted := range flag.UserIDs {
+			if targeted == userID {
+				return true
+			}
+		}
+	}
+
+	if flag.RolloutPercentage >= 100 {
+		return true
+	}
+	if userID == 0 || flag.RolloutPercentage <= 0 {
+		return false
+	}
+	return RolloutBucket(key, userID) < flag.RolloutPercentage
+}
+
+// RolloutBucket places the user in one of 100 buckets for the flag. The
+// bucket is stable, so raising the percentage only adds users, and differs
+// between flags, so the same users aren't always first.
+func RolloutBucket(key string, userID int64) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key + ":" + strconv.FormatInt(userID, 10)))
+	return int(hash.Sum32() % 100)
+}
+
+// GetFlags returns all flags
+func (s *FlagService) GetFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	return models.GetFeatureFlags(ctx)
+}
+
+// CreateFlag stores a new flag, models.ErrFeatureFlagExists when its key is
+// taken
+func (s *FlagService) CreateFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	if err := flag.Create(ctx); err != nil {
+		return err
+	}
+	invalidateFlags()
+	return nil
+}
+
+// UpdateFlag replaces a flag's targeting, sql.ErrNoRows when it doesn't exist
+func (s *FlagService) UpdateFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	if err := flag.Update(ctx); err != nil {
+		return err
+	}
+	invalidateFlags()
+	return nil
+}
+
+// DeleteFlag removes a flag, sql.ErrNoRows when it doesn't exist
+func (s *FlagService) DeleteFlag(ctx context.Context, key string) error {
+	deleted, err := models.DeleteFeatureFlag(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return sql.ErrNoRows
+	}
+	invalidateFlags()
+	return nil
+}
+
+// getFlag returns the flag from the cache, reloading it when stale. When
+// reloading fails the stale flags are kept.
+func (s *FlagService) getFlag(ctx context.Context, key string) (models.FeatureFlag, bool) {
+	flagCache.RLock()
+	fresh := flagCache.flags != nil && time.Since(flagCache.loadedAt) < flagCacheTTL
+	flag, exists := flagCache.flags[key]
+	flagCache.RUnlock()
+	if fresh {
+		return flag, exists
+	}
+
+	flags, err := models.GetFeatureFlags(ctx)
+	if err != nil {
+		sentry.CaptureException(err)
+		return flag, exists
+	}
+
+	byKey := make(map[string]models.FeatureFlag, len(flags))
+	for _, flag := range flags {
+		byKey[flag.Key] = flag
+	}
+	flagCache.Lock()
+	flagCache.flags = byKey
+	flagCache.loadedAt = time.Now()
+	flagCache.Unlock()
+
+	flag, exists = byKey[key]
+	return flag, exists
+}
+
+// invalidateFlags makes the next check reload the flags
+func invalidateFlags() {
+	flagCache.Lock()
+	flagCache.flags = nil
+	flagCache.Unlock()
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"voting-app/app/config"
+)
+
+const (
+	// localReverseGeocodeKm is how close a city must be for coordinates to
+	// be placed near it without asking the external geocoder
+	localReverseGeocodeKm = 50.0
+	// fallbackReverseGeocodeKm is how far the closest city may be when the
+	// external geocoder is unavailable
+	fallbackReverseGeocodeKm = 500.0
+	// fallbackGeocodeConfidence is the confidence of addresses placed in the
+	// city they name when the external geocoder is unavailable
+	fallbackGeocodeConfidence = 0.3
+
+	mapboxGeocodingURL = "https://api.mapbox.com/geocoding/v5/mapbox.places"
+)
+
+var (
+	// ErrGeocoderUnavailable is returned when no external geocoder is
+	// configured, it failed or its breaker is open
+	ErrGeocoderUnavailable = errors.New("geocoder unavailable")
+	// ErrLocationNotFound is returned when the geocoder knows no place for
+	// the address or coordinates
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+// GeocoderBackend looks addresses and coordinates up with an external API
+type GeocoderBackend interface {
+	Name() string
+	// Geocode returns the location of the address, nil when it is unknown
+	Geocode(ctx context.Context, address string) (*LocationResult, error)
+	// ReverseGeocode returns the address of the coordinates, nil when they
+	// are nowhere known
+	ReverseGeocode(ctx context.Context, lat, lng float64) (*LocationResult, error)
+}
+
+// ExternalGeocoder is the configured backend, nil when external geocoding
+// is disabled
+var ExternalGeocoder GeocoderBackend
+
+// GeocoderBreaker guards the calls to the external geocoder, so its outages
+// fall back to the cities database instead of holding requests up
+var GeocoderBreaker *CircuitBreaker
+
+func init() {
+	geocoder := config.Get().Geocoder
+	if geocoder.MapboxToken != "" {
+		ExternalGeocoder = &MapboxGeocoder{URL: mapboxGeocodingURL, Token: geocoder.MapboxToken}
+	}
+	GeocoderBreaker = NewCircuitBreaker("geocoder", geocoder.BreakerFailures, geocoder.BreakerCooldown, geocoder.Timeout)
+}
+
+// geocoderHTTPClient has no timeout of its own, calls are bounded by the
+// breaker's
+var geocoderHTTPClient = &http.Client{}
+
+// MapboxGeocoder looks places up with the Mapbox Geocoding API
+type MapboxGeocoder struct {
+	URL   string
+	Token string
+}
+
+func (m *MapboxGeocoder) Name() string {
+	return "mapbox"
+}
+
+// Geocode returns the most relevant place of the address
+func (m *MapboxGeocoder) Geocode(ctx context.Context, address string) (*LocationResult, error) {
+	return m.lookup(ctx, url.PathEscape(address))
+}
+
+// ReverseGeocode returns the address at the coordinates
+func (m *MapboxGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (*LocationResult, error) {
+	return m.lookup(ctx, fmt.Sprintf("%.6f,%.6f", lng, lat))
+}
+
+func (m *MapboxGeocoder) lookup(ctx context.Context, search string) (*LocationResult, error) {
+	query := url.Values{}
+	query.Set("access_token", m.Token)
+	query.Set("limit", "1")
+	requestURL := fmt.Sprintf("%s/%s.json?%s", strings.TrimRight(m.URL, "/"), search, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := geocoderHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mapbox: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Features []struct {
+			ID        string    `json:"id"`
+			Text      string    `json:"text"`
+			PlaceName string    `json:"place_name"`
+			Center    []float64 `json:"center"` // lng, lat
+			Relevance float64   `json:"relevance"`
+			Context   []struct {
+				ID   string `json:"id"`
+				Text string `json:"text"`
+			} `json:"context"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Features) == 0 || len(result.Features[0].Center) != 2 {
+		return nil, nil
+	}
+
+	feature := result.Features[0]
+	location := &LocationResult{
+		Address:    feature.PlaceName,
+		Latitude:   feature.Center[1],
+		Longitude:  feature.Center[0],
+		Confidence: feature.Relevance,
+	}
+	// The layers of the place, like place.123 for a city, and of the larger
+	// places in its context fill the address fields
+	fields := map[string]*string{
+		"place":    &location.City,
+		"region":   &location.State,
+		"country":  &location.Country,
+		"postcode": &location.PostalCode,
+	}
+	for _, layer := range feature.Context {
+		if field, exists := fields[mapboxLayer(layer.ID)]; exists {
+			*field = layer.Text
+		}
+	}
+	if field, exists := fields[mapboxLayer(feature.ID)]; exists {
+		*field = feature.Text
+	}
+	return location, nil
+}
+
+// mapboxLayer returns the layer of a Mapbox feature ID
+func mapboxLayer(id string) string {
+	return strings.Split(id, ".")[0]
+}
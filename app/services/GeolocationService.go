@@ -1,14 +1,18 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"time"
 	databases "voting-app/app"
 	"voting-app/app/models"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
 )
 
 // GeolocationService handles all location-based operations
@@ -49,6 +53,14 @@ type LatLng struct {
 	Longitude float64 `json:"longitude"`
 }
 
+// MeetingVenue is a candidate venue for a group meetup
+type MeetingVenue struct {
+	Venue                models.Venue `json:"venue"`
+	TotalDistanceKm      float64      `json:"totalDistanceKm"`      // Combined travel distance of all participants
+	MaxDistanceKm        float64      `json:"maxDistanceKm"`        // Longest single travel distance
+	ParticipantDistances []float64    `json:"participantDistances"` // Km per participant, in request order
+}
+
 // Distance represents distance between two points
 type Distance struct {
 	Meters     float64 `json:"meters"`
@@ -56,37 +68,71 @@ type Distance struct {
 	Miles      float64 `json:"miles"`
 }
 
+// VenueDistance is the distance to a venue and whether it is open now
+type VenueDistance struct {
+	VenueID    int64   `json:"venueId"`
+	DistanceKm float64 `json:"distanceKm"`
+	IsOpen     *bool   `json:"isOpen"` // null when the venue has no hours for today
+}
+
 // Geocode converts an address to coordinates
-func (gs *GeolocationService) Geocode(address string) (*LocationResult, error) {
+func (gs *GeolocationService) Geocode(ctx context.Context, address string) (*LocationResult, error) {
 	// First try with our local database
-	result := gs.searchLocalLocations(address)
+	result := gs.searchLocalLocations(ctx, address)
 	if result != nil {
 		return result, nil
 	}
 
 	// Fallback to external geocoding service
-	return gs.externalGeocode(address)
+	result, err := gs.externalGeocode(ctx, address)
+	if err != ErrGeocoderUnavailable {
+		return result, err
+	}
+
+	// Without it, the address is placed in the city it names
+	parts := strings.Split(address, ",")
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := strings.TrimSpace(parts[i])
+		if part == "" || part == address {
+			continue
+		}
+		if result := gs.searchLocalLocations(ctx, part); result != nil {
+			result.Address = address
+			result.Confidence = fallbackGeocodeConfidence
+			return result, nil
+		}
+	}
+	return nil, err
 }
 
 // ReverseGeocode converts coordinates to address
-func (gs *GeolocationService) ReverseGeocode(lat, lng float64) (*LocationResult, error) {
+func (gs *GeolocationService) ReverseGeocode(ctx context.Context, lat, lng float64) (*LocationResult, error) {
 	// Validate coordinates
 	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
 		return nil, fmt.Errorf("invalid coordinates")
 	}
 
 	// Try local database first
-	result := gs.reverseGeocodeLocal(lat, lng)
+	result := gs.reverseGeocodeLocal(ctx, lat, lng, localReverseGeocodeKm)
 	if result != nil {
 		return result, nil
 	}
 
 	// Fallback to external service
-	return gs.externalReverseGeocode(lat, lng)
+	result, err := gs.externalReverseGeocode(ctx, lat, lng)
+	if err != ErrGeocoderUnavailable {
+		return result, err
+	}
+
+	// Without it, the coordinates are placed near the closest city
+	if result := gs.reverseGeocodeLocal(ctx, lat, lng, fallbackReverseGeocodeKm); result != nil {
+		return result, nil
+	}
+	return nil, err
 }
 
 // GetNearbyVenues finds venues within a radius
-func (gs *GeolocationService) GetNearbyVenues(lat, lng, radiusKm float64, filters map[string]interface{}) (*NearbyResult, error) {
+func (gs *GeolocationService) GetNearbyVenues(ctx context.Context, lat, lng, radiusKm float64, filters map[string]interface{}) (*NearbyResult, error) {
 	// Validate inputs
 	if radiusKm <= 0 || radiusKm > 100 {
 		radiusKm = 10 // Default to 10km
@@ -116,6 +162,12 @@ func (gs *GeolocationService) GetNearbyVenues(lat, lng, radiusKm float64, filter
 	args := []interface{}{lng, lat, radiusKm}
 	argCount := 3
 
+	if tenantID, ok := models.TenantFromContext(ctx); ok {
+		argCount++
+		query += fmt.Sprintf(" AND v.tenant_id = $%d", argCount)
+		args = append(args, tenantID)
+	}
+
 	// Add filters
 	if categoryID, exists := filters["category_id"]; exists {
 		argCount++
@@ -136,14 +188,13 @@ func (gs *GeolocationService) GetNearbyVenues(lat, lng, radiusKm float64, filter
 	}
 
 	if isOpen, exists := filters["is_open"]; exists && isOpen.(bool) {
-		// Add opening hours check (simplified)
-		currentHour := time.Now().Hour()
-		query += fmt.Sprintf(" AND (v.opening_hours IS NULL OR jsonb_path_exists(v.opening_hours, '$.*.open ? (@ <= \"%d:00\")'))", currentHour)
+		// Venues without hours are kept unless an exception closes them
+		query += " AND COALESCE(" + models.OpenAtExpression("v", models.VenueLocalNow("v")) + ", true)"
 	}
 
 	query += " ORDER BY distance_km ASC, v.average_rating DESC LIMIT 50"
 
-	rows, err := databases.PostgresDB.Query(query, args...)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
 	if err != nil {
 		sentry.CaptureException(err)
 		return nil, err
@@ -245,7 +296,7 @@ func (gs *GeolocationService) GetBounds(centerLat, centerLng, radiusKm float64)
 }
 
 // FindOptimalMeetingPoint finds the best meeting point for multiple locations
-func (gs *GeolocationService) FindOptimalMeetingPoint(locations []LatLng, preferences map[string]interface{}) (*LocationResult, []models.Venue, error) {
+func (gs *GeolocationService) FindOptimalMeetingPoint(ctx context.Context, locations []LatLng, preferences map[string]interface{}) (*LocationResult, []models.Venue, error) {
 	if len(locations) == 0 {
 		return nil, nil, fmt.Errorf("no locations provided")
 	}
@@ -276,7 +327,7 @@ func (gs *GeolocationService) FindOptimalMeetingPoint(locations []LatLng, prefer
 	}
 
 	// Get reverse geocoding for the center point
-	centerLocation, err := gs.ReverseGeocode(centerLat, centerLng)
+	centerLocation, err := gs.ReverseGeocode(ctx, centerLat, centerLng)
 	if err != nil {
 		centerLocation = &LocationResult{
 			Latitude:  centerLat,
@@ -293,8 +344,11 @@ func (gs *GeolocationService) FindOptimalMeetingPoint(locations []LatLng, prefer
 	if minRating, exists := preferences["min_rating"]; exists {
 		filters["min_rating"] = minRating
 	}
+	if priceRange, exists := preferences["price_range"]; exists {
+		filters["price_range"] = priceRange
+	}
 
-	nearbyResult, err := gs.GetNearbyVenues(centerLat, centerLng, searchRadius, filters)
+	nearbyResult, err := gs.GetNearbyVenues(ctx, centerLat, centerLng, searchRadius, filters)
 	var venues []models.Venue
 	if err == nil {
 		venues = nearbyResult.Venues
@@ -303,11 +357,60 @@ func (gs *GeolocationService) FindOptimalMeetingPoint(locations []LatLng, prefer
 	return centerLocation, venues, nil
 }
 
-// GetVenuesInBounds finds all venues within a bounding box
-func (gs *GeolocationService) GetVenuesInBounds(bounds LocationBounds, filters map[string]interface{}) ([]models.Venue, error) {
+// RankMeetingVenues computes every participant's distance to each venue and
+// sorts the venues by combined travel distance, ties broken by the longest
+// single trip and then rating
+func (gs *GeolocationService) RankMeetingVenues(locations []LatLng, venues []models.Venue) []MeetingVenue {
+	ranked := make([]MeetingVenue, 0, len(venues))
+	for _, venue := range venues {
+		candidate := MeetingVenue{
+			Venue:                venue,
+			ParticipantDistances: make([]float64, len(locations)),
+		}
+
+		for i, loc := range locations {
+			distance := gs.CalculateDistance(loc.Latitude, loc.Longitude, venue.Latitude, venue.Longitude)
+			candidate.ParticipantDistances[i] = math.Round(distance.Kilometers*100) / 100
+			candidate.TotalDistanceKm += distance.Kilometers
+			candidate.MaxDistanceKm = math.Max(candidate.MaxDistanceKm, distance.Kilometers)
+		}
+
+		candidate.TotalDistanceKm = math.Round(candidate.TotalDistanceKm*100) / 100
+		candidate.MaxDistanceKm = math.Round(candidate.MaxDistanceKm*100) / 100
+		ranked = append(ranked, candidate)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].TotalDistanceKm != ranked[j].TotalDistanceKm {
+			return ranked[i].TotalDistanceKm < ranked[j].TotalDistanceKm
+		}
+		if ranked[i].MaxDistanceKm != ranked[j].MaxDistanceKm {
+			return ranked[i].MaxDistanceKm < ranked[j].MaxDistanceKm
+		}
+		return ranked[i].Venue.AverageRating > ranked[j].Venue.AverageRating
+	})
+
+	return ranked
+}
+
+// GetVenuesInBounds finds the 100 best rated venues within a bounding box
+func (gs *GeolocationService) GetVenuesInBounds(ctx context.Context, bounds LocationBounds, filters map[string]interface{}) ([]models.Venue, error) {
+	var venues []models.Venue
+	err := gs.EachVenueInBounds(ctx, bounds, filters, 100, func(venue *models.Venue) error {
+		venues = append(venues, *venue)
+		return nil
+	})
+	return venues, err
+}
+
+// EachVenueInBounds calls fn with each venue within a bounding box, best
+// rated first, up to limit venues. Venues are passed on as they are read,
+// so large areas are never held in memory at once. An error from fn stops
+// the iteration and is returned.
+func (gs *GeolocationService) EachVenueInBounds(ctx context.Context, bounds LocationBounds, filters map[string]interface{}, limit int, fn func(venue *models.Venue) error) error {
 	query := `
 		SELECT v.id, v.name, v.slug, v.address, v.latitude, v.longitude,
-			   v.category_id, v.average_rating, v.total_ratings, v.cover_image
+			   v.category_id, v.average_rating, v.total_ratings, COALESCE(v.cover_image, '')
 		FROM venues v
 		WHERE v.is_active = true
 		  AND v.latitude BETWEEN $1 AND $2
@@ -319,6 +422,12 @@ func (gs *GeolocationService) GetVenuesInBounds(bounds LocationBounds, filters m
 	}
 	argCount := 4
 
+	if tenantID, ok := models.TenantFromContext(ctx); ok {
+		argCount++
+		query += fmt.Sprintf(" AND v.tenant_id = $%d", argCount)
+		args = append(args, tenantID)
+	}
+
 	// Add filters
 	if categoryID, exists := filters["category_id"]; exists {
 		argCount++
@@ -332,15 +441,16 @@ func (gs *GeolocationService) GetVenuesInBounds(bounds LocationBounds, filters m
 		args = append(args, minRating)
 	}
 
-	query += " ORDER BY v.average_rating DESC LIMIT 100"
+	query += fmt.Sprintf(" ORDER BY v.average_rating DESC, v.id LIMIT $%d", argCount+1)
+	args = append(args, limit)
 
-	rows, err := databases.PostgresDB.Query(query, args...)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		sentry.CaptureException(err)
+		return err
 	}
 	defer rows.Close()
 
-	var venues []models.Venue
 	for rows.Next() {
 		var venue models.Venue
 		err := rows.Scan(
@@ -348,17 +458,20 @@ func (gs *GeolocationService) GetVenuesInBounds(bounds LocationBounds, filters m
 			&venue.Latitude, &venue.Longitude, &venue.CategoryID,
 			&venue.AverageRating, &venue.TotalRatings, &venue.CoverImage,
 		)
-		if err == nil {
-			venues = append(venues, venue)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if err := fn(&venue); err != nil {
+			return err
 		}
 	}
-
-	return venues, nil
+	return rows.Err()
 }
 
 // Helper methods for geocoding
 
-func (gs *GeolocationService) searchLocalLocations(address string) *LocationResult {
+func (gs *GeolocationService) searchLocalLocations(ctx context.Context, address string) *LocationResult {
 	// Search in our cities database first
 	query := `
 		SELECT name, latitude, longitude, state, country
@@ -369,7 +482,7 @@ func (gs *GeolocationService) searchLocalLocations(address string) *LocationResu
 		  name
 		LIMIT 1`
 
-	row := databases.PostgresDB.QueryRow(query, "%"+address+"%")
+	row := databases.PostgresDB.QueryRowContext(ctx, query, "%"+address+"%")
 
 	var name, state, country string
 	var lat, lng float64
@@ -390,7 +503,9 @@ func (gs *GeolocationService) searchLocalLocations(address string) *LocationResu
 	}
 }
 
-func (gs *GeolocationService) reverseGeocodeLocal(lat, lng float64) *LocationResult {
+// reverseGeocodeLocal places the coordinates near the closest city within
+// maxKm
+func (gs *GeolocationService) reverseGeocodeLocal(ctx context.Context, lat, lng, maxKm float64) *LocationResult {
 	// Find the nearest city
 	query := `
 		SELECT name, state, country,
@@ -402,13 +517,13 @@ func (gs *GeolocationService) reverseGeocodeLocal(lat, lng float64) *LocationRes
 		ORDER BY distance_km
 		LIMIT 1`
 
-	row := databases.PostgresDB.QueryRow(query, lng, lat)
+	row := databases.PostgresDB.QueryRowContext(ctx, query, lng, lat)
 
 	var name, state, country string
 	var distance float64
 
 	err := row.Scan(&name, &state, &country, &distance)
-	if err != nil || distance > 50 { // Only if within 50km
+	if err != nil || distance > maxKm {
 		return nil
 	}
 
@@ -419,33 +534,53 @@ func (gs *GeolocationService) reverseGeocodeLocal(lat, lng float64) *LocationRes
 		City:       name,
 		State:      state,
 		Country:    country,
-		Confidence: math.Max(0.1, 1.0-(distance/50.0)),
+		Confidence: math.Max(0.1, 1.0-(distance/localReverseGeocodeKm)),
 	}
 }
 
-func (gs *GeolocationService) externalGeocode(address string) (*LocationResult, error) {
-	// This would integrate with Mapbox, Google Maps, or other geocoding service
-	// For now, return a mock result
-	return &LocationResult{
-		Address:    address,
-		Latitude:   0,
-		Longitude:  0,
-		Confidence: 0.1,
-	}, fmt.Errorf("external geocoding not implemented")
+// externalGeocode asks the external geocoder through its circuit breaker
+func (gs *GeolocationService) externalGeocode(ctx context.Context, address string) (*LocationResult, error) {
+	return callGeocoder(ctx, func(ctx context.Context, backend GeocoderBackend) (*LocationResult, error) {
+		return backend.Geocode(ctx, address)
+	})
 }
 
-func (gs *GeolocationService) externalReverseGeocode(lat, lng float64) (*LocationResult, error) {
-	// This would integrate with external reverse geocoding service
-	return &LocationResult{
-		Address:    fmt.Sprintf("%.6f, %.6f", lat, lng),
-		Latitude:   lat,
-		Longitude:  lng,
-		Confidence: 0.1,
-	}, fmt.Errorf("external reverse geocoding not implemented")
+// externalReverseGeocode asks the external geocoder through its circuit
+// breaker
+func (gs *GeolocationService) externalReverseGeocode(ctx context.Context, lat, lng float64) (*LocationResult, error) {
+	return callGeocoder(ctx, func(ctx context.Context, backend GeocoderBackend) (*LocationResult, error) {
+		return backend.ReverseGeocode(ctx, lat, lng)
+	})
+}
+
+// callGeocoder calls the configured geocoder unless its breaker is open.
+// Lookups finding nothing aren't failures of the geocoder.
+func callGeocoder(ctx context.Context, lookup func(ctx context.Context, backend GeocoderBackend) (*LocationResult, error)) (*LocationResult, error) {
+	backend := ExternalGeocoder
+	if backend == nil {
+		return nil, ErrGeocoderUnavailable
+	}
+
+	var result *LocationResult
+	err := GeocoderBreaker.Call(ctx, func(ctx context.Context) (err error) {
+		result, err = lookup(ctx, backend)
+		return err
+	})
+	if err == ErrCircuitOpen {
+		return nil, ErrGeocoderUnavailable
+	}
+	if err != nil {
+		sentry.CaptureException(fmt.Errorf("geocoding %s: %w", backend.Name(), err))
+		return nil, ErrGeocoderUnavailable
+	}
+	if result == nil {
+		return nil, ErrLocationNotFound
+	}
+	return result, nil
 }
 
 // GetLocationSuggestions provides autocomplete suggestions for locations
-func (gs *GeolocationService) GetLocationSuggestions(query string, limit int) ([]LocationResult, error) {
+func (gs *GeolocationService) GetLocationSuggestions(ctx context.Context, query string, limit int) ([]LocationResult, error) {
 	if limit <= 0 || limit > 20 {
 		limit = 10
 	}
@@ -458,7 +593,7 @@ func (gs *GeolocationService) GetLocationSuggestions(query string, limit int) ([
 		ORDER BY score DESC, name
 		LIMIT $3`
 
-	rows, err := databases.PostgresDB.Query(dbQuery, query, "%"+query+"%", limit)
+	rows, err := databases.PostgresDB.QueryContext(ctx, dbQuery, query, "%"+query+"%", limit)
 	if err != nil {
 		return nil, err
 	}
@@ -485,3 +620,50 @@ func (gs *GeolocationService) GetLocationSuggestions(query string, limit int) ([
 
 	return suggestions, nil
 }
+
+// GetVenueDistances annotates active venues with their distance from a
+// location and whether they are open at now, in the order of venueIDs.
+// Unknown and inactive venues are left out.
+func (gs *GeolocationService) GetVenueDistances(ctx context.Context, lat, lng float64, venueIDs []int64, now time.Time) ([]VenueDistance, error) {
+	query := `
+		SELECT v.id,
+			   ST_Distance(
+				   ST_Point(v.longitude, v.latitude)::geography,
+				   ST_Point($1, $2)::geography
+			   ) / 1000 AS distance_km,
+			   ` + models.OpenAtExpression("v", "$4::timestamp") + ` AS is_open
+		FROM venues v
+		WHERE v.id = ANY($3) AND v.is_active = true AND ($5::bigint IS NULL OR v.tenant_id = $5)`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, lng, lat, pq.Array(venueIDs), now.Format("2006-01-02 15:04:05"),
+		models.TenantFilter(ctx))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[int64]VenueDistance, len(venueIDs))
+	for rows.Next() {
+		var distance VenueDistance
+		var isOpen sql.NullBool
+		if err := rows.Scan(&distance.VenueID, &distance.DistanceKm, &isOpen); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		distance.DistanceKm = math.Round(distance.DistanceKm*100) / 100
+		if isOpen.Valid {
+			distance.IsOpen = &isOpen.Bool
+		}
+		found[distance.VenueID] = distance
+	}
+
+	distances := make([]VenueDistance, 0, len(found))
+	for _, venueID := range venueIDs {
+		if distance, exists := found[venueID]; exists {
+			distances = append(distances, distance)
+		}
+	}
+	return distances, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"voting-app/app/config"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Where the location of a discovery request comes from
+const (
+	LocationSourceGPS = "gps" // Coordinates sent by the client
+	LocationSourceIP  = "ip"  // Coarse location of the client's IP address
+)
+
+const (
+	// ipLocationCacheTTL is how long located addresses, found or not, are
+	// served from memory
+	ipLocationCacheTTL = time.Hour
+	// maxCachedIPLocations bounds the cache, which is emptied when full
+	maxCachedIPLocations = 10000
+)
+
+// ErrLocationUnknown is returned when a client sent no coordinates and its IP
+// address can't be located
+var ErrLocationUnknown = errors.New("location unknown")
+
+// IPLocation is the coarse location of an IP address
+type IPLocation struct {
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	AccuracyRadiusKm float64 `json:"accuracyRadiusKm,omitempty"`
+	City             string  `json:"city,omitempty"`
+	Country          string  `json:"country,omitempty"` // ISO code
+}
+
+// IPLocationBackend locates IP addresses
+type IPLocationBackend interface {
+	Name() string
+	// Locate returns the location of the address, nil when it is unknown
+	Locate(ctx context.Context, ip net.IP) (*IPLocation, error)
+}
+
+// IPLocator is the configured backend, nil when IP geolocation is disabled
+var IPLocator IPLocationBackend
+
+func init() {
+	geoIP := config.Get().GeoIP
+	if geoIP.LicenseKey != "" {
+		IPLocator = &GeoLiteBackend{
+			URL:        geoIP.URL,
+			AccountID:  geoIP.AccountID,
+			LicenseKey: geoIP.LicenseKey,
+		}
+	}
+}
+
+// ResolvedLocation is the location discovery results are computed from
+type ResolvedLocation struct {
+	Latitude  float64
+	Longitude float64
+	Source    string // gps or ip
+}
+
+// IPGeolocationService falls back to the client's IP address for the
+// location of discovery requests
+type IPGeolocationService struct{}
+
+// ipLocationCache holds located addresses, nil locations for the unknown ones
+var ipLocationCache struct {
+	sync.Mutex
+	entries map[string]cachedIPLocation
+}
+
+type cachedIPLocation struct {
+	location  *IPLocation
+	expiresAt time.Time
+}
+
+// ResolveLocation returns the coordinates the client sent, or else the
+// location of its IP address. ErrLocationUnknown is returned when the
+// address can't be located.
+func (s *IPGeolocationService) ResolveLocation(ctx context.Context, lat, lng *float64, clientIP string) (*ResolvedLocation, error) {
+	if lat != nil && lng != nil {
+		return &ResolvedLocation{Latitude: *lat, Longitude: *lng, Source: LocationSourceGPS}, nil
+	}
+
+	location, err := s.Locate(ctx, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedLocation{Latitude: location.Latitude, Longitude: location.Longitude, Source: LocationSourceIP}, nil
+}
+
+// Locate returns the location of the IP address. Private and loopback
+// addresses are never located, and backend failures are reported as
+// ErrLocationUnknown so requests can go on without a location.
+func (s *IPGeolocationService) Locate(ctx context.Context, clientIP string) (*IPLocation, error) {
+	backend := IPLocator
+	ip := net.ParseIP(strings.TrimSpace(clientIP))
+	if backend == nil || ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+		return nil, ErrLocationUnknown
+	}
+
+	key := ip.String()
+	now := time.Now()
+	ipLocationCache.Lock()
+	cached, isCached := ipLocationCache.entries[key]
+	ipLocationCache.Unlock()
+	if !isCached || now.After(cached.expiresAt) {
+		location, err := backend.Locate(ctx, ip)
+		if err != nil {
+			// Failures aren't cached, the address is tried again next time
+			sentry.CaptureException(fmt.Errorf("ip geolocation %s: %w", backend.Name(), err))
+			return nil, ErrLocationUnknown
+		}
+		cached = cachedIPLocation{location: location, expiresAt: now.Add(ipLocationCacheTTL)}
+
+		ipLocationCache.Lock()
+		if ipLocationCache.entries == nil || len(ipLocationCache.entries) >= maxCachedIPLocations {
+			ipLocationCache.entries = make(map[string]cachedIPLocation)
+		}
+		ipLocationCache.entries[key] = cached
+		ipLocationCache.Unlock()
+	}
+
+	if cached.location == nil {
+		return nil, ErrLocationUnknown
+	}
+	location := *cached.location
+	return &location, nil
+}
+
+// ClearIPLocationCache forgets the located addresses, used when the backend
+// changes
+func ClearIPLocationCache() {
+	ipLocationCache.Lock()
+	ipLocationCache.entries = nil
+	ipLocationCache.Unlock()
+}
+
+var geoIPHTTPClient = &http.Client{Timeout: 2 * time.Second}
+
+// GeoLiteBackend asks the MaxMind GeoLite2 City web service
+type GeoLiteBackend struct {
+	URL        string
+	AccountID  string
+	LicenseKey string
+}
+
+func (g *GeoLiteBackend) Name() string {
+	return "geolite"
+}
+
+// Locate looks the address up in the web service
+func (g *GeoLiteBackend) Locate(ctx context.Context, ip net.IP) (*IPLocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(g.URL, "/")+"/"+ip.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(g.AccountID, g.LicenseKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := geoIPHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Reserved and unknown addresses are answered with 404
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geolite: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		City struct {
+			Names map[string]string `json:"names"`
+		} `json:"city"`
+		Country struct {
+			ISOCode string `json:"iso_code"`
+		} `json:"country"`
+		Location *struct {
+			Latitude       *float64 `json:"latitude"`
+			Longitude      *float64 `json:"longitude"`
+			AccuracyRadius float64  `json:"accuracy_radius"` // in km
+		} `json:"location"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Location == nil || result.Location.Latitude == nil || result.Location.Longitude == nil {
+		return nil, nil
+	}
+
+	return &IPLocation{
+		Latitude:         *result.Location.Latitude,
+		Longitude:        *result.Location.Longitude,
+		AccuracyRadiusKm: result.Location.AccuracyRadius,
+		City:             result.City.Names["en"],
+		Country:          result.Country.ISOCode,
+	}, nil
+}
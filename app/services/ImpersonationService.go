@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	// ImpersonationTTL is how long an impersonation token works
+	ImpersonationTTL = 30 * time.Minute
+	// ImpersonationHeader carries the token on the requests made as the
+	// impersonated user
+	ImpersonationHeader = "X-Impersonation-Token"
+)
+
+// ErrInvalidImpersonation is returned when an impersonation token is unknown
+// or expired
+var ErrInvalidImpersonation = errors.New("impersonation token is invalid or expired")
+
+// ImpersonationService lets support admins see the app as a snapp user sees
+// it, read-only, auditing every request they make
+type ImpersonationService struct{}
+
+// Start opens a session impersonating the snapp user, returning the session
+// and its token
+func (is *ImpersonationService) Start(ctx context.Context, adminID int64, snappUser *models.SnappUser, reason string) (*models.ImpersonationSession, string, error) {
+	token, err := newUserToken()
+	if err != nil {
+		return nil, "", err
+	}
+	session := &models.ImpersonationSession{
+		AdminID:     adminID,
+		SnappUserID: snappUser.Id,
+		SnappID:     snappUser.SnappId,
+		Reason:      reason,
+		TokenHash:   hashUserToken(token),
+		ExpiresAt:   time.Now().UTC().Add(ImpersonationTTL),
+	}
+	if err := session.Create(ctx); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// Get returns the unexpired session of the token, ErrInvalidImpersonation
+// when there is none
+func (is *ImpersonationService) Get(ctx context.Context, token string) (*models.ImpersonationSession, error) {
+	session, err := models.GetImpersonationSession(ctx, hashUserToken(token), time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidImpersonation
+	}
+	return session, err
+}
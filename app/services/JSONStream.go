@@ -0,0 +1,65 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONArrayWriter writes a JSON array an element at a time, so large
+// listings are sent as they are read instead of built in memory. Nothing is
+// written until the first element or Close, which lets callers still send
+// an error response when reading fails before that.
+type JSONArrayWriter struct {
+	w      io.Writer
+	prefix string
+	count  int
+	open   bool
+}
+
+// NewJSONArrayWriter returns a writer of the array to w. The prefix, like
+// `{"venues":`, is written before the array.
+func NewJSONArrayWriter(w io.Writer, prefix string) *JSONArrayWriter {
+	return &JSONArrayWriter{w: w, prefix: prefix}
+}
+
+// Write appends the element to the array
+func (aw *JSONArrayWriter) Write(element interface{}) error {
+	data, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+	separator := ","
+	if !aw.open {
+		separator = aw.prefix + "["
+		aw.open = true
+	}
+	if _, err := io.WriteString(aw.w, separator); err != nil {
+		return err
+	}
+	if _, err := aw.w.Write(data); err != nil {
+		return err
+	}
+	aw.count++
+	return nil
+}
+
+// Close ends the array, writing an empty one when it has no elements
+func (aw *JSONArrayWriter) Close() error {
+	end := "]"
+	if !aw.open {
+		end = aw.prefix + "[]"
+		aw.open = true
+	}
+	_, err := io.WriteString(aw.w, end)
+	return err
+}
+
+// Count returns how many elements were written
+func (aw *JSONArrayWriter) Count() int {
+	return aw.count
+}
+
+// Started reports whether anything was written
+func (aw *JSONArrayWriter) Started() bool {
+	return aw.open
+}
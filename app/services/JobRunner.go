@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Job is a unit of background work executed periodically by the JobRunner
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// JobRunner runs registered jobs on their own tickers until stopped
+type JobRunner struct {
+	jobs   []Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Register adds a job to the runner. Jobs must be registered before Start.
+// The context passed to run is cancelled when the runner stops.
+func (jr *JobRunner) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	jr.jobs = append(jr.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start launches every registered job. Each job runs once immediately and
+// then on every tick of its interval
+func (jr *JobRunner) Start() {
+	jr.ctx, jr.cancel = context.WithCancel(context.Background())
+
+	for _, job := range jr.jobs {
+		jr.wg.Add(1)
+		go jr.loop(job)
+	}
+}
+
+// Stop cancels running executions and waits for all jobs to exit
+func (jr *JobRunner) Stop() {
+	if jr.cancel == nil {
+		return
+	}
+	jr.cancel()
+	jr.wg.Wait()
+	jr.cancel = nil
+}
+
+func (jr *JobRunner) loop(job Job) {
+	defer jr.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	jr.execute(job)
+	for {
+		select {
+		case <-ticker.C:
+			jr.execute(job)
+		case <-jr.ctx.Done():
+			return
+		}
+	}
+}
+
+// execute runs a job once, reporting errors and panics without killing the loop
+func (jr *JobRunner) execute(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			sentry.CaptureException(fmt.Errorf("job %s panicked: %v", job.Name, r))
+		}
+	}()
+
+	err := job.Run(jr.ctx)
+	if err != nil && jr.ctx.Err() == nil {
+		log.Printf("Job %s failed: %v", job.Name, err)
+		sentry.CaptureException(err)
+	}
+}
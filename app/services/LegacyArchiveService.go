@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+// legacySunset tells whether the legacy votings are read-only, votes going
+// to campaigns instead
+var legacySunset = struct {
+	sync.RWMutex
+	enabled bool
+}{}
+
+func init() {
+	legacySunset.enabled = config.Get().LegacyVotingSunset
+}
+
+// IsLegacyVotingSunset tells whether legacy votes are refused
+func IsLegacyVotingSunset() bool {
+	legacySunset.RLock()
+	defer legacySunset.RUnlock()
+	return legacySunset.enabled
+}
+
+// SetLegacyVotingSunset replaces the sunset switch of the configuration
+func SetLegacyVotingSunset(enabled bool) {
+	legacySunset.Lock()
+	legacySunset.enabled = enabled
+	legacySunset.Unlock()
+}
+
+// ArchivedVoting is the final result of an ended legacy voting round
+type ArchivedVoting struct {
+	Voting     models.Voting       `json:"voting"`
+	TotalVotes int64               `json:"totalVotes"`
+	Results    []ParticipantResult `json:"results"`
+}
+
+// LegacyArchiveService exposes the history of the legacy votings
+type LegacyArchiveService struct{}
+
+// GetArchive returns the final results of every ended voting round, the most
+// recent first
+func (la *LegacyArchiveService) GetArchive(ctx context.Context) ([]ArchivedVoting, error) {
+	votings, err := models.GetEndedLegacyVotings(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	archive := make([]ArchivedVoting, 0, len(votings))
+	if len(votings) == 0 {
+		return archive, nil
+	}
+
+	votingIDs := make([]int64, len(votings))
+	for i, voting := range votings {
+		votingIDs[i] = voting.Id
+	}
+	counts, err := models.GetLegacyVoteCounts(ctx, votingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var participant models.Participant
+	participants := participant.All(ctx)
+	for _, voting := range votings {
+		archived := ArchivedVoting{Voting: voting}
+		archived.Results, archived.TotalVotes = participantResults(voting, counts[voting.Id], participants)
+		archive = append(archive, archived)
+	}
+	return archive, nil
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// Legacy voting datasets
+const (
+	LegacyMentors      = "mentors"
+	LegacyParticipants = "participants"
+	LegacyVoting       = "voting"
+)
+
+// MaxLegacyImportBytes caps the size of an imported CSV file
+const MaxLegacyImportBytes = 10 << 20
+
+// ErrUnknownDataset is returned for datasets other than the legacy ones
+var ErrUnknownDataset = errors.New("unknown dataset, use mentors, participants or voting")
+
+// legacyColumns are the CSV columns of each dataset
+var legacyColumns = map[string][]string{
+	LegacyMentors:      {"id", "name", "photo"},
+	LegacyParticipants: {"id", "name", "code", "photo", "is_active", "mentor_id"},
+	LegacyVoting:       {"id", "name", "description", "winner_id", "started_at", "ended_at"},
+}
+
+// LegacyDataService exports the legacy voting tables to CSV and imports
+// edited CSV files back
+type LegacyDataService struct{}
+
+// LegacyImportResult reports what an import changed, or would change in a
+// dry run. Nothing is saved when a row has errors.
+type LegacyImportResult struct {
+	Dataset string           `json:"dataset"`
+	DryRun  bool             `json:"dryRun"`
+	Rows    int              `json:"rows"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Errors  []LegacyRowError `json:"errors,omitempty"`
+}
+
+// LegacyRowError is an invalid row of an imported CSV file
+type LegacyRowError struct {
+	Line    int    `json:"line"` // Line in the file, the header is line 1
+	Message string `json:"message"`
+}
+
+// Export writes the dataset as CSV, a header followed by the rows by ID.
+// Times are RFC 3339 in UTC.
+func (s *LegacyDataService) Export(ctx context.Context, dataset string, w io.Writer) error {
+	columns, exists := legacyColumns[dataset]
+	if !exists {
+		return ErrUnknownDataset
+	}
+
+	var records [][]string
+	switch dataset {
+	case LegacyMentors:
+		mentors, err := models.GetLegacyMentors(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mentor := range mentors {
+			records = append(records, []string{formatID(mentor.Id), mentor.Name, mentor.Photo})
+		}
+	case LegacyParticipants:
+		participants, err := models.GetLegacyParticipants(ctx)
+		if err != nil {
+			return err
+		}
+		for _, participant := range participants {
+			records = append(records, []string{
+				formatID(participant.Id), participant.Name, participant.Code, participant.Photo,
+				strconv.FormatBool(participant.IsActive), formatID(participant.MentorId),
+			})
+		}
+	case LegacyVoting:
+		votings, err := models.GetLegacyVotings(ctx)
+		if err != nil {
+			return err
+		}
+		for _, voting := range votings {
+			records = append(records, []string{
+				formatID(voting.Id), voting.Name, voting.Description, formatID(voting.WinnerId),
+				voting.StartedAt.UTC().Format(time.RFC3339), voting.EndedAt.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	writer.WriteAll(records)
+	return writer.Error()
+}
+
+// Import validates a CSV file of the dataset and saves its rows. The header
+// names the columns, in any order. Rows with an ID replace that row, rows
+// with an empty ID are created. A dry run reports the changes without
+// saving them.
+func (s *LegacyDataService) Import(ctx context.Context, dataset string, r io.Reader, dryRun bool) (*LegacyImportResult, error) {
+	columns, exists := legacyColumns[dataset]
+	if !exists {
+		return nil, ErrUnknownDataset
+	}
+
+	result := &LegacyImportResult{Dataset: dataset, DryRun: dryRun}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: "the file is empty"})
+		return result, nil
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: err.Error()})
+		return result, nil
+	}
+	index, headerErr := legacyColumnIndex(header, columns)
+	if headerErr != "" {
+		result.Errors = append(result.Errors, LegacyRowError{Line: 1, Message: headerErr})
+		return result, nil
+	}
+
+	parser := &legacyRowParser{seenIDs: make(map[int64]int)}
+	legacyImport := &models.LegacyImport{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, LegacyRowError{Line: line, Message: err.Error()})
+			break
+		}
+
+		result.Rows++
+		field := func(column string) string {
+			return strings.TrimSpace(record[index[column]])
+		}
+		parser.line = line
+		switch dataset {
+		case LegacyMentors:
+			if mentor, ok := parser.mentor(field); ok {
+				legacyImport.Mentors = append(legacyImport.Mentors, mentor)
+			}
+		case LegacyParticipants:
+			if participant, ok := parser.participant(field); ok {
+				legacyImport.Participants = append(legacyImport.Participants, participant)
+			}
+		case LegacyVoting:
+			if voting, ok := parser.voting(field); ok {
+				legacyImport.Votings = append(legacyImport.Votings, voting)
+			}
+		}
+	}
+	result.Errors = append(result.Errors, parser.errors...)
+
+	// References must point at stored rows
+	if err := s.checkReferences(ctx, parser.references, result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	result.Created, result.Updated, err = legacyImport.Apply(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkReferences reports the rows referencing mentors or participants that
+// don't exist
+func (s *LegacyDataService) checkReferences(ctx context.Context, references []legacyReference, result *LegacyImportResult) error {
+	idsByTable := make(map[string][]int64)
+	for _, reference := range references {
+		idsByTable[reference.table] = append(idsByTable[reference.table], reference.id)
+	}
+
+	existing := make(map[string]map[int64]bool, len(idsByTable))
+	for table, ids := range idsByTable {
+		found, err := models.GetExistingLegacyIDs(ctx, table, ids)
+		if err != nil {
+			return err
+		}
+		existing[table] = found
+	}
+
+	for _, reference := range references {
+		if !existing[reference.table][reference.id] {
+			result.Errors = append(result.Errors, LegacyRowError{
+				Line:    reference.line,
+				Message: fmt.Sprintf("%s %d not found", reference.column, reference.id),
+			})
+		}
+	}
+	return nil
+}
+
+// legacyColumnIndex maps the dataset's columns to their position in the
+// header, which must have all of them and no others
+func legacyColumnIndex(header, columns []string) (map[string]int, string) {
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\uFEFF")))
+		if _, duplicate := index[column]; duplicate {
+			return nil, fmt.Sprintf("column %s appears twice", column)
+		}
+		index[column] = i
+	}
+
+	for _, column := range columns {
+		if _, exists := index[column]; !exists {
+			return nil, fmt.Sprintf("missing column %s, expected: %s", column, strings.Join(columns, ", "))
+		}
+	}
+	if len(index) != len(columns) {
+		return nil, fmt.Sprintf("unknown columns, expected: %s", strings.Join(columns, ", "))
+	}
+	return index, ""
+}
+
+// legacyReference is an ID of another table a row refers to
+type legacyReference struct {
+	line   int
+	column string
+	table  string
+	id     int64
+}
+
+// legacyRowParser parses and validates the rows of an imported file,
+// collecting their errors
+type legacyRowParser struct {
+	line       int
+	errors     []LegacyRowError
+	seenIDs    map[int64]int
+	references []legacyReference
+}
+
+func (p *legacyRowParser) fail(format string, args ...interface{}) {
+	p.errors = append(p.errors, LegacyRowError{Line: p.line, Message: fmt.Sprintf(format, args...)})
+}
+
+// id parses an optional positive ID, 0 when empty
+func (p *legacyRowParser) id(column, value string) (int64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		p.fail("%s must be a positive integer", column)
+		return 0, false
+	}
+	return id, true
+}
+
+// rowID parses the row's own ID, which may appear only once in the file
+func (p *legacyRowParser) rowID(value string) (int64, bool) {
+	id, ok := p.id("id", value)
+	if !ok || id == 0 {
+		return id, ok
+	}
+	if line, seen := p.seenIDs[id]; seen {
+		p.fail("id %d is already used on line %d", id, line)
+		return 0, false
+	}
+	p.seenIDs[id] = p.line
+	return id, true
+}
+
+func (p *legacyRowParser) name(value string) (string, bool) {
+	if value == "" || len(value) > 255 {
+		p.fail("name is required and must be at most 255 characters")
+		return "", false
+	}
+	return value, true
+}
+
+func (p *legacyRowParser) reference(column, table, value string) (int64, bool) {
+	id, ok := p.id(column, value)
+	if ok && id != 0 {
+		p.references = append(p.references, legacyReference{line: p.line, column: column, table: table, id: id})
+	}
+	return id, ok
+}
+
+func (p *legacyRowParser) mentor(field func(string) string) (models.Mentor, bool) {
+	id, idOK := p.rowID(field("id"))
+	name, nameOK := p.name(field("name"))
+	return models.Mentor{Id: id, Name: name, Photo: field("photo")}, idOK && nameOK
+}
+
+func (p *legacyRowParser) participant(field func(string) string) (models.Participant, bool) {
+	id, idOK := p.rowID(field("id"))
+	name, nameOK := p.name(field("name"))
+	mentorID, mentorOK := p.reference("mentor_id", LegacyMentors, field("mentor_id"))
+
+	isActive, activeOK := true, true
+	if value := field("is_active"); value != "" {
+		var err error
+		if isActive, err = strconv.ParseBool(value); err != nil {
+			p.fail("is_active must be true or false")
+			activeOK = false
+		}
+	}
+
+	participant := models.Participant{
+		Id:       id,
+		Name:     name,
+		Code:     field("code"),
+		Photo:    field("photo"),
+		IsActive: isActive,
+		MentorId: mentorID,
+	}
+	return participant, idOK && nameOK && mentorOK && activeOK
+}
+
+func (p *legacyRowParser) voting(field func(string) string) (models.Voting, bool) {
+	id, idOK := p.rowID(field("id"))
+	name, nameOK := p.name(field("name"))
+	winnerID, winnerOK := p.reference("winner_id", LegacyParticipants, field("winner_id"))
+
+	startedAt, startErr := time.Parse(time.RFC3339, field("started_at"))
+	if startErr != nil {
+		p.fail("started_at must be an RFC 3339 time like 2024-01-31T18:00:00Z")
+	}
+	endedAt, endErr := time.Parse(time.RFC3339, field("ended_at"))
+	if endErr != nil {
+		p.fail("ended_at must be an RFC 3339 time like 2024-01-31T18:00:00Z")
+	}
+	timesOK := startErr == nil && endErr == nil
+	if timesOK && !endedAt.After(startedAt) {
+		p.fail("ended_at must be after started_at")
+		timesOK = false
+	}
+
+	voting := models.Voting{
+		Id:          id,
+		Name:        name,
+		Description: field("description"),
+		WinnerId:    winnerID,
+		StartedAt:   startedAt.UTC(),
+		EndedAt:     endedAt.UTC(),
+	}
+	return voting, idOK && nameOK && winnerOK && timesOK
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
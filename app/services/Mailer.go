@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"voting-app/app/config"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// AppMailer sends the account emails. It goes through SMTP when configured
+// and logs the emails otherwise, which is enough for local development.
+var AppMailer Mailer = &LogMailer{}
+
+func init() {
+	mail := config.Get().Mail
+	if mail.SMTPHost != "" {
+		AppMailer = &SMTPMailer{
+			Host:     mail.SMTPHost,
+			Port:     mail.SMTPPort,
+			Username: mail.SMTPUser,
+			Password: mail.SMTPPassword,
+			From:     mail.From,
+		}
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server, authenticating when a
+// username is set
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(fmt.Sprintf("%s:%d", m.Host, m.Port), auth, m.From, []string{to}, []byte(message))
+}
+
+// LogMailer writes emails to the log instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
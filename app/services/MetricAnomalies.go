@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+const (
+	// anomalyBaselineDays is how many days before the checked one make up
+	// its baseline
+	anomalyBaselineDays = 28
+	// anomalyMinChange is the smallest absolute deviation from the baseline
+	// mean raising an alert, so quiet metrics don't alert on a handful of
+	// events
+	anomalyMinChange = 5
+)
+
+// DetectAnomalies checks yesterday's platform metrics for anomalies. Alerts
+// are stored once per metric and day so the job can run repeatedly.
+func (as *AnalyticsService) DetectAnomalies(ctx context.Context) error {
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	_, err := as.DetectMetricAnomalies(ctx, yesterday)
+	return err
+}
+
+// DetectMetricAnomalies compares each platform metric of the day with the
+// days before it and stores an alert for those whose z-score reaches the
+// configured threshold. It returns the alerts it created, admins are
+// notified of them when configured to.
+func (as *AnalyticsService) DetectMetricAnomalies(ctx context.Context, day time.Time) ([]models.MetricAlert, error) {
+	threshold := config.Get().Anomalies.Threshold
+	alerts := make([]models.MetricAlert, 0)
+
+	for _, metric := range models.Metrics {
+		totals, err := models.GetDailyMetric(ctx, metric, day.AddDate(0, 0, -anomalyBaselineDays), day)
+		if err != nil {
+			return alerts, err
+		}
+		if len(totals) == 0 {
+			continue
+		}
+
+		alert := detectAnomaly(totals[len(totals)-1], totals[:len(totals)-1], threshold)
+		if alert == nil {
+			continue
+		}
+		alert.Metric = metric
+		alert.Day = day
+
+		created, err := alert.Create(ctx)
+		if err != nil {
+			return alerts, err
+		}
+		if created {
+			alerts = append(alerts, *alert)
+		}
+	}
+
+	if len(alerts) > 0 && config.Get().Anomalies.NotifyAdmins {
+		adminIDs, err := models.GetAdminSnappUserIDs(ctx)
+		if err != nil {
+			return alerts, err
+		}
+		notificationService := new(NotificationService)
+		for _, alert := range alerts {
+			notificationService.NotifyMetricAlert(ctx, adminIDs, alert)
+		}
+	}
+
+	return alerts, nil
+}
+
+// GetMetricAlerts returns the alerts of the last days, of one metric or all
+// when metric is empty
+func (as *AnalyticsService) GetMetricAlerts(ctx context.Context, days int, metric string) ([]models.MetricAlert, error) {
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+	return models.GetMetricAlerts(ctx, since, metric)
+}
+
+// detectAnomaly returns an alert when the value's z-score against the
+// baseline reaches the threshold. Daily metrics are counts, so the standard
+// deviation is floored at the square root of the mean (and 1) to keep a
+// steady baseline from turning any change into an alert.
+func detectAnomaly(value float64, baseline []float64, threshold float64) *models.MetricAlert {
+	if len(baseline) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, total := range baseline {
+		sum += total
+	}
+	mean := sum / float64(len(baseline))
+
+	var squares float64
+	for _, total := range baseline {
+		squares += (total - mean) * (total - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(len(baseline)))
+
+	change := value - mean
+	if math.Abs(change) < anomalyMinChange {
+		return nil
+	}
+	zScore := change / math.Max(stdDev, math.Max(math.Sqrt(mean), 1))
+	if math.Abs(zScore) < threshold {
+		return nil
+	}
+
+	direction := models.MetricSpike
+	if change < 0 {
+		direction = models.MetricDrop
+	}
+	return &models.MetricAlert{
+		Value:     value,
+		Mean:      math.Round(mean*100) / 100,
+		StdDev:    math.Round(stdDev*100) / 100,
+		ZScore:    math.Round(zScore*100) / 100,
+		Direction: direction,
+	}
+}
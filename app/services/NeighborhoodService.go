@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"voting-app/app/models"
+)
+
+// NeighborhoodService places venues in the neighborhood polygons of their city
+type NeighborhoodService struct{}
+
+// FindNeighborhood returns the smallest neighborhood of the city the point
+// lies in, nil when it is in none
+func (ns *NeighborhoodService) FindNeighborhood(ctx context.Context, cityID int64, latitude, longitude float64) (*models.Neighborhood, error) {
+	neighborhood, err := models.FindContainingNeighborhood(ctx, cityID, latitude, longitude)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return neighborhood, err
+}
+
+// CreateNeighborhood stores the neighborhood and places the city's venues
+// within it, moving those of a larger neighborhood it lies in. It returns how
+// many venues were placed, or ErrUnknownCity when the city isn't active.
+func (ns *NeighborhoodService) CreateNeighborhood(ctx context.Context, neighborhood *models.Neighborhood) (int, error) {
+	cities, err := models.GetActiveCities(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cityExists := false
+	for _, city := range cities {
+		cityExists = cityExists || city.ID == neighborhood.CityID
+	}
+	if !cityExists {
+		return 0, ErrUnknownCity
+	}
+
+	if err := neighborhood.Create(ctx); err != nil {
+		return 0, err
+	}
+
+	return models.PlaceVenuesInNeighborhood(ctx, neighborhood.ID)
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// NotificationService stores in-app notifications and fans them out to the
+// user's registered push devices
+type NotificationService struct{}
+
+// DigestRule batches the notifications of a frequent event type: the events
+// of a user on a subject are collected for Window after the first one, then
+// notified at once
+type DigestRule struct {
+	Window time.Duration
+	// Render returns the notification of the digest, an empty title when
+	// its subject is gone
+	Render func(ctx context.Context, digest models.Digest, period string) (title, body string, data map[string]string, err error)
+}
+
+// digestRules are the batched event types
+var digestRules map[string]DigestRule
+
+// marketingEvents are the event types that are marketing, only notified to
+// users who consented to push marketing
+var marketingEvents = map[string]bool{
+	models.NotificationCampaignStart: true,
+}
+
+func init() {
+	digests := config.Get().Digests
+	digestRules = map[string]DigestRule{
+		models.NotificationReviewHelpful: {Window: digests.HelpfulWindow, Render: renderHelpfulDigest},
+		models.NotificationNewFollower:   {Window: digests.FollowerWindow, Render: renderFollowerDigest},
+	}
+}
+
+// Notify creates a notification for the user and pushes it to their devices.
+// Marketing is dropped, returning no notification, when the user didn't
+// consent to it.
+func (ns *NotificationService) Notify(ctx context.Context, userID int64, eventType, title, body string, data map[string]string) (*models.Notification, error) {
+	if marketingEvents[eventType] {
+		consented, err := models.HasConsent(ctx, userID, models.ConsentPushMarketing)
+		if err != nil || !consented {
+			return nil, err
+		}
+	}
+
+	dataJSON, _ := json.Marshal(data)
+
+	notification := &models.Notification{
+		UserID:    userID,
+		EventType: eventType,
+		Title:     title,
+		Body:      body,
+		Data:      dataJSON,
+	}
+
+	err := notification.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		data = make(map[string]string)
+	}
+	data["eventType"] = eventType
+	data["notificationId"] = fmt.Sprintf("%d", notification.ID)
+
+	// Pushed in the background, the caller's context may end first
+	go ns.push(context.Background(), userID, PushMessage{Title: title, Body: body, Data: data})
+
+	return notification, nil
+}
+
+// NotifyCampaignStart tells users that a voting campaign has opened. The
+// announcement is marketing, only users who consented to it are notified.
+func (ns *NotificationService) NotifyCampaignStart(ctx context.Context, userIDs []int64, campaignID int64, campaignTitle string) {
+	userIDs, err := models.FilterConsentedUsers(ctx, userIDs, models.ConsentPushMarketing)
+	if err != nil {
+		return
+	}
+	for _, userID := range userIDs {
+		_, err := ns.Notify(ctx, userID, models.NotificationCampaignStart,
+			"Voting is open!",
+			fmt.Sprintf("%s has started. Cast your vote now.", campaignTitle),
+			map[string]string{"campaignId": fmt.Sprintf("%d", campaignID)},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+}
+
+// NotifyCampaignResults tells the voters of a closed campaign that its
+// results are final
+func (ns *NotificationService) NotifyCampaignResults(ctx context.Context, userIDs []int64, campaignID int64, campaignTitle string) {
+	for _, userID := range userIDs {
+		_, err := ns.Notify(ctx, userID, models.NotificationCampaignResults,
+			"The results are in!",
+			fmt.Sprintf("Voting in %s has closed. See who won.", campaignTitle),
+			map[string]string{"campaignId": fmt.Sprintf("%d", campaignID)},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+}
+
+// AnnounceCampaigns tells the users who consented to push marketing about
+// the campaigns that opened since the previous run. It is run periodically
+// by the job runner.
+func (ns *NotificationService) AnnounceCampaigns(ctx context.Context) error {
+	campaigns, err := models.ClaimStartedCampaigns(ctx, time.Now().UTC())
+	if err != nil || len(campaigns) == 0 {
+		return err
+	}
+
+	userIDs, err := models.GetConsentedUserIDs(ctx, models.ConsentPushMarketing)
+	if err != nil {
+		return err
+	}
+	for _, campaign := range campaigns {
+		ns.NotifyCampaignStart(ctx, userIDs, campaign.ID, campaign.Title)
+	}
+	return nil
+}
+
+// NotifyMetricAlert tells admins that a platform metric deviated sharply
+func (ns *NotificationService) NotifyMetricAlert(ctx context.Context, userIDs []int64, alert models.MetricAlert) {
+	change := "spiked"
+	if alert.Direction == models.MetricDrop {
+		change = "dropped"
+	}
+	for _, userID := range userIDs {
+		_, err := ns.Notify(ctx, userID, models.NotificationMetricAlert,
+			fmt.Sprintf("Unusual %s activity", alert.Metric),
+			fmt.Sprintf("Daily %s %s to %.0f on %s, against an average of %.1f.",
+				alert.Metric, change, alert.Value, alert.Day.Format("2006-01-02"), alert.Mean),
+			map[string]string{"alertId": fmt.Sprintf("%d", alert.ID), "metric": alert.Metric},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+}
+
+// NotifyReviewReply tells a reviewer that someone replied to their review
+func (ns *NotificationService) NotifyReviewReply(ctx context.Context, userID, reviewID int64, venueName string) error {
+	_, err := ns.Notify(ctx, userID, models.NotificationReviewReply,
+		"New reply to your review",
+		fmt.Sprintf("Someone replied to your review of %s.", venueName),
+		map[string]string{"reviewId": fmt.Sprintf("%d", reviewID)},
+	)
+	return err
+}
+
+// NotifyBadgeEarned tells a user they earned a badge
+func (ns *NotificationService) NotifyBadgeEarned(ctx context.Context, userID int64, badgeName string) error {
+	_, err := ns.Notify(ctx, userID, models.NotificationBadge,
+		"You earned a badge!",
+		fmt.Sprintf("Congratulations, you unlocked \"%s\".", badgeName),
+		map[string]string{"badge": badgeName},
+	)
+	return err
+}
+
+// NotifyReviewHelpful tells the author of the review that the voter found
+// it helpful, in the digest of the review's helpful votes
+func (ns *NotificationService) NotifyReviewHelpful(ctx context.Context, reviewID, voterID int64) error {
+	review := &models.VenueReview{ID: reviewID}
+	if err := review.GetByID(ctx); err != nil {
+		return err
+	}
+	if review.UserID == voterID {
+		return nil
+	}
+	return ns.batch(ctx, &models.DigestEvent{
+		UserID:    review.UserID,
+		EventType: models.NotificationReviewHelpful,
+		SubjectID: review.ID,
+		ActorID:   voterID,
+	})
+}
+
+// NotifyNewFollower tells the user someone followed them, in the digest of
+// their new followers
+func (ns *NotificationService) NotifyNewFollower(ctx context.Context, userID, followerID int64) error {
+	return ns.batch(ctx, &models.DigestEvent{
+		UserID:    userID,
+		EventType: models.NotificationNewFollower,
+		ActorID:   followerID,
+	})
+}
+
+// batch keeps the event for its digest, or notifies it at once when its
+// event type isn't batched
+func (ns *NotificationService) batch(ctx context.Context, event *models.DigestEvent) error {
+	rule := digestRules[event.EventType]
+	if rule.Window > 0 {
+		return event.Create(ctx)
+	}
+	return ns.notifyDigest(ctx, rule, models.Digest{
+		UserID:    event.UserID,
+		EventType: event.EventType,
+		SubjectID: event.SubjectID,
+		Actors:    1,
+	})
+}
+
+// SendDigests notifies the digests whose window ended, one notification
+// for all the events of a user on a subject. Registered as a job.
+func (ns *NotificationService) SendDigests(ctx context.Context) error {
+	now := time.Now()
+	for eventType, rule := range digestRules {
+		digests, err := models.GetDueDigests(ctx, eventType, now.Add(-rule.Window))
+		if err != nil {
+			return err
+		}
+		for _, digest := range digests {
+			actors, err := models.TakeDigestEvents(ctx, digest)
+			if err != nil {
+				return err
+			}
+			if actors == 0 {
+				continue
+			}
+			digest.Actors = actors
+			if err := ns.notifyDigest(ctx, rule, digest); err != nil {
+				sentry.CaptureException(err)
+			}
+		}
+	}
+	return nil
+}
+
+// notifyDigest renders the digest and notifies the user
+func (ns *NotificationService) notifyDigest(ctx context.Context, rule DigestRule, digest models.Digest) error {
+	title, body, data, err := rule.Render(ctx, digest, digestPeriod(rule.Window))
+	if err != nil || title == "" {
+		return err
+	}
+	if _, err := ns.Notify(ctx, digest.UserID, digest.EventType, title, body, data); err != nil {
+		return err
+	}
+	ns.emailDigest(ctx, digest.UserID, title, body)
+	return nil
+}
+
+// emailDigest also emails the digest to users who consented to email digests
+// and have a verified email on their linked account
+func (ns *NotificationService) emailDigest(ctx context.Context, userID int64, title, body string) {
+	consented, err := models.HasConsent(ctx, userID, models.ConsentEmailDigest)
+	if err != nil || !consented {
+		return
+	}
+	email, err := models.GetVerifiedEmailBySnappUser(ctx, userID)
+	if err != nil {
+		return
+	}
+	if err := AppMailer.Send(email, title, body); err != nil {
+		sentry.CaptureException(err)
+	}
+}
+
+// digestPeriod names the time the events of a digest window happened in,
+// empty for events notified at once
+func digestPeriod(window time.Duration) string {
+	switch {
+	case window <= 0:
+		return ""
+	case window <= 24*time.Hour:
+		return " today"
+	case window <= 7*24*time.Hour:
+		return " this week"
+	default:
+		return " recently"
+	}
+}
+
+// renderHelpfulDigest renders the helpful votes on a review, like "12
+// people found your review of Tartine helpful today"
+func renderHelpfulDigest(ctx context.Context, digest models.Digest, period string) (string, string, map[string]string, error) {
+	review := &models.VenueReview{ID: digest.SubjectID}
+	if err := review.GetByID(ctx); err == sql.ErrNoRows {
+		return "", "", nil, nil
+	} else if err != nil {
+		return "", "", nil, err
+	}
+
+	people := "Someone"
+	if digest.Actors > 1 {
+		people = fmt.Sprintf("%d people", digest.Actors)
+	}
+	return "Your review is helping others",
+		fmt.Sprintf("%s found your review of %s helpful%s.", people, review.VenueName, period),
+		map[string]string{"reviewId": fmt.Sprintf("%d", review.ID), "count": fmt.Sprintf("%d", digest.Actors)},
+		nil
+}
+
+// renderFollowerDigest renders the new followers of a user
+func renderFollowerDigest(ctx context.Context, digest models.Digest, period string) (string, string, map[string]string, error) {
+	if digest.Actors == 1 {
+		return "New follower", fmt.Sprintf("Someone started following you%s.", period), map[string]string{"count": "1"}, nil
+	}
+	return "New followers", fmt.Sprintf("%d people started following you%s.", digest.Actors, period), map[string]string{"count": fmt.Sprintf("%d", digest.Actors)}, nil
+}
+
+// push delivers the message to every active device of the user, deactivating
+// tokens the provider no longer accepts
+func (ns *NotificationService) push(ctx context.Context, userID int64, message PushMessage) {
+	devices, err := models.GetUserDevices(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	for _, device := range devices {
+		adapter, exists := PushAdapters[device.Platform]
+		if !exists {
+			continue
+		}
+
+		err := adapter.Send(device.Token, message)
+		if err == ErrInvalidDeviceToken {
+			device.Deactivate(ctx)
+		} else if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+}
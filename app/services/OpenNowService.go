@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// OpenNowBoostMinutes is how long a venue has to stay open to rank ahead of
+// the venues about to close
+const OpenNowBoostMinutes = 60
+
+// OpenNowService finds the venues open at a given time
+type OpenNowService struct{}
+
+// OpenVenue is an open venue with how long it stays open
+type OpenVenue struct {
+	Venue             models.Venue `json:"venue"`
+	ClosesAt          string       `json:"closesAt"` // HH:MM, local to the venue's city
+	MinutesUntilClose int          `json:"minutesUntilClose"`
+}
+
+// GetOpenNow returns the venues within the radius in km that are open at now,
+// going by their hours exceptions before their regular hours.
+// Venues open for at least another OpenNowBoostMinutes come first; both
+// groups are sorted by closing time, then distance.
+func (s *OpenNowService) GetOpenNow(ctx context.Context, lat, lng, radius float64, limit int, now time.Time) ([]OpenVenue, error) {
+	venues, err := models.GetNearbyVenuesWithHours(ctx, lat, lng, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	venueIDs := make([]int64, len(venues))
+	for i, venue := range venues {
+		venueIDs[i] = venue.ID
+	}
+	locations, err := models.GetVenueLocations(ctx, venueIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Yesterday's exceptions can run past midnight; a day either way covers
+	// every time zone
+	exceptions, err := models.GetHoursExceptions(ctx, venueIDs,
+		now.AddDate(0, 0, -2).Format(models.DateLayout), now.AddDate(0, 0, 1).Format(models.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	open := make([]OpenVenue, 0, len(venues))
+	for _, venue := range venues {
+		hours, err := models.ParseOpeningHours(venue.OpeningHours)
+		if err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		location, exists := locations[venue.ID]
+		if !exists {
+			location = time.UTC
+		}
+		localNow := now.In(location)
+		closesAt, isOpen := models.NewSchedule(hours, exceptions[venue.ID]).ClosesAt(localNow)
+		if !isOpen {
+			continue
+		}
+
+		venue.IsOpen = &isOpen
+		distance := math.Round(*venue.Distance*100) / 100
+		venue.Distance = &distance
+		open = append(open, OpenVenue{
+			Venue:             venue,
+			ClosesAt:          closesAt.Format("15:04"),
+			MinutesUntilClose: int(closesAt.Sub(localNow).Minutes()),
+		})
+	}
+
+	sort.SliceStable(open, func(i, j int) bool {
+		iBoosted := open[i].MinutesUntilClose >= OpenNowBoostMinutes
+		jBoosted := open[j].MinutesUntilClose >= OpenNowBoostMinutes
+		if iBoosted != jBoosted {
+			return iBoosted
+		}
+		if open[i].MinutesUntilClose != open[j].MinutesUntilClose {
+			return open[i].MinutesUntilClose < open[j].MinutesUntilClose
+		}
+		return *open[i].Venue.Distance < *open[j].Venue.Distance
+	})
+
+	if len(open) > limit {
+		open = open[:limit]
+	}
+	return open, nil
+}
+
+// AttachOpenStatus sets whether the venue is open at now and, when it is
+// closed, when it opens next. Its hours exceptions go before its regular
+// hours. Venues without opening hours are left as they are.
+func (s *OpenNowService) AttachOpenStatus(ctx context.Context, venue *models.Venue, now time.Time) error {
+	if len(venue.OpeningHours) == 0 || string(venue.OpeningHours) == "null" {
+		return nil
+	}
+	hours, err := models.ParseOpeningHours(venue.OpeningHours)
+	if err != nil {
+		return err
+	}
+
+	locations, err := models.GetVenueLocations(ctx, []int64{venue.ID})
+	if err != nil {
+		return err
+	}
+	location, exists := locations[venue.ID]
+	if !exists {
+		location = time.UTC
+	}
+	exceptions, err := models.GetHoursExceptions(ctx, []int64{venue.ID},
+		now.AddDate(0, 0, -2).Format(models.DateLayout), now.AddDate(0, 0, 16).Format(models.DateLayout))
+	if err != nil {
+		return err
+	}
+
+	schedule := models.NewSchedule(hours, exceptions[venue.ID])
+	localNow := now.In(location)
+	_, isOpen := schedule.ClosesAt(localNow)
+	venue.IsOpen = &isOpen
+	if !isOpen {
+		if opensAt, ok := schedule.NextOpenTime(localNow); ok {
+			nextOpenTime := opensAt.Format(time.RFC3339)
+			venue.NextOpenTime = &nextOpenTime
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Milestones venue owners are told about
+const (
+	OwnerMilestoneReviews = 100 // Approved reviews
+	OwnerMilestoneRating  = 4.5 // Average rating
+	// OwnerMilestoneRatingMinReviews keeps a handful of glowing reviews from
+	// counting as the rating milestone
+	OwnerMilestoneRatingMinReviews = 20
+	ownerAlertListedReviews        = 5 // Reviews listed in an email, the rest are counted
+)
+
+// OwnerAlertInterval is the least time between two alerts to an owner, the
+// reviews approved in between are sent together
+var OwnerAlertInterval time.Duration
+
+func init() {
+	OwnerAlertInterval = config.Get().OwnerAlertInterval
+}
+
+// ownerAlert is what an owner is told in one email
+type ownerAlert struct {
+	owner      models.OwnerAlertVenue
+	reviews    []models.OwnerReviewAlert
+	milestones []models.OwnerMilestoneAlert
+}
+
+// OwnerAlertService tells the owners of claimed venues about their new
+// approved reviews and the milestones their venues reach, by email and by
+// notification when their account is linked to a snapp user
+type OwnerAlertService struct{}
+
+// SendOwnerAlerts records the milestones reached and sends every owner not
+// alerted within OwnerAlertInterval one email about what happened since their
+// last one. It is run periodically by the job runner.
+func (oa *OwnerAlertService) SendOwnerAlerts(ctx context.Context) error {
+	err := models.RecordVenueMilestones(ctx, OwnerMilestoneReviews, OwnerMilestoneRating, OwnerMilestoneRatingMinReviews)
+	if err != nil {
+		return err
+	}
+
+	sentBefore := time.Now().Add(-OwnerAlertInterval)
+	reviews, err := models.GetPendingOwnerReviews(ctx, sentBefore)
+	if err != nil {
+		return err
+	}
+	milestones, err := models.GetPendingOwnerMilestones(ctx, sentBefore)
+	if err != nil {
+		return err
+	}
+
+	alerts := make(map[int64]*ownerAlert)
+	owners := make([]int64, 0)
+	alertOf := func(owner models.OwnerAlertVenue) *ownerAlert {
+		alert, ok := alerts[owner.OwnerID]
+		if !ok {
+			alert = &ownerAlert{owner: owner}
+			alerts[owner.OwnerID] = alert
+			owners = append(owners, owner.OwnerID)
+		}
+		return alert
+	}
+	for _, review := range reviews {
+		alert := alertOf(review.OwnerAlertVenue)
+		alert.reviews = append(alert.reviews, review)
+	}
+	for _, milestone := range milestones {
+		alert := alertOf(milestone.OwnerAlertVenue)
+		alert.milestones = append(alert.milestones, milestone)
+	}
+
+	for _, ownerID := range owners {
+		if err := oa.send(ctx, alerts[ownerID]); err != nil {
+			// Keep going, the owner's alert is retried on the next run
+			sentry.CaptureException(err)
+		}
+	}
+	return nil
+}
+
+// send emails the alert to the owner, notifies their linked snapp user and
+// records it as sent
+func (oa *OwnerAlertService) send(ctx context.Context, alert *ownerAlert) error {
+	subject, body := ownerAlertEmail(alert)
+	if err := AppMailer.Send(alert.owner.OwnerEmail, subject, body); err != nil {
+		return err
+	}
+	sentAt := time.Now()
+
+	identity, err := models.GetIdentityByUser(ctx, alert.owner.OwnerID)
+	if err == nil && identity.SnappUserID != 0 {
+		oa.notify(ctx, identity.SnappUserID, alert)
+	}
+
+	var reviewsUntil *time.Time
+	if len(alert.reviews) > 0 {
+		reviewsUntil = &alert.reviews[len(alert.reviews)-1].ApprovedAt
+	}
+	return models.MarkOwnerAlertSent(ctx, alert.owner.OwnerID, sentAt, reviewsUntil, alert.milestones)
+}
+
+func (oa *OwnerAlertService) notify(ctx context.Context, snappUserID int64, alert *ownerAlert) {
+	notificationService := &NotificationService{}
+	if len(alert.reviews) > 0 {
+		latest := alert.reviews[len(alert.reviews)-1]
+		body := fmt.Sprintf("%s received a %.1f star review.", latest.VenueName, latest.Rating)
+		if len(alert.reviews) > 1 {
+			body = fmt.Sprintf("Your venues received %d new reviews.", len(alert.reviews))
+		}
+		_, err := notificationService.Notify(ctx, snappUserID, models.NotificationOwnerReview,
+			"New reviews", body,
+			map[string]string{
+				"venueId":  fmt.Sprintf("%d", latest.VenueID),
+				"reviewId": fmt.Sprintf("%d", latest.ReviewID),
+				"replyUrl": ReviewReplyURL(latest.VenueSlug, latest.ReviewID),
+			},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+	for _, milestone := range alert.milestones {
+		_, err := notificationService.Notify(ctx, snappUserID, models.NotificationOwnerMilestone,
+			"Milestone reached",
+			fmt.Sprintf("%s reached %s.", milestone.VenueName, ownerMilestoneLabel(milestone.Milestone)),
+			map[string]string{
+				"venueId":   fmt.Sprintf("%d", milestone.VenueID),
+				"milestone": milestone.Milestone,
+			},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+}
+
+// ReviewReplyURL returns the page where a venue's owner replies to a review
+func ReviewReplyURL(venueSlug string, reviewID int64) string {
+	return fmt.Sprintf("%s/reviews/%d#reply", VenuePageURL(venueSlug), reviewID)
+}
+
+func ownerMilestoneLabel(milestone string) string {
+	switch milestone {
+	case models.VenueMilestoneReviews:
+		return fmt.Sprintf("%d reviews", OwnerMilestoneReviews)
+	case models.VenueMilestoneRating:
+		return fmt.Sprintf("a %.1f average rating", OwnerMilestoneRating)
+	}
+	return milestone
+}
+
+func ownerAlertEmail(alert *ownerAlert) (string, string) {
+	var subject string
+	switch {
+	case len(alert.reviews) == 1:
+		subject = "New review of " + alert.reviews[0].VenueName
+	case len(alert.reviews) > 1:
+		subject = fmt.Sprintf("%d new reviews of your venues", len(alert.reviews))
+	default:
+		milestone := alert.milestones[0]
+		subject = fmt.Sprintf("%s reached %s", milestone.VenueName, ownerMilestoneLabel(milestone.Milestone))
+	}
+
+	var body strings.Builder
+	body.WriteString("Hello,\n")
+	if len(alert.reviews) > 0 {
+		body.WriteString("\nYour venues received new reviews:\n")
+		// The latest reviews are listed
+		listed := alert.reviews
+		if len(listed) > ownerAlertListedReviews {
+			listed = listed[len(listed)-ownerAlertListedReviews:]
+		}
+		for i := len(listed) - 1; i >= 0; i-- {
+			review := listed[i]
+			fmt.Fprintf(&body, "\n%s, %.1f/5", review.VenueName, review.Rating)
+			if review.Title != "" {
+				fmt.Fprintf(&body, ": %q", review.Title)
+			}
+			fmt.Fprintf(&body, "\nReply: %s\n", ReviewReplyURL(review.VenueSlug, review.ReviewID))
+		}
+		if more := len(alert.reviews) - len(listed); more > 0 {
+			fmt.Fprintf(&body, "\nand %d more.\n", more)
+		}
+	}
+	for _, milestone := range alert.milestones {
+		fmt.Fprintf(&body, "\n%s reached %s. Congratulations!\n%s\n",
+			milestone.VenueName, ownerMilestoneLabel(milestone.Milestone), VenuePageURL(milestone.VenueSlug))
+	}
+	if OwnerAlertInterval > 0 {
+		fmt.Fprintf(&body, "\nYou get at most one of these emails every %g hours.\n", OwnerAlertInterval.Hours())
+	}
+	return subject, body.String()
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"time"
+	"voting-app/app/models"
+)
+
+// Venue analytics levels of the owner tiers
+const (
+	AnalyticsBasic = "basic" // Traffic, reviews, deals and ranks
+	AnalyticsFull  = "full"  // Adds trends, popular times, search, demographics and growth
+)
+
+// FreeVenuePhotos is how many gallery photos the venues of free owners have
+const FreeVenuePhotos = 5
+
+// TierLimits are what an owner tier allows
+type TierLimits struct {
+	Analytics      string `json:"analytics"`      // AnalyticsBasic or AnalyticsFull
+	MaxVenuePhotos int    `json:"maxVenuePhotos"` // Per venue, 0 for unlimited
+}
+
+var tierLimits = map[string]TierLimits{
+	models.TierFree:    {Analytics: AnalyticsBasic, MaxVenuePhotos: FreeVenuePhotos},
+	models.TierPremium: {Analytics: AnalyticsFull},
+}
+
+// OwnerPlan is a venue owner's tier and what it allows, shown in the
+// owner-facing responses
+type OwnerPlan struct {
+	Tier      string     `json:"tier"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Limits    TierLimits `json:"limits"`
+}
+
+// OwnerPlanService resolves the tiers of venue owners and enforces their
+// quotas
+type OwnerPlanService struct{}
+
+// GetPlan returns the owner's current plan
+func (ps *OwnerPlanService) GetPlan(ctx context.Context, userID int64) (*OwnerPlan, error) {
+	subscription, err := models.GetOwnerSubscription(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &OwnerPlan{
+		Tier:      subscription.Tier,
+		ExpiresAt: subscription.ExpiresAt,
+		Limits:    tierLimits[subscription.Tier],
+	}, nil
+}
+
+// GetVenuePlan returns the plan of the venue's owner. Unclaimed venues are
+// on the free tier.
+func (ps *OwnerPlanService) GetVenuePlan(ctx context.Context, venue *models.Venue) (*OwnerPlan, error) {
+	if venue.OwnerID == nil {
+		return &OwnerPlan{Tier: models.TierFree, Limits: tierLimits[models.TierFree]}, nil
+	}
+	return ps.GetPlan(ctx, *venue.OwnerID)
+}
+
+// AddVenuePhoto adds the photo to the venue's gallery within the quota of
+// its owner's plan, models.ErrVenuePhotoQuota once the gallery is full
+func (ps *OwnerPlanService) AddVenuePhoto(ctx context.Context, venue *models.Venue, photo *models.VenuePhoto) (*OwnerPlan, error) {
+	plan, err := ps.GetVenuePlan(ctx, venue)
+	if err != nil {
+		return nil, err
+	}
+
+	photo.VenueID = venue.ID
+	if err := photo.Create(ctx, plan.Limits.MaxVenuePhotos); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"voting-app/app/config"
+	"voting-app/app/models"
+)
+
+// Photo upload limits
+const (
+	MaxPhotoBytes  = 10 << 20
+	MaxPhotoPixels = 40_000_000 // Guards against decompression bombs
+
+	photoJPEGQuality = 90
+	// Dominant colors are found from at most this many sampled pixels
+	photoColorSamples = 10000
+)
+
+// MaxReviewPhotos is how many photos a review and its draft can have
+var MaxReviewPhotos int
+
+func init() {
+	MaxReviewPhotos = config.Get().MaxReviewPhotos
+}
+
+// PhotoBucket is the bucket photos are stored in, served publicly by the
+// file routes
+const PhotoBucket = "reportage-snapp"
+
+// Photo processing errors
+var (
+	ErrUnsupportedPhoto = errors.New("photo must be a JPEG, PNG or GIF image")
+	ErrPhotoTooLarge    = errors.New("photo has too many pixels")
+)
+
+// PutPhotoObject stores a processed photo. It writes to the file storage
+// and is replaced in tests.
+var PutPhotoObject = func(ctx context.Context, key, contentType string, data []byte) error {
+	return FileStorage.Put(ctx, PhotoBucket, key, contentType, data)
+}
+
+// ProcessedPhoto is a photo re-encoded without metadata
+type ProcessedPhoto struct {
+	Data          []byte
+	ContentType   string
+	Extension     string
+	Width         int
+	Height        int
+	DominantColor string
+}
+
+// PhotoService processes and stores uploaded photos
+type PhotoService struct{}
+
+// UploadReviewPhoto processes the photo, stores it and records its metadata
+func (ps *PhotoService) UploadReviewPhoto(ctx context.Context, userID int64, data []byte) (*models.Photo, error) {
+	processed, err := ProcessPhoto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("reviews/photos/%s.%s", hex.EncodeToString(name), processed.Extension)
+
+	if err := PutPhotoObject(ctx, key, processed.ContentType, processed.Data); err != nil {
+		return nil, fmt.Errorf("storing photo: %w", err)
+	}
+
+	photo := &models.Photo{
+		UserID:        userID,
+		URL:           "/v1/files/" + key,
+		ObjectKey:     key,
+		ContentType:   processed.ContentType,
+		Width:         processed.Width,
+		Height:        processed.Height,
+		DominantColor: processed.DominantColor,
+		SizeBytes:     int64(len(processed.Data)),
+	}
+	if err := photo.Create(ctx); err != nil {
+		return nil, err
+	}
+	return photo, nil
+}
+
+// ProcessPhoto decodes the photo, applies its EXIF orientation and encodes it
+// again, which drops EXIF data such as the GPS position. JPEGs stay JPEGs,
+// other formats become PNGs.
+func ProcessPhoto(data []byte) (*ProcessedPhoto, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedPhoto
+	}
+	if config.Width*config.Height > MaxPhotoPixels {
+		return nil, ErrPhotoTooLarge
+	}
+
+	var img image.Image
+	switch format {
+	case "jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case "png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "gif":
+		img, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrUnsupportedPhoto
+	}
+	if err != nil {
+		return nil, ErrUnsupportedPhoto
+	}
+
+	if format == "jpeg" {
+		img = orientImage(img, jpegOrientation(data))
+	}
+
+	processed := &ProcessedPhoto{
+		Width:         img.Bounds().Dx(),
+		Height:        img.Bounds().Dy(),
+		DominantColor: dominantColor(img),
+	}
+
+	var encoded bytes.Buffer
+	if format == "jpeg" {
+		processed.ContentType, processed.Extension = "image/jpeg", "jpg"
+		err = jpeg.Encode(&encoded, img, &jpeg.Options{Quality: photoJPEGQuality})
+	} else {
+		processed.ContentType, processed.Extension = "image/png", "png"
+		err = png.Encode(&encoded, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	processed.Data = encoded.Bytes()
+
+	return processed, nil
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, 1 when it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			// Image data starts, no EXIF segment before it
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of EXIF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientImage turns the image upright for the EXIF orientation, which is
+// lost when the image is encoded again
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	oriented := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			oriented.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return oriented
+}
+
+// dominantColor returns the most common color of the image as #rrggbb.
+// Sampled pixels are grouped by their 4 high bits per channel and the
+// largest group is averaged. Transparent pixels are skipped.
+func dominantColor(img image.Image) string {
+	bounds := img.Bounds()
+	step := 1
+	for (bounds.Dx()/step)*(bounds.Dy()/step) > photoColorSamples {
+		step++
+	}
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[uint16]*bucket)
+	var largest *bucket
+
+	// Sampling straight from RGBA avoids a conversion per pixel
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(bounds)
+		draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := rgba.RGBAAt(x, y)
+			if c.A < 128 {
+				continue
+			}
+			r, g, b := unpremultiply(c)
+			key := uint16(r>>4)<<8 | uint16(g>>4)<<4 | uint16(b>>4)
+			bk, exists := buckets[key]
+			if !exists {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += int(r)
+			bk.g += int(g)
+			bk.b += int(b)
+			if largest == nil || bk.count > largest.count {
+				largest = bk
+			}
+		}
+	}
+
+	if largest == nil {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", largest.r/largest.count, largest.g/largest.count, largest.b/largest.count)
+}
+
+// unpremultiply undoes the alpha premultiplication of RGBA images
+func unpremultiply(c color.RGBA) (uint8, uint8, uint8) {
+	if c.A == 0xFF || c.A == 0 {
+		return c.R, c.G, c.B
+	}
+	a := uint32(c.A)
+	return uint8(uint32(c.R) * 0xFF / a), uint8(uint32(c.G) * 0xFF / a), uint8(uint32(c.B) * 0xFF / a)
+}
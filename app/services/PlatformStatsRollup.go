@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	databases "voting-app/app"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// platformStatSources lists the tables rolled up into platform_stats_rollups.
+// Rows are picked up by id watermark, so a source only needs a serial id
+// (snapp_users has no created_at). Rows deleted or deactivated after being
+// rolled up are not subtracted from the counters.
+var platformStatSources = []struct {
+	Table  string
+	Column string // Counter column in platform_stats_rollups
+	Filter string // Rows counted towards the counter
+}{
+	{Table: "venues", Column: "new_venues", Filter: "is_active = true"},
+	{Table: "snapp_users", Column: "new_users", Filter: "true"},
+	{Table: "venue_reviews", Column: "new_reviews", Filter: "true"},
+	{Table: "venue_checkins", Column: "new_checkins", Filter: "true"},
+}
+
+// openTransactionsQuery finds when the oldest transaction open on the
+// database, other than the caller's, started (NULL when there is none)
+const openTransactionsQuery = `
+	SELECT MIN(xact_start)::timestamp AS oldest
+	FROM pg_stat_activity
+	WHERE datname = current_database() AND backend_type = 'client backend'
+	  AND pid <> pg_backend_pid() AND xact_start IS NOT NULL`
+
+// platformStatsHourlyRetention is how long hourly buckets are kept, daily
+// buckets are kept forever as they back the platform totals
+const platformStatsHourlyRetention = "7 days"
+
+// RollupPlatformStats adds rows created since the previous run to the hourly
+// and daily platform counters. It is safe to run concurrently, runs are
+// serialized with an advisory lock.
+func (as *AnalyticsService) RollupPlatformStats(ctx context.Context) error {
+	tx, err := databases.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('platform_stats_rollup'))")
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	for _, source := range platformStatSources {
+		var lastID, pendingID int64
+		var pendingSettled, idle bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(w.last_id, 0), COALESCE(w.pending_id, 0),
+				COALESCE(w.pending_at < o.oldest, false), o.oldest IS NULL
+			FROM (`+openTransactionsQuery+`) o
+			LEFT JOIN platform_stats_watermarks w ON w.source_table = $1`,
+			source.Table,
+		).Scan(&lastID, &pendingID, &pendingSettled, &idle)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+
+		// Ids handed out so far, committed or not
+		var allocatedID int64
+		err = tx.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT GREATEST(COALESCE(pg_sequence_last_value(pg_get_serial_sequence('%[1]s', 'id')), 0), COALESCE(MAX(id), 0)) FROM %[1]s",
+			source.Table,
+		)).Scan(&allocatedID)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+
+		// Ids are handed out before their rows commit, so rows above the
+		// watermark may still show up below newer ones. Rows are rolled up
+		// to an id once every transaction that could hold a lower one has
+		// ended: right away when none is open, otherwise up to the ids
+		// handed out before the oldest open transaction started.
+		upToID := lastID
+		switch {
+		case idle:
+			upToID = allocatedID
+		case pendingSettled && pendingID > lastID:
+			upToID = pendingID
+		}
+		keepPending := !idle && !pendingSettled && pendingID > lastID
+		if !keepPending {
+			pendingID = allocatedID
+		}
+
+		ratingExpr := "0"
+		if source.Table == "venue_reviews" {
+			ratingExpr = fmt.Sprintf("COALESCE(SUM(overall_rating) FILTER (WHERE %s), 0)", source.Filter)
+		}
+
+		var count int64
+		var ratingSum float64
+		err = tx.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT COUNT(*) FILTER (WHERE %s), %s FROM %s WHERE id > $1 AND id <= $2",
+			source.Filter, ratingExpr, source.Table,
+		), lastID, upToID).Scan(&count, &ratingSum)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+
+		if count > 0 {
+			for _, period := range []string{"hour", "day"} {
+				_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+					INSERT INTO platform_stats_rollups (period, period_start, %[1]s, rating_sum)
+					VALUES ($1, date_trunc($2, CURRENT_TIMESTAMP), $3, $4)
+					ON CONFLICT (period, period_start) DO UPDATE SET
+						%[1]s = platform_stats_rollups.%[1]s + EXCLUDED.%[1]s,
+						rating_sum = platform_stats_rollups.rating_sum + EXCLUDED.rating_sum,
+						updated_at = CURRENT_TIMESTAMP`, source.Column),
+					period, period, count, ratingSum,
+				)
+				if err != nil {
+					sentry.CaptureException(err)
+					return err
+				}
+			}
+		}
+
+		// The watermark is touched even without new rows, its updated_at is
+		// the freshness reported to API consumers. Ids handed out above it
+		// are pending until the transactions open now have ended.
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO platform_stats_watermarks (source_table, last_id, pending_id, pending_at)
+			VALUES ($1, $2, $3, CASE WHEN $3::bigint > $2::bigint THEN clock_timestamp()::timestamp END)
+			ON CONFLICT (source_table) DO UPDATE SET
+				last_id = EXCLUDED.last_id,
+				pending_id = EXCLUDED.pending_id,
+				pending_at = CASE WHEN $4::boolean THEN platform_stats_watermarks.pending_at ELSE EXCLUDED.pending_at END,
+				updated_at = CURRENT_TIMESTAMP`,
+			source.Table, upToID, pendingID, keepPending,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM platform_stats_rollups WHERE period = 'hour' AND period_start < CURRENT_TIMESTAMP - INTERVAL '"+platformStatsHourlyRetention+"'",
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// readPlatformRollups fills the overall platform totals from the daily rollups.
+// StatsUpdatedAt is left nil when the rollup job has never run.
+func (as *AnalyticsService) readPlatformRollups(ctx context.Context, analytics *PlatformAnalytics) error {
+	query := `
+		SELECT
+			COALESCE(SUM(new_venues), 0),
+			COALESCE(SUM(new_users), 0),
+			COALESCE(SUM(new_reviews), 0),
+			COALESCE(SUM(new_checkins), 0),
+			COALESCE(SUM(rating_sum), 0),
+			(SELECT MIN(updated_at) FROM platform_stats_watermarks)
+		FROM platform_stats_rollups
+		WHERE period = 'day'`
+
+	var ratingSum float64
+	var updatedAt sql.NullTime
+
+	err := databases.PostgresDB.QueryRowContext(ctx, query).Scan(
+		&analytics.TotalVenues,
+		&analytics.TotalUsers,
+		&analytics.TotalReviews,
+		&analytics.TotalCheckins,
+		&ratingSum,
+		&updatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if analytics.TotalReviews > 0 {
+		analytics.AverageRating = ratingSum / float64(analytics.TotalReviews)
+	}
+	if updatedAt.Valid {
+		analytics.StatsUpdatedAt = &updatedAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ErrInvalidDeviceToken is returned by adapters when the provider reports the
+// token as unregistered, so the device can be deactivated
+var ErrInvalidDeviceToken = errors.New("invalid device token")
+
+// PushMessage is the provider independent payload of a push notification
+type PushMessage struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Badge *int              `json:"badge,omitempty"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// PushAdapter delivers push messages through a single provider
+type PushAdapter interface {
+	Platform() string
+	Send(token string, message PushMessage) error
+}
+
+// PushAdapters holds the configured adapters keyed by platform
+var PushAdapters = make(map[string]PushAdapter)
+
+func init() {
+	push := config.Get().Push
+
+	if push.FCMServerKey != "" {
+		PushAdapters[models.PlatformFCM] = &FCMAdapter{ServerKey: push.FCMServerKey}
+	}
+
+	if push.APNsKey != "" {
+		PushAdapters[models.PlatformAPNs] = &APNsAdapter{
+			KeyPEM:     push.APNsKey,
+			KeyID:      push.APNsKeyID,
+			TeamID:     push.APNsTeamID,
+			Topic:      push.APNsTopic,
+			Production: push.APNsProduction,
+		}
+	}
+}
+
+var pushHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// FCMAdapter sends notifications through Firebase Cloud Messaging
+type FCMAdapter struct {
+	ServerKey string
+}
+
+func (f *FCMAdapter) Platform() string {
+	return models.PlatformFCM
+}
+
+// Send delivers a message using the FCM HTTP API
+func (f *FCMAdapter) Send(token string, message PushMessage) error {
+	payload := map[string]interface{}{
+		"to": token,
+		"notification": map[string]interface{}{
+			"title": message.Title,
+			"body":  message.Body,
+		},
+		"data": message.Data,
+	}
+	if message.Badge != nil {
+		payload["notification"].(map[string]interface{})["badge"] = fmt.Sprintf("%d", *message.Badge)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://fcm.googleapis.com/fcm/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+f.ServerKey)
+
+	resp, err := pushHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fcm: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Failure int `json:"failure"`
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if result.Failure > 0 && len(result.Results) > 0 {
+		switch result.Results[0].Error {
+		case "NotRegistered", "InvalidRegistration":
+			return ErrInvalidDeviceToken
+		default:
+			return fmt.Errorf("fcm: %s", result.Results[0].Error)
+		}
+	}
+
+	return nil
+}
+
+// APNsAdapter sends notifications through the Apple Push Notification service
+// using token based (.p8) authentication
+type APNsAdapter struct {
+	KeyPEM     string
+	KeyID      string
+	TeamID     string
+	Topic      string // App bundle id
+	Production bool
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+func (a *APNsAdapter) Platform() string {
+	return models.PlatformAPNs
+}
+
+// providerToken returns a cached provider JWT, refreshing it before Apple's
+// one hour expiry
+func (a *APNsAdapter) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.bearer != "" && time.Since(a.bearerIssue) < 50*time.Minute {
+		return a.bearer, nil
+	}
+
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(a.KeyPEM))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.KeyID
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	a.bearer = signed
+	a.bearerIssue = now
+	return signed, nil
+}
+
+// Send delivers a message using the APNs HTTP/2 API
+func (a *APNsAdapter) Send(token string, message PushMessage) error {
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": message.Title,
+			"body":  message.Body,
+		},
+		"sound": "default",
+	}
+	if message.Badge != nil {
+		aps["badge"] = *message.Badge
+	}
+
+	payload := map[string]interface{}{"aps": aps}
+	for key, value := range message.Data {
+		payload[key] = value
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	host := "https://api.sandbox.push.apple.com"
+	if a.Production {
+		host = "https://api.push.apple.com"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	bearer, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := pushHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return ErrInvalidDeviceToken
+	}
+	return fmt.Errorf("apns: status %d %s", resp.StatusCode, result.Reason)
+}
@@ -1,16 +1,55 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
+	"time"
 	databases "voting-app/app"
+	"voting-app/app/config"
 	"voting-app/app/models"
+	"voting-app/app/tracing"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Recommendation signals, the parts of a score that explain a recommendation
+const (
+	SignalCategory     = "category"
+	SignalRating       = "rating"
+	SignalNearby       = "nearby"
+	SignalFrequentArea = "frequent_area"
+	SignalPrice        = "price"
+	SignalAmenities    = "amenities"
+	SignalSocial       = "social"
+	SignalFeatured     = "featured"
+	SignalWishlist     = "wishlist"
+)
+
+// WishlistWeight is what a venue of the user's "Want to Try" collection adds
+// to its score when it is nearby and fits the time and occasion
+var WishlistWeight float64
+
+func init() {
+	WishlistWeight = config.Get().WishlistWeight
+}
+
+// Feedback tuning of the signal weights
+const (
+	// FeedbackWindow is how far back feedback counts towards the weights
+	FeedbackWindow = 90 * 24 * time.Hour
+	// feedbackPrior dampens signals with little feedback, as if each had
+	// this many neutral answers already
+	feedbackPrior = 20.0
+	// Signal weights stay within these bounds
+	minSignalWeight = 0.5
+	maxSignalWeight = 1.5
 )
 
 // RecommendationEngine provides personalized venue recommendations
@@ -21,6 +60,18 @@ type RecommendationScore struct {
 	Venue   models.Venue `json:"venue"`
 	Score   float64      `json:"score"`
 	Reasons []string     `json:"reasons"` // Why this venue was recommended
+	Signals []string     `json:"signals"` // The reasons as signal keys, in the same order
+}
+
+// ScoringWeights scale the signals' share of the score, 1 when unset
+type ScoringWeights map[string]float64
+
+// Weight returns the weight of the signal
+func (w ScoringWeights) Weight(signal string) float64 {
+	if weight, exists := w[signal]; exists {
+		return weight
+	}
+	return 1
 }
 
 // UserPreferences represents user's preferences extracted from their behavior
@@ -31,8 +82,9 @@ type UserPreferences struct {
 	PreferredAmenities  []string          `json:"preferredAmenities"`
 	AverageRatingGiven  float64           `json:"averageRatingGiven"`
 	PreferredLocations  []LocationPref    `json:"preferredLocations"`
-	ActivityHours       map[string]int    `json:"activityHours"`   // Hour -> Frequency
-	SocialInfluence     []int64           `json:"socialInfluence"` // Followed users
+	ActivityHours       map[string]int    `json:"activityHours"`      // Hour -> Frequency
+	SocialInfluence     []int64           `json:"socialInfluence"`    // Followed users
+	Wishlist            map[int64]bool    `json:"wishlist,omitempty"` // Venues of the "Want to Try" collections
 }
 
 type LocationPref struct {
@@ -52,45 +104,150 @@ type RecommendationContext struct {
 	GroupSize   int      `json:"groupSize,omitempty"`
 	MaxDistance float64  `json:"maxDistance"` // in km
 	Limit       int      `json:"limit"`
+	// TravelMode scores the location by trip time instead of straight-line
+	// distance, walking or driving
+	TravelMode string `json:"travelMode,omitempty"`
+	// Variant is the variant of the recommendation_ranking experiment the
+	// user is served
+	Variant string `json:"variant,omitempty"`
 }
 
 // GetPersonalizedRecommendations generates personalized venue recommendations
-func (re *RecommendationEngine) GetPersonalizedRecommendations(ctx RecommendationContext) ([]RecommendationScore, error) {
+func (re *RecommendationEngine) GetPersonalizedRecommendations(ctx context.Context, rc RecommendationContext) (_ []RecommendationScore, err error) {
+	ctx, span := tracing.Start(ctx, "RecommendationEngine.GetPersonalizedRecommendations",
+		attribute.Int64("user.id", rc.UserID), attribute.Int("recommendations.limit", rc.Limit))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// Step 1: Extract user preferences
-	preferences, err := re.extractUserPreferences(ctx.UserID)
+	var preferences *UserPreferences
+	err = tracing.Phase(ctx, "recommendations.preferences", func(ctx context.Context) (err error) {
+		preferences, err = re.consentedPreferences(ctx, rc.UserID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Step 2: Get candidate venues
-	candidates, err := re.getCandidateVenues(ctx, preferences)
+	var candidates []models.Venue
+	err = tracing.Phase(ctx, "recommendations.candidates", func(ctx context.Context) (err error) {
+		candidates, err = re.getCandidateVenues(ctx, rc, preferences)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(attribute.Int("recommendations.candidates", len(candidates)))
+
+	// Candidates that can't be timed are scored by distance
+	if rc.TravelMode != "" && rc.UserLat != nil && rc.UserLng != nil {
+		tracing.Phase(ctx, "recommendations.travel_times", func(ctx context.Context) error {
+			travelService := &TravelTimeService{}
+			origin := LatLng{Latitude: *rc.UserLat, Longitude: *rc.UserLng}
+			return travelService.AttachTravelTimes(ctx, rc.TravelMode, origin, candidates)
+		})
+	}
+
+	// Recommendations go on with neutral weights without the feedback
+	var weights ScoringWeights
+	if rc.Variant != VariantNeutralWeights {
+		err = tracing.Phase(ctx, "recommendations.weights", func(ctx context.Context) (err error) {
+			weights, err = re.GetScoringWeights(ctx)
+			return err
+		})
+		if err != nil {
+			sentry.CaptureException(err)
+			err = nil
+		}
+	}
 
 	// Step 3: Score each venue
 	scores := make([]RecommendationScore, 0, len(candidates))
-	for _, venue := range candidates {
-		score := re.calculateRecommendationScore(venue, preferences, ctx)
-		if score.Score > 0 {
-			scores = append(scores, score)
+	tracing.Phase(ctx, "recommendations.scoring", func(ctx context.Context) error {
+		for _, venue := range candidates {
+			score := re.calculateRecommendationScore(ctx, venue, preferences, rc, weights)
+			if score.Score > 0 {
+				scores = append(scores, score)
+			}
 		}
-	}
+		return nil
+	})
 
 	// Step 4: Sort by score and return top results
 	sort.Slice(scores, func(i, j int) bool {
 		return scores[i].Score > scores[j].Score
 	})
 
-	if len(scores) > ctx.Limit {
-		scores = scores[:ctx.Limit]
+	if len(scores) > rc.Limit {
+		scores = scores[:rc.Limit]
 	}
 
 	return scores, nil
 }
 
+// RecordFeedback stores the user's feedback on a recommended venue with the
+// signals the venue is recommended for, so the venue is left out of the
+// user's recommendations and the signals' weights follow the feedback
+func (re *RecommendationEngine) RecordFeedback(ctx context.Context, userID int64, venue models.Venue, feedback string) (*models.RecommendationFeedback, error) {
+	preferences, err := re.consentedPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	score := re.calculateRecommendationScore(ctx, venue, preferences, RecommendationContext{UserID: userID}, nil)
+
+	recommendationFeedback := &models.RecommendationFeedback{
+		UserID:   userID,
+		VenueID:  venue.ID,
+		Feedback: feedback,
+		Signals:  score.Signals,
+	}
+	if err := recommendationFeedback.Save(ctx); err != nil {
+		return nil, err
+	}
+	return recommendationFeedback, nil
+}
+
+// GetScoringWeights derives the signal weights from the feedback of the last
+// FeedbackWindow. "Been there" answers show a signal finds relevant venues and
+// raise its weight, "not interested" answers lower it.
+func (re *RecommendationEngine) GetScoringWeights(ctx context.Context) (ScoringWeights, error) {
+	feedback, err := models.GetSignalFeedback(ctx, time.Now().Add(-FeedbackWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(ScoringWeights, len(feedback))
+	for _, signal := range feedback {
+		total := float64(signal.NotInterested + signal.BeenThere)
+		weight := 1 + float64(signal.BeenThere-signal.NotInterested)/(total+feedbackPrior)
+		weights[signal.Signal] = math.Max(minSignalWeight, math.Min(maxSignalWeight, weight))
+	}
+	return weights, nil
+}
+
+// consentedPreferences extracts the user's preferences when they consented
+// to personalized recommendations. Others have no preferences and get the
+// recommendations anyone would.
+func (re *RecommendationEngine) consentedPreferences(ctx context.Context, userID int64) (*UserPreferences, error) {
+	consented, err := models.HasConsent(ctx, userID, models.ConsentPersonalizedRecommendations)
+	if err != nil {
+		return nil, err
+	}
+	if !consented {
+		return &UserPreferences{
+			UserID:              userID,
+			PreferredCategories: make(map[int64]float64),
+			ActivityHours:       make(map[string]int),
+		}, nil
+	}
+	return re.extractUserPreferences(ctx, userID)
+}
+
 // extractUserPreferences analyzes user's past behavior to extract preferences
-func (re *RecommendationEngine) extractUserPreferences(userID int64) (*UserPreferences, error) {
+func (re *RecommendationEngine) extractUserPreferences(ctx context.Context, userID int64) (*UserPreferences, error) {
 	prefs := &UserPreferences{
 		UserID:              userID,
 		PreferredCategories: make(map[int64]float64),
@@ -98,19 +255,30 @@ func (re *RecommendationEngine) extractUserPreferences(userID int64) (*UserPrefe
 	}
 
 	// Analyze reviews to extract category preferences
-	err := re.analyzeReviewPreferences(userID, prefs)
+	err := re.analyzeReviewPreferences(ctx, userID, prefs)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+
+	// Favorites are the strongest category signal
+	err = re.analyzeFavoritePreferences(ctx, userID, prefs)
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Analyze check-ins for location and time preferences
-	err = re.analyzeCheckinPreferences(userID, prefs)
+	err = re.analyzeCheckinPreferences(ctx, userID, prefs)
 	if err != nil {
 		sentry.CaptureException(err)
 	}
 
 	// Analyze social connections
-	err = re.analyzeSocialPreferences(userID, prefs)
+	err = re.analyzeSocialPreferences(ctx, userID, prefs)
+	if err != nil {
+		sentry.CaptureException(err)
+	}
+
+	err = re.analyzeWishlist(ctx, userID, prefs)
 	if err != nil {
 		sentry.CaptureException(err)
 	}
@@ -118,18 +286,31 @@ func (re *RecommendationEngine) extractUserPreferences(userID int64) (*UserPrefe
 	return prefs, nil
 }
 
+// analyzeWishlist reads the venues the user wants to try
+func (re *RecommendationEngine) analyzeWishlist(ctx context.Context, userID int64, prefs *UserPreferences) error {
+	venueIDs, err := models.GetWishlistVenueIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	prefs.Wishlist = make(map[int64]bool, len(venueIDs))
+	for _, venueID := range venueIDs {
+		prefs.Wishlist[venueID] = true
+	}
+	return nil
+}
+
 // analyzeReviewPreferences extracts preferences from user's reviews
-func (re *RecommendationEngine) analyzeReviewPreferences(userID int64, prefs *UserPreferences) error {
+func (re *RecommendationEngine) analyzeReviewPreferences(ctx context.Context, userID int64, prefs *UserPreferences) error {
 	query := `
 		SELECT v.category_id, v.price_range, v.amenities, r.overall_rating, r.visit_type,
-			   COUNT(*) as frequency
+			   r.is_verified, COUNT(*) as frequency
 		FROM venue_reviews r
 		JOIN venues v ON r.venue_id = v.id
 		WHERE r.user_id = $1 AND r.moderation_status = 'approved'
-		GROUP BY v.category_id, v.price_range, v.amenities, r.overall_rating, r.visit_type
+		GROUP BY v.category_id, v.price_range, v.amenities, r.overall_rating, r.visit_type, r.is_verified
 		ORDER BY frequency DESC`
 
-	rows, err := databases.PostgresDB.Query(query, userID)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -145,15 +326,19 @@ func (re *RecommendationEngine) analyzeReviewPreferences(userID int64, prefs *Us
 		var priceRange, visitType string
 		var amenitiesJSON []byte
 		var rating float64
+		var isVerified bool
 		var frequency int
 
-		err := rows.Scan(&categoryID, &priceRange, &amenitiesJSON, &rating, &visitType, &frequency)
+		err := rows.Scan(&categoryID, &priceRange, &amenitiesJSON, &rating, &visitType, &isVerified, &frequency)
 		if err != nil {
 			continue
 		}
 
-		// Weight preferences by rating and frequency
+		// Weight preferences by rating and frequency, verified visits count more
 		weight := rating * float64(frequency) / 5.0 // Normalize by max rating
+		if isVerified {
+			weight *= models.VerifiedReviewWeight
+		}
 		prefs.PreferredCategories[categoryID] += weight
 
 		// Accumulate price range preferences
@@ -197,8 +382,39 @@ func (re *RecommendationEngine) analyzeReviewPreferences(userID int64, prefs *Us
 	return nil
 }
 
+// favoriteCategoryWeight is what a favorited venue adds to its category's
+// preference, more than a 5 star review
+const favoriteCategoryWeight = 1.5
+
+// analyzeFavoritePreferences boosts the categories of the user's favorite
+// venues
+func (re *RecommendationEngine) analyzeFavoritePreferences(ctx context.Context, userID int64, prefs *UserPreferences) error {
+	query := `
+		SELECT v.category_id, COUNT(*)
+		FROM venue_favorites f
+		JOIN venues v ON f.venue_id = v.id
+		WHERE f.user_id = $1 AND v.is_active = true AND v.category_id IS NOT NULL
+		GROUP BY v.category_id`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var categoryID int64
+		var favorites int
+		if rows.Scan(&categoryID, &favorites) == nil {
+			prefs.PreferredCategories[categoryID] += favoriteCategoryWeight * float64(favorites)
+		}
+	}
+
+	return rows.Err()
+}
+
 // analyzeCheckinPreferences extracts location and timing preferences from check-ins
-func (re *RecommendationEngine) analyzeCheckinPreferences(userID int64, prefs *UserPreferences) error {
+func (re *RecommendationEngine) analyzeCheckinPreferences(ctx context.Context, userID int64, prefs *UserPreferences) error {
 	query := `
 		SELECT v.latitude, v.longitude, EXTRACT(hour FROM c.created_at) as hour,
 			   COUNT(*) as frequency
@@ -208,7 +424,7 @@ func (re *RecommendationEngine) analyzeCheckinPreferences(userID int64, prefs *U
 		GROUP BY v.latitude, v.longitude, hour
 		ORDER BY frequency DESC`
 
-	rows, err := databases.PostgresDB.Query(query, userID)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -255,11 +471,13 @@ func (re *RecommendationEngine) analyzeCheckinPreferences(userID int64, prefs *U
 }
 
 // analyzeSocialPreferences extracts preferences from social connections
-func (re *RecommendationEngine) analyzeSocialPreferences(userID int64, prefs *UserPreferences) error {
+func (re *RecommendationEngine) analyzeSocialPreferences(ctx context.Context, userID int64, prefs *UserPreferences) error {
+	// Muted users stay followed but do not influence recommendations
 	query := `
-		SELECT following_id FROM user_follows WHERE follower_id = $1`
+		SELECT following_id FROM user_follows
+		WHERE follower_id = $1 AND ` + models.HiddenUsersCondition("following_id", "$1")
 
-	rows, err := databases.PostgresDB.Query(query, userID)
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -276,13 +494,13 @@ func (re *RecommendationEngine) analyzeSocialPreferences(userID int64, prefs *Us
 }
 
 // getCandidateVenues retrieves potential venues for recommendation
-func (re *RecommendationEngine) getCandidateVenues(ctx RecommendationContext, prefs *UserPreferences) ([]models.Venue, error) {
+func (re *RecommendationEngine) getCandidateVenues(ctx context.Context, rc RecommendationContext, prefs *UserPreferences) ([]models.Venue, error) {
 	// Build query based on context and preferences
 	baseQuery := `
-		SELECT DISTINCT v.id, v.name, v.slug, v.description, v.short_description,
+		SELECT DISTINCT v.id, v.name, v.slug, COALESCE(v.description, ''), COALESCE(v.short_description, ''),
 			   v.address, v.latitude, v.longitude, v.category_id, v.subcategory_id,
-			   v.phone, v.website, v.price_range, v.average_rating, v.total_ratings,
-			   v.cover_image, v.amenities, v.is_featured
+			   COALESCE(v.phone, ''), COALESCE(v.website, ''), COALESCE(v.price_range, ''), v.average_rating, v.total_ratings,
+			   COALESCE(v.cover_image, ''), v.amenities, v.is_featured, v.opening_hours
 		FROM venues v
 		WHERE v.is_active = true AND v.average_rating >= 3.0`
 
@@ -291,31 +509,43 @@ func (re *RecommendationEngine) getCandidateVenues(ctx RecommendationContext, pr
 	argCount := 0
 
 	// Location filter
-	if ctx.UserLat != nil && ctx.UserLng != nil {
+	if rc.UserLat != nil && rc.UserLng != nil {
 		argCount += 3
 		conditions = append(conditions, `ST_DWithin(
 			ST_Point(v.longitude, v.latitude)::geography,
 			ST_Point($`+fmt.Sprintf("%d", argCount-2)+`, $`+fmt.Sprintf("%d", argCount-1)+`)::geography,
 			$`+fmt.Sprintf("%d", argCount)+` * 1000)`)
-		args = append(args, *ctx.UserLng, *ctx.UserLat, ctx.MaxDistance)
+		args = append(args, *rc.UserLng, *rc.UserLat, rc.MaxDistance)
 	}
 
-	// Exclude venues user has already reviewed
+	// Venues of the request's tenant
+	argCount++
+	conditions = append(conditions, `($`+fmt.Sprintf("%d", argCount)+`::bigint IS NULL OR v.tenant_id = $`+fmt.Sprintf("%d", argCount)+`)`)
+	args = append(args, models.TenantFilter(ctx))
+
+	// Exclude venues user has already reviewed or given feedback on
 	argCount++
 	conditions = append(conditions, `v.id NOT IN (
 		SELECT venue_id FROM venue_reviews WHERE user_id = $`+fmt.Sprintf("%d", argCount)+`)`)
-	args = append(args, ctx.UserID)
+	conditions = append(conditions, `v.id NOT IN (
+		SELECT venue_id FROM recommendation_feedback WHERE user_id = $`+fmt.Sprintf("%d", argCount)+`)`)
+	args = append(args, rc.UserID)
 
-	// Add preferred categories if available
+	// Add preferred categories if available, the venues the user wants to
+	// try are candidates whatever their category
 	if len(prefs.PreferredCategories) > 0 {
 		var categoryIDs []int64
 		for categoryID := range prefs.PreferredCategories {
 			categoryIDs = append(categoryIDs, categoryID)
 		}
+		var wishlistIDs []int64
+		for venueID := range prefs.Wishlist {
+			wishlistIDs = append(wishlistIDs, venueID)
+		}
 		if len(categoryIDs) > 0 {
-			argCount++
-			conditions = append(conditions, `(v.category_id = ANY($`+fmt.Sprintf("%d", argCount)+`) OR v.is_featured = true)`)
-			args = append(args, categoryIDs)
+			argCount += 2
+			conditions = append(conditions, `(v.category_id = ANY($`+fmt.Sprintf("%d", argCount-1)+`) OR v.is_featured = true OR v.id = ANY($`+fmt.Sprintf("%d", argCount)+`))`)
+			args = append(args, pq.Array(categoryIDs), pq.Array(wishlistIDs))
 		}
 	}
 
@@ -326,7 +556,7 @@ func (re *RecommendationEngine) getCandidateVenues(ctx RecommendationContext, pr
 	}
 	finalQuery += " LIMIT 200" // Limit candidates for performance
 
-	rows, err := databases.PostgresDB.Query(finalQuery, args...)
+	rows, err := databases.PostgresDB.QueryContext(ctx, finalQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -341,7 +571,7 @@ func (re *RecommendationEngine) getCandidateVenues(ctx RecommendationContext, pr
 			&venue.ID, &venue.Name, &venue.Slug, &venue.Description, &venue.ShortDesc,
 			&venue.Address, &venue.Latitude, &venue.Longitude, &venue.CategoryID, &subcategoryID,
 			&venue.Phone, &venue.Website, &venue.PriceRange, &venue.AverageRating, &venue.TotalRatings,
-			&venue.CoverImage, &venue.Amenities, &venue.IsFeatured,
+			&venue.CoverImage, &venue.Amenities, &venue.IsFeatured, &venue.OpeningHours,
 		)
 
 		if err != nil {
@@ -358,46 +588,61 @@ func (re *RecommendationEngine) getCandidateVenues(ctx RecommendationContext, pr
 	return venues, nil
 }
 
-// calculateRecommendationScore calculates recommendation score for a venue
-func (re *RecommendationEngine) calculateRecommendationScore(venue models.Venue, prefs *UserPreferences, ctx RecommendationContext) RecommendationScore {
+// calculateRecommendationScore calculates recommendation score for a venue.
+// Each signal's part of the score is scaled by its weight.
+func (re *RecommendationEngine) calculateRecommendationScore(ctx context.Context, venue models.Venue, prefs *UserPreferences, rc RecommendationContext, weights ScoringWeights) RecommendationScore {
 	score := RecommendationScore{
 		Venue:   venue,
 		Reasons: make([]string, 0),
+		Signals: make([]string, 0),
 	}
 
 	var totalScore float64 = 0
+	addSignal := func(signal, reason string, signalScore float64) {
+		totalScore += signalScore * weights.Weight(signal)
+		score.Reasons = append(score.Reasons, reason)
+		score.Signals = append(score.Signals, signal)
+	}
 
 	// 1. Category preference score (30% weight)
 	if categoryWeight, exists := prefs.PreferredCategories[venue.CategoryID]; exists {
-		categoryScore := categoryWeight * 0.3
-		totalScore += categoryScore
-		score.Reasons = append(score.Reasons, "Matches your preferred category")
+		addSignal(SignalCategory, "Matches your preferred category", categoryWeight*0.3)
 	}
 
 	// 2. Rating quality score (25% weight)
 	ratingScore := (venue.AverageRating / 5.0) * 0.25
 	if venue.AverageRating >= 4.0 {
-		ratingScore *= 1.2 // Boost highly rated venues
-		score.Reasons = append(score.Reasons, "Highly rated venue")
+		addSignal(SignalRating, "Highly rated venue", ratingScore*1.2) // Boost highly rated venues
+	} else {
+		totalScore += ratingScore
 	}
-	totalScore += ratingScore
 
 	// 3. Location preference score (20% weight)
-	if ctx.UserLat != nil && ctx.UserLng != nil {
-		distance := calculateDistance(*ctx.UserLat, *ctx.UserLng, venue.Latitude, venue.Longitude)
-		locationScore := math.Max(0, (ctx.MaxDistance-distance)/ctx.MaxDistance) * 0.2
-		totalScore += locationScore
+	if rc.UserLat != nil && rc.UserLng != nil {
+		distance := calculateDistance(*rc.UserLat, *rc.UserLng, venue.Latitude, venue.Longitude)
+		locationScore := math.Max(0, (rc.MaxDistance-distance)/rc.MaxDistance) * 0.2
+		isClose := distance <= 2.0
+		if venue.TravelMinutes != nil {
+			// Trip times are compared with how long the distances take
+			maxMinutes := TravelMinutesFor(rc.TravelMode, rc.MaxDistance)
+			locationScore = math.Max(0, (maxMinutes-*venue.TravelMinutes)/maxMinutes) * 0.2
+			isClose = *venue.TravelMinutes <= TravelMinutesFor(rc.TravelMode, 2.0)
+		}
+		if isClose {
+			addSignal(SignalNearby, "Close to your location", locationScore)
+		} else {
+			totalScore += locationScore
+		}
 
-		if distance <= 2.0 {
-			score.Reasons = append(score.Reasons, "Close to your location")
+		if isClose && WishlistWeight > 0 && prefs.Wishlist[venue.ID] && fitsContext(venue, rc) {
+			addSignal(SignalWishlist, "On your wishlist and nearby", WishlistWeight)
 		}
 
 		// Check preferred locations
 		for _, locPref := range prefs.PreferredLocations {
 			prefDistance := calculateDistance(locPref.Latitude, locPref.Longitude, venue.Latitude, venue.Longitude)
 			if prefDistance <= locPref.Radius {
-				totalScore += (locPref.Weight / 10.0) * 0.1
-				score.Reasons = append(score.Reasons, "In an area you frequent")
+				addSignal(SignalFrequentArea, "In an area you frequent", (locPref.Weight/10.0)*0.1)
 				break
 			}
 		}
@@ -407,8 +652,7 @@ func (re *RecommendationEngine) calculateRecommendationScore(venue models.Venue,
 	if venue.PriceRange != "" {
 		for _, prefPrice := range prefs.PreferredPriceRange {
 			if venue.PriceRange == prefPrice {
-				totalScore += 0.1
-				score.Reasons = append(score.Reasons, "Matches your price preference")
+				addSignal(SignalPrice, "Matches your price preference", 0.1)
 				break
 			}
 		}
@@ -429,71 +673,142 @@ func (re *RecommendationEngine) calculateRecommendationScore(venue models.Venue,
 			}
 			if amenityMatches > 0 {
 				amenityScore := (float64(amenityMatches) / float64(len(prefs.PreferredAmenities))) * 0.1
-				totalScore += amenityScore
-				score.Reasons = append(score.Reasons, "Has amenities you prefer")
+				addSignal(SignalAmenities, "Has amenities you prefer", amenityScore)
 			}
 		}
 	}
 
 	// 6. Social influence score (5% weight)
-	socialScore := re.calculateSocialScore(venue.ID, prefs.SocialInfluence)
+	socialScore := re.calculateSocialScore(ctx, venue.ID, prefs.SocialInfluence)
 	if socialScore > 0 {
-		totalScore += socialScore * 0.05
-		score.Reasons = append(score.Reasons, "Popular with people you follow")
+		addSignal(SignalSocial, "Popular with people you follow", socialScore*0.05)
 	}
 
 	// 7. Freshness and trending bonus
 	if venue.IsFeatured {
-		totalScore += 0.05
-		score.Reasons = append(score.Reasons, "Featured venue")
+		addSignal(SignalFeatured, "Featured venue", 0.05)
 	}
 
 	// 8. Context-based scoring
-	totalScore += re.calculateContextScore(venue, ctx)
+	totalScore += re.calculateContextScore(venue, rc)
 
 	score.Score = math.Max(0, math.Min(1, totalScore)) // Normalize to 0-1
 	return score
 }
 
 // calculateSocialScore calculates social influence score
-func (re *RecommendationEngine) calculateSocialScore(venueID int64, followedUsers []int64) float64 {
+func (re *RecommendationEngine) calculateSocialScore(ctx context.Context, venueID int64, followedUsers []int64) float64 {
 	if len(followedUsers) == 0 {
 		return 0
 	}
 
-	// Count positive reviews from followed users
+	// Count positive reviews from followed users, verified visits count more
 	query := `
-		SELECT COUNT(*) FROM venue_reviews 
+		SELECT COALESCE(SUM(CASE WHEN is_verified THEN $3::numeric ELSE 1.0 END), 0) FROM venue_reviews 
 		WHERE venue_id = $1 AND user_id = ANY($2) AND overall_rating >= 4.0`
 
-	var positiveReviewCount int
-	err := databases.PostgresDB.QueryRow(query, venueID, followedUsers).Scan(&positiveReviewCount)
+	var positiveReviews float64
+	err := databases.PostgresDB.QueryRowContext(ctx, query, venueID, pq.Array(followedUsers), models.VerifiedReviewWeight).Scan(&positiveReviews)
 	if err != nil {
 		return 0
 	}
 
-	return float64(positiveReviewCount) / float64(len(followedUsers))
+	return math.Min(positiveReviews/float64(len(followedUsers)), 1)
 }
 
 // calculateContextScore applies context-based scoring
-func (re *RecommendationEngine) calculateContextScore(venue models.Venue, ctx RecommendationContext) float64 {
+func (re *RecommendationEngine) calculateContextScore(venue models.Venue, rc RecommendationContext) float64 {
 	var contextScore float64 = 0
 
 	// Time-based scoring (simplified)
-	if ctx.TimeOfDay == "evening" && venue.CategoryID == 2 { // Assuming 2 is bars/nightlife
+	if rc.TimeOfDay == "evening" && venue.CategoryID == 2 { // Assuming 2 is bars/nightlife
 		contextScore += 0.1
-	} else if ctx.TimeOfDay == "morning" && venue.CategoryID == 3 { // Assuming 3 is cafes
+	} else if rc.TimeOfDay == "morning" && venue.CategoryID == 3 { // Assuming 3 is cafes
 		contextScore += 0.1
 	}
 
 	// Group size considerations
-	if ctx.GroupSize > 4 && venue.PriceRange != "$$$$" {
+	if rc.GroupSize > 4 && venue.PriceRange != "$$$$" {
 		contextScore += 0.05 // Favor more affordable options for larger groups
 	}
 
 	return contextScore
 }
 
+// timeOfDayHours are the hours of the parts of the day, as formatHour names
+// them
+var timeOfDayHours = map[string][2]int{
+	"night":     {0, 6},
+	"morning":   {6, 12},
+	"afternoon": {12, 17},
+	"evening":   {17, 24},
+}
+
+// occasionAmenities are the amenities of which venues fit an occasion need
+// one, when they list their amenities
+var occasionAmenities = map[string][]string{
+	"date":        {"reservations"},
+	"business":    {"reservations", "wifi"},
+	"celebration": {"reservations"},
+}
+
+// fitsContext reports whether the venue is open during the requested part of
+// the day on some day of the week and suits the occasion. Venues without
+// hours or amenities aren't ruled out by them.
+func fitsContext(venue models.Venue, rc RecommendationContext) bool {
+	if window, exists := timeOfDayHours[rc.TimeOfDay]; exists && len(venue.OpeningHours) > 0 {
+		hours, err := models.ParseOpeningHours(venue.OpeningHours)
+		if err == nil && len(hours) > 0 && !openDuring(hours, window[0]*60, window[1]*60) {
+			return false
+		}
+	}
+
+	if amenities, exists := occasionAmenities[rc.Occasion]; exists && len(venue.Amenities) > 0 {
+		var venueAmenities []string
+		if json.Unmarshal(venue.Amenities, &venueAmenities) == nil && len(venueAmenities) > 0 {
+			for _, amenity := range amenities {
+				for _, venueAmenity := range venueAmenities {
+					if amenity == venueAmenity {
+						return true
+					}
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// openDuring reports whether any day's hours overlap the minutes of the day
+// from start to end. Hours running past midnight overlap the next morning.
+func openDuring(hours models.OpeningHours, start, end int) bool {
+	const day = 24 * 60
+	for _, dayHours := range hours {
+		open, okOpen := clockMinutes(dayHours.Open)
+		close, okClose := clockMinutes(dayHours.Close)
+		if !okOpen || !okClose {
+			continue
+		}
+		if close <= open {
+			close += day
+		}
+		if (open < end && start < close) || (open < end+day && start+day < close) {
+			return true
+		}
+	}
+	return false
+}
+
+// clockMinutes returns the minutes since midnight of a valid "HH:MM" clock
+func clockMinutes(clock string) (int, bool) {
+	if !models.ValidClock(clock) {
+		return 0, false
+	}
+	var hour, minute int
+	fmt.Sscanf(clock, "%d:%d", &hour, &minute)
+	return hour*60 + minute, true
+}
+
 // Helper functions
 
 func calculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
@@ -527,68 +842,3 @@ func formatHour(hour int) string {
 		return "evening"
 	}
 }
-
-// GetSimilarVenues finds venues similar to a given venue
-func (re *RecommendationEngine) GetSimilarVenues(venueID int64, limit int) ([]models.Venue, error) {
-	// Get the reference venue
-	venue := &models.Venue{ID: venueID}
-	err := venue.GetByID()
-	if err != nil {
-		return nil, err
-	}
-
-	// Find similar venues based on category, location, price range, and amenities
-	query := `
-		SELECT v.id, v.name, v.slug, v.short_description, v.address,
-			   v.latitude, v.longitude, v.category_id, v.price_range,
-			   v.average_rating, v.total_ratings, v.cover_image,
-			   ST_Distance(
-				   ST_Point(v.longitude, v.latitude)::geography,
-				   ST_Point($2, $3)::geography
-			   ) / 1000 as distance
-		FROM venues v
-		WHERE v.is_active = true 
-		  AND v.id != $1
-		  AND (v.category_id = $4 OR v.subcategory_id = $5)
-		  AND ST_DWithin(
-			  ST_Point(v.longitude, v.latitude)::geography,
-			  ST_Point($2, $3)::geography,
-			  50000
-		  )
-		ORDER BY 
-		  CASE WHEN v.category_id = $4 THEN 1 ELSE 2 END,
-		  CASE WHEN v.price_range = $6 THEN 1 ELSE 2 END,
-		  v.average_rating DESC,
-		  distance ASC
-		LIMIT $7`
-
-	rows, err := databases.PostgresDB.Query(
-		query, venue.ID, venue.Longitude, venue.Latitude, venue.CategoryID,
-		venue.SubcategoryID, venue.PriceRange, limit,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var venues []models.Venue
-	for rows.Next() {
-		var v models.Venue
-		var distance float64
-
-		err := rows.Scan(
-			&v.ID, &v.Name, &v.Slug, &v.ShortDesc, &v.Address,
-			&v.Latitude, &v.Longitude, &v.CategoryID, &v.PriceRange,
-			&v.AverageRating, &v.TotalRatings, &v.CoverImage, &distance,
-		)
-
-		if err != nil {
-			continue
-		}
-
-		v.Distance = &distance
-		venues = append(venues, v)
-	}
-
-	return venues, nil
-}
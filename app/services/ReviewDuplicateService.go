@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"time"
+	"unicode"
+	"voting-app/app/models"
+)
+
+// Near-duplicate detection of copy-pasted reviews
+const (
+	reviewShingleWords = 3  // Words per shingle
+	reviewMinHashes    = 64 // Hashes per signature, the error of the similarity estimate is about 1/sqrt(64)
+	// MinDuplicateWords is the length below which reviews aren't compared,
+	// short reviews like "Great food, friendly staff" are alike by nature
+	MinDuplicateWords = 12
+	// DuplicateSimilarity is the estimated share of shingles in common from
+	// which a review is a duplicate
+	DuplicateSimilarity = 0.8
+	// DuplicateWindow is how far back the reviews of other users are
+	// compared, the user's own reviews are compared regardless of age
+	DuplicateWindow        = 30 * 24 * time.Hour
+	maxDuplicateCandidates = 500
+)
+
+// reviewMinHashSeeds salt the hash of every shingle once per signature slot
+var reviewMinHashSeeds = func() []uint64 {
+	seeds := make([]uint64, reviewMinHashes)
+	for i := range seeds {
+		seeds[i] = mixHash(uint64(i) + 1)
+	}
+	return seeds
+}()
+
+// DuplicateMatch is the original a review was found to copy
+type DuplicateMatch struct {
+	OriginalID int64   `json:"originalId"`
+	Similarity float64 `json:"similarity"`
+}
+
+// ReviewDuplicateService flags reviews nearly identical to the same user's
+// other reviews or to other recent reviews, the signature of spammers
+// pasting a review across venues
+type ReviewDuplicateService struct{}
+
+// Check signs the review's text and merges it into the group of the review
+// it copies, if any, flagging it for moderation. Reviews too short to compare
+// return no match.
+func (rd *ReviewDuplicateService) Check(ctx context.Context, review *models.VenueReview) (*DuplicateMatch, error) {
+	signature := ReviewMinHash(review.Title + "\n" + review.ReviewText)
+	if signature == nil {
+		return nil, nil
+	}
+
+	reviewSignature := models.ReviewSignature{ReviewID: review.ID, UserID: review.UserID, Signature: signature}
+	if err := reviewSignature.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-DuplicateWindow)
+	candidates, err := models.GetDuplicateCandidates(ctx, review.ID, review.UserID, signature, since, maxDuplicateCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates come oldest first, the first of equally similar ones is the
+	// original
+	var match *DuplicateMatch
+	for _, candidate := range candidates {
+		similarity := SignatureSimilarity(signature, candidate.Signature)
+		if similarity < DuplicateSimilarity || (match != nil && similarity <= match.Similarity) {
+			continue
+		}
+		// Duplicates join the group of the review they copy
+		originalID := candidate.ReviewID
+		if candidate.DuplicateOf != 0 {
+			originalID = candidate.DuplicateOf
+		}
+		match = &DuplicateMatch{OriginalID: originalID, Similarity: similarity}
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	if err := models.MarkReviewDuplicate(ctx, review.ID, match.OriginalID, match.Similarity); err != nil {
+		return nil, err
+	}
+	review.IsFlagged = true
+	review.DuplicateOf = &match.OriginalID
+	return match, nil
+}
+
+// ReviewMinHash returns the MinHash signature of the word shingles of the
+// text, nil when it has fewer than MinDuplicateWords words. Case,
+// punctuation and spacing don't change the signature.
+func ReviewMinHash(text string) []int64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < MinDuplicateWords {
+		return nil
+	}
+
+	mins := make([]uint64, reviewMinHashes)
+	for i := range mins {
+		mins[i] = ^uint64(0)
+	}
+	for start := 0; start+reviewShingleWords <= len(words); start++ {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(words[start:start+reviewShingleWords], " ")))
+		shingle := hash.Sum64()
+		for i, seed := range reviewMinHashSeeds {
+			if value := mixHash(shingle ^ seed); value < mins[i] {
+				mins[i] = value
+			}
+		}
+	}
+
+	signature := make([]int64, reviewMinHashes)
+	for i, value := range mins {
+		signature[i] = int64(value)
+	}
+	return signature
+}
+
+// SignatureSimilarity estimates the Jaccard similarity of the shingles
+// behind two signatures, the share of slots holding the same hash
+func SignatureSimilarity(a, b []int64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// mixHash is the finalizer of MurmurHash3, spreading the bits of x
+func mixHash(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
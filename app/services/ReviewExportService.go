@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ReviewExportSyncLimit is the most approved reviews a venue can have for
+// its export to be streamed right away. Larger venues get it generated in
+// the background.
+var ReviewExportSyncLimit = 1000
+
+// ReviewExportBucket is the private bucket review exports are stored in.
+// Exports are downloaded by their owner or through a signed URL.
+const ReviewExportBucket = "review-exports"
+
+// ReviewExportURLExpiry is how long signed URLs to exports are valid
+var ReviewExportURLExpiry = 15 * time.Minute
+
+// Review export formats
+const (
+	ReviewExportCSV  = "csv"
+	ReviewExportJSON = "json"
+)
+
+const (
+	reviewExportLease = 15 * time.Minute
+	reviewExportBatch = 10
+)
+
+// PutReviewExportObject stores a generated export, typed by the format in
+// its key's extension. It writes to the file storage and is replaced in
+// tests.
+var PutReviewExportObject = func(ctx context.Context, key string, data []byte) error {
+	contentType := ReviewExportContentType(strings.TrimPrefix(path.Ext(key), "."))
+	return FileStorage.Put(ctx, ReviewExportBucket, key, contentType, data)
+}
+
+// GetReviewExportObject opens a stored export. It reads from the file
+// storage and is replaced in tests.
+var GetReviewExportObject = func(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, _, err := FileStorage.Get(ctx, ReviewExportBucket, key)
+	return file, err
+}
+
+// ReviewExportService exports the approved reviews of a venue for its owner
+type ReviewExportService struct{}
+
+// ExportsInBackground reports whether the venue has too many approved
+// reviews to stream its export right away
+func (rs *ReviewExportService) ExportsInBackground(ctx context.Context, venueID int64) (bool, error) {
+	count, err := models.CountApprovedVenueReviews(ctx, venueID)
+	if err != nil {
+		return false, err
+	}
+	return count > ReviewExportSyncLimit, nil
+}
+
+// ReviewExportContentType returns the content type of the export format
+func ReviewExportContentType(format string) string {
+	if format == ReviewExportJSON {
+		return "application/json; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write writes the approved reviews of the venue in the export format and
+// returns how many were written
+func (rs *ReviewExportService) Write(ctx context.Context, venueID int64, format string, w io.Writer) (int, error) {
+	if format == ReviewExportJSON {
+		return rs.WriteJSON(ctx, venueID, w)
+	}
+	return rs.WriteCSV(ctx, venueID, w)
+}
+
+// WriteJSON writes the approved reviews of the venue as a JSON array, oldest
+// first, streaming them as they are read. Dates are RFC 3339.
+func (rs *ReviewExportService) WriteJSON(ctx context.Context, venueID int64, w io.Writer) (int, error) {
+	array := NewJSONArrayWriter(w, "")
+	err := models.EachExportedReview(ctx, venueID, func(review *models.ExportedReview) error {
+		return array.Write(review)
+	})
+	if err != nil {
+		return array.Count(), err
+	}
+	return array.Count(), array.Close()
+}
+
+// WriteCSV writes the approved reviews of the venue as CSV, oldest first,
+// and returns how many were written. Every rating dimension used by the
+// venue's reviews gets a column. Dates are RFC 3339 in UTC.
+func (rs *ReviewExportService) WriteCSV(ctx context.Context, venueID int64, w io.Writer) (int, error) {
+	averages, err := models.GetDetailedRatingAverages(ctx, []int64{venueID})
+	if err != nil {
+		return 0, err
+	}
+	dimensions := make([]string, 0, len(averages[venueID]))
+	for dimension := range averages[venueID] {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+
+	writer := csv.NewWriter(w)
+	header := []string{"date", "rating"}
+	header = append(header, dimensions...)
+	header = append(header, "title", "text", "helpful_votes")
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = models.EachExportedReview(ctx, venueID, func(review *models.ExportedReview) error {
+		record := []string{
+			review.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(review.OverallRating, 'f', -1, 64),
+		}
+		for _, dimension := range dimensions {
+			record = append(record, formatDimensionRating(review.DetailedRatings[dimension]))
+		}
+		record = append(record, spreadsheetSafe(review.Title), spreadsheetSafe(review.ReviewText),
+			strconv.Itoa(review.HelpfulVotes))
+		count++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// Queue queues a background export of the venue's reviews, or returns the
+// one already waiting
+func (rs *ReviewExportService) Queue(ctx context.Context, venueID, userID int64, format string) (*models.ReviewExport, error) {
+	export := &models.ReviewExport{VenueID: venueID, RequestedBy: userID, Format: format}
+	if err := export.Create(ctx); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Open opens the file of a completed export
+func (rs *ReviewExportService) Open(ctx context.Context, export *models.ReviewExport) (io.ReadCloser, error) {
+	return GetReviewExportObject(ctx, export.ObjectKey)
+}
+
+// SignedURL returns a URL the file of a completed export can be downloaded
+// from without authentication until it expires
+func (rs *ReviewExportService) SignedURL(ctx context.Context, export *models.ReviewExport) (string, error) {
+	return FileStorage.SignedURL(ctx, ReviewExportBucket, export.ObjectKey, ReviewExportURLExpiry)
+}
+
+// ProcessPending generates the queued exports. It is run periodically by
+// the job runner.
+func (rs *ReviewExportService) ProcessPending(ctx context.Context) error {
+	for {
+		exports, err := models.ClaimReviewExports(ctx, reviewExportLease, reviewExportBatch)
+		if err != nil {
+			return err
+		}
+
+		for i := range exports {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			rs.generate(ctx, &exports[i])
+		}
+
+		if len(exports) < reviewExportBatch {
+			return nil
+		}
+	}
+}
+
+// generate writes and stores the export's file, recording the failure when
+// it can't
+func (rs *ReviewExportService) generate(ctx context.Context, export *models.ReviewExport) {
+	var buf bytes.Buffer
+	count, err := rs.Write(ctx, export.VenueID, export.Format, &buf)
+	if err == nil {
+		key := fmt.Sprintf("venues/%d/reviews-%d.%s", export.VenueID, export.ID, export.Format)
+		if err = PutReviewExportObject(ctx, key, buf.Bytes()); err == nil {
+			export.Complete(ctx, key, count)
+			return
+		}
+	}
+
+	sentry.CaptureException(err)
+	export.Fail(ctx, "Failed to generate the export")
+}
+
+// formatDimensionRating formats a detailed rating, which reviews store as
+// a JSON number or string
+func formatDimensionRating(value interface{}) string {
+	switch value := value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return spreadsheetSafe(value)
+	}
+	return ""
+}
+
+// spreadsheetSafe keeps spreadsheets from running reviewer text as a
+// formula by prefixing it with a quote
+func spreadsheetSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
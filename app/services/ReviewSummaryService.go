@@ -0,0 +1,379 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// MinSummaryReviews is how many approved reviews a venue needs to be
+	// summarized
+	MinSummaryReviews = 3
+
+	// maxSummarizedReviews caps the reviews a summary is written from, the
+	// most helpful and recent ones
+	maxSummarizedReviews = 100
+	reviewSummaryBatch   = 20
+)
+
+// ReviewSummaryInput is what a venue's review summary is written from
+type ReviewSummaryInput struct {
+	VenueName     string
+	AverageRating float64
+	ReviewCount   int
+	Reviews       []models.VenueReview // Most helpful first
+}
+
+// ReviewSummaryBackend summarizes the reviews of a venue
+type ReviewSummaryBackend interface {
+	Name() string
+	// Summarize returns a summary of two or three sentences
+	Summarize(ctx context.Context, input ReviewSummaryInput) (string, error)
+}
+
+// ReviewSummarizer is the configured backend
+var ReviewSummarizer ReviewSummaryBackend = ExtractiveSummarizer{}
+
+func init() {
+	reviewSummary := config.Get().ReviewSummary
+	if reviewSummary.URL != "" {
+		ReviewSummarizer = &ChatCompletionSummarizer{
+			URL:    reviewSummary.URL,
+			APIKey: reviewSummary.APIKey,
+			Model:  reviewSummary.Model,
+		}
+	}
+}
+
+// ReviewSummaryService keeps the review summaries shown on venues up to date
+type ReviewSummaryService struct{}
+
+// RefreshSummaries regenerates the summaries of venues whose approved
+// reviews changed. It is run periodically by the job runner.
+func (rs *ReviewSummaryService) RefreshSummaries(ctx context.Context) error {
+	var afterID int64
+	for {
+		venueIDs, err := models.GetStaleReviewSummaryVenues(ctx, afterID, MinSummaryReviews, reviewSummaryBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, venueID := range venueIDs {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := rs.Refresh(ctx, venueID); err != nil {
+				return err
+			}
+			afterID = venueID
+		}
+
+		if len(venueIDs) < reviewSummaryBatch {
+			return nil
+		}
+	}
+}
+
+// Refresh regenerates the venue's summary, removing it when the venue has
+// too few approved reviews. Summaries the configured backend fails on are
+// written by the extractive summarizer instead.
+func (rs *ReviewSummaryService) Refresh(ctx context.Context, venueID int64) error {
+	stats, err := models.GetSummaryReviewStats(ctx, venueID)
+	if err != nil {
+		return err
+	}
+	if stats.ReviewCount < MinSummaryReviews {
+		return models.DeleteGeneratedReviewSummary(ctx, venueID)
+	}
+
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx); err != nil {
+		return err
+	}
+	reviews, err := models.GetSummarizedReviews(ctx, venueID, maxSummarizedReviews)
+	if err != nil {
+		return err
+	}
+	input := ReviewSummaryInput{
+		VenueName:     venue.Name,
+		AverageRating: stats.AverageRating,
+		ReviewCount:   stats.ReviewCount,
+		Reviews:       reviews,
+	}
+
+	backend := ReviewSummarizer
+	text, err := backend.Summarize(ctx, input)
+	if err != nil || text == "" {
+		if err != nil {
+			sentry.CaptureException(fmt.Errorf("review summary %s: %w", backend.Name(), err))
+		}
+		backend = ExtractiveSummarizer{}
+		if text, err = backend.Summarize(ctx, input); err != nil {
+			return err
+		}
+	}
+
+	summary := &models.GeneratedReviewSummary{
+		VenueID:          venueID,
+		Summary:          text,
+		Provider:         backend.Name(),
+		ReviewCount:      stats.ReviewCount,
+		ReviewsChangedAt: stats.ChangedAt,
+	}
+	return summary.Save(ctx)
+}
+
+const (
+	// Sentences outside these lengths in words are never picked
+	minSummarySentenceWords = 4
+	maxSummarySentenceWords = 35
+
+	// maxQuotedSentences is how many review sentences follow the rating
+	maxQuotedSentences = 2
+)
+
+// summaryStopWords are left out when comparing what reviews talk about
+var summaryStopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a about after again all also am an and any are as at be been
+		before but by can could did do does for from had has have he her here his how i if in into is
+		it its just me more most my no not of on once only or other our out over really she so some
+		such than that the their them then there these they this to too very was we were what when
+		where which while who will with would you your place here went got get one back even us`) {
+		summaryStopWords[word] = true
+	}
+}
+
+// ExtractiveSummarizer summarizes reviews without an external service. It
+// states the average rating and quotes the review sentences sharing the
+// most words with other reviews, those saying what most reviewers say.
+type ExtractiveSummarizer struct{}
+
+func (ExtractiveSummarizer) Name() string {
+	return "extractive"
+}
+
+type summarySentence struct {
+	text   string
+	review int // Index of the review it is from
+	words  map[string]bool
+	score  float64
+}
+
+// Summarize writes the rating sentence followed by up to two quotes from
+// different reviews
+func (ExtractiveSummarizer) Summarize(ctx context.Context, input ReviewSummaryInput) (string, error) {
+	var sentences []summarySentence
+	// How many reviews use each word
+	reviewsWithWord := make(map[string]int)
+	for i, review := range input.Reviews {
+		seen := make(map[string]bool)
+		for _, text := range splitSentences(review.ReviewText) {
+			words := summaryWords(text)
+			for word := range words {
+				seen[word] = true
+			}
+			count := len(strings.Fields(text))
+			if count >= minSummarySentenceWords && count <= maxSummarySentenceWords && len(words) > 0 {
+				sentences = append(sentences, summarySentence{text: text, review: i, words: words})
+			}
+		}
+		for word := range seen {
+			reviewsWithWord[word]++
+		}
+	}
+
+	// Sentences score the average number of other reviews sharing their
+	// words, raised for helpful reviews
+	for i := range sentences {
+		shared := 0
+		for word := range sentences[i].words {
+			shared += reviewsWithWord[word] - 1
+		}
+		helpful := float64(input.Reviews[sentences[i].review].HelpfulVotes)
+		sentences[i].score = float64(shared) / float64(len(sentences[i].words)) * (1 + math.Log1p(math.Max(helpful, 0))/4)
+	}
+	sort.SliceStable(sentences, func(i, j int) bool {
+		return sentences[i].score > sentences[j].score
+	})
+
+	parts := []string{fmt.Sprintf("Rated %.1f out of 5 across %d reviews.", input.AverageRating, input.ReviewCount)}
+	var picked []summarySentence
+	for _, sentence := range sentences {
+		if len(picked) == maxQuotedSentences || sentence.score <= 0 {
+			break
+		}
+		if repeatsPicked(sentence, picked) {
+			continue
+		}
+		picked = append(picked, sentence)
+		parts = append(parts, `"`+sentence.text+`"`)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// repeatsPicked reports whether the sentence is from a review already quoted
+// or mostly says what a quoted sentence does
+func repeatsPicked(sentence summarySentence, picked []summarySentence) bool {
+	for _, other := range picked {
+		if other.review == sentence.review {
+			return true
+		}
+		common := 0
+		for word := range sentence.words {
+			if other.words[word] {
+				common++
+			}
+		}
+		if float64(common) >= 0.5*float64(len(sentence.words)+len(other.words)-common) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSentences splits text after sentence ending punctuation and line
+// breaks, ending each sentence with punctuation
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	flush := func(end int) {
+		sentence := strings.TrimSpace(string(runes[start:end]))
+		start = end
+		if sentence == "" {
+			return
+		}
+		if !strings.ContainsAny(sentence[len(sentence)-1:], ".!?") {
+			sentence += "."
+		}
+		sentences = append(sentences, sentence)
+	}
+	for i, r := range runes {
+		switch {
+		case r == '\n':
+			flush(i + 1)
+		case strings.ContainsRune(".!?", r) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			flush(i + 1)
+		}
+	}
+	flush(len(runes))
+	return sentences
+}
+
+// summaryWords returns the distinct lowercase words of the text that say
+// something about the venue
+func summaryWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) >= 3 && !summaryStopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+const (
+	// Reviews sent to the chat completions API are capped to keep prompts
+	// small
+	maxPromptReviews     = 40
+	maxPromptReviewRunes = 600
+)
+
+var reviewSummaryHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// ChatCompletionSummarizer asks an OpenAI compatible chat completions API to
+// summarize the reviews
+type ChatCompletionSummarizer struct {
+	URL    string
+	APIKey string
+	Model  string
+}
+
+func (c *ChatCompletionSummarizer) Name() string {
+	return "chat_completion"
+}
+
+// Summarize sends the most helpful reviews to the API
+func (c *ChatCompletionSummarizer) Summarize(ctx context.Context, input ReviewSummaryInput) (string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Venue: %s\nAverage rating: %.1f out of 5 across %d reviews\n\nReviews:\n",
+		input.VenueName, input.AverageRating, input.ReviewCount)
+	for i, review := range input.Reviews {
+		if i == maxPromptReviews {
+			break
+		}
+		text := []rune(strings.TrimSpace(review.Title + "\n" + review.ReviewText))
+		if len(text) > maxPromptReviewRunes {
+			text = text[:maxPromptReviewRunes]
+		}
+		fmt.Fprintf(&prompt, "- (%.1f/5) %s\n", review.OverallRating, strings.ReplaceAll(string(text), "\n", " "))
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": c.Model,
+		"messages": []map[string]string{
+			{
+				"role": "system",
+				"content": "You summarize customer reviews of a venue in two or three sentences of plain text. " +
+					"Say what reviewers agree on, good and bad, without inventing details or quoting names.",
+			},
+			{"role": "user", "content": prompt.String()},
+		},
+		"temperature": 0.2,
+		"max_tokens":  200,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := reviewSummaryHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chat completion: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("chat completion: no choices")
+	}
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// savedSearchMatchLimit caps the new venues picked up per search and run
+const savedSearchMatchLimit = 100
+
+// SavedSearchService evaluates saved searches and alerts users about new
+// matching venues
+type SavedSearchService struct{}
+
+// EvaluateSavedSearches runs every alerting saved search against the venues
+// added since its last evaluation. It is run periodically by the job runner.
+func (ss *SavedSearchService) EvaluateSavedSearches(ctx context.Context) error {
+	searches, err := models.GetAlertingSavedSearches(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range searches {
+		if _, err := ss.Evaluate(ctx, &searches[i]); err != nil {
+			// Keep going, the search is retried on the next run
+			sentry.CaptureException(err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate records the venues added since the last evaluation that match the
+// saved search and notifies its owner. It returns the number of new matches.
+func (ss *SavedSearchService) Evaluate(ctx context.Context, search *models.SavedSearch) (int, error) {
+	evaluatedAt, err := models.SavedSearchEvaluationTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	params := search.Params
+	params.CreatedAfter = &search.LastEvaluatedAt
+	params.SortBy = "newest"
+	params.Page = 1
+	params.Limit = savedSearchMatchLimit
+
+	venue := &models.Venue{}
+	venues, _, err := venue.Search(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+
+	venueIDs := make([]int64, len(venues))
+	for i, venue := range venues {
+		venueIDs[i] = venue.ID
+	}
+
+	added, err := search.AddMatches(ctx, venueIDs)
+	if err != nil {
+		return added, err
+	}
+
+	if added > 0 {
+		notificationService := &NotificationService{}
+		_, err := notificationService.Notify(ctx, search.UserID, models.NotificationSavedSearch,
+			fmt.Sprintf("New places for \"%s\"", search.Name),
+			savedSearchAlertBody(added, venues[0].Name),
+			map[string]string{"savedSearchId": fmt.Sprintf("%d", search.ID)},
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+	}
+
+	return added, search.MarkEvaluated(ctx, evaluatedAt, added > 0)
+}
+
+func savedSearchAlertBody(added int, newestVenue string) string {
+	if added == 1 {
+		return fmt.Sprintf("%s matches your saved search.", newestVenue)
+	}
+	return fmt.Sprintf("%s and %d other new places match your saved search.", newestVenue, added-1)
+}
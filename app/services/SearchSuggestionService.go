@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Suggestion types
+const (
+	SuggestionQuery = "query"
+	SuggestionVenue = "venue"
+)
+
+const (
+	// suggestionWindow is how far back search_analytics is aggregated
+	suggestionWindow = 30 * 24 * time.Hour
+	// suggestionRecentWindow is the window queries are rising in
+	suggestionRecentWindow = 24 * time.Hour
+	// suggestionMaxQueries bounds the distinct queries kept in memory
+	suggestionMaxQueries = 20000
+	// suggestionMaxVenues bounds the venue names kept in memory
+	suggestionMaxVenues = 50000
+	// trendingMinRecentSearches filters out noise from single searches
+	trendingMinRecentSearches = 2
+)
+
+// SearchSuggestionService serves trending queries and autocomplete from an
+// in-memory index rebuilt by the suggestion refresh job
+type SearchSuggestionService struct{}
+
+// QuerySuggestion is a search query with its popularity
+type QuerySuggestion struct {
+	Query          string  `json:"query"`
+	Searches       int     `json:"searches"`       // Searches in the last 30 days
+	RecentSearches int     `json:"recentSearches"` // Searches in the last 24 hours
+	TrendScore     float64 `json:"trendScore,omitempty"`
+}
+
+// SearchSuggestions are the popular and rising queries of a city
+type SearchSuggestions struct {
+	CityID      *int64            `json:"cityId,omitempty"`
+	Popular     []QuerySuggestion `json:"popular"`
+	Trending    []QuerySuggestion `json:"trending"`
+	RefreshedAt time.Time         `json:"refreshedAt"`
+}
+
+// AutocompleteSuggestion is a ranked autocomplete entry
+type AutocompleteSuggestion struct {
+	Type    string  `json:"type"` // query, venue
+	Text    string  `json:"text"`
+	VenueID int64   `json:"venueId,omitempty"`
+	Slug    string  `json:"slug,omitempty"`
+	Score   float64 `json:"score"`
+}
+
+// prefixKey points a lowercase word suffix of a text (e.g. "pizza" for "best
+// pizza") to its entry, so both whole text and word prefixes match
+type prefixKey struct {
+	key   string
+	entry int
+}
+
+type venueSuggestion struct {
+	id       int64
+	tenantID int64
+	name     string
+	slug     string
+	cityID   int64
+	ratings  int
+	rating   float64
+}
+
+type suggestionIndex struct {
+	// Per city query stats, city 0 aggregates every city
+	popular   map[int64][]QuerySuggestion
+	trending  map[int64][]QuerySuggestion
+	queryKeys map[int64][]prefixKey
+
+	venues    []venueSuggestion
+	venueKeys []prefixKey
+
+	refreshedAt time.Time
+}
+
+var suggestions = struct {
+	sync.RWMutex
+	index *suggestionIndex
+}{}
+
+// RefreshSuggestions rebuilds the suggestion index from search_analytics and
+// venue names. It is run periodically by the job runner.
+func (ss *SearchSuggestionService) RefreshSuggestions(ctx context.Context) error {
+	index := &suggestionIndex{
+		popular:   make(map[int64][]QuerySuggestion),
+		trending:  make(map[int64][]QuerySuggestion),
+		queryKeys: make(map[int64][]prefixKey),
+	}
+
+	if err := ss.loadQueries(ctx, index); err != nil {
+		return err
+	}
+	if err := ss.loadVenues(ctx, index); err != nil {
+		return err
+	}
+	index.refreshedAt = time.Now().UTC()
+
+	suggestions.Lock()
+	suggestions.index = index
+	suggestions.Unlock()
+
+	return nil
+}
+
+// GetSuggestions returns the popular and trending queries of a city, or of
+// every city when cityID is nil
+func (ss *SearchSuggestionService) GetSuggestions(ctx context.Context, cityID *int64, limit int) (*SearchSuggestions, error) {
+	index, err := ss.currentIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	city := suggestionCity(cityID)
+	result := &SearchSuggestions{
+		CityID:      cityID,
+		Popular:     firstQueries(index.popular[city], limit),
+		Trending:    firstQueries(index.trending[city], limit),
+		RefreshedAt: index.refreshedAt,
+	}
+	return result, nil
+}
+
+// Autocomplete ranks past queries and venue names starting with the
+// prefix, venues of the context's tenant only
+func (ss *SearchSuggestionService) Autocomplete(ctx context.Context, prefix string, cityID *int64, limit int) ([]AutocompleteSuggestion, error) {
+	index, err := ss.currentIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = normalizeSuggestion(prefix)
+	results := make([]AutocompleteSuggestion, 0, limit)
+	if prefix == "" {
+		return results, nil
+	}
+
+	city := suggestionCity(cityID)
+	tenantID, scoped := models.TenantFromContext(ctx)
+	seen := make(map[string]bool)
+
+	// Past queries, weighted by popularity with a bonus for rising ones
+	queries := index.popular[city]
+	for _, entry := range matchPrefix(index.queryKeys[city], prefix) {
+		query := queries[entry]
+		if seen[query.Query] {
+			continue
+		}
+		seen[query.Query] = true
+
+		score := math.Log1p(float64(query.Searches)) + 0.5*math.Log1p(float64(query.RecentSearches))
+		if strings.HasPrefix(query.Query, prefix) {
+			score += 1
+		}
+		results = append(results, AutocompleteSuggestion{
+			Type:  SuggestionQuery,
+			Text:  query.Query,
+			Score: roundScore(score),
+		})
+	}
+
+	// Venue names, weighted by how established the venue is
+	for _, entry := range matchPrefix(index.venueKeys, prefix) {
+		venue := index.venues[entry]
+		if cityID != nil && venue.cityID != *cityID {
+			continue
+		}
+		if scoped && venue.tenantID != tenantID {
+			continue
+		}
+		key := strings.ToLower(venue.name)
+		if seen["venue:"+key] {
+			continue
+		}
+		seen["venue:"+key] = true
+
+		score := 0.5*math.Log1p(float64(venue.ratings)) + venue.rating/5
+		if strings.HasPrefix(key, prefix) {
+			score += 1.5
+		}
+		results = append(results, AutocompleteSuggestion{
+			Type:    SuggestionVenue,
+			Text:    venue.name,
+			VenueID: venue.id,
+			Slug:    venue.slug,
+			Score:   roundScore(score),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Text < results[j].Text
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// currentIndex returns the suggestion index, building it on first use. The
+// index holds the venues of every tenant, so it isn't built with the
+// request's tenant.
+func (ss *SearchSuggestionService) currentIndex() (*suggestionIndex, error) {
+	suggestions.RLock()
+	index := suggestions.index
+	suggestions.RUnlock()
+
+	if index != nil {
+		return index, nil
+	}
+
+	if err := ss.RefreshSuggestions(context.Background()); err != nil {
+		return nil, err
+	}
+
+	suggestions.RLock()
+	defer suggestions.RUnlock()
+	return suggestions.index, nil
+}
+
+func (ss *SearchSuggestionService) loadQueries(ctx context.Context, index *suggestionIndex) error {
+	now := time.Now()
+	query := `
+		SELECT LOWER(TRIM(search_query)) AS query,
+			   CASE WHEN filters_used->>'city_id' ~ '^[0-9]+$'
+					THEN (filters_used->>'city_id')::bigint ELSE 0 END AS city_id,
+			   COUNT(*) AS searches,
+			   COUNT(*) FILTER (WHERE created_at >= $2) AS recent_searches
+		FROM search_analytics
+		WHERE created_at >= $1 AND TRIM(COALESCE(search_query, '')) <> ''
+		GROUP BY 1, 2
+		ORDER BY searches DESC
+		LIMIT $3`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query,
+		now.Add(-suggestionWindow), now.Add(-suggestionRecentWindow), suggestionMaxQueries)
+	if err != nil {
+		sentry.CaptureException(err)
+		return err
+	}
+	defer rows.Close()
+
+	// Aggregate per city and over every city
+	stats := make(map[int64]map[string]*QuerySuggestion)
+	add := func(city int64, text string, searches, recent int) {
+		if stats[city] == nil {
+			stats[city] = make(map[string]*QuerySuggestion)
+		}
+		stat, exists := stats[city][text]
+		if !exists {
+			stat = &QuerySuggestion{Query: text}
+			stats[city][text] = stat
+		}
+		stat.Searches += searches
+		stat.RecentSearches += recent
+	}
+
+	for rows.Next() {
+		var text string
+		var city int64
+		var searches, recent int
+		if err := rows.Scan(&text, &city, &searches, &recent); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+
+		text = normalizeSuggestion(text)
+		if text == "" {
+			continue
+		}
+		add(0, text, searches, recent)
+		if city != 0 {
+			add(city, text, searches, recent)
+		}
+	}
+
+	baselineDays := (suggestionWindow - suggestionRecentWindow).Hours() / 24
+	for city, cityStats := range stats {
+		popular := make([]QuerySuggestion, 0, len(cityStats))
+		trending := make([]QuerySuggestion, 0)
+
+		for _, stat := range cityStats {
+			// Rising queries are searched more today than on an average day
+			baseline := float64(stat.Searches-stat.RecentSearches) / baselineDays
+			stat.TrendScore = roundScore(float64(stat.RecentSearches) / (baseline + 1))
+
+			popular = append(popular, *stat)
+			if stat.RecentSearches >= trendingMinRecentSearches && stat.TrendScore > 1 {
+				trending = append(trending, *stat)
+			}
+		}
+
+		sort.Slice(popular, func(i, j int) bool {
+			if popular[i].Searches != popular[j].Searches {
+				return popular[i].Searches > popular[j].Searches
+			}
+			return popular[i].Query < popular[j].Query
+		})
+		sort.Slice(trending, func(i, j int) bool {
+			if trending[i].TrendScore != trending[j].TrendScore {
+				return trending[i].TrendScore > trending[j].TrendScore
+			}
+			return trending[i].Query < trending[j].Query
+		})
+
+		keys := make([]prefixKey, 0, len(popular))
+		for i, stat := range popular {
+			for _, key := range wordSuffixes(stat.Query) {
+				keys = append(keys, prefixKey{key: key, entry: i})
+			}
+		}
+		sortPrefixKeys(keys)
+
+		index.popular[city] = popular
+		index.trending[city] = trending
+		index.queryKeys[city] = keys
+	}
+
+	return nil
+}
+
+func (ss *SearchSuggestionService) loadVenues(ctx context.Context, index *suggestionIndex) error {
+	entries, err := models.GetVenueFeedEntries(ctx, nil, nil, suggestionMaxVenues)
+	if err != nil {
+		return err
+	}
+
+	index.venues = make([]venueSuggestion, len(entries))
+	index.venueKeys = make([]prefixKey, 0, len(entries)*2)
+	for i, entry := range entries {
+		index.venues[i] = venueSuggestion{
+			id:       entry.ID,
+			tenantID: entry.TenantID,
+			name:     entry.Name,
+			slug:     entry.Slug,
+			cityID:   entry.CityID,
+			ratings:  entry.TotalRatings,
+			rating:   entry.AverageRating,
+		}
+		for _, key := range wordSuffixes(normalizeSuggestion(entry.Name)) {
+			index.venueKeys = append(index.venueKeys, prefixKey{key: key, entry: i})
+		}
+	}
+	sortPrefixKeys(index.venueKeys)
+
+	return nil
+}
+
+// matchPrefix returns the entries with a key starting with the prefix
+func matchPrefix(keys []prefixKey, prefix string) []int {
+	start := sort.Search(len(keys), func(i int) bool {
+		return keys[i].key >= prefix
+	})
+
+	entries := make([]int, 0)
+	for i := start; i < len(keys) && strings.HasPrefix(keys[i].key, prefix); i++ {
+		entries = append(entries, keys[i].entry)
+	}
+	return entries
+}
+
+// wordSuffixes returns the text starting at each of its words
+func wordSuffixes(text string) []string {
+	words := strings.Fields(text)
+	suffixes := make([]string, len(words))
+	for i := range words {
+		suffixes[i] = strings.Join(words[i:], " ")
+	}
+	return suffixes
+}
+
+func sortPrefixKeys(keys []prefixKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].key != keys[j].key {
+			return keys[i].key < keys[j].key
+		}
+		return keys[i].entry < keys[j].entry
+	})
+}
+
+func normalizeSuggestion(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func firstQueries(queries []QuerySuggestion, limit int) []QuerySuggestion {
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return append(make([]QuerySuggestion, 0, len(queries)), queries...)
+}
+
+func suggestionCity(cityID *int64) int64 {
+	if cityID == nil {
+		return 0
+	}
+	return *cityID
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// Seed sizes
+const (
+	SeedVenues         = 200
+	seedReviewers      = 20
+	seedMaxVenueReview = 5
+)
+
+// SeedAdminEmail and SeedAdminPassword sign in the admin user the seed
+// creates, who moderated the sample reviews. For local development only.
+const (
+	SeedAdminEmail    = "admin@voteengine.local"
+	SeedAdminPassword = "voteengine"
+)
+
+// seedCity is a sample city and the streets its venues are on
+type seedCity struct {
+	models.City
+	Streets []string
+}
+
+var seedCities = []seedCity{
+	{City: models.City{Name: "Tehran", Country: "Iran", Latitude: 35.6892, Longitude: 51.3890, Timezone: "Asia/Tehran"},
+		Streets: []string{"Valiasr St", "Enghelab St", "Shariati St", "Mirdamad Blvd", "Fereshteh St"}},
+	{City: models.City{Name: "Isfahan", Country: "Iran", Latitude: 32.6546, Longitude: 51.6680, Timezone: "Asia/Tehran"},
+		Streets: []string{"Chaharbagh Abbasi St", "Nazar St", "Hakim Nezami St", "Sheikh Bahaei St"}},
+	{City: models.City{Name: "Shiraz", Country: "Iran", Latitude: 29.5918, Longitude: 52.5837, Timezone: "Asia/Tehran"},
+		Streets: []string{"Zand Blvd", "Eram Blvd", "Molla Sadra St", "Afif Abad St"}},
+	{City: models.City{Name: "Tabriz", Country: "Iran", Latitude: 38.0962, Longitude: 46.2738, Timezone: "Asia/Tehran"},
+		Streets: []string{"Imam St", "Shahnaz St", "Valiasr Blvd", "Abresan St"}},
+}
+
+var seedCategories = []models.VenueCategory{
+	{Name: "Restaurant", Description: "Sit-down dining", Icon: "restaurant"},
+	{Name: "Cafe", Description: "Coffee, tea and light meals", Icon: "cafe"},
+	{Name: "Bar", Description: "Drinks and late nights", Icon: "bar"},
+	{Name: "Bakery", Description: "Bread, pastries and sweets", Icon: "bakery"},
+}
+
+var (
+	seedNameAdjectives = []string{"Golden", "Blue", "Old", "Little", "Green", "Silver", "Hidden", "Royal", "Sunny", "Cozy"}
+	seedNameNouns      = []string{"Pomegranate", "Garden", "Lantern", "Saffron", "Cedar", "Courtyard", "Olive", "Bazaar", "Fig", "Window"}
+	seedPriceRanges    = models.PriceRanges.Values()
+	seedReviewTitles   = []string{"Lovely evening", "Worth the wait", "Decent but pricey", "Our new favourite", "Not for us", "Great for groups"}
+	seedReviewTexts    = []string{
+		"Friendly staff and the food came out quickly.",
+		"Cozy room, a bit loud on weekends.",
+		"Portions were generous and everything was fresh.",
+		"Service was slow but the dessert made up for it.",
+		"We came for a birthday and they looked after us well.",
+	}
+)
+
+// SeedSummary counts what a seed run created. Rows that already existed are
+// left as they are and not counted.
+type SeedSummary struct {
+	Venues       int
+	Reviews      int
+	LegacyRows   int
+	CampaignID   int64
+	AdminCreated bool
+}
+
+// SeedService loads sample data for local development: cities, categories,
+// venues with reviews, an open campaign and the legacy voting tables. Data
+// is generated from a fixed random seed, so running it again finds the rows
+// of the previous run and only adds what is missing.
+type SeedService struct{}
+
+// Run seeds the database
+func (ss *SeedService) Run(ctx context.Context) (*SeedSummary, error) {
+	random := rand.New(rand.NewSource(1))
+	summary := new(SeedSummary)
+
+	admin, created, err := ss.ensureAdmin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("seed admin: %w", err)
+	}
+	summary.AdminCreated = created
+
+	cities := make([]seedCity, len(seedCities))
+	for i := range seedCities {
+		cities[i] = seedCities[i]
+		if err := models.EnsureCity(ctx, &cities[i].City); err != nil {
+			return nil, fmt.Errorf("seed city %s: %w", cities[i].Name, err)
+		}
+	}
+	categories := make([]models.VenueCategory, len(seedCategories))
+	for i := range seedCategories {
+		categories[i] = seedCategories[i]
+		if err := models.EnsureVenueCategory(ctx, &categories[i]); err != nil {
+			return nil, fmt.Errorf("seed category %s: %w", categories[i].Name, err)
+		}
+	}
+	reviewers := make([]models.SnappUser, seedReviewers)
+	for i := range reviewers {
+		reviewers[i].SnappId = fmt.Sprintf("seed_user_%02d", i+1)
+		if err := models.EnsureSnappUser(ctx, &reviewers[i]); err != nil {
+			return nil, fmt.Errorf("seed user %s: %w", reviewers[i].SnappId, err)
+		}
+	}
+
+	for i := 0; i < SeedVenues; i++ {
+		city := cities[i%len(cities)]
+		category := categories[random.Intn(len(categories))]
+		venue := &models.Venue{
+			Name:       fmt.Sprintf("%s %s %s %d", seedNameAdjectives[random.Intn(len(seedNameAdjectives))], seedNameNouns[random.Intn(len(seedNameNouns))], category.Name, i+1),
+			CityID:     city.ID,
+			CategoryID: category.ID,
+			// Within about 5 km of the city centre
+			Latitude:   city.Latitude + (random.Float64()-0.5)*0.09,
+			Longitude:  city.Longitude + (random.Float64()-0.5)*0.11,
+			Address:    fmt.Sprintf("%d %s", 1+random.Intn(300), city.Streets[random.Intn(len(city.Streets))]),
+			PriceRange: seedPriceRanges[random.Intn(len(seedPriceRanges))],
+		}
+		venue.ShortDesc = fmt.Sprintf("A %s in %s", category.Name, city.Name)
+		venue.AvgCostPerPerson = float64(len(venue.PriceRange) * (5 + random.Intn(10)))
+		// Names are plain words, so this is the slug venues get from them
+		venue.Slug = strings.ToLower(strings.ReplaceAll(venue.Name, " ", "-"))
+		reviews := makeSeedReviews(random, reviewers)
+
+		venue.ID, err = models.GetVenueIDBySlug(ctx, venue.Slug)
+		if err == sql.ErrNoRows {
+			if err := venue.Create(ctx); err != nil {
+				return nil, fmt.Errorf("seed venue %s: %w", venue.Name, err)
+			}
+			summary.Venues++
+		} else if err != nil {
+			return nil, err
+		}
+
+		added, err := ss.addReviews(ctx, venue, reviews, admin.Id)
+		if err != nil {
+			return nil, fmt.Errorf("seed reviews of %s: %w", venue.Name, err)
+		}
+		summary.Reviews += added
+	}
+
+	campaign := &models.VotingCampaign{
+		Title:           "Best Restaurant in Tehran",
+		Description:     "Vote for your favourite place to eat in Tehran",
+		CampaignType:    models.CampaignTypeBestRestaurant,
+		CityID:          &cities[0].ID,
+		StartDate:       time.Now().UTC().AddDate(0, 0, -7).Truncate(24 * time.Hour),
+		EndDate:         time.Now().UTC().AddDate(0, 1, 0).Truncate(24 * time.Hour),
+		MaxVotesPerUser: 3,
+		IsActive:        true,
+		IsFeatured:      true,
+	}
+	if err := models.EnsureVotingCampaign(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("seed campaign: %w", err)
+	}
+	summary.CampaignID = campaign.ID
+
+	summary.LegacyRows, _, err = seedLegacyImport().Apply(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("seed legacy voting: %w", err)
+	}
+	return summary, nil
+}
+
+// ensureAdmin returns the seed admin user, creating it when missing
+func (ss *SeedService) ensureAdmin(ctx context.Context) (*models.User, bool, error) {
+	admin := &models.User{Email: SeedAdminEmail}
+	err := admin.Get(ctx)
+	if err == nil {
+		return admin, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	if err := admin.SetPassword(SeedAdminPassword); err != nil {
+		return nil, false, err
+	}
+	if err := admin.Create(ctx); err != nil {
+		return nil, false, err
+	}
+	return admin, true, nil
+}
+
+// addReviews adds the reviews whose users haven't reviewed the venue yet,
+// approved by the moderator, and refreshes the venue's ratings
+func (ss *SeedService) addReviews(ctx context.Context, venue *models.Venue, reviews []*models.VenueReview, moderatorID int64) (int, error) {
+	added := 0
+	for _, review := range reviews {
+		reviewed, err := models.HasUserReviewedVenue(ctx, venue.ID, review.UserID)
+		if err != nil {
+			return added, err
+		}
+		if reviewed {
+			continue
+		}
+		review.VenueID = venue.ID
+		if err := review.Create(ctx); err != nil {
+			return added, err
+		}
+		if err := review.ApproveReview(ctx, moderatorID); err != nil {
+			return added, err
+		}
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	// ApproveReview refreshes the ratings in the background, which the seed
+	// command may exit before
+	return added, venue.UpdateRatingCache(ctx)
+}
+
+// makeSeedReviews draws up to seedMaxVenueReview reviews by distinct users.
+// They are drawn for every venue, existing or not, so the random sequence
+// and with it the rest of the data is the same on every run.
+func makeSeedReviews(random *rand.Rand, reviewers []models.SnappUser) []*models.VenueReview {
+	count := random.Intn(seedMaxVenueReview + 1)
+	reviews := make([]*models.VenueReview, 0, count)
+	for _, i := range random.Perm(len(reviewers))[:count] {
+		food := 1 + random.Intn(5)
+		service := 1 + random.Intn(5)
+		ratings, _ := json.Marshal(map[string]float64{"food": float64(food), "service": float64(service)})
+		reviews = append(reviews, &models.VenueReview{
+			UserID:          reviewers[i].Id,
+			OverallRating:   float64(food+service) / 2,
+			DetailedRatings: ratings,
+			Title:           seedReviewTitles[random.Intn(len(seedReviewTitles))],
+			ReviewText:      seedReviewTexts[random.Intn(len(seedReviewTexts))],
+		})
+	}
+	return reviews
+}
+
+// seedLegacyImport is a finished legacy voting with its mentors and
+// participants. The rows have fixed IDs, so applying it again updates them.
+func seedLegacyImport() *models.LegacyImport {
+	started := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	return &models.LegacyImport{
+		Mentors: []models.Mentor{
+			{Id: 1, Name: "Sara Ahmadi"},
+			{Id: 2, Name: "Reza Karimi"},
+		},
+		Participants: []models.Participant{
+			{Id: 1, Name: "Team Saffron", Code: "P001", IsActive: true, MentorId: 1},
+			{Id: 2, Name: "Team Cedar", Code: "P002", IsActive: true, MentorId: 1},
+			{Id: 3, Name: "Team Lantern", Code: "P003", IsActive: true, MentorId: 2},
+			{Id: 4, Name: "Team Olive", Code: "P004", IsActive: true, MentorId: 2},
+		},
+		Votings: []models.Voting{
+			{Id: 1, Name: "Spring Demo Day", Description: "Final demos of the spring cohort", WinnerId: 3,
+				StartedAt: started, EndedAt: started.Add(8 * time.Hour)},
+		},
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+const (
+	// MaxSimilarVenues is how many similar venues are precomputed per venue
+	MaxSimilarVenues = 20
+	// similarVenuesRefreshAge is how old a list gets before the nightly
+	// precomputation replaces it
+	similarVenuesRefreshAge = 24 * time.Hour
+	// similarVenuesStaleAge marks lists the precomputation failed to refresh
+	similarVenuesStaleAge = 48 * time.Hour
+	// similarVenuesBatchSize caps the lists computed per run of the job
+	similarVenuesBatchSize = 500
+)
+
+// SimilarVenues lists the venues similar to a venue, most similar first
+type SimilarVenues struct {
+	Venues     []models.Venue `json:"venues"`
+	ComputedAt time.Time      `json:"computedAt"`
+	Stale      bool           `json:"stale"` // The list missed its nightly refresh
+}
+
+// GetSimilarVenues returns up to limit (at most MaxSimilarVenues) venues
+// similar to the venue from the precomputed lists. Venues without a list
+// get one computed on the spot.
+func (re *RecommendationEngine) GetSimilarVenues(ctx context.Context, venueID int64, limit int) (*SimilarVenues, error) {
+	venue := &models.Venue{ID: venueID}
+	if err := venue.GetByID(ctx); err != nil {
+		return nil, err
+	}
+	if limit > MaxSimilarVenues {
+		limit = MaxSimilarVenues
+	}
+
+	list, err := models.GetSimilarVenueList(ctx, venueID, similarVenuesStaleAge)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		if list, err = re.refreshSimilarVenues(ctx, venue); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := list.SimilarIDs
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	venues, err := getSimilarVenuesByID(ctx, venue, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &SimilarVenues{Venues: venues, ComputedAt: list.ComputedAt, Stale: list.Stale}, nil
+}
+
+// PrecomputeSimilarVenues computes the similar venue lists that are missing
+// or a day old. Lists are refreshed in batches so the hourly job spreads the
+// work over the day.
+func (re *RecommendationEngine) PrecomputeSimilarVenues(ctx context.Context) error {
+	venueIDs, err := models.GetVenuesDueSimilarRefresh(ctx, similarVenuesRefreshAge, similarVenuesBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, venueID := range venueIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		venue := &models.Venue{ID: venueID}
+		if err := venue.GetByID(ctx); err != nil {
+			// Deactivated since it was picked
+			continue
+		}
+		if _, err := re.refreshSimilarVenues(ctx, venue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshSimilarVenues computes and stores the venue's list
+func (re *RecommendationEngine) refreshSimilarVenues(ctx context.Context, venue *models.Venue) (*models.SimilarVenueList, error) {
+	ids, err := computeSimilarVenues(ctx, venue)
+	if err != nil {
+		return nil, err
+	}
+	list := &models.SimilarVenueList{VenueID: venue.ID, SimilarIDs: ids}
+	if err := list.Save(ctx); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// computeSimilarVenues ranks the venues of the venue's tenant within 50 km
+// sharing its category or subcategory, same category and price range first.
+// Lists are computed by the job for every tenant, so the tenant is the
+// venue's rather than the context's.
+func computeSimilarVenues(ctx context.Context, venue *models.Venue) ([]int64, error) {
+	query := `
+		SELECT v.id
+		FROM venues v
+		WHERE v.is_active = true
+		  AND v.id != $1
+		  AND v.tenant_id = (SELECT tenant_id FROM venues WHERE id = $1)
+		  AND (v.category_id = $4 OR v.subcategory_id = $5)
+		  AND ST_DWithin(
+			  ST_Point(v.longitude, v.latitude)::geography,
+			  ST_Point($2, $3)::geography,
+			  50000
+		  )
+		ORDER BY
+		  CASE WHEN v.category_id = $4 THEN 1 ELSE 2 END,
+		  CASE WHEN v.price_range = $6 THEN 1 ELSE 2 END,
+		  v.average_rating DESC,
+		  ST_Distance(ST_Point(v.longitude, v.latitude)::geography, ST_Point($2, $3)::geography) ASC
+		LIMIT $7`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx,
+		query, venue.ID, venue.Longitude, venue.Latitude, venue.CategoryID,
+		venue.SubcategoryID, venue.PriceRange, MaxSimilarVenues,
+	)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, MaxSimilarVenues)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// getSimilarVenuesByID loads the venues in the order of the IDs with their
+// distance from the venue, leaving out venues deactivated since the list was
+// computed
+func getSimilarVenuesByID(ctx context.Context, venue *models.Venue, ids []int64) ([]models.Venue, error) {
+	venues := make([]models.Venue, 0, len(ids))
+	if len(ids) == 0 {
+		return venues, nil
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		SELECT v.id, v.name, v.slug, COALESCE(v.short_description, ''), v.address,
+			   v.latitude, v.longitude, v.category_id, COALESCE(v.price_range, ''),
+			   v.average_rating, v.total_ratings, COALESCE(v.cover_image, ''),
+			   ST_Distance(
+				   ST_Point(v.longitude, v.latitude)::geography,
+				   ST_Point($2, $3)::geography
+			   ) / 1000 as distance
+		FROM venues v
+		WHERE v.id = ANY($1) AND v.is_active = true
+		ORDER BY array_position($1, v.id)`,
+		pq.Array(ids), venue.Longitude, venue.Latitude)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.Venue
+		var distance float64
+		err := rows.Scan(
+			&v.ID, &v.Name, &v.Slug, &v.ShortDesc, &v.Address,
+			&v.Latitude, &v.Longitude, &v.CategoryID, &v.PriceRange,
+			&v.AverageRating, &v.TotalRatings, &v.CoverImage, &distance,
+		)
+		if err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		v.Distance = &distance
+		venues = append(venues, v)
+	}
+	return venues, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ErrObjectNotFound is returned when a stored file doesn't exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Storage stores files in buckets
+type Storage interface {
+	Name() string
+	Put(ctx context.Context, bucket, key, contentType string, data []byte) error
+	// Get opens a stored file, ErrObjectNotFound when it doesn't exist
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, *ObjectInfo, error)
+	// SignedURL returns a URL anyone can read the file from until it
+	// expires, used for files in private buckets
+	SignedURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error)
+}
+
+// FileStorage is the configured backend
+var FileStorage Storage
+
+func init() {
+	var err error
+	FileStorage, err = NewStorage(config.Get().Storage)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewStorage creates the backend selected by the configuration
+func NewStorage(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Backend {
+	case config.StorageLocal:
+		return &LocalStorage{Dir: cfg.LocalDir, Secret: cfg.SigningSecret}, nil
+	case config.StorageGCS:
+		client, err := newObjectStorageClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &GCSStorage{S3Storage{Client: client}}, nil
+	default:
+		client, err := newObjectStorageClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &S3Storage{Client: client}, nil
+	}
+}
+
+func newObjectStorageClient(cfg config.StorageConfig) (*minio.Client, error) {
+	return minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.Secure,
+		Region: cfg.Region,
+	})
+}
+
+// S3Storage stores files in S3 or MinIO
+type S3Storage struct {
+	Client *minio.Client
+}
+
+func (s *S3Storage) Name() string {
+	return config.StorageS3
+}
+
+func (s *S3Storage) Put(ctx context.Context, bucket, key, contentType string, data []byte) error {
+	_, err := s.Client.PutObject(ctx, bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Storage) Get(ctx context.Context, bucket, key string) (io.ReadCloser, *ObjectInfo, error) {
+	object, err := s.Client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	// The object is only requested once it is read or stat'ed
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, err
+	}
+	contentType := stat.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return object, &ObjectInfo{Size: stat.Size, ContentType: contentType}, nil
+}
+
+// SignedURL presigns a GET of the file
+func (s *S3Storage) SignedURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	signed, err := s.Client.PresignedGetObject(ctx, bucket, key, expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return signed.String(), nil
+}
+
+// GCSStorage stores files in Google Cloud Storage through its S3 compatible
+// XML API, authenticated with HMAC keys
+type GCSStorage struct {
+	S3Storage
+}
+
+func (g *GCSStorage) Name() string {
+	return config.StorageGCS
+}
+
+// LocalStorage keeps files on disk, one directory per bucket. Its signed
+// URLs are served by the file routes.
+type LocalStorage struct {
+	Dir    string
+	Secret string // Signs the URLs
+}
+
+func (l *LocalStorage) Name() string {
+	return config.StorageLocal
+}
+
+// path returns where the file is kept. Keys can't leave their bucket's
+// directory.
+func (l *LocalStorage) path(bucket, key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) || clean == "/" {
+		return "", ErrObjectNotFound
+	}
+	return filepath.Join(l.Dir, bucket, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the file through a temporary one so readers never see it half
+// written. The content type is found from the key's extension when read.
+func (l *LocalStorage) Put(ctx context.Context, bucket, key, contentType string, data []byte) error {
+	name, err := l.path(bucket, key)
+	if err != nil {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *LocalStorage) Get(ctx context.Context, bucket, key string) (io.ReadCloser, *ObjectInfo, error) {
+	name, err := l.path(bucket, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		file.Close()
+		return nil, nil, ErrObjectNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	return file, &ObjectInfo{Size: stat.Size(), ContentType: contentType}, nil
+}
+
+// SignedURL returns a file route URL carrying the bucket, expiry and their
+// signature
+func (l *LocalStorage) SignedURL(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+	if _, err := l.path(bucket, key); err != nil {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{
+		"bucket":    {bucket},
+		"expires":   {expires},
+		"signature": {l.sign(bucket, key, expires)},
+	}
+	return "/v1/files/" + strings.TrimPrefix(key, "/") + "?" + query.Encode(), nil
+}
+
+// Verify reports whether a signed URL's signature is valid and unexpired
+func (l *LocalStorage) Verify(bucket, key, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(l.sign(bucket, strings.TrimPrefix(key, "/"), expires)))
+}
+
+func (l *LocalStorage) sign(bucket, key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(l.Secret))
+	mac.Write([]byte(bucket + "\n" + strings.TrimPrefix(key, "/") + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// Delta sync limits
+const (
+	DefaultSyncLimit = 100
+	MaxSyncLimit     = 500
+	// MaxSyncWait is the longest a long poll waits for changes. Polls also
+	// end in time to answer before the request's query timeout.
+	MaxSyncWait = 30 * time.Second
+	// SyncTombstoneRetention is how long deletions are kept. Clients that
+	// synced before have to fetch everything again.
+	SyncTombstoneRetention = 30 * 24 * time.Hour
+
+	syncPollInterval = 2 * time.Second
+	// syncLoadMargin is left before the request deadline to load the changes
+	syncLoadMargin = 2 * time.Second
+)
+
+// Delta sync errors
+var (
+	ErrSyncExpired       = errors.New("sync position is older than the kept deletions")
+	ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+)
+
+// SyncService lists the venues, reviews and campaigns that changed since a
+// client last synced
+type SyncService struct{}
+
+// SyncPage is a page of changes. Cursor is the position after it, for the
+// next page or, without more, the next sync.
+type SyncPage struct {
+	Venues    []models.Venue
+	Reviews   []models.VenueReview
+	Campaigns []models.VotingCampaign
+	Deleted   []models.SyncTombstone
+	Cursor    string
+	HasMore   bool
+}
+
+// Changes returns at most limit changes of the city (any when nil) after
+// the position. Without changes it polls for up to wait. ErrSyncExpired is
+// returned when deletions after the position may have been forgotten.
+func (s *SyncService) Changes(ctx context.Context, cityID *int64, after models.SyncPosition, limit int, wait time.Duration) (*SyncPage, error) {
+	if !after.ChangedAt.IsZero() && time.Since(after.ChangedAt) > SyncTombstoneRetention {
+		return nil, ErrSyncExpired
+	}
+
+	until := time.Now().Add(wait)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Add(-syncLoadMargin).Before(until) {
+		until = deadline.Add(-syncLoadMargin)
+	}
+
+	for {
+		changes, err := models.GetSyncChanges(ctx, cityID, after, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 || time.Until(until) < syncPollInterval {
+			return s.load(ctx, changes, after, limit)
+		}
+
+		select {
+		case <-time.After(syncPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// load fetches the changed entities, leaving out those deleted since
+func (s *SyncService) load(ctx context.Context, changes []models.SyncChange, after models.SyncPosition, limit int) (*SyncPage, error) {
+	page := &SyncPage{
+		Venues:    make([]models.Venue, 0),
+		Reviews:   make([]models.VenueReview, 0),
+		Campaigns: make([]models.VotingCampaign, 0),
+		Deleted:   make([]models.SyncTombstone, 0),
+	}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		page.HasMore = true
+	}
+
+	changed := make(map[string][]int64)
+	for _, change := range changes {
+		if change.Deleted {
+			page.Deleted = append(page.Deleted, models.SyncTombstone{
+				Type: change.Type, ID: change.ID, DeletedAt: change.ChangedAt,
+			})
+			continue
+		}
+		changed[change.Type] = append(changed[change.Type], change.ID)
+	}
+
+	var err error
+	if page.Venues, err = models.GetVenuesByIDs(ctx, changed[models.SyncVenue]); err != nil {
+		return nil, err
+	}
+	if page.Reviews, err = models.GetReviewsByIDs(ctx, changed[models.SyncReview]); err != nil {
+		return nil, err
+	}
+	if page.Campaigns, err = models.GetCampaignsByIDs(ctx, changed[models.SyncCampaign]); err != nil {
+		return nil, err
+	}
+
+	if len(changes) > 0 {
+		after = changes[len(changes)-1].SyncPosition
+	}
+	page.Cursor = EncodeSyncCursor(after)
+	return page, nil
+}
+
+// ExpireTombstones forgets the deletions past SyncTombstoneRetention
+func (s *SyncService) ExpireTombstones(ctx context.Context) error {
+	_, err := models.ExpireSyncTombstones(ctx, time.Now().Add(-SyncTombstoneRetention))
+	return err
+}
+
+// EncodeSyncCursor encodes the position as an opaque cursor
+func EncodeSyncCursor(position models.SyncPosition) string {
+	raw := fmt.Sprintf("%d:%s:%d", position.ChangedAt.UnixMicro(), position.Type, position.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseSyncCursor decodes a cursor of EncodeSyncCursor, ErrInvalidSyncCursor
+// when it isn't one
+func ParseSyncCursor(cursor string) (models.SyncPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return models.SyncPosition{}, ErrInvalidSyncCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return models.SyncPosition{}, ErrInvalidSyncCursor
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return models.SyncPosition{}, ErrInvalidSyncCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || id < 0 {
+		return models.SyncPosition{}, ErrInvalidSyncCursor
+	}
+	return models.SyncPosition{ChangedAt: time.UnixMicro(micros).UTC(), Type: parts[1], ID: id}, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// tenantCacheTTL is how long tenants are served from memory before
+// reloading. Changes made through TenantService apply at once on this
+// instance.
+const tenantCacheTTL = 30 * time.Second
+
+// ErrUnknownTenant is returned when the requested tenant slug doesn't exist
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantService resolves the tenant of requests and manages tenants
+type TenantService struct{}
+
+// tenantCache holds the active tenants by slug, hostname and ID
+var tenantCache struct {
+	sync.RWMutex
+	bySlug   map[string]models.Tenant
+	byHost   map[string]models.Tenant
+	byID     map[int64]models.Tenant
+	loadedAt time.Time
+}
+
+// Resolve returns the tenant a request is for. The slug, from the X-Tenant
+// header, selects the tenant explicitly and must exist. Otherwise the tenant
+// claiming the host serves the request, the default tenant when none does.
+func (s *TenantService) Resolve(ctx context.Context, host, slug string) (*models.Tenant, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantCache.RLock()
+	defer tenantCache.RUnlock()
+
+	if slug = strings.ToLower(strings.TrimSpace(slug)); slug != "" {
+		tenant, exists := tenantCache.bySlug[slug]
+		if !exists {
+			return nil, ErrUnknownTenant
+		}
+		return &tenant, nil
+	}
+
+	if tenant, exists := tenantCache.byHost[normalizeHost(host)]; exists {
+		return &tenant, nil
+	}
+	if tenant, exists := tenantCache.byID[models.DefaultTenantID]; exists {
+		return &tenant, nil
+	}
+	return nil, ErrUnknownTenant
+}
+
+// CreateTenant stores a new tenant, models.ErrTenantExists when its slug is
+// taken
+func (s *TenantService) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	if err := tenant.Create(ctx); err != nil {
+		return err
+	}
+	invalidateTenants()
+	return nil
+}
+
+// UpdateTenant replaces a tenant's hostnames and configuration,
+// sql.ErrNoRows when it doesn't exist
+func (s *TenantService) UpdateTenant(ctx context.Context, tenant *models.Tenant) error {
+	if err := tenant.Update(ctx); err != nil {
+		return err
+	}
+	invalidateTenants()
+	return nil
+}
+
+// load reloads the tenants when the cache is stale. When reloading fails the
+// stale tenants are kept, and an error is only returned without any.
+func (s *TenantService) load(ctx context.Context) error {
+	tenantCache.RLock()
+	loaded := tenantCache.byID != nil
+	fresh := loaded && time.Since(tenantCache.loadedAt) < tenantCacheTTL
+	tenantCache.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	tenants, err := models.GetActiveTenants(ctx)
+	if err != nil {
+		sentry.CaptureException(err)
+		if loaded {
+			return nil
+		}
+		return err
+	}
+
+	bySlug := make(map[string]models.Tenant, len(tenants))
+	byHost := make(map[string]models.Tenant, len(tenants))
+	byID := make(map[int64]models.Tenant, len(tenants))
+	for _, tenant := range tenants {
+		bySlug[strings.ToLower(tenant.Slug)] = tenant
+		byID[tenant.ID] = tenant
+		for _, hostname := range tenant.Hostnames {
+			byHost[normalizeHost(hostname)] = tenant
+		}
+	}
+	tenantCache.Lock()
+	tenantCache.bySlug = bySlug
+	tenantCache.byHost = byHost
+	tenantCache.byID = byID
+	tenantCache.loadedAt = time.Now()
+	tenantCache.Unlock()
+	return nil
+}
+
+// normalizeHost lowercases the host and strips its port
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// invalidateTenants makes the next request reload the tenants
+func invalidateTenants() {
+	tenantCache.Lock()
+	tenantCache.bySlug = nil
+	tenantCache.byHost = nil
+	tenantCache.byID = nil
+	tenantCache.Unlock()
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// MaxTranslationsPerRequest caps the reviews sent to the translation backend
+// while serving one listing. The rest stay untranslated until a later
+// request, keeping listings fast when many reviews aren't cached yet.
+const MaxTranslationsPerRequest = 20
+
+// TranslationBackend translates texts into a language
+type TranslationBackend interface {
+	Name() string
+	// Translate returns the texts in the target language, in order, and the
+	// language detected in them
+	Translate(ctx context.Context, texts []string, targetLanguage string) ([]string, string, error)
+}
+
+// Translator is the configured backend, nil when translation is disabled
+var Translator TranslationBackend
+
+func init() {
+	translation := config.Get().Translation
+	if translation.URL != "" {
+		Translator = &TranslationAPIBackend{
+			URL:    translation.URL,
+			APIKey: translation.APIKey,
+		}
+	}
+}
+
+// TranslationService translates reviews for readers of other languages
+type TranslationService struct{}
+
+// TranslateReviews sets the translation of the reviews into the language.
+// Translations are cached per review and language until the review is
+// edited. Reviews already in the language, and reviews the backend fails
+// on, are left without a translation.
+func (ts *TranslationService) TranslateReviews(ctx context.Context, reviews []models.VenueReview, language string) {
+	if Translator == nil || len(reviews) == 0 {
+		return
+	}
+
+	reviewIDs := make([]int64, len(reviews))
+	for i, review := range reviews {
+		reviewIDs[i] = review.ID
+	}
+	cached, err := models.GetReviewTranslations(ctx, reviewIDs, language)
+	if err != nil {
+		return
+	}
+
+	translated := 0
+	for i := range reviews {
+		review := &reviews[i]
+		if review.Title == "" && review.ReviewText == "" {
+			continue
+		}
+
+		translation, isCached := cached[review.ID]
+		if !isCached || translation.TranslatedAt.Before(review.UpdatedAt) {
+			if translated >= MaxTranslationsPerRequest {
+				continue
+			}
+			translated++
+
+			texts, sourceLanguage, err := Translator.Translate(ctx, []string{review.Title, review.ReviewText}, language)
+			if err != nil {
+				sentry.CaptureException(fmt.Errorf("translation %s: %w", Translator.Name(), err))
+				continue
+			}
+			translation = models.ReviewTranslation{
+				ReviewID:       review.ID,
+				Language:       language,
+				SourceLanguage: sourceLanguage,
+				Title:          texts[0],
+				ReviewText:     texts[1],
+				Provider:       Translator.Name(),
+			}
+			// A failed save only costs translating the review again
+			translation.Save(ctx)
+		}
+
+		if translation.SourceLanguage != language {
+			review.Translation = &translation
+		}
+	}
+}
+
+var translationHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// TranslationAPIBackend asks an external translation API. The API receives
+// {"texts": [...], "targetLanguage": "xx"} and answers with
+// {"texts": [...], "sourceLanguage": "xx"}.
+type TranslationAPIBackend struct {
+	URL    string
+	APIKey string
+}
+
+func (t *TranslationAPIBackend) Name() string {
+	return "translation_api"
+}
+
+// Translate posts the texts to the translation API
+func (t *TranslationAPIBackend) Translate(ctx context.Context, texts []string, targetLanguage string) ([]string, string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"texts":          texts,
+		"targetLanguage": targetLanguage,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	resp, err := translationHTTPClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("translation api: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Texts          []string `json:"texts"`
+		SourceLanguage string   `json:"sourceLanguage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", err
+	}
+	if len(result.Texts) != len(texts) {
+		return nil, "", fmt.Errorf("translation api: got %d texts for %d", len(result.Texts), len(texts))
+	}
+
+	return result.Texts, result.SourceLanguage, nil
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Travel modes trips to venues are timed for
+const (
+	TravelModeWalking = "walking"
+	TravelModeDriving = "driving"
+)
+
+// travelSpeedsKmh are the typical city speeds of the travel modes, used to
+// turn distances into comparable trip times
+var travelSpeedsKmh = map[string]float64{
+	TravelModeWalking: 5,
+	TravelModeDriving: 30,
+}
+
+// maxCachedTravelTimes bounds the travel time cache, which is emptied when
+// full
+const maxCachedTravelTimes = 50000
+
+// ErrTravelTimeUnavailable is returned when no travel time backend is
+// configured or it failed, callers fall back to straight-line distances
+var ErrTravelTimeUnavailable = errors.New("travel times unavailable")
+
+// TravelTimeBackend times trips with a distance matrix API
+type TravelTimeBackend interface {
+	Name() string
+	// Durations returns the trip times in seconds from the origin to each
+	// destination, nil for the ones that can't be reached
+	Durations(ctx context.Context, mode string, origin LatLng, destinations []LatLng) ([]*float64, error)
+}
+
+// TravelTimer is the configured backend, nil when travel times are disabled
+var TravelTimer TravelTimeBackend
+
+func init() {
+	travel := config.Get().Travel
+	switch travel.Backend {
+	case config.TravelTimeMapbox:
+		TravelTimer = &MapboxMatrixBackend{URL: travel.URL, Token: config.Get().Geocoder.MapboxToken}
+	case config.TravelTimeOSRM:
+		TravelTimer = &OSRMBackend{URL: travel.URL}
+	}
+}
+
+// travelTimeCache holds trip times by mode, origin and destination, nil for
+// the unreachable destinations
+var travelTimeCache struct {
+	sync.Mutex
+	entries map[string]cachedTravelTime
+}
+
+type cachedTravelTime struct {
+	seconds   *float64
+	expiresAt time.Time
+}
+
+// TravelTimeService times the trips from the client to venues, so venues
+// across a river or highway don't rank as close as straight-line distance
+// makes them look
+type TravelTimeService struct{}
+
+// IsTravelMode tells whether trips can be timed for the mode
+func IsTravelMode(mode string) bool {
+	_, ok := travelSpeedsKmh[mode]
+	return ok
+}
+
+// TravelMinutesFor returns how long covering the distance in km typically
+// takes with the travel mode
+func TravelMinutesFor(mode string, km float64) float64 {
+	return km / travelSpeedsKmh[mode] * 60
+}
+
+// AttachTravelTimes sets the minutes the trip to each venue takes from the
+// origin. Unreachable venues are left without travel minutes.
+// ErrTravelTimeUnavailable is returned when trips can't be timed.
+func (ts *TravelTimeService) AttachTravelTimes(ctx context.Context, mode string, origin LatLng, venues []models.Venue) error {
+	backend := TravelTimer
+	if backend == nil {
+		return ErrTravelTimeUnavailable
+	}
+	if len(venues) == 0 {
+		return nil
+	}
+
+	// Origins are rounded to about a hundred meters so nearby clients share
+	// the cached times
+	origin = LatLng{Latitude: roundCoordinate(origin.Latitude, 3), Longitude: roundCoordinate(origin.Longitude, 3)}
+
+	now := time.Now()
+	seconds := make([]*float64, len(venues))
+	var missing []int
+	travelTimeCache.Lock()
+	for i, venue := range venues {
+		cached, ok := travelTimeCache.entries[travelTimeKey(mode, origin, venue)]
+		if ok && now.Before(cached.expiresAt) {
+			seconds[i] = cached.seconds
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	travelTimeCache.Unlock()
+
+	if len(missing) > 0 {
+		destinations := make([]LatLng, len(missing))
+		for j, i := range missing {
+			destinations[j] = LatLng{Latitude: venues[i].Latitude, Longitude: venues[i].Longitude}
+		}
+		durations, err := backend.Durations(ctx, mode, origin, destinations)
+		if err == nil && len(durations) != len(destinations) {
+			err = fmt.Errorf("%d durations for %d destinations", len(durations), len(destinations))
+		}
+		if err != nil {
+			// Failures aren't cached, the trips are timed again next time
+			sentry.CaptureException(fmt.Errorf("travel time %s: %w", backend.Name(), err))
+			return ErrTravelTimeUnavailable
+		}
+
+		expiresAt := now.Add(config.Get().Travel.CacheTTL)
+		travelTimeCache.Lock()
+		if travelTimeCache.entries == nil || len(travelTimeCache.entries)+len(missing) > maxCachedTravelTimes {
+			travelTimeCache.entries = make(map[string]cachedTravelTime)
+		}
+		for j, i := range missing {
+			seconds[i] = durations[j]
+			travelTimeCache.entries[travelTimeKey(mode, origin, venues[i])] = cachedTravelTime{seconds: durations[j], expiresAt: expiresAt}
+		}
+		travelTimeCache.Unlock()
+	}
+
+	for i := range venues {
+		venues[i].TravelMinutes = nil
+		if seconds[i] != nil {
+			minutes := math.Round(*seconds[i]/60*10) / 10
+			venues[i].TravelMinutes = &minutes
+		}
+	}
+	return nil
+}
+
+// SortByTravelTime times the trips to the venues and orders them by travel
+// minutes, dropping the unreachable ones and the ones beyond the limit
+func (ts *TravelTimeService) SortByTravelTime(ctx context.Context, mode string, origin LatLng, venues []models.Venue, limit int) ([]models.Venue, error) {
+	if err := ts.AttachTravelTimes(ctx, mode, origin, venues); err != nil {
+		return nil, err
+	}
+
+	reachable := make([]models.Venue, 0, len(venues))
+	for _, venue := range venues {
+		if venue.TravelMinutes != nil {
+			reachable = append(reachable, venue)
+		}
+	}
+	sort.SliceStable(reachable, func(i, j int) bool {
+		return *reachable[i].TravelMinutes < *reachable[j].TravelMinutes
+	})
+	if len(reachable) > limit {
+		reachable = reachable[:limit]
+	}
+	return reachable, nil
+}
+
+// ClearTravelTimeCache forgets the timed trips, used when the backend changes
+func ClearTravelTimeCache() {
+	travelTimeCache.Lock()
+	travelTimeCache.entries = nil
+	travelTimeCache.Unlock()
+}
+
+func travelTimeKey(mode string, origin LatLng, venue models.Venue) string {
+	return fmt.Sprintf("%s|%.3f,%.3f|%d|%.5f,%.5f", mode, origin.Latitude, origin.Longitude,
+		venue.ID, venue.Latitude, venue.Longitude)
+}
+
+func roundCoordinate(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+var travelTimeHTTPClient = &http.Client{Timeout: 3 * time.Second}
+
+// matrixCoordinates formats the origin followed by the destinations as the
+// lng,lat;lng,lat path of the matrix APIs
+func matrixCoordinates(origin LatLng, destinations []LatLng) string {
+	points := make([]string, 0, len(destinations)+1)
+	for _, point := range append([]LatLng{origin}, destinations...) {
+		points = append(points, fmt.Sprintf("%.6f,%.6f", point.Longitude, point.Latitude))
+	}
+	return strings.Join(points, ";")
+}
+
+// getMatrixDurations asks a matrix API for the durations from the first
+// coordinate, both Mapbox and OSRM answering with the same table
+func getMatrixDurations(ctx context.Context, backend, requestURL string, destinations int) ([]*float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := travelTimeHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code      string       `json:"code"`
+		Message   string       `json:"message"`
+		Durations [][]*float64 `json:"durations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%s: unexpected status %d", backend, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || result.Code != "Ok" {
+		return nil, fmt.Errorf("%s: %s %s (status %d)", backend, result.Code, result.Message, resp.StatusCode)
+	}
+	if len(result.Durations) != 1 || len(result.Durations[0]) != destinations+1 {
+		return nil, fmt.Errorf("%s: malformed duration table", backend)
+	}
+	// The first duration is the origin's to itself
+	return result.Durations[0][1:], nil
+}
+
+// MapboxMatrixBackend times trips with the Mapbox Matrix API
+type MapboxMatrixBackend struct {
+	URL   string // e.g. https://api.mapbox.com/directions-matrix/v1/mapbox
+	Token string
+}
+
+// mapboxMatrixDestinations is how many destinations a Mapbox matrix request
+// holds besides the origin
+const mapboxMatrixDestinations = 24
+
+func (m *MapboxMatrixBackend) Name() string {
+	return "mapbox"
+}
+
+// Durations asks the Matrix API in batches of its coordinate limit
+func (m *MapboxMatrixBackend) Durations(ctx context.Context, mode string, origin LatLng, destinations []LatLng) ([]*float64, error) {
+	profile := map[string]string{TravelModeWalking: "walking", TravelModeDriving: "driving"}[mode]
+	if profile == "" {
+		return nil, fmt.Errorf("mapbox: unsupported travel mode %q", mode)
+	}
+
+	durations := make([]*float64, 0, len(destinations))
+	for start := 0; start < len(destinations); start += mapboxMatrixDestinations {
+		end := start + mapboxMatrixDestinations
+		if end > len(destinations) {
+			end = len(destinations)
+		}
+
+		query := url.Values{}
+		query.Set("sources", "0")
+		query.Set("annotations", "duration")
+		query.Set("access_token", m.Token)
+		requestURL := fmt.Sprintf("%s/%s/%s?%s", strings.TrimRight(m.URL, "/"), profile,
+			matrixCoordinates(origin, destinations[start:end]), query.Encode())
+
+		batch, err := getMatrixDurations(ctx, m.Name(), requestURL, end-start)
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, batch...)
+	}
+	return durations, nil
+}
+
+// OSRMBackend times trips with the table service of an OSRM server
+type OSRMBackend struct {
+	URL string // e.g. http://osrm:5000
+}
+
+func (o *OSRMBackend) Name() string {
+	return "osrm"
+}
+
+// Durations asks the table service for the trips from the origin
+func (o *OSRMBackend) Durations(ctx context.Context, mode string, origin LatLng, destinations []LatLng) ([]*float64, error) {
+	profile := map[string]string{TravelModeWalking: "foot", TravelModeDriving: "car"}[mode]
+	if profile == "" {
+		return nil, fmt.Errorf("osrm: unsupported travel mode %q", mode)
+	}
+
+	requestURL := fmt.Sprintf("%s/table/v1/%s/%s?sources=0&annotations=duration", strings.TrimRight(o.URL, "/"), profile,
+		matrixCoordinates(origin, destinations))
+	return getMatrixDurations(ctx, o.Name(), requestURL, len(destinations))
+}
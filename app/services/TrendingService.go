@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+	"voting-app/app/models"
+)
+
+// TrendingCacheTTL is how long the trending lists are fresh. Stale lists are
+// still served while a request recomputes them in the background.
+const TrendingCacheTTL = 10 * time.Minute
+
+// trendingKey identifies a trending list, 0 standing for every tenant, city
+// or category
+type trendingKey struct {
+	tenantID   int64
+	cityID     int64
+	categoryID int64
+}
+
+type trendingIndex struct {
+	// Venues with activity within models.TrendingWindow, most active first
+	venues []models.Venue
+	// Positions in venues of each list's venues
+	lists      map[trendingKey][]int
+	computedAt time.Time
+}
+
+var trending = struct {
+	sync.RWMutex
+	index      *trendingIndex
+	refreshing bool
+}{}
+
+// TrendingService serves the venues with the most recent reviews, check-ins
+// and campaign votes per city and category from an in-memory index
+// recomputed by the trending refresh job
+type TrendingService struct{}
+
+// TrendingVenues are the trending venues of a list and when it was computed
+type TrendingVenues struct {
+	Venues     []models.Venue
+	ComputedAt time.Time
+}
+
+// RefreshTrending recomputes every trending list. It is run periodically by
+// the job runner.
+func (ts *TrendingService) RefreshTrending(ctx context.Context) error {
+	now := time.Now().UTC()
+	venues, err := models.GetTrendingVenues(ctx, now.Add(-models.TrendingWindow))
+	if err != nil {
+		return err
+	}
+
+	index := &trendingIndex{
+		venues:     make([]models.Venue, len(venues)),
+		lists:      make(map[trendingKey][]int),
+		computedAt: now,
+	}
+	for i, venue := range venues {
+		index.venues[i] = venue.Venue
+		for _, tenantID := range []int64{0, venue.TenantID} {
+			for _, key := range []trendingKey{
+				{tenantID, 0, 0},
+				{tenantID, venue.CityID, 0},
+				{tenantID, 0, venue.CategoryID},
+				{tenantID, venue.CityID, venue.CategoryID},
+			} {
+				index.lists[key] = append(index.lists[key], i)
+			}
+		}
+	}
+
+	trending.Lock()
+	trending.index = index
+	trending.Unlock()
+	return nil
+}
+
+// GetTrending returns the trending venues of the city and category, 0 for
+// any, within the radius in km of the location when one is given
+func (ts *TrendingService) GetTrending(ctx context.Context, cityID, categoryID int64, location *ResolvedLocation, radius float64, limit int) (*TrendingVenues, error) {
+	index, err := ts.currentIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, _ := models.TenantFromContext(ctx)
+	key := trendingKey{tenantID: tenantID, cityID: cityID, categoryID: categoryID}
+
+	geoService := &GeolocationService{}
+	result := &TrendingVenues{Venues: make([]models.Venue, 0, limit), ComputedAt: index.computedAt}
+	for _, i := range index.lists[key] {
+		if len(result.Venues) == limit {
+			break
+		}
+
+		// A copy, the cached venue is shared between requests
+		venue := index.venues[i]
+		if location != nil {
+			distance := geoService.CalculateDistance(location.Latitude, location.Longitude, venue.Latitude, venue.Longitude).Kilometers
+			if distance > radius {
+				continue
+			}
+			distance = math.Round(distance*100) / 100
+			venue.Distance = &distance
+		}
+		result.Venues = append(result.Venues, venue)
+	}
+	return result, nil
+}
+
+// currentIndex returns the trending index, computing it on first use and
+// refreshing it in the background once stale
+func (ts *TrendingService) currentIndex(ctx context.Context) (*trendingIndex, error) {
+	trending.RLock()
+	index := trending.index
+	trending.RUnlock()
+
+	if index == nil {
+		if err := ts.RefreshTrending(ctx); err != nil {
+			return nil, err
+		}
+		trending.RLock()
+		defer trending.RUnlock()
+		return trending.index, nil
+	}
+
+	if time.Since(index.computedAt) >= TrendingCacheTTL {
+		go ts.revalidate()
+	}
+	return index, nil
+}
+
+// revalidate refreshes the stale index unless a refresh is running already
+func (ts *TrendingService) revalidate() {
+	trending.Lock()
+	if trending.refreshing {
+		trending.Unlock()
+		return
+	}
+	trending.refreshing = true
+	trending.Unlock()
+
+	defer func() {
+		trending.Lock()
+		trending.refreshing = false
+		trending.Unlock()
+	}()
+
+	// A failed refresh keeps the stale index, the next request or job run
+	// retries
+	ts.RefreshTrending(context.Background())
+}
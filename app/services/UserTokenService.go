@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// EmailVerificationTTL is how long an email verification link works
+	EmailVerificationTTL = 48 * time.Hour
+	// PasswordResetTTL is how long a password reset link works
+	PasswordResetTTL = time.Hour
+	// userTokenResendInterval is the least time between two emails of the
+	// same kind to one user
+	userTokenResendInterval = time.Minute
+)
+
+// UserTokenService emails users single use links for verifying their email
+// and resetting their password
+type UserTokenService struct{}
+
+// SendEmailVerification emails the user a link verifying their email
+func (us *UserTokenService) SendEmailVerification(ctx context.Context, user *models.User) error {
+	token, err := us.createToken(ctx, user.Id, models.UserTokenVerifyEmail, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return AppMailer.Send(user.Email, "Verify your email",
+		fmt.Sprintf("Open this link to verify your email, it expires in %d hours:\n\n%s/verify-email/%s",
+			int(EmailVerificationTTL.Hours()), config.Get().SiteBaseURL, token))
+}
+
+// ResendEmailVerification emails a new verification link to the account of
+// the email if it is still unverified. Nothing tells the caller whether the
+// account exists.
+func (us *UserTokenService) ResendEmailVerification(ctx context.Context, email string) error {
+	user := &models.User{Email: email}
+	if err := user.Get(ctx); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	err := us.SendEmailVerification(ctx, user)
+	if err == models.ErrUserTokenThrottled {
+		return nil
+	}
+	return err
+}
+
+// SendPasswordReset emails a password reset link to the account of the
+// email. Nothing tells the caller whether the account exists.
+func (us *UserTokenService) SendPasswordReset(ctx context.Context, email string) error {
+	user := &models.User{Email: email}
+	if err := user.Get(ctx); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	token, err := us.createToken(ctx, user.Id, models.UserTokenResetPassword, PasswordResetTTL)
+	if err == models.ErrUserTokenThrottled {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return AppMailer.Send(user.Email, "Reset your password",
+		fmt.Sprintf("Open this link to choose a new password, it expires in %d minutes. Ignore this email if you didn't ask to reset your password.\n\n%s/reset-password/%s",
+			int(PasswordResetTTL.Minutes()), config.Get().SiteBaseURL, token))
+}
+
+// VerifyEmail verifies the email the token was sent to,
+// models.ErrInvalidUserToken when the token can't be used
+func (us *UserTokenService) VerifyEmail(ctx context.Context, token string) error {
+	return models.VerifyUserEmail(ctx, hashUserToken(token), time.Now().UTC())
+}
+
+// ResetPassword sets the password of the user the token was sent to,
+// models.ErrInvalidUserToken when the token can't be used
+func (us *UserTokenService) ResetPassword(ctx context.Context, token, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	return models.ResetUserPassword(ctx, hashUserToken(token), string(hash), time.Now().UTC())
+}
+
+// ExpireUserTokens removes the tokens past their expiry
+func (us *UserTokenService) ExpireUserTokens(ctx context.Context) error {
+	_, err := models.DeleteExpiredUserTokens(ctx, time.Now().UTC())
+	return err
+}
+
+func (us *UserTokenService) createToken(ctx context.Context, userID int64, purpose string, ttl time.Duration) (string, error) {
+	token, err := newUserToken()
+	if err != nil {
+		return "", err
+	}
+
+	userToken := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashUserToken(token),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+	if err := userToken.Create(ctx, userTokenResendInterval); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// newUserToken generates a random URL safe token
+func newUserToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// hashUserToken hashes the token, only hashes are stored
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
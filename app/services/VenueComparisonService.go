@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// Venue comparison limits
+const (
+	MinComparedVenues      = 2
+	MaxComparedVenues      = 4
+	comparisonTopAmenities = 5
+	comparisonHighlights   = 2
+	comparisonExcerptChars = 200
+	comparisonPopularDays  = 90
+)
+
+// ErrComparedVenueNotFound is returned when a compared venue doesn't exist
+// or is inactive
+var ErrComparedVenueNotFound = errors.New("compared venue not found")
+
+// VenueComparison holds aligned facts about a few venues. Every entry has a
+// value for each of the rating dimensions, null when the venue has no rating
+// for it, and 24 popular hour buckets.
+type VenueComparison struct {
+	Dimensions []string               `json:"dimensions"`
+	Venues     []VenueComparisonEntry `json:"venues"`
+	Origin     *LatLng                `json:"origin,omitempty"`
+	Best       map[string]int64       `json:"best"` // Field -> ID of the venue that does best on it
+}
+
+// VenueComparisonEntry is one column of a venue comparison
+type VenueComparisonEntry struct {
+	VenueID          int64               `json:"venueId"`
+	Name             string              `json:"name"`
+	Slug             string              `json:"slug"`
+	CoverImage       string              `json:"coverImage,omitempty"`
+	Category         string              `json:"category,omitempty"`
+	AverageRating    float64             `json:"averageRating"`
+	WeightedRating   float64             `json:"weightedRating"`
+	TotalReviews     int                 `json:"totalReviews"`
+	Ratings          map[string]*float64 `json:"ratings"` // Dimension -> average
+	PriceRange       string              `json:"priceRange,omitempty"`
+	AvgCostPerPerson float64             `json:"averageCostPerPerson,omitempty"`
+	DistanceKm       *float64            `json:"distanceKm,omitempty"`
+	PopularHours     []int               `json:"popularHours"` // Check-ins per hour of day, 0-23
+	PeakHour         *int                `json:"peakHour,omitempty"`
+	TopAmenities     []string            `json:"topAmenities"`
+	Highlights       []ReviewHighlight   `json:"highlights"`
+}
+
+// ReviewHighlight is an excerpt of one of the most helpful reviews
+type ReviewHighlight struct {
+	ReviewID      int64   `json:"reviewId"`
+	Title         string  `json:"title,omitempty"`
+	Excerpt       string  `json:"excerpt"`
+	OverallRating float64 `json:"overallRating"`
+	HelpfulVotes  int     `json:"helpfulVotes"`
+}
+
+// VenueComparisonService builds side by side venue comparisons
+type VenueComparisonService struct{}
+
+// Compare returns the comparison of the venues in the given order. The
+// distance of each venue is included when an origin is given.
+func (cs *VenueComparisonService) Compare(ctx context.Context, venueIDs []int64, origin *LatLng, now time.Time) (*VenueComparison, error) {
+	comparison := &VenueComparison{
+		Dimensions: make([]string, 0),
+		Venues:     make([]VenueComparisonEntry, 0, len(venueIDs)),
+		Origin:     origin,
+		Best:       make(map[string]int64),
+	}
+
+	ratings, err := models.GetDetailedRatingAverages(ctx, venueIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	popularHours, err := cs.popularHours(ctx, venueIDs, now.AddDate(0, 0, -comparisonPopularDays))
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make(map[string]bool)
+	for _, venueRatings := range ratings {
+		for dimension := range venueRatings {
+			dimensions[dimension] = true
+		}
+	}
+	for dimension := range dimensions {
+		comparison.Dimensions = append(comparison.Dimensions, dimension)
+	}
+	sort.Strings(comparison.Dimensions)
+
+	geoService := &GeolocationService{}
+	for _, venueID := range venueIDs {
+		venue := &models.Venue{ID: venueID}
+		if err := venue.GetByID(ctx); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrComparedVenueNotFound
+			}
+			return nil, err
+		}
+
+		summary, err := models.GetVenueReviewSummary(ctx, venueID)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := VenueComparisonEntry{
+			VenueID:          venue.ID,
+			Name:             venue.Name,
+			Slug:             venue.Slug,
+			CoverImage:       venue.CoverImage,
+			AverageRating:    summary.AverageRating,
+			WeightedRating:   summary.WeightedRating,
+			TotalReviews:     summary.TotalReviews,
+			Ratings:          make(map[string]*float64, len(comparison.Dimensions)),
+			PriceRange:       venue.PriceRange,
+			AvgCostPerPerson: venue.AvgCostPerPerson,
+			PopularHours:     popularHours[venueID],
+			TopAmenities:     topAmenities(venue.Amenities, comparisonTopAmenities),
+			Highlights:       reviewHighlights(summary.TopReviews, comparisonHighlights),
+		}
+		if venue.Category != nil {
+			entry.Category = venue.Category.Name
+		}
+
+		for _, dimension := range comparison.Dimensions {
+			if average, exists := ratings[venueID][dimension]; exists {
+				entry.Ratings[dimension] = &average
+			} else {
+				entry.Ratings[dimension] = nil
+			}
+		}
+
+		if entry.PopularHours == nil {
+			entry.PopularHours = make([]int, 24)
+		}
+		for hour, count := range entry.PopularHours {
+			if count > 0 && (entry.PeakHour == nil || count > entry.PopularHours[*entry.PeakHour]) {
+				peak := hour
+				entry.PeakHour = &peak
+			}
+		}
+
+		if origin != nil {
+			distance := geoService.CalculateDistance(origin.Latitude, origin.Longitude, venue.Latitude, venue.Longitude).Kilometers
+			entry.DistanceKm = &distance
+		}
+
+		comparison.Venues = append(comparison.Venues, entry)
+	}
+
+	cs.markBest(comparison)
+	return comparison, nil
+}
+
+// markBest records which venue leads on the rating, each dimension, price
+// and distance. Ties and fields no venue has a value for are left out.
+func (cs *VenueComparisonService) markBest(comparison *VenueComparison) {
+	best := func(field string, value func(entry VenueComparisonEntry) (float64, bool), higherIsBetter bool) {
+		var bestID int64
+		var bestValue float64
+		tied := false
+		for _, entry := range comparison.Venues {
+			v, ok := value(entry)
+			if !ok {
+				continue
+			}
+			better := v > bestValue
+			if !higherIsBetter {
+				better = v < bestValue
+			}
+			switch {
+			case bestID == 0 || better:
+				bestID, bestValue, tied = entry.VenueID, v, false
+			case v == bestValue:
+				tied = true
+			}
+		}
+		if bestID != 0 && !tied {
+			comparison.Best[field] = bestID
+		}
+	}
+
+	best("rating", func(entry VenueComparisonEntry) (float64, bool) {
+		return entry.WeightedRating, entry.TotalReviews > 0
+	}, true)
+
+	for _, dimension := range comparison.Dimensions {
+		dimension := dimension
+		best(dimension, func(entry VenueComparisonEntry) (float64, bool) {
+			if entry.Ratings[dimension] == nil {
+				return 0, false
+			}
+			return *entry.Ratings[dimension], true
+		}, true)
+	}
+
+	best("price", func(entry VenueComparisonEntry) (float64, bool) {
+		return float64(len(entry.PriceRange)), entry.PriceRange != ""
+	}, false)
+
+	best("distance", func(entry VenueComparisonEntry) (float64, bool) {
+		if entry.DistanceKm == nil {
+			return 0, false
+		}
+		return *entry.DistanceKm, true
+	}, false)
+}
+
+// popularHours counts the check-ins of each venue per local hour of day since
+// the given time
+func (cs *VenueComparisonService) popularHours(ctx context.Context, venueIDs []int64, since time.Time) (map[int64][]int, error) {
+	locations, err := models.GetVenueLocations(ctx, venueIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	timezones := make([]string, len(venueIDs))
+	for i, venueID := range venueIDs {
+		timezones[i] = time.UTC.String()
+		if location, exists := locations[venueID]; exists {
+			timezones[i] = location.String()
+		}
+	}
+
+	query := `
+		SELECT c.venue_id, EXTRACT(hour FROM c.created_at::timestamptz AT TIME ZONE tz.name)::int AS hour, COUNT(*)
+		FROM venue_checkins c
+		JOIN unnest($1::bigint[], $3::text[]) AS tz(venue_id, name) ON tz.venue_id = c.venue_id
+		WHERE c.created_at >= $2
+		GROUP BY c.venue_id, hour`
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, query, pq.Array(venueIDs), since, pq.Array(timezones))
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := make(map[int64][]int, len(venueIDs))
+	for rows.Next() {
+		var venueID int64
+		var hour, count int
+		if err := rows.Scan(&venueID, &hour, &count); err != nil {
+			sentry.CaptureException(err)
+			continue
+		}
+		if hours[venueID] == nil {
+			hours[venueID] = make([]int, 24)
+		}
+		hours[venueID][hour] = count
+	}
+
+	return hours, nil
+}
+
+// topAmenities returns the first amenities of a venue. Amenities are stored
+// either as a list or as an object of flags.
+func topAmenities(raw json.RawMessage, limit int) []string {
+	amenities := make([]string, 0, limit)
+	if len(raw) == 0 {
+		return amenities
+	}
+
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		if len(list) > limit {
+			list = list[:limit]
+		}
+		return append(amenities, list...)
+	}
+
+	var flags map[string]bool
+	if json.Unmarshal(raw, &flags) == nil {
+		for amenity, available := range flags {
+			if available {
+				amenities = append(amenities, amenity)
+			}
+		}
+		sort.Strings(amenities)
+		if len(amenities) > limit {
+			amenities = amenities[:limit]
+		}
+	}
+	return amenities
+}
+
+// reviewHighlights turns the most helpful reviews into short excerpts
+func reviewHighlights(reviews []models.VenueReview, limit int) []ReviewHighlight {
+	highlights := make([]ReviewHighlight, 0, limit)
+	for _, review := range reviews {
+		if len(highlights) == limit {
+			break
+		}
+		if review.ReviewText == "" {
+			continue
+		}
+
+		excerpt := []rune(review.ReviewText)
+		text := review.ReviewText
+		if len(excerpt) > comparisonExcerptChars {
+			text = string(excerpt[:comparisonExcerptChars]) + "…"
+		}
+
+		highlights = append(highlights, ReviewHighlight{
+			ReviewID:      review.ID,
+			Title:         review.Title,
+			Excerpt:       text,
+			OverallRating: review.OverallRating,
+			HelpfulVotes:  review.HelpfulVotes,
+		})
+	}
+	return highlights
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+	"voting-app/app/store"
+)
+
+// Receipt kinds
+const (
+	ReceiptCampaign = "campaign"
+	ReceiptLegacy   = "legacy"
+)
+
+// voteReceiptSecret is the HMAC key receipts are signed with
+var voteReceiptSecret []byte
+
+func init() {
+	voteReceiptSecret = []byte(config.Get().VoteReceiptSecret)
+}
+
+// VoteReceipt is a signed proof of a submitted vote
+type VoteReceipt struct {
+	Kind          string    `json:"kind"` // campaign, legacy
+	VoteID        int64     `json:"voteId"`
+	CampaignID    int64     `json:"campaignId,omitempty"`
+	CategoryID    int64     `json:"categoryId,omitempty"`
+	VotingID      int64     `json:"votingId,omitempty"`
+	VenueID       int64     `json:"venueId,omitempty"`
+	ParticipantID int64     `json:"participantId,omitempty"`
+	Votes         int       `json:"votes,omitempty"` // Effective votes of quadratic campaigns
+	Timestamp     time.Time `json:"timestamp"`
+	Signature     string    `json:"signature"`
+}
+
+// VoteReceiptService signs and verifies vote receipts
+type VoteReceiptService struct{}
+
+// CampaignReceipt returns the signed receipt of a campaign vote
+func (rs *VoteReceiptService) CampaignReceipt(vote *models.CampaignVote) *VoteReceipt {
+	receipt := &VoteReceipt{
+		Kind:       ReceiptCampaign,
+		VoteID:     vote.ID,
+		CampaignID: vote.CampaignID,
+		VenueID:    vote.VenueID,
+		Timestamp:  vote.CreatedAt.UTC(),
+	}
+	if vote.CategoryID != nil {
+		receipt.CategoryID = *vote.CategoryID
+	}
+	if vote.Votes > 1 {
+		receipt.Votes = vote.Votes
+	}
+	receipt.Signature = rs.sign(receipt)
+	return receipt
+}
+
+// UserCampaignReceipts returns the signed receipts of the user's votes in a
+// campaign, oldest first
+func (rs *VoteReceiptService) UserCampaignReceipts(ctx context.Context, campaignID, userID int64) ([]VoteReceipt, error) {
+	votes, err := store.Default.GetUserCampaignVotes(ctx, campaignID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]VoteReceipt, len(votes))
+	for i := range votes {
+		receipts[i] = *rs.CampaignReceipt(&votes[i])
+	}
+	return receipts, nil
+}
+
+// LegacyReceipt returns the signed receipt of a legacy participant vote
+func (rs *VoteReceiptService) LegacyReceipt(vote *models.UserVoting, votedAt time.Time) *VoteReceipt {
+	receipt := &VoteReceipt{
+		Kind:          ReceiptLegacy,
+		VoteID:        vote.Id,
+		VotingID:      vote.VotingId,
+		ParticipantID: vote.VoteId,
+		Timestamp:     votedAt.UTC().Truncate(time.Second),
+	}
+	receipt.Signature = rs.sign(receipt)
+	return receipt
+}
+
+// Verify checks the receipt signature
+func (rs *VoteReceiptService) Verify(receipt *VoteReceipt) bool {
+	signature, err := hex.DecodeString(receipt.Signature)
+	if err != nil {
+		return false
+	}
+
+	expected, _ := hex.DecodeString(rs.sign(receipt))
+	return hmac.Equal(signature, expected)
+}
+
+// IsRecorded reports whether the vote of a receipt is still recorded
+func (rs *VoteReceiptService) IsRecorded(ctx context.Context, receipt *VoteReceipt) (bool, error) {
+	switch receipt.Kind {
+	case ReceiptCampaign:
+		vote := &models.CampaignVote{
+			ID:         receipt.VoteID,
+			CampaignID: receipt.CampaignID,
+			VenueID:    receipt.VenueID,
+		}
+		if receipt.CategoryID != 0 {
+			vote.CategoryID = &receipt.CategoryID
+		}
+		if receipt.Votes > 1 {
+			vote.Votes = receipt.Votes
+		}
+		return vote.Exists(ctx)
+	case ReceiptLegacy:
+		vote := &models.UserVoting{
+			Id:       receipt.VoteID,
+			VotingId: receipt.VotingID,
+			VoteId:   receipt.ParticipantID,
+		}
+		return vote.Exists(ctx), nil
+	default:
+		return false, nil
+	}
+}
+
+// sign returns the hex HMAC-SHA256 of the receipt fields
+func (rs *VoteReceiptService) sign(receipt *VoteReceipt) string {
+	payload := fmt.Sprintf("v1|%s|%d|%d|%d|%d|%d|%s",
+		receipt.Kind, receipt.VoteID,
+		receipt.CampaignID, receipt.VotingID,
+		receipt.VenueID, receipt.ParticipantID,
+		receipt.Timestamp.UTC().Format(time.RFC3339Nano),
+	)
+	// Appended only when set so receipts issued before categories still verify
+	if receipt.CategoryID != 0 {
+		payload += fmt.Sprintf("|%d", receipt.CategoryID)
+	}
+	if receipt.Votes > 1 {
+		payload += fmt.Sprintf("|votes=%d", receipt.Votes)
+	}
+
+	mac := hmac.New(sha256.New, voteReceiptSecret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
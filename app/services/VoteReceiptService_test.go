@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"voting-app/app/fixtures"
+	"voting-app/app/models"
+	"voting-app/app/store"
+	"voting-app/app/store/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserCampaignReceipts(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	data := fixtures.Seed(s)
+	campaignID := data.Campaigns[0].ID
+
+	defer func(previous store.Store) { store.Default = previous }(store.Default)
+	store.Default = s
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, venue := range data.Venues {
+		castAt := now.Add(time.Duration(i) * time.Minute)
+		s.SetClock(func() time.Time { return castAt })
+		vote := models.CampaignVote{CampaignID: campaignID, VenueID: venue.ID, UserID: fixtures.UserID}
+		require.NoError(t, s.CreateCampaignVote(ctx, &vote))
+	}
+	other := models.CampaignVote{CampaignID: campaignID, VenueID: data.Venues[0].ID, UserID: fixtures.UserID + 1}
+	require.NoError(t, s.CreateCampaignVote(ctx, &other))
+
+	receiptService := &VoteReceiptService{}
+	receipts, err := receiptService.UserCampaignReceipts(ctx, campaignID, fixtures.UserID)
+	require.NoError(t, err)
+	require.Len(t, receipts, 2)
+	for i, receipt := range receipts {
+		assert.Equal(t, ReceiptCampaign, receipt.Kind)
+		assert.Equal(t, campaignID, receipt.CampaignID)
+		assert.Equal(t, data.Venues[i].ID, receipt.VenueID)
+		assert.True(t, receiptService.Verify(&receipt))
+	}
+
+	// A changed receipt no longer verifies
+	forged := receipts[0]
+	forged.VenueID = data.Venues[1].ID
+	assert.False(t, receiptService.Verify(&forged))
+
+	receipts, err = receiptService.UserCampaignReceipts(ctx, data.Campaigns[1].ID, fixtures.UserID)
+	require.NoError(t, err)
+	assert.Empty(t, receipts)
+}
+
+// failingStore fails every campaign vote lookup
+type failingStore struct {
+	store.Store
+}
+
+func (failingStore) GetUserCampaignVotes(ctx context.Context, campaignID, userID int64) ([]models.CampaignVote, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestUserCampaignReceiptsStoreError(t *testing.T) {
+	defer func(previous store.Store) { store.Default = previous }(store.Default)
+	store.Default = failingStore{}
+
+	receiptService := &VoteReceiptService{}
+	_, err := receiptService.UserCampaignReceipts(context.Background(), 1, fixtures.UserID)
+	assert.EqualError(t, err, "store unavailable")
+}
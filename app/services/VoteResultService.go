@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"voting-app/app/models"
+)
+
+// voteCountsTTL bounds how long cached vote counts are served. Votes
+// invalidate the counts of their voting at once, the TTL only catches votes
+// submitted through other instances.
+const voteCountsTTL = time.Minute
+
+// voteCounts caches the vote counts of legacy votings by voting ID
+var voteCounts = struct {
+	sync.RWMutex
+	votings map[int64]cachedVoteCounts
+}{votings: make(map[int64]cachedVoteCounts)}
+
+type cachedVoteCounts struct {
+	counts   map[int64]int64
+	loadedAt time.Time
+}
+
+// ParticipantResult is a participant's share of the votes of a voting
+type ParticipantResult struct {
+	ParticipantID int64   `json:"participantId"`
+	Name          string  `json:"name"`
+	Photo         string  `json:"photoUrl"`
+	Votes         int64   `json:"votes"`
+	Percentage    float64 `json:"percentage"` // Of all votes, one decimal
+	IsWinner      bool    `json:"isWinner,omitempty"`
+}
+
+// VotingResults are the live totals of a legacy voting
+type VotingResults struct {
+	Voting     models.Voting       `json:"voting"`
+	TotalVotes int64               `json:"totalVotes"`
+	Results    []ParticipantResult `json:"results"`
+	UserVote   *int64              `json:"userVote"` // Participant the user voted for
+	IsFinal    bool                `json:"isFinal"`
+}
+
+// VoteResultService computes the results of legacy votings
+type VoteResultService struct{}
+
+// GetResults returns the results of the voting, most votes first, with the
+// participant the user voted for. sql.ErrNoRows when the voting doesn't
+// exist.
+func (vr *VoteResultService) GetResults(ctx context.Context, votingID, userID int64) (*VotingResults, error) {
+	voting := models.Voting{Id: votingID}
+	if err := voting.Get(ctx); err != nil {
+		return nil, err
+	}
+
+	counts, err := vr.getVoteCounts(ctx, votingID)
+	if err != nil {
+		return nil, err
+	}
+
+	userVoting := models.UserVoting{VotingId: votingID, OwnerId: userID}
+	voted, err := userVoting.GetUserVote(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &VotingResults{
+		Voting:  voting,
+		IsFinal: !time.Now().UTC().Before(voting.EndedAt),
+	}
+	if voted {
+		results.UserVote = &userVoting.VoteId
+	}
+	var participant models.Participant
+	results.Results, results.TotalVotes = participantResults(voting, counts, participant.All(ctx))
+	return results, nil
+}
+
+// InvalidateResults drops the cached vote counts of the voting, called when
+// a vote is submitted
+func (vr *VoteResultService) InvalidateResults(votingID int64) {
+	voteCounts.Lock()
+	delete(voteCounts.votings, votingID)
+	voteCounts.Unlock()
+}
+
+// getVoteCounts returns the cached vote counts of the voting, loading them
+// when missing or stale
+func (vr *VoteResultService) getVoteCounts(ctx context.Context, votingID int64) (map[int64]int64, error) {
+	voteCounts.RLock()
+	cached, exists := voteCounts.votings[votingID]
+	voteCounts.RUnlock()
+	if exists && time.Since(cached.loadedAt) < voteCountsTTL {
+		return cached.counts, nil
+	}
+
+	counts, err := models.GetVotingVoteCounts(ctx, votingID)
+	if err != nil {
+		return nil, err
+	}
+
+	voteCounts.Lock()
+	voteCounts.votings[votingID] = cachedVoteCounts{counts: counts, loadedAt: time.Now()}
+	voteCounts.Unlock()
+	return counts, nil
+}
+
+// participantResults returns the participants' shares of the voting's votes,
+// most votes first, and the total number of votes. Inactive participants
+// only show up when they were voted for.
+func participantResults(voting models.Voting, counts map[int64]int64, participants []models.Participant) ([]ParticipantResult, int64) {
+	var totalVotes int64
+	for _, votes := range counts {
+		totalVotes += votes
+	}
+
+	results := make([]ParticipantResult, 0)
+	for _, p := range participants {
+		votes := counts[p.Id]
+		if !p.IsActive && votes == 0 {
+			continue
+		}
+
+		result := ParticipantResult{
+			ParticipantID: p.Id,
+			Name:          p.Name,
+			Photo:         p.Photo,
+			Votes:         votes,
+			IsWinner:      voting.WinnerId != 0 && voting.WinnerId == p.Id,
+		}
+		if totalVotes > 0 {
+			result.Percentage = math.Round(float64(votes)*1000/float64(totalVotes)) / 10
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Votes != results[j].Votes {
+			return results[i].Votes > results[j].Votes
+		}
+		return results[i].ParticipantID < results[j].ParticipantID
+	})
+	return results, totalVotes
+}
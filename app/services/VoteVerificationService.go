@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"voting-app/app/models"
+)
+
+// VoteCodeTTL is how long the code confirming a held vote can be used
+const VoteCodeTTL = 10 * time.Minute
+
+// ErrNoVerifiedContact is returned when a voter has no verified email to
+// send a vote code to
+var ErrNoVerifiedContact = errors.New("voter has no verified email")
+
+// HeldVote tells a voter where the code confirming their vote went
+type HeldVote struct {
+	SentTo    string    `json:"sentTo"` // The masked email
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// VoteVerificationService holds the votes of campaigns requiring one-time
+// codes until the voter confirms them. Codes go to the verified email of the
+// account linked to the snapp user, snapp users having no contact of their
+// own.
+type VoteVerificationService struct{}
+
+// Hold stores the vote request and emails its code to the voter,
+// ErrNoVerifiedContact when they have no verified email and
+// models.ErrVoteCodeResendTooSoon when their last code was just sent
+func (vs *VoteVerificationService) Hold(ctx context.Context, campaign *models.VotingCampaign, snappUserID int64, vote []byte) (*HeldVote, error) {
+	email, err := models.GetVerifiedEmailBySnappUser(ctx, snappUserID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoVerifiedContact
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+	pending := &models.PendingCampaignVote{
+		CampaignID: campaign.ID,
+		UserID:     snappUserID,
+		Vote:       vote,
+		CodeHash:   hashVerificationCode(code),
+		ExpiresAt:  time.Now().UTC().Add(VoteCodeTTL),
+	}
+	if err := pending.Create(ctx); err != nil {
+		return nil, err
+	}
+
+	err = AppMailer.Send(email, "Confirm your vote",
+		fmt.Sprintf("Your code to confirm your vote in %s is %s. It expires in %d minutes, ignore this email if you didn't vote.",
+			campaign.Title, code, int(VoteCodeTTL.Minutes())))
+	if err != nil {
+		return nil, err
+	}
+
+	return &HeldVote{SentTo: maskEmail(email), ExpiresAt: pending.ExpiresAt}, nil
+}
+
+// Confirm returns the held vote request when the code is the one sent,
+// models.ErrInvalidVoteCode otherwise
+func (vs *VoteVerificationService) Confirm(ctx context.Context, campaignID, snappUserID int64, code string) ([]byte, error) {
+	return models.ConfirmPendingCampaignVote(ctx, campaignID, snappUserID, hashVerificationCode(code), time.Now().UTC())
+}
+
+// maskEmail hides the local part of the email but its first letter, like
+// j***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
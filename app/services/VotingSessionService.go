@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"voting-app/app/models"
+)
+
+const (
+	// VotingSessionTTL is how long a kiosk voter has to cast their votes
+	VotingSessionTTL = 10 * time.Minute
+	// KioskDeviceSessionsPerHour caps the sessions one kiosk opens
+	KioskDeviceSessionsPerHour = 120
+	// KioskDeviceVotesPerHour caps the votes cast through one kiosk in a
+	// campaign, about what a queue of voters can get through
+	KioskDeviceVotesPerHour = 240
+	// votingSessionRetention is how long expired sessions are kept so their
+	// votes still count against the kiosk limit
+	votingSessionRetention = 24 * time.Hour
+)
+
+// Voting session errors
+var (
+	ErrInvalidVotingSession = errors.New("voting session is invalid or expired")
+	ErrInvalidKiosk         = errors.New("kiosk credential is invalid or revoked")
+	ErrKioskDeviceLimit     = errors.New("kiosk device is over its hourly limit")
+)
+
+// VotingSessionService opens anonymous voting sessions for event kiosks
+type VotingSessionService struct{}
+
+// IssueKiosk issues a credential letting a kiosk open sessions in the
+// campaign, returning the kiosk and its credential. Only the hash of the
+// credential is stored.
+func (vs *VotingSessionService) IssueKiosk(ctx context.Context, campaignID int64, name string, issuedBy int64) (*models.KioskDevice, string, error) {
+	token, err := newUserToken()
+	if err != nil {
+		return nil, "", err
+	}
+	kiosk := &models.KioskDevice{
+		CampaignID: campaignID,
+		Name:       name,
+		TokenHash:  hashUserToken(token),
+		CreatedBy:  issuedBy,
+	}
+	if err := kiosk.Create(ctx); err != nil {
+		return nil, "", err
+	}
+	return kiosk, token, nil
+}
+
+// Create opens a session for the campaign on the kiosk holding the
+// credential, returning the session and its token. Returns ErrInvalidKiosk
+// when the credential wasn't issued for the campaign or was revoked.
+func (vs *VotingSessionService) Create(ctx context.Context, campaignID int64, kioskToken string) (*models.VotingSession, string, error) {
+	kiosk, err := models.GetKioskDevice(ctx, campaignID, hashUserToken(kioskToken))
+	if err == sql.ErrNoRows {
+		return nil, "", ErrInvalidKiosk
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	opened, err := models.CountKioskVotingSessions(ctx, kiosk.ID, time.Hour)
+	if err != nil {
+		return nil, "", err
+	}
+	if opened >= KioskDeviceSessionsPerHour {
+		return nil, "", ErrKioskDeviceLimit
+	}
+
+	token, err := newUserToken()
+	if err != nil {
+		return nil, "", err
+	}
+	session := &models.VotingSession{
+		CampaignID: campaignID,
+		TokenHash:  hashUserToken(token),
+		KioskID:    kiosk.ID,
+		ExpiresAt:  time.Now().UTC().Add(VotingSessionTTL),
+	}
+	if err := session.Create(ctx); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// Get returns the unexpired session of the token, ErrInvalidVotingSession
+// when there is none
+func (vs *VotingSessionService) Get(ctx context.Context, token string) (*models.VotingSession, error) {
+	session, err := models.GetVotingSession(ctx, hashUserToken(token), time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidVotingSession
+	}
+	return session, err
+}
+
+// CheckDeviceLimit returns ErrKioskDeviceLimit when the session's kiosk
+// already cast its hourly share of votes in the campaign
+func (vs *VotingSessionService) CheckDeviceLimit(ctx context.Context, session *models.VotingSession) error {
+	votes, err := models.CountKioskCampaignVotes(ctx, session.CampaignID, session.KioskID, time.Hour)
+	if err != nil {
+		return err
+	}
+	if votes >= KioskDeviceVotesPerHour {
+		return ErrKioskDeviceLimit
+	}
+	return nil
+}
+
+// ExpireVotingSessions removes the sessions expired for a day
+func (vs *VotingSessionService) ExpireVotingSessions(ctx context.Context) error {
+	_, err := models.DeleteExpiredVotingSessions(ctx, time.Now().UTC().Add(-votingSessionRetention))
+	return err
+}
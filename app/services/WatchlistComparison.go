@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+)
+
+// WatchlistComparison compares the weekly views, ratings and reviews of a
+// venue with the competitors on its watchlist
+type WatchlistComparison struct {
+	VenueID     int64                 `json:"venueId"`
+	Weeks       []string              `json:"weeks"` // Week start dates, oldest first
+	Venue       WatchlistVenueTrend   `json:"venue"`
+	Competitors []WatchlistVenueTrend `json:"competitors"`
+	// CompetitorAverage averages the competitors week by week
+	CompetitorAverage []WeeklyVenueMetrics `json:"competitorAverage"`
+}
+
+// WatchlistVenueTrend holds one venue's weekly metrics, in the order of
+// WatchlistComparison.Weeks
+type WatchlistVenueTrend struct {
+	VenueID       int64                `json:"venueId"`
+	Name          string               `json:"name"`
+	AverageRating float64              `json:"averageRating"`
+	Weekly        []WeeklyVenueMetrics `json:"weekly"`
+	// RatingChange is the average rating of the reviews of the last week
+	// with reviews minus that of the first, nil with fewer than two such weeks
+	RatingChange *float64 `json:"ratingChange,omitempty"`
+}
+
+// WeeklyVenueMetrics are a venue's metrics of one week
+type WeeklyVenueMetrics struct {
+	WeekStart string   `json:"weekStart"`
+	Views     float64  `json:"views"`
+	Reviews   float64  `json:"reviews"`
+	Rating    *float64 `json:"rating,omitempty"` // Average rating of the week's reviews
+}
+
+// GetWatchlistComparison compares the venue with its watchlist over the
+// given number of weeks, the current week included
+func (as *AnalyticsService) GetWatchlistComparison(ctx context.Context, venue *models.Venue, weeks int) (*WatchlistComparison, error) {
+	competitors, err := models.GetWatchlist(ctx, venue.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	venueIDs := []int64{venue.ID}
+	trends := map[int64]*WatchlistVenueTrend{
+		venue.ID: {VenueID: venue.ID, Name: venue.Name, AverageRating: venue.AverageRating},
+	}
+	for _, competitor := range competitors {
+		venueIDs = append(venueIDs, competitor.VenueID)
+		trends[competitor.VenueID] = &WatchlistVenueTrend{
+			VenueID:       competitor.VenueID,
+			Name:          competitor.Name,
+			AverageRating: competitor.AverageRating,
+		}
+	}
+
+	rows, err := databases.PostgresDB.QueryContext(ctx, `
+		WITH weeks AS (
+			SELECT generate_series(
+				date_trunc('week', CURRENT_DATE) - ($2::int - 1) * INTERVAL '1 week',
+				date_trunc('week', CURRENT_DATE),
+				INTERVAL '1 week'
+			)::date AS week_start
+		)
+		SELECT v.id, w.week_start,
+			   COALESCE((
+				   SELECT SUM(a.profile_views) FROM venue_analytics a
+				   WHERE a.venue_id = v.id AND a.date >= w.week_start AND a.date < w.week_start + 7
+			   ), 0),
+			   r.reviews, r.rating
+		FROM unnest($1::bigint[]) AS v(id)
+		CROSS JOIN weeks w
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS reviews, AVG(overall_rating) AS rating
+			FROM venue_reviews
+			WHERE venue_id = v.id AND moderation_status = 'approved'
+			  AND created_at >= w.week_start AND created_at < w.week_start + 7
+		) r
+		ORDER BY v.id, w.week_start`,
+		pq.Array(venueIDs), weeks)
+	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	comparison := &WatchlistComparison{VenueID: venue.ID, Weeks: []string{}}
+	for rows.Next() {
+		var venueID int64
+		var weekStart time.Time
+		var metrics WeeklyVenueMetrics
+		var rating sql.NullFloat64
+		if err := rows.Scan(&venueID, &weekStart, &metrics.Views, &metrics.Reviews, &rating); err != nil {
+			sentry.CaptureException(err)
+			return nil, err
+		}
+		metrics.WeekStart = weekStart.Format("2006-01-02")
+		if rating.Valid {
+			metrics.Rating = &rating.Float64
+		}
+		if venueID == venue.ID {
+			comparison.Weeks = append(comparison.Weeks, metrics.WeekStart)
+		}
+		trends[venueID].Weekly = append(trends[venueID].Weekly, metrics)
+	}
+	if err := rows.Err(); err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	for _, trend := range trends {
+		trend.RatingChange = ratingChange(trend.Weekly)
+	}
+	comparison.Venue = *trends[venue.ID]
+	comparison.Competitors = make([]WatchlistVenueTrend, 0, len(competitors))
+	for _, competitor := range competitors {
+		comparison.Competitors = append(comparison.Competitors, *trends[competitor.VenueID])
+	}
+	comparison.CompetitorAverage = averageWeeklyMetrics(comparison.Weeks, comparison.Competitors)
+	return comparison, nil
+}
+
+// ratingChange is the rating of the last week with reviews minus that of
+// the first
+func ratingChange(weekly []WeeklyVenueMetrics) *float64 {
+	var first, last *float64
+	rated := 0
+	for _, week := range weekly {
+		if week.Rating == nil {
+			continue
+		}
+		if first == nil {
+			first = week.Rating
+		}
+		last = week.Rating
+		rated++
+	}
+	if rated < 2 {
+		return nil
+	}
+	change := *last - *first
+	return &change
+}
+
+// averageWeeklyMetrics averages the venues' metrics week by week. The rating
+// is averaged over the venues with reviews that week.
+func averageWeeklyMetrics(weeks []string, trends []WatchlistVenueTrend) []WeeklyVenueMetrics {
+	average := make([]WeeklyVenueMetrics, len(weeks))
+	if len(trends) == 0 {
+		return average[:0]
+	}
+
+	for i, weekStart := range weeks {
+		average[i].WeekStart = weekStart
+		var ratingSum float64
+		rated := 0
+		for _, trend := range trends {
+			week := trend.Weekly[i]
+			average[i].Views += week.Views / float64(len(trends))
+			average[i].Reviews += week.Reviews / float64(len(trends))
+			if week.Rating != nil {
+				ratingSum += *week.Rating
+				rated++
+			}
+		}
+		if rated > 0 {
+			rating := ratingSum / float64(rated)
+			average[i].Rating = &rating
+		}
+	}
+	return average
+}
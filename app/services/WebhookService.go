@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Webhook delivery settings. Failed attempts are retried with exponential
+// backoff: 30s, 1m, 2m, ... until WebhookMaxAttempts is reached.
+const (
+	WebhookMaxAttempts    = 8
+	WebhookRetryBaseDelay = 30 * time.Second
+	webhookDeliveryLease  = 2 * time.Minute
+	webhookDeliveryBatch  = 100
+)
+
+// ErrWebhookTargetNotPublic is returned for webhook targets that don't
+// resolve to public addresses. Loopback, link-local and private addresses
+// would let subscribers reach internal services and cloud metadata.
+var ErrWebhookTargetNotPublic = errors.New("webhook target must resolve to public addresses")
+
+// AllowPrivateWebhookTargets lets webhooks reach any address, for local
+// development and tests
+var AllowPrivateWebhookTargets = false
+
+// ResolveWebhookHost resolves the hosts of webhook targets, replaced in
+// tests to run without DNS
+var ResolveWebhookHost = net.DefaultResolver.LookupIPAddr
+
+// webhookDeniedNetworks are the ranges that aren't public besides those the
+// net.IP checks know: shared carrier-grade NAT, IETF protocol assignments,
+// benchmarking, documentation, reserved and NAT64 addresses, which can
+// translate to private ones
+var webhookDeniedNetworks = parseCIDRs(
+	"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4",
+	"64:ff9b::/96", "64:ff9b:1::/48", "100::/64", "2001:db8::/32",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// webhookHTTPClient checks every address it connects to, so targets can't
+// reach private addresses through DNS changes after registration or through
+// redirects
+var webhookHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if !webhookAddressAllowed(net.ParseIP(host)) {
+					return ErrWebhookTargetNotPublic
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// CheckTarget resolves the host of the target URL, ErrWebhookTargetNotPublic
+// when it can't be resolved or any of its addresses isn't public
+func (ws *WebhookService) CheckTarget(ctx context.Context, targetURL string) error {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return err
+	}
+	addresses, err := ResolveWebhookHost(ctx, target.Hostname())
+	if err != nil || len(addresses) == 0 {
+		return ErrWebhookTargetNotPublic
+	}
+	for _, address := range addresses {
+		if !webhookAddressAllowed(address.IP) {
+			return ErrWebhookTargetNotPublic
+		}
+	}
+	return nil
+}
+
+// webhookAddressAllowed tells whether webhooks may be delivered to the address
+func webhookAddressAllowed(ip net.IP) bool {
+	if AllowPrivateWebhookTargets {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	// IPv4-mapped IPv6 addresses are checked as the IPv4 address they map
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range webhookDeniedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookEvent is the JSON body posted to subscribers
+type WebhookEvent struct {
+	ID        int64       `json:"id"` // Delivery ID, stable across retries
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService queues events for webhook subscribers and delivers them
+type WebhookService struct{}
+
+// Publish queues the event for every subscription to it and starts
+// delivering in the background. venueID scopes the event to a venue.
+func (ws *WebhookService) Publish(ctx context.Context, eventType string, venueID *int64, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	queued, err := models.CreateWebhookDeliveries(ctx, eventType, venueID, payload)
+	if err != nil {
+		return err
+	}
+
+	if queued > 0 {
+		// Delivered in the background, the caller's context may end first
+		go func() {
+			if err := ws.DeliverPending(context.Background()); err != nil {
+				sentry.CaptureException(err)
+			}
+		}()
+	}
+	return nil
+}
+
+// DeliverPending attempts every delivery that is due. It is run
+// periodically by the job runner to retry failed attempts.
+func (ws *WebhookService) DeliverPending(ctx context.Context) error {
+	for {
+		deliveries, err := models.ClaimDueWebhookDeliveries(ctx, webhookDeliveryLease, webhookDeliveryBatch)
+		if err != nil {
+			return err
+		}
+
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ws.Deliver(ctx, &deliveries[i])
+		}
+
+		if len(deliveries) < webhookDeliveryBatch {
+			return nil
+		}
+	}
+}
+
+// Deliver posts the delivery to its subscription and records the attempt,
+// scheduling a retry when it failed
+func (ws *WebhookService) Deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	subscription := &models.WebhookSubscription{ID: delivery.SubscriptionID}
+	if err := subscription.GetByID(ctx); err != nil {
+		return
+	}
+	if !subscription.IsActive {
+		delivery.RecordAttempt(ctx, nil, "subscription disabled", false, 0)
+		return
+	}
+
+	var statusCode *int
+	attemptErr := ""
+	secret, err := subscription.GetSecret(ctx)
+	if err == nil {
+		statusCode, err = ws.post(ctx, subscription.TargetURL, secret, delivery)
+	}
+	if err != nil {
+		attemptErr = err.Error()
+	}
+
+	succeeded := err == nil
+	retryIn := time.Duration(0)
+	if !succeeded && delivery.EventType != models.WebhookEventTest && delivery.Attempts+1 < WebhookMaxAttempts {
+		retryIn = WebhookRetryDelay(delivery.Attempts + 1)
+	}
+
+	delivery.RecordAttempt(ctx, statusCode, attemptErr, succeeded, retryIn)
+}
+
+// SendTest delivers a webhook.test event to the subscription right away.
+// Test events are not retried.
+func (ws *WebhookService) SendTest(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookDelivery, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"subscriptionId": subscription.ID,
+		"message":        "This is a test event",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventType:      models.WebhookEventTest,
+		Payload:        payload,
+	}
+	if err := delivery.Create(ctx); err != nil {
+		return nil, err
+	}
+
+	ws.Deliver(ctx, delivery)
+	return delivery, nil
+}
+
+// WebhookRetryDelay returns how long to wait after the given failed attempt
+func WebhookRetryDelay(attempt int) time.Duration {
+	return WebhookRetryBaseDelay * time.Duration(1<<uint(attempt-1))
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.body" that is
+// sent in the X-Webhook-Signature header
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends a single attempt. Any non-2xx response is a failure.
+func (ws *WebhookService) post(ctx context.Context, targetURL, secret string, delivery *models.WebhookDelivery) (*int, error) {
+	body, err := json.Marshal(WebhookEvent{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt.UTC(),
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(secret, timestamp, body))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode >= 300 {
+		return &statusCode, fmt.Errorf("webhook: unexpected status %d", statusCode)
+	}
+	return &statusCode, nil
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"os"
+	"voting-app/app/config"
+)
+
+// The init functions of the package read the configuration. Package
+// variables are initialized before them, so unit tests set a configuration
+// of their own here instead of needing the environment.
+var _ = setUnitTestConfig()
+
+func setUnitTestConfig() bool {
+	config.Set(&config.Config{
+		VoteReceiptSecret: "unit-test-receipt-secret",
+		Storage: config.StorageConfig{
+			Backend:       config.StorageLocal,
+			LocalDir:      os.TempDir(),
+			SigningSecret: "unit-test-storage-secret",
+		},
+	})
+	return true
+}
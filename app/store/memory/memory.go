@@ -0,0 +1,501 @@
+// Package memory is an in-memory implementation of the stores for unit
+// tests. It follows the behaviour of the Postgres queries closely enough
+// for service logic, but ignores what needs PostGIS or other tables: the
+// radius filter of venue searches, blocked users and review invites.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/store"
+)
+
+// Store keeps venues, reviews, campaigns and campaign votes in maps. The
+// zero value is not usable, create stores with New.
+type Store struct {
+	mu sync.RWMutex
+
+	venues        map[int64]models.Venue
+	reviews       map[int64]models.VenueReview
+	campaigns     map[int64]models.VotingCampaign
+	campaignVotes map[int64]models.CampaignVote
+
+	lastID int64
+	now    func() time.Time
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		venues:        make(map[int64]models.Venue),
+		reviews:       make(map[int64]models.VenueReview),
+		campaigns:     make(map[int64]models.VotingCampaign),
+		campaignVotes: make(map[int64]models.CampaignVote),
+		now:           time.Now,
+	}
+}
+
+// SetClock replaces the clock used for created and updated times
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// nextID returns an ID above every ID used so far, including seeded ones
+func (s *Store) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *Store) useID(id int64) int64 {
+	if id == 0 {
+		return s.nextID()
+	}
+	if id > s.lastID {
+		s.lastID = id
+	}
+	return id
+}
+
+// SeedVenues stores venues as given, assigning IDs to those without one, and
+// returns them with their IDs
+func (s *Store) SeedVenues(venues ...models.Venue) []models.Venue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seeded := make([]models.Venue, len(venues))
+	for i, venue := range venues {
+		venue.ID = s.useID(venue.ID)
+		s.venues[venue.ID] = venue
+		seeded[i] = venue
+	}
+	return seeded
+}
+
+// SeedReviews stores reviews as given, assigning IDs to those without one, and
+// returns them with their IDs
+func (s *Store) SeedReviews(reviews ...models.VenueReview) []models.VenueReview {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seeded := make([]models.VenueReview, len(reviews))
+	for i, review := range reviews {
+		review.ID = s.useID(review.ID)
+		s.reviews[review.ID] = review
+		seeded[i] = review
+	}
+	return seeded
+}
+
+// SeedCampaigns stores campaigns as given, assigning IDs to those without one, and
+// returns them with their IDs
+func (s *Store) SeedCampaigns(campaigns ...models.VotingCampaign) []models.VotingCampaign {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seeded := make([]models.VotingCampaign, len(campaigns))
+	for i, campaign := range campaigns {
+		campaign.ID = s.useID(campaign.ID)
+		s.campaigns[campaign.ID] = campaign
+		seeded[i] = campaign
+	}
+	return seeded
+}
+
+func (s *Store) GetVenue(ctx context.Context, id int64) (*models.Venue, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	venue, exists := s.venues[id]
+	if !exists || !venue.IsActive {
+		return nil, sql.ErrNoRows
+	}
+	return &venue, nil
+}
+
+func (s *Store) SearchVenues(ctx context.Context, params models.VenueSearchParams) ([]models.Venue, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := strings.ToLower(params.Query)
+	matches := make([]models.Venue, 0)
+	for _, venue := range s.venues {
+		switch {
+		case !venue.IsActive,
+			query != "" && !strings.Contains(strings.ToLower(venue.Name), query) &&
+				!strings.Contains(strings.ToLower(venue.Description), query),
+			params.CategoryID != nil && venue.CategoryID != *params.CategoryID,
+			params.CityID != nil && venue.CityID != *params.CityID,
+			params.NeighborhoodID != nil && (venue.NeighborhoodID == nil || *venue.NeighborhoodID != *params.NeighborhoodID),
+			params.MinRating != nil && venue.AverageRating < *params.MinRating,
+			len(params.PriceRange) > 0 && !contains(params.PriceRange, venue.PriceRange),
+			params.IsFeatured != nil && *params.IsFeatured && !venue.IsFeatured,
+			params.CreatedAfter != nil && !venue.CreatedAt.After(*params.CreatedAfter):
+			continue
+		}
+		matches = append(matches, venue)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch params.SortBy {
+		case "newest":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+		case "rating", "distance":
+			if a.AverageRating != b.AverageRating {
+				return a.AverageRating > b.AverageRating
+			}
+		default:
+			if a.IsFeatured != b.IsFeatured {
+				return a.IsFeatured
+			}
+			if a.AverageRating != b.AverageRating {
+				return a.AverageRating > b.AverageRating
+			}
+		}
+		if a.TotalRatings != b.TotalRatings {
+			return a.TotalRatings > b.TotalRatings
+		}
+		return a.ID < b.ID
+	})
+
+	start, end := pageBounds(len(matches), params.Page, params.Limit)
+	return matches[start:end], len(matches), nil
+}
+
+func (s *Store) CreateVenue(ctx context.Context, venue *models.Venue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Slugs are made unique like Postgres does, without the slug history
+	base := venue.Slug
+	if base == "" {
+		base = "venue"
+	}
+	venue.Slug = base
+	for suffix := 2; s.slugTaken(venue.Slug); suffix++ {
+		venue.Slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+
+	venue.ID = s.nextID()
+	venue.IsActive = true
+	venue.CreatedAt = s.now()
+	venue.UpdatedAt = venue.CreatedAt
+	s.venues[venue.ID] = *venue
+	return nil
+}
+
+func (s *Store) slugTaken(slug string) bool {
+	for _, venue := range s.venues {
+		if venue.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) GetReview(ctx context.Context, id int64) (*models.VenueReview, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	review, exists := s.reviews[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	review.VenueName = s.venues[review.VenueID].Name
+	return &review, nil
+}
+
+func (s *Store) SearchReviews(ctx context.Context, filters models.ReviewFilters) ([]models.VenueReview, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.searchReviews(filters)
+}
+
+func (s *Store) searchReviews(filters models.ReviewFilters) ([]models.VenueReview, int, error) {
+	matches := make([]models.VenueReview, 0)
+	for _, review := range s.reviews {
+		switch {
+		case review.ModerationStatus != "approved",
+			filters.VenueID != nil && review.VenueID != *filters.VenueID,
+			filters.UserID != nil && review.UserID != *filters.UserID,
+			filters.MinRating != nil && review.OverallRating < *filters.MinRating,
+			filters.MaxRating != nil && review.OverallRating > *filters.MaxRating,
+			filters.VisitType != "" && review.VisitType != filters.VisitType,
+			filters.HasPhotos != nil && *filters.HasPhotos && len(review.Photos) == 0,
+			filters.IsFeatured != nil && review.IsFeatured != *filters.IsFeatured,
+			filters.DateFrom != nil && review.CreatedAt.Before(*filters.DateFrom),
+			filters.DateTo != nil && review.CreatedAt.After(*filters.DateTo):
+			continue
+		}
+		review.VenueName = s.venues[review.VenueID].Name
+		matches = append(matches, review)
+	}
+
+	now := s.now()
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch filters.SortBy {
+		case "oldest":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		case "rating_high":
+			if a.OverallRating != b.OverallRating {
+				return a.OverallRating > b.OverallRating
+			}
+		case "rating_low":
+			if a.OverallRating != b.OverallRating {
+				return a.OverallRating < b.OverallRating
+			}
+		case "helpful":
+			scoreA := models.HelpfulnessScore(a.HelpfulVotes, a.UnhelpfulVotes, a.CreatedAt, now)
+			scoreB := models.HelpfulnessScore(b.HelpfulVotes, b.UnhelpfulVotes, b.CreatedAt, now)
+			if scoreA != scoreB {
+				return scoreA > scoreB
+			}
+			if a.HelpfulVotes != b.HelpfulVotes {
+				return a.HelpfulVotes > b.HelpfulVotes
+			}
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	start, end := pageBounds(len(matches), filters.Page, filters.Limit)
+	return matches[start:end], len(matches), nil
+}
+
+func (s *Store) CreateReview(ctx context.Context, review *models.VenueReview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hasUserReviewedVenue(review.VenueID, review.UserID) {
+		return fmt.Errorf("user has already reviewed this venue")
+	}
+	if review.OverallRating < 1.0 || review.OverallRating > 5.0 {
+		return fmt.Errorf("rating must be between 1.0 and 5.0")
+	}
+
+	review.ID = s.nextID()
+	review.ModerationStatus = "pending"
+	review.CreatedAt = s.now()
+	review.UpdatedAt = review.CreatedAt
+	s.reviews[review.ID] = *review
+	return nil
+}
+
+func (s *Store) HasUserReviewedVenue(ctx context.Context, venueID, userID int64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hasUserReviewedVenue(venueID, userID), nil
+}
+
+func (s *Store) hasUserReviewedVenue(venueID, userID int64) bool {
+	for _, review := range s.reviews {
+		if review.VenueID == venueID && review.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) GetVenueReviewSummary(ctx context.Context, venueID int64) (*models.ReviewSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := &models.ReviewSummary{
+		VenueID:         venueID,
+		RatingBreakdown: map[string]int{"5": 0, "4": 0, "3": 0, "2": 0, "1": 0},
+		DetailedAverage: make(map[string]float64),
+	}
+
+	var total, weightedTotal, weights float64
+	for _, review := range s.reviews {
+		if review.VenueID != venueID || review.ModerationStatus != "approved" {
+			continue
+		}
+
+		weight := 1.0
+		if review.IsVerified {
+			weight = models.VerifiedReviewWeight
+			summary.VerifiedReviews++
+		}
+		summary.TotalReviews++
+		total += review.OverallRating
+		weightedTotal += review.OverallRating * weight
+		weights += weight
+
+		switch rating := review.OverallRating; {
+		case rating >= 4.5:
+			summary.RatingBreakdown["5"]++
+		case rating >= 3.5:
+			summary.RatingBreakdown["4"]++
+		case rating >= 2.5:
+			summary.RatingBreakdown["3"]++
+		case rating >= 1.5:
+			summary.RatingBreakdown["2"]++
+		default:
+			summary.RatingBreakdown["1"]++
+		}
+	}
+	if summary.TotalReviews > 0 {
+		summary.AverageRating = total / float64(summary.TotalReviews)
+		summary.WeightedRating = weightedTotal / weights
+	}
+
+	summary.RecentReviews, _, _ = s.searchReviews(models.ReviewFilters{VenueID: &venueID, SortBy: "newest", Limit: 5, Page: 1})
+	summary.TopReviews, _, _ = s.searchReviews(models.ReviewFilters{VenueID: &venueID, SortBy: "helpful", Limit: 3, Page: 1})
+
+	return summary, nil
+}
+
+func (s *Store) GetCampaign(ctx context.Context, id int64) (*models.VotingCampaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	campaign, exists := s.campaigns[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return &campaign, nil
+}
+
+func (s *Store) CreateCampaignVote(ctx context.Context, vote *models.CampaignVote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[vote.CampaignID]
+	if !exists {
+		return fmt.Errorf("campaign %d does not exist", vote.CampaignID)
+	}
+
+	if err := s.checkVoteLimits(vote); err != nil {
+		return err
+	}
+
+	for _, existing := range s.campaignVotes {
+		if existing.CampaignID == vote.CampaignID && existing.VenueID == vote.VenueID &&
+			existing.UserID == vote.UserID && sameCategory(existing.CategoryID, vote.CategoryID) {
+			return models.ErrCampaignVoteExists
+		}
+	}
+
+	if vote.Votes == 0 {
+		vote.Votes = 1
+	}
+	vote.ID = s.nextID()
+	vote.CreatedAt = s.now()
+	vote.VenueName = s.venues[vote.VenueID].Name
+	s.campaignVotes[vote.ID] = *vote
+
+	campaign.TotalVotes += vote.Votes
+	campaign.UpdatedAt = vote.CreatedAt
+	s.campaigns[campaign.ID] = campaign
+	return nil
+}
+
+// checkVoteLimits applies the MaxVotes and SingleCategory limits of the vote
+// to the voter's earlier votes, like the Postgres query does
+func (s *Store) checkVoteLimits(vote *models.CampaignVote) error {
+	cast := 0
+	otherCategory := false
+	for _, existing := range s.campaignVotes {
+		if existing.CampaignID != vote.CampaignID {
+			continue
+		}
+		if vote.VotingSessionID != nil {
+			if existing.VotingSessionID == nil || *existing.VotingSessionID != *vote.VotingSessionID {
+				continue
+			}
+		} else if existing.UserID != vote.UserID {
+			continue
+		}
+
+		if vote.CategoryID == nil || sameCategory(existing.CategoryID, vote.CategoryID) {
+			cast++
+		}
+		if vote.CategoryID != nil && existing.CategoryID != nil && *existing.CategoryID != *vote.CategoryID {
+			otherCategory = true
+		}
+	}
+
+	if vote.SingleCategory && otherCategory {
+		return models.ErrOtherCategoryVoted
+	}
+	if vote.MaxVotes > 0 && cast >= vote.MaxVotes {
+		return models.ErrVoteLimitReached
+	}
+	return nil
+}
+
+func (s *Store) CountUserCampaignVotes(ctx context.Context, campaignID, userID int64) (int, error) {
+	votes, _ := s.GetUserCampaignVotes(ctx, campaignID, userID)
+	return len(votes), nil
+}
+
+func (s *Store) GetUserCampaignVotes(ctx context.Context, campaignID, userID int64) ([]models.CampaignVote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	votes := make([]models.CampaignVote, 0)
+	for _, vote := range s.campaignVotes {
+		if vote.CampaignID == campaignID && vote.UserID == userID {
+			votes = append(votes, vote)
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		if !votes[i].CreatedAt.Equal(votes[j].CreatedAt) {
+			return votes[i].CreatedAt.Before(votes[j].CreatedAt)
+		}
+		return votes[i].ID < votes[j].ID
+	})
+	return votes, nil
+}
+
+// pageBounds returns the slice bounds of a page of total items, with the
+// page and limit defaults of the Postgres queries
+func pageBounds(total, page, limit int) (int, int) {
+	if limit == 0 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sameCategory(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,161 @@
+package memory_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+	"voting-app/app/fixtures"
+	"voting-app/app/models"
+	"voting-app/app/store/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVenues(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	data := fixtures.Seed(s)
+
+	venue, err := s.GetVenue(ctx, data.Venues[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, data.Venues[0].Name, venue.Name)
+
+	inactive := s.SeedVenues(fixtures.Venue(func(v *models.Venue) { v.IsActive = false }))
+	_, err = s.GetVenue(ctx, inactive[0].ID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	venues, total, err := s.SearchVenues(ctx, models.VenueSearchParams{PriceRange: []string{"$$$"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, venues, 1)
+	assert.Equal(t, data.Venues[1].ID, venues[0].ID)
+
+	// Pages past the end are empty but keep the total
+	venues, total, err = s.SearchVenues(ctx, models.VenueSearchParams{Page: 2, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Empty(t, venues)
+
+	created := fixtures.Venue()
+	require.NoError(t, s.CreateVenue(ctx, &created))
+	assert.Greater(t, created.ID, inactive[0].ID)
+
+	// Taken slugs get a numeric suffix
+	duplicate := fixtures.Venue(func(v *models.Venue) { v.Slug = created.Slug })
+	require.NoError(t, s.CreateVenue(ctx, &duplicate))
+	assert.Equal(t, created.Slug+"-2", duplicate.Slug)
+}
+
+func TestReviews(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	data := fixtures.Seed(s)
+	venueID := data.Venues[0].ID
+
+	summary, err := s.GetVenueReviewSummary(ctx, venueID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.TotalReviews)
+	assert.InDelta(t, 4.5, summary.AverageRating, 0.001)
+	assert.Equal(t, 1, summary.RatingBreakdown["5"])
+	assert.Equal(t, 1, summary.RatingBreakdown["4"])
+
+	// New reviews wait for moderation and can't be repeated
+	review := fixtures.Review(venueID, 3, func(r *models.VenueReview) { r.OverallRating = 1 })
+	require.NoError(t, s.CreateReview(ctx, &review))
+	assert.Equal(t, "pending", review.ModerationStatus)
+	assert.Error(t, s.CreateReview(ctx, &review))
+
+	reviews, total, err := s.SearchReviews(ctx, models.ReviewFilters{VenueID: &venueID, SortBy: "rating_low"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 4.0, reviews[0].OverallRating)
+
+	reviewed, err := s.HasUserReviewedVenue(ctx, venueID, 3)
+	require.NoError(t, err)
+	assert.True(t, reviewed)
+
+	invalid := fixtures.Review(venueID, 4, func(r *models.VenueReview) { r.OverallRating = 6 })
+	assert.Error(t, s.CreateReview(ctx, &invalid))
+}
+
+func TestHelpfulReviews(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	venueID := s.SeedVenues(fixtures.Venue())[0].ID
+	now := time.Now()
+	s.SetClock(func() time.Time { return now })
+
+	review := func(helpful, unhelpful int, age time.Duration) models.VenueReview {
+		return fixtures.Review(venueID, fixtures.UserID, func(r *models.VenueReview) {
+			r.HelpfulVotes, r.UnhelpfulVotes, r.CreatedAt = helpful, unhelpful, now.Add(-age)
+		})
+	}
+	seeded := s.SeedReviews(
+		review(40, 2, 4*365*24*time.Hour), // Old favourite
+		review(12, 1, 7*24*time.Hour),
+		review(1, 0, 24*time.Hour), // Too few votes to tell
+		review(9, 9, 2*24*time.Hour),
+	)
+
+	reviews, _, err := s.SearchReviews(ctx, models.ReviewFilters{VenueID: &venueID, SortBy: "helpful"})
+	require.NoError(t, err)
+	require.Len(t, reviews, 4)
+	ids := []int64{reviews[0].ID, reviews[1].ID, reviews[2].ID, reviews[3].ID}
+	assert.Equal(t, []int64{seeded[1].ID, seeded[3].ID, seeded[2].ID, seeded[0].ID}, ids)
+
+	summary, err := s.GetVenueReviewSummary(ctx, venueID)
+	require.NoError(t, err)
+	require.Len(t, summary.TopReviews, 3)
+	assert.Equal(t, seeded[1].ID, summary.TopReviews[0].ID)
+
+	assert.Zero(t, models.HelpfulnessScore(0, 0, now, now))
+	assert.InDelta(t, models.HelpfulnessScore(10, 0, now, now)/2,
+		models.HelpfulnessScore(10, 0, now.Add(-models.HelpfulnessHalfLife), now), 1e-9)
+}
+
+func TestCampaignVotes(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	data := fixtures.Seed(s)
+	campaignID := data.Campaigns[0].ID
+
+	campaign, err := s.GetCampaign(ctx, campaignID)
+	require.NoError(t, err)
+	assert.True(t, campaign.IsOpen(campaign.StartDate))
+
+	closed, err := s.GetCampaign(ctx, data.Campaigns[1].ID)
+	require.NoError(t, err)
+	assert.False(t, closed.IsOpen(closed.EndDate))
+
+	vote := models.CampaignVote{CampaignID: campaignID, VenueID: data.Venues[0].ID, UserID: fixtures.UserID}
+	require.NoError(t, s.CreateCampaignVote(ctx, &vote))
+	assert.Equal(t, 1, vote.Votes)
+
+	repeated := vote
+	assert.Equal(t, models.ErrCampaignVoteExists, s.CreateCampaignVote(ctx, &repeated))
+
+	count, err := s.CountUserCampaignVotes(ctx, campaignID, fixtures.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	campaign, err = s.GetCampaign(ctx, campaignID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, campaign.TotalVotes)
+
+	// The limits count the voter's earlier votes
+	limited := models.CampaignVote{CampaignID: campaignID, VenueID: data.Venues[1].ID, UserID: fixtures.UserID, MaxVotes: 1}
+	assert.Equal(t, models.ErrVoteLimitReached, s.CreateCampaignVote(ctx, &limited))
+	limited.UserID = fixtures.UserID + 1
+	require.NoError(t, s.CreateCampaignVote(ctx, &limited))
+
+	first, second := int64(1), int64(2)
+	categorized := models.CampaignVote{CampaignID: campaignID, CategoryID: &first, VenueID: data.Venues[0].ID, UserID: fixtures.UserID + 2}
+	require.NoError(t, s.CreateCampaignVote(ctx, &categorized))
+	other := models.CampaignVote{CampaignID: campaignID, CategoryID: &second, VenueID: data.Venues[1].ID, UserID: fixtures.UserID + 2, SingleCategory: true}
+	assert.Equal(t, models.ErrOtherCategoryVoted, s.CreateCampaignVote(ctx, &other))
+
+	_, err = s.GetCampaign(ctx, 999)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,110 @@
+// Package store describes the persistence the services depend on, so they
+// can run against Postgres in production and against the in-memory store of
+// package memory in unit tests.
+package store
+
+import (
+	"context"
+	"voting-app/app/models"
+)
+
+// VenueStore reads and writes venues
+type VenueStore interface {
+	// GetVenue returns sql.ErrNoRows when the venue doesn't exist
+	GetVenue(ctx context.Context, id int64) (*models.Venue, error)
+	SearchVenues(ctx context.Context, params models.VenueSearchParams) ([]models.Venue, int, error)
+	CreateVenue(ctx context.Context, venue *models.Venue) error
+}
+
+// ReviewStore reads and writes venue reviews
+type ReviewStore interface {
+	// GetReview returns sql.ErrNoRows when the review doesn't exist
+	GetReview(ctx context.Context, id int64) (*models.VenueReview, error)
+	SearchReviews(ctx context.Context, filters models.ReviewFilters) ([]models.VenueReview, int, error)
+	CreateReview(ctx context.Context, review *models.VenueReview) error
+	HasUserReviewedVenue(ctx context.Context, venueID, userID int64) (bool, error)
+	GetVenueReviewSummary(ctx context.Context, venueID int64) (*models.ReviewSummary, error)
+}
+
+// CampaignStore reads voting campaigns and records campaign votes
+type CampaignStore interface {
+	// GetCampaign returns sql.ErrNoRows when the campaign doesn't exist
+	GetCampaign(ctx context.Context, id int64) (*models.VotingCampaign, error)
+	// CreateCampaignVote returns models.ErrCampaignVoteExists for a repeated vote
+	CreateCampaignVote(ctx context.Context, vote *models.CampaignVote) error
+	CountUserCampaignVotes(ctx context.Context, campaignID, userID int64) (int, error)
+	GetUserCampaignVotes(ctx context.Context, campaignID, userID int64) ([]models.CampaignVote, error)
+}
+
+// Store combines every store
+type Store interface {
+	VenueStore
+	ReviewStore
+	CampaignStore
+}
+
+// Default is the store used by the application
+var Default Store = Postgres{}
+
+// Postgres implements Store with the models' queries
+type Postgres struct{}
+
+func (Postgres) GetVenue(ctx context.Context, id int64) (*models.Venue, error) {
+	venue := &models.Venue{ID: id}
+	if err := venue.GetByID(ctx); err != nil {
+		return nil, err
+	}
+	return venue, nil
+}
+
+func (Postgres) SearchVenues(ctx context.Context, params models.VenueSearchParams) ([]models.Venue, int, error) {
+	return new(models.Venue).Search(ctx, params)
+}
+
+func (Postgres) CreateVenue(ctx context.Context, venue *models.Venue) error {
+	return venue.Create(ctx)
+}
+
+func (Postgres) GetReview(ctx context.Context, id int64) (*models.VenueReview, error) {
+	review := &models.VenueReview{ID: id}
+	if err := review.GetByID(ctx); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (Postgres) SearchReviews(ctx context.Context, filters models.ReviewFilters) ([]models.VenueReview, int, error) {
+	return new(models.VenueReview).Search(ctx, filters)
+}
+
+func (Postgres) CreateReview(ctx context.Context, review *models.VenueReview) error {
+	return review.Create(ctx)
+}
+
+func (Postgres) HasUserReviewedVenue(ctx context.Context, venueID, userID int64) (bool, error) {
+	return models.HasUserReviewedVenue(ctx, venueID, userID)
+}
+
+func (Postgres) GetVenueReviewSummary(ctx context.Context, venueID int64) (*models.ReviewSummary, error) {
+	return models.GetVenueReviewSummary(ctx, venueID)
+}
+
+func (Postgres) GetCampaign(ctx context.Context, id int64) (*models.VotingCampaign, error) {
+	campaign := &models.VotingCampaign{ID: id}
+	if err := campaign.GetByID(ctx); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (Postgres) CreateCampaignVote(ctx context.Context, vote *models.CampaignVote) error {
+	return vote.Create(ctx)
+}
+
+func (Postgres) CountUserCampaignVotes(ctx context.Context, campaignID, userID int64) (int, error) {
+	return models.CountUserCampaignVotes(ctx, campaignID, userID)
+}
+
+func (Postgres) GetUserCampaignVotes(ctx context.Context, campaignID, userID int64) ([]models.CampaignVote, error) {
+	return models.GetUserCampaignVotes(ctx, campaignID, userID)
+}
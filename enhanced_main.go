@@ -57,8 +57,8 @@ func apiHandlerEnhanced() {
 				// Venue management (requires authentication)
 				venueRoutes.Use(middlewares.AuthorizeJWT())
 				venueRoutes.POST("/", venueController.CreateVenue)
-				// venueRoutes.PUT("/:id", venueController.UpdateVenue)
-				// venueRoutes.DELETE("/:id", venueController.DeleteVenue)
+				venueRoutes.PUT("/:id", venueController.UpdateVenue)
+				venueRoutes.DELETE("/:id", venueController.DeleteVenue)
 				// venueRoutes.POST("/:id/claim", venueController.ClaimVenue)
 			}
 
@@ -15,6 +15,14 @@ CREATE TABLE venue_categories (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Detailed rating dimensions of each category (bars: drinks, music, vibe),
+-- reviews of categories without one can rate any dimension
+CREATE TABLE rating_templates (
+    category_id BIGINT PRIMARY KEY REFERENCES venue_categories(id) ON DELETE CASCADE,
+    dimensions JSONB NOT NULL, -- [{"key": "food", "label": "Food"}]
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Venue Subcategories (Italian Restaurant, Sports Bar, Coffee Shop, etc.)
 CREATE TABLE venue_subcategories (
     id BIGSERIAL PRIMARY KEY,
@@ -243,11 +251,30 @@ CREATE TABLE venue_checkins (
     
     -- Social Features
     is_public BOOLEAN DEFAULT true,
+    is_flagged BOOLEAN DEFAULT false, -- Message flagged by the content filter, kept out of public listings
     tagged_users JSONB, -- Array of user IDs
     
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Public check-ins listed on venue pages
+CREATE INDEX idx_checkins_venue_public ON venue_checkins(venue_id, created_at DESC) WHERE is_public = true;
+
+-- Wait times and crowd levels reported at check-in. Reports of the last hour
+-- make up a venue's live busyness; reports are aged out after a day.
+CREATE TABLE venue_wait_reports (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id),
+    checkin_id BIGINT REFERENCES venue_checkins(id) ON DELETE SET NULL,
+    wait_minutes INTEGER CHECK (wait_minutes >= 0),
+    crowd_level VARCHAR(20), -- quiet, moderate, busy, packed
+    reported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_wait_reports_venue ON venue_wait_reports(venue_id, reported_at DESC);
+CREATE INDEX idx_wait_reports_reported ON venue_wait_reports(reported_at);
+
 -- User Followers/Following for social features
 CREATE TABLE user_follows (
     id BIGSERIAL PRIMARY KEY,
@@ -308,6 +335,23 @@ CREATE TABLE campaign_votes (
     UNIQUE(campaign_id, user_id, venue_id) -- Prevent duplicate votes
 );
 
+-- Date ranges campaigns are featured for, scheduled ahead by operators. The
+-- highest priority promotion running also fills the legacy vote banner.
+CREATE TABLE campaign_promotions (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0, -- Higher priorities are featured first
+    banner_image VARCHAR,
+    banner_link VARCHAR,
+    created_by BIGINT, -- Admin user that scheduled the promotion
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_campaign_promotions_range ON campaign_promotions(starts_at, ends_at);
+
 -- ===============================
 -- ANALYTICS & INSIGHTS
 -- ===============================
@@ -382,3 +426,1180 @@ CREATE INDEX idx_venues_city ON venues(city_id);
 -- Analytics indexes
 CREATE INDEX idx_venue_analytics_date ON venue_analytics(venue_id, date DESC);
 CREATE INDEX idx_search_analytics_user ON search_analytics(user_id, created_at DESC);
+CREATE INDEX idx_search_analytics_created ON search_analytics(created_at); -- Trending queries and autocomplete
+
+-- ===============================
+-- NOTIFICATIONS
+-- ===============================
+
+-- In-app notifications (campaign starts, review replies, badges, ...)
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES snapp_users(id),
+    event_type VARCHAR(50) NOT NULL, -- "campaign_start", "review_reply", "badge"
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    data JSONB, -- Deep-link payload for clients
+    is_read BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Push notification device tokens (APNs / FCM)
+CREATE TABLE user_devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES snapp_users(id),
+    platform VARCHAR(20) NOT NULL, -- "apns", "fcm"
+    token VARCHAR(512) NOT NULL UNIQUE,
+    app_version VARCHAR(50),
+    is_active BOOLEAN DEFAULT true,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_user_devices_user ON user_devices(user_id) WHERE is_active = true;
+
+-- Set once the opening of a campaign is announced to its audience
+ALTER TABLE voting_campaigns ADD COLUMN start_notified_at TIMESTAMP;
+
+-- ===============================
+-- PLATFORM STATS ROLLUPS
+-- ===============================
+
+-- Hourly and daily platform counters maintained by the rollup job, so platform
+-- metrics don't need COUNT(*) over whole tables on every request
+CREATE TABLE platform_stats_rollups (
+    period VARCHAR(10) NOT NULL, -- "hour", "day"
+    period_start TIMESTAMP NOT NULL,
+    new_venues INTEGER DEFAULT 0,
+    new_users INTEGER DEFAULT 0,
+    new_reviews INTEGER DEFAULT 0,
+    new_checkins INTEGER DEFAULT 0,
+    rating_sum DECIMAL(14,2) DEFAULT 0, -- Sum of overall_rating of new reviews
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (period, period_start)
+);
+
+-- Last row id rolled up per source table
+CREATE TABLE platform_stats_watermarks (
+    source_table VARCHAR(50) PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    pending_id BIGINT NOT NULL DEFAULT 0,
+    pending_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Days on which a daily platform metric deviated sharply from the days
+-- before it
+CREATE TABLE metric_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    metric VARCHAR(20) NOT NULL,
+    day DATE NOT NULL,
+    value DECIMAL(14,2) NOT NULL,
+    mean DECIMAL(14,2) NOT NULL,
+    std_dev DECIMAL(14,2) NOT NULL,
+    z_score DECIMAL(8,2) NOT NULL,
+    direction VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (metric, day)
+);
+
+CREATE INDEX idx_metric_alerts_day ON metric_alerts(day DESC);
+
+-- Sessions of client events: runs of a client's events with no gap longer
+-- than 30 minutes
+CREATE TABLE client_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    page_views INTEGER NOT NULL DEFAULT 0,
+    event_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_client_sessions_client ON client_sessions(client_id, ended_at DESC);
+CREATE INDEX idx_client_sessions_started ON client_sessions(started_at);
+
+-- Events reported by the apps, identified by the app install or browser.
+-- session_id is set by the sessionization job.
+CREATE TABLE client_events (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(20) NOT NULL, -- page_view, screen_view, click, search, share
+    path VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    session_id BIGINT REFERENCES client_sessions(id) ON DELETE CASCADE,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_client_events_unsessionized ON client_events(client_id, occurred_at) WHERE session_id IS NULL;
+CREATE INDEX idx_client_events_session ON client_events(session_id);
+
+-- ===============================
+-- VENUE MENUS
+-- ===============================
+
+-- Menus published by venue owners (e.g. "Lunch", "Drinks")
+CREATE TABLE venue_menus (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    currency VARCHAR(3) DEFAULT 'USD',
+    is_active BOOLEAN DEFAULT true,
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE menu_sections (
+    id BIGSERIAL PRIMARY KEY,
+    menu_id BIGINT REFERENCES venue_menus(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE menu_items (
+    id BIGSERIAL PRIMARY KEY,
+    section_id BIGINT REFERENCES menu_sections(id) ON DELETE CASCADE,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE, -- Denormalized for dish search
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price DECIMAL(10,2),
+    dietary_tags JSONB DEFAULT '[]', -- ["vegan", "halal", "gluten-free"]
+    is_available BOOLEAN DEFAULT true,
+    sort_order INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_menus_venue ON venue_menus(venue_id);
+CREATE INDEX idx_menu_sections_menu ON menu_sections(menu_id);
+CREATE INDEX idx_menu_items_venue ON menu_items(venue_id);
+CREATE INDEX idx_menu_items_name ON menu_items USING GIN(to_tsvector('english', name));
+CREATE INDEX idx_menu_items_dietary ON menu_items USING GIN(dietary_tags);
+
+-- ===============================
+-- SAVED SEARCHES
+-- ===============================
+
+-- Venue searches users get alerted about when new venues match
+CREATE TABLE saved_searches (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES snapp_users(id),
+    name VARCHAR(100) NOT NULL,
+    params JSONB NOT NULL, -- Venue search query and filters
+    alerts_enabled BOOLEAN DEFAULT true,
+    last_evaluated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Venues created after this are new matches
+    last_notified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Venues matched by a saved search, unread until the user views them
+CREATE TABLE saved_search_matches (
+    saved_search_id BIGINT REFERENCES saved_searches(id) ON DELETE CASCADE,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    is_read BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, venue_id)
+);
+
+CREATE INDEX idx_saved_searches_user ON saved_searches(user_id);
+CREATE INDEX idx_saved_searches_alerts ON saved_searches(alerts_enabled) WHERE alerts_enabled = true;
+CREATE INDEX idx_saved_search_matches_unread ON saved_search_matches(saved_search_id) WHERE is_read = false;
+
+-- ===============================
+-- USER BLOCKS AND MUTES
+-- ===============================
+
+-- Blocked users are hidden from the blocker and cannot follow or reply to them
+CREATE TABLE user_blocks (
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    target_user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target_user_id)
+);
+
+-- Muted users are hidden from the muting user but can still interact
+CREATE TABLE user_mutes (
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    target_user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target_user_id)
+);
+
+CREATE INDEX idx_user_blocks_target ON user_blocks(target_user_id);
+
+-- ===============================
+-- REVIEW INVITES
+-- ===============================
+
+-- Single-use tokens handed out by venues, reviews written with one are verified visits
+CREATE TABLE review_invites (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_by BIGINT, -- Admin user that generated the invite
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    review_id BIGINT REFERENCES venue_reviews(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_invites_venue ON review_invites(venue_id);
+
+-- ===============================
+-- CAMPAIGN CATEGORIES
+-- ===============================
+
+-- Award categories of umbrella campaigns ("Best Brunch" in "Best of the City"), each with its own winner
+CREATE TABLE campaign_categories (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    description TEXT,
+    venue_category_id BIGINT REFERENCES venue_categories(id), -- Restricts the eligible venues
+    sort_order INTEGER DEFAULT 0,
+    winner_venue_id BIGINT REFERENCES venues(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(campaign_id, slug)
+);
+
+-- Votes of campaigns with categories are scoped to one of them
+ALTER TABLE campaign_votes ADD COLUMN campaign_category_id BIGINT REFERENCES campaign_categories(id) ON DELETE CASCADE;
+ALTER TABLE campaign_votes DROP CONSTRAINT campaign_votes_campaign_id_user_id_venue_id_key;
+CREATE UNIQUE INDEX idx_campaign_votes_unique ON campaign_votes(campaign_id, user_id, COALESCE(campaign_category_id, 0), venue_id);
+
+-- Set once the winners of a closed campaign are recorded
+ALTER TABLE voting_campaigns ADD COLUMN results_finalized_at TIMESTAMP;
+
+-- Periodic copies of the campaign standings, the last one of a closed campaign is final
+CREATE TABLE campaign_result_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    is_final BOOLEAN DEFAULT false,
+    total_votes INTEGER DEFAULT 0,
+    results JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_campaign_result_snapshots_campaign ON campaign_result_snapshots(campaign_id, created_at DESC);
+
+-- ===============================
+-- QUADRATIC VOTING
+-- ===============================
+
+-- Quadratic campaigns give every user a credit budget, n votes for a venue cost n² credits
+ALTER TABLE voting_campaigns ADD COLUMN voting_mode VARCHAR(20) NOT NULL DEFAULT 'standard'; -- "standard", "quadratic"
+ALTER TABLE voting_campaigns ADD COLUMN credit_budget INTEGER; -- Defaults to 100 in quadratic campaigns
+
+ALTER TABLE campaign_votes ADD COLUMN vote_count INTEGER NOT NULL DEFAULT 1; -- Effective votes
+ALTER TABLE campaign_votes ADD COLUMN credits_spent INTEGER NOT NULL DEFAULT 0;
+
+-- Credits spent per user and campaign, debited together with the vote
+CREATE TABLE campaign_credit_balances (
+    campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    credits_spent INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, user_id)
+);
+
+-- ===============================
+-- MODERATION
+-- ===============================
+
+-- Owner requests to claim a venue, resolved by a moderator
+CREATE TABLE venue_claims (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users(id),
+    status VARCHAR(20) DEFAULT 'pending', -- pending, approved, rejected
+    evidence TEXT,
+    reviewed_by BIGINT REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_claims_pending ON venue_claims(created_at) WHERE status = 'pending';
+
+-- Who resolved a review or a flagged campaign vote, for moderator throughput
+ALTER TABLE venue_reviews ADD COLUMN flagged_at TIMESTAMP;
+ALTER TABLE venue_reviews ADD COLUMN moderated_by BIGINT REFERENCES users(id);
+ALTER TABLE venue_reviews ADD COLUMN moderated_at TIMESTAMP;
+
+ALTER TABLE campaign_votes ADD COLUMN is_flagged BOOLEAN DEFAULT false;
+ALTER TABLE campaign_votes ADD COLUMN flagged_at TIMESTAMP;
+ALTER TABLE campaign_votes ADD COLUMN moderated_by BIGINT REFERENCES users(id);
+ALTER TABLE campaign_votes ADD COLUMN moderated_at TIMESTAMP;
+
+CREATE INDEX idx_venue_reviews_pending ON venue_reviews(created_at) WHERE moderation_status = 'pending';
+
+-- ===============================
+-- WEBHOOKS
+-- ===============================
+
+-- Partner endpoints notified of events. Venue subscriptions only receive
+-- the events of their venue, global ones are managed by administrators.
+CREATE TABLE webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id),
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    target_url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL, -- review.created, campaign.results_finalized
+    secret VARCHAR(100) NOT NULL, -- HMAC key of the X-Webhook-Signature header
+    is_active BOOLEAN DEFAULT true,
+    disabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_subscriptions_venue ON webhook_subscriptions(venue_id) WHERE is_active = true;
+
+-- Delivery log, failed attempts are retried with exponential backoff
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending', -- pending, delivered, failed
+    attempts INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT LOCALTIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
+
+-- ===============================
+-- REVIEW DRAFTS
+-- ===============================
+
+-- Autosaved unfinished reviews, deleted when the review is published
+CREATE TABLE review_drafts (
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    overall_rating DECIMAL(3,2) CHECK (overall_rating >= 1.0 AND overall_rating <= 5.0),
+    detailed_ratings JSONB,
+    title VARCHAR(255),
+    review_text TEXT,
+    visit_date DATE,
+    visit_type VARCHAR(50),
+    party_size INTEGER,
+    photos JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, venue_id)
+);
+
+-- ===============================
+-- VENUE CITY CORRECTIONS
+-- ===============================
+
+-- Venues placed in another city than submitted because their coordinates
+-- are nearer to it
+CREATE TABLE venue_city_corrections (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    claimed_city_id BIGINT REFERENCES cities(id),
+    assigned_city_id BIGINT REFERENCES cities(id),
+    distance_km DECIMAL(8,2),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_city_corrections_venue ON venue_city_corrections(venue_id);
+
+-- ===============================
+-- PHOTOS
+-- ===============================
+
+-- Uploaded photos, stored without EXIF data, with the layout hints clients
+-- show before the photo loads
+CREATE TABLE photos (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL,
+    url VARCHAR(500) NOT NULL UNIQUE,
+    object_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    dominant_color VARCHAR(7) NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_photos_user ON photos(user_id);
+
+-- ===============================
+-- NEIGHBORHOODS
+-- ===============================
+
+-- Named areas of a city, outlined by a polygon of longitude/latitude points
+CREATE TABLE neighborhoods (
+    id BIGSERIAL PRIMARY KEY,
+    city_id BIGINT REFERENCES cities(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    boundary geometry(Polygon, 4326) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(city_id, slug)
+);
+
+CREATE INDEX idx_neighborhoods_boundary ON neighborhoods USING GIST (boundary);
+
+ALTER TABLE venues ADD COLUMN neighborhood_id BIGINT REFERENCES neighborhoods(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_venues_neighborhood ON venues(neighborhood_id);
+
+-- ===============================
+-- RECOMMENDATION FEEDBACK
+-- ===============================
+
+-- Users' answers to venue recommendations. Signals are the scoring signals
+-- the venue was recommended for, the feedback tunes their weights.
+CREATE TABLE recommendation_feedback (
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    feedback VARCHAR(20) NOT NULL, -- not_interested, been_there
+    signals TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, venue_id)
+);
+
+CREATE INDEX idx_recommendation_feedback_created ON recommendation_feedback(created_at);
+
+-- ===============================
+-- FEATURE FLAGS
+-- ===============================
+
+-- Flags gating features being rolled out. An enabled flag is on for the
+-- listed users and for rollout_percentage percent of all users.
+CREATE TABLE feature_flags (
+    id BIGSERIAL PRIMARY KEY,
+    key VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    rollout_percentage INTEGER NOT NULL DEFAULT 0 CHECK (rollout_percentage BETWEEN 0 AND 100),
+    user_ids BIGINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===============================
+-- REVIEW TRANSLATIONS
+-- ===============================
+
+-- Cached translations of reviews, replaced once the review is edited after
+-- translated_at
+CREATE TABLE review_translations (
+    review_id BIGINT REFERENCES venue_reviews(id) ON DELETE CASCADE,
+    language VARCHAR(10) NOT NULL,
+    source_language VARCHAR(10),
+    title TEXT,
+    review_text TEXT,
+    provider VARCHAR(50) NOT NULL,
+    translated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (review_id, language)
+);
+
+-- Generated summaries of venues' approved reviews, regenerated once their
+-- count or reviews_changed_at no longer match the reviews
+CREATE TABLE venue_review_summaries (
+    venue_id BIGINT PRIMARY KEY REFERENCES venues(id) ON DELETE CASCADE,
+    summary TEXT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    review_count INTEGER NOT NULL,
+    reviews_changed_at TIMESTAMP,
+    generated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===============================
+-- ACCOUNT LINKS
+-- ===============================
+
+-- JWT users linked to the snapp user they verified they are
+CREATE TABLE account_links (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    snapp_user_id BIGINT NOT NULL UNIQUE REFERENCES snapp_users(id) ON DELETE CASCADE,
+    linked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pending links, confirmed with the code sent to the snapp user
+CREATE TABLE account_link_requests (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    snapp_user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL, -- SHA-256 of the code
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===============================
+-- VENUE SLUG HISTORY
+-- ===============================
+
+-- Slugs venues used before being renamed, so old links keep resolving. A
+-- slug is either a venue's current slug or in its history, never both.
+CREATE TABLE venue_slug_history (
+    slug VARCHAR(255) PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_slug_history_venue ON venue_slug_history(venue_id);
+
+-- ===============================
+-- SIMILAR VENUES
+-- ===============================
+
+-- Venues similar to each venue, most similar first, recomputed nightly
+CREATE TABLE venue_similar (
+    venue_id BIGINT PRIMARY KEY REFERENCES venues(id) ON DELETE CASCADE,
+    similar_venue_ids BIGINT[] NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_similar_computed ON venue_similar(computed_at);
+
+-- ===============================
+-- ACCOUNT EMAILS
+-- ===============================
+
+-- Set once the user opened the verification link, accounts from before
+-- verification existed count as verified
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+UPDATE users SET email_verified_at = created_at;
+
+-- Single use email verification and password reset tokens, only their
+-- SHA-256 is stored
+CREATE TABLE user_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL, -- verify_email, reset_password
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_tokens_user ON user_tokens(user_id, purpose, created_at DESC);
+CREATE INDEX idx_user_tokens_expires ON user_tokens(expires_at);
+
+-- ===============================
+-- COMPETITOR WATCHLISTS
+-- ===============================
+
+-- Competitor venues an owner follows for their venue, at most five
+CREATE TABLE venue_watchlist (
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    competitor_venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (venue_id, competitor_venue_id),
+    CHECK (venue_id <> competitor_venue_id)
+);
+
+-- ===============================
+-- REVIEW EXPORTS
+-- ===============================
+
+-- Review exports of venues too large to stream, generated in the background
+-- and stored in the review-exports bucket
+CREATE TABLE review_exports (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    requested_by BIGINT NOT NULL REFERENCES users(id),
+    format VARCHAR(10) NOT NULL, -- csv or json
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processing, completed, failed
+    object_key VARCHAR(255),
+    review_count INTEGER,
+    error TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_exports_venue ON review_exports(venue_id, created_at DESC);
+CREATE INDEX idx_review_exports_status ON review_exports(status, created_at) WHERE status IN ('pending', 'processing');
+
+-- ===============================
+-- KIOSK VOTING SESSIONS
+-- ===============================
+
+-- Campaigns event kiosks may open anonymous voting sessions in
+ALTER TABLE voting_campaigns ADD COLUMN allow_kiosk_sessions BOOLEAN NOT NULL DEFAULT false;
+
+-- Kiosks an administrator issued a credential to for one campaign. Only the
+-- SHA-256 hash of the credential is stored, revoked kiosks open no sessions.
+CREATE TABLE kiosk_devices (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_kiosk_devices_campaign ON kiosk_devices(campaign_id, created_at DESC);
+
+-- Short lived anonymous sessions voting in one campaign from an event kiosk.
+-- Only the SHA-256 hash of the token is stored.
+CREATE TABLE voting_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    kiosk_id BIGINT NOT NULL REFERENCES kiosk_devices(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_voting_sessions_kiosk ON voting_sessions(kiosk_id, created_at DESC);
+CREATE INDEX idx_voting_sessions_expires ON voting_sessions(expires_at);
+
+-- Votes of a voting session have no user
+ALTER TABLE campaign_votes ADD COLUMN voting_session_id BIGINT REFERENCES voting_sessions(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX idx_campaign_votes_session_unique ON campaign_votes(campaign_id, voting_session_id, COALESCE(campaign_category_id, 0), venue_id)
+    WHERE voting_session_id IS NOT NULL;
+CREATE INDEX idx_campaign_votes_session ON campaign_votes(voting_session_id);
+
+-- ===============================
+-- CAMPAIGN NOMINEES
+-- ===============================
+
+-- Venues voters can choose from in a campaign. Campaigns with nominees only
+-- take votes for them. Generated "best of" campaigns keep the candidate score,
+-- venues nominated by hand have none.
+CREATE TABLE campaign_nominees (
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    score DECIMAL(6,3),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, venue_id)
+);
+
+CREATE INDEX idx_campaign_nominees_venue ON campaign_nominees(venue_id);
+
+-- ===============================
+-- VENUE HOURS EXCEPTIONS
+-- ===============================
+
+-- Dated exceptions to a venue's regular opening hours, like closing for a
+-- holiday or opening longer for an event. A date's exception replaces the
+-- regular hours of its weekday. Times are HH:MM local to the venue's city.
+CREATE TABLE venue_hours_exceptions (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    is_closed BOOLEAN NOT NULL DEFAULT false,
+    open_time VARCHAR(5),
+    close_time VARCHAR(5),
+    reason VARCHAR(255),
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(venue_id, date),
+    CHECK (is_closed OR (open_time IS NOT NULL AND close_time IS NOT NULL))
+);
+
+-- ===============================
+-- TENANTS
+-- ===============================
+
+-- City brands hosted on the platform. Requests are for the tenant named by
+-- the X-Tenant header or claiming the request's host, else the default
+-- tenant, which owns everything from before tenants existed.
+CREATE TABLE tenants (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    hostnames TEXT[] NOT NULL DEFAULT '{}',
+    branding JSONB NOT NULL DEFAULT '{}', -- appName, logoUrl, faviconUrl, primaryColor, supportEmail
+    default_city_id BIGINT REFERENCES cities(id) ON DELETE SET NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default');
+SELECT setval('tenants_id_seq', 1);
+
+ALTER TABLE venues ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE voting_campaigns ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE users ADD COLUMN tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+
+-- The same email can sign up with every tenant
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+CREATE INDEX idx_venues_tenant ON venues(tenant_id, city_id);
+CREATE INDEX idx_voting_campaigns_tenant ON voting_campaigns(tenant_id);
+
+-- ===============================
+-- RESULTS EMBARGO
+-- ===============================
+
+-- Campaigns hiding their standings from everyone but administrators until
+-- end_date, only the participation totals are shown meanwhile
+ALTER TABLE voting_campaigns ADD COLUMN hide_results_until_end BOOLEAN NOT NULL DEFAULT false;
+
+-- ===============================
+-- PRIVACY SETTINGS
+-- ===============================
+
+-- Users without a row have the defaults. Turning the search history off
+-- stops recording the user's searches in search_analytics.
+CREATE TABLE user_privacy_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES snapp_users(id) ON DELETE CASCADE,
+    search_history_enabled BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_search_analytics_user ON search_analytics(user_id, created_at DESC);
+
+-- ===============================
+-- EXTERNAL RATINGS
+-- ===============================
+
+-- Venues' aggregate ratings on other platforms, shown apart from the native
+-- reviews and left out of the venues' ratings
+CREATE TABLE external_ratings (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    source VARCHAR(30) NOT NULL CHECK (source IN ('google', 'tripadvisor', 'yelp', 'facebook')),
+    rating DECIMAL(3,2) NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    review_count INTEGER NOT NULL DEFAULT 0 CHECK (review_count >= 0),
+    url VARCHAR(500),
+    imported_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(venue_id, source)
+);
+
+-- ===============================
+-- COLLECTION COLLABORATORS
+-- ===============================
+
+-- Who added each venue, shown in the social feed
+ALTER TABLE venue_collection_items ADD COLUMN added_by BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL;
+
+-- Users invited to add and remove a collection's venues with its owner
+CREATE TABLE collection_collaborators (
+    collection_id BIGINT NOT NULL REFERENCES venue_collections(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    invited_by BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+    invited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP,
+    PRIMARY KEY (collection_id, user_id)
+);
+
+CREATE INDEX idx_collection_collaborators_user ON collection_collaborators(user_id, status);
+CREATE INDEX idx_venue_collection_items_added ON venue_collection_items(added_by, added_at DESC);
+
+-- ===============================
+-- CAMPAIGN VOTE REASON HIGHLIGHTS
+-- ===============================
+
+-- Reasons pass the content filter when voting, flagged ones stay pending
+-- until a moderator approves or rejects them. Approved highlights are quoted
+-- in the campaign results.
+ALTER TABLE campaign_votes ADD COLUMN reason_status VARCHAR(20) CHECK (reason_status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE campaign_votes ADD COLUMN is_highlight BOOLEAN NOT NULL DEFAULT false;
+UPDATE campaign_votes SET reason_status = 'pending' WHERE reason IS NOT NULL;
+
+CREATE INDEX idx_campaign_votes_highlights ON campaign_votes(campaign_id) WHERE is_highlight = true;
+
+-- ===============================
+-- VENUE DEALS
+-- ===============================
+
+-- Promotions owners run at their venues, shown with the venue while running
+CREATE TABLE venue_deals (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    title VARCHAR(100) NOT NULL,
+    description TEXT,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL CHECK (ends_at > starts_at),
+    redemption_limit INTEGER CHECK (redemption_limit > 0),
+    redemption_count INTEGER NOT NULL DEFAULT 0 CHECK (redemption_count >= 0),
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Deals redeemed with a check-in, attributing the visit to the deal
+CREATE TABLE deal_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    deal_id BIGINT NOT NULL REFERENCES venue_deals(id) ON DELETE CASCADE,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    checkin_id BIGINT REFERENCES venue_checkins(id) ON DELETE SET NULL,
+    redeemed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(deal_id, user_id)
+);
+
+CREATE INDEX idx_venue_deals_venue ON venue_deals(venue_id, ends_at);
+CREATE INDEX idx_deal_redemptions_venue ON deal_redemptions(venue_id, redeemed_at);
+
+-- ===============================
+-- USER CONSENTS
+-- ===============================
+
+-- Double opt-in consents to marketing and personalization. Asking for one
+-- leaves it pending until the user confirms it with the code sent to them.
+CREATE TABLE user_consents (
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    purpose VARCHAR(50) NOT NULL CHECK (purpose IN ('push_marketing', 'email_digest', 'personalized_recommendations')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'granted', 'revoked')),
+    source VARCHAR(20),
+    code_hash VARCHAR(64),
+    code_expires_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    requested_at TIMESTAMP,
+    granted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, purpose)
+);
+
+CREATE INDEX idx_user_consents_granted ON user_consents(purpose, user_id) WHERE status = 'granted';
+
+-- ===============================
+-- MODERATION AUDIT LOG
+-- ===============================
+
+-- Every moderator action on content with the state it changed from and to
+CREATE TABLE moderation_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    moderator_id BIGINT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    target_type VARCHAR(30) NOT NULL,
+    target_id BIGINT NOT NULL,
+    from_state VARCHAR(40) NOT NULL,
+    to_state VARCHAR(40) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_moderation_audit_target ON moderation_audit_log(target_type, target_id, created_at);
+CREATE INDEX idx_moderation_audit_moderator ON moderation_audit_log(moderator_id, created_at);
+
+-- ===============================
+-- VENUE FAVORITES
+-- ===============================
+
+-- The venues users hearted, apart from their collections. The venue's count
+-- is cached like its ratings.
+CREATE TABLE venue_favorites (
+    user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+    venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, venue_id)
+);
+
+CREATE INDEX idx_venue_favorites_user ON venue_favorites(user_id, created_at DESC);
+CREATE INDEX idx_venue_favorites_venue ON venue_favorites(venue_id);
+
+ALTER TABLE venues ADD COLUMN total_favorites INTEGER NOT NULL DEFAULT 0;
+
+-- ===============================
+-- VENUE EVENT RECEIPTS
+-- ===============================
+
+-- Venue interactions received from the apps, remembered for two days so
+-- that resent events count once in venue_analytics
+CREATE TABLE venue_event_receipts (
+    client_id VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (client_id, event_id)
+);
+
+CREATE INDEX idx_venue_event_receipts_received ON venue_event_receipts(received_at);
+
+-- ===============================
+-- CAMPAIGN AUDITS
+-- ===============================
+
+-- Votes moderators found to be fraudulent stay recorded but aren't counted
+ALTER TABLE campaign_votes ADD COLUMN excluded_reason VARCHAR(30) CHECK (excluded_reason IN ('fraud', 'duplicate_voter', 'automated'));
+ALTER TABLE campaign_votes ADD COLUMN excluded_by BIGINT REFERENCES users(id);
+ALTER TABLE campaign_votes ADD COLUMN excluded_at TIMESTAMP;
+
+-- The signed certificate of a campaign's final results. The certificate is
+-- kept as text since its signature covers the exact bytes.
+CREATE TABLE campaign_audits (
+    campaign_id BIGINT PRIMARY KEY REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    certificate TEXT NOT NULL,
+    signature_algorithm VARCHAR(20) NOT NULL,
+    signature VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===============================
+-- OWNER TIERS
+-- ===============================
+
+-- Owners without a running subscription are on the free tier
+CREATE TABLE owner_subscriptions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    tier VARCHAR(20) NOT NULL CHECK (tier IN ('free', 'premium')),
+    expires_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Venue galleries, as many photos as the owner's tier allows
+CREATE TABLE venue_photos (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    caption VARCHAR(200),
+    added_by BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_venue_photos_venue ON venue_photos(venue_id, created_at);
+
+-- ===============================
+-- SLOW QUERY LOG
+-- ===============================
+
+-- Search and analytics statements slower than DB_SLOW_QUERY_THRESHOLD, with
+-- their plan when DB_SLOW_QUERY_EXPLAIN is set
+CREATE TABLE slow_queries (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(255) NOT NULL,
+    statement TEXT NOT NULL,
+    params TEXT[] NOT NULL DEFAULT '{}',
+    duration_ms DOUBLE PRECISION NOT NULL,
+    error TEXT,
+    plan JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_slow_queries_created ON slow_queries(created_at);
+
+-- ===============================
+-- DUPLICATE REVIEWS
+-- ===============================
+
+-- Reviews copying another one are merged into its group and left out of
+-- summaries
+ALTER TABLE venue_reviews ADD COLUMN duplicate_of BIGINT REFERENCES venue_reviews(id) ON DELETE SET NULL;
+ALTER TABLE venue_reviews ADD COLUMN duplicate_similarity REAL;
+
+CREATE INDEX idx_venue_reviews_duplicate_of ON venue_reviews(duplicate_of) WHERE duplicate_of IS NOT NULL;
+
+-- MinHash signatures of review texts, the GIN index finds the reviews sharing
+-- a hash
+CREATE TABLE review_signatures (
+    review_id BIGINT PRIMARY KEY REFERENCES venue_reviews(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    signature BIGINT[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_signatures_signature ON review_signatures USING GIN (signature);
+CREATE INDEX idx_review_signatures_user ON review_signatures(user_id);
+
+-- ===============================
+-- OWNER REVIEW ALERTS
+-- ===============================
+
+-- When each venue owner was last emailed, and the approval time of the last
+-- review they were told about
+CREATE TABLE owner_alert_state (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    reviews_until TIMESTAMP,
+    last_sent_at TIMESTAMP
+);
+
+-- Milestones reached by claimed venues, recorded once and emailed to the
+-- owner at their next alert
+CREATE TABLE venue_milestones (
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    milestone VARCHAR(30) NOT NULL,
+    reached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP,
+    PRIMARY KEY (venue_id, milestone)
+);
+
+CREATE INDEX idx_venue_milestones_pending ON venue_milestones(venue_id) WHERE notified_at IS NULL;
+
+-- ===============================
+-- GEOHASH BUCKETING
+-- ===============================
+
+-- Radius searches first keep the venues of the geohash cells covering the
+-- circle, by prefix through the index, then check the exact distance
+ALTER TABLE venues ADD COLUMN geohash VARCHAR(12)
+    GENERATED ALWAYS AS (ST_GeoHash(ST_Point(longitude, latitude), 12)) STORED;
+
+CREATE INDEX idx_venues_geohash ON venues(geohash text_pattern_ops);
+
+-- ===============================
+-- ADMIN IMPERSONATION
+-- ===============================
+
+-- Read-only sessions in which a support admin sees the app as a snapp user,
+-- only the hash of their token is stored
+CREATE TABLE impersonation_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    snapp_user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every request made while impersonating, including the blocked ones
+CREATE TABLE impersonation_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES impersonation_sessions(id) ON DELETE CASCADE,
+    admin_id BIGINT NOT NULL,
+    snapp_user_id BIGINT NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    route VARCHAR(255),
+    status INTEGER NOT NULL,
+    blocked BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impersonation_audit_log_session ON impersonation_audit_log(session_id, created_at);
+
+-- ===============================
+-- ONE-TIME CODE VOTE VERIFICATION
+-- ===============================
+
+-- Campaigns holding votes until the voter confirms them with a code emailed
+-- to the verified address of their linked account
+ALTER TABLE voting_campaigns ADD COLUMN require_otp BOOLEAN NOT NULL DEFAULT false;
+
+-- The held vote request of each voter, one per campaign, cast once confirmed
+CREATE TABLE pending_campaign_votes (
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    vote JSONB NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, user_id)
+);
+
+-- ===============================
+-- EXPERIMENTS
+-- ===============================
+
+-- Venues recommended to users in an experiment, and the venue events the
+-- apps tagged with the variant they were served
+CREATE TABLE experiment_events (
+    id BIGSERIAL PRIMARY KEY,
+    experiment VARCHAR(100) NOT NULL,
+    variant VARCHAR(50) NOT NULL,
+    user_id BIGINT, -- Impressions only, the apps report events by client
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_experiment_events_experiment ON experiment_events(experiment, occurred_at);
+
+-- ===============================
+-- NOTIFICATION DIGESTS
+-- ===============================
+
+-- Frequent events, like helpful votes and new followers, waiting to be
+-- notified at once when their digest window ends
+CREATE TABLE notification_digest_events (
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    subject_id BIGINT NOT NULL DEFAULT 0, -- The review of helpful votes
+    actor_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, event_type, subject_id, actor_id)
+);
+
+CREATE INDEX idx_notification_digest_events_type ON notification_digest_events(event_type, created_at);
+
+-- ===============================
+-- CITY DISTRICTS
+-- ===============================
+
+-- Districts of the onboarded cities, placed by their centroid
+CREATE TABLE districts (
+    id BIGSERIAL PRIMARY KEY,
+    city_id BIGINT NOT NULL REFERENCES cities(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL,
+    latitude DECIMAL(10, 8) NOT NULL,
+    longitude DECIMAL(11, 8) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(city_id, slug)
+);
+
+-- ===============================
+-- CAMPAIGN VOTE CHANGES
+-- ===============================
+
+-- How long after casting a vote voters may change it, 0 when votes are
+-- final, NULL to allow changes until the campaign ends
+ALTER TABLE voting_campaigns ADD COLUMN vote_change_minutes INTEGER;
+
+-- Changes of campaign votes to other venues. The vote holds the latest
+-- venue, which is the one counted.
+CREATE TABLE vote_changes (
+    id BIGSERIAL PRIMARY KEY,
+    vote_id BIGINT NOT NULL REFERENCES campaign_votes(id) ON DELETE CASCADE,
+    campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+    previous_venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vote_changes_vote ON vote_changes(vote_id, changed_at);
+
+-- ===============================
+-- REVIEW PHOTO CAPTIONS
+-- ===============================
+
+-- Review photos become {url, caption, order, uploadedAt} objects, the
+-- photos stored as plain URLs keep their order and the review's time
+UPDATE venue_reviews r
+SET photos = (
+    SELECT jsonb_agg(jsonb_build_object(
+        'url', photo.url,
+        'order', photo.position - 1,
+        'uploadedAt', to_char(r.created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+    ) ORDER BY photo.position)
+    FROM jsonb_array_elements_text(r.photos) WITH ORDINALITY AS photo(url, position)
+)
+WHERE jsonb_typeof(r.photos) = 'array' AND jsonb_typeof(r.photos -> 0) = 'string';
+
+-- ===============================
+-- MOBILE DELTA SYNC
+-- ===============================
+
+-- Venues removed for good, for syncing clients to drop. Deactivated venues
+-- and campaigns and unapproved reviews are found by their updated_at.
+CREATE TABLE sync_tombstones (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('venue', 'review', 'campaign')),
+    entity_id BIGINT NOT NULL,
+    city_id BIGINT,
+    tenant_id BIGINT,
+    deleted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sync_tombstones_deleted ON sync_tombstones(deleted_at);
+CREATE INDEX idx_venues_updated ON venues(updated_at);
+CREATE INDEX idx_venue_reviews_updated ON venue_reviews(updated_at);
+CREATE INDEX idx_voting_campaigns_updated ON voting_campaigns(updated_at);
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/config"
 	"voting-app/app/controllers"
 	"voting-app/app/middlewares"
+	"voting-app/app/models"
+	"voting-app/app/services"
+	"voting-app/app/tracing"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
@@ -12,27 +20,119 @@ import (
 
 func initSentry() {
 	err := sentry.Init(sentry.ClientOptions{
-		Dsn: os.Getenv("SENTRY_DSN"),
+		Dsn:         config.Get().Sentry.DSN,
+		Environment: config.Get().Sentry.Environment,
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			// Queries cancelled because the client went away are not errors
+			if hint != nil && errors.Is(hint.OriginalException, context.Canceled) {
+				return nil
+			}
+			return event
+		},
 	})
 	if err != nil {
 		log.Fatalf("sentry.Init: %s", err)
 	}
 }
 
-func apiHandler() {
+func startJobs(databasePoolService *services.DatabasePoolService) *services.JobRunner {
+	jobRunner := new(services.JobRunner)
+
+	jobRunner.Register("database-pool-check", time.Minute, databasePoolService.CheckPool)
+	jobRunner.Register("slow-query-expiry", 24*time.Hour, databasePoolService.ExpireSlowQueries)
+
+	analyticsService := new(services.AnalyticsService)
+	jobRunner.Register("platform-stats-rollup", 5*time.Minute, analyticsService.RollupPlatformStats)
+	jobRunner.Register("metric-anomaly-detection", time.Hour, analyticsService.DetectAnomalies)
+	jobRunner.Register("client-event-sessionization", 5*time.Minute, analyticsService.SessionizeClientEvents)
+	jobRunner.Register("venue-event-receipt-expiry", time.Hour, analyticsService.ExpireVenueEventReceipts)
+
+	recommendationEngine := new(services.RecommendationEngine)
+	jobRunner.Register("similar-venues-precompute", time.Hour, recommendationEngine.PrecomputeSimilarVenues)
+
+	busynessService := new(services.BusynessService)
+	jobRunner.Register("wait-report-expiry", 15*time.Minute, busynessService.ExpireWaitReports)
+
+	feedService := new(services.FeedService)
+	jobRunner.Register("feed-regeneration", 30*time.Minute, feedService.RegenerateFeeds)
+
+	savedSearchService := new(services.SavedSearchService)
+	jobRunner.Register("saved-search-alerts", 15*time.Minute, savedSearchService.EvaluateSavedSearches)
+
+	ownerAlertService := new(services.OwnerAlertService)
+	jobRunner.Register("owner-review-alerts", 15*time.Minute, ownerAlertService.SendOwnerAlerts)
+
+	notificationService := new(services.NotificationService)
+	jobRunner.Register("notification-digests", 5*time.Minute, notificationService.SendDigests)
+	jobRunner.Register("campaign-announcements", 5*time.Minute, notificationService.AnnounceCampaigns)
+
+	suggestionService := new(services.SearchSuggestionService)
+	jobRunner.Register("search-suggestions-refresh", 5*time.Minute, suggestionService.RefreshSuggestions)
+
+	// Refreshing twice per TTL keeps the trending lists fresh between runs
+	trendingService := new(services.TrendingService)
+	jobRunner.Register("trending-refresh", services.TrendingCacheTTL/2, trendingService.RefreshTrending)
+
+	campaignResultService := new(services.CampaignResultService)
+	jobRunner.Register("campaign-result-snapshots", time.Hour, campaignResultService.SnapshotCampaignResults)
+
+	webhookService := new(services.WebhookService)
+	jobRunner.Register("webhook-deliveries", time.Minute, webhookService.DeliverPending)
+
+	userTokenService := new(services.UserTokenService)
+	jobRunner.Register("user-token-expiry", time.Hour, userTokenService.ExpireUserTokens)
+
+	votingSessionService := new(services.VotingSessionService)
+	jobRunner.Register("voting-session-expiry", time.Hour, votingSessionService.ExpireVotingSessions)
+
+	reviewExportService := new(services.ReviewExportService)
+	jobRunner.Register("review-exports", time.Minute, reviewExportService.ProcessPending)
+
+	reviewSummaryService := new(services.ReviewSummaryService)
+	jobRunner.Register("review-summaries", 15*time.Minute, reviewSummaryService.RefreshSummaries)
+
+	syncService := new(services.SyncService)
+	jobRunner.Register("sync-tombstone-expiry", 24*time.Hour, syncService.ExpireTombstones)
+
+	jobRunner.Start()
+	return jobRunner
+}
+
+func apiHandler(databasePoolService *services.DatabasePoolService) {
 	routes := gin.Default()
+	// Only the configured proxies may name the client with X-Forwarded-For,
+	// the rate limits would be bypassed with a made up header otherwise
+	if err := routes.SetTrustedProxies(config.Get().TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	routes.Use(middlewares.Tracing())
+	routes.Use(middlewares.SecurityHeaders(config.Get().Security))
+	routes.Use(middlewares.CORS(config.Get().CORS))
 	routes.Use(middlewares.Api())
+	routes.Use(middlewares.QueryTimeout(config.Get().Database.QueryTimeout))
+	routes.Use(middlewares.Tenant())
+	routes.Use(middlewares.Impersonation())
+	routes.Use(middlewares.Compress(config.Get().CompressMinBytes))
+	routes.Use(middlewares.RequestBody(int64(config.Get().MaxBodyBytes), "/v1/reviews/:snapp_id/photos", "/v1/admin/legacy/:dataset/import", "/v1/admin/external-ratings/import"))
+
+	metricsController := controllers.MetricsController{DatabasePool: databasePoolService}
+	routes.GET("/metrics", metricsController.Metrics)
+	routes.GET("/readyz", metricsController.Ready)
 
 	{
-		v1Routes := routes.Group("v1")
+		v1Routes := routes.Group("v1", middlewares.APIVersion("1"))
 		{
+			campaignController := new(controllers.CampaignController)
+			v1Routes.POST("/vote/sessions", middlewares.RateLimit(600, time.Hour), campaignController.CreateVotingSession)
+			voteController := new(controllers.VoteController)
 			voteRoutes := v1Routes.Group("/vote/:snapp_id")
 			{
-				voteRoutes.Use(middlewares.AuthSnappUser())
-				voteController := new(controllers.VoteController)
+				voteRoutes.Use(middlewares.AuthSnappUser(), middlewares.LegacyVotingReadOnly())
 				voteRoutes.GET("/", voteController.Vote)
 				voteRoutes.POST("/:voting_id/:vote_id", voteController.SubmitVote)
+				voteRoutes.GET("/results/:voting_id", voteController.GetResults)
 			}
+			v1Routes.GET("/legacy/archive", voteController.GetArchive)
 			fileRoutes := v1Routes.Group("/files")
 			{
 				var fileController controllers.FileController
@@ -43,12 +143,293 @@ func apiHandler() {
 				authController := new(controllers.User)
 				authRoutes.POST("register", authController.Register)
 				authRoutes.POST("login", authController.Login)
+				accountEmailLimit := middlewares.RateLimit(5, 15*time.Minute)
+				perAccountEmailLimit := middlewares.RateLimitEmail(5, time.Hour)
+				authRoutes.POST("verify-email/resend", accountEmailLimit, perAccountEmailLimit, authController.ResendEmailVerification)
+				authRoutes.POST("verify-email/:token", accountEmailLimit, authController.VerifyEmail)
+				authRoutes.POST("forgot-pass", accountEmailLimit, perAccountEmailLimit, authController.ForgotPassword)
+				authRoutes.POST("reset-pass/:token", accountEmailLimit, authController.ResetForgottenPassword)
 				authRoutes.Use(middlewares.AuthorizeJWT())
 				authRoutes.POST("reset-pass", authController.Reset)
+				authRoutes.POST("link-snapp", accountEmailLimit, authController.LinkSnapp)
+				authRoutes.POST("link-snapp/verify", accountEmailLimit, authController.VerifySnappLink)
+				authRoutes.DELETE("link-snapp", authController.UnlinkSnapp)
+				authRoutes.GET("identity", authController.GetIdentity)
+			}
+			v1Routes.GET("/reviews/featured", controllers.ReviewController{}.GetFeaturedReviews)
+			reviewRoutes := v1Routes.Group("/reviews/:snapp_id")
+			{
+				reviewRoutes.Use(middlewares.AuthSnappUser())
+				reviewController := new(controllers.ReviewController)
+				reviewRoutes.PUT("/drafts/:venue_id", reviewController.SaveReviewDraft)
+				reviewRoutes.GET("/drafts/:venue_id", reviewController.GetReviewDraft)
+				reviewRoutes.POST("/photos", reviewController.UploadReviewPhoto)
+				reviewRoutes.PUT("/:review_id/photos/order", reviewController.ReorderReviewPhotos)
+				reviewRoutes.PUT("/:review_id/photos/caption", reviewController.CaptionReviewPhoto)
+			}
+			notificationRoutes := v1Routes.Group("/notifications/:snapp_id")
+			{
+				notificationRoutes.Use(middlewares.AuthSnappUser())
+				notificationController := new(controllers.NotificationController)
+				notificationRoutes.POST("/devices", notificationController.RegisterDevice)
+			}
+			userRoutes := v1Routes.Group("/users/:snapp_id")
+			{
+				userRoutes.Use(middlewares.AuthSnappUser())
+				savedSearchController := new(controllers.SavedSearchController)
+				userRoutes.POST("/saved-searches", savedSearchController.CreateSavedSearch)
+				userRoutes.GET("/saved-searches", savedSearchController.GetSavedSearches)
+				userRoutes.GET("/saved-searches/:search_id/matches", savedSearchController.GetSavedSearchMatches)
+				userRoutes.DELETE("/saved-searches/:search_id", savedSearchController.DeleteSavedSearch)
+				userRoutes.POST("/checkins", new(controllers.VenueController).CreateCheckin)
+				userRoutes.POST("/deals/:deal_id/redeem", new(controllers.VenueController).RedeemDeal)
+				searchHistoryController := new(controllers.SearchHistoryController)
+				userRoutes.GET("/search-history", searchHistoryController.GetSearchHistory)
+				userRoutes.DELETE("/search-history", searchHistoryController.ClearSearchHistory)
+				userRoutes.GET("/privacy", searchHistoryController.GetPrivacySettings)
+				userRoutes.PUT("/privacy", searchHistoryController.UpdatePrivacySettings)
+				consentController := new(controllers.ConsentController)
+				userRoutes.GET("/consents", consentController.GetConsents)
+				userRoutes.POST("/consents/:purpose", consentController.RequestConsent)
+				userRoutes.POST("/consents/:purpose/confirm", consentController.ConfirmConsent)
+				userRoutes.DELETE("/consents/:purpose", consentController.RevokeConsent)
+				userRoutes.GET("/favorites", new(controllers.FavoriteController).GetFavorites)
+			}
+			collectionRoutes := v1Routes.Group("/collections/:snapp_id")
+			{
+				collectionRoutes.Use(middlewares.AuthSnappUser())
+				collectionController := new(controllers.CollectionController)
+				collectionRoutes.GET("", collectionController.GetCollections)
+				collectionRoutes.POST("", collectionController.CreateCollection)
+				collectionRoutes.GET("/:collection_id", collectionController.GetCollection)
+				collectionRoutes.POST("/:collection_id/venues", collectionController.AddVenueToCollection)
+				collectionRoutes.DELETE("/:collection_id/venues/:venue_id", collectionController.RemoveVenueFromCollection)
+				collectionRoutes.POST("/:collection_id/collaborators", collectionController.InviteCollaborator)
+				collectionRoutes.POST("/:collection_id/collaborators/accept", collectionController.AcceptCollaboration)
+				collectionRoutes.DELETE("/:collection_id/collaborators/:user_id", collectionController.RemoveCollaborator)
+			}
+			socialRoutes := v1Routes.Group("/social/:snapp_id")
+			{
+				socialRoutes.Use(middlewares.AuthSnappUser())
+				socialController := new(controllers.SocialController)
+				socialRoutes.GET("/feed", socialController.GetSocialFeed)
+				socialRoutes.POST("/follow/:target_user_id", socialController.FollowUser)
+				socialRoutes.DELETE("/follow/:target_user_id", socialController.UnfollowUser)
+				socialRoutes.POST("/block/:target_user_id", socialController.BlockUser)
+				socialRoutes.DELETE("/block/:target_user_id", socialController.UnblockUser)
+				socialRoutes.POST("/mute/:target_user_id", socialController.MuteUser)
+				socialRoutes.DELETE("/mute/:target_user_id", socialController.UnmuteUser)
+				socialRoutes.GET("/blocked", socialController.GetUserRelations)
+			}
+			menuController := new(controllers.MenuController)
+			webhookController := new(controllers.WebhookController)
+			v1Routes.GET("/venues/compare", new(controllers.VenueController).CompareVenues)
+			v1Routes.GET("/venues/map", new(controllers.VenueController).GetMapVenues)
+			v1Routes.GET("/venues/by-slug/:slug", new(controllers.VenueController).GetBySlug)
+			v1Routes.POST("/venues", middlewares.AuthorizeJWT(), new(controllers.VenueController).CreateVenue)
+			v1Routes.PUT("/venues/:id", middlewares.AuthorizeJWT(), new(controllers.VenueController).UpdateVenue)
+			v1Routes.DELETE("/venues/:id", middlewares.AuthorizeJWT(), new(controllers.VenueController).DeleteVenue)
+			v1Routes.GET("/venues/:id/menus", menuController.GetVenueMenus)
+			v1Routes.GET("/venues/:id/photos", new(controllers.OwnerController).GetVenuePhotos)
+			v1Routes.GET("/venues/:id/checkins", new(controllers.VenueController).GetVenueCheckins)
+			v1Routes.POST("/venues/:id/:snapp_id/favorite", middlewares.AuthSnappUser(), new(controllers.FavoriteController).FavoriteVenue)
+			v1Routes.DELETE("/venues/:id/:snapp_id/favorite", middlewares.AuthSnappUser(), new(controllers.FavoriteController).UnfavoriteVenue)
+			v1Routes.GET("/venues/:id/reviews/export", middlewares.AuthorizeJWT(), controllers.ReviewController{}.ExportVenueReviews)
+			v1Routes.GET("/venues/:id/reviews/exports/:export_id", middlewares.AuthorizeJWT(), controllers.ReviewController{}.GetReviewExport)
+			v1Routes.GET("/venues/:id/reviews/exports/:export_id/download", middlewares.AuthorizeJWT(), controllers.ReviewController{}.DownloadReviewExport)
+			neighborhoodController := new(controllers.NeighborhoodController)
+			v1Routes.GET("/discover/by-neighborhood/:id", neighborhoodController.DiscoverByNeighborhood)
+			v1Routes.GET("/discover/open-now", new(controllers.VenueController).DiscoverOpenNow)
+			v1Routes.GET("/discover/trending", new(controllers.VenueController).DiscoverTrending)
+			v1Routes.GET("/discover/city", new(controllers.VenueController).DiscoverCity)
+			v1Routes.GET("/discover/:snapp_id/recommendations", middlewares.AuthSnappUser(), new(controllers.RecommendationController).GetRecommendations)
+			v1Routes.POST("/discover/:snapp_id/feedback", middlewares.AuthSnappUser(), new(controllers.RecommendationController).SubmitFeedback)
+			v1Routes.GET("/owner/plan", middlewares.AuthorizeJWT(), new(controllers.OwnerController).GetPlan)
+			ownerRoutes := v1Routes.Group("/owner/venues/:id")
+			{
+				ownerRoutes.Use(middlewares.AuthorizeJWT())
+				ownerRoutes.PUT("/name", new(controllers.VenueController).RenameVenue)
+				ownerRoutes.GET("/watchlist", new(controllers.VenueController).GetWatchlist)
+				ownerRoutes.POST("/watchlist", new(controllers.VenueController).AddToWatchlist)
+				ownerRoutes.DELETE("/watchlist/:competitor_id", new(controllers.VenueController).RemoveFromWatchlist)
+				ownerRoutes.GET("/hours-exceptions", new(controllers.VenueController).GetHoursExceptions)
+				ownerRoutes.POST("/hours-exceptions", new(controllers.VenueController).SetHoursException)
+				ownerRoutes.DELETE("/hours-exceptions/:exception_id", new(controllers.VenueController).DeleteHoursException)
+				ownerRoutes.GET("/deals", new(controllers.VenueController).GetDeals)
+				ownerRoutes.POST("/deals", new(controllers.VenueController).CreateDeal)
+				ownerRoutes.PUT("/deals/:deal_id", new(controllers.VenueController).UpdateDeal)
+				ownerRoutes.DELETE("/deals/:deal_id", new(controllers.VenueController).DeleteDeal)
+				ownerRoutes.GET("/external-ratings", new(controllers.VenueController).GetExternalRatings)
+				ownerRoutes.POST("/external-ratings", new(controllers.VenueController).ImportExternalRatings)
+				ownerRoutes.DELETE("/external-ratings/:source", new(controllers.VenueController).DeleteExternalRating)
+				ownerRoutes.GET("/menus", menuController.GetOwnerMenus)
+				ownerRoutes.POST("/menus", menuController.CreateMenu)
+				ownerRoutes.PUT("/menus/:menu_id", menuController.UpdateMenu)
+				ownerRoutes.DELETE("/menus/:menu_id", menuController.DeleteMenu)
+				ownerRoutes.POST("/menus/:menu_id/sections", menuController.CreateSection)
+				ownerRoutes.PUT("/menus/:menu_id/sections/:section_id", menuController.UpdateSection)
+				ownerRoutes.DELETE("/menus/:menu_id/sections/:section_id", menuController.DeleteSection)
+				ownerRoutes.POST("/menus/:menu_id/sections/:section_id/items", menuController.CreateItem)
+				ownerRoutes.PUT("/menus/:menu_id/sections/:section_id/items/:item_id", menuController.UpdateItem)
+				ownerRoutes.DELETE("/menus/:menu_id/sections/:section_id/items/:item_id", menuController.DeleteItem)
+				ownerRoutes.POST("/review-invites", controllers.ReviewController{}.CreateReviewInvites)
+				ownerRoutes.POST("/webhooks", webhookController.CreateWebhook)
+				ownerRoutes.GET("/webhooks", webhookController.GetWebhooks)
+				ownerRoutes.POST("/webhooks/:webhook_id/test", webhookController.TestWebhook)
+				ownerRoutes.POST("/webhooks/:webhook_id/disable", webhookController.DisableWebhook)
+				ownerRoutes.GET("/webhooks/:webhook_id/deliveries", webhookController.GetWebhookDeliveries)
+				ownerRoutes.GET("/photos", new(controllers.OwnerController).GetOwnerVenuePhotos)
+				ownerRoutes.POST("/photos", new(controllers.OwnerController).AddVenuePhoto)
+				ownerRoutes.DELETE("/photos/:photo_id", new(controllers.OwnerController).DeleteVenuePhoto)
+			}
+			v1Routes.POST("/analytics/events", middlewares.RateLimit(60, time.Minute), new(controllers.AnalyticsController).TrackEvents)
+			v1Routes.POST("/analytics/track", middlewares.RateLimit(60, time.Minute), new(controllers.AnalyticsController).TrackVenueEvents)
+			analyticsRoutes := v1Routes.Group("/analytics")
+			{
+				analyticsRoutes.Use(middlewares.AuthorizeJWT())
+				analyticsController := new(controllers.AnalyticsController)
+				analyticsRoutes.GET("/venues/:id", analyticsController.GetVenueAnalytics)
+				analyticsRoutes.GET("/venues/:id/watchlist", analyticsController.GetWatchlistComparison)
+				analyticsRoutes.GET("/alerts", analyticsController.GetMetricAlerts)
+				analyticsRoutes.GET("/engagement", analyticsController.GetEngagement)
+			}
+			v1Routes.POST("/receipts/verify", campaignController.VerifyReceipt)
+			userCampaignRoutes := v1Routes.Group("/campaigns/:id/:snapp_id")
+			{
+				userCampaignRoutes.Use(middlewares.AuthSnappUser())
+				userCampaignRoutes.POST("/vote", campaignController.SubmitCampaignVote)
+				userCampaignRoutes.POST("/verify-otp", middlewares.RateLimit(10, 15*time.Minute), campaignController.VerifyCampaignVote)
+				userCampaignRoutes.GET("/receipts", campaignController.GetVoteReceipts)
+				userCampaignRoutes.GET("/votes", campaignController.GetUserVotes)
+				userCampaignRoutes.PUT("/votes/:vote_id", campaignController.ChangeCampaignVote)
+				userCampaignRoutes.GET("/credits", campaignController.GetCreditBalance)
+			}
+			v1Routes.GET("/campaigns/featured", campaignController.GetFeaturedCampaigns)
+			v1Routes.GET("/campaigns/:id", campaignController.GetCampaign)
+			v1Routes.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+			v1Routes.GET("/campaigns/:id/audit", campaignController.GetCampaignAudit)
+			v1Routes.GET("/campaigns/audit-key", campaignController.GetCampaignAuditKey)
+			v1Routes.POST("/campaigns/:id/vote", campaignController.SubmitSessionCampaignVote)
+			v1Routes.GET("/campaign-results/:id", middlewares.OptionalJWT(), campaignController.GetCampaignResults)
+			v1Routes.GET("/campaign-results/:id/snapshots", middlewares.OptionalJWT(), campaignController.GetCampaignSnapshots)
+			v1Routes.GET("/embed/campaigns/:id", campaignController.GetCampaignWidget)
+			adminRoutes := v1Routes.Group("/admin")
+			{
+				adminRoutes.Use(middlewares.AuthorizeJWT())
+				adminController := new(controllers.AdminController)
+				adminRoutes.GET("/overview", adminController.GetOverview)
+				adminRoutes.GET("/slow-queries", adminController.GetSlowQueries)
+				adminRoutes.GET("/legacy/:dataset/export", adminController.ExportLegacyData)
+				adminRoutes.POST("/legacy/:dataset/import", adminController.ImportLegacyData)
+				adminRoutes.POST("/external-ratings/import", adminController.ImportExternalRatings)
+				adminRoutes.POST("/reviews/bulk", adminController.BulkModerateReviews)
+				adminRoutes.GET("/reviews/:id/duplicates", adminController.GetReviewDuplicates)
+				adminRoutes.POST("/impersonate/:snapp_id", adminController.Impersonate)
+				adminRoutes.GET("/impersonations/:id/audit", adminController.GetImpersonationAudit)
+				adminRoutes.POST("/campaigns/:id/categories", campaignController.CreateCampaignCategory)
+				adminRoutes.POST("/campaigns/:id/promotions", campaignController.CreateCampaignPromotion)
+				adminRoutes.POST("/campaigns/:id/embed-tokens", campaignController.CreateEmbedToken)
+				adminRoutes.GET("/campaigns/:id/kiosks", campaignController.GetKioskDevices)
+				adminRoutes.POST("/campaigns/:id/kiosks", campaignController.CreateKioskDevice)
+				adminRoutes.DELETE("/campaigns/:id/kiosks/:kiosk_id", campaignController.RevokeKioskDevice)
+				adminRoutes.POST("/campaigns/auto-generate", campaignController.AutoGenerateCampaign)
+				adminRoutes.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+				adminRoutes.POST("/campaigns/:id/nominees", campaignController.AddCampaignNominee)
+				adminRoutes.POST("/campaigns/:id/nominees/eligible", campaignController.AddEligibleCampaignNominees)
+				adminRoutes.DELETE("/campaigns/:id/nominees/:venue_id", campaignController.RemoveCampaignNominee)
+				adminRoutes.POST("/campaigns/:id/publish", campaignController.PublishCampaign)
+				adminRoutes.GET("/campaigns/:id/reasons", campaignController.GetCampaignVoteReasons)
+				adminRoutes.PUT("/campaigns/:id/reasons/:vote_id", campaignController.ModerateVoteReason)
+				adminRoutes.PUT("/campaigns/:id/votes/:vote_id/exclusion", campaignController.ExcludeCampaignVote)
+				adminRoutes.PUT("/owners/:user_id/subscription", new(controllers.OwnerController).SetOwnerSubscription)
+				adminRoutes.GET("/campaign-promotions", campaignController.GetCampaignPromotions)
+				adminRoutes.DELETE("/campaign-promotions/:promotion_id", campaignController.DeleteCampaignPromotion)
+				adminRoutes.POST("/neighborhoods", neighborhoodController.CreateNeighborhood)
+				adminRoutes.POST("/webhooks", webhookController.CreateWebhook)
+				adminRoutes.GET("/webhooks", webhookController.GetWebhooks)
+				adminRoutes.POST("/webhooks/:webhook_id/test", webhookController.TestWebhook)
+				adminRoutes.POST("/webhooks/:webhook_id/disable", webhookController.DisableWebhook)
+				adminRoutes.GET("/webhooks/:webhook_id/deliveries", webhookController.GetWebhookDeliveries)
+				flagController := new(controllers.FeatureFlagController)
+				adminRoutes.GET("/flags", flagController.GetFlags)
+				adminRoutes.POST("/flags", flagController.CreateFlag)
+				adminRoutes.PUT("/flags/:key", flagController.UpdateFlag)
+				adminRoutes.DELETE("/flags/:key", flagController.DeleteFlag)
+				adminRoutes.GET("/experiments/:key/report", new(controllers.RecommendationController).GetExperimentReport)
+				cityController := new(controllers.CityController)
+				adminRoutes.POST("/cities", cityController.CreateCity)
+				adminRoutes.PUT("/cities/:id", cityController.UpdateCity)
+				adminRoutes.POST("/cities/:id/districts", cityController.CreateDistrict)
+				adminRoutes.PUT("/districts/:id", cityController.UpdateDistrict)
+				adminRoutes.DELETE("/districts/:id", cityController.DeleteDistrict)
+				tenantController := new(controllers.TenantController)
+				adminRoutes.POST("/tenants", tenantController.CreateTenant)
+				adminRoutes.PUT("/tenants/:id", tenantController.UpdateTenant)
+				ratingTemplateController := new(controllers.RatingTemplateController)
+				adminRoutes.GET("/rating-templates", ratingTemplateController.GetRatingTemplates)
+				adminRoutes.PUT("/rating-templates/:category_id", ratingTemplateController.SaveRatingTemplate)
+				adminRoutes.DELETE("/rating-templates/:category_id", ratingTemplateController.DeleteRatingTemplate)
+			}
+			v1Routes.GET("/tenant", new(controllers.TenantController).GetTenant)
+			v1Routes.GET("/sync", new(controllers.SyncController).Sync)
+			utilityRoutes := v1Routes.Group("/utils")
+			{
+				utilityController := new(controllers.UtilityController)
+				utilityRoutes.POST("/meeting-point", utilityController.MeetingPoint)
+				utilityRoutes.POST("/distances", utilityController.Distances)
+				utilityRoutes.GET("/suggestions", utilityController.GetSearchSuggestions)
+				utilityRoutes.GET("/autocomplete", utilityController.GetAutocomplete)
+				utilityRoutes.GET("/reference", utilityController.GetReferenceData)
+				utilityRoutes.GET("/cities", utilityController.GetCities)
+				utilityRoutes.GET("/cities/:city_id/districts", utilityController.GetDistricts)
+			}
+			feedRoutes := v1Routes.Group("/feeds")
+			{
+				feedController := new(controllers.FeedController)
+				feedRoutes.GET("/sitemap.xml", feedController.Sitemap)
+				feedRoutes.GET("/venues.json", feedController.VenuesFeed)
 			}
 
 		}
 
+		// v2 serves the venue, review and campaign handlers of v1 with every
+		// response in the {data, meta, errors} envelope
+		v2Routes := routes.Group("v2", middlewares.APIVersion("2"), middlewares.Envelope("2"))
+		{
+			venueController := new(controllers.VenueController)
+			reviewController := new(controllers.ReviewController)
+			campaignController := new(controllers.CampaignController)
+			v2Routes.GET("/venues/search", venueController.Search)
+			v2Routes.GET("/venues/nearby", venueController.GetNearby)
+			v2Routes.GET("/venues/featured", venueController.GetFeatured)
+			v2Routes.GET("/venues/categories", venueController.GetCategories)
+			v2Routes.GET("/venues/compare", venueController.CompareVenues)
+			v2Routes.GET("/venues/by-slug/:slug", venueController.GetBySlug)
+			v2Routes.GET("/venues/:id", venueController.GetByID)
+			v2Routes.GET("/venues/:id/reviews", reviewController.GetVenueReviews)
+			v2Routes.GET("/venues/:id/reviews/summary", reviewController.GetReviewSummary)
+			v2Routes.GET("/reviews/featured", reviewController.GetFeaturedReviews)
+			userReviewRoutes := v2Routes.Group("/reviews/:snapp_id")
+			{
+				userReviewRoutes.Use(middlewares.AuthSnappUser())
+				userReviewRoutes.POST("", reviewController.CreateReview)
+				userReviewRoutes.GET("", reviewController.GetUserReviews)
+				userReviewRoutes.PUT("/:review_id/photos/order", reviewController.ReorderReviewPhotos)
+				userReviewRoutes.PUT("/:review_id/photos/caption", reviewController.CaptionReviewPhoto)
+			}
+			v2Routes.GET("/campaigns/featured", campaignController.GetFeaturedCampaigns)
+			v2Routes.GET("/campaigns/:id", campaignController.GetCampaign)
+			v2Routes.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+			v2Routes.GET("/campaigns/:id/results", middlewares.OptionalJWT(), campaignController.GetCampaignResults)
+			userCampaignRoutes := v2Routes.Group("/campaigns/:id/:snapp_id")
+			{
+				userCampaignRoutes.Use(middlewares.AuthSnappUser())
+				userCampaignRoutes.POST("/vote", campaignController.SubmitCampaignVote)
+				userCampaignRoutes.POST("/verify-otp", middlewares.RateLimit(10, 15*time.Minute), campaignController.VerifyCampaignVote)
+				userCampaignRoutes.GET("/votes", campaignController.GetUserVotes)
+				userCampaignRoutes.PUT("/votes/:vote_id", campaignController.ChangeCampaignVote)
+			}
+		}
 	}
 
 	log.Println("Starting HTTP server...")
@@ -60,9 +441,42 @@ func apiHandler() {
 	}
 }
 
+// seedDatabase loads the sample data for local development, run with
+// "go run main.go seed"
+func seedDatabase() {
+	summary, err := new(services.SeedService).Run(context.Background())
+	if err != nil {
+		log.Fatalf("seed: %s", err)
+	}
+	log.Printf("Seeded %d venues, %d reviews and %d legacy rows, campaign %d is open",
+		summary.Venues, summary.Reviews, summary.LegacyRows, summary.CampaignID)
+	if summary.AdminCreated {
+		log.Printf("Created admin user %s with password %q", services.SeedAdminEmail, services.SeedAdminPassword)
+	}
+}
+
 func main() {
 	log.Println("Starting VoteEngine application...")
 	initSentry()
 	log.Println("Sentry initialized successfully")
-	apiHandler()
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatalf("tracing.Init: %s", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownTracing(ctx)
+	}()
+	databases.ConnectToPostgresDB()
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		seedDatabase()
+		return
+	}
+	models.LoadWinnersVotesCounts()
+	databasePoolService := new(services.DatabasePoolService)
+	jobRunner := startJobs(databasePoolService)
+	defer jobRunner.Stop()
+	log.Println("Background jobs started")
+	apiHandler(databasePoolService)
 }
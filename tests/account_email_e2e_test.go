@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var accountTokenPattern = regexp.MustCompile(`/(verify-email|reset-password)/([0-9a-f]{64})`)
+
+type sentEmail struct {
+	To, Subject, Body string
+}
+
+// recordingMailer keeps the emails instead of sending them
+type recordingMailer struct {
+	sent []sentEmail
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, sentEmail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// lastToken returns the token of the link in the last email
+func (m *recordingMailer) lastToken() string {
+	if len(m.sent) == 0 {
+		return ""
+	}
+	match := accountTokenPattern.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if match == nil {
+		return ""
+	}
+	return match[2]
+}
+
+// TestAccountEmails tests email verification, the forgotten password flow
+// and the rate limit of the account email endpoints
+func (suite *TestSuite) TestAccountEmails() {
+	suite.Run("Account Emails", func() {
+		mailer := &recordingMailer{}
+		defaultMailer := services.AppMailer
+		services.AppMailer = mailer
+		defer func() { services.AppMailer = defaultMailer }()
+
+		credentials := serializers.UserRequest{Email: "owner@example.com", Password: "first-password"}
+		w := suite.makePOSTRequest("/v1/auth/register", credentials)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		suite.Require().Len(mailer.sent, 1)
+		assert.Equal(suite.T(), "owner@example.com", mailer.sent[0].To)
+		verificationToken := mailer.lastToken()
+		suite.Require().NotEmpty(verificationToken)
+
+		// Unverified accounts can't log in
+		w = suite.makePOSTRequest("/v1/auth/login", credentials)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.EmailNotVerified, response.Code)
+
+		w = suite.makePOSTRequest("/v1/auth/verify-email/"+hex.EncodeToString(make([]byte, 32)), nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/auth/verify-email/"+verificationToken, nil)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		w = suite.makePOSTRequest("/v1/auth/verify-email/"+verificationToken, nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/auth/login", credentials)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		// Unknown accounts get the same answer and no email
+		w = suite.makePOSTRequest("/v1/auth/forgot-pass", serializers.EmailRequest{Email: "nobody@example.com"})
+		assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+		assert.Len(suite.T(), mailer.sent, 1)
+
+		w = suite.makePOSTRequest("/v1/auth/forgot-pass", serializers.EmailRequest{Email: "owner@example.com"})
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		suite.Require().Len(mailer.sent, 2)
+		resetToken := mailer.lastToken()
+		suite.Require().NotEmpty(resetToken)
+
+		// A second request right away doesn't send another email
+		w = suite.makePOSTRequest("/v1/auth/forgot-pass", serializers.EmailRequest{Email: "owner@example.com"})
+		assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+		assert.Len(suite.T(), mailer.sent, 2)
+
+		w = suite.makePOSTRequest("/v1/auth/reset-pass/"+resetToken, serializers.ResetPassword{Password: "short"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/auth/reset-pass/"+resetToken, serializers.ResetPassword{Password: "second-password"})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		w = suite.makePOSTRequest("/v1/auth/reset-pass/"+resetToken, serializers.ResetPassword{Password: "third-password"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/auth/login", credentials)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makePOSTRequest("/v1/auth/login", serializers.UserRequest{Email: "owner@example.com", Password: "second-password"})
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+		// Expired tokens don't work
+		expiredToken := "expired"
+		sum := sha256.Sum256([]byte(expiredToken))
+		_, err := suite.db.Exec(`
+			INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+			SELECT id, 'reset_password', $1, $2 FROM users WHERE email = 'owner@example.com'`,
+			hex.EncodeToString(sum[:]), time.Now().Add(-time.Minute))
+		suite.Require().NoError(err)
+		w = suite.makePOSTRequest("/v1/auth/reset-pass/"+expiredToken, serializers.ResetPassword{Password: "fourth-password"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Each client gets five requests per endpoint every 15 minutes
+		for i := 0; i < 2; i++ {
+			w = suite.makePOSTRequest("/v1/auth/forgot-pass", serializers.EmailRequest{Email: "nobody@example.com"})
+			assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+		}
+		w = suite.makePOSTRequest("/v1/auth/forgot-pass", serializers.EmailRequest{Email: "nobody@example.com"})
+		assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+
+		// A made up X-Forwarded-For header doesn't make a new client
+		for i := 0; i < 5; i++ {
+			w = suite.makePOSTRequestFromIP("/v1/auth/forgot-pass", "198.51.100.1", fmt.Sprintf("203.0.113.%d", i),
+				serializers.EmailRequest{Email: fmt.Sprintf("someone%d@example.com", i)})
+			assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+		}
+		w = suite.makePOSTRequestFromIP("/v1/auth/forgot-pass", "198.51.100.1", "203.0.113.99",
+			serializers.EmailRequest{Email: "someone99@example.com"})
+		assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+
+		// Each email address gets five requests an hour, whichever clients
+		// they come from
+		for i := 0; i < 5; i++ {
+			w = suite.makePOSTRequestFromIP("/v1/auth/forgot-pass", fmt.Sprintf("198.51.100.%d", 10+i), "",
+				serializers.EmailRequest{Email: "target@example.com"})
+			assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+		}
+		w = suite.makePOSTRequestFromIP("/v1/auth/forgot-pass", "198.51.100.20", "",
+			serializers.EmailRequest{Email: " Target@Example.com"})
+		assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+		w = suite.makePOSTRequestFromIP("/v1/auth/forgot-pass", "198.51.100.20", "",
+			serializers.EmailRequest{Email: "other@example.com"})
+		assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+	})
+}
+
+// makePOSTRequestFromIP makes a POST request from the IP address, claiming
+// to be forwarded for another one when set
+func (suite *TestSuite) makePOSTRequestFromIP(url, ip, forwardedFor string, payload interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var linkCodePattern = regexp.MustCompile(`\d{6}`)
+
+// TestAccountLinking tests linking JWT users to snapp users
+func (suite *TestSuite) TestAccountLinking() {
+	suite.Run("Account Linking", func() {
+		suite.setupLegacyVotingData()
+		_, err := suite.db.Exec("DELETE FROM user_voting WHERE owner_id = 2")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO user_voting (voting_id, owner_id, vote_id) VALUES (1, 2, 2)")
+		suite.Require().NoError(err)
+
+		w := suite.makePOSTRequest("/v1/auth/link-snapp", serializers.LinkSnappRequest{SnappId: "test_user_2"})
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		code := suite.getLinkCode(2)
+
+		w = suite.makePOSTRequest("/v1/auth/link-snapp/verify", serializers.LinkSnappRequest{SnappId: "test_user_2", Code: "abcdef"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/auth/link-snapp/verify", serializers.LinkSnappRequest{SnappId: "test_user_2", Code: code})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var identity models.Identity
+		suite.parseJSONResponse(w, &identity)
+		assert.Equal(suite.T(), int64(1), identity.UserID)
+		assert.Equal(suite.T(), int64(2), identity.SnappUserID)
+		assert.NotNil(suite.T(), identity.LinkedAt)
+
+		// The code is single use
+		w = suite.makePOSTRequest("/v1/auth/link-snapp/verify", serializers.LinkSnappRequest{SnappId: "test_user_2", Code: code})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// The linked user sees the snapp user's voting history
+		w = suite.makeGETRequest("/v1/auth/identity")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var response serializers.IdentityResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), "test_user_2", response.SnappId)
+		suite.Require().Len(response.Votes, 1)
+		assert.Equal(suite.T(), int64(2), response.Votes[0].ParticipantID)
+
+		// Neither side can be linked twice
+		w = suite.makePOSTRequest("/v1/auth/link-snapp", serializers.LinkSnappRequest{SnappId: "test_user_1"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		var errorResponse serializers.Base
+		suite.parseJSONResponse(w, &errorResponse)
+		assert.Equal(suite.T(), serializers.AlreadyLinked, errorResponse.Code)
+
+		ctx := context.Background()
+		accountLinkService := &services.AccountLinkService{}
+		_, err = accountLinkService.RequestLink(ctx, 2, "test_user_2")
+		assert.Equal(suite.T(), models.ErrAccountAlreadyLinked, err)
+
+		w = suite.makePOSTRequest("/v1/auth/link-snapp", serializers.LinkSnappRequest{SnappId: "unknown_user"})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.makeDELETERequest("/v1/auth/link-snapp")
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		w = suite.makeDELETERequest("/v1/auth/link-snapp")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Too many wrong codes void the request
+		_, err = accountLinkService.RequestLink(ctx, 5, "test_user_1")
+		suite.Require().NoError(err)
+		code = suite.getLinkCode(1)
+		for i := 0; i < models.MaxAccountLinkAttempts; i++ {
+			_, err = accountLinkService.ConfirmLink(ctx, 5, "test_user_1", "wrong")
+			assert.Equal(suite.T(), models.ErrInvalidLinkCode, err)
+		}
+		_, err = accountLinkService.ConfirmLink(ctx, 5, "test_user_1", code)
+		assert.Equal(suite.T(), models.ErrInvalidLinkCode, err)
+
+		// Codes aren't sent again right away, whoever asks
+		_, err = accountLinkService.RequestLink(ctx, 5, "test_user_1")
+		assert.Equal(suite.T(), models.ErrLinkCodeResendTooSoon, err)
+		_, err = accountLinkService.RequestLink(ctx, 6, "test_user_1")
+		assert.Equal(suite.T(), models.ErrLinkCodeResendTooSoon, err)
+
+		// A new code doesn't bring new attempts within the window
+		backdate := func(by string) {
+			_, err := suite.db.Exec("UPDATE account_link_requests SET created_at = created_at - $1::interval WHERE user_id = 5", by)
+			suite.Require().NoError(err)
+		}
+		backdate("2 minutes")
+		_, err = accountLinkService.RequestLink(ctx, 5, "test_user_1")
+		suite.Require().NoError(err)
+		_, err = accountLinkService.ConfirmLink(ctx, 5, "test_user_1", suite.getLinkCode(1))
+		assert.Equal(suite.T(), models.ErrInvalidLinkCode, err)
+
+		backdate("2 hours")
+		_, err = accountLinkService.RequestLink(ctx, 5, "test_user_1")
+		suite.Require().NoError(err)
+		_, err = accountLinkService.ConfirmLink(ctx, 5, "test_user_1", suite.getLinkCode(1))
+		assert.NoError(suite.T(), err)
+	})
+}
+
+// getLinkCode reads the latest verification code sent to the snapp user
+func (suite *TestSuite) getLinkCode(snappUserID int64) string {
+	var body string
+	err := suite.db.QueryRow(`SELECT body FROM notifications
+		WHERE user_id = $1 AND event_type = $2
+		ORDER BY id DESC LIMIT 1`, snappUserID, models.NotificationAccountLink).Scan(&body)
+	suite.Require().NoError(err)
+
+	code := linkCodePattern.FindString(body)
+	suite.Require().NotEmpty(code)
+	return code
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAdminOverview tests the moderation queue sizes and moderator throughput
+func (suite *TestSuite) TestAdminOverview() {
+	suite.Run("Admin Overview End-to-End", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (50, 'Moderated Campaign', 'best_restaurant', $1, $2, 3, true)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		suite.testModerationQueues()
+		suite.testAdminOverviewRequiresSuperuser()
+	})
+}
+
+func (suite *TestSuite) testModerationQueues() {
+	now := time.Now().UTC()
+
+	_, err := suite.db.Exec(`INSERT INTO venue_reviews
+		(venue_id, user_id, overall_rating, moderation_status, is_flagged, flagged_at, created_at, moderated_by, moderated_at)
+		VALUES
+		(1, 1, 4.0, 'pending', false, NULL, $1, NULL, NULL),
+		(2, 1, 1.0, 'approved', true, $2, $1, NULL, NULL),
+		(1, 2, 5.0, 'approved', false, NULL, $1, 1, $2),
+		(2, 2, 3.0, 'approved', false, NULL, $1, 1, $3)`,
+		now.Add(-3*time.Hour), now.Add(-1*time.Hour), now.AddDate(0, 0, -10))
+	suite.Require().NoError(err)
+
+	_, err = suite.db.Exec(`INSERT INTO venue_claims
+		(venue_id, user_id, status, reviewed_by, reviewed_at, created_at)
+		VALUES (1, 2, 'pending', NULL, NULL, $1), (2, 2, 'approved', 1, $2, $1)`,
+		now.Add(-2*time.Hour), now.Add(-30*time.Minute))
+	suite.Require().NoError(err)
+
+	_, err = suite.db.Exec(`INSERT INTO campaign_votes
+		(campaign_id, venue_id, user_id, is_flagged, flagged_at, moderated_by, moderated_at)
+		VALUES (50, 1, 1, true, $1, NULL, NULL), (50, 2, 1, true, $1, 2, $2)`,
+		now.Add(-4*time.Hour), now.Add(-1*time.Hour))
+	suite.Require().NoError(err)
+
+	overview, err := models.GetModerationOverview(context.Background(), now)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.ModerationThroughputDays, overview.WindowDays)
+
+	queues := map[string]models.ModerationQueue{}
+	for _, queue := range overview.Queues {
+		queues[queue.Name] = queue
+	}
+	suite.Require().Len(queues, 4)
+
+	assert.Equal(suite.T(), 1, queues["pending_reviews"].Count)
+	assert.InDelta(suite.T(), 3*3600, queues["pending_reviews"].OldestAgeSeconds, 60)
+	assert.Equal(suite.T(), 1, queues["reported_reviews"].Count)
+	assert.InDelta(suite.T(), 3600, queues["reported_reviews"].OldestAgeSeconds, 60)
+	assert.Equal(suite.T(), 1, queues["pending_venue_claims"].Count)
+	assert.InDelta(suite.T(), 2*3600, queues["pending_venue_claims"].OldestAgeSeconds, 60)
+	assert.Equal(suite.T(), 1, queues["flagged_campaign_votes"].Count)
+	assert.InDelta(suite.T(), 4*3600, queues["flagged_campaign_votes"].OldestAgeSeconds, 60)
+
+	// The review moderated 10 days ago is outside the window
+	suite.Require().Len(overview.Throughput, 2)
+	assert.Equal(suite.T(), int64(1), overview.Throughput[0].ModeratorID)
+	assert.Equal(suite.T(), 1, overview.Throughput[0].Reviews)
+	assert.Equal(suite.T(), 1, overview.Throughput[0].VenueClaims)
+	assert.Equal(suite.T(), 2, overview.Throughput[0].Total)
+	assert.Equal(suite.T(), int64(2), overview.Throughput[1].ModeratorID)
+	assert.Equal(suite.T(), 1, overview.Throughput[1].CampaignVotes)
+}
+
+func (suite *TestSuite) testAdminOverviewRequiresSuperuser() {
+	w := suite.makeGETRequest("/v1/admin/overview")
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"context"
+	"net/http"
 	"time"
 	"voting-app/app/models"
 	"voting-app/app/services"
@@ -25,6 +27,15 @@ func (suite *TestSuite) TestAnalyticsSystem() {
 
 		// Test performance metrics
 		suite.testPerformanceMetrics()
+
+		// Test incremental platform counters
+		suite.testPlatformStatsRollup()
+
+		// Test bucketing by the venue's local time
+		suite.testAnalyticsTimezoneBucketing()
+
+		// Test the venue owner scope
+		suite.testOwnerVenueAnalytics()
 	})
 }
 
@@ -59,7 +70,7 @@ func (suite *TestSuite) testVenueAnalytics() {
 	analyticsService := &services.AnalyticsService{}
 
 	// Test getting venue analytics for different time ranges
-	analytics, err := analyticsService.GetVenueAnalytics(1, "week")
+	analytics, err := analyticsService.GetVenueAnalytics(context.Background(), 1, "week")
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), analytics)
 
@@ -73,24 +84,24 @@ func (suite *TestSuite) testVenueAnalytics() {
 	// Test different time ranges
 	timeRanges := []string{"today", "week", "month"}
 	for _, timeRange := range timeRanges {
-		analytics, err := analyticsService.GetVenueAnalytics(1, timeRange)
+		analytics, err := analyticsService.GetVenueAnalytics(context.Background(), 1, timeRange)
 		assert.NoError(suite.T(), err, "Should get analytics for time range: %s", timeRange)
 		assert.Equal(suite.T(), timeRange, analytics.TimeRange)
 		assert.NotNil(suite.T(), analytics.GrowthMetrics)
 	}
 
 	// Test analytics for venue with no data
-	analytics, err = analyticsService.GetVenueAnalytics(999, "week")
+	analytics, err = analyticsService.GetVenueAnalytics(context.Background(), 999, "week")
 	assert.Error(suite.T(), err) // Should error for non-existent venue
 
 	// Test venue view tracking
-	err = analyticsService.TrackVenueView(1, 1, "profile")
+	err = analyticsService.TrackVenueView(context.Background(), 1, 1, "profile")
 	assert.NoError(suite.T(), err)
 
-	err = analyticsService.TrackVenueView(1, 1, "photo")
+	err = analyticsService.TrackVenueView(context.Background(), 1, 1, "photo")
 	assert.NoError(suite.T(), err)
 
-	err = analyticsService.TrackVenueView(1, 1, "phone")
+	err = analyticsService.TrackVenueView(context.Background(), 1, 1, "phone")
 	assert.NoError(suite.T(), err)
 
 	// Verify tracking was recorded
@@ -122,7 +133,7 @@ func (suite *TestSuite) testSearchAnalytics() {
 	clickedVenueID := int64(1)
 	clickPosition := 1
 
-	err := analyticsService.TrackSearch(
+	err := analyticsService.TrackSearch(context.Background(),
 		1, "best restaurants", filters, searchResults,
 		&clickedVenueID, &clickPosition)
 	assert.NoError(suite.T(), err)
@@ -136,11 +147,11 @@ func (suite *TestSuite) testSearchAnalytics() {
 	assert.True(suite.T(), searchCount > 0)
 
 	// Test search without click
-	err = analyticsService.TrackSearch(1, "no click search", filters, searchResults, nil, nil)
+	err = analyticsService.TrackSearch(context.Background(), 1, "no click search", filters, searchResults, nil, nil)
 	assert.NoError(suite.T(), err)
 
 	// Test different search types
-	err = analyticsService.TrackSearch(1, "", filters, searchResults, &clickedVenueID, &clickPosition)
+	err = analyticsService.TrackSearch(context.Background(), 1, "", filters, searchResults, &clickedVenueID, &clickPosition)
 	assert.NoError(suite.T(), err) // Empty query should be classified as filter search
 }
 
@@ -148,7 +159,7 @@ func (suite *TestSuite) testUserEngagementAnalytics() {
 	// Test platform-wide analytics
 	analyticsService := &services.AnalyticsService{}
 
-	platformAnalytics, err := analyticsService.GetPlatformAnalytics("week")
+	platformAnalytics, err := analyticsService.GetPlatformAnalytics(context.Background(), "week")
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), platformAnalytics)
 
@@ -162,7 +173,7 @@ func (suite *TestSuite) testUserEngagementAnalytics() {
 	// Test different time ranges for platform analytics
 	timeRanges := []string{"today", "week", "month", "quarter"}
 	for _, timeRange := range timeRanges {
-		analytics, err := analyticsService.GetPlatformAnalytics(timeRange)
+		analytics, err := analyticsService.GetPlatformAnalytics(context.Background(), timeRange)
 		assert.NoError(suite.T(), err, "Should get platform analytics for: %s", timeRange)
 		assert.Equal(suite.T(), timeRange, analytics.TimeRange)
 	}
@@ -172,7 +183,7 @@ func (suite *TestSuite) testPerformanceMetrics() {
 	analyticsService := &services.AnalyticsService{}
 
 	// Test top performing venues
-	topVenues, err := analyticsService.GetTopPerformingVenues("week", nil, nil, 10)
+	topVenues, err := analyticsService.GetTopPerformingVenues(context.Background(), "week", nil, nil, 10)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), topVenues)
 
@@ -190,13 +201,13 @@ func (suite *TestSuite) testPerformanceMetrics() {
 
 	// Test filtering by category
 	categoryID := int64(1)
-	topInCategory, err := analyticsService.GetTopPerformingVenues("week", &categoryID, nil, 5)
+	topInCategory, err := analyticsService.GetTopPerformingVenues(context.Background(), "week", &categoryID, nil, 5)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), topInCategory)
 
 	// Test filtering by city
 	cityID := int64(1)
-	topInCity, err := analyticsService.GetTopPerformingVenues("week", nil, &cityID, 5)
+	topInCity, err := analyticsService.GetTopPerformingVenues(context.Background(), "week", nil, &cityID, 5)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), topInCity)
 }
@@ -239,13 +250,18 @@ func (suite *TestSuite) setupRecommendationData() {
 		SET moderation_status = 'approved' 
 		WHERE user_id IN (1, 2)`)
 	suite.Require().NoError(err)
+
+	// Recommendations are only personalized with the user's consent
+	_, err = suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+		VALUES (1, 'personalized_recommendations', 'granted', CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING`)
+	suite.Require().NoError(err)
 }
 
 func (suite *TestSuite) testPersonalizedRecommendations() {
 	recommendationEngine := &services.RecommendationEngine{}
 
 	// Test basic personalized recommendations
-	context := services.RecommendationContext{
+	recContext := services.RecommendationContext{
 		UserID:      1,
 		UserLat:     &suite.testData.TestVenue1.Latitude,
 		UserLng:     &suite.testData.TestVenue1.Longitude,
@@ -256,7 +272,7 @@ func (suite *TestSuite) testPersonalizedRecommendations() {
 		Limit:       10,
 	}
 
-	recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context)
+	recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context.Background(), recContext)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), recommendations)
 
@@ -268,9 +284,9 @@ func (suite *TestSuite) testPersonalizedRecommendations() {
 		assert.True(suite.T(), rec.Venue.ID > 0)
 
 		// Verify distance calculation if location provided
-		if context.UserLat != nil && context.UserLng != nil {
+		if recContext.UserLat != nil && recContext.UserLng != nil {
 			assert.NotNil(suite.T(), rec.Venue.Distance)
-			assert.True(suite.T(), *rec.Venue.Distance <= context.MaxDistance)
+			assert.True(suite.T(), *rec.Venue.Distance <= recContext.MaxDistance)
 		}
 	}
 
@@ -283,21 +299,21 @@ func (suite *TestSuite) testPersonalizedRecommendations() {
 	}
 
 	// Test without location
-	contextNoLocation := context
+	contextNoLocation := recContext
 	contextNoLocation.UserLat = nil
 	contextNoLocation.UserLng = nil
 
-	recommendations, err = recommendationEngine.GetPersonalizedRecommendations(contextNoLocation)
+	recommendations, err = recommendationEngine.GetPersonalizedRecommendations(context.Background(), contextNoLocation)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), recommendations)
 
 	// Test different time contexts
 	timeContexts := []string{"morning", "afternoon", "evening", "night"}
 	for _, timeContext := range timeContexts {
-		testContext := context
+		testContext := recContext
 		testContext.TimeOfDay = timeContext
 
-		recommendations, err := recommendationEngine.GetPersonalizedRecommendations(testContext)
+		recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context.Background(), testContext)
 		assert.NoError(suite.T(), err, "Should get recommendations for time: %s", timeContext)
 		assert.NotNil(suite.T(), recommendations)
 	}
@@ -305,10 +321,10 @@ func (suite *TestSuite) testPersonalizedRecommendations() {
 	// Test different occasions
 	occasions := []string{"casual", "date", "business", "celebration"}
 	for _, occasion := range occasions {
-		testContext := context
+		testContext := recContext
 		testContext.Occasion = occasion
 
-		recommendations, err := recommendationEngine.GetPersonalizedRecommendations(testContext)
+		recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context.Background(), testContext)
 		assert.NoError(suite.T(), err, "Should get recommendations for occasion: %s", occasion)
 		assert.NotNil(suite.T(), recommendations)
 	}
@@ -317,35 +333,61 @@ func (suite *TestSuite) testPersonalizedRecommendations() {
 func (suite *TestSuite) testSimilarVenues() {
 	recommendationEngine := &services.RecommendationEngine{}
 
-	// Test getting similar venues
-	similarVenues, err := recommendationEngine.GetSimilarVenues(1, 5)
-	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), similarVenues)
+	// Test getting similar venues, computed on the spot without a list
+	similarVenues, err := recommendationEngine.GetSimilarVenues(context.Background(), 1, 5)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(similarVenues)
+	assert.False(suite.T(), similarVenues.Stale)
 
 	// Verify similar venues have distance calculated
-	for _, venue := range similarVenues {
+	for _, venue := range similarVenues.Venues {
 		assert.NotNil(suite.T(), venue.Distance, "Similar venues should have distance calculated")
 		assert.NotEqual(suite.T(), int64(1), venue.ID, "Similar venues should not include the reference venue")
 	}
 
+	// The list computed on the spot is stored
+	var stored int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM venue_similar WHERE venue_id = 1").Scan(&stored)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, stored)
+
 	// Test with non-existent venue
-	similarVenues, err = recommendationEngine.GetSimilarVenues(999, 5)
+	_, err = recommendationEngine.GetSimilarVenues(context.Background(), 999, 5)
 	assert.Error(suite.T(), err) // Should error for non-existent venue
 
 	// Test with different limits
 	limits := []int{1, 3, 5, 10}
 	for _, limit := range limits {
-		venues, err := recommendationEngine.GetSimilarVenues(1, limit)
+		similar, err := recommendationEngine.GetSimilarVenues(context.Background(), 1, limit)
 		assert.NoError(suite.T(), err, "Should get similar venues with limit: %d", limit)
-		assert.True(suite.T(), len(venues) <= limit, "Should respect limit")
+		assert.True(suite.T(), len(similar.Venues) <= limit, "Should respect limit")
 	}
+
+	// Lists that missed their nightly refresh are marked stale until the
+	// precomputation replaces them
+	_, err = suite.db.Exec("UPDATE venue_similar SET computed_at = CURRENT_TIMESTAMP - INTERVAL '3 days'")
+	suite.Require().NoError(err)
+	similarVenues, err = recommendationEngine.GetSimilarVenues(context.Background(), 1, 5)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), similarVenues.Stale)
+
+	suite.Require().NoError(recommendationEngine.PrecomputeSimilarVenues(context.Background()))
+	similarVenues, err = recommendationEngine.GetSimilarVenues(context.Background(), 1, 5)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), similarVenues.Stale)
+
+	var missing int
+	err = suite.db.QueryRow(`SELECT COUNT(*) FROM venues v
+		WHERE v.is_active = true AND NOT EXISTS (SELECT 1 FROM venue_similar s WHERE s.venue_id = v.id)`).Scan(&missing)
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), missing)
 }
 
 func (suite *TestSuite) testRecommendationAccuracy() {
 	recommendationEngine := &services.RecommendationEngine{}
 
 	// Test that highly rated venues appear in recommendations
-	context := services.RecommendationContext{
+	recContext := services.RecommendationContext{
 		UserID:      1,
 		UserLat:     &suite.testData.TestVenue1.Latitude,
 		UserLng:     &suite.testData.TestVenue1.Longitude,
@@ -353,7 +395,7 @@ func (suite *TestSuite) testRecommendationAccuracy() {
 		Limit:       10,
 	}
 
-	recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context)
+	recommendations, err := recommendationEngine.GetPersonalizedRecommendations(context.Background(), recContext)
 	assert.NoError(suite.T(), err)
 
 	// Verify that venues with higher ratings generally get higher scores
@@ -467,7 +509,7 @@ func (suite *TestSuite) testNearbySearch() {
 		"min_rating":  3.0,
 	}
 
-	result, err := geoService.GetNearbyVenues(lat, lng, radius, filters)
+	result, err := geoService.GetNearbyVenues(context.Background(), lat, lng, radius, filters)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), result)
 
@@ -494,14 +536,14 @@ func (suite *TestSuite) testNearbySearch() {
 	}
 
 	// Test with invalid coordinates
-	_, err = geoService.GetNearbyVenues(91.0, lng, radius, filters) // Invalid latitude
+	_, err = geoService.GetNearbyVenues(context.Background(), 91.0, lng, radius, filters) // Invalid latitude
 	assert.Error(suite.T(), err)
 
-	_, err = geoService.GetNearbyVenues(lat, 181.0, radius, filters) // Invalid longitude
+	_, err = geoService.GetNearbyVenues(context.Background(), lat, 181.0, radius, filters) // Invalid longitude
 	assert.Error(suite.T(), err)
 
 	// Test with zero radius
-	result, err = geoService.GetNearbyVenues(lat, lng, 0, filters)
+	result, err = geoService.GetNearbyVenues(context.Background(), lat, lng, 0, filters)
 	assert.NoError(suite.T(), err) // Should default to reasonable radius
 	assert.True(suite.T(), result.Radius > 0)
 }
@@ -521,7 +563,7 @@ func (suite *TestSuite) testOptimalMeetingPoint() {
 		"min_rating":  4.0,
 	}
 
-	meetingPoint, venues, err := geoService.FindOptimalMeetingPoint(locations, preferences)
+	meetingPoint, venues, err := geoService.FindOptimalMeetingPoint(context.Background(), locations, preferences)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), meetingPoint)
 	assert.NotNil(suite.T(), venues)
@@ -537,13 +579,13 @@ func (suite *TestSuite) testOptimalMeetingPoint() {
 
 	// Test with single location
 	singleLocation := []services.LatLng{locations[0]}
-	meetingPoint, venues, err = geoService.FindOptimalMeetingPoint(singleLocation, preferences)
+	meetingPoint, venues, err = geoService.FindOptimalMeetingPoint(context.Background(), singleLocation, preferences)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), locations[0].Latitude, meetingPoint.Latitude)
 	assert.Equal(suite.T(), locations[0].Longitude, meetingPoint.Longitude)
 
 	// Test with empty locations
-	_, _, err = geoService.FindOptimalMeetingPoint([]services.LatLng{}, preferences)
+	_, _, err = geoService.FindOptimalMeetingPoint(context.Background(), []services.LatLng{}, preferences)
 	assert.Error(suite.T(), err)
 }
 
@@ -554,3 +596,165 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func (suite *TestSuite) testPlatformStatsRollup() {
+	analyticsService := &services.AnalyticsService{}
+
+	// Start from a fresh rollup so the counters match the fixtures
+	_, err := suite.db.Exec("DELETE FROM platform_stats_watermarks")
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec("DELETE FROM platform_stats_rollups")
+	suite.Require().NoError(err)
+
+	// First read builds the rollups inline
+	analytics, err := analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.NotNil(suite.T(), analytics.StatsUpdatedAt)
+	assert.Equal(suite.T(), 2, analytics.TotalVenues)
+	assert.Equal(suite.T(), 2, analytics.TotalUsers)
+
+	// New rows are only counted once the job has run
+	_, err = suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3') ON CONFLICT (id) DO NOTHING")
+	suite.Require().NoError(err)
+
+	analytics, err = analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 2, analytics.TotalUsers)
+
+	suite.Require().NoError(analyticsService.RollupPlatformStats(context.Background()))
+
+	analytics, err = analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 3, analytics.TotalUsers)
+
+	// Re-running without new rows must not double count
+	suite.Require().NoError(analyticsService.RollupPlatformStats(context.Background()))
+
+	analytics, err = analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 3, analytics.TotalUsers)
+	assert.Equal(suite.T(), 2, analytics.TotalVenues)
+
+	// A check-in committed after a newer one is still counted
+	checkins := analytics.TotalCheckins
+	tx, err := suite.db.Begin()
+	suite.Require().NoError(err)
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT INTO venue_checkins (venue_id, user_id) VALUES (1, 1)")
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec("INSERT INTO venue_checkins (venue_id, user_id) VALUES (1, 2)")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(analyticsService.RollupPlatformStats(context.Background()))
+	analytics, err = analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), checkins, analytics.TotalCheckins)
+
+	suite.Require().NoError(tx.Commit())
+	suite.Require().NoError(analyticsService.RollupPlatformStats(context.Background()))
+	analytics, err = analyticsService.GetPlatformAnalytics(context.Background(), "week")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), checkins+2, analytics.TotalCheckins)
+
+	var hourlyBuckets int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM platform_stats_rollups WHERE period = 'hour'").Scan(&hourlyBuckets)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), hourlyBuckets > 0)
+}
+
+func (suite *TestSuite) testAnalyticsTimezoneBucketing() {
+	ctx := context.Background()
+	analyticsService := &services.AnalyticsService{}
+
+	_, err := suite.db.Exec(`INSERT INTO cities (id, name, country, latitude, longitude, timezone)
+		VALUES (50, 'Tokyo', 'Japan', 35.6762, 139.6503, 'Asia/Tokyo') ON CONFLICT (id) DO NOTHING`)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active)
+		VALUES (50, 'Tokyo Izakaya', 'tokyo-izakaya', '1 Shibuya, Tokyo', 50, 35.6595, 139.7005, 1, true)
+		ON CONFLICT (id) DO NOTHING`)
+	suite.Require().NoError(err)
+	defer func() {
+		for _, query := range []string{
+			"DELETE FROM venue_checkins WHERE venue_id = 50",
+			"DELETE FROM venue_reviews WHERE venue_id = 50",
+			"DELETE FROM venue_analytics WHERE venue_id = 50",
+			"DELETE FROM venues WHERE id = 50",
+			"DELETE FROM cities WHERE id = 50",
+		} {
+			suite.db.Exec(query)
+		}
+	}()
+
+	// 22:00 UTC is the next morning in Tokyo
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	suite.Require().NoError(err)
+	visitedAt := time.Now().UTC().AddDate(0, 0, -3).Truncate(24 * time.Hour).Add(22 * time.Hour)
+	local := visitedAt.In(tokyo)
+
+	_, err = suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id, created_at)
+		VALUES (50, 1, $1::timestamptz), (50, 2, $1::timestamptz)`, visitedAt)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status, created_at)
+		VALUES (50, 1, 4.0, 'approved', $1::timestamptz)`, visitedAt)
+	suite.Require().NoError(err)
+
+	analytics, err := analyticsService.GetVenueAnalytics(ctx, 50, "month")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Asia/Tokyo", analytics.Timezone)
+	assert.Equal(suite.T(), map[int]int{local.Hour(): 2}, analytics.PopularHours)
+	assert.Equal(suite.T(), map[string]int{local.Weekday().String(): 2}, analytics.PopularDays)
+	suite.Require().Len(analytics.RatingTrend, 1)
+	assert.Equal(suite.T(), local.Format("2006-01-02"), analytics.RatingTrend[0].Date)
+
+	// Views count towards the venue's local day
+	suite.Require().NoError(analyticsService.TrackVenueView(ctx, 50, 1, "profile"))
+	var views int
+	err = suite.db.QueryRow(`SELECT profile_views FROM venue_analytics
+		WHERE venue_id = 50 AND date = (CURRENT_TIMESTAMP AT TIME ZONE 'Asia/Tokyo')::date`).Scan(&views)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, views)
+
+	// Venues without a known time zone use UTC
+	assert.Equal(suite.T(), time.UTC, models.CityLocation("Not/A_Zone"))
+	location, err := models.GetVenueLocation(ctx, 1)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), time.UTC, location)
+}
+
+func (suite *TestSuite) testOwnerVenueAnalytics() {
+	_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+	suite.Require().NoError(err)
+	defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+	// Owners see their own venue's ranks, not its competitors
+	w := suite.makeGETRequest("/v1/analytics/venues/1?time_range=month")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var analytics services.VenueAnalytics
+	suite.parseJSONResponse(w, &analytics)
+	assert.Equal(suite.T(), int64(1), analytics.VenueID)
+	assert.Equal(suite.T(), "month", analytics.TimeRange)
+	assert.Equal(suite.T(), services.AnalyticsScopeOwner, analytics.Scope)
+	assert.Equal(suite.T(), 1, analytics.CategoryRank)
+	assert.Empty(suite.T(), analytics.Competitors)
+	assert.NotContains(suite.T(), w.Body.String(), "competitors")
+
+	w = suite.makeGETRequest("/v1/analytics/venues/1?time_range=decade")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Other venues are off limits
+	w = suite.makeGETRequest("/v1/analytics/venues/2")
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	w = suite.makeGETRequest("/v1/analytics/venues/999")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// The admin scope adds the venues competing in the same category and city
+	competitors, err := new(services.AnalyticsService).GetVenueCompetitors(context.Background(), 2, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(competitors, 2)
+	assert.Equal(suite.T(), int64(1), competitors[0].VenueID)
+	assert.Equal(suite.T(), 1, competitors[0].Rank)
+	assert.Equal(suite.T(), int64(2), competitors[1].VenueID)
+	assert.Equal(suite.T(), 2, competitors[1].Rank)
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"voting-app/app/middlewares"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// v2Response is the v2 envelope with its data left undecoded
+type v2Response struct {
+	Data json.RawMessage `json:"data"`
+	Meta struct {
+		APIVersion string                      `json:"apiVersion"`
+		Pagination *serializers.PaginationInfo `json:"pagination"`
+	} `json:"meta"`
+	Errors []serializers.EnvelopeError `json:"errors"`
+}
+
+// TestAPIVersioning tests the v2 envelope over the v1 handlers
+func (suite *TestSuite) TestAPIVersioning() {
+	suite.Run("APIVersioning", func() {
+		// v1 is unchanged
+		w := suite.makeGETRequest("/v1/venues/search?limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "1", w.Header().Get(middlewares.APIVersionHeader))
+		var v1 serializers.VenueSearchResponse
+		suite.parseJSONResponse(w, &v1)
+		assert.Len(suite.T(), v1.Venues, 1)
+		assert.Equal(suite.T(), 2, v1.Pagination.Total)
+
+		// Paginated responses move their pagination to the meta
+		w = suite.makeGETRequest("/v2/venues/search?limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), "2", w.Header().Get(middlewares.APIVersionHeader))
+		var response v2Response
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), "2", response.Meta.APIVersion)
+		assert.Empty(suite.T(), response.Errors)
+		suite.Require().NotNil(response.Meta.Pagination)
+		assert.Equal(suite.T(), 2, response.Meta.Pagination.Total)
+		assert.True(suite.T(), response.Meta.Pagination.HasNext)
+		var search map[string]json.RawMessage
+		suite.Require().NoError(json.Unmarshal(response.Data, &search))
+		assert.NotContains(suite.T(), search, "pagination")
+		var venues []models.Venue
+		suite.Require().NoError(json.Unmarshal(search["venues"], &venues))
+		assert.Len(suite.T(), venues, 1)
+
+		// Arrays become the data as they are
+		w = suite.makeGETRequest("/v2/venues/categories")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		response = v2Response{}
+		suite.parseJSONResponse(w, &response)
+		var categories []models.VenueCategory
+		suite.Require().NoError(json.Unmarshal(response.Data, &categories))
+		assert.NotEmpty(suite.T(), categories)
+		assert.Nil(suite.T(), response.Meta.Pagination)
+
+		// Venue routes share the id wildcard
+		w = suite.makeGETRequest("/v2/venues/1/reviews")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		response = v2Response{}
+		suite.parseJSONResponse(w, &response)
+		assert.NotNil(suite.T(), response.Meta.Pagination)
+
+		// Errors are listed without data
+		for _, url := range []string{"/v2/venues/abc", "/v2/campaigns/999999"} {
+			w = suite.makeGETRequest(url)
+			assert.GreaterOrEqual(suite.T(), w.Code, http.StatusBadRequest, url)
+			response = v2Response{}
+			suite.parseJSONResponse(w, &response)
+			assert.Equal(suite.T(), "null", string(response.Data), url)
+			suite.Require().Len(response.Errors, 1, url)
+			assert.NotEmpty(suite.T(), response.Errors[0].Code, url)
+			assert.NotEmpty(suite.T(), response.Errors[0].Message, url)
+		}
+
+		w = suite.makePOSTRequest("/v2/campaigns/999999/test_user_1/vote", map[string]interface{}{"venueId": 1})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		response = v2Response{}
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Errors, 1)
+		assert.Equal(suite.T(), serializers.NotFound, response.Errors[0].Code)
+	})
+}
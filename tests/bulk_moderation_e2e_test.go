@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBulkReviewModeration tests moderating many reviews in one transaction
+// with per-review results and audit entries
+func (suite *TestSuite) TestBulkReviewModeration() {
+	suite.Run("Bulk Review Moderation", func() {
+		ctx := context.Background()
+
+		reviewIDs := make([]int64, 3)
+		for i := range reviewIDs {
+			err := suite.db.QueryRow(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+				VALUES (1, $1, 4, 'pending') RETURNING id`, i%2+1).Scan(&reviewIDs[i])
+			suite.Require().NoError(err)
+		}
+
+		// The endpoint is for administrators only
+		w := suite.makePOSTRequest("/v1/admin/reviews/bulk", serializers.BulkModerationRequest{
+			ReviewIDs: reviewIDs, Action: models.ModerationApprove,
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		invalid := serializers.BulkModerationRequest{ReviewIDs: reviewIDs, Action: "delete"}
+		_, ok := invalid.Validate()
+		assert.False(suite.T(), ok)
+		invalid = serializers.BulkModerationRequest{Action: models.ModerationFlag}
+		_, ok = invalid.Validate()
+		assert.False(suite.T(), ok)
+		invalid = serializers.BulkModerationRequest{ReviewIDs: []int64{reviewIDs[0], -1}, Action: models.ModerationFlag}
+		_, ok = invalid.Validate()
+		assert.False(suite.T(), ok)
+
+		// Missing reviews are reported without failing the batch, repeated
+		// ones are moderated once
+		now := time.Now().UTC()
+		ids := []int64{reviewIDs[0], reviewIDs[1], 999999, reviewIDs[0]}
+		results, err := models.BulkModerateReviews(ctx, 1, ids, models.ModerationApprove, now)
+		suite.Require().NoError(err)
+		suite.Require().Len(results, 3)
+		assert.Equal(suite.T(), models.BulkResultUpdated, results[0].Result)
+		assert.Equal(suite.T(), "approved", results[0].ModerationStatus)
+		assert.Equal(suite.T(), models.BulkResultUpdated, results[1].Result)
+		assert.Equal(suite.T(), int64(999999), results[2].ReviewID)
+		assert.Equal(suite.T(), models.BulkResultNotFound, results[2].Result)
+
+		response := serializers.NewBulkModerationResponse(models.ModerationApprove, results)
+		assert.Equal(suite.T(), 2, response.Updated)
+		assert.Equal(suite.T(), 1, response.NotFound)
+
+		var status string
+		var moderatedBy int64
+		err = suite.db.QueryRow("SELECT moderation_status, moderated_by FROM venue_reviews WHERE id = $1",
+			reviewIDs[1]).Scan(&status, &moderatedBy)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "approved", status)
+		assert.Equal(suite.T(), int64(1), moderatedBy)
+
+		// Flagging keeps the status, approving again clears the flag
+		results, err = models.BulkModerateReviews(ctx, 1, reviewIDs, models.ModerationFlag, now)
+		suite.Require().NoError(err)
+		for _, result := range results {
+			assert.Equal(suite.T(), models.BulkResultUpdated, result.Result)
+			assert.True(suite.T(), result.IsFlagged)
+		}
+		assert.Equal(suite.T(), "approved", results[0].ModerationStatus)
+		assert.Equal(suite.T(), "pending", results[2].ModerationStatus)
+
+		results, err = models.BulkModerateReviews(ctx, 1, reviewIDs, models.ModerationFlag, now)
+		suite.Require().NoError(err)
+		for _, result := range results {
+			assert.Equal(suite.T(), models.BulkResultUnchanged, result.Result)
+		}
+
+		results, err = models.BulkModerateReviews(ctx, 1, reviewIDs[:1], models.ModerationReject, now)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "rejected", results[0].ModerationStatus)
+
+		// Only changes are audited, oldest first
+		entries, err := models.GetAuditEntries(ctx, models.AuditTargetReview, reviewIDs[0])
+		suite.Require().NoError(err)
+		suite.Require().Len(entries, 3)
+		assert.Equal(suite.T(), models.ModerationApprove, entries[0].Action)
+		assert.Equal(suite.T(), "pending", entries[0].FromState)
+		assert.Equal(suite.T(), "approved", entries[0].ToState)
+		assert.Equal(suite.T(), "approved/flagged", entries[1].ToState)
+		assert.Equal(suite.T(), "rejected/flagged", entries[2].ToState)
+		assert.Equal(suite.T(), int64(1), entries[2].ModeratorID)
+
+		entries, err = models.GetAuditEntries(ctx, models.AuditTargetReview, 999999)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), entries)
+	})
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignAudit tests excluding fraudulent votes and the signed
+// certificate of the final results
+func (suite *TestSuite) TestCampaignAudit() {
+	suite.Run("Campaign Audit", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (90, 'Certified Brunch', 'best_restaurant', 1, $1, $2, 3, true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		var voteIDs []int64
+		for _, vote := range []struct{ user, venue int64 }{{1, 1}, {2, 1}, {2, 2}} {
+			campaignVote := &models.CampaignVote{CampaignID: 90, UserID: vote.user, VenueID: vote.venue}
+			suite.Require().NoError(campaignVote.Create(context.Background()))
+			voteIDs = append(voteIDs, campaignVote.ID)
+		}
+
+		// Nothing is certified while voting runs
+		w := suite.makeGETRequest("/v1/campaigns/90/audit")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.makePUTRequest("/v1/admin/campaigns/90/votes/1/exclusion", map[string]interface{}{"reason": "fraud"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// The second vote for venue 1 turns out to be bought
+		exclusion := &models.ExcludedCampaignVote{VoteID: voteIDs[1], CampaignID: 90, Reason: models.ExclusionFraud, ExcludedBy: 1}
+		suite.Require().NoError(exclusion.Exclude(context.Background()))
+		// Excluding it again doesn't uncount it twice
+		suite.Require().NoError(exclusion.Exclude(context.Background()))
+
+		missing := &models.ExcludedCampaignVote{VoteID: voteIDs[2] + 100, CampaignID: 90, Reason: models.ExclusionFraud}
+		assert.Error(suite.T(), missing.Exclude(context.Background()))
+
+		campaign := &models.VotingCampaign{ID: 90}
+		suite.Require().NoError(campaign.GetByID(context.Background()))
+		assert.Equal(suite.T(), 2, campaign.TotalVotes)
+
+		resultService := &services.CampaignResultService{}
+		suite.Require().NoError(resultService.Snapshot(context.Background(), campaign, campaign.EndDate))
+
+		w = suite.makeGETRequest("/v1/campaigns/90/audit")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(suite.T(), w.Header().Get("Content-Disposition"), `filename="campaign-90-audit.json"`)
+
+		var file models.CampaignAuditFile
+		suite.parseJSONResponse(w, &file)
+		auditService := &services.CampaignAuditService{}
+		assert.True(suite.T(), auditService.Verify(&file))
+
+		// Anyone can verify the certificate with the published key
+		w = suite.makeGETRequest("/v1/campaigns/audit-key")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var key serializers.CampaignAuditKeyResponse
+		suite.parseJSONResponse(w, &key)
+		assert.Equal(suite.T(), "Ed25519", key.Algorithm)
+		assert.Equal(suite.T(), key.Algorithm, file.SignatureAlgorithm)
+		block, _ := pem.Decode([]byte(key.PublicKey))
+		suite.Require().NotNil(block)
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		suite.Require().NoError(err)
+		signature, err := hex.DecodeString(file.Signature)
+		suite.Require().NoError(err)
+		assert.True(suite.T(), ed25519.Verify(publicKey.(ed25519.PublicKey), file.Certificate, signature))
+
+		var audit models.CampaignAudit
+		suite.Require().NoError(json.Unmarshal(file.Certificate, &audit))
+		assert.Equal(suite.T(), int64(90), audit.CampaignID)
+		assert.Equal(suite.T(), 2, audit.TotalVotes)
+		assert.Equal(suite.T(), 1, audit.Excluded.Votes)
+		assert.Equal(suite.T(), 1, audit.Excluded.ByReason[models.ExclusionFraud])
+		assert.Equal(suite.T(), 0, audit.Excluded.ByReason[models.ExclusionAutomated])
+		assert.Equal(suite.T(), 2, audit.VoteSet.Ballots)
+		assert.Len(suite.T(), audit.VoteSet.Hash, 64)
+		suite.Require().Len(audit.Categories, 1)
+		suite.Require().Len(audit.Categories[0].Tallies, 2)
+		assert.Equal(suite.T(), 1, audit.Categories[0].Tallies[0].Votes)
+		assert.Equal(suite.T(), 1, audit.Categories[0].Tallies[1].Votes)
+
+		// Tampering breaks the signature
+		tampered := file
+		tampered.Certificate = append(append([]byte{}, file.Certificate...), ' ')
+		assert.False(suite.T(), auditService.Verify(&tampered))
+
+		// The certificate is issued once
+		w = suite.makeGETRequest("/v1/campaigns/90/audit")
+		var again models.CampaignAuditFile
+		suite.parseJSONResponse(w, &again)
+		assert.Equal(suite.T(), file.Signature, again.Signature)
+
+		// Finalized votes can't be excluded anymore
+		late := &models.ExcludedCampaignVote{VoteID: voteIDs[2], CampaignID: 90, Reason: models.ExclusionAutomated}
+		assert.Equal(suite.T(), models.ErrCampaignFinalized, late.Exclude(context.Background()))
+
+		w = suite.makeGETRequest("/v1/campaigns/999/audit")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
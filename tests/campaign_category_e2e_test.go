@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignCategories tests category scoped votes and per category results
+func (suite *TestSuite) TestCampaignCategories() {
+	suite.Run("Campaign Categories End-to-End", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, allow_multiple_categories, is_active)
+			VALUES (30, 'Best of the City', 'best_of_city', 1, $1, $2, 1, false, true),
+			       (31, 'Best of the Bay', 'best_of_city', 1, $1, $2, 1, true, true),
+			       (32, 'Best Restaurant', 'best_restaurant', 1, $1, $2, 1, false, true)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		_, err = suite.db.Exec(`INSERT INTO campaign_categories
+			(id, campaign_id, name, slug, venue_category_id, sort_order)
+			VALUES (300, 30, 'Best Brunch', 'best-brunch', 1, 1),
+			       (301, 30, 'Best Bar', 'best-bar', NULL, 2),
+			       (310, 31, 'Best Pizza', 'best-pizza', NULL, 1),
+			       (311, 31, 'Best Coffee', 'best-coffee', NULL, 2)`)
+		suite.Require().NoError(err)
+
+		suite.testCategoryVoteRules()
+		suite.testCategoryResults()
+		suite.testCategoryResultSnapshots()
+		suite.testCreateCampaignCategoryForbidden()
+	})
+}
+
+func (suite *TestSuite) testCategoryVoteRules() {
+	// A campaign with categories requires one of its categories
+	w := suite.makePOSTRequest("/v1/campaigns/30/test_user_1/vote", map[string]interface{}{
+		"venueId": 1,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/campaigns/30/test_user_1/vote", map[string]interface{}{
+		"venueId":    1,
+		"categoryId": 310,
+	})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// A campaign without categories rejects one
+	w = suite.makePOSTRequest("/v1/campaigns/32/test_user_1/vote", map[string]interface{}{
+		"venueId":    1,
+		"categoryId": 300,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/campaigns/30/test_user_1/vote", map[string]interface{}{
+		"venueId":    1,
+		"categoryId": 300,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response serializers.SubmitCampaignVoteResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().NotNil(response.Vote.CategoryID)
+	assert.Equal(suite.T(), int64(300), *response.Vote.CategoryID)
+	assert.Equal(suite.T(), int64(300), response.Receipt.CategoryID)
+
+	// The receipt covers the category
+	w = suite.makePOSTRequest("/v1/receipts/verify", response.Receipt)
+	var verification serializers.VerifyReceiptResponse
+	suite.parseJSONResponse(w, &verification)
+	assert.True(suite.T(), verification.Valid)
+	assert.True(suite.T(), verification.Recorded)
+
+	tampered := response.Receipt
+	tampered.CategoryID = 301
+	w = suite.makePOSTRequest("/v1/receipts/verify", tampered)
+	suite.parseJSONResponse(w, &verification)
+	assert.False(suite.T(), verification.Valid)
+
+	// The vote limit applies per category
+	w = suite.makePOSTRequest("/v1/campaigns/30/test_user_1/vote", map[string]interface{}{
+		"venueId":    2,
+		"categoryId": 300,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.AlreadyVoted, errorResponse.Code)
+
+	// Campaign 30 doesn't allow voting in several categories
+	w = suite.makePOSTRequest("/v1/campaigns/30/test_user_1/vote", map[string]interface{}{
+		"venueId":    2,
+		"categoryId": 301,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.AlreadyVoted, errorResponse.Code)
+
+	// Campaign 31 does
+	w = suite.makePOSTRequest("/v1/campaigns/31/test_user_1/vote", map[string]interface{}{
+		"venueId":    1,
+		"categoryId": 310,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	w = suite.makePOSTRequest("/v1/campaigns/31/test_user_1/vote", map[string]interface{}{
+		"venueId":    2,
+		"categoryId": 311,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+}
+
+func (suite *TestSuite) testCategoryResults() {
+	_, err := suite.db.Exec(`INSERT INTO campaign_votes
+		(campaign_id, campaign_category_id, venue_id, user_id, confidence_score)
+		VALUES (31, 310, 1, 2, 4.0), (31, 311, 1, 2, 5.0)`)
+	suite.Require().NoError(err)
+
+	w := suite.makeGETRequest("/v1/campaign-results/31")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var results models.CampaignResults
+	suite.parseJSONResponse(w, &results)
+	assert.Equal(suite.T(), 4, results.TotalVotes)
+	assert.False(suite.T(), results.IsFinal)
+	suite.Require().Len(results.Categories, 2)
+
+	pizza := results.Categories[0]
+	assert.Equal(suite.T(), "best-pizza", pizza.Slug)
+	assert.Equal(suite.T(), 2, pizza.TotalVotes)
+	suite.Require().NotNil(pizza.WinnerVenueID)
+	assert.Equal(suite.T(), int64(1), *pizza.WinnerVenueID)
+
+	// Tied venues share the rank, the higher confidence wins
+	coffee := results.Categories[1]
+	suite.Require().Len(coffee.Standings, 2)
+	assert.Equal(suite.T(), 1, coffee.Standings[0].Rank)
+	assert.Equal(suite.T(), 1, coffee.Standings[1].Rank)
+	suite.Require().NotNil(coffee.WinnerVenueID)
+	assert.Equal(suite.T(), int64(1), *coffee.WinnerVenueID)
+
+	w = suite.makeGETRequest("/v1/campaign-results/999")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) testCategoryResultSnapshots() {
+	campaign := &models.VotingCampaign{ID: 31}
+	suite.Require().NoError(campaign.GetByID(context.Background()))
+
+	resultService := &services.CampaignResultService{}
+	suite.Require().NoError(resultService.Snapshot(context.Background(), campaign, time.Now().UTC()))
+
+	w := suite.makeGETRequest("/v1/campaign-results/31/snapshots")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.CampaignSnapshotsResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Snapshots, 1)
+	assert.False(suite.T(), response.Snapshots[0].IsFinal)
+	assert.Equal(suite.T(), 4, response.Snapshots[0].TotalVotes)
+
+	// Once voting ends the winners are recorded
+	suite.Require().NoError(resultService.Snapshot(context.Background(), campaign, campaign.EndDate))
+
+	var winnerVenueID int64
+	err := suite.db.QueryRow("SELECT winner_venue_id FROM campaign_categories WHERE id = 311").Scan(&winnerVenueID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), winnerVenueID)
+
+	w = suite.makeGETRequest("/v1/campaign-results/31/snapshots")
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Snapshots, 2)
+	assert.True(suite.T(), response.Snapshots[0].IsFinal)
+
+	w = suite.makeGETRequest("/v1/campaign-results/31/snapshots?limit=1")
+	response = serializers.CampaignSnapshotsResponse{}
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Snapshots, 1)
+
+	w = suite.makeGETRequest("/v1/campaign-results/31/snapshots?limit=500")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Finalized campaigns are no longer picked up by the job
+	pending, err := models.GetCampaignsPendingResults(context.Background(), time.Now().UTC())
+	suite.Require().NoError(err)
+	for _, campaign := range pending {
+		assert.NotEqual(suite.T(), int64(31), campaign.ID)
+	}
+}
+
+func (suite *TestSuite) testCreateCampaignCategoryForbidden() {
+	w := suite.makePOSTRequest("/v1/admin/campaigns/30/categories", map[string]interface{}{
+		"name": "Best Dessert",
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignVoting tests campaign votes and signed vote receipts
+func (suite *TestSuite) TestCampaignVoting() {
+	suite.Run("Campaign Voting End-to-End", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, category_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (20, 'Best Restaurant', 'best_restaurant', 1, 1, $1, $2, 2, true),
+			       (21, 'Closed Campaign', 'best_restaurant', 1, 1, $3, $4, 1, true)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour),
+			now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+		suite.Require().NoError(err)
+
+		suite.testSubmitCampaignVoteReceipt()
+		suite.testCampaignVoteReceipts()
+		suite.testCampaignVoteRules()
+		suite.testConcurrentCampaignVotes()
+	})
+}
+
+func (suite *TestSuite) testSubmitCampaignVoteReceipt() {
+	w := suite.makePOSTRequest("/v1/campaigns/20/test_user_1/vote", map[string]interface{}{
+		"venueId":         1,
+		"reason":          "Best pasta in town",
+		"confidenceScore": 4.5,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response serializers.SubmitCampaignVoteResponse
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), int64(1), response.Vote.VenueID)
+	assert.Equal(suite.T(), 1, response.RemainingVotes)
+	assert.Equal(suite.T(), "campaign", response.Receipt.Kind)
+	assert.Equal(suite.T(), response.Vote.ID, response.Receipt.VoteID)
+	assert.Equal(suite.T(), int64(20), response.Receipt.CampaignID)
+	assert.NotEmpty(suite.T(), response.Receipt.Signature)
+
+	var totalVotes int
+	err := suite.db.QueryRow("SELECT total_votes FROM voting_campaigns WHERE id = 20").Scan(&totalVotes)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, totalVotes)
+
+	// The receipt verifies and the vote is recorded
+	w = suite.makePOSTRequest("/v1/receipts/verify", response.Receipt)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var verification serializers.VerifyReceiptResponse
+	suite.parseJSONResponse(w, &verification)
+	assert.True(suite.T(), verification.Valid)
+	assert.True(suite.T(), verification.Recorded)
+
+	// A tampered receipt is rejected
+	tampered := response.Receipt
+	tampered.VenueID = 2
+	w = suite.makePOSTRequest("/v1/receipts/verify", tampered)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	suite.parseJSONResponse(w, &verification)
+	assert.False(suite.T(), verification.Valid)
+
+	// Voting for the same venue twice is rejected
+	w = suite.makePOSTRequest("/v1/campaigns/20/test_user_1/vote", map[string]interface{}{
+		"venueId": 1,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.AlreadyVoted, errorResponse.Code)
+}
+
+func (suite *TestSuite) testCampaignVoteReceipts() {
+	w := suite.makeGETRequest("/v1/campaigns/20/test_user_1/receipts")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.CampaignReceiptsResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Receipts, 1)
+	assert.Equal(suite.T(), int64(1), response.Receipts[0].VenueID)
+
+	// Re-issued receipts verify as well
+	w = suite.makePOSTRequest("/v1/receipts/verify", response.Receipts[0])
+	var verification serializers.VerifyReceiptResponse
+	suite.parseJSONResponse(w, &verification)
+	assert.True(suite.T(), verification.Valid)
+
+	// A receipt whose vote was removed is valid but no longer recorded
+	_, err := suite.db.Exec("DELETE FROM campaign_votes WHERE id = $1", response.Receipts[0].VoteID)
+	suite.Require().NoError(err)
+
+	w = suite.makePOSTRequest("/v1/receipts/verify", response.Receipts[0])
+	suite.parseJSONResponse(w, &verification)
+	assert.True(suite.T(), verification.Valid)
+	assert.False(suite.T(), verification.Recorded)
+
+	w = suite.makeGETRequest("/v1/campaigns/999/test_user_1/receipts")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) testCampaignVoteRules() {
+	w := suite.makePOSTRequest("/v1/campaigns/21/test_user_1/vote", map[string]interface{}{
+		"venueId": 1,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.CampaignClosed, errorResponse.Code)
+
+	w = suite.makePOSTRequest("/v1/campaigns/20/test_user_1/vote", map[string]interface{}{
+		"venueId":         2,
+		"confidenceScore": 9,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/receipts/verify", map[string]interface{}{
+		"kind":   "mystery",
+		"voteId": 1,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+// testConcurrentCampaignVotes casts votes at once, the limits must hold
+// as every vote is counted while it's stored
+func (suite *TestSuite) testConcurrentCampaignVotes() {
+	now := time.Now()
+	_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+		(id, title, campaign_type, start_date, end_date, max_votes_per_user, allow_multiple_categories, is_active)
+		VALUES (22, 'Busy Campaign', 'best_restaurant', $1, $2, 2, false, true),
+		       (23, 'Single Category Campaign', 'best_restaurant', $1, $2, 1, false, true)`,
+		now.Add(-time.Hour), now.Add(24*time.Hour))
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active)
+		SELECT g, 'Busy Venue ' || g, 'busy-venue-' || g, g || ' Main St', 1, 37.7749, -122.4194, 1, true
+		FROM generate_series(301, 308) g`)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`INSERT INTO campaign_categories (id, campaign_id, name, slug)
+		VALUES (230, 23, 'Brunch', 'brunch'), (231, 23, 'Dinner', 'dinner')`)
+	suite.Require().NoError(err)
+
+	castAtOnce := func(votes []*models.CampaignVote) (created int, errs []error) {
+		var wg sync.WaitGroup
+		results := make([]error, len(votes))
+		for i, vote := range votes {
+			wg.Add(1)
+			go func(i int, vote *models.CampaignVote) {
+				defer wg.Done()
+				results[i] = vote.Create(context.Background())
+			}(i, vote)
+		}
+		wg.Wait()
+		for _, err := range results {
+			if err == nil {
+				created++
+			} else {
+				errs = append(errs, err)
+			}
+		}
+		return created, errs
+	}
+
+	var votes []*models.CampaignVote
+	for venueID := int64(301); venueID <= 308; venueID++ {
+		votes = append(votes, &models.CampaignVote{CampaignID: 22, VenueID: venueID, UserID: 2, MaxVotes: 2})
+	}
+	created, errs := castAtOnce(votes)
+	assert.Equal(suite.T(), 2, created)
+	for _, err := range errs {
+		assert.Equal(suite.T(), models.ErrVoteLimitReached, err)
+	}
+	var count int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM campaign_votes WHERE campaign_id = 22 AND user_id = 2").Scan(&count)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 2, count)
+
+	brunch, dinner := int64(230), int64(231)
+	created, errs = castAtOnce([]*models.CampaignVote{
+		{CampaignID: 23, CategoryID: &brunch, VenueID: 301, UserID: 2, MaxVotes: 1, SingleCategory: true},
+		{CampaignID: 23, CategoryID: &dinner, VenueID: 302, UserID: 2, MaxVotes: 1, SingleCategory: true},
+	})
+	assert.Equal(suite.T(), 1, created)
+	suite.Require().Len(errs, 1)
+	assert.Equal(suite.T(), models.ErrOtherCategoryVoted, errs[0])
+}
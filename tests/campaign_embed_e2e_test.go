@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignEmbedWidget tests the embedded results widget, served only to
+// the origins of its signed token
+func (suite *TestSuite) TestCampaignEmbedWidget() {
+	suite.Run("Campaign Embed Widget", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, start_date, end_date, max_votes_per_user, is_active, voting_mode, hide_results_until_end)
+			VALUES (63, 'Best Brunch', 'best_restaurant', $1, $2, 1, true, 'standard', false),
+			       (64, 'Secret Brunch', 'best_restaurant', $1, $2, 1, true, 'standard', true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO campaign_votes (campaign_id, venue_id, user_id)
+			VALUES (63, 1, 1), (63, 1, 2), (63, 2, 1), (64, 1, 1)`)
+		suite.Require().NoError(err)
+
+		w := suite.makePOSTRequest("/v1/admin/campaigns/63/embed-tokens", map[string]interface{}{
+			"origins": []string{"https://news.example.com"},
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		embedService := &services.CampaignEmbedService{}
+		origins := []string{"https://news.example.com"}
+		token, err := embedService.Issue(63, origins, now.Add(time.Hour))
+		suite.Require().NoError(err)
+
+		// Allowed pages get the public standings
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?token="+token, "Origin", "https://news.example.com")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var widget services.CampaignWidget
+		suite.parseJSONResponse(w, &widget)
+		assert.Equal(suite.T(), "Best Brunch", widget.Title)
+		assert.Equal(suite.T(), 3, widget.TotalVotes)
+		suite.Require().NotEmpty(widget.Categories)
+		suite.Require().Len(widget.Categories[0].Standings, 2)
+		assert.Equal(suite.T(), 2, widget.Categories[0].Standings[0].Votes)
+		assert.NotContains(suite.T(), w.Body.String(), "venueId")
+		assert.Equal(suite.T(), "https://news.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+		// Iframes are told by their Referer and may only be framed there
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?format=html&token="+token, "Referer", "https://news.example.com/articles/brunch")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.True(suite.T(), strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+		assert.Contains(suite.T(), w.Body.String(), "Best Brunch")
+		assert.Contains(suite.T(), w.Header().Get("Content-Security-Policy"), "frame-ancestors https://news.example.com")
+
+		// Other origins, other campaigns and bad tokens are refused
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?token="+token, "Origin", "https://evil.example.com")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?token="+token, "", "")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/64?token="+token, "Origin", "https://news.example.com")
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?token="+token+"x", "Origin", "https://news.example.com")
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		expired, err := embedService.Issue(63, origins, now.Add(-time.Minute))
+		suite.Require().NoError(err)
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/63?token="+expired, "Origin", "https://news.example.com")
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+		// Embargoed standings stay hidden
+		hidden, err := embedService.Issue(64, origins, now.Add(time.Hour))
+		suite.Require().NoError(err)
+		w = suite.makeWidgetRequest("/v1/embed/campaigns/64?token="+hidden, "Origin", "https://news.example.com")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &widget)
+		assert.True(suite.T(), widget.ResultsHidden)
+		for _, category := range widget.Categories {
+			assert.Empty(suite.T(), category.Standings)
+		}
+	})
+}
+
+// makeWidgetRequest gets the widget as a page of the origin would
+func (suite *TestSuite) makeWidgetRequest(url, header, value string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
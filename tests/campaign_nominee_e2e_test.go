@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignNominees tests generating "best of" campaigns and voting for
+// their nominees
+func (suite *TestSuite) TestCampaignNominees() {
+	suite.Run("Campaign Nominees End-to-End", func() {
+		generator := new(services.CampaignGeneratorService)
+		proposal := services.CampaignProposal{CityID: 1, CategoryID: 1, Quarter: "2026-Q3"}
+
+		_, _, err := generator.Generate(context.Background(), proposal, time.Now())
+		assert.Equal(suite.T(), services.ErrNotEnoughCandidates, err)
+		_, _, err = generator.Generate(context.Background(), services.CampaignProposal{CityID: 1, CategoryID: 1, Quarter: "Q3"}, time.Now())
+		assert.Equal(suite.T(), services.ErrInvalidQuarter, err)
+
+		_, err = suite.db.Exec(`INSERT INTO snapp_users (id, snapp_id) VALUES (50, 'nominee_user_50'), (51, 'nominee_user_51'), (52, 'nominee_user_52')
+			ON CONFLICT (id) DO NOTHING`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active)
+			VALUES (60, 'Few Reviews Diner', 'few-reviews-diner', '60 Main St', 1, 37.7749, -122.4194, 1, true)`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+			VALUES (1, 1, 5.0, 'approved'), (1, 2, 4.5, 'approved'), (1, 50, 5.0, 'approved'), (1, 51, 4.5, 'approved'),
+			       (2, 1, 3.0, 'approved'), (2, 2, 3.5, 'approved'), (2, 50, 3.0, 'approved'), (2, 51, 5.0, 'pending'),
+			       (60, 1, 5.0, 'approved'), (60, 2, 5.0, 'approved'), (60, 50, 5.0, 'pending')`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_analytics (venue_id, date, profile_views)
+			VALUES (1, CURRENT_DATE - 10, 120), (1, CURRENT_DATE - 120, 40), (2, CURRENT_DATE - 10, 10)`)
+		suite.Require().NoError(err)
+
+		campaign, nominees, err := generator.Generate(context.Background(), proposal, time.Now())
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "Best Restaurants in San Francisco Q3 2026", campaign.Title)
+		assert.Equal(suite.T(), "best_of", campaign.CampaignType)
+		assert.False(suite.T(), campaign.IsActive)
+		assert.Equal(suite.T(), time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), campaign.StartDate.UTC())
+		// Venues with fewer than three approved reviews aren't nominated
+		suite.Require().Len(nominees, 2)
+		assert.Equal(suite.T(), int64(1), nominees[0].VenueID)
+		assert.Equal(suite.T(), int64(2), nominees[1].VenueID)
+		assert.Greater(suite.T(), *nominees[0].Score, *nominees[1].Score)
+
+		suite.testCampaignNomineesUnpublished(campaign.ID)
+
+		// Generating and publishing are admin only
+		w := suite.makePOSTRequest("/v1/admin/campaigns/auto-generate", map[string]interface{}{
+			"cityId": 1, "categoryId": 1,
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makePOSTRequest(fmt.Sprintf("/v1/admin/campaigns/%d/publish", campaign.ID), nil)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		now := time.Now()
+		campaign.StartDate, campaign.EndDate = now.Add(-1*time.Hour), now.Add(24*time.Hour)
+		_, err = suite.db.Exec("UPDATE voting_campaigns SET start_date = $2, end_date = $3 WHERE id = $1",
+			campaign.ID, campaign.StartDate, campaign.EndDate)
+		suite.Require().NoError(err)
+		suite.Require().NoError(campaign.Publish(context.Background()))
+		assert.True(suite.T(), campaign.IsActive)
+
+		w = suite.makeGETRequest(fmt.Sprintf("/v1/campaigns/%d/nominees", campaign.ID))
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var response serializers.CampaignNomineesResponse
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Nominees, 2)
+		assert.Equal(suite.T(), int64(1), response.Nominees[0].VenueID)
+
+		// Only nominees can be voted for
+		voteURL := fmt.Sprintf("/v1/campaigns/%d/test_user_1/vote", campaign.ID)
+		w = suite.makePOSTRequest(voteURL, map[string]interface{}{"venueId": 60})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest(voteURL, map[string]interface{}{"venueId": 1})
+		assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	})
+}
+
+// TestCampaignNomineeCriteria tests nominating venues by eligibility
+// criteria and listing them with the campaign
+func (suite *TestSuite) TestCampaignNomineeCriteria() {
+	suite.Run("Campaign Nominee Criteria", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, category_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (40, 'Best Restaurant', 'best_restaurant', 1, 1, $1, $2, 1, true),
+			       (41, 'Draft', 'best_restaurant', 1, 1, $1, $2, 1, false)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+			VALUES (1, 1, 4.0, 'approved'), (1, 2, 5.0, 'approved'), (2, 1, 2.0, 'approved'), (2, 2, 3.0, 'approved')`)
+		suite.Require().NoError(err)
+
+		// Campaigns without nominees list none and take votes for any venue
+		w := suite.makeGETRequest("/v1/campaigns/40")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var detail serializers.CampaignDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		assert.True(suite.T(), detail.IsOpen)
+		assert.Empty(suite.T(), detail.Nominees)
+
+		w = suite.makePOSTRequest("/v1/admin/campaigns/40/nominees/eligible", map[string]interface{}{"minReviews": 2})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		criteria := models.NomineeCriteria{MinReviews: 2, MinRating: 3.5, Limit: 10}
+		cityID, categoryID := int64(1), int64(1)
+		nominated, err := models.NominateEligibleVenues(context.Background(), 40, &cityID, &categoryID, criteria)
+		suite.Require().NoError(err)
+		suite.Require().Len(nominated, 1)
+		assert.Equal(suite.T(), int64(1), nominated[0].VenueID)
+		assert.Nil(suite.T(), nominated[0].Score)
+
+		// Venues already nominated are skipped
+		nominated, err = models.NominateEligibleVenues(context.Background(), 40, &cityID, &categoryID, criteria)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), nominated)
+
+		w = suite.makeGETRequest("/v1/campaigns/40")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &detail)
+		suite.Require().Len(detail.Nominees, 1)
+		assert.Equal(suite.T(), int64(1), detail.Nominees[0].VenueID)
+
+		w = suite.makePOSTRequest("/v1/campaigns/40/test_user_1/vote", map[string]interface{}{"venueId": 2})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makeGETRequest("/v1/campaigns/41")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makeGETRequest("/v1/campaigns/999")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
+
+func (suite *TestSuite) testCampaignNomineesUnpublished(campaignID int64) {
+	// Unpublished campaigns aren't shown to voters
+	w := suite.makeGETRequest(fmt.Sprintf("/v1/campaigns/%d/nominees", campaignID))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/campaigns/%d/test_user_1/vote", campaignID), map[string]interface{}{"venueId": 1})
+	assert.NotEqual(suite.T(), http.StatusCreated, w.Code)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignPromotions tests scheduled campaign promotions, the featured
+// campaigns and the legacy vote banner they take over
+func (suite *TestSuite) TestCampaignPromotions() {
+	suite.Run("Campaign Promotions", func() {
+		suite.setupLegacyVotingData()
+
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (70, 'Best Brunch', 'best_restaurant', 1, $1, $2, 1, true),
+				   (71, 'Top Bars', 'top_bars', 1, $1, $2, 1, true),
+				   (72, 'Hidden Gems', 'hidden_gems', 1, $1, $2, 1, false)`,
+			now.Add(-time.Hour), now.Add(7*24*time.Hour))
+		suite.Require().NoError(err)
+
+		// Promotions are scheduled by administrators only
+		w := suite.makePOSTRequest("/v1/admin/campaigns/70/promotions", serializers.CampaignPromotionRequest{
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.Add(24 * time.Hour),
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// Running, upcoming, inactive campaign and overlapping promotions
+		_, err = suite.db.Exec(`INSERT INTO campaign_promotions
+			(id, campaign_id, starts_at, ends_at, priority, banner_image, banner_link)
+			VALUES (1, 70, $1, $2, 5, NULL, NULL),
+				   (2, 71, $1, $2, 10, 'bars.jpg', 'https://example.com/bars'),
+				   (3, 71, $1, $2, 1, 'bars-old.jpg', NULL),
+				   (4, 70, $3, $4, 50, 'brunch.jpg', NULL),
+				   (5, 72, $1, $2, 99, 'gems.jpg', NULL)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour), now.Add(48*time.Hour), now.Add(72*time.Hour))
+		suite.Require().NoError(err)
+
+		w = suite.makeGETRequest("/v1/campaigns/featured")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var featured []models.FeaturedCampaign
+		suite.parseJSONResponse(w, &featured)
+		suite.Require().Len(featured, 2)
+		assert.Equal(suite.T(), int64(71), featured[0].ID)
+		assert.Equal(suite.T(), int64(2), featured[0].Promotion.ID)
+		assert.Equal(suite.T(), "Top Bars", featured[0].Title)
+		assert.Equal(suite.T(), int64(70), featured[1].ID)
+
+		// The featured campaign banner takes over the legacy banner slot
+		w = suite.makeGETRequest("/v1/vote/test_user_1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var vote serializers.Vote
+		suite.parseJSONResponse(w, &vote)
+		if assert.NotNil(suite.T(), vote.Banner) {
+			assert.Equal(suite.T(), "bars.jpg", vote.Banner.Image)
+			assert.Equal(suite.T(), "https://example.com/bars", vote.Banner.Link)
+			assert.Equal(suite.T(), int64(71), vote.Banner.CampaignID)
+		}
+
+		// Without promotions the static banner is back
+		_, err = suite.db.Exec("DELETE FROM campaign_promotions WHERE campaign_id = 71")
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest("/v1/vote/test_user_1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		vote = serializers.Vote{}
+		suite.parseJSONResponse(w, &vote)
+		if assert.NotNil(suite.T(), vote.Banner) {
+			assert.Equal(suite.T(), "banner1.jpg", vote.Banner.Image)
+			assert.Zero(suite.T(), vote.Banner.CampaignID)
+		}
+
+		request := serializers.CampaignPromotionRequest{StartsAt: now, EndsAt: now.Add(-time.Hour)}
+		_, isValid := request.Validate(now)
+		assert.False(suite.T(), isValid)
+		request = serializers.CampaignPromotionRequest{StartsAt: now, EndsAt: now.Add(time.Hour), BannerLink: "https://example.com"}
+		_, isValid = request.Validate(now)
+		assert.False(suite.T(), isValid)
+
+		w = suite.makeGETRequest("/v1/admin/campaign-promotions")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
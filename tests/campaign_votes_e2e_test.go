@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUserCampaignVotes tests the user's vote history in a campaign
+func (suite *TestSuite) TestUserCampaignVotes() {
+	suite.Run("User Campaign Votes End-to-End", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, category_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (60, 'Best Dinner', 'best_restaurant', 1, 1, $1, $2, 3, true),
+			       (61, 'Past Dinner', 'best_restaurant', 1, 1, $3, $4, 1, true)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour),
+			now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+		suite.Require().NoError(err)
+
+		w := suite.makePOSTRequest("/v1/campaigns/60/test_user_1/vote", map[string]interface{}{
+			"venueId": 1,
+			"reason":  "Great pasta",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code)
+
+		w = suite.makeGETRequest("/v1/campaigns/60/test_user_1/votes")
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		var response serializers.CampaignVoteHistoryResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), int64(60), response.CampaignID)
+		assert.Equal(suite.T(), 1, response.VotesUsed)
+		assert.Equal(suite.T(), 3, response.MaxVotesPerUser)
+		assert.Equal(suite.T(), 2, response.RemainingVotes)
+		assert.Nil(suite.T(), response.RemainingCredits)
+		assert.True(suite.T(), response.CanChangeVotes)
+		assert.WithinDuration(suite.T(), now.Add(24*time.Hour), response.ChangeDeadline, time.Minute)
+
+		// Votes come with the venue they went to
+		suite.Require().Len(response.Votes, 1)
+		vote := response.Votes[0]
+		assert.Equal(suite.T(), int64(1), vote.VenueID)
+		assert.Equal(suite.T(), "Great pasta", vote.Reason)
+		suite.Require().NotNil(vote.Venue)
+		assert.Equal(suite.T(), "Test Restaurant 1", vote.Venue.Name)
+		assert.Equal(suite.T(), "test-restaurant-1", vote.Venue.Slug)
+		assert.Equal(suite.T(), "San Francisco", vote.Venue.CityName)
+
+		// Votes can't be changed once the campaign has ended
+		w = suite.makeGETRequest("/v1/campaigns/61/test_user_1/votes")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &response)
+		assert.Empty(suite.T(), response.Votes)
+		assert.Equal(suite.T(), 1, response.RemainingVotes)
+		assert.False(suite.T(), response.CanChangeVotes)
+
+		w = suite.makeGETRequest("/v1/campaigns/999/test_user_1/votes")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
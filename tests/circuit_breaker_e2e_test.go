@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingGeocoder is an external geocoder having an outage
+type failingGeocoder struct {
+	calls int
+}
+
+func (g *failingGeocoder) Name() string {
+	return "failing"
+}
+
+func (g *failingGeocoder) Geocode(ctx context.Context, address string) (*services.LocationResult, error) {
+	g.calls++
+	return nil, errors.New("service unavailable")
+}
+
+func (g *failingGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (*services.LocationResult, error) {
+	g.calls++
+	return nil, errors.New("service unavailable")
+}
+
+// TestGeocoderCircuitBreaker tests that geocoder outages open its breaker,
+// fall back to the cities database and show in the metrics and readiness
+func (suite *TestSuite) TestGeocoderCircuitBreaker() {
+	suite.Run("Geocoder Circuit Breaker", func() {
+		ctx := context.Background()
+		geocoder := &failingGeocoder{}
+		defaultGeocoder := services.ExternalGeocoder
+		services.ExternalGeocoder = geocoder
+		services.GeocoderBreaker.Reset()
+		defer func() {
+			services.ExternalGeocoder = defaultGeocoder
+			services.GeocoderBreaker.Reset()
+		}()
+
+		w := suite.makeGETRequest("/readyz")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var readiness serializers.ReadinessResponse
+		suite.parseJSONResponse(w, &readiness)
+		assert.Equal(suite.T(), serializers.ReadinessReady, readiness.Status)
+		assert.Equal(suite.T(), "ok", readiness.Database)
+
+		// Addresses unknown locally are placed in the city they name
+		geoService := &services.GeolocationService{}
+		result, err := geoService.Geocode(ctx, "1 Market St, San Francisco")
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "San Francisco", result.City)
+		assert.Equal(suite.T(), "1 Market St, San Francisco", result.Address)
+		assert.Less(suite.T(), result.Confidence, 0.5)
+		assert.Equal(suite.T(), 1, geocoder.calls)
+
+		// Consecutive failures open the breaker, which stops calling
+		threshold := services.GeocoderBreaker.FailureThreshold
+		for i := 1; i < threshold; i++ {
+			_, err = geoService.ReverseGeocode(ctx, 36.0, -120.0)
+			suite.Require().NoError(err)
+		}
+		assert.Equal(suite.T(), threshold, geocoder.calls)
+		assert.Equal(suite.T(), services.BreakerOpen, services.GeocoderBreaker.Status().State)
+
+		result, err = geoService.ReverseGeocode(ctx, 36.0, -120.0)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "San Francisco", result.City)
+		assert.Equal(suite.T(), threshold, geocoder.calls)
+
+		// Coordinates far from every city stay unknown
+		_, err = geoService.ReverseGeocode(ctx, -45.0, 170.0)
+		assert.Equal(suite.T(), services.ErrGeocoderUnavailable, err)
+
+		w = suite.makeGETRequest("/metrics")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), `circuit_breaker_state{breaker="geocoder"} 1`)
+		assert.Regexp(suite.T(), `(?m)^circuit_breaker_rejections_total\{breaker="geocoder"\} [1-9]\d*$`, w.Body.String())
+
+		w = suite.makeGETRequest("/readyz")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &readiness)
+		assert.Equal(suite.T(), serializers.ReadinessDegraded, readiness.Status)
+		suite.Require().Len(readiness.Breakers, 1)
+		assert.Equal(suite.T(), services.BreakerOpen, readiness.Breakers[0].State)
+	})
+
+	suite.Run("Circuit Breaker Recovery", func() {
+		ctx := context.Background()
+		breaker := services.NewCircuitBreaker("test", 2, 50*time.Millisecond, 20*time.Millisecond)
+		slow := func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		// Calls outlasting the timeout fail
+		start := time.Now()
+		assert.Error(suite.T(), breaker.Call(ctx, slow))
+		assert.Less(suite.T(), int64(time.Since(start)), int64(time.Second))
+		assert.Error(suite.T(), breaker.Call(ctx, slow))
+		assert.Equal(suite.T(), services.ErrCircuitOpen, breaker.Call(ctx, func(ctx context.Context) error { return nil }))
+
+		// After the cooldown a failed trial opens it again, a successful one
+		// closes it
+		time.Sleep(60 * time.Millisecond)
+		assert.Error(suite.T(), breaker.Call(ctx, slow))
+		assert.Equal(suite.T(), services.BreakerOpen, breaker.Status().State)
+		time.Sleep(60 * time.Millisecond)
+		assert.NoError(suite.T(), breaker.Call(ctx, func(ctx context.Context) error { return nil }))
+		assert.Equal(suite.T(), services.BreakerClosed, breaker.Status().State)
+
+		// Calls the caller gave up on don't count
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		for i := 0; i < 3; i++ {
+			assert.Error(suite.T(), breaker.Call(cancelled, slow))
+		}
+		assert.Equal(suite.T(), services.BreakerClosed, breaker.Status().State)
+	})
+}
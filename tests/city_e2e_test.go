@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCityOnboarding tests that onboarded cities and their districts are
+// listed once active, and that changes show at once
+func (suite *TestSuite) TestCityOnboarding() {
+	suite.Run("City Onboarding", func() {
+		ctx := context.Background()
+		cityService := &services.CityService{}
+		// The fixtures insert cities by ID
+		_, err := suite.db.Exec("SELECT setval('cities_id_seq', (SELECT MAX(id) FROM cities))")
+		suite.Require().NoError(err)
+
+		w := suite.makePOSTRequest("/v1/admin/cities", map[string]interface{}{
+			"name": "Oakland", "country": "USA", "latitude": 37.8044, "longitude": -122.2712, "timezone": "America/Los_Angeles",
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		latitude, longitude, isActive := 37.8044, -122.2712, false
+		request := serializers.CityRequest{
+			Name: " Oakland ", State: "California", Country: "USA",
+			Latitude: &latitude, Longitude: &longitude, Timezone: "Mars/Olympus_Mons", IsActive: &isActive,
+		}
+		_, isValid := request.Validate()
+		assert.False(suite.T(), isValid)
+		request.Timezone = "America/Los_Angeles"
+		_, isValid = request.Validate()
+		suite.Require().True(isValid)
+
+		city := request.ToCity(0)
+		suite.Require().NoError(cityService.CreateCity(ctx, city))
+		assert.Equal(suite.T(), "Oakland", city.Name)
+		duplicate := *city
+		duplicate.Name = "oakland"
+		assert.Equal(suite.T(), models.ErrCityExists, cityService.CreateCity(ctx, &duplicate))
+
+		// Cities being onboarded aren't listed
+		w = suite.makeGETRequest("/v1/utils/cities")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), "public, max-age=300", w.Header().Get("Cache-Control"))
+		var cities serializers.CitiesResponse
+		suite.parseJSONResponse(w, &cities)
+		suite.Require().Len(cities.Cities, 1)
+		assert.Equal(suite.T(), suite.testData.TestCity.ID, cities.Cities[0].ID)
+
+		district := &models.District{CityID: city.ID, Name: "Rockridge", Slug: "rockridge", Latitude: 37.8444, Longitude: -122.2516}
+		suite.Require().NoError(cityService.CreateDistrict(ctx, district))
+		assert.Equal(suite.T(), models.ErrDistrictExists, cityService.CreateDistrict(ctx, &models.District{
+			CityID: city.ID, Name: "Rockridge", Slug: "rockridge", Latitude: 37.84, Longitude: -122.25,
+		}))
+		assert.Equal(suite.T(), services.ErrDistrictTooFar, cityService.CreateDistrict(ctx, &models.District{
+			CityID: city.ID, Name: "Downtown LA", Slug: "downtown-la", Latitude: 34.0522, Longitude: -118.2437,
+		}))
+		assert.Equal(suite.T(), services.ErrUnknownCity, cityService.CreateDistrict(ctx, &models.District{
+			CityID: 999, Name: "Nowhere", Slug: "nowhere", Latitude: 37.8, Longitude: -122.2,
+		}))
+
+		w = suite.makeGETRequest(fmt.Sprintf("/v1/utils/cities/%d/districts", city.ID))
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makeGETRequest("/v1/utils/cities/abc/districts")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		city.IsActive = true
+		suite.Require().NoError(cityService.UpdateCity(ctx, city))
+		w = suite.makeGETRequest("/v1/utils/cities")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &cities)
+		suite.Require().Len(cities.Cities, 2)
+		assert.Equal(suite.T(), "Oakland", cities.Cities[1].Name)
+		assert.Equal(suite.T(), "America/Los_Angeles", cities.Cities[1].Timezone)
+		assert.InDelta(suite.T(), 37.8044, cities.Cities[1].Latitude, 0.0001)
+
+		w = suite.makeGETRequest(fmt.Sprintf("/v1/utils/cities/%d/districts", city.ID))
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var districts serializers.DistrictsResponse
+		suite.parseJSONResponse(w, &districts)
+		assert.Equal(suite.T(), city.ID, districts.City.ID)
+		suite.Require().Len(districts.Districts, 1)
+		assert.Equal(suite.T(), "rockridge", districts.Districts[0].Slug)
+		assert.InDelta(suite.T(), 37.8444, districts.Districts[0].Latitude, 0.0001)
+
+		// Changes are listed at once
+		renamed := &models.District{ID: district.ID, Name: "Temescal", Slug: "temescal", Latitude: 37.8330, Longitude: -122.2614}
+		suite.Require().NoError(cityService.UpdateDistrict(ctx, renamed))
+		assert.Equal(suite.T(), city.ID, renamed.CityID)
+		_, listed, err := cityService.GetDistricts(ctx, city.ID)
+		suite.Require().NoError(err)
+		suite.Require().Len(listed, 1)
+		assert.Equal(suite.T(), "Temescal", listed[0].Name)
+
+		suite.Require().NoError(cityService.DeleteDistrict(ctx, district.ID))
+		assert.Equal(suite.T(), sql.ErrNoRows, cityService.DeleteDistrict(ctx, district.ID))
+		_, listed, err = cityService.GetDistricts(ctx, city.ID)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), listed)
+	})
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCollectionCollaboration tests sharing collections with collaborators
+// who edit their venues
+func (suite *TestSuite) TestCollectionCollaboration() {
+	suite.Run("CollectionCollaboration", func() {
+		suite.testCollectionInvites()
+		suite.testCollaboratorEdits()
+	})
+}
+
+func (suite *TestSuite) testCollectionInvites() {
+	w := suite.makePOSTRequest("/v1/collections/test_user_1", map[string]interface{}{
+		"name": "  Date night  ", "isPublic": false,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var collection models.VenueCollection
+	suite.parseJSONResponse(w, &collection)
+	assert.Equal(suite.T(), "Date night", collection.Name)
+	assert.Equal(suite.T(), models.CollectionRoleOwner, collection.Role)
+	baseURL := fmt.Sprintf("/v1/collections/test_user_1/%d", collection.ID)
+
+	w = suite.makePOSTRequest("/v1/collections/test_user_1", map[string]interface{}{"name": "   "})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 1})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 1, "note": "Ask for the patio"})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 999999})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePOSTRequest(baseURL+"/collaborators", map[string]interface{}{"userId": 2})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var invite models.CollectionCollaborator
+	suite.parseJSONResponse(w, &invite)
+	assert.Equal(suite.T(), models.CollaboratorPending, invite.Status)
+
+	w = suite.makePOSTRequest(baseURL+"/collaborators", map[string]interface{}{"userId": 2})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	var base serializers.Base
+	suite.parseJSONResponse(w, &base)
+	assert.Equal(suite.T(), serializers.AlreadyInvited, base.Code)
+	w = suite.makePOSTRequest(baseURL+"/collaborators", map[string]interface{}{"userId": 1})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.makePOSTRequest(baseURL+"/collaborators", map[string]interface{}{"userId": 999999})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makeGETRequest(baseURL)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var detail serializers.CollectionDetailResponse
+	suite.parseJSONResponse(w, &detail)
+	suite.Require().Len(detail.Items, 1)
+	assert.Equal(suite.T(), "Ask for the patio", detail.Items[0].Note)
+	assert.Equal(suite.T(), int64(1), detail.Items[0].AddedBy)
+	suite.Require().Len(detail.Collaborators, 1)
+	assert.Equal(suite.T(), "test_user_2", detail.Collaborators[0].UserName)
+
+	// The owner can cancel the invite
+	w = suite.makeDELETERequest(baseURL + "/collaborators/2")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.makeDELETERequest(baseURL + "/collaborators/2")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) testCollaboratorEdits() {
+	ctx := context.Background()
+
+	// test_user_2 shares a private collection with test_user_1
+	shared := &models.VenueCollection{UserID: 2, Name: "Coffee crawl"}
+	suite.Require().NoError(shared.Create(ctx))
+	suite.Require().NoError((&models.CollectionCollaborator{CollectionID: shared.ID, UserID: 1, InvitedBy: 2}).Invite(ctx))
+	baseURL := fmt.Sprintf("/v1/collections/test_user_1/%d", shared.ID)
+
+	// Pending invites show up but don't grant access yet
+	w := suite.makeGETRequest("/v1/collections/test_user_1")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var list serializers.CollectionsResponse
+	suite.parseJSONResponse(w, &list)
+	suite.Require().Len(list.Invites, 1)
+	assert.Equal(suite.T(), "Coffee crawl", list.Invites[0].CollectionName)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.makeGETRequest(baseURL).Code)
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 2})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePOSTRequest(baseURL+"/collaborators/accept", nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.makePOSTRequest(baseURL+"/collaborators/accept", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makeGETRequest("/v1/collections/test_user_1")
+	suite.parseJSONResponse(w, &list)
+	assert.Empty(suite.T(), list.Invites)
+	roles := make(map[int64]string)
+	for _, collection := range list.Collections {
+		roles[collection.ID] = collection.Role
+	}
+	assert.Equal(suite.T(), models.CollectionRoleCollaborator, roles[shared.ID])
+
+	// Collaborators edit the venues but can't invite others
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 2, "note": "Best cortado"})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.makePOSTRequest(baseURL+"/venues", map[string]interface{}{"venueId": 1})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	w = suite.makeDELETERequest(baseURL + "/venues/1")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.makeDELETERequest(baseURL + "/venues/1")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.makePOSTRequest(baseURL+"/collaborators", map[string]interface{}{"userId": 2})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// The owner sees what collaborators added in the social feed
+	activities, err := models.GetSocialFeed(ctx, 2, 20, 0)
+	suite.Require().NoError(err)
+	var added []models.FeedActivity
+	for _, activity := range activities {
+		if activity.Type == models.ActivityCollectionAdd {
+			added = append(added, activity)
+		}
+	}
+	suite.Require().Len(added, 1)
+	assert.Equal(suite.T(), "test_user_1 added Test Restaurant 2 to Coffee crawl", added[0].Summary)
+	assert.Equal(suite.T(), "Best cortado", added[0].Text)
+	suite.Require().NotNil(added[0].CollectionID)
+	assert.Equal(suite.T(), shared.ID, *added[0].CollectionID)
+
+	// Public collections can be seen but not edited by others
+	public := &models.VenueCollection{UserID: 2, Name: "Brunch spots", IsPublic: true}
+	suite.Require().NoError(public.Create(ctx))
+	publicURL := fmt.Sprintf("/v1/collections/test_user_1/%d", public.ID)
+	w = suite.makeGETRequest(publicURL)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var detail serializers.CollectionDetailResponse
+	suite.parseJSONResponse(w, &detail)
+	assert.Empty(suite.T(), detail.Role)
+	assert.Empty(suite.T(), detail.Collaborators)
+	w = suite.makePOSTRequest(publicURL+"/venues", map[string]interface{}{"venueId": 1})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// Collaborators can leave, losing access
+	w = suite.makeDELETERequest(baseURL + "/collaborators/1")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.makeGETRequest(baseURL).Code)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMapVenuesAndCompression tests the streamed map venues and the
+// compression of large responses
+func (suite *TestSuite) TestMapVenuesAndCompression() {
+	suite.Run("Map Venues And Compression", func() {
+		_, err := suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active, average_rating)
+			SELECT 1000 + g, 'Map Venue ' || g, 'map-venue-' || g, g || ' Map St', 1,
+				   10 + g * 0.001, 20 + g * 0.001, 1, g <> 300, (g % 5) + 0.5
+			FROM generate_series(1, 300) g`)
+		suite.Require().NoError(err)
+
+		url := "/v1/venues/map?sw_lat=10&sw_lng=20&ne_lat=11&ne_lng=21"
+		w := suite.makeGETRequest(url)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(suite.T(), w.Header().Get("Content-Encoding"))
+		var plain serializers.MapVenuesResponse
+		suite.parseJSONResponse(w, &plain)
+		// The inactive venue is left out
+		assert.Equal(suite.T(), 299, plain.Count)
+		suite.Require().Len(plain.Venues, 299)
+		assert.Equal(suite.T(), 4.5, plain.Venues[0].AverageRating)
+
+		w = suite.makeEncodedGETRequest(url+"&min_rating=4&limit=50", "gzip, deflate;q=0.5")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(suite.T(), w.Header().Get("Vary"), "Accept-Encoding")
+		reader, err := gzip.NewReader(w.Body)
+		suite.Require().NoError(err)
+		var gzipped serializers.MapVenuesResponse
+		suite.Require().NoError(json.NewDecoder(reader).Decode(&gzipped))
+		assert.Equal(suite.T(), 50, gzipped.Count)
+		for _, venue := range gzipped.Venues {
+			assert.GreaterOrEqual(suite.T(), venue.AverageRating, 4.0)
+		}
+
+		w = suite.makeEncodedGETRequest(url, "gzip;q=0, deflate")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "deflate", w.Header().Get("Content-Encoding"))
+		var deflated serializers.MapVenuesResponse
+		suite.Require().NoError(json.NewDecoder(flate.NewReader(w.Body)).Decode(&deflated))
+		assert.Equal(suite.T(), 299, deflated.Count)
+
+		// Small responses are sent as they are
+		w = suite.makeEncodedGETRequest("/v1/venues/map?sw_lat=-1&sw_lng=-1&ne_lat=1&ne_lng=1", "gzip")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Empty(suite.T(), w.Header().Get("Content-Encoding"))
+		assert.JSONEq(suite.T(), `{"venues":[],"count":0}`, w.Body.String())
+
+		w = suite.makeGETRequest("/v1/venues/map?sw_lat=11&sw_lng=20&ne_lat=10&ne_lng=21")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makeGETRequest("/v1/venues/map?sw_lat=10&sw_lng=20")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestReviewExportJSON tests streaming a venue's reviews as JSON
+func (suite *TestSuite) TestReviewExportJSON() {
+	suite.Run("Review Export JSON", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, detailed_ratings, title, review_text, moderation_status, helpful_votes)
+			VALUES (1, 1, 4.5, '{"food": 4.5}', 'Great pasta', 'Would come back', 'approved', 3),
+				   (1, 2, 2.0, NULL, 'Pending', 'Not yet moderated', 'pending', 0)`)
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/venues/1/reviews/export?format=json")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(suite.T(), w.Header().Get("Content-Type"), "application/json")
+		assert.Contains(suite.T(), w.Header().Get("Content-Disposition"), "reviews.json")
+
+		var reviews []map[string]interface{}
+		suite.parseJSONResponse(w, &reviews)
+		suite.Require().Len(reviews, 1)
+		assert.Equal(suite.T(), "Great pasta", reviews[0]["title"])
+		assert.Equal(suite.T(), 4.5, reviews[0]["rating"])
+		assert.Equal(suite.T(), map[string]interface{}{"food": 4.5}, reviews[0]["ratings"])
+	})
+}
+
+// makeEncodedGETRequest makes a GET request accepting the encodings
+func (suite *TestSuite) makeEncodedGETRequest(url, acceptEncoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"os"
+	"voting-app/app/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigValidation tests that invalid configuration is reported at once
+func (suite *TestSuite) TestConfigValidation() {
+	suite.Run("Configuration Validation", func() {
+		restore := suite.setConfigEnv(map[string]string{
+			"CONFIG_FILE":         "",
+			"JWT_SECRET":          "",
+			"JWT_KEY":             "not-a-key",
+			"VOTE_RECEIPT_SECRET": "",
+			"CAMPAIGN_AUDIT_KEY":  "",
+			"DB_PORT":             "not-a-port",
+			"SENTRY_DSN":          "ftp://sentry.example.com/1",
+			"DB_QUERY_TIMEOUT":    "10",
+			"STORAGE_BACKEND":     "ftp",
+
+			"TRAVEL_TIME_BACKEND": "google",
+
+			"DB_MAX_OPEN_CONNS": "5",
+			"DB_MAX_IDLE_CONNS": "10",
+
+			"CORS_ALLOWED_ORIGINS":   "*, https://app.example.com/path",
+			"CORS_ALLOW_CREDENTIALS": "true",
+			"FRAME_OPTIONS":          "ALLOW-FROM https://example.com",
+			"TRUSTED_PROXIES":        "10.0.0.0/8, proxy.internal",
+		})
+		defer restore()
+
+		_, err := config.Load()
+		suite.Require().Error(err)
+
+		validationErr, ok := err.(*config.ValidationError)
+		suite.Require().True(ok)
+		assert.Contains(suite.T(), validationErr.Problems, "JWT_SECRET is required")
+		assert.Contains(suite.T(), err.Error(), "DB_PORT must be an integer")
+		assert.Contains(suite.T(), err.Error(), "JWT_KEY must be a PEM encoded Ed25519 public key")
+		assert.Contains(suite.T(), validationErr.Problems, "VOTE_RECEIPT_SECRET is required")
+		assert.Contains(suite.T(), validationErr.Problems, "CAMPAIGN_AUDIT_KEY is required")
+		assert.Contains(suite.T(), err.Error(), "SENTRY_DSN must use one of the schemes")
+		assert.Contains(suite.T(), err.Error(), "DB_QUERY_TIMEOUT must be a duration")
+		assert.Contains(suite.T(), err.Error(), "STORAGE_BACKEND must be one of local, s3 or gcs")
+		assert.Contains(suite.T(), validationErr.Problems, "TRAVEL_TIME_BACKEND must be mapbox or osrm")
+		assert.Contains(suite.T(), validationErr.Problems, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS (5)")
+		assert.Contains(suite.T(), validationErr.Problems, "CORS_ALLOWED_ORIGINS must list the origins when CORS_ALLOW_CREDENTIALS is set")
+		assert.Contains(suite.T(), err.Error(), `CORS_ALLOWED_ORIGINS must hold origins like https://example.com, got "https://app.example.com/path"`)
+		assert.Contains(suite.T(), err.Error(), "FRAME_OPTIONS must be DENY or SAMEORIGIN")
+		assert.Contains(suite.T(), validationErr.Problems, `TRUSTED_PROXIES must hold IP addresses or CIDR ranges, got "proxy.internal"`)
+
+		// Secrets are kept apart from the JWT secret
+		restoreSecrets := suite.setConfigEnv(map[string]string{
+			"JWT_SECRET":             "shared-secret",
+			"VOTE_RECEIPT_SECRET":    "shared-secret",
+			"STORAGE_BACKEND":        "local",
+			"STORAGE_SIGNING_SECRET": "",
+		})
+		_, err = config.Load()
+		restoreSecrets()
+		suite.Require().Error(err)
+		validationErr = err.(*config.ValidationError)
+		assert.Contains(suite.T(), validationErr.Problems, "VOTE_RECEIPT_SECRET must differ from JWT_SECRET")
+		assert.Contains(suite.T(), validationErr.Problems, "STORAGE_SIGNING_SECRET is required with the local storage backend")
+
+		// An explicitly configured file must exist
+		restoreFile := suite.setConfigEnv(map[string]string{
+			"CONFIG_FILE": "missing.env",
+		})
+		defer restoreFile()
+
+		_, err = config.Load()
+		suite.Require().Error(err)
+		assert.Contains(suite.T(), err.Error(), `CONFIG_FILE "missing.env" could not be read`)
+	})
+}
+
+// setConfigEnv overrides environment variables, an empty value unsets one
+func (suite *TestSuite) setConfigEnv(values map[string]string) func() {
+	previous := make(map[string]*string)
+	for key, value := range values {
+		if old, exists := os.LookupEnv(key); exists {
+			previous[key] = &old
+		} else {
+			previous[key] = nil
+		}
+
+		if value == "" {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, value)
+		}
+	}
+
+	return func() {
+		for key, old := range previous {
+			if old == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *old)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getConsentCode returns the code of the last consent confirmation sent to
+// the snapp user
+func (suite *TestSuite) getConsentCode(userID int64) string {
+	var body string
+	err := suite.db.QueryRow(`SELECT body FROM notifications
+		WHERE user_id = $1 AND event_type = $2 ORDER BY id DESC LIMIT 1`,
+		userID, models.NotificationConsent).Scan(&body)
+	suite.Require().NoError(err)
+	code := linkCodePattern.FindString(body)
+	suite.Require().NotEmpty(code)
+	return code
+}
+
+// TestUserConsents tests the double opt-in consents to marketing and
+// personalization, and the services respecting them
+func (suite *TestSuite) TestUserConsents() {
+	suite.Run("User Consents", func() {
+		ctx := context.Background()
+
+		w := suite.makeGETRequest("/v1/users/test_user_1/consents")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var response serializers.ConsentsResponse
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Consents, len(models.ConsentPurposes))
+		for _, consent := range response.Consents {
+			assert.Equal(suite.T(), models.ConsentNone, consent.Status)
+		}
+
+		// Asking for a consent leaves it pending until confirmed
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/push_marketing", serializers.ConsentRequest{Source: "onboarding"})
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		var consent models.UserConsent
+		suite.parseJSONResponse(w, &consent)
+		assert.Equal(suite.T(), models.ConsentPending, consent.Status)
+		assert.Equal(suite.T(), "onboarding", consent.Source)
+		assert.NotNil(suite.T(), consent.ExpiresAt)
+		code := suite.getConsentCode(1)
+
+		granted, err := models.HasConsent(ctx, 1, models.ConsentPushMarketing)
+		suite.Require().NoError(err)
+		assert.False(suite.T(), granted)
+
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/push_marketing/confirm", serializers.ConfirmConsentRequest{Code: "abcdef"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/email_digest/confirm", serializers.ConfirmConsentRequest{Code: code})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/push_marketing/confirm", serializers.ConfirmConsentRequest{Code: code})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		consent = models.UserConsent{}
+		suite.parseJSONResponse(w, &consent)
+		assert.Equal(suite.T(), models.ConsentGranted, consent.Status)
+		assert.NotNil(suite.T(), consent.GrantedAt)
+		assert.Nil(suite.T(), consent.ExpiresAt)
+
+		// The code is single use and granted consents aren't asked for again
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/push_marketing/confirm", serializers.ConfirmConsentRequest{Code: code})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/push_marketing", nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/newsletter", nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/users/test_user_1/consents/email_digest", serializers.ConsentRequest{Source: "billboard"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Marketing only reaches users who consented to it
+		notificationService := &services.NotificationService{}
+		notificationService.NotifyCampaignStart(ctx, []int64{1, 2}, 1, "Best Burger")
+		var notified []int64
+		rows, err := suite.db.Query("SELECT user_id FROM notifications WHERE event_type = $1", models.NotificationCampaignStart)
+		suite.Require().NoError(err)
+		for rows.Next() {
+			var userID int64
+			suite.Require().NoError(rows.Scan(&userID))
+			notified = append(notified, userID)
+		}
+		rows.Close()
+		assert.Equal(suite.T(), []int64{1}, notified)
+
+		// Marketing sent to a single user is dropped without consent too
+		notification, err := notificationService.Notify(ctx, 2, models.NotificationCampaignStart, "Voting is open!", "Best Burger has started.", nil)
+		suite.Require().NoError(err)
+		assert.Nil(suite.T(), notification)
+
+		// Recommendations are only personalized with consent
+		engine := &services.RecommendationEngine{}
+		rc := services.RecommendationContext{UserID: 1, MaxDistance: 10, Limit: 10}
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, review_text, visit_type, moderation_status)
+			VALUES (2, 1, 5.0, 'My kind of place', 'dinner', 'approved')`)
+		suite.Require().NoError(err)
+		hasCategoryReason := func() bool {
+			recommendations, err := engine.GetPersonalizedRecommendations(ctx, rc)
+			suite.Require().NoError(err)
+			for _, recommendation := range recommendations {
+				for _, signal := range recommendation.Signals {
+					if signal == services.SignalCategory {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		assert.False(suite.T(), hasCategoryReason())
+
+		_, err = suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+			VALUES (1, $1, 'granted', CURRENT_TIMESTAMP)`, models.ConsentPersonalizedRecommendations)
+		suite.Require().NoError(err)
+		assert.True(suite.T(), hasCategoryReason())
+
+		// Revoking takes effect at once
+		w = suite.makeDELETERequest("/v1/users/test_user_1/consents/personalized_recommendations?source=settings")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		consent = models.UserConsent{}
+		suite.parseJSONResponse(w, &consent)
+		assert.Equal(suite.T(), models.ConsentRevoked, consent.Status)
+		assert.Equal(suite.T(), "settings", consent.Source)
+		assert.NotNil(suite.T(), consent.RevokedAt)
+		assert.False(suite.T(), hasCategoryReason())
+
+		w = suite.makeDELETERequest("/v1/users/test_user_1/consents/push_marketing?source=billboard")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/consents")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		response = serializers.ConsentsResponse{}
+		suite.parseJSONResponse(w, &response)
+		statuses := make(map[string]string)
+		for _, consent := range response.Consents {
+			statuses[consent.Purpose] = consent.Status
+		}
+		assert.Equal(suite.T(), map[string]string{
+			models.ConsentPushMarketing:               models.ConsentGranted,
+			models.ConsentEmailDigest:                 models.ConsentNone,
+			models.ConsentPersonalizedRecommendations: models.ConsentRevoked,
+		}, statuses)
+	})
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingContentFilter stands in for an unavailable moderation backend
+type failingContentFilter struct{}
+
+func (failingContentFilter) Name() string { return "failing" }
+
+func (failingContentFilter) Check(ctx context.Context, text string) (services.ContentCheck, error) {
+	return services.ContentCheck{}, errors.New("backend unavailable")
+}
+
+// TestContentFilter tests profanity and spam filtering of reviews
+func (suite *TestSuite) TestContentFilter() {
+	suite.Run("Content Filter End-to-End", func() {
+		suite.testReviewContentFilter()
+		suite.testCheckinContentFilter()
+		suite.testContentFilterBackends()
+	})
+}
+
+func (suite *TestSuite) testReviewContentFilter() {
+	// Profanity is rejected, including common substitutions
+	for _, text := range []string{"The food was shit", "Total BULLSH1T service"} {
+		w := suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+			"venueId":       1,
+			"overallRating": 1.0,
+			"reviewText":    text,
+		})
+		suite.Require().Equal(http.StatusBadRequest, w.Code, text)
+
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.ContentRejected, response.Code)
+	}
+
+	var count int
+	suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM venue_reviews WHERE venue_id = 1").Scan(&count))
+	assert.Equal(suite.T(), 0, count)
+
+	// Spam is accepted and flagged for moderators
+	w := suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 5.0,
+		"reviewText":    "Best deals at https://a.example.com https://b.example.com and www.c.example.com",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var flagged models.VenueReview
+	suite.parseJSONResponse(w, &flagged)
+	assert.True(suite.T(), flagged.IsFlagged)
+
+	var hasFlaggedAt bool
+	suite.Require().NoError(suite.db.QueryRow(
+		"SELECT flagged_at IS NOT NULL FROM venue_reviews WHERE id = $1", flagged.ID,
+	).Scan(&hasFlaggedAt))
+	assert.True(suite.T(), hasFlaggedAt)
+
+	// Clean text passes untouched
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       2,
+		"title":         "Scunthorpe special",
+		"overallRating": 4.0,
+		"reviewText":    "Friendly staff and a great view of the bay",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var clean models.VenueReview
+	suite.parseJSONResponse(w, &clean)
+	assert.False(suite.T(), clean.IsFlagged)
+}
+
+func (suite *TestSuite) testCheckinContentFilter() {
+	// Profane messages are rejected
+	w := suite.makePOSTRequest("/v1/users/test_user_2/checkins", serializers.CreateCheckinRequest{
+		VenueID: 2,
+		Message: "What a shit show",
+	})
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+	var response serializers.Base
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), serializers.ContentRejected, response.Code)
+
+	// Spam is stored flagged and kept off the venue's page
+	w = suite.makePOSTRequest("/v1/users/test_user_2/checkins", serializers.CreateCheckinRequest{
+		VenueID: 2,
+		Message: "Cheap eats at https://a.example.com https://b.example.com and www.c.example.com",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var checkin models.VenueCheckin
+	suite.parseJSONResponse(w, &checkin)
+	assert.True(suite.T(), checkin.IsFlagged)
+
+	w = suite.makeGETRequest("/v1/venues/2/checkins")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var checkins serializers.VenueCheckinsResponse
+	suite.parseJSONResponse(w, &checkins)
+	assert.Empty(suite.T(), checkins.Checkins)
+}
+
+func (suite *TestSuite) testContentFilterBackends() {
+	ctx := context.Background()
+	contentFilter := &services.ContentFilterService{}
+
+	check := contentFilter.Check(ctx, "wow wow wow wow wow wow wow wow wow wow wow wow")
+	assert.Equal(suite.T(), services.ContentFlagged, check.Verdict)
+	assert.Equal(suite.T(), []string{services.ContentReasonRepetition}, check.Reasons)
+
+	// The moderation API verdict counts, the most severe verdict wins
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		verdict := services.ContentAllowed
+		if request["text"] == "buy followers" {
+			verdict = services.ContentRejected
+		}
+		json.NewEncoder(w).Encode(services.ContentCheck{Verdict: verdict, Reasons: []string{"spam"}})
+	}))
+	defer server.Close()
+
+	backends := services.ContentFilterBackends
+	defer func() { services.ContentFilterBackends = backends }()
+
+	services.ContentFilterBackends = append([]services.ContentFilterBackend{
+		failingContentFilter{},
+		&services.ModerationAPIFilter{URL: server.URL},
+	}, backends...)
+
+	check = contentFilter.Check(ctx, "buy followers")
+	assert.Equal(suite.T(), services.ContentRejected, check.Verdict)
+	assert.Equal(suite.T(), []string{"spam"}, check.Reasons)
+
+	// Failing backends are skipped
+	check = contentFilter.Check(ctx, "Lovely brunch")
+	assert.Equal(suite.T(), services.ContentAllowed, check.Verdict)
+}
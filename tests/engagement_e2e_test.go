@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEngagementMetrics tests client event ingestion, sessionization and the
+// engagement metrics computed from the sessions
+func (suite *TestSuite) TestEngagementMetrics() {
+	suite.Run("Engagement Metrics", func() {
+		ctx := context.Background()
+		analyticsService := &services.AnalyticsService{}
+		now := time.Now()
+
+		w := suite.makePOSTRequest("/v1/analytics/events", serializers.ClientEventsRequest{
+			ClientID: "client-a",
+			Events: []serializers.ClientEventRequest{
+				{Type: models.ClientEventPageView, Path: "/venues/1", OccurredAt: now.Add(-3 * time.Hour)},
+				{Type: models.ClientEventPageView, Path: "/venues/2", OccurredAt: now.Add(-170 * time.Minute)},
+				{Type: models.ClientEventClick, OccurredAt: now.Add(-165 * time.Minute)},
+				{Type: models.ClientEventScreenView, OccurredAt: now.Add(-time.Hour)},
+				{Type: "bogus", OccurredAt: now.Add(-time.Hour)},
+				{Type: models.ClientEventPageView, OccurredAt: now.Add(-48 * time.Hour)},
+			},
+		})
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		var response serializers.ClientEventsResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 4, response.Accepted)
+		assert.Equal(suite.T(), 2, response.Rejected)
+
+		w = suite.makePOSTRequest("/v1/analytics/events", serializers.ClientEventsRequest{
+			ClientID: "client-b",
+			Events: []serializers.ClientEventRequest{
+				{Type: models.ClientEventPageView, OccurredAt: now.Add(-2 * time.Hour)},
+			},
+		})
+		suite.Require().Equal(http.StatusAccepted, w.Code)
+
+		w = suite.makePOSTRequest("/v1/analytics/events", serializers.ClientEventsRequest{ClientID: "client-c"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		oversized := serializers.ClientEventsRequest{ClientID: "client-c"}
+		for i := 0; i <= serializers.MaxClientEventsPerBatch; i++ {
+			oversized.Events = append(oversized.Events, serializers.ClientEventRequest{Type: models.ClientEventClick, OccurredAt: now})
+		}
+		w = suite.makePOSTRequest("/v1/analytics/events", oversized)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// client-b visited a month ago, so it is a returning client
+		_, err := suite.db.Exec(`
+			INSERT INTO client_sessions (client_id, started_at, ended_at, page_views, event_count)
+			VALUES ('client-b', $1, $1, 1, 1)`, now.AddDate(0, 0, -30))
+		suite.Require().NoError(err)
+
+		suite.Require().NoError(analyticsService.SessionizeClientEvents(ctx))
+
+		metrics, err := analyticsService.GetEngagementMetrics(ctx, "week")
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 3, metrics.Sessions)
+		assert.InDelta(suite.T(), 300, metrics.AverageSessionDuration, 1)
+		assert.InDelta(suite.T(), 4.0/3, metrics.PagesPerSession, 0.01)
+		assert.InDelta(suite.T(), 2.0/3, metrics.BounceRate, 0.01)
+		assert.InDelta(suite.T(), 0.5, metrics.ReturnUserRate, 0.01)
+
+		// A late event within the timeout extends the client's latest session
+		w = suite.makePOSTRequest("/v1/analytics/events", serializers.ClientEventsRequest{
+			ClientID: "client-a",
+			Events: []serializers.ClientEventRequest{
+				{Type: models.ClientEventPageView, OccurredAt: now.Add(-50 * time.Minute)},
+			},
+		})
+		suite.Require().Equal(http.StatusAccepted, w.Code)
+		suite.Require().NoError(analyticsService.SessionizeClientEvents(ctx))
+
+		var sessions, pageViews int
+		err = suite.db.QueryRow(`
+			SELECT COUNT(*), MAX(page_views) FILTER (WHERE ended_at > $1)
+			FROM client_sessions WHERE client_id = 'client-a'`,
+			now.Add(-55*time.Minute)).Scan(&sessions, &pageViews)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 2, sessions)
+		assert.Equal(suite.T(), 2, pageViews)
+
+		var unsessionized int
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM client_events WHERE session_id IS NULL").Scan(&unsessionized)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 0, unsessionized)
+
+		// Engagement metrics are for administrators only
+		w = suite.makeGETRequest("/v1/analytics/engagement")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
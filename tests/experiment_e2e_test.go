@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRecommendationExperiment tests that users in the ranking experiment
+// keep their variant, that it tags their recommendations and that tagged
+// venue events count towards the variant's report
+func (suite *TestSuite) TestRecommendationExperiment() {
+	suite.Run("Recommendation Experiment", func() {
+		ctx := context.Background()
+		experimentService := &services.ExperimentService{}
+
+		// Users are left out until the experiment's flag is on for them
+		w := suite.makeGETRequest("/v1/discover/test_user_1/recommendations")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var response serializers.RecommendationsResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Len(suite.T(), response.Recommendations, 2)
+		assert.Empty(suite.T(), response.Variant)
+		assert.Empty(suite.T(), w.Header().Get("X-Experiment-Variant"))
+
+		flagService := &services.FlagService{}
+		flag := &models.FeatureFlag{Key: services.ExperimentRecommendationRanking, Enabled: true, UserIDs: []int64{1}}
+		suite.Require().NoError(flagService.CreateFlag(ctx, flag))
+		variant, inExperiment := experimentService.Assign(ctx, services.ExperimentRecommendationRanking, 1)
+		suite.Require().True(inExperiment)
+		control, inExperiment := experimentService.Assign(ctx, services.ExperimentRecommendationRanking, 2)
+		assert.False(suite.T(), inExperiment)
+		assert.Equal(suite.T(), services.VariantControl, control)
+
+		w = suite.makeGETRequest("/v1/discover/test_user_1/recommendations")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Recommendations, 2)
+		assert.Equal(suite.T(), services.ExperimentRecommendationRanking, response.Experiment)
+		assert.Equal(suite.T(), variant, response.Variant)
+		assert.Equal(suite.T(), services.ExperimentRecommendationRanking+"="+variant, w.Header().Get("X-Experiment-Variant"))
+
+		// Variants are split evenly and stable
+		flag.RolloutPercentage = 100
+		suite.Require().NoError(flagService.UpdateFlag(ctx, flag))
+		counts := map[string]int{}
+		for userID := int64(1); userID <= 1000; userID++ {
+			variant, _ := experimentService.Assign(ctx, services.ExperimentRecommendationRanking, userID)
+			again, _ := experimentService.Assign(ctx, services.ExperimentRecommendationRanking, userID)
+			assert.Equal(suite.T(), variant, again)
+			counts[variant]++
+		}
+		assert.InDelta(suite.T(), 500, counts[services.VariantControl], 75)
+		assert.InDelta(suite.T(), 500, counts[services.VariantNeutralWeights], 75)
+
+		// The apps tag the events of recommended venues
+		now := time.Now()
+		w = suite.makePOSTRequest("/v1/analytics/track", serializers.VenueEventsRequest{
+			ClientID: "app-install-1",
+			Events: []serializers.VenueEventRequest{
+				{ID: "x1", Type: models.VenueEventView, VenueID: 1, OccurredAt: now, Experiment: response.Experiment, Variant: variant},
+				{ID: "x2", Type: models.VenueEventDirections, VenueID: 1, OccurredAt: now, Experiment: response.Experiment, Variant: variant},
+				{ID: "x3", Type: models.VenueEventView, VenueID: 2, OccurredAt: now},
+				{ID: "x4", Type: models.VenueEventView, VenueID: 2, OccurredAt: now, Experiment: response.Experiment, Variant: "unknown"},
+			},
+		})
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+
+		experiment, exists := services.GetExperiment(services.ExperimentRecommendationRanking)
+		suite.Require().True(exists)
+		report, err := experimentService.Report(ctx, experiment, now.Add(-time.Hour))
+		suite.Require().NoError(err)
+		suite.Require().Len(report.Variants, 2)
+		for _, metrics := range report.Variants {
+			if metrics.Variant != variant {
+				assert.Zero(suite.T(), metrics.Impressions)
+				continue
+			}
+			assert.Equal(suite.T(), 1, metrics.Users)
+			assert.Equal(suite.T(), 2, metrics.Impressions)
+			assert.Equal(suite.T(), 1, metrics.Clicks)
+			assert.Equal(suite.T(), 1, metrics.Conversions)
+			assert.InDelta(suite.T(), 0.5, metrics.CTR, 0.001)
+			assert.InDelta(suite.T(), 1, metrics.ConversionRate, 0.001)
+		}
+
+		w = suite.makeGETRequest("/v1/admin/experiments/recommendation_ranking/report")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
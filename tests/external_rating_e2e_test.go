@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExternalRatings tests importing venue ratings from other platforms
+func (suite *TestSuite) TestExternalRatings() {
+	suite.Run("ExternalRatings", func() {
+		suite.testExternalRatingEndpoints()
+		suite.testExternalRatingCSVImport()
+	})
+}
+
+func (suite *TestSuite) testExternalRatingEndpoints() {
+	_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+	suite.Require().NoError(err)
+	defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+	w := suite.makeGETRequest("/v1/venues/1")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var before serializers.VenueDetailResponse
+	suite.parseJSONResponse(w, &before)
+	assert.Empty(suite.T(), before.ExternalRatings)
+
+	w = suite.makePOSTRequest("/v1/owner/venues/1/external-ratings", map[string]interface{}{
+		"ratings": []map[string]interface{}{
+			{"source": "Google", "rating": 4.6, "reviewCount": 1200, "url": "https://maps.google.com/?cid=1"},
+			{"source": "yelp", "rating": 3.5, "reviewCount": 80},
+		},
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	// Importing a platform again replaces its rating
+	w = suite.makePOSTRequest("/v1/owner/venues/1/external-ratings", map[string]interface{}{
+		"ratings": []map[string]interface{}{{"source": "yelp", "rating": 4, "reviewCount": 95}},
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	w = suite.makeGETRequest("/v1/owner/venues/1/external-ratings")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var response serializers.ExternalRatingsResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Ratings, 2)
+	assert.Equal(suite.T(), "google", response.Ratings[0].Source)
+	assert.Equal(suite.T(), 4.6, response.Ratings[0].Rating)
+	assert.Equal(suite.T(), "yelp", response.Ratings[1].Source)
+	assert.Equal(suite.T(), 95, response.Ratings[1].ReviewCount)
+
+	for _, invalid := range []map[string]interface{}{
+		{"ratings": []map[string]interface{}{}},
+		{"ratings": []map[string]interface{}{{"source": "myspace", "rating": 4}}},
+		{"ratings": []map[string]interface{}{{"source": "google", "rating": 6}}},
+		{"ratings": []map[string]interface{}{{"source": "google", "rating": 4, "reviewCount": -1}}},
+		{"ratings": []map[string]interface{}{{"source": "google", "rating": 4, "url": "javascript:alert(1)"}}},
+		{"ratings": []map[string]interface{}{{"source": "google", "rating": 4}, {"source": "GOOGLE", "rating": 3}}},
+	} {
+		w = suite.makePOSTRequest("/v1/owner/venues/1/external-ratings", invalid)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, invalid)
+	}
+
+	w = suite.makePOSTRequest("/v1/owner/venues/2/external-ratings", map[string]interface{}{
+		"ratings": []map[string]interface{}{{"source": "google", "rating": 4}},
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// The venue shows the ratings apart, its own rating is unchanged
+	w = suite.makeGETRequest("/v1/venues/1")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var detail serializers.VenueDetailResponse
+	suite.parseJSONResponse(w, &detail)
+	assert.Len(suite.T(), detail.ExternalRatings, 2)
+	assert.Equal(suite.T(), before.Venue.AverageRating, detail.Venue.AverageRating)
+
+	w = suite.makeDELETERequest("/v1/owner/venues/1/external-ratings/yelp")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.makeDELETERequest("/v1/owner/venues/1/external-ratings/yelp")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePOSTRequest("/v1/admin/external-ratings/import", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func (suite *TestSuite) testExternalRatingCSVImport() {
+	externalRatingService := &services.ExternalRatingService{}
+	ctx := context.Background()
+
+	invalid := "venue_id,source,rating,review_count,url\n" +
+		"1,myspace,4.0,10,\n" +
+		"2,tripadvisor,4.5,300,https://tripadvisor.com/2\n" +
+		"2,TripAdvisor,4.0,310,\n" +
+		"999999,google,4.0,10,\n"
+	result, err := externalRatingService.ImportCSV(ctx, strings.NewReader(invalid), 0, false)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 4, result.Rows)
+	assert.Equal(suite.T(), 0, result.Imported)
+	lines := make([]int, 0, len(result.Errors))
+	for _, rowError := range result.Errors {
+		lines = append(lines, rowError.Line)
+	}
+	assert.ElementsMatch(suite.T(), []int{2, 4, 5}, lines)
+
+	valid := "source,venue_id,rating,review_count,url\n" +
+		"facebook,2,4.8,52,https://facebook.com/venue2\n" +
+		"tripadvisor,2,4.5,300,\n"
+	result, err = externalRatingService.ImportCSV(ctx, strings.NewReader(valid), 0, true)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), result.Errors)
+	assert.Equal(suite.T(), 2, result.Imported)
+	ratings, err := models.GetExternalRatings(ctx, []int64{2})
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), ratings[2], "dry runs don't save")
+
+	result, err = externalRatingService.ImportCSV(ctx, strings.NewReader(valid), 0, false)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 2, result.Imported)
+	ratings, err = models.GetExternalRatings(ctx, []int64{2})
+	suite.Require().NoError(err)
+	suite.Require().Len(ratings[2], 2)
+	assert.Equal(suite.T(), "facebook", ratings[2][0].Source)
+	assert.Equal(suite.T(), "https://facebook.com/venue2", ratings[2][0].URL)
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/middlewares"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFeatureFlags tests the feature flag targeting and the middleware
+// gating routes behind a flag
+func (suite *TestSuite) TestFeatureFlags() {
+	suite.Run("Feature Flags", func() {
+		ctx := context.Background()
+		flagService := &services.FlagService{}
+
+		flag := &models.FeatureFlag{Key: "quadratic_voting", Enabled: true, UserIDs: []int64{7}}
+		suite.Require().NoError(flagService.CreateFlag(ctx, flag))
+		assert.NotZero(suite.T(), flag.ID)
+		assert.Equal(suite.T(), models.ErrFeatureFlagExists,
+			flagService.CreateFlag(ctx, &models.FeatureFlag{Key: "quadratic_voting"}))
+
+		// Listed users only at 0%
+		assert.True(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 7))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 8))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 0))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "unknown_flag", 7))
+
+		// Roughly half the users at 50%, always the same ones
+		flag.RolloutPercentage = 50
+		suite.Require().NoError(flagService.UpdateFlag(ctx, flag))
+		enabled := 0
+		for userID := int64(1); userID <= 1000; userID++ {
+			isEnabled := flagService.IsEnabled(ctx, "quadratic_voting", userID)
+			if isEnabled {
+				enabled++
+			}
+			assert.Equal(suite.T(), services.RolloutBucket("quadratic_voting", userID) < 50 || userID == 7, isEnabled)
+		}
+		assert.InDelta(suite.T(), 500, enabled, 75)
+
+		flag.RolloutPercentage = 100
+		suite.Require().NoError(flagService.UpdateFlag(ctx, flag))
+		assert.True(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 8))
+
+		// Disabling turns the flag off for everyone, listed users included
+		flag.Enabled = false
+		suite.Require().NoError(flagService.UpdateFlag(ctx, flag))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 7))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "quadratic_voting", 8))
+
+		flags, err := flagService.GetFlags(ctx)
+		suite.Require().NoError(err)
+		suite.Require().Len(flags, 1)
+		assert.Equal(suite.T(), []int64{7}, flags[0].UserIDs)
+
+		// The middleware hides gated routes from users the flag is off for
+		suite.Require().NoError(flagService.CreateFlag(ctx, &models.FeatureFlag{
+			Key: "new_recommendations", Enabled: true, UserIDs: []int64{1},
+		}))
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if snappUserID := c.GetHeader("X-Test-User"); snappUserID == "1" {
+				c.Set("snappUser_id", int64(1))
+			} else {
+				c.Set("snappUser_id", int64(2))
+			}
+			c.Next()
+		})
+		router.GET("/gated", middlewares.RequireFlag("new_recommendations"), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/gated", nil)
+		req.Header.Set("X-Test-User", "1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/gated", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		suite.Require().NoError(flagService.DeleteFlag(ctx, "new_recommendations"))
+		assert.False(suite.T(), flagService.IsEnabled(ctx, "new_recommendations", 1))
+	})
+
+	suite.Run("Feature Flags Require Admin", func() {
+		w := suite.makeGETRequest("/v1/admin/flags")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		w = suite.makePOSTRequest("/v1/admin/flags", serializers.FeatureFlagRequest{Key: "quadratic_voting"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		w = suite.makeDELETERequest("/v1/admin/flags/quadratic_voting")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+
+	suite.Run("Feature Flag Request Validation", func() {
+		request := serializers.FeatureFlagRequest{Key: "Quadratic Voting"}
+		_, isValid := request.ValidKey()
+		assert.False(suite.T(), isValid)
+
+		request = serializers.FeatureFlagRequest{Key: "quadratic_voting", RolloutPercentage: 101}
+		_, isValid = request.Validate()
+		assert.False(suite.T(), isValid)
+
+		request = serializers.FeatureFlagRequest{Key: "quadratic_voting", UserIDs: []int64{3, 0}}
+		_, isValid = request.Validate()
+		assert.False(suite.T(), isValid)
+
+		request = serializers.FeatureFlagRequest{Key: "quadratic_voting", RolloutPercentage: 25, UserIDs: []int64{3, 3}}
+		_, isValid = request.Validate()
+		assert.True(suite.T(), isValid)
+		assert.Equal(suite.T(), []int64{3}, request.UserIDs)
+	})
+}
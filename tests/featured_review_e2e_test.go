@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFeaturedReviews tests the home feed's editorial picks and most helpful
+// reviews of the week, cached for a few minutes
+func (suite *TestSuite) TestFeaturedReviews() {
+	suite.Run("FeaturedReviews", func() {
+		services.ClearFeaturedReviewCache()
+		defer services.ClearFeaturedReviewCache()
+
+		_, err := suite.db.Exec(`INSERT INTO cities (id, name, state, country, latitude, longitude)
+			VALUES (2, 'Oakland', 'California', 'USA', 37.8044, -122.2712) ON CONFLICT (id) DO NOTHING`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("UPDATE venues SET city_id = 2 WHERE id = 2")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3') ON CONFLICT (id) DO NOTHING")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, title, moderation_status, is_featured, helpful_votes, created_at) VALUES
+			(1, 1, 5, 'Editors love it', 'approved', true, 0, CURRENT_TIMESTAMP - INTERVAL '30 days'),
+			(2, 1, 4, 'Very helpful', 'approved', false, 10, CURRENT_TIMESTAMP - INTERVAL '1 day'),
+			(1, 2, 4, 'Somewhat helpful', 'approved', false, 3, CURRENT_TIMESTAMP - INTERVAL '2 days'),
+			(2, 2, 5, 'Helpful but old', 'approved', false, 50, CURRENT_TIMESTAMP - INTERVAL '10 days'),
+			(1, 3, 1, 'Not moderated', 'pending', true, 20, CURRENT_TIMESTAMP)`)
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/reviews/featured")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(suite.T(), w.Header().Get("Cache-Control"), "public, max-age=")
+		var featured serializers.FeaturedReviewsResponse
+		suite.parseJSONResponse(w, &featured)
+		suite.Require().Len(featured.Reviews, 3)
+		assert.Equal(suite.T(), "Editors love it", featured.Reviews[0].Title)
+		assert.Equal(suite.T(), models.FeaturedEditorial, featured.Reviews[0].Reason)
+		assert.Equal(suite.T(), "Very helpful", featured.Reviews[1].Title)
+		assert.Equal(suite.T(), models.FeaturedTopHelpful, featured.Reviews[1].Reason)
+		assert.Equal(suite.T(), "Test Restaurant 2", featured.Reviews[1].VenueName)
+		assert.Equal(suite.T(), "Somewhat helpful", featured.Reviews[2].Title)
+
+		w = suite.makeGETRequest("/v1/reviews/featured?city=2")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		featured = serializers.FeaturedReviewsResponse{}
+		suite.parseJSONResponse(w, &featured)
+		suite.Require().Len(featured.Reviews, 1)
+		assert.Equal(suite.T(), "Very helpful", featured.Reviews[0].Title)
+		suite.Require().NotNil(featured.CityID)
+		assert.Equal(suite.T(), int64(2), *featured.CityID)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, suite.makeGETRequest("/v1/reviews/featured?city=0").Code)
+		assert.Equal(suite.T(), http.StatusBadRequest, suite.makeGETRequest("/v1/reviews/featured?city=oakland").Code)
+
+		// The selection is served from the cache until it expires
+		_, err = suite.db.Exec("UPDATE venue_reviews SET moderation_status = 'approved' WHERE user_id = 3")
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest("/v1/reviews/featured")
+		featured = serializers.FeaturedReviewsResponse{}
+		suite.parseJSONResponse(w, &featured)
+		assert.Len(suite.T(), featured.Reviews, 3)
+
+		services.ClearFeaturedReviewCache()
+		w = suite.makeGETRequest("/v1/reviews/featured")
+		featured = serializers.FeaturedReviewsResponse{}
+		suite.parseJSONResponse(w, &featured)
+		suite.Require().Len(featured.Reviews, 4)
+		assert.Equal(suite.T(), "Not moderated", featured.Reviews[0].Title)
+	})
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFeeds tests sitemap and venue feed generation
+func (suite *TestSuite) TestFeeds() {
+	suite.Run("Sitemap and Feeds", func() {
+		// Feeds are cached across tests, start from the current fixtures
+		feedService := &services.FeedService{}
+		suite.Require().NoError(feedService.RegenerateFeeds(context.Background()))
+
+		suite.testSitemap()
+		suite.testVenuesFeed()
+		suite.testFeedConditionalRequests()
+	})
+}
+
+func (suite *TestSuite) testSitemap() {
+	w := suite.makeGETRequest("/v1/feeds/sitemap.xml")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Header().Get("Content-Type"), "application/xml")
+	assert.Contains(suite.T(), w.Header().Get("Cache-Control"), "max-age=")
+	assert.NotEmpty(suite.T(), w.Header().Get("ETag"))
+	assert.NotEmpty(suite.T(), w.Header().Get("Last-Modified"))
+
+	body := w.Body.String()
+	assert.True(suite.T(), strings.HasPrefix(body, "<?xml"))
+	assert.Contains(suite.T(), body, "/venues/test-restaurant-1</loc>")
+	assert.Contains(suite.T(), body, "<lastmod>")
+}
+
+func (suite *TestSuite) testVenuesFeed() {
+	w := suite.makeGETRequest("/v1/feeds/venues.json?city=1&category=1")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var feed services.VenuesFeed
+	suite.parseJSONResponse(w, &feed)
+	assert.Equal(suite.T(), 2, feed.Count)
+	suite.Require().Len(feed.Venues, 2)
+	assert.Contains(suite.T(), feed.Venues[0].URL, "/venues/")
+	assert.Equal(suite.T(), "San Francisco", feed.Venues[0].CityName)
+
+	// Unknown category yields an empty feed
+	w = suite.makeGETRequest("/v1/feeds/venues.json?category=999")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	suite.parseJSONResponse(w, &feed)
+	assert.Equal(suite.T(), 0, feed.Count)
+
+	w = suite.makeGETRequest("/v1/feeds/venues.json?city=abc")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testFeedConditionalRequests() {
+	w := suite.makeGETRequest("/v1/feeds/sitemap.xml")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+
+	req, _ := http.NewRequest("GET", "/v1/feeds/sitemap.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+	assert.Equal(suite.T(), 0, w.Body.Len())
+
+	// Regenerating unchanged content keeps the ETag
+	feedService := &services.FeedService{}
+	suite.Require().NoError(feedService.RegenerateFeeds(context.Background()))
+
+	w = suite.makeGETRequest("/v1/feeds/sitemap.xml")
+	assert.Equal(suite.T(), etag, w.Header().Get("ETag"))
+}
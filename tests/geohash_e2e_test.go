@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"voting-app/app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGeohashBuckets tests narrowing radius searches to the geohash cells
+// covering the circle without changing their results
+func (suite *TestSuite) TestGeohashBuckets() {
+	suite.Run("Geohash Buckets", func() {
+		// The geohashes PostGIS stores match the ones searches compute
+		var geohash string
+		var lat, lng float64
+		err := suite.db.QueryRow("SELECT geohash, latitude, longitude FROM venues WHERE id = 1").Scan(&geohash, &lat, &lng)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), models.GeohashEncode(lat, lng, models.GeohashPrecision), geohash)
+
+		// Venue 1 is about 1.4km from venue 2
+		cells := models.GeohashCoverCells(37.7749, -122.4194, 2)
+		assert.Len(suite.T(), cells, 9)
+		covered := false
+		for _, cell := range cells {
+			covered = covered || strings.HasPrefix(geohash, cell)
+		}
+		assert.True(suite.T(), covered)
+
+		venue := &models.Venue{}
+		venues, err := venue.GetNearby(context.Background(), 37.7749, -122.4194, 2, 10)
+		suite.Require().NoError(err)
+		suite.Require().Len(venues, 2)
+		assert.Equal(suite.T(), int64(2), venues[0].ID)
+		assert.Equal(suite.T(), int64(1), venues[1].ID)
+		venues, err = venue.GetNearby(context.Background(), 37.7749, -122.4194, 1, 10)
+		suite.Require().NoError(err)
+		suite.Require().Len(venues, 1)
+		assert.Equal(suite.T(), int64(2), venues[0].ID)
+
+		// Cells wrap around the antimeridian, circles near the poles or too
+		// large aren't narrowed
+		cells = models.GeohashCoverCells(0, 179.999, 5)
+		assert.Contains(suite.T(), cells, models.GeohashEncode(0, -179.999, len(cells[0])))
+		assert.Nil(suite.T(), models.GeohashCoverCells(89.5, 0, 1))
+		assert.Nil(suite.T(), models.GeohashCoverCells(0, 0, 10000))
+	})
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAdminImpersonation tests read-only impersonation sessions and their
+// audit log
+func (suite *TestSuite) TestAdminImpersonation() {
+	suite.Run("Admin Impersonation", func() {
+		w := suite.makePOSTRequest("/v1/admin/impersonate/test_user_1", map[string]string{"reason": "Ticket 42"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		var adminID int64
+		err := suite.db.QueryRow("INSERT INTO users (email, password, is_superuser) VALUES ('support@example.com', 'unused', true) RETURNING id").Scan(&adminID)
+		suite.Require().NoError(err)
+		impersonationService := &services.ImpersonationService{}
+		session, token, err := impersonationService.Start(context.Background(), adminID, &suite.testData.TestUser1, "Ticket 42")
+		suite.Require().NoError(err)
+
+		// Reads of the impersonated user go through and are marked
+		w = suite.makeImpersonatedRequest("GET", "/v1/users/test_user_1/favorites", token)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		assert.NotEmpty(suite.T(), w.Header().Get("X-Impersonation"))
+
+		// Writes and other users are refused
+		w = suite.makeImpersonatedRequest("POST", "/v1/reviews/test_user_1/", token)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.ImpersonationReadOnly, response.Code)
+
+		w = suite.makeImpersonatedRequest("GET", "/v1/users/test_user_2/favorites", token)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		w = suite.makeImpersonatedRequest("GET", "/v1/users/test_user_1/favorites", "not-a-token")
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+		// Every request of the session is audited
+		entries, err := models.GetImpersonationAudit(context.Background(), session.ID)
+		suite.Require().NoError(err)
+		suite.Require().Len(entries, 3)
+		assert.False(suite.T(), entries[0].Blocked)
+		assert.Equal(suite.T(), "/v1/users/:snapp_id/favorites", entries[0].Route)
+		assert.True(suite.T(), entries[1].Blocked)
+		assert.Equal(suite.T(), http.MethodPost, entries[1].Method)
+		assert.True(suite.T(), entries[2].Blocked)
+
+		// Searches made while impersonating aren't kept in the user's history
+		w = suite.makeImpersonatedRequest("GET", "/v1/venues/search?q=impersonated", token)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		w = suite.makeGETRequest("/v1/venues/search?q=anonymous")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		countSearches := func(query string) int {
+			var count int
+			suite.Require().NoError(suite.db.QueryRow(
+				"SELECT COUNT(*) FROM search_analytics WHERE search_query = $1", query).Scan(&count))
+			return count
+		}
+		suite.Require().Eventually(func() bool {
+			return countSearches("anonymous") == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(suite.T(), 0, countSearches("impersonated"))
+
+		// Expired sessions stop working
+		_, err = suite.db.Exec("UPDATE impersonation_sessions SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1", session.ID)
+		suite.Require().NoError(err)
+		w = suite.makeImpersonatedRequest("GET", "/v1/users/test_user_1/favorites", token)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	})
+}
+
+// makeImpersonatedRequest makes a request with the impersonation token
+func (suite *TestSuite) makeImpersonatedRequest(method, url, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(services.ImpersonationHeader, token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/controllers"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubIPLocator locates the addresses it knows
+type stubIPLocator map[string]*services.IPLocation
+
+func (s stubIPLocator) Name() string {
+	return "stub"
+}
+
+func (s stubIPLocator) Locate(ctx context.Context, ip net.IP) (*services.IPLocation, error) {
+	return s[ip.String()], nil
+}
+
+// TestIPGeolocationFallback tests locating discovery requests without
+// coordinates by the client's IP address
+func (suite *TestSuite) TestIPGeolocationFallback() {
+	suite.Run("IPGeolocationFallback", func() {
+		previous := services.IPLocator
+		defer func() {
+			services.IPLocator = previous
+			services.ClearIPLocationCache()
+		}()
+		services.IPLocator = nil
+		services.ClearIPLocationCache()
+
+		const sanFranciscoIP = "203.0.113.7"
+		const unknownIP = "198.51.100.9"
+
+		// Coordinates are used as sent
+		w := suite.makeGETRequestFromIP("/v1/venues/nearby?lat=37.7749&lng=-122.4194", sanFranciscoIP, "")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), services.LocationSourceGPS, w.Header().Get(controllers.LocationSourceHeader))
+
+		// Without a backend the client can't be located
+		w = suite.makeGETRequestFromIP("/v1/venues/nearby", sanFranciscoIP, "")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		services.IPLocator = stubIPLocator{
+			sanFranciscoIP: {Latitude: 37.7750, Longitude: -122.4190, City: "San Francisco", Country: "US"},
+		}
+		services.ClearIPLocationCache()
+
+		w = suite.makeGETRequestFromIP("/v1/venues/nearby", sanFranciscoIP, "")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), services.LocationSourceIP, w.Header().Get(controllers.LocationSourceHeader))
+		var nearby []models.Venue
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &nearby))
+		suite.Require().NotEmpty(nearby)
+		assert.Equal(suite.T(), int64(2), nearby[0].ID)
+
+		for _, url := range []string{"/v1/venues/nearby", "/v1/discover/trending", "/v1/discover/open-now"} {
+			w = suite.makeGETRequestFromIP(url, unknownIP, "")
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, url)
+			w = suite.makeGETRequestFromIP(url+"?lat=37.7749", sanFranciscoIP, "")
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, url)
+		}
+
+		// Trending venues rank by the last week's activity
+		_, err := suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id, created_at) VALUES
+			(1, 1, CURRENT_TIMESTAMP - INTERVAL '10 days'),
+			(1, 1, CURRENT_TIMESTAMP - INTERVAL '9 days'),
+			(2, 1, CURRENT_TIMESTAMP - INTERVAL '1 day')`)
+		suite.Require().NoError(err)
+		trendingService := &services.TrendingService{}
+		suite.Require().NoError(trendingService.RefreshTrending(context.Background()))
+
+		w = suite.makeGETRequestFromIP("/v1/discover/trending", sanFranciscoIP, "")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var trending serializers.TrendingVenuesResponse
+		suite.parseJSONResponse(w, &trending)
+		assert.Equal(suite.T(), services.LocationSourceIP, trending.LocationSource)
+		suite.Require().NotEmpty(trending.Venues)
+		assert.Equal(suite.T(), int64(2), trending.Venues[0].ID)
+
+		w = suite.makeGETRequestFromIP("/v1/discover/trending?lat=37.7849&lng=-122.4094", unknownIP, "")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &trending)
+		assert.Equal(suite.T(), services.LocationSourceGPS, trending.LocationSource)
+
+		// The default city is the one the client is in, else the tenant's
+		w = suite.makeGETRequestFromIP("/v1/discover/city", sanFranciscoIP, "")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var city serializers.DefaultCityResponse
+		suite.parseJSONResponse(w, &city)
+		assert.Equal(suite.T(), suite.testData.TestCity.ID, city.City.ID)
+		assert.Equal(suite.T(), services.LocationSourceIP, city.LocationSource)
+
+		w = suite.makeGETRequestFromIP("/v1/discover/city?lat=51.5074&lng=-0.1278", sanFranciscoIP, "")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		cityID := suite.testData.TestCity.ID
+		tenantService := &services.TenantService{}
+		suite.Require().NoError(tenantService.CreateTenant(context.Background(), &models.Tenant{
+			Slug: "geo", Name: "Geo", DefaultCityID: &cityID, IsActive: true,
+		}))
+		w = suite.makeGETRequestFromIP("/v1/discover/city", unknownIP, "geo")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		city = serializers.DefaultCityResponse{}
+		suite.parseJSONResponse(w, &city)
+		assert.Equal(suite.T(), cityID, city.City.ID)
+		assert.Empty(suite.T(), city.LocationSource)
+		assert.Empty(suite.T(), w.Header().Get(controllers.LocationSourceHeader))
+	})
+}
+
+// makeGETRequestFromIP makes a GET request from the IP address, selecting
+// the tenant by slug when set
+func (suite *TestSuite) makeGETRequestFromIP(url, ip, slug string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.RemoteAddr = ip + ":40000"
+	if slug != "" {
+		req.Header.Set("X-Tenant", slug)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
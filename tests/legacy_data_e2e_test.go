@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLegacyDataCSV tests exporting the legacy voting data to CSV and
+// importing edited files with validation and dry runs
+func (suite *TestSuite) TestLegacyDataCSV() {
+	suite.Run("Legacy Data CSV", func() {
+		suite.setupLegacyVotingData()
+		defer func() {
+			suite.db.Exec("DELETE FROM mentors WHERE name = 'New Mentor'")
+			suite.db.Exec("UPDATE mentors SET name = 'Test Mentor 2', photo = 'mentor2.jpg' WHERE id = 2")
+		}()
+
+		legacyDataService := &services.LegacyDataService{}
+		ctx := context.Background()
+
+		var export bytes.Buffer
+		suite.Require().NoError(legacyDataService.Export(ctx, services.LegacyParticipants, &export))
+		lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+		assert.Equal(suite.T(), "id,name,code,photo,is_active,mentor_id", lines[0])
+		assert.Contains(suite.T(), lines, "3,Test Participant 3,P003,participant3.jpg,false,1")
+
+		export.Reset()
+		suite.Require().NoError(legacyDataService.Export(ctx, services.LegacyVoting, &export))
+		assert.Contains(suite.T(), export.String(), "2,Past Competition 2023,Last years competition,1,")
+
+		assert.Equal(suite.T(), services.ErrUnknownDataset, legacyDataService.Export(ctx, "vouchers", &export))
+
+		// A dry run counts the changes without saving them
+		mentorsCSV := "name,id,photo\nRenamed Mentor,2,m2.jpg\nNew Mentor,,\n"
+		result, err := legacyDataService.Import(ctx, services.LegacyMentors, strings.NewReader(mentorsCSV), true)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), result.Errors)
+		assert.Equal(suite.T(), 2, result.Rows)
+		assert.Equal(suite.T(), 1, result.Created)
+		assert.Equal(suite.T(), 1, result.Updated)
+
+		var name string
+		suite.Require().NoError(suite.db.QueryRow("SELECT name FROM mentors WHERE id = 2").Scan(&name))
+		assert.Equal(suite.T(), "Test Mentor 2", name)
+
+		result, err = legacyDataService.Import(ctx, services.LegacyMentors, strings.NewReader(mentorsCSV), false)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, result.Created)
+		suite.Require().NoError(suite.db.QueryRow("SELECT name FROM mentors WHERE id = 2").Scan(&name))
+		assert.Equal(suite.T(), "Renamed Mentor", name)
+
+		var newMentors int
+		suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM mentors WHERE name = 'New Mentor'").Scan(&newMentors))
+		assert.Equal(suite.T(), 1, newMentors)
+
+		// Invalid rows are reported by line and nothing is saved
+		participantsCSV := "id,name,code,photo,is_active,mentor_id\n" +
+			"1,Updated Participant,P001,p1.jpg,true,1\n" +
+			"1,Duplicate,P009,,true,1\n" +
+			",,P010,,true,\n" +
+			",Unknown Mentor,P011,,true,99\n" +
+			",Maybe Active,P012,,maybe,\n"
+		result, err = legacyDataService.Import(ctx, services.LegacyParticipants, strings.NewReader(participantsCSV), false)
+		suite.Require().NoError(err)
+		suite.Require().Len(result.Errors, 4)
+		assert.Equal(suite.T(), 3, result.Errors[0].Line)
+		assert.Equal(suite.T(), 4, result.Errors[1].Line)
+		assert.Equal(suite.T(), 6, result.Errors[2].Line)
+		assert.Equal(suite.T(), 5, result.Errors[3].Line)
+		assert.Equal(suite.T(), "mentor_id 99 not found", result.Errors[3].Message)
+		assert.Zero(suite.T(), result.Updated)
+
+		suite.Require().NoError(suite.db.QueryRow("SELECT name FROM participants WHERE id = 1").Scan(&name))
+		assert.Equal(suite.T(), "Test Participant 1", name)
+
+		votingCSV := "id,name,description,winner_id,started_at,ended_at\n" +
+			"1,Test Competition 2024,,,2024-02-01T00:00:00Z,2024-01-01T00:00:00Z\n"
+		result, err = legacyDataService.Import(ctx, services.LegacyVoting, strings.NewReader(votingCSV), true)
+		suite.Require().NoError(err)
+		suite.Require().Len(result.Errors, 1)
+		assert.Equal(suite.T(), "ended_at must be after started_at", result.Errors[0].Message)
+
+		result, err = legacyDataService.Import(ctx, services.LegacyMentors, strings.NewReader("id,name\n1,Mentor\n"), true)
+		suite.Require().NoError(err)
+		suite.Require().Len(result.Errors, 1)
+		assert.Equal(suite.T(), 1, result.Errors[0].Line)
+
+		// Only admins export and import over the API
+		w := suite.makeGETRequest("/v1/admin/legacy/mentors/export")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		req, _ := http.NewRequest("POST", "/v1/admin/legacy/mentors/import?dry_run=true", strings.NewReader(mentorsCSV))
+		req.Header.Set("Content-Type", "text/csv")
+		w = httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLegacyVotingSunset tests refusing legacy votes once the legacy votings
+// are sunset, and the archive of their ended rounds
+func (suite *TestSuite) TestLegacyVotingSunset() {
+	suite.Run("Legacy Voting Sunset", func() {
+		suite.setupLegacyVotingData()
+		defer func() {
+			services.SetLegacyVotingSunset(false)
+			suite.db.Exec("DELETE FROM user_voting WHERE voting_id = 2")
+		}()
+
+		_, err := suite.db.Exec("DELETE FROM user_voting WHERE voting_id = 2")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO user_voting (voting_id, owner_id, vote_id) VALUES (2, 1, 1), (2, 2, 1)")
+		suite.Require().NoError(err)
+
+		services.SetLegacyVotingSunset(true)
+		var votes int
+		suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM user_voting WHERE voting_id = 1").Scan(&votes))
+
+		w := suite.makePOSTRequest("/v1/vote/test_user_1/1/2", nil)
+		suite.Require().Equal(http.StatusGone, w.Code, w.Body.String())
+		var migrated serializers.LegacyVotingMigratedResponse
+		suite.parseJSONResponse(w, &migrated)
+		assert.Equal(suite.T(), serializers.LegacyVotingMigrated, migrated.Code)
+		assert.Equal(suite.T(), "/v1/campaigns/featured", migrated.CampaignsURL)
+		assert.NotNil(suite.T(), migrated.Campaigns)
+		assert.Equal(suite.T(), "/v1/legacy/archive", migrated.ArchiveURL)
+
+		var after int
+		suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM user_voting WHERE voting_id = 1").Scan(&after))
+		assert.Equal(suite.T(), votes, after)
+
+		// Reading keeps working
+		w = suite.makeGETRequest("/v1/vote/test_user_1")
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		suite.getVoteResults("/v1/vote/test_user_1/results/1")
+
+		// Only ended rounds are archived, with their final results
+		w = suite.makeGETRequest("/v1/legacy/archive")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var archive []services.ArchivedVoting
+		suite.parseJSONResponse(w, &archive)
+		var past *services.ArchivedVoting
+		for i, archived := range archive {
+			assert.NotEqual(suite.T(), int64(1), archived.Voting.Id)
+			if archived.Voting.Id == 2 {
+				past = &archive[i]
+			}
+		}
+		suite.Require().NotNil(past)
+		assert.Equal(suite.T(), int64(2), past.TotalVotes)
+		suite.Require().NotEmpty(past.Results)
+		assert.Equal(suite.T(), int64(1), past.Results[0].ParticipantID)
+		assert.Equal(suite.T(), 100.0, past.Results[0].Percentage)
+		assert.True(suite.T(), past.Results[0].IsWinner)
+
+		// Votes are taken again once the sunset is lifted
+		services.SetLegacyVotingSunset(false)
+		w = suite.makePOSTRequest("/v1/vote/test_user_1/1/2", nil)
+		assert.NotEqual(suite.T(), http.StatusGone, w.Code)
+	})
+}
@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMenuSystem tests venue menus, owner management and dish search
+func (suite *TestSuite) TestMenuSystem() {
+	suite.Run("Menu System End-to-End", func() {
+		// The test user owns venue 1 but not venue 2
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+
+		suite.testMenuOwnerCRUD()
+		suite.testMenuOwnershipEnforced()
+		suite.testMenuItemValidation()
+		suite.testDishSearch()
+	})
+}
+
+func (suite *TestSuite) createTestMenu() (models.VenueMenu, models.MenuSection) {
+	w := suite.makePOSTRequest("/v1/owner/venues/1/menus", map[string]interface{}{
+		"name":     "Dinner",
+		"currency": "usd",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var menu models.VenueMenu
+	suite.parseJSONResponse(w, &menu)
+
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/menus/%d/sections", menu.ID), map[string]interface{}{
+		"name": "Mains",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var section models.MenuSection
+	suite.parseJSONResponse(w, &section)
+
+	return menu, section
+}
+
+func (suite *TestSuite) testMenuOwnerCRUD() {
+	menu, section := suite.createTestMenu()
+	assert.Equal(suite.T(), "USD", menu.Currency)
+	assert.True(suite.T(), menu.IsActive)
+
+	itemsURL := fmt.Sprintf("/v1/owner/venues/1/menus/%d/sections/%d/items", menu.ID, section.ID)
+
+	w := suite.makePOSTRequest(itemsURL, map[string]interface{}{
+		"name":        "Falafel Wrap",
+		"price":       9.5,
+		"dietaryTags": []string{"Vegan", "halal", "vegan"},
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var item models.MenuItem
+	suite.parseJSONResponse(w, &item)
+	assert.Equal(suite.T(), int64(1), item.VenueID)
+	assert.ElementsMatch(suite.T(), []string{"vegan", "halal"}, item.DietaryTags)
+
+	w = suite.makePOSTRequest(itemsURL, map[string]interface{}{
+		"name":  "Ribeye Steak",
+		"price": 32,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	// Public menu lists both items
+	w = suite.makeGETRequest("/v1/venues/1/menus")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.VenueMenusResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Menus, 1)
+	suite.Require().Len(response.Menus[0].Sections, 1)
+	assert.Len(suite.T(), response.Menus[0].Sections[0].Items, 2)
+
+	// Dietary filter only keeps tagged items
+	w = suite.makeGETRequest("/v1/venues/1/menus?dietary=vegan")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Menus[0].Sections[0].Items, 1)
+	assert.Equal(suite.T(), "Falafel Wrap", response.Menus[0].Sections[0].Items[0].Name)
+
+	// Update the item
+	w = suite.makePUTRequest(fmt.Sprintf("%s/%d", itemsURL, item.ID), map[string]interface{}{
+		"name":        "Falafel Wrap",
+		"price":       10.5,
+		"dietaryTags": []string{"vegan", "gluten-free"},
+		"isAvailable": false,
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	updated := models.MenuItem{ID: item.ID}
+	suite.Require().NoError(updated.GetByID(context.Background()))
+	assert.Equal(suite.T(), 10.5, *updated.Price)
+	assert.False(suite.T(), updated.IsAvailable)
+	assert.Contains(suite.T(), updated.DietaryTags, "gluten-free")
+
+	// Deactivated menus are hidden publicly but visible to the owner
+	w = suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/1/menus/%d", menu.ID), map[string]interface{}{
+		"name":     "Dinner",
+		"isActive": false,
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.makeGETRequest("/v1/venues/1/menus")
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Menus, 0)
+
+	w = suite.makeGETRequest("/v1/owner/venues/1/menus")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Menus, 1)
+
+	// Deleting the menu removes its sections and items
+	w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/1/menus/%d", menu.ID))
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var itemCount int
+	err := suite.db.QueryRow("SELECT COUNT(*) FROM menu_items WHERE venue_id = 1").Scan(&itemCount)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, itemCount)
+}
+
+func (suite *TestSuite) testMenuOwnershipEnforced() {
+	// Venue 2 is not owned by the test user
+	w := suite.makePOSTRequest("/v1/owner/venues/2/menus", map[string]interface{}{
+		"name": "Brunch",
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// A menu of venue 1 can not be reached through another venue
+	menu, _ := suite.createTestMenu()
+	_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 2")
+	suite.Require().NoError(err)
+
+	w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/2/menus/%d", menu.ID))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	_, err = suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 2")
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) testMenuItemValidation() {
+	menu, section := suite.createTestMenu()
+	itemsURL := fmt.Sprintf("/v1/owner/venues/1/menus/%d/sections/%d/items", menu.ID, section.ID)
+
+	w := suite.makePOSTRequest(itemsURL, map[string]interface{}{
+		"name":        "Mystery Dish",
+		"dietaryTags": []string{"paleo"},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest(itemsURL, map[string]interface{}{
+		"name":  "Free Lunch",
+		"price": -1,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makeGETRequest("/v1/venues/1/menus?dietary=paleo")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testDishSearch() {
+	menu, section := suite.createTestMenu()
+	itemsURL := fmt.Sprintf("/v1/owner/venues/1/menus/%d/sections/%d/items", menu.ID, section.ID)
+
+	w := suite.makePOSTRequest(itemsURL, map[string]interface{}{
+		"name":  "Margherita Pizza",
+		"price": 14,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	w = suite.makeGETRequest("/v1/venues/search?q=margherita")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.VenueSearchResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.MenuMatches, 1)
+	assert.Equal(suite.T(), "Margherita Pizza", response.MenuMatches[0].Name)
+	assert.Equal(suite.T(), "Test Restaurant 1", response.MenuMatches[0].VenueName)
+
+	// Venue searches, addresses and codes aren't dish names, even when a
+	// dish has them in its name
+	for _, name := range []string{"Combo #12", "Chef's Restaurant Special", "Pizza 2000"} {
+		w = suite.makePOSTRequest(itemsURL, map[string]interface{}{"name": name, "price": 9})
+		suite.Require().Equal(http.StatusCreated, w.Code)
+	}
+	for _, query := range []string{"#12", "restaurant special", "2000", "pizza 2000", "cafe near me", "pi"} {
+		w = suite.makeGETRequest("/v1/venues/search?q=" + url.QueryEscape(query))
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		response = serializers.VenueSearchResponse{}
+		suite.parseJSONResponse(w, &response)
+		assert.Empty(suite.T(), response.MenuMatches, query)
+	}
+
+	// Dish names with apostrophes are
+	w = suite.makeGETRequest("/v1/venues/search?q=" + url.QueryEscape("chef's"))
+	response = serializers.VenueSearchResponse{}
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.MenuMatches, 1)
+
+	// Vegan-only dish search
+	matches, err := models.SearchMenuItems(context.Background(), "pizza", []string{models.DietaryVegan}, nil, 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), matches, 0)
+}
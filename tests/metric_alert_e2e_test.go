@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetricAlerts tests the z-score anomaly detection on the daily
+// platform metrics
+func (suite *TestSuite) TestMetricAlerts() {
+	suite.Run("Metric Alerts", func() {
+		ctx := context.Background()
+		analyticsService := &services.AnalyticsService{}
+		day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+		// Steady reviews and signups for four weeks, then a burst of reviews
+		for i := 28; i >= 1; i-- {
+			reviews := 9 + 2*(i%2)
+			_, err := suite.db.Exec(`
+				INSERT INTO platform_stats_rollups (period, period_start, new_users, new_reviews)
+				VALUES ('day', $1, 20, $2)`,
+				day.AddDate(0, 0, -i), reviews)
+			suite.Require().NoError(err)
+		}
+		_, err := suite.db.Exec(`
+			INSERT INTO platform_stats_rollups (period, period_start, new_users, new_reviews)
+			VALUES ('day', $1, 22, 40)`, day)
+		suite.Require().NoError(err)
+
+		alerts, err := analyticsService.DetectMetricAnomalies(ctx, day)
+		suite.Require().NoError(err)
+		suite.Require().Len(alerts, 1)
+		assert.Equal(suite.T(), models.MetricReviews, alerts[0].Metric)
+		assert.Equal(suite.T(), models.MetricSpike, alerts[0].Direction)
+		assert.Equal(suite.T(), float64(40), alerts[0].Value)
+		assert.Equal(suite.T(), float64(10), alerts[0].Mean)
+		assert.Greater(suite.T(), alerts[0].ZScore, float64(3))
+
+		// Alerts are raised once per day
+		alerts, err = analyticsService.DetectMetricAnomalies(ctx, day)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), alerts)
+
+		listed, err := analyticsService.GetMetricAlerts(ctx, 30, "")
+		suite.Require().NoError(err)
+		assert.Len(suite.T(), listed, 1)
+		listed, err = analyticsService.GetMetricAlerts(ctx, 30, models.MetricVotes)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), listed)
+
+		// Admins only
+		w := suite.makeGETRequest("/v1/analytics/alerts")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
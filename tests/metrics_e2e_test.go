@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics tests the database pool metrics
+func (suite *TestSuite) TestMetrics() {
+	suite.Run("Metrics End-to-End", func() {
+		w := suite.makeGETRequest("/metrics")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Contains(suite.T(), w.Header().Get("Content-Type"), "text/plain")
+
+		body := w.Body.String()
+		assert.Contains(suite.T(), body, "# TYPE db_pool_open_connections gauge")
+		assert.Contains(suite.T(), body, "# TYPE db_pool_wait_count_total counter")
+		assert.Regexp(suite.T(), `(?m)^db_pool_max_open_connections \d+$`, body)
+		assert.Regexp(suite.T(), `(?m)^db_pool_in_use_connections \d+$`, body)
+
+		// The pool check is a job that never fails
+		poolService := new(services.DatabasePoolService)
+		assert.NoError(suite.T(), poolService.CheckPool(context.Background()))
+		assert.NoError(suite.T(), poolService.CheckPool(context.Background()))
+	})
+}
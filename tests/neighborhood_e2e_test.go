@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNeighborhoodDiscovery tests placing venues in neighborhood polygons and
+// discovering them by neighborhood
+func (suite *TestSuite) TestNeighborhoodDiscovery() {
+	suite.Run("Neighborhood Discovery", func() {
+		neighborhoodService := &services.NeighborhoodService{}
+
+		// Test Restaurant 2 lies within the Mission, Test Restaurant 1 doesn't
+		mission := &models.Neighborhood{
+			CityID: 1,
+			Name:   "Mission District",
+			Slug:   "mission-district",
+			Boundary: models.Polygon{
+				{{-122.43, 37.76}, {-122.41, 37.76}, {-122.41, 37.78}, {-122.43, 37.78}, {-122.43, 37.76}},
+			},
+		}
+		placed, err := neighborhoodService.CreateNeighborhood(context.Background(), mission)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, placed)
+
+		_, err = neighborhoodService.CreateNeighborhood(context.Background(), &models.Neighborhood{
+			CityID: 1, Name: "Mission District", Slug: "mission-district", Boundary: mission.Boundary,
+		})
+		assert.Equal(suite.T(), models.ErrNeighborhoodExists, err)
+
+		_, err = neighborhoodService.CreateNeighborhood(context.Background(), &models.Neighborhood{
+			CityID: 999, Name: "Nowhere", Slug: "nowhere", Boundary: mission.Boundary,
+		})
+		assert.Equal(suite.T(), services.ErrUnknownCity, err)
+
+		// Discovery lists only the venues within the neighborhood
+		w := suite.makeGETRequest(fmt.Sprintf("/v1/discover/by-neighborhood/%d", mission.ID))
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		var discovery serializers.NeighborhoodVenuesResponse
+		suite.parseJSONResponse(w, &discovery)
+		assert.Equal(suite.T(), "Mission District", discovery.Neighborhood.Name)
+		assert.Len(suite.T(), discovery.Neighborhood.Boundary, 1)
+		suite.Require().Len(discovery.Venues, 1)
+		assert.Equal(suite.T(), int64(2), discovery.Venues[0].ID)
+		assert.Equal(suite.T(), 1, discovery.Pagination.Total)
+
+		w = suite.makeGETRequest("/v1/discover/by-neighborhood/999999")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Search narrows by neighborhood and counts results per neighborhood
+		w = suite.makeGETRequest("/v1/venues/search?city=1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		var search serializers.VenueSearchResponse
+		suite.parseJSONResponse(w, &search)
+		suite.Require().Len(search.Filters.Neighborhoods, 1)
+		assert.Equal(suite.T(), mission.ID, search.Filters.Neighborhoods[0].ID)
+		assert.Equal(suite.T(), 1, search.Filters.Neighborhoods[0].VenueCount)
+
+		w = suite.makeGETRequest(fmt.Sprintf("/v1/venues/search?neighborhood=%d", mission.ID))
+		suite.Require().Equal(http.StatusOK, w.Code)
+		search = serializers.VenueSearchResponse{}
+		suite.parseJSONResponse(w, &search)
+		suite.Require().Len(search.Venues, 1)
+		assert.Equal(suite.T(), int64(2), search.Venues[0].ID)
+
+		// New venues are placed in the smallest neighborhood containing them
+		inner := &models.Neighborhood{
+			CityID: 1,
+			Name:   "Inner Mission",
+			Slug:   "inner-mission",
+			Boundary: models.Polygon{
+				{{-122.425, 37.762}, {-122.415, 37.762}, {-122.415, 37.768}, {-122.425, 37.768}},
+			},
+		}
+		placed, err = neighborhoodService.CreateNeighborhood(context.Background(), inner)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 0, placed)
+
+		venue := suite.createVenueInCity(serializers.CreateVenueRequest{
+			Name:       "Valencia Taqueria",
+			Address:    "800 Valencia St",
+			CityID:     1,
+			Latitude:   37.765,
+			Longitude:  -122.42,
+			CategoryID: 1,
+		})
+		suite.Require().NotNil(venue.NeighborhoodID)
+		assert.Equal(suite.T(), inner.ID, *venue.NeighborhoodID)
+
+		dolores := suite.createVenueInCity(serializers.CreateVenueRequest{
+			Name:       "Dolores Bakery",
+			Address:    "600 Guerrero St",
+			CityID:     1,
+			Latitude:   37.775,
+			Longitude:  -122.425,
+			CategoryID: 1,
+		})
+		suite.Require().NotNil(dolores.NeighborhoodID)
+		assert.Equal(suite.T(), mission.ID, *dolores.NeighborhoodID)
+
+		neighborhoodOf := func(venueID int64) int64 {
+			var neighborhoodID int64
+			suite.Require().NoError(suite.db.QueryRow("SELECT neighborhood_id FROM venues WHERE id = $1", venueID).Scan(&neighborhoodID))
+			return neighborhoodID
+		}
+
+		// A smaller neighborhood takes over the venues of a larger one
+		civic := &models.Neighborhood{
+			CityID: 1,
+			Name:   "Civic Center",
+			Slug:   "civic-center",
+			Boundary: models.Polygon{
+				{{-122.422, 37.772}, {-122.417, 37.772}, {-122.417, 37.777}, {-122.422, 37.777}},
+			},
+		}
+		placed, err = neighborhoodService.CreateNeighborhood(context.Background(), civic)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, placed)
+		assert.Equal(suite.T(), civic.ID, neighborhoodOf(2))
+
+		// Holes are outside the polygon: the bakery moves, Test Restaurant 2
+		// in the hole stays in the Civic Center
+		hayes := &models.Neighborhood{
+			CityID: 1,
+			Name:   "Hayes Valley",
+			Slug:   "hayes-valley",
+			Boundary: models.Polygon{
+				{{-122.428, 37.770}, {-122.412, 37.770}, {-122.412, 37.780}, {-122.428, 37.780}},
+				{{-122.421, 37.773}, {-122.418, 37.773}, {-122.418, 37.776}, {-122.421, 37.776}},
+			},
+		}
+		placed, err = neighborhoodService.CreateNeighborhood(context.Background(), hayes)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, placed)
+		assert.Equal(suite.T(), hayes.ID, neighborhoodOf(dolores.ID))
+		assert.Equal(suite.T(), civic.ID, neighborhoodOf(2))
+
+		// Boundaries crossing themselves are refused
+		_, err = neighborhoodService.CreateNeighborhood(context.Background(), &models.Neighborhood{
+			CityID: 1, Name: "Bowtie", Slug: "bowtie",
+			Boundary: models.Polygon{{{-122.43, 37.76}, {-122.41, 37.78}, {-122.41, 37.76}, {-122.43, 37.78}}},
+		})
+		assert.Equal(suite.T(), models.ErrInvalidBoundary, err)
+
+		// Only admins add neighborhoods over the API
+		w = suite.makePOSTRequest("/v1/admin/neighborhoods", serializers.NeighborhoodRequest{
+			CityID: 1, Name: "SoMa", Boundary: mission.Boundary,
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
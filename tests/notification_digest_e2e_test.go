@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNotificationDigests tests that helpful votes and new followers are
+// notified in one digest once their window ends
+func (suite *TestSuite) TestNotificationDigests() {
+	suite.Run("Notification Digests", func() {
+		ctx := context.Background()
+		notificationService := &services.NotificationService{}
+		_, err := suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3'), (4, 'test_user_4')")
+		suite.Require().NoError(err)
+		var reviewID int64
+		err = suite.db.QueryRow(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+			VALUES ($1, 2, 5, 'approved') RETURNING id`, suite.testData.TestVenue1.ID).Scan(&reviewID)
+		suite.Require().NoError(err)
+		notifications := func() []models.Notification {
+			notifications, err := models.GetUserNotifications(ctx, 2, 10)
+			suite.Require().NoError(err)
+			return notifications
+		}
+		mailer := &recordingMailer{}
+		defaultMailer := services.AppMailer
+		services.AppMailer = mailer
+		defer func() { services.AppMailer = defaultMailer }()
+		var accountID int64
+		err = suite.db.QueryRow(`INSERT INTO users (email, password, email_verified_at)
+			VALUES ('reviewer@example.com', 'unused', CURRENT_TIMESTAMP) RETURNING id`).Scan(&accountID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO account_links (user_id, snapp_user_id) VALUES ($1, 2)", accountID)
+		suite.Require().NoError(err)
+		flushDue := func(window string) {
+			_, err := suite.db.Exec("UPDATE notification_digest_events SET created_at = created_at - $1::INTERVAL", window)
+			suite.Require().NoError(err)
+			suite.Require().NoError(notificationService.SendDigests(ctx))
+		}
+
+		w := suite.makePOSTRequest(fmt.Sprintf("/v1/reviews/test_user_1/%d/vote", reviewID), map[string]bool{"isHelpful": true})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		suite.Require().NoError(notificationService.NotifyReviewHelpful(ctx, reviewID, 3))
+		suite.Require().NoError(notificationService.NotifyReviewHelpful(ctx, reviewID, 4))
+		// Voting twice and voting on your own review count nothing more
+		suite.Require().NoError(notificationService.NotifyReviewHelpful(ctx, reviewID, 3))
+		suite.Require().NoError(notificationService.NotifyReviewHelpful(ctx, reviewID, 2))
+
+		// Nothing is sent before the window ends
+		suite.Require().NoError(notificationService.SendDigests(ctx))
+		assert.Empty(suite.T(), notifications())
+
+		flushDue("25 hours")
+		sent := notifications()
+		suite.Require().Len(sent, 1)
+		assert.Equal(suite.T(), models.NotificationReviewHelpful, sent[0].EventType)
+		assert.Equal(suite.T(), fmt.Sprintf("3 people found your review of %s helpful today.", suite.testData.TestVenue1.Name), sent[0].Body)
+
+		// The digest is sent once, and not emailed without consent
+		suite.Require().NoError(notificationService.SendDigests(ctx))
+		assert.Len(suite.T(), notifications(), 1)
+		assert.Empty(suite.T(), mailer.sent)
+
+		_, err = suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+			VALUES (2, $1, $2, CURRENT_TIMESTAMP)`, models.ConsentEmailDigest, models.ConsentGranted)
+		suite.Require().NoError(err)
+
+		// Single events read as such
+		w = suite.makePOSTRequest("/v1/social/test_user_1/follow/2", nil)
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		flushDue("2 hours")
+		sent = notifications()
+		suite.Require().Len(sent, 2)
+		assert.Equal(suite.T(), models.NotificationNewFollower, sent[0].EventType)
+		assert.Equal(suite.T(), "New follower", sent[0].Title)
+		assert.Equal(suite.T(), "Someone started following you today.", sent[0].Body)
+
+		// Consenting users also get their digests by email
+		suite.Require().Len(mailer.sent, 1)
+		assert.Equal(suite.T(), "reviewer@example.com", mailer.sent[0].To)
+		assert.Equal(suite.T(), "New follower", mailer.sent[0].Subject)
+	})
+}
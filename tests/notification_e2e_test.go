@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNotificationSystem tests device registration and notification delivery
+func (suite *TestSuite) TestNotificationSystem() {
+	suite.Run("Notification System End-to-End", func() {
+		suite.testRegisterDevice()
+		suite.testRegisterDeviceValidation()
+		suite.testNotifyCreatesNotification()
+		suite.testCampaignNotifications()
+	})
+}
+
+func (suite *TestSuite) testRegisterDevice() {
+	deviceData := map[string]interface{}{
+		"platform":   "fcm",
+		"token":      "fcm-test-token-1",
+		"appVersion": "2.3.0",
+	}
+
+	w := suite.makePOSTRequest("/v1/notifications/test_user_1/devices", deviceData)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var device models.UserDevice
+	suite.parseJSONResponse(w, &device)
+	assert.Equal(suite.T(), int64(1), device.UserID)
+	assert.Equal(suite.T(), "fcm", device.Platform)
+	assert.True(suite.T(), device.IsActive)
+
+	// Registering the same token again should update instead of duplicating
+	w = suite.makePOSTRequest("/v1/notifications/test_user_1/devices", deviceData)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var count int
+	err := suite.db.QueryRow("SELECT COUNT(*) FROM user_devices WHERE token = 'fcm-test-token-1'").Scan(&count)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, count)
+}
+
+func (suite *TestSuite) testRegisterDeviceValidation() {
+	w := suite.makePOSTRequest("/v1/notifications/test_user_1/devices", map[string]interface{}{
+		"platform": "blackberry",
+		"token":    "some-token",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/notifications/test_user_1/devices", map[string]interface{}{
+		"platform": "apns",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testNotifyCreatesNotification() {
+	notificationService := &services.NotificationService{}
+
+	notification, err := notificationService.Notify(context.Background(), 1, models.NotificationBadge, "Badge", "You earned a badge", nil)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), notification.ID > 0)
+
+	notifications, err := models.GetUserNotifications(context.Background(), 1, 10)
+	suite.Require().NoError(err)
+	assert.NotEmpty(suite.T(), notifications)
+	assert.Equal(suite.T(), models.NotificationBadge, notifications[0].EventType)
+}
+
+// testCampaignNotifications tests that consenting users hear of campaigns
+// opening, once, and voters of their results
+func (suite *TestSuite) testCampaignNotifications() {
+	ctx := context.Background()
+	notificationService := &services.NotificationService{}
+
+	_, err := suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+		VALUES (1, $1, 'granted', CURRENT_TIMESTAMP)`, models.ConsentPushMarketing)
+	suite.Require().NoError(err)
+
+	now := time.Now()
+	_, err = suite.db.Exec(`INSERT INTO voting_campaigns
+		(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+		VALUES (70, 'Best Brunch', 'best_restaurant', 1, $1, $2, 3, true)`,
+		now.Add(-time.Minute), now.Add(time.Hour))
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(notificationService.AnnounceCampaigns(ctx))
+	suite.Require().NoError(notificationService.AnnounceCampaigns(ctx))
+	assert.Equal(suite.T(), []int64{1}, suite.campaignNotifiedUsers(models.NotificationCampaignStart, 70))
+
+	_, err = suite.db.Exec(`INSERT INTO campaign_votes (campaign_id, venue_id, user_id) VALUES (70, 1, 2)`)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`UPDATE voting_campaigns SET end_date = $1 WHERE id = 70`, now.Add(-time.Second))
+	suite.Require().NoError(err)
+
+	resultService := &services.CampaignResultService{}
+	suite.Require().NoError(resultService.SnapshotCampaignResults(ctx))
+	suite.Require().NoError(resultService.SnapshotCampaignResults(ctx))
+	assert.Equal(suite.T(), []int64{2}, suite.campaignNotifiedUsers(models.NotificationCampaignResults, 70))
+}
+
+// campaignNotifiedUsers returns the users notified of the event of the
+// campaign, once per notification
+func (suite *TestSuite) campaignNotifiedUsers(eventType string, campaignID int64) []int64 {
+	rows, err := suite.db.Query(`SELECT user_id FROM notifications
+		WHERE event_type = $1 AND data->>'campaignId' = $2::text ORDER BY user_id`,
+		eventType, campaignID)
+	suite.Require().NoError(err)
+	defer rows.Close()
+
+	userIDs := make([]int64, 0)
+	for rows.Next() {
+		var userID int64
+		suite.Require().NoError(rows.Scan(&userID))
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
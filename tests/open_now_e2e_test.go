@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOpenNowDiscovery tests finding the venues still open late at night,
+// sorted by closing time
+func (suite *TestSuite) TestOpenNowDiscovery() {
+	suite.Run("Open Now Discovery", func() {
+		_, err := suite.db.Exec(`UPDATE venues SET opening_hours = '{"friday": {"open": "20:00", "close": "02:00"}}' WHERE id = 1`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`UPDATE venues SET opening_hours = '{"friday": {"open": "18:00", "close": "23:45"}}' WHERE id = 2`)
+		suite.Require().NoError(err)
+
+		var lateBarID int64
+		err = suite.db.QueryRow(`INSERT INTO venues (name, slug, address, city_id, latitude, longitude, category_id,
+			average_rating, is_active, opening_hours)
+			VALUES ('Late Bar', 'late-bar', '789 Test Blvd, San Francisco, CA', 1, 37.7760, -122.4180, 1,
+			4.0, true, '{"friday": {"open": "21:00", "close": "01:00"}}')
+			RETURNING id`).Scan(&lateBarID)
+		suite.Require().NoError(err)
+
+		_, err = suite.db.Exec(`INSERT INTO venues (name, slug, address, city_id, latitude, longitude, category_id,
+			average_rating, is_active, opening_hours)
+			VALUES ('Breakfast Spot', 'breakfast-spot', '790 Test Blvd, San Francisco, CA', 1, 37.7761, -122.4181, 1,
+			4.0, true, '{"friday": {"open": "07:00", "close": "14:00"}}')`)
+		suite.Require().NoError(err)
+
+		openNowService := &services.OpenNowService{}
+
+		// Friday 23:00: venues open for another hour come first, by closing time
+		friday := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+		open, err := openNowService.GetOpenNow(context.Background(), 37.7749, -122.4194, 5, 20, friday)
+		suite.Require().NoError(err)
+		suite.Require().Len(open, 3)
+		assert.Equal(suite.T(), lateBarID, open[0].Venue.ID)
+		assert.Equal(suite.T(), 120, open[0].MinutesUntilClose)
+		assert.Equal(suite.T(), "01:00", open[0].ClosesAt)
+		assert.Equal(suite.T(), int64(1), open[1].Venue.ID)
+		assert.Equal(suite.T(), 180, open[1].MinutesUntilClose)
+		assert.Equal(suite.T(), int64(2), open[2].Venue.ID)
+		assert.Equal(suite.T(), 45, open[2].MinutesUntilClose)
+
+		// Hours running past midnight still count the next day
+		open, err = openNowService.GetOpenNow(context.Background(), 37.7749, -122.4194, 5, 20, friday.Add(150*time.Minute))
+		suite.Require().NoError(err)
+		suite.Require().Len(open, 1)
+		assert.Equal(suite.T(), int64(1), open[0].Venue.ID)
+		assert.Equal(suite.T(), 30, open[0].MinutesUntilClose)
+
+		open, err = openNowService.GetOpenNow(context.Background(), 37.7749, -122.4194, 5, 20, friday.Add(4*time.Hour))
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), open)
+
+		w := suite.makeGETRequest("/v1/discover/open-now?lat=37.7749&lng=-122.4194")
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+		w = suite.makeGETRequest("/v1/discover/open-now")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestHoursExceptions tests holiday closures and special hours replacing the
+// regular opening hours
+func (suite *TestSuite) TestHoursExceptions() {
+	suite.Run("Hours Exceptions", func() {
+		hours := models.OpeningHours{"friday": {Open: "20:00", Close: "02:00"}}
+		friday := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+		schedule := models.NewSchedule(hours, []models.HoursException{
+			{Date: "2026-10-16", IsClosed: true},
+			{Date: "2026-10-17", Open: "10:00", Close: "12:00"},
+		})
+		_, isOpen := schedule.ClosesAt(friday)
+		assert.False(suite.T(), isOpen)
+		opensAt, ok := schedule.NextOpenTime(friday)
+		suite.Require().True(ok)
+		assert.Equal(suite.T(), time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), opensAt)
+		closesAt, isOpen := schedule.ClosesAt(opensAt.Add(time.Hour))
+		assert.True(suite.T(), isOpen)
+		assert.Equal(suite.T(), "12:00", closesAt.Format("15:04"))
+
+		_, err := suite.db.Exec(`UPDATE venues SET opening_hours = '{"friday": {"open": "20:00", "close": "02:00"}}' WHERE id IN (1, 2)`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_hours_exceptions (venue_id, date, is_closed, reason)
+			VALUES (1, '2026-10-16', true, 'Staff party')`)
+		suite.Require().NoError(err)
+
+		openNowService := &services.OpenNowService{}
+		open, err := openNowService.GetOpenNow(context.Background(), 37.7749, -122.4194, 5, 20, friday)
+		suite.Require().NoError(err)
+		suite.Require().Len(open, 1)
+		assert.Equal(suite.T(), int64(2), open[0].Venue.ID)
+
+		suite.testHoursExceptionEndpoints()
+		suite.testOpenFilterHonorsExceptions()
+	})
+}
+
+func (suite *TestSuite) testHoursExceptionEndpoints() {
+	_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+	suite.Require().NoError(err)
+	defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(models.DateLayout)
+	w := suite.makePOSTRequest("/v1/owner/venues/1/hours-exceptions", map[string]interface{}{
+		"date": tomorrow, "isClosed": true, "reason": "Thanksgiving",
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	// Setting the date again replaces its exception
+	w = suite.makePOSTRequest("/v1/owner/venues/1/hours-exceptions", map[string]interface{}{
+		"date": tomorrow, "open": "18:00", "close": "03:00", "reason": "Launch party",
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var exception models.HoursException
+	suite.parseJSONResponse(w, &exception)
+	assert.False(suite.T(), exception.IsClosed)
+	assert.Equal(suite.T(), "03:00", exception.Close)
+
+	w = suite.makeGETRequest("/v1/owner/venues/1/hours-exceptions")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var response serializers.HoursExceptionsResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Exceptions, 1)
+	assert.Equal(suite.T(), tomorrow, response.Exceptions[0].Date)
+	assert.Equal(suite.T(), "Launch party", response.Exceptions[0].Reason)
+
+	for _, invalid := range []map[string]interface{}{
+		{"date": tomorrow, "isClosed": true, "open": "10:00"},
+		{"date": tomorrow, "open": "10:00"},
+		{"date": tomorrow, "open": "25:00", "close": "26:00"},
+		{"date": "2020-01-01", "isClosed": true},
+		{"date": "tomorrow", "isClosed": true},
+	} {
+		w = suite.makePOSTRequest("/v1/owner/venues/1/hours-exceptions", invalid)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, invalid)
+	}
+
+	w = suite.makePOSTRequest("/v1/owner/venues/2/hours-exceptions", map[string]interface{}{
+		"date": tomorrow, "isClosed": true,
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	url := "/v1/owner/venues/1/hours-exceptions/" + strconv.FormatInt(exception.ID, 10)
+	w = suite.makeDELETERequest(url)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.makeDELETERequest(url)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) testOpenFilterHonorsExceptions() {
+	_, err := suite.db.Exec(`UPDATE venues SET opening_hours = (
+		SELECT jsonb_object_agg(day, '{"open": "00:00", "close": "24:00"}'::jsonb)
+		FROM unnest(ARRAY['monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday']) day
+	) WHERE id IN (1, 2)`)
+	suite.Require().NoError(err)
+
+	w := suite.makeGETRequest("/v1/venues/search?is_open=true")
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var response serializers.VenueSearchResponse
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), 2, response.Pagination.Total)
+
+	// Closed around today whatever the time zone
+	_, err = suite.db.Exec(`INSERT INTO venue_hours_exceptions (venue_id, date, is_closed)
+		SELECT 2, CURRENT_DATE + d, true FROM generate_series(-1, 1) d`)
+	suite.Require().NoError(err)
+
+	w = suite.makeGETRequest("/v1/venues/search?is_open=true")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Equal(1, response.Pagination.Total)
+	assert.Equal(suite.T(), int64(1), response.Venues[0].ID)
+
+	w = suite.makeGETRequest("/v1/venues/2")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var detail serializers.VenueDetailResponse
+	suite.parseJSONResponse(w, &detail)
+	suite.Require().NotNil(detail.Venue.IsOpen)
+	assert.False(suite.T(), *detail.Venue.IsOpen)
+	assert.NotNil(suite.T(), detail.Venue.NextOpenTime)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOwnerAlerts tests emailing venue owners about their new approved
+// reviews and milestones, at most once per alert interval
+func (suite *TestSuite) TestOwnerAlerts() {
+	suite.Run("Owner Alerts", func() {
+		mailer := &recordingMailer{}
+		defaultMailer := services.AppMailer
+		services.AppMailer = mailer
+		defer func() { services.AppMailer = defaultMailer }()
+		defaultInterval := services.OwnerAlertInterval
+		services.OwnerAlertInterval = time.Hour
+		defer func() { services.OwnerAlertInterval = defaultInterval }()
+
+		var ownerID int64
+		err := suite.db.QueryRow("INSERT INTO users (email, password) VALUES ('owner-alerts@example.com', 'unused') RETURNING id").Scan(&ownerID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO account_links (user_id, snapp_user_id) VALUES ($1, 1)", ownerID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("UPDATE venues SET owner_id = $1, claimed_at = CURRENT_TIMESTAMP WHERE id = 1", ownerID)
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL, claimed_at = NULL WHERE id IN (1, 2)")
+		var venueName, venueSlug string
+		suite.Require().NoError(suite.db.QueryRow("SELECT name, slug FROM venues WHERE id = 1").Scan(&venueName, &venueSlug))
+
+		alertService := &services.OwnerAlertService{}
+		backdateLastAlert := func() {
+			_, err := suite.db.Exec("UPDATE owner_alert_state SET last_sent_at = last_sent_at - INTERVAL '2 hours' WHERE user_id = $1", ownerID)
+			suite.Require().NoError(err)
+		}
+
+		// Nothing happened since the claim
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		assert.Empty(suite.T(), mailer.sent)
+
+		first := suite.createApprovedOwnerReview("test_user_2", "Lovely terrace")
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		suite.Require().Len(mailer.sent, 1)
+		assert.Equal(suite.T(), "owner-alerts@example.com", mailer.sent[0].To)
+		assert.Equal(suite.T(), "New review of "+venueName, mailer.sent[0].Subject)
+		assert.Contains(suite.T(), mailer.sent[0].Body, services.ReviewReplyURL(venueSlug, first))
+
+		notifications, err := models.GetUserNotifications(context.Background(), 1, 10)
+		suite.Require().NoError(err)
+		suite.Require().NotEmpty(notifications)
+		assert.Equal(suite.T(), models.NotificationOwnerReview, notifications[0].EventType)
+
+		// Reviews approved within the interval wait for the next email
+		second := suite.createApprovedOwnerReview("test_user_1", "Slow service")
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		assert.Len(suite.T(), mailer.sent, 1)
+
+		backdateLastAlert()
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		suite.Require().Len(mailer.sent, 2)
+		assert.Contains(suite.T(), mailer.sent[1].Body, services.ReviewReplyURL(venueSlug, second))
+		assert.NotContains(suite.T(), mailer.sent[1].Body, services.ReviewReplyURL(venueSlug, first))
+
+		// Milestones are sent once
+		_, err = suite.db.Exec(`UPDATE venues SET owner_id = $1, claimed_at = CURRENT_TIMESTAMP,
+			total_ratings = 100, average_rating = 4.6 WHERE id = 2`, ownerID)
+		suite.Require().NoError(err)
+		backdateLastAlert()
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		suite.Require().Len(mailer.sent, 3)
+		assert.Contains(suite.T(), mailer.sent[2].Body, "reached 100 reviews")
+		assert.Contains(suite.T(), mailer.sent[2].Body, "reached a 4.5 average rating")
+
+		backdateLastAlert()
+		suite.Require().NoError(alertService.SendOwnerAlerts(context.Background()))
+		assert.Len(suite.T(), mailer.sent, 3)
+	})
+}
+
+// createApprovedOwnerReview reviews venue 1 and approves the review, returning
+// its ID
+func (suite *TestSuite) createApprovedOwnerReview(snappID, title string) int64 {
+	w := suite.makePOSTRequest("/v1/reviews/"+snappID+"/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 4.0,
+		"title":         title,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var review models.VenueReview
+	suite.parseJSONResponse(w, &review)
+	_, err := suite.db.Exec(`UPDATE venue_reviews SET moderation_status = 'approved', moderated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, review.ID)
+	suite.Require().NoError(err)
+	return review.ID
+}
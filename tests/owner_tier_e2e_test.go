@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOwnerTiers tests the photo and analytics quotas of free and premium
+// venue owners
+func (suite *TestSuite) TestOwnerTiers() {
+	suite.Run("Owner Tiers", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+		// Owners start on the free tier
+		w := suite.makeGETRequest("/v1/owner/plan")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var plan services.OwnerPlan
+		suite.parseJSONResponse(w, &plan)
+		assert.Equal(suite.T(), models.TierFree, plan.Tier)
+		assert.Equal(suite.T(), services.FreeVenuePhotos, plan.Limits.MaxVenuePhotos)
+
+		var photoIDs []int64
+		for i := 0; i < services.FreeVenuePhotos; i++ {
+			w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{
+				URL: fmt.Sprintf("https://cdn.example.com/venues/1/%d.jpg", i),
+			})
+			suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+			var added serializers.VenuePhotoResponse
+			suite.parseJSONResponse(w, &added)
+			photoIDs = append(photoIDs, added.Photo.ID)
+		}
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{URL: "https://cdn.example.com/venues/1/extra.jpg"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), serializers.TierLimitReached)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{URL: "not a url"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/2/photos", serializers.AddVenuePhotoRequest{URL: "https://cdn.example.com/venues/2/1.jpg"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// Removing a photo frees its slot
+		w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/1/photos/%d", photoIDs[0]))
+		suite.Require().Equal(http.StatusOK, w.Code)
+		w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/1/photos/%d", photoIDs[0]))
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{URL: "https://cdn.example.com/venues/1/extra.jpg"})
+		assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+		w = suite.makeGETRequest("/v1/venues/1/photos")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var gallery serializers.VenuePhotosResponse
+		suite.parseJSONResponse(w, &gallery)
+		assert.Len(suite.T(), gallery.Photos, services.FreeVenuePhotos)
+		assert.Nil(suite.T(), gallery.Plan)
+
+		// Free owners get the basic analytics
+		w = suite.makeGETRequest("/v1/analytics/venues/1?time_range=month")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var analytics services.VenueAnalytics
+		suite.parseJSONResponse(w, &analytics)
+		assert.Equal(suite.T(), services.AnalyticsBasic, analytics.Level)
+		suite.Require().NotNil(analytics.Plan)
+		assert.Equal(suite.T(), models.TierFree, analytics.Plan.Tier)
+		assert.Nil(suite.T(), analytics.RatingDistribution)
+		assert.Nil(suite.T(), analytics.PopularHours)
+
+		// Only administrators set tiers
+		w = suite.makePUTRequest("/v1/admin/owners/1/subscription", map[string]interface{}{"tier": "premium"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		subscription := &models.OwnerSubscription{UserID: 1, Tier: models.TierPremium}
+		suite.Require().NoError(subscription.Save(context.Background()))
+		missing := &models.OwnerSubscription{UserID: 999, Tier: models.TierPremium}
+		assert.Error(suite.T(), missing.Save(context.Background()))
+
+		// Premium owners have no photo limit and the full analytics
+		w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{URL: "https://cdn.example.com/venues/1/premium.jpg"})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var added serializers.VenuePhotoResponse
+		suite.parseJSONResponse(w, &added)
+		assert.Equal(suite.T(), models.TierPremium, added.Plan.Tier)
+		assert.Equal(suite.T(), 0, added.Plan.Limits.MaxVenuePhotos)
+
+		w = suite.makeGETRequest("/v1/analytics/venues/1?time_range=month")
+		analytics = services.VenueAnalytics{}
+		suite.parseJSONResponse(w, &analytics)
+		assert.Equal(suite.T(), services.AnalyticsFull, analytics.Level)
+		assert.NotNil(suite.T(), analytics.RatingDistribution)
+
+		// Expired subscriptions fall back to free, keeping the photos added
+		expired := time.Now().Add(-time.Hour)
+		subscription.ExpiresAt = &expired
+		suite.Require().NoError(subscription.Save(context.Background()))
+
+		w = suite.makeGETRequest("/v1/owner/venues/1/photos")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		gallery = serializers.VenuePhotosResponse{}
+		suite.parseJSONResponse(w, &gallery)
+		assert.Len(suite.T(), gallery.Photos, services.FreeVenuePhotos+1)
+		suite.Require().NotNil(gallery.Plan)
+		assert.Equal(suite.T(), models.TierFree, gallery.Plan.Tier)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/photos", serializers.AddVenuePhotoRequest{URL: "https://cdn.example.com/venues/1/late.jpg"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
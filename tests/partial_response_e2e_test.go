@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPartialResponses tests the fields query parameter selecting the
+// fields of venue, review and campaign responses
+func (suite *TestSuite) TestPartialResponses() {
+	suite.Run("Partial Responses", func() {
+		w := suite.makeGETRequest("/v1/venues/1?fields=id,name,latitude,longitude,averageRating")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var detail map[string]map[string]interface{}
+		suite.parseJSONResponse(w, &detail)
+		assert.Len(suite.T(), detail, 1)
+		assert.ElementsMatch(suite.T(), []string{"id", "name", "latitude", "longitude", "averageRating"}, keys(detail["venue"]))
+		assert.Equal(suite.T(), "Test Restaurant 1", detail["venue"]["name"])
+
+		// Detail sections are included when asked for
+		w = suite.makeGETRequest("/v1/venues/1?fields=id,reviewSummary")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var sections map[string]json.RawMessage
+		suite.parseJSONResponse(w, &sections)
+		assert.ElementsMatch(suite.T(), []string{"venue", "reviewSummary"}, rawKeys(sections))
+
+		w = suite.makeGETRequest("/v1/venues/1?fields=id,passwordHash")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Lists have each item filtered, the rest of the response is kept
+		w = suite.makeGETRequest("/v1/venues/search?fields=id,name")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var search struct {
+			Venues     []map[string]interface{} `json:"venues"`
+			Pagination map[string]interface{}   `json:"pagination"`
+		}
+		suite.parseJSONResponse(w, &search)
+		suite.Require().NotEmpty(search.Venues)
+		for _, venue := range search.Venues {
+			assert.ElementsMatch(suite.T(), []string{"id", "name"}, keys(venue))
+		}
+		assert.NotEmpty(suite.T(), search.Pagination)
+
+		w = suite.makeGETRequest("/v1/venues/nearby?lat=37.7749&lng=-122.4194&fields=id,distance")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var nearby []map[string]interface{}
+		suite.parseJSONResponse(w, &nearby)
+		suite.Require().NotEmpty(nearby)
+		assert.NotContains(suite.T(), nearby[0], "name")
+
+		_, err := suite.db.Exec(`INSERT INTO venue_reviews
+			(venue_id, user_id, overall_rating, review_text, moderation_status)
+			VALUES (1, 2, 4.5, 'Great pasta', 'approved')`)
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest("/v1/venues/1/reviews?fields=id,overallRating")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var reviews struct {
+			Reviews []map[string]interface{} `json:"reviews"`
+		}
+		suite.parseJSONResponse(w, &reviews)
+		suite.Require().Len(reviews.Reviews, 1)
+		assert.ElementsMatch(suite.T(), []string{"id", "overallRating"}, keys(reviews.Reviews[0]))
+
+		now := time.Now()
+		_, err = suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (60, 'Best Pasta', 'best_restaurant', 1, $1, $2, 1, true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest("/v1/campaign-results/60?fields=title,totalVotes")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var results map[string]interface{}
+		suite.parseJSONResponse(w, &results)
+		assert.ElementsMatch(suite.T(), []string{"title", "totalVotes"}, keys(results))
+	})
+}
+
+func keys(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	return names
+}
+
+func rawKeys(object map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	return names
+}
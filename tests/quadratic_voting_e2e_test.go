@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestQuadraticVoting tests credit budgets and effective votes of quadratic campaigns
+func (suite *TestSuite) TestQuadraticVoting() {
+	suite.Run("Quadratic Voting End-to-End", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, start_date, end_date, max_votes_per_user, voting_mode, credit_budget, is_active)
+			VALUES (40, 'Quadratic Brunch', 'best_restaurant', $1, $2, 1, 'quadratic', 20, true)`,
+			now.Add(-1*time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		suite.testQuadraticVoteCosts()
+		suite.testQuadraticResults()
+	})
+}
+
+func (suite *TestSuite) testQuadraticVoteCosts() {
+	// 4 votes cost 16 of the 20 credits
+	w := suite.makePOSTRequest("/v1/campaigns/40/test_user_1/vote", map[string]interface{}{
+		"venueId": 1,
+		"votes":   4,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response serializers.SubmitCampaignVoteResponse
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), 4, response.Vote.Votes)
+	assert.Equal(suite.T(), 16, response.Vote.CreditsSpent)
+	suite.Require().NotNil(response.RemainingCredits)
+	assert.Equal(suite.T(), 4, *response.RemainingCredits)
+	assert.Equal(suite.T(), 2, response.RemainingVotes)
+	assert.Equal(suite.T(), 4, response.Receipt.Votes)
+
+	// The receipt covers the number of votes
+	w = suite.makePOSTRequest("/v1/receipts/verify", response.Receipt)
+	var verification serializers.VerifyReceiptResponse
+	suite.parseJSONResponse(w, &verification)
+	assert.True(suite.T(), verification.Valid)
+	assert.True(suite.T(), verification.Recorded)
+
+	inflated := response.Receipt
+	inflated.Votes = 9
+	w = suite.makePOSTRequest("/v1/receipts/verify", inflated)
+	suite.parseJSONResponse(w, &verification)
+	assert.False(suite.T(), verification.Valid)
+
+	// 3 more votes would cost 9 credits, only 4 are left
+	w = suite.makePOSTRequest("/v1/campaigns/40/test_user_1/vote", map[string]interface{}{
+		"venueId": 2,
+		"votes":   3,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.InsufficientCredits, errorResponse.Code)
+
+	// The rejected vote left the balance untouched
+	w = suite.makePOSTRequest("/v1/campaigns/40/test_user_1/vote", map[string]interface{}{
+		"venueId": 2,
+		"votes":   2,
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	w = suite.makeGETRequest("/v1/campaigns/40/test_user_1/credits")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var balance models.CampaignCreditBalance
+	suite.parseJSONResponse(w, &balance)
+	assert.Equal(suite.T(), 20, balance.Budget)
+	assert.Equal(suite.T(), 20, balance.Spent)
+	assert.Equal(suite.T(), 0, balance.Remaining)
+
+	// Standard campaigns accept a single vote per venue
+	_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+		(id, title, campaign_type, start_date, end_date, is_active)
+		VALUES (41, 'Standard Brunch', 'best_restaurant', $1, $2, true)`,
+		time.Now().Add(-1*time.Hour), time.Now().Add(24*time.Hour))
+	suite.Require().NoError(err)
+
+	w = suite.makePOSTRequest("/v1/campaigns/41/test_user_1/vote", map[string]interface{}{
+		"venueId": 1,
+		"votes":   2,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makeGETRequest("/v1/campaigns/41/test_user_1/credits")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testQuadraticResults() {
+	w := suite.makeGETRequest("/v1/campaign-results/40")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var results models.CampaignResults
+	suite.parseJSONResponse(w, &results)
+	assert.Equal(suite.T(), models.VotingModeQuadratic, results.VotingMode)
+	assert.Equal(suite.T(), 6, results.TotalVotes)
+	suite.Require().Len(results.Categories, 1)
+	suite.Require().Len(results.Categories[0].Standings, 2)
+	assert.Equal(suite.T(), int64(1), results.Categories[0].Standings[0].VenueID)
+	assert.Equal(suite.T(), 4, results.Categories[0].Standings[0].Votes)
+
+	var totalVotes int
+	err := suite.db.QueryRow("SELECT total_votes FROM voting_campaigns WHERE id = 40").Scan(&totalVotes)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 6, totalVotes)
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	databases "voting-app/app"
+	"voting-app/app/middlewares"
+	"voting-app/app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestQueryTimeout tests that queries stop with the request context
+func (suite *TestSuite) TestQueryTimeout() {
+	suite.Run("Query Timeout", func() {
+		// A cancelled context fails the query without running it
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := models.GetVenueCategories(ctx)
+		assert.Error(suite.T(), err)
+
+		// A slow query is cut off at the request deadline
+		router := gin.New()
+		router.Use(middlewares.QueryTimeout(200 * time.Millisecond))
+		router.GET("/slow", func(c *gin.Context) {
+			var result string
+			err := databases.PostgresDB.QueryRowContext(c.Request.Context(), "SELECT pg_sleep(5)::text").Scan(&result)
+			if err != nil {
+				c.Status(http.StatusGatewayTimeout)
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+
+		started := time.Now()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(suite.T(), http.StatusGatewayTimeout, w.Code)
+		assert.Less(suite.T(), time.Since(started), 2*time.Second)
+	})
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRatingTemplates tests that detailed ratings follow the template of the
+// venue's category
+func (suite *TestSuite) TestRatingTemplates() {
+	suite.Run("Rating Templates", func() {
+		ctx := context.Background()
+
+		// Without a template any dimension can be rated
+		w := suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		assert.Nil(suite.T(), detail.RatingTemplate)
+
+		template := &models.RatingTemplate{CategoryID: 1, Dimensions: []models.RatingDimension{
+			{Key: "food", Label: "Food"}, {Key: "service", Label: "Service"}, {Key: "ambiance", Label: "Ambiance"},
+		}}
+		suite.Require().NoError(template.Save(ctx))
+		assert.Equal(suite.T(), sql.ErrNoRows, (&models.RatingTemplate{CategoryID: 999}).Save(ctx))
+
+		w = suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		detail = serializers.VenueDetailResponse{}
+		suite.parseJSONResponse(w, &detail)
+		if assert.NotNil(suite.T(), detail.RatingTemplate) {
+			assert.Equal(suite.T(), template.Dimensions, detail.RatingTemplate.Dimensions)
+		}
+
+		review := serializers.CreateReviewRequest{
+			VenueID:         1,
+			OverallRating:   4,
+			DetailedRatings: json.RawMessage(`{"food": 5, "music": 3}`),
+		}
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1", review)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), "music")
+
+		review.DetailedRatings = json.RawMessage(`{"food": 6}`)
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1", review)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		review.DetailedRatings = json.RawMessage(`{"food": 5, "service": 4}`)
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1", review)
+		assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+		w = suite.makeGETRequest("/v1/admin/rating-templates")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+
+	suite.Run("Rating Template Request Validation", func() {
+		request := serializers.RatingTemplateRequest{Dimensions: []models.RatingDimension{{Key: "Drinks"}}}
+		_, isValid := request.Validate()
+		assert.False(suite.T(), isValid)
+
+		request = serializers.RatingTemplateRequest{Dimensions: []models.RatingDimension{{Key: "drinks"}, {Key: "drinks"}}}
+		_, isValid = request.Validate()
+		assert.False(suite.T(), isValid)
+
+		request = serializers.RatingTemplateRequest{Dimensions: []models.RatingDimension{{Key: "drinks"}, {Key: "music", Label: " Music "}}}
+		_, isValid = request.Validate()
+		assert.True(suite.T(), isValid)
+		assert.Equal(suite.T(), "drinks", request.Dimensions[0].Label)
+		assert.Equal(suite.T(), "Music", request.Dimensions[1].Label)
+	})
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRecommendationFeedback tests that venues users gave feedback on leave
+// their recommendations and that the feedback tunes the signal weights
+func (suite *TestSuite) TestRecommendationFeedback() {
+	suite.Run("Recommendation Feedback", func() {
+		engine := &services.RecommendationEngine{}
+		rc := services.RecommendationContext{UserID: 1, MaxDistance: 10, Limit: 10}
+
+		recommendations, err := engine.GetPersonalizedRecommendations(context.Background(), rc)
+		suite.Require().NoError(err)
+		assert.Len(suite.T(), recommendations, 2)
+
+		w := suite.makePOSTRequest("/v1/discover/test_user_1/feedback", serializers.RecommendationFeedbackRequest{
+			VenueID:  1,
+			Feedback: models.RecommendationNotInterested,
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+		var feedback models.RecommendationFeedback
+		suite.parseJSONResponse(w, &feedback)
+		assert.Equal(suite.T(), int64(1), feedback.VenueID)
+		assert.Contains(suite.T(), feedback.Signals, services.SignalRating)
+
+		// The venue isn't recommended again
+		recommendations, err = engine.GetPersonalizedRecommendations(context.Background(), rc)
+		suite.Require().NoError(err)
+		suite.Require().Len(recommendations, 1)
+		assert.Equal(suite.T(), int64(2), recommendations[0].Venue.ID)
+
+		w = suite.makePOSTRequest("/v1/discover/test_user_1/feedback", serializers.RecommendationFeedbackRequest{
+			VenueID:  2,
+			Feedback: "loved_it",
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/discover/test_user_1/feedback", serializers.RecommendationFeedbackRequest{
+			VenueID:  999999,
+			Feedback: models.RecommendationBeenThere,
+		})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Been there raises a signal's weight, not interested lowers it
+		_, err = suite.db.Exec(`INSERT INTO recommendation_feedback (user_id, venue_id, feedback, signals)
+			VALUES (2, 1, $1, $2), (2, 2, $3, $4)`,
+			models.RecommendationBeenThere, pq.Array([]string{services.SignalCategory}),
+			models.RecommendationNotInterested, pq.Array([]string{services.SignalFeatured}))
+		suite.Require().NoError(err)
+
+		weights, err := engine.GetScoringWeights(context.Background())
+		suite.Require().NoError(err)
+		assert.Greater(suite.T(), weights.Weight(services.SignalCategory), 1.0)
+		assert.Less(suite.T(), weights.Weight(services.SignalFeatured), 1.0)
+		assert.Less(suite.T(), weights.Weight(services.SignalRating), 1.0)
+		assert.Equal(suite.T(), 1.0, weights.Weight(services.SignalSocial))
+	})
+}
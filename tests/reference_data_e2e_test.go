@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReferenceData tests listing the enumerations the API accepts and
+// validating requests against the same values
+func (suite *TestSuite) TestReferenceData() {
+	suite.Run("Reference Data", func() {
+		w := suite.makeGETRequest("/v1/utils/reference")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "public, max-age=3600", w.Header().Get("Cache-Control"))
+
+		var reference serializers.ReferenceData
+		suite.parseJSONResponse(w, &reference)
+		assert.Equal(suite.T(), models.VisitTypes, reference.VisitTypes)
+		assert.Equal(suite.T(), models.PriceRanges, reference.PriceRanges)
+		assert.Equal(suite.T(), models.Amenities, reference.Amenities)
+		assert.Equal(suite.T(), models.CampaignTypes, reference.CampaignTypes)
+		for _, option := range reference.Amenities {
+			assert.NotEmpty(suite.T(), option.Label, option.Value)
+		}
+		assert.True(suite.T(), reference.CampaignTypes.Contains(models.CampaignTypeBestOf))
+
+		// Searches only accept the listed amenity keys
+		w = suite.makeGETRequest("/v1/venues/search?amenities=wifi,outdoor_seating")
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+		w = suite.makeGETRequest("/v1/venues/search?amenities=wifi,jacuzzi")
+		suite.Require().Equal(http.StatusBadRequest, w.Code)
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), "amenities must be comma separated amenities: "+
+			strings.Join(models.Amenities.Values(), ", "), response.Message)
+
+		// Reviews only accept the listed visit types
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+			"venueId":       1,
+			"overallRating": 4.0,
+			"visitType":     "brunch",
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+			"venueId":       1,
+			"overallRating": 4.0,
+			"visitType":     reference.VisitTypes[0].Value,
+		})
+		assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	})
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestBodyLimits tests the body size and content type enforcement
+func (suite *TestSuite) TestRequestBodyLimits() {
+	suite.Run("Request Body Limits", func() {
+		suite.testPayloadTooLarge()
+		suite.testUnsupportedMediaType()
+	})
+}
+
+func (suite *TestSuite) sendRawRequest(method, url, contentType string, body []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TestSuite) testPayloadTooLarge() {
+	oversized := []byte(`{"name": "` + strings.Repeat("a", 2<<20) + `"}`)
+
+	w := suite.sendRawRequest("POST", "/v1/users/test_user_1/saved-searches", "application/json", oversized)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+
+	var response serializers.Base
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), serializers.PayloadTooLarge, response.Code)
+
+	// Bodies without a declared length are measured while reading
+	req, _ := http.NewRequest("POST", "/v1/users/test_user_1/saved-searches", ioutil.NopCloser(bytes.NewReader(oversized)))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func (suite *TestSuite) testUnsupportedMediaType() {
+	body := []byte(`{"name": "Date night"}`)
+
+	w := suite.sendRawRequest("POST", "/v1/users/test_user_1/saved-searches", "text/plain", body)
+	assert.Equal(suite.T(), http.StatusUnsupportedMediaType, w.Code)
+
+	var response serializers.Base
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), serializers.UnsupportedMediaType, response.Code)
+
+	w = suite.sendRawRequest("PUT", "/v1/reviews/test_user_1/drafts/1", "application/x-www-form-urlencoded", []byte("reviewText=hello"))
+	assert.Equal(suite.T(), http.StatusUnsupportedMediaType, w.Code)
+
+	// JSON with parameters is accepted
+	w = suite.sendRawRequest("POST", "/v1/users/test_user_1/saved-searches", "application/json; charset=utf-8", body)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	// Requests without a body need no content type
+	w = suite.sendRawRequest("POST", "/v1/owner/venues/1/review-invites", "", nil)
+	assert.NotEqual(suite.T(), http.StatusUnsupportedMediaType, w.Code)
+}
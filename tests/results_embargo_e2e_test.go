@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/models"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResultsEmbargo tests campaigns hiding their standings until they end
+func (suite *TestSuite) TestResultsEmbargo() {
+	suite.Run("Results Embargo", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active, hide_results_until_end)
+			VALUES (50, 'Secret Ballot', 'best_restaurant', 1, $1, $2, 2, true, true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		for _, vote := range []struct {
+			user  string
+			venue int64
+		}{{"test_user_1", 1}, {"test_user_1", 2}, {"test_user_2", 1}} {
+			w := suite.makePOSTRequest("/v1/campaigns/50/"+vote.user+"/vote", map[string]interface{}{"venueId": vote.venue})
+			suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		// Only participation is shown while voting runs
+		w := suite.makeGETRequest("/v1/campaign-results/50")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var results models.CampaignResults
+		suite.parseJSONResponse(w, &results)
+		assert.True(suite.T(), results.ResultsHidden)
+		suite.Require().NotNil(results.ResultsAvailableAt)
+		assert.WithinDuration(suite.T(), now.Add(24*time.Hour), *results.ResultsAvailableAt, time.Second)
+		assert.Equal(suite.T(), 3, results.TotalVotes)
+		assert.Equal(suite.T(), 2, results.Voters)
+		suite.Require().Len(results.Categories, 1)
+		assert.Equal(suite.T(), 3, results.Categories[0].TotalVotes)
+		assert.Empty(suite.T(), results.Categories[0].Standings)
+		assert.Nil(suite.T(), results.Categories[0].WinnerVenueID)
+
+		w = suite.makeGETRequest("/v1/campaign-results/50/snapshots")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// Administrators see the standings all along
+		var adminID int64
+		err = suite.db.QueryRow("INSERT INTO users (email, password, is_superuser) VALUES ('results-admin@example.com', 'unused', true) RETURNING id").Scan(&adminID)
+		suite.Require().NoError(err)
+		token, restoreKey := suite.signAdminJWT(adminID)
+		defer restoreKey()
+
+		w = suite.makeAuthorizedGETRequest("/v1/campaign-results/50", token)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		results = models.CampaignResults{}
+		suite.parseJSONResponse(w, &results)
+		assert.False(suite.T(), results.ResultsHidden)
+		suite.Require().Len(results.Categories[0].Standings, 2)
+
+		w = suite.makeAuthorizedGETRequest("/v2/campaigns/50/results", token)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		results = models.CampaignResults{}
+		suite.parseJSONResponse(w, &results)
+		assert.False(suite.T(), results.ResultsHidden)
+		assert.Len(suite.T(), results.Categories[0].Standings, 2)
+
+		w = suite.makeAuthorizedGETRequest("/v1/campaign-results/50/snapshots", token)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+		w = suite.makeAuthorizedGETRequest("/v1/campaign-results/50", "not-a-token")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// The standings unlock once the campaign ends
+		_, err = suite.db.Exec("UPDATE voting_campaigns SET end_date = $1 WHERE id = 50", now.Add(-time.Minute))
+		suite.Require().NoError(err)
+
+		w = suite.makeGETRequest("/v1/campaign-results/50")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		results = models.CampaignResults{}
+		suite.parseJSONResponse(w, &results)
+		assert.False(suite.T(), results.ResultsHidden)
+		assert.Nil(suite.T(), results.ResultsAvailableAt)
+		suite.Require().Len(results.Categories[0].Standings, 2)
+		assert.Equal(suite.T(), int64(1), results.Categories[0].Standings[0].VenueID)
+		assert.Equal(suite.T(), 2, results.Categories[0].Standings[0].Votes)
+
+		w = suite.makeGETRequest("/v1/campaign-results/50/snapshots")
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+	})
+}
+
+// signAdminJWT signs a JWT of the administrator with a key it makes the
+// API trust, until the returned restore is called
+func (suite *TestSuite) signAdminJWT(userID int64) (string, func()) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	suite.Require().NoError(err)
+
+	cfg := config.Get()
+	defaultKey := cfg.JWT.PublicKey
+	cfg.JWT.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"user_id":      float64(userID),
+		"is_superuser": true,
+	}).SignedString(privateKey)
+	suite.Require().NoError(err)
+	return token, func() { cfg.JWT.PublicKey = defaultKey }
+}
+
+// makeAuthorizedGETRequest makes a GET request with the JWT
+func (suite *TestSuite) makeAuthorizedGETRequest(url, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "JWT "+token)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
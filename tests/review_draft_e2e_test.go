@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewDrafts tests autosaving, resuming and publishing review drafts
+func (suite *TestSuite) TestReviewDrafts() {
+	suite.Run("Review Drafts End-to-End", func() {
+		suite.testSaveReviewDraft()
+		suite.testReviewDraftValidation()
+		suite.testPublishingDeletesDraft()
+	})
+}
+
+func (suite *TestSuite) testSaveReviewDraft() {
+	w := suite.makeGETRequest("/v1/reviews/test_user_1/drafts/1")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePUTRequest("/v1/reviews/test_user_1/drafts/1", map[string]interface{}{
+		"reviewText": "The pasta was",
+	})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	// Saving again replaces the draft
+	w = suite.makePUTRequest("/v1/reviews/test_user_1/drafts/1", map[string]interface{}{
+		"overallRating":   4.5,
+		"detailedRatings": map[string]float64{"food": 5, "service": 4},
+		"reviewText":      "The pasta was excellent, the tiramisu even better",
+		"visitType":       "dinner",
+		"photos":          []string{"reviews/1/pasta.jpg"},
+	})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = suite.makeGETRequest("/v1/reviews/test_user_1/drafts/1")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var draft models.ReviewDraft
+	suite.parseJSONResponse(w, &draft)
+	suite.Require().NotNil(draft.OverallRating)
+	assert.Equal(suite.T(), 4.5, *draft.OverallRating)
+	assert.Equal(suite.T(), "The pasta was excellent, the tiramisu even better", draft.ReviewText)
+	assert.Equal(suite.T(), "dinner", draft.VisitType)
+	assert.JSONEq(suite.T(), `{"food": 5, "service": 4}`, string(draft.DetailedRatings))
+	assert.JSONEq(suite.T(), `["reviews/1/pasta.jpg"]`, string(draft.Photos))
+
+	// Drafts are per user
+	otherDraft := &models.ReviewDraft{UserID: 2, VenueID: 1}
+	assert.Equal(suite.T(), sql.ErrNoRows, otherDraft.Get(context.Background()))
+}
+
+func (suite *TestSuite) testReviewDraftValidation() {
+	w := suite.makePUTRequest("/v1/reviews/test_user_1/drafts/2", map[string]interface{}{
+		"overallRating": 7,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePUTRequest("/v1/reviews/test_user_1/drafts/2", map[string]interface{}{
+		"visitType": "brunch-ish",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePUTRequest("/v1/reviews/test_user_1/drafts/99999", map[string]interface{}{
+		"reviewText": "Nowhere",
+	})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) testPublishingDeletesDraft() {
+	w := suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 4.5,
+		"reviewText":    "The pasta was excellent, the tiramisu even better",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	w = suite.makeGETRequest("/v1/reviews/test_user_1/drafts/1")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// A reviewed venue can't get a new draft
+	w = suite.makePUTRequest("/v1/reviews/test_user_1/drafts/1", map[string]interface{}{
+		"reviewText": "Second thoughts",
+	})
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+
+	var response serializers.Base
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), serializers.AlreadyReviewed, response.Code)
+}
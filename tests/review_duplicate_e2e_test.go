@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewDuplicates tests flagging reviews pasted across venues and
+// leaving them out of the venues' summaries
+func (suite *TestSuite) TestReviewDuplicates() {
+	suite.Run("Review Duplicates", func() {
+		const text = "The tasting menu was outstanding from start to finish, the staff explained every course " +
+			"and the wine pairing was generous. Best dinner we had all year, book a table by the window."
+
+		// Signatures ignore case, punctuation and spacing
+		signature := services.ReviewMinHash(text)
+		suite.Require().Len(signature, 64)
+		assert.Equal(suite.T(), 1.0, services.SignatureSimilarity(signature,
+			services.ReviewMinHash("  "+strings.ToUpper(text)+" !!")))
+		assert.Nil(suite.T(), services.ReviewMinHash("Great food, friendly staff"))
+
+		original := suite.createDuplicateTestReview("test_user_1", 1, text)
+		assert.False(suite.T(), original.IsFlagged)
+		assert.Nil(suite.T(), original.DuplicateOf)
+
+		// Pasting the review at another venue, with a word changed
+		copied := suite.createDuplicateTestReview("test_user_1", 2, text[:len(text)-7]+"garden.")
+		assert.True(suite.T(), copied.IsFlagged)
+		suite.Require().NotNil(copied.DuplicateOf)
+		assert.Equal(suite.T(), original.ID, *copied.DuplicateOf)
+
+		// Deleting the original leaves its copy on its own, other users
+		// pasting a recent review are caught too
+		_, err := suite.db.Exec("DELETE FROM venue_reviews WHERE id = $1", original.ID)
+		suite.Require().NoError(err)
+		different := suite.createDuplicateTestReview("test_user_2", 1,
+			"Slow service on a busy Friday night, the pasta arrived lukewarm and the waiter forgot our drinks twice. "+
+				"Dessert was fine but overall it was not worth the price.")
+		assert.False(suite.T(), different.IsFlagged)
+		assert.Nil(suite.T(), different.DuplicateOf)
+
+		pasted := suite.createDuplicateTestReview("test_user_2", 2, text)
+		suite.Require().NotNil(pasted.DuplicateOf)
+		assert.Equal(suite.T(), copied.ID, *pasted.DuplicateOf)
+
+		duplicates, err := models.GetReviewDuplicates(context.Background(), copied.ID)
+		suite.Require().NoError(err)
+		suite.Require().Len(duplicates, 1)
+		assert.Equal(suite.T(), pasted.ID, duplicates[0].ReviewID)
+		assert.GreaterOrEqual(suite.T(), duplicates[0].Similarity, services.DuplicateSimilarity)
+
+		// Approved duplicates are still left out of the summaries
+		_, err = suite.db.Exec("UPDATE venue_reviews SET moderation_status = 'approved' WHERE venue_id = 2")
+		suite.Require().NoError(err)
+		stats, err := models.GetSummaryReviewStats(context.Background(), 2)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, stats.ReviewCount)
+		summarized, err := models.GetSummarizedReviews(context.Background(), 2, 10)
+		suite.Require().NoError(err)
+		suite.Require().Len(summarized, 1)
+		assert.Equal(suite.T(), copied.ID, summarized[0].ID)
+
+		// Only administrators see the groups
+		w := suite.makeGETRequest("/v1/admin/reviews/1/duplicates")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	})
+}
+
+func (suite *TestSuite) createDuplicateTestReview(snappID string, venueID int64, text string) models.VenueReview {
+	w := suite.makePOSTRequest("/v1/reviews/"+snappID+"/", map[string]interface{}{
+		"venueId":       venueID,
+		"overallRating": 4.0,
+		"reviewText":    text,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var review models.VenueReview
+	suite.parseJSONResponse(w, &review)
+	return review
+}
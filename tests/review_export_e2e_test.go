@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewExport tests owners exporting their venue's approved reviews,
+// streamed for small venues and generated in the background for large ones
+func (suite *TestSuite) TestReviewExport() {
+	suite.Run("Review Export", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+
+		_, err = suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3') ON CONFLICT (id) DO NOTHING")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, detailed_ratings, title, review_text, moderation_status, helpful_votes, created_at)
+			VALUES (1, 1, 4.5, '{"food": 4.5, "service": 4}', 'Great pasta', 'Would come back, again', 'approved', 3, '2026-03-01 19:00:00'),
+				   (1, 2, 2.0, '{"food": 2}', '', '=HYPERLINK("http://example.com")', 'approved', 0, '2026-03-02 12:00:00'),
+				   (1, 3, 1.0, NULL, 'Spam', 'Not yet moderated', 'pending', 0, '2026-03-03 12:00:00')`)
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/venues/1/reviews/export?format=csv")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(suite.T(), w.Header().Get("Content-Type"), "text/csv")
+		assert.Contains(suite.T(), w.Header().Get("Content-Disposition"), "attachment")
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		suite.Require().NoError(err)
+		suite.Require().Len(records, 3)
+		assert.Equal(suite.T(), []string{"date", "rating", "food", "service", "title", "text", "helpful_votes"}, records[0])
+		assert.Equal(suite.T(), []string{"2026-03-01T19:00:00Z", "4.5", "4.5", "4", "Great pasta", "Would come back, again", "3"}, records[1])
+		// Formulas are neutralized and missing dimensions left empty
+		assert.Equal(suite.T(), "", records[2][3])
+		assert.True(suite.T(), strings.HasPrefix(records[2][5], "'="))
+
+		w = suite.makeGETRequest("/v1/venues/1/reviews/export?format=xlsx")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makeGETRequest("/v1/venues/2/reviews/export")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		suite.testReviewExportInBackground()
+	})
+}
+
+func (suite *TestSuite) testReviewExportInBackground() {
+	syncLimit := services.ReviewExportSyncLimit
+	services.ReviewExportSyncLimit = 1
+	defer func() { services.ReviewExportSyncLimit = syncLimit }()
+
+	stored := map[string][]byte{}
+	putObject, getObject := services.PutReviewExportObject, services.GetReviewExportObject
+	defer func() {
+		services.PutReviewExportObject, services.GetReviewExportObject = putObject, getObject
+	}()
+	services.PutReviewExportObject = func(ctx context.Context, key string, data []byte) error {
+		stored[key] = data
+		return nil
+	}
+	services.GetReviewExportObject = func(ctx context.Context, key string) (io.ReadCloser, error) {
+		data, exists := stored[key]
+		if !exists {
+			return nil, fmt.Errorf("no object %s", key)
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	w := suite.makeGETRequest("/v1/venues/1/reviews/export")
+	suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+	var queued serializers.ReviewExportResponse
+	suite.parseJSONResponse(w, &queued)
+	assert.Equal(suite.T(), models.ReviewExportPending, queued.Export.Status)
+	assert.Empty(suite.T(), queued.DownloadURL)
+
+	// Asking again while it waits doesn't queue another
+	w = suite.makeGETRequest("/v1/venues/1/reviews/export")
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	var again serializers.ReviewExportResponse
+	suite.parseJSONResponse(w, &again)
+	assert.Equal(suite.T(), queued.Export.ID, again.Export.ID)
+
+	w = suite.makeGETRequest(queued.StatusURL + "/download")
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	suite.Require().NoError(new(services.ReviewExportService).ProcessPending(context.Background()))
+
+	w = suite.makeGETRequest(queued.StatusURL)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var completed serializers.ReviewExportResponse
+	suite.parseJSONResponse(w, &completed)
+	assert.Equal(suite.T(), models.ReviewExportCompleted, completed.Export.Status)
+	if assert.NotNil(suite.T(), completed.Export.ReviewCount) {
+		assert.Equal(suite.T(), 2, *completed.Export.ReviewCount)
+	}
+	suite.Require().NotEmpty(completed.DownloadURL)
+
+	w = suite.makeGETRequest(completed.DownloadURL)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), records, 3)
+
+	// Exports are only found through their own venue
+	w = suite.makeGETRequest(fmt.Sprintf("/v1/venues/2/reviews/exports/%d", queued.Export.ID))
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	w = suite.makeGETRequest("/v1/venues/1/reviews/exports/999999")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewInvites tests verified reviews written with venue invite links
+func (suite *TestSuite) TestReviewInvites() {
+	suite.Run("Review Invites End-to-End", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+
+		invites := suite.testCreateReviewInvites()
+		suite.testVerifiedReview(invites)
+		suite.testInvalidReviewInvites(invites)
+		suite.testVerifiedReviewWeighting()
+	})
+}
+
+func (suite *TestSuite) testCreateReviewInvites() []models.ReviewInvite {
+	w := suite.makePOSTRequest("/v1/owner/venues/1/review-invites", map[string]interface{}{
+		"count":         2,
+		"expiresInDays": 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var response serializers.ReviewInvitesResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Invites, 2)
+	assert.NotEqual(suite.T(), response.Invites[0].Token, response.Invites[1].Token)
+	assert.Contains(suite.T(), response.Invites[0].InviteURL, "/venues/test-restaurant-1/review?invite=")
+
+	// Only the owner can generate invites
+	w = suite.makePOSTRequest("/v1/owner/venues/2/review-invites", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	w = suite.makePOSTRequest("/v1/owner/venues/1/review-invites", map[string]interface{}{
+		"count": 500,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	return response.Invites
+}
+
+func (suite *TestSuite) testVerifiedReview(invites []models.ReviewInvite) {
+	w := suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 5.0,
+		"reviewText":    "Scanned the QR code on my receipt",
+		"inviteToken":   invites[0].Token,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var review models.VenueReview
+	suite.parseJSONResponse(w, &review)
+	assert.True(suite.T(), review.IsVerified)
+
+	var reviewID int64
+	err := suite.db.QueryRow("SELECT review_id FROM review_invites WHERE token = $1", invites[0].Token).Scan(&reviewID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), review.ID, reviewID)
+}
+
+func (suite *TestSuite) testInvalidReviewInvites(invites []models.ReviewInvite) {
+	// An invite only verifies reviews of its own venue
+	w := suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       2,
+		"overallRating": 4.0,
+		"inviteToken":   invites[1].Token,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// A used invite cannot verify another review
+	_, err := suite.db.Exec("DELETE FROM venue_reviews WHERE venue_id = 1 AND user_id = 1")
+	suite.Require().NoError(err)
+
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 4.0,
+		"inviteToken":   invites[0].Token,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Expired invites are rejected and no review is created
+	_, err = suite.db.Exec("UPDATE review_invites SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 day' WHERE token = $1", invites[1].Token)
+	suite.Require().NoError(err)
+
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 4.0,
+		"inviteToken":   invites[1].Token,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var count int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM venue_reviews WHERE venue_id = 1 AND user_id = 1").Scan(&count)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func (suite *TestSuite) testVerifiedReviewWeighting() {
+	_, err := suite.db.Exec(`INSERT INTO venue_reviews
+		(venue_id, user_id, overall_rating, is_verified, moderation_status)
+		VALUES (1, 1, 5.0, true, 'approved'), (1, 2, 2.0, false, 'approved')`)
+	suite.Require().NoError(err)
+
+	w := suite.makeGETRequest("/v1/venues/1/reviews/summary")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var summary models.ReviewSummary
+	suite.parseJSONResponse(w, &summary)
+	assert.Equal(suite.T(), 3.5, summary.AverageRating)
+	assert.Equal(suite.T(), 1, summary.VerifiedReviews)
+	assert.InDelta(suite.T(), 4.0, summary.WeightedRating, 0.001) // (5.0*2 + 2.0) / 3
+}
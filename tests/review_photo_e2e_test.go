@@ -0,0 +1,268 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewPhotos tests that uploaded photos are stored without EXIF data
+// and that reviews return their dimensions and dominant color
+func (suite *TestSuite) TestReviewPhotos() {
+	stored := make(map[string][]byte)
+	putPhotoObject := services.PutPhotoObject
+	defer func() { services.PutPhotoObject = putPhotoObject }()
+	services.PutPhotoObject = func(ctx context.Context, key, contentType string, data []byte) error {
+		stored[key] = data
+		return nil
+	}
+
+	suite.Run("Review Photos End-to-End", func() {
+		suite.testUploadJPEGWithEXIF(stored)
+		suite.testUploadPNGForm()
+		suite.testUploadValidation()
+		suite.testReviewPhotoDetails()
+	})
+}
+
+func (suite *TestSuite) testUploadJPEGWithEXIF(stored map[string][]byte) {
+	// 40x20, rotated 90° clockwise by its EXIF orientation
+	w := suite.uploadPhoto("image/jpeg", exifJPEG(suite.solidImage(40, 20, color.RGBA{R: 200, G: 50, B: 50, A: 255}), 6))
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var photo models.Photo
+	suite.parseJSONResponse(w, &photo)
+	assert.Equal(suite.T(), "image/jpeg", photo.ContentType)
+	assert.Equal(suite.T(), 20, photo.Width)
+	assert.Equal(suite.T(), 40, photo.Height)
+	assert.Len(suite.T(), photo.DominantColor, 7)
+	assert.Contains(suite.T(), photo.URL, "/v1/files/reviews/photos/")
+
+	suite.Require().Len(stored, 1)
+	for _, data := range stored {
+		assert.False(suite.T(), bytes.Contains(data, []byte("Exif")), "EXIF data must be stripped")
+		assert.False(suite.T(), bytes.Contains(data, []byte("Secret Street")), "GPS data must be stripped")
+		assert.Equal(suite.T(), int64(len(data)), photo.SizeBytes)
+	}
+}
+
+func (suite *TestSuite) testUploadPNGForm() {
+	var img bytes.Buffer
+	suite.Require().NoError(png.Encode(&img, suite.solidImage(30, 10, color.RGBA{R: 200, G: 50, B: 50, A: 255})))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photo", "dish.png")
+	suite.Require().NoError(err)
+	part.Write(img.Bytes())
+	suite.Require().NoError(form.Close())
+
+	w := suite.uploadPhoto(form.FormDataContentType(), body.Bytes())
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var photo models.Photo
+	suite.parseJSONResponse(w, &photo)
+	assert.Equal(suite.T(), "image/png", photo.ContentType)
+	assert.Equal(suite.T(), 30, photo.Width)
+	assert.Equal(suite.T(), 10, photo.Height)
+	assert.Equal(suite.T(), "#c83232", photo.DominantColor)
+}
+
+func (suite *TestSuite) testUploadValidation() {
+	w := suite.uploadPhoto("image/jpeg", []byte("not an image"))
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response serializers.Base
+	suite.parseJSONResponse(w, &response)
+	assert.Equal(suite.T(), serializers.InvalidInput, response.Code)
+
+	// Upload routes skip the JSON body limit but have the photo limit
+	w = suite.uploadPhoto("image/jpeg", make([]byte, services.MaxPhotoBytes+1))
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func (suite *TestSuite) testReviewPhotoDetails() {
+	var img bytes.Buffer
+	suite.Require().NoError(png.Encode(&img, suite.solidImage(16, 9, color.RGBA{R: 10, G: 120, B: 60, A: 255})))
+	w := suite.uploadPhoto("image/png", img.Bytes())
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var photo models.Photo
+	suite.parseJSONResponse(w, &photo)
+
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1", serializers.CreateReviewRequest{
+		VenueID:       2,
+		OverallRating: 4,
+		ReviewText:    "Lovely terrace, the photo says it all",
+		Photos:        []string{photo.URL, "https://example.com/external.jpg"},
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var review models.VenueReview
+	suite.parseJSONResponse(w, &review)
+	suite.Require().Len(review.PhotoDetails, 2)
+	assert.Equal(suite.T(), photo.URL, review.PhotoDetails[0].URL)
+	assert.Equal(suite.T(), 16, review.PhotoDetails[0].Width)
+	assert.Equal(suite.T(), 9, review.PhotoDetails[0].Height)
+	assert.Equal(suite.T(), "#0a783c", review.PhotoDetails[0].DominantColor)
+
+	// External photos only have their URL
+	assert.Equal(suite.T(), "https://example.com/external.jpg", review.PhotoDetails[1].URL)
+	assert.Zero(suite.T(), review.PhotoDetails[1].Width)
+
+	w = suite.makeGETRequest("/v1/venues/2/reviews")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"dominantColor":"#0a783c"`)
+}
+
+func (suite *TestSuite) uploadPhoto(contentType string, data []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/reviews/test_user_1/photos", bytes.NewReader(data))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TestSuite) solidImage(width, height int, c color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifJPEG encodes the image as a JPEG with an EXIF segment holding the
+// orientation and a GPS area name
+func exifJPEG(img image.Image, orientation uint16) []byte {
+	var encoded bytes.Buffer
+	jpeg.Encode(&encoded, img, nil)
+	data := encoded.Bytes()
+
+	gpsArea := []byte("Secret Street\x00")
+	tiff := new(bytes.Buffer)
+	tiff.WriteString("MM\x00\x2A")
+	binary.Write(tiff, binary.BigEndian, uint32(8))
+	binary.Write(tiff, binary.BigEndian, uint16(2))
+	// Orientation
+	binary.Write(tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(tiff, binary.BigEndian, uint32(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{orientation, 0})
+	// Image description, standing in for the GPS data
+	binary.Write(tiff, binary.BigEndian, []uint16{0x010E, 2})
+	binary.Write(tiff, binary.BigEndian, uint32(len(gpsArea)))
+	binary.Write(tiff, binary.BigEndian, uint32(8+2+2*12+4))
+	binary.Write(tiff, binary.BigEndian, uint32(0))
+	tiff.Write(gpsArea)
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	withExif := append([]byte{}, data[:2]...)
+	withExif = append(withExif, app1...)
+	return append(withExif, data[2:]...)
+}
+
+// TestReviewPhotoOrder tests that authors can reorder and caption the
+// photos of their reviews, and that photos stored as plain URLs are read
+func (suite *TestSuite) TestReviewPhotoOrder() {
+	suite.Run("Review Photo Order and Captions", func() {
+		ctx := context.Background()
+
+		var legacyID int64
+		err := suite.db.QueryRow(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, photos, moderation_status)
+			VALUES (2, 2, 4, '["old1.jpg", "old2.jpg"]', 'approved') RETURNING id`).Scan(&legacyID)
+		suite.Require().NoError(err)
+		legacy := &models.VenueReview{ID: legacyID}
+		suite.Require().NoError(legacy.GetByID(ctx))
+		suite.Require().Len(legacy.Photos, 2)
+		assert.Equal(suite.T(), "old2.jpg", legacy.Photos[1].URL)
+		assert.Equal(suite.T(), 1, legacy.Photos[1].Order)
+
+		// Only the author changes the photos
+		w := suite.makePUTRequest(fmt.Sprintf("/v1/reviews/test_user_1/%d/photos/order", legacyID), serializers.ReorderReviewPhotosRequest{
+			URLs: []string{"old2.jpg", "old1.jpg"},
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		tooMany := make([]string, services.MaxReviewPhotos+1)
+		for i := range tooMany {
+			tooMany[i] = fmt.Sprintf("photo%d.jpg", i)
+		}
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1", serializers.CreateReviewRequest{
+			VenueID: 1, OverallRating: 5, Photos: tooMany,
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/reviews/test_user_1", serializers.CreateReviewRequest{
+			VenueID: 1, OverallRating: 5, ReviewText: "Desserts worth the trip",
+			Photos: []string{"pasta.jpg", "tiramisu.jpg", "terrace.jpg"},
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var review models.VenueReview
+		suite.parseJSONResponse(w, &review)
+		suite.Require().Len(review.Photos, 3)
+		assert.Equal(suite.T(), "tiramisu.jpg", review.Photos[1].URL)
+		assert.Equal(suite.T(), 1, review.Photos[1].Order)
+		assert.False(suite.T(), review.Photos[1].UploadedAt.IsZero())
+
+		orderURL := fmt.Sprintf("/v1/reviews/test_user_1/%d/photos/order", review.ID)
+		for _, urls := range [][]string{
+			{"terrace.jpg", "pasta.jpg"},
+			{"terrace.jpg", "pasta.jpg", "pasta.jpg"},
+			{"terrace.jpg", "pasta.jpg", "other.jpg"},
+		} {
+			w = suite.makePUTRequest(orderURL, serializers.ReorderReviewPhotosRequest{URLs: urls})
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, urls)
+		}
+		w = suite.makePUTRequest(orderURL, serializers.ReorderReviewPhotosRequest{
+			URLs: []string{"terrace.jpg", "pasta.jpg", "tiramisu.jpg"},
+		})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var reordered models.VenueReview
+		suite.parseJSONResponse(w, &reordered)
+		assert.Equal(suite.T(), []string{"terrace.jpg", "pasta.jpg", "tiramisu.jpg"}, reordered.Photos.URLs())
+		assert.Equal(suite.T(), 2, reordered.Photos[2].Order)
+		suite.Require().Len(reordered.PhotoDetails, 3)
+		assert.Equal(suite.T(), "terrace.jpg", reordered.PhotoDetails[0].URL)
+
+		captionURL := fmt.Sprintf("/v1/reviews/test_user_1/%d/photos/caption", review.ID)
+		w = suite.makePUTRequest(captionURL, serializers.CaptionReviewPhotoRequest{URL: "tiramisu.jpg", Caption: "  The   tiramisu "})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		w = suite.makePUTRequest(captionURL, serializers.CaptionReviewPhotoRequest{URL: "missing.jpg", Caption: "Nothing"})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePUTRequest(captionURL, serializers.CaptionReviewPhotoRequest{
+			URL: "pasta.jpg", Caption: strings.Repeat("a", serializers.MaxPhotoCaptionLength+1),
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePUTRequest(captionURL, serializers.CaptionReviewPhotoRequest{URL: "pasta.jpg", Caption: "Shit pasta"})
+		suite.Require().Equal(http.StatusBadRequest, w.Code)
+		var rejected serializers.Base
+		suite.parseJSONResponse(w, &rejected)
+		assert.Equal(suite.T(), serializers.ContentRejected, rejected.Code)
+
+		// The order and captions are kept
+		stored := &models.VenueReview{ID: review.ID}
+		suite.Require().NoError(stored.GetByID(ctx))
+		assert.Equal(suite.T(), []string{"terrace.jpg", "pasta.jpg", "tiramisu.jpg"}, stored.Photos.URLs())
+		assert.Equal(suite.T(), "The tiramisu", stored.Photos[2].Caption)
+		assert.Empty(suite.T(), stored.Photos[1].Caption)
+	})
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubReviewSummarizer answers every venue with the same summary or error
+type stubReviewSummarizer struct {
+	summary string
+	err     error
+}
+
+func (s stubReviewSummarizer) Name() string {
+	return "stub"
+}
+
+func (s stubReviewSummarizer) Summarize(ctx context.Context, input services.ReviewSummaryInput) (string, error) {
+	return s.summary, s.err
+}
+
+// TestReviewSummaries tests summarizing venues' approved reviews, refreshed
+// once they change and shown on the venue
+func (suite *TestSuite) TestReviewSummaries() {
+	suite.Run("ReviewSummaries", func() {
+		previous := services.ReviewSummarizer
+		defer func() { services.ReviewSummarizer = previous }()
+		services.ReviewSummarizer = services.ExtractiveSummarizer{}
+		ctx := context.Background()
+		summaryService := &services.ReviewSummaryService{}
+
+		_, err := suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3'), (4, 'test_user_4') ON CONFLICT (id) DO NOTHING")
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, review_text, moderation_status, helpful_votes) VALUES
+			(1, 1, 5, 'The pasta was fresh and the service was friendly. Parking is hard to find.', 'approved', 5),
+			(1, 2, 4, 'Friendly service and fresh pasta every time! We waited ten minutes.', 'approved', 0),
+			(1, 3, 3, 'Great wine list. The pasta portions are generous and fresh.', 'approved', 0),
+			(1, 4, 2, 'Cold food.', 'pending', 0),
+			(2, 1, 4, 'Nice coffee and quiet tables.', 'approved', 0)`)
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		assert.Nil(suite.T(), detail.Venue.ReviewSummary)
+
+		// The rating is followed by the sentences most reviews agree with,
+		// from different reviews
+		suite.Require().NoError(summaryService.RefreshSummaries(ctx))
+		w = suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		detail = serializers.VenueDetailResponse{}
+		suite.parseJSONResponse(w, &detail)
+		suite.Require().NotNil(detail.Venue.ReviewSummary)
+		assert.Equal(suite.T(), `Rated 4.0 out of 5 across 3 reviews. "The pasta was fresh and the service was friendly." "The pasta portions are generous and fresh."`,
+			*detail.Venue.ReviewSummary)
+
+		summary, err := models.GetGeneratedReviewSummary(ctx, 1)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "extractive", summary.Provider)
+		assert.Equal(suite.T(), 3, summary.ReviewCount)
+
+		// Venues with too few reviews aren't summarized
+		_, err = models.GetGeneratedReviewSummary(ctx, 2)
+		assert.Equal(suite.T(), sql.ErrNoRows, err)
+
+		// Summaries are only regenerated once the reviews change
+		services.ReviewSummarizer = stubReviewSummarizer{summary: "Loved for its fresh pasta."}
+		suite.Require().NoError(summaryService.RefreshSummaries(ctx))
+		summary, err = models.GetGeneratedReviewSummary(ctx, 1)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "extractive", summary.Provider)
+
+		_, err = suite.db.Exec("UPDATE venue_reviews SET moderation_status = 'approved' WHERE venue_id = 1 AND user_id = 4")
+		suite.Require().NoError(err)
+		suite.Require().NoError(summaryService.RefreshSummaries(ctx))
+		summary, err = models.GetGeneratedReviewSummary(ctx, 1)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "Loved for its fresh pasta.", summary.Summary)
+		assert.Equal(suite.T(), "stub", summary.Provider)
+		assert.Equal(suite.T(), 4, summary.ReviewCount)
+
+		// Edits are picked up, and the extractive summarizer stands in when
+		// the backend fails
+		services.ReviewSummarizer = stubReviewSummarizer{err: errors.New("unavailable")}
+		_, err = suite.db.Exec("UPDATE venue_reviews SET review_text = 'Cold food, slow service.', updated_at = CURRENT_TIMESTAMP + INTERVAL '1 minute' WHERE venue_id = 1 AND user_id = 4")
+		suite.Require().NoError(err)
+		suite.Require().NoError(summaryService.RefreshSummaries(ctx))
+		summary, err = models.GetGeneratedReviewSummary(ctx, 1)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "extractive", summary.Provider)
+		assert.Contains(suite.T(), summary.Summary, "Rated 3.5 out of 5 across 4 reviews.")
+
+		// Summaries are removed once too few reviews are left
+		_, err = suite.db.Exec("UPDATE venue_reviews SET moderation_status = 'rejected' WHERE venue_id = 1 AND user_id IN (3, 4)")
+		suite.Require().NoError(err)
+		suite.Require().NoError(summaryService.RefreshSummaries(ctx))
+		_, err = models.GetGeneratedReviewSummary(ctx, 1)
+		assert.Equal(suite.T(), sql.ErrNoRows, err)
+	})
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTranslator upper cases texts, pretending they were English
+type fakeTranslator struct {
+	calls int
+	fail  bool
+}
+
+func (f *fakeTranslator) Name() string {
+	return "fake"
+}
+
+func (f *fakeTranslator) Translate(ctx context.Context, texts []string, targetLanguage string) ([]string, string, error) {
+	f.calls++
+	if f.fail {
+		return nil, "", errors.New("translation backend down")
+	}
+	translated := make([]string, len(texts))
+	for i, text := range texts {
+		translated[i] = strings.ToUpper(text)
+	}
+	return translated, "en", nil
+}
+
+// TestReviewTranslation tests translating review listings with translate_to
+func (suite *TestSuite) TestReviewTranslation() {
+	suite.Run("Review Translation", func() {
+		translator := &fakeTranslator{}
+		previous := services.Translator
+		services.Translator = translator
+		defer func() { services.Translator = previous }()
+
+		_, err := suite.db.Exec(`INSERT INTO venue_reviews
+			(id, venue_id, user_id, overall_rating, title, review_text, moderation_status)
+			VALUES (901, 1, 1, 4.5, 'Great', 'Lovely pasta', 'approved')`)
+		suite.Require().NoError(err)
+
+		reviews := suite.getTranslatedReviews("/v1/venues/1/reviews?translate_to=FR")
+		suite.Require().Len(reviews, 1)
+		suite.Require().NotNil(reviews[0].Translation)
+		assert.Equal(suite.T(), "fr", reviews[0].Translation.Language)
+		assert.Equal(suite.T(), "en", reviews[0].Translation.SourceLanguage)
+		assert.Equal(suite.T(), "LOVELY PASTA", reviews[0].Translation.ReviewText)
+		assert.Equal(suite.T(), "Lovely pasta", reviews[0].ReviewText)
+		assert.Equal(suite.T(), 1, translator.calls)
+
+		// Served from the cache
+		reviews = suite.getTranslatedReviews("/v1/venues/1/reviews?translate_to=fr")
+		suite.Require().NotNil(reviews[0].Translation)
+		assert.Equal(suite.T(), 1, translator.calls)
+
+		// Editing the review invalidates its translation
+		_, err = suite.db.Exec(`UPDATE venue_reviews SET review_text = 'Lovely pizza',
+			updated_at = CURRENT_TIMESTAMP + INTERVAL '1 second' WHERE id = 901`)
+		suite.Require().NoError(err)
+		reviews = suite.getTranslatedReviews("/v1/venues/1/reviews?translate_to=fr")
+		suite.Require().NotNil(reviews[0].Translation)
+		assert.Equal(suite.T(), "LOVELY PIZZA", reviews[0].Translation.ReviewText)
+		assert.Equal(suite.T(), 2, translator.calls)
+
+		// Reviews already in the language aren't marked translated
+		reviews = suite.getTranslatedReviews("/v1/venues/1/reviews?translate_to=en")
+		assert.Nil(suite.T(), reviews[0].Translation)
+
+		// Without translate_to nothing is translated
+		reviews = suite.getTranslatedReviews("/v1/venues/1/reviews")
+		assert.Nil(suite.T(), reviews[0].Translation)
+		assert.Equal(suite.T(), 3, translator.calls)
+
+		// Backend failures leave the reviews untranslated
+		translator.fail = true
+		reviews = suite.getTranslatedReviews("/v1/venues/1/reviews?translate_to=de")
+		assert.Nil(suite.T(), reviews[0].Translation)
+
+		w := suite.makeGETRequest("/v1/venues/1/reviews?translate_to=french")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
+
+func (suite *TestSuite) getTranslatedReviews(url string) []models.VenueReview {
+	w := suite.makeGETRequest(url)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	var response serializers.ReviewSearchResponse
+	suite.parseJSONResponse(w, &response)
+	return response.Reviews
+}
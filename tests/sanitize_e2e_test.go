@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInputSanitization tests that user text is stored without markup or
+// invisible characters
+func (suite *TestSuite) TestInputSanitization() {
+	suite.Run("InputSanitization", func() {
+		lines := map[string]string{
+			"<b>Great</b>   pasta":                    "Great pasta",
+			"Fish &amp; chips":                        "Fish & chips",
+			"&lt;script&gt;alert(1)&lt;/script&gt;ok": "ok",
+			"<scr<script>ipt>alert(1)</script>":       "ipt>alert(1)",
+			"1 < 2 and 3 > 2":                         "1 < 2 and 3 > 2",
+			"<img src=x onerror=alert(1)":             "",
+			"Cafe\u0301 \u202eevil\u200b":             "Caf\u00e9 evil",
+			"<!-- hidden -->Shown\x00":                "Shown",
+		}
+		for input, expected := range lines {
+			assert.Equal(suite.T(), expected, serializers.SanitizeLine(input), input)
+		}
+		assert.Equal(suite.T(), "First line\n\nSecond paragraph",
+			serializers.SanitizeText("  First   line\r\n\r\n\r\n<p>Second paragraph</p>\n\n"))
+
+		// Reviews, check-ins and collections are stored sanitized
+		w := suite.makePOSTRequest("/v1/reviews/test_user_1", serializers.CreateReviewRequest{
+			VenueID:       1,
+			OverallRating: 4,
+			Title:         "<script>alert('x')</script>Lovely <i>brunch</i>",
+			ReviewText:    "Try the <a href=\"javascript:alert(1)\">eggs</a>\u200b!",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var review models.VenueReview
+		suite.parseJSONResponse(w, &review)
+		assert.Equal(suite.T(), "Lovely brunch", review.Title)
+		assert.Equal(suite.T(), "Try the eggs!", review.ReviewText)
+
+		w = suite.makePOSTRequest("/v1/reviews/test_user_2", serializers.CreateReviewRequest{
+			VenueID:       1,
+			OverallRating: 4,
+			Title:         strings.Repeat("é", 256),
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{
+			VenueID: 1,
+			Message: "<style>body{display:none}</style>Busy   tonight",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var checkin models.VenueCheckin
+		suite.parseJSONResponse(w, &checkin)
+		assert.Equal(suite.T(), "Busy tonight", checkin.Message)
+
+		w = suite.makePOSTRequest("/v1/collections/test_user_1", map[string]interface{}{"name": "<b></b>"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/collections/test_user_1", map[string]interface{}{"name": "<h1>Date</h1> night"})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var collection models.VenueCollection
+		suite.parseJSONResponse(w, &collection)
+		assert.Equal(suite.T(), "Date night", collection.Name)
+	})
+}
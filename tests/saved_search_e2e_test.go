@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSavedSearches tests saved searches and new match alerts
+func (suite *TestSuite) TestSavedSearches() {
+	suite.Run("Saved Searches End-to-End", func() {
+		suite.testSavedSearchValidation()
+		suite.testSavedSearchAlerts()
+	})
+}
+
+func (suite *TestSuite) testSavedSearchValidation() {
+	// A search without query or filters would match every venue
+	w := suite.makePOSTRequest("/v1/users/test_user_1/saved-searches", map[string]interface{}{
+		"name": "Everything",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/users/test_user_1/saved-searches", map[string]interface{}{
+		"name":     "Half a location",
+		"latitude": 37.77,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/users/test_user_1/saved-searches", map[string]interface{}{
+		"name":      "Too picky",
+		"query":     "pizza",
+		"minRating": 6,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testSavedSearchAlerts() {
+	w := suite.makePOSTRequest("/v1/users/test_user_1/saved-searches", map[string]interface{}{
+		"name":   "Ramen in SF",
+		"query":  "ramen",
+		"cityId": 1,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var search models.SavedSearch
+	suite.parseJSONResponse(w, &search)
+	assert.True(suite.T(), search.AlertsEnabled)
+	assert.Equal(suite.T(), "ramen", search.Params.Query)
+
+	savedSearchService := &services.SavedSearchService{}
+	suite.Require().NoError(savedSearchService.EvaluateSavedSearches(context.Background()))
+
+	// Venues that existed before the search was saved are not new matches
+	w = suite.makeGETRequest("/v1/users/test_user_1/saved-searches")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var list serializers.SavedSearchListResponse
+	suite.parseJSONResponse(w, &list)
+	suite.Require().Len(list.SavedSearches, 1)
+	assert.Equal(suite.T(), 0, list.TotalNewMatches)
+
+	// A new matching venue and a new venue that doesn't match the query
+	_, err := suite.db.Exec(`INSERT INTO venues (id, name, slug, description, address, city_id, latitude, longitude,
+		category_id, price_range, is_active)
+		VALUES (3, 'Ramen House', 'ramen-house', 'Tonkotsu ramen', '789 Test Blvd, San Francisco, CA',
+		1, 37.7800, -122.4100, 1, '$$', true)`)
+	suite.Require().NoError(err)
+	_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, description, address, city_id, latitude, longitude,
+		category_id, price_range, is_active)
+		VALUES (4, 'Sushi Bar', 'sushi-bar', 'Nigiri', '2 Test Ln, San Francisco, CA',
+		1, 37.7810, -122.4110, 1, '$', true)`)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(savedSearchService.EvaluateSavedSearches(context.Background()))
+	// Evaluating again doesn't count the same venue twice
+	suite.Require().NoError(savedSearchService.EvaluateSavedSearches(context.Background()))
+
+	w = suite.makeGETRequest("/v1/users/test_user_1/saved-searches")
+	suite.parseJSONResponse(w, &list)
+	suite.Require().Len(list.SavedSearches, 1)
+	assert.Equal(suite.T(), 1, list.SavedSearches[0].NewMatchesCount)
+	assert.Equal(suite.T(), 1, list.TotalNewMatches)
+	assert.NotNil(suite.T(), list.SavedSearches[0].LastNotifiedAt)
+
+	notifications, err := models.GetUserNotifications(context.Background(), 1, 10)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(notifications)
+	assert.Equal(suite.T(), models.NotificationSavedSearch, notifications[0].EventType)
+	assert.Contains(suite.T(), notifications[0].Body, "Ramen House")
+
+	// Viewing the matches marks them as read
+	matchesURL := fmt.Sprintf("/v1/users/test_user_1/saved-searches/%d/matches", search.ID)
+	w = suite.makeGETRequest(matchesURL)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var matches serializers.SavedSearchMatchesResponse
+	suite.parseJSONResponse(w, &matches)
+	suite.Require().Len(matches.Matches, 1)
+	assert.Equal(suite.T(), int64(3), matches.Matches[0].VenueID)
+	assert.False(suite.T(), matches.Matches[0].IsRead)
+	assert.Equal(suite.T(), 0, matches.SavedSearch.NewMatchesCount)
+
+	w = suite.makeGETRequest("/v1/users/test_user_1/saved-searches")
+	suite.parseJSONResponse(w, &list)
+	assert.Equal(suite.T(), 0, list.TotalNewMatches)
+
+	// Unknown or foreign saved searches are not found
+	w = suite.makeGETRequest("/v1/users/test_user_1/saved-searches/999999/matches")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makeDELETERequest(fmt.Sprintf("/v1/users/test_user_1/saved-searches/%d", search.ID))
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.makeGETRequest(matchesURL)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
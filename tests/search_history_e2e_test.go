@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSearchHistory tests users seeing and clearing their searches, and
+// turning search logging off
+func (suite *TestSuite) TestSearchHistory() {
+	suite.Run("Search History", func() {
+		analyticsService := &services.AnalyticsService{}
+		results := []models.Venue{suite.testData.TestVenue1}
+		filters := map[string]interface{}{"city_id": int64(1)}
+		suite.Require().NoError(analyticsService.TrackSearch(context.Background(), 1, "pizza", filters, results, nil, nil))
+		suite.Require().NoError(analyticsService.TrackSearch(context.Background(), 1, "sushi", nil, nil, nil, nil))
+		suite.Require().NoError(analyticsService.TrackSearch(context.Background(), 2, "tacos", nil, nil, nil, nil))
+
+		// Users only see their own searches, newest first
+		w := suite.makeGETRequest("/v1/users/test_user_1/search-history?limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var history serializers.SearchHistoryResponse
+		suite.parseJSONResponse(w, &history)
+		assert.True(suite.T(), history.SearchHistoryEnabled)
+		assert.Equal(suite.T(), 2, history.Pagination.Total)
+		assert.True(suite.T(), history.Pagination.HasNext)
+		suite.Require().Len(history.History, 1)
+		assert.Equal(suite.T(), "sushi", history.History[0].Query)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/search-history?page=2&limit=1")
+		history = serializers.SearchHistoryResponse{}
+		suite.parseJSONResponse(w, &history)
+		suite.Require().Len(history.History, 1)
+		assert.Equal(suite.T(), "pizza", history.History[0].Query)
+		assert.Equal(suite.T(), 1, history.History[0].ResultsCount)
+		assert.NotEmpty(suite.T(), history.History[0].Filters)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/search-history?limit=500")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Turning logging off stops recording searches
+		w = suite.makePUTRequest("/v1/users/test_user_1/privacy", map[string]interface{}{"searchHistoryEnabled": false})
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var settings models.PrivacySettings
+		suite.parseJSONResponse(w, &settings)
+		assert.False(suite.T(), settings.SearchHistoryEnabled)
+
+		w = suite.makePUTRequest("/v1/users/test_user_1/privacy", map[string]interface{}{})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		suite.Require().NoError(analyticsService.TrackSearch(context.Background(), 1, "ramen", nil, nil, nil, nil))
+		var count int
+		suite.Require().NoError(suite.db.QueryRow(
+			"SELECT COUNT(*) FROM search_analytics WHERE search_query = 'ramen'").Scan(&count))
+		assert.Equal(suite.T(), 0, count)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/privacy")
+		settings = models.PrivacySettings{}
+		suite.parseJSONResponse(w, &settings)
+		assert.False(suite.T(), settings.SearchHistoryEnabled)
+
+		// Clearing only removes the user's own searches
+		w = suite.makeDELETERequest("/v1/users/test_user_1/search-history")
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/search-history")
+		history = serializers.SearchHistoryResponse{}
+		suite.parseJSONResponse(w, &history)
+		assert.Empty(suite.T(), history.History)
+		assert.False(suite.T(), history.SearchHistoryEnabled)
+
+		suite.Require().NoError(suite.db.QueryRow(
+			"SELECT COUNT(*) FROM search_analytics WHERE user_id = 2").Scan(&count))
+		assert.Equal(suite.T(), 1, count)
+	})
+}
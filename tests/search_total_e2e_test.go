@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSearchEstimatedTotal tests estimating the total of broad venue
+// searches from the planner's statistics
+func (suite *TestSuite) TestSearchEstimatedTotal() {
+	suite.Run("SearchEstimatedTotal", func() {
+		_, err := suite.db.Exec(`INSERT INTO venues
+			(id, name, slug, address, city_id, latitude, longitude, category_id, average_rating, is_active)
+			SELECT n, 'Bulk Venue ' || n, 'bulk-venue-' || n, n || ' Bulk St', 1, 37.77, -122.41, 1, 3.0, true
+			FROM generate_series(1000, 2499) AS n`)
+		suite.Require().NoError(err)
+		defer func() {
+			suite.db.Exec("DELETE FROM venues WHERE id BETWEEN 1000 AND 2499")
+			suite.db.Exec("ANALYZE venues")
+		}()
+		_, err = suite.db.Exec("ANALYZE venues")
+		suite.Require().NoError(err)
+
+		// Totals are exact unless asked otherwise
+		w := suite.makeGETRequest("/v1/venues/search?limit=10")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var response serializers.VenueSearchResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 1502, response.Pagination.Total)
+		suite.Require().NotNil(response.Pagination.ExactTotal)
+		assert.True(suite.T(), *response.Pagination.ExactTotal)
+
+		// Broad searches are estimated
+		w = suite.makeGETRequest("/v1/venues/search?limit=10&estimate_total=true")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		response = serializers.VenueSearchResponse{}
+		suite.parseJSONResponse(w, &response)
+		suite.Require().NotNil(response.Pagination.ExactTotal)
+		assert.False(suite.T(), *response.Pagination.ExactTotal)
+		assert.GreaterOrEqual(suite.T(), response.Pagination.Total, models.EstimatedTotalThreshold)
+		assert.InDelta(suite.T(), 1502, response.Pagination.Total, 300)
+		assert.True(suite.T(), response.Pagination.HasNext)
+		assert.Len(suite.T(), response.Venues, 10)
+
+		// Narrow ones are still counted exactly
+		w = suite.makeGETRequest("/v1/venues/search?min_rating=4.4&estimate_total=true")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		response = serializers.VenueSearchResponse{}
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 1, response.Pagination.Total)
+		suite.Require().NotNil(response.Pagination.ExactTotal)
+		assert.True(suite.T(), *response.Pagination.ExactTotal)
+		assert.False(suite.T(), response.Pagination.HasNext)
+	})
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"voting-app/app/config"
+	"voting-app/app/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSecurityHeaders tests the security headers and the CORS answers to
+// allowed and other origins
+func (suite *TestSuite) TestSecurityHeaders() {
+	suite.Run("Security Headers", func() {
+		newRouter := func(cors config.CORSConfig) *gin.Engine {
+			router := gin.New()
+			router.Use(middlewares.SecurityHeaders(config.SecurityConfig{HSTSMaxAge: 24 * time.Hour, FrameOptions: "DENY"}))
+			router.Use(middlewares.CORS(cors))
+			router.GET("/v1/venues", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			return router
+		}
+		request := func(router *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/v1/venues", nil)
+			if origin != "" {
+				req.Header.Set("Origin", origin)
+			}
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+			router.ServeHTTP(w, req)
+			return w
+		}
+		preflight := map[string]string{"Access-Control-Request-Method": "POST"}
+
+		router := newRouter(config.CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		})
+
+		w := request(router, "GET", "", nil)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "max-age=86400; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+		assert.Equal(suite.T(), "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(suite.T(), "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Empty(suite.T(), w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = request(router, "GET", "https://app.example.com", nil)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		assert.Equal(suite.T(), "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(suite.T(), "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(suite.T(), w.Header().Get("Access-Control-Expose-Headers"), middlewares.APIVersionHeader)
+		assert.Contains(suite.T(), w.Header().Values("Vary"), "Origin")
+
+		// Preflight requests are answered without reaching the routes
+		w = request(router, "OPTIONS", "https://app.example.com", preflight)
+		assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+		assert.Equal(suite.T(), "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(suite.T(), "Authorization, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(suite.T(), "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(suite.T(), "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+		// Other origins get no CORS headers
+		w = request(router, "GET", "https://evil.example.com", nil)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		assert.Empty(suite.T(), w.Header().Get("Access-Control-Allow-Origin"))
+		w = request(router, "OPTIONS", "https://evil.example.com", preflight)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// Any origin is allowed with "*", without credentials
+		router = newRouter(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+		w = request(router, "GET", "https://other.example.com", nil)
+		assert.Equal(suite.T(), "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(suite.T(), w.Header().Get("Access-Control-Allow-Credentials"))
+
+		// Without origins CORS is off
+		router = newRouter(config.CORSConfig{})
+		w = request(router, "OPTIONS", "https://app.example.com", preflight)
+		assert.NotEqual(suite.T(), http.StatusNoContent, w.Code)
+		assert.Empty(suite.T(), w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
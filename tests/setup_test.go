@@ -9,9 +9,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 	databases "voting-app/app"
 	"voting-app/app/controllers"
+	"voting-app/app/middlewares"
 	"voting-app/app/models"
+	"voting-app/app/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -143,6 +146,11 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			is_active BOOLEAN DEFAULT true,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS rating_templates (
+			category_id BIGINT PRIMARY KEY REFERENCES venue_categories(id) ON DELETE CASCADE,
+			dimensions JSONB NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 
 		// Venue subcategories
 		`CREATE TABLE IF NOT EXISTS venue_subcategories (
@@ -160,6 +168,44 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			snapp_id VARCHAR NOT NULL UNIQUE
 		)`,
 
+		// Neighborhoods
+		`CREATE TABLE IF NOT EXISTS neighborhoods (
+			id BIGSERIAL PRIMARY KEY,
+			city_id BIGINT REFERENCES cities(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			slug VARCHAR(255) NOT NULL,
+			boundary geometry(Polygon, 4326) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(city_id, slug)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_neighborhoods_boundary ON neighborhoods USING GIST (boundary)`,
+
+		// Districts of the cities, placed by their centroid
+		`CREATE TABLE IF NOT EXISTS districts (
+			id BIGSERIAL PRIMARY KEY,
+			city_id BIGINT NOT NULL REFERENCES cities(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			slug VARCHAR(100) NOT NULL,
+			latitude DECIMAL(10, 8) NOT NULL,
+			longitude DECIMAL(11, 8) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(city_id, slug)
+		)`,
+
+		// Tenants, the city brands venues, campaigns and users belong to
+		`CREATE TABLE IF NOT EXISTS tenants (
+			id BIGSERIAL PRIMARY KEY,
+			slug VARCHAR(50) NOT NULL UNIQUE,
+			name VARCHAR(100) NOT NULL,
+			hostnames TEXT[] NOT NULL DEFAULT '{}',
+			branding JSONB NOT NULL DEFAULT '{}',
+			default_city_id BIGINT REFERENCES cities(id) ON DELETE SET NULL,
+			is_active BOOLEAN DEFAULT true,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default') ON CONFLICT (id) DO NOTHING`,
+		`SELECT setval('tenants_id_seq', GREATEST((SELECT MAX(id) FROM tenants), 1))`,
+
 		// Enhanced venues table
 		`CREATE TABLE IF NOT EXISTS venues (
 			id BIGSERIAL PRIMARY KEY,
@@ -185,15 +231,30 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			average_rating DECIMAL(3,2) DEFAULT 0.00,
 			total_ratings INTEGER DEFAULT 0,
 			total_reviews INTEGER DEFAULT 0,
+			total_favorites INTEGER NOT NULL DEFAULT 0,
 			amenities JSONB,
 			is_active BOOLEAN DEFAULT true,
 			is_verified BOOLEAN DEFAULT false,
 			is_featured BOOLEAN DEFAULT false,
 			owner_id BIGINT,
 			claimed_at TIMESTAMP,
+			geohash VARCHAR(12) GENERATED ALWAYS AS (ST_GeoHash(ST_Point(longitude, latitude), 12)) STORED,
+			neighborhood_id BIGINT REFERENCES neighborhoods(id) ON DELETE SET NULL,
+			tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id),
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE INDEX IF NOT EXISTS idx_venues_geohash ON venues(geohash text_pattern_ops)`,
+		`CREATE TABLE IF NOT EXISTS venue_similar (
+			venue_id BIGINT PRIMARY KEY REFERENCES venues(id) ON DELETE CASCADE,
+			similar_venue_ids BIGINT[] NOT NULL,
+			computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS venue_slug_history (
+			slug VARCHAR(255) PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 
 		// Venue reviews
 		`CREATE TABLE IF NOT EXISTS venue_reviews (
@@ -211,7 +272,12 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			is_verified BOOLEAN DEFAULT false,
 			is_featured BOOLEAN DEFAULT false,
 			is_flagged BOOLEAN DEFAULT false,
+			flagged_at TIMESTAMP,
 			moderation_status VARCHAR(20) DEFAULT 'pending',
+			moderated_by BIGINT,
+			moderated_at TIMESTAMP,
+			duplicate_of BIGINT REFERENCES venue_reviews(id) ON DELETE SET NULL,
+			duplicate_similarity REAL,
 			helpful_votes INTEGER DEFAULT 0,
 			unhelpful_votes INTEGER DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -219,6 +285,56 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			UNIQUE(venue_id, user_id)
 		)`,
 
+		// MinHash signatures of review texts
+		`CREATE TABLE IF NOT EXISTS review_signatures (
+			review_id BIGINT PRIMARY KEY REFERENCES venue_reviews(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL,
+			signature BIGINT[] NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_review_signatures_signature ON review_signatures USING GIN (signature)`,
+
+		// Review drafts
+		`CREATE TABLE IF NOT EXISTS review_drafts (
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			overall_rating DECIMAL(3,2),
+			detailed_ratings JSONB,
+			title VARCHAR(255),
+			review_text TEXT,
+			visit_date DATE,
+			visit_type VARCHAR(50),
+			party_size INTEGER,
+			photos JSONB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, venue_id)
+		)`,
+
+		// Photos
+		`CREATE TABLE IF NOT EXISTS photos (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL,
+			url VARCHAR(500) NOT NULL UNIQUE,
+			object_key VARCHAR(255) NOT NULL,
+			content_type VARCHAR(50) NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			dominant_color VARCHAR(7) NOT NULL DEFAULT '',
+			size_bytes BIGINT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Venue city corrections
+		`CREATE TABLE IF NOT EXISTS venue_city_corrections (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			claimed_city_id BIGINT REFERENCES cities(id),
+			assigned_city_id BIGINT REFERENCES cities(id),
+			distance_km DECIMAL(8,2),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
 		// Venue collections
 		`CREATE TABLE IF NOT EXISTS venue_collections (
 			id BIGSERIAL PRIMARY KEY,
@@ -237,10 +353,22 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			collection_id BIGINT REFERENCES venue_collections(id),
 			venue_id BIGINT REFERENCES venues(id),
 			note TEXT,
+			added_by BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL,
 			added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(collection_id, venue_id)
 		)`,
 
+		// Collection collaborators
+		`CREATE TABLE IF NOT EXISTS collection_collaborators (
+			collection_id BIGINT NOT NULL REFERENCES venue_collections(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			invited_by BIGINT REFERENCES snapp_users(id) ON DELETE SET NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			invited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			accepted_at TIMESTAMP,
+			PRIMARY KEY (collection_id, user_id)
+		)`,
+
 		// Venue checkins
 		`CREATE TABLE IF NOT EXISTS venue_checkins (
 			id BIGSERIAL PRIMARY KEY,
@@ -250,9 +378,19 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			photos JSONB,
 			rating DECIMAL(3,2) CHECK (rating >= 1.0 AND rating <= 5.0),
 			is_public BOOLEAN DEFAULT true,
+			is_flagged BOOLEAN DEFAULT false,
 			tagged_users JSONB,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS venue_wait_reports (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id),
+			checkin_id BIGINT REFERENCES venue_checkins(id) ON DELETE SET NULL,
+			wait_minutes INTEGER,
+			crowd_level VARCHAR(20),
+			reported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 
 		// Voting campaigns
 		`CREATE TABLE IF NOT EXISTS voting_campaigns (
@@ -267,24 +405,301 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			max_votes_per_user INTEGER DEFAULT 1,
 			allow_multiple_categories BOOLEAN DEFAULT false,
 			require_review BOOLEAN DEFAULT false,
+			voting_mode VARCHAR(20) NOT NULL DEFAULT 'standard',
+			credit_budget INTEGER,
 			is_active BOOLEAN DEFAULT true,
 			is_featured BOOLEAN DEFAULT false,
 			winner_venue_id BIGINT REFERENCES venues(id),
 			total_votes INTEGER DEFAULT 0,
+			results_finalized_at TIMESTAMP,
+			hide_results_until_end BOOLEAN NOT NULL DEFAULT false,
+			require_otp BOOLEAN NOT NULL DEFAULT false,
+			allow_kiosk_sessions BOOLEAN NOT NULL DEFAULT false,
+			vote_change_minutes INTEGER,
+			start_notified_at TIMESTAMP,
+			tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id),
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		// Campaign categories
+		`CREATE TABLE IF NOT EXISTS campaign_categories (
+			id BIGSERIAL PRIMARY KEY,
+			campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			slug VARCHAR(255) NOT NULL,
+			description TEXT,
+			venue_category_id BIGINT REFERENCES venue_categories(id),
+			sort_order INTEGER DEFAULT 0,
+			winner_venue_id BIGINT REFERENCES venues(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(campaign_id, slug)
+		)`,
+
+		// Kiosk voting sessions
+		`CREATE TABLE IF NOT EXISTS kiosk_devices (
+			id BIGSERIAL PRIMARY KEY,
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			created_by BIGINT,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS voting_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			kiosk_id BIGINT NOT NULL REFERENCES kiosk_devices(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Campaign nominees
+		`CREATE TABLE IF NOT EXISTS campaign_nominees (
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			score DECIMAL(6,3),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (campaign_id, venue_id)
+		)`,
+
 		// Campaign votes
 		`CREATE TABLE IF NOT EXISTS campaign_votes (
 			id BIGSERIAL PRIMARY KEY,
 			campaign_id BIGINT REFERENCES voting_campaigns(id),
+			campaign_category_id BIGINT REFERENCES campaign_categories(id) ON DELETE CASCADE,
 			venue_id BIGINT REFERENCES venues(id),
 			user_id BIGINT REFERENCES snapp_users(id),
+			voting_session_id BIGINT REFERENCES voting_sessions(id) ON DELETE SET NULL,
 			reason TEXT,
 			confidence_score DECIMAL(3,2),
+			vote_count INTEGER NOT NULL DEFAULT 1,
+			credits_spent INTEGER NOT NULL DEFAULT 0,
+			is_flagged BOOLEAN DEFAULT false,
+			flagged_at TIMESTAMP,
+			moderated_by BIGINT,
+			moderated_at TIMESTAMP,
+			reason_status VARCHAR(20),
+			is_highlight BOOLEAN NOT NULL DEFAULT false,
+			excluded_reason VARCHAR(30),
+			excluded_by BIGINT,
+			excluded_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_votes_unique
+			ON campaign_votes(campaign_id, user_id, COALESCE(campaign_category_id, 0), venue_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_votes_session_unique
+			ON campaign_votes(campaign_id, voting_session_id, COALESCE(campaign_category_id, 0), venue_id)
+			WHERE voting_session_id IS NOT NULL`,
+
+		// Changes of campaign votes to other venues
+		`CREATE TABLE IF NOT EXISTS vote_changes (
+			id BIGSERIAL PRIMARY KEY,
+			vote_id BIGINT NOT NULL REFERENCES campaign_votes(id) ON DELETE CASCADE,
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			previous_venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Deleted entities syncing clients are told to drop
+		`CREATE TABLE IF NOT EXISTS sync_tombstones (
+			id BIGSERIAL PRIMARY KEY,
+			entity_type VARCHAR(20) NOT NULL,
+			entity_id BIGINT NOT NULL,
+			city_id BIGINT,
+			tenant_id BIGINT,
+			deleted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Venue claims
+		`CREATE TABLE IF NOT EXISTS venue_claims (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			user_id BIGINT,
+			status VARCHAR(20) DEFAULT 'pending',
+			evidence TEXT,
+			reviewed_by BIGINT,
+			reviewed_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Webhooks
+		`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			target_url TEXT NOT NULL,
+			event_types TEXT[] NOT NULL,
+			secret VARCHAR(100) NOT NULL,
+			is_active BOOLEAN DEFAULT true,
+			disabled_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			subscription_id BIGINT REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+			event_type VARCHAR(50) NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			attempts INTEGER DEFAULT 0,
+			next_attempt_at TIMESTAMP DEFAULT LOCALTIMESTAMP,
+			last_status_code INTEGER,
+			last_error TEXT,
+			delivered_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Campaign credit balances
+		`CREATE TABLE IF NOT EXISTS campaign_credit_balances (
+			campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			credits_spent INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (campaign_id, user_id)
+		)`,
+
+		// Campaign promotions
+		`CREATE TABLE IF NOT EXISTS campaign_promotions (
+			id BIGSERIAL PRIMARY KEY,
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 0,
+			banner_image VARCHAR,
+			banner_link VARCHAR,
+			created_by BIGINT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (ends_at > starts_at)
+		)`,
+
+		// Campaign result snapshots
+		`CREATE TABLE IF NOT EXISTS campaign_result_snapshots (
+			id BIGSERIAL PRIMARY KEY,
+			campaign_id BIGINT REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			is_final BOOLEAN DEFAULT false,
+			total_votes INTEGER DEFAULT 0,
+			results JSONB NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Certified campaign results
+		`CREATE TABLE IF NOT EXISTS campaign_audits (
+			campaign_id BIGINT PRIMARY KEY REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			certificate TEXT NOT NULL,
+			signature_algorithm VARCHAR(20) NOT NULL,
+			signature VARCHAR(128) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Slow search and analytics statements
+		`CREATE TABLE IF NOT EXISTS slow_queries (
+			id BIGSERIAL PRIMARY KEY,
+			source VARCHAR(255) NOT NULL,
+			statement TEXT NOT NULL,
+			params TEXT[] NOT NULL DEFAULT '{}',
+			duration_ms DOUBLE PRECISION NOT NULL,
+			error TEXT,
+			plan JSONB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Owner tiers and venue galleries
+		`CREATE TABLE IF NOT EXISTS owner_subscriptions (
+			user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			tier VARCHAR(20) NOT NULL CHECK (tier IN ('free', 'premium')),
+			expires_at TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS owner_alert_state (
+			user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			reviews_until TIMESTAMP,
+			last_sent_at TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS venue_milestones (
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			milestone VARCHAR(30) NOT NULL,
+			reached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			notified_at TIMESTAMP,
+			PRIMARY KEY (venue_id, milestone)
+		)`,
+		`CREATE TABLE IF NOT EXISTS venue_photos (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			url VARCHAR(2048) NOT NULL,
+			caption VARCHAR(200),
+			added_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Competitor watchlists
+		`CREATE TABLE IF NOT EXISTS venue_watchlist (
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			competitor_venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(campaign_id, user_id, venue_id)
+			PRIMARY KEY (venue_id, competitor_venue_id),
+			CHECK (venue_id <> competitor_venue_id)
+		)`,
+
+		// Venue favorites
+		`CREATE TABLE IF NOT EXISTS venue_favorites (
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, venue_id)
+		)`,
+
+		// Venue hours exceptions
+		`CREATE TABLE IF NOT EXISTS venue_hours_exceptions (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			date DATE NOT NULL,
+			is_closed BOOLEAN NOT NULL DEFAULT false,
+			open_time VARCHAR(5),
+			close_time VARCHAR(5),
+			reason VARCHAR(255),
+			created_by BIGINT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(venue_id, date)
+		)`,
+
+		// Venue deals
+		`CREATE TABLE IF NOT EXISTS venue_deals (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			title VARCHAR(100) NOT NULL,
+			description TEXT,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			redemption_limit INTEGER,
+			redemption_count INTEGER NOT NULL DEFAULT 0,
+			created_by BIGINT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS deal_redemptions (
+			id BIGSERIAL PRIMARY KEY,
+			deal_id BIGINT NOT NULL REFERENCES venue_deals(id) ON DELETE CASCADE,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id),
+			checkin_id BIGINT REFERENCES venue_checkins(id) ON DELETE SET NULL,
+			redeemed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(deal_id, user_id)
+		)`,
+
+		// External ratings
+		`CREATE TABLE IF NOT EXISTS external_ratings (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			source VARCHAR(30) NOT NULL,
+			rating DECIMAL(3,2) NOT NULL,
+			review_count INTEGER NOT NULL DEFAULT 0,
+			url VARCHAR(500),
+			imported_by BIGINT,
+			imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(venue_id, source)
 		)`,
 
 		// Venue analytics
@@ -319,6 +734,360 @@ func (suite *TestSuite) runEnhancedMigrations() {
 			click_position INTEGER,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
+
+		`CREATE TABLE IF NOT EXISTS user_privacy_settings (
+			user_id BIGINT PRIMARY KEY REFERENCES snapp_users(id) ON DELETE CASCADE,
+			search_history_enabled BOOLEAN NOT NULL DEFAULT true,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// User consents
+		`CREATE TABLE IF NOT EXISTS user_consents (
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			purpose VARCHAR(50) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			source VARCHAR(20),
+			code_hash VARCHAR(64),
+			code_expires_at TIMESTAMP,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			requested_at TIMESTAMP,
+			granted_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, purpose)
+		)`,
+
+		// Moderation audit log
+		`CREATE TABLE IF NOT EXISTS moderation_audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			moderator_id BIGINT NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			target_type VARCHAR(30) NOT NULL,
+			target_id BIGINT NOT NULL,
+			from_state VARCHAR(40) NOT NULL,
+			to_state VARCHAR(40) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Notifications
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES snapp_users(id),
+			event_type VARCHAR(50) NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			body TEXT,
+			data JSONB,
+			is_read BOOLEAN DEFAULT false,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Push notification devices
+		`CREATE TABLE IF NOT EXISTS user_devices (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES snapp_users(id),
+			platform VARCHAR(20) NOT NULL,
+			token VARCHAR(512) NOT NULL UNIQUE,
+			app_version VARCHAR(50),
+			is_active BOOLEAN DEFAULT true,
+			last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Venue menus
+		`CREATE TABLE IF NOT EXISTS venue_menus (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			description TEXT,
+			currency VARCHAR(3) DEFAULT 'USD',
+			is_active BOOLEAN DEFAULT true,
+			sort_order INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS menu_sections (
+			id BIGSERIAL PRIMARY KEY,
+			menu_id BIGINT REFERENCES venue_menus(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			description TEXT,
+			sort_order INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS menu_items (
+			id BIGSERIAL PRIMARY KEY,
+			section_id BIGINT REFERENCES menu_sections(id) ON DELETE CASCADE,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			description TEXT,
+			price DECIMAL(10,2),
+			dietary_tags JSONB DEFAULT '[]',
+			is_available BOOLEAN DEFAULT true,
+			sort_order INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Platform stats rollups
+		`CREATE TABLE IF NOT EXISTS platform_stats_rollups (
+			period VARCHAR(10) NOT NULL,
+			period_start TIMESTAMP NOT NULL,
+			new_venues INTEGER DEFAULT 0,
+			new_users INTEGER DEFAULT 0,
+			new_reviews INTEGER DEFAULT 0,
+			new_checkins INTEGER DEFAULT 0,
+			rating_sum DECIMAL(14,2) DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (period, period_start)
+		)`,
+		`CREATE TABLE IF NOT EXISTS platform_stats_watermarks (
+			source_table VARCHAR(50) PRIMARY KEY,
+			last_id BIGINT NOT NULL DEFAULT 0,
+			pending_id BIGINT NOT NULL DEFAULT 0,
+			pending_at TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS metric_alerts (
+			id BIGSERIAL PRIMARY KEY,
+			metric VARCHAR(20) NOT NULL,
+			day DATE NOT NULL,
+			value DECIMAL(14,2) NOT NULL,
+			mean DECIMAL(14,2) NOT NULL,
+			std_dev DECIMAL(14,2) NOT NULL,
+			z_score DECIMAL(8,2) NOT NULL,
+			direction VARCHAR(10) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (metric, day)
+		)`,
+		`CREATE TABLE IF NOT EXISTS client_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			client_id VARCHAR(64) NOT NULL,
+			started_at TIMESTAMP NOT NULL,
+			ended_at TIMESTAMP NOT NULL,
+			page_views INTEGER NOT NULL DEFAULT 0,
+			event_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS client_events (
+			id BIGSERIAL PRIMARY KEY,
+			client_id VARCHAR(64) NOT NULL,
+			event_type VARCHAR(20) NOT NULL,
+			path VARCHAR(255),
+			occurred_at TIMESTAMP NOT NULL,
+			session_id BIGINT REFERENCES client_sessions(id) ON DELETE CASCADE,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Venue events received from client apps, to count resent ones once
+		`CREATE TABLE IF NOT EXISTS venue_event_receipts (
+			client_id VARCHAR(64) NOT NULL,
+			event_id VARCHAR(64) NOT NULL,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (client_id, event_id)
+		)`,
+
+		// Saved searches
+		`CREATE TABLE IF NOT EXISTS saved_searches (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES snapp_users(id),
+			name VARCHAR(100) NOT NULL,
+			params JSONB NOT NULL,
+			alerts_enabled BOOLEAN DEFAULT true,
+			last_evaluated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_notified_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS saved_search_matches (
+			saved_search_id BIGINT REFERENCES saved_searches(id) ON DELETE CASCADE,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			is_read BOOLEAN DEFAULT false,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (saved_search_id, venue_id)
+		)`,
+
+		// Social relations
+		`CREATE TABLE IF NOT EXISTS user_follows (
+			id BIGSERIAL PRIMARY KEY,
+			follower_id BIGINT REFERENCES snapp_users(id),
+			following_id BIGINT REFERENCES snapp_users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(follower_id, following_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_blocks (
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			target_user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, target_user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_mutes (
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			target_user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, target_user_id)
+		)`,
+
+		// Review invites
+		`CREATE TABLE IF NOT EXISTS review_invites (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			token VARCHAR(64) NOT NULL UNIQUE,
+			created_by BIGINT,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			review_id BIGINT REFERENCES venue_reviews(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Review exports
+		`CREATE TABLE IF NOT EXISTS review_exports (
+			id BIGSERIAL PRIMARY KEY,
+			venue_id BIGINT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+			requested_by BIGINT NOT NULL,
+			format VARCHAR(10) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			object_key VARCHAR(255),
+			review_count INTEGER,
+			error TEXT,
+			started_at TIMESTAMP,
+			completed_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Recommendation feedback
+		`CREATE TABLE IF NOT EXISTS recommendation_feedback (
+			user_id BIGINT REFERENCES snapp_users(id) ON DELETE CASCADE,
+			venue_id BIGINT REFERENCES venues(id) ON DELETE CASCADE,
+			feedback VARCHAR(20) NOT NULL,
+			signals TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, venue_id)
+		)`,
+
+		// Feature flags
+		`CREATE TABLE IF NOT EXISTS feature_flags (
+			id BIGSERIAL PRIMARY KEY,
+			key VARCHAR(100) NOT NULL UNIQUE,
+			description TEXT,
+			enabled BOOLEAN NOT NULL DEFAULT false,
+			rollout_percentage INTEGER NOT NULL DEFAULT 0 CHECK (rollout_percentage BETWEEN 0 AND 100),
+			user_ids BIGINT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Review translations
+		`CREATE TABLE IF NOT EXISTS review_translations (
+			review_id BIGINT REFERENCES venue_reviews(id) ON DELETE CASCADE,
+			language VARCHAR(10) NOT NULL,
+			source_language VARCHAR(10),
+			title TEXT,
+			review_text TEXT,
+			provider VARCHAR(50) NOT NULL,
+			translated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (review_id, language)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS venue_review_summaries (
+			venue_id BIGINT PRIMARY KEY REFERENCES venues(id) ON DELETE CASCADE,
+			summary TEXT NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			review_count INTEGER NOT NULL,
+			reviews_changed_at TIMESTAMP,
+			generated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Users and their email verification and password reset tokens
+		`CREATE TABLE IF NOT EXISTS users (
+			id BIGSERIAL PRIMARY KEY,
+			email VARCHAR(255) NOT NULL,
+			password VARCHAR(255) NOT NULL,
+			is_superuser BOOLEAN DEFAULT false,
+			email_verified_at TIMESTAMP,
+			tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (tenant_id, email)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose VARCHAR(20) NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Account links
+		`CREATE TABLE IF NOT EXISTS account_links (
+			user_id BIGINT PRIMARY KEY,
+			snapp_user_id BIGINT NOT NULL UNIQUE REFERENCES snapp_users(id) ON DELETE CASCADE,
+			linked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS account_link_requests (
+			user_id BIGINT PRIMARY KEY,
+			snapp_user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Votes held until confirmed with a one-time code
+		`CREATE TABLE IF NOT EXISTS pending_campaign_votes (
+			campaign_id BIGINT NOT NULL REFERENCES voting_campaigns(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			vote JSONB NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (campaign_id, user_id)
+		)`,
+
+		// Events waiting to be notified in a digest
+		`CREATE TABLE IF NOT EXISTS notification_digest_events (
+			user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			event_type VARCHAR(50) NOT NULL,
+			subject_id BIGINT NOT NULL DEFAULT 0,
+			actor_id BIGINT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, event_type, subject_id, actor_id)
+		)`,
+
+		// Experiment impressions and the tagged venue events
+		`CREATE TABLE IF NOT EXISTS experiment_events (
+			id BIGSERIAL PRIMARY KEY,
+			experiment VARCHAR(100) NOT NULL,
+			variant VARCHAR(50) NOT NULL,
+			user_id BIGINT,
+			venue_id BIGINT NOT NULL,
+			event_type VARCHAR(20) NOT NULL,
+			occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Admin impersonation sessions and their audit log
+		`CREATE TABLE IF NOT EXISTS impersonation_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			admin_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			snapp_user_id BIGINT NOT NULL REFERENCES snapp_users(id) ON DELETE CASCADE,
+			reason TEXT NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS impersonation_audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			session_id BIGINT NOT NULL REFERENCES impersonation_sessions(id) ON DELETE CASCADE,
+			admin_id BIGINT NOT NULL,
+			snapp_user_id BIGINT NOT NULL,
+			method VARCHAR(10) NOT NULL,
+			path TEXT NOT NULL,
+			route VARCHAR(255),
+			status INTEGER NOT NULL,
+			blocked BOOLEAN NOT NULL DEFAULT false,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
 
 	for _, migration := range migrations {
@@ -330,12 +1099,25 @@ func (suite *TestSuite) runEnhancedMigrations() {
 // setupRouter initializes the Gin router with all routes
 func (suite *TestSuite) setupRouter() {
 	suite.router = gin.New()
+	suite.router.SetTrustedProxies(nil)
 	suite.router.Use(gin.Recovery())
+	suite.router.Use(middlewares.QueryTimeout(10 * time.Second))
+	suite.router.Use(middlewares.Tenant())
+	suite.router.Use(middlewares.Impersonation())
+	suite.router.Use(middlewares.Compress(1024))
+	suite.router.Use(middlewares.RequestBody(1<<20, "/v1/reviews/:snapp_id/photos", "/v1/admin/legacy/:dataset/import", "/v1/admin/external-ratings/import"))
 
 	// Add test middleware that bypasses authentication
 	suite.router.Use(suite.testAuthMiddleware())
 
-	v1 := suite.router.Group("/v1")
+	metricsController := controllers.MetricsController{DatabasePool: new(services.DatabasePoolService)}
+	suite.router.GET("/metrics", metricsController.Metrics)
+	suite.router.GET("/readyz", metricsController.Ready)
+
+	v1 := suite.router.Group("/v1", middlewares.APIVersion("1"))
+
+	v1.GET("/tenant", new(controllers.TenantController).GetTenant)
+	v1.GET("/sync", new(controllers.SyncController).Sync)
 
 	// Venue routes
 	venueRoutes := v1.Group("/venues")
@@ -345,28 +1127,310 @@ func (suite *TestSuite) setupRouter() {
 		venueRoutes.GET("/nearby", venueController.GetNearby)
 		venueRoutes.GET("/featured", venueController.GetFeatured)
 		venueRoutes.GET("/categories", venueController.GetCategories)
+		venueRoutes.GET("/compare", venueController.CompareVenues)
+		venueRoutes.GET("/map", venueController.GetMapVenues)
+		venueRoutes.GET("/by-slug/:slug", venueController.GetBySlug)
 		venueRoutes.GET("/:id", venueController.GetByID)
 		venueRoutes.POST("/", venueController.CreateVenue)
+		venueRoutes.PUT("/:id", venueController.UpdateVenue)
+		venueRoutes.DELETE("/:id", venueController.DeleteVenue)
 	}
+	neighborhoodController := new(controllers.NeighborhoodController)
+	v1.GET("/discover/by-neighborhood/:id", neighborhoodController.DiscoverByNeighborhood)
+	v1.GET("/discover/open-now", new(controllers.VenueController).DiscoverOpenNow)
+	v1.GET("/discover/trending", new(controllers.VenueController).DiscoverTrending)
+	v1.GET("/discover/city", new(controllers.VenueController).DiscoverCity)
+	v1.GET("/discover/:snapp_id/recommendations", new(controllers.RecommendationController).GetRecommendations)
+	v1.POST("/discover/:snapp_id/feedback", new(controllers.RecommendationController).SubmitFeedback)
 
 	// Review routes
 	v1.GET("/venues/:venue_id/reviews", controllers.ReviewController{}.GetVenueReviews)
 	v1.GET("/venues/:venue_id/reviews/summary", controllers.ReviewController{}.GetReviewSummary)
 
+	v1.GET("/reviews/featured", controllers.ReviewController{}.GetFeaturedReviews)
 	userReviewRoutes := v1.Group("/reviews/:snapp_id")
 	{
 		reviewController := new(controllers.ReviewController)
 		userReviewRoutes.POST("/", reviewController.CreateReview)
 		userReviewRoutes.GET("/", reviewController.GetUserReviews)
 		userReviewRoutes.POST("/:review_id/vote", reviewController.VoteReviewHelpful)
+		userReviewRoutes.PUT("/drafts/:venue_id", reviewController.SaveReviewDraft)
+		userReviewRoutes.GET("/drafts/:venue_id", reviewController.GetReviewDraft)
+		userReviewRoutes.POST("/photos", reviewController.UploadReviewPhoto)
+		userReviewRoutes.PUT("/:review_id/photos/order", reviewController.ReorderReviewPhotos)
+		userReviewRoutes.PUT("/:review_id/photos/caption", reviewController.CaptionReviewPhoto)
+	}
+
+	// Account and account linking routes
+	authRoutes := v1.Group("/auth")
+	{
+		authController := new(controllers.User)
+		authRoutes.POST("register", authController.Register)
+		authRoutes.POST("login", authController.Login)
+		accountEmailLimit := middlewares.RateLimit(5, 15*time.Minute)
+		perAccountEmailLimit := middlewares.RateLimitEmail(5, time.Hour)
+		authRoutes.POST("verify-email/resend", accountEmailLimit, perAccountEmailLimit, authController.ResendEmailVerification)
+		authRoutes.POST("verify-email/:token", accountEmailLimit, authController.VerifyEmail)
+		authRoutes.POST("forgot-pass", accountEmailLimit, perAccountEmailLimit, authController.ForgotPassword)
+		authRoutes.POST("reset-pass/:token", accountEmailLimit, authController.ResetForgottenPassword)
+		authRoutes.POST("link-snapp", accountEmailLimit, authController.LinkSnapp)
+		authRoutes.POST("link-snapp/verify", accountEmailLimit, authController.VerifySnappLink)
+		authRoutes.DELETE("link-snapp", authController.UnlinkSnapp)
+		authRoutes.GET("identity", authController.GetIdentity)
 	}
 
 	// Legacy vote routes for backwards compatibility
+	voteController := new(controllers.VoteController)
 	voteRoutes := v1.Group("/vote/:snapp_id")
 	{
-		voteController := new(controllers.VoteController)
+		voteRoutes.Use(middlewares.LegacyVotingReadOnly())
 		voteRoutes.GET("/", voteController.Vote)
 		voteRoutes.POST("/:voting_id/:vote_id", voteController.SubmitVote)
+		voteRoutes.GET("/results/:voting_id", voteController.GetResults)
+	}
+	v1.GET("/legacy/archive", voteController.GetArchive)
+
+	// Stored files
+	v1.GET("/files/*file_name", controllers.FileController{}.Serve)
+
+	// Notification routes
+	notificationRoutes := v1.Group("/notifications/:snapp_id")
+	{
+		notificationController := new(controllers.NotificationController)
+		notificationRoutes.POST("/devices", notificationController.RegisterDevice)
+	}
+
+	// Campaign routes
+	campaignController := new(controllers.CampaignController)
+	webhookController := new(controllers.WebhookController)
+	v1.POST("/receipts/verify", campaignController.VerifyReceipt)
+	userCampaignRoutes := v1.Group("/campaigns/:id/:snapp_id")
+	{
+		userCampaignRoutes.POST("/vote", campaignController.SubmitCampaignVote)
+		userCampaignRoutes.POST("/verify-otp", middlewares.RateLimit(10, 15*time.Minute), campaignController.VerifyCampaignVote)
+		userCampaignRoutes.GET("/receipts", campaignController.GetVoteReceipts)
+		userCampaignRoutes.GET("/votes", campaignController.GetUserVotes)
+		userCampaignRoutes.PUT("/votes/:vote_id", campaignController.ChangeCampaignVote)
+		userCampaignRoutes.GET("/credits", campaignController.GetCreditBalance)
+	}
+	v1.GET("/campaigns/featured", campaignController.GetFeaturedCampaigns)
+	v1.GET("/campaigns/:id", campaignController.GetCampaign)
+	v1.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+	v1.GET("/campaigns/:id/audit", campaignController.GetCampaignAudit)
+	v1.GET("/campaigns/audit-key", campaignController.GetCampaignAuditKey)
+	v1.POST("/campaigns/:id/vote", campaignController.SubmitSessionCampaignVote)
+	v1.POST("/vote/sessions", campaignController.CreateVotingSession)
+	v1.GET("/campaign-results/:id", middlewares.OptionalJWT(), campaignController.GetCampaignResults)
+	v1.GET("/campaign-results/:id/snapshots", middlewares.OptionalJWT(), campaignController.GetCampaignSnapshots)
+	v1.GET("/embed/campaigns/:id", campaignController.GetCampaignWidget)
+	adminRoutes := v1.Group("/admin")
+	{
+		adminController := new(controllers.AdminController)
+		adminRoutes.GET("/overview", adminController.GetOverview)
+		adminRoutes.GET("/slow-queries", adminController.GetSlowQueries)
+		adminRoutes.GET("/legacy/:dataset/export", adminController.ExportLegacyData)
+		adminRoutes.POST("/legacy/:dataset/import", adminController.ImportLegacyData)
+		adminRoutes.POST("/external-ratings/import", adminController.ImportExternalRatings)
+		adminRoutes.POST("/reviews/bulk", adminController.BulkModerateReviews)
+		adminRoutes.GET("/reviews/:id/duplicates", adminController.GetReviewDuplicates)
+		adminRoutes.POST("/impersonate/:snapp_id", adminController.Impersonate)
+		adminRoutes.GET("/impersonations/:id/audit", adminController.GetImpersonationAudit)
+		adminRoutes.POST("/campaigns/:id/categories", campaignController.CreateCampaignCategory)
+		adminRoutes.POST("/campaigns/:id/promotions", campaignController.CreateCampaignPromotion)
+		adminRoutes.POST("/campaigns/auto-generate", campaignController.AutoGenerateCampaign)
+		adminRoutes.GET("/campaigns/:id/kiosks", campaignController.GetKioskDevices)
+		adminRoutes.POST("/campaigns/:id/kiosks", campaignController.CreateKioskDevice)
+		adminRoutes.DELETE("/campaigns/:id/kiosks/:kiosk_id", campaignController.RevokeKioskDevice)
+		adminRoutes.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+		adminRoutes.POST("/campaigns/:id/nominees", campaignController.AddCampaignNominee)
+		adminRoutes.POST("/campaigns/:id/nominees/eligible", campaignController.AddEligibleCampaignNominees)
+		adminRoutes.DELETE("/campaigns/:id/nominees/:venue_id", campaignController.RemoveCampaignNominee)
+		adminRoutes.POST("/campaigns/:id/publish", campaignController.PublishCampaign)
+		adminRoutes.GET("/campaigns/:id/reasons", campaignController.GetCampaignVoteReasons)
+		adminRoutes.PUT("/campaigns/:id/reasons/:vote_id", campaignController.ModerateVoteReason)
+		adminRoutes.PUT("/campaigns/:id/votes/:vote_id/exclusion", campaignController.ExcludeCampaignVote)
+		adminRoutes.PUT("/owners/:user_id/subscription", new(controllers.OwnerController).SetOwnerSubscription)
+		adminRoutes.GET("/campaign-promotions", campaignController.GetCampaignPromotions)
+		adminRoutes.DELETE("/campaign-promotions/:promotion_id", campaignController.DeleteCampaignPromotion)
+		adminRoutes.POST("/neighborhoods", neighborhoodController.CreateNeighborhood)
+		adminRoutes.POST("/webhooks", webhookController.CreateWebhook)
+		adminRoutes.GET("/webhooks", webhookController.GetWebhooks)
+		adminRoutes.POST("/webhooks/:webhook_id/test", webhookController.TestWebhook)
+		adminRoutes.POST("/webhooks/:webhook_id/disable", webhookController.DisableWebhook)
+		adminRoutes.GET("/webhooks/:webhook_id/deliveries", webhookController.GetWebhookDeliveries)
+		flagController := new(controllers.FeatureFlagController)
+		adminRoutes.GET("/flags", flagController.GetFlags)
+		adminRoutes.POST("/flags", flagController.CreateFlag)
+		adminRoutes.PUT("/flags/:key", flagController.UpdateFlag)
+		adminRoutes.DELETE("/flags/:key", flagController.DeleteFlag)
+		adminRoutes.GET("/experiments/:key/report", new(controllers.RecommendationController).GetExperimentReport)
+		cityController := new(controllers.CityController)
+		adminRoutes.POST("/cities", cityController.CreateCity)
+		adminRoutes.PUT("/cities/:id", cityController.UpdateCity)
+		adminRoutes.POST("/cities/:id/districts", cityController.CreateDistrict)
+		adminRoutes.PUT("/districts/:id", cityController.UpdateDistrict)
+		adminRoutes.DELETE("/districts/:id", cityController.DeleteDistrict)
+		tenantController := new(controllers.TenantController)
+		adminRoutes.POST("/tenants", tenantController.CreateTenant)
+		adminRoutes.PUT("/tenants/:id", tenantController.UpdateTenant)
+		ratingTemplateController := new(controllers.RatingTemplateController)
+		adminRoutes.GET("/rating-templates", ratingTemplateController.GetRatingTemplates)
+		adminRoutes.PUT("/rating-templates/:category_id", ratingTemplateController.SaveRatingTemplate)
+		adminRoutes.DELETE("/rating-templates/:category_id", ratingTemplateController.DeleteRatingTemplate)
+	}
+
+	// User routes
+	userRoutes := v1.Group("/users/:snapp_id")
+	{
+		savedSearchController := new(controllers.SavedSearchController)
+		userRoutes.POST("/saved-searches", savedSearchController.CreateSavedSearch)
+		userRoutes.GET("/saved-searches", savedSearchController.GetSavedSearches)
+		userRoutes.GET("/saved-searches/:search_id/matches", savedSearchController.GetSavedSearchMatches)
+		userRoutes.DELETE("/saved-searches/:search_id", savedSearchController.DeleteSavedSearch)
+		userRoutes.POST("/checkins", new(controllers.VenueController).CreateCheckin)
+		userRoutes.POST("/deals/:deal_id/redeem", new(controllers.VenueController).RedeemDeal)
+		searchHistoryController := new(controllers.SearchHistoryController)
+		userRoutes.GET("/search-history", searchHistoryController.GetSearchHistory)
+		userRoutes.DELETE("/search-history", searchHistoryController.ClearSearchHistory)
+		userRoutes.GET("/privacy", searchHistoryController.GetPrivacySettings)
+		userRoutes.PUT("/privacy", searchHistoryController.UpdatePrivacySettings)
+		consentController := new(controllers.ConsentController)
+		userRoutes.GET("/consents", consentController.GetConsents)
+		userRoutes.POST("/consents/:purpose", consentController.RequestConsent)
+		userRoutes.POST("/consents/:purpose/confirm", consentController.ConfirmConsent)
+		userRoutes.DELETE("/consents/:purpose", consentController.RevokeConsent)
+		userRoutes.GET("/favorites", new(controllers.FavoriteController).GetFavorites)
+	}
+
+	// Collection routes
+	collectionRoutes := v1.Group("/collections/:snapp_id")
+	{
+		collectionController := new(controllers.CollectionController)
+		collectionRoutes.GET("", collectionController.GetCollections)
+		collectionRoutes.POST("", collectionController.CreateCollection)
+		collectionRoutes.GET("/:collection_id", collectionController.GetCollection)
+		collectionRoutes.POST("/:collection_id/venues", collectionController.AddVenueToCollection)
+		collectionRoutes.DELETE("/:collection_id/venues/:venue_id", collectionController.RemoveVenueFromCollection)
+		collectionRoutes.POST("/:collection_id/collaborators", collectionController.InviteCollaborator)
+		collectionRoutes.POST("/:collection_id/collaborators/accept", collectionController.AcceptCollaboration)
+		collectionRoutes.DELETE("/:collection_id/collaborators/:user_id", collectionController.RemoveCollaborator)
+	}
+
+	// Social routes
+	socialRoutes := v1.Group("/social/:snapp_id")
+	{
+		socialController := new(controllers.SocialController)
+		socialRoutes.GET("/feed", socialController.GetSocialFeed)
+		socialRoutes.POST("/follow/:target_user_id", socialController.FollowUser)
+		socialRoutes.DELETE("/follow/:target_user_id", socialController.UnfollowUser)
+		socialRoutes.POST("/block/:target_user_id", socialController.BlockUser)
+		socialRoutes.DELETE("/block/:target_user_id", socialController.UnblockUser)
+		socialRoutes.POST("/mute/:target_user_id", socialController.MuteUser)
+		socialRoutes.DELETE("/mute/:target_user_id", socialController.UnmuteUser)
+		socialRoutes.GET("/blocked", socialController.GetUserRelations)
+	}
+
+	// Menu routes
+	menuController := new(controllers.MenuController)
+	venueRoutes.GET("/:id/menus", menuController.GetVenueMenus)
+	venueRoutes.GET("/:id/photos", new(controllers.OwnerController).GetVenuePhotos)
+	venueRoutes.GET("/:id/checkins", new(controllers.VenueController).GetVenueCheckins)
+	venueRoutes.POST("/:id/:snapp_id/favorite", new(controllers.FavoriteController).FavoriteVenue)
+	venueRoutes.DELETE("/:id/:snapp_id/favorite", new(controllers.FavoriteController).UnfavoriteVenue)
+	venueRoutes.GET("/:id/reviews/export", controllers.ReviewController{}.ExportVenueReviews)
+	venueRoutes.GET("/:id/reviews/exports/:export_id", controllers.ReviewController{}.GetReviewExport)
+	venueRoutes.GET("/:id/reviews/exports/:export_id/download", controllers.ReviewController{}.DownloadReviewExport)
+	v1.GET("/owner/plan", new(controllers.OwnerController).GetPlan)
+	ownerRoutes := v1.Group("/owner/venues/:id")
+	{
+		ownerRoutes.PUT("/name", new(controllers.VenueController).RenameVenue)
+		ownerRoutes.GET("/watchlist", new(controllers.VenueController).GetWatchlist)
+		ownerRoutes.POST("/watchlist", new(controllers.VenueController).AddToWatchlist)
+		ownerRoutes.DELETE("/watchlist/:competitor_id", new(controllers.VenueController).RemoveFromWatchlist)
+		ownerRoutes.GET("/hours-exceptions", new(controllers.VenueController).GetHoursExceptions)
+		ownerRoutes.POST("/hours-exceptions", new(controllers.VenueController).SetHoursException)
+		ownerRoutes.DELETE("/hours-exceptions/:exception_id", new(controllers.VenueController).DeleteHoursException)
+		ownerRoutes.GET("/deals", new(controllers.VenueController).GetDeals)
+		ownerRoutes.POST("/deals", new(controllers.VenueController).CreateDeal)
+		ownerRoutes.PUT("/deals/:deal_id", new(controllers.VenueController).UpdateDeal)
+		ownerRoutes.DELETE("/deals/:deal_id", new(controllers.VenueController).DeleteDeal)
+		ownerRoutes.GET("/external-ratings", new(controllers.VenueController).GetExternalRatings)
+		ownerRoutes.POST("/external-ratings", new(controllers.VenueController).ImportExternalRatings)
+		ownerRoutes.DELETE("/external-ratings/:source", new(controllers.VenueController).DeleteExternalRating)
+		ownerRoutes.GET("/menus", menuController.GetOwnerMenus)
+		ownerRoutes.POST("/menus", menuController.CreateMenu)
+		ownerRoutes.PUT("/menus/:menu_id", menuController.UpdateMenu)
+		ownerRoutes.DELETE("/menus/:menu_id", menuController.DeleteMenu)
+		ownerRoutes.POST("/menus/:menu_id/sections", menuController.CreateSection)
+		ownerRoutes.PUT("/menus/:menu_id/sections/:section_id", menuController.UpdateSection)
+		ownerRoutes.DELETE("/menus/:menu_id/sections/:section_id", menuController.DeleteSection)
+		ownerRoutes.POST("/menus/:menu_id/sections/:section_id/items", menuController.CreateItem)
+		ownerRoutes.PUT("/menus/:menu_id/sections/:section_id/items/:item_id", menuController.UpdateItem)
+		ownerRoutes.DELETE("/menus/:menu_id/sections/:section_id/items/:item_id", menuController.DeleteItem)
+		ownerRoutes.POST("/review-invites", controllers.ReviewController{}.CreateReviewInvites)
+		ownerRoutes.POST("/webhooks", webhookController.CreateWebhook)
+		ownerRoutes.GET("/webhooks", webhookController.GetWebhooks)
+		ownerRoutes.POST("/webhooks/:webhook_id/test", webhookController.TestWebhook)
+		ownerRoutes.POST("/webhooks/:webhook_id/disable", webhookController.DisableWebhook)
+		ownerRoutes.GET("/webhooks/:webhook_id/deliveries", webhookController.GetWebhookDeliveries)
+		ownerRoutes.GET("/photos", new(controllers.OwnerController).GetOwnerVenuePhotos)
+		ownerRoutes.POST("/photos", new(controllers.OwnerController).AddVenuePhoto)
+		ownerRoutes.DELETE("/photos/:photo_id", new(controllers.OwnerController).DeleteVenuePhoto)
+	}
+	v1.GET("/analytics/venues/:id", new(controllers.AnalyticsController).GetVenueAnalytics)
+	v1.GET("/analytics/venues/:id/watchlist", new(controllers.AnalyticsController).GetWatchlistComparison)
+	v1.GET("/analytics/alerts", new(controllers.AnalyticsController).GetMetricAlerts)
+	v1.GET("/analytics/engagement", new(controllers.AnalyticsController).GetEngagement)
+	v1.POST("/analytics/events", middlewares.RateLimit(60, time.Minute), new(controllers.AnalyticsController).TrackEvents)
+	v1.POST("/analytics/track", middlewares.RateLimit(60, time.Minute), new(controllers.AnalyticsController).TrackVenueEvents)
+
+	// Utility routes
+	utilityRoutes := v1.Group("/utils")
+	{
+		utilityController := new(controllers.UtilityController)
+		utilityRoutes.POST("/meeting-point", utilityController.MeetingPoint)
+		utilityRoutes.POST("/distances", utilityController.Distances)
+		utilityRoutes.GET("/suggestions", utilityController.GetSearchSuggestions)
+		utilityRoutes.GET("/autocomplete", utilityController.GetAutocomplete)
+		utilityRoutes.GET("/reference", utilityController.GetReferenceData)
+		utilityRoutes.GET("/cities", utilityController.GetCities)
+		utilityRoutes.GET("/cities/:city_id/districts", utilityController.GetDistricts)
+	}
+
+	// Feed routes
+	feedRoutes := v1.Group("/feeds")
+	{
+		feedController := new(controllers.FeedController)
+		feedRoutes.GET("/sitemap.xml", feedController.Sitemap)
+		feedRoutes.GET("/venues.json", feedController.VenuesFeed)
+	}
+
+	// v2 routes, enveloping the v1 handlers
+	v2 := suite.router.Group("/v2", middlewares.APIVersion("2"), middlewares.Envelope("2"))
+	{
+		venueController := new(controllers.VenueController)
+		reviewController := new(controllers.ReviewController)
+		v2.GET("/venues/search", venueController.Search)
+		v2.GET("/venues/nearby", venueController.GetNearby)
+		v2.GET("/venues/featured", venueController.GetFeatured)
+		v2.GET("/venues/categories", venueController.GetCategories)
+		v2.GET("/venues/compare", venueController.CompareVenues)
+		v2.GET("/venues/by-slug/:slug", venueController.GetBySlug)
+		v2.GET("/venues/:id", venueController.GetByID)
+		v2.GET("/venues/:id/reviews", reviewController.GetVenueReviews)
+		v2.GET("/venues/:id/reviews/summary", reviewController.GetReviewSummary)
+		v2.GET("/reviews/featured", reviewController.GetFeaturedReviews)
+		v2.POST("/reviews/:snapp_id", reviewController.CreateReview)
+		v2.GET("/reviews/:snapp_id", reviewController.GetUserReviews)
+		v2.PUT("/reviews/:snapp_id/:review_id/photos/order", reviewController.ReorderReviewPhotos)
+		v2.PUT("/reviews/:snapp_id/:review_id/photos/caption", reviewController.CaptionReviewPhoto)
+		v2.GET("/campaigns/featured", campaignController.GetFeaturedCampaigns)
+		v2.GET("/campaigns/:id", campaignController.GetCampaign)
+		v2.GET("/campaigns/:id/nominees", campaignController.GetCampaignNominees)
+		v2.GET("/campaigns/:id/results", middlewares.OptionalJWT(), campaignController.GetCampaignResults)
+		v2.POST("/campaigns/:id/:snapp_id/vote", campaignController.SubmitCampaignVote)
+		v2.POST("/campaigns/:id/:snapp_id/verify-otp", middlewares.RateLimit(10, 15*time.Minute), campaignController.VerifyCampaignVote)
+		v2.GET("/campaigns/:id/:snapp_id/votes", campaignController.GetUserVotes)
 	}
 }
 
@@ -443,9 +1507,17 @@ func (suite *TestSuite) createTestData() {
 // cleanupTestData removes test data
 func (suite *TestSuite) cleanupTestData() {
 	tables := []string{
-		"search_analytics", "venue_analytics", "campaign_votes", "voting_campaigns",
-		"venue_checkins", "venue_collection_items", "venue_collections", "venue_reviews",
-		"venues", "venue_subcategories", "venue_categories", "cities", "snapp_users",
+		"notification_digest_events", "experiment_events", "pending_campaign_votes", "impersonation_audit_log", "impersonation_sessions", "slow_queries", "owner_alert_state", "venue_milestones", "owner_subscriptions", "venue_photos", "user_tokens", "users", "account_link_requests", "account_links", "feature_flags", "metric_alerts", "client_events", "client_sessions", "venue_event_receipts", "platform_stats_watermarks", "platform_stats_rollups", "recommendation_feedback",
+		"menu_items", "menu_sections", "venue_menus",
+		"saved_search_matches", "saved_searches",
+		"user_blocks", "user_mutes", "user_follows", "review_invites", "review_exports", "venue_claims",
+		"webhook_deliveries", "webhook_subscriptions",
+		"user_devices", "notifications", "venue_city_corrections", "photos", "sync_tombstones",
+		"moderation_audit_log", "user_consents", "user_privacy_settings", "search_analytics", "venue_analytics",
+		"campaign_promotions", "campaign_audits", "campaign_result_snapshots", "campaign_credit_balances",
+		"vote_changes", "campaign_votes", "voting_sessions", "kiosk_devices", "campaign_nominees", "campaign_categories", "voting_campaigns",
+		"deal_redemptions", "venue_deals", "venue_wait_reports", "venue_checkins", "venue_collection_items", "collection_collaborators", "venue_collections", "review_drafts", "review_translations", "venue_review_summaries", "review_signatures", "venue_reviews",
+		"venue_favorites", "venue_watchlist", "venue_hours_exceptions", "external_ratings", "venue_similar", "venue_slug_history", "venues", "districts", "neighborhoods", "venue_subcategories", "rating_templates", "venue_categories", "cities", "snapp_users",
 	}
 
 	for _, table := range tables {
@@ -454,6 +1526,8 @@ func (suite *TestSuite) cleanupTestData() {
 			// Table might not exist yet, which is fine
 		}
 	}
+	// The default tenant is created by the migrations and owns the fixtures
+	suite.db.Exec("DELETE FROM tenants WHERE id <> 1")
 }
 
 // Helper methods for making HTTP requests
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"voting-app/app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSlowQueryLog tests recording slow search and analytics statements with
+// their plans
+func (suite *TestSuite) TestSlowQueryLog() {
+	suite.Run("Slow Query Log", func() {
+		previous := models.GetSlowQuerySettings()
+		defer models.SetSlowQuerySettings(previous)
+
+		const searchSource = "models.(*Venue).SearchWithTotal"
+		since := time.Now().UTC().Add(-24 * time.Hour)
+
+		// Every statement is slow with a nanosecond threshold
+		models.SetSlowQuerySettings(models.SlowQuerySettings{Enabled: true, Threshold: time.Nanosecond, Explain: true})
+		w := suite.makeGETRequest("/v1/venues/search?q=Test&category=1")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		// Slow statements are recorded in the background
+		var slowQueries, counts []models.SlowQuery
+		suite.Require().Eventually(func() bool {
+			var err error
+			slowQueries, err = models.GetSlowQueries(context.Background(), since, searchSource, 10)
+			if err != nil {
+				return false
+			}
+			counts, err = models.GetSlowQueries(context.Background(), since, "models.countSearchResults", 10)
+			return err == nil && len(slowQueries) > 0 && len(counts) > 0
+		}, 5*time.Second, 50*time.Millisecond)
+		models.SetSlowQuerySettings(models.SlowQuerySettings{})
+
+		slowQuery := slowQueries[0]
+		assert.Contains(suite.T(), slowQuery.Statement, "FROM venues")
+		assert.Contains(suite.T(), slowQuery.Params, "1")
+		assert.Empty(suite.T(), slowQuery.Error)
+		suite.Require().NotEmpty(slowQuery.Plan)
+		var plan []map[string]interface{}
+		suite.Require().NoError(json.Unmarshal(slowQuery.Plan, &plan))
+		suite.Require().Len(plan, 1)
+		assert.Contains(suite.T(), plan[0], "Plan")
+		// The plan isn't analyzed, the statement isn't run again
+		assert.NotContains(suite.T(), string(slowQuery.Plan), "Actual Total Time")
+
+		// The exact count of the search is logged too
+		assert.True(suite.T(), strings.HasPrefix(counts[0].Statement, "SELECT COUNT(*)"))
+
+		all, err := models.GetSlowQueries(context.Background(), since, "", 100)
+		suite.Require().NoError(err)
+		assert.GreaterOrEqual(suite.T(), len(all), 2)
+
+		// Only administrators list them
+		w = suite.makeGETRequest("/v1/admin/slow-queries")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		deleted, err := models.DeleteSlowQueries(context.Background(), time.Now().UTC().Add(time.Minute))
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), int64(len(all)), deleted)
+	})
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUserBlocking tests blocking and muting users in feeds and review listings
+func (suite *TestSuite) TestUserBlocking() {
+	suite.Run("User Blocking End-to-End", func() {
+		_, err := suite.db.Exec("INSERT INTO snapp_users (id, snapp_id) VALUES (3, 'test_user_3') ON CONFLICT (id) DO NOTHING")
+		suite.Require().NoError(err)
+
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews
+			(venue_id, user_id, overall_rating, review_text, moderation_status)
+			VALUES (1, 2, 4.0, 'Lovely place', 'approved'),
+			       (2, 3, 3.5, 'Decent food', 'approved')`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id, message, is_public)
+			VALUES (2, 2, 'Lunch time', true)`)
+		suite.Require().NoError(err)
+
+		suite.testFollowAndFeed()
+		suite.testMuteHidesActivity()
+		suite.testBlockHidesActivity()
+		suite.testBlockedUserCannotFollow()
+		suite.testUserRelationEdgeCases()
+	})
+}
+
+func (suite *TestSuite) testFollowAndFeed() {
+	for _, target := range []string{"2", "3"} {
+		w := suite.makePOSTRequest("/v1/social/test_user_1/follow/"+target, nil)
+		assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	}
+
+	feed := suite.getSocialFeed()
+	assert.Len(suite.T(), feed.Activities, 3)
+}
+
+func (suite *TestSuite) testMuteHidesActivity() {
+	w := suite.makePOSTRequest("/v1/social/test_user_1/mute/3", nil)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	// Muted users stay followed but their activity is hidden
+	feed := suite.getSocialFeed()
+	suite.Require().Len(feed.Activities, 2)
+	for _, activity := range feed.Activities {
+		assert.Equal(suite.T(), int64(2), activity.UserID)
+	}
+
+	var follows int
+	err := suite.db.QueryRow("SELECT COUNT(*) FROM user_follows WHERE follower_id = 1 AND following_id = 3").Scan(&follows)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, follows)
+
+	w = suite.makeGETRequest("/v1/venues/2/reviews?viewer=test_user_1")
+	var reviews serializers.ReviewSearchResponse
+	suite.parseJSONResponse(w, &reviews)
+	assert.Empty(suite.T(), reviews.Reviews)
+}
+
+func (suite *TestSuite) testBlockHidesActivity() {
+	w := suite.makePOSTRequest("/v1/social/test_user_1/block/2", nil)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	feed := suite.getSocialFeed()
+	assert.Empty(suite.T(), feed.Activities)
+
+	// Blocking removes the follow
+	var follows int
+	err := suite.db.QueryRow("SELECT COUNT(*) FROM user_follows WHERE follower_id = 1 AND following_id = 2").Scan(&follows)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, follows)
+
+	// The blocked user's reviews are hidden from the blocker only
+	w = suite.makeGETRequest("/v1/venues/1/reviews?viewer=test_user_1")
+	var reviews serializers.ReviewSearchResponse
+	suite.parseJSONResponse(w, &reviews)
+	assert.Empty(suite.T(), reviews.Reviews)
+	assert.Equal(suite.T(), 0, reviews.Pagination.Total)
+
+	w = suite.makeGETRequest("/v1/venues/1/reviews")
+	suite.parseJSONResponse(w, &reviews)
+	assert.Len(suite.T(), reviews.Reviews, 1)
+
+	w = suite.makeGETRequest("/v1/social/test_user_1/blocked")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var relations serializers.UserRelationsResponse
+	suite.parseJSONResponse(w, &relations)
+	suite.Require().Len(relations.Blocked, 1)
+	assert.Equal(suite.T(), int64(2), relations.Blocked[0].TargetUserID)
+	suite.Require().Len(relations.Muted, 1)
+	assert.Equal(suite.T(), int64(3), relations.Muted[0].TargetUserID)
+}
+
+func (suite *TestSuite) testBlockedUserCannotFollow() {
+	_, err := suite.db.Exec("INSERT INTO user_blocks (user_id, target_user_id) VALUES (3, 1)")
+	suite.Require().NoError(err)
+
+	w := suite.makeDELETERequest("/v1/social/test_user_1/follow/3")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.makePOSTRequest("/v1/social/test_user_1/follow/3", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.Forbidden, errorResponse.Code)
+}
+
+func (suite *TestSuite) testUserRelationEdgeCases() {
+	w := suite.makeDELETERequest("/v1/social/test_user_1/block/2")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.makeDELETERequest("/v1/social/test_user_1/block/2")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.makePOSTRequest("/v1/social/test_user_1/block/1", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/social/test_user_1/mute/999", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *TestSuite) getSocialFeed() serializers.SocialFeedResponse {
+	w := suite.makeGETRequest("/v1/social/test_user_1/feed")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var feed serializers.SocialFeedResponse
+	suite.parseJSONResponse(w, &feed)
+	return feed
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFileStorage tests serving files through the storage backend, with
+// signed URLs to files in private buckets
+func (suite *TestSuite) TestFileStorage() {
+	suite.Run("FileStorage", func() {
+		previous := services.FileStorage
+		defer func() { services.FileStorage = previous }()
+		dir := suite.T().TempDir()
+		local := &services.LocalStorage{Dir: dir, Secret: "test-signing-secret"}
+		services.FileStorage = local
+		ctx := context.Background()
+
+		// Photos are public
+		photo := []byte("\x89PNG\r\n\x1a\nnot really a png")
+		suite.Require().NoError(services.PutPhotoObject(ctx, "reviews/photos/1.png", "image/png", photo))
+		w := suite.makeGETRequest("/v1/files/reviews/photos/1.png")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(suite.T(), photo, w.Body.Bytes())
+		assert.Equal(suite.T(), http.StatusNotFound, suite.makeGETRequest("/v1/files/reviews/photos/2.png").Code)
+
+		// Keys can't leave their bucket
+		suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o600))
+		_, _, err := local.Get(ctx, services.PhotoBucket, "../secret.txt")
+		assert.Equal(suite.T(), services.ErrObjectNotFound, err)
+
+		// Private files need a valid signed URL
+		export := &models.ReviewExport{ObjectKey: "venues/1/reviews-1.csv"}
+		suite.Require().NoError(services.PutReviewExportObject(ctx, export.ObjectKey, []byte("date,rating\n")))
+		_, err = os.Stat(filepath.Join(dir, services.ReviewExportBucket, "venues", "1", "reviews-1.csv"))
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), http.StatusNotFound, suite.makeGETRequest("/v1/files/"+export.ObjectKey).Code)
+
+		signedURL, err := new(services.ReviewExportService).SignedURL(ctx, export)
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest(signedURL)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), "date,rating\n", w.Body.String())
+
+		tampered := strings.Replace(signedURL, "reviews-1.csv", "reviews-2.csv", 1)
+		assert.Equal(suite.T(), http.StatusForbidden, suite.makeGETRequest(tampered).Code)
+		otherBucket := strings.Replace(signedURL, "bucket="+services.ReviewExportBucket, "bucket=other", 1)
+		assert.Equal(suite.T(), http.StatusForbidden, suite.makeGETRequest(otherBucket).Code)
+
+		expiredURL, err := local.SignedURL(ctx, services.ReviewExportBucket, export.ObjectKey, -time.Minute)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), http.StatusForbidden, suite.makeGETRequest(expiredURL).Code)
+
+		// Other backends sign their own URLs, the file routes don't accept any
+		services.FileStorage = &services.GCSStorage{}
+		assert.Equal(suite.T(), http.StatusForbidden, suite.makeGETRequest(signedURL).Code)
+	})
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSearchSuggestions tests trending searches and autocomplete
+func (suite *TestSuite) TestSearchSuggestions() {
+	suite.Run("Search Suggestions", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO search_analytics (user_id, search_query, search_type, filters_used, created_at) VALUES
+			(1, 'Pizza', 'text', '{"city_id": 1}', $1),
+			(1, 'pizza ', 'text', '{"city_id": 1}', $1),
+			(1, 'pizza', 'text', '{"city_id": 1}', $1),
+			(1, 'pizza', 'text', '{"city_id": 1}', $2),
+			(1, 'pasta carbonara', 'text', '{"city_id": 1}', $2),
+			(1, 'pasta carbonara', 'text', '{}', $2),
+			(1, 'tacos', 'text', '{"city_id": 2}', $1)`,
+			now.Add(-1*time.Hour), now.Add(-10*24*time.Hour))
+		suite.Require().NoError(err)
+
+		suggestionService := &services.SearchSuggestionService{}
+		suite.Require().NoError(suggestionService.RefreshSuggestions(context.Background()))
+
+		suite.testPopularAndTrendingSearches()
+		suite.testAutocomplete()
+	})
+}
+
+func (suite *TestSuite) testPopularAndTrendingSearches() {
+	w := suite.makeGETRequest("/v1/utils/suggestions?city=1")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response services.SearchSuggestions
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Popular, 2)
+	assert.Equal(suite.T(), "pizza", response.Popular[0].Query)
+	assert.Equal(suite.T(), 4, response.Popular[0].Searches)
+	assert.Equal(suite.T(), 3, response.Popular[0].RecentSearches)
+	assert.Equal(suite.T(), 1, response.Popular[1].Searches, "Searches of other cities are not counted")
+
+	// Only pizza was searched more than usual in the last day
+	suite.Require().Len(response.Trending, 1)
+	assert.Equal(suite.T(), "pizza", response.Trending[0].Query)
+
+	// Without a city every search counts
+	w = suite.makeGETRequest("/v1/utils/suggestions")
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Popular, 3)
+
+	w = suite.makeGETRequest("/v1/utils/suggestions?city=abc")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testAutocomplete() {
+	w := suite.makeGETRequest("/v1/utils/autocomplete?q=P&city=1")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.AutocompleteResponse
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Suggestions, 2)
+	assert.Equal(suite.T(), "pizza", response.Suggestions[0].Text)
+	assert.Equal(suite.T(), services.SuggestionQuery, response.Suggestions[0].Type)
+
+	// Word prefixes match venue names
+	w = suite.makeGETRequest("/v1/utils/autocomplete?q=restaurant&city=1")
+	suite.parseJSONResponse(w, &response)
+	suite.Require().Len(response.Suggestions, 2)
+	assert.Equal(suite.T(), services.SuggestionVenue, response.Suggestions[0].Type)
+	assert.Equal(suite.T(), "Test Restaurant 1", response.Suggestions[0].Text, "Higher rated venue ranks first")
+	assert.Equal(suite.T(), "test-restaurant-1", response.Suggestions[0].Slug)
+
+	// Venues of other cities are filtered out
+	w = suite.makeGETRequest("/v1/utils/autocomplete?q=test&city=2")
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Suggestions, 0)
+
+	w = suite.makeGETRequest("/v1/utils/autocomplete?q=test&limit=1")
+	suite.parseJSONResponse(w, &response)
+	assert.Len(suite.T(), response.Suggestions, 1)
+
+	w = suite.makeGETRequest("/v1/utils/autocomplete?q=")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeltaSync tests that clients get the venues, reviews and campaigns
+// changed since their cursor, and tombstones for the removed ones
+func (suite *TestSuite) TestDeltaSync() {
+	suite.Run("Delta Sync End-to-End", func() {
+		cursor := suite.testSyncEverything()
+		suite.testSyncChanges(cursor)
+		suite.testSyncLateCommit()
+		suite.testSyncHoldBackLimit()
+		suite.testSyncValidation()
+	})
+}
+
+// testSyncEverything pages through all changes from the beginning and
+// returns the cursor to sync from next
+func (suite *TestSuite) testSyncEverything() string {
+	venues := make(map[int64]bool)
+	cursor := ""
+	for i := 0; i < 50; i++ {
+		response := suite.sync("/v1/sync?limit=2&cursor=" + url.QueryEscape(cursor))
+		for _, venue := range response.Venues {
+			venues[venue.ID] = true
+		}
+		suite.Require().NotEmpty(response.Cursor)
+		cursor = response.Cursor
+		if !response.HasMore {
+			break
+		}
+	}
+	assert.True(suite.T(), venues[1])
+	assert.True(suite.T(), venues[2])
+
+	response := suite.sync("/v1/sync?cursor=" + url.QueryEscape(cursor))
+	assert.Empty(suite.T(), response.Venues)
+	assert.Empty(suite.T(), response.Reviews)
+	assert.Empty(suite.T(), response.Campaigns)
+	assert.Empty(suite.T(), response.Deleted)
+	assert.False(suite.T(), response.HasMore)
+	assert.Equal(suite.T(), cursor, response.Cursor)
+	return cursor
+}
+
+func (suite *TestSuite) testSyncChanges(cursor string) {
+	_, err := suite.db.Exec(`UPDATE venues SET name = 'Renamed Restaurant', updated_at = CURRENT_TIMESTAMP WHERE id = 2`)
+	suite.Require().NoError(err)
+
+	var approvedID, pendingID int64
+	err = suite.db.QueryRow(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+		VALUES (1, 1, 5, 'approved') RETURNING id`).Scan(&approvedID)
+	suite.Require().NoError(err)
+	err = suite.db.QueryRow(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+		VALUES (1, 2, 2, 'pending') RETURNING id`).Scan(&pendingID)
+	suite.Require().NoError(err)
+
+	now := time.Now()
+	_, err = suite.db.Exec(`INSERT INTO voting_campaigns
+		(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+		VALUES (65, 'Synced Campaign', 'best_restaurant', 1, $1, $2, 3, true)`,
+		now.Add(-time.Hour), now.Add(24*time.Hour))
+	suite.Require().NoError(err)
+
+	_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active)
+		VALUES (3, 'Closing Restaurant', 'closing-restaurant', '789 Test Blvd, San Francisco, CA', 1, 37.77, -122.41, 1, true)`)
+	suite.Require().NoError(err)
+	venue := models.Venue{ID: 3}
+	suite.Require().NoError(venue.Delete(context.Background()))
+
+	response := suite.sync("/v1/sync?city=1&cursor=" + url.QueryEscape(cursor))
+	assert.False(suite.T(), response.HasMore)
+	assert.NotEqual(suite.T(), cursor, response.Cursor)
+
+	suite.Require().Len(response.Venues, 1)
+	assert.Equal(suite.T(), int64(2), response.Venues[0].ID)
+	assert.Equal(suite.T(), "Renamed Restaurant", response.Venues[0].Name)
+
+	suite.Require().Len(response.Reviews, 1)
+	assert.Equal(suite.T(), approvedID, response.Reviews[0].ID)
+
+	suite.Require().Len(response.Campaigns, 1)
+	assert.Equal(suite.T(), int64(65), response.Campaigns[0].ID)
+
+	deleted := make(map[string]int64)
+	for _, tombstone := range response.Deleted {
+		deleted[tombstone.Type] = tombstone.ID
+	}
+	assert.Len(suite.T(), response.Deleted, 2)
+	assert.Equal(suite.T(), int64(3), deleted[models.SyncVenue])
+	assert.Equal(suite.T(), pendingID, deleted[models.SyncReview])
+
+	// Other cities see none of it
+	response = suite.sync("/v1/sync?city=999&cursor=" + url.QueryEscape(cursor))
+	assert.Empty(suite.T(), response.Venues)
+	assert.Empty(suite.T(), response.Reviews)
+	assert.Empty(suite.T(), response.Campaigns)
+	assert.Empty(suite.T(), response.Deleted)
+}
+
+// testSyncLateCommit tests that a change committed after later ones isn't
+// passed by the cursor
+func (suite *TestSuite) testSyncLateCommit() {
+	cursor := suite.testSyncEverything()
+
+	tx, err := suite.db.Begin()
+	suite.Require().NoError(err)
+	defer tx.Rollback()
+	_, err = tx.Exec(`UPDATE venues SET name = 'Slow Restaurant', updated_at = CURRENT_TIMESTAMP WHERE id = 1`)
+	suite.Require().NoError(err)
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = suite.db.Exec(`UPDATE venues SET name = 'Quick Restaurant', updated_at = CURRENT_TIMESTAMP WHERE id = 2`)
+	suite.Require().NoError(err)
+
+	// The committed change waits for the older transaction
+	response := suite.sync("/v1/sync?cursor=" + url.QueryEscape(cursor))
+	assert.Empty(suite.T(), response.Venues)
+	assert.Equal(suite.T(), cursor, response.Cursor)
+
+	suite.Require().NoError(tx.Commit())
+	response = suite.sync("/v1/sync?cursor=" + url.QueryEscape(cursor))
+	names := make(map[int64]string)
+	for _, venue := range response.Venues {
+		names[venue.ID] = venue.Name
+	}
+	assert.Equal(suite.T(), "Slow Restaurant", names[1])
+	assert.Equal(suite.T(), "Quick Restaurant", names[2])
+}
+
+// testSyncHoldBackLimit tests that a transaction left open holds changes
+// back for a limited time only
+func (suite *TestSuite) testSyncHoldBackLimit() {
+	cursor := suite.testSyncEverything()
+
+	tx, err := suite.db.Begin()
+	suite.Require().NoError(err)
+	defer tx.Rollback()
+	_, err = tx.Exec(`UPDATE venues SET name = 'Forgotten Restaurant' WHERE id = 1`)
+	suite.Require().NoError(err)
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = suite.db.Exec(`UPDATE venues SET name = 'Listed Restaurant', updated_at = CURRENT_TIMESTAMP WHERE id = 2`)
+	suite.Require().NoError(err)
+
+	response := suite.sync("/v1/sync?cursor=" + url.QueryEscape(cursor))
+	assert.Empty(suite.T(), response.Venues)
+
+	defer func(holdBack time.Duration) { models.SyncMaxHoldBack = holdBack }(models.SyncMaxHoldBack)
+	models.SyncMaxHoldBack = 0
+	response = suite.sync("/v1/sync?cursor=" + url.QueryEscape(cursor))
+	suite.Require().Len(response.Venues, 1)
+	assert.Equal(suite.T(), "Listed Restaurant", response.Venues[0].Name)
+}
+
+func (suite *TestSuite) testSyncValidation() {
+	w := suite.makeGETRequest("/v1/sync?cursor=not-a-cursor")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makeGETRequest("/v1/sync?limit=501")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	since := time.Now().Add(-31 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	w = suite.makeGETRequest("/v1/sync?since=" + url.QueryEscape(since))
+	suite.Require().Equal(http.StatusGone, w.Code)
+	var errorResponse serializers.Base
+	suite.parseJSONResponse(w, &errorResponse)
+	assert.Equal(suite.T(), serializers.SyncExpired, errorResponse.Code)
+
+	// A long poll without changes answers once the wait is over
+	since = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	start := time.Now()
+	response := suite.sync("/v1/sync?wait=1&since=" + url.QueryEscape(since))
+	assert.Less(suite.T(), time.Since(start), 5*time.Second)
+	assert.Empty(suite.T(), response.Venues)
+}
+
+func (suite *TestSuite) sync(path string) serializers.SyncResponse {
+	w := suite.makeGETRequest(path)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(suite.T(), "no-store", w.Header().Get("Cache-Control"))
+
+	var response serializers.SyncResponse
+	suite.parseJSONResponse(w, &response)
+	return response
+}
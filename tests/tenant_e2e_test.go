@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTenants tests resolving the tenant of requests and scoping venues to
+// it
+func (suite *TestSuite) TestTenants() {
+	suite.Run("Tenants", func() {
+		tenantService := &services.TenantService{}
+		cityID := int64(1)
+		tenant := &models.Tenant{
+			Slug:          "brand",
+			Name:          "Brand Eats",
+			Hostnames:     []string{"brand.example.com"},
+			Branding:      models.TenantBranding{AppName: "Brand Eats", PrimaryColor: "#ff5500"},
+			DefaultCityID: &cityID,
+			IsActive:      true,
+		}
+		suite.Require().NoError(tenantService.CreateTenant(context.Background(), tenant))
+		assert.Equal(suite.T(), models.ErrTenantExists,
+			tenantService.CreateTenant(context.Background(), &models.Tenant{Slug: "brand", Name: "Copy"}))
+
+		var venueID int64
+		err := suite.db.QueryRow(`INSERT INTO venues (name, slug, address, city_id, latitude, longitude, category_id, tenant_id, average_rating)
+			VALUES ('Brand Bistro', 'brand-bistro', '1 Brand St', 1, 37.7750, -122.4195, 1, $1, 4.5) RETURNING id`,
+			tenant.ID).Scan(&venueID)
+		suite.Require().NoError(err)
+
+		// The host picks the tenant, and the X-Tenant header overrides it
+		w := suite.makeTenantGETRequest("/v1/tenant", "brand.example.com:443", "")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var config serializers.TenantConfigResponse
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &config))
+		assert.Equal(suite.T(), "brand", config.Slug)
+		assert.Equal(suite.T(), "#ff5500", config.Branding.PrimaryColor)
+		suite.Require().NotNil(config.DefaultCityID)
+		assert.Equal(suite.T(), cityID, *config.DefaultCityID)
+
+		w = suite.makeTenantGETRequest("/v1/tenant", "unclaimed.example.com", "")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &config))
+		assert.Equal(suite.T(), "default", config.Slug)
+
+		w = suite.makeTenantGETRequest("/v1/tenant", "unclaimed.example.com", "BRAND")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &config))
+		assert.Equal(suite.T(), "brand", config.Slug)
+
+		w = suite.makeTenantGETRequest("/v1/tenant", "brand.example.com", "missing")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Venues are only found within their tenant
+		venueURL := fmt.Sprintf("/v1/venues/%d", venueID)
+		assert.Equal(suite.T(), http.StatusOK, suite.makeTenantGETRequest(venueURL, "brand.example.com", "").Code)
+		assert.Equal(suite.T(), http.StatusNotFound, suite.makeGETRequest(venueURL).Code)
+		assert.Equal(suite.T(), http.StatusNotFound,
+			suite.makeTenantGETRequest(fmt.Sprintf("/v1/venues/%d", suite.testData.TestVenue1.ID), "brand.example.com", "").Code)
+
+		searchIDs := func(host string) []int64 {
+			w := suite.makeTenantGETRequest("/v1/venues/search?city=1", host, "")
+			suite.Require().Equal(http.StatusOK, w.Code)
+			var response serializers.VenueSearchResponse
+			suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+			ids := make([]int64, 0, len(response.Venues))
+			for _, venue := range response.Venues {
+				ids = append(ids, venue.ID)
+			}
+			return ids
+		}
+		assert.Equal(suite.T(), []int64{venueID}, searchIDs("brand.example.com"))
+		defaultIDs := searchIDs("localhost")
+		assert.NotContains(suite.T(), defaultIDs, venueID)
+		assert.Contains(suite.T(), defaultIDs, suite.testData.TestVenue1.ID)
+
+		// Feeds and autocomplete only list the venues of the tenant
+		feedIDs := func(host string) []int64 {
+			w := suite.makeTenantGETRequest("/v1/feeds/venues.json?city=1", host, "")
+			suite.Require().Equal(http.StatusOK, w.Code)
+			var feed services.VenuesFeed
+			suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &feed))
+			ids := make([]int64, 0, len(feed.Venues))
+			for _, venue := range feed.Venues {
+				ids = append(ids, venue.ID)
+			}
+			return ids
+		}
+		assert.Equal(suite.T(), []int64{venueID}, feedIDs("brand.example.com"))
+		assert.NotContains(suite.T(), feedIDs("localhost"), venueID)
+
+		suggestionService := &services.SearchSuggestionService{}
+		suite.Require().NoError(suggestionService.RefreshSuggestions(context.Background()))
+		autocomplete := func(host string) []services.AutocompleteSuggestion {
+			w := suite.makeTenantGETRequest("/v1/utils/autocomplete?q=brand", host, "")
+			suite.Require().Equal(http.StatusOK, w.Code)
+			var response serializers.AutocompleteResponse
+			suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Suggestions
+		}
+		suite.Require().Len(autocomplete("brand.example.com"), 1)
+		assert.Equal(suite.T(), venueID, autocomplete("brand.example.com")[0].VenueID)
+		assert.Empty(suite.T(), autocomplete("localhost"))
+
+		// Recommendations only suggest the venues of the tenant
+		recommendedIDs := func(host string) []int64 {
+			w := suite.makeTenantGETRequest("/v1/discover/test_user_1/recommendations", host, "")
+			suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+			var response serializers.RecommendationsResponse
+			suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+			ids := make([]int64, 0, len(response.Recommendations))
+			for _, recommendation := range response.Recommendations {
+				ids = append(ids, recommendation.Venue.ID)
+			}
+			return ids
+		}
+		assert.Equal(suite.T(), []int64{venueID}, recommendedIDs("brand.example.com"))
+		defaultIDs = recommendedIDs("localhost")
+		assert.NotContains(suite.T(), defaultIDs, venueID)
+		assert.Contains(suite.T(), defaultIDs, suite.testData.TestVenue1.ID)
+
+		// and rank venues among the tenant's venues
+		analyticsService := &services.AnalyticsService{}
+		brandCtx := models.WithTenant(context.Background(), tenant.ID)
+		competitors, err := analyticsService.GetVenueCompetitors(brandCtx, venueID, 10)
+		suite.Require().NoError(err)
+		suite.Require().Len(competitors, 1)
+		assert.Equal(suite.T(), venueID, competitors[0].VenueID)
+		competitors, err = analyticsService.GetVenueCompetitors(brandCtx, suite.testData.TestVenue1.ID, 10)
+		suite.Require().NoError(err)
+		assert.Empty(suite.T(), competitors)
+		topVenues, err := analyticsService.GetTopPerformingVenues(brandCtx, "month", nil, &cityID, 10)
+		suite.Require().NoError(err)
+		for _, venue := range topVenues {
+			assert.Equal(suite.T(), venueID, venue.VenueID)
+		}
+		_, err = analyticsService.GetVenueAnalytics(brandCtx, suite.testData.TestVenue1.ID, "month")
+		assert.Error(suite.T(), err)
+
+		// Deactivated tenants stop resolving
+		tenant.IsActive = false
+		suite.Require().NoError(tenantService.UpdateTenant(context.Background(), tenant))
+		w = suite.makeTenantGETRequest("/v1/tenant", "brand.example.com", "")
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &config))
+		assert.Equal(suite.T(), "default", config.Slug)
+		assert.Equal(suite.T(), http.StatusNotFound, suite.makeTenantGETRequest("/v1/tenant", "", "brand").Code)
+	})
+}
+
+// makeTenantGETRequest makes a GET request to the host, selecting the
+// tenant by slug when set
+func (suite *TestSuite) makeTenantGETRequest(url, host, slug string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Host = host
+	if slug != "" {
+		req.Header.Set("X-Tenant", slug)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
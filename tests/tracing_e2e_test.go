@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/middlewares"
+	"voting-app/app/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTracing tests the request spans and the spans of the recommendation
+// phases
+func (suite *TestSuite) TestTracing() {
+	suite.Run("Tracing", func() {
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+
+		router := gin.New()
+		router.Use(middlewares.Tracing())
+		router.GET("/recommendations/:user_id", func(c *gin.Context) {
+			engine := &services.RecommendationEngine{}
+			rc := services.RecommendationContext{UserID: 1, MaxDistance: 10, Limit: 10}
+			if _, err := engine.GetPersonalizedRecommendations(c.Request.Context(), rc); err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recommendations/1", nil))
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+
+		server, exists := spans["GET /recommendations/:user_id"]
+		suite.Require().True(exists)
+		assert.Equal(suite.T(), trace.SpanKindServer, server.SpanKind())
+
+		engine, exists := spans["RecommendationEngine.GetPersonalizedRecommendations"]
+		suite.Require().True(exists)
+		assert.Equal(suite.T(), server.SpanContext().SpanID(), engine.Parent().SpanID())
+
+		for _, phase := range []string{"recommendations.preferences", "recommendations.candidates",
+			"recommendations.weights", "recommendations.scoring"} {
+			span, exists := spans[phase]
+			if assert.True(suite.T(), exists, phase) {
+				assert.Equal(suite.T(), engine.SpanContext().SpanID(), span.Parent().SpanID(), phase)
+			}
+		}
+
+		// Incoming trace context is continued
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		req := httptest.NewRequest(http.MethodGet, "/recommendations/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req.WithContext(context.Background()))
+		suite.Require().NotEmpty(recorder.Ended())
+		for _, span := range recorder.Ended() {
+			assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		}
+	})
+}
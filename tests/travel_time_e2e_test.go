@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"voting-app/app/controllers"
+	"voting-app/app/models"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTravelTimer times the trips to the destinations it knows by latitude,
+// counting the destinations it was asked for
+type stubTravelTimer struct {
+	seconds map[float64]float64
+	asked   int
+}
+
+func (s *stubTravelTimer) Name() string {
+	return "stub"
+}
+
+func (s *stubTravelTimer) Durations(ctx context.Context, mode string, origin services.LatLng, destinations []services.LatLng) ([]*float64, error) {
+	durations := make([]*float64, len(destinations))
+	for i, destination := range destinations {
+		if seconds, ok := s.seconds[destination.Latitude]; ok {
+			durations[i] = &seconds
+		}
+	}
+	s.asked += len(destinations)
+	return durations, nil
+}
+
+// TestTravelTimes tests ordering nearby venues and scoring recommendations by
+// trip time
+func (suite *TestSuite) TestTravelTimes() {
+	suite.Run("Travel Times", func() {
+		previous := services.TravelTimer
+		defer func() {
+			services.TravelTimer = previous
+			services.ClearTravelTimeCache()
+		}()
+		services.TravelTimer = nil
+		services.ClearTravelTimeCache()
+
+		// Venue 2 is at the origin, venue 1 is a bit more than a km away
+		const nearbyURL = "/v1/venues/nearby?lat=37.7749&lng=-122.4194&travel_mode=walking"
+
+		// Without a backend venues are ordered by distance
+		w := suite.makeGETRequest(nearbyURL)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(suite.T(), w.Header().Get(controllers.TravelModeHeader))
+		var nearby []models.Venue
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &nearby))
+		suite.Require().Len(nearby, 2)
+		assert.Equal(suite.T(), int64(2), nearby[0].ID)
+		assert.Nil(suite.T(), nearby[0].TravelMinutes)
+
+		// The closest venue is across a highway
+		stub := &stubTravelTimer{seconds: map[float64]float64{
+			suite.testData.TestVenue1.Latitude: 600,
+			suite.testData.TestVenue2.Latitude: 1800,
+		}}
+		services.TravelTimer = stub
+
+		w = suite.makeGETRequest(nearbyURL)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(suite.T(), services.TravelModeWalking, w.Header().Get(controllers.TravelModeHeader))
+		nearby = nil
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &nearby))
+		suite.Require().Len(nearby, 2)
+		assert.Equal(suite.T(), int64(1), nearby[0].ID)
+		suite.Require().NotNil(nearby[0].TravelMinutes)
+		assert.Equal(suite.T(), 10.0, *nearby[0].TravelMinutes)
+		assert.Equal(suite.T(), 30.0, *nearby[1].TravelMinutes)
+		assert.NotNil(suite.T(), nearby[0].Distance)
+
+		// Trips are timed once
+		w = suite.makeGETRequest(nearbyURL + "&limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		nearby = nil
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &nearby))
+		suite.Require().Len(nearby, 1)
+		assert.Equal(suite.T(), int64(1), nearby[0].ID)
+		assert.Equal(suite.T(), 2, stub.asked)
+
+		// Unreachable venues are left out
+		delete(stub.seconds, suite.testData.TestVenue2.Latitude)
+		services.ClearTravelTimeCache()
+		w = suite.makeGETRequest(nearbyURL)
+		nearby = nil
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &nearby))
+		suite.Require().Len(nearby, 1)
+		assert.Equal(suite.T(), int64(1), nearby[0].ID)
+
+		w = suite.makeGETRequest("/v1/venues/nearby?lat=37.7749&lng=-122.4194&travel_mode=cycling")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Recommendations score the location by trip time
+		stub.seconds[suite.testData.TestVenue2.Latitude] = 1800
+		services.ClearTravelTimeCache()
+		lat, lng := 37.7749, -122.4194
+		engine := &services.RecommendationEngine{}
+		recommendations, err := engine.GetPersonalizedRecommendations(context.Background(), services.RecommendationContext{
+			UserID: 1, UserLat: &lat, UserLng: &lng, MaxDistance: 10, Limit: 10, TravelMode: services.TravelModeWalking,
+		})
+		suite.Require().NoError(err)
+		suite.Require().Len(recommendations, 2)
+		for _, recommendation := range recommendations {
+			suite.Require().NotNil(recommendation.Venue.TravelMinutes)
+			if recommendation.Venue.ID == 1 {
+				assert.Contains(suite.T(), recommendation.Signals, services.SignalNearby)
+			} else {
+				assert.NotContains(suite.T(), recommendation.Signals, services.SignalNearby)
+			}
+		}
+	})
+}
+
+// TestOSRMBackend tests reading the duration table of an OSRM server
+func (suite *TestSuite) TestOSRMBackend() {
+	suite.Run("OSRM Backend", func() {
+		var requested string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = r.URL.String()
+			fmt.Fprint(w, `{"code":"Ok","durations":[[0,420.5,null]]}`)
+		}))
+		defer server.Close()
+
+		backend := &services.OSRMBackend{URL: server.URL}
+		durations, err := backend.Durations(context.Background(), services.TravelModeDriving,
+			services.LatLng{Latitude: 37.7749, Longitude: -122.4194},
+			[]services.LatLng{{Latitude: 37.7849, Longitude: -122.4094}, {Latitude: 37.8, Longitude: -122.5}})
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), "/table/v1/car/-122.419400,37.774900;-122.409400,37.784900;-122.500000,37.800000?sources=0&annotations=duration", requested)
+		suite.Require().Len(durations, 2)
+		suite.Require().NotNil(durations[0])
+		assert.Equal(suite.T(), 420.5, *durations[0])
+		assert.Nil(suite.T(), durations[1])
+
+		_, err = backend.Durations(context.Background(), "cycling", services.LatLng{}, []services.LatLng{{}})
+		assert.Error(suite.T(), err)
+	})
+}
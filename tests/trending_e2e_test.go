@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrendingCache tests the cached trending lists per city and category
+func (suite *TestSuite) TestTrendingCache() {
+	suite.Run("Trending Cache", func() {
+		_, err := suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id, created_at) VALUES
+			(1, 1, CURRENT_TIMESTAMP - INTERVAL '1 day'),
+			(1, 2, CURRENT_TIMESTAMP - INTERVAL '2 days'),
+			(2, 1, CURRENT_TIMESTAMP - INTERVAL '10 days')`)
+		suite.Require().NoError(err)
+
+		trendingService := &services.TrendingService{}
+		suite.Require().NoError(trendingService.RefreshTrending(context.Background()))
+
+		cityURL := fmt.Sprintf("/v1/discover/trending?city=%d&category=%d",
+			suite.testData.TestCity.ID, suite.testData.TestCategory.ID)
+		w := suite.makeGETRequest(cityURL)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		var trending serializers.TrendingVenuesResponse
+		suite.parseJSONResponse(w, &trending)
+		assert.Empty(suite.T(), trending.LocationSource)
+		assert.False(suite.T(), trending.ComputedAt.IsZero())
+		// Venue 2 was only active before the trending window
+		suite.Require().Len(trending.Venues, 1)
+		assert.Equal(suite.T(), int64(1), trending.Venues[0].ID)
+		assert.Nil(suite.T(), trending.Venues[0].Distance)
+
+		// New activity shows once the lists are recomputed
+		_, err = suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id) VALUES (2, 1), (2, 2)`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, moderation_status)
+			VALUES (2, 1, 5, 'approved')`)
+		suite.Require().NoError(err)
+
+		w = suite.makeGETRequest(cityURL)
+		trending = serializers.TrendingVenuesResponse{}
+		suite.parseJSONResponse(w, &trending)
+		suite.Require().Len(trending.Venues, 1)
+
+		suite.Require().NoError(trendingService.RefreshTrending(context.Background()))
+		w = suite.makeGETRequest(cityURL)
+		trending = serializers.TrendingVenuesResponse{}
+		suite.parseJSONResponse(w, &trending)
+		suite.Require().Len(trending.Venues, 2)
+		assert.Equal(suite.T(), int64(2), trending.Venues[0].ID)
+
+		// Other categories have nothing trending
+		w = suite.makeGETRequest(fmt.Sprintf("/v1/discover/trending?city=%d&category=%d",
+			suite.testData.TestCity.ID, suite.testData.TestCategory.ID+1000))
+		suite.Require().Equal(http.StatusOK, w.Code)
+		trending = serializers.TrendingVenuesResponse{}
+		suite.parseJSONResponse(w, &trending)
+		assert.Empty(suite.T(), trending.Venues)
+
+		// Coordinates locate the venues of the city
+		w = suite.makeGETRequest(cityURL + "&lat=37.7749&lng=-122.4194&radius=100&limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		trending = serializers.TrendingVenuesResponse{}
+		suite.parseJSONResponse(w, &trending)
+		assert.Equal(suite.T(), services.LocationSourceGPS, trending.LocationSource)
+		suite.Require().Len(trending.Venues, 1)
+		assert.NotNil(suite.T(), trending.Venues[0].Distance)
+
+		w = suite.makeGETRequest("/v1/discover/trending?city=-1")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
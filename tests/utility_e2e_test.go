@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUtilityEndpoints tests the utility APIs
+func (suite *TestSuite) TestUtilityEndpoints() {
+	suite.Run("Utility Endpoints", func() {
+		suite.testMeetingPoint()
+		suite.testMeetingPointValidation()
+		suite.testVenueDistances()
+	})
+}
+
+func (suite *TestSuite) testMeetingPoint() {
+	w := suite.makePOSTRequest("/v1/utils/meeting-point", map[string]interface{}{
+		"participants": []map[string]interface{}{
+			{"name": "Alex", "latitude": 37.7849, "longitude": -122.4094},
+			{"name": "Sam", "latitude": 37.7649, "longitude": -122.4294},
+			{"name": "Kim", "latitude": 37.7749, "longitude": -122.4194},
+		},
+		"preferences": map[string]interface{}{
+			"categoryId": 1,
+			"limit":      5,
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.MeetingPointResponse
+	suite.parseJSONResponse(w, &response)
+
+	// Centroid is the average of the participant locations
+	suite.Require().NotNil(response.Centroid)
+	assert.InDelta(suite.T(), 37.7749, response.Centroid.Latitude, 0.0001)
+	assert.InDelta(suite.T(), -122.4194, response.Centroid.Longitude, 0.0001)
+
+	suite.Require().Len(response.Participants, 3)
+	assert.Equal(suite.T(), "Alex", response.Participants[0].Name)
+	assert.InDelta(suite.T(), 0.0, response.Participants[2].DistanceKm, 0.01)
+
+	// Venues are sorted by combined travel distance with per participant distances
+	suite.Require().Len(response.Venues, 2)
+	for _, venue := range response.Venues {
+		assert.Len(suite.T(), venue.ParticipantDistances, 3)
+	}
+	assert.True(suite.T(), response.Venues[0].TotalDistanceKm <= response.Venues[1].TotalDistanceKm)
+	assert.Equal(suite.T(), int64(2), response.Venues[0].Venue.ID) // Venue 2 sits on the centroid
+}
+
+func (suite *TestSuite) testMeetingPointValidation() {
+	// A single participant is not a group
+	w := suite.makePOSTRequest("/v1/utils/meeting-point", map[string]interface{}{
+		"participants": []map[string]interface{}{
+			{"latitude": 37.7849, "longitude": -122.4094},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// More than 10 participants is rejected
+	participants := make([]map[string]interface{}, 11)
+	for i := range participants {
+		participants[i] = map[string]interface{}{"latitude": 37.77, "longitude": -122.41}
+	}
+	w = suite.makePOSTRequest("/v1/utils/meeting-point", map[string]interface{}{
+		"participants": participants,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Out of range coordinates
+	w = suite.makePOSTRequest("/v1/utils/meeting-point", map[string]interface{}{
+		"participants": []map[string]interface{}{
+			{"latitude": 95, "longitude": -122.4094},
+			{"latitude": 37.7649, "longitude": -122.4294},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testVenueDistances() {
+	// Venue 1 is open around the clock, venue 2 has no opening hours
+	_, err := suite.db.Exec(`UPDATE venues SET opening_hours = (
+		SELECT jsonb_object_agg(day, '{"open": "00:00", "close": "00:00"}'::jsonb)
+		FROM unnest(ARRAY['monday','tuesday','wednesday','thursday','friday','saturday','sunday']) AS day
+	) WHERE id = 1`)
+	suite.Require().NoError(err)
+
+	w := suite.makePOSTRequest("/v1/utils/distances", map[string]interface{}{
+		"lat":      37.7749,
+		"lng":      -122.4194,
+		"venueIds": []int64{2, 1, 999, 2},
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response serializers.DistancesResponse
+	suite.parseJSONResponse(w, &response)
+
+	// Distances keep the request order, unknown venues are reported separately
+	suite.Require().Len(response.Distances, 2)
+	assert.Equal(suite.T(), int64(2), response.Distances[0].VenueID)
+	assert.InDelta(suite.T(), 0, response.Distances[0].DistanceKm, 0.01)
+	assert.Nil(suite.T(), response.Distances[0].IsOpen)
+
+	assert.Equal(suite.T(), int64(1), response.Distances[1].VenueID)
+	assert.InDelta(suite.T(), 1.41, response.Distances[1].DistanceKm, 0.1)
+	suite.Require().NotNil(response.Distances[1].IsOpen)
+	assert.True(suite.T(), *response.Distances[1].IsOpen)
+
+	assert.Equal(suite.T(), []int64{999}, response.NotFound)
+
+	tooMany := make([]int64, 201)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+	w = suite.makePOSTRequest("/v1/utils/distances", map[string]interface{}{
+		"lat":      37.7749,
+		"lng":      -122.4194,
+		"venueIds": tooMany,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/utils/distances", map[string]interface{}{
+		"lat":      137.7749,
+		"lng":      -122.4194,
+		"venueIds": []int64{1},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
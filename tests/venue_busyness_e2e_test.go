@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueBusyness tests wait reports sent at check-in and the live
+// busyness aggregated from them
+func (suite *TestSuite) TestVenueBusyness() {
+	suite.Run("Venue Busyness", func() {
+		// Stale reports and reports superseded by the same user don't count
+		_, err := suite.db.Exec(`
+			INSERT INTO venue_wait_reports (venue_id, user_id, wait_minutes, crowd_level, reported_at)
+			VALUES (1, 1, 90, 'packed', CURRENT_TIMESTAMP - INTERVAL '2 hours'),
+				   (1, 2, 0, 'quiet', CURRENT_TIMESTAMP - INTERVAL '30 minutes'),
+				   (1, 2, 10, 'busy', CURRENT_TIMESTAMP - INTERVAL '10 minutes'),
+				   (2, 1, 5, 'quiet', CURRENT_TIMESTAMP - INTERVAL '2 days')`)
+		suite.Require().NoError(err)
+
+		wait := 20
+		w := suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{
+			VenueID:     1,
+			Message:     "Long line tonight",
+			WaitMinutes: &wait,
+			CrowdLevel:  models.CrowdBusy,
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var checkin models.VenueCheckin
+		suite.parseJSONResponse(w, &checkin)
+		assert.True(suite.T(), checkin.IsPublic)
+		if assert.NotNil(suite.T(), checkin.WaitReport) {
+			assert.Equal(suite.T(), checkin.ID, *checkin.WaitReport.CheckinID)
+		}
+
+		// A check-in without a report leaves the busyness alone
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{VenueID: 2})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{
+			VenueID:    1,
+			CrowdLevel: "heaving",
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{VenueID: 999})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		if assert.NotNil(suite.T(), detail.Venue.Busyness) {
+			assert.Equal(suite.T(), models.CrowdBusy, detail.Venue.Busyness.Level)
+			assert.Equal(suite.T(), 2, detail.Venue.Busyness.Reports)
+			if assert.NotNil(suite.T(), detail.Venue.Busyness.WaitMinutes) {
+				assert.Equal(suite.T(), 15, *detail.Venue.Busyness.WaitMinutes)
+			}
+		}
+
+		w = suite.makeGETRequest("/v1/venues/nearby?lat=37.7749&lng=-122.4194&radius=10")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var venues []models.Venue
+		suite.parseJSONResponse(w, &venues)
+		suite.Require().NotEmpty(venues)
+		for _, venue := range venues {
+			if venue.ID == 1 {
+				assert.NotNil(suite.T(), venue.Busyness)
+			} else {
+				assert.Nil(suite.T(), venue.Busyness)
+			}
+		}
+
+		// Reports past their retention are aged out
+		busynessService := &services.BusynessService{}
+		suite.Require().NoError(busynessService.ExpireWaitReports(context.Background()))
+		var reports int
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM venue_wait_reports").Scan(&reports)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 4, reports)
+	})
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueCheckins tests the public check-ins of a venue, with photos
+// from the upload pipeline only
+func (suite *TestSuite) TestVenueCheckins() {
+	suite.Run("Venue Checkins", func() {
+		_, err := suite.db.Exec(`
+			INSERT INTO photos (user_id, url, object_key, content_type, width, height, size_bytes) VALUES
+			(1, '/v1/files/reviews/photos/own.jpg', 'reviews/photos/own.jpg', 'image/jpeg', 800, 600, 1000),
+			(2, '/v1/files/reviews/photos/other.jpg', 'reviews/photos/other.jpg', 'image/jpeg', 800, 600, 1000)`)
+		suite.Require().NoError(err)
+
+		_, err = suite.db.Exec(`
+			INSERT INTO venue_checkins (venue_id, user_id, message, photos, is_public, created_at) VALUES
+			(1, 1, 'Great brunch', '["/v1/files/reviews/photos/own.jpg", "/v1/files/reviews/photos/other.jpg", "https://example.com/raw.jpg"]', true, NOW() - INTERVAL '2 hours'),
+			(1, 2, 'Quiet evening', NULL, true, NOW() - INTERVAL '1 hour'),
+			(1, 2, 'Secret visit', NULL, false, NOW()),
+			(2, 1, 'Elsewhere', NULL, true, NOW())`)
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/venues/1/checkins")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var response serializers.VenueCheckinsResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 2, response.Pagination.Total)
+		suite.Require().Len(response.Checkins, 2)
+		assert.Equal(suite.T(), "Quiet evening", response.Checkins[0].Message)
+		assert.Empty(suite.T(), response.Checkins[0].Photos)
+
+		// Only the author's uploaded photos are shown
+		brunch := response.Checkins[1]
+		assert.Equal(suite.T(), "test_user_1", brunch.UserName)
+		if assert.Len(suite.T(), brunch.Photos, 1) {
+			assert.Equal(suite.T(), "/v1/files/reviews/photos/own.jpg", brunch.Photos[0].URL)
+			assert.Equal(suite.T(), 800, brunch.Photos[0].Width)
+		}
+
+		w = suite.makeGETRequest("/v1/venues/1/checkins?page=2&limit=1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		response = serializers.VenueCheckinsResponse{}
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Checkins, 1)
+		assert.Equal(suite.T(), "Great brunch", response.Checkins[0].Message)
+		assert.True(suite.T(), response.Pagination.HasPrev)
+		assert.False(suite.T(), response.Pagination.HasNext)
+
+		// Viewers don't see check-ins of users they blocked
+		_, err = suite.db.Exec("INSERT INTO user_blocks (user_id, target_user_id) VALUES (1, 2)")
+		suite.Require().NoError(err)
+		w = suite.makeGETRequest("/v1/venues/1/checkins?viewer=test_user_1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		response = serializers.VenueCheckinsResponse{}
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Checkins, 1)
+		assert.Equal(suite.T(), "Great brunch", response.Checkins[0].Message)
+
+		w = suite.makeGETRequest("/v1/venues/1/checkins?limit=500")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makeGETRequest("/v1/venues/999/checkins")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
+
+// TestDuplicateCheckins tests that checking in again at a venue shortly
+// after returns the previous check-in
+func (suite *TestSuite) TestDuplicateCheckins() {
+	suite.Run("Duplicate Checkins", func() {
+		w := suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{
+			VenueID: 1,
+			Message: "Brunch time",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var first models.VenueCheckin
+		suite.parseJSONResponse(w, &first)
+		assert.False(suite.T(), first.Deduplicated)
+
+		// The duplicate's wait report still counts, on the first check-in
+		wait := 15
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{
+			VenueID:     1,
+			Message:     "Brunch time!",
+			WaitMinutes: &wait,
+		})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var duplicate models.VenueCheckin
+		suite.parseJSONResponse(w, &duplicate)
+		assert.True(suite.T(), duplicate.Deduplicated)
+		assert.Equal(suite.T(), first.ID, duplicate.ID)
+		assert.Equal(suite.T(), "Brunch time", duplicate.Message)
+		if assert.NotNil(suite.T(), duplicate.WaitReport) {
+			assert.Equal(suite.T(), first.ID, *duplicate.WaitReport.CheckinID)
+		}
+
+		// Other venues and other users check in as usual
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", serializers.CreateCheckinRequest{VenueID: 2})
+		assert.Equal(suite.T(), http.StatusCreated, w.Code)
+		other := &models.VenueCheckin{VenueID: 1, UserID: 2, IsPublic: true}
+		suite.Require().NoError(other.Create(context.Background(), 2*time.Hour))
+		assert.False(suite.T(), other.Deduplicated)
+
+		var count int
+		suite.Require().NoError(suite.db.QueryRow(
+			"SELECT COUNT(*) FROM venue_checkins WHERE venue_id = 1 AND user_id = 1").Scan(&count))
+		assert.Equal(suite.T(), 1, count)
+
+		// Check-ins older than the window don't count
+		_, err := suite.db.Exec("UPDATE venue_checkins SET created_at = CURRENT_TIMESTAMP - INTERVAL '3 hours' WHERE id = $1", first.ID)
+		suite.Require().NoError(err)
+		checkin := &models.VenueCheckin{VenueID: 1, UserID: 1, IsPublic: true}
+		suite.Require().NoError(checkin.Create(context.Background(), 2*time.Hour))
+		assert.False(suite.T(), checkin.Deduplicated)
+		assert.NotEqual(suite.T(), first.ID, checkin.ID)
+
+		// A zero window disables deduplication
+		checkin = &models.VenueCheckin{VenueID: 1, UserID: 1, IsPublic: true}
+		suite.Require().NoError(checkin.Create(context.Background(), 0))
+		assert.False(suite.T(), checkin.Deduplicated)
+	})
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueCityAssignment tests that new venues are placed in the city their
+// coordinates are in
+func (suite *TestSuite) TestVenueCityAssignment() {
+	suite.Run("Venue City Assignment", func() {
+		_, err := suite.db.Exec(`INSERT INTO cities (id, name, state, country, latitude, longitude)
+			VALUES (60, 'Los Angeles', 'California', 'USA', 34.0522, -118.2437),
+			       (61, 'Oakland', 'California', 'USA', 37.8044, -122.2712)`)
+		suite.Require().NoError(err)
+
+		venueData := serializers.CreateVenueRequest{
+			Name:       "City Check Bistro",
+			Address:    "1 Mission St",
+			CityID:     1,
+			Latitude:   37.7649,
+			Longitude:  -122.4094,
+			CategoryID: 1,
+		}
+
+		// The submitted city is kept when the venue is in it
+		venue := suite.createVenueInCity(venueData)
+		assert.Equal(suite.T(), int64(1), venue.CityID)
+		assert.Equal(suite.T(), 0, suite.countCityCorrections(venue.ID))
+
+		// A wrong city is corrected and the correction recorded
+		venueData.Name = "Wrong City Bistro"
+		venueData.CityID = 60
+		venue = suite.createVenueInCity(venueData)
+		assert.Equal(suite.T(), int64(1), venue.CityID)
+		suite.Require().NotNil(venue.City)
+		assert.Equal(suite.T(), "San Francisco", venue.City.Name)
+
+		var claimedCityID, assignedCityID int64
+		var distanceKm float64
+		err = suite.db.QueryRow(`SELECT claimed_city_id, assigned_city_id, distance_km
+			FROM venue_city_corrections WHERE venue_id = $1`, venue.ID).Scan(&claimedCityID, &assignedCityID, &distanceKm)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), int64(60), claimedCityID)
+		assert.Equal(suite.T(), int64(1), assignedCityID)
+		assert.Less(suite.T(), distanceKm, 5.0)
+
+		// Without a city the nearest one is assigned
+		venueData.Name = "Lake Merritt Cafe"
+		venueData.CityID = 0
+		venueData.Latitude, venueData.Longitude = 37.8030, -122.2600
+		venue = suite.createVenueInCity(venueData)
+		assert.Equal(suite.T(), int64(61), venue.CityID)
+		assert.Equal(suite.T(), 0, suite.countCityCorrections(venue.ID))
+
+		// Coordinates far from the submitted city and any other are rejected
+		venueData.Name = "Sacramento Diner"
+		venueData.CityID = 1
+		venueData.Latitude, venueData.Longitude = 38.5816, -121.4944
+		w := suite.makePOSTRequest("/v1/venues", venueData)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.InvalidLocation, response.Code)
+
+		venueData.CityID = 0
+		w = suite.makePOSTRequest("/v1/venues", venueData)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		venueData.CityID = 999
+		w = suite.makePOSTRequest("/v1/venues", venueData)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
+
+func (suite *TestSuite) createVenueInCity(venueData serializers.CreateVenueRequest) models.Venue {
+	w := suite.makePOSTRequest("/v1/venues", venueData)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var venue models.Venue
+	suite.parseJSONResponse(w, &venue)
+	return venue
+}
+
+func (suite *TestSuite) countCityCorrections(venueID int64) int {
+	var count int
+	err := suite.db.QueryRow("SELECT COUNT(*) FROM venue_city_corrections WHERE venue_id = $1", venueID).Scan(&count)
+	suite.Require().NoError(err)
+	return count
+}
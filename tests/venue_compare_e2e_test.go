@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"net/http"
+	"time"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueComparison tests comparing venues side by side
+func (suite *TestSuite) TestVenueComparison() {
+	suite.Run("Venue Comparison End-to-End", func() {
+		now := time.Now()
+
+		_, err := suite.db.Exec(`INSERT INTO venue_reviews
+			(venue_id, user_id, overall_rating, detailed_ratings, review_text, moderation_status)
+			VALUES
+			(1, 1, 5.0, '{"food": 5, "service": 4}', 'Excellent pasta and friendly staff', 'approved'),
+			(1, 2, 4.0, '{"food": 4, "service": 3}', 'Good food', 'approved'),
+			(2, 1, 3.0, '{"food": 3, "ambiance": 5}', 'Great views, average food', 'approved'),
+			(2, 2, 1.0, '{"food": 1}', 'Not yet moderated', 'pending')`)
+		suite.Require().NoError(err)
+
+		evening := time.Date(now.Year(), now.Month(), now.Day(), 19, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+		_, err = suite.db.Exec(`INSERT INTO venue_checkins (venue_id, user_id, created_at)
+			VALUES (1, 1, $1), (1, 2, $1), (1, 1, $2), (2, 1, $3)`,
+			evening, evening.Add(-6*time.Hour), now.AddDate(0, -6, 0))
+		suite.Require().NoError(err)
+
+		w := suite.makeGETRequest("/v1/venues/compare?ids=2,1&lat=37.7849&lng=-122.4094")
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		var comparison services.VenueComparison
+		suite.parseJSONResponse(w, &comparison)
+
+		assert.Equal(suite.T(), []string{"ambiance", "food", "service"}, comparison.Dimensions)
+		suite.Require().Len(comparison.Venues, 2)
+
+		// Venues keep the requested order
+		second, first := comparison.Venues[0], comparison.Venues[1]
+		assert.Equal(suite.T(), int64(2), second.VenueID)
+		assert.Equal(suite.T(), int64(1), first.VenueID)
+
+		// Every venue has each dimension, null when it wasn't rated
+		suite.Require().NotNil(first.Ratings["food"])
+		assert.InDelta(suite.T(), 4.5, *first.Ratings["food"], 0.01)
+		assert.Nil(suite.T(), first.Ratings["ambiance"])
+		suite.Require().NotNil(second.Ratings["food"])
+		assert.InDelta(suite.T(), 3.0, *second.Ratings["food"], 0.01)
+		assert.Nil(suite.T(), second.Ratings["service"])
+
+		// Distances are measured from the given point
+		suite.Require().NotNil(first.DistanceKm)
+		suite.Require().NotNil(second.DistanceKm)
+		assert.InDelta(suite.T(), 0, *first.DistanceKm, 0.01)
+		assert.Greater(suite.T(), *second.DistanceKm, 1.0)
+
+		// Popular hours only count recent check-ins
+		assert.Len(suite.T(), first.PopularHours, 24)
+		assert.Equal(suite.T(), 2, first.PopularHours[19])
+		suite.Require().NotNil(first.PeakHour)
+		assert.Equal(suite.T(), 19, *first.PeakHour)
+		assert.Nil(suite.T(), second.PeakHour)
+
+		assert.Equal(suite.T(), "$$", first.PriceRange)
+		assert.NotEmpty(suite.T(), first.Highlights)
+
+		assert.Equal(suite.T(), int64(1), comparison.Best["food"])
+		assert.Equal(suite.T(), int64(2), comparison.Best["ambiance"])
+		assert.Equal(suite.T(), int64(1), comparison.Best["price"])
+		assert.Equal(suite.T(), int64(1), comparison.Best["distance"])
+
+		// Invalid selections
+		for _, ids := range []string{"1", "1,2,3,4,5", "1,1", "1,abc", ""} {
+			w = suite.makeGETRequest("/v1/venues/compare?ids=" + ids)
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, ids)
+		}
+
+		w = suite.makeGETRequest("/v1/venues/compare?ids=1,2&lat=91&lng=0")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makeGETRequest("/v1/venues/compare?ids=1,99999")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
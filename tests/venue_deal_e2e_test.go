@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueDeals tests owners running deals at their venues and users
+// redeeming them with their check-ins
+func (suite *TestSuite) TestVenueDeals() {
+	suite.Run("Venue Deals", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+		now := time.Now()
+		limit := 1
+
+		// Owners manage their venue's deals
+		w := suite.makePOSTRequest("/v1/owner/venues/1/deals", serializers.VenueDealRequest{
+			Title:       "  Two <b>for</b> one  ",
+			Description: "Every second drink is on us",
+			StartsAt:    now.Add(-24 * time.Hour),
+			EndsAt:      now.Add(7 * 24 * time.Hour),
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var deal models.VenueDeal
+		suite.parseJSONResponse(w, &deal)
+		assert.Equal(suite.T(), "Two for one", deal.Title)
+		assert.Nil(suite.T(), deal.RedemptionLimit)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/deals", serializers.VenueDealRequest{
+			Title:           "First guest free",
+			StartsAt:        now.Add(-24 * time.Hour),
+			EndsAt:          now.Add(24 * time.Hour),
+			RedemptionLimit: &limit,
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var limited models.VenueDeal
+		suite.parseJSONResponse(w, &limited)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/deals", serializers.VenueDealRequest{
+			Title:    "Next month",
+			StartsAt: now.Add(30 * 24 * time.Hour),
+			EndsAt:   now.Add(31 * 24 * time.Hour),
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var upcoming models.VenueDeal
+		suite.parseJSONResponse(w, &upcoming)
+
+		for _, request := range []serializers.VenueDealRequest{
+			{Title: "Ended", StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-24 * time.Hour)},
+			{Title: "Backwards", StartsAt: now.Add(48 * time.Hour), EndsAt: now.Add(24 * time.Hour)},
+			{Title: "Forever", StartsAt: now, EndsAt: now.Add(400 * 24 * time.Hour)},
+			{Title: "<i></i>", StartsAt: now, EndsAt: now.Add(time.Hour)},
+		} {
+			w = suite.makePOSTRequest("/v1/owner/venues/1/deals", request)
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, request.Title)
+		}
+		w = suite.makePOSTRequest("/v1/owner/venues/2/deals", serializers.VenueDealRequest{
+			Title: "Not mine", StartsAt: now, EndsAt: now.Add(time.Hour),
+		})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		w = suite.makeGETRequest("/v1/owner/venues/1/deals")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var deals serializers.VenueDealsResponse
+		suite.parseJSONResponse(w, &deals)
+		suite.Require().Len(deals.Deals, 3)
+		assert.Equal(suite.T(), upcoming.ID, deals.Deals[0].ID)
+
+		// Running deals are shown with the venue
+		w = suite.makeGETRequest("/v1/venues/1")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		suite.Require().Len(detail.Venue.Deals, 2)
+		assert.Equal(suite.T(), limited.ID, detail.Venue.Deals[0].ID)
+		assert.Equal(suite.T(), deal.ID, detail.Venue.Deals[1].ID)
+
+		w = suite.makeGETRequest("/v1/venues/nearby?lat=37.7749&lng=-122.4194&radius=10")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var nearby []models.Venue
+		suite.parseJSONResponse(w, &nearby)
+		for _, venue := range nearby {
+			if venue.ID == 1 {
+				assert.Len(suite.T(), venue.Deals, 2)
+			} else {
+				assert.Empty(suite.T(), venue.Deals)
+			}
+		}
+
+		// Deals are redeemed with a recent check-in at their venue
+		w = suite.makePOSTRequest("/v1/users/test_user_1/checkins", map[string]interface{}{"venueId": 1})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var checkin models.VenueCheckin
+		suite.parseJSONResponse(w, &checkin)
+		var otherCheckin, staleCheckin int64
+		suite.Require().NoError(suite.db.QueryRow(
+			"INSERT INTO venue_checkins (venue_id, user_id) VALUES (2, 1) RETURNING id").Scan(&otherCheckin))
+		suite.Require().NoError(suite.db.QueryRow(`INSERT INTO venue_checkins (venue_id, user_id, created_at)
+			VALUES (1, 1, CURRENT_TIMESTAMP - INTERVAL '3 hours') RETURNING id`).Scan(&staleCheckin))
+
+		redeem := func(dealID, checkinID int64) (int, serializers.Base) {
+			w := suite.makePOSTRequest(fmt.Sprintf("/v1/users/test_user_1/deals/%d/redeem", dealID),
+				serializers.RedeemDealRequest{CheckinID: checkinID})
+			var base serializers.Base
+			suite.parseJSONResponse(w, &base)
+			return w.Code, base
+		}
+		for _, checkinID := range []int64{otherCheckin, staleCheckin} {
+			code, base := redeem(deal.ID, checkinID)
+			assert.Equal(suite.T(), http.StatusBadRequest, code)
+			assert.Equal(suite.T(), serializers.CheckinRequired, base.Code)
+		}
+		code, base := redeem(upcoming.ID, checkin.ID)
+		assert.Equal(suite.T(), http.StatusBadRequest, code)
+		assert.Equal(suite.T(), serializers.DealUnavailable, base.Code)
+		code, _ = redeem(999999, checkin.ID)
+		assert.Equal(suite.T(), http.StatusNotFound, code)
+
+		w = suite.makePOSTRequest(fmt.Sprintf("/v1/users/test_user_1/deals/%d/redeem", limited.ID),
+			serializers.RedeemDealRequest{CheckinID: checkin.ID})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var redemption models.DealRedemption
+		suite.parseJSONResponse(w, &redemption)
+		assert.Equal(suite.T(), int64(1), redemption.VenueID)
+		assert.Equal(suite.T(), checkin.ID, redemption.CheckinID)
+
+		code, base = redeem(limited.ID, checkin.ID)
+		assert.Equal(suite.T(), http.StatusBadRequest, code)
+		assert.Equal(suite.T(), serializers.AlreadyRedeemed, base.Code)
+
+		// Fully redeemed deals are no longer shown or redeemable
+		var secondCheckin int64
+		suite.Require().NoError(suite.db.QueryRow(
+			"INSERT INTO venue_checkins (venue_id, user_id) VALUES (1, 2) RETURNING id").Scan(&secondCheckin))
+		_, err = models.RedeemDeal(context.Background(), limited.ID, 2, secondCheckin)
+		assert.Equal(suite.T(), models.ErrDealLimitReached, err)
+		active, err := models.GetActiveDeals(context.Background(), []int64{1})
+		suite.Require().NoError(err)
+		suite.Require().Len(active[1], 1)
+		assert.Equal(suite.T(), deal.ID, active[1][0].ID)
+
+		_, err = models.RedeemDeal(context.Background(), deal.ID, 2, secondCheckin)
+		suite.Require().NoError(err)
+
+		// Owners see the traffic each deal brought
+		w = suite.makeGETRequest("/v1/analytics/venues/1?time_range=week")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var analytics services.VenueAnalytics
+		suite.parseJSONResponse(w, &analytics)
+		assert.Equal(suite.T(), 2, analytics.DealRedemptions)
+		assert.ElementsMatch(suite.T(), []models.DealAttribution{
+			{DealID: deal.ID, Title: "Two for one", Redemptions: 1},
+			{DealID: limited.ID, Title: "First guest free", Redemptions: 1},
+		}, analytics.Deals)
+
+		// Updating keeps the redemptions
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/1/deals/%d", deal.ID), serializers.VenueDealRequest{
+			Title:    "Two for one, all week",
+			StartsAt: deal.StartsAt,
+			EndsAt:   deal.EndsAt,
+		})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var updated models.VenueDeal
+		suite.parseJSONResponse(w, &updated)
+		assert.Equal(suite.T(), "Two for one, all week", updated.Title)
+		assert.Empty(suite.T(), updated.Description)
+		assert.Equal(suite.T(), 1, updated.Redemptions)
+
+		w = suite.makePUTRequest("/v1/owner/venues/1/deals/999999", serializers.VenueDealRequest{
+			Title: "Missing", StartsAt: now, EndsAt: now.Add(time.Hour),
+		})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/1/deals/%d", upcoming.ID))
+		suite.Require().Equal(http.StatusOK, w.Code)
+		w = suite.makeDELETERequest(fmt.Sprintf("/v1/owner/venues/1/deals/%d", upcoming.ID))
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	})
+}
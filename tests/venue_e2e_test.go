@@ -118,6 +118,16 @@ func (suite *TestSuite) testGetVenueDetails() {
 	w = suite.makeGETRequest("/v1/venues/999")
 	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
 
+	// Test getting venue by slug, case insensitively
+	w = suite.makeGETRequest("/v1/venues/by-slug/Test-Restaurant-1?user_lat=37.7749&user_lng=-122.4194")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	venueResponse = serializers.VenueDetailResponse{}
+	suite.parseJSONResponse(w, &venueResponse)
+	assert.Equal(suite.T(), int64(1), venueResponse.Venue.ID)
+	assert.NotNil(suite.T(), venueResponse.ReviewSummary)
+	assert.NotNil(suite.T(), venueResponse.Venue.Distance)
+	assert.Nil(suite.T(), venueResponse.Redirect)
+
 	// Test invalid venue ID
 	w = suite.makeGETRequest("/v1/venues/invalid")
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
@@ -261,31 +271,38 @@ func (suite *TestSuite) TestVenueSearchEdgeCases() {
 		suite.parseJSONResponse(w, &searchResponse)
 		assert.Empty(suite.T(), searchResponse.Venues)
 
-		// Test search with invalid price range
-		w = suite.makeGETRequest("/v1/venues/search?price_range=invalid")
-		assert.Equal(suite.T(), http.StatusOK, w.Code) // Should not error, just filter out
-
 		// Test search with extreme coordinates
 		w = suite.makeGETRequest("/v1/venues/search?lat=90&lng=180&radius=1")
 		assert.Equal(suite.T(), http.StatusOK, w.Code)
 		suite.parseJSONResponse(w, &searchResponse)
 		assert.Empty(suite.T(), searchResponse.Venues) // No venues at North Pole
 
-		// Test search with negative radius
-		w = suite.makeGETRequest("/v1/venues/search?lat=37.7749&lng=-122.4194&radius=-5")
-		assert.Equal(suite.T(), http.StatusOK, w.Code) // Should default to positive radius
+		// Invalid parameters are rejected rather than ignored
+		invalidSearches := map[string]string{
+			"price_range=invalid":                 "price_range must be comma separated price ranges: $, $$, $$$, $$$$",
+			"lat=37.7749&lng=-122.4194&radius=-5": "radius must be greater than 0",
+			"limit=1000":                          "limit must be at most 100",
+			"page=-1":                             "page must be at least 1",
+			"min_rating=abc":                      "Invalid request parameters",
+			"min_rating=7":                        "min_rating must be at most 5",
+			"sort_by=cheapest":                    "sort_by must be one of: rating, distance, popularity, newest",
+			"lat=37.7749":                         "Latitude and longitude must be provided together",
+			"radius=5":                            "Radius requires a location",
+			"category=0":                          "category must be greater than 0",
+			"is_open=maybe":                       "Invalid request parameters",
+		}
+		for query, message := range invalidSearches {
+			w = suite.makeGETRequest("/v1/venues/search?" + query)
+			assert.Equal(suite.T(), http.StatusBadRequest, w.Code, query)
 
-		// Test search with very large limit
-		w = suite.makeGETRequest("/v1/venues/search?limit=1000")
-		assert.Equal(suite.T(), http.StatusOK, w.Code)
-		suite.parseJSONResponse(w, &searchResponse)
-		assert.True(suite.T(), len(searchResponse.Venues) <= 100) // Should be capped
+			var response serializers.Base
+			suite.parseJSONResponse(w, &response)
+			assert.Equal(suite.T(), message, response.Message, query)
+		}
 
-		// Test search with negative page
-		w = suite.makeGETRequest("/v1/venues/search?page=-1")
+		// Valid price ranges still filter
+		w = suite.makeGETRequest("/v1/venues/search?price_range=$$,$$$")
 		assert.Equal(suite.T(), http.StatusOK, w.Code)
-		suite.parseJSONResponse(w, &searchResponse)
-		assert.Equal(suite.T(), 1, searchResponse.Pagination.Page) // Should default to 1
 	})
 }
 
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueEventTracking tests that venue interactions reported by the apps
+// feed the venue analytics, counting resent events once
+func (suite *TestSuite) TestVenueEventTracking() {
+	suite.Run("Venue Event Tracking", func() {
+		now := time.Now()
+		batch := serializers.VenueEventsRequest{
+			ClientID: "app-install-1",
+			Events: []serializers.VenueEventRequest{
+				{ID: "e1", Type: models.VenueEventView, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e2", Type: models.VenueEventView, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e3", Type: models.VenueEventPhoneClick, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e4", Type: models.VenueEventShare, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e5", Type: models.VenueEventDirections, VenueID: 2, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e1", Type: models.VenueEventView, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e6", Type: "website_click", VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+				{ID: "e7", Type: models.VenueEventView, VenueID: 1, OccurredAt: now.Add(-48 * time.Hour)},
+				{ID: "e8", Type: models.VenueEventView, VenueID: 999999, OccurredAt: now.Add(-time.Hour)},
+				{ID: "", Type: models.VenueEventView, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+			},
+		}
+		w := suite.makePOSTRequest("/v1/analytics/track", batch)
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		var response serializers.VenueEventsResponse
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 5, response.Accepted)
+		assert.Equal(suite.T(), 1, response.Duplicates)
+		assert.Equal(suite.T(), 4, response.Rejected)
+
+		// Resending the batch counts nothing again
+		w = suite.makePOSTRequest("/v1/analytics/track", batch)
+		suite.Require().Equal(http.StatusAccepted, w.Code)
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 0, response.Accepted)
+		assert.Equal(suite.T(), 6, response.Duplicates)
+
+		// Event IDs are unique per client
+		w = suite.makePOSTRequest("/v1/analytics/track", serializers.VenueEventsRequest{
+			ClientID: "app-install-2",
+			Events: []serializers.VenueEventRequest{
+				{ID: "e1", Type: models.VenueEventPhotoView, VenueID: 1, OccurredAt: now.Add(-time.Hour)},
+			},
+		})
+		suite.Require().Equal(http.StatusAccepted, w.Code)
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), 1, response.Accepted)
+
+		var views, photoViews, phoneClicks, shares, directions int
+		err := suite.db.QueryRow(`SELECT COALESCE(SUM(profile_views), 0), COALESCE(SUM(photo_views), 0),
+			COALESCE(SUM(phone_clicks), 0), COALESCE(SUM(shares), 0) FROM venue_analytics WHERE venue_id = 1`,
+		).Scan(&views, &photoViews, &phoneClicks, &shares)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 2, views)
+		assert.Equal(suite.T(), 1, photoViews)
+		assert.Equal(suite.T(), 1, phoneClicks)
+		assert.Equal(suite.T(), 1, shares)
+		suite.Require().NoError(suite.db.QueryRow(
+			"SELECT COALESCE(SUM(direction_requests), 0) FROM venue_analytics WHERE venue_id = 2").Scan(&directions))
+		assert.Equal(suite.T(), 1, directions)
+
+		w = suite.makePOSTRequest("/v1/analytics/track", serializers.VenueEventsRequest{ClientID: "app-install-3"})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Old receipts are forgotten
+		_, err = suite.db.Exec("UPDATE venue_event_receipts SET received_at = received_at - INTERVAL '3 days' WHERE client_id = 'app-install-2'")
+		suite.Require().NoError(err)
+		analyticsService := &services.AnalyticsService{}
+		suite.Require().NoError(analyticsService.ExpireVenueEventReceipts(context.Background()))
+		var receipts int
+		suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM venue_event_receipts").Scan(&receipts))
+		assert.Equal(suite.T(), 5, receipts)
+	})
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueFavorites tests hearting venues, their favorite counts and the
+// boost favorites give to recommendations
+func (suite *TestSuite) TestVenueFavorites() {
+	suite.Run("Venue Favorites", func() {
+		ctx := context.Background()
+
+		w := suite.makePOSTRequest("/v1/venues/1/test_user_1/favorite", nil)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var status models.FavoriteStatus
+		suite.parseJSONResponse(w, &status)
+		assert.True(suite.T(), status.IsFavorite)
+		assert.Equal(suite.T(), 1, status.TotalFavorites)
+
+		// Favoriting again changes nothing
+		w = suite.makePOSTRequest("/v1/venues/1/test_user_1/favorite", nil)
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &status)
+		assert.Equal(suite.T(), 1, status.TotalFavorites)
+
+		other, err := models.SetFavorite(ctx, 2, 1, true)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 2, other.TotalFavorites)
+
+		w = suite.makePOSTRequest("/v1/venues/2/test_user_1/favorite", nil)
+		suite.Require().Equal(http.StatusOK, w.Code)
+		w = suite.makePOSTRequest("/v1/venues/999999/test_user_1/favorite", nil)
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePOSTRequest("/v1/venues/abc/test_user_1/favorite", nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		venue := &models.Venue{ID: 1}
+		suite.Require().NoError(venue.GetByID(ctx))
+		assert.Equal(suite.T(), 2, venue.TotalFavorites)
+
+		// The latest favorite comes first
+		w = suite.makeGETRequest("/v1/users/test_user_1/favorites")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var response serializers.FavoritesResponse
+		suite.parseJSONResponse(w, &response)
+		suite.Require().Len(response.Favorites, 2)
+		assert.Equal(suite.T(), int64(2), response.Favorites[0].VenueID)
+		assert.Equal(suite.T(), int64(1), response.Favorites[1].VenueID)
+		assert.Equal(suite.T(), 2, response.Favorites[1].TotalFavorites)
+		assert.Equal(suite.T(), 2, response.Pagination.Total)
+
+		w = suite.makeGETRequest("/v1/users/test_user_1/favorites?limit=500")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Favorited categories are preferred in personalized recommendations
+		engine := &services.RecommendationEngine{}
+		rc := services.RecommendationContext{UserID: 1, MaxDistance: 10, Limit: 10}
+		recommendations, err := engine.GetPersonalizedRecommendations(ctx, rc)
+		suite.Require().NoError(err)
+		for _, recommendation := range recommendations {
+			assert.NotContains(suite.T(), recommendation.Signals, services.SignalCategory)
+		}
+
+		_, err = suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+			VALUES (1, 'personalized_recommendations', 'granted', CURRENT_TIMESTAMP)`)
+		suite.Require().NoError(err)
+		recommendations, err = engine.GetPersonalizedRecommendations(ctx, rc)
+		suite.Require().NoError(err)
+		suite.Require().NotEmpty(recommendations)
+		for _, recommendation := range recommendations {
+			assert.Contains(suite.T(), recommendation.Signals, services.SignalCategory)
+		}
+
+		// Unfavoriting is idempotent too
+		w = suite.makeDELETERequest("/v1/venues/1/test_user_1/favorite")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &status)
+		assert.False(suite.T(), status.IsFavorite)
+		assert.Equal(suite.T(), 1, status.TotalFavorites)
+		w = suite.makeDELETERequest("/v1/venues/1/test_user_1/favorite")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		suite.parseJSONResponse(w, &status)
+		assert.Equal(suite.T(), 1, status.TotalFavorites)
+	})
+}
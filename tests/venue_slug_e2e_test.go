@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueSlugs tests unique slugs on creation and renames, and the lookup
+// of venues by current and historical slug
+func (suite *TestSuite) TestVenueSlugs() {
+	suite.Run("Venue Slugs", func() {
+		venueData := serializers.CreateVenueRequest{
+			Name:       "Joe's Pizza",
+			Address:    "1 Slug Street, San Francisco, CA",
+			CityID:     1,
+			Latitude:   37.7749,
+			Longitude:  -122.4194,
+			CategoryID: 1,
+		}
+		first := suite.createVenueInCity(venueData)
+		second := suite.createVenueInCity(venueData)
+		assert.Equal(suite.T(), "joes-pizza", first.Slug)
+		assert.Equal(suite.T(), "joes-pizza-2", second.Slug)
+
+		w := suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/%d/name", second.ID),
+			serializers.RenameVenueRequest{Name: "Joe's Famous Pizza"})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var renamed models.Venue
+		suite.parseJSONResponse(w, &renamed)
+		assert.Equal(suite.T(), "Joe's Famous Pizza", renamed.Name)
+		assert.Equal(suite.T(), "joes-famous-pizza", renamed.Slug)
+
+		// The old slug redirects to the current one
+		w = suite.makeGETRequest("/v1/venues/by-slug/joes-pizza-2")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		assert.Equal(suite.T(), second.ID, detail.Venue.ID)
+		if assert.NotNil(suite.T(), detail.Redirect) {
+			assert.Equal(suite.T(), http.StatusMovedPermanently, detail.Redirect.Status)
+			assert.Equal(suite.T(), "joes-pizza-2", detail.Redirect.From)
+			assert.Equal(suite.T(), "joes-famous-pizza", detail.Redirect.To)
+		}
+
+		w = suite.makeGETRequest("/v1/venues/by-slug/joes-famous-pizza")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		detail = serializers.VenueDetailResponse{}
+		suite.parseJSONResponse(w, &detail)
+		assert.Equal(suite.T(), second.ID, detail.Venue.ID)
+		assert.Nil(suite.T(), detail.Redirect)
+
+		w = suite.makeGETRequest("/v1/venues/by-slug/no-such-venue")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Historical slugs are not handed out again, except to their venue
+		third := suite.createVenueInCity(venueData)
+		assert.Equal(suite.T(), "joes-pizza-3", third.Slug)
+
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/%d/name", second.ID),
+			serializers.RenameVenueRequest{Name: "Joe's Pizza"})
+		suite.Require().Equal(http.StatusOK, w.Code)
+		renamed = models.Venue{}
+		suite.parseJSONResponse(w, &renamed)
+		assert.Equal(suite.T(), "joes-pizza-2", renamed.Slug)
+
+		w = suite.makeGETRequest("/v1/venues/by-slug/joes-famous-pizza")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		detail = serializers.VenueDetailResponse{}
+		suite.parseJSONResponse(w, &detail)
+		if assert.NotNil(suite.T(), detail.Redirect) {
+			assert.Equal(suite.T(), "joes-pizza-2", detail.Redirect.To)
+		}
+
+		// Renames keeping the slug's base keep the slug
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/%d/name", third.ID),
+			serializers.RenameVenueRequest{Name: "JOE'S PIZZA"})
+		suite.Require().Equal(http.StatusOK, w.Code)
+		renamed = models.Venue{}
+		suite.parseJSONResponse(w, &renamed)
+		assert.Equal(suite.T(), "joes-pizza-3", renamed.Slug)
+
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/owner/venues/%d/name", third.ID),
+			serializers.RenameVenueRequest{Name: "  "})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVenueUpdateAndDelete tests partial venue updates and the delete
+// policies, hiding a venue with its data or deleting it with its data
+func (suite *TestSuite) TestVenueUpdateAndDelete() {
+	suite.Run("Venue Update And Delete", func() {
+		venue := suite.createVenueInCity(serializers.CreateVenueRequest{
+			Name:        "Corner Bistro",
+			Description: "A small bistro",
+			Address:     "5 Update Lane, San Francisco, CA",
+			CityID:      1,
+			Latitude:    37.7749,
+			Longitude:   -122.4194,
+			CategoryID:  1,
+			PriceRange:  "$$",
+		})
+		path := fmt.Sprintf("/v1/venues/%d", venue.ID)
+
+		// Only the given fields change
+		phone := "+1 555 0100"
+		w := suite.makePUTRequest(path, serializers.UpdateVenueRequest{Phone: &phone})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var updated models.Venue
+		suite.parseJSONResponse(w, &updated)
+		assert.Equal(suite.T(), phone, updated.Phone)
+		assert.Equal(suite.T(), "A small bistro", updated.Description)
+		assert.Equal(suite.T(), "$$", updated.PriceRange)
+		assert.Equal(suite.T(), "corner-bistro", updated.Slug)
+
+		// A new name moves the slug and keeps the old one resolving
+		name := "Corner Bistro & Bar"
+		w = suite.makePUTRequest(path, serializers.UpdateVenueRequest{Name: &name})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		updated = models.Venue{}
+		suite.parseJSONResponse(w, &updated)
+		assert.Equal(suite.T(), "corner-bistro-and-bar", updated.Slug)
+		assert.Equal(suite.T(), phone, updated.Phone)
+
+		w = suite.makeGETRequest("/v1/venues/by-slug/corner-bistro")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var detail serializers.VenueDetailResponse
+		suite.parseJSONResponse(w, &detail)
+		assert.Equal(suite.T(), venue.ID, detail.Venue.ID)
+		assert.NotNil(suite.T(), detail.Redirect)
+
+		priceRange := "$$$$$"
+		w = suite.makePUTRequest(path, serializers.UpdateVenueRequest{PriceRange: &priceRange})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Venues without an owner can only be changed by administrators
+		w = suite.makePUTRequest("/v1/venues/2", serializers.UpdateVenueRequest{Phone: &phone})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makeDELETERequest("/v1/venues/2")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		// Owners cannot delete a venue with its data
+		w = suite.makeDELETERequest(path + "?policy=cascade")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		w = suite.makeDELETERequest(path + "?policy=purge")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Hiding keeps the venue's reviews but takes the venue out of lookups
+		_, err := suite.db.Exec(`
+			INSERT INTO venue_reviews (venue_id, user_id, overall_rating, review_text, moderation_status)
+			VALUES ($1, 1, 4, 'Lovely place', 'approved')`, venue.ID)
+		suite.Require().NoError(err)
+
+		w = suite.makeDELETERequest(path)
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		w = suite.makeGETRequest(path)
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makeGETRequest("/v1/venues/by-slug/corner-bistro-and-bar")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePUTRequest(path, serializers.UpdateVenueRequest{Phone: &phone})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		var reviewCount int
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM venue_reviews WHERE venue_id = $1", venue.ID).Scan(&reviewCount)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 1, reviewCount)
+	})
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignVoteChanges tests that votes can be moved to another venue
+// while the campaign allows it, that only the latest venue is counted and
+// that the changes are kept
+func (suite *TestSuite) TestCampaignVoteChanges() {
+	suite.Run("Campaign Vote Changes", func() {
+		ctx := context.Background()
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, category_id, start_date, end_date, max_votes_per_user, is_active, vote_change_minutes)
+			VALUES (62, 'Best Lunch', 'best_restaurant', 1, 1, $1, $2, 1, true, NULL),
+			       (63, 'Best Brunch', 'best_restaurant', 1, 1, $1, $2, 1, true, 15),
+			       (64, 'Best Supper', 'best_restaurant', 1, 1, $1, $2, 1, true, 0)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+		vote := func(campaignID int64) serializers.SubmitCampaignVoteResponse {
+			w := suite.makePOSTRequest(fmt.Sprintf("/v1/campaigns/%d/test_user_1/vote", campaignID), map[string]interface{}{
+				"venueId": 1,
+				"reason":  "Great pasta",
+			})
+			suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+			var response serializers.SubmitCampaignVoteResponse
+			suite.parseJSONResponse(w, &response)
+			return response
+		}
+
+		// Votes of campaigns without a window can be changed until the end
+		submitted := vote(62)
+		cast := submitted.Vote
+		w := suite.makePUTRequest(fmt.Sprintf("/v1/campaigns/62/test_user_1/votes/%d", cast.ID), map[string]interface{}{
+			"venueId": 2,
+		})
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var changed serializers.ChangeCampaignVoteResponse
+		suite.parseJSONResponse(w, &changed)
+		assert.Equal(suite.T(), cast.ID, changed.Vote.ID)
+		assert.Equal(suite.T(), int64(2), changed.Vote.VenueID)
+		assert.Empty(suite.T(), changed.Vote.Reason)
+		assert.Equal(suite.T(), int64(2), changed.Receipt.VenueID)
+		suite.Require().NotNil(changed.Vote.ChangeableUntil)
+		assert.WithinDuration(suite.T(), now.Add(24*time.Hour), *changed.Vote.ChangeableUntil, time.Minute)
+		suite.Require().Len(changed.Changes, 1)
+		assert.Equal(suite.T(), int64(1), changed.Changes[0].PreviousVenueID)
+		assert.Equal(suite.T(), int64(2), changed.Changes[0].VenueID)
+
+		// Only the latest venue is counted
+		campaign := &models.VotingCampaign{ID: 62}
+		suite.Require().NoError(campaign.GetByID(ctx))
+		results, err := models.GetCampaignResults(ctx, campaign)
+		suite.Require().NoError(err)
+		suite.Require().Len(results.Categories, 1)
+		suite.Require().Len(results.Categories[0].Standings, 1)
+		assert.Equal(suite.T(), int64(2), results.Categories[0].Standings[0].VenueID)
+		assert.Equal(suite.T(), 1, results.Categories[0].TotalVotes)
+
+		// The receipt of the previous venue no longer verifies as recorded
+		var verification serializers.VerifyReceiptResponse
+		w = suite.makePOSTRequest("/v1/receipts/verify", submitted.Receipt)
+		suite.parseJSONResponse(w, &verification)
+		assert.True(suite.T(), verification.Valid)
+		assert.False(suite.T(), verification.Recorded)
+		w = suite.makePOSTRequest("/v1/receipts/verify", changed.Receipt)
+		suite.parseJSONResponse(w, &verification)
+		assert.True(suite.T(), verification.Recorded)
+
+		// Moving a vote to where it already goes changes nothing
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/campaigns/62/test_user_1/votes/%d", cast.ID), map[string]interface{}{
+			"venueId": 2,
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePUTRequest("/v1/campaigns/62/test_user_1/votes/999999", map[string]interface{}{
+			"venueId": 1,
+		})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		// Votes of windowed campaigns are final once the window passes
+		windowed := vote(63).Vote
+		w = suite.makeGETRequest("/v1/campaigns/63/test_user_1/votes")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var history serializers.CampaignVoteHistoryResponse
+		suite.parseJSONResponse(w, &history)
+		assert.True(suite.T(), history.CanChangeVotes)
+		suite.Require().Len(history.Votes, 1)
+		suite.Require().NotNil(history.Votes[0].ChangeableUntil)
+		assert.WithinDuration(suite.T(), windowed.CreatedAt.Add(15*time.Minute), *history.Votes[0].ChangeableUntil, time.Second)
+
+		_, err = suite.db.Exec("UPDATE campaign_votes SET created_at = created_at - INTERVAL '16 minutes' WHERE id = $1", windowed.ID)
+		suite.Require().NoError(err)
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/campaigns/63/test_user_1/votes/%d", windowed.ID), map[string]interface{}{
+			"venueId": 2,
+		})
+		suite.Require().Equal(http.StatusBadRequest, w.Code)
+		var errorResponse serializers.Base
+		suite.parseJSONResponse(w, &errorResponse)
+		assert.Equal(suite.T(), serializers.VoteFinal, errorResponse.Code)
+
+		// and votes of campaigns without changes at once
+		final := vote(64).Vote
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/campaigns/64/test_user_1/votes/%d", final.ID), map[string]interface{}{
+			"venueId": 2,
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makeGETRequest("/v1/campaigns/64/test_user_1/votes")
+		var finalHistory serializers.CampaignVoteHistoryResponse
+		suite.parseJSONResponse(w, &finalHistory)
+		assert.False(suite.T(), finalHistory.CanChangeVotes)
+		suite.Require().Len(finalHistory.Votes, 1)
+		assert.Nil(suite.T(), finalHistory.Votes[0].ChangeableUntil)
+	})
+}
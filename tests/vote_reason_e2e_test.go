@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVoteReasonHighlights tests moderating the reasons given with campaign
+// votes and quoting the approved highlights in the results
+func (suite *TestSuite) TestVoteReasonHighlights() {
+	suite.Run("Vote Reason Highlights", func() {
+		ctx := context.Background()
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, city_id, start_date, end_date, max_votes_per_user, is_active)
+			VALUES (80, 'Quotable Campaign', 'best_restaurant', 1, $1, $2, 3, true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		// Clean reasons are approved, flagged ones wait for a moderator
+		w := suite.makePOSTRequest("/v1/campaigns/80/test_user_1/vote", map[string]interface{}{
+			"venueId": 1, "reason": "  The <b>staff</b> remember your name  ",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var approved serializers.SubmitCampaignVoteResponse
+		suite.parseJSONResponse(w, &approved)
+		assert.Equal(suite.T(), "The staff remember your name", approved.Vote.Reason)
+		assert.Equal(suite.T(), models.ReasonApproved, approved.Vote.ReasonStatus)
+
+		w = suite.makePOSTRequest("/v1/campaigns/80/test_user_1/vote", map[string]interface{}{
+			"venueId": 2, "reason": "The food was shit",
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		var base serializers.Base
+		suite.parseJSONResponse(w, &base)
+		assert.Equal(suite.T(), serializers.ContentRejected, base.Code)
+
+		w = suite.makePOSTRequest("/v1/campaigns/80/test_user_1/vote", map[string]interface{}{
+			"venueId": 2, "reason": "Deals at https://a.example.com https://b.example.com and www.c.example.com",
+		})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var pending serializers.SubmitCampaignVoteResponse
+		suite.parseJSONResponse(w, &pending)
+		assert.Equal(suite.T(), models.ReasonPending, pending.Vote.ReasonStatus)
+
+		w = suite.makePOSTRequest("/v1/campaigns/80/test_user_2/vote", map[string]interface{}{"venueId": 1})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var silent serializers.SubmitCampaignVoteResponse
+		suite.parseJSONResponse(w, &silent)
+		assert.Empty(suite.T(), silent.Vote.ReasonStatus)
+
+		// Moderation is admin only
+		assert.Equal(suite.T(), http.StatusForbidden, suite.makeGETRequest("/v1/admin/campaigns/80/reasons").Code)
+		w = suite.makePUTRequest("/v1/admin/campaigns/80/reasons/1", map[string]interface{}{"status": "approved"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		request := serializers.ModerateVoteReasonRequest{Status: models.ReasonRejected, Highlight: true}
+		_, isValid := request.Validate()
+		assert.False(suite.T(), isValid)
+
+		queue, err := models.GetCampaignVoteReasons(ctx, 80, models.ReasonPending, 50, 0)
+		suite.Require().NoError(err)
+		suite.Require().Len(queue, 1)
+		assert.Equal(suite.T(), pending.Vote.ID, queue[0].VoteID)
+		assert.True(suite.T(), queue[0].IsFlagged)
+		assert.Equal(suite.T(), "Test Restaurant 2", queue[0].VenueName)
+		all, err := models.GetCampaignVoteReasons(ctx, 80, "", 50, 0)
+		suite.Require().NoError(err)
+		assert.Len(suite.T(), all, 2)
+
+		// Approved reasons are quoted once picked as highlights
+		results, err := models.GetCampaignResults(ctx, &models.VotingCampaign{ID: 80, Title: "Quotable Campaign"})
+		suite.Require().NoError(err)
+		suite.Require().Len(results.Categories, 1)
+		assert.Empty(suite.T(), results.Categories[0].Highlights)
+
+		highlight := &models.CampaignVoteReason{VoteID: approved.Vote.ID, CampaignID: 80}
+		suite.Require().NoError(highlight.Moderate(ctx, 1, models.ReasonApproved, true))
+		assert.True(suite.T(), highlight.IsHighlight)
+		suite.Require().NotNil(highlight.ModeratedBy)
+		assert.Equal(suite.T(), int64(1), *highlight.ModeratedBy)
+
+		rejected := &models.CampaignVoteReason{VoteID: pending.Vote.ID, CampaignID: 80}
+		suite.Require().NoError(rejected.Moderate(ctx, 1, models.ReasonRejected, true))
+		assert.Equal(suite.T(), models.ReasonRejected, rejected.Status)
+		assert.False(suite.T(), rejected.IsHighlight)
+
+		err = (&models.CampaignVoteReason{VoteID: silent.Vote.ID, CampaignID: 80}).Moderate(ctx, 1, models.ReasonApproved, true)
+		assert.Equal(suite.T(), sql.ErrNoRows, err)
+		err = (&models.CampaignVoteReason{VoteID: approved.Vote.ID, CampaignID: 50}).Moderate(ctx, 1, models.ReasonApproved, true)
+		assert.Equal(suite.T(), sql.ErrNoRows, err)
+
+		results, err = models.GetCampaignResults(ctx, &models.VotingCampaign{ID: 80, Title: "Quotable Campaign"})
+		suite.Require().NoError(err)
+		suite.Require().Len(results.Categories[0].Highlights, 1)
+		assert.Equal(suite.T(), models.ReasonHighlight{
+			VoteID: approved.Vote.ID, VenueID: 1, VenueName: "Test Restaurant 1", Reason: "The staff remember your name",
+		}, results.Categories[0].Highlights[0])
+
+		w = suite.makeGETRequest("/v1/campaign-results/80")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var public models.CampaignResults
+		suite.parseJSONResponse(w, &public)
+		assert.Len(suite.T(), public.Categories[0].Highlights, 1)
+
+		// Embargoed results don't quote voters either
+		results.HideStandings(now.Add(24 * time.Hour))
+		assert.Empty(suite.T(), results.Categories[0].Highlights)
+	})
+}
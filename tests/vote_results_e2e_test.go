@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLegacyVoteResults tests the live totals of legacy votings
+func (suite *TestSuite) TestLegacyVoteResults() {
+	suite.Run("Legacy Vote Results", func() {
+		suite.setupLegacyVotingData()
+		_, err := suite.db.Exec("DELETE FROM user_voting")
+		suite.Require().NoError(err)
+		voteResultService := &services.VoteResultService{}
+		voteResultService.InvalidateResults(1)
+
+		results := suite.getVoteResults("/v1/vote/test_user_1/results/1")
+		assert.Equal(suite.T(), int64(0), results.TotalVotes)
+		assert.Nil(suite.T(), results.UserVote)
+		assert.False(suite.T(), results.IsFinal)
+		// Inactive participant 3 has no votes and is left out
+		suite.Require().Len(results.Results, 2)
+
+		w := suite.makePOSTRequest("/v1/vote/test_user_1/1/2", nil)
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		// The new vote invalidates the cached counts
+		results = suite.getVoteResults("/v1/vote/test_user_1/results/1")
+		assert.Equal(suite.T(), int64(1), results.TotalVotes)
+		suite.Require().NotNil(results.UserVote)
+		assert.Equal(suite.T(), int64(2), *results.UserVote)
+		assert.Equal(suite.T(), int64(2), results.Results[0].ParticipantID)
+		assert.Equal(suite.T(), 100.0, results.Results[0].Percentage)
+
+		w = suite.makePOSTRequest("/v1/vote/test_user_2/1/1", nil)
+		suite.Require().Equal(http.StatusOK, w.Code)
+		_, err = suite.db.Exec("INSERT INTO user_voting (voting_id, owner_id, vote_id) VALUES (1, 1, 1)")
+		suite.Require().NoError(err)
+		voteResultService.InvalidateResults(1)
+
+		results = suite.getVoteResults("/v1/vote/test_user_2/results/1")
+		assert.Equal(suite.T(), int64(3), results.TotalVotes)
+		assert.Equal(suite.T(), int64(1), *results.UserVote)
+		assert.Equal(suite.T(), int64(1), results.Results[0].ParticipantID)
+		assert.Equal(suite.T(), int64(2), results.Results[0].Votes)
+		assert.Equal(suite.T(), 66.7, results.Results[0].Percentage)
+		assert.Equal(suite.T(), 33.3, results.Results[1].Percentage)
+
+		// Ended votings are final and mark their winner
+		results = suite.getVoteResults("/v1/vote/test_user_1/results/2")
+		assert.True(suite.T(), results.IsFinal)
+		for _, result := range results.Results {
+			assert.Equal(suite.T(), result.ParticipantID == 1, result.IsWinner)
+		}
+
+		w = suite.makeGETRequest("/v1/vote/test_user_1/results/999")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.makeGETRequest("/v1/vote/test_user_1/results/latest")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		var errorResponse serializers.Base
+		suite.parseJSONResponse(w, &errorResponse)
+		assert.Equal(suite.T(), serializers.InvalidInput, errorResponse.Code)
+	})
+}
+
+func (suite *TestSuite) getVoteResults(url string) services.VotingResults {
+	w := suite.makeGETRequest(url)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	var results services.VotingResults
+	suite.parseJSONResponse(w, &results)
+	return results
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var voteCodePattern = regexp.MustCompile(`is (\d{6})\.`)
+
+// TestCampaignVoteVerification tests holding the votes of campaigns
+// requiring one-time codes until the voter confirms them
+func (suite *TestSuite) TestCampaignVoteVerification() {
+	suite.Run("Campaign Vote Verification", func() {
+		mailer := &recordingMailer{}
+		defaultMailer := services.AppMailer
+		services.AppMailer = mailer
+		defer func() { services.AppMailer = defaultMailer }()
+
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, start_date, end_date, max_votes_per_user, is_active, voting_mode, require_otp)
+			VALUES (65, 'Best Bakery', 'best_restaurant', $1, $2, 1, true, 'standard', true)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+		vote := serializers.SubmitCampaignVoteRequest{VenueID: suite.testData.TestVenue1.ID}
+		countVotes := func() int {
+			var count int
+			suite.Require().NoError(suite.db.QueryRow("SELECT COUNT(*) FROM campaign_votes WHERE campaign_id = 65").Scan(&count))
+			return count
+		}
+
+		// Codes need a verified email
+		w := suite.makePOSTRequest("/v1/campaigns/65/test_user_1/vote", vote)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+		var response serializers.Base
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.EmailNotVerified, response.Code)
+
+		var userID int64
+		err = suite.db.QueryRow(`INSERT INTO users (email, password, email_verified_at)
+			VALUES ('voter@example.com', 'unused', CURRENT_TIMESTAMP) RETURNING id`).Scan(&userID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO account_links (user_id, snapp_user_id) VALUES ($1, 1)", userID)
+		suite.Require().NoError(err)
+
+		// The vote waits for the emailed code
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/vote", vote)
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		var held serializers.HeldCampaignVoteResponse
+		suite.parseJSONResponse(w, &held)
+		assert.Equal(suite.T(), serializers.VotePendingVerification, held.Status)
+		assert.Equal(suite.T(), "v***@example.com", held.SentTo)
+		assert.Equal(suite.T(), 0, countVotes())
+
+		suite.Require().Len(mailer.sent, 1)
+		assert.Equal(suite.T(), "voter@example.com", mailer.sent[0].To)
+		match := voteCodePattern.FindStringSubmatch(mailer.sent[0].Body)
+		suite.Require().NotNil(match)
+		code := match[1]
+
+		// Holding the vote again right away sends no other code
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/vote", vote)
+		assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+		assert.Len(suite.T(), mailer.sent, 1)
+
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/verify-otp", map[string]string{"code": wrong})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		assert.Equal(suite.T(), 0, countVotes())
+
+		// Past the resend interval a new code replaces the old one, its
+		// attempts starting afresh
+		_, err = suite.db.Exec("UPDATE pending_campaign_votes SET created_at = created_at - INTERVAL '2 minutes' WHERE campaign_id = 65")
+		suite.Require().NoError(err)
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/vote", vote)
+		suite.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+		suite.Require().Len(mailer.sent, 2)
+		match = voteCodePattern.FindStringSubmatch(mailer.sent[1].Body)
+		suite.Require().NotNil(match)
+		code = match[1]
+		var attempts int
+		suite.Require().NoError(suite.db.QueryRow("SELECT attempts FROM pending_campaign_votes WHERE campaign_id = 65").Scan(&attempts))
+		assert.Equal(suite.T(), 0, attempts)
+
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/verify-otp", map[string]string{"code": code})
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var cast serializers.SubmitCampaignVoteResponse
+		suite.parseJSONResponse(w, &cast)
+		assert.Equal(suite.T(), suite.testData.TestVenue1.ID, cast.Vote.VenueID)
+		assert.Equal(suite.T(), 1, countVotes())
+
+		// Codes are used once
+		w = suite.makePOSTRequest("/v1/campaigns/65/test_user_1/verify-otp", map[string]string{"code": code})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// Confirmed votes can't be moved without a new code
+		w = suite.makePUTRequest(fmt.Sprintf("/v1/campaigns/65/test_user_1/votes/%d", cast.Vote.ID), map[string]interface{}{
+			"venueId": suite.testData.TestVenue2.ID,
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		suite.parseJSONResponse(w, &response)
+		assert.Equal(suite.T(), serializers.VoteFinal, response.Code)
+		assert.Equal(suite.T(), 1, countVotes())
+		var venueID int64
+		err = suite.db.QueryRow("SELECT venue_id FROM campaign_votes WHERE id = $1", cast.Vote.ID).Scan(&venueID)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), suite.testData.TestVenue1.ID, venueID)
+
+		// Kiosks have no one to send codes to
+		_, err = suite.db.Exec("UPDATE voting_campaigns SET allow_kiosk_sessions = true WHERE id = 65")
+		suite.Require().NoError(err)
+		_, kioskToken, err := new(services.VotingSessionService).IssueKiosk(context.Background(), 65, "Hall B", 1)
+		suite.Require().NoError(err)
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 65, KioskToken: kioskToken})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
@@ -174,6 +174,12 @@ func (suite *TestSuite) testLegacySubmitVote() {
 	}
 	assert.True(suite.T(), voted, "User's vote should appear in vote history")
 
+	// The vote comes with a signed receipt
+	suite.Require().NotNil(voteResponse.Receipt)
+	assert.Equal(suite.T(), "legacy", voteResponse.Receipt.Kind)
+	assert.Equal(suite.T(), int64(1), voteResponse.Receipt.ParticipantID)
+	assert.NotEmpty(suite.T(), voteResponse.Receipt.Signature)
+
 	// Test duplicate vote prevention
 	w = suite.makePOSTRequest("/v1/vote/test_user_1/1/1", nil)
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
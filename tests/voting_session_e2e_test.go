@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestKioskVotingSessions tests anonymous voting through kiosk sessions
+func (suite *TestSuite) TestKioskVotingSessions() {
+	suite.Run("Kiosk Voting Sessions", func() {
+		now := time.Now()
+		_, err := suite.db.Exec(`INSERT INTO voting_campaigns
+			(id, title, campaign_type, start_date, end_date, max_votes_per_user, is_active, voting_mode, allow_kiosk_sessions)
+			VALUES (60, 'Festival Favourite', 'best_restaurant', $1, $2, 1, true, 'standard', true),
+			       (61, 'Festival Quadratic', 'best_restaurant', $1, $2, 1, true, 'quadratic', true),
+			       (62, 'Festival Extra', 'best_restaurant', $1, $2, 1, true, 'standard', true),
+			       (66, 'Online Only', 'best_restaurant', $1, $2, 1, true, 'standard', false)`,
+			now.Add(-time.Hour), now.Add(24*time.Hour))
+		suite.Require().NoError(err)
+
+		// Kiosk credentials are issued by administrators
+		w := suite.makePOSTRequest("/v1/admin/campaigns/60/kiosks", serializers.KioskDeviceRequest{Name: "Hall A"})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		sessionService := &services.VotingSessionService{}
+		kiosk, kioskToken, err := sessionService.IssueKiosk(context.Background(), 60, "Hall A", 1)
+		suite.Require().NoError(err)
+		_, quadraticToken, err := sessionService.IssueKiosk(context.Background(), 61, "Hall A", 1)
+		suite.Require().NoError(err)
+		_, onlineToken, err := sessionService.IssueKiosk(context.Background(), 66, "Hall A", 1)
+		suite.Require().NoError(err)
+
+		// Sessions need a credential issued for the campaign
+		w = suite.makePOSTRequest("/v1/vote/sessions", map[string]interface{}{"campaignId": 60})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 60, KioskToken: "kiosk-hall-a-01"})
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 60, KioskToken: quadraticToken})
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 62, KioskToken: kioskToken})
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		// and a campaign taking votes from kiosks
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 66, KioskToken: onlineToken})
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		request := serializers.VotingSessionRequest{CampaignID: 60, KioskToken: kioskToken}
+		w = suite.makePOSTRequest("/v1/vote/sessions", request)
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var session serializers.VotingSessionResponse
+		suite.parseJSONResponse(w, &session)
+		suite.Require().NotEmpty(session.Token)
+		assert.Equal(suite.T(), 1, session.MaxVotes)
+		assert.WithinDuration(suite.T(), time.Now().Add(services.VotingSessionTTL), session.Session.ExpiresAt, time.Minute)
+		assert.Equal(suite.T(), kiosk.ID, session.Session.KioskID)
+
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 61, KioskToken: quadraticToken})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/vote/sessions", serializers.VotingSessionRequest{CampaignID: 999, KioskToken: kioskToken})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		vote := map[string]interface{}{"venueId": 1}
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", "", vote)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		// The session only votes in its campaign
+		w = suite.makeSessionVoteRequest("/v1/campaigns/62/vote", session.Token, vote)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", session.Token, vote)
+		suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		var voted serializers.SubmitCampaignVoteResponse
+		suite.parseJSONResponse(w, &voted)
+		assert.Equal(suite.T(), int64(0), voted.Vote.UserID)
+		assert.Equal(suite.T(), 0, voted.RemainingVotes)
+		assert.NotEmpty(suite.T(), voted.Receipt.Signature)
+
+		// The session has the vote limit of a user
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", session.Token, map[string]interface{}{"venueId": 2})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		// A new voter at the same kiosk gets a new session
+		w = suite.makePOSTRequest("/v1/vote/sessions", request)
+		suite.Require().Equal(http.StatusCreated, w.Code)
+		var next serializers.VotingSessionResponse
+		suite.parseJSONResponse(w, &next)
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", next.Token, vote)
+		assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+		var votes int
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM campaign_votes WHERE campaign_id = 60 AND user_id IS NULL").Scan(&votes)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), 2, votes)
+
+		// Expired sessions can't vote
+		_, err = suite.db.Exec("UPDATE voting_sessions SET expires_at = $1", time.Now().UTC().Add(-time.Minute))
+		suite.Require().NoError(err)
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", next.Token, map[string]interface{}{"venueId": 2})
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+		// Each kiosk has an hourly vote limit, filled here by earlier
+		// sessions of the kiosk
+		_, err = suite.db.Exec(`
+			WITH sessions AS (
+				INSERT INTO voting_sessions (campaign_id, token_hash, kiosk_id, expires_at, created_at)
+				SELECT 60, md5('filler' || g) || md5(g::text), $2,
+					   LOCALTIMESTAMP, LOCALTIMESTAMP - INTERVAL '2 hours'
+				FROM generate_series(1, $1) g
+				RETURNING id
+			)
+			INSERT INTO campaign_votes (campaign_id, venue_id, voting_session_id)
+			SELECT 60, 1, id FROM sessions`, services.KioskDeviceVotesPerHour, kiosk.ID)
+		suite.Require().NoError(err)
+
+		w = suite.makePOSTRequest("/v1/vote/sessions", request)
+		suite.Require().Equal(http.StatusCreated, w.Code)
+		var limited serializers.VotingSessionResponse
+		suite.parseJSONResponse(w, &limited)
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", limited.Token, vote)
+		assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+
+		// Revoking the credential ends the kiosk's sessions
+		revoked, err := models.RevokeKioskDevice(context.Background(), 60, kiosk.ID)
+		suite.Require().NoError(err)
+		assert.True(suite.T(), revoked)
+		w = suite.makeSessionVoteRequest("/v1/campaigns/60/vote", limited.Token, vote)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+		w = suite.makePOSTRequest("/v1/vote/sessions", request)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+		kiosks, err := models.GetCampaignKioskDevices(context.Background(), 60)
+		suite.Require().NoError(err)
+		suite.Require().Len(kiosks, 1)
+		assert.NotNil(suite.T(), kiosks[0].RevokedAt)
+	})
+}
+
+// makeSessionVoteRequest posts a campaign vote with the voting session token
+func (suite *TestSuite) makeSessionVoteRequest(url, token string, payload interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Voting-Session", token)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
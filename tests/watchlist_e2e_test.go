@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"net/http"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompetitorWatchlist tests owners following competitor venues and
+// comparing their venue with them
+func (suite *TestSuite) TestCompetitorWatchlist() {
+	suite.Run("Competitor Watchlist", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		defer suite.db.Exec("UPDATE venues SET owner_id = NULL WHERE id = 1")
+		_, err = suite.db.Exec(`INSERT INTO venues (id, name, slug, address, city_id, latitude, longitude, category_id, is_active)
+			VALUES (70, 'Rival 70', 'rival-70', '70 Main St', 1, 37.77, -122.41, 1, true),
+				   (71, 'Rival 71', 'rival-71', '71 Main St', 1, 37.77, -122.41, 1, true),
+				   (72, 'Rival 72', 'rival-72', '72 Main St', 1, 37.77, -122.41, 1, true),
+				   (73, 'Rival 73', 'rival-73', '73 Main St', 1, 37.77, -122.41, 1, true),
+				   (74, 'Rival 74', 'rival-74', '74 Main St', 1, 37.77, -122.41, 1, true),
+				   (75, 'Closed Rival', 'closed-rival', '75 Main St', 1, 37.77, -122.41, 1, false)`)
+		suite.Require().NoError(err)
+
+		for _, competitorID := range []int64{2, 70, 71, 72, 73} {
+			w := suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: competitorID})
+			suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		// Watching a competitor again changes nothing
+		w := suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: 2})
+		suite.Require().Equal(http.StatusCreated, w.Code)
+		var competitor models.WatchlistCompetitor
+		suite.parseJSONResponse(w, &competitor)
+		assert.Equal(suite.T(), "Test Restaurant 2", competitor.Name)
+
+		w = suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: 74})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		w = suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: 1})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+		w = suite.makeDELETERequest("/v1/owner/venues/1/watchlist/73")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		w = suite.makeDELETERequest("/v1/owner/venues/1/watchlist/73")
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: 75})
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+		w = suite.makePOSTRequest("/v1/owner/venues/1/watchlist", serializers.WatchlistRequest{CompetitorVenueID: 74})
+		suite.Require().Equal(http.StatusCreated, w.Code)
+
+		w = suite.makeGETRequest("/v1/owner/venues/1/watchlist")
+		suite.Require().Equal(http.StatusOK, w.Code)
+		var watchlist serializers.WatchlistResponse
+		suite.parseJSONResponse(w, &watchlist)
+		suite.Require().Len(watchlist.Competitors, 5)
+		assert.Equal(suite.T(), int64(2), watchlist.Competitors[0].VenueID)
+		assert.Equal(suite.T(), int64(74), watchlist.Competitors[4].VenueID)
+
+		// Only the owner manages the watchlist
+		w = suite.makeGETRequest("/v1/owner/venues/2/watchlist")
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+		_, err = suite.db.Exec(`INSERT INTO venue_analytics (venue_id, date, profile_views)
+			VALUES (1, CURRENT_DATE, 10), (2, CURRENT_DATE, 30)`)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`INSERT INTO venue_reviews (venue_id, user_id, overall_rating, review_text, moderation_status, created_at)
+			VALUES (1, 1, 4.0, 'Solid', 'approved', CURRENT_TIMESTAMP),
+				   (2, 1, 3.0, 'Fine', 'approved', CURRENT_TIMESTAMP - INTERVAL '7 days'),
+				   (2, 2, 5.0, 'Much better now', 'approved', CURRENT_TIMESTAMP)`)
+		suite.Require().NoError(err)
+
+		w = suite.makeGETRequest("/v1/analytics/venues/1/watchlist?weeks=2")
+		suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var comparison services.WatchlistComparison
+		suite.parseJSONResponse(w, &comparison)
+		suite.Require().Len(comparison.Weeks, 2)
+		suite.Require().Len(comparison.Venue.Weekly, 2)
+		assert.Equal(suite.T(), float64(10), comparison.Venue.Weekly[1].Views)
+		assert.Equal(suite.T(), float64(1), comparison.Venue.Weekly[1].Reviews)
+		assert.Nil(suite.T(), comparison.Venue.RatingChange)
+
+		suite.Require().Len(comparison.Competitors, 5)
+		rival := comparison.Competitors[0]
+		assert.Equal(suite.T(), int64(2), rival.VenueID)
+		if assert.NotNil(suite.T(), rival.RatingChange) {
+			assert.InDelta(suite.T(), 2.0, *rival.RatingChange, 0.001)
+		}
+		suite.Require().Len(comparison.CompetitorAverage, 2)
+		assert.InDelta(suite.T(), 6.0, comparison.CompetitorAverage[1].Views, 0.001)
+
+		w = suite.makeGETRequest("/v1/analytics/venues/1/watchlist?weeks=100")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	})
+}
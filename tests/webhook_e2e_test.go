@@ -0,0 +1,303 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+	"voting-app/app/models"
+	"voting-app/app/serializers"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookReceiver records the requests posted to a test webhook endpoint
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	r.mu.Unlock()
+	w.WriteHeader(r.status)
+}
+
+// TestWebhooks tests webhook subscriptions, signed deliveries and retries
+func (suite *TestSuite) TestWebhooks() {
+	suite.Run("Webhooks End-to-End", func() {
+		_, err := suite.db.Exec("UPDATE venues SET owner_id = 1 WHERE id = 1")
+		suite.Require().NoError(err)
+		// The test receivers listen on loopback
+		services.AllowPrivateWebhookTargets = true
+		defer func() { services.AllowPrivateWebhookTargets = false }()
+		defer func(resolve func(context.Context, string) ([]net.IPAddr, error)) {
+			services.ResolveWebhookHost = resolve
+		}(services.ResolveWebhookHost)
+		services.ResolveWebhookHost = resolveTestWebhookHost
+
+		suite.testWebhookDelivery()
+		suite.testWebhookRetries()
+		suite.testWebhookAccess()
+	})
+}
+
+// testWebhookHosts are the addresses of the test webhook hosts, resolved
+// without DNS
+var testWebhookHosts = map[string]string{
+	"localhost":             "127.0.0.1",
+	"partner.example.com":   "93.184.216.34",
+	"cgnat.example.com":     "100.64.12.7",
+	"benchmark.example.com": "198.18.0.1",
+	"mapped.example.com":    "::ffff:192.168.1.10",
+}
+
+// resolveTestWebhookHost resolves IP addresses and testWebhookHosts
+func resolveTestWebhookHost(_ context.Context, host string) ([]net.IPAddr, error) {
+	if address, ok := testWebhookHosts[host]; ok {
+		host = address
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: ip}}, nil
+}
+
+func (suite *TestSuite) createVenueWebhook(targetURL string) models.WebhookSubscription {
+	w := suite.makePOSTRequest("/v1/owner/venues/1/webhooks", map[string]interface{}{
+		"targetUrl":  targetURL,
+		"eventTypes": []string{models.WebhookEventReviewCreated},
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var subscription models.WebhookSubscription
+	suite.parseJSONResponse(w, &subscription)
+	return subscription
+}
+
+func (suite *TestSuite) testWebhookDelivery() {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	subscription := suite.createVenueWebhook(server.URL)
+	assert.True(suite.T(), subscription.IsActive)
+	assert.NotEmpty(suite.T(), subscription.Secret)
+
+	// The secret is not listed again
+	w := suite.makeGETRequest("/v1/owner/venues/1/webhooks")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var list serializers.WebhookSubscriptionsResponse
+	suite.parseJSONResponse(w, &list)
+	suite.Require().Len(list.Subscriptions, 1)
+	assert.Empty(suite.T(), list.Subscriptions[0].Secret)
+
+	// A review of the venue is delivered in the background
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       1,
+		"overallRating": 4.0,
+		"reviewText":    "Lovely terrace",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	suite.Require().Eventually(func() bool {
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		return len(receiver.requests) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	request, body := receiver.requests[0], receiver.bodies[0]
+	assert.Equal(suite.T(), models.WebhookEventReviewCreated, request.Header.Get("X-Webhook-Event"))
+	timestamp, err := strconv.ParseInt(request.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), services.SignWebhookPayload(subscription.Secret, timestamp, body), request.Header.Get("X-Webhook-Signature"))
+
+	var event services.WebhookEvent
+	suite.Require().NoError(json.Unmarshal(body, &event))
+	assert.Equal(suite.T(), models.WebhookEventReviewCreated, event.Type)
+	assert.Equal(suite.T(), float64(1), event.Data.(map[string]interface{})["venueId"])
+
+	// Reviews of other venues are not sent to the venue's subscription
+	w = suite.makePOSTRequest("/v1/reviews/test_user_1/", map[string]interface{}{
+		"venueId":       2,
+		"overallRating": 3.0,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	// The delivery log records the attempt
+	url := fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/deliveries", subscription.ID)
+	suite.Require().Eventually(func() bool {
+		var deliveries serializers.WebhookDeliveriesResponse
+		suite.parseJSONResponse(suite.makeGETRequest(url), &deliveries)
+		return len(deliveries.Deliveries) == 1 && deliveries.Deliveries[0].Status == models.WebhookDeliveryDelivered
+	}, 5*time.Second, 50*time.Millisecond)
+
+	var deliveries serializers.WebhookDeliveriesResponse
+	suite.parseJSONResponse(suite.makeGETRequest(url), &deliveries)
+	assert.Equal(suite.T(), 1, deliveries.Deliveries[0].Attempts)
+	suite.Require().NotNil(deliveries.Deliveries[0].LastStatusCode)
+	assert.Equal(suite.T(), http.StatusOK, *deliveries.Deliveries[0].LastStatusCode)
+
+	// Test events are sent right away
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/test", subscription.ID), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var testDelivery models.WebhookDelivery
+	suite.parseJSONResponse(w, &testDelivery)
+	assert.Equal(suite.T(), models.WebhookEventTest, testDelivery.EventType)
+	assert.Equal(suite.T(), models.WebhookDeliveryDelivered, testDelivery.Status)
+	assert.Len(suite.T(), receiver.requests, 2)
+
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/disable", subscription.ID), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+}
+
+func (suite *TestSuite) testWebhookRetries() {
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	subscription := suite.createVenueWebhook(server.URL)
+
+	// Failed test events are not retried
+	w := suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/test", subscription.ID), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var testDelivery models.WebhookDelivery
+	suite.parseJSONResponse(w, &testDelivery)
+	assert.Equal(suite.T(), models.WebhookDeliveryFailed, testDelivery.Status)
+	suite.Require().NotNil(testDelivery.LastStatusCode)
+	assert.Equal(suite.T(), http.StatusInternalServerError, *testDelivery.LastStatusCode)
+
+	// Events are retried with backoff
+	ctx := context.Background()
+	venueID := int64(1)
+	queued, err := models.CreateWebhookDeliveries(ctx, models.WebhookEventReviewCreated, &venueID, []byte(`{"reviewId":1}`))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), queued)
+
+	webhookService := &services.WebhookService{}
+	suite.Require().NoError(webhookService.DeliverPending(ctx))
+
+	var status string
+	var attempts int
+	var retryIn float64
+	err = suite.db.QueryRow(`
+		SELECT status, attempts, EXTRACT(EPOCH FROM next_attempt_at - LOCALTIMESTAMP)
+		FROM webhook_deliveries WHERE subscription_id = $1 AND event_type = $2`,
+		subscription.ID, models.WebhookEventReviewCreated,
+	).Scan(&status, &attempts, &retryIn)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.WebhookDeliveryPending, status)
+	assert.Equal(suite.T(), 1, attempts)
+	assert.InDelta(suite.T(), services.WebhookRetryBaseDelay.Seconds(), retryIn, 5)
+	assert.Equal(suite.T(), 2*time.Minute, services.WebhookRetryDelay(3))
+
+	// Not due yet
+	suite.Require().NoError(webhookService.DeliverPending(ctx))
+	assert.Len(suite.T(), receiver.requests, 2)
+
+	// Disabling fails the pending deliveries
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/disable", subscription.ID), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var disabled models.WebhookSubscription
+	suite.parseJSONResponse(w, &disabled)
+	assert.False(suite.T(), disabled.IsActive)
+	assert.NotNil(suite.T(), disabled.DisabledAt)
+
+	err = suite.db.QueryRow(`SELECT status FROM webhook_deliveries WHERE subscription_id = $1 AND event_type = $2`,
+		subscription.ID, models.WebhookEventReviewCreated).Scan(&status)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.WebhookDeliveryFailed, status)
+
+	w = suite.makePOSTRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/test", subscription.ID), nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *TestSuite) testWebhookAccess() {
+	services.AllowPrivateWebhookTargets = false
+	defer func() { services.AllowPrivateWebhookTargets = true }()
+
+	// Targets must be public
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hooks", "http://169.254.169.254/latest/meta-data", "https://10.0.0.5/hooks",
+		"http://localhost/hooks", "https://cgnat.example.com/hooks", "https://benchmark.example.com/hooks",
+		"https://mapped.example.com/hooks", "http://[::ffff:10.0.0.5]/hooks", "https://unknown.example.com/hooks",
+	} {
+		w := suite.makePOSTRequest("/v1/owner/venues/1/webhooks", map[string]interface{}{
+			"targetUrl":  target,
+			"eventTypes": []string{models.WebhookEventReviewCreated},
+		})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, target)
+	}
+
+	// and are checked again on delivery
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	venueID := int64(1)
+	private := &models.WebhookSubscription{
+		UserID:     1,
+		VenueID:    &venueID,
+		TargetURL:  server.URL,
+		EventTypes: []string{models.WebhookEventReviewCreated},
+	}
+	suite.Require().NoError(private.Create(context.Background()))
+	webhookService := &services.WebhookService{}
+	delivery, err := webhookService.SendTest(context.Background(), private)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.WebhookDeliveryFailed, delivery.Status)
+	assert.Empty(suite.T(), receiver.requests)
+
+	// Public targets are accepted
+	w := suite.makePOSTRequest("/v1/owner/venues/1/webhooks", map[string]interface{}{
+		"targetUrl":  "https://partner.example.com/hooks",
+		"eventTypes": []string{models.WebhookEventReviewCreated},
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	// Owners can only subscribe to their venue's review events
+	w = suite.makePOSTRequest("/v1/owner/venues/1/webhooks", map[string]interface{}{
+		"targetUrl":  "https://partner.example.com/hooks",
+		"eventTypes": []string{models.WebhookEventCampaignResults},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/owner/venues/1/webhooks", map[string]interface{}{
+		"targetUrl":  "ftp://partner.example.com/hooks",
+		"eventTypes": []string{models.WebhookEventReviewCreated},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.makePOSTRequest("/v1/owner/venues/2/webhooks", map[string]interface{}{
+		"targetUrl":  "https://partner.example.com/hooks",
+		"eventTypes": []string{models.WebhookEventReviewCreated},
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// Global subscriptions are for administrators
+	w = suite.makeGETRequest("/v1/admin/webhooks")
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// Subscriptions of another scope are not found
+	global := &models.WebhookSubscription{
+		UserID:     1,
+		TargetURL:  "https://partner.example.com/results",
+		EventTypes: []string{models.WebhookEventCampaignResults},
+	}
+	suite.Require().NoError(global.Create(context.Background()))
+
+	w = suite.makeGETRequest(fmt.Sprintf("/v1/owner/venues/1/webhooks/%d/deliveries", global.ID))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
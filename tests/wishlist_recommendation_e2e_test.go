@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"voting-app/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWishlistRecommendations tests boosting the nearby venues of the user's
+// "Want to Try" collection that fit the time and occasion
+func (suite *TestSuite) TestWishlistRecommendations() {
+	suite.Run("Wishlist Recommendations", func() {
+		_, err := suite.db.Exec(`INSERT INTO user_consents (user_id, purpose, status, granted_at)
+			VALUES (1, 'personalized_recommendations', 'granted', CURRENT_TIMESTAMP)`)
+		suite.Require().NoError(err)
+		var collectionID int64
+		err = suite.db.QueryRow(`INSERT INTO venue_collections (user_id, name, is_public)
+			VALUES (1, 'want to try', false) RETURNING id`).Scan(&collectionID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec("INSERT INTO venue_collection_items (collection_id, venue_id, added_by) VALUES ($1, 1, 1)", collectionID)
+		suite.Require().NoError(err)
+		_, err = suite.db.Exec(`UPDATE venues SET opening_hours = '{"friday": {"open": "18:00", "close": "01:00"}}',
+			amenities = '["wifi"]' WHERE id = 1`)
+		suite.Require().NoError(err)
+
+		lat, lng := 37.7849, -122.4094
+		wishlistSignals := func(rc services.RecommendationContext) map[int64]bool {
+			engine := &services.RecommendationEngine{}
+			recommendations, err := engine.GetPersonalizedRecommendations(context.Background(), rc)
+			suite.Require().NoError(err)
+			suite.Require().NotEmpty(recommendations)
+			signals := make(map[int64]bool)
+			for _, recommendation := range recommendations {
+				for i, signal := range recommendation.Signals {
+					if signal == services.SignalWishlist {
+						signals[recommendation.Venue.ID] = true
+						assert.Equal(suite.T(), "On your wishlist and nearby", recommendation.Reasons[i])
+					}
+				}
+			}
+			return signals
+		}
+
+		rc := services.RecommendationContext{UserID: 1, UserLat: &lat, UserLng: &lng, MaxDistance: 10, Limit: 10,
+			TimeOfDay: "evening", Occasion: "business"}
+		assert.Equal(suite.T(), map[int64]bool{1: true}, wishlistSignals(rc))
+
+		// Hours past midnight count for the night
+		rc.TimeOfDay = "night"
+		assert.True(suite.T(), wishlistSignals(rc)[1])
+
+		rc.TimeOfDay = "morning"
+		assert.Empty(suite.T(), wishlistSignals(rc))
+
+		rc.TimeOfDay = "evening"
+		rc.Occasion = "date"
+		assert.Empty(suite.T(), wishlistSignals(rc))
+
+		// Only nearby venues are boosted
+		farLat := lat + 0.5
+		rc = services.RecommendationContext{UserID: 1, UserLat: &farLat, UserLng: &lng, MaxDistance: 100, Limit: 10}
+		assert.Empty(suite.T(), wishlistSignals(rc))
+	})
+}